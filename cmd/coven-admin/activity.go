@@ -0,0 +1,469 @@
+// ABOUTME: Activity commands for coven-admin: threads, usage and the admin audit log
+// ABOUTME: Read the gateway's v1 HTTP API with the caller's token and print tabwriter tables
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/2389/coven-gateway/internal/httpapi"
+	"github.com/2389/coven-gateway/internal/timeparse"
+	"github.com/2389/coven-gateway/internal/usage"
+)
+
+// apiTimeout bounds each command's HTTP API calls, pagination included.
+const apiTimeout = 30 * time.Second
+
+// adminAPI reads the gateway's v1 HTTP API as the token's principal.
+type adminAPI struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// newAdminAPI returns an adminAPI for the gateway named by the environment.
+func newAdminAPI(token string) *adminAPI {
+	return &adminAPI{baseURL: adminURL(), token: token, client: &http.Client{Timeout: apiTimeout}}
+}
+
+// adminURL returns the gateway's HTTP base URL: COVEN_ADMIN_URL, then
+// COVEN_GATEWAY_URL, then http:// on COVEN_GATEWAY_HOST, as for invite links.
+func adminURL() string {
+	for _, env := range []string{"COVEN_ADMIN_URL", "COVEN_GATEWAY_URL"} {
+		if u := os.Getenv(env); u != "" {
+			return strings.TrimSuffix(u, "/")
+		}
+	}
+	if host := os.Getenv("COVEN_GATEWAY_HOST"); host != "" {
+		return "http://" + host
+	}
+	return "http://localhost:8080"
+}
+
+func (a *adminAPI) url(path string, q url.Values) string {
+	u := a.baseURL + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return u
+}
+
+func (a *adminAPI) header() http.Header {
+	h := http.Header{}
+	if a.token != "" {
+		h.Set("Authorization", "Bearer "+a.token)
+	}
+	return h
+}
+
+// getJSON GETs a single-object endpoint into out.
+func (a *adminAPI) getJSON(ctx context.Context, path string, q url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.url(path, q), nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header = a.header()
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("requesting %s: %w", path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return fmt.Errorf("%s: %s: %s", path, resp.Status, body.Error)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return nil
+}
+
+// fetchUpTo follows a list endpoint's cursors until it has limit items or
+// the list ends, so callers never see page boundaries.
+func fetchUpTo[T any](ctx context.Context, a *adminAPI, path string, q url.Values, limit int) ([]T, error) {
+	var items []T
+	cursor := ""
+	for len(items) < limit {
+		page := url.Values{}
+		for k, v := range q {
+			page[k] = v
+		}
+		page.Set(httpapi.LimitParam, strconv.Itoa(limit-len(items)))
+		list, err := httpapi.FetchPage[T](ctx, a.client, a.url(path, page), cursor, a.header())
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, list.Items...)
+		if list.NextCursor == "" {
+			break
+		}
+		cursor = list.NextCursor
+	}
+	return items[:min(len(items), limit)], nil
+}
+
+// parseSince turns a --since value into an API timestamp. It takes a
+// lookback such as 7d or 36h, or anything the API itself accepts.
+func parseSince(s string, now time.Time) (string, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("invalid --since %q: want e.g. 7d, 12h or an RFC3339 time", s)
+		}
+		return timeparse.Format(now.AddDate(0, 0, -n)), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return timeparse.Format(now.Add(-d)), nil
+	}
+	t, err := timeparse.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid --since %q: want e.g. 7d, 12h or an RFC3339 time", s)
+	}
+	return timeparse.Format(t), nil
+}
+
+// flagValue reads the value of the flag at args[i], failing when it is missing.
+func flagValue(args []string, i int) (string, error) {
+	if i+1 >= len(args) {
+		return "", fmt.Errorf("%s needs a value", args[i])
+	}
+	return args[i+1], nil
+}
+
+// threadItem is a row of GET /api/v1/threads.
+type threadItem struct {
+	ID           string `json:"id"`
+	FrontendName string `json:"frontend_name"`
+	AgentID      string `json:"agent_id"`
+	Archived     bool   `json:"archived"`
+	Pinned       bool   `json:"pinned"`
+	UpdatedAt    string `json:"updated_at"`
+}
+
+// messageItem is a row of GET /api/v1/threads/{id}/messages.
+type messageItem struct {
+	Sender    string `json:"sender"`
+	Content   string `json:"content"`
+	Type      string `json:"type"`
+	ToolName  string `json:"tool_name"`
+	CreatedAt string `json:"created_at"`
+}
+
+// auditItem is a row of GET /api/v1/admin/audit.
+type auditItem struct {
+	ActorPrincipalID string `json:"actor_principal_id"`
+	Action           string `json:"action"`
+	TargetType       string `json:"target_type"`
+	TargetID         string `json:"target_id"`
+	SourceIP         string `json:"source_ip"`
+	Timestamp        string `json:"timestamp"`
+}
+
+// cmdThreads handles threads subcommands.
+func cmdThreads(w io.Writer, api *adminAPI, args []string) error {
+	if api.token == "" {
+		return errors.New("COVEN_TOKEN environment variable is required")
+	}
+
+	// Default to list
+	subcmd := "list"
+	if len(args) > 0 {
+		subcmd = args[0]
+		args = args[1:]
+	}
+
+	switch subcmd {
+	case "list", "ls":
+		return cmdThreadsList(w, api, args)
+	case "show":
+		return cmdThreadsShow(w, api, args)
+	default:
+		return fmt.Errorf("unknown threads subcommand: %s (use list, show)", subcmd)
+	}
+}
+
+// cmdThreadsList lists recent threads, pinned first.
+func cmdThreadsList(w io.Writer, api *adminAPI, args []string) error {
+	q := url.Values{}
+	limit := 20
+	for i := 0; i < len(args); i++ {
+		v, err := flagValue(args, i)
+		switch args[i] {
+		case "--agent", "-a":
+			if err != nil {
+				return err
+			}
+			q.Set("agent_id", v)
+			i++
+		case "--limit", "-n":
+			if err != nil {
+				return err
+			}
+			if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+				return fmt.Errorf("invalid --limit %q", v)
+			}
+			i++
+		default:
+			return fmt.Errorf("unknown flag %s (usage: threads list [--agent <id>] [--limit <n>])", args[i])
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+	threads, err := fetchUpTo[threadItem](ctx, api, "/api/v1/threads", q, limit)
+	if err != nil {
+		return err
+	}
+
+	cyan := color.New(color.FgCyan)
+	_, _ = fmt.Fprintln(w)
+	_, _ = cyan.Fprintln(w, "  Threads")
+	_, _ = cyan.Fprintln(w, "  -------")
+
+	if len(threads) == 0 {
+		_, _ = fmt.Fprintln(w, "  (no threads)")
+		_, _ = fmt.Fprintln(w)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  ID\tAGENT\tFRONTEND\tFLAGS\tUPDATED")
+	_, _ = fmt.Fprintln(tw, "  --\t-----\t--------\t-----\t-------")
+	for _, t := range threads {
+		var flags []string
+		if t.Pinned {
+			flags = append(flags, "pinned")
+		}
+		if t.Archived {
+			flags = append(flags, "archived")
+		}
+		if len(flags) == 0 {
+			flags = []string{"-"}
+		}
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n",
+			t.ID, truncate(t.AgentID, 24), t.FrontendName, strings.Join(flags, ","), displayTime(t.UpdatedAt))
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(w)
+
+	return nil
+}
+
+// cmdThreadsShow prints a thread's transcript, oldest first.
+func cmdThreadsShow(w io.Writer, api *adminAPI, args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: threads show <thread-id>")
+	}
+	threadID := args[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+
+	// The first page is the newest; each page is chronological, so older
+	// pages go in front.
+	path := "/api/v1/threads/" + url.PathEscape(threadID) + "/messages"
+	var messages []messageItem
+	cursor := ""
+	for {
+		page, err := httpapi.FetchPage[messageItem](ctx, api.client, api.url(path, nil), cursor, api.header())
+		if err != nil {
+			return err
+		}
+		messages = append(page.Items, messages...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if len(messages) == 0 {
+		_, _ = fmt.Fprintln(w, "(no messages)")
+		return nil
+	}
+	green := color.New(color.FgGreen)
+	dim := color.New(color.Faint, color.Italic)
+	sender := ""
+	for _, m := range messages {
+		if m.Sender != sender {
+			sender = m.Sender
+			_, _ = fmt.Fprintln(w)
+			_, _ = green.Fprintf(w, "%s ", sender)
+			_, _ = dim.Fprintln(w, displayTime(m.CreatedAt))
+		}
+		switch m.Type {
+		case "tool_use":
+			printToolUse(w, m.ToolName)
+		case "tool_result":
+			printToolResult(w, m.Content, false)
+		case "citation":
+			_, _ = dim.Fprintln(w, m.Content)
+		default:
+			_, _ = fmt.Fprintln(w, m.Content)
+		}
+	}
+	_, _ = fmt.Fprintln(w)
+
+	return nil
+}
+
+// cmdUsage prints token usage per agent or per day.
+func cmdUsage(w io.Writer, api *adminAPI, args []string) error {
+	if api.token == "" {
+		return errors.New("COVEN_TOKEN environment variable is required")
+	}
+
+	since, by := "7d", usage.GroupByAgent
+	for i := 0; i < len(args); i++ {
+		v, err := flagValue(args, i)
+		switch args[i] {
+		case "--since", "-s":
+			if err != nil {
+				return err
+			}
+			since = v
+			i++
+		case "--by", "-b":
+			if err != nil {
+				return err
+			}
+			by = v
+			i++
+		default:
+			return fmt.Errorf("unknown flag %s (usage: usage [--since 7d] [--by agent|day])", args[i])
+		}
+	}
+	if by != usage.GroupByAgent && by != usage.GroupByDay {
+		return fmt.Errorf("invalid --by %q (use agent or day)", by)
+	}
+	sinceTS, err := parseSince(since, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+	var summary usage.Summary
+	q := url.Values{"group_by": {by}, "since": {sinceTS}}
+	if err := api.getJSON(ctx, "/api/v1/usage/summary", q, &summary); err != nil {
+		return err
+	}
+
+	cyan := color.New(color.FgCyan)
+	_, _ = fmt.Fprintln(w)
+	_, _ = cyan.Fprintf(w, "  Token Usage since %s\n", displayTime(sinceTS))
+	_, _ = cyan.Fprintln(w, "  -----------------")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintf(tw, "  %s\tREQUESTS\tINPUT\tOUTPUT\tCACHE READ\tCACHE WRITE\tTHINKING\tTOTAL\tCOST\t\n", strings.ToUpper(summary.GroupBy))
+	for _, g := range summary.Groups {
+		writeUsageRow(tw, g.Key, g.Totals)
+	}
+	writeUsageRow(tw, "total", summary.Totals)
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(w)
+
+	return nil
+}
+
+// writeUsageRow writes one usage table row. Cost is "-" when some agent
+// has no price.
+func writeUsageRow(w io.Writer, key string, t usage.Totals) {
+	cost := "-"
+	if t.EstimatedCostUSD != nil {
+		cost = fmt.Sprintf("$%.2f", *t.EstimatedCostUSD)
+	}
+	_, _ = fmt.Fprintf(w, "  %s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t\n", key, t.RequestCount,
+		t.InputTokens, t.OutputTokens, t.CacheReadTokens, t.CacheWriteTokens, t.ThinkingTokens, t.TotalTokens, cost)
+}
+
+// cmdAudit lists admin audit log entries, newest first.
+func cmdAudit(w io.Writer, api *adminAPI, args []string) error {
+	if api.token == "" {
+		return errors.New("COVEN_TOKEN environment variable is required")
+	}
+
+	q := url.Values{}
+	limit := 50
+	for i := 0; i < len(args); i++ {
+		v, err := flagValue(args, i)
+		switch args[i] {
+		case "--action":
+			if err != nil {
+				return err
+			}
+			q.Set("action", v)
+			i++
+		case "--since", "-s":
+			if err != nil {
+				return err
+			}
+			since, err := parseSince(v, time.Now())
+			if err != nil {
+				return err
+			}
+			q.Set("since", since)
+			i++
+		case "--limit", "-n":
+			if err != nil {
+				return err
+			}
+			if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+				return fmt.Errorf("invalid --limit %q", v)
+			}
+			i++
+		default:
+			return fmt.Errorf("unknown flag %s (usage: audit [--action <action>] [--since 7d] [--limit <n>])", args[i])
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+	entries, err := fetchUpTo[auditItem](ctx, api, "/api/v1/admin/audit", q, limit)
+	if err != nil {
+		return err
+	}
+
+	cyan := color.New(color.FgCyan)
+	_, _ = fmt.Fprintln(w)
+	_, _ = cyan.Fprintln(w, "  Audit Log")
+	_, _ = cyan.Fprintln(w, "  ---------")
+
+	if len(entries) == 0 {
+		_, _ = fmt.Fprintln(w, "  (no entries)")
+		_, _ = fmt.Fprintln(w)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  TIME\tACTOR\tACTION\tTARGET\tSOURCE")
+	_, _ = fmt.Fprintln(tw, "  ----\t-----\t------\t------\t------")
+	for _, e := range entries {
+		source := e.SourceIP
+		if source == "" {
+			source = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\t%s\t%s:%s\t%s\n", displayTime(e.Timestamp), truncate(e.ActorPrincipalID, 20),
+			e.Action, e.TargetType, truncate(e.TargetID, 24), source)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(w)
+
+	return nil
+}
@@ -0,0 +1,217 @@
+// ABOUTME: Tests for the threads, usage and audit commands against a mocked gateway HTTP API.
+// ABOUTME: Covers transparent pagination, filters passed through, transcript order and table output.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/httpapi"
+)
+
+const testToken = "test-token"
+
+// newMockGateway serves mux behind a bearer token check and returns an
+// adminAPI pointed at it.
+func newMockGateway(t *testing.T, mux *http.ServeMux) *adminAPI {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "missing token"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return &adminAPI{baseURL: srv.URL, token: testToken, client: srv.Client()}
+}
+
+// servePages answers a list endpoint from items with offset cursors,
+// honoring limit like the gateway does. It records each request's query.
+func servePages[T any](items []T, queries *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*queries = append(*queries, r.URL.RawQuery)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		limit = min(limit, 2) // force several pages
+		offset := 0
+		if c := r.URL.Query().Get("cursor"); c != "" {
+			pos, _ := httpapi.DecodeCursor(c)
+			offset, _ = strconv.Atoi(pos)
+		}
+		end := min(offset+limit, len(items))
+		list := httpapi.List[T]{Items: items[offset:end]}
+		if end < len(items) {
+			list.NextCursor = httpapi.EncodeCursor(strconv.Itoa(end))
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	}
+}
+
+func TestThreadsList(t *testing.T) {
+	var threads []threadItem
+	for i := range 5 {
+		threads = append(threads, threadItem{
+			ID: fmt.Sprintf("thread-%d", i), AgentID: "agent-a", FrontendName: "slack",
+			Pinned: i == 0, UpdatedAt: "2026-03-01T12:00:00Z",
+		})
+	}
+	var queries []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/threads", servePages(threads, &queries))
+	api := newMockGateway(t, mux)
+
+	var out bytes.Buffer
+	if err := cmdThreads(&out, api, []string{"list", "--agent", "agent-a", "--limit", "3"}); err != nil {
+		t.Fatalf("threads list: %v", err)
+	}
+	for _, want := range []string{"thread-0", "thread-1", "thread-2", "pinned"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "thread-3") {
+		t.Errorf("output goes past --limit 3:\n%s", out.String())
+	}
+	if len(queries) != 2 {
+		t.Fatalf("made %d requests (%v), want 2 pages", len(queries), queries)
+	}
+	for _, q := range queries {
+		if !strings.Contains(q, "agent_id=agent-a") {
+			t.Errorf("query %q lost the agent filter", q)
+		}
+	}
+}
+
+func TestThreadsShow(t *testing.T) {
+	// Pages come newest first, each in chronological order.
+	pages := [][]messageItem{
+		{
+			{Sender: "agent-a", Type: "tool_result", Content: "42 rows"},
+			{Sender: "agent-a", Type: "message", Content: "there are 42 rows"},
+		},
+		{
+			{Sender: "alice", Type: "message", Content: "how many rows?"},
+			{Sender: "agent-a", Type: "tool_use", ToolName: "sql_query"},
+		},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/threads/{id}/messages", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "thread-1" {
+			http.NotFound(w, r)
+			return
+		}
+		list := httpapi.List[messageItem]{Items: pages[0]}
+		if r.URL.Query().Get("cursor") != "" {
+			list.Items = pages[1]
+		} else {
+			list.NextCursor = httpapi.EncodeCursor("older")
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	})
+	api := newMockGateway(t, mux)
+
+	var out bytes.Buffer
+	if err := cmdThreads(&out, api, []string{"show", "thread-1"}); err != nil {
+		t.Fatalf("threads show: %v", err)
+	}
+	got := out.String()
+	order := []string{"alice", "how many rows?", "[tool: sql_query]", "  42 rows", "there are 42 rows"}
+	last := -1
+	for _, want := range order {
+		i := strings.Index(got, want)
+		if i <= last {
+			t.Fatalf("transcript out of order at %q:\n%s", want, got)
+		}
+		last = i
+	}
+
+	if err := cmdThreads(&out, api, []string{"show", "missing"}); err == nil {
+		t.Error("show of an unknown thread succeeded")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	var query string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/usage/summary", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		_, _ = w.Write([]byte(`{"group_by":"day","totals":{"input_tokens":30,"output_tokens":12,"total_tokens":42,"request_count":3,"estimated_cost_usd":0.5},
+			"groups":[{"key":"2026-03-01","input_tokens":30,"output_tokens":12,"total_tokens":42,"request_count":3,"estimated_cost_usd":0.5}]}`))
+	})
+	api := newMockGateway(t, mux)
+
+	var out bytes.Buffer
+	if err := cmdUsage(&out, api, []string{"--since", "7d", "--by", "day"}); err != nil {
+		t.Fatalf("usage: %v", err)
+	}
+	for _, want := range []string{"DAY", "2026-03-01", "42", "$0.50", "total"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if !strings.Contains(query, "group_by=day") || !strings.Contains(query, "since=") {
+		t.Errorf("query = %q, want group_by and since", query)
+	}
+
+	for _, args := range [][]string{{"--by", "week"}, {"--since", "soon"}, {"--since"}} {
+		if err := cmdUsage(&out, api, args); err == nil {
+			t.Errorf("usage %v succeeded, want an error", args)
+		}
+	}
+}
+
+func TestAudit(t *testing.T) {
+	entries := []auditItem{
+		{ActorPrincipalID: "admin-1", Action: "delete_binding", TargetType: "binding", TargetID: "binding-2", Timestamp: "2026-03-01T12:02:00Z"},
+		{ActorPrincipalID: "admin-1", Action: "delete_binding", TargetType: "binding", TargetID: "binding-1", Timestamp: "2026-03-01T12:01:00Z", SourceIP: "10.0.0.4"},
+		{ActorPrincipalID: "admin-1", Action: "delete_binding", TargetType: "binding", TargetID: "binding-0", Timestamp: "2026-03-01T12:00:00Z"},
+	}
+	var queries []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/admin/audit", servePages(entries, &queries))
+	api := newMockGateway(t, mux)
+
+	var out bytes.Buffer
+	if err := cmdAudit(&out, api, []string{"--action", "delete_binding", "--since", "24h"}); err != nil {
+		t.Fatalf("audit: %v", err)
+	}
+	for _, want := range []string{"binding:binding-2", "binding:binding-0", "10.0.0.4", "delete_binding"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if len(queries) != 2 || !strings.Contains(queries[0], "action=delete_binding") || !strings.Contains(queries[0], "since=") {
+		t.Errorf("queries = %v, want two pages filtered by action and since", queries)
+	}
+
+	api.token = "wrong"
+	if err := cmdAudit(&out, api, nil); err == nil || !strings.Contains(err.Error(), "missing token") {
+		t.Errorf("err = %v, want the gateway's 401 message", err)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC)
+	for in, want := range map[string]string{
+		"7d":                   "2026-03-01T12:00:00Z",
+		"36h":                  "2026-03-07T00:00:00Z",
+		"2026-01-01T00:00:00Z": "2026-01-01T00:00:00Z",
+	} {
+		got, err := parseSince(in, now)
+		if err != nil || got != want {
+			t.Errorf("parseSince(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := parseSince("xd", now); err == nil {
+		t.Error(`parseSince("xd") succeeded`)
+	}
+}
@@ -0,0 +1,69 @@
+// ABOUTME: Tests for the database invite create writes to.
+// ABOUTME: The gateway config's driver and path are honored, with --db and COVEN_DB_PATH overriding a SQLite path.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeGatewayConfig writes a gateway config with the given database
+// section and points COVEN_CONFIG at it.
+func writeGatewayConfig(t *testing.T, database string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gateway.yaml")
+	data := "server:\n  grpc_addr: localhost:50051\n  http_addr: localhost:8080\ndatabase:\n" + database
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	t.Setenv("COVEN_CONFIG", path)
+}
+
+func TestOpenInviteStore(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("COVEN_DB_PATH", "")
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	opened := func(t *testing.T, dbPath, want string) {
+		t.Helper()
+		s, err := openInviteStore(dbPath)
+		if err != nil {
+			t.Fatalf("openInviteStore: %v", err)
+		}
+		_ = s.Close()
+		if _, err := os.Stat(want); err != nil {
+			t.Errorf("database not created at %s: %v", want, err)
+		}
+	}
+
+	t.Run("no config", func(t *testing.T) {
+		t.Setenv("COVEN_CONFIG", filepath.Join(dir, "missing.yaml"))
+		opened(t, "", filepath.Join(dir, "coven", "gateway.db"))
+	})
+	t.Run("sqlite config", func(t *testing.T) {
+		want := filepath.Join(dir, "configured.db")
+		writeGatewayConfig(t, "  path: "+want+"\n")
+		opened(t, "", want)
+	})
+	t.Run("COVEN_DB_PATH overrides the config path", func(t *testing.T) {
+		want := filepath.Join(dir, "env.db")
+		writeGatewayConfig(t, "  path: "+filepath.Join(dir, "unused.db")+"\n")
+		t.Setenv("COVEN_DB_PATH", want)
+		opened(t, "", want)
+	})
+	t.Run("--db overrides the config", func(t *testing.T) {
+		want := filepath.Join(dir, "flag.db")
+		writeGatewayConfig(t, "  driver: postgres\n  dsn: postgres://[bad\n")
+		opened(t, want, want)
+	})
+	t.Run("postgres config", func(t *testing.T) {
+		writeGatewayConfig(t, "  driver: postgres\n  dsn: postgres://[bad\n")
+		_, err := openInviteStore("")
+		if err == nil || !strings.Contains(err.Error(), "postgres") {
+			t.Errorf("error = %v, want the Postgres DSN to be used", err)
+		}
+	})
+}
@@ -1,5 +1,5 @@
 // ABOUTME: Admin CLI for coven-gateway identity and binding management
-// ABOUTME: Uses gRPC with JWT authentication to manage principals and bindings
+// ABOUTME: Uses gRPC with JWT authentication to manage principals and bindings; activity commands use the HTTP API
 
 package main
 
@@ -11,8 +11,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"text/tabwriter"
@@ -20,11 +23,14 @@ import (
 
 	"github.com/fatih/color"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	"github.com/2389/coven-gateway/internal/config"
 	"github.com/2389/coven-gateway/internal/store"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
@@ -75,6 +81,12 @@ func main() {
 		err = cmdInvite(args)
 	case "chat":
 		err = cmdChat(grpcAddr, token, args)
+	case "threads":
+		err = cmdThreads(os.Stdout, newAdminAPI(token), args)
+	case "usage":
+		err = cmdUsage(os.Stdout, newAdminAPI(token), args)
+	case "audit":
+		err = cmdAudit(os.Stdout, newAdminAPI(token), args)
 	case "help", "-h", "--help":
 		printUsage()
 	default:
@@ -84,11 +96,24 @@ func main() {
 	}
 
 	if err != nil {
-		color.Red("Error: %v\n", err)
+		printError(err)
 		os.Exit(1)
 	}
 }
 
+// printError reports a failed command. A PermissionDenied from the gateway
+// gets the role it asked for and a pointer to the caller's own roles instead
+// of the raw gRPC status.
+func printError(err error) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.PermissionDenied {
+		color.Red("Error: %v\n", err)
+		return
+	}
+	color.Red("Permission denied: %s\n", st.Message())
+	fmt.Fprintln(os.Stderr, "Run 'coven-admin me' to see your roles; an admin or owner can grant the one needed.")
+}
+
 func printUsage() {
 	cyan := color.New(color.FgCyan)
 	yellow := color.New(color.FgYellow)
@@ -104,17 +129,24 @@ func printUsage() {
 	fmt.Println("  bindings list           List all channel bindings")
 	fmt.Println("  bindings create         Create a new binding")
 	fmt.Println("  bindings delete <id>    Delete a binding by ID")
+	fmt.Println("  bindings context <id>   Set or clear a binding's channel context")
 	fmt.Println("  agents                  List all agent principals")
 	fmt.Println("  agents list             List all agent principals")
 	fmt.Println("  agents create           Register a new agent")
 	fmt.Println("  agents delete <id>      Delete an agent by ID")
 	fmt.Println("  token create            Generate a JWT token for a principal")
+	fmt.Println("                          (--agent/--cap limit it to agents or capabilities)")
 	fmt.Println("  invite create           Generate an admin web UI invite link")
 	fmt.Println("  chat <agent-id> [msg]   Chat with an agent (REPL if no message)")
+	fmt.Println("  threads list            List recent threads (--agent <id>, --limit <n>)")
+	fmt.Println("  threads show <id>       Print a thread's transcript")
+	fmt.Println("  usage                   Token usage table (--since 7d, --by agent|day)")
+	fmt.Println("  audit                   Admin audit log (--action <action>, --since 7d)")
 	fmt.Println()
 	_, _ = yellow.Println("Environment:")
 	fmt.Println("  COVEN_GATEWAY_HOST       Gateway hostname (derives gRPC :50051 and HTTPS URLs)")
 	fmt.Println("  COVEN_TOKEN              JWT authentication token (required)")
+	fmt.Println("  COVEN_CONFIG             Gateway config naming the database for invite create")
 	fmt.Println()
 	_, _ = yellow.Println("Legacy (overrides COVEN_GATEWAY_HOST if set):")
 	fmt.Println("  COVEN_GATEWAY_GRPC       Gateway gRPC address (default: localhost:50051)")
@@ -126,6 +158,9 @@ func printUsage() {
 	fmt.Println("  coven-admin bindings")
 	fmt.Println("  coven-admin agents create --name 'My Agent' --pubkey-fp <fingerprint>")
 	fmt.Println("  coven-admin bindings create --frontend matrix --channel '!room:example.org' --agent <agent-id>")
+	fmt.Println("  coven-admin bindings create --frontend slack --channel C123 --agent <agent-id> --fallback <id1>,<id2>")
+	fmt.Println("  coven-admin bindings context <binding-id> 'Answer tersely; this is #support.'")
+	fmt.Println("  coven-admin usage --since 30d --by day")
 	fmt.Println()
 }
 
@@ -279,8 +314,10 @@ func cmdBindings(addr, token string, args []string) error {
 		return cmdBindingsCreate(addr, token, args)
 	case "delete", "rm", "remove":
 		return cmdBindingsDelete(addr, token, args)
+	case "context":
+		return cmdBindingsContext(addr, token, args)
 	default:
-		return fmt.Errorf("unknown bindings subcommand: %s (use list, create, delete)", subcmd)
+		return fmt.Errorf("unknown bindings subcommand: %s (use list, create, delete, context)", subcmd)
 	}
 }
 
@@ -312,18 +349,30 @@ func cmdBindingsList(addr, token string) error {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintln(w, "  ID\tFRONTEND\tCHANNEL\tAGENT\tCREATED")
-	_, _ = fmt.Fprintln(w, "  --\t--------\t-------\t-----\t-------")
+	_, _ = fmt.Fprintln(w, "  ID\tFRONTEND\tCHANNEL\tAGENT\tSTATUS\tROUTES TO\tCREATED")
+	_, _ = fmt.Fprintln(w, "  --\t--------\t-------\t-----\t------\t---------\t-------")
 
 	for _, b := range resp.Bindings {
 		id := truncate(b.Id, 12)
 		channel := truncate(b.ChannelId, 24)
-		agent := truncate(b.AgentId, 20)
-		created := b.CreatedAt
-		if t, err := time.Parse(time.RFC3339, b.CreatedAt); err == nil {
-			created = t.Format("Jan 02 15:04")
+		agent := b.AgentId
+		if b.AgentName != "" {
+			agent = b.AgentName
 		}
-		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", id, b.Frontend, channel, agent, created)
+		agent = truncate(agent, 20)
+		agentStatus := b.AgentStatus
+		if agentStatus == "" {
+			agentStatus = "-" // gateway predates agent status
+		}
+		routesTo := "-" // no agent would take a message right now
+		switch {
+		case b.EffectiveAgentId == b.AgentId:
+			routesTo = "bound agent"
+		case b.EffectiveAgentId != "":
+			routesTo = truncate(b.EffectiveAgentId, 20) + " (fallback)"
+		}
+		created := displayTime(b.CreatedAt)
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\t%s\n", id, b.Frontend, channel, agent, agentStatus, routesTo, created)
 	}
 	_ = w.Flush()
 	fmt.Println()
@@ -334,7 +383,9 @@ func cmdBindingsList(addr, token string) error {
 // cmdBindingsCreate creates a new binding.
 func cmdBindingsCreate(addr, token string, args []string) error {
 	// Parse args
-	var frontend, channelID, agentID string
+	var frontend, channelID, agentID, maxResponse, contextFile string
+	var fallbacks []string
+	var bindingContext *string
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -353,11 +404,61 @@ func cmdBindingsCreate(addr, token string, args []string) error {
 				agentID = args[i+1]
 				i++
 			}
+		case "--max-response":
+			if i+1 < len(args) {
+				maxResponse = args[i+1]
+				i++
+			}
+		case "--context":
+			if i+1 < len(args) {
+				bindingContext = &args[i+1]
+				i++
+			}
+		case "--context-file":
+			if i+1 < len(args) {
+				contextFile = args[i+1]
+				i++
+			}
+		case "--fallback":
+			// Repeatable, and each value may list several IDs separated by commas
+			if i+1 < len(args) {
+				for id := range strings.SplitSeq(args[i+1], ",") {
+					if id = strings.TrimSpace(id); id != "" {
+						fallbacks = append(fallbacks, id)
+					}
+				}
+				i++
+			}
 		}
 	}
 
 	if frontend == "" || channelID == "" || agentID == "" {
-		return errors.New("usage: bindings create --frontend <name> --channel <id> --agent <id>")
+		return errors.New("usage: bindings create --frontend <name> --channel <id> --agent <id> [--max-response <duration>] [--fallback <id>[,<id>...]] [--context <text> | --context-file <path>]")
+	}
+	if contextFile != "" {
+		text, err := readContextFile(contextFile)
+		if err != nil {
+			return err
+		}
+		bindingContext = &text
+	}
+
+	req := &pb.CreateBindingRequest{
+		Frontend:         frontend,
+		ChannelId:        channelID,
+		AgentId:          agentID,
+		FallbackAgentIds: fallbacks,
+	}
+	if bindingContext != nil {
+		req.Context = *bindingContext
+	}
+	if maxResponse != "" {
+		d, err := time.ParseDuration(maxResponse)
+		if err != nil {
+			return fmt.Errorf("invalid --max-response %q: %w", maxResponse, err)
+		}
+		seconds := int32(d / time.Second)
+		req.MaxResponseSeconds = &seconds
 	}
 
 	conn, err := createClient(addr)
@@ -369,11 +470,7 @@ func cmdBindingsCreate(addr, token string, args []string) error {
 	client := pb.NewAdminServiceClient(conn)
 	ctx := authContext(token)
 
-	resp, err := client.CreateBinding(ctx, &pb.CreateBindingRequest{
-		Frontend:  frontend,
-		ChannelId: channelID,
-		AgentId:   agentID,
-	})
+	resp, err := client.CreateBinding(ctx, req)
 	if err != nil {
 		return fmt.Errorf("CreateBinding: %w", err)
 	}
@@ -383,10 +480,110 @@ func cmdBindingsCreate(addr, token string, args []string) error {
 	fmt.Printf("  Frontend:  %s\n", resp.Frontend)
 	fmt.Printf("  Channel:   %s\n", resp.ChannelId)
 	fmt.Printf("  Agent:     %s\n", resp.AgentId)
+	if resp.MaxResponseSeconds != nil {
+		fmt.Printf("  Max reply: %s\n", time.Duration(resp.GetMaxResponseSeconds())*time.Second)
+	}
+	if len(resp.FallbackAgentIds) > 0 {
+		fmt.Printf("  Fallback:  %s\n", strings.Join(resp.FallbackAgentIds, ", "))
+	}
+	if resp.Context != "" {
+		fmt.Printf("  Context:   %d bytes\n", len(resp.Context))
+	}
+
+	return nil
+}
+
+// cmdBindingsContext sets, clears or shows a binding's channel context.
+func cmdBindingsContext(addr, token string, args []string) error {
+	const usage = "usage: bindings context <binding-id> [<text> | --file <path> | --clear]"
+	if len(args) < 1 {
+		return errors.New(usage)
+	}
+	bindingID := args[0]
+
+	var text *string
+	switch {
+	case len(args) == 1:
+		// Show the current context
+	case args[1] == "--clear":
+		empty := ""
+		text = &empty
+	case args[1] == "--file":
+		if len(args) < 3 {
+			return errors.New(usage)
+		}
+		contents, err := readContextFile(args[2])
+		if err != nil {
+			return err
+		}
+		text = &contents
+	default:
+		joined := strings.Join(args[1:], " ")
+		text = &joined
+	}
+
+	conn, err := createClient(addr)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	client := pb.NewAdminServiceClient(conn)
+	ctx := authContext(token)
+
+	// UpdateBinding needs the binding's agent, so look the binding up first.
+	resp, err := client.ListBindings(ctx, &pb.ListBindingsRequest{})
+	if err != nil {
+		return fmt.Errorf("ListBindings: %w", err)
+	}
+	var binding *pb.Binding
+	for _, b := range resp.Bindings {
+		if b.Id == bindingID {
+			binding = b
+			break
+		}
+	}
+	if binding == nil {
+		return fmt.Errorf("binding not found: %s", bindingID)
+	}
+
+	if text == nil {
+		if binding.Context == "" {
+			fmt.Println("(no context)")
+		} else {
+			fmt.Println(binding.Context)
+		}
+		return nil
+	}
 
+	_, err = client.UpdateBinding(ctx, &pb.UpdateBindingRequest{
+		Id:      binding.Id,
+		AgentId: binding.AgentId,
+		Context: text,
+	})
+	if err != nil {
+		return fmt.Errorf("UpdateBinding: %w", err)
+	}
+
+	green := color.New(color.FgGreen)
+	if *text == "" {
+		_, _ = green.Printf("✓ Cleared context for binding: %s\n", bindingID)
+	} else {
+		_, _ = green.Printf("✓ Set context for binding: %s (%d bytes)\n", bindingID, len(*text))
+	}
 	return nil
 }
 
+// readContextFile reads a binding context from a file, without its
+// trailing newline.
+func readContextFile(path string) (string, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("reading context file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
 // cmdBindingsDelete deletes a binding.
 func cmdBindingsDelete(addr, token string, args []string) error {
 	if len(args) < 1 {
@@ -434,7 +631,7 @@ func cmdToken(addr, token string, args []string) error {
 	case "create":
 		return cmdTokenCreate(addr, token, args)
 	default:
-		return errors.New("usage: token create --principal <id> [--ttl <duration>]")
+		return errors.New("usage: token create --principal <id> [--ttl <days>] [--agent <id>]... [--cap <capability>]...")
 	}
 }
 
@@ -442,7 +639,8 @@ func cmdToken(addr, token string, args []string) error {
 func cmdTokenCreate(addr, token string, args []string) error {
 	// Parse args
 	var principalID string
-	var ttlDays int64 = 30 // default 30 days
+	var ttlDays int64 = 30              // default 30 days
+	var agentIDs, capabilities []string // repeatable; empty leaves the token unscoped
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -461,11 +659,21 @@ func cmdTokenCreate(addr, token string, args []string) error {
 				ttlDays = days
 				i++
 			}
+		case "--agent", "-a":
+			if i+1 < len(args) {
+				agentIDs = append(agentIDs, args[i+1])
+				i++
+			}
+		case "--cap", "-c":
+			if i+1 < len(args) {
+				capabilities = append(capabilities, args[i+1])
+				i++
+			}
 		}
 	}
 
 	if principalID == "" {
-		return errors.New("usage: token create --principal <id> [--ttl <days>]")
+		return errors.New("usage: token create --principal <id> [--ttl <days>] [--agent <id>]... [--cap <capability>]...")
 	}
 
 	conn, err := createClient(addr)
@@ -481,8 +689,10 @@ func cmdTokenCreate(addr, token string, args []string) error {
 	ttlSeconds := ttlDays * 24 * 60 * 60
 
 	resp, err := client.CreateToken(ctx, &pb.CreateTokenRequest{
-		PrincipalId: principalID,
-		TtlSeconds:  ttlSeconds,
+		PrincipalId:        principalID,
+		TtlSeconds:         ttlSeconds,
+		AgentIds:           agentIDs,
+		CapabilitiesSubset: capabilities,
 	})
 	if err != nil {
 		return fmt.Errorf("CreateToken: %w", err)
@@ -496,6 +706,12 @@ func cmdTokenCreate(addr, token string, args []string) error {
 	fmt.Println()
 	_, _ = cyan.Println("  Principal:  " + principalID)
 	_, _ = cyan.Println("  Expires:    " + resp.ExpiresAt)
+	if len(agentIDs) > 0 {
+		_, _ = cyan.Println("  Agents:     " + strings.Join(agentIDs, ", "))
+	}
+	if len(capabilities) > 0 {
+		_, _ = cyan.Println("  Caps:       " + strings.Join(capabilities, ", "))
+	}
 	fmt.Println()
 	fmt.Println("  Token (keep this secret!):")
 	fmt.Println()
@@ -568,8 +784,8 @@ func cmdAgentsList(addr, token string) error {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintln(w, "  ID\tNAME\tSTATUS\tFINGERPRINT\tCREATED")
-	_, _ = fmt.Fprintln(w, "  --\t----\t------\t-----------\t-------")
+	_, _ = fmt.Fprintln(w, "  ID\tNAME\tSTATUS\tFINGERPRINT\tTAGS\tCREATED")
+	_, _ = fmt.Fprintln(w, "  --\t----\t------\t-----------\t----\t-------")
 
 	for _, p := range resp.Principals {
 		id := truncate(p.Id, 20)
@@ -578,11 +794,8 @@ func cmdAgentsList(addr, token string) error {
 		if p.PubkeyFp != nil {
 			fp = truncate(*p.PubkeyFp, 20)
 		}
-		created := p.CreatedAt
-		if t, err := time.Parse(time.RFC3339, p.CreatedAt); err == nil {
-			created = t.Format("Jan 02 15:04")
-		}
-		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", id, name, p.Status, fp, created)
+		created := displayTime(p.CreatedAt)
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\n", id, name, p.Status, fp, formatTags(p.GetTags()), created)
 	}
 	_ = w.Flush()
 	fmt.Println()
@@ -590,6 +803,18 @@ func cmdAgentsList(addr, token string) error {
 	return nil
 }
 
+// formatTags renders tags as sorted key=value pairs, or "-" when there are none.
+func formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return "-"
+	}
+	pairs := make([]string, 0, len(tags))
+	for _, k := range slices.Sorted(maps.Keys(tags)) {
+		pairs = append(pairs, k+"="+tags[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
 // cmdAgentsCreate creates a new agent principal.
 func cmdAgentsCreate(addr, token string, args []string) error {
 	// Parse args
@@ -690,6 +915,16 @@ func cmdAgentsDelete(addr, token string, args []string) error {
 	return nil
 }
 
+// displayTime renders an API timestamp (always RFC3339 UTC) in local time for
+// tables. Anything else is shown as-is.
+func displayTime(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return t.Local().Format("Jan 02 15:04")
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -740,6 +975,65 @@ func cmdInvite(args []string) error {
 	}
 }
 
+// openInviteStore opens the gateway's database for invite create: the SQLite
+// file at dbPath when --db is given, else the database the gateway config
+// names, opened by store.Open as the gateway does. COVEN_DB_PATH overrides a
+// SQLite config's path, as it does for the gateway. Without a config file it
+// falls back to COVEN_DB_PATH or the default SQLite path.
+func openInviteStore(dbPath string) (*store.SQLStore, error) {
+	if dbPath != "" {
+		return store.Open(store.DriverSQLite, dbPath)
+	}
+	envPath := os.Getenv("COVEN_DB_PATH")
+	cfg, err := config.Load(gatewayConfigPath())
+	switch {
+	case err == nil:
+		source := cfg.Database.Path
+		if cfg.Database.Driver == store.DriverPostgres {
+			source = cfg.Database.DSN
+		} else if envPath != "" {
+			source = envPath
+		}
+		return store.Open(cfg.Database.Driver, source)
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("loading gateway config: %w", err)
+	}
+
+	if envPath != "" {
+		return store.Open(store.DriverSQLite, envPath)
+	}
+	configDir, err := xdgConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return store.Open(store.DriverSQLite, filepath.Join(configDir, "coven", "gateway.db"))
+}
+
+// gatewayConfigPath returns the gateway's config file, found as the gateway
+// finds it: COVEN_CONFIG, else coven/gateway.yaml under the XDG config dir.
+func gatewayConfigPath() string {
+	if envPath := os.Getenv("COVEN_CONFIG"); envPath != "" {
+		return envPath
+	}
+	configDir, err := xdgConfigDir()
+	if err != nil {
+		return "gateway.yaml"
+	}
+	return filepath.Join(configDir, "coven", "gateway.yaml")
+}
+
+// xdgConfigDir returns XDG_CONFIG_HOME, defaulting to ~/.config.
+func xdgConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine config directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config"), nil
+}
+
 // cmdInviteCreate creates a new admin invite link.
 func cmdInviteCreate(args []string) error {
 	// Parse args
@@ -771,23 +1065,6 @@ func cmdInviteCreate(args []string) error {
 		}
 	}
 
-	// Default database path
-	// COVEN_DB_PATH env var takes precedence for Docker deployments
-	if dbPath == "" {
-		dbPath = os.Getenv("COVEN_DB_PATH")
-	}
-	if dbPath == "" {
-		configDir := os.Getenv("XDG_CONFIG_HOME")
-		if configDir == "" {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				return fmt.Errorf("could not determine config directory: %w", err)
-			}
-			configDir = filepath.Join(homeDir, ".config")
-		}
-		dbPath = filepath.Join(configDir, "coven", "gateway.db")
-	}
-
 	// Default base URL
 	// COVEN_GATEWAY_URL takes full URL, or COVEN_GATEWAY_HOST derives http:// URL
 	// (use http:// for tailnet-only; WireGuard already encrypts)
@@ -802,7 +1079,7 @@ func cmdInviteCreate(args []string) error {
 	}
 
 	// Open database
-	db, err := store.NewSQLiteStore(dbPath)
+	db, err := openInviteStore(dbPath)
 	if err != nil {
 		return fmt.Errorf("opening database: %w", err)
 	}
@@ -946,7 +1223,6 @@ func streamResponse(ctx context.Context, client pb.ClientServiceClient, agentID
 	}
 
 	dim := color.New(color.Faint, color.Italic)
-	yellow := color.New(color.FgYellow)
 
 	for {
 		event, err := stream.Recv()
@@ -963,13 +1239,10 @@ func streamResponse(ctx context.Context, client pb.ClientServiceClient, agentID
 		case *pb.ClientStreamEvent_Thinking:
 			_, _ = dim.Print(p.Thinking.Content)
 		case *pb.ClientStreamEvent_ToolUse:
-			_, _ = yellow.Printf("\n[tool: %s]\n", p.ToolUse.Name)
+			fmt.Println()
+			printToolUse(os.Stdout, p.ToolUse.Name)
 		case *pb.ClientStreamEvent_ToolResult:
-			if p.ToolResult.IsError {
-				color.Red("  %s\n", p.ToolResult.Output)
-			} else if p.ToolResult.Output != "" {
-				fmt.Printf("  %s\n", p.ToolResult.Output)
-			}
+			printToolResult(os.Stdout, p.ToolResult.Output, p.ToolResult.IsError)
 		case *pb.ClientStreamEvent_Done:
 			fmt.Println()
 			return nil
@@ -979,6 +1252,22 @@ func streamResponse(ctx context.Context, client pb.ClientServiceClient, agentID
 	}
 }
 
+// printToolUse renders a tool call the way chat shows it.
+func printToolUse(w io.Writer, name string) {
+	_, _ = color.New(color.FgYellow).Fprintf(w, "[tool: %s]\n", name)
+}
+
+// printToolResult renders a tool's output indented under its call, in red
+// when the tool failed.
+func printToolResult(w io.Writer, output string, isError bool) {
+	switch {
+	case isError:
+		_, _ = color.New(color.FgRed).Fprintf(w, "  %s\n", output)
+	case output != "":
+		_, _ = fmt.Fprintf(w, "  %s\n", output)
+	}
+}
+
 // idemCounter provides a monotonic fallback sequence for idempotency keys.
 var idemCounter atomic.Uint64
 
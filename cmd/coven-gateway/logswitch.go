@@ -0,0 +1,76 @@
+// ABOUTME: Log handler whose level and format can be replaced while the gateway runs
+// ABOUTME: Loggers derived with With and WithGroup follow the swap, so a config reload reaches every component
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+
+	"github.com/2389/coven-gateway/internal/config"
+)
+
+// logSwitch holds the handler built from the logging settings in effect.
+type logSwitch struct {
+	current atomic.Pointer[switchState]
+}
+
+// switchState is one set of logging settings' handler. Each apply stores a
+// new one, so its address tells derived handlers their cache is stale.
+type switchState struct {
+	handler slog.Handler
+}
+
+// apply swaps in a handler for cfg.
+func (s *logSwitch) apply(cfg config.LoggingConfig) {
+	s.current.Store(&switchState{handler: newLogHandler(cfg)})
+}
+
+// switchHandler forwards to the switch's current handler, replaying the
+// attrs and groups it was derived with. The derived handler is cached until
+// the next swap.
+type switchHandler struct {
+	sw     *logSwitch
+	derive []func(slog.Handler) slog.Handler
+	cache  atomic.Pointer[derivedHandler]
+}
+
+type derivedHandler struct {
+	base    *switchState
+	handler slog.Handler
+}
+
+func (h *switchHandler) handler() slog.Handler {
+	base := h.sw.current.Load()
+	if c := h.cache.Load(); c != nil && c.base == base {
+		return c.handler
+	}
+	handler := base.handler
+	for _, derive := range h.derive {
+		handler = derive(handler)
+	}
+	h.cache.Store(&derivedHandler{base: base, handler: handler})
+	return handler
+}
+
+func (h *switchHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler().Enabled(ctx, level)
+}
+
+func (h *switchHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler().Handle(ctx, r)
+}
+
+func (h *switchHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *switchHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h *switchHandler) with(derive func(slog.Handler) slog.Handler) *switchHandler {
+	return &switchHandler{sw: h.sw, derive: append(slices.Clip(h.derive), derive)}
+}
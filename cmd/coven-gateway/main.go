@@ -27,6 +27,7 @@ import (
 	"github.com/2389/coven-gateway/internal/auth"
 	"github.com/2389/coven-gateway/internal/config"
 	"github.com/2389/coven-gateway/internal/gateway"
+	"github.com/2389/coven-gateway/internal/sdnotify"
 	"github.com/2389/coven-gateway/internal/store"
 )
 
@@ -90,6 +91,8 @@ func run() int {
 		fmt.Println("  bootstrap --name NAME  Create initial owner principal and token")
 		fmt.Println("  health                 Check gateway health")
 		fmt.Println("  agents                 List connected agents")
+		fmt.Println("  export --out FILE      Export gateway state to a tarball")
+		fmt.Println("  import --in FILE       Import gateway state from an export")
 		return 1
 	}
 
@@ -108,6 +111,10 @@ func run() int {
 		err = runHealth(ctx)
 	case "agents":
 		err = runAgents(ctx)
+	case "export":
+		err = runExport(ctx)
+	case "import":
+		err = runImport(ctx)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", os.Args[1])
 		return 1
@@ -138,7 +145,7 @@ func runServe(ctx context.Context) error {
 	}
 
 	// Setup logger
-	logger := setupLogger(cfg.Logging)
+	logger, applyLogging := setupLogger(cfg.Logging)
 
 	// Startup info
 	green := color.New(color.FgGreen)
@@ -179,10 +186,29 @@ func runServe(ctx context.Context) error {
 		return fmt.Errorf("creating gateway: %w", err)
 	}
 
+	// Under systemd with Type=notify, report readiness and feed the watchdog.
+	// Both are no-ops when NOTIFY_SOCKET / WATCHDOG_USEC are unset.
+	watchdog, err := sdnotify.WatchdogInterval()
+	if err != nil {
+		logger.Warn("ignoring invalid systemd watchdog setting", "error", err)
+	}
+	gw.SetSystemdNotifier(sdnotify.FromEnv(), watchdog)
+	// SIGHUP re-reads the config file and applies what can change live.
+	gw.SetConfigReload(configPath, applyLogging)
+
 	return gw.Run(ctx)
 }
 
-func setupLogger(cfg config.LoggingConfig) *slog.Logger {
+// setupLogger builds the process logger. The returned function applies new
+// logging settings to it and every logger derived from it, for config reloads.
+func setupLogger(cfg config.LoggingConfig) (*slog.Logger, func(config.LoggingConfig)) {
+	sw := &logSwitch{}
+	sw.apply(cfg)
+	return slog.New(&switchHandler{sw: sw}), sw.apply
+}
+
+// newLogHandler builds the handler for one set of logging settings.
+func newLogHandler(cfg config.LoggingConfig) slog.Handler {
 	var level slog.Level
 	switch cfg.Level {
 	case "debug":
@@ -210,7 +236,7 @@ func setupLogger(cfg config.LoggingConfig) *slog.Logger {
 		}
 	}
 
-	return slog.New(handler)
+	return handler
 }
 
 // colorHandler provides colorized log output with thread-safe writes.
@@ -504,23 +530,18 @@ func runBootstrap(ctx context.Context) error {
 	}
 
 	// Open the store directly
-	s, err := store.NewSQLiteStore(cfg.Database.Path)
+	s, err := store.Open(cfg.Database.Driver, databaseSource(cfg))
 	if err != nil {
 		return fmt.Errorf("opening database: %w", err)
 	}
 	defer func() { _ = s.Close() }()
 
-	_, _ = green.Printf("  ✓ Database: %s\n", cfg.Database.Path)
-
-	// Check if any principals already exist
-	count, err := s.CountPrincipals(ctx, store.PrincipalFilter{})
-	if err != nil {
-		return fmt.Errorf("checking principals: %w", err)
-	}
-
-	if count > 0 {
-		return fmt.Errorf("bootstrap already complete: %d principal(s) exist", count)
+	// The DSN may carry a password, so Postgres is shown by name only.
+	dbLabel := cfg.Database.Path
+	if cfg.Database.Driver == store.DriverPostgres {
+		dbLabel = "postgres"
 	}
+	_, _ = green.Printf("  ✓ Database: %s\n", dbLabel)
 
 	// Create the owner principal
 	principalID := uuid.New().String()
@@ -532,16 +553,27 @@ func runBootstrap(ctx context.Context) error {
 		CreatedAt:   time.Now().UTC(),
 	}
 
-	if err := s.CreatePrincipal(ctx, principal); err != nil {
-		return fmt.Errorf("creating principal: %w", err)
-	}
-
-	// Grant owner role. If this fails, attempt to clean up the principal
-	// to avoid leaving the system in a partially bootstrapped state.
-	if err := s.AddRole(ctx, store.RoleSubjectPrincipal, principalID, store.RoleOwner); err != nil {
-		// Best-effort cleanup - ignore errors since we're already failing
-		_ = s.DeletePrincipal(ctx, principalID)
-		return fmt.Errorf("granting owner role: %w", err)
+	// The check, the principal and its owner role commit together, so a
+	// failure cannot leave the system partially bootstrapped and two
+	// concurrent bootstraps cannot both succeed.
+	err = s.WithSQLTx(ctx, func(tx *store.SQLStore) error {
+		count, err := tx.CountPrincipals(ctx, store.PrincipalFilter{})
+		if err != nil {
+			return fmt.Errorf("checking principals: %w", err)
+		}
+		if count > 0 {
+			return fmt.Errorf("bootstrap already complete: %d principal(s) exist", count)
+		}
+		if err := tx.CreatePrincipal(ctx, principal); err != nil {
+			return fmt.Errorf("creating principal: %w", err)
+		}
+		if err := tx.AddRole(ctx, store.RoleSubjectPrincipal, principalID, store.RoleOwner); err != nil {
+			return fmt.Errorf("granting owner role: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	_, _ = green.Printf("  ✓ Created owner principal: %s\n", displayName)
@@ -685,6 +717,7 @@ func runInit() error {
 	cfg.WriteString("metrics:\n")
 	cfg.WriteString("  enabled: false\n")
 	cfg.WriteString("  path: \"/metrics\"\n")
+	cfg.WriteString("  # addr: \"127.0.0.1:9090\"  # serve metrics on their own listener\n")
 
 	// Ensure config directory exists
 	configDir := filepath.Dir(outputFile)
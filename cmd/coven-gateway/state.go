@@ -0,0 +1,140 @@
+// ABOUTME: export and import subcommands that move gateway state between machines
+// ABOUTME: Wrap the store's state export, a gzipped tarball of JSONL tables with a manifest
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// openConfiguredStore opens the database named by the gateway config.
+func openConfiguredStore() (*store.SQLStore, error) {
+	cfg, err := config.Load(getConfigPath())
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	s, err := store.Open(cfg.Database.Driver, databaseSource(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	return s, nil
+}
+
+// databaseSource is what store.Open needs for the configured driver: the
+// SQLite path or the Postgres DSN.
+func databaseSource(cfg *config.Config) string {
+	if cfg.Database.Driver == store.DriverPostgres {
+		return cfg.Database.DSN
+	}
+	return cfg.Database.Path
+}
+
+// runExport writes the gateway's state to a tarball:
+// coven-gateway export --out state.tar.gz [--include-auth].
+func runExport(ctx context.Context) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("out", "", "file to write the export to")
+	includeAuth := fs.Bool("include-auth", false, "also export admin sessions and WebAuthn credentials")
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("--out is required")
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+
+	s, err := openConfiguredStore()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.Close() }()
+
+	// The export holds secrets and tokens, so only the owner may read it.
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", *out, err)
+	}
+	manifest, err := s.ExportState(ctx, f, store.ExportOptions{IncludeAuth: *includeAuth})
+	if err == nil {
+		err = f.Close()
+	} else {
+		_ = f.Close()
+	}
+	if err != nil {
+		_ = os.Remove(*out)
+		return fmt.Errorf("exporting state: %w", err)
+	}
+
+	green := color.New(color.FgGreen)
+	_, _ = green.Printf("  ✓ Exported schema version %d to %s\n", manifest.SchemaVersion, *out)
+	for _, table := range manifest.Tables {
+		if table.Rows > 0 {
+			fmt.Printf("    %-28s %d rows\n", table.Name, table.Rows)
+		}
+	}
+	return nil
+}
+
+// runImport loads a tarball written by export:
+// coven-gateway import --in state.tar.gz [--skip-existing | --overwrite] [--include-auth].
+func runImport(ctx context.Context) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	in := fs.String("in", "", "export file to import")
+	skipExisting := fs.Bool("skip-existing", false, "keep rows that already exist (default)")
+	overwrite := fs.Bool("overwrite", false, "replace rows that already exist")
+	includeAuth := fs.Bool("include-auth", false, "also import admin sessions and WebAuthn credentials")
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		return err
+	}
+	if *in == "" {
+		return errors.New("--in is required")
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+	if *skipExisting && *overwrite {
+		return errors.New("--skip-existing and --overwrite are mutually exclusive")
+	}
+	opts := store.ImportOptions{Conflict: store.ConflictSkipExisting, IncludeAuth: *includeAuth}
+	if *overwrite {
+		opts.Conflict = store.ConflictOverwrite
+	}
+
+	f, err := os.Open(*in)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", *in, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	s, err := openConfiguredStore()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.Close() }()
+
+	result, err := s.ImportState(ctx, f, opts)
+	if err != nil {
+		return fmt.Errorf("importing state: %w", err)
+	}
+
+	green := color.New(color.FgGreen)
+	_, _ = green.Printf("  ✓ Imported %s (schema version %d, exported %s)\n",
+		*in, result.Manifest.SchemaVersion, result.Manifest.CreatedAt.Format("Jan 02, 2006 15:04 MST"))
+	for _, table := range result.Tables {
+		if table.Written > 0 || table.Skipped > 0 {
+			fmt.Printf("    %-28s %d written, %d skipped\n", table.Name, table.Written, table.Skipped)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+// ABOUTME: Entry point for coven-slack, the Slack bridge for coven agents
+// ABOUTME: Connects over Slack Socket Mode and relays messages through the gateway
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/2389/coven-gateway/internal/slackbridge"
+)
+
+// getConfigPath returns the path to the bridge config file.
+// Priority: COVEN_SLACK_CONFIG env var > XDG_CONFIG_HOME/coven/slack-bridge.yaml > ~/.config/coven/slack-bridge.yaml.
+func getConfigPath() string {
+	if envPath := os.Getenv("COVEN_SLACK_CONFIG"); envPath != "" {
+		return envPath
+	}
+
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "slack-bridge.yaml" // fallback
+		}
+		configDir = filepath.Join(homeDir, ".config")
+	}
+
+	return filepath.Join(configDir, "coven", "slack-bridge.yaml")
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	if len(os.Args) < 2 {
+		fmt.Println("Usage: coven-slack <command>")
+		fmt.Println()
+		fmt.Println("Commands:")
+		fmt.Println("  serve    Start the Slack bridge")
+		fmt.Println("  init     Create a new config file interactively")
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	var err error
+	switch os.Args[1] {
+	case "serve":
+		err = runServe(ctx)
+	case "init":
+		err = runInit()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", os.Args[1])
+		return 1
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runServe(ctx context.Context) error {
+	configPath := getConfigPath()
+	cfg, err := slackbridge.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("loading config from %s: %w (run 'coven-slack init' to create one)", configPath, err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	slog.SetDefault(logger)
+
+	return slackbridge.New(cfg, logger).Run(ctx)
+}
+
+func runInit() error {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Println("coven-slack configuration setup")
+	fmt.Println("===============================")
+	fmt.Println()
+
+	outputFile := filepath.Clean(prompt(reader, "Config file path", getConfigPath()))
+
+	if _, err := os.Stat(outputFile); err == nil {
+		overwrite := prompt(reader, "File exists. Overwrite?", "no")
+		if strings.ToLower(overwrite) != "yes" && strings.ToLower(overwrite) != "y" {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	// Slack app
+	fmt.Println("\n--- Slack App ---")
+	fmt.Println("Enable Socket Mode, subscribe to message.channels, message.groups,")
+	fmt.Println("message.im and app_mention, and add the chat:write bot scope.")
+	appToken := prompt(reader, "App-level token (xapp-...)", "${SLACK_APP_TOKEN}")
+	botToken := prompt(reader, "Bot token (xoxb-...)", "${SLACK_BOT_TOKEN}")
+
+	// Gateway
+	fmt.Println("\n--- Gateway ---")
+	gatewayURL := prompt(reader, "Gateway URL", "http://localhost:8080")
+	gatewayToken := prompt(reader, "Gateway API token (leave empty if auth is off)", "${COVEN_TOKEN}")
+
+	// Bridge behavior
+	fmt.Println("\n--- Bridge ---")
+	channels := prompt(reader, "Allowed channel IDs, comma-separated (empty for all)", "")
+	commandPrefix := prompt(reader, "Command prefix (empty to answer every message)", "")
+	editInterval := prompt(reader, "Least time between reply edits", "1s")
+
+	// Generate config
+	var cfg strings.Builder
+	cfg.WriteString("# coven-slack configuration\n")
+	cfg.WriteString("# Generated by coven-slack init\n\n")
+
+	cfg.WriteString("slack:\n")
+	fmt.Fprintf(&cfg, "  app_token: \"%s\"\n", appToken)
+	fmt.Fprintf(&cfg, "  bot_token: \"%s\"\n", botToken)
+	cfg.WriteString("\n")
+
+	cfg.WriteString("gateway:\n")
+	fmt.Fprintf(&cfg, "  url: \"%s\"\n", gatewayURL)
+	if gatewayToken != "" {
+		fmt.Fprintf(&cfg, "  token: \"%s\"\n", gatewayToken)
+	}
+	cfg.WriteString("\n")
+
+	cfg.WriteString("bridge:\n")
+	var quoted []string
+	for _, ch := range strings.Split(channels, ",") {
+		if ch = strings.TrimSpace(ch); ch != "" {
+			quoted = append(quoted, "\""+ch+"\"")
+		}
+	}
+	fmt.Fprintf(&cfg, "  allowed_channels: [%s]\n", strings.Join(quoted, ", "))
+	fmt.Fprintf(&cfg, "  command_prefix: \"%s\"\n", commandPrefix)
+	fmt.Fprintf(&cfg, "  edit_interval: \"%s\"\n", editInterval)
+
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0750); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(outputFile, []byte(cfg.String()), 0600); err != nil {
+		return fmt.Errorf("writing config file: %w", err)
+	}
+
+	fmt.Printf("\nConfig written to %s\n", outputFile)
+	fmt.Println("\nBind each Slack channel to an agent:")
+	fmt.Println("  coven-admin bindings create --frontend slack --channel <channel-id> --agent <agent-id>")
+	fmt.Println("\nTo start the bridge:")
+	fmt.Println("  coven-slack serve")
+
+	return nil
+}
+
+func prompt(reader *bufio.Reader, question, defaultVal string) string {
+	if defaultVal != "" {
+		fmt.Printf("%s [%s]: ", question, defaultVal)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		// On EOF or error, return default
+		fmt.Println()
+		return defaultVal
+	}
+	input = strings.TrimSpace(input)
+
+	if input == "" {
+		return defaultVal
+	}
+	return input
+}
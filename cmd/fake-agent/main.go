@@ -1,22 +1,18 @@
-// ABOUTME: Minimal fake agent for E2E testing — connects via gRPC, echoes messages with markdown.
-// ABOUTME: Usage: fake-agent [-addr localhost:50051] [-name "Echo Agent"]
+// ABOUTME: Minimal fake agent for E2E testing — connects via client.AgentConnection, echoes messages with markdown.
+// ABOUTME: Usage: fake-agent [-addr localhost:50051] [-name "Echo Agent"] [-id e2e-echo-agent]
 package main
 
 import (
 	"context"
-	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"os/signal"
 	"strings"
 	"time"
 
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-
+	"github.com/2389/coven-gateway/internal/client"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
@@ -32,100 +28,46 @@ func main() {
 }
 
 func run(addr, name, agentID string) error {
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return fmt.Errorf("failed to connect: %w", err)
-	}
-	defer conn.Close()
-
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
-	client := pb.NewCovenControlClient(conn)
-	stream, err := client.AgentStream(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to open stream: %w", err)
-	}
-
-	// Register
-	if err := stream.Send(&pb.AgentMessage{
-		Payload: &pb.AgentMessage_Register{
-			Register: &pb.RegisterAgent{
-				AgentId:      agentID,
-				Name:         name,
-				Capabilities: []string{"chat", "echo"},
-				Metadata: &pb.AgentMetadata{
-					WorkingDirectory: "/tmp/fake-agent",
-					Hostname:         "e2e-test",
-					Os:               "test",
-					Backend:          "direct",
-				},
-			},
+	conn, err := client.Connect(ctx, client.AgentConfig{
+		Addr:         addr,
+		AgentID:      agentID,
+		Name:         name,
+		Capabilities: []string{"chat", "echo"},
+		Metadata: &pb.AgentMetadata{
+			WorkingDirectory: "/tmp/fake-agent",
+			Hostname:         "e2e-test",
+			Os:               "test",
+			Backend:          "direct",
 		},
-	}); err != nil {
-		return fmt.Errorf("failed to register: %w", err)
-	}
-
-	// Receive Welcome
-	msg, err := stream.Recv()
+		OnMessage: handleMessage,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to receive welcome: %w", err)
-	}
-	welcome := msg.GetWelcome()
-	if welcome == nil {
-		return fmt.Errorf("expected welcome, got: %v", msg)
+		return fmt.Errorf("failed to connect: %w", err)
 	}
-	fmt.Fprintf(os.Stderr, "registered as %s (instance: %s)\n", welcome.AgentId, welcome.InstanceId)
+	welcome := conn.Welcome()
+	fmt.Fprintf(os.Stderr, "registered as %s (instance: %s)\n", welcome.GetAgentId(), welcome.GetInstanceId())
 
-	// Message loop
-	for {
-		msg, err := stream.Recv()
-		if errors.Is(err, io.EOF) {
-			return nil
-		}
-		if err != nil {
-			if ctx.Err() != nil {
-				return nil // graceful shutdown
-			}
-			return fmt.Errorf("recv error: %w", err)
-		}
-
-		sm := msg.GetSendMessage()
-		if sm == nil {
-			continue
-		}
-
-		log.Printf("received message [%s]: %s", sm.RequestId, sm.Content)
+	return conn.Wait()
+}
 
-		reply := echoReply(sm.Content)
+// handleMessage echoes the message back as markdown.
+func handleMessage(_ context.Context, req *client.RequestContext) {
+	log.Printf("received message [%s]: %s", req.RequestID, req.Content)
 
-		// Send text response with markdown
-		if err := stream.Send(&pb.AgentMessage{
-			Payload: &pb.AgentMessage_Response{
-				Response: &pb.MessageResponse{
-					RequestId: sm.RequestId,
-					Event:     &pb.MessageResponse_Text{Text: reply},
-				},
-			},
-		}); err != nil {
-			log.Printf("send text error: %v", err)
-			continue
-		}
+	reply := echoReply(req.Content)
+	if err := req.SendText(reply); err != nil {
+		log.Printf("send text error: %v", err)
+		return
+	}
 
-		// Small delay to simulate streaming
-		time.Sleep(50 * time.Millisecond)
+	// Small delay to simulate streaming
+	time.Sleep(50 * time.Millisecond)
 
-		// Send Done
-		if err := stream.Send(&pb.AgentMessage{
-			Payload: &pb.AgentMessage_Response{
-				Response: &pb.MessageResponse{
-					RequestId: sm.RequestId,
-					Event:     &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: reply}},
-				},
-			},
-		}); err != nil {
-			log.Printf("send done error: %v", err)
-		}
+	if err := req.SendDone(reply); err != nil {
+		log.Printf("send done error: %v", err)
 	}
 }
 
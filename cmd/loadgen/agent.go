@@ -0,0 +1,169 @@
+// ABOUTME: Scripted fake agents for the load generator.
+// ABOUTME: Each agent connects over gRPC and streams a configurable canned response per request.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// agentScript describes the response every scripted agent produces.
+type agentScript struct {
+	FirstTokenDelay time.Duration // delay before the first text chunk
+	Chunks          int           // number of text chunks per response
+	ChunkSize       int           // bytes per text chunk
+	ChunkInterval   time.Duration // delay between chunks
+	ToolCalls       int           // tool_use/tool_result pairs emitted before the text
+}
+
+// scriptedAgent is a fake agent that answers every SendMessage with agentScript.
+type scriptedAgent struct {
+	id     string
+	script agentScript
+
+	stream  pb.CovenControl_AgentStreamClient
+	sendMu  sync.Mutex // gRPC client streams are not safe for concurrent Send
+	wg      sync.WaitGroup
+	chunk   string
+	counter *counters
+}
+
+// connectAgent dials the gateway, registers the agent, and waits for the welcome.
+func connectAgent(ctx context.Context, addr, id string, script agentScript, c *counters) (*scriptedAgent, *grpc.ClientConn, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("dialing gateway: %w", err)
+	}
+
+	stream, err := pb.NewCovenControlClient(conn).AgentStream(ctx)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("opening agent stream: %w", err)
+	}
+
+	if err := stream.Send(&pb.AgentMessage{
+		Payload: &pb.AgentMessage_Register{
+			Register: &pb.RegisterAgent{
+				AgentId:      id,
+				Name:         "Load Agent " + id,
+				Capabilities: []string{"chat"},
+				Metadata: &pb.AgentMetadata{
+					WorkingDirectory: "/tmp/loadgen/" + id,
+					Hostname:         "loadgen",
+					Os:               "test",
+					Backend:          "direct",
+				},
+			},
+		},
+	}); err != nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("registering agent: %w", err)
+	}
+
+	msg, err := stream.Recv()
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("waiting for welcome: %w", err)
+	}
+	if msg.GetWelcome() == nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("expected welcome, got %T", msg.GetPayload())
+	}
+
+	size := max(script.ChunkSize, 1)
+	return &scriptedAgent{
+		id:      id,
+		script:  script,
+		stream:  stream,
+		chunk:   strings.Repeat("x", size),
+		counter: c,
+	}, conn, nil
+}
+
+// serve receives requests until the stream ends, answering each in its own goroutine.
+func (a *scriptedAgent) serve(ctx context.Context) error {
+	defer a.wg.Wait()
+	for {
+		msg, err := a.stream.Recv()
+		if errors.Is(err, io.EOF) || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("agent %s recv: %w", a.id, err)
+		}
+
+		sm := msg.GetSendMessage()
+		if sm == nil {
+			continue
+		}
+
+		a.wg.Add(1)
+		go func(requestID string) {
+			defer a.wg.Done()
+			a.respond(ctx, requestID)
+		}(sm.GetRequestId())
+	}
+}
+
+// respond plays the script for a single request.
+func (a *scriptedAgent) respond(ctx context.Context, requestID string) {
+	if !sleepCtx(ctx, a.script.FirstTokenDelay) {
+		return
+	}
+
+	for i := range a.script.ToolCalls {
+		toolID := fmt.Sprintf("%s-tool-%d", requestID, i)
+		a.send(requestID, &pb.MessageResponse{Event: &pb.MessageResponse_ToolUse{ToolUse: &pb.ToolUse{Id: toolID, Name: "loadgen_tool", InputJson: `{"n":1}`}}})
+		a.send(requestID, &pb.MessageResponse{Event: &pb.MessageResponse_ToolResult{ToolResult: &pb.ToolResult{Id: toolID, Output: "ok"}}})
+	}
+
+	var full strings.Builder
+	for i := range a.script.Chunks {
+		if i > 0 && !sleepCtx(ctx, a.script.ChunkInterval) {
+			return
+		}
+		a.send(requestID, &pb.MessageResponse{Event: &pb.MessageResponse_Text{Text: a.chunk}})
+		full.WriteString(a.chunk)
+	}
+
+	a.send(requestID, &pb.MessageResponse{Event: &pb.MessageResponse_Usage{Usage: &pb.TokenUsage{InputTokens: 10, OutputTokens: int32(a.script.Chunks)}}})
+	a.send(requestID, &pb.MessageResponse{Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: full.String()}}})
+}
+
+// send writes a single response event to the gateway.
+func (a *scriptedAgent) send(requestID string, resp *pb.MessageResponse) {
+	resp.RequestId = requestID
+
+	a.sendMu.Lock()
+	err := a.stream.Send(&pb.AgentMessage{Payload: &pb.AgentMessage_Response{Response: resp}})
+	a.sendMu.Unlock()
+	if err != nil {
+		a.counter.agentSendErrors.Add(1)
+	}
+}
+
+// sleepCtx sleeps for d, returning false if ctx is canceled first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
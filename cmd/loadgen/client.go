@@ -0,0 +1,103 @@
+// ABOUTME: Simulated HTTP clients for the load generator.
+// ABOUTME: Each client posts to /api/v1/send and consumes the SSE stream, timing every request.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/client"
+)
+
+// requestResult captures the timings of a single /api/v1/send round trip.
+type requestResult struct {
+	FirstToken time.Duration // time from POST to the first text event
+	Total      time.Duration // time from POST to the done event
+	Events     int           // SSE events received
+	Err        error
+}
+
+// simClient sends messages to one agent sequentially over HTTP.
+type simClient struct {
+	id      int
+	baseURL string
+	agentID string
+	http    *http.Client
+}
+
+// run performs n sequential requests, delivering each result to out.
+func (c *simClient) run(ctx context.Context, n int, out chan<- requestResult) {
+	for i := range n {
+		if ctx.Err() != nil {
+			return
+		}
+		out <- c.send(ctx, fmt.Sprintf("load message %d from client %d", i, c.id))
+	}
+}
+
+// send posts one message and reads the SSE stream until done, error, or EOF.
+func (c *simClient) send(ctx context.Context, content string) requestResult {
+	body, err := json.Marshal(map[string]string{
+		"agent_id": c.agentID,
+		"sender":   fmt.Sprintf("loadgen-%d", c.id),
+		"content":  content,
+	})
+	if err != nil {
+		return requestResult{Err: err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+client.APIPrefix+"/send", bytes.NewReader(body))
+	if err != nil {
+		return requestResult{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return requestResult{Err: err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return requestResult{Err: fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))}
+	}
+
+	var res requestResult
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		event, ok := strings.CutPrefix(scanner.Text(), "event: ")
+		if !ok {
+			continue
+		}
+		res.Events++
+		switch event {
+		case "text":
+			if res.FirstToken == 0 {
+				res.FirstToken = time.Since(start)
+			}
+		case "done":
+			res.Total = time.Since(start)
+			return res
+		case "error":
+			res.Err = fmt.Errorf("error event after %d events", res.Events)
+			return res
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		res.Err = err
+		return res
+	}
+	res.Err = fmt.Errorf("stream ended without done after %d events", res.Events)
+	return res
+}
@@ -0,0 +1,352 @@
+// ABOUTME: Load generator that drives concurrent SSE clients against an in-process gateway.
+// ABOUTME: Usage: loadgen [-clients 200] [-agents 10] [-requests 5] [-json report.json]
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "expvar" // registers /debug/vars on http.DefaultServeMux
+
+	_ "modernc.org/sqlite"
+
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/gateway"
+)
+
+// options are the command-line settings for a run.
+type options struct {
+	clients    int
+	agents     int
+	requests   int
+	script     agentScript
+	jsonPath   string
+	expvarAddr string
+	maxP99     time.Duration
+	verbose    bool
+}
+
+func main() {
+	var opts options
+	flag.IntVar(&opts.clients, "clients", 50, "Concurrent simulated clients")
+	flag.IntVar(&opts.agents, "agents", 5, "Scripted fake agents (clients are spread round-robin)")
+	flag.IntVar(&opts.requests, "requests", 5, "Sequential requests per client")
+	flag.DurationVar(&opts.script.FirstTokenDelay, "first-token-delay", 20*time.Millisecond, "Agent delay before the first text chunk")
+	flag.IntVar(&opts.script.Chunks, "chunks", 10, "Text chunks per response")
+	flag.IntVar(&opts.script.ChunkSize, "chunk-size", 64, "Bytes per text chunk")
+	flag.DurationVar(&opts.script.ChunkInterval, "chunk-interval", 10*time.Millisecond, "Delay between text chunks")
+	flag.IntVar(&opts.script.ToolCalls, "tool-calls", 1, "Tool use/result pairs per response")
+	flag.StringVar(&opts.jsonPath, "json", "", "Write the JSON report to this path (\"-\" for stdout)")
+	flag.StringVar(&opts.expvarAddr, "expvar-addr", "", "Serve live expvar counters at this address (e.g. localhost:6060)")
+	flag.DurationVar(&opts.maxP99, "max-p99-overhead", 0, "Exit non-zero if p99 first-token overhead exceeds this budget")
+	flag.BoolVar(&opts.verbose, "v", false, "Show gateway logs")
+	flag.Parse()
+
+	if opts.clients < 1 || opts.agents < 1 || opts.requests < 1 {
+		log.Fatal("clients, agents and requests must be at least 1")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	report, err := run(ctx, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report.writeText(os.Stderr)
+	if err := writeReport(opts.jsonPath, report); err != nil {
+		log.Fatal(err)
+	}
+
+	overhead := time.Duration(report.FirstTokenAdded.P99 * float64(time.Millisecond))
+	if opts.maxP99 > 0 && overhead > opts.maxP99 {
+		log.Fatalf("p99 first-token overhead %s exceeds budget %s", overhead, opts.maxP99)
+	}
+	if report.RequestsFailed > 0 {
+		os.Exit(1)
+	}
+}
+
+// run starts the gateway and agents, drives the clients, and builds the report.
+func run(ctx context.Context, opts options) (*Report, error) {
+	dir, err := os.MkdirTemp("", "coven-loadgen-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	dbPath := filepath.Join(dir, "gateway.db")
+
+	grpcAddr, err := freeAddr()
+	if err != nil {
+		return nil, err
+	}
+	httpAddr, err := freeAddr()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config.Config{
+		Server:   config.ServerConfig{GRPCAddr: grpcAddr, HTTPAddr: httpAddr},
+		Database: config.DatabaseConfig{Path: dbPath},
+	}
+
+	// The store logs through slog.Default, so quiet it along with the gateway.
+	logger := newLogger(opts.verbose)
+	slog.SetDefault(logger)
+
+	gw, err := gateway.New(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("creating gateway: %w", err)
+	}
+
+	gwCtx, stopGateway := context.WithCancel(ctx)
+	gwDone := make(chan error, 1)
+	go func() { gwDone <- gw.Run(gwCtx) }()
+	defer func() {
+		stopGateway()
+		<-gwDone
+	}()
+
+	baseURL := "http://" + httpAddr
+	if err := waitForHTTP(ctx, baseURL+"/health"); err != nil {
+		return nil, err
+	}
+
+	c := &counters{}
+	c.publish()
+	if opts.expvarAddr != "" {
+		go func() {
+			srv := &http.Server{Addr: opts.expvarAddr, ReadHeaderTimeout: 10 * time.Second}
+			if err := srv.ListenAndServe(); err != nil {
+				log.Printf("expvar server: %v", err)
+			}
+		}()
+	}
+
+	agentIDs, stopAgents, err := startAgents(ctx, grpcAddr, opts, c)
+	if err != nil {
+		return nil, err
+	}
+	defer stopAgents()
+
+	ledgerBefore, usageBefore, err := countWrites(ctx, dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sampler := &runtimeSampler{}
+	stopSampler := make(chan struct{})
+	go sampler.run(100*time.Millisecond, stopSampler)
+
+	start := time.Now()
+	results := driveClients(ctx, baseURL, agentIDs, opts, c)
+	elapsed := time.Since(start)
+	close(stopSampler)
+
+	// Give the persistence goroutines a moment to flush final writes.
+	time.Sleep(200 * time.Millisecond)
+	ledgerAfter, usageAfter, err := countWrites(ctx, dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	report := buildReport(opts, results, elapsed)
+	report.SSEEvents = c.sseEvents.Load()
+	report.SSEEventsPerSec = float64(report.SSEEvents) / elapsed.Seconds()
+	report.LedgerWrites = ledgerAfter - ledgerBefore
+	report.LedgerWritesPerS = float64(report.LedgerWrites) / elapsed.Seconds()
+	report.UsageWrites = usageAfter - usageBefore
+	report.MaxGoroutines = sampler.maxGoroutines
+	report.MaxHeapBytes = sampler.maxHeapBytes
+	report.AgentSendErrors = c.agentSendErrors.Load()
+	return report, nil
+}
+
+// startAgents connects the scripted agents and returns their IDs and a stop function.
+func startAgents(ctx context.Context, grpcAddr string, opts options, c *counters) ([]string, func(), error) {
+	agentCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	var closers []io.Closer
+	stop := func() {
+		cancel()
+		for _, cl := range closers {
+			_ = cl.Close()
+		}
+		wg.Wait()
+	}
+
+	ids := make([]string, opts.agents)
+	for i := range opts.agents {
+		ids[i] = fmt.Sprintf("loadgen-agent-%d", i)
+		a, conn, err := connectAgent(agentCtx, grpcAddr, ids[i], opts.script, c)
+		if err != nil {
+			stop()
+			return nil, nil, fmt.Errorf("starting agent %s: %w", ids[i], err)
+		}
+		closers = append(closers, conn)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := a.serve(agentCtx); err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+	return ids, stop, nil
+}
+
+// driveClients runs all simulated clients to completion and returns every result.
+func driveClients(ctx context.Context, baseURL string, agentIDs []string, opts options, c *counters) []requestResult {
+	httpClient := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: opts.clients}}
+	out := make(chan requestResult, opts.clients)
+
+	var wg sync.WaitGroup
+	for i := range opts.clients {
+		sc := &simClient{id: i, baseURL: baseURL, agentID: agentIDs[i%len(agentIDs)], http: httpClient}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sc.run(ctx, opts.requests, out)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	results := make([]requestResult, 0, opts.clients*opts.requests)
+	for res := range out {
+		c.sseEvents.Add(int64(res.Events))
+		if res.Err != nil {
+			c.requestsFailed.Add(1)
+		} else {
+			c.requestsOK.Add(1)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// buildReport aggregates per-request results into a Report.
+func buildReport(opts options, results []requestResult, elapsed time.Duration) *Report {
+	r := &Report{
+		Clients:           opts.clients,
+		Agents:            opts.agents,
+		RequestsPerClient: opts.requests,
+		DurationSeconds:   elapsed.Seconds(),
+	}
+
+	var firstToken, total []time.Duration
+	for _, res := range results {
+		if res.Err != nil {
+			r.RequestsFailed++
+			if len(r.FirstErrors) < 5 {
+				r.FirstErrors = append(r.FirstErrors, res.Err.Error())
+			}
+			continue
+		}
+		r.RequestsOK++
+		if res.FirstToken > 0 {
+			firstToken = append(firstToken, res.FirstToken)
+		}
+		total = append(total, res.Total)
+	}
+
+	// The agent itself spends FirstTokenDelay plus any tool calls before the
+	// first text chunk; everything beyond that is attributed to the gateway.
+	r.FirstToken = summarize(firstToken, 0)
+	r.FirstTokenAdded = summarize(firstToken, opts.script.FirstTokenDelay)
+	r.Total = summarize(total, 0)
+	return r
+}
+
+// countWrites returns the current row counts of the ledger and usage tables.
+func countWrites(ctx context.Context, dbPath string) (ledger, usage int64, err error) {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("opening database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM ledger_events`).Scan(&ledger); err != nil {
+		return 0, 0, fmt.Errorf("counting ledger events: %w", err)
+	}
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM message_usage`).Scan(&usage); err != nil {
+		return 0, 0, fmt.Errorf("counting usage records: %w", err)
+	}
+	return ledger, usage, nil
+}
+
+// writeReport writes the JSON report to path; "-" means stdout, "" disables it.
+func writeReport(path string, r *Report) error {
+	switch path {
+	case "":
+		return nil
+	case "-":
+		return r.writeJSON(os.Stdout)
+	}
+	f, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("creating report file: %w", err)
+	}
+	if err := r.writeJSON(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("writing report: %w", err)
+	}
+	return f.Close()
+}
+
+// freeAddr returns a loopback address with a currently unused port.
+func freeAddr() (string, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("finding free port: %w", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	return addr, nil
+}
+
+// waitForHTTP polls url until it answers 200 or ctx ends.
+func waitForHTTP(ctx context.Context, url string) error {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		if !sleepCtx(ctx, 50*time.Millisecond) {
+			return ctx.Err()
+		}
+	}
+	return errors.New("gateway did not become healthy within 10s")
+}
+
+// newLogger returns a gateway logger; quiet unless verbose is set.
+func newLogger(verbose bool) *slog.Logger {
+	if verbose {
+		return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	}
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
@@ -0,0 +1,156 @@
+// ABOUTME: Metrics collection and reporting for the load generator.
+// ABOUTME: Computes latency percentiles, throughput, and runtime stats; prints text or JSON.
+
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// counters are live values published via expvar while the run is in progress.
+type counters struct {
+	requestsOK      atomic.Int64
+	requestsFailed  atomic.Int64
+	sseEvents       atomic.Int64
+	agentSendErrors atomic.Int64
+}
+
+// publish exposes the counters under the "loadgen" expvar map.
+func (c *counters) publish() {
+	m := expvar.NewMap("loadgen")
+	m.Set("requests_ok", expvar.Func(func() any { return c.requestsOK.Load() }))
+	m.Set("requests_failed", expvar.Func(func() any { return c.requestsFailed.Load() }))
+	m.Set("sse_events", expvar.Func(func() any { return c.sseEvents.Load() }))
+	m.Set("agent_send_errors", expvar.Func(func() any { return c.agentSendErrors.Load() }))
+	m.Set("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+}
+
+// runtimeSampler records peak goroutine count and heap size during the run.
+type runtimeSampler struct {
+	mu            sync.Mutex
+	maxGoroutines int
+	maxHeapBytes  uint64
+}
+
+// sample takes a single reading.
+func (s *runtimeSampler) sample() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	g := runtime.NumGoroutine()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxGoroutines = max(s.maxGoroutines, g)
+	s.maxHeapBytes = max(s.maxHeapBytes, ms.HeapAlloc)
+}
+
+// run samples at the given interval until stop is closed.
+func (s *runtimeSampler) run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.sample()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// LatencySummary holds percentile latencies in milliseconds.
+type LatencySummary struct {
+	P50 float64 `json:"p50_ms"`
+	P90 float64 `json:"p90_ms"`
+	P99 float64 `json:"p99_ms"`
+	Max float64 `json:"max_ms"`
+}
+
+// Report is the machine-readable result of a load run.
+type Report struct {
+	Clients           int            `json:"clients"`
+	Agents            int            `json:"agents"`
+	RequestsPerClient int            `json:"requests_per_client"`
+	DurationSeconds   float64        `json:"duration_seconds"`
+	RequestsOK        int64          `json:"requests_ok"`
+	RequestsFailed    int64          `json:"requests_failed"`
+	FirstErrors       []string       `json:"first_errors,omitempty"`
+	FirstToken        LatencySummary `json:"first_token"`
+	FirstTokenAdded   LatencySummary `json:"first_token_overhead"`
+	Total             LatencySummary `json:"total"`
+	SSEEvents         int64          `json:"sse_events"`
+	SSEEventsPerSec   float64        `json:"sse_events_per_second"`
+	LedgerWrites      int64          `json:"ledger_writes"`
+	LedgerWritesPerS  float64        `json:"ledger_writes_per_second"`
+	UsageWrites       int64          `json:"usage_writes"`
+	MaxGoroutines     int            `json:"max_goroutines"`
+	MaxHeapBytes      uint64         `json:"max_heap_bytes"`
+	AgentSendErrors   int64          `json:"agent_send_errors"`
+}
+
+// summarize computes percentile latencies; durations are shifted by offset first.
+func summarize(samples []time.Duration, offset time.Duration) LatencySummary {
+	if len(samples) == 0 {
+		return LatencySummary{}
+	}
+	sorted := make([]time.Duration, len(samples))
+	for i, d := range samples {
+		sorted[i] = max(d-offset, 0)
+	}
+	slices.Sort(sorted)
+	return LatencySummary{
+		P50: ms(percentile(sorted, 0.50)),
+		P90: ms(percentile(sorted, 0.90)),
+		P99: ms(percentile(sorted, 0.99)),
+		Max: ms(sorted[len(sorted)-1]),
+	}
+}
+
+// percentile returns the nearest-rank percentile of a sorted slice.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(float64(len(sorted))*p+0.5) - 1
+	idx = min(max(idx, 0), len(sorted)-1)
+	return sorted[idx]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// writeText prints a human-readable summary.
+func (r *Report) writeText(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "clients=%d agents=%d requests/client=%d duration=%.2fs\n",
+		r.Clients, r.Agents, r.RequestsPerClient, r.DurationSeconds)
+	_, _ = fmt.Fprintf(w, "requests: ok=%d failed=%d\n", r.RequestsOK, r.RequestsFailed)
+	for _, e := range r.FirstErrors {
+		_, _ = fmt.Fprintf(w, "  error: %s\n", e)
+	}
+	printLatency(w, "first token", r.FirstToken)
+	printLatency(w, "first token (gateway overhead)", r.FirstTokenAdded)
+	printLatency(w, "total", r.Total)
+	_, _ = fmt.Fprintf(w, "sse events: %d (%.0f/s)\n", r.SSEEvents, r.SSEEventsPerSec)
+	_, _ = fmt.Fprintf(w, "store writes: ledger=%d (%.0f/s) usage=%d\n", r.LedgerWrites, r.LedgerWritesPerS, r.UsageWrites)
+	_, _ = fmt.Fprintf(w, "runtime: max goroutines=%d max heap=%.1fMiB\n", r.MaxGoroutines, float64(r.MaxHeapBytes)/(1<<20))
+	if r.AgentSendErrors > 0 {
+		_, _ = fmt.Fprintf(w, "agent send errors: %d\n", r.AgentSendErrors)
+	}
+}
+
+func printLatency(w io.Writer, label string, s LatencySummary) {
+	_, _ = fmt.Fprintf(w, "%s: p50=%.1fms p90=%.1fms p99=%.1fms max=%.1fms\n", label, s.P50, s.P90, s.P99, s.Max)
+}
+
+// writeJSON writes the report as indented JSON.
+func (r *Report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,39 @@
+// ABOUTME: Builds audit entries for AdminService mutations from the gRPC caller
+// ABOUTME: Records the caller's principal and peer address with each entry
+
+package admin
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/peer"
+
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// auditEntry returns an audit entry for an action by the authenticated caller.
+func auditEntry(ctx context.Context, action store.AuditAction, targetType, targetID string, detail map[string]any) *store.AuditEntry {
+	return &store.AuditEntry{
+		ActorPrincipalID: auth.MustFromContext(ctx).PrincipalID,
+		Action:           action,
+		TargetType:       targetType,
+		TargetID:         targetID,
+		Detail:           detail,
+		SourceIP:         peerIP(ctx),
+	}
+}
+
+// peerIP returns the host part of the gRPC peer address, if known.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
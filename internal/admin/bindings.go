@@ -6,6 +6,7 @@ package admin
 import (
 	"context"
 	"errors"
+	"fmt"
 	"regexp"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 
 	"github.com/2389/coven-gateway/internal/auth"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
@@ -29,15 +31,43 @@ type BindingStore interface {
 	CreateBindingV2(ctx context.Context, b *store.Binding) error
 	GetBindingByID(ctx context.Context, id string) (*store.Binding, error)
 	UpdateBinding(ctx context.Context, id, agentID string) error
+	SetBindingMaxResponseDuration(ctx context.Context, id string, d time.Duration) error
+	SetBindingContext(ctx context.Context, id, text string) error
 	DeleteBindingByID(ctx context.Context, id string) error
 	ListBindingsV2(ctx context.Context, f store.BindingFilter) ([]store.Binding, error)
 	AppendAuditLog(ctx context.Context, e *store.AuditEntry) error
 }
 
+// Agent statuses reported with listed bindings.
+const (
+	AgentStatusOnline = "online"
+	// AgentStatusGrace means the agent is disconnected but can still resume
+	// its session within the reconnect grace period.
+	AgentStatusGrace   = "grace"
+	AgentStatusOffline = "offline"
+)
+
+// DeletedAgentName stands in for the name of a bound agent whose principal
+// no longer exists.
+const DeletedAgentName = "(deleted agent)"
+
+// BoundAgent describes the agent behind a binding in listings.
+type BoundAgent struct {
+	Name   string // display name, DeletedAgentName if the principal is gone
+	Status string // AgentStatusOnline, AgentStatusGrace, or AgentStatusOffline
+	// EffectiveID is the principal the binding's messages go to now: the
+	// bound agent, one of its fallbacks, or empty when none would take them.
+	EffectiveID string
+}
+
+// AgentLookup reports the agent behind a binding.
+type AgentLookup func(ctx context.Context, b *store.Binding) BoundAgent
+
 // AdminService implements the AdminService gRPC service.
 type AdminService struct {
 	pb.UnimplementedAdminServiceServer
-	store BindingStore
+	store       BindingStore
+	agentLookup AgentLookup
 }
 
 // NewAdminService creates a new AdminService with the given store.
@@ -45,6 +75,11 @@ func NewAdminService(s BindingStore) *AdminService {
 	return &AdminService{store: s}
 }
 
+// SetAgentLookup makes ListBindings report each binding's agent name and status.
+func (s *AdminService) SetAgentLookup(fn AgentLookup) {
+	s.agentLookup = fn
+}
+
 // CreateBinding creates a new channel-to-agent binding.
 func (s *AdminService) CreateBinding(ctx context.Context, req *pb.CreateBindingRequest) (*pb.Binding, error) {
 	authCtx := auth.MustFromContext(ctx)
@@ -62,18 +97,42 @@ func (s *AdminService) CreateBinding(ctx context.Context, req *pb.CreateBindingR
 	if req.AgentId == "" {
 		return nil, status.Error(codes.InvalidArgument, "agent_id required")
 	}
+	if req.GetMaxResponseSeconds() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "max_response_seconds must not be negative")
+	}
+	if err := validateFallbacks(req.AgentId, req.FallbackAgentIds); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if len(req.Context) > store.MaxBindingContextBytes {
+		return nil, status.Error(codes.InvalidArgument, store.ErrBindingContextTooLong.Error())
+	}
 
 	// Create binding
 	b := &store.Binding{
-		ID:        uuid.New().String(),
-		Frontend:  req.Frontend,
-		ChannelID: req.ChannelId,
-		AgentID:   req.AgentId,
-		CreatedAt: time.Now().UTC(),
-		CreatedBy: &authCtx.PrincipalID,
+		ID:                  uuid.New().String(),
+		Frontend:            req.Frontend,
+		ChannelID:           req.ChannelId,
+		AgentID:             req.AgentId,
+		CreatedAt:           time.Now().UTC(),
+		CreatedBy:           &authCtx.PrincipalID,
+		MaxResponseDuration: time.Duration(req.GetMaxResponseSeconds()) * time.Second,
+		FallbackAgentIDs:    req.FallbackAgentIds,
+		Context:             req.Context,
 	}
 
-	if err := s.store.CreateBindingV2(ctx, b); err != nil {
+	detail := map[string]any{
+		"frontend":   b.Frontend,
+		"channel_id": b.ChannelID,
+		"agent_id":   b.AgentID,
+	}
+	if len(b.FallbackAgentIDs) > 0 {
+		detail["fallback_agent_ids"] = b.FallbackAgentIDs
+	}
+	if b.Context != "" {
+		detail["context_hash"] = store.BindingContextHash(b.Context)
+	}
+	auditCtx := store.WithAuditEntry(ctx, auditEntry(ctx, store.AuditCreateBinding, "binding", b.ID, detail))
+	if err := s.store.CreateBindingV2(auditCtx, b); err != nil {
 		if errors.Is(err, store.ErrDuplicateChannel) {
 			return nil, status.Error(codes.AlreadyExists, "channel already bound")
 		}
@@ -83,26 +142,12 @@ func (s *AdminService) CreateBinding(ctx context.Context, req *pb.CreateBindingR
 		return nil, status.Error(codes.Internal, "failed to create binding")
 	}
 
-	// Audit log
-	_ = s.store.AppendAuditLog(ctx, &store.AuditEntry{
-		ActorPrincipalID: authCtx.PrincipalID,
-		Action:           store.AuditCreateBinding,
-		TargetType:       "binding",
-		TargetID:         b.ID,
-		Detail: map[string]any{
-			"frontend":   b.Frontend,
-			"channel_id": b.ChannelID,
-			"agent_id":   b.AgentID,
-		},
-	})
-
 	return toProtoBinding(b), nil
 }
 
-// UpdateBinding updates a binding's agent_id.
+// UpdateBinding updates a binding's agent_id and, if given, its response
+// limit and channel context.
 func (s *AdminService) UpdateBinding(ctx context.Context, req *pb.UpdateBindingRequest) (*pb.Binding, error) {
-	authCtx := auth.MustFromContext(ctx)
-
 	// Validate request
 	if req.Id == "" {
 		return nil, status.Error(codes.InvalidArgument, "id required")
@@ -110,9 +155,25 @@ func (s *AdminService) UpdateBinding(ctx context.Context, req *pb.UpdateBindingR
 	if req.AgentId == "" {
 		return nil, status.Error(codes.InvalidArgument, "agent_id required")
 	}
+	if req.GetMaxResponseSeconds() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "max_response_seconds must not be negative")
+	}
+	if len(req.GetContext()) > store.MaxBindingContextBytes {
+		return nil, status.Error(codes.InvalidArgument, store.ErrBindingContextTooLong.Error())
+	}
 
-	// Update binding
-	if err := s.store.UpdateBinding(ctx, req.Id, req.AgentId); err != nil {
+	// Update binding, auditing it in the same transaction
+	detail := map[string]any{
+		"agent_id": req.AgentId,
+	}
+	if req.MaxResponseSeconds != nil {
+		detail["max_response_seconds"] = req.GetMaxResponseSeconds()
+	}
+	if req.Context != nil {
+		detail["context_hash"] = store.BindingContextHash(req.GetContext())
+	}
+	auditCtx := store.WithAuditEntry(ctx, auditEntry(ctx, store.AuditUpdateBinding, "binding", req.Id, detail))
+	if err := s.store.UpdateBinding(auditCtx, req.Id, req.AgentId); err != nil {
 		if errors.Is(err, store.ErrBindingNotFound) {
 			return nil, status.Error(codes.NotFound, "binding not found")
 		}
@@ -121,6 +182,17 @@ func (s *AdminService) UpdateBinding(ctx context.Context, req *pb.UpdateBindingR
 		}
 		return nil, status.Error(codes.Internal, "failed to update binding")
 	}
+	if req.MaxResponseSeconds != nil {
+		limit := time.Duration(req.GetMaxResponseSeconds()) * time.Second
+		if err := s.store.SetBindingMaxResponseDuration(ctx, req.Id, limit); err != nil {
+			return nil, status.Error(codes.Internal, "failed to update binding")
+		}
+	}
+	if req.Context != nil {
+		if err := s.store.SetBindingContext(ctx, req.Id, req.GetContext()); err != nil {
+			return nil, status.Error(codes.Internal, "failed to update binding")
+		}
+	}
 
 	// Get updated binding
 	b, err := s.store.GetBindingByID(ctx, req.Id)
@@ -128,24 +200,11 @@ func (s *AdminService) UpdateBinding(ctx context.Context, req *pb.UpdateBindingR
 		return nil, status.Error(codes.Internal, "failed to retrieve updated binding")
 	}
 
-	// Audit log
-	_ = s.store.AppendAuditLog(ctx, &store.AuditEntry{
-		ActorPrincipalID: authCtx.PrincipalID,
-		Action:           store.AuditUpdateBinding,
-		TargetType:       "binding",
-		TargetID:         b.ID,
-		Detail: map[string]any{
-			"agent_id": req.AgentId,
-		},
-	})
-
 	return toProtoBinding(b), nil
 }
 
 // DeleteBinding removes a binding by ID.
 func (s *AdminService) DeleteBinding(ctx context.Context, req *pb.DeleteBindingRequest) (*pb.DeleteBindingResponse, error) {
-	authCtx := auth.MustFromContext(ctx)
-
 	// Validate request
 	if req.Id == "" {
 		return nil, status.Error(codes.InvalidArgument, "id required")
@@ -160,27 +219,19 @@ func (s *AdminService) DeleteBinding(ctx context.Context, req *pb.DeleteBindingR
 		return nil, status.Error(codes.Internal, "failed to get binding")
 	}
 
-	// Delete binding
-	if err := s.store.DeleteBindingByID(ctx, req.Id); err != nil {
+	// Delete binding, auditing it in the same transaction
+	auditCtx := store.WithAuditEntry(ctx, auditEntry(ctx, store.AuditDeleteBinding, "binding", req.Id, map[string]any{
+		"frontend":   b.Frontend,
+		"channel_id": b.ChannelID,
+		"agent_id":   b.AgentID,
+	}))
+	if err := s.store.DeleteBindingByID(auditCtx, req.Id); err != nil {
 		if errors.Is(err, store.ErrBindingNotFound) {
 			return nil, status.Error(codes.NotFound, "binding not found")
 		}
 		return nil, status.Error(codes.Internal, "failed to delete binding")
 	}
 
-	// Audit log
-	_ = s.store.AppendAuditLog(ctx, &store.AuditEntry{
-		ActorPrincipalID: authCtx.PrincipalID,
-		Action:           store.AuditDeleteBinding,
-		TargetType:       "binding",
-		TargetID:         req.Id,
-		Detail: map[string]any{
-			"frontend":   b.Frontend,
-			"channel_id": b.ChannelID,
-			"agent_id":   b.AgentID,
-		},
-	})
-
 	return &pb.DeleteBindingResponse{}, nil
 }
 
@@ -203,6 +254,12 @@ func (s *AdminService) ListBindings(ctx context.Context, req *pb.ListBindingsReq
 	pbBindings := make([]*pb.Binding, len(bindings))
 	for i := range bindings {
 		pbBindings[i] = toProtoBinding(&bindings[i])
+		if s.agentLookup != nil {
+			bound := s.agentLookup(ctx, &bindings[i])
+			pbBindings[i].AgentName = bound.Name
+			pbBindings[i].AgentStatus = bound.Status
+			pbBindings[i].EffectiveAgentId = bound.EffectiveID
+		}
 	}
 
 	return &pb.ListBindingsResponse{Bindings: pbBindings}, nil
@@ -219,14 +276,37 @@ func validateFrontend(frontend string) error {
 	return nil
 }
 
+// validateFallbacks checks that fallback agents are named once each and do
+// not include the bound agent itself.
+func validateFallbacks(agentID string, fallbacks []string) error {
+	seen := map[string]bool{agentID: true}
+	for _, id := range fallbacks {
+		if id == "" {
+			return errors.New("fallback agent IDs must not be empty")
+		}
+		if seen[id] {
+			return fmt.Errorf("fallback agent %q is listed twice or is the bound agent", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
 // toProtoBinding converts a store.Binding to a protobuf Binding.
 func toProtoBinding(b *store.Binding) *pb.Binding {
-	return &pb.Binding{
-		Id:        b.ID,
-		Frontend:  b.Frontend,
-		ChannelId: b.ChannelID,
-		AgentId:   b.AgentID,
-		CreatedAt: b.CreatedAt.Format(time.RFC3339),
-		CreatedBy: b.CreatedBy,
+	pbBinding := &pb.Binding{
+		Id:               b.ID,
+		Frontend:         b.Frontend,
+		ChannelId:        b.ChannelID,
+		AgentId:          b.AgentID,
+		CreatedAt:        timeparse.Format(b.CreatedAt),
+		CreatedBy:        b.CreatedBy,
+		FallbackAgentIds: b.FallbackAgentIDs,
+		Context:          b.Context,
+	}
+	if b.MaxResponseDuration > 0 {
+		seconds := int32(b.MaxResponseDuration / time.Second)
+		pbBinding.MaxResponseSeconds = &seconds
 	}
+	return pbBinding
 }
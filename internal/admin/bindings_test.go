@@ -21,7 +21,7 @@ import (
 )
 
 // createTestStore creates a real SQLite store in a temp directory.
-func createTestStore(t *testing.T) *store.SQLiteStore {
+func createTestStore(t *testing.T) *store.SQLStore {
 	t.Helper()
 
 	tmpDir := t.TempDir()
@@ -41,13 +41,13 @@ func createTestStore(t *testing.T) *store.SQLiteStore {
 }
 
 // createAdminService creates an AdminService with the given store.
-func createAdminService(t *testing.T, s *store.SQLiteStore) *AdminService {
+func createAdminService(t *testing.T, s *store.SQLStore) *AdminService {
 	t.Helper()
 	return NewAdminService(s)
 }
 
 // createTestAgent creates a principal with type="agent" for binding tests.
-func createTestAgent(t *testing.T, s *store.SQLiteStore, id string) {
+func createTestAgent(t *testing.T, s *store.SQLStore, id string) {
 	t.Helper()
 	// Create a unique 64-char hex fingerprint from the full ID
 	fp := id
@@ -104,6 +104,35 @@ func TestCreateBinding_Success(t *testing.T) {
 	assert.Equal(t, "admin-001", *binding.CreatedBy)
 }
 
+func TestCreateBinding_FallbackAgents(t *testing.T) {
+	s := createTestStore(t)
+	svc := createAdminService(t, s)
+	ctx := createAdminContext("admin-001")
+
+	createTestAgent(t, s, "agent-001")
+	createTestAgent(t, s, "agent-002")
+
+	binding, err := svc.CreateBinding(ctx, &pb.CreateBindingRequest{
+		Frontend: "slack", ChannelId: "C001", AgentId: "agent-001", FallbackAgentIds: []string{"agent-002"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"agent-002"}, binding.FallbackAgentIds)
+
+	for _, fallbacks := range [][]string{{"agent-001"}, {"agent-002", "agent-002"}, {""}} {
+		_, err := svc.CreateBinding(ctx, &pb.CreateBindingRequest{
+			Frontend: "slack", ChannelId: "C002", AgentId: "agent-001", FallbackAgentIds: fallbacks,
+		})
+		st, _ := status.FromError(err)
+		assert.Equal(t, codes.InvalidArgument, st.Code(), "fallbacks %q", fallbacks)
+	}
+
+	_, err = svc.CreateBinding(ctx, &pb.CreateBindingRequest{
+		Frontend: "slack", ChannelId: "C003", AgentId: "agent-001", FallbackAgentIds: []string{"nobody"},
+	})
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.NotFound, st.Code())
+}
+
 func TestCreateBinding_MissingFrontend(t *testing.T) {
 	s := createTestStore(t)
 	svc := createAdminService(t, s)
@@ -272,6 +301,68 @@ func TestUpdateBinding_Success(t *testing.T) {
 	assert.Equal(t, "agent-002", updated.AgentId)
 }
 
+func TestUpdateBinding_MaxResponseSeconds(t *testing.T) {
+	s := createTestStore(t)
+	svc := createAdminService(t, s)
+	ctx := createAdminContext("admin-001")
+
+	createTestAgent(t, s, "agent-001")
+
+	limit := int32(600)
+	created, err := svc.CreateBinding(ctx, &pb.CreateBindingRequest{
+		Frontend:           "slack",
+		ChannelId:          "C123",
+		AgentId:            "agent-001",
+		MaxResponseSeconds: &limit,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(600), created.GetMaxResponseSeconds())
+
+	clear := int32(0)
+	updated, err := svc.UpdateBinding(ctx, &pb.UpdateBindingRequest{Id: created.Id, AgentId: "agent-001", MaxResponseSeconds: &clear})
+	require.NoError(t, err)
+	assert.Nil(t, updated.MaxResponseSeconds)
+
+	negative := int32(-1)
+	_, err = svc.UpdateBinding(ctx, &pb.UpdateBindingRequest{Id: created.Id, AgentId: "agent-001", MaxResponseSeconds: &negative})
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+}
+
+func TestUpdateBinding_Context(t *testing.T) {
+	s := createTestStore(t)
+	svc := createAdminService(t, s)
+	ctx := createAdminContext("admin-001")
+
+	createTestAgent(t, s, "agent-001")
+
+	created, err := svc.CreateBinding(ctx, &pb.CreateBindingRequest{
+		Frontend:  "slack",
+		ChannelId: "C123",
+		AgentId:   "agent-001",
+		Context:   "Answer tersely.",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Answer tersely.", created.Context)
+
+	// Leaving context unset keeps it.
+	updated, err := svc.UpdateBinding(ctx, &pb.UpdateBindingRequest{Id: created.Id, AgentId: "agent-001"})
+	require.NoError(t, err)
+	assert.Equal(t, "Answer tersely.", updated.Context)
+
+	cleared := ""
+	updated, err = svc.UpdateBinding(ctx, &pb.UpdateBindingRequest{Id: created.Id, AgentId: "agent-001", Context: &cleared})
+	require.NoError(t, err)
+	assert.Empty(t, updated.Context)
+
+	tooLong := strings.Repeat("x", store.MaxBindingContextBytes+1)
+	_, err = svc.UpdateBinding(ctx, &pb.UpdateBindingRequest{Id: created.Id, AgentId: "agent-001", Context: &tooLong})
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+}
+
 func TestUpdateBinding_NotFound(t *testing.T) {
 	s := createTestStore(t)
 	svc := createAdminService(t, s)
@@ -482,6 +573,31 @@ func TestListBindings_ByAgentID(t *testing.T) {
 	}
 }
 
+func TestListBindings_AgentLookup(t *testing.T) {
+	s := createTestStore(t)
+	svc := createAdminService(t, s)
+	ctx := createAdminContext("admin-001")
+
+	createTestAgent(t, s, "agent-001")
+	_, err := svc.CreateBinding(ctx, &pb.CreateBindingRequest{Frontend: "slack", ChannelId: "C001", AgentId: "agent-001"})
+	require.NoError(t, err)
+
+	// Without a lookup the fields stay empty.
+	resp, err := svc.ListBindings(ctx, &pb.ListBindingsRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Bindings, 1)
+	assert.Empty(t, resp.Bindings[0].AgentStatus)
+
+	svc.SetAgentLookup(func(_ context.Context, b *store.Binding) BoundAgent {
+		return BoundAgent{Name: "name-of-" + b.AgentID, Status: AgentStatusGrace}
+	})
+	resp, err = svc.ListBindings(ctx, &pb.ListBindingsRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Bindings, 1)
+	assert.Equal(t, "name-of-agent-001", resp.Bindings[0].AgentName)
+	assert.Equal(t, AgentStatusGrace, resp.Bindings[0].AgentStatus)
+}
+
 func TestCreateBinding_InvalidFrontendFormat(t *testing.T) {
 	s := createTestStore(t)
 	svc := createAdminService(t, s)
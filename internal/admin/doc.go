@@ -62,6 +62,10 @@
 //	md := metadata.Pairs("authorization", "Bearer <admin-token>")
 //	ctx := metadata.NewOutgoingContext(ctx, md)
 //
+// The auth.RequireAdmin interceptor checks roles before a handler runs:
+// ListBindings and ListPrincipals need member, admin or owner; the rest need
+// admin or owner. Agents are refused.
+//
 // # Usage
 //
 // The AdminService is typically created by the gateway:
@@ -16,6 +16,7 @@ import (
 
 	"github.com/2389/coven-gateway/internal/auth"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
@@ -84,8 +85,9 @@ func (s *PrincipalService) ListPrincipals(ctx context.Context, req *pb.ListPrinc
 			Type:        string(p.Type),
 			DisplayName: p.DisplayName,
 			Status:      string(p.Status),
-			CreatedAt:   p.CreatedAt.Format(time.RFC3339),
+			CreatedAt:   timeparse.Format(p.CreatedAt),
 			Roles:       roleStrings,
+			Tags:        p.Tags,
 		}
 		if p.PubkeyFP != "" {
 			proto.PubkeyFp = &p.PubkeyFP
@@ -157,8 +159,6 @@ func (s *PrincipalService) assignRoles(ctx context.Context, principalID string,
 
 // CreatePrincipal creates a new principal.
 func (s *PrincipalService) CreatePrincipal(ctx context.Context, req *pb.CreatePrincipalRequest) (*pb.Principal, error) {
-	authCtx := auth.MustFromContext(ctx)
-
 	if err := validateCreatePrincipalRequest(req); err != nil {
 		return nil, err
 	}
@@ -186,7 +186,12 @@ func (s *PrincipalService) CreatePrincipal(ctx context.Context, req *pb.CreatePr
 		CreatedAt:   time.Now().UTC(),
 	}
 
-	if err := s.principalStore.CreatePrincipal(ctx, p); err != nil {
+	auditCtx := store.WithAuditEntry(ctx, auditEntry(ctx, store.AuditCreatePrincipal, "principal", principalID, map[string]any{
+		"type":         req.Type,
+		"display_name": req.DisplayName,
+		"roles":        req.Roles,
+	}))
+	if err := s.principalStore.CreatePrincipal(auditCtx, p); err != nil {
 		if errors.Is(err, store.ErrDuplicatePubkey) {
 			return nil, status.Error(codes.AlreadyExists, "pubkey already registered to another principal")
 		}
@@ -195,24 +200,12 @@ func (s *PrincipalService) CreatePrincipal(ctx context.Context, req *pb.CreatePr
 
 	roleStrings := s.assignRoles(ctx, principalID, req.Roles)
 
-	_ = s.principalStore.AppendAuditLog(ctx, &store.AuditEntry{
-		ActorPrincipalID: authCtx.PrincipalID,
-		Action:           store.AuditCreatePrincipal,
-		TargetType:       "principal",
-		TargetID:         principalID,
-		Detail: map[string]any{
-			"type":         req.Type,
-			"display_name": req.DisplayName,
-			"roles":        req.Roles,
-		},
-	})
-
 	proto := &pb.Principal{
 		Id:          principalID,
 		Type:        string(pType),
 		DisplayName: p.DisplayName,
 		Status:      string(p.Status),
-		CreatedAt:   p.CreatedAt.Format(time.RFC3339),
+		CreatedAt:   timeparse.Format(p.CreatedAt),
 		Roles:       roleStrings,
 	}
 	if fingerprint != "" {
@@ -224,8 +217,6 @@ func (s *PrincipalService) CreatePrincipal(ctx context.Context, req *pb.CreatePr
 
 // DeletePrincipal deletes a principal.
 func (s *PrincipalService) DeletePrincipal(ctx context.Context, req *pb.DeletePrincipalRequest) (*pb.DeletePrincipalResponse, error) {
-	authCtx := auth.MustFromContext(ctx)
-
 	if req.Id == "" {
 		return nil, status.Error(codes.InvalidArgument, "id required")
 	}
@@ -239,22 +230,15 @@ func (s *PrincipalService) DeletePrincipal(ctx context.Context, req *pb.DeletePr
 		return nil, status.Errorf(codes.Internal, "failed to lookup principal: %v", err)
 	}
 
-	// Delete
-	if err := s.principalStore.DeletePrincipal(ctx, req.Id); err != nil {
+	// Delete, auditing it in the same transaction
+	auditCtx := store.WithAuditEntry(ctx, auditEntry(ctx, store.AuditDeletePrincipal, "principal", req.Id, nil))
+	if err := s.principalStore.DeletePrincipal(auditCtx, req.Id); err != nil {
 		if errors.Is(err, store.ErrPrincipalNotFound) {
 			return nil, status.Error(codes.NotFound, "principal not found")
 		}
 		return nil, status.Errorf(codes.Internal, "failed to delete principal: %v", err)
 	}
 
-	// Audit log
-	_ = s.principalStore.AppendAuditLog(ctx, &store.AuditEntry{
-		ActorPrincipalID: authCtx.PrincipalID,
-		Action:           store.AuditDeletePrincipal,
-		TargetType:       "principal",
-		TargetID:         req.Id,
-	})
-
 	return &pb.DeletePrincipalResponse{}, nil
 }
 
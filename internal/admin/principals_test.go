@@ -20,7 +20,7 @@ import (
 )
 
 // createPrincipalService creates a PrincipalService with the given store.
-func createPrincipalService(t *testing.T, s *store.SQLiteStore) *PrincipalService {
+func createPrincipalService(t *testing.T, s *store.SQLStore) *PrincipalService {
 	t.Helper()
 	verifier, err := auth.NewJWTVerifier(testSecret)
 	require.NoError(t, err)
@@ -214,6 +214,28 @@ func TestListPrincipals_IncludesPubkeyFP(t *testing.T) {
 	assert.Equal(t, fp, *resp.Principals[0].PubkeyFp)
 }
 
+func TestListPrincipals_IncludesTags(t *testing.T) {
+	s := createTestStore(t)
+	svc := createPrincipalService(t, s)
+	ctx := createAdminContext("admin-1")
+
+	require.NoError(t, s.CreatePrincipal(context.Background(), &store.Principal{
+		ID:          "agent-001",
+		Type:        store.PrincipalTypeAgent,
+		PubkeyFP:    testFingerprint("tagged"),
+		DisplayName: "Tagged Agent",
+		Status:      store.PrincipalStatusApproved,
+		CreatedAt:   time.Now().UTC(),
+	}))
+	tags := map[string]string{"env": "prod", "repo": "gateway"}
+	require.NoError(t, s.SetPrincipalTags(context.Background(), "agent-001", tags))
+
+	resp, err := svc.ListPrincipals(ctx, &pb.ListPrincipalsRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Principals, 1)
+	assert.Equal(t, tags, resp.Principals[0].GetTags())
+}
+
 // --- CreatePrincipal Tests ---
 
 func TestCreatePrincipal_ClientSuccess(t *testing.T) {
@@ -12,6 +12,7 @@ import (
 
 	"github.com/2389/coven-gateway/internal/auth"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
@@ -26,6 +27,11 @@ type TokenGenerator interface {
 	Generate(principalID string, ttl time.Duration) (string, error)
 }
 
+// TokenMinter issues tokens that are recorded so they can be listed and revoked.
+type TokenMinter interface {
+	Mint(ctx context.Context, req auth.MintRequest) (string, *store.APIToken, error)
+}
+
 // PrincipalLookup looks up principals by ID.
 type PrincipalLookup interface {
 	GetPrincipal(ctx context.Context, id string) (*store.Principal, error)
@@ -35,6 +41,7 @@ type PrincipalLookup interface {
 type TokenService struct {
 	*AdminService
 	tokenGen   TokenGenerator
+	minter     TokenMinter
 	principals PrincipalLookup
 }
 
@@ -47,6 +54,12 @@ func NewTokenService(s BindingStore, tokenGen TokenGenerator, principals Princip
 	}
 }
 
+// SetTokenMinter makes CreateToken record its tokens. Without a minter,
+// tokens are signed directly and cannot be listed or revoked.
+func (s *TokenService) SetTokenMinter(m TokenMinter) {
+	s.minter = m
+}
+
 // CreateToken generates a JWT token for a principal.
 func (s *TokenService) CreateToken(ctx context.Context, req *pb.CreateTokenRequest) (*pb.CreateTokenResponse, error) {
 	authCtx := auth.MustFromContext(ctx)
@@ -81,28 +94,52 @@ func (s *TokenService) CreateToken(ctx context.Context, req *pb.CreateTokenReque
 		}
 	}
 
+	// Agent and capability limits live on the token record, so a token
+	// can only be scoped when tokens are recorded.
+	scoped := len(req.AgentIds) > 0 || len(req.CapabilitiesSubset) > 0
+	if scoped && s.minter == nil {
+		return nil, status.Error(codes.FailedPrecondition, "scoped tokens require recorded tokens")
+	}
+
 	// Generate token
-	token, err := s.tokenGen.Generate(req.PrincipalId, ttl)
+	detail := map[string]any{"ttl_seconds": int64(ttl.Seconds())}
+	var token string
+	if s.minter != nil {
+		var rec *store.APIToken
+		token, rec, err = s.minter.Mint(ctx, auth.MintRequest{
+			PrincipalID: req.PrincipalId,
+			CreatedBy:   authCtx.PrincipalID,
+			TTL:         ttl,
+			Scopes:      auth.AllScopes,
+
+			AgentIDs:     req.AgentIds,
+			Capabilities: req.CapabilitiesSubset,
+		})
+		if err == nil {
+			detail["token_id"] = rec.ID
+			detail["scopes"] = rec.Scopes
+			if rec.AgentIDs != nil {
+				detail["agent_ids"] = rec.AgentIDs
+			}
+			if rec.Capabilities != nil {
+				detail["capabilities"] = rec.Capabilities
+			}
+		}
+	} else {
+		token, err = s.tokenGen.Generate(req.PrincipalId, ttl)
+	}
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to generate token")
 	}
 
 	expiresAt := time.Now().Add(ttl).UTC()
+	detail["expires_at"] = timeparse.Format(expiresAt)
 
 	// Audit log (ignore error - best effort)
-	_ = s.store.AppendAuditLog(ctx, &store.AuditEntry{
-		ActorPrincipalID: authCtx.PrincipalID,
-		Action:           store.AuditCreateToken,
-		TargetType:       "principal",
-		TargetID:         req.PrincipalId,
-		Detail: map[string]any{
-			"ttl_seconds": int64(ttl.Seconds()),
-			"expires_at":  expiresAt.Format(time.RFC3339),
-		},
-	})
+	_ = s.store.AppendAuditLog(ctx, auditEntry(ctx, store.AuditCreateToken, "principal", req.PrincipalId, detail))
 
 	return &pb.CreateTokenResponse{
 		Token:     token,
-		ExpiresAt: expiresAt.Format(time.RFC3339),
+		ExpiresAt: timeparse.Format(expiresAt),
 	}, nil
 }
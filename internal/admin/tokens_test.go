@@ -200,3 +200,33 @@ func TestCreateToken_AuditLog(t *testing.T) {
 	}
 	assert.True(t, found, "expected to find create_token audit entry, got %d entries total", len(entries))
 }
+
+func TestCreateToken_RecordsWithMinter(t *testing.T) {
+	s := createTestStore(t)
+	ctx := createAdminContext("admin-1")
+
+	require.NoError(t, s.CreatePrincipal(context.Background(), &store.Principal{
+		ID:          "test-principal",
+		Type:        store.PrincipalTypeClient,
+		DisplayName: "Test Principal",
+		Status:      store.PrincipalStatusApproved,
+	}))
+
+	verifier, _ := auth.NewJWTVerifier(testSecret)
+	svc := NewTokenService(s, verifier, s)
+	svc.SetTokenMinter(auth.NewTokenMinter(verifier, s))
+
+	resp, err := svc.CreateToken(ctx, &pb.CreateTokenRequest{PrincipalId: "test-principal", TtlSeconds: 3600})
+	require.NoError(t, err)
+
+	claims, err := verifier.VerifyClaims(resp.Token)
+	require.NoError(t, err)
+	require.NotEmpty(t, claims.TokenID)
+
+	tokens, err := s.ListAPITokens(context.Background(), "test-principal")
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, claims.TokenID, tokens[0].ID)
+	assert.Equal(t, "admin-1", tokens[0].CreatedBy)
+	assert.Equal(t, auth.AllScopes, tokens[0].Scopes)
+}
@@ -0,0 +1,71 @@
+// ABOUTME: Tracks what each agent is currently working on: thread and open tool calls.
+// ABOUTME: Used to give humans context when an agent interrupts them mid-request.
+
+package agent
+
+import (
+	"slices"
+	"time"
+)
+
+// ToolCall is a tool invocation the agent has started but not yet finished.
+type ToolCall struct {
+	ID        string
+	Name      string
+	InputJSON string
+}
+
+// Activity describes an agent's most recent in-flight request.
+type Activity struct {
+	RequestID string
+	ThreadID  string
+	StartedAt time.Time
+	ToolCalls []ToolCall // open tool calls in invocation order
+}
+
+// Activity returns a copy of the agent's most recent in-flight request, if any.
+func (m *Manager) Activity(agentID string) (Activity, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	act, ok := m.activity[agentID]
+	if !ok {
+		return Activity{}, false
+	}
+	out := *act
+	out.ToolCalls = slices.Clone(act.ToolCalls)
+	return out, true
+}
+
+func (m *Manager) startActivity(agentID, requestID, threadID string) {
+	m.mu.Lock()
+	m.activity[agentID] = &Activity{RequestID: requestID, ThreadID: threadID, StartedAt: time.Now()}
+	m.mu.Unlock()
+}
+
+// trackActivity updates the open tool call chain from a response event.
+func (m *Manager) trackActivity(agentID, requestID string, resp *Response) {
+	if resp.ToolUse == nil && resp.ToolResult == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	act, ok := m.activity[agentID]
+	if !ok || act.RequestID != requestID {
+		return
+	}
+	if tu := resp.ToolUse; tu != nil {
+		act.ToolCalls = append(act.ToolCalls, ToolCall{ID: tu.ID, Name: tu.Name, InputJSON: tu.InputJSON})
+	}
+	if tr := resp.ToolResult; tr != nil && !tr.Partial {
+		act.ToolCalls = slices.DeleteFunc(act.ToolCalls, func(c ToolCall) bool { return c.ID == tr.ID })
+	}
+}
+
+// endActivity clears the agent's activity if it still belongs to requestID.
+func (m *Manager) endActivity(agentID, requestID string) {
+	m.mu.Lock()
+	if act, ok := m.activity[agentID]; ok && act.RequestID == requestID {
+		delete(m.activity, agentID)
+	}
+	m.mu.Unlock()
+}
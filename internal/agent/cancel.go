@@ -0,0 +1,66 @@
+// ABOUTME: Client-initiated cancellation: a send whose context is canceled with ErrRequestCanceled
+// ABOUTME: or a CancelCause tells the agent to stop and ends the caller's stream with a canceled event.
+
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/logctx"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// ErrRequestCanceled, given as the cause when canceling a send's context
+// (see context.WithCancelCause), asks the manager to cancel the request at
+// the agent instead of just abandoning it.
+var ErrRequestCanceled = coverr.New(coverr.Canceled, "request canceled by client")
+
+// CancelReason is the cancel reason given to requests a client canceled.
+const CancelReason = "client_canceled"
+
+// UserRequestedReason is the cancel reason given to requests someone stopped
+// on purpose, possibly from a client other than the one following them.
+const UserRequestedReason = "user_requested"
+
+// CancelCause returns a cause for canceling a send's context that, like
+// ErrRequestCanceled, cancels the request at the agent, with reason in place
+// of CancelReason.
+func CancelCause(reason string) error {
+	return &cancelCause{reason: reason}
+}
+
+// cancelCause is a client cancellation that carries its own reason.
+type cancelCause struct {
+	reason string
+}
+
+func (c *cancelCause) Error() string { return "request canceled: " + c.reason }
+
+func (c *cancelCause) Is(target error) bool { return target == ErrRequestCanceled }
+
+// canceledByClient reports whether ctx was canceled with ErrRequestCanceled
+// or a CancelCause, and the reason to give the agent and the caller.
+func canceledByClient(ctx context.Context) (string, bool) {
+	cause := context.Cause(ctx)
+	var c *cancelCause
+	if errors.As(cause, &c) {
+		return c.reason, true
+	}
+	return CancelReason, errors.Is(cause, ErrRequestCanceled)
+}
+
+// cancelAtAgent tells the agent to stop requestID and returns the caller's
+// final response, a canceled event with reason.
+func (m *Manager) cancelAtAgent(ctx context.Context, agent *Connection, requestID, reason string) *Response {
+	cancel := &pb.ServerMessage{
+		Payload: &pb.ServerMessage_CancelRequest{
+			CancelRequest: &pb.CancelRequest{RequestId: requestID, Reason: &reason},
+		},
+	}
+	if err := agent.Send(cancel); err != nil {
+		logctx.FromContext(ctx, m.logger).Warn("failed to cancel request", "agent_id", agent.ID, "agent_request_id", requestID, "reason", reason, "error", err)
+	}
+	return &Response{Event: EventCanceled, Error: reason, Done: true}
+}
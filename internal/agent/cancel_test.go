@@ -0,0 +1,89 @@
+// ABOUTME: Tests for client-initiated cancellation via a context canceled with ErrRequestCanceled.
+// ABOUTME: Checks the agent is sent a CancelRequest and the caller gets a canceled event with its reason.
+
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestManagerCancelsRequestAtAgent(t *testing.T) {
+	manager := NewManager(slog.Default())
+	stream := newMockStream()
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Name: "Test Agent", Stream: stream, Logger: slog.Default()})
+	if err := manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	respChan, err := manager.SendMessage(ctx, &SendRequest{ThreadID: "thread-1", Sender: "u", Content: "hi", AgentID: "agent-1"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	requestID := lastRequestID(stream)
+	cancel(ErrRequestCanceled)
+
+	var last *Response
+	for resp := range respChan {
+		last = resp
+	}
+	if last == nil || last.Event != EventCanceled || !last.Done || last.Error != CancelReason {
+		t.Fatalf("last response = %+v, want a done canceled event with reason %s", last, CancelReason)
+	}
+	sent := stream.getSentMessages()
+	cancelReq := sent[len(sent)-1].GetCancelRequest()
+	if cancelReq == nil || cancelReq.GetRequestId() != requestID || cancelReq.GetReason() != CancelReason {
+		t.Errorf("last message to agent = %v, want a cancel for %s", sent[len(sent)-1], requestID)
+	}
+}
+
+func TestManagerPlainCancelDoesNotNotifyAgent(t *testing.T) {
+	manager := NewManager(slog.Default())
+	stream := newMockStream()
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Name: "Test Agent", Stream: stream, Logger: slog.Default()})
+	if err := manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	respChan, err := manager.SendMessage(ctx, &SendRequest{ThreadID: "thread-1", Sender: "u", Content: "hi", AgentID: "agent-1"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	cancel()
+	for range respChan {
+	}
+	if n := len(stream.getSentMessages()); n != 1 {
+		t.Errorf("agent got %d messages, want only the send", n)
+	}
+}
+
+func TestManagerCancelsWithCauseReason(t *testing.T) {
+	manager := NewManager(slog.Default())
+	stream := newMockStream()
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Name: "Test Agent", Stream: stream, Logger: slog.Default()})
+	if err := manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	respChan, err := manager.SendMessage(ctx, &SendRequest{ThreadID: "thread-1", Sender: "u", Content: "hi", AgentID: "agent-1"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	cancel(CancelCause(UserRequestedReason))
+
+	var last *Response
+	for resp := range respChan {
+		last = resp
+	}
+	if last == nil || last.Event != EventCanceled || last.Error != UserRequestedReason {
+		t.Fatalf("last response = %+v, want a canceled event with reason %s", last, UserRequestedReason)
+	}
+	sent := stream.getSentMessages()
+	if reason := sent[len(sent)-1].GetCancelRequest().GetReason(); reason != UserRequestedReason {
+		t.Errorf("cancel reason sent to agent = %q, want %s", reason, UserRequestedReason)
+	}
+}
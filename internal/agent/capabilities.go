@@ -0,0 +1,47 @@
+// ABOUTME: Live capability set of a connected agent, replaced when an admin changes its principal's grants.
+// ABOUTME: Agents advertising capability_update are pushed the new set without reconnecting.
+
+package agent
+
+import (
+	"slices"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// FeatureCapabilityUpdate marks agents that handle CapabilityUpdate, applying
+// a new capability set and tool list without reconnecting.
+const FeatureCapabilityUpdate = "capability_update"
+
+// Capabilities returns a copy of the agent's current capabilities.
+func (c *Connection) Capabilities() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.capabilities)
+}
+
+// HasCapability reports whether the agent currently holds capability.
+func (c *Connection) HasCapability(capability string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Contains(c.capabilities, capability)
+}
+
+// UpdateCapabilities replaces the agent's capabilities, so routing and agent
+// listings see the new set at once, and sends it a CapabilityUpdate with
+// tools if it advertised FeatureCapabilityUpdate. pushed is false, and err
+// nil, for agents that did not.
+func (c *Connection) UpdateCapabilities(capabilities []string, tools []*pb.ToolDefinition) (pushed bool, err error) {
+	c.mu.Lock()
+	c.capabilities = slices.Clone(capabilities)
+	c.mu.Unlock()
+
+	if !c.Metadata.HasFeature(FeatureCapabilityUpdate) {
+		return false, nil
+	}
+	err = c.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_CapabilityUpdate{CapabilityUpdate: &pb.CapabilityUpdate{
+		Capabilities:   capabilities,
+		AvailableTools: tools,
+	}}})
+	return err == nil, err
+}
@@ -0,0 +1,137 @@
+// ABOUTME: Tests for replacing a connected agent's capabilities: routing and listings see the
+// ABOUTME: new set at once, only capability_update agents are pushed it, and revocation races in-flight work.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestUpdateCapabilities(t *testing.T) {
+	tools := []*pb.ToolDefinition{{Name: "log_entry"}}
+
+	t.Run("pushes to agents with the feature", func(t *testing.T) {
+		stream := newMockStream()
+		conn := NewConnection(ConnectionParams{
+			ID: "agent-1", Capabilities: []string{"chat", "notes"}, Stream: stream, Logger: slog.Default(),
+			Metadata: &Metadata{ProtocolFeatures: []string{FeatureCapabilityUpdate}},
+		})
+
+		pushed, err := conn.UpdateCapabilities([]string{"chat"}, tools)
+		if err != nil || !pushed {
+			t.Fatalf("UpdateCapabilities = %v, %v; want pushed", pushed, err)
+		}
+		if got := conn.Capabilities(); !slices.Equal(got, []string{"chat"}) {
+			t.Errorf("capabilities = %v, want [chat]", got)
+		}
+		sent := stream.getSentMessages()
+		if len(sent) != 1 {
+			t.Fatalf("sent %d messages, want 1", len(sent))
+		}
+		update := sent[0].GetCapabilityUpdate()
+		if !slices.Equal(update.GetCapabilities(), []string{"chat"}) || len(update.GetAvailableTools()) != 1 {
+			t.Errorf("update = %v, want [chat] with one tool", update)
+		}
+	})
+
+	t.Run("only records the set for agents without it", func(t *testing.T) {
+		stream := newMockStream()
+		conn := NewConnection(ConnectionParams{ID: "agent-1", Capabilities: []string{"chat"}, Stream: stream, Logger: slog.Default()})
+
+		pushed, err := conn.UpdateCapabilities([]string{"chat", "notes"}, tools)
+		if err != nil || pushed {
+			t.Fatalf("UpdateCapabilities = %v, %v; want not pushed", pushed, err)
+		}
+		if !conn.HasCapability("notes") {
+			t.Error("notes not recorded")
+		}
+		if sent := stream.getSentMessages(); len(sent) != 0 {
+			t.Errorf("sent %v to an agent without capability_update", sent)
+		}
+	})
+
+	t.Run("routing and listings see the new set", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		conn := addCapableAgent(t, m, "a", 0, "chat")
+
+		if _, err := conn.UpdateCapabilities([]string{"code"}, nil); err != nil {
+			t.Fatalf("UpdateCapabilities: %v", err)
+		}
+		if _, _, err := m.SelectByCapability(context.Background(), "chat"); !errors.Is(err, ErrNoCapableAgent) {
+			t.Errorf("select chat err = %v, want ErrNoCapableAgent", err)
+		}
+		if picked, _, err := m.SelectByCapability(context.Background(), "code"); err != nil || picked != conn {
+			t.Errorf("select code = %v, %v; want the agent", picked, err)
+		}
+		if got := m.ListAgents()[0].Capabilities; !slices.Equal(got, []string{"code"}) {
+			t.Errorf("listed capabilities = %v, want [code]", got)
+		}
+	})
+}
+
+// TestUpdateCapabilitiesDuringRequest revokes a capability while a request
+// routed by it is in flight. The request finishes normally; new work is not
+// routed to the agent. Run with -race.
+func TestUpdateCapabilitiesDuringRequest(t *testing.T) {
+	m := NewManager(slog.Default())
+	stream := newMockStream()
+	conn := NewConnection(ConnectionParams{
+		ID: "agent-1", Name: "agent-1", Capabilities: []string{"chat"}, Stream: stream, Logger: slog.Default(),
+		Metadata: &Metadata{ProtocolFeatures: []string{FeatureCapabilityUpdate}},
+	})
+	if err := m.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	picked, _, err := m.SelectByCapability(context.Background(), "chat")
+	if err != nil {
+		t.Fatalf("SelectByCapability: %v", err)
+	}
+	respChan, err := m.SendMessage(context.Background(), &SendRequest{AgentID: picked.ID, Sender: "alice", Content: "hi", ThreadID: "thread-1"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	requestID := stream.getSentMessages()[0].GetSendMessage().GetRequestId()
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		if _, err := conn.UpdateCapabilities([]string{"code"}, nil); err != nil {
+			t.Errorf("UpdateCapabilities: %v", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			m.ListAgents()
+			_, _, _ = m.SelectByCapability(context.Background(), "chat")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		conn.HandleResponse(&pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Text{Text: "hello"}})
+		conn.HandleResponse(&pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "hello"}}})
+	}()
+	wg.Wait()
+
+	var done bool
+	for resp := range respChan {
+		if resp.Done {
+			done = true
+		}
+	}
+	if !done {
+		t.Error("in-flight request did not finish after revocation")
+	}
+	if _, _, err := m.SelectByCapability(context.Background(), "chat"); !errors.Is(err, ErrNoCapableAgent) {
+		t.Errorf("select after revocation err = %v, want ErrNoCapableAgent", err)
+	}
+}
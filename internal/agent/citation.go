@@ -0,0 +1,81 @@
+// ABOUTME: Citation events: sources an agent attaches to its response text.
+// ABOUTME: Text markers like "[1]" refer to citations by index; ordering relative to text is not guaranteed.
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// CitationEvent is a source the response text refers to with a "[Index]" marker.
+type CitationEvent struct {
+	Index   int    `json:"index"`
+	Title   string `json:"title"`
+	URI     string `json:"uri"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// Text renders the citation as a reference line, e.g. "[1] Title <uri>".
+func (c *CitationEvent) Text() string {
+	switch {
+	case c.Title == "":
+		return fmt.Sprintf("[%d] %s", c.Index, c.URI)
+	case c.URI == "":
+		return fmt.Sprintf("[%d] %s", c.Index, c.Title)
+	}
+	return fmt.Sprintf("[%d] %s <%s>", c.Index, c.Title, c.URI)
+}
+
+// JSON returns the citation encoded as JSON, the form the ledger stores.
+func (c *CitationEvent) JSON() string {
+	b, _ := json.Marshal(c) // plain strings and ints cannot fail to encode
+	return string(b)
+}
+
+// Sources collects the citations of one response. A later citation with the
+// same index replaces an earlier one, so agents can refine a source.
+type Sources struct {
+	byIndex map[int]CitationEvent
+}
+
+// Add records a citation.
+func (s *Sources) Add(c *CitationEvent) {
+	if c == nil {
+		return
+	}
+	if s.byIndex == nil {
+		s.byIndex = make(map[int]CitationEvent)
+	}
+	s.byIndex[c.Index] = *c
+}
+
+// List returns the recorded citations ordered by index, or nil if none.
+func (s *Sources) List() []CitationEvent {
+	if len(s.byIndex) == 0 {
+		return nil
+	}
+	list := make([]CitationEvent, 0, len(s.byIndex))
+	for _, c := range s.byIndex {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Index < list[j].Index })
+	return list
+}
+
+// convertCitationEvent handles citation events.
+func convertCitationEvent(event any) *Response {
+	e, ok := event.(*pb.MessageResponse_Citation)
+	if !ok {
+		return nil
+	}
+	return &Response{Event: EventCitation, Citation: &CitationEvent{
+		Index:   int(e.Citation.GetIndex()),
+		Title:   e.Citation.GetTitle(),
+		URI:     e.Citation.GetUri(),
+		Snippet: e.Citation.GetSnippet(),
+	}}
+}
@@ -0,0 +1,210 @@
+// ABOUTME: Per-agent concurrency limits: at most N requests in flight per agent, the rest queued or refused.
+// ABOUTME: The bounded FIFO queue hands each freed slot to the oldest waiter and drops waiters that give up.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/logctx"
+)
+
+// DefaultMaxConcurrent is the per-agent request limit the gateway applies
+// when agents.max_concurrent is unset.
+const DefaultMaxConcurrent = 4
+
+// ErrAgentBusy indicates the agent is at its concurrency limit and the
+// request could not be queued.
+var ErrAgentBusy = coverr.New(coverr.AgentBusy, "agent busy")
+
+// BusyError names the agent that refused a request and its limit. It matches
+// ErrAgentBusy with errors.Is.
+type BusyError struct {
+	AgentID       string
+	MaxConcurrent int
+}
+
+func (e *BusyError) Error() string {
+	return fmt.Sprintf("%s: agent %s is at its limit of %d concurrent requests", ErrAgentBusy, e.AgentID, e.MaxConcurrent)
+}
+
+// Unwrap returns ErrAgentBusy, so the error matches it and carries its code.
+func (e *BusyError) Unwrap() error {
+	return ErrAgentBusy
+}
+
+// slotWaiter is a request queued for an agent at its limit.
+type slotWaiter struct {
+	ready chan struct{} // closed once the waiter holds a slot or has failed
+	err   error         // set before ready is closed when the waiter failed
+}
+
+// SetConcurrencyLimit caps the requests each agent handles at once. An
+// agent's own max_concurrent hint lowers the cap further. Requests beyond it
+// wait in a FIFO queue of up to queueSize per agent, and are refused with
+// ErrAgentBusy when the queue is full. Zero maxConcurrent disables the limit;
+// zero queueSize refuses at once. Call before the manager starts handling
+// requests.
+func (m *Manager) SetConcurrencyLimit(maxConcurrent, queueSize int) {
+	m.maxConcurrent = maxConcurrent
+	m.queueSize = queueSize
+}
+
+// SetQueueObserver registers a function called with an agent's queue depth
+// whenever it changes. Call before the manager starts handling requests.
+func (m *Manager) SetQueueObserver(fn func(agentID string, depth int)) {
+	m.observeQueue = fn
+}
+
+// QueueDepth returns how many requests are waiting for one of the agent's slots.
+func (m *Manager) QueueDepth(agentID string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.queues[agentID])
+}
+
+// concurrencyLimit returns the number of requests conn may handle at once,
+// zero for no limit.
+func (m *Manager) concurrencyLimit(conn *Connection) int {
+	limit := m.maxConcurrent
+	if hint := conn.maxConcurrent(); hint > 0 && (limit == 0 || hint < limit) {
+		limit = hint
+	}
+	return limit
+}
+
+// acquireSlot counts a new request against conn's limit. It returns a nil
+// waiter when the slot was taken at once, or the queued waiter and its
+// 1-based position. It returns a BusyError when the queue is full.
+func (m *Manager) acquireSlot(conn *Connection) (*slotWaiter, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	load := m.load[conn.ID]
+	if load == nil {
+		load = &agentLoad{}
+		m.load[conn.ID] = load
+	}
+	limit := m.concurrencyLimit(conn)
+	queue := m.queues[conn.ID]
+	if limit == 0 || (load.inFlight < limit && len(queue) == 0) {
+		load.inFlight++
+		return nil, 0, nil
+	}
+	if len(queue) >= m.queueSize {
+		return nil, 0, &BusyError{AgentID: conn.ID, MaxConcurrent: limit}
+	}
+
+	w := &slotWaiter{ready: make(chan struct{})}
+	m.queues[conn.ID] = append(queue, w)
+	m.queueChangedLocked(conn.ID)
+	return w, len(m.queues[conn.ID]), nil
+}
+
+// waitSlot blocks until w holds a slot. If ctx ends first, w leaves the
+// queue, giving back the slot if one was handed over in the meantime.
+func (m *Manager) waitSlot(ctx context.Context, agentID string, w *slotWaiter) error {
+	select {
+	case <-w.ready:
+		return w.err
+	case <-ctx.Done():
+	}
+
+	m.mu.Lock()
+	queue := m.queues[agentID]
+	if i := slices.Index(queue, w); i >= 0 {
+		m.queues[agentID] = slices.Delete(queue, i, i+1)
+		m.queueChangedLocked(agentID)
+		m.mu.Unlock()
+		return ctx.Err()
+	}
+	m.mu.Unlock()
+
+	// The slot was handed over (or the queue failed) as ctx ended.
+	<-w.ready
+	if w.err == nil {
+		m.endLoad(agentID)
+	}
+	return ctx.Err()
+}
+
+// handOffSlotLocked gives a freed slot of agentID to its oldest waiter. It
+// reports false when nobody is waiting. m.mu must be held.
+func (m *Manager) handOffSlotLocked(agentID string) bool {
+	queue := m.queues[agentID]
+	if len(queue) == 0 {
+		return false
+	}
+	w := queue[0]
+	m.queues[agentID] = queue[1:]
+	m.queueChangedLocked(agentID)
+	close(w.ready)
+	return true
+}
+
+// failQueueLocked fails every request waiting for agentID with err.
+// m.mu must be held.
+func (m *Manager) failQueueLocked(agentID string, err error) {
+	queue := m.queues[agentID]
+	if len(queue) == 0 {
+		return
+	}
+	for _, w := range queue {
+		w.err = err
+		close(w.ready)
+	}
+	m.queues[agentID] = nil
+	m.queueChangedLocked(agentID)
+}
+
+// queueChangedLocked reports agentID's queue depth and drops empty queues.
+// m.mu must be held.
+func (m *Manager) queueChangedLocked(agentID string) {
+	depth := len(m.queues[agentID])
+	if depth == 0 {
+		delete(m.queues, agentID)
+	}
+	if m.observeQueue != nil {
+		m.observeQueue(agentID, depth)
+	}
+}
+
+// sendQueued delivers req once its waiter gets a slot. Failures while
+// waiting, including ctx ending, arrive as a final error response.
+func (m *Manager) sendQueued(ctx context.Context, agent *Connection, req *SendRequest, w *slotWaiter) <-chan *Response {
+	out := make(chan *Response, 16)
+	go func() {
+		defer close(out)
+		fail := func(err error) {
+			out <- &Response{Event: EventError, Error: err.Error(), Code: coverr.CodeOf(err), Done: true}
+		}
+
+		if err := m.waitSlot(ctx, agent.ID, w); err != nil {
+			if reason, ok := canceledByClient(ctx); ok {
+				// The agent never saw the request, so there is nothing to tell it.
+				out <- &Response{Event: EventCanceled, Error: reason, Done: true}
+				return
+			}
+			fail(err)
+			return
+		}
+		logctx.FromContext(ctx, m.logger).Debug("queued request got a slot", "agent_id", agent.ID)
+		if err := m.CheckNotPaused(agent.ID); err != nil {
+			m.endLoad(agent.ID)
+			fail(err)
+			return
+		}
+		in, err := m.deliver(ctx, agent, req)
+		if err != nil {
+			fail(err)
+			return
+		}
+		for resp := range in {
+			out <- resp
+		}
+	}()
+	return out
+}
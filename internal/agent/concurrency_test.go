@@ -0,0 +1,136 @@
+// ABOUTME: Tests for per-agent concurrency limits: queueing in FIFO order, refusing when full,
+// ABOUTME: and freeing a queued slot when the caller gives up.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func registerLimited(t *testing.T, maxConcurrent, queueSize int) (*Manager, *Connection, *mockStream) {
+	t.Helper()
+	m := NewManager(slog.Default())
+	m.SetConcurrencyLimit(maxConcurrent, queueSize)
+	stream := newMockStream()
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Name: "Test Agent", Stream: stream, Logger: slog.Default()})
+	if err := m.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return m, conn, stream
+}
+
+func lastRequestID(stream *mockStream) string {
+	sent := stream.getSentMessages()
+	return sent[len(sent)-1].GetSendMessage().GetRequestId()
+}
+
+func TestConcurrencyLimit_QueuesInOrder(t *testing.T) {
+	m, conn, stream := registerLimited(t, 1, 2)
+	var depths []int
+	m.SetQueueObserver(func(_ string, depth int) { depths = append(depths, depth) })
+	ctx := context.Background()
+
+	first, err := m.SendMessage(ctx, &SendRequest{AgentID: "agent-1", Content: "one"})
+	if err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+	firstID := lastRequestID(stream)
+
+	var positions []int
+	onQueued := func(p int) { positions = append(positions, p) }
+	second, err := m.SendMessage(ctx, &SendRequest{AgentID: "agent-1", Content: "two", OnQueued: onQueued})
+	if err != nil {
+		t.Fatalf("second send: %v", err)
+	}
+	if _, err := m.SendMessage(ctx, &SendRequest{AgentID: "agent-1", Content: "three", OnQueued: onQueued}); err != nil {
+		t.Fatalf("third send: %v", err)
+	}
+	if len(positions) != 2 || positions[0] != 1 || positions[1] != 2 {
+		t.Errorf("queue positions = %v, want [1 2]", positions)
+	}
+	if n := len(stream.getSentMessages()); n != 1 {
+		t.Fatalf("agent got %d messages, want only the first", n)
+	}
+
+	var busy *BusyError
+	if _, err := m.SendMessage(ctx, &SendRequest{AgentID: "agent-1", Content: "four"}); !errors.As(err, &busy) || busy.MaxConcurrent != 1 {
+		t.Fatalf("send with a full queue = %v, want a BusyError with limit 1", err)
+	}
+
+	conn.HandleResponse(&pb.MessageResponse{RequestId: firstID, Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "ok"}}})
+	for range first {
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(stream.getSentMessages()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	sent := stream.getSentMessages()
+	if len(sent) != 2 || sent[1].GetSendMessage().GetContent() != "two" {
+		t.Fatalf("agent messages = %v, want the oldest queued request next", sent)
+	}
+	if n := m.InFlight("agent-1"); n != 1 {
+		t.Errorf("InFlight = %d, want 1", n)
+	}
+	if d := m.QueueDepth("agent-1"); d != 1 {
+		t.Errorf("QueueDepth = %d, want 1", d)
+	}
+	conn.HandleResponse(&pb.MessageResponse{RequestId: lastRequestID(stream), Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "ok"}}})
+	for range second {
+	}
+	if len(depths) < 3 || depths[0] != 1 || depths[1] != 2 || depths[2] != 1 {
+		t.Errorf("observed depths = %v, want to start 1, 2, 1", depths)
+	}
+}
+
+func TestConcurrencyLimit_NoQueueFailsFast(t *testing.T) {
+	m, _, _ := registerLimited(t, 1, 0)
+	if _, err := m.SendMessage(context.Background(), &SendRequest{AgentID: "agent-1", Content: "one"}); err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+	if _, err := m.SendMessage(context.Background(), &SendRequest{AgentID: "agent-1", Content: "two"}); !errors.Is(err, ErrAgentBusy) {
+		t.Errorf("second send = %v, want ErrAgentBusy", err)
+	}
+}
+
+func TestConcurrencyLimit_CanceledWaiterLeavesQueue(t *testing.T) {
+	m, conn, stream := registerLimited(t, 1, 1)
+	first, err := m.SendMessage(context.Background(), &SendRequest{AgentID: "agent-1", Content: "one"})
+	if err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+	firstID := lastRequestID(stream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queued, err := m.SendMessage(ctx, &SendRequest{AgentID: "agent-1", Content: "abandoned"})
+	if err != nil {
+		t.Fatalf("queued send: %v", err)
+	}
+	cancel()
+	var last *Response
+	for resp := range queued {
+		last = resp
+	}
+	if last == nil || last.Event != EventError || !last.Done {
+		t.Fatalf("last response = %+v, want a done error", last)
+	}
+	if d := m.QueueDepth("agent-1"); d != 0 {
+		t.Errorf("QueueDepth = %d after cancel, want 0", d)
+	}
+
+	conn.HandleResponse(&pb.MessageResponse{RequestId: firstID, Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "ok"}}})
+	for range first {
+	}
+	if n := m.InFlight("agent-1"); n != 0 {
+		t.Errorf("InFlight = %d, the abandoned request must not hold a slot", n)
+	}
+	if n := len(stream.getSentMessages()); n != 1 {
+		t.Errorf("agent got %d messages, want the abandoned one never sent", n)
+	}
+}
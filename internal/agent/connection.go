@@ -7,25 +7,51 @@ import (
 	"errors"
 	"log/slog"
 	"sync"
+	"time"
 
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
 // Connection represents a connected agent with its GRPC stream.
 type Connection struct {
-	ID           string
-	Name         string
-	Capabilities []string
-	PrincipalID  string   // Authenticated principal UUID (for audit)
-	Workspaces   []string // From registration metadata
-	WorkingDir   string   // From registration metadata
-	InstanceID   string   // Short code for binding commands
-	Backend      string   // Backend type: "mux", "cli", "acp", "direct"
+	ID          string
+	Name        string
+	PrincipalID string    // Authenticated principal UUID (for audit)
+	Workspaces  []string  // From registration metadata
+	WorkingDir  string    // From registration metadata
+	InstanceID  string    // Short code for binding commands
+	Backend     string    // Backend type: "mux", "cli", "acp", "direct"
+	Metadata    *Metadata // Normalized registration metadata; nil if none was recorded
+
+	// capabilities is what the agent registered with until an admin changes
+	// its principal's grants. Guarded by mu; read it with Capabilities.
+	capabilities []string
 
 	stream  pb.CovenControl_AgentStreamServer
 	pending map[string]chan *pb.MessageResponse
+	// resumes holds, per pending request, the message that re-sends it to a
+	// successor connection that supports resume.
+	resumes map[string]*pb.ResumeRequest
 	mu      sync.RWMutex
 	logger  *slog.Logger
+
+	// Handoff state, guarded by mu. Once superseded, requests and sends go to
+	// successor; dropped holds requests the handoff failed instead of moving.
+	superseded bool
+	successor  *Connection
+	dropped    map[string]bool
+	// oversized holds requests RejectOversized ended, with the reason.
+	oversized map[string]string
+	// handedOver holds requests moved here from a superseded connection,
+	// waiting for ResumeHandedOver to re-send them.
+	handedOver []*pb.ResumeRequest
+
+	// beat is closed by Heartbeat to wake requests waiting on the agent.
+	beat   chan struct{}
+	beatMu sync.Mutex
+
+	// heartbeat holds round-trip samples and the health last reported.
+	heartbeat heartbeatStats
 }
 
 // ConnectionParams contains the parameters needed to create a new Connection.
@@ -38,6 +64,7 @@ type ConnectionParams struct {
 	WorkingDir   string
 	InstanceID   string
 	Backend      string
+	Metadata     *Metadata
 	Stream       pb.CovenControl_AgentStreamServer
 	Logger       *slog.Logger
 }
@@ -51,24 +78,29 @@ func NewConnection(params ConnectionParams) *Connection {
 	return &Connection{
 		ID:           params.ID,
 		Name:         params.Name,
-		Capabilities: params.Capabilities,
 		PrincipalID:  params.PrincipalID,
 		Workspaces:   params.Workspaces,
 		WorkingDir:   params.WorkingDir,
 		InstanceID:   params.InstanceID,
 		Backend:      params.Backend,
+		Metadata:     params.Metadata,
+		capabilities: params.Capabilities,
 		stream:       params.Stream,
 		pending:      make(map[string]chan *pb.MessageResponse),
+		resumes:      make(map[string]*pb.ResumeRequest),
 		logger:       logger,
+		heartbeat:    heartbeatStats{since: time.Now()},
 	}
 }
 
 // ErrNilStream is returned when attempting to send on a nil stream.
 var ErrNilStream = errors.New("connection stream is nil")
 
-// Send transmits a ServerMessage to the agent via the GRPC stream.
+// Send transmits a ServerMessage to the agent via the GRPC stream, or via
+// its successor's once the connection has been superseded.
 // Returns ErrNilStream if the stream is nil.
 func (c *Connection) Send(msg *pb.ServerMessage) error {
+	c = c.current()
 	if c.stream == nil {
 		return ErrNilStream
 	}
@@ -78,22 +110,25 @@ func (c *Connection) Send(msg *pb.ServerMessage) error {
 // CreateRequest registers a new pending request and returns a channel for responses.
 // The caller is responsible for eventually calling CloseRequest to clean up.
 func (c *Connection) CreateRequest(requestID string) <-chan *pb.MessageResponse {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	ch := make(chan *pb.MessageResponse, 16)
-	c.pending[requestID] = ch
+	ch, _ := c.startRequest(requestID, nil, nil)
 	return ch
 }
 
 // CloseRequest closes and removes the response channel for a request.
+// A request moved to a successor connection is closed there.
 func (c *Connection) CloseRequest(requestID string) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	if ch, ok := c.pending[requestID]; ok {
 		close(ch)
 		delete(c.pending, requestID)
+		delete(c.resumes, requestID)
+		c.mu.Unlock()
+		return
+	}
+	next := c.successor
+	c.mu.Unlock()
+	if next != nil {
+		next.CloseRequest(requestID)
 	}
 }
 
@@ -107,21 +142,18 @@ func (c *Connection) Close() {
 	for requestID, ch := range c.pending {
 		close(ch)
 		delete(c.pending, requestID)
+		delete(c.resumes, requestID)
 	}
 }
 
 // HandleResponse routes a MessageResponse to the appropriate pending request channel.
-// If no matching request is found, the response is logged and discarded.
-func (c *Connection) HandleResponse(resp *pb.MessageResponse) {
+// It reports false, discarding the response, if no request is pending for it.
+func (c *Connection) HandleResponse(resp *pb.MessageResponse) bool {
 	c.mu.RLock()
 	ch, ok := c.pending[resp.GetRequestId()]
 	if !ok {
 		c.mu.RUnlock()
-		c.logger.Warn("received response for unknown request",
-			"request_id", resp.GetRequestId(),
-			"agent_id", c.ID,
-		)
-		return
+		return false
 	}
 
 	// Non-blocking send to avoid deadlock if channel is full.
@@ -135,4 +167,5 @@ func (c *Connection) HandleResponse(resp *pb.MessageResponse) {
 		)
 	}
 	c.mu.RUnlock()
+	return true
 }
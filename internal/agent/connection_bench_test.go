@@ -0,0 +1,59 @@
+// ABOUTME: Benchmarks for request correlation in Connection.
+// ABOUTME: Tracks the per-event cost of routing agent responses to pending requests.
+
+package agent
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// BenchmarkConnectionHandleResponse measures routing one response to a pending request.
+func BenchmarkConnectionHandleResponse(b *testing.B) {
+	conn := NewConnection(ConnectionParams{
+		ID:     "bench-agent",
+		Stream: newMockStream(),
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	ch := conn.CreateRequest("req-1")
+	resp := &pb.MessageResponse{
+		RequestId: "req-1",
+		Event:     &pb.MessageResponse_Text{Text: "chunk"},
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		conn.HandleResponse(resp)
+		<-ch
+	}
+}
+
+// BenchmarkConnectionRequestLifecycle measures create/route/close for concurrent
+// requests sharing one connection, which is the per-message path under load.
+func BenchmarkConnectionRequestLifecycle(b *testing.B) {
+	conn := NewConnection(ConnectionParams{
+		ID:     "bench-agent",
+		Stream: newMockStream(),
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+
+	var seq atomic.Int64
+	b.ReportAllocs()
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			requestID := fmt.Sprintf("req-%d", seq.Add(1))
+			ch := conn.CreateRequest(requestID)
+			conn.HandleResponse(&pb.MessageResponse{
+				RequestId: requestID,
+				Event:     &pb.MessageResponse_Text{Text: "chunk"},
+			})
+			<-ch
+			conn.CloseRequest(requestID)
+		}
+	})
+}
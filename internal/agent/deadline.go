@@ -0,0 +1,173 @@
+// ABOUTME: Enforces a maximum response duration on in-flight agent requests.
+// ABOUTME: The clock pauses while a tool waits for human approval.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/logctx"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// TruncatedReasonMaxDuration is the reason reported when a response runs past
+// its maximum duration.
+const TruncatedReasonMaxDuration = "max_response_duration"
+
+// TruncatedEvent describes a response the gateway cut short.
+type TruncatedEvent struct {
+	Reason  string
+	Elapsed time.Duration // wall-clock time since the request was sent
+	Paused  time.Duration // time spent waiting for tool approval, not counted against Limit
+	Limit   time.Duration
+}
+
+// Summary is the human-readable marker recorded in the ledger after the
+// partial reply.
+func (t *TruncatedEvent) Summary() string {
+	return fmt.Sprintf("Response truncated after %s: exceeded max response duration of %s",
+		t.Elapsed.Round(time.Second), t.Limit)
+}
+
+// SetMaxResponseDuration sets the default limit applied to requests that do
+// not carry their own. Zero disables the limit. Call before the manager
+// starts handling requests.
+func (m *Manager) SetMaxResponseDuration(d time.Duration) {
+	m.maxDuration = d
+}
+
+// SetTruncationObserver registers a function called whenever a response is
+// cut short. Call before the manager starts handling requests.
+func (m *Manager) SetTruncationObserver(fn func(agentID string, ev *TruncatedEvent)) {
+	m.observeTruncation = fn
+}
+
+// limitFor returns the limit for a request: its own if set, else the default.
+func (m *Manager) limitFor(req *SendRequest) time.Duration {
+	if req.MaxDuration > 0 {
+		return req.MaxDuration
+	}
+	return m.maxDuration
+}
+
+// responseClock measures how long a response has been actively running.
+// Time spent paused does not count against the limit.
+type responseClock struct {
+	limit   time.Duration
+	started time.Time
+	resumed time.Time // start of the current running stretch
+	used    time.Duration
+	paused  bool
+	timer   *time.Timer
+}
+
+// newResponseClock starts a clock. A zero limit yields a clock that never fires.
+func newResponseClock(limit time.Duration, now time.Time) *responseClock {
+	c := &responseClock{limit: limit, started: now, resumed: now}
+	if limit > 0 {
+		c.timer = time.NewTimer(limit)
+	}
+	return c
+}
+
+// C returns the channel that fires when the limit is reached, or nil when
+// the clock is paused or unlimited.
+func (c *responseClock) C() <-chan time.Time {
+	if c.timer == nil || c.paused {
+		return nil
+	}
+	return c.timer.C
+}
+
+// remaining returns the active time left before the limit.
+func (c *responseClock) remaining(now time.Time) time.Duration {
+	used := c.used
+	if !c.paused {
+		used += now.Sub(c.resumed)
+	}
+	return c.limit - used
+}
+
+// pause stops the clock until resume is called.
+func (c *responseClock) pause(now time.Time) {
+	if c.paused || c.timer == nil {
+		return
+	}
+	c.used += now.Sub(c.resumed)
+	c.paused = true
+	c.timer.Stop()
+}
+
+// resume restarts a paused clock with whatever time was left.
+func (c *responseClock) resume(now time.Time) {
+	if !c.paused {
+		return
+	}
+	c.paused = false
+	c.resumed = now
+	c.timer.Reset(max(c.remaining(now), 0))
+}
+
+// observe pauses the clock while the agent waits for a human to approve a
+// tool, and resumes it on the agent's next event for the request.
+func (c *responseClock) observe(resp *Response, now time.Time) {
+	if awaitingApproval(resp) {
+		c.pause(now)
+		return
+	}
+	c.resume(now)
+}
+
+// stop releases the timer.
+func (c *responseClock) stop() {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+}
+
+// truncated builds the event reported when the clock runs out.
+func (c *responseClock) truncated(now time.Time) *TruncatedEvent {
+	elapsed := now.Sub(c.started)
+	return &TruncatedEvent{
+		Reason:  TruncatedReasonMaxDuration,
+		Elapsed: elapsed,
+		Paused:  elapsed - (c.limit - c.remaining(now)),
+		Limit:   c.limit,
+	}
+}
+
+func awaitingApproval(resp *Response) bool {
+	if resp.Event == EventToolApprovalRequest {
+		return true
+	}
+	return resp.Event == EventToolState && resp.ToolState != nil && resp.ToolState.State == "awaiting_approval"
+}
+
+// truncate cancels a request that ran out of time on the agent and returns
+// the final response for the caller.
+func (m *Manager) truncate(ctx context.Context, agent *Connection, requestID string, ev *TruncatedEvent) *Response {
+	log := logctx.FromContext(ctx, m.logger)
+	reason := ev.Reason
+	cancel := &pb.ServerMessage{
+		Payload: &pb.ServerMessage_CancelRequest{
+			CancelRequest: &pb.CancelRequest{RequestId: requestID, Reason: &reason},
+		},
+	}
+	if err := agent.Send(cancel); err != nil {
+		log.Warn("failed to cancel truncated request", "agent_id", agent.ID, "agent_request_id", requestID, "error", err)
+	}
+
+	log.Warn("response truncated",
+		"agent_id", agent.ID,
+		"agent_request_id", requestID,
+		"elapsed", ev.Elapsed,
+		"paused", ev.Paused,
+		"limit", ev.Limit,
+	)
+	if m.observeTruncation != nil {
+		m.observeTruncation(agent.ID, ev)
+	}
+	return &Response{Event: EventTruncated, Error: ev.Reason, Done: true, Truncated: ev}
+}
@@ -0,0 +1,134 @@
+// ABOUTME: Tests for the maximum response duration and its approval pause.
+// ABOUTME: Covers the clock arithmetic and the cancel sent to a truncated agent.
+
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestResponseClock_PauseExcludesApprovalWait(t *testing.T) {
+	start := time.Now()
+	c := newResponseClock(time.Minute, start)
+	defer c.stop()
+
+	c.observe(&Response{Event: EventText}, start.Add(10*time.Second))
+	c.observe(&Response{Event: EventToolApprovalRequest}, start.Add(20*time.Second))
+	if c.C() != nil {
+		t.Fatal("clock still armed while awaiting approval")
+	}
+	// Another approval-state event while paused keeps the clock paused.
+	c.observe(&Response{Event: EventToolState, ToolState: &ToolStateEvent{State: "awaiting_approval"}}, start.Add(5*time.Minute))
+	if got := c.remaining(start.Add(time.Hour)); got != 40*time.Second {
+		t.Errorf("remaining while paused = %v, want 40s", got)
+	}
+
+	c.observe(&Response{Event: EventToolState, ToolState: &ToolStateEvent{State: "running"}}, start.Add(10*time.Minute))
+	if c.C() == nil {
+		t.Fatal("clock not rearmed after approval")
+	}
+	now := start.Add(10*time.Minute + 15*time.Second)
+	if got := c.remaining(now); got != 25*time.Second {
+		t.Errorf("remaining after resume = %v, want 25s", got)
+	}
+	ev := c.truncated(now)
+	if ev.Paused != 9*time.Minute+40*time.Second || ev.Elapsed != 10*time.Minute+15*time.Second {
+		t.Errorf("truncated = %+v, want 9m40s paused of 10m15s", ev)
+	}
+}
+
+func TestResponseClock_Unlimited(t *testing.T) {
+	c := newResponseClock(0, time.Now())
+	c.observe(&Response{Event: EventToolApprovalRequest}, time.Now())
+	c.observe(&Response{Event: EventText}, time.Now())
+	if c.C() != nil {
+		t.Error("unlimited clock has a timer channel")
+	}
+	c.stop()
+}
+
+// sendWithLimit registers an agent and sends it a request with the given limit.
+func sendWithLimit(t *testing.T, manager *Manager, limit time.Duration) (*Connection, *mockStream, string, <-chan *Response) {
+	t.Helper()
+	stream := newMockStream()
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Name: "Test Agent", Stream: stream, Logger: slog.Default()})
+	if err := manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	respChan, err := manager.SendMessage(context.Background(), &SendRequest{ThreadID: "thread-1", Sender: "u", Content: "loop forever", AgentID: "agent-1", MaxDuration: limit})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	sent := stream.getSentMessages()
+	return conn, stream, sent[len(sent)-1].GetSendMessage().GetRequestId(), respChan
+}
+
+func TestManagerTruncatesLongResponse(t *testing.T) {
+	manager := NewManager(slog.Default())
+	manager.SetMaxResponseDuration(time.Hour)
+	var observed *TruncatedEvent
+	manager.SetTruncationObserver(func(agentID string, ev *TruncatedEvent) { observed = ev })
+	conn, stream, requestID, respChan := sendWithLimit(t, manager, 50*time.Millisecond)
+
+	conn.HandleResponse(&pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Text{Text: "partial"}})
+
+	var last *Response
+	for resp := range respChan {
+		last = resp
+	}
+	if last == nil || last.Event != EventTruncated || !last.Done || last.Truncated == nil {
+		t.Fatalf("last response = %+v, want a done truncated event", last)
+	}
+	if last.Truncated.Limit != 50*time.Millisecond || last.Truncated.Reason != TruncatedReasonMaxDuration {
+		t.Errorf("truncated = %+v, want the per-request limit", last.Truncated)
+	}
+	if observed != last.Truncated {
+		t.Error("truncation observer not called with the event")
+	}
+
+	sent := stream.getSentMessages()
+	cancel := sent[len(sent)-1].GetCancelRequest()
+	if cancel == nil || cancel.GetRequestId() != requestID || cancel.GetReason() != TruncatedReasonMaxDuration {
+		t.Errorf("last message to agent = %v, want a cancel for %s", sent[len(sent)-1], requestID)
+	}
+}
+
+func TestManagerResponseClockPausesForApproval(t *testing.T) {
+	manager := NewManager(slog.Default())
+	conn, _, requestID, respChan := sendWithLimit(t, manager, 100*time.Millisecond)
+
+	conn.HandleResponse(&pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_ToolApprovalRequest{
+		ToolApprovalRequest: &pb.ToolApprovalRequest{Id: "t1", Name: "bash"},
+	}})
+	if resp := <-respChan; resp.Event != EventToolApprovalRequest {
+		t.Fatalf("event = %v, want tool approval request", resp.Event)
+	}
+
+	// The human takes longer than the whole limit to approve.
+	select {
+	case resp := <-respChan:
+		t.Fatalf("got %+v while awaiting approval, want the clock paused", resp)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	conn.HandleResponse(&pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_ToolState{
+		ToolState: &pb.ToolStateUpdate{Id: "t1", State: pb.ToolState_TOOL_STATE_RUNNING},
+	}})
+	if resp := <-respChan; resp.Event != EventToolState {
+		t.Fatalf("event = %v, want tool state", resp.Event)
+	}
+
+	select {
+	case resp := <-respChan:
+		if resp.Event != EventTruncated || resp.Truncated.Paused < 300*time.Millisecond {
+			t.Errorf("response = %+v, want truncation excluding the approval wait", resp.Truncated)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("response never truncated after approval")
+	}
+}
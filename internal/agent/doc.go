@@ -15,6 +15,7 @@
 //
 //   - Register(conn): Add a new agent connection
 //   - Unregister(agentID): Remove an agent connection
+//   - UnregisterConnection(conn): Remove conn unless a reconnect superseded it
 //   - SendMessage(ctx, req): Route a message to an agent
 //   - ListAgents(): Get all connected agents
 //   - GetAgent(id): Get a specific agent by ID
@@ -26,7 +27,7 @@
 //	type Connection struct {
 //	    ID           string
 //	    Name         string
-//	    Capabilities []string
+//	    capabilities []string // read with Capabilities()
 //	    stream       pb.CovenControl_AgentStreamServer
 //	    pending      map[string]chan *pb.MessageResponse
 //	}
@@ -53,6 +54,62 @@
 //
 // When a response event arrives, it's routed to the correct channel.
 //
+// # Maximum Response Duration
+//
+// Each request may carry a MaxDuration (falling back to the manager default
+// from SetMaxResponseDuration). When it runs out, the manager sends the agent
+// a CancelRequest and ends the stream with a Done EventTruncated response.
+// The clock pauses while a tool awaits human approval and resumes on the
+// agent's next event for the request.
+//
+// # Workspaces
+//
+// Agents list the workspaces they can work in (repository checkouts, say) in
+// their registration metadata. A request may name one in its Workspace;
+// SendMessage refuses workspaces the agent did not register with a
+// WorkspaceError, and the agent handles an empty Workspace in its default.
+//
+// # Idle Timeout
+//
+// A request that hears nothing from its agent for the SetIdleTimeout window
+// is canceled at the agent and ends with a Done EventError whose Code is
+// ErrorCodeAgentTimeout. Every response event for the request and every
+// Connection.Heartbeat restarts the window; like the response clock it is
+// paused while a tool awaits approval.
+//
+// # Oversized Responses
+//
+// The gRPC layer refuses agent responses over its size limit by calling
+// Connection.RejectOversized, which ends the request with a Done EventError
+// whose Code is ErrorCodePayloadTooLarge and cancels it at the agent. The
+// agent stays connected and its other requests carry on.
+//
+// # Capability Routing
+//
+// SelectByCapability picks an agent for sends addressed to a capability
+// rather than an agent. The manager counts each agent's in-flight requests
+// and keeps a moving average of its time to first response. Paused agents
+// are skipped, and agents flagged by the SetDegradedCheck function are used
+// only when no healthy candidate remains. The fewest in-flight requests wins,
+// then the lower latency, then a random pick. An agent's max_concurrent
+// metadata hint caps what it is assigned; when every candidate is full the
+// call waits for a request to finish.
+//
+// An agent's capabilities start as the set it registered with.
+// UpdateCapabilities replaces them when an admin changes its principal's
+// grants, so routing and ListAgents use the new set from then on; agents
+// advertising the capability_update protocol feature are also sent a
+// CapabilityUpdate. Requests already in flight are not affected.
+//
+// # Tag Routing
+//
+// SelectByTags picks the agent for sends addressed to key=value tags. The
+// send goes to the one connected agent carrying every tag; when several do
+// it fails with an *AmbiguousTagsError naming them, unless the caller allows
+// any, in which case the capability routing rules choose among them.
+// ParseTagFilter and Metadata.MatchesTags serve the same matching to agent
+// listings.
+//
 // # Heartbeat Monitoring
 //
 // Agents send periodic heartbeats to indicate they're alive:
@@ -71,6 +128,19 @@
 //  3. If agent reconnects within grace period, state is restored
 //  4. If grace period expires, pending requests are failed
 //
+// # Reconnect Handoff
+//
+// Registering an agent ID that is already connected by the same principal
+// supersedes the old connection instead of failing. The old connection is
+// marked superseded, its pending requests move to the new connection when
+// the new one advertises the resume feature (ResumeHandedOver re-sends them
+// once the agent is welcomed) and otherwise end with a Done EventError whose
+// Code is ErrorCodeAgentSuperseded, which callers may retry. The agent is
+// sent Goodbye on the old stream before the new connection is installed;
+// anything later addressed to the old connection goes to the new one, and
+// UnregisterConnection leaves the new one alone when the old stream ends.
+// AgentInfo.SupersedeCount counts these handoffs per agent ID.
+//
 // # Agent Metadata
 //
 // Agents provide metadata during registration:
@@ -82,6 +152,15 @@
 //   - backend: LLM backend (e.g., "claude")
 //   - workspaces: Available workspace paths
 //   - protocol_features: Supported protocol capabilities
+//   - max_concurrent: Most requests the agent wants at once, for routing
+//   - tags: key=value labels for filtering and routing (env=prod, team=infra)
+//
+// NormalizeMetadata validates these before they reach the Connection or the
+// principal's stored metadata. Values with the wrong format (relative paths,
+// invalid hostnames, control characters, unknown protocol features) are
+// rejected and dropped; values over the MetadataLimits are truncated and end
+// in TruncationMarker. Each change is reported as a MetadataIssue so the
+// caller can tell the two apart when logging.
 //
 // # Thread Safety
 //
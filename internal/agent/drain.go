@@ -0,0 +1,64 @@
+// ABOUTME: Draining before shutdown: refuse new requests and let in-flight responses finish.
+// ABOUTME: Requests still running when the drain deadline passes are canceled with reason gateway_draining.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+)
+
+// ErrDraining indicates the gateway is shutting down and accepts no new requests.
+var ErrDraining = coverr.New(coverr.GatewayDraining, "gateway is draining")
+
+// DrainReason is the cancel reason given to requests cut off by a drain deadline.
+const DrainReason = string(coverr.GatewayDraining)
+
+// Draining reports whether Drain has been called.
+func (m *Manager) Draining() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.draining
+}
+
+// Drain stops the manager accepting new requests and waits for in-flight
+// ones to finish. Requests still queued for a slot fail with ErrDraining.
+// If ctx ends first, the remaining requests are canceled: each agent is
+// sent a CancelRequest and each caller gets a canceled response with
+// DrainReason. It returns ctx's error in that case.
+func (m *Manager) Drain(ctx context.Context) error {
+	m.mu.Lock()
+	m.draining = true
+	for agentID := range m.queues {
+		m.failQueueLocked(agentID, ErrDraining)
+	}
+	m.mu.Unlock()
+
+	for {
+		m.mu.RLock()
+		busy := make(map[string]int)
+		for agentID, load := range m.load {
+			if load.inFlight > 0 {
+				busy[agentID] = load.inFlight
+			}
+		}
+		freed := m.loadFreed
+		m.mu.RUnlock()
+
+		if len(busy) == 0 {
+			m.logger.Info("agents drained")
+			return nil
+		}
+		m.logger.Info("draining agents", "in_flight", busy)
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			m.logger.Warn("drain deadline passed, canceling in-flight requests", "in_flight", busy)
+			m.drainOnce.Do(func() { close(m.drainExpired) })
+			return fmt.Errorf("draining agents: %w", ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// ABOUTME: Tests for draining the agent manager before shutdown.
+// ABOUTME: Covers refusing new requests, waiting for Done, and canceling an agent that never finishes.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestManagerDrain_WaitsForInFlight(t *testing.T) {
+	manager := NewManager(slog.Default())
+	conn, _, requestID, respChan := sendWithLimit(t, manager, 0)
+
+	drained := make(chan error, 1)
+	go func() { drained <- manager.Drain(context.Background()) }()
+
+	// New requests are refused as soon as the drain starts.
+	deadline := time.Now().Add(time.Second)
+	for !manager.Draining() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := manager.SendMessage(context.Background(), &SendRequest{AgentID: "agent-1", Content: "more"}); !errors.Is(err, ErrDraining) {
+		t.Errorf("SendMessage while draining = %v, want ErrDraining", err)
+	}
+	select {
+	case err := <-drained:
+		t.Fatalf("Drain returned %v with a request in flight", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	conn.HandleResponse(&pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "ok"}}})
+	var last *Response
+	for resp := range respChan {
+		last = resp
+	}
+	if last == nil || last.Event != EventDone {
+		t.Errorf("last response = %+v, want the agent's done", last)
+	}
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Errorf("Drain = %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Drain did not return after the request finished")
+	}
+}
+
+func TestManagerDrain_CancelsAgentThatNeverFinishes(t *testing.T) {
+	manager := NewManager(slog.Default())
+	var outcomes []bool
+	manager.SetOutcomeObserver(func(_ string, ok bool) { outcomes = append(outcomes, ok) })
+	conn, stream, requestID, respChan := sendWithLimit(t, manager, 0)
+	conn.HandleResponse(&pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Text{Text: "partial"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := manager.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain = %v, want the deadline", err)
+	}
+
+	var last *Response
+	for resp := range respChan {
+		last = resp
+	}
+	if last == nil || last.Event != EventCanceled || !last.Done || last.Error != DrainReason {
+		t.Fatalf("last response = %+v, want a done canceled event with reason %s", last, DrainReason)
+	}
+	if len(outcomes) != 0 {
+		t.Errorf("outcomes = %v, a drained request must not count against the agent", outcomes)
+	}
+
+	sent := stream.getSentMessages()
+	cancelReq := sent[len(sent)-1].GetCancelRequest()
+	if cancelReq == nil || cancelReq.GetRequestId() != requestID || cancelReq.GetReason() != DrainReason {
+		t.Errorf("last message to agent = %v, want a cancel for %s", sent[len(sent)-1], requestID)
+	}
+	if n := manager.InFlight("agent-1"); n != 0 {
+		t.Errorf("InFlight = %d after drain, want 0", n)
+	}
+}
+
+func TestManagerDrain_Idle(t *testing.T) {
+	manager := NewManager(slog.Default())
+	if err := manager.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain with nothing in flight = %v", err)
+	}
+	if _, err := manager.ResumeRequest(context.Background(), "req-1", &SendRequest{AgentID: "agent-1"}); !errors.Is(err, ErrDraining) {
+		t.Errorf("ResumeRequest after drain = %v, want ErrDraining", err)
+	}
+}
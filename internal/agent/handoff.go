@@ -0,0 +1,186 @@
+// ABOUTME: Hands an agent ID over from its connection to a newer one when the agent reconnects.
+// ABOUTME: Moves in-flight requests to the new connection when it can resume them, else fails them retriably.
+
+package agent
+
+import (
+	"github.com/2389/coven-gateway/internal/coverr"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// ErrorCodeAgentSuperseded is the Code of the error response that ends a
+// request the agent's new connection could not take over. The request
+// never finished, so clients may retry it.
+const ErrorCodeAgentSuperseded = coverr.AgentSuperseded
+
+// GoodbyeReasonSuperseded is the Goodbye reason sent on a connection
+// replaced by a newer one for the same agent ID.
+const GoodbyeReasonSuperseded = "superseded"
+
+// handOffLocked retires old in favor of next, which is about to be
+// installed under the same agent ID: old is marked superseded, its pending
+// requests move to next (or fail retriably), and the agent is told Goodbye
+// on old's stream. Requests and sends that still reach old are forwarded
+// to next from then on.
+func (m *Manager) handOffLocked(old, next *Connection) {
+	moved, dropped := old.supersede(next)
+	m.supersedes[old.ID]++
+
+	goodbye := &pb.ServerMessage{
+		Payload: &pb.ServerMessage_Goodbye{Goodbye: &pb.Goodbye{Reason: GoodbyeReasonSuperseded}},
+	}
+	if old.stream != nil {
+		if err := old.stream.Send(goodbye); err != nil {
+			m.logger.Warn("failed to send goodbye to superseded connection", "agent_id", old.ID, "error", err)
+		}
+	}
+	// Wake requests waiting on old's heartbeats so they follow next's.
+	old.Heartbeat()
+
+	m.logger.Info("=== AGENT SUPERSEDED ===",
+		"agent_id", old.ID,
+		"moved_requests", moved,
+		"failed_requests", dropped,
+		"supersede_count", m.supersedes[old.ID],
+	)
+}
+
+// ResumeHandedOver re-sends to conn the requests it took over from the
+// connection it superseded, and returns how many were sent. Call it once
+// the agent has been welcomed on conn; a request that can't be re-sent ends
+// as if the agent had disconnected.
+func (m *Manager) ResumeHandedOver(conn *Connection) int {
+	conn.mu.Lock()
+	resumes := conn.handedOver
+	conn.handedOver = nil
+	conn.mu.Unlock()
+
+	sent := 0
+	for _, r := range resumes {
+		msg := &pb.ServerMessage{Payload: &pb.ServerMessage_ResumeRequest{ResumeRequest: r}}
+		if err := conn.Send(msg); err != nil {
+			m.logger.Warn("failed to resume handed-over request", "agent_id", conn.ID, "request_id", r.GetRequestId(), "error", err)
+			conn.CloseRequest(r.GetRequestId())
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+// supersededResponse ends a request that a handoff failed.
+func supersededResponse() *Response {
+	return &Response{
+		Event: EventError,
+		Error: "agent reconnected before the request finished; retry it",
+		Code:  ErrorCodeAgentSuperseded,
+		Done:  true,
+	}
+}
+
+// Superseded reports whether a newer connection for the same agent ID has
+// replaced this one.
+func (c *Connection) Superseded() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.superseded
+}
+
+// HasRequest reports whether requestID is pending on this connection.
+func (c *Connection) HasRequest(requestID string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.pending[requestID]
+	return ok
+}
+
+// current returns the connection now serving this one's agent ID: c itself
+// unless it has been superseded.
+func (c *Connection) current() *Connection {
+	for {
+		c.mu.RLock()
+		next := c.successor
+		c.mu.RUnlock()
+		if next == nil {
+			return c
+		}
+		c = next
+	}
+}
+
+// startRequest sends msg (if any) and registers the request's response
+// channel in one step, so a handoff sees the request either entirely on this
+// connection or not at all. On a superseded connection it starts the
+// request on the successor instead.
+func (c *Connection) startRequest(requestID string, msg *pb.ServerMessage, resume *pb.ResumeRequest) (<-chan *pb.MessageResponse, error) {
+	c.mu.Lock()
+	if c.superseded {
+		next := c.successor
+		c.mu.Unlock()
+		return next.startRequest(requestID, msg, resume)
+	}
+	defer c.mu.Unlock()
+
+	if msg != nil {
+		if c.stream == nil {
+			return nil, ErrNilStream
+		}
+		if err := c.stream.Send(msg); err != nil {
+			return nil, err
+		}
+	}
+	ch := make(chan *pb.MessageResponse, 16)
+	c.pending[requestID] = ch
+	if resume != nil {
+		c.resumes[requestID] = resume
+	}
+	return ch, nil
+}
+
+// supersede marks c as replaced by next and empties its pending requests:
+// those next can resume move there, queued for ResumeHandedOver, and the
+// rest are closed and remembered as dropped.
+func (c *Connection) supersede(next *Connection) (moved, dropped int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next.mu.Lock()
+	defer next.mu.Unlock()
+
+	c.superseded = true
+	c.successor = next
+	c.handedOver = nil
+	canResume := next.Metadata.HasFeature(FeatureResume)
+	for requestID, ch := range c.pending {
+		if resume := c.resumes[requestID]; canResume && resume != nil {
+			next.pending[requestID] = ch
+			next.resumes[requestID] = resume
+			next.handedOver = append(next.handedOver, resume)
+			moved++
+		} else {
+			if c.dropped == nil {
+				c.dropped = make(map[string]bool)
+			}
+			c.dropped[requestID] = true
+			close(ch)
+			dropped++
+		}
+		delete(c.pending, requestID)
+		delete(c.resumes, requestID)
+	}
+	return moved, dropped
+}
+
+// wasDropped reports whether a handoff failed requestID rather than moving
+// it, on this connection or any that succeeded it.
+func (c *Connection) wasDropped(requestID string) bool {
+	for c != nil {
+		c.mu.RLock()
+		dropped, next := c.dropped[requestID], c.successor
+		c.mu.RUnlock()
+		if dropped {
+			return true
+		}
+		c = next
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+// ABOUTME: Tests for handing an agent ID over to a reconnecting connection: Goodbye on the old
+// ABOUTME: stream, retriable failure or resumption of in-flight requests, and racing sends.
+
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func newHandoffConn(stream *mockStream, features ...string) *Connection {
+	return NewConnection(ConnectionParams{
+		ID:          "agent-1",
+		Name:        "Test Agent",
+		PrincipalID: "p1",
+		Metadata:    &Metadata{ProtocolFeatures: features},
+		Stream:      stream,
+		Logger:      slog.Default(),
+	})
+}
+
+func TestManagerHandOffFailsRequestsRetriably(t *testing.T) {
+	manager := NewManager(slog.Default())
+	stream1, stream2 := newMockStream(), newMockStream()
+	old := newHandoffConn(stream1)
+	if err := manager.Register(old); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	respChan, err := manager.SendMessage(context.Background(), &SendRequest{ThreadID: "t1", Sender: "u", Content: "hi", AgentID: "agent-1"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	next := newHandoffConn(stream2)
+	if err := manager.Register(next); err != nil {
+		t.Fatalf("re-Register: %v", err)
+	}
+
+	resp := <-respChan
+	if resp.Event != EventError || !resp.Done || resp.Code != ErrorCodeAgentSuperseded {
+		t.Fatalf("response = %+v, want a done agent_superseded error", resp)
+	}
+	sent := stream1.getSentMessages()
+	if goodbye := sent[len(sent)-1].GetGoodbye(); goodbye.GetReason() != GoodbyeReasonSuperseded {
+		t.Errorf("last message on old stream = %v, want a superseded goodbye", sent[len(sent)-1])
+	}
+	if !old.Superseded() || next.Superseded() {
+		t.Errorf("superseded = %v/%v, want old only", old.Superseded(), next.Superseded())
+	}
+	if got, _ := manager.GetAgent("agent-1"); got != next {
+		t.Error("agent-1 does not resolve to the new connection")
+	}
+	if n := manager.InFlight("agent-1"); n != 0 {
+		t.Errorf("in flight = %d, want 0", n)
+	}
+
+	// The old stream ending must not take the new connection with it.
+	manager.UnregisterConnection(old)
+	agents := manager.ListAgents()
+	if len(agents) != 1 || agents[0].SupersedeCount != 1 {
+		t.Fatalf("agents = %+v, want agent-1 with supersede count 1", agents)
+	}
+	manager.UnregisterConnection(next)
+	if len(manager.ListAgents()) != 0 {
+		t.Error("agent still listed after its current connection unregistered")
+	}
+}
+
+func TestManagerHandOffMovesResumableRequests(t *testing.T) {
+	manager := NewManager(slog.Default())
+	stream1, stream2 := newMockStream(), newMockStream()
+	if err := manager.Register(newHandoffConn(stream1, FeatureResume)); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	respChan, err := manager.SendMessage(context.Background(), &SendRequest{ThreadID: "t1", Sender: "u", Content: "hi", AgentID: "agent-1"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	requestID := stream1.getSentMessages()[0].GetSendMessage().GetRequestId()
+
+	next := newHandoffConn(stream2, FeatureResume)
+	if err := manager.Register(next); err != nil {
+		t.Fatalf("re-Register: %v", err)
+	}
+	if len(stream2.getSentMessages()) != 0 {
+		t.Fatal("request re-sent before ResumeHandedOver")
+	}
+	if n := manager.ResumeHandedOver(next); n != 1 {
+		t.Fatalf("ResumeHandedOver = %d, want 1", n)
+	}
+	resume := stream2.getSentMessages()[0].GetResumeRequest()
+	if resume.GetRequestId() != requestID || resume.GetContent() != "hi" || resume.GetThreadId() != "t1" {
+		t.Fatalf("resume = %v, want request %s re-sent", resume, requestID)
+	}
+
+	// The caller that started on the old connection hears the new one.
+	next.HandleResponse(&pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "ok"}}})
+	if resp := <-respChan; resp.Event != EventDone {
+		t.Fatalf("response = %+v, want done", resp)
+	}
+	if _, open := <-respChan; open {
+		t.Error("response channel still open after done")
+	}
+	if next.HasRequest(requestID) {
+		t.Error("finished request still pending on the new connection")
+	}
+}
+
+// TestManagerHandOffRacesSendMessage reconnects while sends are in flight:
+// every request must reach the new connection exactly once, as either its
+// original SendMessage or a ResumeRequest, and finish there.
+func TestManagerHandOffRacesSendMessage(t *testing.T) {
+	const senders = 50
+	manager := NewManager(slog.Default())
+	stream1, stream2 := newMockStream(), newMockStream()
+	if err := manager.Register(newHandoffConn(stream1, FeatureResume)); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	next := newHandoffConn(stream2, FeatureResume)
+
+	var wg sync.WaitGroup
+	respChans := make(chan (<-chan *Response), senders)
+	for range senders {
+		wg.Go(func() {
+			ch, err := manager.SendMessage(context.Background(), &SendRequest{ThreadID: "t1", Sender: "u", Content: "hi", AgentID: "agent-1"})
+			if err != nil {
+				t.Errorf("SendMessage: %v", err)
+				return
+			}
+			respChans <- ch
+		})
+	}
+	wg.Go(func() {
+		if err := manager.Register(next); err != nil {
+			t.Errorf("re-Register: %v", err)
+		}
+	})
+	wg.Wait()
+	close(respChans)
+	manager.ResumeHandedOver(next)
+
+	seen := make(map[string]int)
+	for _, msg := range stream2.getSentMessages() {
+		switch {
+		case msg.GetSendMessage() != nil:
+			seen[msg.GetSendMessage().GetRequestId()]++
+		case msg.GetResumeRequest() != nil:
+			seen[msg.GetResumeRequest().GetRequestId()]++
+		}
+	}
+	if len(seen) != senders {
+		t.Fatalf("new connection saw %d requests, want %d", len(seen), senders)
+	}
+	for requestID, n := range seen {
+		if n != 1 {
+			t.Errorf("request %s reached the new connection %d times", requestID, n)
+		}
+		next.HandleResponse(&pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "ok"}}})
+	}
+	for ch := range respChans {
+		if resp := <-ch; resp.Event != EventDone {
+			t.Errorf("response = %+v, want done", resp)
+		}
+	}
+	sent := stream1.getSentMessages()
+	if sent[len(sent)-1].GetGoodbye() == nil {
+		t.Error("old stream's last message is not goodbye")
+	}
+}
@@ -0,0 +1,217 @@
+// ABOUTME: Heartbeat-driven agent health: round-trip latency and clock skew from acked
+// ABOUTME: heartbeats, kept in a bounded window, and a healthy/degraded/stale state.
+
+package agent
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// FeatureHeartbeatAck marks agents that handle HeartbeatAck and report the
+// round trip in their next heartbeat.
+const FeatureHeartbeatAck = "heartbeat_ack"
+
+// HealthState summarizes an agent's heartbeats.
+type HealthState string
+
+const (
+	HealthHealthy  HealthState = "healthy"
+	HealthDegraded HealthState = "degraded" // slow round trips or heartbeats running late
+	HealthStale    HealthState = "stale"    // no heartbeat for longer than StaleAfter
+)
+
+// latencyWindowSize is how many round-trip samples each connection keeps.
+const latencyWindowSize = 100
+
+// maxHeartbeatRoundTrip discards round trips no real heartbeat takes, such
+// as those from an agent clock that jumped between heartbeat and ack.
+const maxHeartbeatRoundTrip = 10 * time.Minute
+
+// Default health thresholds, used when HealthThresholds fields are zero.
+const (
+	DefaultDegradedLatency = time.Second
+	DefaultDegradedAfter   = time.Minute
+	DefaultStaleAfter      = 90 * time.Second
+)
+
+// HealthThresholds decide an agent's HealthState.
+type HealthThresholds struct {
+	DegradedLatency time.Duration // p95 round trip above this is degraded
+	DegradedAfter   time.Duration // no heartbeat for this long is degraded
+	StaleAfter      time.Duration // no heartbeat for this long is stale
+}
+
+// withDefaults fills in zero thresholds.
+func (t HealthThresholds) withDefaults() HealthThresholds {
+	if t.DegradedLatency <= 0 {
+		t.DegradedLatency = DefaultDegradedLatency
+	}
+	if t.DegradedAfter <= 0 {
+		t.DegradedAfter = DefaultDegradedAfter
+	}
+	if t.StaleAfter <= 0 {
+		t.StaleAfter = DefaultStaleAfter
+	}
+	return t
+}
+
+// HeartbeatHealth is an agent's health as seen through its heartbeats.
+type HeartbeatHealth struct {
+	State         HealthState
+	Reason        string    // why the state is not healthy; empty when healthy
+	LastHeartbeat time.Time // zero before the first heartbeat
+	LatencyP50    time.Duration
+	LatencyP95    time.Duration
+	Samples       int // round trips in the window; zero for agents without acks
+	// ClockSkew is the gateway clock minus the agent clock, from the most
+	// recent round trip.
+	ClockSkew time.Duration
+}
+
+// heartbeatStats is a connection's heartbeat history. Samples form a ring
+// buffer so memory stays bounded however long the agent is connected.
+type heartbeatStats struct {
+	mu        sync.Mutex
+	since     time.Time // connection time, the baseline before any heartbeat
+	last      time.Time
+	samples   [latencyWindowSize]time.Duration
+	count     int // samples recorded, capped at latencyWindowSize
+	next      int // ring position for the next sample
+	skew      time.Duration
+	ackAgent  int64       // agent timestamp echoed by the last ack sent
+	ackServer int64       // gateway timestamp of the last ack sent
+	state     HealthState // last state reported by CheckHealth
+}
+
+// ObserveHeartbeat records a heartbeat received at now. If it reports the
+// round trip of the last ack sent, the latency and clock skew are sampled.
+func (c *Connection) ObserveHeartbeat(hb *pb.Heartbeat, now time.Time) {
+	s := &c.heartbeat
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = now
+
+	sent, received := hb.GetAckTimestampMs(), hb.GetAckReceivedMs()
+	if sent == 0 || sent != s.ackAgent {
+		return
+	}
+	rtt := time.Duration(received-sent) * time.Millisecond
+	if rtt < 0 || rtt > maxHeartbeatRoundTrip {
+		return
+	}
+	s.samples[s.next] = rtt
+	s.next = (s.next + 1) % latencyWindowSize
+	s.count = min(s.count+1, latencyWindowSize)
+	// The agent's clock read the midpoint of the round trip as (sent+received)/2
+	// while the gateway's read ackServer.
+	s.skew = time.Duration(s.ackServer-(sent+received)/2) * time.Millisecond
+	s.ackAgent = 0
+}
+
+// AckHeartbeat returns the ack for a heartbeat, remembering it so the round
+// trip the agent reports next can be matched to it.
+func (c *Connection) AckHeartbeat(hb *pb.Heartbeat, now time.Time) *pb.ServerMessage {
+	s := &c.heartbeat
+	s.mu.Lock()
+	s.ackAgent = hb.GetTimestampMs()
+	s.ackServer = now.UnixMilli()
+	s.mu.Unlock()
+	return &pb.ServerMessage{Payload: &pb.ServerMessage_HeartbeatAck{HeartbeatAck: &pb.HeartbeatAck{
+		TimestampMs:       hb.GetTimestampMs(),
+		ServerTimestampMs: now.UnixMilli(),
+	}}}
+}
+
+// Health reports the connection's heartbeat health at now.
+func (c *Connection) Health(t HealthThresholds, now time.Time) HeartbeatHealth {
+	t = t.withDefaults()
+	s := &c.heartbeat
+	s.mu.Lock()
+	h := HeartbeatHealth{LastHeartbeat: s.last, Samples: s.count, ClockSkew: s.skew}
+	window := slices.Clone(s.samples[:s.count])
+	since := s.since
+	s.mu.Unlock()
+
+	if len(window) > 0 {
+		slices.Sort(window)
+		h.LatencyP50 = percentile(window, 50)
+		h.LatencyP95 = percentile(window, 95)
+	}
+	if !h.LastHeartbeat.IsZero() {
+		since = h.LastHeartbeat
+	}
+	silent := now.Sub(since)
+	switch {
+	case silent > t.StaleAfter:
+		h.State, h.Reason = HealthStale, "no heartbeat for "+silent.Round(time.Second).String()
+	case silent > t.DegradedAfter:
+		h.State, h.Reason = HealthDegraded, "no heartbeat for "+silent.Round(time.Second).String()
+	case h.LatencyP95 > t.DegradedLatency:
+		h.State, h.Reason = HealthDegraded, "p95 heartbeat latency "+h.LatencyP95.String()+" over "+t.DegradedLatency.String()
+	default:
+		h.State = HealthHealthy
+	}
+	return h
+}
+
+// percentile returns the p-th percentile of sorted, by nearest rank.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank-1, 0)]
+}
+
+// SetHealthThresholds sets the thresholds agent health is judged by. It may
+// be called while agents are connected; the next check uses the new ones.
+func (m *Manager) SetHealthThresholds(t HealthThresholds) {
+	m.mu.Lock()
+	m.healthThresholds = t
+	m.mu.Unlock()
+}
+
+// SetHealthObserver registers a function called whenever an agent's health
+// state changes, with the state it left. Call before agents connect.
+func (m *Manager) SetHealthObserver(fn func(agentID string, from HealthState, h HeartbeatHealth)) {
+	m.observeHealth = fn
+}
+
+// CheckHealth re-evaluates the health of every connected agent at now,
+// reporting state changes to the health observer.
+func (m *Manager) CheckHealth(now time.Time) {
+	m.mu.RLock()
+	conns := make([]*Connection, 0, len(m.agents))
+	for _, conn := range m.agents {
+		conns = append(conns, conn)
+	}
+	m.mu.RUnlock()
+
+	for _, conn := range conns {
+		m.CheckAgentHealth(conn, now)
+	}
+}
+
+// CheckAgentHealth re-evaluates one connection's health at now, reporting a
+// state change to the health observer, and returns it.
+func (m *Manager) CheckAgentHealth(conn *Connection, now time.Time) HeartbeatHealth {
+	m.mu.RLock()
+	thresholds := m.healthThresholds
+	m.mu.RUnlock()
+	h := conn.Health(thresholds, now)
+	s := &conn.heartbeat
+	s.mu.Lock()
+	from := s.state
+	s.state = h.State
+	s.mu.Unlock()
+
+	// A new connection starts out healthy without it being news.
+	if from == "" {
+		from = HealthHealthy
+	}
+	if from != h.State && m.observeHealth != nil {
+		m.observeHealth(conn.ID, from, h)
+	}
+	return h
+}
@@ -0,0 +1,152 @@
+// ABOUTME: Tests for heartbeat-driven health: acked round trips, clock skew, the bounded
+// ABOUTME: latency window, the healthy/degraded/stale states and transition reporting.
+
+package agent
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// roundTrip has conn ack a heartbeat the agent sent at agentSent (agent
+// clock, ms) and answers with one reporting the ack arrived after rtt.
+func roundTrip(conn *Connection, gatewayNow time.Time, agentSent int64, rtt time.Duration) {
+	ack := conn.AckHeartbeat(&pb.Heartbeat{TimestampMs: agentSent}, gatewayNow).GetHeartbeatAck()
+	received := ack.GetTimestampMs() + rtt.Milliseconds()
+	conn.ObserveHeartbeat(&pb.Heartbeat{
+		TimestampMs:    received,
+		AckTimestampMs: ack.GetTimestampMs(),
+		AckReceivedMs:  received,
+	}, gatewayNow.Add(rtt))
+}
+
+func TestHeartbeatRoundTripAndSkew(t *testing.T) {
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Logger: slog.Default()})
+	now := time.UnixMilli(1_000_000)
+
+	// The agent's clock runs 5s behind the gateway's.
+	roundTrip(conn, now, now.UnixMilli()-5000, 40*time.Millisecond)
+
+	h := conn.Health(HealthThresholds{}, now.Add(time.Second))
+	if h.Samples != 1 || h.LatencyP50 != 40*time.Millisecond || h.LatencyP95 != 40*time.Millisecond {
+		t.Errorf("latency = %d samples p50 %v p95 %v, want 1 sample of 40ms", h.Samples, h.LatencyP50, h.LatencyP95)
+	}
+	// The gateway acked at now; the agent's midpoint read now-5s+20ms.
+	if want := 5*time.Second - 20*time.Millisecond; h.ClockSkew != want {
+		t.Errorf("ClockSkew = %v, want %v", h.ClockSkew, want)
+	}
+	if h.State != HealthHealthy || !h.LastHeartbeat.Equal(now.Add(40*time.Millisecond)) {
+		t.Errorf("health = %+v, want healthy with the last heartbeat at the reply", h)
+	}
+}
+
+func TestHeartbeatIgnoresUnmatchedAcks(t *testing.T) {
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Logger: slog.Default()})
+	now := time.UnixMilli(1_000_000)
+
+	// No ack sent yet, so reported round trips have nothing to match.
+	conn.ObserveHeartbeat(&pb.Heartbeat{TimestampMs: 10, AckTimestampMs: 5, AckReceivedMs: 9}, now)
+	// A round trip is sampled once, not again for a repeated report.
+	roundTrip(conn, now, 100, 10*time.Millisecond)
+	conn.ObserveHeartbeat(&pb.Heartbeat{TimestampMs: 200, AckTimestampMs: 100, AckReceivedMs: 110}, now)
+	// A negative round trip means the agent's clock jumped back.
+	roundTrip(conn, now, 300, -time.Second)
+
+	if h := conn.Health(HealthThresholds{}, now); h.Samples != 1 {
+		t.Errorf("Samples = %d, want 1", h.Samples)
+	}
+}
+
+func TestHeartbeatLatencyWindowIsBounded(t *testing.T) {
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Logger: slog.Default()})
+	now := time.UnixMilli(1_000_000)
+
+	// 100 slow round trips, then a full window of fast ones pushes them out.
+	for i := range latencyWindowSize {
+		roundTrip(conn, now, int64(i), 5*time.Second)
+	}
+	for i := range latencyWindowSize {
+		roundTrip(conn, now, int64(1000+i), time.Duration(i+1)*time.Millisecond)
+	}
+
+	h := conn.Health(HealthThresholds{}, now)
+	if h.Samples != latencyWindowSize {
+		t.Errorf("Samples = %d, want %d", h.Samples, latencyWindowSize)
+	}
+	if h.LatencyP50 != 50*time.Millisecond || h.LatencyP95 != 95*time.Millisecond {
+		t.Errorf("p50 %v p95 %v, want 50ms and 95ms", h.LatencyP50, h.LatencyP95)
+	}
+}
+
+func TestHealthStates(t *testing.T) {
+	thresholds := HealthThresholds{DegradedLatency: 200 * time.Millisecond, DegradedAfter: time.Minute, StaleAfter: 2 * time.Minute}
+	now := time.UnixMilli(1_000_000)
+
+	fast := NewConnection(ConnectionParams{ID: "fast", Logger: slog.Default()})
+	roundTrip(fast, now, 1, 50*time.Millisecond)
+	slow := NewConnection(ConnectionParams{ID: "slow", Logger: slog.Default()})
+	roundTrip(slow, now, 1, 500*time.Millisecond)
+
+	tests := []struct {
+		name string
+		conn *Connection
+		at   time.Time
+		want HealthState
+	}{
+		{"recent fast heartbeats", fast, now.Add(30 * time.Second), HealthHealthy},
+		{"slow round trips", slow, now.Add(30 * time.Second), HealthDegraded},
+		{"heartbeats running late", fast, now.Add(90 * time.Second), HealthDegraded},
+		{"silent past stale_after", fast, now.Add(3 * time.Minute), HealthStale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.conn.Health(thresholds, tt.at)
+			if h.State != tt.want {
+				t.Errorf("State = %s (%s), want %s", h.State, h.Reason, tt.want)
+			}
+			if (h.State == HealthHealthy) != (h.Reason == "") {
+				t.Errorf("Reason = %q for state %s", h.Reason, h.State)
+			}
+		})
+	}
+}
+
+func TestManagerReportsHealthTransitions(t *testing.T) {
+	manager := NewManager(slog.Default())
+	manager.SetHealthThresholds(HealthThresholds{DegradedAfter: time.Minute, StaleAfter: 2 * time.Minute})
+	type transition struct{ from, to HealthState }
+	var got []transition
+	manager.SetHealthObserver(func(agentID string, from HealthState, h HeartbeatHealth) {
+		got = append(got, transition{from, h.State})
+	})
+
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Name: "Test Agent", Stream: newMockStream(), Logger: slog.Default()})
+	if err := manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	start := time.Now()
+	manager.CheckHealth(start)
+	manager.CheckHealth(start.Add(90 * time.Second))
+	manager.CheckHealth(start.Add(100 * time.Second))
+	manager.CheckHealth(start.Add(3 * time.Minute))
+	conn.ObserveHeartbeat(&pb.Heartbeat{TimestampMs: 1}, start.Add(3*time.Minute))
+	manager.CheckHealth(start.Add(3 * time.Minute))
+
+	want := []transition{{HealthHealthy, HealthDegraded}, {HealthDegraded, HealthStale}, {HealthStale, HealthHealthy}}
+	if len(got) != len(want) {
+		t.Fatalf("transitions = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("transition %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	agents := manager.ListAgents()
+	if len(agents) != 1 || agents[0].Health.LastHeartbeat.IsZero() {
+		t.Errorf("ListAgents health = %+v, want the last heartbeat", agents)
+	}
+}
@@ -0,0 +1,122 @@
+// ABOUTME: Cancels in-flight requests whose agent goes silent for longer than the idle timeout.
+// ABOUTME: Any response event or heartbeat from the agent restarts the timer.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/logctx"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// DefaultIdleTimeout is how long a request may go without hearing from its
+// agent when agents.idle_timeout is not set.
+const DefaultIdleTimeout = 2 * time.Minute
+
+// ErrorCodeAgentTimeout is the Code of the error response that ends a
+// request whose agent went silent; it is also the cancel reason sent to
+// the agent.
+const ErrorCodeAgentTimeout = coverr.AgentTimeout
+
+// SetIdleTimeout sets how long a request may go without a response event or
+// heartbeat from its agent before it is canceled. Zero disables it. Call
+// before the manager starts handling requests.
+func (m *Manager) SetIdleTimeout(d time.Duration) {
+	m.idleTimeout = d
+}
+
+// Heartbeat records that the agent is still alive, restarting the idle
+// timer of every request it has in flight.
+func (c *Connection) Heartbeat() {
+	c.beatMu.Lock()
+	if c.beat != nil {
+		close(c.beat)
+		c.beat = nil
+	}
+	c.beatMu.Unlock()
+}
+
+// heartbeats returns a channel closed by the next Heartbeat on the agent's
+// current connection.
+func (c *Connection) heartbeats() <-chan struct{} {
+	c = c.current()
+	c.beatMu.Lock()
+	defer c.beatMu.Unlock()
+	if c.beat == nil {
+		c.beat = make(chan struct{})
+	}
+	return c.beat
+}
+
+// idleTimer fires when a request has gone a full timeout without hearing
+// from its agent. Like the response clock it is paused while a tool waits
+// for human approval.
+type idleTimer struct {
+	timeout time.Duration
+	timer   *time.Timer
+	paused  bool
+}
+
+// newIdleTimer starts a timer. A zero timeout yields one that never fires.
+func newIdleTimer(timeout time.Duration) *idleTimer {
+	t := &idleTimer{timeout: timeout}
+	if timeout > 0 {
+		t.timer = time.NewTimer(timeout)
+	}
+	return t
+}
+
+// C returns the channel that fires on timeout, or nil when paused or disabled.
+func (t *idleTimer) C() <-chan time.Time {
+	if t.timer == nil || t.paused {
+		return nil
+	}
+	return t.timer.C
+}
+
+// reset restarts the full timeout.
+func (t *idleTimer) reset() {
+	if t.timer != nil {
+		t.timer.Reset(t.timeout)
+	}
+}
+
+// observe restarts the timer on a response event, pausing it instead while
+// the agent waits for tool approval.
+func (t *idleTimer) observe(resp *Response) {
+	t.paused = awaitingApproval(resp)
+	t.reset()
+}
+
+// stop releases the timer.
+func (t *idleTimer) stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// timeOut cancels a request whose agent went silent and returns the final
+// response for the caller.
+func (m *Manager) timeOut(ctx context.Context, agent *Connection, requestID string, idle time.Duration) *Response {
+	log := logctx.FromContext(ctx, m.logger)
+	reason := string(ErrorCodeAgentTimeout)
+	cancel := &pb.ServerMessage{
+		Payload: &pb.ServerMessage_CancelRequest{
+			CancelRequest: &pb.CancelRequest{RequestId: requestID, Reason: &reason},
+		},
+	}
+	if err := agent.Send(cancel); err != nil {
+		log.Warn("failed to cancel idle request", "agent_id", agent.ID, "agent_request_id", requestID, "error", err)
+	}
+	log.Warn("request timed out waiting for agent", "agent_id", agent.ID, "agent_request_id", requestID, "idle", idle)
+	return &Response{
+		Event: EventError,
+		Error: fmt.Sprintf("agent sent nothing for %s", idle),
+		Code:  ErrorCodeAgentTimeout,
+		Done:  true,
+	}
+}
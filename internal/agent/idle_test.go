@@ -0,0 +1,102 @@
+// ABOUTME: Tests for the idle timeout on in-flight requests: timeout, cancel at
+// ABOUTME: the agent, resets on events and heartbeats, and client cancellation.
+
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// sendIdle registers an agent and sends it a request under manager's idle timeout.
+func sendIdle(t *testing.T, manager *Manager, ctx context.Context) (*Connection, *mockStream, string, <-chan *Response) {
+	t.Helper()
+	stream := newMockStream()
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Name: "Test Agent", Stream: stream, Logger: slog.Default()})
+	if err := manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	respChan, err := manager.SendMessage(ctx, &SendRequest{ThreadID: "thread-1", Sender: "u", Content: "hi", AgentID: "agent-1"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	sent := stream.getSentMessages()
+	return conn, stream, sent[len(sent)-1].GetSendMessage().GetRequestId(), respChan
+}
+
+func pendingRequests(conn *Connection) int {
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+	return len(conn.pending)
+}
+
+func TestManagerTimesOutSilentAgent(t *testing.T) {
+	manager := NewManager(slog.Default())
+	manager.SetIdleTimeout(50 * time.Millisecond)
+	conn, stream, requestID, respChan := sendIdle(t, manager, context.Background())
+
+	conn.HandleResponse(&pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Thinking{Thinking: "hmm"}})
+
+	var last *Response
+	for resp := range respChan {
+		last = resp
+	}
+	if last == nil || last.Event != EventError || !last.Done || last.Code != ErrorCodeAgentTimeout {
+		t.Fatalf("last response = %+v, want a done agent_timeout error", last)
+	}
+	sent := stream.getSentMessages()
+	cancel := sent[len(sent)-1].GetCancelRequest()
+	if cancel == nil || cancel.GetRequestId() != requestID || cancel.GetReason() != string(ErrorCodeAgentTimeout) {
+		t.Errorf("last message to agent = %v, want a cancel for %s", sent[len(sent)-1], requestID)
+	}
+	if n := pendingRequests(conn); n != 0 {
+		t.Errorf("pending requests = %d, want 0", n)
+	}
+}
+
+func TestManagerIdleTimerResetsOnEventsAndHeartbeats(t *testing.T) {
+	manager := NewManager(slog.Default())
+	manager.SetIdleTimeout(150 * time.Millisecond)
+	conn, _, requestID, respChan := sendIdle(t, manager, context.Background())
+
+	// Alternate events and heartbeats well past the timeout in total.
+	for i := range 6 {
+		time.Sleep(50 * time.Millisecond)
+		if i%2 == 0 {
+			conn.Heartbeat()
+			continue
+		}
+		conn.HandleResponse(&pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Thinking{Thinking: "still here"}})
+		if resp := <-respChan; resp.Event != EventThinking {
+			t.Fatalf("event = %+v, want thinking", resp)
+		}
+	}
+	conn.HandleResponse(&pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "ok"}}})
+	if resp := <-respChan; resp.Event != EventDone {
+		t.Fatalf("final event = %+v, want done", resp)
+	}
+}
+
+func TestManagerClientCancelDoesNotTimeOut(t *testing.T) {
+	manager := NewManager(slog.Default())
+	manager.SetIdleTimeout(50 * time.Millisecond)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	conn, _, _, respChan := sendIdle(t, manager, ctx)
+
+	cancel(ErrRequestCanceled)
+	var events []*Response
+	for resp := range respChan {
+		events = append(events, resp)
+	}
+	if len(events) != 1 || events[0].Event != EventCanceled {
+		t.Fatalf("events = %+v, want just canceled", events)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := pendingRequests(conn); n != 0 {
+		t.Errorf("pending requests = %d, want 0", n)
+	}
+}
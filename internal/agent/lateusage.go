@@ -0,0 +1,97 @@
+// ABOUTME: Late usage: some backends report a request's token usage after its Done event.
+// ABOUTME: Finished requests are remembered briefly so that usage is still attributed to them.
+
+package agent
+
+import (
+	"time"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// lateUsageWindow is how long after a request finishes its usage is still
+// accepted.
+const lateUsageWindow = 2 * time.Minute
+
+// finishedRequest is a request that stopped streaming within lateUsageWindow.
+type finishedRequest struct {
+	agentID  string
+	threadID string
+	// sawUsage is set once usage has been reported for the request, in its
+	// stream or late; later reports are ignored, as in the stream.
+	sawUsage bool
+	expires  time.Time
+}
+
+// SetLateUsageObserver registers a function called with usage an agent
+// reports for a request after the request finished, unless the request
+// already reported usage. Call before the manager starts handling requests.
+func (m *Manager) SetLateUsageObserver(fn func(agentID, threadID, requestID string, usage *UsageEvent)) {
+	m.observeLateUsage = fn
+}
+
+// finishRequest remembers a request that stopped streaming, then passes
+// anything the agent sent it that was still buffered on respChan to
+// HandleLateResponse. respChan must already be closed.
+func (m *Manager) finishRequest(agentID, threadID, requestID string, sawUsage bool, respChan <-chan *pb.MessageResponse) {
+	if m.observeLateUsage == nil {
+		return
+	}
+	now := time.Now()
+	m.mu.Lock()
+	for id, f := range m.finished {
+		if now.After(f.expires) {
+			delete(m.finished, id)
+		}
+	}
+	m.finished[requestID] = &finishedRequest{
+		agentID:  agentID,
+		threadID: threadID,
+		sawUsage: sawUsage,
+		expires:  now.Add(lateUsageWindow),
+	}
+	m.mu.Unlock()
+
+	for {
+		select {
+		case resp, ok := <-respChan:
+			if !ok {
+				return
+			}
+			m.HandleLateResponse(agentID, resp)
+		default:
+			// Not closed after all (e.g. moved to a successor); stop rather
+			// than wait on it.
+			return
+		}
+	}
+}
+
+// HandleLateResponse takes a response from agentID for a request that is no
+// longer in flight. It reports whether the request finished within
+// lateUsageWindow; the response is then dropped, except for usage, which
+// goes to the late usage observer.
+func (m *Manager) HandleLateResponse(agentID string, resp *pb.MessageResponse) bool {
+	m.mu.Lock()
+	f, ok := m.finished[resp.GetRequestId()]
+	if !ok || f.agentID != agentID || time.Now().After(f.expires) {
+		m.mu.Unlock()
+		return false
+	}
+	usage := resp.GetUsage()
+	report := usage != nil && !f.sawUsage
+	if report {
+		f.sawUsage = true
+	}
+	m.mu.Unlock()
+
+	if report {
+		m.logger.Info("usage reported after request finished",
+			"agent_id", agentID,
+			"request_id", resp.GetRequestId(),
+		)
+		event := buildUsageResponse(&pb.MessageResponse_Usage{Usage: usage})
+		m.observeLateUsage(agentID, f.threadID, resp.GetRequestId(), event.Usage)
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+// ABOUTME: Tests for usage reported after a request's Done event: attributed to the
+// ABOUTME: finished request once, ignored if the stream already had usage.
+
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+type lateUsage struct {
+	agentID, threadID, requestID string
+	input                        int32
+}
+
+func watchLateUsage(manager *Manager) func() []lateUsage {
+	var mu sync.Mutex
+	var got []lateUsage
+	manager.SetLateUsageObserver(func(agentID, threadID, requestID string, u *UsageEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, lateUsage{agentID, threadID, requestID, u.InputTokens})
+	})
+	return func() []lateUsage {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]lateUsage(nil), got...)
+	}
+}
+
+func usageResponse(requestID string, input int32) *pb.MessageResponse {
+	return &pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Usage{Usage: &pb.TokenUsage{InputTokens: input}}}
+}
+
+func doneResponse(requestID string) *pb.MessageResponse {
+	return &pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "ok"}}}
+}
+
+func TestManagerAttributesUsageAfterDone(t *testing.T) {
+	manager := NewManager(slog.Default())
+	late := watchLateUsage(manager)
+	conn, _, requestID, respChan := sendIdle(t, manager, context.Background())
+
+	conn.HandleResponse(doneResponse(requestID))
+	for range respChan {
+	}
+
+	if conn.HandleResponse(usageResponse(requestID, 42)) {
+		t.Fatal("connection accepted usage for a finished request")
+	}
+	if !manager.HandleLateResponse("agent-1", usageResponse(requestID, 42)) {
+		t.Fatal("HandleLateResponse did not recognize the finished request")
+	}
+	// A repeat report, or one from another agent, is not counted again.
+	manager.HandleLateResponse("agent-1", usageResponse(requestID, 7))
+	if manager.HandleLateResponse("agent-2", usageResponse(requestID, 42)) {
+		t.Error("HandleLateResponse accepted another agent's response")
+	}
+	if manager.HandleLateResponse("agent-1", usageResponse("unknown", 1)) {
+		t.Error("HandleLateResponse accepted an unknown request")
+	}
+
+	got := late()
+	want := lateUsage{"agent-1", "thread-1", requestID, 42}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("late usage = %+v, want [%+v]", got, want)
+	}
+}
+
+func TestManagerSkipsLateUsageWhenStreamHadUsage(t *testing.T) {
+	manager := NewManager(slog.Default())
+	late := watchLateUsage(manager)
+	conn, _, requestID, respChan := sendIdle(t, manager, context.Background())
+
+	conn.HandleResponse(usageResponse(requestID, 10))
+	conn.HandleResponse(doneResponse(requestID))
+	for range respChan {
+	}
+
+	if !manager.HandleLateResponse("agent-1", usageResponse(requestID, 10)) {
+		t.Fatal("HandleLateResponse did not recognize the finished request")
+	}
+	if got := late(); len(got) != 0 {
+		t.Errorf("late usage = %+v, want none after in-stream usage", got)
+	}
+}
+
+func TestManagerDrainsUsageBufferedBehindDone(t *testing.T) {
+	manager := NewManager(slog.Default())
+	late := watchLateUsage(manager)
+	conn, _, requestID, respChan := sendIdle(t, manager, context.Background())
+
+	// Holding the lock as HandleResponse does, queue usage behind the Done
+	// so that the request cannot close before both are buffered.
+	conn.mu.RLock()
+	ch := conn.pending[requestID]
+	ch <- doneResponse(requestID)
+	ch <- usageResponse(requestID, 5)
+	conn.mu.RUnlock()
+
+	// The response channel closes only after the buffer is drained.
+	for range respChan {
+	}
+	got := late()
+	if len(got) != 1 || got[0].requestID != requestID || got[0].input != 5 {
+		t.Errorf("late usage = %+v, want 5 input tokens for %s", got, requestID)
+	}
+}
@@ -0,0 +1,207 @@
+// ABOUTME: Load-aware agent selection for sends that target a capability instead of an agent.
+// ABOUTME: Tracks in-flight requests and first-response latency per agent and picks the least loaded.
+
+package agent
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+)
+
+// ErrNoCapableAgent indicates no connected, routable agent has the capability.
+var ErrNoCapableAgent = coverr.New(coverr.NoCapableAgent, "no agent available with capability")
+
+// ErrAgentsAtCapacity indicates every eligible agent stayed at its
+// max_concurrent limit until the caller gave up waiting.
+var ErrAgentsAtCapacity = coverr.New(coverr.AgentBusy, "all capable agents are at capacity")
+
+// latencyWeight is the EWMA weight given to each new latency sample.
+const latencyWeight = 0.3
+
+// agentLoad is the router's view of one agent.
+type agentLoad struct {
+	inFlight int
+	// latency is a moving average of the time from send to the agent's first
+	// response; zero until the first sample.
+	latency time.Duration
+}
+
+// Route describes how a capability- or tag-based send chose its agent.
+type Route struct {
+	Candidates int           // connected agents advertising the capability (or tags)
+	Eligible   int           // candidates neither paused nor degraded
+	InFlight   int           // requests the chosen agent already had
+	Latency    time.Duration // the chosen agent's average first-response latency, zero if unmeasured
+	Queued     time.Duration // time spent waiting for a free slot
+}
+
+// SetDegradedCheck registers a function reporting agents whose recent
+// failures should keep them out of capability routing while healthier
+// candidates exist. Call before the manager starts handling requests.
+func (m *Manager) SetDegradedCheck(fn func(agentID string) bool) {
+	m.degraded = fn
+}
+
+// SelectByCapability picks the agent to handle a send addressed to a
+// capability. Paused agents are skipped, and degraded agents are used only
+// when no healthy candidate exists. Among those, the agent with the fewest
+// in-flight requests wins, then the one with the lower first-response
+// latency (agents not yet measured first), then a random one. Agents at
+// their concurrency limit are passed over; when all are full, the call
+// waits for a slot until ctx is done and then returns ErrAgentsAtCapacity.
+func (m *Manager) SelectByCapability(ctx context.Context, capability string) (*Connection, Route, error) {
+	return m.selectWhere(ctx, func(c *Connection) bool { return c.HasCapability(capability) }, ErrNoCapableAgent)
+}
+
+// selectWhere runs the SelectByCapability rules over the agents match
+// accepts, returning errNone when none of them is eligible.
+func (m *Manager) selectWhere(ctx context.Context, match func(*Connection) bool, errNone error) (*Connection, Route, error) {
+	start := time.Now()
+	for {
+		m.mu.RLock()
+		conn, route := m.pickLocked(match)
+		freed := m.loadFreed
+		m.mu.RUnlock()
+
+		switch {
+		case conn != nil:
+			route.Queued = time.Since(start)
+			return conn, route, nil
+		case route.Eligible == 0:
+			return nil, route, errNone
+		}
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, route, ErrAgentsAtCapacity
+		}
+	}
+}
+
+// pickLocked applies the selection rules to the current load. It returns a
+// nil connection when every eligible agent is at capacity. m.mu must be held.
+func (m *Manager) pickLocked(match func(*Connection) bool) (*Connection, Route) {
+	var route Route
+	var healthy, degraded []*Connection
+	for _, conn := range m.agents {
+		if !match(conn) {
+			continue
+		}
+		route.Candidates++
+		if _, paused := m.paused[conn.ID]; paused {
+			continue
+		}
+		if m.degraded != nil && m.degraded(conn.ID) {
+			degraded = append(degraded, conn)
+			continue
+		}
+		healthy = append(healthy, conn)
+	}
+	eligible := healthy
+	if len(eligible) == 0 {
+		eligible = degraded
+	}
+	route.Eligible = len(eligible)
+
+	var best []*Connection
+	var bestLoad agentLoad
+	for _, conn := range eligible {
+		load := m.load[conn.ID]
+		if load == nil {
+			load = &agentLoad{}
+		}
+		if limit := m.concurrencyLimit(conn); limit > 0 && load.inFlight >= limit {
+			continue
+		}
+		switch {
+		case best == nil || load.inFlight < bestLoad.inFlight ||
+			(load.inFlight == bestLoad.inFlight && load.latency < bestLoad.latency):
+			best = []*Connection{conn}
+			bestLoad = *load
+		case load.inFlight == bestLoad.inFlight && load.latency == bestLoad.latency:
+			best = append(best, conn)
+		}
+	}
+	if len(best) == 0 {
+		return nil, route
+	}
+	route.InFlight = bestLoad.inFlight
+	route.Latency = bestLoad.latency
+	// Map order is already random, but a stable order lets tests pin the pick.
+	slices.SortFunc(best, func(a, b *Connection) int { return strings.Compare(a.ID, b.ID) })
+	return best[m.randN(len(best))], route
+}
+
+// maxConcurrent returns the agent's concurrency hint, zero for no limit.
+func (c *Connection) maxConcurrent() int {
+	if c.Metadata == nil {
+		return 0
+	}
+	return c.Metadata.MaxConcurrent
+}
+
+// InFlight returns how many requests the agent is currently handling.
+func (m *Manager) InFlight(agentID string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if load := m.load[agentID]; load != nil {
+		return load.inFlight
+	}
+	return 0
+}
+
+// beginLoad counts a request sent to the agent.
+func (m *Manager) beginLoad(agentID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	load := m.load[agentID]
+	if load == nil {
+		load = &agentLoad{}
+		m.load[agentID] = load
+	}
+	load.inFlight++
+}
+
+// endLoad uncounts a finished request and wakes senders waiting for a slot.
+// A request queued for the agent takes over the slot instead.
+func (m *Manager) endLoad(agentID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if load := m.load[agentID]; load != nil && load.inFlight > 0 && !m.handOffSlotLocked(agentID) {
+		load.inFlight--
+		if _, connected := m.agents[agentID]; !connected && load.inFlight == 0 {
+			delete(m.load, agentID)
+		}
+	}
+	close(m.loadFreed)
+	m.loadFreed = make(chan struct{})
+}
+
+// observeLatency folds a first-response latency sample into the average.
+func (m *Manager) observeLatency(agentID string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	load := m.load[agentID]
+	if load == nil {
+		return
+	}
+	if load.latency == 0 {
+		load.latency = d
+		return
+	}
+	load.latency = time.Duration(latencyWeight*float64(d) + (1-latencyWeight)*float64(load.latency))
+}
+
+// randN picks a tie-breaker index; tests replace it.
+func (m *Manager) randN(n int) int {
+	if m.rand != nil {
+		return m.rand(n)
+	}
+	return rand.IntN(n)
+}
@@ -0,0 +1,238 @@
+// ABOUTME: Tests for load-aware capability routing: eligibility, tie-breaking, capacity, and queueing.
+// ABOUTME: Includes a simulation showing assignments skew toward faster, idler agents.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"testing"
+	"time"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func addCapableAgent(t *testing.T, m *Manager, id string, maxConcurrent int, caps ...string) *Connection {
+	t.Helper()
+	conn := NewConnection(ConnectionParams{
+		ID: id, Name: id, Capabilities: caps, Stream: newMockStream(), Logger: slog.Default(),
+		Metadata: &Metadata{MaxConcurrent: maxConcurrent},
+	})
+	if err := m.Register(conn); err != nil {
+		t.Fatalf("register %s: %v", id, err)
+	}
+	return conn
+}
+
+func TestSelectByCapability(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no candidates", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		addCapableAgent(t, m, "a", 0, "code")
+		_, route, err := m.SelectByCapability(ctx, "chat")
+		if !errors.Is(err, ErrNoCapableAgent) {
+			t.Fatalf("err = %v, want ErrNoCapableAgent", err)
+		}
+		if route.Candidates != 0 {
+			t.Errorf("candidates = %d, want 0", route.Candidates)
+		}
+	})
+
+	t.Run("prefers fewest in-flight", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		addCapableAgent(t, m, "busy", 0, "chat")
+		addCapableAgent(t, m, "idle", 0, "chat")
+		m.beginLoad("busy")
+		conn, route, err := m.SelectByCapability(ctx, "chat")
+		if err != nil {
+			t.Fatalf("select: %v", err)
+		}
+		if conn.ID != "idle" || route.Candidates != 2 || route.Eligible != 2 || route.InFlight != 0 {
+			t.Errorf("picked %s with %+v, want idle of 2", conn.ID, route)
+		}
+	})
+
+	t.Run("breaks ties by latency then randomly", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		for _, id := range []string{"slow", "fast", "twin"} {
+			addCapableAgent(t, m, id, 0, "chat")
+		}
+		for id, d := range map[string]time.Duration{"slow": 3 * time.Second, "fast": time.Second, "twin": time.Second} {
+			m.beginLoad(id)
+			m.observeLatency(id, d)
+			m.endLoad(id)
+		}
+		conn, route, err := m.SelectByCapability(ctx, "chat")
+		if err != nil {
+			t.Fatalf("select: %v", err)
+		}
+		if conn.ID != "fast" && conn.ID != "twin" {
+			t.Errorf("picked %s, want one of the fast agents", conn.ID)
+		}
+		if route.Latency != time.Second {
+			t.Errorf("latency = %v, want 1s", route.Latency)
+		}
+
+		seen := map[string]bool{}
+		for i := range 2 {
+			m.rand = func(int) int { return i }
+			conn, _, _ := m.SelectByCapability(ctx, "chat")
+			seen[conn.ID] = true
+		}
+		if !seen["fast"] || !seen["twin"] {
+			t.Errorf("random tie-break reached %v, want both fast agents", seen)
+		}
+	})
+
+	t.Run("skips paused and avoids degraded", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		addCapableAgent(t, m, "paused", 0, "chat")
+		addCapableAgent(t, m, "flaky", 0, "chat")
+		addCapableAgent(t, m, "healthy", 0, "chat")
+		m.Pause("paused", PauseInfo{PausedBy: "admin"})
+		degraded := map[string]bool{"flaky": true}
+		m.SetDegradedCheck(func(id string) bool { return degraded[id] })
+		m.beginLoad("healthy")
+		m.beginLoad("healthy")
+
+		conn, route, err := m.SelectByCapability(ctx, "chat")
+		if err != nil {
+			t.Fatalf("select: %v", err)
+		}
+		if conn.ID != "healthy" || route.Candidates != 3 || route.Eligible != 1 {
+			t.Errorf("picked %s with %+v, want the busy healthy agent", conn.ID, route)
+		}
+
+		degraded["healthy"] = true
+		conn, _, err = m.SelectByCapability(ctx, "chat")
+		if err != nil || conn.ID != "flaky" {
+			t.Errorf("picked %v (err %v), want the idle degraded agent when none is healthy", conn, err)
+		}
+	})
+
+	t.Run("overflows past agents at capacity", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		addCapableAgent(t, m, "small", 1, "chat")
+		addCapableAgent(t, m, "large", 0, "chat")
+		m.beginLoad("small")
+		m.beginLoad("large")
+		m.beginLoad("large")
+		conn, _, err := m.SelectByCapability(ctx, "chat")
+		if err != nil || conn.ID != "large" {
+			t.Errorf("picked %v (err %v), want large since small is full", conn, err)
+		}
+	})
+
+	t.Run("queues until a slot frees", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		addCapableAgent(t, m, "only", 1, "chat")
+		m.beginLoad("only")
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			m.endLoad("only")
+		}()
+		conn, route, err := m.SelectByCapability(ctx, "chat")
+		if err != nil || conn.ID != "only" {
+			t.Fatalf("picked %v (err %v), want only after it freed up", conn, err)
+		}
+		if route.Queued < 20*time.Millisecond {
+			t.Errorf("queued = %v, want at least 20ms", route.Queued)
+		}
+	})
+
+	t.Run("gives up when the context ends", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		addCapableAgent(t, m, "only", 1, "chat")
+		m.beginLoad("only")
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		if _, _, err := m.SelectByCapability(ctx, "chat"); !errors.Is(err, ErrAgentsAtCapacity) {
+			t.Errorf("err = %v, want ErrAgentsAtCapacity", err)
+		}
+	})
+}
+
+func TestSendMessage_TracksLoad(t *testing.T) {
+	m := NewManager(slog.Default())
+	conn := addCapableAgent(t, m, "agent-1", 0, "chat")
+	stream := conn.stream.(*mockStream)
+
+	ch, err := m.SendMessage(context.Background(), &SendRequest{AgentID: "agent-1", Content: "hi"})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if got := m.InFlight("agent-1"); got != 1 {
+		t.Errorf("in-flight = %d, want 1", got)
+	}
+
+	requestID := stream.getSentMessages()[0].GetSendMessage().GetRequestId()
+	time.Sleep(5 * time.Millisecond)
+	conn.HandleResponse(&pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Done{Done: &pb.Done{}}})
+	for range ch {
+	}
+
+	if got := m.InFlight("agent-1"); got != 0 {
+		t.Errorf("in-flight after done = %d, want 0", got)
+	}
+	m.mu.RLock()
+	latency := m.load["agent-1"].latency
+	m.mu.RUnlock()
+	if latency < 5*time.Millisecond {
+		t.Errorf("latency = %v, want the first-response delay recorded", latency)
+	}
+}
+
+// TestSelectByCapability_SkewsTowardFastAgents replays a steady stream of
+// requests against agents of different speeds on a virtual clock. Faster
+// agents finish sooner, so they are idle more often and should win more work.
+func TestSelectByCapability_SkewsTowardFastAgents(t *testing.T) {
+	m := NewManager(slog.Default())
+	service := map[string]time.Duration{
+		"fast":   100 * time.Millisecond,
+		"medium": 300 * time.Millisecond,
+		"slow":   900 * time.Millisecond,
+	}
+	for id := range service {
+		addCapableAgent(t, m, id, 0, "chat")
+	}
+
+	type completion struct {
+		at    time.Duration
+		agent string
+	}
+	var pending []completion
+	assigned := map[string]int{}
+	const arrivals = 600
+	const spacing = 60 * time.Millisecond
+
+	for i := range arrivals {
+		now := time.Duration(i) * spacing
+		sort.Slice(pending, func(a, b int) bool { return pending[a].at < pending[b].at })
+		for len(pending) > 0 && pending[0].at <= now {
+			m.endLoad(pending[0].agent)
+			pending = pending[1:]
+		}
+
+		conn, _, err := m.SelectByCapability(context.Background(), "chat")
+		if err != nil {
+			t.Fatalf("arrival %d: %v", i, err)
+		}
+		assigned[conn.ID]++
+		m.beginLoad(conn.ID)
+		// Latency tracks speed: a slower agent also answers later.
+		m.observeLatency(conn.ID, service[conn.ID]/3)
+		pending = append(pending, completion{at: now + service[conn.ID], agent: conn.ID})
+	}
+
+	t.Logf("assignments: %v", assigned)
+	if !(assigned["fast"] > assigned["medium"] && assigned["medium"] > assigned["slow"]) {
+		t.Errorf("assignments %v, want fast > medium > slow", assigned)
+	}
+	if assigned["fast"] < arrivals/2 {
+		t.Errorf("fast agent got %d of %d, want the majority", assigned["fast"], arrivals)
+	}
+}
@@ -7,10 +7,16 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/logctx"
+	"github.com/2389/coven-gateway/internal/tracing"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
@@ -18,38 +24,117 @@ import (
 var ErrAgentAlreadyRegistered = errors.New("agent already registered")
 
 // ErrAgentNotFound indicates the specified agent was not found.
-var ErrAgentNotFound = errors.New("agent not found")
+var ErrAgentNotFound = coverr.New(coverr.AgentOffline, "agent not found")
 
 // Manager coordinates all connected agents and routes messages to them.
 type Manager struct {
-	agents map[string]*Connection
-	mu     sync.RWMutex
-	logger *slog.Logger
+	agents  map[string]*Connection
+	pending map[string]*pendingAgent // agents waiting for principal approval
+	paused  map[string]PauseInfo     // agents that must not receive new messages
+	// activity holds each agent's most recent in-flight request.
+	activity map[string]*Activity
+	mu       sync.RWMutex
+	logger   *slog.Logger
+
+	// observe, if set, receives the outcome of each completed request.
+	observe func(agentID string, ok bool)
+	// observeRequest, if set, is told about each request sent to an agent.
+	observeRequest func(agentID, requestID string, req *SendRequest)
+	// observePending, if set, is told about each agent that starts waiting
+	// for approval.
+	observePending func(PendingAgent)
+	// observeLateUsage, if set, receives usage reported after a request
+	// finished; finished holds those requests by ID until they expire.
+	observeLateUsage func(agentID, threadID, requestID string, usage *UsageEvent)
+	finished         map[string]*finishedRequest
+	// approvalPolicy, if set, may answer tool approval requests before they
+	// reach the client; see SetToolApprovalPolicy.
+	approvalPolicy func(agentID, threadID string, req *ToolApprovalRequestEvent) (approved, decided bool)
+
+	// maxDuration is the default response limit; zero means unlimited.
+	maxDuration time.Duration
+	// idleTimeout cancels requests whose agent goes quiet; zero disables it.
+	idleTimeout time.Duration
+	// observeTruncation, if set, is told about each truncated response.
+	observeTruncation func(agentID string, ev *TruncatedEvent)
+
+	// healthThresholds judge heartbeat health; observeHealth, if set, is
+	// told about health state changes.
+	healthThresholds HealthThresholds
+	observeHealth    func(agentID string, from HealthState, h HeartbeatHealth)
+
+	// supersedes counts, per agent ID, connections replaced by a reconnect.
+	supersedes map[string]int
+
+	// load tracks in-flight requests and latency for capability routing.
+	load map[string]*agentLoad
+	// loadFreed is closed and replaced whenever a request finishes, waking
+	// sends queued for a free slot.
+	loadFreed chan struct{}
+	// degraded, if set, reports agents to avoid in capability routing.
+	degraded func(agentID string) bool
+	// rand picks among equally loaded agents; nil uses math/rand.
+	rand func(n int) int
+
+	// maxConcurrent caps each agent's in-flight requests; zero is no limit.
+	maxConcurrent int
+	// queueSize bounds the requests waiting for a slot per agent.
+	queueSize int
+	// queues holds each agent's requests waiting for a slot, oldest first.
+	queues map[string][]*slotWaiter
+	// observeQueue, if set, is told each agent's queue depth as it changes.
+	observeQueue func(agentID string, depth int)
+
+	// draining refuses new requests once Drain is called.
+	draining bool
+	// drainExpired is closed when Drain's deadline passes, canceling the
+	// requests still in flight.
+	drainExpired chan struct{}
+	drainOnce    sync.Once
 }
 
 // NewManager creates a new Manager instance.
 func NewManager(logger *slog.Logger) *Manager {
 	return &Manager{
-		agents: make(map[string]*Connection),
-		logger: logger,
+		agents:    make(map[string]*Connection),
+		pending:   make(map[string]*pendingAgent),
+		paused:    make(map[string]PauseInfo),
+		activity:  make(map[string]*Activity),
+		load:      make(map[string]*agentLoad),
+		loadFreed: make(chan struct{}),
+		queues:    make(map[string][]*slotWaiter),
+		logger:    logger,
+
+		supersedes: make(map[string]int),
+		finished:   make(map[string]*finishedRequest),
+
+		drainExpired: make(chan struct{}),
 	}
 }
 
-// Register adds a new agent connection to the manager.
-// Returns ErrAgentAlreadyRegistered if an agent with the same ID exists.
+// Register adds a new agent connection to the manager. If the agent ID is
+// already connected by the same principal, the old connection is handed off
+// to the new one first (see handOffLocked). Returns ErrAgentAlreadyRegistered
+// if the ID is held by another principal or waiting for approval.
 func (m *Manager) Register(agent *Connection) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.agents[agent.ID]; exists {
+	if _, waiting := m.pending[agent.ID]; waiting {
 		return ErrAgentAlreadyRegistered
 	}
+	if old, exists := m.agents[agent.ID]; exists {
+		if old.PrincipalID != agent.PrincipalID {
+			return ErrAgentAlreadyRegistered
+		}
+		m.handOffLocked(old, agent)
+	}
 
 	m.agents[agent.ID] = agent
 	m.logger.Info("=== AGENT CONNECTED ===",
 		"agent_id", agent.ID,
 		"name", agent.Name,
-		"capabilities", agent.Capabilities,
+		"capabilities", agent.Capabilities(),
 		"total_agents", len(m.agents),
 	)
 	return nil
@@ -60,11 +145,28 @@ func (m *Manager) Register(agent *Connection) error {
 func (m *Manager) Unregister(agentID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.unregisterLocked(agentID)
+}
+
+// UnregisterConnection is Unregister for a specific connection: it does
+// nothing if conn has since been superseded by a reconnect.
+func (m *Manager) UnregisterConnection(conn *Connection) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.agents[conn.ID] == conn {
+		m.unregisterLocked(conn.ID)
+	}
+}
 
+func (m *Manager) unregisterLocked(agentID string) {
 	if agent, exists := m.agents[agentID]; exists {
 		// Close all pending request channels to unblock waiting goroutines
 		agent.Close()
 		delete(m.agents, agentID)
+		m.failQueueLocked(agentID, ErrAgentNotFound)
+		if load := m.load[agentID]; load != nil && load.inFlight == 0 {
+			delete(m.load, agentID)
+		}
 		m.logger.Info("=== AGENT DISCONNECTED ===",
 			"agent_id", agentID,
 			"name", agent.Name,
@@ -76,30 +178,61 @@ func (m *Manager) Unregister(agentID string) {
 // SendMessage routes a message to a specified agent and returns a channel for responses.
 // The channel will receive Response events until a Done or Error event is sent.
 // AgentID is required - the caller must specify which agent should receive the message.
+// When the agent is at its concurrency limit the request is queued, and
+// req.OnQueued is told its position, or refused with a BusyError.
 func (m *Manager) SendMessage(ctx context.Context, req *SendRequest) (<-chan *Response, error) {
 	if req.AgentID == "" {
 		return nil, errors.New("agent_id is required")
 	}
+	if m.Draining() {
+		return nil, ErrDraining
+	}
 
 	agent, ok := m.GetAgent(req.AgentID)
 	if !ok {
 		return nil, ErrAgentNotFound
 	}
+	if err := m.CheckNotPaused(req.AgentID); err != nil {
+		return nil, err
+	}
+	if err := agent.CheckWorkspace(req.Workspace); err != nil {
+		return nil, err
+	}
+
+	w, position, err := m.acquireSlot(agent)
+	if err != nil {
+		logctx.FromContext(ctx, m.logger).Warn("request refused: agent at concurrency limit", "agent_id", agent.ID, "error", err)
+		return nil, err
+	}
+	if w != nil {
+		logctx.FromContext(ctx, m.logger).Info("request queued: agent at concurrency limit",
+			"agent_id", agent.ID,
+			"queue_depth", position,
+		)
+		if req.OnQueued != nil {
+			req.OnQueued(position)
+		}
+		return m.sendQueued(ctx, agent, req, w), nil
+	}
+	return m.deliver(ctx, agent, req)
+}
 
+// deliver sends req to an agent once it holds a slot for it.
+func (m *Manager) deliver(ctx context.Context, agent *Connection, req *SendRequest) (<-chan *Response, error) {
 	// Generate a unique request ID
 	requestID := uuid.New().String()
 
-	// Create the request channel on the connection
-	respChan := agent.CreateRequest(requestID)
-
 	// Build the protobuf message
 	pbMsg := &pb.ServerMessage{
 		Payload: &pb.ServerMessage_SendMessage{
 			SendMessage: &pb.SendMessage{
-				RequestId: requestID,
-				ThreadId:  req.ThreadID,
-				Sender:    req.Sender,
-				Content:   req.Content,
+				RequestId:      requestID,
+				ThreadId:       req.ThreadID,
+				Sender:         req.Sender,
+				Content:        req.Content,
+				Workspace:      req.Workspace,
+				ChannelContext: req.ChannelContext,
+				TraceContext:   logctx.Inject(ctx, tracing.Inject(ctx)),
 			},
 		},
 	}
@@ -109,71 +242,216 @@ func (m *Manager) SendMessage(ctx context.Context, req *SendRequest) (<-chan *Re
 		attachments := make([]*pb.FileAttachment, len(req.Attachments))
 		for i, att := range req.Attachments {
 			attachments[i] = &pb.FileAttachment{
-				Filename: att.Filename,
-				MimeType: att.MimeType,
-				Data:     att.Data,
+				Filename:     att.Filename,
+				MimeType:     att.MimeType,
+				Data:         att.Data,
+				AttachmentId: att.ID,
 			}
 		}
 		pbMsg.GetSendMessage().Attachments = attachments
 	}
 
-	// Send the message
-	if err := agent.Send(pbMsg); err != nil {
-		agent.CloseRequest(requestID)
+	// Observe before sending so a fast reply can't finish the request first.
+	if m.observeRequest != nil {
+		m.observeRequest(agent.ID, requestID, req)
+	}
+	resume := &pb.ResumeRequest{
+		RequestId:      requestID,
+		ThreadId:       req.ThreadID,
+		Sender:         req.Sender,
+		Content:        req.Content,
+		Workspace:      req.Workspace,
+		ChannelContext: req.ChannelContext,
+	}
+	return m.dispatch(ctx, agent, requestID, req, pbMsg, resume)
+}
+
+// ResumeRequest re-sends a request that was in flight when the agent's
+// previous connection went away, keeping its original request ID so the
+// agent can pick up where it left off. Attachments are not re-sent.
+func (m *Manager) ResumeRequest(ctx context.Context, requestID string, req *SendRequest) (<-chan *Response, error) {
+	if m.Draining() {
+		return nil, ErrDraining
+	}
+	agent, ok := m.GetAgent(req.AgentID)
+	if !ok {
+		return nil, ErrAgentNotFound
+	}
+
+	// A resumed request already held a slot, so it skips the limit.
+	m.beginLoad(agent.ID)
+	resume := &pb.ResumeRequest{
+		RequestId:      requestID,
+		ThreadId:       req.ThreadID,
+		Sender:         req.Sender,
+		Content:        req.Content,
+		Workspace:      req.Workspace,
+		ChannelContext: req.ChannelContext,
+	}
+	pbMsg := &pb.ServerMessage{
+		Payload: &pb.ServerMessage_ResumeRequest{ResumeRequest: resume},
+	}
+	return m.dispatch(ctx, agent, requestID, req, pbMsg, resume)
+}
+
+// dispatch sends a request message to the agent and starts streaming its
+// responses back on the returned channel. The caller has already counted
+// the request's load; a failed send uncounts it. resume re-sends the
+// request if the agent's connection is handed off before it finishes.
+func (m *Manager) dispatch(
+	ctx context.Context,
+	agent *Connection,
+	requestID string,
+	req *SendRequest,
+	pbMsg *pb.ServerMessage,
+	resume *pb.ResumeRequest,
+) (<-chan *Response, error) {
+	respChan, err := agent.startRequest(requestID, pbMsg, resume)
+	if err != nil {
+		m.endLoad(agent.ID)
 		return nil, err
 	}
 
-	m.logger.Debug("message sent to agent",
+	logctx.FromContext(ctx, m.logger).Debug("message sent to agent",
 		"agent_id", agent.ID,
-		"request_id", requestID,
+		"agent_request_id", requestID,
 		"thread_id", req.ThreadID,
 	)
 
+	m.startActivity(agent.ID, requestID, req.ThreadID)
+
 	// Create a channel to transform pb responses into Response types
 	outChan := make(chan *Response, 16)
 
 	// Start a goroutine to transform responses
-	go m.transformResponses(ctx, agent, requestID, respChan, outChan)
+	clock := newResponseClock(m.limitFor(req), time.Now())
+	go m.transformResponses(ctx, agent, requestID, req.ThreadID, clock, newIdleTimer(m.idleTimeout), respChan, outChan)
 
 	return outChan, nil
 }
 
 // transformResponses converts pb.MessageResponse events into Response events.
+// If the clock runs out first, the request is canceled and ends truncated;
+// if the agent goes quiet for the idle timeout, it is canceled with an error.
 func (m *Manager) transformResponses(
 	ctx context.Context,
 	agent *Connection,
 	requestID string,
+	threadID string,
+	clock *responseClock,
+	idle *idleTimer,
 	respChan <-chan *pb.MessageResponse,
 	outChan chan<- *Response,
 ) {
+	sawUsage := false
 	defer close(outChan)
-	defer agent.CloseRequest(requestID)
+	defer func() {
+		agent.CloseRequest(requestID)
+		m.finishRequest(agent.ID, threadID, requestID, sawUsage, respChan)
+	}()
+	defer m.endActivity(agent.ID, requestID)
+	defer m.endLoad(agent.ID)
+	defer clock.stop()
+	defer idle.stop()
+
+	answered := false
 
 	for {
 		select {
 		case <-ctx.Done():
+			// The caller gave up; that says nothing about the agent.
+			if reason, ok := canceledByClient(ctx); ok {
+				outChan <- m.cancelAtAgent(ctx, agent, requestID, reason)
+				return
+			}
 			outChan <- &Response{
 				Event: EventError,
 				Error: "context canceled",
+				Code:  coverr.Canceled,
 				Done:  true,
 			}
 			return
 
+		case now := <-clock.C():
+			outChan <- m.truncate(ctx, agent, requestID, clock.truncated(now))
+			m.recordOutcome(agent.ID, false)
+			return
+
+		case <-idle.C():
+			outChan <- m.timeOut(ctx, agent, requestID, idle.timeout)
+			m.recordOutcome(agent.ID, false)
+			return
+
+		case <-agent.heartbeats():
+			idle.reset()
+
+		case <-m.drainExpired:
+			// Shutdown cut the request off; that says nothing about the agent.
+			outChan <- m.cancelAtAgent(ctx, agent, requestID, DrainReason)
+			return
+
 		case pbResp, ok := <-respChan:
 			if !ok {
+				if reason := agent.takeOversized(requestID); reason != "" {
+					outChan <- m.rejectOversized(ctx, agent, requestID, reason)
+					m.recordOutcome(agent.ID, false)
+					return
+				}
+				if agent.wasDropped(requestID) {
+					// A newer connection took over; that says nothing about the agent.
+					outChan <- supersededResponse()
+					return
+				}
+				// Stream closed without a Done event, e.g. the agent disconnected.
+				m.recordOutcome(agent.ID, false)
 				return
 			}
 
+			if !answered {
+				answered = true
+				m.observeLatency(agent.ID, time.Since(clock.started))
+			}
 			resp := m.convertResponse(pbResp)
+			if resp.Event == EventToolApprovalRequest && m.decideApproval(ctx, agent, threadID, resp.ToolApprovalRequest) {
+				// Answered already: nobody is waiting on approval, so the
+				// clocks keep running.
+				idle.reset()
+				continue
+			}
+			sawUsage = sawUsage || resp.Event == EventUsage
+			m.trackActivity(agent.ID, requestID, resp)
+			clock.observe(resp, time.Now())
+			idle.observe(resp)
 			outChan <- resp
 
 			if resp.Done {
+				m.recordOutcome(agent.ID, resp.Event != EventError)
 				return
 			}
 		}
 	}
 }
 
+// SetOutcomeObserver registers a function called with the outcome of each
+// request an agent finishes. Call before the manager starts handling requests.
+func (m *Manager) SetOutcomeObserver(fn func(agentID string, ok bool)) {
+	m.observe = fn
+}
+
+// SetRequestObserver registers a function called with each request
+// SendMessage is about to deliver to an agent. Resumed requests are not
+// reported again.
+// Call before the manager starts handling requests.
+func (m *Manager) SetRequestObserver(fn func(agentID, requestID string, req *SendRequest)) {
+	m.observeRequest = fn
+}
+
+func (m *Manager) recordOutcome(agentID string, ok bool) {
+	if m.observe != nil {
+		m.observe(agentID, ok)
+	}
+}
+
 // convertResponse transforms a pb.MessageResponse into a Response.
 // Response builders for each event type.
 
@@ -200,9 +478,12 @@ func buildToolResultResponse(event *pb.MessageResponse_ToolResult) *Response {
 	return &Response{
 		Event: EventToolResult,
 		ToolResult: &ToolResultEvent{
-			ID:      event.ToolResult.GetId(),
-			Output:  event.ToolResult.GetOutput(),
-			IsError: event.ToolResult.GetIsError(),
+			ID:       event.ToolResult.GetId(),
+			Output:   event.ToolResult.GetOutput(),
+			IsError:  event.ToolResult.GetIsError(),
+			Partial:  event.ToolResult.GetPartial(),
+			Sequence: event.ToolResult.GetSequence(),
+			Cached:   event.ToolResult.GetCached(),
 		},
 	}
 }
@@ -223,7 +504,7 @@ func buildDoneResponse(event *pb.MessageResponse_Done) *Response {
 }
 
 func buildErrorResponse(event *pb.MessageResponse_Error) *Response {
-	return &Response{Event: EventError, Error: event.Error, Done: true}
+	return &Response{Event: EventError, Error: event.Error, Code: coverr.AgentError, Done: true}
 }
 
 func buildSessionInitResponse(event *pb.MessageResponse_SessionInit) *Response {
@@ -333,6 +614,12 @@ func (m *Manager) convertResponse(pbResp *pb.MessageResponse) *Response {
 	if resp := convertToolStateEvent(event, pbResp.GetRequestId()); resp != nil {
 		return resp
 	}
+	if resp := convertPlanEvent(event); resp != nil {
+		return resp
+	}
+	if resp := convertCitationEvent(event); resp != nil {
+		return resp
+	}
 	return &Response{}
 }
 
@@ -366,16 +653,26 @@ func (m *Manager) ListAgents() []*AgentInfo {
 	defer m.mu.RUnlock()
 
 	agents := make([]*AgentInfo, 0, len(m.agents))
+	now := time.Now()
 	for _, agent := range m.agents {
+		var paused *PauseInfo
+		if info, ok := m.paused[agent.ID]; ok {
+			paused = &info
+		}
 		agents = append(agents, &AgentInfo{
 			ID:           agent.ID,
 			PrincipalID:  agent.PrincipalID,
 			Name:         agent.Name,
-			Capabilities: agent.Capabilities,
+			Capabilities: agent.Capabilities(),
 			Workspaces:   agent.Workspaces,
 			WorkingDir:   agent.WorkingDir,
 			InstanceID:   agent.InstanceID,
 			Backend:      agent.Backend,
+			Metadata:     agent.Metadata,
+			Paused:       paused,
+
+			SupersedeCount: m.supersedes[agent.ID],
+			Health:         agent.Health(m.healthThresholds, now),
 		})
 	}
 	return agents
@@ -425,6 +722,22 @@ func (m *Manager) GetByPrincipalAndWorkDir(principalID, workingDir string) *Conn
 	return nil
 }
 
+// ListByPrincipal returns the online agents authenticated as principalID,
+// ordered by agent ID.
+func (m *Manager) ListByPrincipal(principalID string) []*Connection {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var conns []*Connection
+	for _, agent := range m.agents {
+		if agent.PrincipalID == principalID {
+			conns = append(conns, agent)
+		}
+	}
+	slices.SortFunc(conns, func(a, b *Connection) int { return strings.Compare(a.ID, b.ID) })
+	return conns
+}
+
 // SendToolApproval sends a tool approval response to an agent.
 // toolID must match the ToolApprovalRequest.id from the agent.
 func (m *Manager) SendToolApproval(agentID, toolID string, approved, approveAll bool) error {
@@ -464,10 +777,22 @@ type SendRequest struct {
 	Content     string
 	Attachments []Attachment
 	AgentID     string // Required: specifies which agent should handle this request
+	// Workspace, if set, must be one the agent registered; the agent handles
+	// the request there instead of in its default workspace.
+	Workspace string
+	// ChannelContext is the binding's channel guidance, passed to the agent
+	// as its own field rather than folded into Content.
+	ChannelContext string
+	// MaxDuration caps how long the response may run; zero uses the manager default.
+	MaxDuration time.Duration
+	// OnQueued, if set, is called with the request's 1-based queue position
+	// when the agent is at its concurrency limit, before SendMessage returns.
+	OnQueued func(position int)
 }
 
 // Attachment represents a file attached to a message.
 type Attachment struct {
+	ID       string // stored attachment ID, set once the gateway has saved it
 	Filename string
 	MimeType string
 	Data     []byte
@@ -481,11 +806,16 @@ type Response struct {
 	ToolResult          *ToolResultEvent
 	File                *FileEvent
 	Error               string
+	Code                coverr.Code // machine-readable cause for EventError, e.g. ErrorCodeAgentTimeout
 	Done                bool
 	SessionID           string                    // For EventSessionInit
 	Usage               *UsageEvent               // For EventUsage
 	ToolState           *ToolStateEvent           // For EventToolState
 	ToolApprovalRequest *ToolApprovalRequestEvent // For EventToolApprovalRequest
+	Truncated           *TruncatedEvent           // For EventTruncated
+	Plan                *PlanEvent                // For EventPlan
+	PlanStep            *PlanStepEvent            // For EventPlanStepUpdate
+	Citation            *CitationEvent            // For EventCitation
 }
 
 // ResponseEvent indicates the type of response event.
@@ -505,6 +835,10 @@ const (
 	EventToolState           // Tool lifecycle state change
 	EventCanceled            // Request was canceled
 	EventToolApprovalRequest // Tool needs approval before execution
+	EventTruncated           // Gateway cut the response short
+	EventPlan                // Agent announced the steps it intends to take
+	EventPlanStepUpdate      // Status change for one step of the latest plan
+	EventCitation            // Source backing part of the response text
 )
 
 // ToolUseEvent represents a tool invocation by the agent.
@@ -519,6 +853,13 @@ type ToolResultEvent struct {
 	ID      string
 	Output  string
 	IsError bool
+	// Partial marks a chunk of a streaming tool's output; Sequence numbers
+	// the chunks of one call from 1. The final result follows unmarked.
+	Partial  bool
+	Sequence uint64
+	// Cached marks output the gateway served from its tool result cache,
+	// as echoed by the agent from PackToolResult.cached.
+	Cached bool
 }
 
 // FileEvent represents a file output from the agent.
@@ -526,6 +867,8 @@ type FileEvent struct {
 	Filename string
 	MimeType string
 	Data     []byte
+	// AttachmentID is set once the file is stored as a thread attachment.
+	AttachmentID string
 }
 
 // UsageEvent represents token consumption from an LLM call.
@@ -562,4 +905,11 @@ type AgentInfo struct {
 	WorkingDir   string
 	InstanceID   string
 	Backend      string
+	Metadata     *Metadata  // normalized registration metadata, if recorded
+	Paused       *PauseInfo // nil unless an admin paused the agent
+	// SupersedeCount is how many of the agent's connections have been
+	// replaced by a reconnect since the gateway started.
+	SupersedeCount int
+	// Health is the agent's heartbeat health when the list was taken.
+	Health HeartbeatHealth
 }
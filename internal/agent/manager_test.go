@@ -14,6 +14,7 @@ import (
 	"time"
 
 	pb "github.com/2389/coven-gateway/proto/coven"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 )
 
@@ -175,13 +176,13 @@ func TestManagerRegister(t *testing.T) {
 		}
 	})
 
-	t.Run("returns error for duplicate agent ID", func(t *testing.T) {
+	t.Run("returns error for agent ID held by another principal", func(t *testing.T) {
 		manager := NewManager(slog.Default())
 		stream1 := newMockStream()
 		stream2 := newMockStream()
 
-		conn1 := NewConnection(ConnectionParams{ID: "agent-1", Name: "Agent One", Capabilities: []string{"chat"}, Stream: stream1, Logger: slog.Default()})
-		conn2 := NewConnection(ConnectionParams{ID: "agent-1", Name: "Agent One Duplicate", Capabilities: []string{"chat"}, Stream: stream2, Logger: slog.Default()})
+		conn1 := NewConnection(ConnectionParams{ID: "agent-1", Name: "Agent One", Capabilities: []string{"chat"}, PrincipalID: "p1", Stream: stream1, Logger: slog.Default()})
+		conn2 := NewConnection(ConnectionParams{ID: "agent-1", Name: "Agent One Duplicate", Capabilities: []string{"chat"}, PrincipalID: "p2", Stream: stream2, Logger: slog.Default()})
 
 		err := manager.Register(conn1)
 		if err != nil {
@@ -408,6 +409,29 @@ func TestManagerSendMessage(t *testing.T) {
 		}
 	})
 
+	t.Run("carries the caller's trace context", func(t *testing.T) {
+		manager := NewManager(slog.Default())
+		stream := newMockStream()
+		conn := NewConnection(ConnectionParams{ID: "agent-1", Name: "Test Agent", Capabilities: []string{"chat"}, Stream: stream, Logger: slog.Default()})
+		manager.Register(conn)
+
+		sc := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{0x01, 0x02},
+			SpanID:     trace.SpanID{0x03},
+			TraceFlags: trace.FlagsSampled,
+		})
+		ctx := trace.ContextWithSpanContext(context.Background(), sc)
+		if _, err := manager.SendMessage(ctx, &SendRequest{ThreadID: "thread-1", Sender: "user@test.com", Content: "Hello", AgentID: "agent-1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got := stream.getSentMessages()[0].GetSendMessage().GetTraceContext()["traceparent"]
+		want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"
+		if got != want {
+			t.Errorf("traceparent = %q, want %q", got, want)
+		}
+	})
+
 	t.Run("generates unique request ID", func(t *testing.T) {
 		manager := NewManager(slog.Default())
 		stream := newMockStream()
@@ -727,3 +751,104 @@ func TestConcurrentAccess(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+func TestManagerOutcomeObserver(t *testing.T) {
+	manager := NewManager(slog.Default())
+	outcomes := make(chan bool, 4)
+	manager.SetOutcomeObserver(func(agentID string, ok bool) {
+		if agentID != "agent-1" {
+			t.Errorf("outcome for %q, want agent-1", agentID)
+		}
+		outcomes <- ok
+	})
+
+	stream := newMockStream()
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Name: "Test Agent", Stream: stream, Logger: slog.Default()})
+	if err := manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	send := func(final *pb.MessageResponse) []*Response {
+		respChan, err := manager.SendMessage(context.Background(), &SendRequest{ThreadID: "t", Sender: "u", Content: "hi", AgentID: "agent-1"})
+		if err != nil {
+			t.Fatalf("SendMessage: %v", err)
+		}
+		sent := stream.getSentMessages()
+		final.RequestId = sent[len(sent)-1].GetSendMessage().GetRequestId()
+		conn.HandleResponse(final)
+		var got []*Response
+		for r := range respChan {
+			got = append(got, r)
+		}
+		return got
+	}
+
+	send(&pb.MessageResponse{Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "ok"}}})
+	send(&pb.MessageResponse{Event: &pb.MessageResponse_Error{Error: "boom"}})
+
+	for i, want := range []bool{true, false} {
+		select {
+		case got := <-outcomes:
+			if got != want {
+				t.Errorf("outcome %d = %v, want %v", i, got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("outcome %d not observed", i)
+		}
+	}
+
+	// A caller abandoning the request is not the agent's fault.
+	ctx, cancel := context.WithCancel(context.Background())
+	respChan, err := manager.SendMessage(ctx, &SendRequest{ThreadID: "t", Sender: "u", Content: "hi", AgentID: "agent-1"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	cancel()
+	for range respChan {
+	}
+	select {
+	case got := <-outcomes:
+		t.Errorf("canceled request recorded outcome %v", got)
+	default:
+	}
+}
+
+func TestManagerActivityTracksOpenToolCalls(t *testing.T) {
+	manager := NewManager(slog.Default())
+	stream := newMockStream()
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Name: "Test Agent", Stream: stream, Logger: slog.Default()})
+	if err := manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	respChan, err := manager.SendMessage(context.Background(), &SendRequest{ThreadID: "thread-1", Sender: "u", Content: "hi", AgentID: "agent-1"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	sent := stream.getSentMessages()
+	requestID := sent[len(sent)-1].GetSendMessage().GetRequestId()
+
+	deliver := func(resp *pb.MessageResponse) {
+		resp.RequestId = requestID
+		conn.HandleResponse(resp)
+		<-respChan
+	}
+	deliver(&pb.MessageResponse{Event: &pb.MessageResponse_ToolUse{ToolUse: &pb.ToolUse{Id: "t1", Name: "read", InputJson: `{"path":"a"}`}}})
+	deliver(&pb.MessageResponse{Event: &pb.MessageResponse_ToolUse{ToolUse: &pb.ToolUse{Id: "t2", Name: "bash", InputJson: `{"command":"ls"}`}}})
+	deliver(&pb.MessageResponse{Event: &pb.MessageResponse_ToolResult{ToolResult: &pb.ToolResult{Id: "t1", Output: "ok"}}})
+
+	act, ok := manager.Activity("agent-1")
+	if !ok || act.ThreadID != "thread-1" || act.RequestID != requestID {
+		t.Fatalf("Activity = %+v, %v; want thread-1 request", act, ok)
+	}
+	if len(act.ToolCalls) != 1 || act.ToolCalls[0].Name != "bash" {
+		t.Errorf("open tool calls = %+v, want only bash", act.ToolCalls)
+	}
+
+	deliver(&pb.MessageResponse{Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "ok"}}})
+	for range respChan {
+	}
+	if _, ok := manager.Activity("agent-1"); ok {
+		t.Error("activity still recorded after the request finished")
+	}
+}
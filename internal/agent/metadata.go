@@ -0,0 +1,417 @@
+// ABOUTME: Normalizes agent-supplied registration metadata before it is stored or rendered.
+// ABOUTME: Enforces per-field and total size limits, format checks, and reports truncations vs rejections.
+
+package agent
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// TruncationMarker is appended to string fields cut to fit their limit.
+const TruncationMarker = "…[truncated]"
+
+// Default metadata limits, used for any MetadataLimits field left at zero.
+const (
+	DefaultMaxMetadataFieldBytes = 1024
+	DefaultMaxWorkspaces         = 64
+	DefaultMaxTags               = 32
+	DefaultMaxProtocolFeatures   = 32
+	DefaultMaxMetadataTotalBytes = 16 * 1024
+)
+
+// KnownProtocolFeatures lists the protocol features the gateway understands.
+// Agents may also advertise experimental features prefixed with "x_".
+var KnownProtocolFeatures = []string{"token_usage", "tool_states", "injection", "cancellation", FeatureResume, FeatureHeartbeatAck, FeatureCapabilityUpdate}
+
+// FeatureResume marks agents that handle ResumeRequest for requests in
+// flight when a previous connection dropped.
+const FeatureResume = "resume"
+
+// experimentalFeaturePrefix marks agent-defined protocol features.
+const experimentalFeaturePrefix = "x_"
+
+const hostnameLabel = `[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?`
+
+var (
+	hostnamePattern = regexp.MustCompile(`^` + hostnameLabel + `(\.` + hostnameLabel + `)*$`)
+	tokenPattern    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,31}$`)
+	commitPattern   = regexp.MustCompile(`^[0-9a-f]{4,64}$`)
+	featurePattern  = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	tagKeyPattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9._/-]{0,62}$`)
+	tagValuePattern = regexp.MustCompile(`^[A-Za-z0-9._:/@+-]{1,128}$`)
+	windowsAbsPath  = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+)
+
+// maxHostnameBytes is the longest hostname DNS allows.
+const maxHostnameBytes = 253
+
+// MetadataLimits bounds the metadata an agent may register with. Zero fields
+// fall back to the defaults above.
+type MetadataLimits struct {
+	MaxFieldBytes int // longest single string value
+	MaxWorkspaces int // most workspace tags kept
+	MaxFeatures   int // most protocol features kept
+	MaxTotalBytes int // combined size of all string values
+	MaxTags       int // most tags kept
+}
+
+func (l MetadataLimits) withDefaults() MetadataLimits {
+	if l.MaxFieldBytes <= 0 {
+		l.MaxFieldBytes = DefaultMaxMetadataFieldBytes
+	}
+	if l.MaxWorkspaces <= 0 {
+		l.MaxWorkspaces = DefaultMaxWorkspaces
+	}
+	if l.MaxFeatures <= 0 {
+		l.MaxFeatures = DefaultMaxProtocolFeatures
+	}
+	if l.MaxTotalBytes <= 0 {
+		l.MaxTotalBytes = DefaultMaxMetadataTotalBytes
+	}
+	if l.MaxTags <= 0 {
+		l.MaxTags = DefaultMaxTags
+	}
+	return l
+}
+
+// GitInfo is the normalized git state an agent reported.
+type GitInfo struct {
+	Branch string `json:"branch,omitempty"`
+	Commit string `json:"commit,omitempty"`
+	Dirty  bool   `json:"dirty,omitempty"`
+	Remote string `json:"remote,omitempty"`
+	Ahead  int32  `json:"ahead,omitempty"`
+	Behind int32  `json:"behind,omitempty"`
+}
+
+// Metadata is the normalized form of an agent's registration metadata.
+type Metadata struct {
+	WorkingDir       string   `json:"working_dir,omitempty"`
+	Hostname         string   `json:"hostname,omitempty"`
+	OS               string   `json:"os,omitempty"`
+	Backend          string   `json:"backend,omitempty"`
+	Workspaces       []string `json:"workspaces,omitempty"`
+	Git              *GitInfo `json:"git,omitempty"`
+	ProtocolFeatures []string `json:"protocol_features,omitempty"`
+	// MaxConcurrent is the agent's hint for how many requests it can take at
+	// once; zero means no limit. Capability routing honors it.
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+	// Tags are key=value labels such as env=prod; sends and agent lists
+	// can select agents by them.
+	Tags map[string]string `json:"tags,omitempty"`
+	// Truncated names the fields that were cut short to fit the limits.
+	Truncated []string `json:"truncated,omitempty"`
+}
+
+// HasFeature reports whether the agent advertised a protocol feature.
+func (m *Metadata) HasFeature(feature string) bool {
+	return m != nil && slices.Contains(m.ProtocolFeatures, feature)
+}
+
+// MetadataIssueKind distinguishes values that were shortened from values
+// that were discarded.
+type MetadataIssueKind string
+
+const (
+	// MetadataTruncated means the value was kept but cut short.
+	MetadataTruncated MetadataIssueKind = "truncated"
+	// MetadataRejected means the value was invalid and dropped.
+	MetadataRejected MetadataIssueKind = "rejected"
+)
+
+// MetadataIssue records one change made while normalizing metadata.
+type MetadataIssue struct {
+	Field  string
+	Kind   MetadataIssueKind
+	Reason string
+}
+
+// metadataNormalizer accumulates issues while normalizing one registration.
+type metadataNormalizer struct {
+	limits MetadataLimits
+	meta   *Metadata
+	issues []MetadataIssue
+}
+
+func (n *metadataNormalizer) reject(field, format string, args ...any) {
+	n.issues = append(n.issues, MetadataIssue{Field: field, Kind: MetadataRejected, Reason: fmt.Sprintf(format, args...)})
+}
+
+func (n *metadataNormalizer) truncate(field, format string, args ...any) {
+	n.issues = append(n.issues, MetadataIssue{Field: field, Kind: MetadataTruncated, Reason: fmt.Sprintf(format, args...)})
+	if !slices.Contains(n.meta.Truncated, field) {
+		n.meta.Truncated = append(n.meta.Truncated, field)
+	}
+}
+
+// NormalizeMetadata validates and bounds the metadata and protocol features
+// in a registration. Invalid values are dropped and oversized values are cut
+// with TruncationMarker; every change is returned as an issue so callers can
+// log it. The result is always non-nil.
+func NormalizeMetadata(reg *pb.RegisterAgent, limits MetadataLimits) (*Metadata, []MetadataIssue) {
+	n := &metadataNormalizer{limits: limits.withDefaults(), meta: &Metadata{}}
+
+	n.checkUnknown("register", len(reg.ProtoReflect().GetUnknown()))
+	if md := reg.GetMetadata(); md != nil {
+		n.checkUnknown("metadata", len(md.ProtoReflect().GetUnknown()))
+		n.meta.WorkingDir = n.path("working_directory", md.GetWorkingDirectory())
+		n.meta.Hostname = n.hostname(md.GetHostname())
+		n.meta.OS = n.token("os", md.GetOs())
+		n.meta.Backend = n.token("backend", md.GetBackend())
+		n.meta.Workspaces = n.workspaces(md.GetWorkspaces())
+		n.meta.Tags = n.tags(md.GetTags())
+		if mc := md.GetMaxConcurrent(); mc < 0 {
+			n.reject("max_concurrent", "must not be negative, got %d", mc)
+		} else {
+			n.meta.MaxConcurrent = int(mc)
+		}
+		if git := md.GetGit(); git != nil {
+			n.checkUnknown("git", len(git.ProtoReflect().GetUnknown()))
+			n.meta.Git = n.git(git)
+		}
+	}
+	n.meta.ProtocolFeatures = n.features(reg.GetProtocolFeatures())
+	n.enforceTotal()
+	return n.meta, n.issues
+}
+
+// checkUnknown reports unknown protobuf fields that are too large to ignore.
+// They are never carried into the normalized form.
+func (n *metadataNormalizer) checkUnknown(field string, size int) {
+	if size > n.limits.MaxFieldBytes {
+		n.reject(field, "dropped %d bytes of unknown fields", size)
+	}
+}
+
+// text checks a free-form string for invalid UTF-8 and control characters
+// and cuts it to the field limit. ok is false when the value was rejected.
+func (n *metadataNormalizer) text(field, v string) (string, bool) {
+	if v == "" {
+		return "", true
+	}
+	if reason := invalidText(v); reason != "" {
+		n.reject(field, "%s", reason)
+		return "", false
+	}
+	if len(v) > n.limits.MaxFieldBytes {
+		n.truncate(field, "%d bytes exceeds limit of %d", len(v), n.limits.MaxFieldBytes)
+		v = truncateString(v, n.limits.MaxFieldBytes)
+	}
+	return v, true
+}
+
+func (n *metadataNormalizer) path(field, v string) string {
+	if v == "" {
+		return ""
+	}
+	if !strings.HasPrefix(v, "/") && !windowsAbsPath.MatchString(v) {
+		n.reject(field, "path is not absolute")
+		return ""
+	}
+	v, _ = n.text(field, v)
+	return v
+}
+
+func (n *metadataNormalizer) hostname(v string) string {
+	if v == "" {
+		return ""
+	}
+	v = strings.TrimSuffix(strings.ToLower(v), ".")
+	if len(v) > maxHostnameBytes || !hostnamePattern.MatchString(v) {
+		n.reject("hostname", "not a valid hostname")
+		return ""
+	}
+	return v
+}
+
+func (n *metadataNormalizer) token(field, v string) string {
+	if v == "" {
+		return ""
+	}
+	if !tokenPattern.MatchString(v) {
+		n.reject(field, "must be a short name of letters, digits, '.', '_' or '-'")
+		return ""
+	}
+	return v
+}
+
+func (n *metadataNormalizer) workspaces(in []string) []string {
+	var out []string
+	for i, raw := range in {
+		w, ok := n.text(fmt.Sprintf("workspaces[%d]", i), strings.TrimSpace(raw))
+		if !ok || w == "" || slices.Contains(out, w) {
+			continue
+		}
+		if len(out) == n.limits.MaxWorkspaces {
+			n.truncate("workspaces", "kept %d of %d workspaces", len(out), len(in))
+			break
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// tags keeps the well-formed tags, taking keys in sorted order so the same
+// registration always keeps the same tags when there are too many.
+func (n *metadataNormalizer) tags(in map[string]string) map[string]string {
+	var out map[string]string
+	for _, k := range slices.Sorted(maps.Keys(in)) {
+		if !tagKeyPattern.MatchString(k) {
+			n.reject("tags", "invalid key %.64q", k)
+			continue
+		}
+		v := strings.TrimSpace(in[k])
+		if !tagValuePattern.MatchString(v) {
+			n.reject("tags."+k, "value must be 1-128 letters, digits or '.', '_', ':', '/', '@', '+', '-'")
+			continue
+		}
+		if len(out) == n.limits.MaxTags {
+			n.truncate("tags", "kept %d of %d tags", len(out), len(in))
+			break
+		}
+		if out == nil {
+			out = make(map[string]string)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func (n *metadataNormalizer) git(in *pb.GitInfo) *GitInfo {
+	g := &GitInfo{Dirty: in.GetDirty()}
+	g.Branch, _ = n.text("git.branch", in.GetBranch())
+	if c := strings.ToLower(in.GetCommit()); c != "" {
+		if commitPattern.MatchString(c) {
+			g.Commit = c
+		} else {
+			n.reject("git.commit", "not a hex commit hash")
+		}
+	}
+	if remote, ok := n.text("git.remote", in.GetRemote()); ok {
+		g.Remote = redactRemote(remote)
+	}
+	if in.GetAhead() < 0 || in.GetBehind() < 0 {
+		n.reject("git.ahead_behind", "counts must not be negative")
+	} else {
+		g.Ahead, g.Behind = in.GetAhead(), in.GetBehind()
+	}
+	if *g == (GitInfo{}) {
+		return nil
+	}
+	return g
+}
+
+func (n *metadataNormalizer) features(in []string) []string {
+	var out []string
+	for _, f := range in {
+		if !featurePattern.MatchString(f) ||
+			(!slices.Contains(KnownProtocolFeatures, f) && !strings.HasPrefix(f, experimentalFeaturePrefix)) {
+			n.reject("protocol_features", "unknown feature %.64q", f)
+			continue
+		}
+		if slices.Contains(out, f) {
+			continue
+		}
+		if len(out) == n.limits.MaxFeatures {
+			n.truncate("protocol_features", "kept %d of %d features", len(out), len(in))
+			break
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// enforceTotal sheds list entries and tags, then the git remote, until the combined
+// size of all string values fits MaxTotalBytes.
+func (n *metadataNormalizer) enforceTotal() {
+	m := n.meta
+	total := m.size()
+	if total <= n.limits.MaxTotalBytes {
+		return
+	}
+	reason := fmt.Sprintf("metadata totals %d bytes, limit is %d", total, n.limits.MaxTotalBytes)
+	for len(m.Workspaces) > 0 && m.size() > n.limits.MaxTotalBytes {
+		m.Workspaces = m.Workspaces[:len(m.Workspaces)-1]
+		if !slices.Contains(m.Truncated, "workspaces") {
+			n.truncate("workspaces", "%s", reason)
+		}
+	}
+	for len(m.Tags) > 0 && m.size() > n.limits.MaxTotalBytes {
+		delete(m.Tags, slices.Max(slices.Collect(maps.Keys(m.Tags))))
+		if !slices.Contains(m.Truncated, "tags") {
+			n.truncate("tags", "%s", reason)
+		}
+	}
+	for len(m.ProtocolFeatures) > 0 && m.size() > n.limits.MaxTotalBytes {
+		m.ProtocolFeatures = m.ProtocolFeatures[:len(m.ProtocolFeatures)-1]
+		if !slices.Contains(m.Truncated, "protocol_features") {
+			n.truncate("protocol_features", "%s", reason)
+		}
+	}
+	if m.Git != nil && m.Git.Remote != "" && m.size() > n.limits.MaxTotalBytes {
+		m.Git.Remote = ""
+		n.reject("git.remote", "%s", reason)
+	}
+}
+
+// size is the combined length of the metadata's string values.
+func (m *Metadata) size() int {
+	total := len(m.WorkingDir) + len(m.Hostname) + len(m.OS) + len(m.Backend)
+	for _, w := range m.Workspaces {
+		total += len(w)
+	}
+	for k, v := range m.Tags {
+		total += len(k) + len(v)
+	}
+	for _, f := range m.ProtocolFeatures {
+		total += len(f)
+	}
+	if m.Git != nil {
+		total += len(m.Git.Branch) + len(m.Git.Commit) + len(m.Git.Remote)
+	}
+	return total
+}
+
+// invalidText explains why v cannot be stored, or returns "" if it can.
+func invalidText(v string) string {
+	if !utf8.ValidString(v) {
+		return "invalid UTF-8"
+	}
+	if strings.IndexFunc(v, unicode.IsControl) >= 0 {
+		return "contains control characters"
+	}
+	return ""
+}
+
+// truncateString cuts v to at most limit bytes, including TruncationMarker,
+// without splitting a UTF-8 sequence.
+func truncateString(v string, limit int) string {
+	marker := TruncationMarker
+	keep := limit - len(marker)
+	if keep <= 0 {
+		marker, keep = "", limit
+	}
+	for keep > 0 && !utf8.RuneStart(v[keep]) {
+		keep--
+	}
+	return v[:keep] + marker
+}
+
+// redactRemote strips credentials from a URL-style git remote.
+func redactRemote(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil || u.Scheme == "" {
+		return remote
+	}
+	u.User = nil
+	return u.String()
+}
@@ -0,0 +1,76 @@
+// ABOUTME: Ending a request whose agent sent a response over the gateway's size limit
+// ABOUTME: The caller gets a payload_too_large error while the agent stays connected
+
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/logctx"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// ErrorCodePayloadTooLarge is the Code of the error response that ends a
+// request whose agent sent a response larger than the gateway accepts.
+const ErrorCodePayloadTooLarge = coverr.PayloadTooLarge
+
+// RejectOversized ends requestID because its agent sent a response of size
+// bytes, over limit. The request's caller gets an ErrorCodePayloadTooLarge
+// error. It reports false if no request is pending for requestID.
+func (c *Connection) RejectOversized(requestID string, size, limit int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch, ok := c.pending[requestID]
+	if !ok {
+		return false
+	}
+	if c.oversized == nil {
+		c.oversized = make(map[string]string)
+	}
+	c.oversized[requestID] = fmt.Sprintf("agent response of %d bytes is over the %d byte limit", size, limit)
+	close(ch)
+	delete(c.pending, requestID)
+	delete(c.resumes, requestID)
+	return true
+}
+
+// takeOversized returns, and forgets, why RejectOversized ended requestID on
+// this connection or any that succeeded it; "" if it did not.
+func (c *Connection) takeOversized(requestID string) string {
+	for c != nil {
+		c.mu.Lock()
+		reason, ok := c.oversized[requestID]
+		delete(c.oversized, requestID)
+		next := c.successor
+		c.mu.Unlock()
+		if ok {
+			return reason
+		}
+		c = next
+	}
+	return ""
+}
+
+// rejectOversized cancels a request whose response was too large at its
+// agent and returns the final response for the caller.
+func (m *Manager) rejectOversized(ctx context.Context, agent *Connection, requestID, reason string) *Response {
+	log := logctx.FromContext(ctx, m.logger)
+	code := string(ErrorCodePayloadTooLarge)
+	cancel := &pb.ServerMessage{
+		Payload: &pb.ServerMessage_CancelRequest{
+			CancelRequest: &pb.CancelRequest{RequestId: requestID, Reason: &code},
+		},
+	}
+	if err := agent.Send(cancel); err != nil {
+		log.Warn("failed to cancel oversized request", "agent_id", agent.ID, "agent_request_id", requestID, "error", err)
+	}
+	log.Warn("agent response over the size limit", "agent_id", agent.ID, "agent_request_id", requestID, "reason", reason)
+	return &Response{
+		Event: EventError,
+		Error: reason,
+		Code:  ErrorCodePayloadTooLarge,
+		Done:  true,
+	}
+}
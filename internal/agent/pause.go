@@ -0,0 +1,75 @@
+// ABOUTME: Paused state for agents that stay connected but must not receive new work.
+// ABOUTME: SendMessage rejects paused agents with a PausedError naming who paused them.
+
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+)
+
+// ErrAgentPaused indicates the agent is paused and not accepting new messages.
+// Use errors.As with *PausedError to get who paused it and when.
+var ErrAgentPaused = coverr.New(coverr.AgentPaused, "agent paused")
+
+// PauseInfo records who paused an agent and when.
+type PauseInfo struct {
+	PausedBy string
+	PausedAt time.Time
+}
+
+// PausedError is returned when sending to a paused agent.
+type PausedError struct {
+	AgentID string
+	PauseInfo
+}
+
+func (e *PausedError) Error() string {
+	return fmt.Sprintf("agent %s paused by %s at %s", e.AgentID, e.PausedBy, e.PausedAt.UTC().Format(time.RFC3339))
+}
+
+// Unwrap returns ErrAgentPaused, so the error matches it and carries its code.
+func (e *PausedError) Unwrap() error {
+	return ErrAgentPaused
+}
+
+// Pause marks an agent as paused. The agent may be connected or not; the state
+// applies whenever it is connected until Resume is called.
+func (m *Manager) Pause(agentID string, info PauseInfo) {
+	m.mu.Lock()
+	m.paused[agentID] = info
+	m.mu.Unlock()
+
+	m.logger.Info("agent paused", "agent_id", agentID, "paused_by", info.PausedBy)
+}
+
+// Resume clears an agent's paused state. Returns false if it was not paused.
+func (m *Manager) Resume(agentID string) bool {
+	m.mu.Lock()
+	_, was := m.paused[agentID]
+	delete(m.paused, agentID)
+	m.mu.Unlock()
+
+	if was {
+		m.logger.Info("agent resumed", "agent_id", agentID)
+	}
+	return was
+}
+
+// PauseState returns the agent's pause info and whether it is paused.
+func (m *Manager) PauseState(agentID string) (PauseInfo, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	info, ok := m.paused[agentID]
+	return info, ok
+}
+
+// CheckNotPaused returns a *PausedError if the agent is paused, nil otherwise.
+func (m *Manager) CheckNotPaused(agentID string) error {
+	if info, ok := m.PauseState(agentID); ok {
+		return &PausedError{AgentID: agentID, PauseInfo: info}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// ABOUTME: Tests for pausing and resuming agents.
+// ABOUTME: Covers send rejection, the structured error, and listing pause state.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestManagerPause(t *testing.T) {
+	manager := NewManager(slog.Default())
+	stream := newMockStream()
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Name: "Test Agent", Stream: stream, Logger: slog.Default()})
+	if err := manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	pausedAt := time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC)
+	manager.Pause("agent-1", PauseInfo{PausedBy: "admin", PausedAt: pausedAt})
+
+	req := &SendRequest{ThreadID: "thread-1", Sender: "user", Content: "hi", AgentID: "agent-1"}
+	_, err := manager.SendMessage(context.Background(), req)
+	if !errors.Is(err, ErrAgentPaused) {
+		t.Fatalf("SendMessage error = %v, want ErrAgentPaused", err)
+	}
+	var pausedErr *PausedError
+	if !errors.As(err, &pausedErr) {
+		t.Fatalf("error %T is not *PausedError", err)
+	}
+	if pausedErr.AgentID != "agent-1" || pausedErr.PausedBy != "admin" || !pausedErr.PausedAt.Equal(pausedAt) {
+		t.Errorf("PausedError = %+v, want agent-1 paused by admin", pausedErr)
+	}
+	if len(stream.getSentMessages()) != 0 {
+		t.Error("paused agent received a message")
+	}
+
+	agents := manager.ListAgents()
+	if len(agents) != 1 || agents[0].Paused == nil || agents[0].Paused.PausedBy != "admin" {
+		t.Errorf("ListAgents pause info = %+v, want paused by admin", agents[0].Paused)
+	}
+
+	if !manager.Resume("agent-1") {
+		t.Error("Resume returned false for a paused agent")
+	}
+	if manager.Resume("agent-1") {
+		t.Error("Resume returned true for an agent that was not paused")
+	}
+	if _, err := manager.SendMessage(context.Background(), req); err != nil {
+		t.Fatalf("SendMessage after resume: %v", err)
+	}
+	if len(stream.getSentMessages()) != 1 {
+		t.Error("resumed agent did not receive the message")
+	}
+}
+
+func TestManagerPause_SurvivesReregistration(t *testing.T) {
+	manager := NewManager(slog.Default())
+	manager.Pause("agent-1", PauseInfo{PausedBy: "admin", PausedAt: time.Now()})
+
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Name: "Test Agent", Stream: newMockStream(), Logger: slog.Default()})
+	if err := manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	manager.Unregister("agent-1")
+	if err := manager.Register(conn); err != nil {
+		t.Fatalf("Register again: %v", err)
+	}
+
+	if err := manager.CheckNotPaused("agent-1"); !errors.Is(err, ErrAgentPaused) {
+		t.Errorf("CheckNotPaused = %v, want ErrAgentPaused after reconnect", err)
+	}
+}
@@ -0,0 +1,140 @@
+// ABOUTME: Tracks agents whose principal is connected but still awaiting admin approval.
+// ABOUTME: Lets the approval path push the decision to the waiting stream without a reconnect.
+
+package agent
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// ApprovalDecision is the outcome delivered to an agent awaiting approval.
+type ApprovalDecision int
+
+const (
+	// ApprovalApproved means the agent may complete registration.
+	ApprovalApproved ApprovalDecision = iota + 1
+	// ApprovalRevoked means the agent must disconnect.
+	ApprovalRevoked
+)
+
+// String returns the decision name for logging.
+func (d ApprovalDecision) String() string {
+	switch d {
+	case ApprovalApproved:
+		return "approved"
+	case ApprovalRevoked:
+		return "revoked"
+	default:
+		return "unknown"
+	}
+}
+
+// PendingAgent describes an agent connected while its principal is pending.
+type PendingAgent struct {
+	AgentID     string
+	Name        string
+	PrincipalID string
+	ConnectedAt time.Time
+	// Fingerprint is the SSH key fingerprint the principal registered with.
+	Fingerprint string
+	// Metadata is what the agent reported about its host and repository.
+	Metadata *Metadata
+	// NewPrincipal is set when this connection auto-registered the principal.
+	NewPrincipal bool
+}
+
+// pendingAgent pairs a waiting agent with its decision channel.
+type pendingAgent struct {
+	info      PendingAgent
+	decisions chan ApprovalDecision
+}
+
+// SetPendingObserver registers a function called with each agent that starts
+// waiting for approval, after it is listed by ListPending. Call before agents
+// connect.
+func (m *Manager) SetPendingObserver(fn func(PendingAgent)) {
+	m.observePending = fn
+}
+
+// AddPending records an agent that is connected but awaiting approval and
+// returns the channel on which the admin decision will be delivered.
+// Returns ErrAgentAlreadyRegistered if the agent ID is already in use.
+func (m *Manager) AddPending(info PendingAgent) (<-chan ApprovalDecision, error) {
+	m.mu.Lock()
+	if _, exists := m.agents[info.AgentID]; exists {
+		m.mu.Unlock()
+		return nil, ErrAgentAlreadyRegistered
+	}
+	if _, exists := m.pending[info.AgentID]; exists {
+		m.mu.Unlock()
+		return nil, ErrAgentAlreadyRegistered
+	}
+
+	entry := &pendingAgent{info: info, decisions: make(chan ApprovalDecision, 1)}
+	m.pending[info.AgentID] = entry
+	m.logger.Info("agent awaiting approval",
+		"agent_id", info.AgentID,
+		"principal_id", info.PrincipalID,
+		"total_pending", len(m.pending),
+	)
+	m.mu.Unlock()
+
+	if m.observePending != nil {
+		m.observePending(info)
+	}
+	return entry.decisions, nil
+}
+
+// RemovePending forgets a waiting agent, typically when its stream ends or
+// after it has received a decision.
+func (m *Manager) RemovePending(agentID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pending, agentID)
+}
+
+// ResolvePending delivers an approval decision to every connected agent of
+// the given principal and returns how many agents were notified.
+func (m *Manager) ResolvePending(principalID string, decision ApprovalDecision) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notified := 0
+	for id, entry := range m.pending {
+		if entry.info.PrincipalID != principalID {
+			continue
+		}
+		// The channel is buffered and each entry is resolved once.
+		entry.decisions <- decision
+		delete(m.pending, id)
+		notified++
+	}
+	if notified > 0 {
+		m.logger.Info("resolved pending agents",
+			"principal_id", principalID,
+			"decision", decision,
+			"agents", notified,
+		)
+	}
+	return notified
+}
+
+// ListPending returns the agents currently awaiting approval, oldest first.
+func (m *Manager) ListPending() []PendingAgent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]PendingAgent, 0, len(m.pending))
+	for _, entry := range m.pending {
+		result = append(result, entry.info)
+	}
+	slices.SortFunc(result, func(a, b PendingAgent) int {
+		if c := a.ConnectedAt.Compare(b.ConnectedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.AgentID, b.AgentID)
+	})
+	return result
+}
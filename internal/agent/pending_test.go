@@ -0,0 +1,121 @@
+// ABOUTME: Tests for tracking agents that await principal approval.
+// ABOUTME: Covers decision delivery, duplicate IDs, and listing order.
+
+package agent
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestManagerPending(t *testing.T) {
+	t.Run("delivers decision to every agent of the principal", func(t *testing.T) {
+		m := NewManager(slog.Default())
+
+		first, err := m.AddPending(PendingAgent{AgentID: "a1", PrincipalID: "p1"})
+		if err != nil {
+			t.Fatalf("AddPending a1: %v", err)
+		}
+		second, err := m.AddPending(PendingAgent{AgentID: "a2", PrincipalID: "p1"})
+		if err != nil {
+			t.Fatalf("AddPending a2: %v", err)
+		}
+		other, err := m.AddPending(PendingAgent{AgentID: "a3", PrincipalID: "p2"})
+		if err != nil {
+			t.Fatalf("AddPending a3: %v", err)
+		}
+
+		if n := m.ResolvePending("p1", ApprovalApproved); n != 2 {
+			t.Errorf("ResolvePending notified %d agents, want 2", n)
+		}
+
+		for name, ch := range map[string]<-chan ApprovalDecision{"a1": first, "a2": second} {
+			select {
+			case d := <-ch:
+				if d != ApprovalApproved {
+					t.Errorf("%s decision = %v, want approved", name, d)
+				}
+			default:
+				t.Errorf("%s did not receive a decision", name)
+			}
+		}
+
+		select {
+		case d := <-other:
+			t.Errorf("a3 received unexpected decision %v", d)
+		default:
+		}
+
+		pending := m.ListPending()
+		if len(pending) != 1 || pending[0].AgentID != "a3" {
+			t.Errorf("ListPending = %+v, want only a3", pending)
+		}
+	})
+
+	t.Run("resolving an unknown principal is a no-op", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		if n := m.ResolvePending("nobody", ApprovalRevoked); n != 0 {
+			t.Errorf("ResolvePending notified %d agents, want 0", n)
+		}
+	})
+
+	t.Run("rejects IDs that are registered or already waiting", func(t *testing.T) {
+		m := NewManager(slog.Default())
+
+		if _, err := m.AddPending(PendingAgent{AgentID: "a1", PrincipalID: "p1"}); err != nil {
+			t.Fatalf("AddPending: %v", err)
+		}
+		if _, err := m.AddPending(PendingAgent{AgentID: "a1", PrincipalID: "p1"}); !errors.Is(err, ErrAgentAlreadyRegistered) {
+			t.Errorf("second AddPending error = %v, want ErrAgentAlreadyRegistered", err)
+		}
+
+		conn := NewConnection(ConnectionParams{ID: "a1", Stream: newMockStream(), Logger: slog.Default()})
+		if err := m.Register(conn); !errors.Is(err, ErrAgentAlreadyRegistered) {
+			t.Errorf("Register error = %v, want ErrAgentAlreadyRegistered", err)
+		}
+
+		m.RemovePending("a1")
+		if err := m.Register(conn); err != nil {
+			t.Errorf("Register after RemovePending: %v", err)
+		}
+		if _, err := m.AddPending(PendingAgent{AgentID: "a1", PrincipalID: "p1"}); !errors.Is(err, ErrAgentAlreadyRegistered) {
+			t.Errorf("AddPending for registered agent error = %v, want ErrAgentAlreadyRegistered", err)
+		}
+	})
+
+	t.Run("lists oldest first", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		now := time.Now()
+
+		_, _ = m.AddPending(PendingAgent{AgentID: "newer", PrincipalID: "p1", ConnectedAt: now})
+		_, _ = m.AddPending(PendingAgent{AgentID: "older", PrincipalID: "p2", ConnectedAt: now.Add(-time.Minute)})
+
+		pending := m.ListPending()
+		if len(pending) != 2 || pending[0].AgentID != "older" || pending[1].AgentID != "newer" {
+			t.Errorf("ListPending order = %+v, want older then newer", pending)
+		}
+	})
+	t.Run("tells the observer about each waiting agent", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		var observed []PendingAgent
+		m.SetPendingObserver(func(p PendingAgent) {
+			// Already listed, so a notified admin can see it
+			if len(m.ListPending()) != len(observed)+1 {
+				t.Errorf("observer ran before %s was listed", p.AgentID)
+			}
+			observed = append(observed, p)
+		})
+
+		meta := &Metadata{Hostname: "build-box"}
+		if _, err := m.AddPending(PendingAgent{AgentID: "a1", PrincipalID: "p1", Metadata: meta, NewPrincipal: true}); err != nil {
+			t.Fatalf("AddPending: %v", err)
+		}
+		_, _ = m.AddPending(PendingAgent{AgentID: "a1", PrincipalID: "p1"})
+
+		if len(observed) != 1 || observed[0].Metadata != meta || !observed[0].NewPrincipal {
+			t.Errorf("observed = %+v, want a1 once with its metadata", observed)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+// ABOUTME: Plan events: the steps an agent intends to take and per-step progress.
+// ABOUTME: Each event carries a plain-text rendering for clients that do not draw plans.
+
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// PlanStep is one step of an agent's plan.
+type PlanStep struct {
+	Title  string
+	Status string // "pending", "in_progress", "completed", "skipped", "failed"
+}
+
+// PlanEvent is the plan an agent announces before executing. A later plan in
+// the same request replaces it.
+type PlanEvent struct {
+	Steps []PlanStep
+}
+
+// Text renders the plan as a numbered list. Steps that are not pending carry
+// their status in parentheses.
+func (p *PlanEvent) Text() string {
+	var b strings.Builder
+	b.WriteString("Plan:")
+	for i, step := range p.Steps {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, step.Title)
+		if step.Status != "pending" {
+			fmt.Fprintf(&b, " (%s)", step.Status)
+		}
+	}
+	return b.String()
+}
+
+// PlanStepEvent is a status change for one step of the latest plan.
+type PlanStepEvent struct {
+	Index  int // zero-based index into PlanEvent.Steps
+	Status string
+	Detail string
+}
+
+// Text renders the update as a single line, numbering steps from one.
+func (e *PlanStepEvent) Text() string {
+	text := fmt.Sprintf("Step %d %s", e.Index+1, e.Status)
+	if e.Detail != "" {
+		text += ": " + e.Detail
+	}
+	return text
+}
+
+func buildPlanResponse(event *pb.MessageResponse_Plan) *Response {
+	steps := make([]PlanStep, len(event.Plan.GetSteps()))
+	for i, s := range event.Plan.GetSteps() {
+		steps[i] = PlanStep{Title: s.GetTitle(), Status: planStepStatusToString(s.GetStatus())}
+	}
+	return &Response{Event: EventPlan, Plan: &PlanEvent{Steps: steps}}
+}
+
+func buildPlanStepUpdateResponse(event *pb.MessageResponse_PlanStepUpdate) *Response {
+	return &Response{
+		Event: EventPlanStepUpdate,
+		PlanStep: &PlanStepEvent{
+			Index:  int(event.PlanStepUpdate.GetIndex()),
+			Status: planStepStatusToString(event.PlanStepUpdate.GetStatus()),
+			Detail: event.PlanStepUpdate.GetDetail(),
+		},
+	}
+}
+
+// convertPlanEvent handles plan events.
+func convertPlanEvent(event any) *Response {
+	switch e := event.(type) {
+	case *pb.MessageResponse_Plan:
+		return buildPlanResponse(e)
+	case *pb.MessageResponse_PlanStepUpdate:
+		return buildPlanStepUpdateResponse(e)
+	}
+	return nil
+}
+
+// planStepStatusToString converts a pb.PlanStepStatus enum to a string.
+// Unspecified is treated as pending, the status of a freshly announced step.
+func planStepStatusToString(status pb.PlanStepStatus) string {
+	switch status {
+	case pb.PlanStepStatus_PLAN_STEP_STATUS_IN_PROGRESS:
+		return "in_progress"
+	case pb.PlanStepStatus_PLAN_STEP_STATUS_COMPLETED:
+		return "completed"
+	case pb.PlanStepStatus_PLAN_STEP_STATUS_SKIPPED:
+		return "skipped"
+	case pb.PlanStepStatus_PLAN_STEP_STATUS_FAILED:
+		return "failed"
+	default:
+		return "pending"
+	}
+}
@@ -411,6 +411,71 @@ func TestConvertToolStateEvent(t *testing.T) {
 	})
 }
 
+func TestConvertPlanEvent(t *testing.T) {
+	t.Run("converts plan with unspecified status as pending", func(t *testing.T) {
+		event := &pb.MessageResponse_Plan{Plan: &pb.Plan{Steps: []*pb.PlanStep{
+			{Title: "check logs"},
+			{Title: "restart service", Status: pb.PlanStepStatus_PLAN_STEP_STATUS_IN_PROGRESS},
+		}}}
+		resp := convertPlanEvent(event)
+		require.NotNil(t, resp)
+		assert.Equal(t, EventPlan, resp.Event)
+		require.Len(t, resp.Plan.Steps, 2)
+		assert.Equal(t, PlanStep{Title: "check logs", Status: "pending"}, resp.Plan.Steps[0])
+		assert.Equal(t, "Plan:\n1. check logs\n2. restart service (in_progress)", resp.Plan.Text())
+	})
+
+	t.Run("converts step update", func(t *testing.T) {
+		detail := "permission denied"
+		event := &pb.MessageResponse_PlanStepUpdate{PlanStepUpdate: &pb.PlanStepUpdate{
+			Index: 1, Status: pb.PlanStepStatus_PLAN_STEP_STATUS_FAILED, Detail: &detail,
+		}}
+		resp := convertPlanEvent(event)
+		require.NotNil(t, resp)
+		assert.Equal(t, EventPlanStepUpdate, resp.Event)
+		assert.Equal(t, &PlanStepEvent{Index: 1, Status: "failed", Detail: detail}, resp.PlanStep)
+		assert.Equal(t, "Step 2 failed: permission denied", resp.PlanStep.Text())
+	})
+
+	t.Run("returns nil for unknown event", func(t *testing.T) {
+		assert.Nil(t, convertPlanEvent("invalid"))
+	})
+}
+
+func TestConvertCitationEvent(t *testing.T) {
+	t.Run("converts citation", func(t *testing.T) {
+		snippet := "HTTP is stateless"
+		event := &pb.MessageResponse_Citation{Citation: &pb.Citation{
+			Index: 2, Title: "RFC 9110", Uri: "https://www.rfc-editor.org/rfc/rfc9110", Snippet: &snippet,
+		}}
+		resp := convertCitationEvent(event)
+		require.NotNil(t, resp)
+		assert.Equal(t, EventCitation, resp.Event)
+		assert.Equal(t, &CitationEvent{Index: 2, Title: "RFC 9110", URI: "https://www.rfc-editor.org/rfc/rfc9110", Snippet: snippet}, resp.Citation)
+		assert.Equal(t, "[2] RFC 9110 <https://www.rfc-editor.org/rfc/rfc9110>", resp.Citation.Text())
+	})
+
+	t.Run("returns nil for unknown event", func(t *testing.T) {
+		assert.Nil(t, convertCitationEvent("invalid"))
+	})
+}
+
+func TestSources(t *testing.T) {
+	var s Sources
+	assert.Nil(t, s.List())
+
+	s.Add(&CitationEvent{Index: 3, URI: "https://c.example"})
+	s.Add(&CitationEvent{Index: 1, Title: "draft", URI: "https://a.example"})
+	s.Add(nil)
+	s.Add(&CitationEvent{Index: 1, Title: "final", URI: "https://a.example"})
+
+	list := s.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, 1, list[0].Index)
+	assert.Equal(t, "final", list[0].Title, "a repeated index replaces the earlier citation")
+	assert.Equal(t, "[3] https://c.example", list[1].Text())
+}
+
 // =============================================================================
 // Manager ConvertResponse Tests
 // =============================================================================
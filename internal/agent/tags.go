@@ -0,0 +1,102 @@
+// ABOUTME: Tag filters and tag-addressed sends for agents that registered key=value tags.
+// ABOUTME: SelectByTags picks the one agent carrying every requested tag, or any of them on request.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+)
+
+// ErrNoTaggedAgent indicates no connected agent carries every requested tag.
+var ErrNoTaggedAgent = coverr.New(coverr.NoCapableAgent, "no agent available with tags")
+
+// ErrAmbiguousTags indicates several agents carry every requested tag and the
+// send did not allow any of them. Use errors.As with *AmbiguousTagsError to
+// get the candidates.
+var ErrAmbiguousTags = coverr.New(coverr.Conflict, "tags match more than one agent")
+
+// AmbiguousTagsError lists the agents that matched a tag-addressed send. It
+// matches ErrAmbiguousTags with errors.Is.
+type AmbiguousTagsError struct {
+	Candidates []string // matching agent IDs, sorted
+}
+
+func (e *AmbiguousTagsError) Error() string {
+	return fmt.Sprintf("%s: %s; name one with agent_id or set any", ErrAmbiguousTags, strings.Join(e.Candidates, ", "))
+}
+
+// Unwrap returns ErrAmbiguousTags, so the error matches it and carries its code.
+func (e *AmbiguousTagsError) Unwrap() error {
+	return ErrAmbiguousTags
+}
+
+// ParseTagFilter parses key=value filters, as given in repeated ?tag=
+// parameters, into the tags an agent must carry.
+func ParseTagFilter(filters []string) (map[string]string, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	tags := make(map[string]string, len(filters))
+	for _, f := range filters {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok || k == "" || v == "" {
+			return nil, fmt.Errorf("invalid tag filter %q: want key=value", f)
+		}
+		if prev, dup := tags[k]; dup && prev != v {
+			return nil, fmt.Errorf("tag %q given twice", k)
+		}
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+// MatchesTags reports whether the agent registered every tag in want with
+// the same value. Any agent matches an empty want.
+func (m *Metadata) MatchesTags(want map[string]string) bool {
+	for k, v := range want {
+		if m == nil || m.Tags[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// SelectByTags picks the agent for a send addressed to tags. When exactly one
+// connected agent carries every tag it is chosen, paused or not, so the send
+// fails the way a send naming it would. When several do, the send is refused
+// with an *AmbiguousTagsError unless pickAny is set, in which case one is
+// chosen by the SelectByCapability rules.
+func (m *Manager) SelectByTags(ctx context.Context, tags map[string]string, pickAny bool) (*Connection, Route, error) {
+	match := func(c *Connection) bool { return c.Metadata.MatchesTags(tags) }
+	if pickAny {
+		return m.selectWhere(ctx, match, ErrNoTaggedAgent)
+	}
+
+	m.mu.RLock()
+	var matched []*Connection
+	for _, conn := range m.agents {
+		if match(conn) {
+			matched = append(matched, conn)
+		}
+	}
+	m.mu.RUnlock()
+
+	route := Route{Candidates: len(matched), Eligible: len(matched)}
+	switch len(matched) {
+	case 0:
+		return nil, route, ErrNoTaggedAgent
+	case 1:
+		return matched[0], route, nil
+	}
+	ids := make([]string, len(matched))
+	for i, conn := range matched {
+		ids[i] = conn.ID
+	}
+	slices.Sort(ids)
+	return nil, route, &AmbiguousTagsError{Candidates: ids}
+}
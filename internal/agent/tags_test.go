@@ -0,0 +1,129 @@
+// ABOUTME: Tests for tag filters and tag-addressed agent selection.
+// ABOUTME: Covers filter parsing, matching, and the none/one/ambiguous/any cases of SelectByTags.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func addTaggedAgent(t *testing.T, m *Manager, id string, tags map[string]string) *Connection {
+	t.Helper()
+	conn := NewConnection(ConnectionParams{
+		ID: id, Name: id, Stream: newMockStream(), Logger: slog.Default(),
+		Metadata: &Metadata{Tags: tags},
+	})
+	if err := m.Register(conn); err != nil {
+		t.Fatalf("register %s: %v", id, err)
+	}
+	return conn
+}
+
+func TestParseTagFilter(t *testing.T) {
+	got, err := ParseTagFilter([]string{"env=prod", "team=infra", "env=prod"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if want := map[string]string{"env": "prod", "team": "infra"}; !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got, err := ParseTagFilter(nil); err != nil || got != nil {
+		t.Errorf("empty filter = %v, %v; want nil, nil", got, err)
+	}
+
+	for _, bad := range [][]string{{"env"}, {"=prod"}, {"env="}, {"env=prod", "env=dev"}} {
+		if _, err := ParseTagFilter(bad); err == nil {
+			t.Errorf("ParseTagFilter(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestMetadataMatchesTags(t *testing.T) {
+	md := &Metadata{Tags: map[string]string{"env": "prod", "team": "infra"}}
+	cases := []struct {
+		want  map[string]string
+		match bool
+	}{
+		{nil, true},
+		{map[string]string{"env": "prod"}, true},
+		{map[string]string{"env": "prod", "team": "infra"}, true},
+		{map[string]string{"env": "dev"}, false},
+		{map[string]string{"region": "us"}, false},
+	}
+	for _, c := range cases {
+		if got := md.MatchesTags(c.want); got != c.match {
+			t.Errorf("MatchesTags(%v) = %v, want %v", c.want, got, c.match)
+		}
+	}
+
+	var none *Metadata
+	if !none.MatchesTags(nil) || none.MatchesTags(map[string]string{"env": "prod"}) {
+		t.Error("nil metadata should match only an empty filter")
+	}
+}
+
+func TestSelectByTags(t *testing.T) {
+	ctx := context.Background()
+	prod := map[string]string{"env": "prod"}
+
+	t.Run("no match", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		addTaggedAgent(t, m, "a", map[string]string{"env": "dev"})
+		if _, _, err := m.SelectByTags(ctx, prod, false); !errors.Is(err, ErrNoTaggedAgent) {
+			t.Fatalf("err = %v, want ErrNoTaggedAgent", err)
+		}
+		if _, _, err := m.SelectByTags(ctx, prod, true); !errors.Is(err, ErrNoTaggedAgent) {
+			t.Fatalf("any: err = %v, want ErrNoTaggedAgent", err)
+		}
+	})
+
+	t.Run("single match", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		addTaggedAgent(t, m, "dev", map[string]string{"env": "dev"})
+		addTaggedAgent(t, m, "prod", map[string]string{"env": "prod", "team": "infra"})
+		conn, route, err := m.SelectByTags(ctx, prod, false)
+		if err != nil {
+			t.Fatalf("select: %v", err)
+		}
+		if conn.ID != "prod" || route.Candidates != 1 {
+			t.Errorf("picked %s with %+v, want prod of 1", conn.ID, route)
+		}
+	})
+
+	t.Run("ambiguous lists candidates", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		addTaggedAgent(t, m, "b", prod)
+		addTaggedAgent(t, m, "a", prod)
+		_, route, err := m.SelectByTags(ctx, prod, false)
+		if !errors.Is(err, ErrAmbiguousTags) {
+			t.Fatalf("err = %v, want ErrAmbiguousTags", err)
+		}
+		var amb *AmbiguousTagsError
+		if !errors.As(err, &amb) || !slices.Equal(amb.Candidates, []string{"a", "b"}) {
+			t.Errorf("candidates = %v, want [a b]", amb)
+		}
+		if route.Candidates != 2 {
+			t.Errorf("route candidates = %d, want 2", route.Candidates)
+		}
+	})
+
+	t.Run("any picks least loaded", func(t *testing.T) {
+		m := NewManager(slog.Default())
+		addTaggedAgent(t, m, "busy", prod)
+		addTaggedAgent(t, m, "idle", prod)
+		m.beginLoad("busy")
+		conn, _, err := m.SelectByTags(ctx, prod, true)
+		if err != nil {
+			t.Fatalf("select: %v", err)
+		}
+		if conn.ID != "idle" {
+			t.Errorf("picked %s, want idle", conn.ID)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+// ABOUTME: Tool approval policy hook: lets the gateway answer an agent's tool approval requests itself.
+// ABOUTME: Requests the policy decides never reach the client; the rest pass through unchanged.
+
+package agent
+
+import (
+	"context"
+
+	"github.com/2389/coven-gateway/internal/logctx"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// SetToolApprovalPolicy registers a function consulted for each tool
+// approval request before it is passed to the client. It returns decided
+// true to answer the agent itself with approved, or false to let the client
+// be asked. Call before the manager starts handling requests.
+func (m *Manager) SetToolApprovalPolicy(fn func(agentID, threadID string, req *ToolApprovalRequestEvent) (approved, decided bool)) {
+	m.approvalPolicy = fn
+}
+
+// decideApproval answers req at the agent when the approval policy decides
+// it, reporting whether it did. If the answer cannot be sent the request is
+// left for the client.
+func (m *Manager) decideApproval(ctx context.Context, agent *Connection, threadID string, req *ToolApprovalRequestEvent) bool {
+	if m.approvalPolicy == nil {
+		return false
+	}
+	approved, decided := m.approvalPolicy(agent.ID, threadID, req)
+	if !decided {
+		return false
+	}
+
+	msg := &pb.ServerMessage{
+		Payload: &pb.ServerMessage_ToolApproval{
+			ToolApproval: &pb.ToolApprovalResponse{Id: req.ID, Approved: approved},
+		},
+	}
+	if err := agent.Send(msg); err != nil {
+		logctx.FromContext(ctx, m.logger).Warn("failed to send policy tool approval",
+			"agent_id", agent.ID,
+			"tool_id", req.ID,
+			"error", err,
+		)
+		return false
+	}
+
+	logctx.FromContext(ctx, m.logger).Info("tool approval decided by policy",
+		"agent_id", agent.ID,
+		"tool_id", req.ID,
+		"tool_name", req.Name,
+		"approved", approved,
+	)
+	return true
+}
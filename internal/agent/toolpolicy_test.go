@@ -0,0 +1,62 @@
+// ABOUTME: Tests for the tool approval policy hook: decided requests are answered at the agent
+// ABOUTME: and withheld from the client, undecided ones pass through.
+
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func approvalRequest(requestID, toolID, name string) *pb.MessageResponse {
+	return &pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_ToolApprovalRequest{
+		ToolApprovalRequest: &pb.ToolApprovalRequest{Id: toolID, Name: name, InputJson: `{}`},
+	}}
+}
+
+func TestManagerAppliesToolApprovalPolicy(t *testing.T) {
+	manager := NewManager(slog.Default())
+	var seenThread string
+	manager.SetToolApprovalPolicy(func(agentID, threadID string, req *ToolApprovalRequestEvent) (bool, bool) {
+		seenThread = threadID
+		switch req.Name {
+		case "read_file":
+			return true, true
+		case "rm":
+			return false, true
+		}
+		return false, false
+	})
+	conn, stream, requestID, respChan := sendIdle(t, manager, context.Background())
+
+	conn.HandleResponse(approvalRequest(requestID, "tool-1", "read_file"))
+	conn.HandleResponse(approvalRequest(requestID, "tool-2", "rm"))
+	conn.HandleResponse(approvalRequest(requestID, "tool-3", "deploy"))
+	conn.HandleResponse(doneResponse(requestID))
+
+	var asked []string
+	for resp := range respChan {
+		if resp.Event == EventToolApprovalRequest {
+			asked = append(asked, resp.ToolApprovalRequest.ID)
+		}
+	}
+	if len(asked) != 1 || asked[0] != "tool-3" {
+		t.Errorf("client asked about %v, want only tool-3", asked)
+	}
+	if seenThread != "thread-1" {
+		t.Errorf("policy saw thread %q, want thread-1", seenThread)
+	}
+
+	answers := map[string]bool{}
+	for _, msg := range stream.getSentMessages() {
+		if a := msg.GetToolApproval(); a != nil {
+			answers[a.GetId()] = a.GetApproved()
+		}
+	}
+	if len(answers) != 2 || !answers["tool-1"] || answers["tool-2"] {
+		t.Errorf("agent was answered %v, want tool-1 approved and tool-2 denied", answers)
+	}
+}
@@ -0,0 +1,45 @@
+// ABOUTME: Workspace targeting for requests sent to agents that registered several workspaces.
+// ABOUTME: SendMessage rejects a workspace the agent did not register with a WorkspaceError listing valid ones.
+
+package agent
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+)
+
+// ErrUnknownWorkspace indicates a request named a workspace its agent did not
+// register. Use errors.As with *WorkspaceError to get the valid workspaces.
+var ErrUnknownWorkspace = coverr.New(coverr.InvalidRequest, "unknown workspace")
+
+// WorkspaceError names the workspace a request asked for and the workspaces
+// its agent registered. It matches ErrUnknownWorkspace with errors.Is.
+type WorkspaceError struct {
+	AgentID   string
+	Workspace string
+	Valid     []string
+}
+
+func (e *WorkspaceError) Error() string {
+	if len(e.Valid) == 0 {
+		return fmt.Sprintf("%s %q: agent %s registered no workspaces", ErrUnknownWorkspace, e.Workspace, e.AgentID)
+	}
+	return fmt.Sprintf("%s %q: agent %s has workspaces %s", ErrUnknownWorkspace, e.Workspace, e.AgentID, strings.Join(e.Valid, ", "))
+}
+
+// Unwrap returns ErrUnknownWorkspace, so the error matches it and carries its code.
+func (e *WorkspaceError) Unwrap() error {
+	return ErrUnknownWorkspace
+}
+
+// CheckWorkspace returns a *WorkspaceError unless workspace is empty, meaning
+// the agent's default, or one of the workspaces the agent registered.
+func (c *Connection) CheckWorkspace(workspace string) error {
+	if workspace == "" || slices.Contains(c.Workspaces, workspace) {
+		return nil
+	}
+	return &WorkspaceError{AgentID: c.ID, Workspace: workspace, Valid: slices.Clone(c.Workspaces)}
+}
@@ -0,0 +1,58 @@
+// ABOUTME: Tests for sending requests to one of an agent's registered workspaces.
+// ABOUTME: Covers the workspace reaching the agent and the error listing valid workspaces.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+)
+
+func TestSendMessage_Workspace(t *testing.T) {
+	manager := NewManager(slog.Default())
+	stream := newMockStream()
+	conn := NewConnection(ConnectionParams{
+		ID: "agent-1", Name: "Test Agent", Workspaces: []string{"api", "web"}, Stream: stream, Logger: slog.Default(),
+	})
+	if err := manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	_, err := manager.SendMessage(context.Background(), &SendRequest{AgentID: "agent-1", Content: "hi", Workspace: "docs"})
+	var wsErr *WorkspaceError
+	if !errors.As(err, &wsErr) || !errors.Is(err, ErrUnknownWorkspace) || coverr.CodeOf(err) != coverr.InvalidRequest {
+		t.Fatalf("SendMessage error = %v, want an invalid_request WorkspaceError", err)
+	}
+	if wsErr.Workspace != "docs" || !reflect.DeepEqual(wsErr.Valid, []string{"api", "web"}) {
+		t.Errorf("WorkspaceError = %+v, want docs with valid api, web", wsErr)
+	}
+	if len(stream.getSentMessages()) != 0 {
+		t.Fatal("agent received a message for an unknown workspace")
+	}
+
+	for _, workspace := range []string{"web", ""} {
+		if _, err := manager.SendMessage(context.Background(), &SendRequest{AgentID: "agent-1", Content: "hi", Workspace: workspace}); err != nil {
+			t.Fatalf("SendMessage(%q): %v", workspace, err)
+		}
+	}
+	sent := stream.getSentMessages()
+	if len(sent) != 2 || sent[0].GetSendMessage().GetWorkspace() != "web" || sent[1].GetSendMessage().GetWorkspace() != "" {
+		t.Errorf("sent workspaces = %v, want web then the default", sent)
+	}
+}
+
+func TestCheckWorkspace_NoneRegistered(t *testing.T) {
+	conn := NewConnection(ConnectionParams{ID: "agent-1", Logger: slog.Default()})
+	if err := conn.CheckWorkspace(""); err != nil {
+		t.Errorf("CheckWorkspace(\"\") = %v, want nil", err)
+	}
+	err := conn.CheckWorkspace("api")
+	if err == nil || err.Error() != `unknown workspace "api": agent agent-1 registered no workspaces` {
+		t.Errorf("CheckWorkspace(api) = %v", err)
+	}
+}
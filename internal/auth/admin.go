@@ -1,62 +1,174 @@
-// ABOUTME: Admin gate interceptor restricting AdminService to admin/owner roles
-// ABOUTME: Used as second interceptor after authentication to enforce RBAC
+// ABOUTME: Admin gate interceptor enforcing per-method roles on AdminService
+// ABOUTME: Members may read, admins and owners may mutate, agents may call nothing; denials are audited
 
 package auth
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net"
+	"slices"
 	"strings"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+
+	"github.com/2389/coven-gateway/internal/store"
 )
 
-// RequireAdmin returns a gRPC unary interceptor that enforces admin role
-// for AdminService methods. Non-admin services pass through unchanged.
-func RequireAdmin(logger *slog.Logger) grpc.UnaryServerInterceptor {
+// adminServicePrefix prefixes the full method names of AdminService RPCs.
+const adminServicePrefix = "/coven.AdminService/"
+
+// adminRoles may call every AdminService method.
+var adminRoles = []string{string(store.RoleAdmin), string(store.RoleOwner)}
+
+// readerRoles may call the AdminService methods in adminReadMethods.
+var readerRoles = []string{string(store.RoleMember), string(store.RoleAdmin), string(store.RoleOwner)}
+
+// adminReadMethods are the AdminService methods that only read. Every other
+// method, including ones added later, needs an admin role.
+var adminReadMethods = map[string]bool{
+	adminServicePrefix + "ListBindings":   true,
+	adminServicePrefix + "ListPrincipals": true,
+}
+
+// AuditRecorder records audit entries for denied AdminService calls.
+type AuditRecorder interface {
+	AppendAuditLog(ctx context.Context, e *store.AuditEntry) error
+}
+
+// AdminMethodRoles returns the roles any one of which may call an
+// AdminService method, given its full name.
+func AdminMethodRoles(fullMethod string) []string {
+	if adminReadMethods[fullMethod] {
+		return readerRoles
+	}
+	return adminRoles
+}
+
+// HasAnyRole reports whether the principal holds one of roles.
+func (a *AuthContext) HasAnyRole(roles ...string) bool {
+	return slices.ContainsFunc(a.Roles, func(r string) bool { return slices.Contains(roles, r) })
+}
+
+// adminGate is the authorization check shared by the unary and stream
+// interceptors. The roles it checks are the ones the authentication
+// interceptor loaded into the AuthContext, so each call looks them up once.
+type adminGate struct {
+	audit  AuditRecorder // optional
+	logger *slog.Logger
+}
+
+// authorize returns nil if the caller may call fullMethod, or the
+// Unauthenticated or PermissionDenied status to fail the call with.
+func (g adminGate) authorize(ctx context.Context, fullMethod string) error {
+	auth := FromContext(ctx)
+	if auth == nil {
+		logAuthFailure(g.logger, ctx, "not_authenticated")
+		return status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	method := strings.TrimPrefix(fullMethod, adminServicePrefix)
+	required := AdminMethodRoles(fullMethod)
+	var msg string
+	switch {
+	case auth.PrincipalType == string(store.PrincipalTypeAgent):
+		msg = "agents may not call AdminService"
+	case !auth.HasScope(ScopeAdmin):
+		msg = fmt.Sprintf("%s requires a token with the %s scope", method, ScopeAdmin)
+	case !auth.HasAnyRole(required...):
+		msg = fmt.Sprintf("%s requires the %s role", method, roleList(required))
+	default:
+		return nil
+	}
+
+	logAuthFailure(g.logger, ctx, "admin_role_required", "principal_id", auth.PrincipalID, "method", fullMethod, "roles", auth.Roles)
+	g.recordDenial(ctx, auth, method, required, msg)
+	return status.Error(codes.PermissionDenied, msg)
+}
+
+// recordDenial appends an audit entry for a denied call. A failure to record
+// it is logged and does not change the outcome.
+func (g adminGate) recordDenial(ctx context.Context, auth *AuthContext, method string, required []string, reason string) {
+	if g.audit == nil {
+		return
+	}
+	entry := &store.AuditEntry{
+		ActorPrincipalID: auth.PrincipalID,
+		Action:           store.AuditDenyAdminCall,
+		TargetType:       "rpc",
+		TargetID:         method,
+		Detail: map[string]any{
+			"reason":         reason,
+			"required_roles": required,
+			"roles":          auth.Roles,
+			"principal_type": auth.PrincipalType,
+		},
+		SourceIP: peerHost(ctx),
+	}
+	if err := g.audit.AppendAuditLog(context.WithoutCancel(ctx), entry); err != nil {
+		logger := g.logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Error("failed to audit denied admin call", "principal_id", auth.PrincipalID, "method", method, "error", err)
+	}
+}
+
+// roleList joins roles for an error message: "member, admin or owner".
+func roleList(roles []string) string {
+	if len(roles) == 1 {
+		return roles[0]
+	}
+	return strings.Join(roles[:len(roles)-1], ", ") + " or " + roles[len(roles)-1]
+}
+
+// peerHost returns the host part of the gRPC peer address, if known.
+func peerHost(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// RequireAdmin returns a gRPC unary interceptor that enforces the roles of
+// AdminService methods (see AdminMethodRoles). Agents may call none of them.
+// Non-admin services pass through unchanged. Denied calls are recorded in
+// audit, which may be nil.
+func RequireAdmin(audit AuditRecorder, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	gate := adminGate{audit: audit, logger: logger}
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 		// Skip for non-admin services
-		if !strings.HasPrefix(info.FullMethod, "/coven.AdminService/") {
+		if !strings.HasPrefix(info.FullMethod, adminServicePrefix) {
 			return handler(ctx, req)
 		}
-
-		auth := FromContext(ctx)
-		if auth == nil {
-			logAuthFailure(logger, ctx, "not_authenticated")
-			return nil, status.Error(codes.Unauthenticated, "authentication required")
-		}
-
-		if !auth.IsAdmin() {
-			logAuthFailure(logger, ctx, "admin_required", "principal_id", auth.PrincipalID, "roles", auth.Roles)
-			return nil, status.Error(codes.PermissionDenied, "admin role required")
+		if err := gate.authorize(ctx, info.FullMethod); err != nil {
+			return nil, err
 		}
-
 		return handler(ctx, req)
 	}
 }
 
-// RequireAdminStream returns a gRPC stream interceptor that enforces admin role
-// for AdminService streaming methods.
-func RequireAdminStream(logger *slog.Logger) grpc.StreamServerInterceptor {
+// RequireAdminStream returns a gRPC stream interceptor that enforces the
+// roles of AdminService streaming methods, like RequireAdmin.
+func RequireAdminStream(audit AuditRecorder, logger *slog.Logger) grpc.StreamServerInterceptor {
+	gate := adminGate{audit: audit, logger: logger}
 	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		// Skip for non-admin services
-		if !strings.HasPrefix(info.FullMethod, "/coven.AdminService/") {
+		if !strings.HasPrefix(info.FullMethod, adminServicePrefix) {
 			return handler(srv, ss)
 		}
-
-		auth := FromContext(ss.Context())
-		if auth == nil {
-			logAuthFailure(logger, ss.Context(), "not_authenticated")
-			return status.Error(codes.Unauthenticated, "authentication required")
+		if err := gate.authorize(ss.Context(), info.FullMethod); err != nil {
+			return err
 		}
-
-		if !auth.IsAdmin() {
-			logAuthFailure(logger, ss.Context(), "admin_required", "principal_id", auth.PrincipalID, "roles", auth.Roles)
-			return status.Error(codes.PermissionDenied, "admin role required")
-		}
-
 		return handler(srv, ss)
 	}
 }
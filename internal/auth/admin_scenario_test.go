@@ -51,7 +51,7 @@ func TestScenario_AdminGateFullFlow(t *testing.T) {
 
 	// 4. Chain auth interceptor -> admin gate interceptor
 	authInterceptor := UnaryInterceptor(s, s, verifier, nil, nil, nil, nil)
-	adminGateInterceptor := RequireAdmin(nil)
+	adminGateInterceptor := RequireAdmin(nil, nil)
 
 	reqCtx := scenarioContextWithAuth(token)
 
@@ -136,7 +136,7 @@ func TestScenario_NonAdminBlockedByGate(t *testing.T) {
 
 	// 4. Chain auth interceptor -> admin gate interceptor
 	authInterceptor := UnaryInterceptor(s, s, verifier, nil, nil, nil, nil)
-	adminGateInterceptor := RequireAdmin(nil)
+	adminGateInterceptor := RequireAdmin(s, nil)
 
 	reqCtx := scenarioContextWithAuth(token)
 
@@ -158,7 +158,7 @@ func TestScenario_NonAdminBlockedByGate(t *testing.T) {
 		return nil, errors.New("unexpected handler call")
 	}
 
-	adminInfo := &grpc.UnaryServerInfo{FullMethod: "/coven.AdminService/ListPrincipals"}
+	adminInfo := &grpc.UnaryServerInfo{FullMethod: "/coven.AdminService/CreatePrincipal"}
 	_, err = adminGateInterceptor(authCtx, nil, adminInfo, adminHandler)
 
 	if err == nil {
@@ -183,6 +183,16 @@ func TestScenario_NonAdminBlockedByGate(t *testing.T) {
 	if auth.IsAdmin() {
 		t.Error("IsAdmin() should be false")
 	}
+
+	// 6. The denial is in the audit log
+	action := store.AuditDenyAdminCall
+	entries, err := s.ListAuditLog(ctx, store.AuditFilter{Action: &action})
+	if err != nil {
+		t.Fatalf("ListAuditLog: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ActorPrincipalID != principalID || entries[0].TargetID != "CreatePrincipal" {
+		t.Errorf("audit entries = %+v, want one denial of CreatePrincipal", entries)
+	}
 }
 
 func TestScenario_OwnerPassesAdminGate(t *testing.T) {
@@ -221,7 +231,7 @@ func TestScenario_OwnerPassesAdminGate(t *testing.T) {
 
 	// 4. Chain auth interceptor -> admin gate interceptor
 	authInterceptor := UnaryInterceptor(s, s, verifier, nil, nil, nil, nil)
-	adminGateInterceptor := RequireAdmin(nil)
+	adminGateInterceptor := RequireAdmin(nil, nil)
 
 	reqCtx := scenarioContextWithAuth(token)
 
@@ -306,7 +316,7 @@ func TestScenario_NonAdminServiceBypassesGate(t *testing.T) {
 
 	// 4. Chain auth interceptor -> admin gate interceptor
 	authInterceptor := UnaryInterceptor(s, s, verifier, nil, nil, nil, nil)
-	adminGateInterceptor := RequireAdmin(nil)
+	adminGateInterceptor := RequireAdmin(nil, nil)
 
 	reqCtx := scenarioContextWithAuth(token)
 
@@ -382,7 +392,7 @@ func TestScenario_StreamAdminGateFullFlow(t *testing.T) {
 
 	// 4. Chain stream auth interceptor -> admin gate stream interceptor
 	authInterceptor := StreamInterceptor(s, s, verifier, nil, nil, nil, nil)
-	adminGateInterceptor := RequireAdminStream(nil)
+	adminGateInterceptor := RequireAdminStream(nil, nil)
 
 	reqCtx := scenarioContextWithAuth(token)
 	stream := &mockServerStream{ctx: reqCtx}
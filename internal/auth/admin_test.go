@@ -1,20 +1,24 @@
 // ABOUTME: Unit tests for admin gate interceptor
-// ABOUTME: Tests role-based access control for AdminService methods
+// ABOUTME: Tests per-method role checks for every AdminService RPC and the audit of denials
 
 package auth
 
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
 func TestAdminGate_AdminCan(t *testing.T) {
-	interceptor := RequireAdmin(nil)
+	interceptor := RequireAdmin(nil, nil)
 
 	// Create context with admin auth
 	authCtx := &AuthContext{
@@ -47,7 +51,7 @@ func TestAdminGate_AdminCan(t *testing.T) {
 }
 
 func TestAdminGate_NonAdminCannot(t *testing.T) {
-	interceptor := RequireAdmin(nil)
+	interceptor := RequireAdmin(nil, nil)
 
 	// Create context with member (non-admin) auth
 	authCtx := &AuthContext{
@@ -62,7 +66,7 @@ func TestAdminGate_NonAdminCannot(t *testing.T) {
 		return nil, errors.New("unexpected handler call")
 	}
 
-	info := &grpc.UnaryServerInfo{FullMethod: "/coven.AdminService/ListPrincipals"}
+	info := &grpc.UnaryServerInfo{FullMethod: "/coven.AdminService/CreatePrincipal"}
 	_, err := interceptor(ctx, nil, info, handler)
 
 	if err == nil {
@@ -78,13 +82,13 @@ func TestAdminGate_NonAdminCannot(t *testing.T) {
 		t.Errorf("status code = %v, want %v", st.Code(), codes.PermissionDenied)
 	}
 
-	if st.Message() != "admin role required" {
-		t.Errorf("message = %q, want %q", st.Message(), "admin role required")
+	if want := "CreatePrincipal requires the admin or owner role"; st.Message() != want {
+		t.Errorf("message = %q, want %q", st.Message(), want)
 	}
 }
 
 func TestAdminGate_OwnerCan(t *testing.T) {
-	interceptor := RequireAdmin(nil)
+	interceptor := RequireAdmin(nil, nil)
 
 	// Create context with owner auth (owner is also considered admin)
 	authCtx := &AuthContext{
@@ -117,7 +121,7 @@ func TestAdminGate_OwnerCan(t *testing.T) {
 }
 
 func TestAdminGate_ClientServiceOpen(t *testing.T) {
-	interceptor := RequireAdmin(nil)
+	interceptor := RequireAdmin(nil, nil)
 
 	// Create context with member (non-admin) auth
 	authCtx := &AuthContext{
@@ -151,7 +155,7 @@ func TestAdminGate_ClientServiceOpen(t *testing.T) {
 }
 
 func TestAdminGate_AgentServiceOpen(t *testing.T) {
-	interceptor := RequireAdmin(nil)
+	interceptor := RequireAdmin(nil, nil)
 
 	// Create context with member (non-admin) auth
 	authCtx := &AuthContext{
@@ -185,7 +189,7 @@ func TestAdminGate_AgentServiceOpen(t *testing.T) {
 }
 
 func TestAdminGate_NoAuthContext(t *testing.T) {
-	interceptor := RequireAdmin(nil)
+	interceptor := RequireAdmin(nil, nil)
 
 	// Create context without any auth
 	ctx := context.Background()
@@ -217,7 +221,7 @@ func TestAdminGate_NoAuthContext(t *testing.T) {
 }
 
 func TestAdminGateStream_AdminCan(t *testing.T) {
-	interceptor := RequireAdminStream(nil)
+	interceptor := RequireAdminStream(nil, nil)
 
 	// Create context with admin auth
 	authCtx := &AuthContext{
@@ -248,7 +252,7 @@ func TestAdminGateStream_AdminCan(t *testing.T) {
 }
 
 func TestAdminGateStream_NonAdminCannot(t *testing.T) {
-	interceptor := RequireAdminStream(nil)
+	interceptor := RequireAdminStream(nil, nil)
 
 	// Create context with member (non-admin) auth
 	authCtx := &AuthContext{
@@ -283,7 +287,7 @@ func TestAdminGateStream_NonAdminCannot(t *testing.T) {
 }
 
 func TestAdminGateStream_NonAdminServicePassThrough(t *testing.T) {
-	interceptor := RequireAdminStream(nil)
+	interceptor := RequireAdminStream(nil, nil)
 
 	// Create context with member (non-admin) auth
 	authCtx := &AuthContext{
@@ -315,7 +319,7 @@ func TestAdminGateStream_NonAdminServicePassThrough(t *testing.T) {
 }
 
 func TestAdminGateStream_NoAuthContext(t *testing.T) {
-	interceptor := RequireAdminStream(nil)
+	interceptor := RequireAdminStream(nil, nil)
 
 	// Create context without any auth
 	ctx := context.Background()
@@ -345,7 +349,7 @@ func TestAdminGateStream_NoAuthContext(t *testing.T) {
 }
 
 func TestAdminGate_EmptyRoles(t *testing.T) {
-	interceptor := RequireAdmin(nil)
+	interceptor := RequireAdmin(nil, nil)
 
 	// Create context with auth but no roles
 	authCtx := &AuthContext{
@@ -378,7 +382,7 @@ func TestAdminGate_EmptyRoles(t *testing.T) {
 }
 
 func TestAdminGate_MultipleRolesIncludingAdmin(t *testing.T) {
-	interceptor := RequireAdmin(nil)
+	interceptor := RequireAdmin(nil, nil)
 
 	// Create context with multiple roles including admin
 	authCtx := &AuthContext{
@@ -409,3 +413,71 @@ func TestAdminGate_MultipleRolesIncludingAdmin(t *testing.T) {
 		t.Errorf("response = %v, want %v", resp, "multi-success")
 	}
 }
+
+// auditLog records audit entries in memory.
+type auditLog struct{ entries []*store.AuditEntry }
+
+func (l *auditLog) AppendAuditLog(_ context.Context, e *store.AuditEntry) error {
+	l.entries = append(l.entries, e)
+	return nil
+}
+
+func TestAdminGate_MethodRoles(t *testing.T) {
+	reads := map[string]bool{"ListBindings": true, "ListPrincipals": true}
+	callers := []struct {
+		name       string
+		auth       *AuthContext
+		canRead    bool
+		canMutate  bool
+		wantDenial string // substring of the PermissionDenied message
+	}{
+		{"owner", &AuthContext{PrincipalType: "client", Roles: []string{"owner"}}, true, true, "role"},
+		{"admin", &AuthContext{PrincipalType: "client", Roles: []string{"admin"}}, true, true, "role"},
+		{"member", &AuthContext{PrincipalType: "client", Roles: []string{"member"}}, true, false, "requires the admin or owner role"},
+		{"no role", &AuthContext{PrincipalType: "client"}, false, false, "role"},
+		{"agent", &AuthContext{PrincipalType: "agent", Roles: []string{"admin"}}, false, false, "agents may not call AdminService"},
+		{"admin without admin scope", &AuthContext{PrincipalType: "client", Roles: []string{"admin"}, Scopes: []string{ScopeClient}}, false, false, "scope"},
+	}
+
+	for _, m := range pb.AdminService_ServiceDesc.Methods {
+		for _, c := range callers {
+			t.Run(m.MethodName+"/"+c.name, func(t *testing.T) {
+				audit := &auditLog{}
+				interceptor := RequireAdmin(audit, nil)
+				c.auth.PrincipalID = "p-" + c.name
+				ctx := WithAuth(context.Background(), c.auth)
+				called := false
+				handler := func(context.Context, any) (any, error) {
+					called = true
+					return nil, nil
+				}
+
+				_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/coven.AdminService/" + m.MethodName}, handler)
+
+				allowed := c.canMutate || (reads[m.MethodName] && c.canRead)
+				if allowed {
+					if err != nil || !called {
+						t.Fatalf("err = %v, called = %v; want the call allowed", err, called)
+					}
+					if len(audit.entries) != 0 {
+						t.Errorf("audit entries = %d, want none", len(audit.entries))
+					}
+					return
+				}
+				if called || status.Code(err) != codes.PermissionDenied {
+					t.Fatalf("err = %v, called = %v; want PermissionDenied", err, called)
+				}
+				if reads[m.MethodName] && c.name == "member" {
+					t.Fatal("member denied a read")
+				}
+				if msg := status.Convert(err).Message(); !strings.Contains(msg, c.wantDenial) {
+					t.Errorf("message = %q, want it to contain %q", msg, c.wantDenial)
+				}
+				if len(audit.entries) != 1 || audit.entries[0].Action != store.AuditDenyAdminCall ||
+					audit.entries[0].TargetID != m.MethodName || audit.entries[0].ActorPrincipalID != c.auth.PrincipalID {
+					t.Errorf("audit entries = %+v, want one denial of %s by %s", audit.entries, m.MethodName, c.auth.PrincipalID)
+				}
+			})
+		}
+	}
+}
@@ -5,6 +5,9 @@ package auth
 
 import (
 	"context"
+	"slices"
+
+	"github.com/2389/coven-gateway/internal/logctx"
 )
 
 // AuthContext holds the authenticated identity information extracted from a request.
@@ -14,10 +17,64 @@ type AuthContext struct {
 	PrincipalType string   // "client" | "agent" | "pack"
 	MemberID      *string  // always nil in v1 (reserved for future member-level auth)
 	Roles         []string // roles assigned to this principal
+	Pending       bool     // principal awaits admin approval (agent streams only)
+	NewPrincipal  bool     // principal was auto-registered by this request
+	Scopes        []string // scopes of the presented token; nil means unscoped
+	AgentIDs      []string // agents the presented token is limited to; nil means any
+	Capabilities  []string // capability subset the token is limited to; nil means all
+}
+
+// HasScope reports whether the request's token grants scope. Requests made
+// without a scoped token have every scope.
+func (a *AuthContext) HasScope(scope string) bool {
+	if a.Scopes == nil {
+		return true
+	}
+	for _, s := range a.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsAgent reports whether the request's token may reach an agent known by
+// any of ids (typically its connection ID and its principal ID). Requests
+// made without an agent-scoped token may reach every agent.
+func (a *AuthContext) AllowsAgent(ids ...string) bool {
+	if a.AgentIDs == nil {
+		return true
+	}
+	for _, id := range ids {
+		if id != "" && slices.Contains(a.AgentIDs, id) {
+			return true
+		}
+	}
+	return false
 }
 
-// IsAdmin returns true if the principal has admin or owner role.
+// LimitCapabilities returns the part of held the request's token may use:
+// held itself for tokens without a capability subset, else the intersection.
+func (a *AuthContext) LimitCapabilities(held []string) []string {
+	if a.Capabilities == nil {
+		return held
+	}
+	return IntersectCapabilities(held, a.Capabilities)
+}
+
+// applyClaims copies the presented token's scopes and limits onto a.
+func (a *AuthContext) applyClaims(claims *Claims) {
+	a.Scopes = claims.Scopes
+	a.AgentIDs = claims.AgentIDs
+	a.Capabilities = claims.Capabilities
+}
+
+// IsAdmin returns true if the principal has admin or owner role and the
+// token, if scoped, carries the admin scope.
 func (a *AuthContext) IsAdmin() bool {
+	if !a.HasScope(ScopeAdmin) {
+		return false
+	}
 	for _, r := range a.Roles {
 		if r == "admin" || r == "owner" {
 			return true
@@ -29,8 +86,12 @@ func (a *AuthContext) IsAdmin() bool {
 // authContextKey is the key type for storing AuthContext in context.Context.
 type authContextKey struct{}
 
-// WithAuth returns a new context with the AuthContext attached.
+// WithAuth returns a new context with the AuthContext attached. Within a
+// request, the principal is also added to the request's log attributes.
 func WithAuth(ctx context.Context, auth *AuthContext) context.Context {
+	if auth != nil && auth.PrincipalID != "" {
+		ctx = logctx.With(ctx, "principal_id", auth.PrincipalID)
+	}
 	return context.WithValue(ctx, authContextKey{}, auth)
 }
 
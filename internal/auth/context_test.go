@@ -4,8 +4,13 @@
 package auth
 
 import (
+	"bytes"
 	"context"
+	"log/slog"
+	"strings"
 	"testing"
+
+	"github.com/2389/coven-gateway/internal/logctx"
 )
 
 func TestAuthContext_IsAdmin_True(t *testing.T) {
@@ -115,6 +120,19 @@ func TestFromContext_Present(t *testing.T) {
 	}
 }
 
+func TestWithAuth_LogsPrincipal(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	ctx := logctx.WithRequestID(context.Background(), "req-1")
+	ctx = WithAuth(ctx, &AuthContext{PrincipalID: "p-1"})
+	logctx.FromContext(ctx, logger).Info("handled")
+
+	if line := buf.String(); !strings.Contains(line, "request_id=req-1") || !strings.Contains(line, "principal_id=p-1") {
+		t.Errorf("log line = %q, want request_id and principal_id", line)
+	}
+}
+
 func TestFromContext_Missing(t *testing.T) {
 	ctx := context.Background()
 	got := FromContext(ctx)
@@ -152,3 +170,24 @@ func TestMustFromContext_Missing(t *testing.T) {
 
 	MustFromContext(ctx)
 }
+
+func TestAuthContext_TokenLimits(t *testing.T) {
+	unscoped := &AuthContext{}
+	if !unscoped.AllowsAgent("agent-1") {
+		t.Error("unscoped token should reach every agent")
+	}
+	if got := unscoped.LimitCapabilities([]string{"base", "notes"}); len(got) != 2 {
+		t.Errorf("unscoped LimitCapabilities = %v, want all held", got)
+	}
+
+	scoped := &AuthContext{AgentIDs: []string{"agent-1"}, Capabilities: []string{"notes", "admin"}}
+	if !scoped.AllowsAgent("conn-9", "agent-1") {
+		t.Error("scoped token should reach an agent listed by principal ID")
+	}
+	if scoped.AllowsAgent("agent-2", "") {
+		t.Error("scoped token should not reach an unlisted agent")
+	}
+	if got := scoped.LimitCapabilities([]string{"base", "notes"}); len(got) != 1 || got[0] != "notes" {
+		t.Errorf("LimitCapabilities = %v, want [notes]", got)
+	}
+}
@@ -35,15 +35,24 @@
 //
 // # Token Management
 //
-// API tokens for principals:
+// API tokens for principals are minted through a TokenMinter, which records
+// each token in the TokenStore and signs it with a jti and scopes:
 //
-//	token, err := GenerateToken(principalID, secret)
-//	claims, err := ValidateToken(token, secret)
+//	minter := NewTokenMinter(jwtVerifier, store)
+//	token, rec, err := minter.Mint(ctx, MintRequest{PrincipalID: id, TTL: ttl, Scopes: []string{ScopeClient}})
 //
-// Tokens include:
-//   - Principal ID
-//   - Expiration time
-//   - Custom claims (optional)
+// A TrackedVerifier checks recorded tokens against their record, rejecting
+// revoked ones and writing last_used_at at most once per TokenTouchInterval.
+// Tokens without the admin scope cannot use admin or owner roles. Tokens
+// signed before tokens were recorded carry no jti and stay unscoped.
+//
+// # AdminService Roles
+//
+// RequireAdmin gates AdminService by method: members may call the read-only
+// ListBindings and ListPrincipals, every other method needs admin or owner,
+// and agent principals may call none. Calls also need the admin scope when
+// the token is scoped. A denied call fails with PermissionDenied naming the
+// role it needed and is written to the audit log as deny_admin_call.
 //
 // # Agent Registration Modes
 //
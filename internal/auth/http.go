@@ -103,12 +103,13 @@ func HTTPAuthMiddleware(principals PrincipalStore, roles RoleStore, verifier Tok
 				return
 			}
 
-			principalID, err := verifier.Verify(token)
+			claims, err := verifyClaims(verifier, token)
 			if err != nil {
 				logHTTPAuthFailure(logger, r, "token_verification_failed", "error", err.Error())
 				jsonError(w, "invalid token", http.StatusUnauthorized)
 				return
 			}
+			principalID := claims.PrincipalID
 
 			principal, err := principals.GetPrincipal(r.Context(), principalID)
 			if err != nil {
@@ -129,6 +130,7 @@ func HTTPAuthMiddleware(principals PrincipalStore, roles RoleStore, verifier Tok
 
 			roleNames, _ := roles.ListRoles(r.Context(), store.RoleSubjectPrincipal, principalID)
 			authCtx := buildAuthContext(principalID, principal.Type, roleNames)
+			authCtx.applyClaims(claims)
 			next.ServeHTTP(w, r.WithContext(WithAuth(r.Context(), authCtx)))
 		})
 	}
@@ -169,11 +171,12 @@ func OptionalAuthMiddleware(principals PrincipalStore, roles RoleStore, verifier
 				return
 			}
 
-			principalID, err := verifier.Verify(token)
+			claims, err := verifyClaims(verifier, token)
 			if err != nil {
 				next.ServeHTTP(w, r)
 				return
 			}
+			principalID := claims.PrincipalID
 
 			principal, err := principals.GetPrincipal(r.Context(), principalID)
 			if err != nil {
@@ -188,6 +191,7 @@ func OptionalAuthMiddleware(principals PrincipalStore, roles RoleStore, verifier
 
 			roleNames, _ := roles.ListRoles(r.Context(), store.RoleSubjectPrincipal, principalID)
 			authCtx := buildAuthContext(principalID, principal.Type, roleNames)
+			authCtx.applyClaims(claims)
 			next.ServeHTTP(w, r.WithContext(WithAuth(r.Context(), authCtx)))
 		})
 	}
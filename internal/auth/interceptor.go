@@ -35,6 +35,10 @@ type RoleStore interface {
 	ListRoles(ctx context.Context, subjectType store.RoleSubjectType, subjectID string) ([]store.RoleName, error)
 }
 
+// agentStreamMethod is the only RPC a pending principal may open. The stream
+// stays restricted until an admin approves or revokes the principal.
+const agentStreamMethod = "/coven.CovenControl/AgentStream"
+
 // AuthConfig holds auth configuration options.
 type AuthConfig struct {
 	AgentAutoRegistration string // "approved", "pending", or "disabled"
@@ -64,7 +68,7 @@ func UnaryInterceptor(principals PrincipalStore, roles RoleStore, tokens TokenVe
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (any, error) {
-		authCtx, err := extractAuth(ctx, principals, roles, tokens, sshVerifier, config, creator, logger)
+		authCtx, err := extractAuth(ctx, principals, roles, tokens, sshVerifier, config, creator, logger, false)
 		if err != nil {
 			return nil, err
 		}
@@ -84,7 +88,8 @@ func StreamInterceptor(principals PrincipalStore, roles RoleStore, tokens TokenV
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler,
 	) error {
-		authCtx, err := extractAuth(ss.Context(), principals, roles, tokens, sshVerifier, config, creator, logger)
+		allowPending := info.FullMethod == agentStreamMethod
+		authCtx, err := extractAuth(ss.Context(), principals, roles, tokens, sshVerifier, config, creator, logger, allowPending)
 		if err != nil {
 			return err
 		}
@@ -244,31 +249,31 @@ func authenticateWithSSH(ctx context.Context, req *SSHAuthRequest, verifier *SSH
 }
 
 // authenticateWithJWT handles JWT-based authentication for clients.
-func authenticateWithJWT(ctx context.Context, md metadata.MD, tokens TokenVerifier, principals PrincipalStore) (*store.Principal, error) {
+func authenticateWithJWT(ctx context.Context, md metadata.MD, tokens TokenVerifier, principals PrincipalStore) (*store.Principal, *Claims, error) {
 	authHeaders := md.Get("authorization")
 	if len(authHeaders) == 0 {
-		return nil, status.Error(codes.Unauthenticated, "missing authorization header")
+		return nil, nil, status.Error(codes.Unauthenticated, "missing authorization header")
 	}
 
 	authHeader := authHeaders[0]
 	if !strings.HasPrefix(authHeader, "Bearer ") {
-		return nil, status.Error(codes.Unauthenticated, "invalid authorization header format")
+		return nil, nil, status.Error(codes.Unauthenticated, "invalid authorization header format")
 	}
 
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-	principalID, err := tokens.Verify(tokenString)
+	claims, err := verifyClaims(tokens, tokenString)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+		return nil, nil, status.Error(codes.Unauthenticated, "invalid or expired token")
 	}
 
-	p, err := principals.GetPrincipal(ctx, principalID)
+	p, err := principals.GetPrincipal(ctx, claims.PrincipalID)
 	if err != nil {
 		if errors.Is(err, store.ErrPrincipalNotFound) {
-			return nil, status.Error(codes.Unauthenticated, "principal not found")
+			return nil, nil, status.Error(codes.Unauthenticated, "principal not found")
 		}
-		return nil, status.Errorf(codes.Internal, "failed to lookup principal: %v", err)
+		return nil, nil, status.Errorf(codes.Internal, "failed to lookup principal: %v", err)
 	}
-	return p, nil
+	return p, claims, nil
 }
 
 // validatePrincipalStatusGRPC validates that the principal has an allowed status for gRPC.
@@ -311,7 +316,9 @@ func buildAuthContextGRPC(ctx context.Context, p *store.Principal, roles RoleSto
 // extractAuth extracts authentication context from gRPC metadata.
 // Supports SSH auth for agents and JWT auth for clients.
 // The optional logger enables auth failure logging for security monitoring.
-func extractAuth(ctx context.Context, principals PrincipalStore, roles RoleStore, tokens TokenVerifier, sshVerifier *SSHVerifier, config *AuthConfig, creator PrincipalCreator, logger *slog.Logger) (*AuthContext, error) {
+// When allowPending is set, an SSH-authenticated principal that is still
+// pending is admitted with AuthContext.Pending instead of being rejected.
+func extractAuth(ctx context.Context, principals PrincipalStore, roles RoleStore, tokens TokenVerifier, sshVerifier *SSHVerifier, config *AuthConfig, creator PrincipalCreator, logger *slog.Logger, allowPending bool) (*AuthContext, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
 		logAuthFailure(logger, ctx, "missing_metadata")
@@ -319,7 +326,8 @@ func extractAuth(ctx context.Context, principals PrincipalStore, roles RoleStore
 	}
 
 	var principal *store.Principal
-	var autoRegistered bool
+	var claims *Claims
+	var autoRegistered, viaSSH bool
 
 	// Try SSH auth first (for agents)
 	if sshReq := ExtractSSHAuthFromMetadata(md); sshReq != nil {
@@ -330,19 +338,33 @@ func extractAuth(ctx context.Context, principals PrincipalStore, roles RoleStore
 		}
 		principal = result.principal
 		autoRegistered = result.autoRegistered
+		viaSSH = true
 	} else {
-		p, err := authenticateWithJWT(ctx, md, tokens, principals)
+		p, c, err := authenticateWithJWT(ctx, md, tokens, principals)
 		if err != nil {
 			logAuthFailure(logger, ctx, "jwt_auth_failed", "error", err.Error())
 			return nil, err
 		}
 		principal = p
+		claims = c
 	}
 
-	if err := validatePrincipalStatusGRPC(principal, autoRegistered); err != nil {
-		logAuthFailure(logger, ctx, "principal_status_invalid", "principal_id", principal.ID, "status", string(principal.Status))
-		return nil, err
+	pending := allowPending && viaSSH && principal.Status == store.PrincipalStatusPending
+	if !pending {
+		if err := validatePrincipalStatusGRPC(principal, autoRegistered); err != nil {
+			logAuthFailure(logger, ctx, "principal_status_invalid", "principal_id", principal.ID, "status", string(principal.Status))
+			return nil, err
+		}
 	}
 
-	return buildAuthContextGRPC(ctx, principal, roles)
+	authCtx, err := buildAuthContextGRPC(ctx, principal, roles)
+	if err != nil {
+		return nil, err
+	}
+	authCtx.Pending = pending
+	authCtx.NewPrincipal = autoRegistered
+	if claims != nil {
+		authCtx.applyClaims(claims)
+	}
+	return authCtx, nil
 }
@@ -817,6 +817,71 @@ func TestExtractAuth_AutoCreatesPrincipalPending(t *testing.T) {
 	}
 }
 
+func TestStreamInterceptor_AdmitsPendingAgentStream(t *testing.T) {
+	signer, _, pubkeyStr := generateTestKeyPairForInterceptor(t)
+
+	principals := newMockPrincipalStoreWithCreator()
+	roles := &mockRoleStore{}
+	jwtVerifier, _ := NewJWTVerifier(interceptorTestSecret)
+	config := &AuthConfig{AgentAutoRegistration: "pending"}
+
+	timestamp := time.Now().Unix()
+	signature := signMessageForInterceptor(t, signer, fmt.Sprintf("%d|%s", timestamp, testNonce))
+	stream := &mockServerStream{ctx: contextWithSSHAuth(pubkeyStr, signature, timestamp)}
+
+	interceptor := StreamInterceptor(principals, roles, jwtVerifier, NewSSHVerifier(), config, principals, nil)
+
+	var authCtx *AuthContext
+	handler := func(srv any, ss grpc.ServerStream) error {
+		authCtx = FromContext(ss.Context())
+		return nil
+	}
+
+	info := &grpc.StreamServerInfo{FullMethod: "/coven.CovenControl/AgentStream"}
+	if err := interceptor(nil, stream, info, handler); err != nil {
+		t.Fatalf("interceptor error = %v", err)
+	}
+
+	if authCtx == nil {
+		t.Fatal("AuthContext not set in stream context")
+	}
+	if !authCtx.Pending {
+		t.Error("Pending = false, want true")
+	}
+	if !authCtx.NewPrincipal {
+		t.Error("NewPrincipal = false, want true for an auto-registered key")
+	}
+	if authCtx.PrincipalType != string(store.PrincipalTypeAgent) {
+		t.Errorf("PrincipalType = %q, want %q", authCtx.PrincipalType, store.PrincipalTypeAgent)
+	}
+}
+
+func TestStreamInterceptor_RejectsPendingOnOtherStreams(t *testing.T) {
+	signer, _, pubkeyStr := generateTestKeyPairForInterceptor(t)
+
+	principals := newMockPrincipalStoreWithCreator()
+	roles := &mockRoleStore{}
+	jwtVerifier, _ := NewJWTVerifier(interceptorTestSecret)
+	config := &AuthConfig{AgentAutoRegistration: "pending"}
+
+	timestamp := time.Now().Unix()
+	signature := signMessageForInterceptor(t, signer, fmt.Sprintf("%d|%s", timestamp, testNonce))
+	stream := &mockServerStream{ctx: contextWithSSHAuth(pubkeyStr, signature, timestamp)}
+
+	interceptor := StreamInterceptor(principals, roles, jwtVerifier, NewSSHVerifier(), config, principals, nil)
+
+	handler := func(srv any, ss grpc.ServerStream) error {
+		t.Error("handler should not be called for pending principal")
+		return nil
+	}
+
+	info := &grpc.StreamServerInfo{FullMethod: "/coven.ClientService/StreamEvents"}
+	err := interceptor(nil, stream, info, handler)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("status code = %v, want %v", status.Code(err), codes.PermissionDenied)
+	}
+}
+
 func TestExtractAuth_RejectsUnknownWhenDisabled(t *testing.T) {
 	signer, _, pubkeyStr := generateTestKeyPairForInterceptor(t)
 
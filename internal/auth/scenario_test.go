@@ -19,7 +19,7 @@ import (
 )
 
 // createTestStore creates a real SQLite store in a temp directory.
-func createTestStore(t *testing.T) *store.SQLiteStore {
+func createTestStore(t *testing.T) *store.SQLStore {
 	t.Helper()
 
 	tmpDir := t.TempDir()
@@ -17,6 +17,7 @@ var (
 	ErrExpiredToken = errors.New("token expired")
 	ErrMissingClaim = errors.New("missing required claim")
 	ErrWeakSecret   = errors.New("JWT secret must be at least 32 bytes")
+	ErrRevokedToken = errors.New("token revoked")
 )
 
 // MinSecretLength is the minimum required length for JWT secrets (256 bits).
@@ -27,6 +28,33 @@ type TokenVerifier interface {
 	Verify(tokenString string) (principalID string, err error)
 }
 
+// Claims are the identity claims carried by a verified token.
+type Claims struct {
+	PrincipalID  string
+	TokenID      string   // jti; empty for tokens minted before tokens were recorded
+	Scopes       []string // nil means unscoped
+	AgentIDs     []string // from the token record; nil means any agent
+	Capabilities []string // from the token record; nil means all capabilities
+}
+
+// ClaimsVerifier is implemented by verifiers that expose a token's ID and
+// scopes in addition to its principal.
+type ClaimsVerifier interface {
+	VerifyClaims(tokenString string) (*Claims, error)
+}
+
+// verifyClaims verifies a token with v, using VerifyClaims when available.
+func verifyClaims(v TokenVerifier, tokenString string) (*Claims, error) {
+	if cv, ok := v.(ClaimsVerifier); ok {
+		return cv.VerifyClaims(tokenString)
+	}
+	principalID, err := v.Verify(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	return &Claims{PrincipalID: principalID}, nil
+}
+
 // JWTVerifier implements TokenVerifier using HS256 signed JWTs.
 type JWTVerifier struct {
 	secret []byte
@@ -44,6 +72,15 @@ func NewJWTVerifier(secret []byte) (*JWTVerifier, error) {
 
 // Verify validates the token and extracts the principal ID from the "sub" claim.
 func (v *JWTVerifier) Verify(tokenString string) (principalID string, err error) {
+	claims, err := v.VerifyClaims(tokenString)
+	if err != nil {
+		return "", err
+	}
+	return claims.PrincipalID, nil
+}
+
+// VerifyClaims validates the token and extracts its subject, ID, and scopes.
+func (v *JWTVerifier) VerifyClaims(tokenString string) (*Claims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
 		// Validate the signing method is HS256
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -55,26 +92,36 @@ func (v *JWTVerifier) Verify(tokenString string) (principalID string, err error)
 	if err != nil {
 		// Check if it's specifically an expiration error
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return "", ErrExpiredToken
+			return nil, ErrExpiredToken
 		}
-		return "", fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
 	}
 
 	if !token.Valid {
-		return "", ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
 	sub, ok := claims["sub"].(string)
 	if !ok || sub == "" {
-		return "", fmt.Errorf("%w: sub", ErrMissingClaim)
+		return nil, fmt.Errorf("%w: sub", ErrMissingClaim)
 	}
 
-	return sub, nil
+	result := &Claims{PrincipalID: sub}
+	result.TokenID, _ = claims["jti"].(string)
+	if scp, ok := claims["scp"].([]any); ok {
+		result.Scopes = make([]string, 0, len(scp))
+		for _, s := range scp {
+			if str, ok := s.(string); ok {
+				result.Scopes = append(result.Scopes, str)
+			}
+		}
+	}
+	return result, nil
 }
 
 // Generate creates a new JWT token for the given principal ID with expiration.
@@ -89,3 +136,19 @@ func (v *JWTVerifier) Generate(principalID string, expiresIn time.Duration) (str
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(v.secret)
 }
+
+// GenerateWithID creates a token carrying a jti and scopes so it can be
+// looked up, restricted, and revoked through its TokenStore record.
+func (v *JWTVerifier) GenerateWithID(principalID string, expiresIn time.Duration, tokenID string, scopes []string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": principalID,
+		"jti": tokenID,
+		"scp": scopes,
+		"iat": now.Unix(),
+		"exp": now.Add(expiresIn).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(v.secret)
+}
@@ -0,0 +1,228 @@
+// ABOUTME: Recorded API tokens: minting with a jti, revocation checks, and last-use tracking
+// ABOUTME: Scopes narrow what a token may do on top of its principal's roles
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// Token scopes. Every token carries the client scope; a token without the
+// admin scope cannot use admin or owner roles even if its principal holds them.
+const (
+	ScopeClient = "client" // client API and ClientService
+	ScopeAdmin  = "admin"  // AdminService and admin HTTP endpoints
+)
+
+// AllScopes lists every scope, in display order.
+var AllScopes = []string{ScopeClient, ScopeAdmin}
+
+// TokenTouchInterval is the minimum time between last_used_at writes for
+// one token.
+const TokenTouchInterval = time.Minute
+
+// tokenLookupTimeout bounds the store lookup made while verifying a token.
+const tokenLookupTimeout = 5 * time.Second
+
+// ValidateScopes checks that scopes includes the client scope and only names
+// known scopes.
+func ValidateScopes(scopes []string) error {
+	if !slices.Contains(scopes, ScopeClient) {
+		return fmt.Errorf("scope %q is required", ScopeClient)
+	}
+	for _, s := range scopes {
+		if !slices.Contains(AllScopes, s) {
+			return fmt.Errorf("unknown scope %q", s)
+		}
+	}
+	return nil
+}
+
+// IntersectCapabilities returns what both held and subset cover, matching
+// patterns the way packs.MatchCapability does: the capabilities in held that
+// a pattern in subset covers, then those in subset that a pattern in held
+// covers and that are not already included. A principal holding "web.*" and
+// a token limited to "web.search" yields ["web.search"].
+func IntersectCapabilities(held, subset []string) []string {
+	result := []string{}
+	for _, c := range held {
+		if packs.HasCapability(subset, c) {
+			result = append(result, c)
+		}
+	}
+	for _, c := range subset {
+		if packs.HasCapability(held, c) && !packs.HasCapability(result, c) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// normalizeTokenLimit trims, dedupes and sorts a token's agent or capability
+// list, returning nil when nothing is left so the token stays unlimited.
+func normalizeTokenLimit(list []string) []string {
+	var out []string
+	for _, v := range list {
+		if v = strings.TrimSpace(v); v != "" && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// TokenStore persists API token records.
+type TokenStore interface {
+	CreateAPIToken(ctx context.Context, t *store.APIToken) error
+	GetAPIToken(ctx context.Context, id string) (*store.APIToken, error)
+	TouchAPIToken(ctx context.Context, id string, at time.Time) error
+}
+
+// TrackedVerifier wraps a JWTVerifier so that recorded tokens are checked
+// against their TokenStore record: revoked tokens are rejected, scopes come
+// from the record, and last use is written at most once per
+// TokenTouchInterval. Tokens without a jti are passed through unscoped.
+type TrackedVerifier struct {
+	inner   *JWTVerifier
+	tokens  TokenStore
+	logger  *slog.Logger
+	now     func() time.Time
+	mu      sync.Mutex
+	touched map[string]time.Time
+}
+
+// NewTrackedVerifier creates a verifier backed by the given token store.
+func NewTrackedVerifier(inner *JWTVerifier, tokens TokenStore, logger *slog.Logger) *TrackedVerifier {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &TrackedVerifier{
+		inner:   inner,
+		tokens:  tokens,
+		logger:  logger,
+		now:     time.Now,
+		touched: make(map[string]time.Time),
+	}
+}
+
+// Verify implements TokenVerifier.
+func (v *TrackedVerifier) Verify(tokenString string) (string, error) {
+	claims, err := v.VerifyClaims(tokenString)
+	if err != nil {
+		return "", err
+	}
+	return claims.PrincipalID, nil
+}
+
+// VerifyClaims implements ClaimsVerifier.
+func (v *TrackedVerifier) VerifyClaims(tokenString string) (*Claims, error) {
+	claims, err := v.inner.VerifyClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenID == "" {
+		return claims, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tokenLookupTimeout)
+	defer cancel()
+
+	rec, err := v.tokens.GetAPIToken(ctx, claims.TokenID)
+	if errors.Is(err, store.ErrAPITokenNotFound) {
+		return nil, ErrRevokedToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("looking up token: %w", err)
+	}
+	if rec.RevokedAt != nil || rec.PrincipalID != claims.PrincipalID {
+		return nil, ErrRevokedToken
+	}
+
+	claims.Scopes = rec.Scopes
+	claims.AgentIDs = rec.AgentIDs
+	claims.Capabilities = rec.Capabilities
+	v.touch(ctx, rec.ID)
+	return claims, nil
+}
+
+// touch records a use of the token unless one was recorded recently.
+func (v *TrackedVerifier) touch(ctx context.Context, id string) {
+	now := v.now()
+	v.mu.Lock()
+	if last, ok := v.touched[id]; ok && now.Sub(last) < TokenTouchInterval {
+		v.mu.Unlock()
+		return
+	}
+	v.touched[id] = now
+	v.mu.Unlock()
+
+	if err := v.tokens.TouchAPIToken(ctx, id, now); err != nil {
+		v.logger.Warn("failed to record token use", "token_id", id, "error", err)
+	}
+}
+
+// MintRequest describes a token to issue.
+type MintRequest struct {
+	PrincipalID string
+	CreatedBy   string
+	TTL         time.Duration
+	Scopes      []string
+
+	// AgentIDs and Capabilities optionally limit the token to some agents
+	// and to a subset of the principal's capabilities. Nil leaves it
+	// unlimited; an empty list is normalized to nil.
+	AgentIDs     []string
+	Capabilities []string
+}
+
+// TokenMinter issues JWTs that are recorded in a TokenStore so they can be
+// listed and revoked.
+type TokenMinter struct {
+	gen    *JWTVerifier
+	tokens TokenStore
+}
+
+// NewTokenMinter creates a minter that signs with gen and records to tokens.
+func NewTokenMinter(gen *JWTVerifier, tokens TokenStore) *TokenMinter {
+	return &TokenMinter{gen: gen, tokens: tokens}
+}
+
+// Mint records and signs a new token. The returned token string is the only
+// copy; the record never holds it.
+func (m *TokenMinter) Mint(ctx context.Context, req MintRequest) (string, *store.APIToken, error) {
+	if err := ValidateScopes(req.Scopes); err != nil {
+		return "", nil, err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	rec := &store.APIToken{
+		ID:          uuid.New().String(),
+		PrincipalID: req.PrincipalID,
+		Scopes:      req.Scopes,
+		CreatedBy:   req.CreatedBy,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(req.TTL),
+
+		AgentIDs:     normalizeTokenLimit(req.AgentIDs),
+		Capabilities: normalizeTokenLimit(req.Capabilities),
+	}
+	if err := m.tokens.CreateAPIToken(ctx, rec); err != nil {
+		return "", nil, fmt.Errorf("recording token: %w", err)
+	}
+	token, err := m.gen.GenerateWithID(req.PrincipalID, req.TTL, rec.ID, req.Scopes)
+	if err != nil {
+		return "", nil, fmt.Errorf("signing token: %w", err)
+	}
+	return token, rec, nil
+}
@@ -0,0 +1,198 @@
+// ABOUTME: Tests for recorded API tokens: minting, revocation, scopes, capability limits, and throttled last-use writes
+// ABOUTME: Uses a real SQLite store so the jti round-trips through the record
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func newTestMinter(t *testing.T) (*TokenMinter, *TrackedVerifier, *store.SQLStore) {
+	t.Helper()
+	s := createTestStore(t)
+	jwtVerifier, err := NewJWTVerifier(httpTestSecret)
+	if err != nil {
+		t.Fatalf("NewJWTVerifier: %v", err)
+	}
+	return NewTokenMinter(jwtVerifier, s), NewTrackedVerifier(jwtVerifier, s, nil), s
+}
+
+func TestTokenMinter_MintAndVerify(t *testing.T) {
+	minter, verifier, s := newTestMinter(t)
+	ctx := context.Background()
+
+	token, rec, err := minter.Mint(ctx, MintRequest{
+		PrincipalID: "p1", CreatedBy: "alice", TTL: time.Hour, Scopes: []string{ScopeClient},
+	})
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+
+	claims, err := verifier.VerifyClaims(token)
+	if err != nil {
+		t.Fatalf("VerifyClaims: %v", err)
+	}
+	if claims.PrincipalID != "p1" || claims.TokenID != rec.ID {
+		t.Errorf("claims = %+v, want p1 with jti %s", claims, rec.ID)
+	}
+	if len(claims.Scopes) != 1 || claims.Scopes[0] != ScopeClient {
+		t.Errorf("scopes = %v, want [client]", claims.Scopes)
+	}
+
+	stored, err := s.GetAPIToken(ctx, rec.ID)
+	if err != nil {
+		t.Fatalf("GetAPIToken: %v", err)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("last_used_at not recorded on verification")
+	}
+
+	if err := s.RevokeAPIToken(ctx, rec.ID, "alice"); err != nil {
+		t.Fatalf("RevokeAPIToken: %v", err)
+	}
+	if _, err := verifier.Verify(token); !errors.Is(err, ErrRevokedToken) {
+		t.Errorf("Verify after revoke = %v, want ErrRevokedToken", err)
+	}
+}
+
+func TestTokenMinter_RejectsInvalidScopes(t *testing.T) {
+	minter, _, _ := newTestMinter(t)
+	for _, scopes := range [][]string{nil, {ScopeAdmin}, {ScopeClient, "root"}} {
+		if _, _, err := minter.Mint(context.Background(), MintRequest{PrincipalID: "p1", TTL: time.Hour, Scopes: scopes}); err == nil {
+			t.Errorf("Mint(%v) succeeded, want error", scopes)
+		}
+	}
+}
+
+func TestTrackedVerifier_ThrottlesLastUsedWrites(t *testing.T) {
+	minter, verifier, s := newTestMinter(t)
+	ctx := context.Background()
+	token, rec, err := minter.Mint(ctx, MintRequest{PrincipalID: "p1", TTL: time.Hour, Scopes: []string{ScopeClient}})
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	verifier.now = func() time.Time { return now }
+	lastUsed := func() time.Time {
+		t.Helper()
+		stored, err := s.GetAPIToken(ctx, rec.ID)
+		if err != nil || stored.LastUsedAt == nil {
+			t.Fatalf("GetAPIToken: %v, last used %v", err, stored)
+		}
+		return *stored.LastUsedAt
+	}
+
+	if _, err := verifier.Verify(token); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	first := now
+
+	now = now.Add(30 * time.Second)
+	if _, err := verifier.Verify(token); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got := lastUsed(); !got.Equal(first) {
+		t.Errorf("last_used_at = %v, want unchanged %v within the throttle window", got, first)
+	}
+
+	now = now.Add(TokenTouchInterval)
+	if _, err := verifier.Verify(token); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got := lastUsed(); !got.Equal(now) {
+		t.Errorf("last_used_at = %v, want %v after the window", got, now)
+	}
+}
+
+func TestTrackedVerifier_LegacyTokenIsUnscoped(t *testing.T) {
+	_, verifier, _ := newTestMinter(t)
+	legacy, _ := verifier.inner.Generate("p1", time.Hour)
+
+	claims, err := verifier.VerifyClaims(legacy)
+	if err != nil {
+		t.Fatalf("VerifyClaims: %v", err)
+	}
+	if claims.TokenID != "" || claims.Scopes != nil {
+		t.Errorf("claims = %+v, want no jti and nil scopes", claims)
+	}
+}
+
+func TestTokenMinter_AgentAndCapabilityLimits(t *testing.T) {
+	minter, verifier, _ := newTestMinter(t)
+
+	token, rec, err := minter.Mint(context.Background(), MintRequest{
+		PrincipalID: "p1", TTL: time.Hour, Scopes: []string{ScopeClient},
+		AgentIDs: []string{" agent-2", "agent-1", "agent-2"}, Capabilities: []string{},
+	})
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+	if len(rec.AgentIDs) != 2 || rec.AgentIDs[0] != "agent-1" || rec.Capabilities != nil {
+		t.Errorf("record limits = %v / %v, want [agent-1 agent-2] / nil", rec.AgentIDs, rec.Capabilities)
+	}
+
+	claims, err := verifier.VerifyClaims(token)
+	if err != nil {
+		t.Fatalf("VerifyClaims: %v", err)
+	}
+	if len(claims.AgentIDs) != 2 || claims.Capabilities != nil {
+		t.Errorf("claims limits = %v / %v, want the record's", claims.AgentIDs, claims.Capabilities)
+	}
+}
+
+func TestIntersectCapabilities(t *testing.T) {
+	tests := []struct {
+		name         string
+		held, subset []string
+		want         string
+	}{
+		{"exact", []string{"base", "notes"}, []string{"notes", "admin"}, "notes"},
+		{"wildcard principal", []string{"*"}, []string{"web.search", "notes"}, "web.search,notes"},
+		{"prefix principal", []string{"web.*", "notes"}, []string{"web.search", "webhook.fire"}, "web.search"},
+		{"wildcard token", []string{"web.*", "notes"}, []string{"*"}, "web.*,notes"},
+		{"prefix token", []string{"web.search", "web.fetch", "notes"}, []string{"web.*"}, "web.search,web.fetch"},
+		{"narrower prefix token", []string{"web.*"}, []string{"web.search.*"}, "web.search.*"},
+		{"same pattern", []string{"web.*"}, []string{"web.*"}, "web.*"},
+		{"disjoint", []string{"web.*"}, []string{"notes"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(IntersectCapabilities(tt.held, tt.subset), ","); got != tt.want {
+				t.Errorf("IntersectCapabilities(%v, %v) = %s, want %s", tt.held, tt.subset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPAuthMiddleware_ClientScopedTokenIsNotAdmin(t *testing.T) {
+	minter, verifier, _ := newTestMinter(t)
+	token, _, err := minter.Mint(context.Background(), MintRequest{PrincipalID: "owner-1", TTL: time.Hour, Scopes: []string{ScopeClient}})
+	if err != nil {
+		t.Fatalf("Mint: %v", err)
+	}
+
+	principals := &mockPrincipalStore{principal: &store.Principal{
+		ID: "owner-1", Type: store.PrincipalTypeClient, Status: store.PrincipalStatusApproved,
+	}}
+	roles := &mockRoleStore{roles: []store.RoleName{store.RoleOwner}}
+	handler := HTTPAuthMiddleware(principals, roles, verifier, nil)(RequireAdminHTTP(nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }),
+	))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/bindings", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403 for an owner token without the admin scope", rec.Code)
+	}
+}
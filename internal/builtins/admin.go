@@ -9,11 +9,11 @@ import (
 	"errors"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/2389/coven-gateway/internal/agent"
 	"github.com/2389/coven-gateway/internal/packs"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
@@ -109,7 +109,7 @@ func convertEventToMap(evt store.LedgerEvent) map[string]any {
 		"direction": string(evt.Direction),
 		"author":    evt.Author,
 		"type":      string(evt.Type),
-		"timestamp": evt.Timestamp.Format(time.RFC3339),
+		"timestamp": timeparse.Format(evt.Timestamp),
 	}
 	if evt.ThreadID != nil {
 		e["thread_id"] = *evt.ThreadID
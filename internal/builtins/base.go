@@ -1,5 +1,5 @@
 // ABOUTME: Base pack provides default tools for all agents: log, todo, bbs.
-// ABOUTME: Requires the "base" capability.
+// ABOUTME: Requires the "base" capability, except bbs_pin_thread which requires "admin".
 
 package builtins
 
@@ -8,16 +8,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"time"
+	"strings"
 
 	"github.com/2389/coven-gateway/internal/packs"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
 // BasePack creates the base pack with log, todo, and bbs tools.
 func BasePack(s store.BuiltinStore) *packs.BuiltinPack {
-	b := &baseHandlers{store: s}
+	return BasePackWithLimits(s, DefaultLimits())
+}
+
+// BasePackWithLimits creates the base pack with the given write limits.
+func BasePackWithLimits(s store.BuiltinStore, limits Limits) *packs.BuiltinPack {
+	b := &baseHandlers{store: s, limits: limits}
 	return &packs.BuiltinPack{
 		ID: "builtin:base",
 		Tools: []*packs.BuiltinTool{
@@ -29,7 +35,8 @@ func BasePack(s store.BuiltinStore) *packs.BuiltinPack {
 					InputSchemaJson:      `{"type":"object","properties":{"message":{"type":"string"},"tags":{"type":"array","items":{"type":"string"}}},"required":["message"]}`,
 					RequiredCapabilities: []string{"base"},
 				},
-				Handler: b.LogEntry,
+				Handler:  b.LogEntry,
+				Precheck: limits.precheck(s, QuotaLogs),
 			},
 			{
 				Definition: &pb.ToolDefinition{
@@ -45,16 +52,17 @@ func BasePack(s store.BuiltinStore) *packs.BuiltinPack {
 				Definition: &pb.ToolDefinition{
 					Name:                 "todo_add",
 					Description:          "Create a todo",
-					InputSchemaJson:      `{"type":"object","properties":{"description":{"type":"string"},"priority":{"type":"string","enum":["low","medium","high"]},"due_date":{"type":"string","format":"date-time"},"notes":{"type":"string"}},"required":["description"]}`,
+					InputSchemaJson:      `{"type":"object","properties":{"description":{"type":"string"},"priority":{"type":"string","enum":["low","medium","high"]},"due_date":{"type":"string","format":"date-time"},"notes":{"type":"string"},"project":{"type":"string"},"assignee":{"type":"string"}},"required":["description"]}`,
 					RequiredCapabilities: []string{"base"},
 				},
-				Handler: b.TodoAdd,
+				Handler:  b.TodoAdd,
+				Precheck: limits.precheck(s, QuotaTodos),
 			},
 			{
 				Definition: &pb.ToolDefinition{
 					Name:                 "todo_list",
-					Description:          "List todos",
-					InputSchemaJson:      `{"type":"object","properties":{"status":{"type":"string","enum":["pending","in_progress","completed"]},"priority":{"type":"string","enum":["low","medium","high"]}}}`,
+					Description:          "List todos. scope is mine (default), assigned_to_me, or project:<name> for a project you are a member of",
+					InputSchemaJson:      `{"type":"object","properties":{"scope":{"type":"string"},"status":{"type":"string","enum":["pending","in_progress","completed"]},"priority":{"type":"string","enum":["low","medium","high"]},"project":{"type":"string"},"assignee":{"type":"string"}}}`,
 					RequiredCapabilities: []string{"base"},
 				},
 				Handler: b.TodoList,
@@ -63,10 +71,11 @@ func BasePack(s store.BuiltinStore) *packs.BuiltinPack {
 				Definition: &pb.ToolDefinition{
 					Name:                 "todo_update",
 					Description:          "Update a todo",
-					InputSchemaJson:      `{"type":"object","properties":{"id":{"type":"string"},"status":{"type":"string","enum":["pending","in_progress","completed"]},"priority":{"type":"string","enum":["low","medium","high"]},"notes":{"type":"string"},"due_date":{"type":"string","format":"date-time"}},"required":["id"]}`,
+					InputSchemaJson:      `{"type":"object","properties":{"id":{"type":"string"},"status":{"type":"string","enum":["pending","in_progress","completed"]},"priority":{"type":"string","enum":["low","medium","high"]},"notes":{"type":"string"},"due_date":{"type":"string","format":"date-time"},"project":{"type":"string"},"assignee":{"type":"string"}},"required":["id"]}`,
 					RequiredCapabilities: []string{"base"},
 				},
-				Handler: b.TodoUpdate,
+				Handler:  b.TodoUpdate,
+				Precheck: limits.precheck(s, QuotaTodos),
 			},
 			{
 				Definition: &pb.ToolDefinition{
@@ -85,7 +94,8 @@ func BasePack(s store.BuiltinStore) *packs.BuiltinPack {
 					InputSchemaJson:      `{"type":"object","properties":{"subject":{"type":"string"},"content":{"type":"string"}},"required":["subject","content"]}`,
 					RequiredCapabilities: []string{"base"},
 				},
-				Handler: b.BBSCreateThread,
+				Handler:  b.BBSCreateThread,
+				Precheck: limits.precheck(s, QuotaBBS),
 			},
 			{
 				Definition: &pb.ToolDefinition{
@@ -94,13 +104,14 @@ func BasePack(s store.BuiltinStore) *packs.BuiltinPack {
 					InputSchemaJson:      `{"type":"object","properties":{"thread_id":{"type":"string"},"content":{"type":"string"}},"required":["thread_id","content"]}`,
 					RequiredCapabilities: []string{"base"},
 				},
-				Handler: b.BBSReply,
+				Handler:  b.BBSReply,
+				Precheck: limits.precheck(s, QuotaBBS),
 			},
 			{
 				Definition: &pb.ToolDefinition{
 					Name:                 "bbs_list_threads",
-					Description:          "List discussion threads",
-					InputSchemaJson:      `{"type":"object","properties":{"limit":{"type":"integer"}}}`,
+					Description:          "List discussion threads, pinned first and then newest first. Page with offset and limit (at most 200)",
+					InputSchemaJson:      `{"type":"object","properties":{"offset":{"type":"integer","minimum":0},"limit":{"type":"integer","minimum":1,"maximum":200},"since":{"type":"string","format":"date-time"}}}`,
 					RequiredCapabilities: []string{"base"},
 				},
 				Handler: b.BBSListThreads,
@@ -108,18 +119,37 @@ func BasePack(s store.BuiltinStore) *packs.BuiltinPack {
 			{
 				Definition: &pb.ToolDefinition{
 					Name:                 "bbs_read_thread",
-					Description:          "Read a thread with replies",
-					InputSchemaJson:      `{"type":"object","properties":{"thread_id":{"type":"string"}},"required":["thread_id"]}`,
+					Description:          "Read a thread with a page of its replies. HasMore means there are more: pass the last reply's ID as after_reply_id",
+					InputSchemaJson:      `{"type":"object","properties":{"thread_id":{"type":"string"},"after_reply_id":{"type":"string"},"limit":{"type":"integer","minimum":1,"maximum":200}},"required":["thread_id"]}`,
 					RequiredCapabilities: []string{"base"},
 				},
 				Handler: b.BBSReadThread,
 			},
+			{
+				Definition: &pb.ToolDefinition{
+					Name:                 "bbs_search",
+					Description:          "Search thread subjects and post contents, newest first",
+					InputSchemaJson:      `{"type":"object","properties":{"query":{"type":"string"},"limit":{"type":"integer","minimum":1,"maximum":200}},"required":["query"]}`,
+					RequiredCapabilities: []string{"base"},
+				},
+				Handler: b.BBSSearch,
+			},
+			{
+				Definition: &pb.ToolDefinition{
+					Name:                 "bbs_pin_thread",
+					Description:          "Pin a thread to the top of the board, or unpin it with pinned false",
+					InputSchemaJson:      `{"type":"object","properties":{"thread_id":{"type":"string"},"pinned":{"type":"boolean"}},"required":["thread_id"]}`,
+					RequiredCapabilities: []string{"admin"},
+				},
+				Handler: b.BBSPinThread,
+			},
 		},
 	}
 }
 
 type baseHandlers struct {
-	store store.BuiltinStore
+	store  store.BuiltinStore
+	limits Limits
 }
 
 // Valid todo priority and status values (must match JSON schema enums).
@@ -137,24 +167,31 @@ type logEntryInput struct {
 
 func (b *baseHandlers) LogEntry(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
 	var in logEntryInput
-	if err := json.Unmarshal(input, &in); err != nil {
-		return nil, fmt.Errorf("invalid input: %w", err)
+	if err := decodeInput(input, &in); err != nil {
+		return nil, err
 	}
 
-	if in.Message == "" {
+	c := &fieldCleaner{limits: b.limits}
+	message := c.text(FieldLogMessage, "message", in.Message)
+	if strings.TrimSpace(message) == "" {
 		return nil, errors.New("message is required")
 	}
+	tags := c.tags(in.Tags)
+
+	if err := b.limits.consume(ctx, b.store, agentID, QuotaLogs); err != nil {
+		return nil, err
+	}
 
 	entry := &store.LogEntry{
 		AgentID: agentID,
-		Message: in.Message,
-		Tags:    in.Tags,
+		Message: message,
+		Tags:    tags,
 	}
 	if err := b.store.CreateLogEntry(ctx, entry); err != nil {
 		return nil, err
 	}
 
-	return json.Marshal(map[string]string{"id": entry.ID, "status": "logged"})
+	return c.result(map[string]any{"id": entry.ID, "status": "logged"})
 }
 
 type logSearchInput struct {
@@ -169,13 +206,9 @@ func (b *baseHandlers) LogSearch(ctx context.Context, agentID string, input json
 		return nil, fmt.Errorf("invalid input: %w", err)
 	}
 
-	var since *time.Time
-	if in.Since != "" {
-		t, err := time.Parse(time.RFC3339, in.Since)
-		if err != nil {
-			return nil, fmt.Errorf("invalid since date: %w", err)
-		}
-		since = &t
+	since, err := timeparse.Optional("since", in.Since)
+	if err != nil {
+		return nil, err
 	}
 
 	entries, err := b.store.SearchLogEntries(ctx, agentID, in.Query, since, in.Limit)
@@ -193,12 +226,14 @@ type todoAddInput struct {
 	Priority    string `json:"priority"`
 	DueDate     string `json:"due_date"`
 	Notes       string `json:"notes"`
+	Project     string `json:"project"`
+	Assignee    string `json:"assignee"`
 }
 
 func (b *baseHandlers) TodoAdd(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
 	var in todoAddInput
-	if err := json.Unmarshal(input, &in); err != nil {
-		return nil, fmt.Errorf("invalid input: %w", err)
+	if err := decodeInput(input, &in); err != nil {
+		return nil, err
 	}
 
 	// Validate priority if provided
@@ -206,32 +241,52 @@ func (b *baseHandlers) TodoAdd(ctx context.Context, agentID string, input json.R
 		return nil, fmt.Errorf("invalid priority %q: must be low, medium, or high", in.Priority)
 	}
 
+	c := &fieldCleaner{limits: b.limits}
 	todo := &store.Todo{
-		AgentID:     agentID,
-		Description: in.Description,
-		Priority:    in.Priority,
-		Notes:       in.Notes,
+		AgentID:         agentID,
+		Description:     c.line(FieldTodoDescription, "description", in.Description),
+		Priority:        in.Priority,
+		Notes:           c.text(FieldTodoNotes, "notes", in.Notes),
+		Project:         in.Project,
+		AssigneeAgentID: in.Assignee,
 	}
 	if in.DueDate != "" {
-		t, err := time.Parse(time.RFC3339, in.DueDate)
+		t, err := timeparse.ParseParam("due_date", in.DueDate)
 		if err != nil {
-			return nil, fmt.Errorf("invalid due_date: %w", err)
+			return nil, err
 		}
 		todo.DueDate = &t
 	}
+	if err := b.checkTodoSharing(ctx, agentID, todo); err != nil {
+		return nil, err
+	}
 
+	if err := b.limits.consume(ctx, b.store, agentID, QuotaTodos); err != nil {
+		return nil, err
+	}
 	if err := b.store.CreateTodo(ctx, todo); err != nil {
 		return nil, err
 	}
 
-	return json.Marshal(map[string]string{"id": todo.ID, "status": "created"})
+	return c.result(map[string]any{"id": todo.ID, "status": "created"})
 }
 
 type todoListInput struct {
+	Scope    string `json:"scope"`
 	Status   string `json:"status"`
 	Priority string `json:"priority"`
+	Project  string `json:"project"`
+	Assignee string `json:"assignee"`
 }
 
+// todo_list scopes; a project scope is todoScopeProject followed by the
+// project name.
+const (
+	todoScopeMine         = "mine"
+	todoScopeAssignedToMe = "assigned_to_me"
+	todoScopeProject      = "project:"
+)
+
 func (b *baseHandlers) TodoList(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
 	var in todoListInput
 	if err := json.Unmarshal(input, &in); err != nil {
@@ -246,7 +301,37 @@ func (b *baseHandlers) TodoList(ctx context.Context, agentID string, input json.
 		return nil, fmt.Errorf("invalid priority %q: must be low, medium, or high", in.Priority)
 	}
 
-	todos, err := b.store.ListTodos(ctx, agentID, in.Status, in.Priority)
+	filter := store.TodoFilter{
+		Status:          in.Status,
+		Priority:        in.Priority,
+		Project:         in.Project,
+		AssigneeAgentID: in.Assignee,
+	}
+	switch {
+	case in.Scope == "" || in.Scope == todoScopeMine:
+		filter.AgentID = agentID
+	case in.Scope == todoScopeAssignedToMe:
+		// A todo assigned to this agent stays hidden once the agent leaves
+		// the todo's project.
+		filter.AssigneeAgentID = agentID
+		filter.VisibleTo = agentID
+	case strings.HasPrefix(in.Scope, todoScopeProject):
+		project := strings.TrimPrefix(in.Scope, todoScopeProject)
+		if project == "" {
+			return nil, errors.New("project scope needs a project name")
+		}
+		if err := b.requireProjectMember(ctx, project, agentID); err != nil {
+			return nil, err
+		}
+		if in.Project != "" && in.Project != project {
+			return nil, fmt.Errorf("project %q does not match scope %q", in.Project, in.Scope)
+		}
+		filter.Project = project
+	default:
+		return nil, fmt.Errorf("invalid scope %q: must be mine, assigned_to_me, or project:<name>", in.Scope)
+	}
+
+	todos, err := b.store.ListTodos(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -260,10 +345,12 @@ type todoUpdateInput struct {
 	Priority string `json:"priority"`
 	Notes    string `json:"notes"`
 	DueDate  string `json:"due_date"`
+	Project  string `json:"project"`
+	Assignee string `json:"assignee"`
 }
 
 // applyTodoUpdates validates and applies update fields to a todo.
-func applyTodoUpdates(todo *store.Todo, in *todoUpdateInput) error {
+func applyTodoUpdates(todo *store.Todo, in *todoUpdateInput, c *fieldCleaner) error {
 	if in.Status != "" {
 		if !validStatuses[in.Status] {
 			return fmt.Errorf("invalid status %q: must be pending, in_progress, or completed", in.Status)
@@ -277,22 +364,79 @@ func applyTodoUpdates(todo *store.Todo, in *todoUpdateInput) error {
 		todo.Priority = in.Priority
 	}
 	if in.Notes != "" {
-		todo.Notes = in.Notes
+		todo.Notes = c.text(FieldTodoNotes, "notes", in.Notes)
 	}
 	if in.DueDate != "" {
-		t, err := time.Parse(time.RFC3339, in.DueDate)
+		t, err := timeparse.ParseParam("due_date", in.DueDate)
 		if err != nil {
-			return fmt.Errorf("invalid due_date: %w", err)
+			return err
 		}
 		todo.DueDate = &t
 	}
+	if in.Project != "" {
+		todo.Project = in.Project
+	}
+	if in.Assignee != "" {
+		todo.AssigneeAgentID = in.Assignee
+	}
+	return nil
+}
+
+// requireProjectMember returns an error unless agentID is a member of the
+// project.
+func (b *baseHandlers) requireProjectMember(ctx context.Context, project, agentID string) error {
+	ok, err := b.store.IsProjectMember(ctx, project, agentID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("not a member of project %q", project)
+	}
 	return nil
 }
 
+// checkTodoSharing verifies that agentID may put the todo in its project and
+// that its assignee can see it. Only todos in a project can be assigned, and
+// only to the project's members.
+func (b *baseHandlers) checkTodoSharing(ctx context.Context, agentID string, todo *store.Todo) error {
+	if todo.Project == "" {
+		if todo.AssigneeAgentID != "" {
+			return errors.New("assignee requires a project")
+		}
+		return nil
+	}
+	if err := b.requireProjectMember(ctx, todo.Project, agentID); err != nil {
+		return err
+	}
+	if todo.AssigneeAgentID == "" || todo.AssigneeAgentID == agentID {
+		return nil
+	}
+	ok, err := b.store.IsProjectMember(ctx, todo.Project, todo.AssigneeAgentID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("assignee %q is not a member of project %q", todo.AssigneeAgentID, todo.Project)
+	}
+	return nil
+}
+
+// canUpdateTodo reports whether agentID may update the todo: its creator
+// always can, and so can members of the todo's project.
+func (b *baseHandlers) canUpdateTodo(ctx context.Context, agentID string, todo *store.Todo) (bool, error) {
+	if todo.AgentID == agentID {
+		return true, nil
+	}
+	if todo.Project == "" {
+		return false, nil
+	}
+	return b.store.IsProjectMember(ctx, todo.Project, agentID)
+}
+
 func (b *baseHandlers) TodoUpdate(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
 	var in todoUpdateInput
-	if err := json.Unmarshal(input, &in); err != nil {
-		return nil, fmt.Errorf("invalid input: %w", err)
+	if err := decodeInput(input, &in); err != nil {
+		return nil, err
 	}
 
 	todo, err := b.store.GetTodo(ctx, in.ID)
@@ -300,20 +444,33 @@ func (b *baseHandlers) TodoUpdate(ctx context.Context, agentID string, input jso
 		return nil, err
 	}
 
-	// Verify ownership - agents can only update their own todos
-	if todo.AgentID != agentID {
+	// Agents can update their own todos and those of their projects
+	ok, err := b.canUpdateTodo(ctx, agentID, todo)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
 		return nil, errors.New("todo not found")
 	}
 
-	if err := applyTodoUpdates(todo, &in); err != nil {
+	c := &fieldCleaner{limits: b.limits}
+	if err := applyTodoUpdates(todo, &in, c); err != nil {
 		return nil, fmt.Errorf("apply todo updates: %w", err)
 	}
+	if in.Project != "" || in.Assignee != "" {
+		if err := b.checkTodoSharing(ctx, agentID, todo); err != nil {
+			return nil, err
+		}
+	}
 
+	if err := b.limits.consume(ctx, b.store, agentID, QuotaTodos); err != nil {
+		return nil, err
+	}
 	if err := b.store.UpdateTodo(ctx, todo); err != nil {
 		return nil, err
 	}
 
-	return json.Marshal(map[string]string{"status": "updated"})
+	return c.result(map[string]any{"status": "updated"})
 }
 
 type todoDeleteInput struct {
@@ -351,27 +508,34 @@ type bbsCreateThreadInput struct {
 
 func (b *baseHandlers) BBSCreateThread(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
 	var in bbsCreateThreadInput
-	if err := json.Unmarshal(input, &in); err != nil {
-		return nil, fmt.Errorf("invalid input: %w", err)
+	if err := decodeInput(input, &in); err != nil {
+		return nil, err
 	}
 
-	if in.Subject == "" {
+	c := &fieldCleaner{limits: b.limits}
+	subject := c.line(FieldBBSSubject, "subject", in.Subject)
+	content := c.text(FieldBBSContent, "content", in.Content)
+	if subject == "" {
 		return nil, errors.New("subject is required")
 	}
-	if in.Content == "" {
+	if strings.TrimSpace(content) == "" {
 		return nil, errors.New("content is required")
 	}
 
+	if err := b.limits.consume(ctx, b.store, agentID, QuotaBBS); err != nil {
+		return nil, err
+	}
+
 	post := &store.BBSPost{
 		AgentID: agentID,
-		Subject: in.Subject,
-		Content: in.Content,
+		Subject: subject,
+		Content: content,
 	}
 	if err := b.store.CreateBBSPost(ctx, post); err != nil {
 		return nil, err
 	}
 
-	return json.Marshal(map[string]string{"thread_id": post.ID, "status": "created"})
+	return c.result(map[string]any{"thread_id": post.ID, "status": "created"})
 }
 
 type bbsReplyInput struct {
@@ -381,14 +545,16 @@ type bbsReplyInput struct {
 
 func (b *baseHandlers) BBSReply(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
 	var in bbsReplyInput
-	if err := json.Unmarshal(input, &in); err != nil {
-		return nil, fmt.Errorf("invalid input: %w", err)
+	if err := decodeInput(input, &in); err != nil {
+		return nil, err
 	}
 
 	if in.ThreadID == "" {
 		return nil, errors.New("thread_id is required")
 	}
-	if in.Content == "" {
+	c := &fieldCleaner{limits: b.limits}
+	content := c.text(FieldBBSContent, "content", in.Content)
+	if strings.TrimSpace(content) == "" {
 		return nil, errors.New("content is required")
 	}
 
@@ -405,20 +571,26 @@ func (b *baseHandlers) BBSReply(ctx context.Context, agentID string, input json.
 		return nil, errors.New("cannot reply to a reply, use original thread_id")
 	}
 
+	if err := b.limits.consume(ctx, b.store, agentID, QuotaBBS); err != nil {
+		return nil, err
+	}
+
 	post := &store.BBSPost{
 		AgentID:  agentID,
 		ThreadID: in.ThreadID,
-		Content:  in.Content,
+		Content:  content,
 	}
 	if err := b.store.CreateBBSPost(ctx, post); err != nil {
 		return nil, err
 	}
 
-	return json.Marshal(map[string]string{"post_id": post.ID, "status": "posted"})
+	return c.result(map[string]any{"post_id": post.ID, "status": "posted"})
 }
 
 type bbsListThreadsInput struct {
-	Limit int `json:"limit"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+	Since  string `json:"since"`
 }
 
 func (b *baseHandlers) BBSListThreads(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
@@ -426,17 +598,31 @@ func (b *baseHandlers) BBSListThreads(ctx context.Context, agentID string, input
 	if err := json.Unmarshal(input, &in); err != nil {
 		return nil, fmt.Errorf("invalid input: %w", err)
 	}
+	if in.Offset < 0 {
+		return nil, errors.New("offset must not be negative")
+	}
+	since, err := timeparse.Optional("since", in.Since)
+	if err != nil {
+		return nil, err
+	}
 
-	threads, err := b.store.ListBBSThreads(ctx, in.Limit)
+	limit := bbsPageSize(in.Limit)
+	threads, err := b.store.ListBBSThreads(ctx, store.BBSThreadFilter{Since: since, Offset: in.Offset, Limit: limit})
 	if err != nil {
 		return nil, err
 	}
 
-	return json.Marshal(map[string]any{"threads": threads, "count": len(threads)})
+	result := map[string]any{"threads": threads, "count": len(threads)}
+	if len(threads) == limit {
+		result["next_offset"] = in.Offset + limit
+	}
+	return json.Marshal(result)
 }
 
 type bbsReadThreadInput struct {
-	ThreadID string `json:"thread_id"`
+	ThreadID     string `json:"thread_id"`
+	AfterReplyID string `json:"after_reply_id"`
+	Limit        int    `json:"limit"`
 }
 
 func (b *baseHandlers) BBSReadThread(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
@@ -445,10 +631,77 @@ func (b *baseHandlers) BBSReadThread(ctx context.Context, agentID string, input
 		return nil, fmt.Errorf("invalid input: %w", err)
 	}
 
-	thread, err := b.store.GetBBSThread(ctx, in.ThreadID)
+	thread, err := b.store.GetBBSThreadPage(ctx, in.ThreadID, in.AfterReplyID, bbsPageSize(in.Limit))
+	if errors.Is(err, store.ErrNotFound) {
+		if in.AfterReplyID != "" {
+			return nil, errors.New("thread not found, or after_reply_id is not one of its replies")
+		}
+		return nil, errors.New("thread not found")
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	return json.Marshal(thread)
 }
+
+type bbsSearchInput struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+func (b *baseHandlers) BBSSearch(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
+	var in bbsSearchInput
+	if err := json.Unmarshal(input, &in); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	query := strings.TrimSpace(in.Query)
+	if query == "" {
+		return nil, errors.New("query is required")
+	}
+
+	posts, err := b.store.SearchBBSPosts(ctx, query, bbsPageSize(in.Limit))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]any{"posts": posts, "count": len(posts)})
+}
+
+type bbsPinThreadInput struct {
+	ThreadID string `json:"thread_id"`
+	Pinned   *bool  `json:"pinned"`
+}
+
+func (b *baseHandlers) BBSPinThread(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
+	var in bbsPinThreadInput
+	if err := json.Unmarshal(input, &in); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	if in.ThreadID == "" {
+		return nil, errors.New("thread_id is required")
+	}
+	pinned := in.Pinned == nil || *in.Pinned
+
+	if err := b.store.SetBBSThreadPinned(ctx, in.ThreadID, pinned); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, errors.New("thread not found, use the original thread_id")
+		}
+		return nil, err
+	}
+
+	return json.Marshal(map[string]any{"thread_id": in.ThreadID, "pinned": pinned, "status": "updated"})
+}
+
+// maxBBSPageSize caps the threads, replies or search results one BBS tool
+// call returns.
+const maxBBSPageSize = 200
+
+// bbsPageSize returns the page size for a requested limit: 50 when unset,
+// at most maxBBSPageSize.
+func bbsPageSize(limit int) int {
+	if limit <= 0 {
+		return 50
+	}
+	return min(limit, maxBBSPageSize)
+}
@@ -121,6 +121,102 @@ func TestTodoOwnershipVerification(t *testing.T) {
 	}
 }
 
+func TestTodoSharedProjects(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	pack := BasePack(s)
+	addHandler := findHandler(pack, "todo_add")
+	listHandler := findHandler(pack, "todo_list")
+	updateHandler := findHandler(pack, "todo_update")
+
+	for _, agent := range []string{"agent-1", "agent-2"} {
+		if err := s.AddProjectMember(ctx, "apollo", agent); err != nil {
+			t.Fatalf("AddProjectMember: %v", err)
+		}
+	}
+
+	add := func(agentID, input string) string {
+		t.Helper()
+		result, err := addHandler(ctx, agentID, json.RawMessage(input))
+		if err != nil {
+			t.Fatalf("todo_add %s: %v", input, err)
+		}
+		var resp map[string]string
+		if err := json.Unmarshal(result, &resp); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return resp["id"]
+	}
+	list := func(agentID, input string) ([]*store.Todo, error) {
+		t.Helper()
+		result, err := listHandler(ctx, agentID, json.RawMessage(input))
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			Todos []*store.Todo `json:"todos"`
+		}
+		if err := json.Unmarshal(result, &resp); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return resp.Todos, nil
+	}
+
+	shared := add("agent-1", `{"description": "ship it", "project": "apollo", "assignee": "agent-2"}`)
+	add("agent-1", `{"description": "private"}`)
+
+	// agent-3 is not in the project, so it cannot use it or be assigned to it.
+	if _, err := addHandler(ctx, "agent-3", json.RawMessage(`{"description": "x", "project": "apollo"}`)); err == nil {
+		t.Error("expected error adding to a project the agent is not in")
+	}
+	if _, err := addHandler(ctx, "agent-1", json.RawMessage(`{"description": "x", "project": "apollo", "assignee": "agent-3"}`)); err == nil {
+		t.Error("expected error assigning to a non-member")
+	}
+	if _, err := addHandler(ctx, "agent-1", json.RawMessage(`{"description": "x", "assignee": "agent-2"}`)); err == nil {
+		t.Error("expected error assigning a todo without a project")
+	}
+
+	mine, err := list("agent-1", `{}`)
+	if err != nil || len(mine) != 2 {
+		t.Fatalf("agent-1 mine = %d todos, err %v; want 2", len(mine), err)
+	}
+	theirs, err := list("agent-2", `{"scope": "mine"}`)
+	if err != nil || len(theirs) != 0 {
+		t.Errorf("agent-2 mine = %d todos, err %v; want 0", len(theirs), err)
+	}
+	assigned, err := list("agent-2", `{"scope": "assigned_to_me"}`)
+	if err != nil || len(assigned) != 1 || assigned[0].ID != shared {
+		t.Errorf("agent-2 assigned_to_me = %v, err %v; want the shared todo", assigned, err)
+	}
+	board, err := list("agent-2", `{"scope": "project:apollo"}`)
+	if err != nil || len(board) != 1 || board[0].Project != "apollo" || board[0].AssigneeAgentID != "agent-2" {
+		t.Errorf("agent-2 project:apollo = %v, err %v; want only the shared todo", board, err)
+	}
+	if _, err := list("agent-3", `{"scope": "project:apollo"}`); err == nil {
+		t.Error("expected error listing a project the agent is not in")
+	}
+	if _, err := list("agent-1", `{"scope": "everything"}`); err == nil {
+		t.Error("expected error for an unknown scope")
+	}
+
+	// Project members may update shared todos.
+	if _, err := updateHandler(ctx, "agent-2", json.RawMessage(`{"id": "`+shared+`", "status": "completed"}`)); err != nil {
+		t.Fatalf("agent-2 todo_update shared todo: %v", err)
+	}
+	if _, err := updateHandler(ctx, "agent-3", json.RawMessage(`{"id": "`+shared+`", "status": "pending"}`)); err == nil {
+		t.Error("expected error when a non-member updates a shared todo")
+	}
+
+	// Leaving the project hides todos assigned through it.
+	if err := s.RemoveProjectMember(ctx, "apollo", "agent-2"); err != nil {
+		t.Fatalf("RemoveProjectMember: %v", err)
+	}
+	assigned, err = list("agent-2", `{"scope": "assigned_to_me"}`)
+	if err != nil || len(assigned) != 0 {
+		t.Errorf("agent-2 assigned_to_me after leaving = %d todos, err %v; want 0", len(assigned), err)
+	}
+}
+
 func TestBBS(t *testing.T) {
 	s := newTestStore(t)
 	pack := BasePack(s)
@@ -155,6 +251,125 @@ func TestBBS(t *testing.T) {
 	if len(thread.Replies) != 1 {
 		t.Errorf("expected 1 reply, got %d", len(thread.Replies))
 	}
+	if thread.Post.AuthorKind != store.BBSAuthorAgent {
+		t.Errorf("thread author kind = %q, want agent", thread.Post.AuthorKind)
+	}
+}
+
+func TestBBSReadThreadShowsHumanReplies(t *testing.T) {
+	s := newTestStore(t)
+	pack := BasePack(s)
+	ctx := context.Background()
+
+	thread := &store.BBSPost{AgentID: "agent-1", Subject: "Question", Content: "Anyone?"}
+	if err := s.CreateBBSPost(ctx, thread); err != nil {
+		t.Fatalf("CreateBBSPost: %v", err)
+	}
+	reply := &store.BBSPost{
+		AgentID: "human:alice", ThreadID: thread.ID, Content: "Yes",
+		AuthorKind: store.BBSAuthorHuman, AuthorName: "alice (human)",
+	}
+	if err := s.CreateBBSPost(ctx, reply); err != nil {
+		t.Fatalf("CreateBBSPost reply: %v", err)
+	}
+
+	result, err := findHandler(pack, "bbs_read_thread")(ctx, "agent-1", json.RawMessage(`{"thread_id": "`+thread.ID+`"}`))
+	if err != nil {
+		t.Fatalf("bbs_read_thread: %v", err)
+	}
+	var got store.BBSThread
+	if err := json.Unmarshal(result, &got); err != nil {
+		t.Fatalf("unmarshal thread: %v", err)
+	}
+	if len(got.Replies) != 1 || got.Replies[0].AuthorKind != store.BBSAuthorHuman || got.Replies[0].AuthorName != "alice (human)" {
+		t.Errorf("replies = %+v, want the human reply marked as such", got.Replies)
+	}
+}
+
+func TestBBSPagingSearchAndPinTools(t *testing.T) {
+	s := newTestStore(t)
+	pack := BasePack(s)
+	ctx := context.Background()
+
+	for _, subject := range []string{"Deploy notes", "Lunch", "Standup"} {
+		if err := s.CreateBBSPost(ctx, &store.BBSPost{AgentID: "agent-1", Subject: subject, Content: subject}); err != nil {
+			t.Fatalf("CreateBBSPost: %v", err)
+		}
+	}
+	threads, err := s.ListBBSThreads(ctx, store.BBSThreadFilter{})
+	if err != nil || len(threads) != 3 {
+		t.Fatalf("ListBBSThreads = %d, %v", len(threads), err)
+	}
+	last := threads[2]
+	for range 3 {
+		if err := s.CreateBBSPost(ctx, &store.BBSPost{AgentID: "agent-2", ThreadID: last.ID, Content: "reply"}); err != nil {
+			t.Fatalf("CreateBBSPost reply: %v", err)
+		}
+	}
+
+	if _, err := findHandler(pack, "bbs_pin_thread")(ctx, "agent-1", json.RawMessage(`{"thread_id": "`+last.ID+`"}`)); err != nil {
+		t.Fatalf("bbs_pin_thread: %v", err)
+	}
+	result, err := findHandler(pack, "bbs_list_threads")(ctx, "agent-1", json.RawMessage(`{"limit": 2}`))
+	if err != nil {
+		t.Fatalf("bbs_list_threads: %v", err)
+	}
+	var list struct {
+		Threads    []store.BBSPost `json:"threads"`
+		NextOffset int             `json:"next_offset"`
+	}
+	if err := json.Unmarshal(result, &list); err != nil {
+		t.Fatalf("unmarshal list: %v", err)
+	}
+	if len(list.Threads) != 2 || list.Threads[0].ID != last.ID || !list.Threads[0].Pinned || list.NextOffset != 2 {
+		t.Errorf("first page = %+v, want the pinned thread first and next_offset 2", list)
+	}
+
+	result, err = findHandler(pack, "bbs_read_thread")(ctx, "agent-1", json.RawMessage(`{"thread_id": "`+last.ID+`", "limit": 2}`))
+	if err != nil {
+		t.Fatalf("bbs_read_thread: %v", err)
+	}
+	var page store.BBSThread
+	if err := json.Unmarshal(result, &page); err != nil {
+		t.Fatalf("unmarshal thread: %v", err)
+	}
+	if len(page.Replies) != 2 || !page.HasMore {
+		t.Fatalf("first reply page = %d replies, HasMore %v; want 2 and more", len(page.Replies), page.HasMore)
+	}
+	result, err = findHandler(pack, "bbs_read_thread")(ctx, "agent-1", json.RawMessage(`{"thread_id": "`+last.ID+`", "after_reply_id": "`+page.Replies[1].ID+`"}`))
+	if err != nil {
+		t.Fatalf("bbs_read_thread next page: %v", err)
+	}
+	page = store.BBSThread{}
+	if err := json.Unmarshal(result, &page); err != nil || len(page.Replies) != 1 || page.HasMore {
+		t.Errorf("last reply page = %+v, %v; want the one remaining reply", page, err)
+	}
+
+	result, err = findHandler(pack, "bbs_search")(ctx, "agent-1", json.RawMessage(`{"query": "deploy"}`))
+	if err != nil {
+		t.Fatalf("bbs_search: %v", err)
+	}
+	var found struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(result, &found); err != nil || found.Count != 1 {
+		t.Errorf("search count = %d, %v; want 1", found.Count, err)
+	}
+	if _, err := findHandler(pack, "bbs_search")(ctx, "agent-1", json.RawMessage(`{"query": " "}`)); err == nil {
+		t.Error("bbs_search accepted an empty query")
+	}
+}
+
+func TestBBSPinThreadRequiresAdmin(t *testing.T) {
+	for _, tool := range BasePack(newTestStore(t)).Tools {
+		want := []string{"base"}
+		if tool.Definition.GetName() == "bbs_pin_thread" {
+			want = []string{"admin"}
+		}
+		if got := tool.Definition.GetRequiredCapabilities(); len(got) != 1 || got[0] != want[0] {
+			t.Errorf("%s requires %v, want %v", tool.Definition.GetName(), got, want)
+		}
+	}
 }
 
 func TestBBSInputValidation(t *testing.T) {
@@ -229,7 +444,7 @@ func findHandler(pack *packs.BuiltinPack, name string) packs.ToolHandler {
 	return nil
 }
 
-func newTestStore(t *testing.T) *store.SQLiteStore {
+func newTestStore(t *testing.T) *store.SQLStore {
 	t.Helper()
 	s, err := store.NewSQLiteStore(":memory:")
 	if err != nil {
@@ -0,0 +1,149 @@
+// ABOUTME: Delegate pack provides delegate_task, a synchronous request from one agent to another.
+// ABOUTME: Requires the "delegate" capability; targets must be on the caller's delegation allowlist.
+
+package builtins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/packs"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// Delegation limits. A delegated agent may delegate again, up to
+// MaxDelegationDepth requests deep.
+const (
+	DefaultDelegationTimeout = 2 * time.Minute
+	MaxDelegationTimeout     = 10 * time.Minute
+	MaxDelegationResultBytes = 64 * 1024
+	MaxDelegationDepth       = 3
+)
+
+// DelegationRequest asks the gateway to run Prompt on TargetID for CallerID.
+type DelegationRequest struct {
+	CallerID string
+	TargetID string
+	Prompt   string
+	Timeout  time.Duration
+	MaxBytes int
+}
+
+// DelegationResult is the delegated agent's reply.
+type DelegationResult struct {
+	ThreadID        string // the child thread holding the exchange
+	ParentRequestID string // the caller's request that delegated, if known
+	Depth           int    // 1 for a delegation made outside any delegated request
+	Response        string
+	Truncated       bool // more than MaxBytes arrived; Response holds the first MaxBytes
+}
+
+// Delegator sends a delegated request through the conversation pipeline and
+// waits for the reply. Errors, including timeouts, become tool errors.
+type Delegator interface {
+	Delegate(ctx context.Context, req *DelegationRequest) (*DelegationResult, error)
+}
+
+// DelegationPolicy is the allowlist of agents each agent may delegate to.
+type DelegationPolicy interface {
+	CanDelegate(ctx context.Context, agentID, targetAgentID string) (bool, error)
+}
+
+// DelegatePack creates the delegate pack.
+func DelegatePack(delegator Delegator, policy DelegationPolicy) *packs.BuiltinPack {
+	d := &delegateHandlers{delegator: delegator, policy: policy}
+	return &packs.BuiltinPack{
+		ID: "builtin:delegate",
+		Tools: []*packs.BuiltinTool{
+			{
+				Definition: &pb.ToolDefinition{
+					Name:                 "delegate_task",
+					Description:          "Ask another agent to do a task and wait for its answer. The other agent works in a thread of its own; its final reply is returned as the result. Only agents an admin has allowed you to delegate to can be asked.",
+					InputSchemaJson:      `{"type":"object","properties":{"agent_id":{"type":"string","description":"The agent to delegate to"},"prompt":{"type":"string","description":"What the agent should do, with everything it needs to know"},"timeout_seconds":{"type":"integer","description":"How long to wait for the reply (default: 120, max: 600)"}},"required":["agent_id","prompt"]}`,
+					RequiredCapabilities: []string{"delegate"},
+					TimeoutSeconds:       int32(MaxDelegationTimeout / time.Second),
+				},
+				Handler: d.Delegate,
+			},
+		},
+	}
+}
+
+type delegateHandlers struct {
+	delegator Delegator
+	policy    DelegationPolicy
+}
+
+type delegateTaskInput struct {
+	AgentID        string `json:"agent_id"`
+	Prompt         string `json:"prompt"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+type delegateTaskOutput struct {
+	AgentID         string `json:"agent_id"`
+	ThreadID        string `json:"thread_id"`
+	ParentRequestID string `json:"parent_request_id,omitempty"`
+	Depth           int    `json:"depth"`
+	Response        string `json:"response"`
+	Truncated       bool   `json:"truncated,omitempty"`
+}
+
+// Delegate handles delegate_task: it checks the allowlist, then blocks until
+// the target agent replies, fails, or runs out of time.
+func (d *delegateHandlers) Delegate(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
+	var in delegateTaskInput
+	if err := decodeInput(input, &in); err != nil {
+		return nil, err
+	}
+	if in.AgentID == "" {
+		return nil, errors.New("agent_id is required")
+	}
+	if strings.TrimSpace(in.Prompt) == "" {
+		return nil, errors.New("prompt is required")
+	}
+	if in.AgentID == agentID {
+		return nil, errors.New("cannot delegate to yourself")
+	}
+	timeout := DefaultDelegationTimeout
+	if in.TimeoutSeconds < 0 {
+		return nil, errors.New("timeout_seconds must not be negative")
+	}
+	if in.TimeoutSeconds > 0 {
+		timeout = min(time.Duration(in.TimeoutSeconds)*time.Second, MaxDelegationTimeout)
+	}
+
+	allowed, err := d.policy.CanDelegate(ctx, agentID, in.AgentID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, fmt.Errorf("not allowed to delegate to %s", in.AgentID)
+	}
+
+	res, err := d.delegator.Delegate(ctx, &DelegationRequest{
+		CallerID: agentID,
+		TargetID: in.AgentID,
+		Prompt:   in.Prompt,
+		Timeout:  timeout,
+		MaxBytes: MaxDelegationResultBytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Truncated {
+		res.Response = truncate(res.Response, MaxDelegationResultBytes)
+	}
+	return json.Marshal(delegateTaskOutput{
+		AgentID:         in.AgentID,
+		ThreadID:        res.ThreadID,
+		ParentRequestID: res.ParentRequestID,
+		Depth:           res.Depth,
+		Response:        res.Response,
+		Truncated:       res.Truncated,
+	})
+}
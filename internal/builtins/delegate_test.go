@@ -0,0 +1,127 @@
+// ABOUTME: Tests for the delegate pack: input validation, the delegation allowlist,
+// ABOUTME: timeout clamping, and truncation of oversized replies.
+
+package builtins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// fakeDelegator records the last request and answers with reply or err.
+type fakeDelegator struct {
+	last  *DelegationRequest
+	reply *DelegationResult
+	err   error
+}
+
+func (f *fakeDelegator) Delegate(_ context.Context, req *DelegationRequest) (*DelegationResult, error) {
+	f.last = req
+	return f.reply, f.err
+}
+
+func TestDelegateTask(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	if err := s.AddDelegationTarget(ctx, "agent-a", "agent-b"); err != nil {
+		t.Fatalf("AddDelegationTarget: %v", err)
+	}
+	delegator := &fakeDelegator{reply: &DelegationResult{ThreadID: "thread-1", ParentRequestID: "req-1", Depth: 1, Response: "done"}}
+	handler := findHandler(DelegatePack(delegator, s), "delegate_task")
+	if handler == nil {
+		t.Fatal("delegate_task handler not found")
+	}
+
+	result, err := handler(ctx, "agent-a", json.RawMessage(`{"agent_id":"agent-b","prompt":"Summarize the logs","timeout_seconds":3600}`))
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	var out delegateTaskOutput
+	if err := json.Unmarshal(result, &out); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if out.Response != "done" || out.ThreadID != "thread-1" || out.ParentRequestID != "req-1" || out.Depth != 1 {
+		t.Errorf("output = %+v", out)
+	}
+	if delegator.last.CallerID != "agent-a" || delegator.last.TargetID != "agent-b" || delegator.last.Prompt != "Summarize the logs" {
+		t.Errorf("request = %+v", delegator.last)
+	}
+	if delegator.last.Timeout != MaxDelegationTimeout {
+		t.Errorf("timeout = %s, want it clamped to %s", delegator.last.Timeout, MaxDelegationTimeout)
+	}
+
+	if _, err := handler(ctx, "agent-a", json.RawMessage(`{"agent_id":"agent-b","prompt":"again"}`)); err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if delegator.last.Timeout != DefaultDelegationTimeout {
+		t.Errorf("timeout = %s, want default %s", delegator.last.Timeout, DefaultDelegationTimeout)
+	}
+}
+
+func TestDelegateTaskRefusals(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	if err := s.AddDelegationTarget(ctx, "agent-a", "agent-b"); err != nil {
+		t.Fatalf("AddDelegationTarget: %v", err)
+	}
+	delegator := &fakeDelegator{err: errors.New("agent agent-b did not answer within 2m0s")}
+	handler := findHandler(DelegatePack(delegator, s), "delegate_task")
+
+	tests := []struct {
+		name, agentID, input, want string
+	}{
+		{"missing agent", "agent-a", `{"prompt":"hi"}`, "agent_id is required"},
+		{"missing prompt", "agent-a", `{"agent_id":"agent-b","prompt":"  "}`, "prompt is required"},
+		{"self", "agent-a", `{"agent_id":"agent-a","prompt":"hi"}`, "yourself"},
+		{"negative timeout", "agent-a", `{"agent_id":"agent-b","prompt":"hi","timeout_seconds":-1}`, "timeout_seconds"},
+		{"not allowed", "agent-b", `{"agent_id":"agent-a","prompt":"hi"}`, "not allowed to delegate to agent-a"},
+		{"delegator error", "agent-a", `{"agent_id":"agent-b","prompt":"hi"}`, "did not answer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delegator.last = nil
+			_, err := handler(ctx, tt.agentID, json.RawMessage(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one mentioning %q", err, tt.want)
+			}
+			if tt.name != "delegator error" && delegator.last != nil {
+				t.Errorf("refused call reached the delegator: %+v", delegator.last)
+			}
+		})
+	}
+}
+
+func TestDelegateTaskTruncatesReply(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	if err := s.AddDelegationTarget(ctx, "agent-a", "agent-b"); err != nil {
+		t.Fatalf("AddDelegationTarget: %v", err)
+	}
+	// The delegator hands back the first MaxBytes, cut mid-rune.
+	long := strings.Repeat("é", MaxDelegationResultBytes)[:MaxDelegationResultBytes-1]
+	delegator := &fakeDelegator{reply: &DelegationResult{ThreadID: "thread-1", Depth: 1, Response: long, Truncated: true}}
+	handler := findHandler(DelegatePack(delegator, s), "delegate_task")
+
+	result, err := handler(ctx, "agent-a", json.RawMessage(`{"agent_id":"agent-b","prompt":"hi","timeout_seconds":1}`))
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	var out delegateTaskOutput
+	if err := json.Unmarshal(result, &out); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if !out.Truncated || len(out.Response) > MaxDelegationResultBytes || !utf8.ValidString(out.Response) {
+		t.Errorf("truncated = %v, %d bytes, valid UTF-8 = %v", out.Truncated, len(out.Response), utf8.ValidString(out.Response))
+	}
+	if !strings.HasSuffix(out.Response, truncationMarker) {
+		t.Errorf("response does not end with the truncation marker")
+	}
+	if delegator.last.Timeout != time.Second {
+		t.Errorf("timeout = %s, want 1s", delegator.last.Timeout)
+	}
+}
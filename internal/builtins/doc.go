@@ -8,33 +8,47 @@
 //
 // # Tool Packs
 //
-// The package provides 5 packs with 21 tools:
+// The package provides 6 packs with 26 tools:
 //
 // Base Pack (builtin:base) - requires "base" capability:
 //
 //   - log_entry: Log an activity or event
 //   - log_search: Search past log entries
-//   - todo_add: Create a todo
-//   - todo_list: List todos (filter by status/priority)
-//   - todo_update: Update a todo's status, priority, or notes
+//   - todo_add: Create a todo, optionally in a project and assigned to a member
+//   - todo_list: List todos in a scope (mine, assigned_to_me, project:<name>)
+//   - todo_update: Update a todo's status, priority, notes, due date, project, or assignee
 //   - todo_delete: Delete a todo
 //   - bbs_create_thread: Create a new discussion thread
 //   - bbs_reply: Reply to a thread
-//   - bbs_list_threads: List discussion threads
-//   - bbs_read_thread: Read a thread with replies
+//   - bbs_list_threads: List threads, pinned first, paged with offset, limit and since
+//   - bbs_read_thread: Read a thread with a page of replies (after_reply_id, limit)
+//   - bbs_search: Search thread subjects and post contents
+//   - bbs_pin_thread: Pin or unpin a thread (requires "admin" instead)
+//
+// BBS listings return at most 200 threads, replies or posts a call, 50 by
+// default, so a busy board does not fill an agent's context.
+//
+// Todos without a project are private to the agent that created them. Todos in
+// a project can be seen, updated and assigned by the project's members, which
+// admins manage from the web UI; only the creator can delete a todo.
 //
 // Notes Pack (builtin:notes) - requires "notes" capability:
 //
-//   - note_set: Store a note
+//   - note_set: Store a note, optionally expiring after ttl_seconds
 //   - note_get: Retrieve a note
-//   - note_list: List all note keys
+//   - note_list: List note keys, optionally only those starting with prefix
 //   - note_delete: Delete a note
 //
+// Expired notes are skipped by note_get and note_list and swept from the
+// store the next time the agent's notes are read or written.
+//
 // Mail Pack (builtin:mail) - requires "mail" capability:
 //
 //   - mail_send: Send message to another agent
+//   - mail_reply: Reply to a message, continuing its thread
 //   - mail_inbox: List received messages
 //   - mail_read: Read and mark message as read
+//   - mail_thread: Show the conversation a message belongs to
 //
 // Admin Pack (builtin:admin) - requires "admin" capability:
 //
@@ -46,6 +60,17 @@
 //
 //   - ask_user: Ask the user a question and wait for response
 //
+// Delegate Pack (builtin:delegate) - requires "delegate" capability:
+//
+//   - delegate_task: Ask another agent to do a task and wait for its reply
+//
+// Unlike mail, delegate_task is synchronous: the gateway (the Delegator)
+// sends the prompt to the target in a child thread and returns its reply as
+// the tool result, cut to MaxDelegationResultBytes. Callers may only reach
+// agents on their allowlist (DelegationPolicy), chains stop at
+// MaxDelegationDepth, and a target that does not answer within the timeout
+// is canceled and reported as a tool error.
+//
 // # Registration
 //
 // Register all built-in packs:
@@ -76,6 +101,25 @@
 //   - Notes are key-value pairs per agent
 //   - Mail is inter-agent (not scoped)
 //
+// # Write Limits
+//
+// Writes through log_entry, todo_add, todo_update, note_set, the BBS tools,
+// and mail_send pass through Limits before they reach the store:
+//
+//   - Input that is not valid UTF-8, including unpaired \u surrogate
+//     escapes, is rejected with an invalid_utf8 error.
+//   - Control characters are removed. Multi-line fields keep newlines and
+//     tabs; subjects, descriptions, keys, and tags flatten them to spaces.
+//   - Fields over their byte cap are cut on a rune boundary and end in
+//     "…[truncated]". The response lists them under "truncated" with
+//     original and stored sizes. Note keys are rejected instead.
+//   - Each agent has a daily write quota per data type (logs, todos, notes,
+//     bbs, mail), counted in the store per UTC day. Past it, writes fail
+//     with quota_exceeded and the reset time until the next UTC midnight.
+//
+// Defaults come from DefaultLimits; packs.builtins in the config overrides
+// them, and the agent detail page shows today's usage.
+//
 // # User Interaction
 //
 // The ask_user tool enables agents to request input from users:
@@ -0,0 +1,458 @@
+// ABOUTME: Input limits for builtin writes: field size caps, control-character
+// ABOUTME: stripping, UTF-8 validation, and per-agent daily write quotas.
+
+package builtins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// Field keys for Limits.MaxBytes, named "<data type>.<field>".
+const (
+	FieldLogMessage      = "log.message"
+	FieldLogTag          = "log.tag"
+	FieldTodoDescription = "todo.description"
+	FieldTodoNotes       = "todo.notes"
+	FieldNoteKey         = "note.key"
+	FieldNoteValue       = "note.value"
+	FieldBBSSubject      = "bbs.subject"
+	FieldBBSContent      = "bbs.content"
+	FieldMailSubject     = "mail.subject"
+	FieldMailContent     = "mail.content"
+)
+
+// Quota kinds for Limits.DailyQuota, one per builtin data type.
+const (
+	QuotaLogs  = "logs"
+	QuotaTodos = "todos"
+	QuotaNotes = "notes"
+	QuotaBBS   = "bbs"
+	QuotaMail  = "mail"
+)
+
+// QuotaKinds lists the quota kinds in display order.
+var QuotaKinds = []string{QuotaLogs, QuotaTodos, QuotaNotes, QuotaBBS, QuotaMail}
+
+// maxLogTags caps how many tags one log entry keeps.
+const maxLogTags = 32
+
+// truncationMarker is appended to a truncated field so that whoever reads
+// the stored value can tell it was cut. It counts toward the field's cap.
+const truncationMarker = "…[truncated]"
+
+var defaultMaxBytes = map[string]int{
+	FieldLogMessage:      16 << 10,
+	FieldLogTag:          128,
+	FieldTodoDescription: 1024,
+	FieldTodoNotes:       16 << 10,
+	FieldNoteKey:         256,
+	FieldNoteValue:       64 << 10,
+	FieldBBSSubject:      512,
+	FieldBBSContent:      64 << 10,
+	FieldMailSubject:     512,
+	FieldMailContent:     64 << 10,
+}
+
+var defaultDailyQuota = map[string]int{
+	QuotaLogs:  5000,
+	QuotaTodos: 1000,
+	QuotaNotes: 2000,
+	QuotaBBS:   500,
+	QuotaMail:  1000,
+}
+
+// Limits bounds what agents can write through the builtin tools. MaxBytes
+// caps each field's stored size; DailyQuota caps writes per agent per UTC
+// day for each data type, where a negative quota means unlimited.
+type Limits struct {
+	MaxBytes   map[string]int
+	DailyQuota map[string]int
+
+	// now is the clock used for quota windows; nil means time.Now.
+	now func() time.Time
+}
+
+// DefaultLimits returns the built-in caps: generous enough for real notes
+// and posts, small enough that one call cannot bloat the database.
+func DefaultLimits() Limits {
+	l := Limits{MaxBytes: make(map[string]int), DailyQuota: make(map[string]int)}
+	for k, v := range defaultMaxBytes {
+		l.MaxBytes[k] = v
+	}
+	for k, v := range defaultDailyQuota {
+		l.DailyQuota[k] = v
+	}
+	return l
+}
+
+// NewLimits returns DefaultLimits with the given overrides applied. Unknown
+// field or quota names are an error so that typos in config don't silently
+// leave a default in place.
+func NewLimits(maxBytes, dailyQuota map[string]int) (Limits, error) {
+	l := DefaultLimits()
+	for _, k := range sortedKeys(maxBytes) {
+		if _, ok := defaultMaxBytes[k]; !ok {
+			return Limits{}, fmt.Errorf("unknown builtin field %q", k)
+		}
+		if maxBytes[k] <= 0 {
+			return Limits{}, fmt.Errorf("builtin field %q: max bytes must be positive", k)
+		}
+		l.MaxBytes[k] = maxBytes[k]
+	}
+	for _, k := range sortedKeys(dailyQuota) {
+		if _, ok := defaultDailyQuota[k]; !ok {
+			return Limits{}, fmt.Errorf("unknown builtin quota %q", k)
+		}
+		l.DailyQuota[k] = dailyQuota[k]
+	}
+	return l, nil
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// maxBytesFor returns the cap for a field, falling back to the default for
+// a zero-value Limits.
+func (l Limits) maxBytesFor(field string) int {
+	if n, ok := l.MaxBytes[field]; ok && n > 0 {
+		return n
+	}
+	return defaultMaxBytes[field]
+}
+
+// QuotaFor returns the daily write quota for kind; negative means unlimited.
+func (l Limits) QuotaFor(kind string) int {
+	if n, ok := l.DailyQuota[kind]; ok {
+		return n
+	}
+	return defaultDailyQuota[kind]
+}
+
+func (l Limits) clock() time.Time {
+	if l.now != nil {
+		return l.now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// QuotaDay returns the UTC day key that quota counters are stored under.
+func QuotaDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// consume charges one write of kind to agentID's quota for today.
+func (l Limits) consume(ctx context.Context, s store.BuiltinStore, agentID, kind string) error {
+	limit := l.QuotaFor(kind)
+	if limit < 0 {
+		return nil
+	}
+	now := l.clock()
+	if _, err := s.ConsumeBuiltinQuota(ctx, agentID, kind, QuotaDay(now), limit); err != nil {
+		if errors.Is(err, store.ErrQuotaExceeded) {
+			return quotaExceeded(kind, limit, now)
+		}
+		return fmt.Errorf("consume %s quota: %w", kind, err)
+	}
+	return nil
+}
+
+// check reports whether agentID has used up today's quota of kind, without
+// charging it.
+func (l Limits) check(ctx context.Context, s store.BuiltinStore, agentID, kind string) error {
+	limit := l.QuotaFor(kind)
+	if limit < 0 {
+		return nil
+	}
+	now := l.clock()
+	usage, err := s.GetBuiltinQuotaUsage(ctx, agentID, QuotaDay(now))
+	if err != nil {
+		return fmt.Errorf("read %s quota: %w", kind, err)
+	}
+	if usage[kind] >= limit {
+		return quotaExceeded(kind, limit, now)
+	}
+	return nil
+}
+
+// precheck returns a BuiltinTool.Precheck for tools that write kind.
+func (l Limits) precheck(s store.BuiltinStore, kind string) func(context.Context, string) error {
+	return func(ctx context.Context, agentID string) error {
+		return l.check(ctx, s, agentID, kind)
+	}
+}
+
+func quotaExceeded(kind string, limit int, now time.Time) *QuotaError {
+	y, m, d := now.Date()
+	return &QuotaError{Kind: kind, Limit: limit, ResetsAt: time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)}
+}
+
+// Error codes in the JSON form of InputError and QuotaError.
+const (
+	ErrCodeInvalidUTF8   = "invalid_utf8"
+	ErrCodeFieldTooLong  = "field_too_long"
+	ErrCodeQuotaExceeded = "quota_exceeded"
+)
+
+// InputError rejects a builtin write whose input cannot be stored. Its
+// message starts with Code so agents can match on it.
+type InputError struct {
+	Code    string
+	Field   string
+	Message string
+}
+
+func (e *InputError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// MarshalJSON renders the error as a structured body.
+func (e *InputError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+		Field string `json:"field,omitempty"`
+	}{e.Error(), e.Code, e.Field})
+}
+
+// QuotaError reports that an agent has used up a daily write quota. Writes
+// of that kind fail until ResetsAt, the next UTC midnight.
+type QuotaError struct {
+	Kind     string
+	Limit    int
+	ResetsAt time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s: daily %s quota of %d writes reached; resets at %s",
+		ErrCodeQuotaExceeded, e.Kind, e.Limit, timeparse.Format(e.ResetsAt))
+}
+
+// MarshalJSON renders the error as a structured body.
+func (e *QuotaError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error    string `json:"error"`
+		Code     string `json:"code"`
+		Kind     string `json:"kind"`
+		Limit    int    `json:"limit"`
+		ResetsAt string `json:"resets_at"`
+	}{e.Error(), ErrCodeQuotaExceeded, e.Kind, e.Limit, timeparse.Format(e.ResetsAt)})
+}
+
+// decodeInput unmarshals a tool input after checking it is valid UTF-8;
+// json.Unmarshal would otherwise replace bad bytes and unpaired surrogate
+// escapes with U+FFFD and store a silently altered value.
+func decodeInput(input json.RawMessage, v any) error {
+	if !utf8.Valid(input) {
+		return &InputError{Code: ErrCodeInvalidUTF8, Message: "input is not valid UTF-8"}
+	}
+	if hasLoneSurrogate(input) {
+		return &InputError{Code: ErrCodeInvalidUTF8, Message: "input contains an unpaired UTF-16 surrogate escape"}
+	}
+	if err := json.Unmarshal(input, v); err != nil {
+		return fmt.Errorf("invalid input: %w", err)
+	}
+	return nil
+}
+
+// hasLoneSurrogate reports whether a JSON document contains a \uXXXX escape
+// for half of a surrogate pair without its other half.
+func hasLoneSurrogate(input []byte) bool {
+	pendingHigh := false
+	for i := 0; i < len(input); i++ {
+		if input[i] != '\\' || i+1 >= len(input) {
+			if pendingHigh {
+				return true
+			}
+			continue
+		}
+		if input[i+1] != 'u' {
+			if pendingHigh {
+				return true
+			}
+			i++ // skip the escaped character, which may itself be a backslash
+			continue
+		}
+		if i+6 > len(input) {
+			return false // truncated escape; json.Unmarshal reports it
+		}
+		r, err := strconv.ParseUint(string(input[i+2:i+6]), 16, 16)
+		if err != nil {
+			return false
+		}
+		switch {
+		case r >= 0xD800 && r <= 0xDBFF:
+			if pendingHigh {
+				return true
+			}
+			pendingHigh = true
+		case r >= 0xDC00 && r <= 0xDFFF:
+			if !pendingHigh {
+				return true
+			}
+			pendingHigh = false
+		default:
+			if pendingHigh {
+				return true
+			}
+		}
+		i += 5
+	}
+	return pendingHigh
+}
+
+// Truncation tells the agent that a field was stored shorter than sent.
+type Truncation struct {
+	Field         string `json:"field"`
+	OriginalBytes int    `json:"original_bytes"`
+	StoredBytes   int    `json:"stored_bytes"`
+}
+
+// fieldCleaner sanitizes the string fields of one write and records which
+// of them were truncated.
+type fieldCleaner struct {
+	limits    Limits
+	truncated []Truncation
+}
+
+// text cleans a multi-line field: control characters other than newline
+// and tab are dropped, then the value is capped at the field's limit.
+func (c *fieldCleaner) text(field, param, s string) string {
+	return c.bound(field, param, stripControl(s, true))
+}
+
+// line cleans a single-line field such as a subject or description:
+// newlines and tabs become spaces as well.
+func (c *fieldCleaner) line(field, param, s string) string {
+	return c.bound(field, param, strings.TrimSpace(stripControl(s, false)))
+}
+
+// exact cleans a single-line field that identifies something, such as a
+// note key. Truncating it would change which record is written, so an
+// oversized value is rejected instead.
+func (c *fieldCleaner) exact(field, param, s string) (string, error) {
+	s = strings.TrimSpace(stripControl(s, false))
+	if limit := c.limits.maxBytesFor(field); len(s) > limit {
+		return "", &InputError{
+			Code:    ErrCodeFieldTooLong,
+			Field:   param,
+			Message: fmt.Sprintf("%s is %d bytes; the limit is %d", param, len(s), limit),
+		}
+	}
+	return s, nil
+}
+
+func (c *fieldCleaner) bound(field, param, s string) string {
+	limit := c.limits.maxBytesFor(field)
+	if len(s) <= limit {
+		return s
+	}
+	out := truncate(s, limit)
+	c.truncated = append(c.truncated, Truncation{Field: param, OriginalBytes: len(s), StoredBytes: len(out)})
+	return out
+}
+
+// tags cleans log tags, dropping empty ones and any past maxLogTags.
+func (c *fieldCleaner) tags(tags []string) []string {
+	if len(tags) == 0 {
+		return tags
+	}
+	out := make([]string, 0, len(tags))
+	var dropped, droppedBytes, total int
+	for _, t := range tags {
+		total += len(t)
+		t = c.line(FieldLogTag, "tags", t)
+		if t == "" {
+			continue
+		}
+		if len(out) == maxLogTags {
+			dropped++
+			droppedBytes += len(t)
+			continue
+		}
+		out = append(out, t)
+	}
+	if dropped > 0 {
+		c.truncated = append(c.truncated, Truncation{Field: "tags", OriginalBytes: total, StoredBytes: total - droppedBytes})
+	}
+	return out
+}
+
+// result marshals a write's response, adding the truncated fields if any.
+func (c *fieldCleaner) result(resp map[string]any) (json.RawMessage, error) {
+	if len(c.truncated) > 0 {
+		resp["truncated"] = c.truncated
+	}
+	return json.Marshal(resp)
+}
+
+// truncate cuts s to at most limit bytes on a rune boundary, ending with
+// truncationMarker when there is room for it.
+func truncate(s string, limit int) string {
+	keep := limit - len(truncationMarker)
+	marker := truncationMarker
+	if keep <= 0 {
+		keep, marker = limit, ""
+	}
+	for keep > 0 && !utf8.RuneStart(s[keep]) {
+		keep--
+	}
+	return s[:keep] + marker
+}
+
+// stripControl removes C0 and C1 control characters and DEL. CRLF and lone
+// CR become LF; when multiline is false, newlines and tabs become spaces.
+func stripControl(s string, multiline bool) string {
+	clean := true
+	for _, r := range s {
+		if isControl(r) {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return s
+	}
+
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		switch {
+		case r == '\n' || r == '\r' || r == '\t':
+			switch {
+			case !multiline:
+				b.WriteByte(' ')
+			case r == '\r':
+				b.WriteByte('\n')
+			default:
+				b.WriteRune(r)
+			}
+		case isControl(r):
+			// dropped
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || (r >= 0x7f && r <= 0x9f)
+}
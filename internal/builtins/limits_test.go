@@ -0,0 +1,292 @@
+// ABOUTME: Tests for builtin write limits: size caps, control characters,
+// ABOUTME: UTF-8 validation, and daily quota enforcement and reset.
+
+package builtins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+type writeResult struct {
+	ID        string       `json:"id"`
+	Status    string       `json:"status"`
+	Truncated []Truncation `json:"truncated"`
+}
+
+func callTool(t *testing.T, handler func(context.Context, string, json.RawMessage) (json.RawMessage, error), input any) (writeResult, error) {
+	t.Helper()
+	raw, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	out, err := handler(context.Background(), "agent-1", raw)
+	if err != nil {
+		return writeResult{}, err
+	}
+	var res writeResult
+	if err := json.Unmarshal(out, &res); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	return res, nil
+}
+
+func TestLogEntry_BoundarySizes(t *testing.T) {
+	s := newTestStore(t)
+	limits, err := NewLimits(map[string]int{FieldLogMessage: 64}, nil)
+	if err != nil {
+		t.Fatalf("NewLimits: %v", err)
+	}
+	handler := findHandler(BasePackWithLimits(s, limits), "log_entry")
+
+	// Exactly at the cap is stored as-is.
+	res, err := callTool(t, handler, map[string]any{"message": strings.Repeat("a", 64)})
+	if err != nil {
+		t.Fatalf("at cap: %v", err)
+	}
+	if len(res.Truncated) != 0 {
+		t.Errorf("at cap truncated = %+v, want none", res.Truncated)
+	}
+
+	// One byte over is cut and marked.
+	res, err = callTool(t, handler, map[string]any{"message": strings.Repeat("a", 65)})
+	if err != nil {
+		t.Fatalf("over cap: %v", err)
+	}
+	if len(res.Truncated) != 1 {
+		t.Fatalf("over cap truncated = %+v, want one entry", res.Truncated)
+	}
+	tr := res.Truncated[0]
+	if tr.Field != "message" || tr.OriginalBytes != 65 || tr.StoredBytes != 64 {
+		t.Errorf("truncation = %+v, want message 65 -> 64", tr)
+	}
+
+	entries, err := s.SearchLogEntries(context.Background(), "agent-1", "", nil, 10)
+	if err != nil {
+		t.Fatalf("SearchLogEntries: %v", err)
+	}
+	var stored string
+	for _, e := range entries {
+		if e.ID == res.ID {
+			stored = e.Message
+		}
+	}
+	if len(stored) != 64 || !strings.HasSuffix(stored, truncationMarker) {
+		t.Errorf("stored message = %q (%d bytes), want 64 bytes ending in the marker", stored, len(stored))
+	}
+}
+
+func TestTruncate_RuneBoundary(t *testing.T) {
+	// "é" is two bytes; a cut in the middle of one must back off.
+	s := strings.Repeat("é", 20)
+	for limit := len(truncationMarker) + 1; limit < len(s); limit++ {
+		got := truncate(s, limit)
+		if len(got) > limit {
+			t.Errorf("truncate(%d) = %d bytes, over the limit", limit, len(got))
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncate(%d) = %q, not valid UTF-8", limit, got)
+		}
+		if !strings.HasSuffix(got, truncationMarker) {
+			t.Errorf("truncate(%d) = %q, missing marker", limit, got)
+		}
+	}
+	if got := truncate("abcdef", 3); got != "abc" {
+		t.Errorf("truncate below marker size = %q, want plain cut", got)
+	}
+}
+
+func TestBuiltinWrites_StripControlCharacters(t *testing.T) {
+	s := newTestStore(t)
+	pack := BasePack(s)
+
+	res, err := callTool(t, findHandler(pack, "todo_add"), map[string]any{
+		"description": "fix\x1b[31m build\r\nnow\x00",
+		"notes":       "line one\r\nline two\ttabbed\x07\u0085",
+	})
+	if err != nil {
+		t.Fatalf("todo_add: %v", err)
+	}
+	todo, err := s.GetTodo(context.Background(), res.ID)
+	if err != nil {
+		t.Fatalf("GetTodo: %v", err)
+	}
+	if todo.Description != "fix[31m build now" {
+		t.Errorf("description = %q, want control characters removed and newlines flattened", todo.Description)
+	}
+	if todo.Notes != "line one\nline two\ttabbed" {
+		t.Errorf("notes = %q, want newlines and tabs kept, other controls removed", todo.Notes)
+	}
+
+	// A subject made only of control characters is empty once cleaned.
+	_, err = callTool(t, findHandler(pack, "bbs_create_thread"), map[string]any{"subject": "\x01\x02", "content": "body"})
+	if err == nil || err.Error() != "subject is required" {
+		t.Errorf("control-only subject err = %v, want subject is required", err)
+	}
+}
+
+func TestBuiltinWrites_RejectInvalidUTF8(t *testing.T) {
+	s := newTestStore(t)
+	handler := findHandler(NotesPack(s), "note_set")
+
+	cases := map[string]string{
+		"raw bytes":      "{\"key\":\"k\",\"value\":\"bad \xff\xfe\"}",
+		"lone low":       `{"key":"k","value":"bad \udc00"}`,
+		"lone high":      `{"key":"k","value":"bad \ud800 end"}`,
+		"high then high": `{"key":"k","value":"\ud800\ud800\udc00"}`,
+	}
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := handler(context.Background(), "agent-1", json.RawMessage(input))
+			var ierr *InputError
+			if !errors.As(err, &ierr) || ierr.Code != ErrCodeInvalidUTF8 {
+				t.Fatalf("err = %v, want invalid_utf8 InputError", err)
+			}
+			body, _ := json.Marshal(ierr)
+			if !strings.Contains(string(body), `"code":"invalid_utf8"`) {
+				t.Errorf("JSON = %s, want code field", body)
+			}
+		})
+	}
+
+	// A valid surrogate pair and an escaped backslash before "u" are fine.
+	for _, input := range []string{
+		`{"key":"k","value":"smile \ud83d\ude00"}`,
+		`{"key":"k","value":"path C:\\udc00"}`,
+	} {
+		if _, err := handler(context.Background(), "agent-1", json.RawMessage(input)); err != nil {
+			t.Errorf("%s: %v", input, err)
+		}
+	}
+}
+
+func TestNoteSet_RejectsOversizedKey(t *testing.T) {
+	s := newTestStore(t)
+	limits, err := NewLimits(map[string]int{FieldNoteKey: 8}, nil)
+	if err != nil {
+		t.Fatalf("NewLimits: %v", err)
+	}
+	handler := findHandler(NotesPackWithLimits(s, limits), "note_set")
+
+	if _, err := callTool(t, handler, map[string]any{"key": "12345678", "value": "v"}); err != nil {
+		t.Fatalf("key at cap: %v", err)
+	}
+	_, err = callTool(t, handler, map[string]any{"key": "123456789", "value": "v"})
+	var ierr *InputError
+	if !errors.As(err, &ierr) || ierr.Code != ErrCodeFieldTooLong || ierr.Field != "key" {
+		t.Fatalf("err = %v, want field_too_long on key", err)
+	}
+}
+
+func TestDailyQuota_ExceededAndReset(t *testing.T) {
+	s := newTestStore(t)
+	limits, err := NewLimits(nil, map[string]int{QuotaBBS: 2, QuotaLogs: -1})
+	if err != nil {
+		t.Fatalf("NewLimits: %v", err)
+	}
+	now := time.Date(2026, 3, 9, 23, 59, 0, 0, time.UTC)
+	limits.now = func() time.Time { return now }
+	pack := BasePackWithLimits(s, limits)
+	create := findHandler(pack, "bbs_create_thread")
+	post := map[string]any{"subject": "s", "content": "c"}
+
+	for i := range 2 {
+		if _, err := callTool(t, create, post); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	_, err = callTool(t, create, post)
+	var qerr *QuotaError
+	if !errors.As(err, &qerr) {
+		t.Fatalf("third write err = %v, want QuotaError", err)
+	}
+	if qerr.Kind != QuotaBBS || qerr.Limit != 2 || !qerr.ResetsAt.Equal(time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("quota error = %+v", qerr)
+	}
+	if !strings.HasPrefix(err.Error(), "quota_exceeded:") {
+		t.Errorf("message = %q, want quota_exceeded prefix", err.Error())
+	}
+
+	// A refused write is neither stored nor counted.
+	threads, err := s.ListBBSThreads(context.Background(), store.BBSThreadFilter{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListBBSThreads: %v", err)
+	}
+	if len(threads) != 2 {
+		t.Errorf("threads = %d, want 2", len(threads))
+	}
+	usage, err := s.GetBuiltinQuotaUsage(context.Background(), "agent-1", QuotaDay(now))
+	if err != nil {
+		t.Fatalf("GetBuiltinQuotaUsage: %v", err)
+	}
+	if usage[QuotaBBS] != 2 {
+		t.Errorf("usage = %v, want bbs 2", usage)
+	}
+
+	// Other agents and unlimited kinds are unaffected.
+	if _, err := create(context.Background(), "agent-2", json.RawMessage(`{"subject":"s","content":"c"}`)); err != nil {
+		t.Errorf("other agent: %v", err)
+	}
+	for range 3 {
+		if _, err := callTool(t, findHandler(pack, "log_entry"), map[string]any{"message": "m"}); err != nil {
+			t.Errorf("unlimited logs: %v", err)
+		}
+	}
+
+	// The window resets at UTC midnight.
+	now = now.Add(2 * time.Minute)
+	if _, err := callTool(t, create, post); err != nil {
+		t.Errorf("after reset: %v", err)
+	}
+}
+
+func TestPrecheck_ReportsSpentQuotaWithoutCharging(t *testing.T) {
+	s := newTestStore(t)
+	limits, err := NewLimits(nil, map[string]int{QuotaNotes: 1})
+	if err != nil {
+		t.Fatalf("NewLimits: %v", err)
+	}
+	pack := NotesPackWithLimits(s, limits)
+	var precheck func(context.Context, string) error
+	for _, tool := range pack.Tools {
+		if tool.Definition.GetName() == "note_set" {
+			precheck = tool.Precheck
+		}
+	}
+	if precheck == nil {
+		t.Fatal("note_set has no precheck")
+	}
+	ctx := context.Background()
+
+	for range 3 {
+		if err := precheck(ctx, "agent-1"); err != nil {
+			t.Fatalf("precheck before any write: %v", err)
+		}
+	}
+	if _, err := callTool(t, findHandler(pack, "note_set"), map[string]any{"key": "k", "value": "v"}); err != nil {
+		t.Fatalf("write after prechecks: %v", err)
+	}
+	var qerr *QuotaError
+	if err := precheck(ctx, "agent-1"); !errors.As(err, &qerr) || qerr.Kind != QuotaNotes {
+		t.Errorf("precheck after spending the quota = %v, want QuotaError", err)
+	}
+}
+
+func TestNewLimits_RejectsUnknownKeys(t *testing.T) {
+	if _, err := NewLimits(map[string]int{"log.msg": 10}, nil); err == nil {
+		t.Error("unknown field accepted")
+	}
+	if _, err := NewLimits(nil, map[string]int{"posts": 10}); err == nil {
+		t.Error("unknown quota accepted")
+	}
+	if _, err := NewLimits(map[string]int{FieldLogMessage: 0}, nil); err == nil {
+		t.Error("zero max bytes accepted")
+	}
+}
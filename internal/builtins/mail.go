@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/2389/coven-gateway/internal/packs"
 	"github.com/2389/coven-gateway/internal/store"
@@ -16,7 +17,12 @@ import (
 
 // MailPack creates the mail pack with inter-agent messaging tools.
 func MailPack(s store.BuiltinStore) *packs.BuiltinPack {
-	m := &mailHandlers{store: s}
+	return MailPackWithLimits(s, DefaultLimits())
+}
+
+// MailPackWithLimits creates the mail pack with the given write limits.
+func MailPackWithLimits(s store.BuiltinStore, limits Limits) *packs.BuiltinPack {
+	m := &mailHandlers{store: s, limits: limits}
 	return &packs.BuiltinPack{
 		ID: "builtin:mail",
 		Tools: []*packs.BuiltinTool{
@@ -27,13 +33,24 @@ func MailPack(s store.BuiltinStore) *packs.BuiltinPack {
 					InputSchemaJson:      `{"type":"object","properties":{"to_agent_id":{"type":"string"},"subject":{"type":"string"},"content":{"type":"string"}},"required":["to_agent_id","subject","content"]}`,
 					RequiredCapabilities: []string{"mail"},
 				},
-				Handler: m.Send,
+				Handler:  m.Send,
+				Precheck: limits.precheck(s, QuotaMail),
+			},
+			{
+				Definition: &pb.ToolDefinition{
+					Name:                 "mail_reply",
+					Description:          "Reply to a message you sent or received",
+					InputSchemaJson:      `{"type":"object","properties":{"message_id":{"type":"string"},"content":{"type":"string"},"subject":{"type":"string"}},"required":["message_id","content"]}`,
+					RequiredCapabilities: []string{"mail"},
+				},
+				Handler:  m.Reply,
+				Precheck: limits.precheck(s, QuotaMail),
 			},
 			{
 				Definition: &pb.ToolDefinition{
 					Name:                 "mail_inbox",
 					Description:          "List received messages",
-					InputSchemaJson:      `{"type":"object","properties":{"limit":{"type":"integer"},"unread_only":{"type":"boolean"}}}`,
+					InputSchemaJson:      `{"type":"object","properties":{"limit":{"type":"integer"},"unread_only":{"type":"boolean"},"thread_id":{"type":"string"}}}`,
 					RequiredCapabilities: []string{"mail"},
 				},
 				Handler: m.Inbox,
@@ -42,17 +59,27 @@ func MailPack(s store.BuiltinStore) *packs.BuiltinPack {
 				Definition: &pb.ToolDefinition{
 					Name:                 "mail_read",
 					Description:          "Read and mark message as read",
-					InputSchemaJson:      `{"type":"object","properties":{"message_id":{"type":"string"}},"required":["message_id"]}`,
+					InputSchemaJson:      `{"type":"object","properties":{"message_id":{"type":"string"},"include_thread":{"type":"boolean"}},"required":["message_id"]}`,
 					RequiredCapabilities: []string{"mail"},
 				},
 				Handler: m.Read,
 			},
+			{
+				Definition: &pb.ToolDefinition{
+					Name:                 "mail_thread",
+					Description:          "Show the full conversation a message belongs to",
+					InputSchemaJson:      `{"type":"object","properties":{"message_id":{"type":"string"}},"required":["message_id"]}`,
+					RequiredCapabilities: []string{"mail"},
+				},
+				Handler: m.Thread,
+			},
 		},
 	}
 }
 
 type mailHandlers struct {
-	store store.BuiltinStore
+	store  store.BuiltinStore
+	limits Limits
 }
 
 type mailSendInput struct {
@@ -63,36 +90,103 @@ type mailSendInput struct {
 
 func (m *mailHandlers) Send(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
 	var in mailSendInput
-	if err := json.Unmarshal(input, &in); err != nil {
-		return nil, fmt.Errorf("invalid input: %w", err)
+	if err := decodeInput(input, &in); err != nil {
+		return nil, err
 	}
 
 	if in.ToAgentID == "" {
 		return nil, errors.New("to_agent_id is required")
 	}
-	if in.Subject == "" {
+	c := &fieldCleaner{limits: m.limits}
+	subject := c.line(FieldMailSubject, "subject", in.Subject)
+	content := c.text(FieldMailContent, "content", in.Content)
+	if subject == "" {
 		return nil, errors.New("subject is required")
 	}
-	if in.Content == "" {
+	if strings.TrimSpace(content) == "" {
 		return nil, errors.New("content is required")
 	}
 
+	if err := m.limits.consume(ctx, m.store, agentID, QuotaMail); err != nil {
+		return nil, err
+	}
+
 	mail := &store.AgentMail{
 		FromAgentID: agentID,
 		ToAgentID:   in.ToAgentID,
-		Subject:     in.Subject,
-		Content:     in.Content,
+		Subject:     subject,
+		Content:     content,
 	}
 	if err := m.store.SendMail(ctx, mail); err != nil {
 		return nil, err
 	}
 
-	return json.Marshal(map[string]string{"id": mail.ID, "status": "sent"})
+	return c.result(map[string]any{"id": mail.ID, "status": "sent"})
+}
+
+type mailReplyInput struct {
+	MessageID string `json:"message_id"`
+	Subject   string `json:"subject"`
+	Content   string `json:"content"`
+}
+
+// Reply answers a mail the agent sent or received. The reply goes to the
+// other party and joins the original's thread; the subject defaults to the
+// original's with a "Re: " prefix.
+func (m *mailHandlers) Reply(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
+	var in mailReplyInput
+	if err := decodeInput(input, &in); err != nil {
+		return nil, err
+	}
+	if in.MessageID == "" {
+		return nil, errors.New("message_id is required")
+	}
+	c := &fieldCleaner{limits: m.limits}
+	content := c.text(FieldMailContent, "content", in.Content)
+	if strings.TrimSpace(content) == "" {
+		return nil, errors.New("content is required")
+	}
+
+	parent, err := m.visibleMail(ctx, agentID, in.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	to := parent.FromAgentID
+	if to == agentID {
+		to = parent.ToAgentID
+	}
+	subject := in.Subject
+	if subject == "" {
+		subject = parent.Subject
+		if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+			subject = "Re: " + subject
+		}
+	}
+	subject = c.line(FieldMailSubject, "subject", subject)
+
+	if err := m.limits.consume(ctx, m.store, agentID, QuotaMail); err != nil {
+		return nil, err
+	}
+
+	mail := &store.AgentMail{
+		FromAgentID: agentID,
+		ToAgentID:   to,
+		Subject:     subject,
+		Content:     content,
+		InReplyTo:   parent.ID,
+		ThreadID:    parent.ThreadID,
+	}
+	if err := m.store.SendMail(ctx, mail); err != nil {
+		return nil, err
+	}
+
+	return c.result(map[string]any{"id": mail.ID, "thread_id": mail.ThreadID, "to_agent_id": to, "status": "sent"})
 }
 
 type mailInboxInput struct {
-	Limit      int  `json:"limit"`
-	UnreadOnly bool `json:"unread_only"`
+	Limit      int    `json:"limit"`
+	UnreadOnly bool   `json:"unread_only"`
+	ThreadID   string `json:"thread_id"`
 }
 
 func (m *mailHandlers) Inbox(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
@@ -106,7 +200,7 @@ func (m *mailHandlers) Inbox(ctx context.Context, agentID string, input json.Raw
 		limit = 20
 	}
 
-	messages, err := m.store.ListInbox(ctx, agentID, in.UnreadOnly, limit)
+	messages, err := m.store.ListInbox(ctx, agentID, in.UnreadOnly, in.ThreadID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -115,9 +209,12 @@ func (m *mailHandlers) Inbox(ctx context.Context, agentID string, input json.Raw
 }
 
 type mailReadInput struct {
-	MessageID string `json:"message_id"`
+	MessageID     string `json:"message_id"`
+	IncludeThread bool   `json:"include_thread"`
 }
 
+// Read returns a received mail and marks it read. With include_thread the
+// result is {"message": ..., "thread": [...]}, the thread oldest first.
 func (m *mailHandlers) Read(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
 	var in mailReadInput
 	if err := json.Unmarshal(input, &in); err != nil {
@@ -143,5 +240,69 @@ func (m *mailHandlers) Read(ctx context.Context, agentID string, input json.RawM
 		return nil, err
 	}
 
-	return json.Marshal(mail)
+	if !in.IncludeThread {
+		return json.Marshal(mail)
+	}
+	thread, err := m.thread(ctx, agentID, mail.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]any{"message": mail, "thread": thread})
+}
+
+type mailThreadInput struct {
+	MessageID string `json:"message_id"`
+}
+
+// Thread returns the conversation a mail belongs to, oldest first. Only
+// the mail's sender and recipient may see it.
+func (m *mailHandlers) Thread(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
+	var in mailThreadInput
+	if err := json.Unmarshal(input, &in); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	if in.MessageID == "" {
+		return nil, errors.New("message_id is required")
+	}
+
+	mail, err := m.visibleMail(ctx, agentID, in.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	thread, err := m.thread(ctx, agentID, mail.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]any{"thread_id": mail.ThreadID, "messages": thread, "count": len(thread)})
+}
+
+// visibleMail returns a mail the agent sent or received. Any other mail,
+// including one that no longer exists, is reported as not found.
+func (m *mailHandlers) visibleMail(ctx context.Context, agentID, id string) (*store.AgentMail, error) {
+	mail, err := m.store.GetMail(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, errors.New("message not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mail.FromAgentID != agentID && mail.ToAgentID != agentID {
+		return nil, errors.New("message not found")
+	}
+	return mail, nil
+}
+
+// thread returns the mail in threadID that the agent sent or received.
+func (m *mailHandlers) thread(ctx context.Context, agentID, threadID string) ([]*store.AgentMail, error) {
+	all, err := m.store.GetMailThread(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	visible := make([]*store.AgentMail, 0, len(all))
+	for _, mail := range all {
+		if mail.FromAgentID == agentID || mail.ToAgentID == agentID {
+			visible = append(visible, mail)
+		}
+	}
+	return visible, nil
 }
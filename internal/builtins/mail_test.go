@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/2389/coven-gateway/internal/packs"
 	"github.com/2389/coven-gateway/internal/store"
 )
 
@@ -256,3 +257,135 @@ func TestMailReadValidation(t *testing.T) {
 		t.Error("expected error for missing message_id")
 	}
 }
+
+// callMail runs a mail tool as agentID and decodes its result.
+func callMail(t *testing.T, pack *packs.BuiltinPack, tool, agentID, input string) map[string]any {
+	t.Helper()
+	result, err := findHandler(pack, tool)(context.Background(), agentID, json.RawMessage(input))
+	if err != nil {
+		t.Fatalf("%s as %s: %v", tool, agentID, err)
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(result, &resp); err != nil {
+		t.Fatalf("unmarshal %s result: %v", tool, err)
+	}
+	return resp
+}
+
+func TestMailReply(t *testing.T) {
+	s := newTestStore(t)
+	pack := MailPack(s)
+
+	sent := callMail(t, pack, "mail_send", "agent-1", `{"to_agent_id": "agent-2", "subject": "Hello", "content": "World"}`)
+	rootID := sent["id"].(string)
+
+	// The recipient's reply goes back to the sender, in the same thread.
+	reply := callMail(t, pack, "mail_reply", "agent-2", `{"message_id": "`+rootID+`", "content": "Hi back"}`)
+	if reply["to_agent_id"] != "agent-1" || reply["thread_id"] != rootID {
+		t.Errorf("reply = %v, want it sent to agent-1 in thread %s", reply, rootID)
+	}
+	got, err := s.GetMail(context.Background(), reply["id"].(string))
+	if err != nil {
+		t.Fatalf("GetMail: %v", err)
+	}
+	if got.InReplyTo != rootID || got.Subject != "Re: Hello" || got.Content != "Hi back" {
+		t.Errorf("stored reply = %+v", got)
+	}
+
+	// A reply to a reply keeps the thread and doesn't stack prefixes; the
+	// original sender following up on its own mail writes to the recipient.
+	again := callMail(t, pack, "mail_reply", "agent-1", `{"message_id": "`+got.ID+`", "content": "Great"}`)
+	followUp := callMail(t, pack, "mail_reply", "agent-1", `{"message_id": "`+rootID+`", "content": "Also", "subject": "One more thing"}`)
+	if again["to_agent_id"] != "agent-2" || again["thread_id"] != rootID || followUp["to_agent_id"] != "agent-2" {
+		t.Errorf("reply to reply = %v, follow-up = %v", again, followUp)
+	}
+	if m, _ := s.GetMail(context.Background(), again["id"].(string)); m.Subject != "Re: Hello" {
+		t.Errorf("reply-to-reply subject = %q", m.Subject)
+	}
+	if m, _ := s.GetMail(context.Background(), followUp["id"].(string)); m.Subject != "One more thing" {
+		t.Errorf("explicit subject = %q", m.Subject)
+	}
+}
+
+func TestMailReplyUnknownOrHidden(t *testing.T) {
+	s := newTestStore(t)
+	pack := MailPack(s)
+	handler := findHandler(pack, "mail_reply")
+
+	sent := callMail(t, pack, "mail_send", "agent-1", `{"to_agent_id": "agent-2", "subject": "Private", "content": "Secret"}`)
+	mailID := sent["id"].(string)
+
+	tests := []struct {
+		name, agent, input string
+	}{
+		// A deleted mail is indistinguishable from one that never existed.
+		{"unknown message", "agent-2", `{"message_id": "no-such-mail", "content": "Hi"}`},
+		{"someone else's mail", "agent-3", `{"message_id": "` + mailID + `", "content": "Hi"}`},
+		{"missing message_id", "agent-2", `{"content": "Hi"}`},
+		{"missing content", "agent-2", `{"message_id": "` + mailID + `"}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := handler(context.Background(), tc.agent, json.RawMessage(tc.input)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	inbox, err := s.ListInbox(context.Background(), "agent-1", false, "", 10)
+	if err != nil || len(inbox) != 0 {
+		t.Errorf("agent-1 inbox = %v, %v; want no replies sent", inbox, err)
+	}
+}
+
+func TestMailThread(t *testing.T) {
+	s := newTestStore(t)
+	pack := MailPack(s)
+
+	rootID := callMail(t, pack, "mail_send", "agent-1", `{"to_agent_id": "agent-2", "subject": "Plan", "content": "Start?"}`)["id"].(string)
+	replyID := callMail(t, pack, "mail_reply", "agent-2", `{"message_id": "`+rootID+`", "content": "Yes"}`)["id"].(string)
+	callMail(t, pack, "mail_send", "agent-3", `{"to_agent_id": "agent-1", "subject": "Unrelated", "content": "Hi"}`)
+
+	for _, agentID := range []string{"agent-1", "agent-2"} {
+		resp := callMail(t, pack, "mail_thread", agentID, `{"message_id": "`+replyID+`"}`)
+		messages, _ := resp["messages"].([]any)
+		if resp["thread_id"] != rootID || len(messages) != 2 {
+			t.Fatalf("mail_thread as %s = %v", agentID, resp)
+		}
+		if first, _ := messages[0].(map[string]any); first["ID"] != rootID {
+			t.Errorf("first message = %v, want the root", first)
+		}
+	}
+
+	if _, err := findHandler(pack, "mail_thread")(context.Background(), "agent-3", json.RawMessage(`{"message_id": "`+rootID+`"}`)); err == nil {
+		t.Error("expected agent-3 to be refused a thread it isn't part of")
+	}
+}
+
+func TestMailInboxThreadFilter(t *testing.T) {
+	s := newTestStore(t)
+	pack := MailPack(s)
+
+	rootID := callMail(t, pack, "mail_send", "agent-2", `{"to_agent_id": "agent-1", "subject": "A", "content": "a"}`)["id"].(string)
+	callMail(t, pack, "mail_send", "agent-3", `{"to_agent_id": "agent-1", "subject": "B", "content": "b"}`)
+
+	resp := callMail(t, pack, "mail_inbox", "agent-1", `{"thread_id": "`+rootID+`"}`)
+	if resp["count"].(float64) != 1 {
+		t.Errorf("thread-filtered inbox count = %v, want 1", resp["count"])
+	}
+}
+
+func TestMailReadIncludeThread(t *testing.T) {
+	s := newTestStore(t)
+	pack := MailPack(s)
+
+	rootID := callMail(t, pack, "mail_send", "agent-1", `{"to_agent_id": "agent-2", "subject": "Plan", "content": "Start?"}`)["id"].(string)
+	replyID := callMail(t, pack, "mail_reply", "agent-2", `{"message_id": "`+rootID+`", "content": "Yes"}`)["id"].(string)
+
+	resp := callMail(t, pack, "mail_read", "agent-1", `{"message_id": "`+replyID+`", "include_thread": true}`)
+	message, _ := resp["message"].(map[string]any)
+	thread, _ := resp["thread"].([]any)
+	if message["ID"] != replyID || message["InReplyTo"] != rootID || len(thread) != 2 {
+		t.Errorf("mail_read with thread = %v", resp)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/2389/coven-gateway/internal/packs"
 	"github.com/2389/coven-gateway/internal/store"
@@ -16,18 +17,24 @@ import (
 
 // NotesPack creates the notes pack with key-value storage tools.
 func NotesPack(s store.BuiltinStore) *packs.BuiltinPack {
-	n := &notesHandlers{store: s}
+	return NotesPackWithLimits(s, DefaultLimits())
+}
+
+// NotesPackWithLimits creates the notes pack with the given write limits.
+func NotesPackWithLimits(s store.BuiltinStore, limits Limits) *packs.BuiltinPack {
+	n := &notesHandlers{store: s, limits: limits}
 	return &packs.BuiltinPack{
 		ID: "builtin:notes",
 		Tools: []*packs.BuiltinTool{
 			{
 				Definition: &pb.ToolDefinition{
 					Name:                 "note_set",
-					Description:          "Store a note",
-					InputSchemaJson:      `{"type":"object","properties":{"key":{"type":"string"},"value":{"type":"string"}},"required":["key","value"]}`,
+					Description:          "Store a note, optionally expiring after ttl_seconds",
+					InputSchemaJson:      `{"type":"object","properties":{"key":{"type":"string"},"value":{"type":"string"},"ttl_seconds":{"type":"integer","minimum":1}},"required":["key","value"]}`,
 					RequiredCapabilities: []string{"notes"},
 				},
-				Handler: n.Set,
+				Handler:  n.Set,
+				Precheck: limits.precheck(s, QuotaNotes),
 			},
 			{
 				Definition: &pb.ToolDefinition{
@@ -41,8 +48,8 @@ func NotesPack(s store.BuiltinStore) *packs.BuiltinPack {
 			{
 				Definition: &pb.ToolDefinition{
 					Name:                 "note_list",
-					Description:          "List all note keys",
-					InputSchemaJson:      `{"type":"object","properties":{}}`,
+					Description:          "List note keys, optionally only those starting with prefix",
+					InputSchemaJson:      `{"type":"object","properties":{"prefix":{"type":"string"}}}`,
 					RequiredCapabilities: []string{"notes"},
 				},
 				Handler: n.List,
@@ -61,34 +68,55 @@ func NotesPack(s store.BuiltinStore) *packs.BuiltinPack {
 }
 
 type notesHandlers struct {
-	store store.BuiltinStore
+	store  store.BuiltinStore
+	limits Limits
 }
 
 type noteSetInput struct {
-	Key   string `json:"key"`
-	Value string `json:"value"`
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+	TTLSeconds int64  `json:"ttl_seconds"`
 }
 
 func (n *notesHandlers) Set(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
 	var in noteSetInput
-	if err := json.Unmarshal(input, &in); err != nil {
-		return nil, fmt.Errorf("invalid input: %w", err)
+	if err := decodeInput(input, &in); err != nil {
+		return nil, err
 	}
 
-	if in.Key == "" {
+	c := &fieldCleaner{limits: n.limits}
+	key, err := c.exact(FieldNoteKey, "key", in.Key)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
 		return nil, errors.New("key is required")
 	}
+	value := c.text(FieldNoteValue, "value", in.Value)
+	if in.TTLSeconds < 0 {
+		return nil, errors.New("ttl_seconds must be positive")
+	}
+
+	if err := n.limits.consume(ctx, n.store, agentID, QuotaNotes); err != nil {
+		return nil, err
+	}
 
 	note := &store.AgentNote{
 		AgentID: agentID,
-		Key:     in.Key,
-		Value:   in.Value,
+		Key:     key,
+		Value:   value,
+	}
+	result := map[string]any{"key": key, "status": "saved"}
+	if in.TTLSeconds > 0 {
+		expiresAt := n.limits.clock().Add(time.Duration(in.TTLSeconds) * time.Second).Truncate(time.Second)
+		note.ExpiresAt = &expiresAt
+		result["expires_at"] = expiresAt.Format(time.RFC3339)
 	}
 	if err := n.store.SetNote(ctx, note); err != nil {
 		return nil, err
 	}
 
-	return json.Marshal(map[string]string{"key": in.Key, "status": "saved"})
+	return c.result(result)
 }
 
 type noteGetInput struct {
@@ -110,11 +138,26 @@ func (n *notesHandlers) Get(ctx context.Context, agentID string, input json.RawM
 		return nil, err
 	}
 
-	return json.Marshal(map[string]string{"key": note.Key, "value": note.Value})
+	out := map[string]string{"key": note.Key, "value": note.Value}
+	if note.ExpiresAt != nil {
+		out["expires_at"] = note.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return json.Marshal(out)
+}
+
+type noteListInput struct {
+	Prefix string `json:"prefix"`
 }
 
 func (n *notesHandlers) List(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
-	notes, err := n.store.ListNotes(ctx, agentID)
+	var in noteListInput
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &in); err != nil {
+			return nil, fmt.Errorf("invalid input: %w", err)
+		}
+	}
+
+	notes, err := n.store.ListNotes(ctx, agentID, in.Prefix)
 	if err != nil {
 		return nil, err
 	}
@@ -6,7 +6,11 @@ package builtins
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
 )
 
 func TestNoteSet(t *testing.T) {
@@ -344,3 +348,83 @@ func TestNoteEmptyKeyValidation(t *testing.T) {
 		}
 	})
 }
+
+func TestNoteSetTTL(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	setHandler := findHandler(NotesPack(s), "note_set")
+	getHandler := findHandler(NotesPack(s), "note_get")
+
+	before := time.Now().UTC().Truncate(time.Second)
+	result, err := setHandler(ctx, "agent-1", json.RawMessage(`{"key": "session", "value": "abc", "ttl_seconds": 3600}`))
+	if err != nil {
+		t.Fatalf("note_set: %v", err)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(result, &resp); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	expiresAt, err := time.Parse(time.RFC3339, resp["expires_at"])
+	if err != nil {
+		t.Fatalf("expires_at %q: %v", resp["expires_at"], err)
+	}
+	if expiresAt.Before(before.Add(time.Hour)) || expiresAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("expires_at = %v, want about an hour from now", expiresAt)
+	}
+
+	result, err = getHandler(ctx, "agent-1", json.RawMessage(`{"key": "session"}`))
+	if err != nil {
+		t.Fatalf("note_get: %v", err)
+	}
+	if err := json.Unmarshal(result, &resp); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if resp["value"] != "abc" || resp["expires_at"] != expiresAt.Format(time.RFC3339) {
+		t.Errorf("note_get = %v", resp)
+	}
+
+	// A TTL computed from a clock an hour behind has already run out.
+	limits := DefaultLimits()
+	limits.now = func() time.Time { return time.Now().Add(-time.Hour) }
+	pastSet := findHandler(NotesPackWithLimits(s, limits), "note_set")
+	if _, err := pastSet(ctx, "agent-1", json.RawMessage(`{"key": "stale", "value": "x", "ttl_seconds": 60}`)); err != nil {
+		t.Fatalf("note_set: %v", err)
+	}
+	if _, err := getHandler(ctx, "agent-1", json.RawMessage(`{"key": "stale"}`)); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("note_get expired: err = %v, want ErrNotFound", err)
+	}
+
+	if _, err := setHandler(ctx, "agent-1", json.RawMessage(`{"key": "k", "value": "v", "ttl_seconds": -5}`)); err == nil {
+		t.Error("expected error for negative ttl_seconds")
+	}
+}
+
+func TestNoteListPrefix(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	pack := NotesPack(s)
+
+	setHandler := findHandler(pack, "note_set")
+	listHandler := findHandler(pack, "note_list")
+	for _, key := range []string{"todo/1", "todo/2", "idea/1"} {
+		if _, err := setHandler(ctx, "agent-1", json.RawMessage(`{"key": "`+key+`", "value": "v"}`)); err != nil {
+			t.Fatalf("note_set %s: %v", key, err)
+		}
+	}
+
+	result, err := listHandler(ctx, "agent-1", json.RawMessage(`{"prefix": "todo/"}`))
+	if err != nil {
+		t.Fatalf("note_list: %v", err)
+	}
+	var resp struct {
+		Keys  []string `json:"keys"`
+		Count int      `json:"count"`
+	}
+	if err := json.Unmarshal(result, &resp); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if resp.Count != 2 || len(resp.Keys) != 2 || resp.Keys[0] != "todo/1" || resp.Keys[1] != "todo/2" {
+		t.Errorf("note_list = %+v, want todo/1 and todo/2", resp)
+	}
+}
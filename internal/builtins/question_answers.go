@@ -0,0 +1,136 @@
+// ABOUTME: Validation of ask_user answers against the question's options and free-text constraints.
+// ABOUTME: Invalid answers are refused with per-field violations so clients can show inline errors.
+
+package builtins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// ErrInvalidAnswer is wrapped by every InvalidAnswerError.
+var ErrInvalidAnswer = coverr.New(coverr.InvalidRequest, "invalid answer")
+
+// AnswerViolation is one way an answer breaks its question's constraints.
+// Field is the answer field at fault: "selected" or "custom_text".
+type AnswerViolation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// InvalidAnswerError is returned when an answer breaks its question's
+// constraints. The question stays pending so it can be answered again.
+type InvalidAnswerError struct {
+	Violations []AnswerViolation
+}
+
+func (e *InvalidAnswerError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Field + ": " + v.Message
+	}
+	return "invalid answer: " + strings.Join(msgs, "; ")
+}
+
+func (e *InvalidAnswerError) Unwrap() error {
+	return ErrInvalidAnswer
+}
+
+// MarshalJSON renders the error as the body of a 422 response:
+// {"error": "...", "code": "invalid_request", "violations": [{"field": "selected", "message": "..."}]}.
+func (e *InvalidAnswerError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error      string            `json:"error"`
+		Code       coverr.Code       `json:"code"`
+		Violations []AnswerViolation `json:"violations"`
+	}{e.Error(), coverr.InvalidRequest, e.Violations})
+}
+
+// ValidateAnswer checks an answer against the question it answers:
+// selections must be distinct options, only one unless the question is
+// multi-select, and custom text must be allowed and fit the question's
+// pattern and max_length. It returns an *InvalidAnswerError listing every
+// violation, or nil.
+func ValidateAnswer(q *pb.UserQuestionRequest, answer *pb.AnswerQuestionRequest) error {
+	var violations []AnswerViolation
+	add := func(field, format string, args ...any) {
+		violations = append(violations, AnswerViolation{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	selected := answer.GetSelected()
+	customText := answer.GetCustomText()
+	if len(selected) == 0 && customText == "" {
+		add("selected", "select an option or enter a response")
+	}
+
+	labels := make(map[string]bool, len(q.GetOptions()))
+	for _, opt := range q.GetOptions() {
+		labels[opt.GetLabel()] = true
+	}
+	seen := make(map[string]bool, len(selected))
+	for _, label := range selected {
+		switch {
+		case seen[label]:
+			add("selected", "%q is selected more than once", label)
+		case !labels[label]:
+			add("selected", "%q is not one of the options", label)
+		}
+		seen[label] = true
+	}
+	if len(selected) > 1 && !q.GetMultiSelect() {
+		add("selected", "only one option may be selected")
+	}
+
+	if customText != "" {
+		if !AllowsFreeText(q) {
+			add("custom_text", "this question only accepts the listed options")
+		} else {
+			if limit := q.GetMaxLength(); limit > 0 && utf8.RuneCountInString(customText) > int(limit) {
+				add("custom_text", "must be at most %d characters", limit)
+			}
+			if pattern := q.GetPattern(); pattern != "" {
+				if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(customText) {
+					add("custom_text", "must match the pattern %s", pattern)
+				}
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &InvalidAnswerError{Violations: violations}
+	}
+	return nil
+}
+
+// AllowsFreeText reports whether a question accepts custom text besides its
+// options. Questions accept it unless the agent turned it off.
+func AllowsFreeText(q *pb.UserQuestionRequest) bool {
+	return q.AllowFreeText == nil || q.GetAllowFreeText()
+}
+
+// AnswerViolations returns the violations carried by err, or nil if err is
+// not an invalid answer.
+func AnswerViolations(err error) []AnswerViolation {
+	var invalid *InvalidAnswerError
+	if errors.As(err, &invalid) {
+		return invalid.Violations
+	}
+	return nil
+}
+
+// formatAnswer renders an answer as one string for the agent: the selected
+// labels, then any custom text, separated by commas.
+func formatAnswer(answer *pb.AnswerQuestionRequest) string {
+	parts := answer.GetSelected()
+	if text := answer.GetCustomText(); text != "" {
+		parts = append(parts[:len(parts):len(parts)], text)
+	}
+	return strings.Join(parts, ", ")
+}
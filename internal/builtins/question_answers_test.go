@@ -0,0 +1,92 @@
+// ABOUTME: Tests for validating ask_user answers against their question's constraints.
+// ABOUTME: One case per constraint: option set, multi-select, free text, pattern and max_length.
+
+package builtins
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestValidateAnswer(t *testing.T) {
+	closed := false
+	pattern := `^[A-Z]+-\d+$`
+	maxLength := int32(8)
+	options := []*pb.QuestionOption{{Label: "Yes"}, {Label: "No"}, {Label: "Later"}}
+	single := &pb.UserQuestionRequest{Options: options}
+	multi := &pb.UserQuestionRequest{Options: options, MultiSelect: true}
+	optionsOnly := &pb.UserQuestionRequest{Options: options, AllowFreeText: &closed}
+	constrained := &pb.UserQuestionRequest{Options: options, Pattern: &pattern, MaxLength: &maxLength}
+
+	text := func(s string) *string { return &s }
+	for _, tc := range []struct {
+		name     string
+		question *pb.UserQuestionRequest
+		answer   *pb.AnswerQuestionRequest
+		want     []AnswerViolation
+	}{
+		{"one option", single, &pb.AnswerQuestionRequest{Selected: []string{"Yes"}}, nil},
+		{"empty", single, &pb.AnswerQuestionRequest{},
+			[]AnswerViolation{{"selected", "select an option or enter a response"}}},
+		{"unknown option", single, &pb.AnswerQuestionRequest{Selected: []string{"Maybe"}},
+			[]AnswerViolation{{"selected", `"Maybe" is not one of the options`}}},
+		{"two options on single select", single, &pb.AnswerQuestionRequest{Selected: []string{"Yes", "No"}},
+			[]AnswerViolation{{"selected", "only one option may be selected"}}},
+		{"two options on multi select", multi, &pb.AnswerQuestionRequest{Selected: []string{"Yes", "Later"}}, nil},
+		{"repeated option", multi, &pb.AnswerQuestionRequest{Selected: []string{"Yes", "Yes"}},
+			[]AnswerViolation{{"selected", `"Yes" is selected more than once`}}},
+		{"free text by default", single, &pb.AnswerQuestionRequest{CustomText: text("tomorrow")}, nil},
+		{"free text not allowed", optionsOnly, &pb.AnswerQuestionRequest{CustomText: text("tomorrow")},
+			[]AnswerViolation{{"custom_text", "this question only accepts the listed options"}}},
+		{"option when free text not allowed", optionsOnly, &pb.AnswerQuestionRequest{Selected: []string{"No"}}, nil},
+		{"text matching pattern", constrained, &pb.AnswerQuestionRequest{CustomText: text("OPS-42")}, nil},
+		{"text not matching pattern", constrained, &pb.AnswerQuestionRequest{CustomText: text("ops 42")},
+			[]AnswerViolation{{"custom_text", `must match the pattern ^[A-Z]+-\d+$`}}},
+		{"text too long", constrained, &pb.AnswerQuestionRequest{CustomText: text("OPS-123456")},
+			[]AnswerViolation{{"custom_text", "must be at most 8 characters"}}},
+		{"length counts characters", &pb.UserQuestionRequest{Options: options, MaxLength: &maxLength},
+			&pb.AnswerQuestionRequest{CustomText: text("ééééééé")}, nil},
+		{"every violation reported", optionsOnly, &pb.AnswerQuestionRequest{Selected: []string{"Maybe"}, CustomText: text("x")},
+			[]AnswerViolation{
+				{"selected", `"Maybe" is not one of the options`},
+				{"custom_text", "this question only accepts the listed options"},
+			}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateAnswer(tc.question, tc.answer)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("ValidateAnswer = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidAnswer) || coverr.CodeOf(err) != coverr.InvalidRequest {
+				t.Fatalf("ValidateAnswer = %v, want an invalid_request ErrInvalidAnswer", err)
+			}
+			if got := AnswerViolations(err); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("violations = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatAnswer(t *testing.T) {
+	text := "after lunch"
+	for _, tc := range []struct {
+		answer *pb.AnswerQuestionRequest
+		want   string
+	}{
+		{&pb.AnswerQuestionRequest{Selected: []string{"Yes"}}, "Yes"},
+		{&pb.AnswerQuestionRequest{Selected: []string{"Yes", "Later"}}, "Yes, Later"},
+		{&pb.AnswerQuestionRequest{CustomText: &text}, "after lunch"},
+		{&pb.AnswerQuestionRequest{Selected: []string{"Later"}, CustomText: &text}, "Later, after lunch"},
+	} {
+		if got := formatAnswer(tc.answer); got != tc.want {
+			t.Errorf("formatAnswer(%v) = %q, want %q", tc.answer, got, tc.want)
+		}
+	}
+}
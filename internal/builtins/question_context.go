@@ -0,0 +1,192 @@
+// ABOUTME: Context snapshots attached to ask_user questions so answerers know what the agent was doing.
+// ABOUTME: Snapshots are size-capped, scrubbed of secret values, and versioned by content hash.
+
+package builtins
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Snapshot limits. MaxQuestionContextBytes bounds the encoded snapshot;
+// the rest bound individual pieces before the overall cap is applied.
+const (
+	MaxQuestionContextBytes = 4096
+	MaxContextExchanges     = 5
+	MaxContextToolCalls     = 8
+
+	maxSummaryRunes = 280
+	maxToolParams   = 6
+	maxParamRunes   = 120
+	versionBytes    = 6 // hex-encoded into a 12-character version
+)
+
+// redactedPlaceholder replaces secret values found in snapshot text.
+const redactedPlaceholder = "[redacted]"
+
+// QuestionContext is a snapshot of what an agent was doing when it asked a question.
+type QuestionContext struct {
+	Version   string            `json:"version"`
+	ThreadID  string            `json:"thread_id,omitempty"`
+	Exchanges []ExchangeSummary `json:"exchanges,omitempty"`
+	ToolCalls []ToolCallSummary `json:"tool_calls,omitempty"`
+	Truncated bool              `json:"truncated,omitempty"`
+
+	// Redact lists secret values to scrub from the snapshot before it is sent.
+	Redact []string `json:"-"`
+}
+
+// ExchangeSummary is a shortened message from the thread, oldest first.
+type ExchangeSummary struct {
+	Author    string `json:"author"`
+	Direction string `json:"direction"`
+	Text      string `json:"text"`
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+// ToolCallSummary names an in-flight tool call and its key scalar parameters.
+type ToolCallSummary struct {
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// QuestionContextProvider captures the context snapshot for an agent's question.
+// Implementations return nil when nothing useful is known about the agent.
+type QuestionContextProvider interface {
+	QuestionContext(ctx context.Context, agentID string) *QuestionContext
+}
+
+// SummarizeToolCall keeps the top-level parameters of a tool call's JSON input,
+// in key order. Nested objects and arrays are described, not copied.
+func SummarizeToolCall(name, inputJSON string) ToolCallSummary {
+	summary := ToolCallSummary{Name: name}
+	var input map[string]any
+	if err := json.Unmarshal([]byte(inputJSON), &input); err != nil || len(input) == 0 {
+		return summary
+	}
+
+	keys := make([]string, 0, len(input))
+	for k := range input {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	if len(keys) > maxToolParams {
+		keys = keys[:maxToolParams]
+	}
+
+	summary.Params = make(map[string]string, len(keys))
+	for _, k := range keys {
+		summary.Params[k] = describeParam(input[k])
+	}
+	return summary
+}
+
+func describeParam(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		return fmt.Sprintf("[%d items]", len(v))
+	case map[string]any:
+		return fmt.Sprintf("{%d fields}", len(v))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Finalize scrubs the Redact values from the snapshot, shortens long text, trims it
+// to MaxQuestionContextBytes, stamps its version, and returns the encoded JSON.
+// Oldest exchanges are dropped first, then the outermost tool calls. Redaction
+// runs before shortening so a cut never leaves part of a secret behind.
+func (q *QuestionContext) Finalize() (string, error) {
+	if len(q.Exchanges) > MaxContextExchanges {
+		q.Exchanges = q.Exchanges[len(q.Exchanges)-MaxContextExchanges:]
+		q.Truncated = true
+	}
+	if len(q.ToolCalls) > MaxContextToolCalls {
+		q.ToolCalls = q.ToolCalls[len(q.ToolCalls)-MaxContextToolCalls:]
+		q.Truncated = true
+	}
+	q.redact()
+	for i := range q.Exchanges {
+		q.Exchanges[i].Text = truncateRunes(strings.TrimSpace(q.Exchanges[i].Text), maxSummaryRunes)
+	}
+	for i := range q.ToolCalls {
+		for k, v := range q.ToolCalls[i].Params {
+			q.ToolCalls[i].Params[k] = truncateRunes(v, maxParamRunes)
+		}
+	}
+
+	for {
+		q.Version = ""
+		body, err := json.Marshal(q)
+		if err != nil {
+			return "", fmt.Errorf("encoding question context: %w", err)
+		}
+		// Leave room for the version hash added below.
+		if len(body)+2*versionBytes <= MaxQuestionContextBytes {
+			sum := sha256.Sum256(body)
+			q.Version = hex.EncodeToString(sum[:versionBytes])
+			out, err := json.Marshal(q)
+			if err != nil {
+				return "", fmt.Errorf("encoding question context: %w", err)
+			}
+			return string(out), nil
+		}
+
+		q.Truncated = true
+		switch {
+		case len(q.Exchanges) > 0:
+			q.Exchanges = q.Exchanges[1:]
+		case len(q.ToolCalls) > 0:
+			q.ToolCalls = q.ToolCalls[1:]
+		default:
+			return "", fmt.Errorf("question context exceeds %d bytes with no content to drop", MaxQuestionContextBytes)
+		}
+	}
+}
+
+// redact replaces every occurrence of a secret value with a placeholder.
+func (q *QuestionContext) redact() {
+	var pairs []string
+	for _, s := range q.Redact {
+		// Very short values would redact ordinary words.
+		if len(s) >= 4 {
+			pairs = append(pairs, s, redactedPlaceholder)
+		}
+	}
+	if len(pairs) == 0 {
+		return
+	}
+	r := strings.NewReplacer(pairs...)
+	for i := range q.Exchanges {
+		q.Exchanges[i].Text = r.Replace(q.Exchanges[i].Text)
+	}
+	for i := range q.ToolCalls {
+		for k, v := range q.ToolCalls[i].Params {
+			q.ToolCalls[i].Params[k] = r.Replace(v)
+		}
+	}
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut with an ellipsis.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,196 @@
+// ABOUTME: Tests for ask_user context snapshots and their attachment to questions.
+// ABOUTME: Covers redaction, size capping, versioning, and the include_context switch.
+
+package builtins
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+type staticContextProvider struct {
+	snap *QuestionContext
+}
+
+func (p staticContextProvider) QuestionContext(context.Context, string) *QuestionContext {
+	if p.snap == nil {
+		return nil
+	}
+	// Finalize mutates the snapshot; hand out a copy each time.
+	cp := *p.snap
+	return &cp
+}
+
+func TestSummarizeToolCall(t *testing.T) {
+	got := SummarizeToolCall("bash", `{"command":"git push --force origin main","timeout":30,"env":{"A":"1"},"args":["a","b"]}`)
+	want := map[string]string{
+		"command": "git push --force origin main",
+		"timeout": "30",
+		"env":     "{1 fields}",
+		"args":    "[2 items]",
+	}
+	if got.Name != "bash" || len(got.Params) != len(want) {
+		t.Fatalf("summary = %+v, want bash with %d params", got, len(want))
+	}
+	for k, v := range want {
+		if got.Params[k] != v {
+			t.Errorf("param %s = %q, want %q", k, got.Params[k], v)
+		}
+	}
+
+	if s := SummarizeToolCall("noop", "not json"); s.Name != "noop" || s.Params != nil {
+		t.Errorf("invalid input summary = %+v, want name only", s)
+	}
+}
+
+func TestQuestionContextFinalize_RedactsBeforeTruncating(t *testing.T) {
+	secret := "sk-live-abcdefghijklmnop"
+	// Place the secret across the truncation point.
+	text := strings.Repeat("x", maxSummaryRunes-5) + secret
+	snap := &QuestionContext{
+		ThreadID:  "thread-1",
+		Exchanges: []ExchangeSummary{{Author: "alice", Direction: "inbound", Text: text}},
+		ToolCalls: []ToolCallSummary{SummarizeToolCall("http", `{"url":"https://x?key=`+secret+`"}`)},
+		Redact:    []string{secret, "abc"},
+	}
+
+	body, err := snap.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize: %v", err)
+	}
+	if strings.Contains(body, "sk-live") {
+		t.Errorf("snapshot leaks part of the secret: %s", body)
+	}
+	if !strings.Contains(body, redactedPlaceholder) {
+		t.Errorf("snapshot has no redaction marker: %s", body)
+	}
+	if strings.Contains(body, "Redact") {
+		t.Errorf("redaction list was serialized: %s", body)
+	}
+	if len(snap.Version) != 2*versionBytes {
+		t.Errorf("version = %q, want %d hex characters", snap.Version, 2*versionBytes)
+	}
+}
+
+func TestQuestionContextFinalize_CapsSize(t *testing.T) {
+	snap := &QuestionContext{ThreadID: "thread-1"}
+	for i := 0; i < 20; i++ {
+		snap.Exchanges = append(snap.Exchanges, ExchangeSummary{Author: "bob", Direction: "inbound", Text: strings.Repeat("long message ", 100)})
+	}
+	for i := 0; i < 20; i++ {
+		snap.ToolCalls = append(snap.ToolCalls, SummarizeToolCall("tool", `{"a":"`+strings.Repeat("y", 500)+`","b":"`+strings.Repeat("z", 500)+`"}`))
+	}
+
+	body, err := snap.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize: %v", err)
+	}
+	if len(body) > MaxQuestionContextBytes {
+		t.Errorf("snapshot is %d bytes, want at most %d", len(body), MaxQuestionContextBytes)
+	}
+	if !snap.Truncated || len(snap.Exchanges) > MaxContextExchanges || len(snap.ToolCalls) > MaxContextToolCalls {
+		t.Errorf("snapshot not trimmed: truncated=%v exchanges=%d tool calls=%d", snap.Truncated, len(snap.Exchanges), len(snap.ToolCalls))
+	}
+}
+
+func TestQuestionContextFinalize_VersionTracksContent(t *testing.T) {
+	a := &QuestionContext{ThreadID: "t", Exchanges: []ExchangeSummary{{Author: "a", Direction: "inbound", Text: "hi"}}}
+	b := &QuestionContext{ThreadID: "t", Exchanges: []ExchangeSummary{{Author: "a", Direction: "inbound", Text: "hi"}}}
+	c := &QuestionContext{ThreadID: "t", Exchanges: []ExchangeSummary{{Author: "a", Direction: "inbound", Text: "bye"}}}
+	for _, q := range []*QuestionContext{a, b, c} {
+		if _, err := q.Finalize(); err != nil {
+			t.Fatalf("Finalize: %v", err)
+		}
+	}
+	if a.Version != b.Version {
+		t.Errorf("identical snapshots got versions %q and %q", a.Version, b.Version)
+	}
+	if a.Version == c.Version {
+		t.Errorf("different snapshots share version %q", a.Version)
+	}
+}
+
+// askAndCapture runs ask_user with the given input and returns the question
+// the client received, answering it with the given context version.
+func askAndCapture(t *testing.T, provider QuestionContextProvider, input, seenVersion string) (*pb.UserQuestionRequest, AskUserOutput, *InMemoryQuestionRouter) {
+	t.Helper()
+	streamer := newMockClientStreamer()
+	router := NewInMemoryQuestionRouter(streamer)
+	handler := findAskUserHandler(UIPackWithContext(router, provider))
+
+	outChan := make(chan json.RawMessage, 1)
+	go func() {
+		out, err := handler(context.Background(), "agent-1", json.RawMessage(input))
+		if err != nil {
+			t.Errorf("handler: %v", err)
+		}
+		outChan <- out
+	}()
+
+	select {
+	case <-streamer.sent:
+	case <-time.After(2 * time.Second):
+		t.Fatal("question not sent")
+	}
+	q := streamer.getQuestions()[0]
+
+	pending := router.Pending()
+	if len(pending) != 1 || pending[0].Request.GetQuestionId() != q.GetQuestionId() {
+		t.Fatalf("Pending() = %+v, want the sent question", pending)
+	}
+
+	answer := &pb.AnswerQuestionRequest{AgentId: "agent-1", QuestionId: q.GetQuestionId(), Selected: []string{"No"}}
+	if seenVersion != "" {
+		answer.ContextVersion = &seenVersion
+	}
+	if err := router.DeliverAnswer("agent-1", q.GetQuestionId(), answer); err != nil {
+		t.Fatalf("DeliverAnswer: %v", err)
+	}
+
+	var out AskUserOutput
+	select {
+	case raw := <-outChan:
+		if err := json.Unmarshal(raw, &out); err != nil {
+			t.Fatalf("decode output: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler did not return")
+	}
+	return q, out, router
+}
+
+func TestAskUser_AttachesContext(t *testing.T) {
+	provider := staticContextProvider{snap: &QuestionContext{
+		ThreadID:  "thread-1",
+		ToolCalls: []ToolCallSummary{{Name: "bash", Params: map[string]string{"command": "git push -f"}}},
+	}}
+
+	q, out, router := askAndCapture(t, provider, `{"question":"Force-push?","options":[{"label":"Yes"},{"label":"No"}]}`, "v-seen")
+	if q.ContextJson == nil {
+		t.Fatal("question has no context attached")
+	}
+	pq := PendingQuestion{Request: q}
+	snap := pq.Context()
+	if snap == nil || snap.ThreadID != "thread-1" || len(snap.ToolCalls) != 1 || snap.Version == "" {
+		t.Errorf("context = %+v, want thread-1 with one tool call and a version", snap)
+	}
+	if out.ContextVersion != "v-seen" {
+		t.Errorf("output context_version = %q, want the version the answerer saw", out.ContextVersion)
+	}
+	if len(router.Pending()) != 0 {
+		t.Error("answered question still pending")
+	}
+}
+
+func TestAskUser_IncludeContextFalse(t *testing.T) {
+	provider := staticContextProvider{snap: &QuestionContext{ThreadID: "thread-1"}}
+	q, _, _ := askAndCapture(t, provider, `{"question":"Q?","options":[{"label":"Yes"},{"label":"No"}],"include_context":false}`, "")
+	if q.ContextJson != nil {
+		t.Errorf("context attached despite include_context=false: %s", q.GetContextJson())
+	}
+}
@@ -8,12 +8,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/2389/coven-gateway/internal/coverr"
 	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
@@ -30,7 +35,13 @@ type QuestionRouter interface {
 
 // UIPack creates the UI pack with user interaction tools.
 func UIPack(router QuestionRouter) *packs.BuiltinPack {
-	u := &uiHandlers{router: router}
+	return UIPackWithContext(router, nil)
+}
+
+// UIPackWithContext creates the UI pack with questions carrying context
+// snapshots from the given provider. A nil provider disables snapshots.
+func UIPackWithContext(router QuestionRouter, contexts QuestionContextProvider) *packs.BuiltinPack {
+	u := &uiHandlers{router: router, contexts: contexts}
 	return &packs.BuiltinPack{
 		ID: "builtin:ui",
 		Tools: []*packs.BuiltinTool{
@@ -68,6 +79,22 @@ func UIPack(router QuestionRouter) *packs.BuiltinPack {
 							"timeout_seconds": {
 								"type": "integer",
 								"description": "How long to wait for response (default: 60, max: 300)"
+							},
+							"include_context": {
+								"type": "boolean",
+								"description": "Show the user what you are working on (thread, recent messages, in-flight tool calls) alongside the question (default: true)"
+							},
+							"allow_free_text": {
+								"type": "boolean",
+								"description": "Let the user type a response instead of picking an option (default: true). When false, only the listed options are accepted"
+							},
+							"pattern": {
+								"type": "string",
+								"description": "Regular expression a typed response must match (optional)"
+							},
+							"max_length": {
+								"type": "integer",
+								"description": "Maximum length in characters of a typed response (optional)"
 							}
 						},
 						"required": ["question", "options"]
@@ -82,7 +109,8 @@ func UIPack(router QuestionRouter) *packs.BuiltinPack {
 }
 
 type uiHandlers struct {
-	router QuestionRouter
+	router   QuestionRouter
+	contexts QuestionContextProvider
 }
 
 // AskUserInput is the input schema for the ask_user tool.
@@ -92,6 +120,10 @@ type AskUserInput struct {
 	MultiSelect    bool            `json:"multi_select,omitempty"`
 	Header         string          `json:"header,omitempty"`
 	TimeoutSeconds int             `json:"timeout_seconds,omitempty"`
+	IncludeContext *bool           `json:"include_context,omitempty"`
+	AllowFreeText  *bool           `json:"allow_free_text,omitempty"`
+	Pattern        string          `json:"pattern,omitempty"`
+	MaxLength      int             `json:"max_length,omitempty"`
 }
 
 type AskUserOption struct {
@@ -104,7 +136,11 @@ type AskUserOutput struct {
 	Answered   bool     `json:"answered"`
 	Selected   []string `json:"selected,omitempty"`
 	CustomText string   `json:"custom_text,omitempty"`
-	Reason     string   `json:"reason,omitempty"`
+	// Answer is the selections and custom text joined into one string.
+	Answer string `json:"answer,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	// ContextVersion is the version of the context snapshot the answerer saw.
+	ContextVersion string `json:"context_version,omitempty"`
 }
 
 // validateAskUserInput validates the input fields for the ask_user tool.
@@ -122,6 +158,17 @@ func validateAskUserInput(in *AskUserInput) error {
 		}
 		seenLabels[opt.Label] = true
 	}
+	if in.MaxLength < 0 {
+		return errors.New("max_length must not be negative")
+	}
+	if in.Pattern != "" {
+		if _, err := regexp.Compile(in.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	if in.AllowFreeText != nil && !*in.AllowFreeText && (in.Pattern != "" || in.MaxLength > 0) {
+		return errors.New("pattern and max_length constrain typed responses, which allow_free_text=false turns off")
+	}
 	return nil
 }
 
@@ -150,6 +197,14 @@ func buildQuestionRequest(agentID string, in *AskUserInput, timeout int32) *pb.U
 	if in.Header != "" {
 		req.Header = &in.Header
 	}
+	req.AllowFreeText = in.AllowFreeText
+	if in.Pattern != "" {
+		req.Pattern = &in.Pattern
+	}
+	if in.MaxLength > 0 {
+		maxLength := int32(in.MaxLength)
+		req.MaxLength = &maxLength
+	}
 	for i, opt := range in.Options {
 		req.Options[i] = &pb.QuestionOption{Label: opt.Label}
 		if opt.Description != "" {
@@ -170,6 +225,13 @@ func (u *uiHandlers) AskUser(ctx context.Context, agentID string, input json.Raw
 
 	timeout := normalizeTimeout(in.TimeoutSeconds)
 	req := buildQuestionRequest(agentID, &in, timeout)
+	if u.contexts != nil && (in.IncludeContext == nil || *in.IncludeContext) {
+		if snap := u.contexts.QuestionContext(ctx, agentID); snap != nil {
+			if body, err := snap.Finalize(); err == nil {
+				req.ContextJson = &body
+			}
+		}
+	}
 
 	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
 	defer cancel()
@@ -189,7 +251,12 @@ func (u *uiHandlers) awaitAnswer(answerChan <-chan *pb.AnswerQuestionRequest, ct
 		if !ok || answer == nil {
 			return json.Marshal(AskUserOutput{Answered: false, Reason: "no_response"})
 		}
-		out := AskUserOutput{Answered: true, Selected: answer.Selected}
+		out := AskUserOutput{
+			Answered:       true,
+			Selected:       answer.Selected,
+			Answer:         formatAnswer(answer),
+			ContextVersion: answer.GetContextVersion(),
+		}
 		if answer.CustomText != nil {
 			out.CustomText = *answer.CustomText
 		}
@@ -199,20 +266,42 @@ func (u *uiHandlers) awaitAnswer(answerChan <-chan *pb.AnswerQuestionRequest, ct
 	}
 }
 
+var (
+	// ErrQuestionNotFound is returned when answering a question the gateway never asked.
+	ErrQuestionNotFound = coverr.New(coverr.NotFound, "question not found")
+	// ErrQuestionAnswered is returned when answering a question that already has an answer.
+	ErrQuestionAnswered = coverr.New(coverr.Conflict, "question already answered")
+	// ErrQuestionExpired is returned when answering a question the agent stopped waiting for.
+	ErrQuestionExpired = coverr.New(coverr.Conflict, "question expired")
+)
+
 // pendingQuestion tracks a question awaiting an answer.
 type pendingQuestion struct {
 	agentID    string
+	req        *pb.UserQuestionRequest
+	askedAt    time.Time
 	answerChan chan *pb.AnswerQuestionRequest
 	done       chan struct{} // signals when answer delivered or context canceled
 	closeOnce  sync.Once     // ensures answerChan is closed exactly once
 }
 
+// QuestionStore persists questions so clients that connect after a question
+// was asked can still see and answer it.
+type QuestionStore interface {
+	CreateQuestion(ctx context.Context, q *store.Question) error
+	GetQuestion(ctx context.Context, id string) (*store.Question, error)
+	ListPendingQuestions(ctx context.Context, agentID string) ([]*store.Question, error)
+	ResolveQuestion(ctx context.Context, id string, status store.QuestionStatus) error
+}
+
 // InMemoryQuestionRouter is a simple in-memory implementation of QuestionRouter.
-// It tracks pending questions and routes answers to waiting handlers.
+// It tracks pending questions and routes answers to waiting handlers. With a
+// store set, questions are also persisted and outlive a missed delivery.
 type InMemoryQuestionRouter struct {
 	mu       sync.RWMutex
 	pending  map[string]*pendingQuestion // questionID -> pending question
 	streamer ClientStreamer
+	store    QuestionStore // optional; set by SetStore
 }
 
 // ClientStreamer is the interface for sending events to clients.
@@ -229,13 +318,30 @@ func NewInMemoryQuestionRouter(streamer ClientStreamer) *InMemoryQuestionRouter
 	}
 }
 
+// SetStore persists questions in s. Once set, a question no client is
+// connected to receive stays pending until it times out, so a client that
+// connects later can still answer it.
+func (r *InMemoryQuestionRouter) SetStore(s QuestionStore) {
+	r.store = s
+}
+
 func (r *InMemoryQuestionRouter) SendQuestion(ctx context.Context, agentID string, req *pb.UserQuestionRequest) (<-chan *pb.AnswerQuestionRequest, error) {
+	askedAt := time.Now()
+	if r.store != nil {
+		q := storedQuestion(agentID, req, askedAt)
+		if err := r.store.CreateQuestion(ctx, q); err != nil {
+			return nil, fmt.Errorf("persisting question: %w", err)
+		}
+	}
+
 	// Create answer channel and done signal
 	answerChan := make(chan *pb.AnswerQuestionRequest, 1)
 	done := make(chan struct{})
 
 	pq := &pendingQuestion{
 		agentID:    agentID,
+		req:        req,
+		askedAt:    askedAt,
 		answerChan: answerChan,
 		done:       done,
 	}
@@ -245,8 +351,9 @@ func (r *InMemoryQuestionRouter) SendQuestion(ctx context.Context, agentID strin
 	r.pending[req.QuestionId] = pq
 	r.mu.Unlock()
 
-	// Send question to client
-	if err := r.streamer.SendUserQuestion(agentID, req); err != nil {
+	// Send question to client. Without a store nobody could find the
+	// question later, so a failed send fails the question.
+	if err := r.streamer.SendUserQuestion(agentID, req); err != nil && r.store == nil {
 		r.mu.Lock()
 		delete(r.pending, req.QuestionId)
 		r.mu.Unlock()
@@ -260,11 +367,15 @@ func (r *InMemoryQuestionRouter) SendQuestion(ctx context.Context, agentID strin
 		select {
 		case <-ctx.Done():
 			r.mu.Lock()
-			if pq, ok := r.pending[req.QuestionId]; ok {
+			pq, ok := r.pending[req.QuestionId]
+			if ok {
 				delete(r.pending, req.QuestionId)
 				pq.closeOnce.Do(func() { close(pq.answerChan) })
 			}
 			r.mu.Unlock()
+			if ok && r.store != nil {
+				_ = r.store.ResolveQuestion(context.WithoutCancel(ctx), req.QuestionId, store.QuestionExpired)
+			}
 		case <-done:
 			// Answer was already delivered, nothing to clean up
 		}
@@ -276,19 +387,27 @@ func (r *InMemoryQuestionRouter) SendQuestion(ctx context.Context, agentID strin
 func (r *InMemoryQuestionRouter) DeliverAnswer(agentID, questionID string, answer *pb.AnswerQuestionRequest) error {
 	r.mu.Lock()
 	pq, ok := r.pending[questionID]
-	if ok {
-		delete(r.pending, questionID)
-	}
-	r.mu.Unlock()
-
 	if !ok {
-		return fmt.Errorf("no pending question with ID %s", questionID)
+		r.mu.Unlock()
+		return r.AnswerError(questionID)
 	}
 
 	// Validate that the answer is for the correct agent
 	if pq.agentID != agentID {
-		return fmt.Errorf("answer agent_id %q does not match question agent_id %q", agentID, pq.agentID)
+		r.mu.Unlock()
+		return fmt.Errorf("%w: answer agent_id %q does not match question agent_id %q", ErrQuestionNotFound, agentID, pq.agentID)
+	}
+	if err := ValidateAnswer(pq.req, answer); err != nil {
+		r.mu.Unlock()
+		return err
 	}
+	delete(r.pending, questionID)
+	// Record the answer before letting go of the lock, so a concurrent answer
+	// that finds the question gone reads it as answered rather than expired.
+	if r.store != nil {
+		_ = r.store.ResolveQuestion(context.Background(), questionID, store.QuestionAnswered)
+	}
+	r.mu.Unlock()
 
 	// Signal cleanup goroutine to exit
 	close(pq.done)
@@ -303,6 +422,217 @@ func (r *InMemoryQuestionRouter) DeliverAnswer(agentID, questionID string, answe
 	// Use sync.Once to ensure answerChan is closed exactly once, preventing
 	// double-close panic if context cancellation races with answer delivery.
 	pq.closeOnce.Do(func() { close(pq.answerChan) })
-
 	return nil
 }
+
+// AnswerError explains why a question that is not pending cannot be
+// answered: ErrQuestionAnswered, ErrQuestionExpired or ErrQuestionNotFound.
+// A question the store still holds as pending belonged to a tool call that
+// ended with an earlier process, so it counts as expired.
+func (r *InMemoryQuestionRouter) AnswerError(questionID string) error {
+	if r.store == nil {
+		return fmt.Errorf("%w: %s", ErrQuestionNotFound, questionID)
+	}
+	q, err := r.store.GetQuestion(context.Background(), questionID)
+	switch {
+	case errors.Is(err, store.ErrQuestionNotFound):
+		return fmt.Errorf("%w: %s", ErrQuestionNotFound, questionID)
+	case err != nil:
+		return fmt.Errorf("looking up question %s: %w", questionID, err)
+	case q.Status == store.QuestionAnswered:
+		return fmt.Errorf("%w: %s", ErrQuestionAnswered, questionID)
+	default:
+		return fmt.Errorf("%w: %s", ErrQuestionExpired, questionID)
+	}
+}
+
+// ListPending returns the unanswered questions, oldest first, optionally
+// filtered to one agent. With a store set they are read from it.
+func (r *InMemoryQuestionRouter) ListPending(ctx context.Context, agentID string) ([]PendingQuestion, error) {
+	if r.store == nil {
+		out := []PendingQuestion{}
+		for _, pq := range r.Pending() {
+			if agentID == "" || pq.Request.GetAgentId() == agentID {
+				out = append(out, pq)
+			}
+		}
+		return out, nil
+	}
+
+	stored, err := r.store.ListPendingQuestions(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]PendingQuestion, len(stored))
+	for i, q := range stored {
+		out[i] = PendingQuestion{Request: questionRequest(q), AskedAt: q.AskedAt, ExpiresAt: q.ExpiresAt}
+	}
+	return out, nil
+}
+
+// storedQuestion converts a question request to its stored form.
+func storedQuestion(agentID string, req *pb.UserQuestionRequest, askedAt time.Time) *store.Question {
+	options := make([]store.QuestionOption, len(req.GetOptions()))
+	for i, opt := range req.GetOptions() {
+		options[i] = store.QuestionOption{Label: opt.GetLabel(), Description: opt.GetDescription()}
+	}
+	return &store.Question{
+		ID:             req.GetQuestionId(),
+		AgentID:        agentID,
+		Question:       req.GetQuestion(),
+		Header:         req.GetHeader(),
+		Options:        options,
+		MultiSelect:    req.GetMultiSelect(),
+		TimeoutSeconds: req.GetTimeoutSeconds(),
+		ContextJSON:    req.GetContextJson(),
+		AllowFreeText:  req.AllowFreeText,
+		Pattern:        req.GetPattern(),
+		MaxLength:      req.GetMaxLength(),
+		AskedAt:        askedAt,
+		ExpiresAt:      askedAt.Add(time.Duration(req.GetTimeoutSeconds()) * time.Second),
+	}
+}
+
+// questionRequest rebuilds the question request from its stored form.
+func questionRequest(q *store.Question) *pb.UserQuestionRequest {
+	req := &pb.UserQuestionRequest{
+		AgentId:        q.AgentID,
+		QuestionId:     q.ID,
+		Question:       q.Question,
+		Options:        make([]*pb.QuestionOption, len(q.Options)),
+		MultiSelect:    q.MultiSelect,
+		TimeoutSeconds: q.TimeoutSeconds,
+	}
+	if q.Header != "" {
+		req.Header = &q.Header
+	}
+	if q.ContextJSON != "" {
+		req.ContextJson = &q.ContextJSON
+	}
+	req.AllowFreeText = q.AllowFreeText
+	if q.Pattern != "" {
+		req.Pattern = &q.Pattern
+	}
+	if q.MaxLength > 0 {
+		req.MaxLength = &q.MaxLength
+	}
+	for i, opt := range q.Options {
+		req.Options[i] = &pb.QuestionOption{Label: opt.Label}
+		if opt.Description != "" {
+			req.Options[i].Description = &opt.Description
+		}
+	}
+	return req
+}
+
+// PendingQuestion is a question still waiting for an answer.
+type PendingQuestion struct {
+	Request   *pb.UserQuestionRequest
+	AskedAt   time.Time
+	ExpiresAt time.Time
+}
+
+// Pending returns the unanswered questions, oldest first.
+func (r *InMemoryQuestionRouter) Pending() []PendingQuestion {
+	r.mu.RLock()
+	out := make([]PendingQuestion, 0, len(r.pending))
+	for _, pq := range r.pending {
+		out = append(out, pq.pending())
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(out, func(a, b PendingQuestion) int { return a.AskedAt.Compare(b.AskedAt) })
+	return out
+}
+
+// Context decodes the question's context snapshot, or returns nil if none was attached.
+func (pq PendingQuestion) Context() *QuestionContext {
+	if pq.Request.ContextJson == nil {
+		return nil
+	}
+	var snap QuestionContext
+	if err := json.Unmarshal([]byte(pq.Request.GetContextJson()), &snap); err != nil {
+		return nil
+	}
+	return &snap
+}
+
+// Lookup returns a pending question by ID.
+func (r *InMemoryQuestionRouter) Lookup(questionID string) (PendingQuestion, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pq, ok := r.pending[questionID]
+	if !ok {
+		return PendingQuestion{}, false
+	}
+	return pq.pending(), true
+}
+
+// pending returns the exported view of pq.
+func (pq *pendingQuestion) pending() PendingQuestion {
+	return PendingQuestion{
+		Request:   pq.req,
+		AskedAt:   pq.askedAt,
+		ExpiresAt: pq.askedAt.Add(time.Duration(pq.req.GetTimeoutSeconds()) * time.Second),
+	}
+}
+
+// ContextVersion returns the version of the question's context snapshot, or "" if none.
+func (pq PendingQuestion) ContextVersion() string {
+	if snap := pq.Context(); snap != nil {
+		return snap.Version
+	}
+	return ""
+}
+
+// pendingQuestionJSON is the HTTP API form of a PendingQuestion.
+type pendingQuestionJSON struct {
+	AgentID        string               `json:"agent_id"`
+	QuestionID     string               `json:"question_id"`
+	Question       string               `json:"question"`
+	Header         string               `json:"header,omitempty"`
+	Options        []questionOptionJSON `json:"options"`
+	MultiSelect    bool                 `json:"multi_select"`
+	AllowFreeText  bool                 `json:"allow_free_text"`
+	Pattern        string               `json:"pattern,omitempty"`
+	MaxLength      int32                `json:"max_length,omitempty"`
+	TimeoutSeconds int32                `json:"timeout_seconds"`
+	AskedAt        string               `json:"asked_at"`
+	ExpiresAt      string               `json:"expires_at"`
+	Context        *QuestionContext     `json:"context,omitempty"`
+}
+
+type questionOptionJSON struct {
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
+}
+
+// MarshalJSON renders the question for the HTTP APIs, with its context
+// snapshot decoded inline.
+func (pq PendingQuestion) MarshalJSON() ([]byte, error) {
+	req := pq.Request
+	options := make([]questionOptionJSON, len(req.GetOptions()))
+	for i, opt := range req.GetOptions() {
+		options[i] = questionOptionJSON{Label: opt.GetLabel(), Description: opt.GetDescription()}
+	}
+	return json.Marshal(pendingQuestionJSON{
+		AgentID:        req.GetAgentId(),
+		QuestionID:     req.GetQuestionId(),
+		Question:       req.GetQuestion(),
+		Header:         req.GetHeader(),
+		Options:        options,
+		MultiSelect:    req.GetMultiSelect(),
+		AllowFreeText:  AllowsFreeText(req),
+		Pattern:        req.GetPattern(),
+		MaxLength:      req.GetMaxLength(),
+		TimeoutSeconds: req.GetTimeoutSeconds(),
+		AskedAt:        timeparse.Format(pq.AskedAt),
+		ExpiresAt:      timeparse.Format(pq.ExpiresAt),
+		Context:        pq.Context(),
+	})
+}
+
+// JSONShape returns the form MarshalJSON encodes, for the OpenAPI document.
+func (PendingQuestion) JSONShape() any {
+	return pendingQuestionJSON{}
+}
@@ -1,16 +1,19 @@
 // ABOUTME: Tests for UI pack tool handlers.
-// ABOUTME: Tests the ask_user tool and question routing.
+// ABOUTME: Tests the ask_user tool, question routing, and store-backed pending questions.
 
 package builtins
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/2389/coven-gateway/internal/coverr"
 	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
@@ -69,6 +72,17 @@ func TestUIPackToolDefinitions(t *testing.T) {
 				if len(caps) != 1 || caps[0] != "ui" {
 					t.Errorf("ask_user should require only 'ui' capability, got %v", caps)
 				}
+				var schema struct {
+					Properties map[string]json.RawMessage `json:"properties"`
+				}
+				if err := json.Unmarshal([]byte(tool.Definition.GetInputSchemaJson()), &schema); err != nil {
+					t.Fatalf("input schema: %v", err)
+				}
+				for _, prop := range []string{"allow_free_text", "pattern", "max_length"} {
+					if _, ok := schema.Properties[prop]; !ok {
+						t.Errorf("input schema lacks %s", prop)
+					}
+				}
 			}
 		}
 		if !found {
@@ -120,6 +134,116 @@ func TestAskUserValidation(t *testing.T) {
 			t.Error("expected error for duplicate option labels")
 		}
 	})
+
+	for name, input := range map[string]string{
+		"rejects an invalid pattern":                 `{"question": "Ticket?", "options": [{"label": "None"}], "pattern": "("}`,
+		"rejects a negative max_length":              `{"question": "Ticket?", "options": [{"label": "None"}], "max_length": -1}`,
+		"rejects text constraints without free text": `{"question": "Ticket?", "options": [{"label": "None"}], "allow_free_text": false, "max_length": 10}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := handler(context.Background(), "test-agent", json.RawMessage(input)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestAskUserAnswerConstraints(t *testing.T) {
+	streamer := newMockClientStreamer()
+	router := NewInMemoryQuestionRouter(streamer)
+	handler := findAskUserHandler(UIPack(router))
+
+	input := json.RawMessage(`{
+		"question": "Which environments?",
+		"options": [{"label": "staging"}, {"label": "prod"}, {"label": "dev"}],
+		"multi_select": true,
+		"allow_free_text": false,
+		"timeout_seconds": 5
+	}`)
+	outputs := make(chan json.RawMessage, 1)
+	go func() {
+		output, err := handler(context.Background(), "agent-1", input)
+		if err != nil {
+			t.Errorf("handler error: %v", err)
+		}
+		outputs <- output
+	}()
+
+	select {
+	case <-streamer.sent:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for question to be sent")
+	}
+	q := streamer.getQuestions()[0]
+	if !q.GetMultiSelect() || q.AllowFreeText == nil || q.GetAllowFreeText() {
+		t.Fatalf("question constraints = multi %v, free text %v; want multi-select without free text", q.GetMultiSelect(), q.AllowFreeText)
+	}
+
+	text := "qa"
+	for name, answer := range map[string]*pb.AnswerQuestionRequest{
+		"unknown option": {Selected: []string{"qa"}},
+		"free text":      {Selected: []string{"staging"}, CustomText: &text},
+	} {
+		err := router.DeliverAnswer("agent-1", q.GetQuestionId(), answer)
+		if !errors.Is(err, ErrInvalidAnswer) {
+			t.Errorf("%s: DeliverAnswer = %v, want ErrInvalidAnswer", name, err)
+		}
+	}
+	if _, ok := router.Lookup(q.GetQuestionId()); !ok {
+		t.Fatal("question no longer pending after invalid answers")
+	}
+
+	if err := router.DeliverAnswer("agent-1", q.GetQuestionId(), &pb.AnswerQuestionRequest{Selected: []string{"staging", "prod"}}); err != nil {
+		t.Fatalf("DeliverAnswer: %v", err)
+	}
+	select {
+	case output := <-outputs:
+		var out AskUserOutput
+		if err := json.Unmarshal(output, &out); err != nil {
+			t.Fatalf("unmarshal output: %v", err)
+		}
+		if !out.Answered || out.Answer != "staging, prod" || len(out.Selected) != 2 {
+			t.Errorf("output = %+v, want both selections joined in answer", out)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for handler result")
+	}
+}
+
+func TestAskUserTextConstraints(t *testing.T) {
+	streamer := newMockClientStreamer()
+	router := NewInMemoryQuestionRouter(streamer)
+	handler := findAskUserHandler(UIPack(router))
+
+	go func() {
+		_, _ = handler(context.Background(), "agent-1", json.RawMessage(`{
+			"question": "Ticket number?",
+			"options": [{"label": "No ticket"}],
+			"pattern": "^[A-Z]+-[0-9]+$",
+			"max_length": 8,
+			"timeout_seconds": 5
+		}`))
+	}()
+	select {
+	case <-streamer.sent:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for question to be sent")
+	}
+	q := streamer.getQuestions()[0]
+	if q.GetPattern() != "^[A-Z]+-[0-9]+$" || q.GetMaxLength() != 8 || !AllowsFreeText(q) {
+		t.Fatalf("question constraints = %q %d %v", q.GetPattern(), q.GetMaxLength(), AllowsFreeText(q))
+	}
+
+	// The valid answer goes last: it uses up the question.
+	for _, tc := range []struct {
+		text    string
+		invalid bool
+	}{{"ops-1", true}, {"OPS-123456", true}, {"OPS-12", false}} {
+		err := router.DeliverAnswer("agent-1", q.GetQuestionId(), &pb.AnswerQuestionRequest{CustomText: &tc.text})
+		if got := errors.Is(err, ErrInvalidAnswer); got != tc.invalid {
+			t.Errorf("answer %q: DeliverAnswer = %v, want invalid %v", tc.text, err, tc.invalid)
+		}
+	}
 }
 
 func TestAskUserWithAnswer(t *testing.T) {
@@ -256,8 +380,8 @@ func TestDeliverAnswerUnknownQuestion(t *testing.T) {
 	router := NewInMemoryQuestionRouter(streamer)
 
 	err := router.DeliverAnswer("test-agent", "unknown-question-id", &pb.AnswerQuestionRequest{})
-	if err == nil {
-		t.Error("expected error for unknown question ID")
+	if !errors.Is(err, ErrQuestionNotFound) {
+		t.Errorf("expected ErrQuestionNotFound for unknown question ID, got %v", err)
 	}
 }
 
@@ -306,6 +430,147 @@ func TestDeliverAnswerWrongAgent(t *testing.T) {
 	if err == nil {
 		t.Error("expected error when answering with wrong agent ID")
 	}
+
+	// The wrong answer must not use up the question.
+	if _, ok := router.Lookup(q.GetQuestionId()); !ok {
+		t.Error("question no longer pending after a mismatched answer")
+	}
+}
+
+// failingStreamer has no clients to deliver questions to.
+type failingStreamer struct{}
+
+func (failingStreamer) SendUserQuestion(agentID string, req *pb.UserQuestionRequest) error {
+	return errors.New("no connected clients")
+}
+
+func TestStoredQuestionWaitsForClient(t *testing.T) {
+	s := newTestStore(t)
+	router := NewInMemoryQuestionRouter(failingStreamer{})
+	router.SetStore(s)
+
+	req := &pb.UserQuestionRequest{
+		AgentId: "agent-1", QuestionId: "q-1", Question: "Deploy?", TimeoutSeconds: 60,
+		Options: []*pb.QuestionOption{{Label: "Yes"}, {Label: "No"}},
+	}
+	answers, err := router.SendQuestion(context.Background(), "agent-1", req)
+	if err != nil {
+		t.Fatalf("SendQuestion with no clients: %v", err)
+	}
+
+	pending, err := router.ListPending(context.Background(), "agent-1")
+	if err != nil {
+		t.Fatalf("ListPending: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Request.GetQuestion() != "Deploy?" || len(pending[0].Request.GetOptions()) != 2 {
+		t.Fatalf("pending = %+v, want the stored question", pending)
+	}
+	if got := pending[0].ExpiresAt.Sub(pending[0].AskedAt); got != time.Minute {
+		t.Errorf("expires %v after asking, want 1m", got)
+	}
+
+	if err := router.DeliverAnswer("agent-1", "q-1", &pb.AnswerQuestionRequest{Selected: []string{"Yes"}}); err != nil {
+		t.Fatalf("DeliverAnswer: %v", err)
+	}
+	if answer := <-answers; answer.GetSelected()[0] != "Yes" {
+		t.Errorf("answer = %v, want Yes", answer)
+	}
+	if q, _ := s.GetQuestion(context.Background(), "q-1"); q.Status != store.QuestionAnswered {
+		t.Errorf("stored status = %s, want answered", q.Status)
+	}
+
+	err = router.DeliverAnswer("agent-1", "q-1", &pb.AnswerQuestionRequest{Selected: []string{"No"}})
+	if !errors.Is(err, ErrQuestionAnswered) {
+		t.Errorf("second answer error = %v, want ErrQuestionAnswered", err)
+	}
+	if coverr.CodeOf(err) != coverr.Conflict {
+		t.Errorf("second answer code = %q, want conflict", coverr.CodeOf(err))
+	}
+	if err := router.DeliverAnswer("agent-1", "q-missing", &pb.AnswerQuestionRequest{}); !errors.Is(err, ErrQuestionNotFound) {
+		t.Errorf("unknown question error = %v, want ErrQuestionNotFound", err)
+	}
+}
+
+func TestDeliverAnswerConcurrent(t *testing.T) {
+	s := newTestStore(t)
+	router := NewInMemoryQuestionRouter(newMockClientStreamer())
+	router.SetStore(s)
+
+	req := &pb.UserQuestionRequest{
+		AgentId: "agent-1", QuestionId: "q-1", Question: "Deploy?", TimeoutSeconds: 60,
+		Options: []*pb.QuestionOption{{Label: "Yes"}, {Label: "No"}},
+	}
+	answers, err := router.SendQuestion(context.Background(), "agent-1", req)
+	if err != nil {
+		t.Fatalf("SendQuestion: %v", err)
+	}
+
+	// A TUI and the web chat answering at once: exactly one answer wins.
+	const answerers = 8
+	errs := make([]error, answerers)
+	var wg sync.WaitGroup
+	for i := range answerers {
+		wg.Go(func() {
+			errs[i] = router.DeliverAnswer("agent-1", "q-1", &pb.AnswerQuestionRequest{Selected: []string{"Yes"}})
+		})
+	}
+	wg.Wait()
+
+	delivered := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			delivered++
+		case !errors.Is(err, ErrQuestionAnswered):
+			t.Errorf("losing answer error = %v, want ErrQuestionAnswered", err)
+		}
+	}
+	if delivered != 1 {
+		t.Fatalf("%d answers delivered, want exactly 1", delivered)
+	}
+	received := 0
+	for range answers {
+		received++
+	}
+	if received != 1 {
+		t.Errorf("agent received %d answers, want 1", received)
+	}
+}
+
+func TestStoredQuestionExpires(t *testing.T) {
+	s := newTestStore(t)
+	router := NewInMemoryQuestionRouter(newMockClientStreamer())
+	router.SetStore(s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := &pb.UserQuestionRequest{AgentId: "agent-1", QuestionId: "q-1", Question: "Deploy?", TimeoutSeconds: 60}
+	if _, err := router.SendQuestion(ctx, "agent-1", req); err != nil {
+		t.Fatalf("SendQuestion: %v", err)
+	}
+	cancel()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		q, err := s.GetQuestion(context.Background(), "q-1")
+		if err != nil {
+			t.Fatalf("GetQuestion: %v", err)
+		}
+		if q.Status == store.QuestionExpired {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stored status = %s, want expired", q.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	err := router.DeliverAnswer("agent-1", "q-1", &pb.AnswerQuestionRequest{Selected: []string{"Yes"}})
+	if !errors.Is(err, ErrQuestionExpired) {
+		t.Errorf("late answer error = %v, want ErrQuestionExpired", err)
+	}
+	if pending, _ := router.ListPending(context.Background(), ""); len(pending) != 0 {
+		t.Errorf("pending = %+v, want none", pending)
+	}
 }
 
 func findAskUserHandler(pack *packs.BuiltinPack) packs.ToolHandler {
@@ -0,0 +1,456 @@
+// ABOUTME: AgentConnection: the agent side of the CovenControl AgentStream, for agents written in Go.
+// ABOUTME: Registers, heartbeats, reconnects with backoff, and hands each request to an OnMessage callback.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+const (
+	// DefaultHeartbeatInterval is how often an AgentConnection sends a
+	// heartbeat when AgentConfig.HeartbeatInterval is zero.
+	DefaultHeartbeatInterval = 30 * time.Second
+
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// agentFeatures are the protocol features an AgentConnection handles itself.
+var agentFeatures = []string{"heartbeat_ack", "resume"}
+
+var (
+	// ErrNotConnected is returned by the RequestContext helpers while the
+	// connection is re-registering after losing its stream.
+	ErrNotConnected = errors.New("agent is not connected")
+
+	// ErrShutdown is returned by Wait when the gateway told the agent to shut down.
+	ErrShutdown = errors.New("gateway requested shutdown")
+
+	// ErrSuperseded is returned by Wait when a newer registration for the
+	// same agent ID took over the connection.
+	ErrSuperseded = errors.New("connection superseded by a newer registration")
+)
+
+// RegistrationError is the gateway's refusal of a registration.
+type RegistrationError struct {
+	Reason      string
+	SuggestedID string // an agent ID the gateway would accept, if it offered one
+}
+
+func (e *RegistrationError) Error() string {
+	if e.SuggestedID != "" {
+		return fmt.Sprintf("registration refused: %s (try agent ID %q)", e.Reason, e.SuggestedID)
+	}
+	return "registration refused: " + e.Reason
+}
+
+// AgentConfig configures an AgentConnection. Addr, AgentID and OnMessage are
+// required.
+type AgentConfig struct {
+	Addr         string // gateway gRPC address, host:port
+	AgentID      string
+	Name         string
+	Capabilities []string
+	Metadata     *pb.AgentMetadata
+	// Token is sent as a bearer token when set.
+	Token string
+	// DialOptions replace the default of an insecure transport.
+	DialOptions []grpc.DialOption
+
+	// HeartbeatInterval is how often heartbeats are sent; zero means
+	// DefaultHeartbeatInterval.
+	HeartbeatInterval time.Duration
+	// MinBackoff and MaxBackoff bound the wait between reconnection attempts,
+	// which doubles after each failure. Zero means 1s and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// OnMessage handles one request. Requests are handled one at a time in
+	// the order they arrive; ctx ends when the connection is closed.
+	OnMessage func(ctx context.Context, req *RequestContext)
+
+	Logger *slog.Logger
+}
+
+// RequestContext is a request the gateway sent the agent, with helpers that
+// answer it under its request ID.
+type RequestContext struct {
+	RequestID      string
+	ThreadID       string
+	Sender         string
+	Content        string
+	Workspace      string
+	ChannelContext string
+	Attachments    []*pb.FileAttachment
+	// Resumed marks a request re-sent after a reconnect that the agent was
+	// no longer handling; it should start it over.
+	Resumed bool
+
+	conn *AgentConnection
+}
+
+// SendText streams a chunk of response text.
+func (r *RequestContext) SendText(text string) error {
+	return r.send(&pb.MessageResponse{Event: &pb.MessageResponse_Text{Text: text}})
+}
+
+// SendThinking streams a chunk of the agent's reasoning.
+func (r *RequestContext) SendThinking(text string) error {
+	return r.send(&pb.MessageResponse{Event: &pb.MessageResponse_Thinking{Thinking: text}})
+}
+
+// SendToolUse reports a tool call.
+func (r *RequestContext) SendToolUse(id, name, inputJSON string) error {
+	return r.send(&pb.MessageResponse{Event: &pb.MessageResponse_ToolUse{
+		ToolUse: &pb.ToolUse{Id: id, Name: name, InputJson: inputJSON},
+	}})
+}
+
+// SendDone finishes the request with its full response text.
+func (r *RequestContext) SendDone(fullResponse string) error {
+	return r.send(&pb.MessageResponse{Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: fullResponse}}})
+}
+
+// SendError finishes the request with an error.
+func (r *RequestContext) SendError(msg string) error {
+	return r.send(&pb.MessageResponse{Event: &pb.MessageResponse_Error{Error: msg}})
+}
+
+// Send streams any other response event, such as usage or a tool result.
+// The request ID is set from r.
+func (r *RequestContext) Send(resp *pb.MessageResponse) error {
+	return r.send(resp)
+}
+
+func (r *RequestContext) send(resp *pb.MessageResponse) error {
+	resp.RequestId = r.RequestID
+	return r.conn.send(&pb.AgentMessage{Payload: &pb.AgentMessage_Response{Response: resp}})
+}
+
+// AgentConnection is a registered agent's stream to the gateway. It sends
+// heartbeats, and when the stream breaks it re-registers under the agent ID
+// the gateway confirmed, presenting the session token from the last Welcome
+// so requests in flight carry on.
+type AgentConnection struct {
+	cfg    AgentConfig
+	conn   *grpc.ClientConn
+	logger *slog.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error // why the connection ended; set before done is closed
+
+	mu           sync.Mutex
+	stream       pb.CovenControl_AgentStreamClient // nil while reconnecting
+	welcome      *pb.Welcome
+	agentID      string
+	sessionToken string
+	ackSent      int64 // timestamp_ms of the last HeartbeatAck
+	ackReceived  int64 // when it arrived
+	inFlight     map[string]bool
+
+	sendMu   sync.Mutex // serializes Send on the stream
+	requests chan *RequestContext
+}
+
+// Connect dials the gateway, registers the agent and waits for its Welcome.
+// The connection then runs until ctx ends or Close is called. A refused
+// registration is returned as a *RegistrationError.
+func Connect(ctx context.Context, cfg AgentConfig) (*AgentConnection, error) {
+	if cfg.Addr == "" || cfg.AgentID == "" || cfg.OnMessage == nil {
+		return nil, errors.New("agent config needs Addr, AgentID and OnMessage")
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = DefaultHeartbeatInterval
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = defaultMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = max(defaultMaxBackoff, cfg.MinBackoff)
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	opts := cfg.DialOptions
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+
+	conn, err := grpc.NewClient(cfg.Addr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("dialing %s: %w", cfg.Addr, err)
+	}
+	c := &AgentConnection{
+		cfg:      cfg,
+		conn:     conn,
+		logger:   logger.With("agent_id", cfg.AgentID),
+		done:     make(chan struct{}),
+		agentID:  cfg.AgentID,
+		inFlight: make(map[string]bool),
+		requests: make(chan *RequestContext, 16),
+	}
+	c.ctx, c.cancel = context.WithCancel(ctx)
+
+	stream, err := c.register()
+	if err != nil {
+		c.cancel()
+		_ = conn.Close()
+		return nil, err
+	}
+	go c.handleRequests()
+	go c.run(stream)
+	return c, nil
+}
+
+// Welcome returns the Welcome of the current registration.
+func (c *AgentConnection) Welcome() *pb.Welcome {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.welcome
+}
+
+// Wait blocks until the connection ends. It returns nil after Close or the
+// end of Connect's context, ErrShutdown or ErrSuperseded when the gateway
+// ended it.
+func (c *AgentConnection) Wait() error {
+	<-c.done
+	return c.err
+}
+
+// Close ends the connection and waits for it to stop.
+func (c *AgentConnection) Close() error {
+	c.cancel()
+	<-c.done
+	return nil
+}
+
+// register opens a stream, registers on it and waits for the Welcome,
+// skipping status updates while the agent's principal awaits approval.
+func (c *AgentConnection) register() (pb.CovenControl_AgentStreamClient, error) {
+	ctx := c.ctx
+	if c.cfg.Token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.cfg.Token)
+	}
+	stream, err := pb.NewCovenControlClient(c.conn).AgentStream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("opening agent stream: %w", err)
+	}
+
+	c.mu.Lock()
+	reg := &pb.RegisterAgent{
+		AgentId:          c.agentID,
+		Name:             c.cfg.Name,
+		Capabilities:     c.cfg.Capabilities,
+		Metadata:         c.cfg.Metadata,
+		ProtocolFeatures: agentFeatures,
+		SessionToken:     c.sessionToken,
+	}
+	c.mu.Unlock()
+	if err := stream.Send(&pb.AgentMessage{Payload: &pb.AgentMessage_Register{Register: reg}}); err != nil {
+		return nil, fmt.Errorf("registering: %w", err)
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return nil, fmt.Errorf("waiting for welcome: %w", err)
+		}
+		switch p := msg.GetPayload().(type) {
+		case *pb.ServerMessage_Welcome:
+			c.mu.Lock()
+			c.welcome = p.Welcome
+			c.agentID = p.Welcome.GetAgentId()
+			c.sessionToken = p.Welcome.GetSessionToken()
+			c.stream = stream
+			c.mu.Unlock()
+			c.logger.Info("agent registered", "instance_id", p.Welcome.GetInstanceId(), "resumed", p.Welcome.GetResumed())
+			return stream, nil
+		case *pb.ServerMessage_RegistrationError:
+			return nil, &RegistrationError{Reason: p.RegistrationError.GetReason(), SuggestedID: p.RegistrationError.GetSuggestedId()}
+		case *pb.ServerMessage_RegistrationStatus:
+			st := p.RegistrationStatus
+			if st.GetState() == pb.RegistrationState_REGISTRATION_STATE_REVOKED {
+				return nil, &RegistrationError{Reason: "principal revoked: " + st.GetHint()}
+			}
+			c.logger.Info("waiting for registration approval", "principal_id", st.GetPrincipalId(), "admin_url", st.GetAdminUrl())
+		}
+	}
+}
+
+// run serves stream, and each stream that replaces it, until the connection
+// ends.
+func (c *AgentConnection) run(stream pb.CovenControl_AgentStreamClient) {
+	defer close(c.done)
+	defer c.conn.Close()
+	defer c.cancel()
+
+	for {
+		err := c.serve(stream)
+		if c.ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, ErrShutdown) || errors.Is(err, ErrSuperseded) {
+			c.err = err
+			return
+		}
+		c.logger.Warn("agent stream lost, reconnecting", "error", err)
+
+		stream = c.reconnect()
+		if stream == nil {
+			return
+		}
+	}
+}
+
+// reconnect registers again, doubling the wait after each failure up to
+// MaxBackoff. It returns nil once the connection is closed.
+func (c *AgentConnection) reconnect() pb.CovenControl_AgentStreamClient {
+	backoff := c.cfg.MinBackoff
+	for {
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		stream, err := c.register()
+		if err == nil {
+			return stream
+		}
+		if c.ctx.Err() != nil {
+			return nil
+		}
+		backoff = min(2*backoff, c.cfg.MaxBackoff)
+		c.logger.Warn("reconnect failed", "error", err, "retry_in", backoff)
+	}
+}
+
+// serve reads stream until it fails or the gateway ends the connection,
+// sending heartbeats meanwhile.
+func (c *AgentConnection) serve(stream pb.CovenControl_AgentStreamClient) error {
+	hbCtx, stopHeartbeats := context.WithCancel(c.ctx)
+	defer stopHeartbeats()
+	go c.heartbeat(hbCtx)
+	defer func() {
+		c.mu.Lock()
+		if c.stream == stream {
+			c.stream = nil
+		}
+		c.mu.Unlock()
+	}()
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		switch p := msg.GetPayload().(type) {
+		case *pb.ServerMessage_SendMessage:
+			sm := p.SendMessage
+			c.enqueue(&RequestContext{
+				RequestID: sm.GetRequestId(), ThreadID: sm.GetThreadId(), Sender: sm.GetSender(),
+				Content: sm.GetContent(), Workspace: sm.GetWorkspace(), ChannelContext: sm.GetChannelContext(),
+				Attachments: sm.GetAttachments(),
+			})
+		case *pb.ServerMessage_ResumeRequest:
+			rr := p.ResumeRequest
+			c.mu.Lock()
+			held := c.inFlight[rr.GetRequestId()]
+			c.mu.Unlock()
+			if held {
+				// Still being handled; its responses now go to this stream.
+				continue
+			}
+			c.enqueue(&RequestContext{
+				RequestID: rr.GetRequestId(), ThreadID: rr.GetThreadId(), Sender: rr.GetSender(),
+				Content: rr.GetContent(), Workspace: rr.GetWorkspace(), ChannelContext: rr.GetChannelContext(),
+				Resumed: true,
+			})
+		case *pb.ServerMessage_HeartbeatAck:
+			c.mu.Lock()
+			c.ackSent = p.HeartbeatAck.GetTimestampMs()
+			c.ackReceived = time.Now().UnixMilli()
+			c.mu.Unlock()
+		case *pb.ServerMessage_Shutdown:
+			c.logger.Info("gateway requested shutdown", "reason", p.Shutdown.GetReason())
+			return ErrShutdown
+		case *pb.ServerMessage_Goodbye:
+			c.logger.Info("connection superseded", "reason", p.Goodbye.GetReason())
+			return ErrSuperseded
+		}
+	}
+}
+
+// enqueue hands a request to the handler goroutine.
+func (c *AgentConnection) enqueue(req *RequestContext) {
+	req.conn = c
+	c.mu.Lock()
+	c.inFlight[req.RequestID] = true
+	c.mu.Unlock()
+	select {
+	case c.requests <- req:
+	case <-c.ctx.Done():
+	}
+}
+
+// handleRequests calls OnMessage for each request in turn.
+func (c *AgentConnection) handleRequests() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case req := <-c.requests:
+			c.cfg.OnMessage(c.ctx, req)
+			c.mu.Lock()
+			delete(c.inFlight, req.RequestID)
+			c.mu.Unlock()
+		}
+	}
+}
+
+// heartbeat sends a heartbeat every HeartbeatInterval until ctx ends,
+// reporting the round trip of the last acknowledged one.
+func (c *AgentConnection) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		c.mu.Lock()
+		hb := &pb.Heartbeat{TimestampMs: time.Now().UnixMilli(), AckTimestampMs: c.ackSent, AckReceivedMs: c.ackReceived}
+		c.mu.Unlock()
+		if err := c.send(&pb.AgentMessage{Payload: &pb.AgentMessage_Heartbeat{Heartbeat: hb}}); err != nil {
+			c.logger.Debug("heartbeat not sent", "error", err)
+		}
+	}
+}
+
+// send writes msg to the current stream.
+func (c *AgentConnection) send(msg *pb.AgentMessage) error {
+	c.mu.Lock()
+	stream := c.stream
+	c.mu.Unlock()
+	if stream == nil {
+		return ErrNotConnected
+	}
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	return stream.Send(msg)
+}
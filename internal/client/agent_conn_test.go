@@ -0,0 +1,243 @@
+// ABOUTME: Tests for AgentConnection against a fake CovenControl server over bufconn.
+// ABOUTME: Covers request round trips, heartbeats, reconnecting under the same ID, and refused registration.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// fakeControl is a CovenControl server whose AgentStream handler is supplied
+// per test. Each call handles one stream; n counts them from 1.
+type fakeControl struct {
+	pb.UnimplementedCovenControlServer
+	calls  atomic.Int32
+	handle func(n int, stream pb.CovenControl_AgentStreamServer) error
+}
+
+func (f *fakeControl) AgentStream(stream pb.CovenControl_AgentStreamServer) error {
+	return f.handle(int(f.calls.Add(1)), stream)
+}
+
+// startFakeControl serves handle over bufconn and returns an AgentConfig
+// that dials it.
+func startFakeControl(t *testing.T, handle func(n int, stream pb.CovenControl_AgentStreamServer) error) AgentConfig {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterCovenControlServer(srv, &fakeControl{handle: handle})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return AgentConfig{
+		Addr:    "passthrough:///bufnet",
+		AgentID: "agent-1",
+		Name:    "Test Agent",
+		DialOptions: []grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		},
+		MinBackoff: 5 * time.Millisecond,
+		MaxBackoff: 20 * time.Millisecond,
+		OnMessage:  func(context.Context, *RequestContext) {},
+	}
+}
+
+// welcome reads the registration and answers it with a Welcome.
+func welcome(stream pb.CovenControl_AgentStreamServer, sessionToken string) (*pb.RegisterAgent, error) {
+	msg, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	reg := msg.GetRegister()
+	return reg, stream.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_Welcome{Welcome: &pb.Welcome{
+		AgentId: reg.GetAgentId(), InstanceId: "inst-1", SessionToken: sessionToken,
+	}}})
+}
+
+// nextResponse reads until the next response, skipping heartbeats.
+func nextResponse(stream pb.CovenControl_AgentStreamServer) (*pb.MessageResponse, error) {
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if resp := msg.GetResponse(); resp != nil {
+			return resp, nil
+		}
+	}
+}
+
+func TestAgentConnection_RequestRoundTrip(t *testing.T) {
+	regs := make(chan *pb.RegisterAgent, 1)
+	responses := make(chan *pb.MessageResponse, 4)
+	cfg := startFakeControl(t, func(_ int, stream pb.CovenControl_AgentStreamServer) error {
+		reg, err := welcome(stream, "tok-1")
+		if err != nil {
+			return err
+		}
+		regs <- reg
+		if err := stream.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_SendMessage{SendMessage: &pb.SendMessage{
+			RequestId: "req-1", ThreadId: "thread-1", Sender: "alice", Content: "hi",
+		}}}); err != nil {
+			return err
+		}
+		for range 4 {
+			resp, err := nextResponse(stream)
+			if err != nil {
+				return err
+			}
+			responses <- resp
+		}
+		return stream.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_Shutdown{Shutdown: &pb.Shutdown{Reason: "done"}}})
+	})
+	cfg.Capabilities = []string{"chat"}
+	cfg.Metadata = &pb.AgentMetadata{Hostname: "test-host"}
+	cfg.OnMessage = func(_ context.Context, req *RequestContext) {
+		assert.Equal(t, "thread-1", req.ThreadID)
+		assert.Equal(t, "alice", req.Sender)
+		assert.NoError(t, req.SendThinking("hmm"))
+		assert.NoError(t, req.SendToolUse("call-1", "echo", `{}`))
+		assert.NoError(t, req.SendText("echo: "+req.Content))
+		assert.NoError(t, req.SendDone("echo: "+req.Content))
+	}
+
+	conn, err := Connect(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "inst-1", conn.Welcome().GetInstanceId())
+
+	reg := <-regs
+	assert.Equal(t, "Test Agent", reg.GetName())
+	assert.Equal(t, []string{"chat"}, reg.GetCapabilities())
+	assert.Equal(t, "test-host", reg.GetMetadata().GetHostname())
+	assert.ElementsMatch(t, []string{"heartbeat_ack", "resume"}, reg.GetProtocolFeatures())
+
+	var got []*pb.MessageResponse
+	for range 4 {
+		got = append(got, <-responses)
+	}
+	for _, resp := range got {
+		assert.Equal(t, "req-1", resp.GetRequestId())
+	}
+	assert.Equal(t, "hmm", got[0].GetThinking())
+	assert.Equal(t, "echo", got[1].GetToolUse().GetName())
+	assert.Equal(t, "echo: hi", got[2].GetText())
+	assert.Equal(t, "echo: hi", got[3].GetDone().GetFullResponse())
+
+	assert.ErrorIs(t, conn.Wait(), ErrShutdown)
+}
+
+func TestAgentConnection_Heartbeats(t *testing.T) {
+	beats := make(chan *pb.Heartbeat, 2)
+	cfg := startFakeControl(t, func(_ int, stream pb.CovenControl_AgentStreamServer) error {
+		if _, err := welcome(stream, "tok-1"); err != nil {
+			return err
+		}
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			hb := msg.GetHeartbeat()
+			if hb == nil {
+				continue
+			}
+			select {
+			case beats <- hb:
+			default:
+			}
+			if err := stream.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_HeartbeatAck{HeartbeatAck: &pb.HeartbeatAck{
+				TimestampMs: hb.GetTimestampMs(), ServerTimestampMs: time.Now().UnixMilli(),
+			}}}); err != nil {
+				return err
+			}
+		}
+	})
+	cfg.HeartbeatInterval = 10 * time.Millisecond
+
+	conn, err := Connect(context.Background(), cfg)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	first := <-beats
+	assert.NotZero(t, first.GetTimestampMs())
+	// Drain beats until one reports an acknowledged predecessor.
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case hb := <-beats:
+			if hb.GetAckTimestampMs() != 0 {
+				assert.NotZero(t, hb.GetAckReceivedMs())
+				return
+			}
+		case <-deadline:
+			t.Fatal("no heartbeat reported an ack")
+		}
+	}
+}
+
+func TestAgentConnection_ReconnectKeepsIdentity(t *testing.T) {
+	regs := make(chan *pb.RegisterAgent, 2)
+	cfg := startFakeControl(t, func(n int, stream pb.CovenControl_AgentStreamServer) error {
+		reg, err := welcome(stream, "tok-1")
+		if err != nil {
+			return err
+		}
+		regs <- reg
+		if n == 1 {
+			return errors.New("stream dropped")
+		}
+		<-stream.Context().Done()
+		return nil
+	})
+
+	conn, err := Connect(context.Background(), cfg)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	first := <-regs
+	assert.Empty(t, first.GetSessionToken())
+	select {
+	case second := <-regs:
+		assert.Equal(t, "agent-1", second.GetAgentId())
+		assert.Equal(t, "tok-1", second.GetSessionToken())
+	case <-time.After(2 * time.Second):
+		t.Fatal("agent did not reconnect")
+	}
+}
+
+func TestAgentConnection_RegistrationRefused(t *testing.T) {
+	cfg := startFakeControl(t, func(_ int, stream pb.CovenControl_AgentStreamServer) error {
+		if _, err := stream.Recv(); err != nil {
+			return err
+		}
+		return stream.Send(&pb.ServerMessage{Payload: &pb.ServerMessage_RegistrationError{RegistrationError: &pb.RegistrationError{
+			Reason: "agent ID in use", SuggestedId: "agent-1-2",
+		}}})
+	})
+
+	_, err := Connect(context.Background(), cfg)
+	var regErr *RegistrationError
+	require.ErrorAs(t, err, &regErr)
+	assert.Equal(t, "agent ID in use", regErr.Reason)
+	assert.Equal(t, "agent-1-2", regErr.SuggestedID)
+}
+
+func TestAgentConnection_SendWhileDisconnected(t *testing.T) {
+	c := &AgentConnection{}
+	req := &RequestContext{RequestID: "req-1", conn: c}
+	assert.ErrorIs(t, req.SendText("hi"), ErrNotConnected)
+}
@@ -36,6 +36,26 @@
 //   - error: Error occurred
 //   - canceled: Request canceled
 //
+// # Agent Connections
+//
+// AgentConnection is a client-side helper: the agent end of the
+// CovenControl AgentStream, for agents written in Go. Connect registers and
+// waits for the Welcome; the connection then sends heartbeats, reconnects
+// with exponential backoff under the same agent ID and session token, and
+// calls AgentConfig.OnMessage for each request, one at a time. The
+// RequestContext it receives answers under the request's ID:
+//
+//	conn, err := client.Connect(ctx, client.AgentConfig{
+//		Addr:    "localhost:50051",
+//		AgentID: "echo",
+//		OnMessage: func(ctx context.Context, req *client.RequestContext) {
+//			req.SendText(req.Content)
+//			req.SendDone(req.Content)
+//		},
+//	})
+//
+// cmd/fake-agent is built on it.
+//
 // # Authentication
 //
 // Requests include authentication via gRPC metadata:
@@ -5,13 +5,13 @@ package client
 
 import (
 	"context"
-	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/2389/coven-gateway/internal/conversation"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
@@ -32,11 +32,13 @@ type ClientService struct {
 	store       EventStore
 	principals  PrincipalStore
 	dedupe      DedupeCache
+	idempotency IdempotencyKeys
 	agents      AgentLister
 	router      MessageRouter
 	approver    ToolApprover
 	answerer    QuestionAnswerer
 	broadcaster *conversation.EventBroadcaster
+	writeGuard  func() error
 }
 
 // NewClientService creates a new ClientService with the given stores.
@@ -65,17 +67,17 @@ func (s *ClientService) GetEvents(ctx context.Context, req *pb.GetEventsRequest)
 	}
 
 	if req.Since != nil {
-		t, err := time.Parse(time.RFC3339, *req.Since)
+		t, err := timeparse.ParseParam("since", *req.Since)
 		if err != nil {
-			return nil, status.Error(codes.InvalidArgument, "invalid since timestamp")
+			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
 		params.Since = &t
 	}
 
 	if req.Until != nil {
-		t, err := time.Parse(time.RFC3339, *req.Until)
+		t, err := timeparse.ParseParam("until", *req.Until)
 		if err != nil {
-			return nil, status.Error(codes.InvalidArgument, "invalid until timestamp")
+			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
 		params.Until = &t
 	}
@@ -117,7 +119,7 @@ func toProtoEvent(e *store.LedgerEvent) *pb.Event {
 		ConversationKey: e.ConversationKey,
 		Direction:       string(e.Direction),
 		Author:          e.Author,
-		Timestamp:       e.Timestamp.Format(time.RFC3339),
+		Timestamp:       timeparse.Format(e.Timestamp),
 		Type:            string(e.Type),
 	}
 
@@ -19,7 +19,7 @@ import (
 )
 
 // createTestStore creates a real SQLite store in a temp directory.
-func createTestStore(t *testing.T) *store.SQLiteStore {
+func createTestStore(t *testing.T) *store.SQLStore {
 	t.Helper()
 
 	tmpDir := t.TempDir()
@@ -38,7 +38,7 @@ func createTestStore(t *testing.T) *store.SQLiteStore {
 }
 
 // createClientService creates a ClientService with the given store.
-func createClientService(t *testing.T, s *store.SQLiteStore) *ClientService {
+func createClientService(t *testing.T, s *store.SQLStore) *ClientService {
 	t.Helper()
 	return NewClientService(s, s)
 }
@@ -54,7 +54,7 @@ func createMemberContext() context.Context {
 }
 
 // seedEvents creates test events in the store.
-func seedEvents(t *testing.T, s *store.SQLiteStore, convKey string, count int, baseTime time.Time) []store.LedgerEvent {
+func seedEvents(t *testing.T, s *store.SQLStore, convKey string, count int, baseTime time.Time) []store.LedgerEvent {
 	t.Helper()
 	ctx := context.Background()
 
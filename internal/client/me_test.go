@@ -16,7 +16,7 @@ import (
 )
 
 // seedPrincipal creates an approved test client principal in the store.
-func seedPrincipal(t *testing.T, s *store.SQLiteStore, id, displayName string) *store.Principal {
+func seedPrincipal(t *testing.T, s *store.SQLStore, id, displayName string) *store.Principal {
 	t.Helper()
 	ctx := context.Background()
 
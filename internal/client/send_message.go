@@ -5,9 +5,9 @@ package client
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,6 +15,8 @@ import (
 	"google.golang.org/grpc/status"
 
 	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/coverr"
 	"github.com/2389/coven-gateway/internal/dedupe"
 	"github.com/2389/coven-gateway/internal/store"
 	pb "github.com/2389/coven-gateway/proto/coven"
@@ -31,6 +33,28 @@ type MessageRouter interface {
 	SendMessage(ctx context.Context, req *agent.SendRequest) (<-chan *agent.Response, error)
 }
 
+// IdempotencyKeys tracks idempotency keys per principal, shared with POST
+// /api/send; see dedupe.Idempotency.
+type IdempotencyKeys interface {
+	Begin(ctx context.Context, principalID, key string) (*store.IdempotencyKey, error)
+	Complete(ctx context.Context, principalID, key, requestID string) error
+	Abandon(ctx context.Context, principalID, key string) error
+}
+
+// SetIdempotency makes SendMessage track idempotency keys with keys, per
+// principal and durably, in place of the dedupe cache. A duplicate's
+// response then carries the original message ID.
+func (s *ClientService) SetIdempotency(keys IdempotencyKeys) {
+	s.idempotency = keys
+}
+
+// SetWriteGuard installs a check that runs before every send; a non-nil
+// error rejects the message with ResourceExhausted (code storage_full unless
+// the error carries its own) before anything is stored.
+func (s *ClientService) SetWriteGuard(guard func() error) {
+	s.writeGuard = guard
+}
+
 // SendMessage handles a direct client message with idempotency key deduplication.
 // It validates the idempotency key and returns "duplicate" status if the key has been seen.
 func (s *ClientService) SendMessage(ctx context.Context, req *pb.ClientSendMessageRequest) (*pb.ClientSendMessageResponse, error) {
@@ -38,7 +62,7 @@ func (s *ClientService) SendMessage(ctx context.Context, req *pb.ClientSendMessa
 	if req.IdempotencyKey == "" {
 		return nil, status.Error(codes.InvalidArgument, "idempotency_key required")
 	}
-	if len(req.IdempotencyKey) > 100 {
+	if len(req.IdempotencyKey) > dedupe.MaxKeyLength {
 		return nil, status.Error(codes.InvalidArgument, "idempotency_key too long")
 	}
 
@@ -49,33 +73,112 @@ func (s *ClientService) SendMessage(ctx context.Context, req *pb.ClientSendMessa
 	if req.Content == "" {
 		return nil, status.Error(codes.InvalidArgument, "content required")
 	}
+	if s.writeGuard != nil {
+		if err := s.writeGuard(); err != nil {
+			code := coverr.CodeOf(err)
+			if code == coverr.Internal {
+				code = coverr.StorageFull
+			}
+			return nil, coverr.Status(code, err.Error())
+		}
+	}
 
-	// Check dedupe - use "client:" prefix to avoid collisions with bridge keys
-	key := "client:" + req.IdempotencyKey
-	if s.dedupe != nil && s.dedupe.Check(key) {
+	duplicate, err := s.checkDuplicate(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate != nil {
 		slog.Debug("duplicate client message ignored",
 			"idempotency_key", req.IdempotencyKey,
 		)
-		return &pb.ClientSendMessageResponse{
-			Status: "duplicate",
-		}, nil
+		return duplicate, nil
 	}
 
 	// Process message
 	messageID, err := s.processClientMessage(ctx, req)
+	s.finishIdempotencyKey(ctx, req.IdempotencyKey, messageID, err)
 	if err != nil {
 		return nil, err
 	}
 
-	// Mark after success
-	if s.dedupe != nil {
-		s.dedupe.Mark(key)
-	}
-
-	return &pb.ClientSendMessageResponse{
+	resp := &pb.ClientSendMessageResponse{
 		Status:    "accepted",
 		MessageId: messageID,
-	}, nil
+	}
+	if info := s.findAgent(req.ConversationKey); info != nil {
+		resp.AgentId = info.ID
+		resp.AgentName = info.Name
+		resp.InstanceId = info.InstanceID
+	}
+	return resp, nil
+}
+
+// checkDuplicate returns the response for a send whose idempotency key was
+// already used, or nil when the send should go ahead.
+func (s *ClientService) checkDuplicate(ctx context.Context, key string) (*pb.ClientSendMessageResponse, error) {
+	if s.idempotency != nil {
+		prior, err := s.idempotency.Begin(ctx, principalIDFrom(ctx), key)
+		if err != nil {
+			slog.Error("failed to check idempotency key", "error", err)
+			return nil, status.Error(codes.Internal, "failed to check idempotency key")
+		}
+		if prior == nil {
+			return nil, nil
+		}
+		return &pb.ClientSendMessageResponse{Status: "duplicate", MessageId: prior.RequestID}, nil
+	}
+
+	// Use "client:" prefix to avoid collisions with bridge keys
+	if s.dedupe != nil && s.dedupe.Check("client:"+key) {
+		return &pb.ClientSendMessageResponse{Status: "duplicate"}, nil
+	}
+	return nil, nil
+}
+
+// finishIdempotencyKey records the message a send's idempotency key started,
+// or releases the key when the send failed so a retry is processed.
+func (s *ClientService) finishIdempotencyKey(ctx context.Context, key, messageID string, sendErr error) {
+	if s.idempotency == nil {
+		// Mark after success
+		if sendErr == nil && s.dedupe != nil {
+			s.dedupe.Mark("client:" + key)
+		}
+		return
+	}
+	principalID := principalIDFrom(ctx)
+	ctx = context.WithoutCancel(ctx)
+	var err error
+	if sendErr != nil {
+		err = s.idempotency.Abandon(ctx, principalID, key)
+	} else {
+		err = s.idempotency.Complete(ctx, principalID, key, messageID)
+	}
+	if err != nil {
+		slog.Error("failed to record idempotency key", "error", err, "idempotency_key", key)
+	}
+}
+
+// principalIDFrom returns the caller's principal ID, or "" when the call is
+// not authenticated.
+func principalIDFrom(ctx context.Context) string {
+	if a := auth.FromContext(ctx); a != nil {
+		return a.PrincipalID
+	}
+	return ""
+}
+
+// findAgent returns the connected agent with the given ID, or nil when it is
+// not connected or no agent lister is configured.
+func (s *ClientService) findAgent(agentID string) *agent.AgentInfo {
+	if s.agents == nil {
+		return nil
+	}
+	for _, info := range s.agents.ListAgents() {
+		if info.ID == agentID {
+			return info
+		}
+	}
+	return nil
 }
 
 // processClientMessage handles the actual message processing logic.
@@ -149,10 +252,14 @@ func (s *ClientService) processClientMessage(ctx context.Context, req *pb.Client
 				)
 			}
 		}
-		if errors.Is(err, agent.ErrAgentNotFound) {
-			return "", status.Error(codes.NotFound, "agent not found")
+		switch code := coverr.CodeOf(err); code {
+		case coverr.AgentOffline:
+			return "", coverr.Status(code, "agent not found")
+		case coverr.Internal:
+			return "", status.Error(codes.Unavailable, "agent unavailable")
+		default:
+			return "", coverr.Status(code, err.Error())
 		}
-		return "", status.Error(codes.Unavailable, "agent unavailable")
 	}
 
 	// Spawn goroutine to consume responses with a bounded timeout to prevent leaks
@@ -176,6 +283,9 @@ func (s *ClientService) consumeAgentResponses(
 	if respChan == nil {
 		return
 	}
+	// Text chunks are only persisted via EventDone; keep them in case the
+	// gateway truncates the response before it gets that far.
+	var partial strings.Builder
 	for {
 		select {
 		case <-ctx.Done():
@@ -188,11 +298,47 @@ func (s *ClientService) consumeAgentResponses(
 			if !ok {
 				return
 			}
+			switch resp.Event {
+			case agent.EventText:
+				partial.WriteString(resp.Text)
+			case agent.EventTruncated:
+				s.saveTruncatedResponse(ctx, conversationKey, threadID, partial.String(), resp.Truncated)
+				continue
+			}
 			s.handleAgentResponse(ctx, conversationKey, threadID, resp)
 		}
 	}
 }
 
+// saveTruncatedResponse stores the partial reply of a truncated response and
+// the system marker that flags it.
+func (s *ClientService) saveTruncatedResponse(ctx context.Context, conversationKey, threadID, partial string, ev *agent.TruncatedEvent) {
+	if partial != "" {
+		s.handleAgentResponse(ctx, conversationKey, threadID, &agent.Response{Event: agent.EventDone, Text: partial, Done: true})
+	}
+	if ev == nil || s.store == nil {
+		return
+	}
+	marker := ev.Summary()
+	event := &store.LedgerEvent{
+		ID:              uuid.New().String(),
+		ConversationKey: conversationKey,
+		ThreadID:        &threadID,
+		Direction:       store.EventDirectionOutbound,
+		Author:          "system",
+		Timestamp:       time.Now(),
+		Type:            store.EventTypeSystem,
+		Text:            &marker,
+	}
+	if err := s.store.SaveEvent(ctx, event); err != nil {
+		slog.Error("failed to store truncation marker", "error", err, "conversation_key", conversationKey)
+		return
+	}
+	if s.broadcaster != nil {
+		s.broadcaster.Publish(conversationKey, event, "")
+	}
+}
+
 // handleAgentResponse processes a single agent response: broadcasts text chunks
 // for real-time streaming, and saves other event types to the ledger.
 func (s *ClientService) handleAgentResponse(ctx context.Context, conversationKey, threadID string, resp *agent.Response) {
@@ -258,6 +404,20 @@ func (s *ClientService) responseToLedgerEvent(convKey, threadID string, resp *ag
 		if resp.ToolResult != nil {
 			event.Text = &resp.ToolResult.Output
 		}
+	case agent.EventPlan:
+		if resp.Plan == nil {
+			return nil
+		}
+		planText := resp.Plan.Text()
+		event.Type = store.EventTypePlan
+		event.Text = &planText
+	case agent.EventCitation:
+		if resp.Citation == nil {
+			return nil
+		}
+		citationText := resp.Citation.JSON()
+		event.Type = store.EventTypeCitation
+		event.Text = &citationText
 	case agent.EventError:
 		event.Type = store.EventTypeError
 		event.Text = &resp.Error
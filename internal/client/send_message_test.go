@@ -175,6 +175,84 @@ func TestSendMessage_MissingContent(t *testing.T) {
 	assert.Equal(t, "content required", st.Message())
 }
 
+func TestSendMessage_WriteGuardRejects(t *testing.T) {
+	svc := newTestClientService(t)
+	svc.SetWriteGuard(func() error { return fmt.Errorf("storage_full: disk nearly full") })
+
+	req := &pb.ClientSendMessageRequest{
+		ConversationKey: "test-conversation",
+		Content:         "Hello, world!",
+		IdempotencyKey:  "unique-key-guard",
+	}
+
+	_, err := svc.SendMessage(context.Background(), req)
+
+	require.Error(t, err)
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Contains(t, st.Message(), "storage_full")
+
+	// A rejected send must not burn the idempotency key
+	svc.SetWriteGuard(nil)
+	resp, err := svc.SendMessage(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "accepted", resp.Status)
+}
+
+func TestSendMessage_SharedIdempotency(t *testing.T) {
+	s := createTestStore(t)
+	keys := dedupe.NewIdempotency(s, time.Hour)
+	t.Cleanup(keys.Close)
+	svc := newTestClientService(t)
+	svc.SetIdempotency(keys)
+
+	req := &pb.ClientSendMessageRequest{
+		ConversationKey: "test-conversation",
+		Content:         "Hello, world!",
+		IdempotencyKey:  "shared-key",
+	}
+	ctx := createMemberContext()
+
+	resp1, err := svc.SendMessage(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "accepted", resp1.Status)
+
+	// The duplicate carries the original message ID, found through the store
+	resp2, err := svc.SendMessage(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "duplicate", resp2.Status)
+	assert.Equal(t, resp1.MessageId, resp2.MessageId)
+
+	prior, err := s.GetIdempotencyKey(context.Background(), "client-001", "shared-key")
+	require.NoError(t, err)
+	assert.Equal(t, resp1.MessageId, prior.RequestID)
+}
+
+func TestSendMessage_SharedIdempotencyReleasedOnFailure(t *testing.T) {
+	keys := dedupe.NewIdempotency(createTestStore(t), time.Hour)
+	t.Cleanup(keys.Close)
+	router := &mockRouter{err: fmt.Errorf("agent offline")}
+	svc := newTestClientServiceWithRouting(t, &mockEventStore{}, router)
+	svc.SetIdempotency(keys)
+
+	req := &pb.ClientSendMessageRequest{
+		ConversationKey: "test-conversation",
+		Content:         "Hello, world!",
+		IdempotencyKey:  "shared-key",
+	}
+	_, err := svc.SendMessage(createMemberContext(), req)
+	require.Error(t, err)
+
+	// The failed send released the key, so the retry is processed
+	router.mu.Lock()
+	router.err = nil
+	router.mu.Unlock()
+	resp, err := svc.SendMessage(createMemberContext(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "accepted", resp.Status)
+}
+
 // newTestClientService creates a ClientService with a real dedupe cache for testing.
 func newTestClientService(t *testing.T) *ClientService {
 	t.Helper()
@@ -271,6 +349,28 @@ func newTestClientServiceWithRouting(t *testing.T, eventStore *mockEventStore, r
 
 // Tests for message routing functionality
 
+func TestSendMessage_ReportsAnsweringAgent(t *testing.T) {
+	dedupeCache := dedupe.New(5*time.Minute, 1000)
+	t.Cleanup(dedupeCache.Close)
+	lister := &mockAgentLister{agents: []*agent.AgentInfo{{ID: "agent-123", Name: "infra-agent", InstanceID: "abc123"}}}
+	svc := NewClientServiceWithRouter(&mockEventStore{}, nil, dedupeCache, lister, &mockRouter{agentOnline: true})
+
+	resp, err := svc.SendMessage(context.Background(), &pb.ClientSendMessageRequest{
+		ConversationKey: "agent-123", Content: "hi", IdempotencyKey: "attr-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "agent-123", resp.AgentId)
+	assert.Equal(t, "infra-agent", resp.AgentName)
+	assert.Equal(t, "abc123", resp.InstanceId)
+
+	dup, err := svc.SendMessage(context.Background(), &pb.ClientSendMessageRequest{
+		ConversationKey: "agent-123", Content: "hi", IdempotencyKey: "attr-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "duplicate", dup.Status)
+	assert.Empty(t, dup.AgentId)
+}
+
 func TestProcessClientMessage_StoresInboundEvent(t *testing.T) {
 	eventStore := &mockEventStore{}
 	router := &mockRouter{agentOnline: true}
@@ -483,7 +583,7 @@ func TestConsumeAgentResponses_BroadcastsEvents(t *testing.T) {
 	t.Cleanup(func() { broadcaster.Close() })
 	svc.SetBroadcaster(broadcaster)
 
-	eventCh, _ := broadcaster.Subscribe(t.Context(), "agent-broadcast")
+	eventCh := broadcaster.Subscribe(t.Context(), "agent-broadcast").Events()
 
 	req := &pb.ClientSendMessageRequest{
 		ConversationKey: "agent-broadcast",
@@ -500,7 +600,8 @@ func TestConsumeAgentResponses_BroadcastsEvents(t *testing.T) {
 	timeout := time.After(2 * time.Second)
 	for {
 		select {
-		case event := <-eventCh:
+		case delivery := <-eventCh:
+			event := delivery.Event
 			require.NotNil(t, event, "expected a non-nil broadcast event")
 			broadcasts = append(broadcasts, event)
 			// Stop collecting after we get the final message
@@ -13,7 +13,9 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/2389/coven-gateway/internal/conversation"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
@@ -64,8 +66,12 @@ func (s *ClientService) StreamEvents(req *pb.StreamEventsRequest, stream pb.Clie
 
 // streamViaBroadcaster subscribes to the event broadcaster and forwards events
 // to the gRPC stream. Uses push-based delivery instead of polling.
+// If the client falls behind and events are dropped, the stream ends with
+// Aborted so the client can resume from its last event with since_event_id.
 func (s *ClientService) streamViaBroadcaster(ctx context.Context, stream pb.ClientService_StreamEventsServer, convKey string) error {
-	ch, _ := s.broadcaster.Subscribe(ctx, convKey)
+	sub := s.broadcaster.Subscribe(ctx, convKey)
+	defer sub.Close()
+	ch := sub.Events()
 
 	idleTimer := time.NewTimer(streamIdleTimeout)
 	defer idleTimer.Stop()
@@ -76,13 +82,19 @@ func (s *ClientService) streamViaBroadcaster(ctx context.Context, stream pb.Clie
 			return nil
 		case <-idleTimer.C:
 			return nil
-		case event, ok := <-ch:
+		case delivery, ok := <-ch:
 			if !ok {
+				if errors.Is(sub.Err(), conversation.ErrSlowSubscriber) {
+					return status.Error(codes.ResourceExhausted, "stream fell too far behind; resume with since_event_id")
+				}
 				// Broadcaster closed the channel (shutdown)
 				return nil
 			}
+			if delivery.Lagged > 0 {
+				return status.Errorf(codes.Aborted, "stream lagged: %d events skipped; resume with since_event_id", delivery.Lagged)
+			}
 
-			streamEvent := eventToClientStreamEvent(event)
+			streamEvent := eventToClientStreamEvent(delivery.Event)
 			if err := stream.Send(streamEvent); err != nil {
 				return err
 			}
@@ -263,7 +275,7 @@ func (s *ClientService) pollAndSendNewEvents(ctx context.Context, stream pb.Clie
 func eventToClientStreamEvent(e *store.LedgerEvent) *pb.ClientStreamEvent {
 	streamEvent := &pb.ClientStreamEvent{
 		ConversationKey: e.ConversationKey,
-		Timestamp:       e.Timestamp.Format(time.RFC3339),
+		Timestamp:       timeparse.Format(e.Timestamp),
 	}
 
 	if e.Type == store.EventTypeTextChunk {
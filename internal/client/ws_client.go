@@ -0,0 +1,126 @@
+// ABOUTME: WebSocket client for the gateway's /api/v1/ws endpoint and the JSON frames it exchanges.
+// ABOUTME: Sends messages, cancels requests, and reads back the same events /api/v1/send streams as SSE.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/httpapi"
+)
+
+// APIPrefix is the HTTP API version the clients in this module speak.
+const APIPrefix = httpapi.V1Prefix
+
+// WSURL returns the WebSocket endpoint of the gateway at gatewayURL, an
+// http:// or https:// base URL.
+func WSURL(gatewayURL string) string {
+	base := strings.TrimSuffix(gatewayURL, "/")
+	if rest, ok := strings.CutPrefix(base, "https://"); ok {
+		return "wss://" + rest + APIPrefix + "/ws"
+	}
+	return "ws://" + strings.TrimPrefix(base, "http://") + APIPrefix + "/ws"
+}
+
+// WebSocket frame types sent by the client. The gateway answers with frames
+// typed by SSE event name (started, text, done, ...) or FrameRejected.
+const (
+	FrameSend     = "send"     // start a request; Data holds a WSSendRequest
+	FrameCancel   = "cancel"   // cancel the request named by RequestID
+	FrameRejected = "rejected" // a send or cancel the gateway refused
+)
+
+// WSFrame is one JSON message on the /api/v1/ws WebSocket.
+type WSFrame struct {
+	Type string `json:"type"`
+	// Ref is chosen by the client on a send and echoed on every frame for
+	// the resulting request, so the started frame can be matched to it.
+	Ref       string          `json:"ref,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	EventID   uint64          `json:"event_id,omitempty"` // same numbering as SSE ids
+	Data      json.RawMessage `json:"data,omitempty"`
+	// Code, Error and Retriable explain a rejected frame.
+	Code      coverr.Code `json:"code,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Retriable bool        `json:"retriable,omitempty"`
+}
+
+// WSSendRequest is the data of a send frame; it mirrors the /api/v1/send body.
+type WSSendRequest struct {
+	ThreadID           string            `json:"thread_id,omitempty"`
+	Sender             string            `json:"sender"`
+	Content            string            `json:"content"`
+	AgentID            string            `json:"agent_id,omitempty"`
+	Frontend           string            `json:"frontend,omitempty"`
+	ChannelID          string            `json:"channel_id,omitempty"`
+	Capability         string            `json:"capability,omitempty"`
+	Tags               map[string]string `json:"tags,omitempty"`
+	Any                bool              `json:"any,omitempty"`
+	MaxResponseSeconds int               `json:"max_response_seconds,omitempty"`
+	Workspace          string            `json:"workspace,omitempty"`
+}
+
+// WSClient is a connection to the gateway's /api/v1/ws endpoint.
+type WSClient struct {
+	conn *websocket.Conn
+}
+
+// DialWS connects to the WebSocket endpoint at url (ws:// or wss://),
+// authenticating with token as a bearer token when it is not empty.
+func DialWS(ctx context.Context, url, token string) (*WSClient, error) {
+	opts := &websocket.DialOptions{HTTPHeader: http.Header{}}
+	if token != "" {
+		opts.HTTPHeader.Set("Authorization", "Bearer "+token)
+	}
+	conn, resp, err := websocket.Dial(ctx, url, opts)
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dialing %s: %w", url, err)
+	}
+	return &WSClient{conn: conn}, nil
+}
+
+// Send asks the gateway to start req. Its frames carry ref.
+func (c *WSClient) Send(ctx context.Context, ref string, req *WSSendRequest) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("encoding send: %w", err)
+	}
+	return c.write(ctx, &WSFrame{Type: FrameSend, Ref: ref, Data: data})
+}
+
+// Cancel asks the gateway to cancel requestID.
+func (c *WSClient) Cancel(ctx context.Context, requestID string) error {
+	return c.write(ctx, &WSFrame{Type: FrameCancel, RequestID: requestID})
+}
+
+// Next returns the next frame from the gateway.
+func (c *WSClient) Next(ctx context.Context) (*WSFrame, error) {
+	var frame WSFrame
+	if err := wsjson.Read(ctx, c.conn, &frame); err != nil {
+		return nil, fmt.Errorf("reading frame: %w", err)
+	}
+	return &frame, nil
+}
+
+// Close closes the connection normally.
+func (c *WSClient) Close() error {
+	return c.conn.Close(websocket.StatusNormalClosure, "")
+}
+
+func (c *WSClient) write(ctx context.Context, frame *WSFrame) error {
+	if err := wsjson.Write(ctx, c.conn, frame); err != nil {
+		return fmt.Errorf("writing %s frame: %w", frame.Type, err)
+	}
+	return nil
+}
@@ -7,12 +7,17 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/2389/coven-gateway/internal/ratelimit"
 )
 
 // envVarPattern matches ${VAR_NAME} patterns for environment variable expansion.
@@ -29,7 +34,13 @@ type Config struct {
 	Frontends FrontendsConfig `yaml:"frontends"`
 	Logging   LoggingConfig   `yaml:"logging"`
 	Metrics   MetricsConfig   `yaml:"metrics"`
+	Tracing   TracingConfig   `yaml:"tracing"`
 	WebAdmin  WebAdminConfig  `yaml:"webadmin"`
+	Packs     PacksConfig     `yaml:"packs"`
+	API       APIConfig       `yaml:"api"`
+	Usage     UsageConfig     `yaml:"usage"`
+	Retention RetentionConfig `yaml:"retention"`
+	MCP       MCPConfig       `yaml:"mcp"`
 }
 
 // AuthConfig holds authentication configuration.
@@ -53,11 +64,129 @@ type TailscaleConfig struct {
 type ServerConfig struct {
 	GRPCAddr string `yaml:"grpc_addr"`
 	HTTPAddr string `yaml:"http_addr"`
+	// AdminHTTPAddr, when set, moves the web admin UI off HTTPAddr onto its
+	// own TCP listener, leaving the API, MCP and health routes on HTTPAddr.
+	// It is used even when Tailscale is enabled.
+	AdminHTTPAddr string `yaml:"admin_http_addr"`
+
+	// StreamRetention is how long a finished send's SSE events stay
+	// available to GET /api/requests/{id}/stream (default 5m).
+	StreamRetention    time.Duration `yaml:"-"`
+	StreamRetentionRaw string        `yaml:"stream_retention"`
+
+	// WebSocketMaxSends caps the sends one /api/ws connection may have in
+	// flight at once (default 8).
+	WebSocketMaxSends int `yaml:"websocket_max_sends"`
+
+	// SSEChunkBytes is the largest text an SSE text or thinking event
+	// carries (default 32 KiB). Longer text is split into several events,
+	// the later ones marked "continued".
+	SSEChunkBytes int `yaml:"sse_chunk_bytes"`
+
+	// GRPCMaxRecvBytes is the largest message an agent may send (default
+	// 4 MiB). A response over it fails its request with payload_too_large
+	// instead of ending the agent's stream.
+	GRPCMaxRecvBytes int `yaml:"grpc_max_recv_bytes"`
+	// GRPCMaxSendBytes is the largest message the gateway sends over gRPC
+	// (default: gRPC's own, 2 GiB).
+	GRPCMaxSendBytes int `yaml:"grpc_max_send_bytes"`
+
+	// Readiness sets what GET /health/ready checks.
+	Readiness ReadinessConfig `yaml:"readiness"`
+}
+
+// ReadinessChecks are the dependency checks server.readiness.checks may
+// list: the store answers a query, the gRPC and HTTP listeners are serving,
+// the pack registry has its builtin packs, and enough agents are connected.
+var ReadinessChecks = []string{"store", "grpc", "http", "packs", "agents"}
+
+// ReadinessConfig holds the readiness probe's checks and thresholds.
+type ReadinessConfig struct {
+	// Checks lists the checks to run (default: all of ReadinessChecks).
+	Checks []string `yaml:"checks"`
+	// MinAgents is how many agents must be connected for the agents check
+	// to pass (default 1).
+	MinAgents int `yaml:"min_agents"`
+	// CheckTimeout bounds each check (default 1s).
+	CheckTimeout    time.Duration `yaml:"-"`
+	CheckTimeoutRaw string        `yaml:"check_timeout"`
+	// ShutdownDelay is how long shutdown keeps serving after readiness
+	// turns false, so load balancers stop sending traffic before the
+	// listeners close (default 0).
+	ShutdownDelay    time.Duration `yaml:"-"`
+	ShutdownDelayRaw string        `yaml:"shutdown_delay"`
 }
 
+// RateLimitGroups are the route groups api.rate_limit may configure: send
+// covers POST /api/send and sends over /api/ws, default every other /api route.
+var RateLimitGroups = []string{"default", "send"}
+
+// MCPConfig tunes the MCP endpoint agents and external clients call tools
+// through. Zero values fall back to the mcp package defaults.
+type MCPConfig struct {
+	// MaxBatchSize is the most requests one JSON-RPC batch may hold
+	// (default 50); larger batches are rejected with -32600.
+	MaxBatchSize int `yaml:"max_batch_size"`
+	// BatchWorkers is how many entries of a batch are handled at once
+	// (default 4).
+	BatchWorkers int `yaml:"batch_workers"`
+}
+
+// APIConfig holds HTTP API settings.
+type APIConfig struct {
+	// RateLimit maps a route group to a per-principal token bucket such as
+	// "10/min"; requests without a principal are limited per remote IP.
+	// Groups left unset are not limited.
+	RateLimit map[string]string `yaml:"rate_limit"`
+	// RateLimitMaxKeys bounds the principals and IPs tracked per group
+	// (default 10000).
+	RateLimitMaxKeys int `yaml:"rate_limit_max_keys"`
+	// Attachments limits files uploaded with multipart POST /api/send.
+	Attachments AttachmentsConfig `yaml:"attachments"`
+	// IdempotencyTTL is how long a send's idempotency key is remembered,
+	// on POST /api/send and gRPC SendMessage alike. Zero uses the default
+	// (24h).
+	IdempotencyTTL    time.Duration `yaml:"-"`
+	IdempotencyTTLRaw string        `yaml:"idempotency_ttl"`
+}
+
+// AttachmentsConfig limits message attachments.
+type AttachmentsConfig struct {
+	MaxBytes int64 `yaml:"max_bytes"` // per attachment, default 10 MiB
+	MaxCount int   `yaml:"max_count"` // per message, default 10
+	// AllowedTypes lists accepted content types; "image/*" matches a whole
+	// family. Default: image/*, text/*, application/pdf, application/json.
+	AllowedTypes []string `yaml:"allowed_types"`
+}
+
+// DatabaseDrivers are the values database.driver accepts. Postgres lets
+// several gateway replicas share one database.
+var DatabaseDrivers = []string{"sqlite", "postgres"}
+
 // DatabaseConfig holds database configuration.
 type DatabaseConfig struct {
+	// Driver is one of DatabaseDrivers (default sqlite).
+	Driver string `yaml:"driver"`
+	// Path is the SQLite database file.
 	Path string `yaml:"path"`
+	// DSN is the Postgres connection string, as a URL or key=value pairs.
+	DSN string `yaml:"dsn"`
+
+	// Monitor configures disk space and database growth alarms.
+	Monitor StorageMonitorConfig `yaml:"monitor"`
+}
+
+// StorageMonitorConfig tunes the disk space and database growth checker.
+// Zero values fall back to the diskmon package defaults.
+type StorageMonitorConfig struct {
+	Disabled        bool    `yaml:"disabled"`
+	WarnFreePercent float64 `yaml:"warn_free_percent"` // warn below this share of free disk (default 10)
+	WarnDBSizeMB    int64   `yaml:"warn_db_size_mb"`   // warn above this database+WAL size (default off)
+	MinFreeMB       int64   `yaml:"min_free_mb"`       // refuse sends below this much free disk (default 256)
+	WebhookURL      string  `yaml:"webhook_url"`       // POST level changes here as JSON
+
+	Interval    time.Duration `yaml:"-"`
+	IntervalRaw string        `yaml:"interval"` // time between checks (default 1m)
 }
 
 // AgentsConfig holds agent-related timing configuration.
@@ -65,17 +194,74 @@ type AgentsConfig struct {
 	HeartbeatInterval    time.Duration `yaml:"-"`
 	HeartbeatTimeout     time.Duration `yaml:"-"`
 	ReconnectGracePeriod time.Duration `yaml:"-"`
+	// MaxResponseDuration cuts off responses that run longer than this,
+	// not counting time spent waiting for tool approval. Zero disables it.
+	MaxResponseDuration time.Duration `yaml:"-"`
+	// IdleTimeout cancels a request after this long without a response
+	// event or heartbeat from its agent. Zero uses the gateway default
+	// (2m); negative disables it.
+	IdleTimeout time.Duration `yaml:"-"`
+	// DrainTimeout is how long shutdown lets in-flight responses finish
+	// before canceling them. Zero uses the gateway default.
+	DrainTimeout time.Duration `yaml:"-"`
 
 	// Raw string values for YAML unmarshaling
 	HeartbeatIntervalRaw    string `yaml:"heartbeat_interval"`
 	HeartbeatTimeoutRaw     string `yaml:"heartbeat_timeout"`
 	ReconnectGracePeriodRaw string `yaml:"reconnect_grace_period"`
+	MaxResponseDurationRaw  string `yaml:"max_response_duration"`
+	IdleTimeoutRaw          string `yaml:"idle_timeout"`
+	DrainTimeoutRaw         string `yaml:"drain_timeout"`
+
+	// BlockPausedToolCalls rejects pack tool calls from paused agents.
+	// By default a paused agent can still finish in-flight work that uses tools.
+	BlockPausedToolCalls bool `yaml:"block_paused_tool_calls"`
+
+	// MaxConcurrent caps the requests each agent handles at once (default 4;
+	// negative for no limit). QueueSize is how many more may wait for a slot
+	// per agent; beyond that, and always when it is zero, sends fail with 429.
+	MaxConcurrent int `yaml:"max_concurrent"`
+	QueueSize     int `yaml:"queue_size"`
+
+	// MetadataLimits bounds the metadata agents send at registration.
+	MetadataLimits MetadataLimitsConfig `yaml:"metadata_limits"`
+
+	// Health sets when an agent's heartbeats make it degraded or stale.
+	Health AgentHealthConfig `yaml:"health"`
+}
+
+// AgentHealthConfig holds the thresholds for heartbeat-driven agent health.
+// Zero values use the gateway defaults.
+type AgentHealthConfig struct {
+	DegradedLatency time.Duration `yaml:"-"`
+	DegradedAfter   time.Duration `yaml:"-"`
+	StaleAfter      time.Duration `yaml:"-"`
+
+	DegradedLatencyRaw string `yaml:"degraded_latency"` // p95 heartbeat round trip (default 1s)
+	DegradedAfterRaw   string `yaml:"degraded_after"`   // heartbeat silence (default 2x heartbeat_interval, else 1m)
+	StaleAfterRaw      string `yaml:"stale_after"`      // heartbeat silence (default heartbeat_timeout, else 90s)
+}
+
+// MetadataLimitsConfig holds size limits for agent registration metadata.
+// Zero values use the gateway defaults.
+type MetadataLimitsConfig struct {
+	MaxFieldBytes int `yaml:"max_field_bytes"`
+	MaxWorkspaces int `yaml:"max_workspaces"`
+	MaxFeatures   int `yaml:"max_protocol_features"`
+	MaxTotalBytes int `yaml:"max_total_bytes"`
+	MaxTags       int `yaml:"max_tags"`
 }
 
 // FrontendsConfig holds configuration for all frontend integrations.
 type FrontendsConfig struct {
 	Slack  SlackConfig  `yaml:"slack"`
 	Matrix MatrixConfig `yaml:"matrix"`
+	Email  EmailConfig  `yaml:"email"`
+
+	// DeliveryAckTimeout is how long a bridge using ack_mode=explicit has to
+	// acknowledge a response before it is dead-lettered (default 2m).
+	DeliveryAckTimeout    time.Duration `yaml:"-"`
+	DeliveryAckTimeoutRaw string        `yaml:"delivery_ack_timeout"`
 }
 
 // SlackConfig holds Slack integration configuration.
@@ -96,16 +282,131 @@ type MatrixConfig struct {
 	AllowedRooms []string `yaml:"allowed_rooms"`
 }
 
+// EmailConfig configures inbound email to agents. Disabled by default.
+type EmailConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Mode    string `yaml:"mode"`   // "smtp" (embedded listener, default) or "imap" (poll a mailbox)
+	Domain  string `yaml:"domain"` // agents are reached at <name>@domain
+
+	ListenAddr string          `yaml:"listen_addr"` // smtp mode (default :2525)
+	IMAP       EmailIMAPConfig `yaml:"imap"`
+
+	// Addresses maps mailbox names to agent IDs; other names are looked up
+	// in bindings with frontend "email" and the full address as channel ID.
+	Addresses map[string]string `yaml:"addresses"`
+	// AllowedSenders lists addresses, or "@domain" for a whole domain, that
+	// may mail agents. Required when enabled.
+	AllowedSenders []string `yaml:"allowed_senders"`
+	// RequireAuth refuses mail that passes neither SPF nor DKIM for its From domain.
+	RequireAuth bool `yaml:"require_auth"`
+
+	MaxMessageBytes    int64 `yaml:"max_message_bytes"`    // default 10 MiB
+	MaxAttachmentBytes int64 `yaml:"max_attachment_bytes"` // per attachment, default 5 MiB
+
+	// Relay is where agent replies are sent; without it replies are only
+	// kept in the thread.
+	Relay EmailRelayConfig `yaml:"relay"`
+}
+
+// EmailIMAPConfig names the mailbox polled in imap mode.
+type EmailIMAPConfig struct {
+	Addr     string `yaml:"addr"` // host:port, TLS unless insecure
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Mailbox  string `yaml:"mailbox"`  // default INBOX
+	Insecure bool   `yaml:"insecure"` // plain TCP, for a server on localhost
+
+	PollInterval    time.Duration `yaml:"-"`
+	PollIntervalRaw string        `yaml:"poll_interval"` // default 1m
+}
+
+// EmailRelayConfig is the outbound SMTP server for agent replies.
+type EmailRelayConfig struct {
+	Addr     string `yaml:"addr"` // host:port; STARTTLS is used when offered
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"` // envelope sender (default: the agent's address)
+}
+
 // LoggingConfig holds logging configuration.
 type LoggingConfig struct {
 	Level  string `yaml:"level"`
 	Format string `yaml:"format"`
 }
 
+// UsageConfig holds token usage reporting settings.
+type UsageConfig struct {
+	// Pricing maps an agent ID, or "default" for agents without an entry of
+	// their own, to the prices used to estimate the cost of its usage.
+	// Without a matching entry, usage has no estimated cost.
+	Pricing map[string]TokenPrice `yaml:"pricing"`
+}
+
+// RetentionConfig sets how long old rows are kept before the pruning job
+// deletes them. An unset duration keeps that table's rows forever.
+type RetentionConfig struct {
+	// LedgerEvents is how long conversation events are kept. Events of a
+	// thread updated within this window are kept however old they are.
+	LedgerEvents    time.Duration `yaml:"-"`
+	LedgerEventsRaw string        `yaml:"ledger_events"`
+	// Messages is the same for the legacy messages table.
+	Messages    time.Duration `yaml:"-"`
+	MessagesRaw string        `yaml:"messages"`
+	// Logs is how long agent log entries are kept.
+	Logs    time.Duration `yaml:"-"`
+	LogsRaw string        `yaml:"logs"`
+
+	Interval      time.Duration `yaml:"-"`
+	IntervalRaw   string        `yaml:"interval"`   // time between pruning runs (default 1h)
+	BatchSize     int           `yaml:"batch_size"` // rows per DELETE (default 500)
+	BatchPause    time.Duration `yaml:"-"`
+	BatchPauseRaw string        `yaml:"batch_pause"` // sleep between batches (default 100ms)
+	// Vacuum is "incremental" or "full" to shrink the database file after a
+	// run that deleted rows. Unset leaves freed pages for reuse.
+	Vacuum string `yaml:"vacuum"`
+}
+
+// Enabled reports whether any table has a retention period.
+func (r RetentionConfig) Enabled() bool {
+	return r.LedgerEvents > 0 || r.Messages > 0 || r.Logs > 0
+}
+
+// TokenPrice is a price per million tokens, in US dollars.
+type TokenPrice struct {
+	Input      float64 `yaml:"input"`
+	Output     float64 `yaml:"output"`
+	CacheRead  float64 `yaml:"cache_read"`
+	CacheWrite float64 `yaml:"cache_write"`
+	Thinking   float64 `yaml:"thinking"`
+}
+
 // MetricsConfig holds metrics endpoint configuration.
 type MetricsConfig struct {
 	Enabled bool   `yaml:"enabled"`
 	Path    string `yaml:"path"`
+	// Addr, when set, serves metrics on a listener of their own instead of
+	// server.http_addr.
+	Addr        string            `yaml:"addr"`
+	Reliability ReliabilityConfig `yaml:"reliability"`
+}
+
+// TracingConfig configures OpenTelemetry tracing of sends, exported over
+// OTLP/gRPC. It is off unless Enabled.
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	Endpoint    string  `yaml:"endpoint"`     // collector host:port (default localhost:4317)
+	Insecure    bool    `yaml:"insecure"`     // export without TLS
+	SampleRatio float64 `yaml:"sample_ratio"` // share of new traces kept, 0-1 (default 1)
+	ServiceName string  `yaml:"service_name"` // default coven-gateway
+}
+
+// ReliabilityConfig tunes per-tool and per-agent success-rate tracking.
+// Zero values fall back to the reliability package defaults.
+type ReliabilityConfig struct {
+	WindowSize  int     `yaml:"window_size"`  // outcomes kept per tool/agent (default 100)
+	MinSamples  int     `yaml:"min_samples"`  // outcomes required before flagging (default 5)
+	Threshold   float64 `yaml:"threshold"`    // success rate below which to flag (default 0.95)
+	MaxEntities int     `yaml:"max_entities"` // tracked tools+agents before LRU eviction (default 1000)
 }
 
 // WebAdminConfig holds web admin UI configuration.
@@ -113,6 +414,93 @@ type WebAdminConfig struct {
 	// BaseURL is the external URL for the admin UI (used for invite links)
 	// If not set, it's auto-detected from server.http_addr or tailscale hostname
 	BaseURL string `yaml:"base_url"`
+
+	// OIDC enables "Sign in with SSO" alongside password and passkey login.
+	OIDC OIDCConfig `yaml:"oidc"`
+
+	// PendingAgentWebhookURL, when set, is POSTed a JSON notice each time an
+	// agent auto-registers a principal that awaits approval.
+	PendingAgentWebhookURL string `yaml:"pending_agent_webhook_url"`
+}
+
+// OIDCConfig configures single sign-on for the web admin through an OpenID
+// Connect provider. It is enabled when Issuer is set.
+type OIDCConfig struct {
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"` // default: webadmin.base_url + /auth/oidc/callback
+	Scopes       []string `yaml:"scopes"`       // default: openid, email, profile
+
+	// AllowedDomains restricts login to verified emails in these domains.
+	AllowedDomains []string `yaml:"allowed_domains"`
+	// RequiredGroups requires at least one of these values in GroupsClaim.
+	RequiredGroups []string `yaml:"required_groups"`
+	GroupsClaim    string   `yaml:"groups_claim"` // default: groups
+
+	// RoleClaim names the claim (string or list) holding the role given to
+	// users provisioned on first login: owner, admin, or member (the default).
+	RoleClaim string `yaml:"role_claim"`
+
+	// ProviderLogout sends SSO users to the provider's end-session endpoint
+	// after logging out of the admin UI.
+	ProviderLogout bool `yaml:"provider_logout"`
+}
+
+// PacksConfig holds tool pack configuration.
+type PacksConfig struct {
+	// MCPServers are external MCP servers whose tools are imported as
+	// mcp:<name> packs.
+	MCPServers []MCPServerConfig `yaml:"mcp_servers"`
+
+	// Builtins overrides the write limits of the builtin tool packs.
+	Builtins BuiltinLimitsConfig `yaml:"builtins"`
+
+	// CapabilityEnforcement decides what happens when an agent calls a tool
+	// requiring a capability its principal has not been granted: "enforce"
+	// rejects the call, "warn" (the default) runs it and records a
+	// capability_warning ledger event.
+	CapabilityEnforcement string `yaml:"capability_enforcement"`
+
+	// HeartbeatMissWindow is how long an external pack that sends
+	// heartbeats may go without one before its tools fail fast with
+	// pack_unavailable (default 30s).
+	HeartbeatMissWindow    time.Duration `yaml:"-"`
+	HeartbeatMissWindowRaw string        `yaml:"heartbeat_miss_window"`
+
+	// ToolCacheMaxEntries bounds the results cached for pack tools that
+	// declare themselves cacheable (default 1000).
+	ToolCacheMaxEntries int `yaml:"tool_cache_max_entries"`
+}
+
+// BuiltinLimitsConfig overrides builtin write limits. Keys left out keep
+// their defaults; unknown keys are rejected when the gateway starts.
+type BuiltinLimitsConfig struct {
+	// MaxFieldBytes caps stored field sizes, keyed like "log.message".
+	MaxFieldBytes map[string]int `yaml:"max_field_bytes"`
+	// DailyQuota caps writes per agent per UTC day, keyed by data type
+	// (logs, todos, notes, bbs, mail). A negative value means unlimited.
+	DailyQuota map[string]int `yaml:"daily_quota"`
+}
+
+// MCPServerConfig describes one upstream MCP server (Streamable HTTP).
+type MCPServerConfig struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+
+	// AuthSecret names a global secret whose value is sent in AuthHeader
+	// (default Authorization), prefixed by AuthScheme (e.g. "Bearer") if set.
+	AuthHeader string `yaml:"auth_header"`
+	AuthSecret string `yaml:"auth_secret"`
+	AuthScheme string `yaml:"auth_scheme"`
+
+	// Capability, if set, is required of agents to use the server's tools.
+	Capability string `yaml:"capability"`
+
+	PollInterval    time.Duration `yaml:"-"`
+	PollIntervalRaw string        `yaml:"poll_interval"` // re-list tools and health check (default 5m)
+	Timeout         time.Duration `yaml:"-"`
+	TimeoutRaw      string        `yaml:"timeout"` // per request, including tool calls (default 30s)
 }
 
 // Load reads a configuration file from the given path and returns a parsed Config.
@@ -172,15 +560,174 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.Server.AdminHTTPAddr != "" && c.Server.AdminHTTPAddr == c.Server.HTTPAddr {
+		return errors.New("server.admin_http_addr must differ from server.http_addr")
+	}
+	if a := c.Metrics.Addr; a != "" && (a == c.Server.HTTPAddr || a == c.Server.AdminHTTPAddr) {
+		return errors.New("metrics.addr must differ from server.http_addr and server.admin_http_addr")
+	}
+
 	// Tailscale requires a hostname
 	if c.Tailscale.Enabled && c.Tailscale.Hostname == "" {
 		return errors.New("tailscale.hostname is required when tailscale is enabled")
 	}
 
-	if c.Database.Path == "" {
-		return errors.New("database.path is required")
+	switch c.Database.Driver {
+	case "", "sqlite":
+		if c.Database.Path == "" {
+			return errors.New("database.path is required")
+		}
+	case "postgres":
+		if c.Database.DSN == "" {
+			return errors.New("database.dsn is required when database.driver is postgres")
+		}
+	default:
+		return fmt.Errorf("database.driver %q must be one of %s", c.Database.Driver, strings.Join(DatabaseDrivers, ", "))
+	}
+
+	if m := c.Database.Monitor; m.WarnFreePercent < 0 || m.WarnFreePercent > 100 {
+		return fmt.Errorf("database.monitor.warn_free_percent must be between 0 and 100, got %v", m.WarnFreePercent)
+	}
+	if m := c.Database.Monitor; m.WarnDBSizeMB < 0 || m.MinFreeMB < 0 {
+		return errors.New("database.monitor size thresholds must not be negative")
+	}
+
+	if l := c.Agents.MetadataLimits; l.MaxFieldBytes < 0 || l.MaxWorkspaces < 0 || l.MaxFeatures < 0 || l.MaxTotalBytes < 0 || l.MaxTags < 0 {
+		return errors.New("agents.metadata_limits values must not be negative")
+	}
+
+	if c.Server.WebSocketMaxSends < 0 {
+		return fmt.Errorf("server.websocket_max_sends must not be negative, got %d", c.Server.WebSocketMaxSends)
+	}
+
+	if c.Server.SSEChunkBytes < 0 {
+		return fmt.Errorf("server.sse_chunk_bytes must not be negative, got %d", c.Server.SSEChunkBytes)
+	}
+
+	if c.Server.GRPCMaxRecvBytes < 0 || c.Server.GRPCMaxSendBytes < 0 {
+		return errors.New("server.grpc_max_recv_bytes and server.grpc_max_send_bytes must not be negative")
+	}
+
+	for _, check := range c.Server.Readiness.Checks {
+		if !slices.Contains(ReadinessChecks, check) {
+			return fmt.Errorf("server.readiness.checks entry %q must be one of %s", check, strings.Join(ReadinessChecks, ", "))
+		}
+	}
+	if c.Server.Readiness.MinAgents < 0 {
+		return fmt.Errorf("server.readiness.min_agents must not be negative, got %d", c.Server.Readiness.MinAgents)
+	}
+
+	if c.Retention.BatchSize < 0 {
+		return fmt.Errorf("retention.batch_size must not be negative, got %d", c.Retention.BatchSize)
+	}
+	switch c.Retention.Vacuum {
+	case "", "incremental", "full":
+	default:
+		return fmt.Errorf("retention.vacuum must be incremental or full, got %q", c.Retention.Vacuum)
+	}
+
+	if err := c.API.validate(); err != nil {
+		return err
+	}
+
+	if c.MCP.MaxBatchSize < 0 || c.MCP.BatchWorkers < 0 {
+		return errors.New("mcp.max_batch_size and mcp.batch_workers must not be negative")
+	}
+
+	if c.Agents.QueueSize < 0 {
+		return fmt.Errorf("agents.queue_size must not be negative, got %d", c.Agents.QueueSize)
+	}
+
+	if o := c.WebAdmin.OIDC; o.Issuer != "" && o.ClientID == "" {
+		return errors.New("webadmin.oidc.client_id is required when webadmin.oidc.issuer is set")
 	}
 
+	if r := c.Tracing.SampleRatio; r < 0 || r > 1 {
+		return fmt.Errorf("tracing.sample_ratio must be between 0 and 1, got %v", r)
+	}
+
+	if t := c.Metrics.Reliability.Threshold; t < 0 || t > 1 {
+		return fmt.Errorf("metrics.reliability.threshold must be between 0 and 1, got %v", t)
+	}
+
+	for key, p := range c.Usage.Pricing {
+		if p.Input < 0 || p.Output < 0 || p.CacheRead < 0 || p.CacheWrite < 0 || p.Thinking < 0 {
+			return fmt.Errorf("usage.pricing.%s prices must not be negative", key)
+		}
+	}
+
+	if err := c.Frontends.Email.validate(); err != nil {
+		return err
+	}
+
+	switch c.Packs.CapabilityEnforcement {
+	case "", "warn", "enforce":
+	default:
+		return fmt.Errorf("packs.capability_enforcement must be warn or enforce, got %q", c.Packs.CapabilityEnforcement)
+	}
+	if c.Packs.ToolCacheMaxEntries < 0 {
+		return fmt.Errorf("packs.tool_cache_max_entries must not be negative, got %d", c.Packs.ToolCacheMaxEntries)
+	}
+
+	seen := make(map[string]bool, len(c.Packs.MCPServers))
+	for i, m := range c.Packs.MCPServers {
+		if m.Name == "" || strings.ContainsAny(m.Name, ": ") {
+			return fmt.Errorf("packs.mcp_servers[%d].name is required and must not contain ':' or spaces", i)
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("packs.mcp_servers name %q is used more than once", m.Name)
+		}
+		seen[m.Name] = true
+		if u, err := url.Parse(m.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("packs.mcp_servers[%d].url must be an http(s) URL, got %q", i, m.URL)
+		}
+	}
+
+	return nil
+}
+
+// validate checks the rate limit groups and their rates, and the attachment limits.
+func (a *APIConfig) validate() error {
+	for group, rate := range a.RateLimit {
+		if !slices.Contains(RateLimitGroups, group) {
+			return fmt.Errorf("api.rate_limit group %q must be one of %s", group, strings.Join(RateLimitGroups, ", "))
+		}
+		if _, err := ratelimit.ParseRate(rate); err != nil {
+			return fmt.Errorf("api.rate_limit.%s: %w", group, err)
+		}
+	}
+	if a.RateLimitMaxKeys < 0 {
+		return fmt.Errorf("api.rate_limit_max_keys must not be negative, got %d", a.RateLimitMaxKeys)
+	}
+	if a.Attachments.MaxBytes < 0 || a.Attachments.MaxCount < 0 {
+		return errors.New("api.attachments limits must not be negative")
+	}
+	return nil
+}
+
+// validate checks the email frontend settings when it is enabled.
+func (e *EmailConfig) validate() error {
+	if !e.Enabled {
+		return nil
+	}
+	if e.Domain == "" {
+		return errors.New("frontends.email.domain is required when email is enabled")
+	}
+	switch e.Mode {
+	case "", "smtp":
+	case "imap":
+		if e.IMAP.Addr == "" || e.IMAP.Username == "" {
+			return errors.New("frontends.email.imap.addr and username are required in imap mode")
+		}
+	default:
+		return fmt.Errorf("frontends.email.mode must be smtp or imap, got %q", e.Mode)
+	}
+	if len(e.AllowedSenders) == 0 {
+		return errors.New("frontends.email.allowed_senders is required when email is enabled")
+	}
+	if e.MaxMessageBytes < 0 || e.MaxAttachmentBytes < 0 {
+		return errors.New("frontends.email size limits must not be negative")
+	}
 	return nil
 }
 
@@ -209,5 +756,143 @@ func parseDurations(cfg *Config) error {
 		}
 	}
 
+	if cfg.Agents.MaxResponseDurationRaw != "" {
+		cfg.Agents.MaxResponseDuration, err = time.ParseDuration(cfg.Agents.MaxResponseDurationRaw)
+		if err != nil {
+			return fmt.Errorf("parsing max_response_duration %q: %w", cfg.Agents.MaxResponseDurationRaw, err)
+		}
+		if cfg.Agents.MaxResponseDuration < 0 {
+			return fmt.Errorf("max_response_duration %q must not be negative", cfg.Agents.MaxResponseDurationRaw)
+		}
+	}
+
+	if cfg.API.IdempotencyTTLRaw != "" {
+		if cfg.API.IdempotencyTTL, err = time.ParseDuration(cfg.API.IdempotencyTTLRaw); err != nil || cfg.API.IdempotencyTTL <= 0 {
+			return fmt.Errorf("api.idempotency_ttl %q must be a positive duration", cfg.API.IdempotencyTTLRaw)
+		}
+	}
+
+	if cfg.Agents.IdleTimeoutRaw != "" {
+		cfg.Agents.IdleTimeout, err = time.ParseDuration(cfg.Agents.IdleTimeoutRaw)
+		if err != nil {
+			return fmt.Errorf("parsing idle_timeout %q: %w", cfg.Agents.IdleTimeoutRaw, err)
+		}
+	}
+
+	for _, d := range []struct {
+		raw  string
+		dst  *time.Duration
+		name string
+	}{
+		{cfg.Agents.Health.DegradedLatencyRaw, &cfg.Agents.Health.DegradedLatency, "degraded_latency"},
+		{cfg.Agents.Health.DegradedAfterRaw, &cfg.Agents.Health.DegradedAfter, "degraded_after"},
+		{cfg.Agents.Health.StaleAfterRaw, &cfg.Agents.Health.StaleAfter, "stale_after"},
+	} {
+		if d.raw == "" {
+			continue
+		}
+		if *d.dst, err = time.ParseDuration(d.raw); err != nil || *d.dst <= 0 {
+			return fmt.Errorf("agents.health.%s %q must be a positive duration", d.name, d.raw)
+		}
+	}
+	if h := cfg.Agents.Health; h.DegradedAfter > 0 && h.StaleAfter > 0 && h.StaleAfter < h.DegradedAfter {
+		return fmt.Errorf("agents.health.stale_after %q must not be shorter than degraded_after %q", h.StaleAfterRaw, h.DegradedAfterRaw)
+	}
+
+	if cfg.Server.StreamRetentionRaw != "" {
+		cfg.Server.StreamRetention, err = time.ParseDuration(cfg.Server.StreamRetentionRaw)
+		if err != nil || cfg.Server.StreamRetention <= 0 {
+			return fmt.Errorf("server.stream_retention %q must be a positive duration", cfg.Server.StreamRetentionRaw)
+		}
+	}
+
+	if r := &cfg.Server.Readiness; r.CheckTimeoutRaw != "" {
+		r.CheckTimeout, err = time.ParseDuration(r.CheckTimeoutRaw)
+		if err != nil || r.CheckTimeout <= 0 {
+			return fmt.Errorf("server.readiness.check_timeout %q must be a positive duration", r.CheckTimeoutRaw)
+		}
+	}
+	if r := &cfg.Server.Readiness; r.ShutdownDelayRaw != "" {
+		r.ShutdownDelay, err = time.ParseDuration(r.ShutdownDelayRaw)
+		if err != nil || r.ShutdownDelay < 0 {
+			return fmt.Errorf("server.readiness.shutdown_delay %q must not be negative", r.ShutdownDelayRaw)
+		}
+	}
+
+	if cfg.Agents.DrainTimeoutRaw != "" {
+		cfg.Agents.DrainTimeout, err = time.ParseDuration(cfg.Agents.DrainTimeoutRaw)
+		if err != nil || cfg.Agents.DrainTimeout <= 0 {
+			return fmt.Errorf("agents.drain_timeout %q must be a positive duration", cfg.Agents.DrainTimeoutRaw)
+		}
+	}
+
+	if cfg.Frontends.DeliveryAckTimeoutRaw != "" {
+		cfg.Frontends.DeliveryAckTimeout, err = time.ParseDuration(cfg.Frontends.DeliveryAckTimeoutRaw)
+		if err != nil {
+			return fmt.Errorf("parsing delivery_ack_timeout %q: %w", cfg.Frontends.DeliveryAckTimeoutRaw, err)
+		}
+	}
+
+	if raw := cfg.Frontends.Email.IMAP.PollIntervalRaw; raw != "" {
+		cfg.Frontends.Email.IMAP.PollInterval, err = time.ParseDuration(raw)
+		if err != nil || cfg.Frontends.Email.IMAP.PollInterval <= 0 {
+			return fmt.Errorf("frontends.email.imap.poll_interval %q must be a positive duration", raw)
+		}
+	}
+
+	if raw := cfg.Packs.HeartbeatMissWindowRaw; raw != "" {
+		cfg.Packs.HeartbeatMissWindow, err = time.ParseDuration(raw)
+		if err != nil || cfg.Packs.HeartbeatMissWindow <= 0 {
+			return fmt.Errorf("packs.heartbeat_miss_window %q must be a positive duration", raw)
+		}
+	}
+
+	for i := range cfg.Packs.MCPServers {
+		m := &cfg.Packs.MCPServers[i]
+		if m.PollIntervalRaw != "" {
+			if m.PollInterval, err = time.ParseDuration(m.PollIntervalRaw); err != nil || m.PollInterval <= 0 {
+				return fmt.Errorf("packs.mcp_servers[%d].poll_interval %q must be a positive duration", i, m.PollIntervalRaw)
+			}
+		}
+		if m.TimeoutRaw != "" {
+			if m.Timeout, err = time.ParseDuration(m.TimeoutRaw); err != nil || m.Timeout <= 0 {
+				return fmt.Errorf("packs.mcp_servers[%d].timeout %q must be a positive duration", i, m.TimeoutRaw)
+			}
+		}
+	}
+
+	for _, d := range []struct {
+		raw  string
+		dst  *time.Duration
+		name string
+	}{
+		{cfg.Retention.LedgerEventsRaw, &cfg.Retention.LedgerEvents, "ledger_events"},
+		{cfg.Retention.MessagesRaw, &cfg.Retention.Messages, "messages"},
+		{cfg.Retention.LogsRaw, &cfg.Retention.Logs, "logs"},
+		{cfg.Retention.IntervalRaw, &cfg.Retention.Interval, "interval"},
+	} {
+		if d.raw == "" {
+			continue
+		}
+		if *d.dst, err = time.ParseDuration(d.raw); err != nil || *d.dst <= 0 {
+			return fmt.Errorf("retention.%s %q must be a positive duration", d.name, d.raw)
+		}
+	}
+	if raw := cfg.Retention.BatchPauseRaw; raw != "" {
+		if cfg.Retention.BatchPause, err = time.ParseDuration(raw); err != nil || cfg.Retention.BatchPause < 0 {
+			return fmt.Errorf("retention.batch_pause %q must be a non-negative duration", raw)
+		}
+	}
+
+	if cfg.Database.Monitor.IntervalRaw != "" {
+		cfg.Database.Monitor.Interval, err = time.ParseDuration(cfg.Database.Monitor.IntervalRaw)
+		if err != nil {
+			return fmt.Errorf("parsing database.monitor.interval %q: %w", cfg.Database.Monitor.IntervalRaw, err)
+		}
+		if cfg.Database.Monitor.Interval <= 0 {
+			return fmt.Errorf("database.monitor.interval %q must be positive", cfg.Database.Monitor.IntervalRaw)
+		}
+	}
+
 	return nil
 }
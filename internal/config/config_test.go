@@ -272,6 +272,7 @@ func TestLoad_DurationParsing(t *testing.T) {
 server:
   grpc_addr: "0.0.0.0:50051"
   http_addr: "0.0.0.0:8080"
+  stream_retention: "90s"
 
 database:
   path: "./test.db"
@@ -280,6 +281,14 @@ agents:
   heartbeat_interval: "1m30s"
   heartbeat_timeout: "2h"
   reconnect_grace_period: "10m"
+  max_response_duration: "45m"
+  idle_timeout: "5m"
+  drain_timeout: "20s"
+  max_concurrent: 2
+  queue_size: 8
+
+api:
+  idempotency_ttl: "36h"
 
 frontends:
   slack:
@@ -327,6 +336,501 @@ metrics:
 	if cfg.Agents.ReconnectGracePeriod != 10*time.Minute {
 		t.Errorf("Agents.ReconnectGracePeriod = %v, want %v", cfg.Agents.ReconnectGracePeriod, 10*time.Minute)
 	}
+
+	if cfg.Agents.MaxResponseDuration != 45*time.Minute {
+		t.Errorf("Agents.MaxResponseDuration = %v, want %v", cfg.Agents.MaxResponseDuration, 45*time.Minute)
+	}
+
+	if cfg.Server.StreamRetention != 90*time.Second {
+		t.Errorf("Server.StreamRetention = %v, want %v", cfg.Server.StreamRetention, 90*time.Second)
+	}
+	if cfg.Agents.MaxConcurrent != 2 || cfg.Agents.QueueSize != 8 {
+		t.Errorf("Agents.MaxConcurrent, QueueSize = %d, %d, want 2, 8", cfg.Agents.MaxConcurrent, cfg.Agents.QueueSize)
+	}
+	if cfg.Agents.IdleTimeout != 5*time.Minute {
+		t.Errorf("Agents.IdleTimeout = %v, want %v", cfg.Agents.IdleTimeout, 5*time.Minute)
+	}
+	if cfg.Agents.DrainTimeout != 20*time.Second {
+		t.Errorf("Agents.DrainTimeout = %v, want %v", cfg.Agents.DrainTimeout, 20*time.Second)
+	}
+	if cfg.API.IdempotencyTTL != 36*time.Hour {
+		t.Errorf("API.IdempotencyTTL = %v, want %v", cfg.API.IdempotencyTTL, 36*time.Hour)
+	}
+}
+
+func TestLoad_MetadataLimits(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+
+database:
+  path: "./test.db"
+
+agents:
+  metadata_limits:
+    max_field_bytes: 512
+    max_workspaces: 8
+    max_total_bytes: 4096
+    max_tags: 4
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := MetadataLimitsConfig{MaxFieldBytes: 512, MaxWorkspaces: 8, MaxTotalBytes: 4096, MaxTags: 4}
+	if cfg.Agents.MetadataLimits != want {
+		t.Errorf("Agents.MetadataLimits = %+v, want %+v", cfg.Agents.MetadataLimits, want)
+	}
+
+	cfg.Agents.MetadataLimits.MaxWorkspaces = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "metadata_limits") {
+		t.Errorf("Validate() with negative limit = %v, want metadata_limits error", err)
+	}
+}
+
+func TestLoad_APIRateLimit(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+
+database:
+  path: "./test.db"
+
+api:
+  rate_limit:
+    send: "10/min"
+    default: "120/min"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.API.RateLimit["send"] != "10/min" || cfg.API.RateLimit["default"] != "120/min" {
+		t.Errorf("API.RateLimit = %v", cfg.API.RateLimit)
+	}
+
+	cfg.API.RateLimit["uploads"] = "1/min"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `group "uploads"`) {
+		t.Errorf("Validate() with unknown group = %v, want group error", err)
+	}
+	delete(cfg.API.RateLimit, "uploads")
+	cfg.API.RateLimit["send"] = "10 per minute"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "api.rate_limit.send") {
+		t.Errorf("Validate() with bad rate = %v, want api.rate_limit.send error", err)
+	}
+}
+
+func TestLoad_UsagePricing(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+
+database:
+  path: "./test.db"
+
+usage:
+  pricing:
+    default:
+      input: 3
+      output: 15
+    agent-cheap:
+      input: 0.25
+      cache_read: 0.03
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := cfg.Usage.Pricing["default"]; got.Input != 3 || got.Output != 15 {
+		t.Errorf("default price = %+v", got)
+	}
+	if got := cfg.Usage.Pricing["agent-cheap"]; got.Input != 0.25 || got.CacheRead != 0.03 || got.Output != 0 {
+		t.Errorf("agent-cheap price = %+v", got)
+	}
+
+	cfg.Usage.Pricing["agent-cheap"] = TokenPrice{Output: -1}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "usage.pricing.agent-cheap") {
+		t.Errorf("Validate() with negative price = %v, want usage.pricing.agent-cheap error", err)
+	}
+}
+
+func TestLoad_StorageMonitor(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+
+database:
+  path: "./test.db"
+  monitor:
+    interval: "30s"
+    warn_free_percent: 15
+    warn_db_size_mb: 2048
+    min_free_mb: 512
+    webhook_url: "https://alerts.example.com/hook"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := StorageMonitorConfig{
+		WarnFreePercent: 15, WarnDBSizeMB: 2048, MinFreeMB: 512,
+		WebhookURL: "https://alerts.example.com/hook", Interval: 30 * time.Second, IntervalRaw: "30s",
+	}
+	if cfg.Database.Monitor != want {
+		t.Errorf("Database.Monitor = %+v, want %+v", cfg.Database.Monitor, want)
+	}
+
+	cfg.Database.Monitor.WarnFreePercent = 150
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "warn_free_percent") {
+		t.Errorf("Validate() with 150%% = %v, want warn_free_percent error", err)
+	}
+}
+
+func TestLoad_PostgresDatabase(t *testing.T) {
+	t.Setenv("TEST_PG_PASSWORD", "secret")
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+
+database:
+  driver: postgres
+  dsn: "postgres://coven:${TEST_PG_PASSWORD}@db:5432/coven?sslmode=disable"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Database.Driver != "postgres" {
+		t.Errorf("Database.Driver = %q, want postgres", cfg.Database.Driver)
+	}
+	if want := "postgres://coven:secret@db:5432/coven?sslmode=disable"; cfg.Database.DSN != want {
+		t.Errorf("Database.DSN = %q, want %q", cfg.Database.DSN, want)
+	}
+}
+
+func TestLoad_Tracing(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+
+database:
+  path: "./test.db"
+
+tracing:
+  enabled: true
+  endpoint: "otel-collector:4317"
+  insecure: true
+  sample_ratio: 0.25
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := TracingConfig{Enabled: true, Endpoint: "otel-collector:4317", Insecure: true, SampleRatio: 0.25}
+	if cfg.Tracing != want {
+		t.Errorf("Tracing = %+v, want %+v", cfg.Tracing, want)
+	}
+}
+
+func TestLoad_MCP(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+
+database:
+  path: "./test.db"
+
+mcp:
+  max_batch_size: 10
+  batch_workers: 2
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := MCPConfig{MaxBatchSize: 10, BatchWorkers: 2}
+	if cfg.MCP != want {
+		t.Errorf("MCP = %+v, want %+v", cfg.MCP, want)
+	}
+}
+
+func TestLoad_Retention(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+
+database:
+  path: "./test.db"
+
+retention:
+  ledger_events: "2160h"
+  logs: "168h"
+  interval: "30m"
+  batch_size: 200
+  batch_pause: "0s"
+  vacuum: "incremental"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	r := cfg.Retention
+	if r.LedgerEvents != 90*24*time.Hour || r.Messages != 0 || r.Logs != 7*24*time.Hour {
+		t.Errorf("retention periods = %v/%v/%v, want 2160h/0/168h", r.LedgerEvents, r.Messages, r.Logs)
+	}
+	if r.Interval != 30*time.Minute || r.BatchSize != 200 || r.BatchPause != 0 || r.Vacuum != "incremental" {
+		t.Errorf("retention job = %+v", r)
+	}
+	if !r.Enabled() {
+		t.Error("Enabled() = false, want true")
+	}
+
+	cfg.Retention.Vacuum = "always"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "retention.vacuum") {
+		t.Errorf("Validate() with vacuum=always = %v, want retention.vacuum error", err)
+	}
+
+	if err := os.WriteFile(configPath, []byte(strings.Replace(configContent, `"168h"`, `"-1h"`, 1)), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+	if _, err := Load(configPath); err == nil || !strings.Contains(err.Error(), "retention.logs") {
+		t.Errorf("Load() with negative retention = %v, want retention.logs error", err)
+	}
+}
+
+func TestLoad_AgentHealth(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+
+database:
+  path: "./test.db"
+
+agents:
+  health:
+    degraded_latency: "500ms"
+    degraded_after: "45s"
+    stale_after: "2m"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	h := cfg.Agents.Health
+	if h.DegradedLatency != 500*time.Millisecond || h.DegradedAfter != 45*time.Second || h.StaleAfter != 2*time.Minute {
+		t.Errorf("Agents.Health = %+v, want 500ms/45s/2m", h)
+	}
+
+	bad := strings.Replace(configContent, `stale_after: "2m"`, `stale_after: "30s"`, 1)
+	if err := os.WriteFile(configPath, []byte(bad), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+	if _, err := Load(configPath); err == nil || !strings.Contains(err.Error(), "stale_after") {
+		t.Errorf("Load() with stale_after < degraded_after = %v, want stale_after error", err)
+	}
+}
+
+func TestLoad_WebAdminOIDC(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+
+database:
+  path: "./test.db"
+
+webadmin:
+  oidc:
+    issuer: "https://sso.example.com"
+    client_id: "coven"
+    allowed_domains: ["example.com"]
+    role_claim: "coven_role"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	oidc := cfg.WebAdmin.OIDC
+	if oidc.Issuer != "https://sso.example.com" || oidc.ClientID != "coven" || oidc.RoleClaim != "coven_role" ||
+		len(oidc.AllowedDomains) != 1 || oidc.AllowedDomains[0] != "example.com" {
+		t.Errorf("WebAdmin.OIDC = %+v", oidc)
+	}
+
+	cfg.WebAdmin.OIDC.ClientID = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "client_id") {
+		t.Errorf("Validate() without client_id = %v, want client_id error", err)
+	}
+}
+
+func TestLoad_MCPServers(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+
+database:
+  path: "./test.db"
+
+packs:
+  mcp_servers:
+    - name: github
+      url: "https://mcp.example.com/github"
+      auth_secret: GITHUB_TOKEN
+      auth_scheme: Bearer
+      capability: code
+      poll_interval: "2m"
+      timeout: "10s"
+  heartbeat_miss_window: "45s"
+  tool_cache_max_entries: 50
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Packs.MCPServers) != 1 {
+		t.Fatalf("MCPServers = %+v, want one entry", cfg.Packs.MCPServers)
+	}
+	if cfg.Packs.HeartbeatMissWindow != 45*time.Second {
+		t.Errorf("HeartbeatMissWindow = %v, want 45s", cfg.Packs.HeartbeatMissWindow)
+	}
+	if cfg.Packs.ToolCacheMaxEntries != 50 {
+		t.Errorf("ToolCacheMaxEntries = %d, want 50", cfg.Packs.ToolCacheMaxEntries)
+	}
+	m := cfg.Packs.MCPServers[0]
+	if m.Name != "github" || m.AuthSecret != "GITHUB_TOKEN" || m.AuthScheme != "Bearer" || m.Capability != "code" ||
+		m.PollInterval != 2*time.Minute || m.Timeout != 10*time.Second {
+		t.Errorf("MCPServers[0] = %+v", m)
+	}
+
+	cfg.Packs.MCPServers = append(cfg.Packs.MCPServers, m)
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Errorf("Validate() with duplicate names = %v, want duplicate error", err)
+	}
+	cfg.Packs.MCPServers = []MCPServerConfig{{Name: "fs:local", URL: "http://localhost:9000"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "name") {
+		t.Errorf("Validate() with ':' in name = %v, want name error", err)
+	}
+	cfg.Packs.MCPServers = []MCPServerConfig{{Name: "fs", URL: "localhost:9000"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "url") {
+		t.Errorf("Validate() with bad url = %v, want url error", err)
+	}
+}
+
+func TestLoad_Email(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+
+database:
+  path: "./test.db"
+
+frontends:
+  email:
+    enabled: true
+    mode: imap
+    domain: agents.example.com
+    imap:
+      addr: "imap.example.com:993"
+      username: agents
+      poll_interval: "30s"
+    addresses:
+      billing: billing-agent
+    allowed_senders: ["@example.com"]
+    relay:
+      addr: "smtp.example.com:587"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	e := cfg.Frontends.Email
+	if !e.Enabled || e.Mode != "imap" || e.IMAP.PollInterval != 30*time.Second || e.Addresses["billing"] != "billing-agent" || e.Relay.Addr != "smtp.example.com:587" {
+		t.Errorf("Email = %+v", e)
+	}
+
+	cfg.Frontends.Email.AllowedSenders = nil
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "allowed_senders") {
+		t.Errorf("Validate() without allowed_senders = %v, want allowed_senders error", err)
+	}
+	cfg.Frontends.Email = EmailConfig{Enabled: true, Domain: "agents.example.com", Mode: "pop3", AllowedSenders: []string{"a@example.com"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "mode") {
+		t.Errorf("Validate() with mode pop3 = %v, want mode error", err)
+	}
+	// Disabled (the default) needs nothing else.
+	cfg.Frontends.Email = EmailConfig{}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with email disabled = %v", err)
+	}
 }
 
 func TestLoad_MissingFile(t *testing.T) {
@@ -436,6 +940,134 @@ database:
 `,
 			wantErrSubstr: "server.http_addr is required",
 		},
+		{
+			name: "admin_http_addr same as http_addr",
+			configContent: `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+  admin_http_addr: "0.0.0.0:8080"
+database:
+  path: "./test.db"
+`,
+			wantErrSubstr: "server.admin_http_addr must differ",
+		},
+		{
+			name: "metrics addr same as http_addr",
+			configContent: `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+database:
+  path: "./test.db"
+metrics:
+  enabled: true
+  addr: "0.0.0.0:8080"
+`,
+			wantErrSubstr: "metrics.addr must differ",
+		},
+		{
+			name: "unknown capability enforcement mode",
+			configContent: `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+database:
+  path: "./test.db"
+packs:
+  capability_enforcement: "strict"
+`,
+			wantErrSubstr: "packs.capability_enforcement must be warn or enforce",
+		},
+		{
+			name: "negative agent queue size",
+			configContent: `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+database:
+  path: "./test.db"
+agents:
+  queue_size: -1
+`,
+			wantErrSubstr: "agents.queue_size must not be negative",
+		},
+		{
+			name: "negative mcp batch size",
+			configContent: `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+database:
+  path: "./test.db"
+mcp:
+  max_batch_size: -1
+`,
+			wantErrSubstr: "mcp.max_batch_size and mcp.batch_workers must not be negative",
+		},
+		{
+			name: "negative grpc_max_recv_bytes",
+			configContent: `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+  grpc_max_recv_bytes: -1
+database:
+  path: "./test.db"
+`,
+			wantErrSubstr: "server.grpc_max_recv_bytes and server.grpc_max_send_bytes must not be negative",
+		},
+		{
+			name: "unknown readiness check",
+			configContent: `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+  readiness:
+    checks: [store, disk]
+database:
+  path: "./test.db"
+`,
+			wantErrSubstr: `server.readiness.checks entry "disk" must be one of`,
+		},
+		{
+			name: "tracing sample ratio above 1",
+			configContent: `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+database:
+  path: "./test.db"
+tracing:
+  enabled: true
+  sample_ratio: 1.5
+`,
+			wantErrSubstr: "tracing.sample_ratio must be between 0 and 1",
+		},
+		{
+			name: "unknown database driver",
+			configContent: `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+database:
+  driver: mysql
+  path: "./test.db"
+`,
+			wantErrSubstr: `database.driver "mysql" must be one of`,
+		},
+		{
+			name: "postgres without dsn",
+			configContent: `
+server:
+  grpc_addr: "0.0.0.0:50051"
+  http_addr: "0.0.0.0:8080"
+database:
+  driver: postgres
+  path: "./test.db"
+`,
+			wantErrSubstr: "database.dsn is required when database.driver is postgres",
+		},
 		{
 			name: "missing database path",
 			configContent: `
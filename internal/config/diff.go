@@ -0,0 +1,74 @@
+// ABOUTME: Comparison of two configurations for hot reload, keyed by YAML path
+// ABOUTME: Each change is marked dynamic (applied on reload) or as needing a restart
+
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Change is a setting that differs between two configurations.
+type Change struct {
+	// Key is the setting's dotted YAML path, such as "logging.level".
+	Key string
+	// Dynamic is set for settings the gateway applies when it reloads its
+	// config; the rest take effect at the next restart.
+	Dynamic bool
+}
+
+// dynamicKeys are the settings, or whole sections, a running gateway
+// applies on reload.
+var dynamicKeys = []string{
+	"logging",
+	"agents.heartbeat_interval",
+	"agents.heartbeat_timeout",
+	"agents.reconnect_grace_period",
+	"agents.health",
+	"agents.block_paused_tool_calls",
+	"api.rate_limit",
+	"api.rate_limit_max_keys",
+	"retention",
+	"packs.capability_enforcement",
+}
+
+// IsDynamic reports whether the setting at key is applied on reload.
+func IsDynamic(key string) bool {
+	for _, k := range dynamicKeys {
+		if key == k || strings.HasPrefix(key, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff returns the settings that differ between old and new, in file order.
+// Settings are compared as written in the file, after environment variable
+// expansion; lists and maps are compared whole. Values are left out so
+// secrets can be logged safely.
+func Diff(old, new *Config) []Change {
+	var changes []Change
+	diffStruct(reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem(), "", &changes)
+	return changes
+}
+
+// diffStruct appends the YAML fields of two structs of the same type that
+// differ, recursing into nested sections.
+func diffStruct(a, b reflect.Value, prefix string, changes *[]Change) {
+	t := a.Type()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+		fa, fb := a.Field(i), b.Field(i)
+		if fa.Kind() == reflect.Struct {
+			diffStruct(fa, fb, key+".", changes)
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			*changes = append(*changes, Change{Key: key, Dynamic: IsDynamic(key)})
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// ABOUTME: Tests for comparing configurations on reload
+// ABOUTME: Covers nested keys, whole-map comparison, and the dynamic/restart split
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := &Config{
+		Server:   ServerConfig{GRPCAddr: ":50051", HTTPAddr: ":8080"},
+		Database: DatabaseConfig{Path: "gateway.db"},
+		Agents:   AgentsConfig{HeartbeatIntervalRaw: "30s", MaxConcurrent: 4},
+		Logging:  LoggingConfig{Level: "info"},
+		API:      APIConfig{RateLimit: map[string]string{"send": "10/min"}},
+	}
+	same := *old
+	if changes := Diff(old, &same); len(changes) != 0 {
+		t.Errorf("Diff of identical configs = %v, want none", changes)
+	}
+
+	updated := *old
+	updated.Server.HTTPAddr = ":9090"
+	updated.Database.Path = "other.db"
+	updated.Tailscale.Enabled = true
+	updated.Agents.HeartbeatIntervalRaw = "10s"
+	updated.Agents.Health.StaleAfterRaw = "2m"
+	updated.Agents.MaxConcurrent = 8
+	updated.Logging.Level = "debug"
+	updated.API.RateLimit = map[string]string{"send": "20/min"}
+	updated.Retention.LogsRaw = "720h"
+
+	want := []Change{
+		{Key: "server.http_addr"},
+		{Key: "tailscale.enabled"},
+		{Key: "database.path"},
+		{Key: "agents.heartbeat_interval", Dynamic: true},
+		{Key: "agents.max_concurrent"},
+		{Key: "agents.health.stale_after", Dynamic: true},
+		{Key: "logging.level", Dynamic: true},
+		{Key: "api.rate_limit", Dynamic: true},
+		{Key: "retention.logs", Dynamic: true},
+	}
+	if got := Diff(old, &updated); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestIsDynamic(t *testing.T) {
+	for key, want := range map[string]bool{
+		"logging.format":                true,
+		"api.rate_limit":                true,
+		"api.rate_limit_max_keys":       true,
+		"api.attachments.max_bytes":     false,
+		"agents.reconnect_grace_period": true,
+		"agents.drain_timeout":          false,
+		"packs.capability_enforcement":  true,
+		"packs.mcp_servers":             false,
+		"server.grpc_addr":              false,
+	} {
+		if got := IsDynamic(key); got != want {
+			t.Errorf("IsDynamic(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
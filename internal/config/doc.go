@@ -40,6 +40,7 @@
 //	server:
 //	  grpc_addr: "0.0.0.0:50051"  # Agent connections
 //	  http_addr: "0.0.0.0:8080"   # API and web admin
+//	  admin_http_addr: "127.0.0.1:8081"  # optional: web admin on its own listener
 //
 // Database:
 //
@@ -74,6 +75,13 @@
 //	  level: "info"   # debug, info, warn, error
 //	  format: "text"  # text, json
 //
+// Token pricing for usage cost estimates (USD per million tokens; keys are
+// agent IDs, "default" covers the rest):
+//
+//	usage:
+//	  pricing:
+//	    default: {input: 3.00, output: 15.00, cache_read: 0.30}
+//
 // # Validation
 //
 // Validate() checks:
@@ -84,6 +92,12 @@
 //
 // Duration parsing happens during Load() and returns errors for invalid formats.
 //
+// # Reloading
+//
+// Diff compares two configs setting by setting, keyed by YAML path such as
+// "logging.level". Each Change says whether the gateway applies it on reload
+// (see IsDynamic) or it needs a restart.
+//
 // # Usage
 //
 // Load configuration:
@@ -1,12 +1,15 @@
 // ABOUTME: In-memory fan-out event broadcaster for cross-client awareness
-// ABOUTME: Publishes persisted LedgerEvents to all subscribers of a conversation key
+// ABOUTME: Publishes persisted LedgerEvents to subscribers of a conversation key without blocking on slow ones
 
 package conversation
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -14,18 +17,47 @@ import (
 )
 
 const (
-	// subscriberBufferSize is the channel buffer for each subscriber.
+	// subscriberBufferSize bounds the events queued for each subscriber.
 	// Matches the existing chatHub pattern (64 events).
 	subscriberBufferSize = 64
+
+	// maxConsecutiveOverflows is how many publishes in a row may overflow a
+	// subscriber's buffer, without it taking a single event in between,
+	// before the subscriber is unsubscribed.
+	maxConsecutiveOverflows = 256
 )
 
+// ErrSlowSubscriber is reported by Subscription.Err for a subscription the
+// broadcaster dropped because it stopped taking events.
+var ErrSlowSubscriber = errors.New("subscriber unsubscribed after repeated buffer overflows")
+
 // EventBroadcaster provides in-memory pub/sub for persisted LedgerEvents.
 // Subscribers register for a conversation key (agent_id) and receive events
 // as they are persisted. This enables cross-client awareness without polling.
+//
+// Publish never blocks: each subscriber has a bounded buffer, and when it is
+// full the oldest queued event is dropped and the subscriber is sent a lag
+// marker in its place. A subscriber that keeps overflowing is unsubscribed.
 type EventBroadcaster struct {
 	mu          sync.RWMutex
-	subscribers map[string]map[string]chan *store.LedgerEvent // conversationKey -> subID -> ch
+	subscribers map[string]map[string]*Subscription // conversationKey -> subID -> subscription
 	logger      *slog.Logger
+
+	// bufferSize and maxOverflows are subscriberBufferSize and
+	// maxConsecutiveOverflows outside of tests.
+	bufferSize   int
+	maxOverflows int
+
+	// dropped and slowUnsubscribes count events dropped from full buffers
+	// and subscribers removed for overflowing, for metrics.
+	dropped          atomic.Uint64
+	slowUnsubscribes atomic.Uint64
+
+	// Replay buffers for resumable SSE streams, see replay.go.
+	requestMu        sync.Mutex
+	requests         map[string]*requestStream // requestID -> stream
+	requestRetention time.Duration
+	now              func() time.Time
 }
 
 // NewEventBroadcaster creates a broadcaster. Pass nil logger for default.
@@ -34,43 +66,167 @@ func NewEventBroadcaster(logger *slog.Logger) *EventBroadcaster {
 		logger = slog.Default()
 	}
 	return &EventBroadcaster{
-		subscribers: make(map[string]map[string]chan *store.LedgerEvent),
-		logger:      logger.With("component", "broadcaster"),
+		subscribers:      make(map[string]map[string]*Subscription),
+		logger:           logger.With("component", "broadcaster"),
+		bufferSize:       subscriberBufferSize,
+		maxOverflows:     maxConsecutiveOverflows,
+		requests:         make(map[string]*requestStream),
+		requestRetention: DefaultRequestRetention,
+		now:              time.Now,
+	}
+}
+
+// BroadcastEvent is one delivery to a subscriber: a persisted event, or a lag
+// marker (Event nil) counting the events dropped just before the next one.
+type BroadcastEvent struct {
+	Event  *store.LedgerEvent
+	Lagged int
+}
+
+// Subscription is a subscriber's handle on the events of one conversation key.
+type Subscription struct {
+	// ID identifies the subscription to Publish's excludeSubID and Unsubscribe.
+	ID string
+
+	b    *EventBroadcaster
+	key  string
+	out  chan BroadcastEvent
+	wake chan struct{} // signaled when something is queued
+	done chan struct{} // closed on unsubscribe
+
+	mu        sync.Mutex
+	queue     []*store.LedgerEvent
+	skipped   int   // events dropped since the last lag marker
+	overflows int   // publishes in a row that overflowed the buffer
+	err       error // why the broadcaster ended the subscription
+}
+
+// Events returns the channel events are delivered on. It is closed when the
+// subscription ends; see Err.
+func (s *Subscription) Events() <-chan BroadcastEvent {
+	return s.out
+}
+
+// Err reports ErrSlowSubscriber once the broadcaster has ended the
+// subscription for overflowing, and nil otherwise.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close ends the subscription. Closing it again is a no-op.
+func (s *Subscription) Close() {
+	s.b.Unsubscribe(s.key, s.ID)
+}
+
+// enqueue adds event to the buffer, dropping the oldest queued event if it
+// is full. tooSlow reports that the subscriber has overflowed maxOverflows
+// publishes in a row.
+func (s *Subscription) enqueue(event *store.LedgerEvent, size, maxOverflows int) (dropped, tooSlow bool) {
+	s.mu.Lock()
+	if len(s.queue) >= size {
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		s.skipped++
+		s.overflows++
+		dropped = true
+	}
+	s.queue = append(s.queue, event)
+	tooSlow = s.overflows >= maxOverflows
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return dropped, tooSlow
+}
+
+// next takes the next delivery off the buffer: a lag marker if events were
+// dropped since the last one, else the oldest queued event.
+func (s *Subscription) next() (BroadcastEvent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.skipped > 0 {
+		n := s.skipped
+		s.skipped = 0
+		return BroadcastEvent{Lagged: n}, true
+	}
+	if len(s.queue) == 0 {
+		return BroadcastEvent{}, false
+	}
+	event := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	return BroadcastEvent{Event: event}, true
+}
+
+// run moves queued events to the subscriber as fast as it takes them, until
+// the subscription ends or ctx is canceled.
+func (s *Subscription) run(ctx context.Context) {
+	defer close(s.out)
+	for {
+		select {
+		case <-ctx.Done():
+			s.Close()
+			return
+		case <-s.done:
+			return
+		case <-s.wake:
+		}
+		for {
+			delivery, ok := s.next()
+			if !ok {
+				break
+			}
+			select {
+			case s.out <- delivery:
+				s.mu.Lock()
+				s.overflows = 0
+				s.mu.Unlock()
+			case <-ctx.Done():
+				s.Close()
+				return
+			case <-s.done:
+				return
+			}
+		}
 	}
 }
 
 // Subscribe registers a subscriber for events on the given conversation key.
-// Returns a channel that receives events and a subscription ID for later
-// unsubscription. The subscription is automatically cleaned up when ctx is
-// canceled.
-func (b *EventBroadcaster) Subscribe(ctx context.Context, conversationKey string) (<-chan *store.LedgerEvent, string) {
-	subID := uuid.New().String()
-	ch := make(chan *store.LedgerEvent, subscriberBufferSize)
+// The subscription is automatically cleaned up when ctx is canceled.
+func (b *EventBroadcaster) Subscribe(ctx context.Context, conversationKey string) *Subscription {
+	sub := &Subscription{
+		ID:   uuid.New().String(),
+		b:    b,
+		key:  conversationKey,
+		out:  make(chan BroadcastEvent),
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
 
 	b.mu.Lock()
 	if _, ok := b.subscribers[conversationKey]; !ok {
-		b.subscribers[conversationKey] = make(map[string]chan *store.LedgerEvent)
+		b.subscribers[conversationKey] = make(map[string]*Subscription)
 	}
-	b.subscribers[conversationKey][subID] = ch
+	b.subscribers[conversationKey][sub.ID] = sub
 	b.mu.Unlock()
 
 	b.logger.Debug("subscriber added",
 		"conversation_key", conversationKey,
-		"sub_id", subID)
+		"sub_id", sub.ID)
 
-	// Auto-cleanup on context cancellation
-	go func() {
-		<-ctx.Done()
-		b.Unsubscribe(conversationKey, subID)
-	}()
-
-	return ch, subID
+	go sub.run(ctx)
+	return sub
 }
 
 // Publish sends an event to all subscribers of the given conversation key.
 // If excludeSubID is non-empty, that subscriber is skipped (used to avoid
 // sending events back to the originating client).
-// Non-blocking: events are dropped for subscribers whose channels are full.
+// Never blocks: a subscriber whose buffer is full loses its oldest queued
+// event, and one that keeps overflowing is unsubscribed.
 func (b *EventBroadcaster) Publish(conversationKey string, event *store.LedgerEvent, excludeSubID string) {
 	b.mu.RLock()
 	subs, ok := b.subscribers[conversationKey]
@@ -79,46 +235,57 @@ func (b *EventBroadcaster) Publish(conversationKey string, event *store.LedgerEv
 		return
 	}
 
-	// Copy subscriber channels under read lock to avoid holding lock during sends
-	targets := make([]chan *store.LedgerEvent, 0, len(subs))
-	for id, ch := range subs {
+	// Copy subscribers under read lock to avoid holding lock while queuing
+	targets := make([]*Subscription, 0, len(subs))
+	for id, sub := range subs {
 		if excludeSubID != "" && id == excludeSubID {
 			continue
 		}
-		targets = append(targets, ch)
+		targets = append(targets, sub)
 	}
 	b.mu.RUnlock()
 
-	for _, ch := range targets {
-		select {
-		case ch <- event:
-			// Sent
-		default:
-			// Subscriber channel full — drop event for this subscriber
-			b.logger.Debug("dropped event for slow subscriber",
+	for _, sub := range targets {
+		dropped, tooSlow := sub.enqueue(event, b.bufferSize, b.maxOverflows)
+		if dropped {
+			b.dropped.Add(1)
+			b.logger.Debug("dropped oldest event for slow subscriber",
+				"conversation_key", conversationKey,
+				"sub_id", sub.ID)
+		}
+		if tooSlow && b.remove(conversationKey, sub.ID, ErrSlowSubscriber) {
+			b.slowUnsubscribes.Add(1)
+			b.logger.Warn("unsubscribed slow subscriber",
 				"conversation_key", conversationKey,
-				"event_id", event.ID)
+				"sub_id", sub.ID,
+				"consecutive_overflows", b.maxOverflows)
 		}
 	}
 }
 
 // Unsubscribe removes a subscription and closes its channel.
 func (b *EventBroadcaster) Unsubscribe(conversationKey, subID string) {
+	b.remove(conversationKey, subID, nil)
+}
+
+// remove ends a subscription, recording err as the reason, and reports
+// whether it was still live.
+func (b *EventBroadcaster) remove(conversationKey, subID string, err error) bool {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	subs, ok := b.subscribers[conversationKey]
 	if !ok {
-		return
+		return false
 	}
 
-	ch, exists := subs[subID]
+	sub, exists := subs[subID]
 	if !exists {
-		return
+		return false
 	}
 
 	delete(subs, subID)
-	close(ch)
+	sub.end(err)
 
 	// Clean up empty conversation key entries
 	if len(subs) == 0 {
@@ -128,6 +295,46 @@ func (b *EventBroadcaster) Unsubscribe(conversationKey, subID string) {
 	b.logger.Debug("subscriber removed",
 		"conversation_key", conversationKey,
 		"sub_id", subID)
+	return true
+}
+
+// end records why the subscription ended and stops its delivery goroutine,
+// which closes the Events channel. Callers hold the broadcaster's lock and
+// have just removed the subscription, so it runs once per subscription.
+func (s *Subscription) end(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.queue = nil
+	s.mu.Unlock()
+	close(s.done)
+}
+
+// SubscriberCount returns the number of live subscriptions across all
+// conversation keys.
+func (b *EventBroadcaster) SubscriberCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	n := 0
+	for _, subs := range b.subscribers {
+		n += len(subs)
+	}
+	return n
+}
+
+// BroadcasterStats are the broadcaster's counters, for metrics.
+type BroadcasterStats struct {
+	Subscribers      int    // live subscriptions
+	Dropped          uint64 // events dropped from full subscriber buffers
+	SlowUnsubscribes uint64 // subscribers removed for overflowing too many times in a row
+}
+
+// Stats returns the current subscriber count and the drop counters.
+func (b *EventBroadcaster) Stats() BroadcasterStats {
+	return BroadcasterStats{
+		Subscribers:      b.SubscriberCount(),
+		Dropped:          b.dropped.Load(),
+		SlowUnsubscribes: b.slowUnsubscribes.Load(),
+	}
 }
 
 // Close shuts down the broadcaster and closes all subscriber channels.
@@ -136,8 +343,8 @@ func (b *EventBroadcaster) Close() {
 	defer b.mu.Unlock()
 
 	for convKey, subs := range b.subscribers {
-		for subID, ch := range subs {
-			close(ch)
+		for subID, sub := range subs {
+			sub.end(nil)
 			delete(subs, subID)
 		}
 		delete(b.subscribers, convKey)
@@ -0,0 +1,34 @@
+// ABOUTME: Benchmarks for EventBroadcaster publish fan-out.
+// ABOUTME: Tracks publish cost as the number of subscribers per conversation grows.
+
+package conversation
+
+import (
+	"fmt"
+	"testing"
+)
+
+// BenchmarkBroadcasterPublish measures Publish with subscribers draining concurrently.
+func BenchmarkBroadcasterPublish(b *testing.B) {
+	for _, subs := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("subscribers=%d", subs), func(b *testing.B) {
+			bc := NewEventBroadcaster(nil)
+			defer bc.Close()
+
+			ctx := b.Context()
+			for range subs {
+				sub := bc.Subscribe(ctx, "agent-1")
+				go func() {
+					for range sub.Events() {
+					}
+				}()
+			}
+
+			event := makeEvent("evt-1", "agent-1")
+			b.ReportAllocs()
+			for b.Loop() {
+				bc.Publish("agent-1", event, "")
+			}
+		})
+	}
+}
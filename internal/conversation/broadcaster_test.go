@@ -1,11 +1,13 @@
 // ABOUTME: Tests for EventBroadcaster fan-out pub/sub system
-// ABOUTME: Covers subscribe, publish, unsubscribe, context cancellation, concurrency
+// ABOUTME: Covers subscribe, publish, unsubscribe, context cancellation, concurrency, and slow-subscriber handling
 
 package conversation
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -33,14 +35,14 @@ func TestBroadcaster_SingleSubscriberReceivesEvent(t *testing.T) {
 
 	ctx := t.Context()
 
-	ch, _ := b.Subscribe(ctx, "agent-1")
+	ch := b.Subscribe(ctx, "agent-1").Events()
 
 	event := makeEvent("evt-1", "agent-1")
 	b.Publish("agent-1", event, "")
 
 	select {
 	case received := <-ch:
-		assert.Equal(t, "evt-1", received.ID)
+		assert.Equal(t, "evt-1", received.Event.ID)
 	case <-time.After(time.Second):
 		t.Fatal("timed out waiting for event")
 	}
@@ -52,17 +54,17 @@ func TestBroadcaster_MultipleSubscribersReceiveSameEvent(t *testing.T) {
 
 	ctx := t.Context()
 
-	ch1, _ := b.Subscribe(ctx, "agent-1")
-	ch2, _ := b.Subscribe(ctx, "agent-1")
-	ch3, _ := b.Subscribe(ctx, "agent-1")
+	ch1 := b.Subscribe(ctx, "agent-1").Events()
+	ch2 := b.Subscribe(ctx, "agent-1").Events()
+	ch3 := b.Subscribe(ctx, "agent-1").Events()
 
 	event := makeEvent("evt-2", "agent-1")
 	b.Publish("agent-1", event, "")
 
-	for i, ch := range []<-chan *store.LedgerEvent{ch1, ch2, ch3} {
+	for i, ch := range []<-chan BroadcastEvent{ch1, ch2, ch3} {
 		select {
 		case received := <-ch:
-			assert.Equal(t, "evt-2", received.ID, "subscriber %d got wrong event", i)
+			assert.Equal(t, "evt-2", received.Event.ID, "subscriber %d got wrong event", i)
 		case <-time.After(time.Second):
 			t.Fatalf("subscriber %d timed out", i)
 		}
@@ -75,8 +77,8 @@ func TestBroadcaster_DifferentConversationKeysAreIsolated(t *testing.T) {
 
 	ctx := t.Context()
 
-	ch1, _ := b.Subscribe(ctx, "agent-1")
-	ch2, _ := b.Subscribe(ctx, "agent-2")
+	ch1 := b.Subscribe(ctx, "agent-1").Events()
+	ch2 := b.Subscribe(ctx, "agent-2").Events()
 
 	event := makeEvent("evt-3", "agent-1")
 	b.Publish("agent-1", event, "")
@@ -84,7 +86,7 @@ func TestBroadcaster_DifferentConversationKeysAreIsolated(t *testing.T) {
 	// ch1 should receive the event
 	select {
 	case received := <-ch1:
-		assert.Equal(t, "evt-3", received.ID)
+		assert.Equal(t, "evt-3", received.Event.ID)
 	case <-time.After(time.Second):
 		t.Fatal("subscriber for agent-1 timed out")
 	}
@@ -104,11 +106,12 @@ func TestBroadcaster_ExcludeSubIDSkipsOriginator(t *testing.T) {
 
 	ctx := t.Context()
 
-	ch1, subID1 := b.Subscribe(ctx, "agent-1")
-	ch2, _ := b.Subscribe(ctx, "agent-1")
+	sub1 := b.Subscribe(ctx, "agent-1")
+	ch1 := sub1.Events()
+	ch2 := b.Subscribe(ctx, "agent-1").Events()
 
 	event := makeEvent("evt-4", "agent-1")
-	b.Publish("agent-1", event, subID1)
+	b.Publish("agent-1", event, sub1.ID)
 
 	// ch1 (the excluded subscriber) should NOT receive the event
 	select {
@@ -121,7 +124,7 @@ func TestBroadcaster_ExcludeSubIDSkipsOriginator(t *testing.T) {
 	// ch2 should still receive it
 	select {
 	case received := <-ch2:
-		assert.Equal(t, "evt-4", received.ID)
+		assert.Equal(t, "evt-4", received.Event.ID)
 	case <-time.After(time.Second):
 		t.Fatal("non-excluded subscriber timed out")
 	}
@@ -134,8 +137,8 @@ func TestBroadcaster_SlowConsumerDoesNotBlockPublisher(t *testing.T) {
 	ctx := t.Context()
 
 	// Subscribe but never read from ch1 (slow consumer)
-	_, _ = b.Subscribe(ctx, "agent-1")
-	ch2, _ := b.Subscribe(ctx, "agent-1")
+	_ = b.Subscribe(ctx, "agent-1")
+	ch2 := b.Subscribe(ctx, "agent-1").Events()
 
 	// Publish more events than the buffer size to overflow ch1
 	for i := range 100 {
@@ -162,7 +165,8 @@ func TestBroadcaster_ContextCancellationCleansUp(t *testing.T) {
 	defer b.Close()
 
 	ctx, cancel := context.WithCancel(context.Background())
-	ch, subID := b.Subscribe(ctx, "agent-1")
+	sub := b.Subscribe(ctx, "agent-1")
+	ch, subID := sub.Events(), sub.ID
 
 	// Verify subscription exists
 	b.mu.RLock()
@@ -200,9 +204,10 @@ func TestBroadcaster_ManualUnsubscribe(t *testing.T) {
 
 	ctx := t.Context()
 
-	ch, subID := b.Subscribe(ctx, "agent-1")
+	sub := b.Subscribe(ctx, "agent-1")
+	ch := sub.Events()
 
-	b.Unsubscribe("agent-1", subID)
+	b.Unsubscribe("agent-1", sub.ID)
 
 	// Channel should be closed
 	select {
@@ -223,13 +228,13 @@ func TestBroadcaster_CloseClosesAllSubscriptions(t *testing.T) {
 	ctx1 := t.Context()
 	ctx2 := t.Context()
 
-	ch1, _ := b.Subscribe(ctx1, "agent-1")
-	ch2, _ := b.Subscribe(ctx2, "agent-2")
+	ch1 := b.Subscribe(ctx1, "agent-1").Events()
+	ch2 := b.Subscribe(ctx2, "agent-2").Events()
 
 	b.Close()
 
 	// Both channels should be closed
-	for i, ch := range []<-chan *store.LedgerEvent{ch1, ch2} {
+	for i, ch := range []<-chan BroadcastEvent{ch1, ch2} {
 		select {
 		case _, ok := <-ch:
 			assert.False(t, ok, "channel %d should be closed after Close()", i)
@@ -249,7 +254,7 @@ func TestBroadcaster_ConcurrentPublishSubscribe(t *testing.T) {
 	// Spawn concurrent subscribers
 	for range 10 {
 		wg.Go(func() {
-			ch, _ := b.Subscribe(ctx, "agent-concurrent")
+			ch := b.Subscribe(ctx, "agent-concurrent").Events()
 			// Read a few events then exit
 			for range 5 {
 				select {
@@ -281,9 +286,9 @@ func TestBroadcaster_SubscribeReturnsUniqueIDs(t *testing.T) {
 
 	ctx := t.Context()
 
-	_, id1 := b.Subscribe(ctx, "agent-1")
-	_, id2 := b.Subscribe(ctx, "agent-1")
-	_, id3 := b.Subscribe(ctx, "agent-2")
+	id1 := b.Subscribe(ctx, "agent-1").ID
+	id2 := b.Subscribe(ctx, "agent-1").ID
+	id3 := b.Subscribe(ctx, "agent-2").ID
 
 	require.NotEqual(t, id1, id2)
 	require.NotEqual(t, id1, id3)
@@ -298,3 +303,185 @@ func TestBroadcaster_PublishToNonexistentConversation(t *testing.T) {
 	event := makeEvent("evt-nowhere", "nobody-listening")
 	b.Publish("nobody-listening", event, "")
 }
+
+// receive waits for the next delivery on ch, failing the test on timeout.
+func receive(t *testing.T, ch <-chan BroadcastEvent) BroadcastEvent {
+	t.Helper()
+	select {
+	case d, ok := <-ch:
+		require.True(t, ok, "channel closed")
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for delivery")
+		return BroadcastEvent{}
+	}
+}
+
+func TestBroadcaster_OverflowDropsOldestWithLagMarker(t *testing.T) {
+	b := NewEventBroadcaster(nil)
+	defer b.Close()
+	b.bufferSize = 3
+
+	sub := b.Subscribe(t.Context(), "agent-1")
+	ch := sub.Events()
+
+	// The first event is taken by the delivery goroutine and held until
+	// read; the next three fill the buffer and two more push out the oldest.
+	b.Publish("agent-1", makeEvent("evt-0", "agent-1"), "")
+	require.Eventually(t, func() bool {
+		sub.mu.Lock()
+		defer sub.mu.Unlock()
+		return len(sub.queue) == 0
+	}, time.Second, time.Millisecond)
+	for _, id := range []string{"evt-1", "evt-2", "evt-3", "evt-4", "evt-5"} {
+		b.Publish("agent-1", makeEvent(id, "agent-1"), "")
+	}
+
+	assert.Equal(t, "evt-0", receive(t, ch).Event.ID)
+	marker := receive(t, ch)
+	assert.Nil(t, marker.Event)
+	assert.Equal(t, 2, marker.Lagged)
+	for _, id := range []string{"evt-3", "evt-4", "evt-5"} {
+		assert.Equal(t, id, receive(t, ch).Event.ID)
+	}
+	assert.Equal(t, uint64(2), b.Stats().Dropped)
+	require.NoError(t, sub.Err())
+}
+
+func TestBroadcaster_UnsubscribesAfterConsecutiveOverflows(t *testing.T) {
+	b := NewEventBroadcaster(nil)
+	defer b.Close()
+	b.bufferSize = 2
+	b.maxOverflows = 5
+
+	sub := b.Subscribe(t.Context(), "agent-1")
+
+	// Never read: the first event is held by the delivery goroutine, two fill
+	// the buffer, and each publish after that overflows it.
+	for i := range 8 {
+		b.Publish("agent-1", makeEvent(fmt.Sprintf("evt-%d", i), "agent-1"), "")
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case _, ok := <-sub.Events():
+		if ok {
+			// The held event may still be delivered before the close.
+			_, ok = <-sub.Events()
+		}
+		assert.False(t, ok, "channel should be closed for a slow subscriber")
+	case <-time.After(time.Second):
+		t.Fatal("slow subscriber was not unsubscribed")
+	}
+	require.ErrorIs(t, sub.Err(), ErrSlowSubscriber)
+
+	stats := b.Stats()
+	assert.Equal(t, 0, stats.Subscribers)
+	assert.Equal(t, uint64(1), stats.SlowUnsubscribes)
+	assert.GreaterOrEqual(t, stats.Dropped, uint64(5))
+}
+
+func TestBroadcaster_ReadingResetsOverflowCount(t *testing.T) {
+	b := NewEventBroadcaster(nil)
+	defer b.Close()
+	b.bufferSize = 1
+	b.maxOverflows = 3
+
+	sub := b.Subscribe(t.Context(), "agent-1")
+	ch := sub.Events()
+
+	// Two overflows, then the subscriber catches up; two more must not
+	// add up to an unsubscribe.
+	for round := range 2 {
+		b.Publish("agent-1", makeEvent(fmt.Sprintf("r%d-held", round), "agent-1"), "")
+		require.Eventually(t, func() bool {
+			sub.mu.Lock()
+			defer sub.mu.Unlock()
+			return len(sub.queue) == 0
+		}, time.Second, time.Millisecond)
+		for i := range 3 {
+			b.Publish("agent-1", makeEvent(fmt.Sprintf("r%d-%d", round, i), "agent-1"), "")
+		}
+		assert.Equal(t, fmt.Sprintf("r%d-held", round), receive(t, ch).Event.ID)
+		assert.Equal(t, 2, receive(t, ch).Lagged)
+		assert.Equal(t, fmt.Sprintf("r%d-2", round), receive(t, ch).Event.ID)
+	}
+	require.NoError(t, sub.Err())
+	assert.Equal(t, 1, b.SubscriberCount())
+}
+
+// TestBroadcaster_FastAndSlowSubscribers publishes a stream of events to one
+// subscriber that keeps up and one that stalls. The fast one gets every event
+// in order, the slow one gets lag markers accounting for every event it
+// missed, and publishing never waits on the slow one. Run with -race.
+func TestBroadcaster_FastAndSlowSubscribers(t *testing.T) {
+	b := NewEventBroadcaster(nil)
+	defer b.Close()
+	b.maxOverflows = 1 << 30 // stalls in this test are deliberate
+
+	const total = 5000
+	fast := b.Subscribe(t.Context(), "agent-1")
+	slow := b.Subscribe(t.Context(), "agent-1")
+
+	var wg sync.WaitGroup
+	var fastIDs []string
+	var fastCount atomic.Int64
+	wg.Go(func() {
+		for d := range fast.Events() {
+			if d.Lagged > 0 {
+				t.Errorf("fast subscriber lagged by %d", d.Lagged)
+				return
+			}
+			fastIDs = append(fastIDs, d.Event.ID)
+			if fastCount.Add(1) == total {
+				return
+			}
+		}
+	})
+	var slowEvents, slowSkipped int
+	wg.Go(func() {
+		for d := range slow.Events() {
+			time.Sleep(time.Millisecond)
+			if d.Lagged > 0 {
+				slowSkipped += d.Lagged
+			} else {
+				slowEvents++
+			}
+			if slowEvents+slowSkipped == total {
+				return
+			}
+		}
+	})
+
+	// Publish in batches of half the fast subscriber's buffer, waiting for it
+	// (never the slow one) to catch up in between.
+	start := time.Now()
+	for i := range total {
+		b.Publish("agent-1", makeEvent(fmt.Sprintf("evt-%05d", i), "agent-1"), "")
+		if (i+1)%(subscriberBufferSize/2) == 0 {
+			require.Eventually(t, func() bool { return fastCount.Load() > int64(i)-subscriberBufferSize/2 },
+				5*time.Second, 10*time.Microsecond, "fast subscriber stalled")
+		}
+	}
+	publishTime := time.Since(start)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatalf("subscribers did not finish: fast %d, slow %d events + %d skipped", fastCount.Load(), slowEvents, slowSkipped)
+	}
+
+	require.Len(t, fastIDs, total)
+	for i, id := range fastIDs {
+		require.Equal(t, fmt.Sprintf("evt-%05d", i), id, "fast subscriber out of order")
+	}
+	assert.Equal(t, total, slowEvents+slowSkipped, "slow subscriber's events and lag markers should account for every publish")
+	assert.Positive(t, slowSkipped, "slow subscriber should have lagged")
+	assert.Equal(t, uint64(slowSkipped), b.Stats().Dropped)
+	assert.Less(t, publishTime, time.Duration(total)*time.Millisecond/2, "publishing should not wait on the slow subscriber")
+}
@@ -0,0 +1,92 @@
+// ABOUTME: Tracks sends still streaming so they can be stopped by request ID from any client
+// ABOUTME: Canceling one cancels its send context, which has the agent manager cancel it at the agent
+
+package conversation
+
+import (
+	"context"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/coverr"
+)
+
+// ErrRequestNotInFlight is returned when canceling a request that finished
+// or never existed.
+var ErrRequestNotInFlight = coverr.New(coverr.NotFound, "request not in flight")
+
+// InFlightRequest describes a send still streaming its response.
+type InFlightRequest struct {
+	RequestID        string // the user message ID, as in SendResponse.MessageID
+	AgentID          string
+	ThreadID         string
+	ActorPrincipalID string // who sent it, if known
+}
+
+// inFlightSend is a tracked send and the cancel function of its context.
+type inFlightSend struct {
+	InFlightRequest
+	cancel context.CancelCauseFunc
+}
+
+// trackSend registers a send under its request ID until untrackSend.
+func (s *Service) trackSend(req InFlightRequest, cancel context.CancelCauseFunc) {
+	s.inFlightMu.Lock()
+	defer s.inFlightMu.Unlock()
+	if s.inFlight == nil {
+		s.inFlight = make(map[string]*inFlightSend)
+	}
+	s.inFlight[req.RequestID] = &inFlightSend{InFlightRequest: req, cancel: cancel}
+}
+
+// untrackSend forgets a send once its response has been persisted.
+func (s *Service) untrackSend(requestID string) {
+	s.inFlightMu.Lock()
+	delete(s.inFlight, requestID)
+	s.inFlightMu.Unlock()
+}
+
+// InFlight returns the send with requestID if its response is still
+// streaming.
+func (s *Service) InFlight(requestID string) (InFlightRequest, bool) {
+	s.inFlightMu.Lock()
+	defer s.inFlightMu.Unlock()
+	send, ok := s.inFlight[requestID]
+	if !ok {
+		return InFlightRequest{}, false
+	}
+	return send.InFlightRequest, true
+}
+
+// InFlightOnThread returns a send to agentID on threadID whose response is
+// still streaming, if there is one.
+func (s *Service) InFlightOnThread(agentID, threadID string) (InFlightRequest, bool) {
+	s.inFlightMu.Lock()
+	defer s.inFlightMu.Unlock()
+	for _, send := range s.inFlight {
+		if send.AgentID == agentID && send.ThreadID == threadID {
+			return send.InFlightRequest, true
+		}
+	}
+	return InFlightRequest{}, false
+}
+
+// CancelRequest stops the send with requestID: the agent is told to cancel
+// it, its stream ends with a canceled event giving reason, and anything the
+// agent sends for it afterwards is dropped. It returns ErrRequestNotInFlight
+// if the response already finished.
+func (s *Service) CancelRequest(requestID, reason string) error {
+	s.inFlightMu.Lock()
+	send, ok := s.inFlight[requestID]
+	delete(s.inFlight, requestID)
+	s.inFlightMu.Unlock()
+	if !ok {
+		return ErrRequestNotInFlight
+	}
+
+	s.logger.Info("canceling request",
+		"request_id", requestID,
+		"agent_id", send.AgentID,
+		"reason", reason)
+	send.cancel(agent.CancelCause(reason))
+	return nil
+}
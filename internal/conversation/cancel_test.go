@@ -0,0 +1,71 @@
+// ABOUTME: Tests for canceling in-flight sends by request ID
+// ABOUTME: Verifies tracking, the cancel cause handed to the sender, and untracking once finished
+
+package conversation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/2389/coven-gateway/internal/agent"
+)
+
+// cancelAwareSender answers like the agent manager does when its send
+// context is canceled: with a canceled event giving the cause.
+type cancelAwareSender struct{}
+
+func (cancelAwareSender) SendMessage(ctx context.Context, _ *agent.SendRequest) (<-chan *agent.Response, error) {
+	ch := make(chan *agent.Response, 1)
+	go func() {
+		defer close(ch)
+		<-ctx.Done()
+		ch <- &agent.Response{Event: agent.EventCanceled, Error: context.Cause(ctx).Error(), Done: true}
+	}()
+	return ch, nil
+}
+
+func TestService_CancelRequest(t *testing.T) {
+	svc := New(createTestStore(t), cancelAwareSender{}, nil, nil)
+
+	resp, err := svc.SendMessage(context.Background(), &SendRequest{
+		AgentID: "test-agent", Sender: "user", Content: "run forever", ActorPrincipalID: "principal-1",
+	})
+	require.NoError(t, err)
+
+	req, ok := svc.InFlight(resp.MessageID)
+	require.True(t, ok, "send should be in flight")
+	assert.Equal(t, InFlightRequest{
+		RequestID: resp.MessageID, AgentID: "test-agent", ThreadID: resp.ThreadID, ActorPrincipalID: "principal-1",
+	}, req)
+
+	require.NoError(t, svc.CancelRequest(resp.MessageID, agent.UserRequestedReason))
+
+	var last *agent.Response
+	for r := range resp.Stream {
+		last = r
+	}
+	require.NotNil(t, last)
+	assert.Equal(t, agent.EventCanceled, last.Event)
+	assert.Equal(t, agent.CancelCause(agent.UserRequestedReason).Error(), last.Error)
+
+	_, ok = svc.InFlight(resp.MessageID)
+	assert.False(t, ok, "canceled send should no longer be in flight")
+	assert.ErrorIs(t, svc.CancelRequest(resp.MessageID, agent.UserRequestedReason), ErrRequestNotInFlight)
+}
+
+func TestService_FinishedSendIsNotInFlight(t *testing.T) {
+	sender := &mockSender{responses: []*agent.Response{{Event: agent.EventDone, Text: "hi", Done: true}}}
+	svc := New(createTestStore(t), sender, nil, nil)
+
+	resp, err := svc.SendMessage(context.Background(), &SendRequest{AgentID: "test-agent", Sender: "user", Content: "hi"})
+	require.NoError(t, err)
+	for range resp.Stream {
+	}
+
+	_, ok := svc.InFlight(resp.MessageID)
+	assert.False(t, ok)
+	assert.ErrorIs(t, svc.CancelRequest(resp.MessageID, agent.UserRequestedReason), ErrRequestNotInFlight)
+}
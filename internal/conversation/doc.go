@@ -34,11 +34,26 @@
 //  3. Route the message to the thread's agent
 //  4. Store the exchange in the ledger
 //
+// A message to an archived thread unarchives it first, recording a system
+// event in the thread's ledger.
+//
+// Steps 1, 2 and the user message's ledger event, including any unarchive,
+// run in one store transaction (Store.WithTx) together with the message's
+// attachments, so a failure part-way cannot leave a new thread with no
+// message in it or a message missing its attachments.
+//
 // # Event Broadcasting
 //
 // The service broadcasts response events for real-time updates:
 //
-//	svc.Subscribe(threadID) -> <-chan Event
+//	sub := svc.Subscribe(ctx, conversationKey)
+//	for ev := range sub.Events() { ... } // ev.Event, or ev.Lagged on a lag marker
+//
+// Publishing never waits for subscribers. Each has a bounded buffer; when it
+// fills, the oldest queued event is dropped and the subscriber gets a lag
+// marker counting the dropped events. A subscriber that overflows too many
+// publishes in a row is unsubscribed, its channel closed and Err reporting
+// ErrSlowSubscriber.
 //
 // Events include:
 //   - Thinking: Agent is processing
@@ -0,0 +1,230 @@
+// ABOUTME: Per-request replay buffers on the EventBroadcaster for resumable SSE streams
+// ABOUTME: Each request keeps a ring of its recent events, numbered so clients can resume by Last-Event-ID
+
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultRequestRetention is how long a finished request's events stay
+	// available for replay.
+	DefaultRequestRetention = 5 * time.Minute
+
+	// requestBufferSize is the number of recent events kept per request.
+	requestBufferSize = 1024
+)
+
+// ErrRequestOrphaned is returned when a request's events can no longer be
+// replayed: the request finished longer ago than the retention window, the
+// events the client missed have been overwritten, or the request is unknown.
+var ErrRequestOrphaned = errors.New("request stream orphaned")
+
+// RequestEvent is one SSE event recorded for a request. IDs start at 1 and
+// increase by one per event.
+type RequestEvent struct {
+	ID    uint64
+	Event string
+	Data  json.RawMessage
+}
+
+// RequestOwner records who a request's stream belongs to, so that resuming
+// it can be limited to its sender.
+type RequestOwner struct {
+	PrincipalID      string // the sender; empty when auth is disabled
+	AgentID          string // the answering agent's connection ID
+	AgentPrincipalID string // the answering agent's principal, if known
+}
+
+// requestStream is the replay buffer for one request.
+type requestStream struct {
+	ring     []RequestEvent // at most requestBufferSize events
+	head     int            // index of the oldest event once the ring is full
+	lastID   uint64
+	owner    RequestOwner
+	finished time.Time     // zero while the request is running
+	changed  chan struct{} // closed and replaced on every append or finish
+}
+
+// since returns the buffered events after id. ok is false when some of
+// those events have already been overwritten.
+func (s *requestStream) since(id uint64) (events []RequestEvent, ok bool) {
+	if id >= s.lastID {
+		return nil, true
+	}
+	oldest := s.lastID - uint64(len(s.ring)) + 1
+	if id+1 < oldest {
+		return nil, false
+	}
+	for i := range s.ring {
+		ev := s.ring[(s.head+i)%len(s.ring)]
+		if ev.ID > id {
+			events = append(events, ev)
+		}
+	}
+	return events, true
+}
+
+// append adds an event to the ring and returns its ID.
+func (s *requestStream) append(event string, data json.RawMessage) uint64 {
+	s.lastID++
+	ev := RequestEvent{ID: s.lastID, Event: event, Data: data}
+	if len(s.ring) < requestBufferSize {
+		s.ring = append(s.ring, ev)
+	} else {
+		s.ring[s.head] = ev
+		s.head = (s.head + 1) % len(s.ring)
+	}
+	s.notify()
+	return ev.ID
+}
+
+func (s *requestStream) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// SetRequestRetention sets how long finished requests stay replayable.
+func (b *EventBroadcaster) SetRequestRetention(d time.Duration) {
+	b.requestMu.Lock()
+	defer b.requestMu.Unlock()
+	b.requestRetention = d
+}
+
+// RecordRequestEvent appends an event to the request's replay buffer and
+// returns its ID. The buffer is created on the first event.
+func (b *EventBroadcaster) RecordRequestEvent(requestID, event string, data json.RawMessage) uint64 {
+	b.requestMu.Lock()
+	defer b.requestMu.Unlock()
+	return b.streamLocked(requestID).append(event, data)
+}
+
+// SetRequestOwner records who the request's stream belongs to, creating its
+// replay buffer if there is none yet.
+func (b *EventBroadcaster) SetRequestOwner(requestID string, owner RequestOwner) {
+	b.requestMu.Lock()
+	defer b.requestMu.Unlock()
+	b.streamLocked(requestID).owner = owner
+}
+
+// RequestOwner returns the owner recorded for the request's stream. ok is
+// false when the stream is unknown or no longer replayable.
+func (b *EventBroadcaster) RequestOwner(requestID string) (owner RequestOwner, ok bool) {
+	b.requestMu.Lock()
+	defer b.requestMu.Unlock()
+
+	s, ok := b.requests[requestID]
+	if !ok || b.expiredLocked(s) {
+		return RequestOwner{}, false
+	}
+	return s.owner, true
+}
+
+// streamLocked returns the request's replay buffer, creating it if needed.
+// The caller must hold requestMu.
+func (b *EventBroadcaster) streamLocked(requestID string) *requestStream {
+	s, ok := b.requests[requestID]
+	if !ok {
+		b.pruneRequestsLocked()
+		s = &requestStream{changed: make(chan struct{})}
+		b.requests[requestID] = s
+	}
+	return s
+}
+
+// RecordRunningRequestEvent appends an event to the request's replay buffer
+// if the request has one and has not finished, and reports whether it did.
+// It is for events raised outside the request's own response stream.
+func (b *EventBroadcaster) RecordRunningRequestEvent(requestID, event string, data json.RawMessage) bool {
+	b.requestMu.Lock()
+	defer b.requestMu.Unlock()
+
+	s, ok := b.requests[requestID]
+	if !ok || !s.finished.IsZero() {
+		return false
+	}
+	s.append(event, data)
+	return true
+}
+
+// FinishRequest marks the request complete. Its events stay replayable for
+// the retention window.
+func (b *EventBroadcaster) FinishRequest(requestID string) {
+	b.requestMu.Lock()
+	defer b.requestMu.Unlock()
+
+	s, ok := b.requests[requestID]
+	if !ok || !s.finished.IsZero() {
+		return
+	}
+	s.finished = b.now()
+	s.notify()
+}
+
+// WaitRequestEvents returns the request's events after lastEventID, blocking
+// until there is at least one or the request finishes. done reports that the
+// request has finished and no events remain after those returned. It returns
+// ErrRequestOrphaned when the missed events cannot be replayed, and the
+// context's error if ctx ends while waiting.
+func (b *EventBroadcaster) WaitRequestEvents(ctx context.Context, requestID string, lastEventID uint64) (events []RequestEvent, done bool, err error) {
+	for {
+		b.requestMu.Lock()
+		s, ok := b.requests[requestID]
+		if !ok || b.expiredLocked(s) {
+			b.requestMu.Unlock()
+			return nil, false, ErrRequestOrphaned
+		}
+		missed, ok := s.since(lastEventID)
+		finished := !s.finished.IsZero()
+		changed := s.changed
+		b.requestMu.Unlock()
+
+		if !ok {
+			return nil, false, ErrRequestOrphaned
+		}
+		if len(missed) > 0 || finished {
+			return missed, finished, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+// CanReplayRequest reports whether the request's stream is still buffered
+// from its first event, so a client can be served the whole of it again.
+func (b *EventBroadcaster) CanReplayRequest(requestID string) bool {
+	b.requestMu.Lock()
+	defer b.requestMu.Unlock()
+
+	s, ok := b.requests[requestID]
+	if !ok || b.expiredLocked(s) {
+		return false
+	}
+	_, ok = s.since(0)
+	return ok
+}
+
+// expiredLocked reports whether s finished before the retention window.
+// The caller must hold requestMu.
+func (b *EventBroadcaster) expiredLocked(s *requestStream) bool {
+	return !s.finished.IsZero() && s.finished.Before(b.now().Add(-b.requestRetention))
+}
+
+// pruneRequestsLocked drops expired requests. It runs when a new request
+// starts recording, so the map stays bounded by the requests of the last
+// retention window. The caller must hold requestMu.
+func (b *EventBroadcaster) pruneRequestsLocked() {
+	for id, s := range b.requests {
+		if b.expiredLocked(s) {
+			delete(b.requests, id)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// ABOUTME: Tests for the per-request replay buffers behind resumable SSE streams
+// ABOUTME: Covers resuming by event ID, stream owners, waiting for live events, events for running requests, retention expiry, and ring overflow
+
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func eventIDs(events []RequestEvent) []uint64 {
+	ids := make([]uint64, len(events))
+	for i, ev := range events {
+		ids[i] = ev.ID
+	}
+	return ids
+}
+
+func TestRequestReplay_ResumesAfterLastEventID(t *testing.T) {
+	b := NewEventBroadcaster(nil)
+	ctx := t.Context()
+
+	for i := 1; i <= 3; i++ {
+		id := b.RecordRequestEvent("req-1", "text", json.RawMessage(fmt.Sprintf(`{"text":"%d"}`, i)))
+		assert.Equal(t, uint64(i), id)
+	}
+	assert.Equal(t, uint64(1), b.RecordRequestEvent("req-2", "text", json.RawMessage(`{}`)), "IDs are per request")
+
+	events, done, err := b.WaitRequestEvents(ctx, "req-1", 1)
+	require.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, []uint64{2, 3}, eventIDs(events))
+	assert.JSONEq(t, `{"text":"2"}`, string(events[0].Data))
+
+	b.FinishRequest("req-1")
+	events, done, err = b.WaitRequestEvents(ctx, "req-1", 3)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Empty(t, events)
+}
+
+func TestRequestReplay_RecordRunningRequestEvent(t *testing.T) {
+	b := NewEventBroadcaster(nil)
+
+	assert.False(t, b.RecordRunningRequestEvent("req-1", "question", json.RawMessage(`{}`)), "unknown request")
+	_, _, err := b.WaitRequestEvents(t.Context(), "req-1", 0)
+	assert.ErrorIs(t, err, ErrRequestOrphaned, "no buffer is created for an unknown request")
+
+	b.RecordRequestEvent("req-1", "started", json.RawMessage(`{}`))
+	assert.True(t, b.RecordRunningRequestEvent("req-1", "question", json.RawMessage(`{}`)))
+	b.FinishRequest("req-1")
+	assert.False(t, b.RecordRunningRequestEvent("req-1", "question", json.RawMessage(`{}`)), "finished request")
+
+	events, done, err := b.WaitRequestEvents(t.Context(), "req-1", 0)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, []uint64{1, 2}, eventIDs(events))
+	assert.Equal(t, "question", events[1].Event)
+}
+
+func TestRequestReplay_Owner(t *testing.T) {
+	b := NewEventBroadcaster(nil)
+
+	_, ok := b.RequestOwner("req-1")
+	assert.False(t, ok, "unknown request")
+
+	owner := RequestOwner{PrincipalID: "alice", AgentID: "conn-1", AgentPrincipalID: "agent-1"}
+	b.SetRequestOwner("req-1", owner)
+	b.RecordRequestEvent("req-1", "started", json.RawMessage(`{}`))
+	got, ok := b.RequestOwner("req-1")
+	require.True(t, ok)
+	assert.Equal(t, owner, got)
+
+	b.FinishRequest("req-1")
+	b.SetRequestRetention(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	_, ok = b.RequestOwner("req-1")
+	assert.False(t, ok, "expired request")
+}
+
+func TestRequestReplay_WaitsForLiveEvents(t *testing.T) {
+	b := NewEventBroadcaster(nil)
+	b.RecordRequestEvent("req-1", "started", json.RawMessage(`{}`))
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.RecordRequestEvent("req-1", "text", json.RawMessage(`{}`))
+	}()
+
+	events, _, err := b.WaitRequestEvents(t.Context(), "req-1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2}, eventIDs(events))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	_, _, err = b.WaitRequestEvents(ctx, "req-1", 2)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRequestReplay_OrphanedAfterRetention(t *testing.T) {
+	b := NewEventBroadcaster(nil)
+	b.SetRequestRetention(time.Minute)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	b.RecordRequestEvent("req-1", "done", json.RawMessage(`{}`))
+	b.FinishRequest("req-1")
+
+	now = now.Add(30 * time.Second)
+	events, done, err := b.WaitRequestEvents(t.Context(), "req-1", 0)
+	require.NoError(t, err, "still inside the retention window")
+	assert.True(t, done)
+	assert.Len(t, events, 1)
+	assert.True(t, b.CanReplayRequest("req-1"))
+
+	now = now.Add(time.Minute)
+	_, _, err = b.WaitRequestEvents(t.Context(), "req-1", 0)
+	assert.ErrorIs(t, err, ErrRequestOrphaned)
+	assert.False(t, b.CanReplayRequest("req-1"))
+
+	b.RecordRequestEvent("req-2", "started", json.RawMessage(`{}`))
+	assert.NotContains(t, b.requests, "req-1", "expired requests are pruned")
+
+	_, _, err = b.WaitRequestEvents(t.Context(), "unknown", 0)
+	assert.ErrorIs(t, err, ErrRequestOrphaned)
+	assert.False(t, b.CanReplayRequest("unknown"))
+}
+
+func TestRequestReplay_RingOverflow(t *testing.T) {
+	b := NewEventBroadcaster(nil)
+	total := requestBufferSize + 10
+	for range total {
+		b.RecordRequestEvent("req-1", "text", json.RawMessage(`{}`))
+	}
+
+	_, _, err := b.WaitRequestEvents(t.Context(), "req-1", 5)
+	assert.ErrorIs(t, err, ErrRequestOrphaned, "events 6-10 were overwritten")
+	assert.False(t, b.CanReplayRequest("req-1"), "the first events were overwritten")
+
+	events, _, err := b.WaitRequestEvents(t.Context(), "req-1", 10)
+	require.NoError(t, err)
+	require.Len(t, events, requestBufferSize)
+	assert.Equal(t, uint64(11), events[0].ID)
+	assert.Equal(t, uint64(total), events[len(events)-1].ID)
+}
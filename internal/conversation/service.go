@@ -8,27 +8,55 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/logctx"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/tracing"
 )
 
+// tracer records the steps of a send: thread lookup, ledger writes, and the
+// agent round trip.
+var tracer = otel.Tracer("github.com/2389/coven-gateway/internal/conversation")
+
 // ConversationStore defines what the service needs from storage.
 type ConversationStore interface {
+	threadStore
+	GetEventsByThreadID(ctx context.Context, threadID string, limit int) ([]*store.LedgerEvent, error)
+
+	// WithTx runs a send's thread resolution and user message in one
+	// transaction.
+	WithTx(ctx context.Context, fn func(tx store.Store) error) error
+
+	// Token usage tracking
+	SaveUsage(ctx context.Context, usage *store.TokenUsage) error
+	LinkUsageToMessage(ctx context.Context, requestID, messageID string) error
+}
+
+// threadStore is what a send resolves its thread and records its user
+// message through: the store, or the transaction WithTx hands out.
+type threadStore interface {
 	CreateThread(ctx context.Context, thread *store.Thread) error
 	GetThread(ctx context.Context, id string) (*store.Thread, error)
 	GetThreadByFrontendID(ctx context.Context, frontendName, externalID string) (*store.Thread, error)
+	ArchiveThread(ctx context.Context, id string, archived bool) error
 
 	// Ledger events (unified message storage)
 	SaveEvent(ctx context.Context, event *store.LedgerEvent) error
-	GetEventsByThreadID(ctx context.Context, threadID string, limit int) ([]*store.LedgerEvent, error)
+}
 
-	// Token usage tracking
-	SaveUsage(ctx context.Context, usage *store.TokenUsage) error
-	LinkUsageToMessage(ctx context.Context, requestID, messageID string) error
+// AttachmentStore stores files sent with messages and returned by agents.
+type AttachmentStore interface {
+	SaveAttachment(ctx context.Context, a *store.Attachment) error
 }
 
 // MessageSender defines what the service needs from the agent layer.
@@ -43,6 +71,19 @@ type Service struct {
 	sender      MessageSender
 	broadcaster *EventBroadcaster
 	logger      *slog.Logger
+
+	// writeGuard, if set, is consulted before a send writes anything; a
+	// non-nil error rejects the send (e.g. storage below its hard floor).
+	writeGuard func() error
+
+	// attachments, if set, keeps a copy of every attachment and agent file
+	// so clients can download them later.
+	attachments AttachmentStore
+
+	// inFlight holds the sends still streaming, by request ID, so they can
+	// be canceled; see CancelRequest.
+	inFlightMu sync.Mutex
+	inFlight   map[string]*inFlightSend
 }
 
 // New creates a new ConversationService.
@@ -60,6 +101,20 @@ func New(store ConversationStore, sender MessageSender, logger *slog.Logger, bro
 	}
 }
 
+// SetWriteGuard installs a check that runs before every send. Reads are
+// never guarded. Call during setup, before the service handles traffic.
+func (s *Service) SetWriteGuard(guard func() error) {
+	s.writeGuard = guard
+}
+
+// SetAttachmentStore makes the service store message attachments and agent
+// files, giving each an ID. A send's attachments are written in the send's
+// transaction when the conversation store can hold them, as an *store.SQLStore
+// can. Call during setup, before the service handles traffic.
+func (s *Service) SetAttachmentStore(a AttachmentStore) {
+	s.attachments = a
+}
+
 // SendRequest contains everything needed to send a message through the conversation layer.
 type SendRequest struct {
 	// Thread identification (provide ThreadID directly, or FrontendName+ExternalID for lookup)
@@ -69,18 +124,46 @@ type SendRequest struct {
 
 	// Agent routing (required)
 	AgentID string
+	// Workspace, if set, is the agent workspace to handle the message in;
+	// see agent.SendRequest.
+	Workspace string
+	// ChannelContext is the binding's channel guidance, sent to the agent
+	// alongside the message. The user message's ledger event records its hash.
+	ChannelContext string
 
 	// Message content
 	Sender      string
 	Content     string
 	Attachments []agent.Attachment
+
+	// MaxDuration caps how long the agent may respond; zero uses the gateway default.
+	MaxDuration time.Duration
+
+	// OnQueued, if set, is told the message's queue position when the agent
+	// is at its concurrency limit; see agent.SendRequest.
+	OnQueued func(position int)
+
+	// Transport and PayloadRef, if set, are recorded on the user message's
+	// ledger event as its raw transport and raw payload reference.
+	Transport  string
+	PayloadRef string
+
+	// ActorPrincipalID, if set, is recorded as the user message's actor and
+	// as the uploader of its attachments.
+	ActorPrincipalID string
+
+	// ParentRequestID, if set, is the request that delegated this message;
+	// it is recorded on the user message's ledger event.
+	ParentRequestID string
 }
 
 // SendResponse contains the result of sending a message.
 type SendResponse struct {
-	ThreadID  string                 // The thread this message belongs to
-	MessageID string                 // ID of the saved user message
-	Stream    <-chan *agent.Response // Responses flow through here (and get persisted)
+	ThreadID      string                 // The thread this message belongs to
+	ThreadCreated bool                   // True if this message started a new thread
+	MessageID     string                 // ID of the saved user message
+	Attachments   []agent.Attachment     // the message's attachments, with IDs once stored
+	Stream        <-chan *agent.Response // Responses flow through here (and get persisted)
 }
 
 // SendMessage records the user message, sends to the agent, and returns a channel
@@ -90,30 +173,20 @@ type SendResponse struct {
 // BEFORE being sent to the agent. This ensures we have a record even if the agent fails.
 func (s *Service) SendMessage(ctx context.Context, req *SendRequest) (*SendResponse, error) {
 	if req.AgentID == "" {
-		return nil, errors.New("agent_id is required")
+		return nil, coverr.New(coverr.InvalidRequest, "agent_id is required")
 	}
-
-	// 1. Resolve or create thread
-	thread, err := s.ensureThread(ctx, req)
-	if err != nil {
-		return nil, fmt.Errorf("thread resolution failed: %w", err)
+	if s.writeGuard != nil {
+		if err := s.writeGuard(); err != nil {
+			return nil, err
+		}
 	}
 
-	// 2. Record user message FIRST (source of truth in ledger_events)
-	now := time.Now()
+	// 1-2. Resolve or create the thread and record the user message FIRST
+	// (source of truth in ledger_events)
 	messageID := uuid.New().String()
-	userEvent := &store.LedgerEvent{
-		ID:              messageID,
-		ConversationKey: req.AgentID,
-		ThreadID:        &thread.ID,
-		Direction:       store.EventDirectionInbound,
-		Author:          req.Sender,
-		Timestamp:       now,
-		Type:            store.EventTypeMessage,
-		Text:            &req.Content,
-	}
-	if err := s.store.SaveEvent(ctx, userEvent); err != nil {
-		return nil, fmt.Errorf("failed to record message: %w", err)
+	thread, created, userEvent, attachments, err := s.recordUserMessage(ctx, req, messageID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Broadcast user message to other clients watching this conversation
@@ -121,41 +194,191 @@ func (s *Service) SendMessage(ctx context.Context, req *SendRequest) (*SendRespo
 		s.broadcaster.Publish(req.AgentID, userEvent, "")
 	}
 
-	s.logger.Debug("user message recorded",
+	logctx.FromContext(ctx, s.logger).Debug("user message recorded",
 		"thread_id", thread.ID,
 		"message_id", messageID,
 		"sender", req.Sender)
 
 	// 3. Send to agent
 	agentReq := &agent.SendRequest{
-		ThreadID:    thread.ID,
-		Sender:      req.Sender,
-		Content:     req.Content,
-		Attachments: req.Attachments,
-		AgentID:     req.AgentID,
-	}
+		ThreadID:       thread.ID,
+		Sender:         req.Sender,
+		Content:        req.Content,
+		Attachments:    attachments,
+		AgentID:        req.AgentID,
+		MaxDuration:    req.MaxDuration,
+		OnQueued:       req.OnQueued,
+		Workspace:      req.Workspace,
+		ChannelContext: req.ChannelContext,
+	}
+	// The round-trip span lasts until the last response is persisted; the
+	// agent manager passes it to the agent as the trace's parent.
+	ctx, roundTrip := tracer.Start(ctx, "conversation.agent_round_trip", trace.WithAttributes(
+		attribute.String("agent.id", req.AgentID),
+		attribute.String("thread.id", thread.ID),
+		attribute.String("request.id", messageID),
+	))
+	// The send gets its own context so CancelRequest can stop it.
+	ctx, cancel := context.WithCancelCause(ctx)
 	respChan, err := s.sender.SendMessage(ctx, agentReq)
 	if err != nil {
+		cancel(err)
+		tracing.EndSpan(roundTrip, err)
 		// Message is recorded, but agent failed
 		// Future: could mark message as "pending" or "failed"
 		return nil, fmt.Errorf("agent send failed: %w", err)
 	}
+	s.trackSend(InFlightRequest{
+		RequestID:        messageID,
+		AgentID:          req.AgentID,
+		ThreadID:         thread.ID,
+		ActorPrincipalID: req.ActorPrincipalID,
+	}, cancel)
 
 	// 4. Wrap channel to persist responses as they stream
-	persistedChan := s.persistResponses(ctx, thread.ID, req.AgentID, respChan)
+	persistedChan := s.persistResponses(ctx, thread.ID, req.AgentID, respChan, func() {
+		s.untrackSend(messageID)
+		cancel(nil)
+		roundTrip.End()
+	})
 
 	return &SendResponse{
-		ThreadID:  thread.ID,
-		MessageID: messageID,
-		Stream:    persistedChan,
+		ThreadID:      thread.ID,
+		ThreadCreated: created,
+		MessageID:     messageID,
+		Attachments:   attachments,
+		Stream:        persistedChan,
 	}, nil
 }
 
+// recordUserMessage resolves req's thread and saves the user message under
+// messageID and its attachments in one transaction, so a failure part-way
+// cannot leave a new thread without its first message or a message without
+// its attachments. It returns the attachments with their new IDs.
+func (s *Service) recordUserMessage(ctx context.Context, req *SendRequest, messageID string) (thread *store.Thread, created bool, event *store.LedgerEvent, attachments []agent.Attachment, err error) {
+	record := func(tx store.Store) error {
+		thread, created, err = s.resolveThread(ctx, tx, req)
+		if err != nil {
+			return err
+		}
+		event = newUserEvent(req, messageID, thread.ID)
+		recordCtx, recordSpan := tracer.Start(ctx, "conversation.record_message",
+			trace.WithAttributes(attribute.String("message.id", messageID)))
+		err = tx.SaveEvent(recordCtx, event)
+		tracing.EndSpan(recordSpan, err)
+		if err != nil {
+			return fmt.Errorf("failed to record message: %w", err)
+		}
+		attachments, err = s.saveAttachments(ctx, s.txAttachments(tx), thread.ID, messageID, req)
+		return err
+	}
+	err = s.store.WithTx(ctx, record)
+	if errors.Is(err, store.ErrDuplicateThread) {
+		// Another send created the thread first. On Postgres the failed
+		// insert aborts the transaction, so the lookup that finds the
+		// winner's thread needs a fresh one.
+		err = s.store.WithTx(ctx, record)
+	}
+	if err != nil {
+		return nil, false, nil, nil, err
+	}
+	return thread, created, event, attachments, nil
+}
+
+// newUserEvent returns the ledger event recording req's message.
+func newUserEvent(req *SendRequest, messageID, threadID string) *store.LedgerEvent {
+	event := &store.LedgerEvent{
+		ID:              messageID,
+		ConversationKey: req.AgentID,
+		ThreadID:        &threadID,
+		Direction:       store.EventDirectionInbound,
+		Author:          req.Sender,
+		Timestamp:       time.Now(),
+		Type:            store.EventTypeMessage,
+		Text:            &req.Content,
+	}
+	if req.Transport != "" {
+		event.RawTransport = &req.Transport
+	}
+	if req.PayloadRef != "" {
+		event.RawPayloadRef = &req.PayloadRef
+	}
+	if req.ActorPrincipalID != "" {
+		event.ActorPrincipalID = &req.ActorPrincipalID
+	}
+	if hash := store.BindingContextHash(req.ChannelContext); hash != "" {
+		event.ChannelContextHash = &hash
+	}
+	if req.ParentRequestID != "" {
+		event.ParentRequestID = &req.ParentRequestID
+	}
+	return event
+}
+
+// resolveThread finds or creates req's thread through st, unarchiving it if
+// needed.
+func (s *Service) resolveThread(ctx context.Context, st threadStore, req *SendRequest) (thread *store.Thread, created bool, err error) {
+	ctx, span := tracer.Start(ctx, "conversation.resolve_thread")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	thread, created, err = s.ensureThread(ctx, st, req)
+	if err != nil {
+		return nil, false, fmt.Errorf("thread resolution failed: %w", err)
+	}
+	span.SetAttributes(attribute.String("thread.id", thread.ID), attribute.Bool("thread.created", created))
+	if thread.Archived {
+		if err := s.unarchiveThread(ctx, st, thread, req.AgentID); err != nil {
+			return nil, false, err
+		}
+	}
+	return thread, created, nil
+}
+
+// txAttachments returns where a send's attachments are stored inside its
+// transaction: the transaction itself when it can store them, so they commit
+// or roll back with the message, otherwise the attachment store. Returns nil
+// without an attachment store.
+func (s *Service) txAttachments(tx store.Store) AttachmentStore {
+	if s.attachments == nil {
+		return nil
+	}
+	if a, ok := tx.(AttachmentStore); ok {
+		return a
+	}
+	return s.attachments
+}
+
+// saveAttachments stores req's attachments under the user message in st and
+// returns copies carrying their new IDs. With a nil st they are passed
+// through unstored.
+func (s *Service) saveAttachments(ctx context.Context, st AttachmentStore, threadID, messageID string, req *SendRequest) ([]agent.Attachment, error) {
+	if st == nil || len(req.Attachments) == 0 {
+		return req.Attachments, nil
+	}
+	saved := make([]agent.Attachment, len(req.Attachments))
+	for i, att := range req.Attachments {
+		att.ID = uuid.New().String()
+		if err := st.SaveAttachment(ctx, &store.Attachment{
+			ID:          att.ID,
+			ThreadID:    threadID,
+			MessageID:   messageID,
+			Filename:    att.Filename,
+			ContentType: att.MimeType,
+			Data:        att.Data,
+			UploadedBy:  req.ActorPrincipalID,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to store attachment %q: %w", att.Filename, err)
+		}
+		saved[i] = att
+	}
+	return saved, nil
+}
+
 // Subscribe registers a subscriber for broadcast events on a conversation key.
-// Returns nil channel if the broadcaster is not configured.
-func (s *Service) Subscribe(ctx context.Context, conversationKey string) (<-chan *store.LedgerEvent, string) {
+// Returns nil if the broadcaster is not configured.
+func (s *Service) Subscribe(ctx context.Context, conversationKey string) *Subscription {
 	if s.broadcaster == nil {
-		return nil, ""
+		return nil
 	}
 	return s.broadcaster.Subscribe(ctx, conversationKey)
 }
@@ -191,12 +414,12 @@ func newThreadRecord(req *SendRequest, threadID string) *store.Thread {
 }
 
 // tryRecoverDuplicateThread handles the race condition when thread already exists.
-func (s *Service) tryRecoverDuplicateThread(ctx context.Context, req *SendRequest, threadID string) (*store.Thread, error) {
+func (s *Service) tryRecoverDuplicateThread(ctx context.Context, st threadStore, req *SendRequest, threadID string) (*store.Thread, error) {
 	// First try by the provided ThreadID
 	if threadID != "" {
-		thread, err := s.store.GetThread(ctx, threadID)
+		thread, err := st.GetThread(ctx, threadID)
 		if err == nil {
-			s.logger.Debug("found existing thread after race", "thread_id", thread.ID)
+			logctx.FromContext(ctx, s.logger).Debug("found existing thread after race", "thread_id", thread.ID)
 			return thread, nil
 		}
 	}
@@ -206,69 +429,110 @@ func (s *Service) tryRecoverDuplicateThread(ctx context.Context, req *SendReques
 		return nil, store.ErrDuplicateThread
 	}
 
-	thread, err := s.store.GetThreadByFrontendID(ctx, req.FrontendName, req.ExternalID)
+	thread, err := st.GetThreadByFrontendID(ctx, req.FrontendName, req.ExternalID)
 	if err == nil {
-		s.logger.Debug("found existing thread by frontend ID after race", "thread_id", thread.ID)
+		logctx.FromContext(ctx, s.logger).Debug("found existing thread by frontend ID after race", "thread_id", thread.ID)
 		return thread, nil
 	}
 
-	s.logger.Error("retry lookup failed after duplicate error", "lookup_error", err)
+	logctx.FromContext(ctx, s.logger).Error("retry lookup failed after duplicate error", "lookup_error", err)
 	return nil, store.ErrDuplicateThread
 }
 
 // ensureThreadByID finds or creates a thread with the given ID.
-func (s *Service) ensureThreadByID(ctx context.Context, req *SendRequest) (*store.Thread, error) {
-	thread, err := s.store.GetThread(ctx, req.ThreadID)
+// created reports whether the thread was created by this call.
+func (s *Service) ensureThreadByID(ctx context.Context, st threadStore, req *SendRequest) (*store.Thread, bool, error) {
+	thread, err := st.GetThread(ctx, req.ThreadID)
 	if err == nil {
-		return thread, nil
+		return thread, false, nil
 	}
 	if !errors.Is(err, store.ErrNotFound) {
-		return nil, err
+		return nil, false, err
 	}
 
 	thread = newThreadRecord(req, req.ThreadID)
-	if err := s.store.CreateThread(ctx, thread); err != nil {
+	if err := st.CreateThread(ctx, thread); err != nil {
 		if errors.Is(err, store.ErrDuplicateThread) {
-			return s.tryRecoverDuplicateThread(ctx, req, req.ThreadID)
+			thread, err = s.tryRecoverDuplicateThread(ctx, st, req, req.ThreadID)
+			return thread, false, err
 		}
-		return nil, err
+		return nil, false, err
 	}
-	s.logger.Debug("thread created", "thread_id", thread.ID)
-	return thread, nil
+	logctx.FromContext(ctx, s.logger).Debug("thread created", "thread_id", thread.ID)
+	return thread, true, nil
 }
 
 // ensureThreadByFrontendID finds or creates a thread by frontend/external ID.
-func (s *Service) ensureThreadByFrontendID(ctx context.Context, req *SendRequest) (*store.Thread, error) {
+// created reports whether the thread was created by this call.
+func (s *Service) ensureThreadByFrontendID(ctx context.Context, st threadStore, req *SendRequest) (*store.Thread, bool, error) {
 	if req.FrontendName != "" && req.ExternalID != "" {
-		s.logger.Debug("looking up thread by frontend ID", "frontend", req.FrontendName, "external_id", req.ExternalID)
-		thread, err := s.store.GetThreadByFrontendID(ctx, req.FrontendName, req.ExternalID)
+		logctx.FromContext(ctx, s.logger).Debug("looking up thread by frontend ID", "frontend", req.FrontendName, "external_id", req.ExternalID)
+		thread, err := st.GetThreadByFrontendID(ctx, req.FrontendName, req.ExternalID)
 		if err == nil {
-			s.logger.Debug("found existing thread", "thread_id", thread.ID)
-			return thread, nil
+			logctx.FromContext(ctx, s.logger).Debug("found existing thread", "thread_id", thread.ID)
+			return thread, false, nil
 		}
 		if !errors.Is(err, store.ErrNotFound) {
-			return nil, err
+			return nil, false, err
 		}
-		s.logger.Debug("thread not found, will create new one")
+		logctx.FromContext(ctx, s.logger).Debug("thread not found, will create new one")
 	}
 
 	thread := newThreadRecord(req, "")
-	if err := s.store.CreateThread(ctx, thread); err != nil {
+	if err := st.CreateThread(ctx, thread); err != nil {
 		if errors.Is(err, store.ErrDuplicateThread) {
-			return s.tryRecoverDuplicateThread(ctx, req, "")
+			thread, err = s.tryRecoverDuplicateThread(ctx, st, req, "")
+			return thread, false, err
 		}
-		return nil, err
+		return nil, false, err
 	}
-	s.logger.Debug("thread created", "thread_id", thread.ID)
-	return thread, nil
+	logctx.FromContext(ctx, s.logger).Debug("thread created", "thread_id", thread.ID)
+	return thread, true, nil
 }
 
 // ensureThread resolves an existing thread or creates a new one.
-func (s *Service) ensureThread(ctx context.Context, req *SendRequest) (*store.Thread, error) {
+// Threads merged away by an admin resolve to the thread they were merged into.
+func (s *Service) ensureThread(ctx context.Context, st threadStore, req *SendRequest) (*store.Thread, bool, error) {
+	var thread *store.Thread
+	var created bool
+	var err error
 	if req.ThreadID != "" {
-		return s.ensureThreadByID(ctx, req)
+		thread, created, err = s.ensureThreadByID(ctx, st, req)
+	} else {
+		thread, created, err = s.ensureThreadByFrontendID(ctx, st, req)
 	}
-	return s.ensureThreadByFrontendID(ctx, req)
+	if err != nil || thread.MergedInto == "" {
+		return thread, created, err
+	}
+	logctx.FromContext(ctx, s.logger).Debug("following merged thread tombstone", "thread_id", thread.ID, "merged_into", thread.MergedInto)
+	thread, err = st.GetThread(ctx, thread.MergedInto)
+	return thread, false, err
+}
+
+// unarchiveThread brings an archived thread back into listings because a new
+// message arrived on it, and records that in the thread's ledger.
+func (s *Service) unarchiveThread(ctx context.Context, st threadStore, thread *store.Thread, agentID string) error {
+	if err := st.ArchiveThread(ctx, thread.ID, false); err != nil {
+		return fmt.Errorf("unarchiving thread: %w", err)
+	}
+	thread.Archived = false
+
+	text := "Thread unarchived by a new message"
+	event := &store.LedgerEvent{
+		ID:              uuid.New().String(),
+		ConversationKey: agentID,
+		ThreadID:        &thread.ID,
+		Direction:       store.EventDirectionInbound,
+		Author:          "system",
+		Timestamp:       time.Now(),
+		Type:            store.EventTypeSystem,
+		Text:            &text,
+	}
+	if err := st.SaveEvent(ctx, event); err != nil {
+		return fmt.Errorf("recording unarchive: %w", err)
+	}
+	logctx.FromContext(ctx, s.logger).Info("thread unarchived by new message", "thread_id", thread.ID)
+	return nil
 }
 
 // responsePersister holds state for persisting agent responses.
@@ -302,9 +566,10 @@ func (p *responsePersister) handleToolUse(tu *agent.ToolUseEvent) {
 	})
 }
 
-// handleToolResult persists a tool result event.
+// handleToolResult persists a tool result event. Partial results are
+// live-only; the ledger keeps the final result.
 func (p *responsePersister) handleToolResult(tr *agent.ToolResultEvent) {
-	if tr == nil {
+	if tr == nil || tr.Partial {
 		return
 	}
 	isErrorStr := "false"
@@ -324,6 +589,43 @@ func (p *responsePersister) handleToolResult(tr *agent.ToolResultEvent) {
 	})
 }
 
+// handlePlan persists a plan as its numbered-list text. Step updates are
+// live-only; the ledger keeps the plan as announced.
+func (p *responsePersister) handlePlan(plan *agent.PlanEvent) {
+	if plan == nil {
+		return
+	}
+	planText := plan.Text()
+	p.service.saveEvent(p.ctx, &store.LedgerEvent{
+		ID:              uuid.New().String(),
+		ConversationKey: p.agentID,
+		ThreadID:        &p.threadID,
+		Direction:       store.EventDirectionOutbound,
+		Author:          p.sender,
+		Timestamp:       time.Now(),
+		Type:            store.EventTypePlan,
+		Text:            &planText,
+	})
+}
+
+// handleCitation persists a citation as JSON so history can rebuild footnotes.
+func (p *responsePersister) handleCitation(c *agent.CitationEvent) {
+	if c == nil {
+		return
+	}
+	citationText := c.JSON()
+	p.service.saveEvent(p.ctx, &store.LedgerEvent{
+		ID:              uuid.New().String(),
+		ConversationKey: p.agentID,
+		ThreadID:        &p.threadID,
+		Direction:       store.EventDirectionOutbound,
+		Author:          p.sender,
+		Timestamp:       time.Now(),
+		Type:            store.EventTypeCitation,
+		Text:            &citationText,
+	})
+}
+
 // handleUsage persists a usage event.
 func (p *responsePersister) handleUsage(usage *agent.UsageEvent) {
 	if usage == nil || p.savedUsage {
@@ -350,6 +652,53 @@ func (p *responsePersister) handleDone(resp *agent.Response) {
 	if !p.receivedStreamText && resp.Text != "" {
 		content = resp.Text
 	}
+	p.saveReply(content)
+}
+
+// handleTruncated persists whatever the agent streamed before the gateway cut
+// it off, followed by a system event marking the reply as truncated.
+func (p *responsePersister) handleTruncated(ev *agent.TruncatedEvent) {
+	p.saveReply(p.textBuffer)
+	if ev == nil {
+		return
+	}
+	marker := ev.Summary()
+	p.service.saveEvent(p.ctx, &store.LedgerEvent{
+		ID:              uuid.New().String(),
+		ConversationKey: p.agentID,
+		ThreadID:        &p.threadID,
+		Direction:       store.EventDirectionOutbound,
+		Author:          "system",
+		Timestamp:       time.Now(),
+		Type:            store.EventTypeSystem,
+		Text:            &marker,
+	})
+}
+
+// handleError persists the end of a request the gateway gave up on because
+// its agent went silent: the partial reply, then a system event saying so.
+// Errors reported by the agent itself are not recorded.
+func (p *responsePersister) handleError(resp *agent.Response) {
+	trace.SpanFromContext(p.ctx).SetStatus(codes.Error, resp.Error)
+	if resp.Code != agent.ErrorCodeAgentTimeout {
+		return
+	}
+	p.saveReply(p.textBuffer)
+	marker := "Response canceled: " + resp.Error
+	p.service.saveEvent(p.ctx, &store.LedgerEvent{
+		ID:              uuid.New().String(),
+		ConversationKey: p.agentID,
+		ThreadID:        &p.threadID,
+		Direction:       store.EventDirectionOutbound,
+		Author:          "system",
+		Timestamp:       time.Now(),
+		Type:            store.EventTypeSystem,
+		Text:            &marker,
+	})
+}
+
+// saveReply persists the agent's reply text and links usage to it.
+func (p *responsePersister) saveReply(content string) {
 	if content == "" {
 		return
 	}
@@ -379,20 +728,56 @@ func (p *responsePersister) handleResponse(resp *agent.Response) {
 		p.handleToolUse(resp.ToolUse)
 	case agent.EventToolResult:
 		p.handleToolResult(resp.ToolResult)
+	case agent.EventPlan:
+		p.handlePlan(resp.Plan)
+	case agent.EventCitation:
+		p.handleCitation(resp.Citation)
 	case agent.EventUsage:
 		p.handleUsage(resp.Usage)
 	case agent.EventDone:
 		p.handleDone(resp)
+	case agent.EventTruncated:
+		p.handleTruncated(resp.Truncated)
+	case agent.EventError:
+		p.handleError(resp)
+	case agent.EventFile:
+		p.handleFile(resp.File)
+	}
+}
+
+// handleFile stores a file the agent returned as a thread attachment and
+// records its ID on the event, so clients can download it.
+func (p *responsePersister) handleFile(f *agent.FileEvent) {
+	if f == nil || p.service.attachments == nil || len(f.Data) == 0 {
+		return
+	}
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(p.ctx), 5*time.Second)
+	defer cancel()
+	id := uuid.New().String()
+	if err := p.service.attachments.SaveAttachment(saveCtx, &store.Attachment{
+		ID:          id,
+		ThreadID:    p.threadID,
+		Filename:    f.Filename,
+		ContentType: f.MimeType,
+		Data:        f.Data,
+	}); err != nil {
+		logctx.FromContext(p.ctx, p.service.logger).Error("failed to store agent file", "error", err, "thread_id", p.threadID, "filename", f.Filename)
+		return
 	}
+	f.AttachmentID = id
 }
 
 // persistResponses wraps the agent response channel to save messages as they stream.
 // Events are keyed by agentID for cross-client history sync (TUI, web, mobile all query by agent).
-func (s *Service) persistResponses(ctx context.Context, threadID, agentID string, in <-chan *agent.Response) <-chan *agent.Response {
+// done, if set, is called once every response has been persisted.
+func (s *Service) persistResponses(ctx context.Context, threadID, agentID string, in <-chan *agent.Response, done func()) <-chan *agent.Response {
 	out := make(chan *agent.Response, 16)
 
 	go func() {
 		defer close(out)
+		if done != nil {
+			defer done()
+		}
 
 		p := &responsePersister{
 			service:   s,
@@ -410,6 +795,14 @@ func (s *Service) persistResponses(ctx context.Context, threadID, agentID string
 		for resp := range in {
 			p.handleResponse(resp)
 
+			// Forward what is ready even once the caller has canceled, so
+			// its stream still gets the final canceled event.
+			select {
+			case out <- resp:
+				continue
+			default:
+			}
+
 			// Reset timer for each send attempt
 			if !sendTimer.Stop() {
 				select {
@@ -422,9 +815,9 @@ func (s *Service) persistResponses(ctx context.Context, threadID, agentID string
 			select {
 			case out <- resp:
 			case <-sendTimer.C:
-				s.logger.Warn("response channel full, dropping message", "thread_id", threadID, "event", resp.Event)
+				logctx.FromContext(ctx, s.logger).Warn("response channel full, dropping message", "thread_id", threadID, "event", resp.Event)
 			case <-ctx.Done():
-				s.logger.Debug("context canceled during response streaming", "thread_id", threadID)
+				logctx.FromContext(ctx, s.logger).Debug("context canceled during response streaming", "thread_id", threadID)
 				go func() {
 					for range in {
 					}
@@ -437,6 +830,35 @@ func (s *Service) persistResponses(ctx context.Context, threadID, agentID string
 	return out
 }
 
+// PersistResumed persists the responses to a request the gateway re-sent
+// after the agent reconnected. No caller is waiting on the stream, so it is
+// drained here; clients see the events through the broadcaster.
+func (s *Service) PersistResumed(ctx context.Context, threadID, agentID string, in <-chan *agent.Response) {
+	out := s.persistResponses(ctx, threadID, agentID, in, nil)
+	go func() {
+		for range out {
+		}
+	}()
+}
+
+// SaveLateUsage records usage an agent reported after its request finished,
+// against the request and thread it belongs to. It suits
+// agent.Manager.SetLateUsageObserver.
+func (s *Service) SaveLateUsage(agentID, threadID, requestID string, usage *agent.UsageEvent) {
+	s.saveUsage(context.Background(), &store.TokenUsage{
+		ID:               uuid.New().String(),
+		ThreadID:         threadID,
+		RequestID:        requestID,
+		AgentID:          agentID,
+		InputTokens:      usage.InputTokens,
+		OutputTokens:     usage.OutputTokens,
+		CacheReadTokens:  usage.CacheReadTokens,
+		CacheWriteTokens: usage.CacheWriteTokens,
+		ThinkingTokens:   usage.ThinkingTokens,
+		CreatedAt:        time.Now(),
+	})
+}
+
 // saveUsage saves a token usage record with a separate timeout context.
 // Uses WithoutCancel to ensure saves complete even if parent context is canceled.
 func (s *Service) saveUsage(ctx context.Context, usage *store.TokenUsage) {
@@ -444,15 +866,15 @@ func (s *Service) saveUsage(ctx context.Context, usage *store.TokenUsage) {
 	defer cancel()
 
 	if err := s.store.SaveUsage(saveCtx, usage); err != nil {
-		s.logger.Error("failed to save usage",
+		logctx.FromContext(ctx, s.logger).Error("failed to save usage",
 			"error", err,
 			"thread_id", usage.ThreadID,
-			"request_id", usage.RequestID,
+			"usage_request_id", usage.RequestID,
 			"usage_id", usage.ID)
 	} else {
-		s.logger.Debug("usage saved",
+		logctx.FromContext(ctx, s.logger).Debug("usage saved",
 			"thread_id", usage.ThreadID,
-			"request_id", usage.RequestID,
+			"usage_request_id", usage.RequestID,
 			"input_tokens", usage.InputTokens,
 			"output_tokens", usage.OutputTokens)
 	}
@@ -465,9 +887,9 @@ func (s *Service) linkUsageToMessage(ctx context.Context, requestID, messageID s
 	defer cancel()
 
 	if err := s.store.LinkUsageToMessage(saveCtx, requestID, messageID); err != nil {
-		s.logger.Error("failed to link usage to message",
+		logctx.FromContext(ctx, s.logger).Error("failed to link usage to message",
 			"error", err,
-			"request_id", requestID,
+			"usage_request_id", requestID,
 			"message_id", messageID)
 	}
 }
@@ -475,11 +897,15 @@ func (s *Service) linkUsageToMessage(ctx context.Context, requestID, messageID s
 // saveEvent saves a ledger event with a separate timeout context.
 // Uses WithoutCancel to ensure persistence continues even if the request context is canceled.
 func (s *Service) saveEvent(ctx context.Context, event *store.LedgerEvent) {
+	ctx, span := tracer.Start(ctx, "conversation.save_event",
+		trace.WithAttributes(attribute.String("event.type", string(event.Type))))
 	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
 	defer cancel()
 
-	if err := s.store.SaveEvent(saveCtx, event); err != nil {
-		s.logger.Error("failed to save event",
+	err := s.store.SaveEvent(saveCtx, event)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		logctx.FromContext(ctx, s.logger).Error("failed to save event",
 			"error", err,
 			"event_id", event.ID,
 			"thread_id", event.ThreadID,
@@ -490,7 +916,7 @@ func (s *Service) saveEvent(ctx context.Context, event *store.LedgerEvent) {
 			s.broadcaster.Publish(event.ConversationKey, event, "")
 		}
 
-		s.logger.Debug("event saved",
+		logctx.FromContext(ctx, s.logger).Debug("event saved",
 			"event_id", event.ID,
 			"thread_id", event.ThreadID,
 			"type", event.Type)
@@ -5,6 +5,7 @@ package conversation
 
 import (
 	"context"
+	"errors"
 	"path/filepath"
 	"testing"
 	"time"
@@ -37,7 +38,7 @@ func (m *mockSender) SendMessage(ctx context.Context, req *agent.SendRequest) (<
 	return ch, nil
 }
 
-func createTestStore(t *testing.T) *store.SQLiteStore {
+func createTestStore(t *testing.T) *store.SQLStore {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
 	s, err := store.NewSQLiteStore(dbPath)
@@ -182,6 +183,84 @@ func TestService_SendMessage_ReusesExistingThread(t *testing.T) {
 	assert.Equal(t, 2, userEvtCount)
 }
 
+func TestService_SendMessage_FollowsMergedThread(t *testing.T) {
+	testStore := createTestStore(t)
+	sender := &mockSender{responses: []*agent.Response{{Event: agent.EventDone, Done: true}}}
+	svc := New(testStore, sender, nil, nil)
+	ctx := context.Background()
+
+	for _, th := range []*store.Thread{
+		{ID: "target", FrontendName: "test-frontend", ExternalID: "channel-new", AgentID: "test-agent"},
+		{ID: "source", FrontendName: "test-frontend", ExternalID: "channel-old", AgentID: "test-agent"},
+	} {
+		th.CreatedAt, th.UpdatedAt = time.Now(), time.Now()
+		require.NoError(t, testStore.CreateThread(ctx, th))
+	}
+	_, err := testStore.MergeThreads(ctx, "target", []string{"source"})
+	require.NoError(t, err)
+
+	// Both the tombstone's frontend mapping and its ID resolve to the target
+	resp, err := svc.SendMessage(ctx, &SendRequest{
+		AgentID:      "test-agent",
+		FrontendName: "test-frontend",
+		ExternalID:   "channel-old",
+		Sender:       "user",
+		Content:      "Hello",
+	})
+	require.NoError(t, err)
+	for range resp.Stream {
+	}
+	assert.Equal(t, "target", resp.ThreadID)
+
+	sender.responses = []*agent.Response{{Event: agent.EventDone, Done: true}}
+	resp, err = svc.SendMessage(ctx, &SendRequest{
+		ThreadID: "source",
+		AgentID:  "test-agent",
+		Sender:   "user",
+		Content:  "Again",
+	})
+	require.NoError(t, err)
+	for range resp.Stream {
+	}
+	assert.Equal(t, "target", resp.ThreadID)
+}
+
+func TestService_SendMessage_UnarchivesThread(t *testing.T) {
+	testStore := createTestStore(t)
+	sender := &mockSender{responses: []*agent.Response{{Event: agent.EventDone, Done: true}}}
+	svc := New(testStore, sender, nil, nil)
+	ctx := context.Background()
+
+	th := &store.Thread{ID: "archived", FrontendName: "test-frontend", ExternalID: "channel-1", AgentID: "test-agent", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, testStore.CreateThread(ctx, th))
+	require.NoError(t, testStore.ArchiveThread(ctx, "archived", true))
+
+	resp, err := svc.SendMessage(ctx, &SendRequest{
+		AgentID:      "test-agent",
+		FrontendName: "test-frontend",
+		ExternalID:   "channel-1",
+		Sender:       "user",
+		Content:      "Back again",
+	})
+	require.NoError(t, err)
+	for range resp.Stream {
+	}
+
+	thread, err := testStore.GetThread(ctx, "archived")
+	require.NoError(t, err)
+	assert.False(t, thread.Archived)
+
+	events, err := testStore.GetEventsByThreadID(ctx, "archived", 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	texts := map[store.EventType]string{}
+	for _, e := range events {
+		texts[e.Type] = *e.Text
+	}
+	assert.Equal(t, "Thread unarchived by a new message", texts[store.EventTypeSystem])
+	assert.Equal(t, "Back again", texts[store.EventTypeMessage])
+}
+
 func TestService_SendMessage_UsesProvidedThreadID(t *testing.T) {
 	testStore := createTestStore(t)
 	sender := &mockSender{
@@ -277,6 +356,83 @@ func TestService_SendMessage_PersistsToolUse(t *testing.T) {
 	assert.Contains(t, *toolResultEvt.Text, "file contents here")
 }
 
+func TestService_SendMessage_PersistsPlan(t *testing.T) {
+	testStore := createTestStore(t)
+	sender := &mockSender{
+		responses: []*agent.Response{
+			{Event: agent.EventPlan, Plan: &agent.PlanEvent{Steps: []agent.PlanStep{
+				{Title: "check logs", Status: "pending"},
+				{Title: "restart service", Status: "pending"},
+			}}},
+			{Event: agent.EventPlanStepUpdate, PlanStep: &agent.PlanStepEvent{Index: 0, Status: "completed"}},
+			{Event: agent.EventDone, Done: true},
+		},
+	}
+	svc := New(testStore, sender, nil, nil)
+
+	ctx := context.Background()
+	resp, err := svc.SendMessage(ctx, &SendRequest{
+		AgentID: "test-agent",
+		Sender:  "user",
+		Content: "Fix the service",
+	})
+	require.NoError(t, err)
+	for range resp.Stream {
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	events, err := testStore.GetEventsByThreadID(ctx, resp.ThreadID, 10)
+	require.NoError(t, err)
+	var plans []*store.LedgerEvent
+	for _, evt := range events {
+		if evt.Type == store.EventTypePlan {
+			plans = append(plans, evt)
+		}
+	}
+	require.Len(t, plans, 1, "step updates should not be persisted")
+	require.NotNil(t, plans[0].Text)
+	assert.Equal(t, "Plan:\n1. check logs\n2. restart service", *plans[0].Text)
+}
+
+func TestService_SendMessage_PersistsCitations(t *testing.T) {
+	// Citations may arrive before, between, and after the text they annotate
+	testStore := createTestStore(t)
+	sender := &mockSender{
+		responses: []*agent.Response{
+			{Event: agent.EventCitation, Citation: &agent.CitationEvent{Index: 1, Title: "Go spec", URI: "https://go.dev/ref/spec"}},
+			{Event: agent.EventText, Text: "Slices share arrays [1]"},
+			{Event: agent.EventCitation, Citation: &agent.CitationEvent{Index: 2, Title: "Blog", URI: "https://go.dev/blog/slices", Snippet: "a slice is a descriptor"}},
+			{Event: agent.EventText, Text: " and grow by append [2][3]."},
+			{Event: agent.EventCitation, Citation: &agent.CitationEvent{Index: 3, URI: "https://pkg.go.dev/builtin#append"}},
+			{Event: agent.EventDone, Done: true},
+		},
+	}
+	svc := New(testStore, sender, nil, nil)
+
+	ctx := context.Background()
+	resp, err := svc.SendMessage(ctx, &SendRequest{AgentID: "test-agent", Sender: "user", Content: "How do slices work?"})
+	require.NoError(t, err)
+	for range resp.Stream {
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	events, err := testStore.GetEventsByThreadID(ctx, resp.ThreadID, 20)
+	require.NoError(t, err)
+	var citations []string
+	var text string
+	for _, evt := range events {
+		switch {
+		case evt.Type == store.EventTypeCitation:
+			citations = append(citations, *evt.Text)
+		case evt.Type == store.EventTypeMessage && evt.Direction == store.EventDirectionOutbound:
+			text = *evt.Text
+		}
+	}
+	assert.Equal(t, "Slices share arrays [1] and grow by append [2][3].", text, "citations must not disturb the text")
+	require.Len(t, citations, 3)
+	assert.Contains(t, citations, `{"index":2,"title":"Blog","uri":"https://go.dev/blog/slices","snippet":"a slice is a descriptor"}`)
+}
+
 func TestService_SendMessage_AccumulatesStreamingText(t *testing.T) {
 	testStore := createTestStore(t)
 	sender := &mockSender{
@@ -337,6 +493,109 @@ func TestService_SendMessage_RequiresAgentID(t *testing.T) {
 	assert.Contains(t, err.Error(), "agent_id")
 }
 
+func TestService_SendMessage_WriteGuardRejectsBeforePersisting(t *testing.T) {
+	testStore := createTestStore(t)
+	sender := &mockSender{}
+	svc := New(testStore, sender, nil, nil)
+	errFull := errors.New("storage full")
+	svc.SetWriteGuard(func() error { return errFull })
+
+	ctx := context.Background()
+	_, err := svc.SendMessage(ctx, &SendRequest{
+		FrontendName: "test",
+		ExternalID:   "guarded",
+		AgentID:      "agent-1",
+		Sender:       "user",
+		Content:      "Hello",
+	})
+
+	require.ErrorIs(t, err, errFull)
+	assert.Nil(t, sender.lastReq, "guarded send must not reach the agent")
+	_, err = testStore.GetThreadByFrontendID(ctx, "test", "guarded")
+	assert.ErrorIs(t, err, store.ErrNotFound, "guarded send must not create a thread")
+}
+
+// failingEventStore fails saving the user message inside a send's
+// transaction, after its thread has been created.
+type failingEventStore struct {
+	*store.SQLStore
+}
+
+func (s failingEventStore) WithTx(ctx context.Context, fn func(tx store.Store) error) error {
+	return s.SQLStore.WithTx(ctx, func(tx store.Store) error { return fn(failingEventTx{tx}) })
+}
+
+type failingEventTx struct {
+	store.Store
+}
+
+func (failingEventTx) SaveEvent(context.Context, *store.LedgerEvent) error {
+	return errors.New("disk full")
+}
+
+func TestService_SendMessage_FailedRecordLeavesNoThread(t *testing.T) {
+	testStore := createTestStore(t)
+	sender := &mockSender{}
+	svc := New(failingEventStore{testStore}, sender, nil, nil)
+
+	ctx := context.Background()
+	_, err := svc.SendMessage(ctx, &SendRequest{
+		FrontendName: "test",
+		ExternalID:   "half-written",
+		AgentID:      "agent-1",
+		Sender:       "user",
+		Content:      "Hello",
+	})
+
+	require.ErrorContains(t, err, "disk full")
+	assert.Nil(t, sender.lastReq, "unrecorded send must not reach the agent")
+	_, err = testStore.GetThreadByFrontendID(ctx, "test", "half-written")
+	assert.ErrorIs(t, err, store.ErrNotFound, "the thread must roll back with its message")
+}
+
+// failingAttachmentStore fails saving attachments inside a send's
+// transaction, after its user message has been saved.
+type failingAttachmentStore struct {
+	*store.SQLStore
+}
+
+func (s failingAttachmentStore) WithTx(ctx context.Context, fn func(tx store.Store) error) error {
+	return s.SQLStore.WithTx(ctx, func(tx store.Store) error { return fn(failingAttachmentTx{tx}) })
+}
+
+type failingAttachmentTx struct {
+	store.Store
+}
+
+func (failingAttachmentTx) SaveAttachment(context.Context, *store.Attachment) error {
+	return errors.New("disk full")
+}
+
+func TestService_SendMessage_FailedAttachmentLeavesNoMessage(t *testing.T) {
+	testStore := createTestStore(t)
+	sender := &mockSender{}
+	svc := New(failingAttachmentStore{testStore}, sender, nil, nil)
+	svc.SetAttachmentStore(testStore)
+
+	ctx := context.Background()
+	_, err := svc.SendMessage(ctx, &SendRequest{
+		FrontendName: "test",
+		ExternalID:   "with-file",
+		AgentID:      "agent-1",
+		Sender:       "user",
+		Content:      "See attached",
+		Attachments:  []agent.Attachment{{Filename: "notes.txt", Data: []byte("content")}},
+	})
+
+	require.ErrorContains(t, err, "disk full")
+	assert.Nil(t, sender.lastReq, "unrecorded send must not reach the agent")
+	events, err := testStore.ListEventsByConversation(ctx, "agent-1", 10)
+	require.NoError(t, err)
+	assert.Empty(t, events, "the user message must roll back with its attachments")
+	_, err = testStore.GetThreadByFrontendID(ctx, "test", "with-file")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
 func TestService_SendMessage_ForwardsToSender(t *testing.T) {
 	testStore := createTestStore(t)
 	sender := &mockSender{
@@ -442,7 +701,7 @@ func TestService_SendMessage_SenderError(t *testing.T) {
 
 	// User message should still be saved even though agent failed
 	// (this is the "record first" principle) - now in ledger_events
-	threads, err := testStore.ListThreads(ctx, 10)
+	threads, err := testStore.ListThreads(ctx, 10, false)
 	require.NoError(t, err)
 	require.Len(t, threads, 1)
 
@@ -453,3 +712,59 @@ func TestService_SendMessage_SenderError(t *testing.T) {
 	require.NotNil(t, events[0].Text)
 	assert.Equal(t, "Hello", *events[0].Text)
 }
+
+func TestService_SendMessage_PersistsTruncatedReply(t *testing.T) {
+	testStore := createTestStore(t)
+	truncated := &agent.TruncatedEvent{Reason: agent.TruncatedReasonMaxDuration, Elapsed: 95 * time.Second, Limit: time.Minute}
+	sender := &mockSender{
+		responses: []*agent.Response{
+			{Event: agent.EventText, Text: "Still looping"},
+			{Event: agent.EventTruncated, Error: truncated.Reason, Done: true, Truncated: truncated},
+		},
+	}
+	svc := New(testStore, sender, nil, nil)
+
+	ctx := context.Background()
+	resp, err := svc.SendMessage(ctx, &SendRequest{AgentID: "test-agent", Sender: "user", Content: "Go", MaxDuration: time.Minute})
+	require.NoError(t, err)
+	for range resp.Stream {
+	}
+	assert.Equal(t, time.Minute, sender.lastReq.MaxDuration)
+
+	events, err := testStore.GetEventsByThreadID(ctx, resp.ThreadID, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	texts := map[string]string{}
+	for _, evt := range events {
+		texts[evt.Author+"/"+string(evt.Type)] = *evt.Text
+	}
+	assert.Equal(t, "Still looping", texts["agent:test-agent/message"])
+	assert.Equal(t, "Response truncated after 1m35s: exceeded max response duration of 1m0s", texts["system/system"])
+}
+
+func TestService_SendMessage_PersistsAgentTimeout(t *testing.T) {
+	testStore := createTestStore(t)
+	sender := &mockSender{
+		responses: []*agent.Response{
+			{Event: agent.EventText, Text: "Thinking about"},
+			{Event: agent.EventError, Error: "agent sent nothing for 2m0s", Code: agent.ErrorCodeAgentTimeout, Done: true},
+		},
+	}
+	svc := New(testStore, sender, nil, nil)
+
+	ctx := context.Background()
+	resp, err := svc.SendMessage(ctx, &SendRequest{AgentID: "test-agent", Sender: "user", Content: "Go"})
+	require.NoError(t, err)
+	for range resp.Stream {
+	}
+
+	events, err := testStore.GetEventsByThreadID(ctx, resp.ThreadID, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	texts := map[string]string{}
+	for _, evt := range events {
+		texts[evt.Author+"/"+string(evt.Type)] = *evt.Text
+	}
+	assert.Equal(t, "Thinking about", texts["agent:test-agent/message"])
+	assert.Equal(t, "Response canceled: agent sent nothing for 2m0s", texts["system/system"])
+}
@@ -0,0 +1,274 @@
+// ABOUTME: Machine-readable error codes shared by SSE error events, HTTP JSON errors and gRPC statuses
+// ABOUTME: Errors carry a Code so callers can classify failures and decide whether to retry
+
+// Package coverr defines the gateway's error codes. Every error a client can
+// see, whether an SSE error event, an HTTP JSON error body or a gRPC status,
+// names one of these codes alongside its human-readable message.
+package coverr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code is a machine-readable error code. Values are stable lowercase
+// snake_case strings and are part of the client protocol.
+type Code string
+
+const (
+	// AgentOffline: the target agent is not connected.
+	AgentOffline Code = "agent_offline"
+	// AgentTimeout: the agent went silent and the gateway gave up on it.
+	AgentTimeout Code = "agent_timeout"
+	// AgentSuperseded: the agent reconnected before the request finished.
+	AgentSuperseded Code = "agent_superseded"
+	// AgentBusy: the agent is at its concurrency limit and its queue is full.
+	AgentBusy Code = "agent_busy"
+	// AgentPaused: an admin paused the agent.
+	AgentPaused Code = "agent_paused"
+	// AgentError: the agent itself reported the failure.
+	AgentError Code = "agent_error"
+	// PayloadTooLarge: a message was over the gateway's size limit.
+	PayloadTooLarge Code = "payload_too_large"
+	// NoCapableAgent: no connected agent has the capability asked for.
+	NoCapableAgent Code = "no_capable_agent"
+	// CapabilityDenied: the caller lacks a capability the tool requires.
+	CapabilityDenied Code = "capability_denied"
+	// ToolDisabled: an admin disabled the tool for the calling agent.
+	ToolDisabled Code = "tool_disabled"
+	// ToolNotFound: no pack provides the tool.
+	ToolNotFound Code = "tool_not_found"
+	// ToolUnavailable: the pack that owns the tool is gone.
+	ToolUnavailable Code = "tool_unavailable"
+	// PackUnavailable: the pack that owns the tool missed its heartbeats.
+	PackUnavailable Code = "pack_unavailable"
+	// ToolTimeout: the tool did not finish in time.
+	ToolTimeout Code = "tool_timeout"
+	// RateLimited: the caller exceeded a rate limit.
+	RateLimited Code = "rate_limited"
+	// GatewayDraining: the gateway is shutting down.
+	GatewayDraining Code = "gateway_draining"
+	// StorageFull: writes are refused because the disk is nearly full.
+	StorageFull Code = "storage_full"
+	// InvalidRequest: the request is malformed or missing a field.
+	InvalidRequest Code = "invalid_request"
+	// Unauthenticated: the request has no valid credentials.
+	Unauthenticated Code = "unauthenticated"
+	// PermissionDenied: the caller may not do this.
+	PermissionDenied Code = "permission_denied"
+	// NotFound: the named resource does not exist.
+	NotFound Code = "not_found"
+	// Conflict: the request conflicts with the resource's current state.
+	Conflict Code = "conflict"
+	// Canceled: the request was canceled before it finished.
+	Canceled Code = "canceled"
+	// Internal: anything else. Details are in the gateway's logs.
+	Internal Code = "internal"
+)
+
+// Retriable reports whether the same request may succeed if sent again
+// later, without any change by the caller or an admin.
+func (c Code) Retriable() bool {
+	switch c {
+	case AgentOffline, AgentTimeout, AgentSuperseded, AgentBusy,
+		ToolUnavailable, PackUnavailable, ToolTimeout, RateLimited, GatewayDraining:
+		return true
+	}
+	return false
+}
+
+// GRPCCode is the gRPC status code that best matches c.
+func (c Code) GRPCCode() codes.Code {
+	switch c {
+	case ToolUnavailable, PackUnavailable, GatewayDraining, AgentSuperseded:
+		return codes.Unavailable
+	case AgentTimeout, ToolTimeout:
+		return codes.DeadlineExceeded
+	case AgentBusy, RateLimited, StorageFull, PayloadTooLarge:
+		return codes.ResourceExhausted
+	case AgentPaused, NoCapableAgent:
+		return codes.FailedPrecondition
+	case CapabilityDenied, ToolDisabled, PermissionDenied:
+		return codes.PermissionDenied
+	case AgentOffline, ToolNotFound, NotFound:
+		return codes.NotFound
+	case InvalidRequest:
+		return codes.InvalidArgument
+	case Unauthenticated:
+		return codes.Unauthenticated
+	case Conflict:
+		return codes.AlreadyExists
+	case Canceled:
+		return codes.Canceled
+	}
+	return codes.Internal
+}
+
+// ForGRPCCode is the code for a gRPC status with nothing more specific to
+// say about it.
+func ForGRPCCode(c codes.Code) Code {
+	switch c {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return InvalidRequest
+	case codes.Unauthenticated:
+		return Unauthenticated
+	case codes.PermissionDenied:
+		return PermissionDenied
+	case codes.NotFound:
+		return NotFound
+	case codes.AlreadyExists, codes.FailedPrecondition, codes.Aborted:
+		return Conflict
+	case codes.ResourceExhausted:
+		return RateLimited
+	case codes.Canceled:
+		return Canceled
+	}
+	return Internal
+}
+
+// ForHTTPStatus is the code for an HTTP error status with nothing more
+// specific to say about it.
+func ForHTTPStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest, http.StatusMethodNotAllowed, http.StatusRequestEntityTooLarge,
+		http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+		return InvalidRequest
+	case http.StatusUnauthorized:
+		return Unauthenticated
+	case http.StatusForbidden:
+		return PermissionDenied
+	case http.StatusNotFound, http.StatusGone:
+		return NotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return Conflict
+	case http.StatusTooManyRequests:
+		return RateLimited
+	case http.StatusInsufficientStorage:
+		return StorageFull
+	}
+	return Internal
+}
+
+// Error is an error with a code. Sentinel errors are declared with New so
+// that CodeOf finds their code through any wrapping.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+// New returns an error with the given code and message.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// CodeOf returns the code of the first *Error in err's chain. A bare
+// context.Canceled is Canceled and anything else is Internal. It returns ""
+// for a nil err.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	if errors.Is(err, context.Canceled) {
+		return Canceled
+	}
+	return Internal
+}
+
+// Body is the JSON payload of an SSE error event. Error repeats Message for
+// clients written before codes existed. HTTPRequestID is the X-Request-ID of
+// the send, for finding its log lines.
+type Body struct {
+	Error         string `json:"error"`
+	Code          Code   `json:"code"`
+	Message       string `json:"message"`
+	Retriable     bool   `json:"retriable"`
+	HTTPRequestID string `json:"http_request_id,omitempty"`
+}
+
+// NewBody returns the error event payload for code and message.
+func NewBody(code Code, message string) Body {
+	return Body{Error: message, Code: code, Message: message, Retriable: code.Retriable()}
+}
+
+// errorInfoDomain is the ErrorInfo domain of gRPC statuses built by Status.
+const errorInfoDomain = "coven-gateway"
+
+// Status returns a gRPC status error for code and message. The code itself
+// travels as the Reason of an ErrorInfo detail, which FromStatus reads back.
+func Status(code Code, message string) error {
+	return withCode(status.New(code.GRPCCode(), message), code)
+}
+
+// withCode returns st as an error carrying code in an ErrorInfo detail.
+func withCode(st *status.Status, code Code) error {
+	metadata := map[string]string{}
+	if code.Retriable() {
+		metadata["retriable"] = "true"
+	}
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   string(code),
+		Domain:   errorInfoDomain,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// FromStatus returns the code carried by a gRPC status error made with
+// Status, or "" if err carries none.
+func FromStatus(err error) Code {
+	st, ok := status.FromError(err)
+	if !ok {
+		return ""
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetDomain() == errorInfoDomain {
+			return Code(info.GetReason())
+		}
+	}
+	return ""
+}
+
+// grpcError gives err a code if it does not carry one yet, keeping its gRPC
+// status code and message.
+func grpcError(err error) error {
+	if err == nil || FromStatus(err) != "" {
+		return err
+	}
+	if st, ok := status.FromError(err); ok {
+		return withCode(st, ForGRPCCode(st.Code()))
+	}
+	return Status(CodeOf(err), err.Error())
+}
+
+// UnaryServerInterceptor makes every error a unary handler or a later
+// interceptor returns carry a code. Install it first in the chain.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		return resp, grpcError(err)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming RPCs.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return grpcError(handler(srv, ss))
+	}
+}
@@ -0,0 +1,115 @@
+// ABOUTME: Tests for error codes, their retry hints and their gRPC encoding
+// ABOUTME: Checks CodeOf through wrapping and that codes survive a gRPC status round trip
+
+package coverr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCodeOf(t *testing.T) {
+	errOffline := New(AgentOffline, "agent not found")
+	for _, tc := range []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{"nil", nil, ""},
+		{"coded", errOffline, AgentOffline},
+		{"wrapped", fmt.Errorf("agent send failed: %w", errOffline), AgentOffline},
+		{"canceled", fmt.Errorf("waiting: %w", context.Canceled), Canceled},
+		{"plain", errors.New("boom"), Internal},
+	} {
+		if got := CodeOf(tc.err); got != tc.want {
+			t.Errorf("%s: CodeOf = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRetriable(t *testing.T) {
+	for code, want := range map[Code]bool{
+		AgentOffline:     true,
+		AgentTimeout:     true,
+		RateLimited:      true,
+		ToolUnavailable:  true,
+		PackUnavailable:  true,
+		CapabilityDenied: false,
+		ToolDisabled:     false,
+		ToolNotFound:     false,
+		InvalidRequest:   false,
+		Internal:         false,
+	} {
+		if got := code.Retriable(); got != want {
+			t.Errorf("%s.Retriable() = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestNewBody(t *testing.T) {
+	body := NewBody(AgentTimeout, "agent sent nothing for 2m0s")
+	want := Body{Error: "agent sent nothing for 2m0s", Code: AgentTimeout, Message: "agent sent nothing for 2m0s", Retriable: true}
+	if body != want {
+		t.Errorf("NewBody = %+v, want %+v", body, want)
+	}
+}
+
+func TestForHTTPStatus(t *testing.T) {
+	for status, want := range map[int]Code{
+		http.StatusBadRequest:          InvalidRequest,
+		http.StatusUnauthorized:        Unauthenticated,
+		http.StatusForbidden:           PermissionDenied,
+		http.StatusNotFound:            NotFound,
+		http.StatusConflict:            Conflict,
+		http.StatusTooManyRequests:     RateLimited,
+		http.StatusInternalServerError: Internal,
+	} {
+		if got := ForHTTPStatus(status); got != want {
+			t.Errorf("ForHTTPStatus(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestStatusRoundTrip(t *testing.T) {
+	err := Status(AgentBusy, "agent busy")
+	st, _ := status.FromError(err)
+	if st.Code() != codes.ResourceExhausted || st.Message() != "agent busy" {
+		t.Errorf("status = %v %q, want ResourceExhausted \"agent busy\"", st.Code(), st.Message())
+	}
+	if got := FromStatus(err); got != AgentBusy {
+		t.Errorf("FromStatus = %q, want %q", got, AgentBusy)
+	}
+	if got := FromStatus(status.Error(codes.NotFound, "nope")); got != "" {
+		t.Errorf("FromStatus of a bare status = %q, want none", got)
+	}
+}
+
+func TestGRPCError(t *testing.T) {
+	if grpcError(nil) != nil {
+		t.Error("grpcError(nil) != nil")
+	}
+
+	// A bare status keeps its gRPC code and gains a code derived from it.
+	err := grpcError(status.Error(codes.InvalidArgument, "content required"))
+	if status.Code(err) != codes.InvalidArgument || FromStatus(err) != InvalidRequest {
+		t.Errorf("bare status: %v / %q, want InvalidArgument / invalid_request", status.Code(err), FromStatus(err))
+	}
+
+	// A coded status is left alone.
+	coded := Status(AgentPaused, "agent paused")
+	if err := grpcError(coded); FromStatus(err) != AgentPaused {
+		t.Errorf("coded status: FromStatus = %q, want %q", FromStatus(err), AgentPaused)
+	}
+
+	// A plain coded error becomes a status.
+	err = grpcError(fmt.Errorf("routing: %w", New(ToolNotFound, "tool not found")))
+	if status.Code(err) != codes.NotFound || FromStatus(err) != ToolNotFound {
+		t.Errorf("plain error: %v / %q, want NotFound / tool_not_found", status.Code(err), FromStatus(err))
+	}
+}
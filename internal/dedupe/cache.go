@@ -79,6 +79,16 @@ func (c *Cache) Mark(key string) {
 	c.markLocked(key)
 }
 
+// Forget removes a key, so it is no longer seen.
+func (c *Cache) Forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.seen[key]; ok {
+		c.order.Remove(entry.element)
+		delete(c.seen, key)
+	}
+}
+
 // markLocked is the internal mark implementation. Must be called with mu held.
 func (c *Cache) markLocked(key string) {
 	now := time.Now()
@@ -66,6 +66,24 @@ func TestCache_Mark(t *testing.T) {
 	assert.False(t, cache.Check("key-4"))
 }
 
+func TestCache_Forget(t *testing.T) {
+	cache := New(5*time.Minute, 2)
+	defer cache.Close()
+
+	cache.Mark("key-1")
+	cache.Mark("key-2")
+	cache.Forget("key-1")
+	cache.Forget("never-seen-key")
+
+	assert.False(t, cache.Check("key-1"))
+	assert.True(t, cache.Check("key-2"))
+
+	// The forgotten key freed its slot, so marking another evicts nothing.
+	cache.Mark("key-3")
+	assert.True(t, cache.Check("key-2"))
+	assert.True(t, cache.Check("key-3"))
+}
+
 func TestCache_Mark_UpdatesTimestamp(t *testing.T) {
 	// Use a short TTL
 	cache := New(50*time.Millisecond, 100)
@@ -43,6 +43,18 @@
 //   - Multiple bridge instances
 //   - Federation delays
 //
+// # Send Idempotency Keys
+//
+// Idempotency tracks the idempotency keys clients put on sends, for both
+// POST /api/send and gRPC SendMessage. Keys are scoped per principal and
+// persisted with the request they started, so a retry after a restart still
+// gets the original request back:
+//
+//	prior, err := keys.Begin(ctx, principalID, key)
+//	// prior == nil: send, then Complete(requestID) or Abandon on failure
+//
+// Its window comes from api.idempotency_ttl rather than the bridge TTL below.
+//
 // # Configuration
 //
 // The TTL should be long enough to catch retries but short enough
@@ -0,0 +1,110 @@
+// ABOUTME: Per-principal idempotency keys for sends, shared by POST /api/send and gRPC SendMessage.
+// ABOUTME: A Cache answers recent retries; the store keeps each key's request ID across restarts.
+
+package dedupe
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+const (
+	// MaxKeyLength is the longest idempotency key a send may carry.
+	MaxKeyLength = 100
+
+	// DefaultIdempotencyWindow is how long a key is remembered when the
+	// gateway does not configure api.idempotency_ttl.
+	DefaultIdempotencyWindow = 24 * time.Hour
+
+	// maxIdempotencyKeys bounds the keys the in-memory cache tracks; older
+	// ones are still found in the store.
+	maxIdempotencyKeys = 100_000
+)
+
+// IdempotencyStore is the storage an Idempotency needs.
+type IdempotencyStore interface {
+	ClaimIdempotencyKey(ctx context.Context, principalID, key string, now, expiredBefore time.Time) (bool, error)
+	GetIdempotencyKey(ctx context.Context, principalID, key string) (*store.IdempotencyKey, error)
+	SetIdempotencyKeyRequest(ctx context.Context, principalID, key, requestID string) error
+	DeleteIdempotencyKey(ctx context.Context, principalID, key string) error
+}
+
+// Idempotency tracks the idempotency keys of sends, scoped per principal, for
+// a window after each is first used.
+type Idempotency struct {
+	seen   *Cache
+	store  IdempotencyStore
+	window time.Duration
+}
+
+// NewIdempotency returns an Idempotency remembering keys for window, or
+// DefaultIdempotencyWindow when window is not positive. Close it when done.
+func NewIdempotency(s IdempotencyStore, window time.Duration) *Idempotency {
+	if window <= 0 {
+		window = DefaultIdempotencyWindow
+	}
+	return &Idempotency{seen: New(window, maxIdempotencyKeys), store: s, window: window}
+}
+
+// Window is how long a key is remembered.
+func (i *Idempotency) Window() time.Duration {
+	return i.window
+}
+
+// Begin claims key for principalID. A nil result means the caller holds the
+// key and must Complete it once the send is accepted, or Abandon it if the
+// send fails. Otherwise the key was already used within the window and the
+// result is that use; its RequestID is empty while that send is still being
+// accepted.
+func (i *Idempotency) Begin(ctx context.Context, principalID, key string) (*store.IdempotencyKey, error) {
+	scoped := principalID + "\x00" + key
+	now := time.Now()
+	if i.seen.Check(scoped) {
+		prior, err := i.store.GetIdempotencyKey(ctx, principalID, key)
+		if err == nil && prior.CreatedAt.After(now.Add(-i.window)) {
+			return prior, nil
+		}
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	claimed, err := i.store.ClaimIdempotencyKey(ctx, principalID, key, now, now.Add(-i.window))
+	if err != nil {
+		return nil, err
+	}
+	i.seen.Mark(scoped)
+	if claimed {
+		return nil, nil
+	}
+	prior, err := i.store.GetIdempotencyKey(ctx, principalID, key)
+	if errors.Is(err, store.ErrNotFound) {
+		// The holder abandoned the key since the claim failed; its send did
+		// not go through, but this one lost the race all the same.
+		return &store.IdempotencyKey{PrincipalID: principalID, Key: key, CreatedAt: now}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return prior, nil
+}
+
+// Complete records the request the send holding key started, so retries
+// are answered with it.
+func (i *Idempotency) Complete(ctx context.Context, principalID, key, requestID string) error {
+	return i.store.SetIdempotencyKeyRequest(ctx, principalID, key, requestID)
+}
+
+// Abandon releases a key whose send failed, so a retry is processed afresh.
+func (i *Idempotency) Abandon(ctx context.Context, principalID, key string) error {
+	i.seen.Forget(principalID + "\x00" + key)
+	return i.store.DeleteIdempotencyKey(ctx, principalID, key)
+}
+
+// Close stops the in-memory cache's cleanup goroutine.
+func (i *Idempotency) Close() {
+	i.seen.Close()
+}
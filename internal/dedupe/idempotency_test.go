@@ -0,0 +1,92 @@
+// ABOUTME: Tests for per-principal send idempotency keys.
+// ABOUTME: Covers claiming, duplicates before and after completion, abandoning, and surviving a restart.
+
+package dedupe
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func newIdempotencyStore(t *testing.T) *store.SQLStore {
+	t.Helper()
+	s, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestIdempotency_BeginCompleteDuplicate(t *testing.T) {
+	s := newIdempotencyStore(t)
+	keys := NewIdempotency(s, time.Hour)
+	defer keys.Close()
+	ctx := context.Background()
+
+	prior, err := keys.Begin(ctx, "alice", "key-1")
+	require.NoError(t, err)
+	assert.Nil(t, prior, "first use should hold the key")
+
+	// A retry while the first send is being accepted has no request yet.
+	prior, err = keys.Begin(ctx, "alice", "key-1")
+	require.NoError(t, err)
+	require.NotNil(t, prior)
+	assert.Empty(t, prior.RequestID)
+
+	require.NoError(t, keys.Complete(ctx, "alice", "key-1", "req-1"))
+	prior, err = keys.Begin(ctx, "alice", "key-1")
+	require.NoError(t, err)
+	require.NotNil(t, prior)
+	assert.Equal(t, "req-1", prior.RequestID)
+
+	// Keys are scoped per principal.
+	prior, err = keys.Begin(ctx, "bob", "key-1")
+	require.NoError(t, err)
+	assert.Nil(t, prior)
+}
+
+func TestIdempotency_Abandon(t *testing.T) {
+	s := newIdempotencyStore(t)
+	keys := NewIdempotency(s, time.Hour)
+	defer keys.Close()
+	ctx := context.Background()
+
+	_, err := keys.Begin(ctx, "alice", "key-1")
+	require.NoError(t, err)
+	require.NoError(t, keys.Abandon(ctx, "alice", "key-1"))
+
+	prior, err := keys.Begin(ctx, "alice", "key-1")
+	require.NoError(t, err)
+	assert.Nil(t, prior, "an abandoned key should be free again")
+}
+
+func TestIdempotency_SurvivesRestart(t *testing.T) {
+	s := newIdempotencyStore(t)
+	ctx := context.Background()
+
+	first := NewIdempotency(s, time.Hour)
+	_, err := first.Begin(ctx, "alice", "key-1")
+	require.NoError(t, err)
+	require.NoError(t, first.Complete(ctx, "alice", "key-1", "req-1"))
+	first.Close()
+
+	// A fresh cache, as after a restart, still finds the key in the store.
+	second := NewIdempotency(s, time.Hour)
+	defer second.Close()
+	prior, err := second.Begin(ctx, "alice", "key-1")
+	require.NoError(t, err)
+	require.NotNil(t, prior)
+	assert.Equal(t, "req-1", prior.RequestID)
+}
+
+func TestIdempotency_DefaultWindow(t *testing.T) {
+	keys := NewIdempotency(newIdempotencyStore(t), 0)
+	defer keys.Close()
+	assert.Equal(t, DefaultIdempotencyWindow, keys.Window())
+}
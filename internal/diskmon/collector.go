@@ -0,0 +1,70 @@
+// ABOUTME: Prometheus collector exporting the monitor's storage gauges.
+// ABOUTME: Values come from the latest check rather than probing at scrape time.
+
+package diskmon
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	diskFreeDesc = prometheus.NewDesc(
+		"coven_storage_disk_free_bytes",
+		"Free bytes on the database volume at the last check.",
+		nil, nil,
+	)
+	diskTotalDesc = prometheus.NewDesc(
+		"coven_storage_disk_total_bytes",
+		"Capacity of the database volume at the last check.",
+		nil, nil,
+	)
+	dbSizeDesc = prometheus.NewDesc(
+		"coven_storage_db_size_bytes",
+		"Size of the SQLite database file, labeled by file (db or wal).",
+		[]string{"file"}, nil,
+	)
+	growthDesc = prometheus.NewDesc(
+		"coven_storage_growth_bytes_per_day",
+		"Database plus WAL growth extrapolated from the last 24 hours of samples.",
+		nil, nil,
+	)
+	warningDesc = prometheus.NewDesc(
+		"coven_storage_warning",
+		"1 if any storage threshold is exceeded, else 0.",
+		nil, nil,
+	)
+	degradedDesc = prometheus.NewDesc(
+		"coven_storage_degraded",
+		"1 if free space is below the hard floor and new sends are refused, else 0.",
+		nil, nil,
+	)
+)
+
+// Describe implements prometheus.Collector.
+func (m *Monitor) Describe(ch chan<- *prometheus.Desc) {
+	ch <- diskFreeDesc
+	ch <- diskTotalDesc
+	ch <- dbSizeDesc
+	ch <- growthDesc
+	ch <- warningDesc
+	ch <- degradedDesc
+}
+
+// Collect implements prometheus.Collector.
+func (m *Monitor) Collect(ch chan<- prometheus.Metric) {
+	st := m.Status()
+	warning, degraded := 0.0, 0.0
+	if st.Level != LevelOK {
+		warning = 1
+	}
+	if st.Degraded {
+		degraded = 1
+	}
+	ch <- prometheus.MustNewConstMetric(diskFreeDesc, prometheus.GaugeValue, float64(st.DiskFree))
+	ch <- prometheus.MustNewConstMetric(diskTotalDesc, prometheus.GaugeValue, float64(st.DiskTotal))
+	ch <- prometheus.MustNewConstMetric(dbSizeDesc, prometheus.GaugeValue, float64(st.DBSize), "db")
+	ch <- prometheus.MustNewConstMetric(dbSizeDesc, prometheus.GaugeValue, float64(st.WALSize), "wal")
+	ch <- prometheus.MustNewConstMetric(growthDesc, prometheus.GaugeValue, float64(st.GrowthPerDay))
+	ch <- prometheus.MustNewConstMetric(warningDesc, prometheus.GaugeValue, warning)
+	ch <- prometheus.MustNewConstMetric(degradedDesc, prometheus.GaugeValue, degraded)
+}
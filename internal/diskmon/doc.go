@@ -0,0 +1,32 @@
+// Package diskmon watches the storage behind the gateway's SQLite database
+// and raises alarms before the disk fills.
+//
+// # Overview
+//
+// A Monitor checks on a fixed interval (one minute by default). Each check
+// records:
+//
+//   - free and total space on the volume holding the database
+//   - the size of the database file and its WAL
+//   - growth per day, extrapolated from samples over the last 24 hours
+//
+// # Levels
+//
+// The status is "warning" when free space falls below WarnFreePercent or the
+// database plus WAL exceed WarnDBSize. It is "critical" when free space drops
+// below MinFree, the hard floor. While critical the monitor is degraded:
+// conversation.Service refuses new sends with ErrStorageFull, while reads
+// keep working. Degraded mode ends on its own once free space climbs 10%
+// above the floor, so a volume hovering at the limit does not flap.
+//
+// If the disk probe fails, the check reports a warning and keeps the previous
+// degraded decision.
+//
+// # Surfacing
+//
+// Level changes are logged and passed to Config.Notify; WebhookNotifier
+// posts them as JSON. Monitor implements prometheus.Collector, exposing
+// coven_storage_* gauges. The gateway includes the status in
+// GET /health/ready?verbose=1, and the web admin serves it at
+// GET /api/admin/storage for its storage banner.
+package diskmon
@@ -0,0 +1,340 @@
+// ABOUTME: Background checker for free disk space, database/WAL size, and growth rate.
+// ABOUTME: Raises warnings past configured thresholds and flags degraded mode below a hard floor.
+
+package diskmon
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+)
+
+// ErrStorageFull is returned for writes refused while free space is below
+// the hard floor.
+var ErrStorageFull = coverr.New(coverr.StorageFull, "storage_full: free disk space is below the configured floor")
+
+// Defaults applied when Config fields are zero.
+const (
+	DefaultInterval        = time.Minute
+	DefaultWarnFreePercent = 10.0
+	DefaultMinFree         = 256 << 20 // 256 MiB
+)
+
+// growthWindow is how far back growth rate samples reach.
+const growthWindow = 24 * time.Hour
+
+// maxSamples bounds the growth history regardless of interval.
+const maxSamples = 2000
+
+// Level summarizes storage health.
+type Level string
+
+const (
+	LevelOK       Level = "ok"
+	LevelWarning  Level = "warning"
+	LevelCritical Level = "critical" // below the hard floor; sends are refused
+)
+
+// DiskUsage is a filesystem's capacity and free space in bytes.
+type DiskUsage struct {
+	Total uint64
+	Free  uint64 // available to unprivileged users
+}
+
+// Probe reports usage of the filesystem holding dir.
+type Probe interface {
+	DiskUsage(dir string) (DiskUsage, error)
+}
+
+// ProbeFunc adapts a function to Probe.
+type ProbeFunc func(dir string) (DiskUsage, error)
+
+// DiskUsage implements Probe.
+func (f ProbeFunc) DiskUsage(dir string) (DiskUsage, error) { return f(dir) }
+
+// Alert is sent to Config.Notify when the storage level changes.
+type Alert struct {
+	Event    string `json:"event"` // always "storage_level_changed"
+	Level    Level  `json:"level"`
+	Previous Level  `json:"previous"`
+	Status   Status `json:"status"`
+}
+
+// Config controls check frequency and thresholds.
+type Config struct {
+	// Interval between checks.
+	Interval time.Duration
+	// WarnFreePercent warns when free space drops below this share of the disk.
+	WarnFreePercent float64
+	// WarnDBSize warns when database plus WAL exceed this many bytes; zero disables.
+	WarnDBSize int64
+	// MinFree is the hard floor in bytes. Below it the monitor reports
+	// degraded and sends are refused; it recovers once free space is 10%
+	// above the floor again.
+	MinFree int64
+
+	// Probe measures the database volume; nil uses the operating system.
+	Probe Probe
+	// Notify, if set, is called on every level change.
+	Notify func(context.Context, Alert)
+	// Now overrides the clock in tests.
+	Now func() time.Time
+}
+
+// Status is the result of the latest check.
+type Status struct {
+	Level        Level     `json:"level"`
+	Degraded     bool      `json:"degraded"`
+	Warnings     []string  `json:"warnings,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	DiskTotal    uint64    `json:"disk_total_bytes"`
+	DiskFree     uint64    `json:"disk_free_bytes"`
+	FreePercent  float64   `json:"free_percent"`
+	DBSize       int64     `json:"db_size_bytes"`
+	WALSize      int64     `json:"wal_size_bytes"`
+	GrowthPerDay int64     `json:"growth_bytes_per_day"`
+	CheckedAt    time.Time `json:"checked_at,omitzero"`
+}
+
+type sample struct {
+	at   time.Time
+	size int64
+}
+
+// Monitor periodically checks storage for one SQLite database. It is safe
+// for concurrent use.
+type Monitor struct {
+	cfg    Config
+	dbPath string
+	logger *slog.Logger
+
+	degraded atomic.Bool
+	started  atomic.Bool
+
+	mu      sync.Mutex
+	status  Status
+	samples []sample
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// New creates a Monitor for the database at dbPath, filling zero Config
+// fields with defaults. Call Start to begin periodic checks.
+func New(dbPath string, cfg Config, logger *slog.Logger) *Monitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.WarnFreePercent <= 0 {
+		cfg.WarnFreePercent = DefaultWarnFreePercent
+	}
+	if cfg.MinFree <= 0 {
+		cfg.MinFree = DefaultMinFree
+	}
+	if cfg.Probe == nil {
+		cfg.Probe = ProbeFunc(systemDiskUsage)
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Monitor{
+		cfg:    cfg,
+		dbPath: dbPath,
+		logger: logger,
+		status: Status{Level: LevelOK},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start runs a check immediately and then every Interval until Close.
+func (m *Monitor) Start() {
+	m.Check(context.Background())
+	m.started.Store(true)
+	go m.run()
+}
+
+func (m *Monitor) run() {
+	defer close(m.done)
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.Check(context.Background())
+		}
+	}
+}
+
+// Close stops periodic checks and waits for the checker to exit. It is safe
+// to call on a Monitor that was never started.
+func (m *Monitor) Close() {
+	m.stopOnce.Do(func() { close(m.stop) })
+	if m.started.Load() {
+		<-m.done
+	}
+}
+
+// Degraded reports whether free space is below the hard floor.
+func (m *Monitor) Degraded() bool {
+	return m.degraded.Load()
+}
+
+// CheckWrite returns ErrStorageFull while degraded.
+func (m *Monitor) CheckWrite() error {
+	if m.Degraded() {
+		return ErrStorageFull
+	}
+	return nil
+}
+
+// Status returns the result of the latest check.
+func (m *Monitor) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.status
+	st.Warnings = append([]string(nil), st.Warnings...)
+	return st
+}
+
+// Config returns the effective configuration.
+func (m *Monitor) Config() Config {
+	return m.cfg
+}
+
+// Check samples storage now, updates the status, and notifies on a level change.
+func (m *Monitor) Check(ctx context.Context) Status {
+	now := m.cfg.Now()
+	st := Status{CheckedAt: now}
+	st.DBSize = fileSize(m.dbPath)
+	st.WALSize = fileSize(m.dbPath + "-wal")
+
+	usage, err := m.cfg.Probe.DiskUsage(filepath.Dir(m.dbPath))
+
+	m.mu.Lock()
+	prev := m.status
+	st.GrowthPerDay = m.recordSample(now, st.DBSize+st.WALSize)
+
+	if err != nil {
+		// Without a reading, keep the previous degraded decision
+		st.Error = fmt.Sprintf("disk probe failed: %v", err)
+		st.Degraded = prev.Degraded
+		st.Warnings = append(st.Warnings, st.Error)
+	} else {
+		st.DiskTotal, st.DiskFree = usage.Total, usage.Free
+		if usage.Total > 0 {
+			st.FreePercent = float64(usage.Free) / float64(usage.Total) * 100
+		}
+		st.Degraded = m.belowFloor(usage.Free, prev.Degraded)
+		if st.Degraded {
+			st.Warnings = append(st.Warnings, fmt.Sprintf("free space %s is below the %s floor; new sends are refused",
+				FormatBytes(int64(usage.Free)), FormatBytes(m.cfg.MinFree)))
+		} else if usage.Total > 0 && st.FreePercent < m.cfg.WarnFreePercent {
+			st.Warnings = append(st.Warnings, fmt.Sprintf("free space is %.1f%% (%s), below the %.0f%% warning threshold",
+				st.FreePercent, FormatBytes(int64(usage.Free)), m.cfg.WarnFreePercent))
+		}
+	}
+	if m.cfg.WarnDBSize > 0 && st.DBSize+st.WALSize > m.cfg.WarnDBSize {
+		st.Warnings = append(st.Warnings, fmt.Sprintf("database and WAL use %s, above the %s warning threshold",
+			FormatBytes(st.DBSize+st.WALSize), FormatBytes(m.cfg.WarnDBSize)))
+	}
+
+	switch {
+	case st.Degraded:
+		st.Level = LevelCritical
+	case len(st.Warnings) > 0:
+		st.Level = LevelWarning
+	default:
+		st.Level = LevelOK
+	}
+	m.status = st
+	m.degraded.Store(st.Degraded)
+	m.mu.Unlock()
+
+	if st.Level != prev.Level {
+		m.logTransition(prev.Level, st)
+		if m.cfg.Notify != nil {
+			m.cfg.Notify(ctx, Alert{Event: "storage_level_changed", Level: st.Level, Previous: prev.Level, Status: st})
+		}
+	}
+	return st
+}
+
+// belowFloor applies the hard floor with hysteresis, so free space hovering
+// at the floor does not flap in and out of degraded mode.
+func (m *Monitor) belowFloor(free uint64, wasDegraded bool) bool {
+	floor := m.cfg.MinFree
+	if wasDegraded {
+		floor += floor / 10
+	}
+	return int64(free) < floor
+}
+
+// recordSample appends a size sample and returns growth per day over the
+// retained window, or zero with fewer than two samples. Callers hold m.mu.
+func (m *Monitor) recordSample(now time.Time, size int64) int64 {
+	m.samples = append(m.samples, sample{at: now, size: size})
+	cut := 0
+	for cut < len(m.samples)-1 && now.Sub(m.samples[cut].at) > growthWindow {
+		cut++
+	}
+	if over := len(m.samples) - cut - maxSamples; over > 0 {
+		cut += over
+	}
+	m.samples = m.samples[cut:]
+
+	first := m.samples[0]
+	elapsed := now.Sub(first.at)
+	if elapsed <= 0 {
+		return 0
+	}
+	return int64(float64(size-first.size) * float64(24*time.Hour) / float64(elapsed))
+}
+
+func (m *Monitor) logTransition(prev Level, st Status) {
+	attrs := []any{"level", st.Level, "previous", prev, "disk_free", st.DiskFree, "db_size", st.DBSize, "wal_size", st.WALSize}
+	switch st.Level {
+	case LevelCritical:
+		m.logger.Error("storage below hard floor, refusing new sends", append(attrs, "warnings", st.Warnings)...)
+	case LevelWarning:
+		m.logger.Warn("storage warning", append(attrs, "warnings", st.Warnings)...)
+	default:
+		m.logger.Info("storage recovered", attrs...)
+	}
+}
+
+// fileSize returns the size of path, or zero if it does not exist.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// FormatBytes renders n with a binary unit, e.g. "1.5 GiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,256 @@
+// ABOUTME: Tests for the storage monitor, its webhook notifier, and Prometheus collector.
+// ABOUTME: Uses an injected disk probe and clock so thresholds are exercised deterministically.
+
+package diskmon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const mib = 1 << 20
+
+// fakeProbe reports whatever free space the test sets.
+type fakeProbe struct {
+	total, free uint64
+	err         error
+}
+
+func (p *fakeProbe) DiskUsage(string) (DiskUsage, error) {
+	return DiskUsage{Total: p.total, Free: p.free}, p.err
+}
+
+type fixture struct {
+	mon    *Monitor
+	probe  *fakeProbe
+	now    *time.Time
+	dbPath string
+	alerts []Alert
+}
+
+func newFixture(t *testing.T, cfg Config) *fixture {
+	t.Helper()
+	f := &fixture{
+		probe:  &fakeProbe{total: 1000 * mib, free: 500 * mib},
+		dbPath: filepath.Join(t.TempDir(), "gateway.db"),
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = &now
+	cfg.Probe = f.probe
+	cfg.Now = func() time.Time { return *f.now }
+	cfg.Notify = func(_ context.Context, a Alert) { f.alerts = append(f.alerts, a) }
+	f.mon = New(f.dbPath, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return f
+}
+
+func (f *fixture) writeDB(t *testing.T, dbBytes, walBytes int) {
+	t.Helper()
+	if err := os.WriteFile(f.dbPath, make([]byte, dbBytes), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(f.dbPath+"-wal", make([]byte, walBytes), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMonitor_HealthyDisk(t *testing.T) {
+	f := newFixture(t, Config{})
+	f.writeDB(t, 4096, 1024)
+
+	st := f.mon.Check(context.Background())
+	if st.Level != LevelOK || st.Degraded || len(st.Warnings) != 0 {
+		t.Errorf("status = %+v, want ok", st)
+	}
+	if st.DBSize != 4096 || st.WALSize != 1024 {
+		t.Errorf("sizes = %d/%d, want 4096/1024", st.DBSize, st.WALSize)
+	}
+	if st.FreePercent != 50 {
+		t.Errorf("free percent = %v, want 50", st.FreePercent)
+	}
+	if len(f.alerts) != 0 {
+		t.Errorf("alerts = %v, want none for ok at startup", f.alerts)
+	}
+}
+
+func TestMonitor_FreePercentWarning(t *testing.T) {
+	f := newFixture(t, Config{WarnFreePercent: 20, MinFree: 10 * mib})
+	f.probe.free = 150 * mib
+
+	st := f.mon.Check(context.Background())
+	if st.Level != LevelWarning || st.Degraded {
+		t.Fatalf("status = %+v, want warning without degraded", st)
+	}
+	if !strings.Contains(st.Warnings[0], "below the 20% warning threshold") {
+		t.Errorf("warning = %q", st.Warnings[0])
+	}
+	if len(f.alerts) != 1 || f.alerts[0].Level != LevelWarning || f.alerts[0].Previous != LevelOK {
+		t.Errorf("alerts = %+v, want one ok->warning", f.alerts)
+	}
+	if err := f.mon.CheckWrite(); err != nil {
+		t.Errorf("CheckWrite = %v, want nil while only warning", err)
+	}
+}
+
+func TestMonitor_DBSizeWarning(t *testing.T) {
+	f := newFixture(t, Config{WarnDBSize: 3000})
+	f.writeDB(t, 2000, 2000)
+
+	st := f.mon.Check(context.Background())
+	if st.Level != LevelWarning {
+		t.Fatalf("level = %s, want warning", st.Level)
+	}
+	if !strings.Contains(st.Warnings[0], "database and WAL use") {
+		t.Errorf("warning = %q", st.Warnings[0])
+	}
+}
+
+func TestMonitor_HardFloorAndRecovery(t *testing.T) {
+	f := newFixture(t, Config{MinFree: 100 * mib})
+
+	f.probe.free = 90 * mib
+	st := f.mon.Check(context.Background())
+	if st.Level != LevelCritical || !st.Degraded {
+		t.Fatalf("status = %+v, want critical and degraded", st)
+	}
+	if !errors.Is(f.mon.CheckWrite(), ErrStorageFull) {
+		t.Errorf("CheckWrite = %v, want ErrStorageFull", f.mon.CheckWrite())
+	}
+
+	// Just above the floor is inside the hysteresis band: still degraded
+	f.probe.free = 105 * mib
+	if st := f.mon.Check(context.Background()); !st.Degraded {
+		t.Error("recovered inside hysteresis band")
+	}
+
+	f.probe.free = 400 * mib
+	st = f.mon.Check(context.Background())
+	if st.Degraded || st.Level != LevelOK {
+		t.Errorf("status = %+v, want recovered to ok", st)
+	}
+	if err := f.mon.CheckWrite(); err != nil {
+		t.Errorf("CheckWrite after recovery = %v", err)
+	}
+
+	var levels []Level
+	for _, a := range f.alerts {
+		levels = append(levels, a.Level)
+	}
+	if len(levels) != 2 || levels[0] != LevelCritical || levels[1] != LevelOK {
+		t.Errorf("alert levels = %v, want [critical ok]", levels)
+	}
+}
+
+func TestMonitor_ProbeErrorKeepsDegraded(t *testing.T) {
+	f := newFixture(t, Config{MinFree: 100 * mib})
+	f.probe.free = 10 * mib
+	f.mon.Check(context.Background())
+
+	f.probe.err = errors.New("stale NFS handle")
+	st := f.mon.Check(context.Background())
+	if !st.Degraded {
+		t.Error("probe error cleared degraded mode")
+	}
+	if !strings.Contains(st.Error, "stale NFS handle") {
+		t.Errorf("error = %q", st.Error)
+	}
+}
+
+func TestMonitor_GrowthRate(t *testing.T) {
+	f := newFixture(t, Config{Interval: time.Hour})
+	f.writeDB(t, 1000, 0)
+	if st := f.mon.Check(context.Background()); st.GrowthPerDay != 0 {
+		t.Errorf("growth with one sample = %d, want 0", st.GrowthPerDay)
+	}
+
+	*f.now = f.now.Add(6 * time.Hour)
+	f.writeDB(t, 1500, 500)
+	if st := f.mon.Check(context.Background()); st.GrowthPerDay != 4000 {
+		t.Errorf("growth = %d, want 4000/day (1000 bytes in 6h)", st.GrowthPerDay)
+	}
+
+	// Samples older than 24h drop out of the window
+	*f.now = f.now.Add(30 * time.Hour)
+	f.mon.Check(context.Background())
+	*f.now = f.now.Add(12 * time.Hour)
+	f.writeDB(t, 3000, 0)
+	if st := f.mon.Check(context.Background()); st.GrowthPerDay != 2000 {
+		t.Errorf("growth = %d, want 2000/day (1000 bytes in 12h)", st.GrowthPerDay)
+	}
+}
+
+func TestMonitor_StartAndClose(t *testing.T) {
+	f := newFixture(t, Config{Interval: time.Millisecond})
+	f.probe.free = 1 * mib
+	f.mon.Start()
+	if !f.mon.Degraded() {
+		t.Error("Start should run an initial check")
+	}
+	f.mon.Close()
+	f.mon.Close()
+
+	New(f.dbPath, Config{Probe: f.probe}, nil).Close()
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	got := make(chan Alert, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("content type = %q", ct)
+		}
+		var a Alert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			t.Errorf("decoding: %v", err)
+		}
+		got <- a
+	}))
+	defer srv.Close()
+
+	notify := WebhookNotifier(srv.URL, srv.Client(), nil)
+	notify(context.Background(), Alert{Event: "storage_level_changed", Level: LevelCritical, Previous: LevelOK})
+
+	a := <-got
+	if a.Level != LevelCritical || a.Previous != LevelOK {
+		t.Errorf("alert = %+v", a)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	f := newFixture(t, Config{MinFree: 100 * mib})
+	f.probe.free = 50 * mib
+	f.writeDB(t, 2048, 0)
+	f.mon.Check(context.Background())
+
+	expected := `
+# HELP coven_storage_degraded 1 if free space is below the hard floor and new sends are refused, else 0.
+# TYPE coven_storage_degraded gauge
+coven_storage_degraded 1
+# HELP coven_storage_db_size_bytes Size of the SQLite database file, labeled by file (db or wal).
+# TYPE coven_storage_db_size_bytes gauge
+coven_storage_db_size_bytes{file="db"} 2048
+coven_storage_db_size_bytes{file="wal"} 0
+`
+	if err := testutil.CollectAndCompare(f.mon, strings.NewReader(expected),
+		"coven_storage_degraded", "coven_storage_db_size_bytes"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{512: "512 B", 1536: "1.5 KiB", 256 * mib: "256.0 MiB", 3 << 30: "3.0 GiB"} {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+// ABOUTME: Disk usage probe fallback for platforms without statfs(2).
+// ABOUTME: Always errors, so the monitor reports the probe failure instead of guessing.
+
+//go:build !unix
+
+package diskmon
+
+import (
+	"errors"
+	"runtime"
+)
+
+func systemDiskUsage(string) (DiskUsage, error) {
+	return DiskUsage{}, errors.New("disk usage probe not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,20 @@
+// ABOUTME: Disk usage probe backed by statfs(2) on Unix systems.
+// ABOUTME: Reports space available to unprivileged users, matching what SQLite can use.
+
+//go:build unix
+
+package diskmon
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func systemDiskUsage(dir string) (DiskUsage, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(dir, &fs); err != nil {
+		return DiskUsage{}, fmt.Errorf("statfs %s: %w", dir, err)
+	}
+	bsize := uint64(fs.Bsize) // Bsize is int64 on Linux, uint32 on Darwin
+	return DiskUsage{Total: fs.Blocks * bsize, Free: fs.Bavail * bsize}, nil
+}
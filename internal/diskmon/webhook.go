@@ -0,0 +1,54 @@
+// ABOUTME: Webhook notifier that POSTs storage alerts as JSON.
+// ABOUTME: Delivery is best effort; failures are logged and not retried.
+
+package diskmon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const webhookTimeout = 5 * time.Second
+
+// WebhookNotifier returns a Config.Notify function that POSTs each Alert to url.
+func WebhookNotifier(url string, client *http.Client, logger *slog.Logger) func(context.Context, Alert) {
+	if client == nil {
+		client = &http.Client{Timeout: webhookTimeout}
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(ctx context.Context, alert Alert) {
+		if err := postAlert(ctx, client, url, alert); err != nil {
+			logger.Warn("storage alert webhook failed", "url", url, "level", alert.Level, "error", err)
+		}
+	}
+}
+
+func postAlert(ctx context.Context, client *http.Client, url string, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("encoding alert: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting alert: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
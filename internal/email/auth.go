@@ -0,0 +1,71 @@
+// ABOUTME: Combines SPF and DKIM outcomes into the authentication summary recorded for each email.
+// ABOUTME: Checks alignment with the From domain and reads upstream Authentication-Results for IMAP.
+
+package email
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// AuthResults is how an inbound message authenticated.
+type AuthResults struct {
+	SPF       string // SPF result keyword
+	SPFDomain string // domain SPF was evaluated for
+	DKIM      DKIMResult
+}
+
+// Details renders the results like an Authentication-Results header value.
+func (a AuthResults) Details() string {
+	return spfDetail(a.SPF, a.SPFDomain) + "; " + dkimDetail(a.DKIM)
+}
+
+// AlignedWith reports whether SPF or DKIM passed for a domain related to the
+// From domain (equal, or one a subdomain of the other). A pass for an
+// unrelated domain proves nothing about who wrote From.
+func (a AuthResults) AlignedWith(fromDomain string) bool {
+	return (a.SPF == ResultPass && domainsAligned(a.SPFDomain, fromDomain)) ||
+		(a.DKIM.Result == ResultPass && domainsAligned(a.DKIM.Domain, fromDomain))
+}
+
+func domainsAligned(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	if a == "" || b == "" {
+		return false
+	}
+	return a == b || strings.HasSuffix(a, "."+b) || strings.HasSuffix(b, "."+a)
+}
+
+// authenticateSMTP checks SPF for the connection and DKIM for the content.
+func authenticateSMTP(ctx context.Context, r Resolver, env *Envelope) AuthResults {
+	res := AuthResults{
+		SPF:  CheckSPF(ctx, r, env.RemoteIP, env.Helo, env.MailFrom),
+		DKIM: VerifyDKIM(ctx, r, env.Data),
+	}
+	if _, d, ok := strings.Cut(env.MailFrom, "@"); ok {
+		res.SPFDomain = strings.ToLower(d)
+	} else {
+		res.SPFDomain = strings.ToLower(env.Helo)
+	}
+	return res
+}
+
+var (
+	spfResultPattern   = regexp.MustCompile(`(?i)\bspf=([a-z]+)`)
+	spfMailFromPattern = regexp.MustCompile(`(?i)\bsmtp\.mailfrom=(?:[^@\s;]*@)?([^\s;]+)`)
+)
+
+// authenticateFetched verifies DKIM locally; SPF cannot be checked without
+// the connecting IP, so it is taken from the Authentication-Results header
+// the mailbox's own server added. Mail without one records spf=none.
+func authenticateFetched(ctx context.Context, r Resolver, m *Message, raw []byte) AuthResults {
+	res := AuthResults{SPF: ResultNone, DKIM: VerifyDKIM(ctx, r, raw)}
+	if match := spfResultPattern.FindStringSubmatch(m.AuthenticationResults); match != nil {
+		res.SPF = strings.ToLower(match[1])
+		if from := spfMailFromPattern.FindStringSubmatch(m.AuthenticationResults); from != nil {
+			res.SPFDomain = strings.ToLower(from[1])
+		}
+	}
+	return res
+}
@@ -0,0 +1,242 @@
+// ABOUTME: Tests for SPF evaluation and DKIM verification against a fake DNS resolver.
+// ABOUTME: Signs messages in-test with RSA and Ed25519 keys published under the selector.
+
+package email
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeResolver answers from fixed tables; unknown names are NXDOMAIN.
+type fakeResolver struct {
+	txt map[string][]string
+	ips map[string][]string
+	mx  map[string][]string
+}
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if v, ok := r.txt[strings.TrimSuffix(name, ".")]; ok {
+		return v, nil
+	}
+	return nil, notFound(name)
+}
+
+func (r *fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	v, ok := r.ips[strings.TrimSuffix(host, ".")]
+	if !ok {
+		return nil, notFound(host)
+	}
+	addrs := make([]net.IPAddr, len(v))
+	for i, s := range v {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(s)}
+	}
+	return addrs, nil
+}
+
+func (r *fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	v, ok := r.mx[strings.TrimSuffix(name, ".")]
+	if !ok {
+		return nil, notFound(name)
+	}
+	mxs := make([]*net.MX, len(v))
+	for i, host := range v {
+		mxs[i] = &net.MX{Host: host, Pref: uint16(10 * (i + 1))}
+	}
+	return mxs, nil
+}
+
+func TestCheckSPF(t *testing.T) {
+	r := &fakeResolver{
+		txt: map[string][]string{
+			"example.com":      {"v=spf1 ip4:192.0.2.0/24 include:_spf.example.net -all"},
+			"_spf.example.net": {"v=spf1 a:relay.example.net ~all"},
+			"mx.example.org":   {"v=spf1 mx -all"},
+			"soft.example.org": {"v=spf1 ?ip4:198.51.100.1 ~all"},
+			"redir.example.org": {
+				"v=spf1 redirect=example.com",
+			},
+			"loop.example.org": {"v=spf1 include:loop.example.org -all"},
+			"two.example.org":  {"v=spf1 -all", "v=spf1 +all"},
+		},
+		ips: map[string][]string{
+			"relay.example.net": {"203.0.113.7"},
+			"mail.example.org":  {"2001:db8::25"},
+		},
+		mx: map[string][]string{
+			"mx.example.org": {"mail.example.org."},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		ip     string
+		sender string
+		want   string
+	}{
+		{"ip4 range", "192.0.2.44", "alice@example.com", ResultPass},
+		{"include a", "203.0.113.7", "alice@example.com", ResultPass},
+		{"not listed", "198.51.100.9", "alice@example.com", ResultFail},
+		{"mx ipv6", "2001:db8::25", "bob@mx.example.org", ResultPass},
+		{"neutral qualifier", "198.51.100.1", "bob@soft.example.org", ResultNeutral},
+		{"softfail", "198.51.100.2", "bob@soft.example.org", ResultSoftFail},
+		{"redirect", "192.0.2.1", "bob@redir.example.org", ResultPass},
+		{"no record", "192.0.2.1", "bob@nospf.example.org", ResultNone},
+		{"include loop", "192.0.2.1", "bob@loop.example.org", ResultPermError},
+		{"two records", "192.0.2.1", "bob@two.example.org", ResultPermError},
+		{"null sender uses helo", "192.0.2.1", "", ResultPass},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckSPF(context.Background(), r, net.ParseIP(tt.ip), "example.com", tt.sender)
+			if got != tt.want {
+				t.Errorf("CheckSPF(%s, %q) = %q, want %q", tt.ip, tt.sender, got, tt.want)
+			}
+		})
+	}
+}
+
+const dkimTestMessage = "From: Alice <alice@example.com>\r\n" +
+	"To: helper@agents.example.org\r\n" +
+	"Subject:  Quarterly   numbers\r\n" +
+	"Message-ID: <q1@example.com>\r\n" +
+	"\r\n" +
+	"Can you summarize the attached figures?  \r\n" +
+	"\r\n\r\n"
+
+// signDKIM prepends a relaxed/relaxed DKIM-Signature for d=example.com, s=sel.
+func signDKIM(t *testing.T, msg, algo string, sign func(digest []byte) []byte) string {
+	t.Helper()
+	headers, body := splitRaw([]byte(msg))
+	bh := sha256.Sum256(canonicalizeBody(body, true))
+	sigField := "DKIM-Signature: v=1; a=" + algo + "; c=relaxed/relaxed; d=example.com; s=sel;\r\n" +
+		" h=from:to:subject:message-id; bh=" + base64.StdEncoding.EncodeToString(bh[:]) + "; b=\r\n"
+
+	h := sha256.New()
+	for _, name := range []string{"from", "to", "subject", "message-id"} {
+		for _, hdr := range headers {
+			if strings.EqualFold(hdr.name, name) {
+				h.Write([]byte(canonicalizeHeader(hdr.raw, true)))
+			}
+		}
+	}
+	h.Write([]byte(strings.TrimSuffix(canonicalizeHeader(sigField, true), "\r\n")))
+	sig := base64.StdEncoding.EncodeToString(sign(h.Sum(nil)))
+	return strings.TrimSuffix(sigField, "\r\n") + sig + "\r\n" + msg
+}
+
+func TestVerifyDKIM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signRSA := func(digest []byte) []byte {
+		sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+	signEd := func(digest []byte) []byte { return ed25519.Sign(edPriv, digest) }
+
+	rsaDNS := &fakeResolver{txt: map[string][]string{
+		"sel._domainkey.example.com": {"v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der)},
+	}}
+	edDNS := &fakeResolver{txt: map[string][]string{
+		"sel._domainkey.example.com": {"v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(edPub)},
+	}}
+	ctx := context.Background()
+
+	t.Run("rsa pass", func(t *testing.T) {
+		res := VerifyDKIM(ctx, rsaDNS, []byte(signDKIM(t, dkimTestMessage, "rsa-sha256", signRSA)))
+		if res.Result != ResultPass || res.Domain != "example.com" {
+			t.Errorf("got %+v, want pass for example.com", res)
+		}
+	})
+
+	t.Run("ed25519 pass with LF line endings", func(t *testing.T) {
+		signed := signDKIM(t, dkimTestMessage, "ed25519-sha256", signEd)
+		res := VerifyDKIM(ctx, edDNS, []byte(strings.ReplaceAll(signed, "\r\n", "\n")))
+		if res.Result != ResultPass {
+			t.Errorf("got %+v, want pass", res)
+		}
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		signed := signDKIM(t, dkimTestMessage, "rsa-sha256", signRSA)
+		signed = strings.Replace(signed, "summarize", "delete", 1)
+		if res := VerifyDKIM(ctx, rsaDNS, []byte(signed)); res.Result != ResultFail {
+			t.Errorf("got %+v, want fail", res)
+		}
+	})
+
+	t.Run("tampered header", func(t *testing.T) {
+		signed := signDKIM(t, dkimTestMessage, "rsa-sha256", signRSA)
+		signed = strings.Replace(signed, "Quarterly", "Annual", 1)
+		if res := VerifyDKIM(ctx, rsaDNS, []byte(signed)); res.Result != ResultFail {
+			t.Errorf("got %+v, want fail", res)
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		signed := signDKIM(t, dkimTestMessage, "rsa-sha256", signRSA)
+		if res := VerifyDKIM(ctx, edDNS, []byte(signed)); res.Result != ResultFail {
+			t.Errorf("got %+v, want fail", res)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		signed := signDKIM(t, dkimTestMessage, "rsa-sha256", signRSA)
+		if res := VerifyDKIM(ctx, &fakeResolver{}, []byte(signed)); res.Result != ResultPermError {
+			t.Errorf("got %+v, want permerror", res)
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		if res := VerifyDKIM(ctx, rsaDNS, []byte(dkimTestMessage)); res.Result != ResultNone {
+			t.Errorf("got %+v, want none", res)
+		}
+	})
+}
+
+func TestAuthResultsAlignedWith(t *testing.T) {
+	tests := []struct {
+		auth AuthResults
+		from string
+		want bool
+	}{
+		{AuthResults{SPF: ResultPass, SPFDomain: "example.com"}, "example.com", true},
+		{AuthResults{SPF: ResultPass, SPFDomain: "bounce.example.com"}, "example.com", true},
+		{AuthResults{SPF: ResultPass, SPFDomain: "example.net"}, "example.com", false},
+		{AuthResults{DKIM: DKIMResult{Result: ResultPass, Domain: "example.com"}}, "example.com", true},
+		{AuthResults{DKIM: DKIMResult{Result: ResultFail, Domain: "example.com"}}, "example.com", false},
+		{AuthResults{SPF: ResultSoftFail, SPFDomain: "example.com"}, "example.com", false},
+	}
+	for _, tt := range tests {
+		if got := tt.auth.AlignedWith(tt.from); got != tt.want {
+			t.Errorf("%s aligned with %s = %v, want %v", tt.auth.Details(), tt.from, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// ABOUTME: DKIM (RFC 6376) signature verification for inbound mail, rsa-sha256 and ed25519-sha256.
+// ABOUTME: Public keys come from <selector>._domainkey.<domain> TXT records through the Resolver.
+
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDKIMSignatures bounds how many signatures are tried per message.
+const maxDKIMSignatures = 5
+
+// DKIMResult is the outcome of verifying a message's DKIM signatures.
+type DKIMResult struct {
+	Result string // pass, fail, neutral, none, temperror, permerror
+	Domain string // d= of the passing signature, or of the first one tried
+}
+
+// VerifyDKIM checks the message's DKIM signatures and passes if any one of
+// them verifies.
+func VerifyDKIM(ctx context.Context, r Resolver, raw []byte) DKIMResult {
+	headers, body := splitRaw(raw)
+	var sigs []int
+	for i, h := range headers {
+		if strings.EqualFold(h.name, "DKIM-Signature") {
+			sigs = append(sigs, i)
+		}
+	}
+	if len(sigs) == 0 {
+		return DKIMResult{Result: ResultNone}
+	}
+
+	var first DKIMResult
+	for n, i := range sigs {
+		if n == maxDKIMSignatures {
+			break
+		}
+		res := verifySignature(ctx, r, headers, i, body)
+		if res.Result == ResultPass {
+			return res
+		}
+		if n == 0 {
+			first = res
+		}
+	}
+	return first
+}
+
+// rawHeader is one header field as it appeared on the wire.
+type rawHeader struct {
+	name string
+	raw  string // full field including name, folding, and trailing CRLF
+}
+
+// splitRaw splits a message into its header fields and body, normalizing
+// bare LF line endings to CRLF.
+func splitRaw(raw []byte) ([]rawHeader, []byte) {
+	if !bytes.Contains(raw, []byte("\r\n")) {
+		raw = bytes.ReplaceAll(raw, []byte("\n"), []byte("\r\n"))
+	}
+	var headers []rawHeader
+	rest := raw
+	for len(rest) > 0 {
+		var line string
+		end := bytes.Index(rest, []byte("\r\n"))
+		switch {
+		case end == 0:
+			return headers, rest[2:]
+		case end < 0:
+			line, rest = string(rest)+"\r\n", nil
+		default:
+			line, rest = string(rest[:end+2]), rest[end+2:]
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(headers) > 0 {
+			headers[len(headers)-1].raw += line
+			continue
+		}
+		name, _, _ := strings.Cut(line, ":")
+		headers = append(headers, rawHeader{name: strings.TrimSpace(name), raw: line})
+	}
+	return headers, nil
+}
+
+func verifySignature(ctx context.Context, r Resolver, headers []rawHeader, sigIndex int, body []byte) DKIMResult {
+	sigHeader := headers[sigIndex]
+	_, value, _ := strings.Cut(sigHeader.raw, ":")
+	tags, err := parseTagList(value)
+	if err != nil {
+		return DKIMResult{Result: ResultPermError}
+	}
+	res := DKIMResult{Domain: strings.ToLower(tags["d"])}
+	if tags["v"] != "1" || tags["d"] == "" || tags["s"] == "" || tags["b"] == "" || tags["bh"] == "" || tags["h"] == "" {
+		res.Result = ResultPermError
+		return res
+	}
+	if x := tags["x"]; x != "" {
+		if exp, err := strconv.ParseInt(x, 10, 64); err == nil && time.Now().Unix() > exp {
+			res.Result = ResultFail
+			return res
+		}
+	}
+
+	headerCanon, bodyCanon, _ := strings.Cut(tags["c"], "/")
+	if headerCanon == "" {
+		headerCanon = "simple"
+	}
+	if bodyCanon == "" {
+		bodyCanon = "simple"
+	}
+	if (headerCanon != "simple" && headerCanon != "relaxed") || (bodyCanon != "simple" && bodyCanon != "relaxed") {
+		res.Result = ResultPermError
+		return res
+	}
+
+	// Body hash.
+	canonBody := canonicalizeBody(body, bodyCanon == "relaxed")
+	if l := tags["l"]; l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 0 || n > len(canonBody) {
+			res.Result = ResultPermError
+			return res
+		}
+		canonBody = canonBody[:n]
+	}
+	bodyHash := sha256.Sum256(canonBody)
+	if stripWSP(tags["bh"]) != base64.StdEncoding.EncodeToString(bodyHash[:]) {
+		res.Result = ResultFail
+		return res
+	}
+
+	// Header hash: signed fields bottom-up, then the signature itself with b= emptied.
+	h := sha256.New()
+	used := make(map[int]bool)
+	for _, name := range strings.Split(tags["h"], ":") {
+		name = strings.TrimSpace(name)
+		for i := len(headers) - 1; i >= 0; i-- {
+			if used[i] || i == sigIndex || !strings.EqualFold(headers[i].name, name) {
+				continue
+			}
+			used[i] = true
+			h.Write([]byte(canonicalizeHeader(headers[i].raw, headerCanon == "relaxed")))
+			break
+		}
+	}
+	unsigned := canonicalizeHeader(removeSignatureValue(sigHeader.raw), headerCanon == "relaxed")
+	h.Write([]byte(strings.TrimSuffix(unsigned, "\r\n")))
+	digest := h.Sum(nil)
+
+	sig, err := base64.StdEncoding.DecodeString(stripWSP(tags["b"]))
+	if err != nil {
+		res.Result = ResultPermError
+		return res
+	}
+
+	key, err := lookupDKIMKey(ctx, r, tags["s"], tags["d"])
+	if err != nil {
+		if errors.Is(err, errTempError) {
+			res.Result = ResultTempError
+		} else {
+			res.Result = ResultPermError
+		}
+		return res
+	}
+
+	ok := false
+	switch strings.ToLower(tags["a"]) {
+	case "rsa-sha256":
+		if pub, isRSA := key.(*rsa.PublicKey); isRSA {
+			ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest, sig) == nil
+		}
+	case "ed25519-sha256":
+		if pub, isEd := key.(ed25519.PublicKey); isEd {
+			ok = ed25519.Verify(pub, digest, sig)
+		}
+	default:
+		res.Result = ResultPermError
+		return res
+	}
+	if ok {
+		res.Result = ResultPass
+	} else {
+		res.Result = ResultFail
+	}
+	return res
+}
+
+// lookupDKIMKey fetches and decodes the selector's public key.
+func lookupDKIMKey(ctx context.Context, r Resolver, selector, domain string) (crypto.PublicKey, error) {
+	txts, err := r.LookupTXT(ctx, selector+"._domainkey."+domain)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, errPermError
+		}
+		return nil, errTempError
+	}
+	if len(txts) == 0 {
+		return nil, errPermError
+	}
+	tags, err := parseTagList(strings.Join(txts, ""))
+	if err != nil || tags["p"] == "" {
+		return nil, errPermError // missing or revoked key
+	}
+	der, err := base64.StdEncoding.DecodeString(stripWSP(tags["p"]))
+	if err != nil {
+		return nil, errPermError
+	}
+	if strings.EqualFold(tags["k"], "ed25519") {
+		if len(der) != ed25519.PublicKeySize {
+			return nil, errPermError
+		}
+		return ed25519.PublicKey(der), nil
+	}
+	if key, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errPermError
+}
+
+// parseTagList parses a DKIM tag=value list.
+func parseTagList(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, errors.New("malformed tag")
+		}
+		tags[strings.TrimSpace(name)] = strings.TrimSpace(unfold(value))
+	}
+	return tags, nil
+}
+
+// removeSignatureValue empties the b= tag of a DKIM-Signature field,
+// leaving everything else (including bh=) byte for byte.
+func removeSignatureValue(field string) string {
+	name, value, _ := strings.Cut(field, ":")
+	var out strings.Builder
+	out.WriteString(name + ":")
+	for i, part := range strings.Split(value, ";") {
+		if i > 0 {
+			out.WriteByte(';')
+		}
+		tag, _, _ := strings.Cut(part, "=")
+		if strings.TrimSpace(tag) == "b" {
+			eq := strings.IndexByte(part, '=')
+			out.WriteString(part[:eq+1])
+			if strings.HasSuffix(part, "\r\n") {
+				out.WriteString("\r\n")
+			}
+			continue
+		}
+		out.WriteString(part)
+	}
+	return out.String()
+}
+
+// canonicalizeHeader applies the simple or relaxed header algorithm to one
+// field (RFC 6376 section 3.4.1-2).
+func canonicalizeHeader(field string, relaxed bool) string {
+	if !relaxed {
+		return field
+	}
+	name, value, _ := strings.Cut(field, ":")
+	value = strings.Join(strings.Fields(unfold(value)), " ")
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + value + "\r\n"
+}
+
+// canonicalizeBody applies the simple or relaxed body algorithm (RFC 6376
+// section 3.4.3-4).
+func canonicalizeBody(body []byte, relaxed bool) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	if relaxed {
+		for i, line := range lines {
+			line = strings.TrimRight(line, " \t")
+			lines[i] = strings.Join(strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == '\t' }), " ")
+			if line != "" && (line[0] == ' ' || line[0] == '\t') {
+				lines[i] = " " + lines[i]
+			}
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		if relaxed {
+			return nil
+		}
+		return []byte("\r\n")
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+func unfold(s string) string {
+	return strings.NewReplacer("\r\n", "", "\n", "").Replace(s)
+}
+
+func stripWSP(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+// dkimDetail formats the DKIM part of an Authentication-Results style summary.
+func dkimDetail(res DKIMResult) string {
+	if res.Domain == "" {
+		return "dkim=" + res.Result
+	}
+	return "dkim=" + res.Result + " header.d=" + res.Domain
+}
@@ -0,0 +1,41 @@
+// Package email lets people mail tasks to agents.
+//
+// # Overview
+//
+// Inbound mail arrives one of two ways, chosen by frontends.email.mode: an
+// embedded SMTP listener (smtp, the default) that a mail exchanger forwards
+// to, or a poller that fetches unseen messages from an IMAP mailbox (imap).
+// Either way each message is parsed, checked, and sent to an agent through
+// the conversation service with the From address as the sender. The agent's
+// reply is mailed back through the configured outbound relay.
+//
+// # Addressing
+//
+// Agents are reached at <name>@<domain>. With plus-addressing,
+// inbox+<name>@<domain> reaches the same agent, which suits a single IMAP
+// mailbox. A name maps to an agent through frontends.email.addresses, or
+// through a binding with frontend "email" and the mailbox address as its
+// channel ID. Over SMTP, unknown recipients are refused at RCPT time and
+// recipients whose agent is offline get a temporary failure, so the sending
+// server retries.
+//
+// # Threading
+//
+// A conversation is named by the first Message-ID in References (else
+// In-Reply-To, else the message's own ID), so a back-and-forth lands in one
+// thread. Replies carry In-Reply-To and References pointing at the message
+// they answer. Redelivered messages (same Message-ID) are ignored.
+//
+// # Checks and Limits
+//
+// Senders must match frontends.email.allowed_senders. SPF is evaluated for
+// the connecting IP over SMTP; DKIM signatures are verified in both modes.
+// Over IMAP, SPF is read from the Authentication-Results header added by the
+// mailbox's server. The results are stored in email_messages, which the
+// message's ledger event references through raw_payload_ref
+// ("email:<Message-ID>"); with require_auth, mail must pass SPF or DKIM for
+// its From domain. Messages are capped at max_message_bytes, text at
+// MaxTextBytes, and attachments at max_attachment_bytes each (MaxAttachments
+// per message); oversized attachments are left out and the agent is told.
+// Bounces and auto-replies are dropped so agents never answer them.
+package email
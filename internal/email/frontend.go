@@ -0,0 +1,414 @@
+// ABOUTME: Email frontend: routes inbound mail to agents through the conversation service and mails
+// ABOUTME: their replies back. Mailboxes map to agents via config or "email" bindings.
+
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// FrontendName is the frontend of email threads and of bindings that map a
+// mailbox (channel ID "name@domain") to an agent.
+const FrontendName = "email"
+
+// Ingestion modes.
+const (
+	ModeSMTP = "smtp"
+	ModeIMAP = "imap"
+)
+
+// Defaults for Config fields left zero.
+const (
+	DefaultListenAddr         = ":2525"
+	DefaultMaxMessageBytes    = 10 << 20
+	DefaultMaxAttachmentBytes = 5 << 20
+)
+
+// Config configures email ingestion.
+type Config struct {
+	Mode       string // ModeSMTP (default) or ModeIMAP
+	Domain     string // agent mailboxes are <name>@Domain
+	ListenAddr string // SMTP mode
+	IMAP       IMAPConfig
+
+	// Addresses maps mailbox names to agent IDs. Names not listed here are
+	// looked up in the bindings.
+	Addresses map[string]string
+	// AllowedSenders lists From addresses that may mail agents; "@example.com"
+	// (or "*@example.com") allows a whole domain. Everyone else is refused.
+	AllowedSenders []string
+	// RequireAuth refuses mail unless SPF or DKIM passed for the From domain.
+	RequireAuth bool
+
+	MaxMessageBytes    int64
+	MaxAttachmentBytes int64
+
+	Relay RelayConfig
+}
+
+// Conversation is the part of the conversation service email uses.
+type Conversation interface {
+	SendMessage(ctx context.Context, req *conversation.SendRequest) (*conversation.SendResponse, error)
+}
+
+// MessageStore records inbound mail; the SQL store satisfies it.
+type MessageStore interface {
+	SaveEmailMessage(ctx context.Context, m *store.EmailMessage) error
+	GetEmailMessage(ctx context.Context, messageID string) (*store.EmailMessage, error)
+}
+
+// Target is the agent a mailbox delivers to.
+type Target struct {
+	AgentID     string
+	MaxDuration time.Duration
+	Context     string // the mailbox binding's channel context, if any
+}
+
+// Errors returned by a Deps.Lookup function.
+var (
+	ErrUnknownMailbox = errors.New("no agent for mailbox")
+	ErrAgentOffline   = errors.New("agent offline")
+)
+
+// Deps are the gateway services the frontend uses.
+type Deps struct {
+	Conversation Conversation
+	Store        MessageStore
+	// Lookup resolves a mailbox ("name@domain") that is not in
+	// Config.Addresses, returning ErrUnknownMailbox or ErrAgentOffline when
+	// it cannot be delivered to.
+	Lookup func(ctx context.Context, mailbox string) (*Target, error)
+	// Online reports whether an agent from Config.Addresses is connected.
+	Online   func(agentID string) bool
+	Resolver Resolver // nil uses the system resolver
+	Logger   *slog.Logger
+}
+
+// Frontend receives mail for agents over SMTP or IMAP.
+type Frontend struct {
+	cfg     Config
+	deps    Deps
+	allowed []string
+	logger  *slog.Logger
+
+	server   *Server
+	stopPoll context.CancelFunc
+
+	// ctx outlives intake so replies still being written can finish.
+	ctx    context.Context
+	cancel context.CancelFunc
+	intake sync.WaitGroup
+	wg     sync.WaitGroup
+}
+
+// New creates a frontend; Start begins receiving mail.
+func New(cfg Config, deps Deps) *Frontend {
+	if cfg.Mode == "" {
+		cfg.Mode = ModeSMTP
+	}
+	if cfg.ListenAddr == "" {
+		cfg.ListenAddr = DefaultListenAddr
+	}
+	if cfg.MaxMessageBytes <= 0 {
+		cfg.MaxMessageBytes = DefaultMaxMessageBytes
+	}
+	if cfg.MaxAttachmentBytes <= 0 {
+		cfg.MaxAttachmentBytes = DefaultMaxAttachmentBytes
+	}
+	if cfg.IMAP.Mailbox == "" {
+		cfg.IMAP.Mailbox = DefaultIMAPMailbox
+	}
+	if cfg.IMAP.PollInterval <= 0 {
+		cfg.IMAP.PollInterval = DefaultIMAPPollInterval
+	}
+	cfg.Domain = strings.ToLower(cfg.Domain)
+	addresses := make(map[string]string, len(cfg.Addresses))
+	for name, agentID := range cfg.Addresses {
+		addresses[strings.ToLower(name)] = agentID
+	}
+	cfg.Addresses = addresses
+	if deps.Resolver == nil {
+		deps.Resolver = net.DefaultResolver
+	}
+
+	f := &Frontend{cfg: cfg, deps: deps, logger: deps.Logger}
+	for _, a := range cfg.AllowedSenders {
+		f.allowed = append(f.allowed, strings.TrimPrefix(strings.ToLower(strings.TrimSpace(a)), "*"))
+	}
+	f.ctx, f.cancel = context.WithCancel(context.Background())
+	return f
+}
+
+// Start opens the SMTP listener or starts polling the IMAP mailbox.
+func (f *Frontend) Start() error {
+	if f.cfg.Mode == ModeIMAP {
+		ctx, cancel := context.WithCancel(f.ctx)
+		f.stopPoll = cancel
+		p := &poller{cfg: f.cfg.IMAP, maxBytes: f.cfg.MaxMessageBytes, deliver: f.Deliver, logger: f.logger}
+		f.intake.Add(1)
+		go func() {
+			defer f.intake.Done()
+			p.run(ctx)
+		}()
+		f.logger.Info("polling IMAP mailbox for agent mail", "addr", f.cfg.IMAP.Addr, "mailbox", f.cfg.IMAP.Mailbox)
+		return nil
+	}
+	f.server = NewServer(f.cfg.ListenAddr, f.cfg.Domain, f.cfg.MaxMessageBytes, f.checkRecipient, f.Deliver, f.logger)
+	return f.server.Listen()
+}
+
+// Addr returns the SMTP listener's address, or nil in IMAP mode.
+func (f *Frontend) Addr() net.Addr {
+	if f.server == nil {
+		return nil
+	}
+	return f.server.Addr()
+}
+
+// Stop stops receiving mail. Replies already being written continue.
+func (f *Frontend) Stop() {
+	if f.server != nil {
+		f.server.Close()
+	}
+	if f.stopPoll != nil {
+		f.stopPoll()
+	}
+	f.intake.Wait()
+}
+
+// Close stops receiving mail and waits for pending replies to be sent.
+func (f *Frontend) Close() {
+	f.Stop()
+	f.wg.Wait()
+	f.cancel()
+}
+
+// route maps a recipient address to its canonical mailbox and agent.
+// Plus-addressing ("inbox+billing@domain") routes by the part after the plus.
+func (f *Frontend) route(ctx context.Context, addr string) (string, *Target, error) {
+	addr = strings.ToLower(addr)
+	at := strings.LastIndexByte(addr, '@')
+	if at <= 0 || addr[at+1:] != f.cfg.Domain {
+		return "", nil, ErrUnknownMailbox
+	}
+	name := addr[:at]
+	if _, tag, ok := strings.Cut(name, "+"); ok && tag != "" {
+		name = tag
+	}
+	mailbox := name + "@" + f.cfg.Domain
+
+	if agentID, ok := f.cfg.Addresses[name]; ok {
+		if f.deps.Online != nil && !f.deps.Online(agentID) {
+			return mailbox, nil, ErrAgentOffline
+		}
+		return mailbox, &Target{AgentID: agentID}, nil
+	}
+	if f.deps.Lookup == nil {
+		return mailbox, nil, ErrUnknownMailbox
+	}
+	target, err := f.deps.Lookup(ctx, mailbox)
+	return mailbox, target, err
+}
+
+// routeError turns a routing failure into an SMTP reply.
+func routeError(err error) *Error {
+	switch {
+	case errors.Is(err, ErrUnknownMailbox):
+		return &Error{Code: 550, Message: "5.1.1 no agent at this address"}
+	case errors.Is(err, ErrAgentOffline):
+		return &Error{Code: 451, Message: "4.2.1 agent is offline, try again later"}
+	}
+	return &Error{Code: 451, Message: "4.3.0 local error, try again later"}
+}
+
+func (f *Frontend) checkRecipient(ctx context.Context, addr string) error {
+	if _, _, err := f.route(ctx, addr); err != nil {
+		return routeError(err)
+	}
+	return nil
+}
+
+func (f *Frontend) senderAllowed(addr string) bool {
+	_, domain, _ := strings.Cut(addr, "@")
+	for _, a := range f.allowed {
+		if a == addr || (strings.HasPrefix(a, "@") && a[1:] == domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Deliver hands one message to its agent. Errors are *Error values with the
+// SMTP reply to give; 4xx replies ask the sender (or the poller) to retry.
+func (f *Frontend) Deliver(ctx context.Context, env *Envelope) error {
+	m, err := ParseMessage(env.Data, f.cfg.MaxAttachmentBytes)
+	if err != nil {
+		f.logger.Info("refused unparseable email", "error", err)
+		return &Error{Code: 550, Message: "5.6.0 message could not be parsed"}
+	}
+	if m.AutoGenerated || env.MailFrom == "<>" {
+		f.logger.Info("dropped automatic email", "from", m.From, "message_id", m.MessageID)
+		return nil
+	}
+	if !f.senderAllowed(m.From) {
+		f.logger.Warn("refused email from sender not on the allowlist", "from", m.From)
+		return &Error{Code: 550, Message: "5.7.1 sender is not allowed to mail agents"}
+	}
+
+	var auth AuthResults
+	if env.RemoteIP != nil {
+		auth = authenticateSMTP(ctx, f.deps.Resolver, env)
+	} else {
+		auth = authenticateFetched(ctx, f.deps.Resolver, m, env.Data)
+	}
+	_, fromDomain, _ := strings.Cut(m.From, "@")
+	if f.cfg.RequireAuth && !auth.AlignedWith(fromDomain) {
+		f.logger.Warn("refused unauthenticated email", "from", m.From, "auth", auth.Details())
+		return &Error{Code: 550, Message: "5.7.1 message failed SPF and DKIM checks"}
+	}
+
+	recipients := env.Recipients
+	if len(recipients) == 0 {
+		recipients = m.To
+	}
+	var mailbox string
+	var target *Target
+	err = ErrUnknownMailbox
+	for _, rcpt := range recipients {
+		var rerr error
+		mailbox, target, rerr = f.route(ctx, rcpt)
+		if rerr == nil {
+			err = nil
+			break
+		}
+		// An offline agent is worth retrying; remember that over "unknown".
+		if !errors.Is(err, ErrAgentOffline) {
+			err = rerr
+		}
+	}
+	if err != nil {
+		return routeError(err)
+	}
+
+	if _, err := f.deps.Store.GetEmailMessage(ctx, m.MessageID); err == nil {
+		f.logger.Info("ignored redelivered email", "message_id", m.MessageID)
+		return nil
+	}
+
+	resp, err := f.deps.Conversation.SendMessage(f.ctx, &conversation.SendRequest{
+		FrontendName:   FrontendName,
+		ExternalID:     target.AgentID + ":" + m.ThreadRoot(),
+		AgentID:        target.AgentID,
+		Sender:         m.From,
+		Content:        agentContent(m),
+		Attachments:    m.Attachments,
+		MaxDuration:    target.MaxDuration,
+		ChannelContext: target.Context,
+		Transport:      FrontendName,
+		PayloadRef:     store.EmailPayloadRefPrefix + m.MessageID,
+	})
+	if err != nil {
+		f.logger.Warn("email send to agent failed", "agent_id", target.AgentID, "error", err)
+		return &Error{Code: 451, Message: "4.3.0 agent unavailable, try again later"}
+	}
+
+	err = f.deps.Store.SaveEmailMessage(ctx, &store.EmailMessage{
+		MessageID:   m.MessageID,
+		AgentID:     target.AgentID,
+		From:        m.From,
+		To:          mailbox,
+		Subject:     m.Subject,
+		SPF:         auth.SPF,
+		DKIM:        auth.DKIM.Result,
+		AuthDetails: auth.Details(),
+	})
+	if err != nil && !errors.Is(err, store.ErrDuplicateEmail) {
+		f.logger.Warn("failed to record email", "message_id", m.MessageID, "error", err)
+	}
+	f.logger.Info("email delivered to agent",
+		"agent_id", target.AgentID,
+		"thread_id", resp.ThreadID,
+		"from", m.From,
+		"auth", auth.Details(),
+	)
+
+	f.wg.Add(1)
+	go f.awaitReply(resp.Stream, m, mailbox, target.AgentID)
+	return nil
+}
+
+// agentContent is the message text the agent sees.
+func agentContent(m *Message) string {
+	from := m.From
+	if m.FromName != "" {
+		from = fmt.Sprintf("%s <%s>", m.FromName, m.From)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Email from %s\nSubject: %s\n\n%s", from, m.Subject, m.Text)
+	if m.Truncated {
+		b.WriteString("\n\n[message text truncated]")
+	}
+	if len(m.Skipped) > 0 {
+		fmt.Fprintf(&b, "\n\n[attachments not included (too large or too many): %s]", strings.Join(m.Skipped, ", "))
+	}
+	return b.String()
+}
+
+// awaitReply waits for the agent to finish and mails its answer back.
+func (f *Frontend) awaitReply(stream <-chan *agent.Response, m *Message, mailbox, agentID string) {
+	defer f.wg.Done()
+	var text string
+	streamed := false
+	for resp := range stream {
+		switch resp.Event {
+		case agent.EventText:
+			text += resp.Text
+			streamed = true
+		case agent.EventDone:
+			if !streamed && resp.Text != "" {
+				text = resp.Text
+			}
+		case agent.EventError:
+			f.logger.Warn("agent failed to answer email", "agent_id", agentID, "message_id", m.MessageID, "error", resp.Error)
+		}
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if f.cfg.Relay.Addr == "" {
+		f.logger.Debug("no relay configured, email reply not sent", "agent_id", agentID, "to", m.From)
+		return
+	}
+
+	reply := &Reply{
+		From:       mailbox,
+		FromName:   agentID,
+		To:         m.From,
+		Subject:    m.Subject,
+		Text:       text,
+		InReplyTo:  m.MessageID,
+		References: m.References,
+	}
+	msg, messageID := reply.compose(f.cfg.Domain, time.Now())
+	sender := f.cfg.Relay.From
+	if sender == "" {
+		sender = mailbox
+	}
+	if err := f.cfg.Relay.send(f.ctx, sender, m.From, msg); err != nil {
+		f.logger.Error("failed to send email reply", "agent_id", agentID, "to", m.From, "error", err)
+		return
+	}
+	f.logger.Info("email reply sent", "agent_id", agentID, "to", m.From, "message_id", messageID)
+}
@@ -0,0 +1,333 @@
+// ABOUTME: End-to-end tests for the email frontend over SMTP and IMAP with fake conversation and store.
+// ABOUTME: A second SMTP server stands in for the outbound relay to capture threaded replies.
+
+package email
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+type fakeConversation struct {
+	mu    sync.Mutex
+	sent  []*conversation.SendRequest
+	reply string
+}
+
+func (c *fakeConversation) SendMessage(_ context.Context, req *conversation.SendRequest) (*conversation.SendResponse, error) {
+	c.mu.Lock()
+	c.sent = append(c.sent, req)
+	c.mu.Unlock()
+	ch := make(chan *agent.Response, 2)
+	ch <- &agent.Response{Event: agent.EventText, Text: c.reply}
+	ch <- &agent.Response{Event: agent.EventDone, Done: true}
+	close(ch)
+	return &conversation.SendResponse{ThreadID: "thread-1", Stream: ch}, nil
+}
+
+func (c *fakeConversation) requests() []*conversation.SendRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*conversation.SendRequest(nil), c.sent...)
+}
+
+type fakeMessageStore struct {
+	mu   sync.Mutex
+	msgs map[string]*store.EmailMessage
+}
+
+func (s *fakeMessageStore) SaveEmailMessage(_ context.Context, m *store.EmailMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.msgs[m.MessageID]; ok {
+		return store.ErrDuplicateEmail
+	}
+	s.msgs[m.MessageID] = m
+	return nil
+}
+
+func (s *fakeMessageStore) GetEmailMessage(_ context.Context, id string) (*store.EmailMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m, ok := s.msgs[id]; ok {
+		return m, nil
+	}
+	return nil, store.ErrNotFound
+}
+
+// startRelay runs an SMTP server that captures everything sent to it.
+func startRelay(t *testing.T) (string, <-chan *Envelope) {
+	t.Helper()
+	got := make(chan *Envelope, 4)
+	relay := NewServer("127.0.0.1:0", "relay.test", 1<<20,
+		func(context.Context, string) error { return nil },
+		func(_ context.Context, env *Envelope) error { got <- env; return nil },
+		slog.New(slog.DiscardHandler))
+	if err := relay.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(relay.Close)
+	return relay.Addr().String(), got
+}
+
+func newTestFrontend(t *testing.T, cfg Config, conv *fakeConversation) (*Frontend, *fakeMessageStore) {
+	t.Helper()
+	ms := &fakeMessageStore{msgs: make(map[string]*store.EmailMessage)}
+	cfg.Domain = "agents.example.org"
+	cfg.ListenAddr = "127.0.0.1:0"
+	cfg.Addresses = map[string]string{"helper": "agent-1"}
+	f := New(cfg, Deps{
+		Conversation: conv,
+		Store:        ms,
+		Lookup: func(_ context.Context, mailbox string) (*Target, error) {
+			if mailbox == "sleepy@agents.example.org" {
+				return nil, ErrAgentOffline
+			}
+			return nil, ErrUnknownMailbox
+		},
+		Online:   func(string) bool { return true },
+		Resolver: &fakeResolver{},
+		Logger:   slog.New(slog.DiscardHandler),
+	})
+	return f, ms
+}
+
+const inboundMessage = "From: Alice <alice@example.com>\r\n" +
+	"To: helper@agents.example.org\r\n" +
+	"Subject: Weekly report\r\n" +
+	"Message-ID: <m2@example.com>\r\n" +
+	"In-Reply-To: <m1@example.com>\r\n" +
+	"References: <m1@example.com>\r\n" +
+	"\r\n" +
+	"Please draft the weekly report.\r\n"
+
+func TestFrontend_SMTPDeliversAndReplies(t *testing.T) {
+	relayAddr, relayed := startRelay(t)
+	conv := &fakeConversation{reply: "Here is the draft."}
+	f, ms := newTestFrontend(t, Config{
+		AllowedSenders: []string{"@example.com"},
+		Relay:          RelayConfig{Addr: relayAddr},
+	}, conv)
+	if err := f.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	err := smtp.SendMail(f.Addr().String(), nil, "alice@example.com", []string{"inbox+helper@agents.example.org"}, []byte(inboundMessage))
+	if err != nil {
+		t.Fatalf("SendMail: %v", err)
+	}
+
+	reqs := conv.requests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d sends, want 1", len(reqs))
+	}
+	req := reqs[0]
+	if req.AgentID != "agent-1" || req.FrontendName != FrontendName || req.Sender != "alice@example.com" {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if req.ExternalID != "agent-1:<m1@example.com>" {
+		t.Errorf("ExternalID = %q, want thread keyed by the reference root", req.ExternalID)
+	}
+	if req.PayloadRef != "email:<m2@example.com>" || req.Transport != "email" {
+		t.Errorf("PayloadRef = %q, Transport = %q", req.PayloadRef, req.Transport)
+	}
+	if !strings.Contains(req.Content, "Please draft the weekly report.") {
+		t.Errorf("Content = %q", req.Content)
+	}
+	if saved := ms.msgs["<m2@example.com>"]; saved == nil || saved.To != "helper@agents.example.org" {
+		t.Errorf("saved message = %+v", saved)
+	}
+
+	select {
+	case env := <-relayed:
+		if env.MailFrom != "helper@agents.example.org" || env.Recipients[0] != "alice@example.com" {
+			t.Errorf("relay envelope %s -> %v", env.MailFrom, env.Recipients)
+		}
+		reply, err := ParseMessage(env.Data, DefaultMaxAttachmentBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.InReplyTo != "<m2@example.com>" || strings.Join(reply.References, " ") != "<m1@example.com> <m2@example.com>" {
+			t.Errorf("reply threading In-Reply-To=%q References=%v", reply.InReplyTo, reply.References)
+		}
+		if reply.Subject != "Re: Weekly report" || strings.TrimSpace(reply.Text) != "Here is the draft." {
+			t.Errorf("reply %q: %q", reply.Subject, reply.Text)
+		}
+		if !reply.AutoGenerated {
+			t.Error("reply should be marked Auto-Submitted")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reply was not relayed")
+	}
+
+	// Redelivery of the same message is accepted but not sent again.
+	if err := smtp.SendMail(f.Addr().String(), nil, "alice@example.com", []string{"helper@agents.example.org"}, []byte(inboundMessage)); err != nil {
+		t.Fatalf("redelivery: %v", err)
+	}
+	if n := len(conv.requests()); n != 1 {
+		t.Errorf("got %d sends after redelivery, want 1", n)
+	}
+}
+
+func TestFrontend_SMTPRefusals(t *testing.T) {
+	conv := &fakeConversation{}
+	f, _ := newTestFrontend(t, Config{AllowedSenders: []string{"alice@example.com"}}, conv)
+	if err := f.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	addr := f.Addr().String()
+
+	assertCode := func(err error, code int) {
+		t.Helper()
+		if err == nil || !strings.HasPrefix(err.Error(), fmt.Sprint(code)) {
+			t.Errorf("got %v, want %d", err, code)
+		}
+	}
+	assertCode(smtp.SendMail(addr, nil, "alice@example.com", []string{"nobody@agents.example.org"}, []byte(inboundMessage)), 550)
+	assertCode(smtp.SendMail(addr, nil, "alice@example.com", []string{"helper@elsewhere.org"}, []byte(inboundMessage)), 550)
+	assertCode(smtp.SendMail(addr, nil, "alice@example.com", []string{"sleepy@agents.example.org"}, []byte(inboundMessage)), 451)
+
+	stranger := strings.Replace(inboundMessage, "alice@example.com", "mallory@example.net", 1)
+	assertCode(smtp.SendMail(addr, nil, "mallory@example.net", []string{"helper@agents.example.org"}, []byte(stranger)), 550)
+
+	// Auto-replies are accepted and dropped.
+	auto := "Auto-Submitted: auto-replied\r\n" + inboundMessage
+	if err := smtp.SendMail(addr, nil, "alice@example.com", []string{"helper@agents.example.org"}, []byte(auto)); err != nil {
+		t.Errorf("auto-reply: %v", err)
+	}
+	if n := len(conv.requests()); n != 0 {
+		t.Errorf("got %d sends, want none", n)
+	}
+}
+
+func TestFrontend_RequireAuth(t *testing.T) {
+	conv := &fakeConversation{}
+	f, _ := newTestFrontend(t, Config{AllowedSenders: []string{"@example.com"}, RequireAuth: true}, conv)
+	err := f.Deliver(context.Background(), &Envelope{
+		RemoteIP:   net.ParseIP("192.0.2.1"),
+		Helo:       "mx.example.com",
+		MailFrom:   "alice@example.com",
+		Recipients: []string{"helper@agents.example.org"},
+		Data:       []byte(inboundMessage),
+	})
+	var e *Error
+	if !errors.As(err, &e) || e.Code != 550 {
+		t.Errorf("got %v, want 550 for mail without SPF or DKIM", err)
+	}
+}
+
+// fakeIMAPServer serves one mailbox over a scripted IMAP subset.
+type fakeIMAPServer struct {
+	ln       net.Listener
+	messages map[string]string // uid -> raw message
+	mu       sync.Mutex
+	seen     map[string]bool
+}
+
+func startIMAPServer(t *testing.T, messages map[string]string) *fakeIMAPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeIMAPServer{ln: ln, messages: messages, seen: make(map[string]bool)}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeIMAPServer) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	_, _ = io.WriteString(conn, "* OK IMAP4rev1 ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, cmd, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch {
+		case strings.HasPrefix(cmd, "UID SEARCH"):
+			var unseen []string
+			s.mu.Lock()
+			for uid := range s.messages {
+				if !s.seen[uid] {
+					unseen = append(unseen, uid)
+				}
+			}
+			s.mu.Unlock()
+			fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(unseen, " "))
+		case strings.HasPrefix(cmd, "UID FETCH"):
+			uid := strings.Fields(cmd)[2]
+			msg := s.messages[uid]
+			fmt.Fprintf(conn, "* 1 FETCH (UID %s BODY[] {%d}\r\n%s)\r\n", uid, len(msg), msg)
+		case strings.HasPrefix(cmd, "UID STORE"):
+			s.mu.Lock()
+			s.seen[strings.Fields(cmd)[2]] = true
+			s.mu.Unlock()
+		case strings.HasPrefix(cmd, "LOGOUT"):
+			fmt.Fprintf(conn, "* BYE\r\n%s OK done\r\n", tag)
+			return
+		}
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+	}
+}
+
+func (s *fakeIMAPServer) isSeen(uid string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen[uid]
+}
+
+func TestFrontend_IMAPPoll(t *testing.T) {
+	offline := strings.Replace(inboundMessage, "helper@", "sleepy@", 1)
+	offline = strings.Replace(offline, "<m2@", "<m3@", 1)
+	srv := startIMAPServer(t, map[string]string{"7": inboundMessage, "8": offline})
+
+	conv := &fakeConversation{reply: "ok"}
+	f, _ := newTestFrontend(t, Config{
+		Mode:           ModeIMAP,
+		IMAP:           IMAPConfig{Addr: srv.ln.Addr().String(), Username: "inbox", Password: `p"w`, Insecure: true},
+		AllowedSenders: []string{"@example.com"},
+	}, conv)
+	p := &poller{cfg: f.cfg.IMAP, maxBytes: f.cfg.MaxMessageBytes, deliver: f.Deliver, logger: f.logger}
+	if err := p.poll(context.Background()); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	f.Close()
+
+	reqs := conv.requests()
+	if len(reqs) != 1 || reqs[0].AgentID != "agent-1" {
+		t.Fatalf("got %d sends, want one to agent-1", len(reqs))
+	}
+	if !srv.isSeen("7") {
+		t.Error("delivered message should be marked seen")
+	}
+	if srv.isSeen("8") {
+		t.Error("message for an offline agent should stay unseen for retry")
+	}
+}
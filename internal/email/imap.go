@@ -0,0 +1,247 @@
+// ABOUTME: IMAP poller that fetches unseen mail from a mailbox and delivers it like SMTP mail.
+// ABOUTME: Speaks the small IMAP4rev1 subset it needs: LOGIN, SELECT, UID SEARCH/FETCH/STORE, LOGOUT.
+
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IMAPConfig names the mailbox polled in imap mode.
+type IMAPConfig struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	Mailbox  string // default INBOX
+	// Insecure connects without TLS; only for servers on localhost.
+	Insecure     bool
+	PollInterval time.Duration // default 1m
+}
+
+// Defaults for IMAPConfig fields left zero.
+const (
+	DefaultIMAPMailbox      = "INBOX"
+	DefaultIMAPPollInterval = time.Minute
+)
+
+const (
+	// maxFetchPerPoll bounds how many messages one poll delivers.
+	maxFetchPerPoll = 50
+	imapTimeout     = 2 * time.Minute
+)
+
+// poller fetches unseen messages on an interval.
+type poller struct {
+	cfg      IMAPConfig
+	maxBytes int64
+	deliver  func(ctx context.Context, env *Envelope) error
+	logger   *slog.Logger
+}
+
+func (p *poller) run(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := p.poll(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Warn("IMAP poll failed", "addr", p.cfg.Addr, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll delivers every unseen message once. Delivered and permanently refused
+// messages are marked seen; temporary failures stay unseen for the next poll.
+func (p *poller) poll(ctx context.Context) error {
+	c, err := p.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	if _, err := c.cmd("LOGIN", imapQuote(p.cfg.Username), imapQuote(p.cfg.Password)); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	if _, err := c.cmd("SELECT", imapQuote(p.cfg.Mailbox)); err != nil {
+		return fmt.Errorf("selecting %s: %w", p.cfg.Mailbox, err)
+	}
+	untagged, err := c.cmd("UID SEARCH UNSEEN")
+	if err != nil {
+		return fmt.Errorf("searching: %w", err)
+	}
+	var uids []string
+	for _, line := range untagged {
+		if rest, ok := strings.CutPrefix(line.text, "SEARCH"); ok {
+			uids = append(uids, strings.Fields(rest)...)
+		}
+	}
+	if len(uids) > maxFetchPerPoll {
+		uids = uids[:maxFetchPerPoll]
+	}
+
+	for _, uid := range uids {
+		if ctx.Err() != nil {
+			return nil
+		}
+		untagged, err := c.cmd("UID FETCH " + uid + " (BODY.PEEK[])")
+		if err != nil {
+			return fmt.Errorf("fetching uid %s: %w", uid, err)
+		}
+		var data []byte
+		tooLarge := false
+		for _, line := range untagged {
+			if line.literal != nil {
+				data = line.literal
+			}
+			tooLarge = tooLarge || line.skipped
+		}
+
+		seen := true
+		switch {
+		case tooLarge:
+			p.logger.Warn("skipping oversized message", "uid", uid)
+		case data == nil:
+			p.logger.Warn("fetch returned no message body", "uid", uid)
+		default:
+			err := p.deliver(ctx, &Envelope{Data: data})
+			var e *Error
+			if err != nil && (!errors.As(err, &e) || e.Temporary()) {
+				p.logger.Info("delivery deferred, will retry", "uid", uid, "error", err)
+				seen = false
+			} else if err != nil {
+				p.logger.Info("message refused", "uid", uid, "reason", e.Message)
+			}
+		}
+		if seen {
+			if _, err := c.cmd("UID STORE " + uid + ` +FLAGS.SILENT (\Seen)`); err != nil {
+				return fmt.Errorf("marking uid %s seen: %w", uid, err)
+			}
+		}
+	}
+	_, _ = c.cmd("LOGOUT")
+	return nil
+}
+
+func (p *poller) dial(ctx context.Context) (*imapConn, error) {
+	ctx, cancel := context.WithTimeout(ctx, imapTimeout)
+	defer cancel()
+	var conn net.Conn
+	var err error
+	if p.cfg.Insecure {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", p.cfg.Addr)
+	} else {
+		host, _, _ := net.SplitHostPort(p.cfg.Addr)
+		d := &tls.Dialer{Config: &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}}
+		conn, err = d.DialContext(ctx, "tcp", p.cfg.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connecting to IMAP server: %w", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(imapTimeout))
+	c := &imapConn{conn: conn, r: bufio.NewReader(conn), maxLiteral: p.maxBytes}
+	greeting, err := c.readLine()
+	if err != nil {
+		c.close()
+		return nil, fmt.Errorf("reading greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		c.close()
+		return nil, fmt.Errorf("unexpected greeting %q", greeting)
+	}
+	return c, nil
+}
+
+// imapConn is a client connection running one command at a time.
+type imapConn struct {
+	conn       net.Conn
+	r          *bufio.Reader
+	tag        int
+	maxLiteral int64
+}
+
+// imapLine is an untagged response, with any literal it carried.
+type imapLine struct {
+	text    string // without the leading "* "
+	literal []byte
+	skipped bool // a literal over maxLiteral was discarded
+}
+
+var literalPattern = regexp.MustCompile(`\{(\d+)\}$`)
+
+// cmd sends a command and collects untagged responses until its tagged
+// completion, which must be OK.
+func (c *imapConn) cmd(command string, args ...string) ([]imapLine, error) {
+	c.tag++
+	tag := "c" + strconv.Itoa(c.tag)
+	line := strings.Join(append([]string{tag, command}, args...), " ")
+	if _, err := io.WriteString(c.conn, line+"\r\n"); err != nil {
+		return nil, err
+	}
+
+	var untagged []imapLine
+	for {
+		text, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if rest, ok := strings.CutPrefix(text, tag+" "); ok {
+			if !strings.HasPrefix(rest, "OK") {
+				return nil, fmt.Errorf("server said %q", rest)
+			}
+			return untagged, nil
+		}
+		rest, ok := strings.CutPrefix(text, "* ")
+		if !ok {
+			continue // continuation request or noise
+		}
+		entry := imapLine{text: rest}
+		if m := literalPattern.FindStringSubmatch(rest); m != nil {
+			n, _ := strconv.ParseInt(m[1], 10, 64)
+			if n > c.maxLiteral {
+				if _, err := io.CopyN(io.Discard, c.r, n); err != nil {
+					return nil, err
+				}
+				entry.skipped = true
+			} else {
+				entry.literal = make([]byte, n)
+				if _, err := io.ReadFull(c.r, entry.literal); err != nil {
+					return nil, err
+				}
+			}
+			// The rest of the response (e.g. the closing parenthesis).
+			if _, err := c.readLine(); err != nil {
+				return nil, err
+			}
+		}
+		untagged = append(untagged, entry)
+	}
+}
+
+func (c *imapConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (c *imapConn) close() { _ = c.conn.Close() }
+
+// imapQuote renders s as an IMAP quoted string.
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,270 @@
+// ABOUTME: Parses inbound mail into the parts the gateway passes on: addresses, threading headers,
+// ABOUTME: the text body (HTML-only mail is flattened), and attachments, all within size limits.
+
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/agent"
+)
+
+// Limits applied while parsing a message.
+const (
+	// MaxTextBytes is how much body text reaches the agent; the rest is cut.
+	MaxTextBytes = 256 << 10
+	// MaxAttachments is how many attachments are passed on per message.
+	MaxAttachments = 10
+	// maxMIMEDepth bounds nested multiparts.
+	maxMIMEDepth = 8
+)
+
+// Message is an inbound email reduced to what the gateway needs.
+type Message struct {
+	MessageID  string // "<id@host>"; synthesized when the header is missing
+	InReplyTo  string
+	References []string
+	From       string // bare address, lowercased
+	FromName   string
+	To         []string // To and Cc addresses, lowercased
+	Subject    string
+	Text       string
+	// Truncated is set when Text was cut at MaxTextBytes.
+	Truncated   bool
+	Attachments []agent.Attachment
+	// Skipped names attachments that were left out for size or count.
+	Skipped []string
+	// AutoGenerated marks bounces, auto-replies, and bulk mail (RFC 3834),
+	// which are never answered.
+	AutoGenerated bool
+	// AuthenticationResults holds the topmost Authentication-Results header,
+	// added by the receiving mail server (used when polling over IMAP).
+	AuthenticationResults string
+}
+
+// ErrNoSender is returned for messages without a usable From address.
+var ErrNoSender = errors.New("message has no From address")
+
+// ParseMessage parses a raw RFC 5322 message. Attachments larger than
+// maxAttachmentBytes are skipped rather than failing the message.
+func ParseMessage(raw []byte, maxAttachmentBytes int64) (*Message, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("reading message: %w", err)
+	}
+	h := msg.Header
+
+	from, err := mail.ParseAddress(h.Get("From"))
+	if err != nil || from.Address == "" {
+		return nil, ErrNoSender
+	}
+	m := &Message{
+		MessageID:             firstMessageID(h.Get("Message-Id")),
+		InReplyTo:             firstMessageID(h.Get("In-Reply-To")),
+		References:            messageIDs(h.Get("References")),
+		From:                  strings.ToLower(from.Address),
+		FromName:              from.Name,
+		Subject:               decodeHeader(h.Get("Subject")),
+		AuthenticationResults: h.Get("Authentication-Results"),
+	}
+	auto := strings.ToLower(strings.TrimSpace(h.Get("Auto-Submitted")))
+	precedence := strings.ToLower(strings.TrimSpace(h.Get("Precedence")))
+	m.AutoGenerated = (auto != "" && auto != "no") || precedence == "bulk" || precedence == "junk" || precedence == "list"
+	if m.MessageID == "" {
+		m.MessageID = "<" + uuid.New().String() + "@coven-gateway.invalid>"
+	}
+	for _, field := range []string{"To", "Cc"} {
+		if list, err := h.AddressList(field); err == nil {
+			for _, a := range list {
+				m.To = append(m.To, strings.ToLower(a.Address))
+			}
+		}
+	}
+
+	p := &partWalker{msg: m, maxAttachmentBytes: maxAttachmentBytes}
+	if err := p.walk(textproto.MIMEHeader(h), msg.Body, 0); err != nil {
+		return nil, err
+	}
+	text := p.plain
+	if text == "" && p.html != "" {
+		text = htmlToText(p.html)
+	}
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if len(text) > MaxTextBytes {
+		text = text[:MaxTextBytes]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+		m.Truncated = true
+	}
+	m.Text = text
+	return m, nil
+}
+
+// ThreadRoot is the Message-ID that names the conversation this message
+// belongs to: the first entry of References, else In-Reply-To, else its own.
+func (m *Message) ThreadRoot() string {
+	if len(m.References) > 0 {
+		return m.References[0]
+	}
+	if m.InReplyTo != "" {
+		return m.InReplyTo
+	}
+	return m.MessageID
+}
+
+// partWalker collects text and attachments from a MIME tree.
+type partWalker struct {
+	msg                *Message
+	maxAttachmentBytes int64
+	plain, html        string
+}
+
+func (p *partWalker) walk(h textproto.MIMEHeader, body io.Reader, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := dparams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMIMEDepth || params["boundary"] == "" {
+			return nil
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("reading MIME part: %w", err)
+			}
+			if err := p.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	decoded := decodeTransfer(body, h.Get("Content-Transfer-Encoding"))
+	if disposition != "attachment" && filename == "" && (mediaType == "text/plain" || mediaType == "text/html") {
+		data, err := io.ReadAll(io.LimitReader(decoded, MaxTextBytes+1))
+		if err != nil {
+			return fmt.Errorf("reading text part: %w", err)
+		}
+		text := toUTF8(data, params["charset"])
+		if mediaType == "text/plain" && p.plain == "" {
+			p.plain = text
+		} else if mediaType == "text/html" && p.html == "" {
+			p.html = text
+		}
+		return nil
+	}
+
+	if filename == "" {
+		filename = "attachment"
+	}
+	filename = decodeHeader(filename)
+	if len(p.msg.Attachments) >= MaxAttachments {
+		p.msg.Skipped = append(p.msg.Skipped, filename)
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(decoded, p.maxAttachmentBytes+1))
+	if err != nil {
+		return fmt.Errorf("reading attachment: %w", err)
+	}
+	if int64(len(data)) > p.maxAttachmentBytes {
+		p.msg.Skipped = append(p.msg.Skipped, filename)
+		return nil
+	}
+	p.msg.Attachments = append(p.msg.Attachments, agent.Attachment{
+		Filename: filename,
+		MimeType: mediaType,
+		Data:     data,
+	})
+	return nil
+}
+
+func decodeTransfer(r io.Reader, encoding string) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// toUTF8 converts Latin-1 text to UTF-8; other charsets are passed through
+// with invalid bytes replaced.
+func toUTF8(data []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	return strings.ToValidUTF8(string(data), "�")
+}
+
+func decodeHeader(s string) string {
+	dec := new(mime.WordDecoder)
+	if out, err := dec.DecodeHeader(s); err == nil {
+		return out
+	}
+	return s
+}
+
+var messageIDPattern = regexp.MustCompile(`<[^<>\s]+>`)
+
+func messageIDs(s string) []string {
+	return messageIDPattern.FindAllString(s, -1)
+}
+
+func firstMessageID(s string) string {
+	if ids := messageIDs(s); len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+var (
+	htmlDropPattern  = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|tr|h[1-6])>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+	blankRunPattern  = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText flattens an HTML body well enough for an agent to read.
+func htmlToText(s string) string {
+	s = htmlDropPattern.ReplaceAllString(s, "")
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return blankRunPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+}
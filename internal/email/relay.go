@@ -0,0 +1,125 @@
+// ABOUTME: Outbound SMTP relay for agent replies, threaded to the inbound mail with In-Reply-To/References.
+// ABOUTME: Uses STARTTLS when the relay offers it and PLAIN auth when credentials are configured.
+
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RelayConfig names the SMTP server replies go out through.
+type RelayConfig struct {
+	Addr     string // host:port; replies are not sent when empty
+	Username string
+	Password string
+	// From is the envelope sender; the agent's mailbox is used when empty.
+	From string
+}
+
+// relayTimeout bounds one reply's whole SMTP exchange.
+const relayTimeout = time.Minute
+
+// Reply is an agent response addressed back to the sender of an email.
+type Reply struct {
+	From     string // agent mailbox
+	FromName string
+	To       string
+	Subject  string
+	Text     string
+	// InReplyTo and References thread the reply under the inbound message.
+	InReplyTo  string
+	References []string
+}
+
+// compose renders the reply as an RFC 5322 message and returns its Message-ID.
+func (r *Reply) compose(domain string, now time.Time) ([]byte, string) {
+	messageID := "<" + uuid.New().String() + "@" + domain + ">"
+	subject := r.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+	refs := append(append([]string(nil), r.References...), r.InReplyTo)
+
+	var b bytes.Buffer
+	header := func(name, value string) { fmt.Fprintf(&b, "%s: %s\r\n", name, value) }
+	from := r.From
+	if r.FromName != "" {
+		from = mime.QEncoding.Encode("utf-8", r.FromName) + " <" + r.From + ">"
+	}
+	header("From", from)
+	header("To", r.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	header("In-Reply-To", r.InReplyTo)
+	header("References", strings.Join(refs, " "))
+	header("Auto-Submitted", "auto-replied")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+	qp := quotedprintable.NewWriter(&b)
+	_, _ = qp.Write([]byte(strings.ReplaceAll(r.Text, "\n", "\r\n")))
+	_ = qp.Close()
+	b.WriteString("\r\n")
+	return b.Bytes(), messageID
+}
+
+// send delivers a composed message through the relay.
+func (c RelayConfig) send(ctx context.Context, from, to string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, relayTimeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return fmt.Errorf("dialing relay: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	host, _, _ := net.SplitHostPort(c.Addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("starting relay session: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("relay STARTTLS: %w", err)
+		}
+	}
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, host)); err != nil {
+			return fmt.Errorf("relay auth: %w", err)
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("relay MAIL: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("relay RCPT: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("relay DATA: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("relay rejected message: %w", err)
+	}
+	return client.Quit()
+}
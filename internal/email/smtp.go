@@ -0,0 +1,358 @@
+// ABOUTME: Embedded SMTP listener that accepts mail for agent mailboxes (RFC 5321 receiver subset).
+// ABOUTME: Recipients are checked at RCPT time; accepted messages are handed to a delivery function.
+
+package email
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMTP server limits.
+const (
+	maxRecipients      = 20
+	maxSMTPLineBytes   = 4096
+	maxSMTPConns       = 50
+	smtpCommandTimeout = 5 * time.Minute
+)
+
+// Envelope is one message as received over SMTP or fetched over IMAP.
+type Envelope struct {
+	RemoteIP   net.IP // nil for IMAP
+	Helo       string
+	MailFrom   string
+	Recipients []string // empty for IMAP; the To and Cc headers are used
+	Data       []byte
+}
+
+// Error is a delivery failure carrying the SMTP reply to send back.
+// 4xx codes are temporary: the sender should retry.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%d %s", e.Code, e.Message) }
+
+// Temporary reports whether the sender should retry later.
+func (e *Error) Temporary() bool { return e.Code >= 400 && e.Code < 500 }
+
+// smtpReply maps a delivery error to an SMTP reply; errors that are not an
+// *Error are treated as temporary local failures.
+func smtpReply(err error) (int, string) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code, e.Message
+	}
+	return 451, "4.3.0 local error, try again later"
+}
+
+// Server is a minimal SMTP receiver. It implements EHLO/HELO, MAIL, RCPT,
+// DATA, RSET, NOOP, VRFY, and QUIT; it offers no STARTTLS or AUTH, so run it
+// behind a mail exchanger or on a trusted network.
+type Server struct {
+	addr     string
+	domain   string
+	maxBytes int64
+	// checkRcpt vets a recipient at RCPT time.
+	checkRcpt func(ctx context.Context, addr string) error
+	deliver   func(ctx context.Context, env *Envelope) error
+	logger    *slog.Logger
+
+	ln     net.Listener
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	slots  chan struct{}
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// NewServer creates a server; call Listen to start accepting.
+func NewServer(addr, domain string, maxBytes int64, checkRcpt func(context.Context, string) error, deliver func(context.Context, *Envelope) error, logger *slog.Logger) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		addr:      addr,
+		domain:    domain,
+		maxBytes:  maxBytes,
+		checkRcpt: checkRcpt,
+		deliver:   deliver,
+		logger:    logger,
+		ctx:       ctx,
+		cancel:    cancel,
+		slots:     make(chan struct{}, maxSMTPConns),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// Listen binds the address and serves connections in the background.
+func (s *Server) Listen() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("listening on SMTP address: %w", err)
+	}
+	s.ln = ln
+	s.logger.Info("SMTP listener started", "addr", ln.Addr().String(), "domain", s.domain)
+	s.wg.Add(1)
+	go s.acceptLoop()
+	return nil
+}
+
+// Addr returns the bound address, or nil before Listen.
+func (s *Server) Addr() net.Addr {
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+// Close stops accepting, drops open sessions, and waits for them to end.
+func (s *Server) Close() {
+	s.cancel()
+	if s.ln != nil {
+		_ = s.ln.Close()
+	}
+	s.mu.Lock()
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if s.ctx.Err() == nil {
+				s.logger.Error("SMTP accept failed", "error", err)
+			}
+			return
+		}
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			_, _ = io.WriteString(conn, "421 4.3.2 too many connections, try again later\r\n")
+			_ = conn.Close()
+			continue
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() { <-s.slots }()
+			s.serve(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			_ = conn.Close()
+		}()
+	}
+}
+
+// smtpSession is the state of one connection.
+type smtpSession struct {
+	s   *Server
+	r   *bufio.Reader
+	w   *textproto.Writer
+	env Envelope
+}
+
+func (s *Server) serve(conn net.Conn) {
+	sess := &smtpSession{
+		s: s,
+		r: bufio.NewReaderSize(conn, maxSMTPLineBytes),
+		w: textproto.NewWriter(bufio.NewWriter(conn)),
+	}
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		sess.env.RemoteIP = addr.IP
+	}
+	sess.reply(220, s.domain+" ESMTP coven-gateway")
+	for {
+		_ = conn.SetDeadline(time.Now().Add(smtpCommandTimeout))
+		line, err := sess.readLine()
+		if errors.Is(err, bufio.ErrBufferFull) {
+			sess.reply(500, "5.5.2 line too long")
+			return
+		}
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		if !sess.handle(strings.ToUpper(verb), strings.TrimSpace(arg)) {
+			return
+		}
+	}
+}
+
+func (c *smtpSession) readLine() (string, error) {
+	line, err := c.r.ReadSlice('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+func (c *smtpSession) reply(code int, lines ...string) {
+	for i, line := range lines {
+		sep := " "
+		if i < len(lines)-1 {
+			sep = "-"
+		}
+		_ = c.w.PrintfLine("%d%s%s", code, sep, line)
+	}
+}
+
+// handle runs one command and reports whether the session continues.
+func (c *smtpSession) handle(verb, arg string) bool {
+	switch verb {
+	case "EHLO", "HELO":
+		if arg == "" {
+			c.reply(501, "5.5.4 domain required")
+			return true
+		}
+		c.env = Envelope{RemoteIP: c.env.RemoteIP, Helo: arg}
+		if verb == "HELO" {
+			c.reply(250, c.s.domain)
+		} else {
+			c.reply(250, c.s.domain, "SIZE "+strconv.FormatInt(c.s.maxBytes, 10), "8BITMIME")
+		}
+	case "MAIL":
+		c.mail(arg)
+	case "RCPT":
+		c.rcpt(arg)
+	case "DATA":
+		return c.data()
+	case "RSET":
+		c.resetTransaction()
+		c.reply(250, "2.0.0 ok")
+	case "NOOP":
+		c.reply(250, "2.0.0 ok")
+	case "VRFY":
+		c.reply(252, "2.5.0 cannot verify, but will attempt delivery")
+	case "QUIT":
+		c.reply(221, "2.0.0 bye")
+		return false
+	default:
+		c.reply(502, "5.5.1 command not implemented")
+	}
+	return true
+}
+
+func (c *smtpSession) resetTransaction() {
+	c.env = Envelope{RemoteIP: c.env.RemoteIP, Helo: c.env.Helo}
+}
+
+func (c *smtpSession) mail(arg string) {
+	if c.env.Helo == "" {
+		c.reply(503, "5.5.1 send EHLO first")
+		return
+	}
+	if c.env.MailFrom != "" {
+		c.reply(503, "5.5.1 sender already given")
+		return
+	}
+	path, params, ok := parsePath(arg, "FROM:")
+	if !ok {
+		c.reply(501, "5.5.4 syntax: MAIL FROM:<address>")
+		return
+	}
+	for _, p := range strings.Fields(params) {
+		if k, v, _ := strings.Cut(p, "="); strings.EqualFold(k, "SIZE") {
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > c.s.maxBytes {
+				c.reply(552, "5.3.4 message too large")
+				return
+			}
+		}
+	}
+	if path == "" {
+		path = "<>" // null reverse-path (bounce)
+	}
+	c.env.MailFrom = path
+	c.reply(250, "2.1.0 ok")
+}
+
+func (c *smtpSession) rcpt(arg string) {
+	if c.env.MailFrom == "" {
+		c.reply(503, "5.5.1 send MAIL first")
+		return
+	}
+	if len(c.env.Recipients) >= maxRecipients {
+		c.reply(452, "4.5.3 too many recipients")
+		return
+	}
+	path, _, ok := parsePath(arg, "TO:")
+	if !ok || path == "" {
+		c.reply(501, "5.5.4 syntax: RCPT TO:<address>")
+		return
+	}
+	if err := c.s.checkRcpt(c.s.ctx, path); err != nil {
+		c.reply(smtpReply(err))
+		return
+	}
+	c.env.Recipients = append(c.env.Recipients, strings.ToLower(path))
+	c.reply(250, "2.1.5 ok")
+}
+
+func (c *smtpSession) data() bool {
+	if len(c.env.Recipients) == 0 {
+		c.reply(503, "5.5.1 send RCPT first")
+		return true
+	}
+	c.reply(354, "end data with <CR><LF>.<CR><LF>")
+	dot := textproto.NewReader(c.r).DotReader()
+	data, err := io.ReadAll(io.LimitReader(dot, c.s.maxBytes+1))
+	if err != nil {
+		return false
+	}
+	if int64(len(data)) > c.s.maxBytes {
+		if _, err := io.Copy(io.Discard, dot); err != nil {
+			return false
+		}
+		c.resetTransaction()
+		c.reply(552, "5.3.4 message too large")
+		return true
+	}
+
+	env := c.env
+	env.Data = data
+	c.resetTransaction()
+	if err := c.s.deliver(c.s.ctx, &env); err != nil {
+		code, msg := smtpReply(err)
+		c.s.logger.Info("SMTP delivery refused", "from", env.MailFrom, "code", code, "reason", msg)
+		c.reply(code, msg)
+		return true
+	}
+	c.reply(250, "2.0.0 accepted")
+	return true
+}
+
+// parsePath extracts the address from "FROM:<addr> params" or "TO:<addr>".
+func parsePath(arg, prefix string) (path, params string, ok bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", "", false
+	}
+	end := strings.IndexByte(rest, '>')
+	if end < 0 {
+		return "", "", false
+	}
+	return rest[1:end], strings.TrimSpace(rest[end+1:]), true
+}
@@ -0,0 +1,375 @@
+// ABOUTME: SPF (RFC 7208) evaluation of the connecting IP against the envelope sender's domain.
+// ABOUTME: Supports the common mechanisms and modifiers; DNS goes through an injectable Resolver.
+
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Resolver is the DNS access SPF and DKIM need. *net.Resolver satisfies it.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// SPF results (RFC 7208 section 2.6).
+const (
+	ResultPass      = "pass"
+	ResultFail      = "fail"
+	ResultSoftFail  = "softfail"
+	ResultNeutral   = "neutral"
+	ResultNone      = "none"
+	ResultTempError = "temperror"
+	ResultPermError = "permerror"
+)
+
+// spfLookupLimit caps DNS-querying terms per check (RFC 7208 section 4.6.4).
+const spfLookupLimit = 10
+
+// CheckSPF evaluates the SPF policy of the sender's domain for a message from
+// ip. sender is the MAIL FROM address; when it is empty (bounces) the HELO
+// name is checked instead.
+func CheckSPF(ctx context.Context, r Resolver, ip net.IP, helo, sender string) string {
+	domain := helo
+	if _, d, ok := strings.Cut(sender, "@"); ok && d != "" {
+		domain = d
+	} else {
+		sender = "postmaster@" + helo
+	}
+	if ip == nil || domain == "" {
+		return ResultNone
+	}
+	c := &spfCheck{r: r, ip: ip, sender: sender, helo: helo}
+	return c.checkHost(ctx, strings.ToLower(strings.TrimSuffix(domain, ".")))
+}
+
+type spfCheck struct {
+	r       Resolver
+	ip      net.IP
+	sender  string
+	helo    string
+	lookups int
+}
+
+// errPermError and errTempError abort SPF or DKIM evaluation with a
+// permerror or temperror result.
+var (
+	errPermError = errors.New("permanent error")
+	errTempError = errors.New("temporary error")
+)
+
+func (c *spfCheck) checkHost(ctx context.Context, domain string) string {
+	record, err := c.record(ctx, domain)
+	switch {
+	case errors.Is(err, errTempError):
+		return ResultTempError
+	case errors.Is(err, errPermError):
+		return ResultPermError
+	case record == "":
+		return ResultNone
+	}
+
+	var redirect string
+	for _, term := range strings.Fields(record)[1:] {
+		if name, value, ok := strings.Cut(term, "="); ok && !strings.ContainsAny(name, ":/") {
+			if strings.EqualFold(name, "redirect") {
+				redirect = value
+			}
+			continue // exp= and unknown modifiers are ignored
+		}
+
+		qualifier := ResultPass
+		switch term[0] {
+		case '+':
+			term = term[1:]
+		case '-':
+			qualifier, term = ResultFail, term[1:]
+		case '~':
+			qualifier, term = ResultSoftFail, term[1:]
+		case '?':
+			qualifier, term = ResultNeutral, term[1:]
+		}
+
+		matched, err := c.match(ctx, domain, term)
+		switch {
+		case errors.Is(err, errTempError):
+			return ResultTempError
+		case err != nil:
+			return ResultPermError
+		case matched:
+			return qualifier
+		}
+	}
+
+	if redirect != "" {
+		target, err := c.expand(redirect, domain)
+		if err != nil {
+			return ResultPermError
+		}
+		if err := c.countLookup(); err != nil {
+			return ResultPermError
+		}
+		result := c.checkHost(ctx, target)
+		if result == ResultNone {
+			return ResultPermError
+		}
+		return result
+	}
+	return ResultNeutral
+}
+
+// record returns the domain's single v=spf1 record, or "" when it has none.
+func (c *spfCheck) record(ctx context.Context, domain string) (string, error) {
+	txts, err := c.r.LookupTXT(ctx, domain)
+	if err != nil {
+		if isNotFound(err) {
+			return "", nil
+		}
+		return "", errTempError
+	}
+	var found []string
+	for _, txt := range txts {
+		if strings.EqualFold(txt, "v=spf1") || strings.HasPrefix(strings.ToLower(txt), "v=spf1 ") {
+			found = append(found, txt)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	default:
+		return "", errPermError
+	}
+}
+
+func (c *spfCheck) countLookup() error {
+	c.lookups++
+	if c.lookups > spfLookupLimit {
+		return errPermError
+	}
+	return nil
+}
+
+// match reports whether one mechanism matches the connecting IP.
+func (c *spfCheck) match(ctx context.Context, domain, term string) (bool, error) {
+	name, arg, _ := strings.Cut(term, ":")
+	name = strings.ToLower(name)
+	// a and mx may carry a CIDR suffix without a domain: "a/24".
+	if arg == "" {
+		if n, cidr, ok := strings.Cut(name, "/"); ok {
+			name, arg = n, "/"+cidr
+		}
+	}
+
+	switch name {
+	case "all":
+		return true, nil
+	case "ip4", "ip6":
+		return c.matchCIDR(arg)
+	case "a", "mx":
+		if err := c.countLookup(); err != nil {
+			return false, err
+		}
+		target, v4, v6, err := c.splitCIDR(arg, domain)
+		if err != nil {
+			return false, err
+		}
+		hosts := []string{target}
+		if name == "mx" {
+			mxs, err := c.r.LookupMX(ctx, target)
+			if err != nil && !isNotFound(err) {
+				return false, errTempError
+			}
+			hosts = hosts[:0]
+			for _, mx := range mxs {
+				hosts = append(hosts, mx.Host)
+			}
+		}
+		for _, host := range hosts {
+			ok, err := c.hostMatches(ctx, host, v4, v6)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case "include":
+		if arg == "" {
+			return false, errPermError
+		}
+		if err := c.countLookup(); err != nil {
+			return false, err
+		}
+		target, err := c.expand(arg, domain)
+		if err != nil {
+			return false, err
+		}
+		switch c.checkHost(ctx, target) {
+		case ResultPass:
+			return true, nil
+		case ResultTempError:
+			return false, errTempError
+		case ResultPermError, ResultNone:
+			return false, errPermError
+		}
+		return false, nil
+	case "exists":
+		if err := c.countLookup(); err != nil {
+			return false, err
+		}
+		target, err := c.expand(arg, domain)
+		if err != nil {
+			return false, err
+		}
+		addrs, err := c.r.LookupIPAddr(ctx, target)
+		if err != nil && !isNotFound(err) {
+			return false, errTempError
+		}
+		return len(addrs) > 0, nil
+	case "ptr":
+		// Deprecated (RFC 7208 section 5.5); treated as not matching.
+		return false, c.countLookup()
+	}
+	return false, errPermError
+}
+
+func (c *spfCheck) matchCIDR(arg string) (bool, error) {
+	if !strings.Contains(arg, "/") {
+		ip := net.ParseIP(arg)
+		if ip == nil {
+			return false, errPermError
+		}
+		return ip.Equal(c.ip), nil
+	}
+	_, network, err := net.ParseCIDR(arg)
+	if err != nil {
+		return false, errPermError
+	}
+	return network.Contains(c.ip), nil
+}
+
+// splitCIDR parses "domain/v4cidr//v6cidr" as used by a and mx.
+func (c *spfCheck) splitCIDR(arg, domain string) (target string, v4, v6 int, err error) {
+	v4, v6 = 32, 128
+	target, cidrs, _ := strings.Cut(arg, "/")
+	if cidrs != "" {
+		four, six, dual := strings.Cut(cidrs, "/")
+		if dual {
+			six = strings.TrimPrefix(six, "/")
+		}
+		if four != "" {
+			if v4, err = strconv.Atoi(four); err != nil || v4 < 0 || v4 > 32 {
+				return "", 0, 0, errPermError
+			}
+		}
+		if six != "" {
+			if v6, err = strconv.Atoi(six); err != nil || v6 < 0 || v6 > 128 {
+				return "", 0, 0, errPermError
+			}
+		}
+	}
+	if target == "" {
+		return domain, v4, v6, nil
+	}
+	target, err = c.expand(target, domain)
+	return target, v4, v6, err
+}
+
+func (c *spfCheck) hostMatches(ctx context.Context, host string, v4, v6 int) (bool, error) {
+	addrs, err := c.r.LookupIPAddr(ctx, host)
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, errTempError
+	}
+	for _, a := range addrs {
+		bits, size := v6, 128
+		if a.IP.To4() != nil {
+			bits, size = v4, 32
+		}
+		if (c.ip.To4() != nil) != (size == 32) {
+			continue
+		}
+		mask := net.CIDRMask(bits, size)
+		if a.IP.Mask(mask).Equal(c.ip.Mask(mask)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// expand performs SPF macro expansion for the simple macros (no
+// transformers); anything fancier is a permerror.
+func (c *spfCheck) expand(spec, domain string) (string, error) {
+	if !strings.Contains(spec, "%") {
+		return strings.ToLower(strings.TrimSuffix(spec, ".")), nil
+	}
+	local, senderDomain, _ := strings.Cut(c.sender, "@")
+	var b strings.Builder
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != '%' {
+			b.WriteByte(spec[i])
+			continue
+		}
+		if i+1 >= len(spec) {
+			return "", errPermError
+		}
+		i++
+		switch spec[i] {
+		case '%':
+			b.WriteByte('%')
+		case '_':
+			b.WriteByte(' ')
+		case '-':
+			b.WriteString("%20")
+		case '{':
+			end := strings.IndexByte(spec[i:], '}')
+			if end != 2 {
+				return "", errPermError
+			}
+			switch spec[i+1] {
+			case 's':
+				b.WriteString(c.sender)
+			case 'l':
+				b.WriteString(local)
+			case 'o':
+				b.WriteString(senderDomain)
+			case 'd':
+				b.WriteString(domain)
+			case 'i':
+				b.WriteString(c.ip.String())
+			case 'h':
+				b.WriteString(c.helo)
+			default:
+				return "", errPermError
+			}
+			i += end
+		default:
+			return "", errPermError
+		}
+	}
+	return strings.ToLower(strings.TrimSuffix(b.String(), ".")), nil
+}
+
+// isNotFound reports whether a DNS error means the name has no records, as
+// opposed to a lookup failure worth retrying.
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// spfDetail formats the SPF part of an Authentication-Results style summary.
+func spfDetail(result, domain string) string {
+	if domain == "" {
+		return "spf=" + result
+	}
+	return fmt.Sprintf("spf=%s smtp.mailfrom=%s", result, domain)
+}
@@ -0,0 +1,36 @@
+// Package flags provides store-backed feature flags with runtime toggling.
+//
+// # Overview
+//
+// Every flag is declared in the Known registry with a description and a
+// compiled-in default. Admins override a flag at runtime through the web
+// admin; overrides are stored in the feature_flags table. A flag with no
+// stored row uses its default, so an empty or missing table never breaks
+// startup.
+//
+// # Rollout
+//
+// An enabled override can be narrowed to a percentage of principals and/or an
+// explicit principal allowlist:
+//
+//   - Allowlisted principals always see the flag enabled.
+//   - With a percentage, each principal is hashed with the flag name into a
+//     stable bucket 0-99 and sees the flag when its bucket is below the
+//     percentage. The same principal gets the same answer on every call.
+//   - With neither, the flag is enabled for everyone.
+//
+// # Usage
+//
+//	if featureFlags.Enabled(ctx, flags.SSEResume) {
+//	    // gated behavior
+//	}
+//
+// Enabled reads the principal from the request's auth context; use
+// EnabledFor when the principal is known some other way.
+//
+// # Caching
+//
+// Overrides are loaded once and served from memory. Set writes through to
+// the store and refreshes the cache, so lookups never hit the database on the
+// hot path.
+package flags
@@ -0,0 +1,209 @@
+// ABOUTME: Feature flag registry and cached evaluator backed by the store.
+// ABOUTME: Evaluates percentage rollouts deterministically per principal.
+
+package flags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// Names of known flags.
+const (
+	BatchedLedgerWrites = "batched_ledger_writes"
+	SSEResume           = "sse_resume"
+	FanOutSends         = "fan_out_sends"
+)
+
+// Definition describes a known flag and its compiled-in default.
+type Definition struct {
+	Name        string
+	Description string
+	Default     bool
+}
+
+// Known lists every flag the gateway understands. Overrides for names not in
+// this list are rejected.
+var Known = []Definition{
+	{
+		Name:        BatchedLedgerWrites,
+		Description: "Buffer ledger event writes and flush them in batches instead of one insert per event.",
+	},
+	{
+		Name:        SSEResume,
+		Description: "Let SSE clients resume a dropped stream with Last-Event-ID.",
+	},
+	{
+		Name:        FanOutSends,
+		Description: "Allow a single send to be delivered to several agents at once.",
+	},
+}
+
+// ErrUnknownFlag indicates a flag name that is not in the Known registry.
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+// Store persists flag overrides.
+type Store interface {
+	ListFeatureFlags(ctx context.Context) ([]*store.FeatureFlag, error)
+	SaveFeatureFlag(ctx context.Context, f *store.FeatureFlag) error
+}
+
+// State is a flag's definition combined with its current override, if any.
+type State struct {
+	Definition
+	Override *store.FeatureFlag // nil when the default applies
+}
+
+// Service evaluates flags from an in-memory copy of the stored overrides.
+type Service struct {
+	store  Store
+	logger *slog.Logger
+
+	mu        sync.RWMutex
+	loaded    bool
+	overrides map[string]*store.FeatureFlag
+}
+
+// New creates a Service. Overrides are loaded lazily on first lookup.
+func New(s Store, logger *slog.Logger) *Service {
+	return &Service{store: s, logger: logger}
+}
+
+// Lookup returns the definition for a known flag.
+func Lookup(name string) (Definition, bool) {
+	for _, d := range Known {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Definition{}, false
+}
+
+// Enabled reports whether a flag is on for the principal in ctx's auth context.
+// Requests without a principal only match overrides with no percentage.
+func (s *Service) Enabled(ctx context.Context, name string) bool {
+	var principalID string
+	if a := auth.FromContext(ctx); a != nil {
+		principalID = a.PrincipalID
+	}
+	return s.EnabledFor(ctx, name, principalID)
+}
+
+// EnabledFor reports whether a flag is on for the given principal.
+func (s *Service) EnabledFor(ctx context.Context, name, principalID string) bool {
+	def, ok := Lookup(name)
+	if !ok {
+		s.logger.Warn("lookup of unknown feature flag", "flag", name)
+		return false
+	}
+	override := s.override(ctx, name)
+	if override == nil {
+		return def.Default
+	}
+	return evaluate(override, principalID)
+}
+
+// evaluate applies an override's rollout rules to a principal.
+func evaluate(f *store.FeatureFlag, principalID string) bool {
+	if !f.Enabled {
+		return false
+	}
+	if principalID != "" && slices.Contains(f.Principals, principalID) {
+		return true
+	}
+	if f.Percentage != nil {
+		if principalID == "" {
+			return *f.Percentage >= 100
+		}
+		return Bucket(f.Name, principalID) < *f.Percentage
+	}
+	// An allowlist without a percentage restricts the flag to that list.
+	return len(f.Principals) == 0
+}
+
+// Bucket maps a principal to a stable bucket in [0, 100) for a flag. Hashing
+// the flag name in keeps rollouts of different flags independent.
+func Bucket(name, principalID string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(principalID))
+	return int(h.Sum32() % 100)
+}
+
+// List returns every known flag with its current override.
+func (s *Service) List(ctx context.Context) []State {
+	s.ensureLoaded(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	states := make([]State, 0, len(Known))
+	for _, d := range Known {
+		states = append(states, State{Definition: d, Override: s.overrides[d.Name]})
+	}
+	return states
+}
+
+// Set stores an override for a known flag and refreshes the cache.
+func (s *Service) Set(ctx context.Context, f *store.FeatureFlag) error {
+	if _, ok := Lookup(f.Name); !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownFlag, f.Name)
+	}
+	if err := s.store.SaveFeatureFlag(ctx, f); err != nil {
+		return err
+	}
+	s.Invalidate()
+	s.ensureLoaded(ctx)
+	s.logger.Info("feature flag updated", "flag", f.Name, "enabled", f.Enabled, "by", f.UpdatedBy)
+	return nil
+}
+
+// Invalidate drops the cached overrides; the next lookup reloads them.
+func (s *Service) Invalidate() {
+	s.mu.Lock()
+	s.loaded = false
+	s.overrides = nil
+	s.mu.Unlock()
+}
+
+// override returns the cached override for a flag, or nil.
+func (s *Service) override(ctx context.Context, name string) *store.FeatureFlag {
+	s.ensureLoaded(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.overrides[name]
+}
+
+// ensureLoaded fills the cache from the store if needed. A load failure is
+// logged and leaves the defaults in effect until the next attempt.
+func (s *Service) ensureLoaded(ctx context.Context) {
+	s.mu.RLock()
+	loaded := s.loaded
+	s.mu.RUnlock()
+	if loaded {
+		return
+	}
+
+	rows, err := s.store.ListFeatureFlags(ctx)
+	if err != nil {
+		s.logger.Warn("failed to load feature flags, using defaults", "error", err)
+		return
+	}
+	overrides := make(map[string]*store.FeatureFlag, len(rows))
+	for _, f := range rows {
+		overrides[f.Name] = f
+	}
+
+	s.mu.Lock()
+	s.overrides = overrides
+	s.loaded = true
+	s.mu.Unlock()
+}
@@ -0,0 +1,164 @@
+// ABOUTME: Tests for feature flag evaluation, caching, and percentage rollouts.
+// ABOUTME: Verifies rollouts are deterministic per principal and independent per flag.
+
+package flags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func newTestService(t *testing.T) (*Service, *store.SQLStore) {
+	t.Helper()
+	s, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return New(s, slog.Default()), s
+}
+
+func percent(p int) *int { return &p }
+
+func TestService_DefaultsWithoutRows(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	for _, d := range Known {
+		if got := svc.EnabledFor(ctx, d.Name, "p1"); got != d.Default {
+			t.Errorf("%s = %v, want default %v", d.Name, got, d.Default)
+		}
+	}
+	if svc.EnabledFor(ctx, "no_such_flag", "p1") {
+		t.Error("unknown flag reported enabled")
+	}
+}
+
+func TestService_SetUpdatesCache(t *testing.T) {
+	svc, s := newTestService(t)
+	ctx := context.Background()
+
+	if svc.Enabled(ctx, SSEResume) {
+		t.Fatal("sse_resume enabled before override")
+	}
+	if err := svc.Set(ctx, &store.FeatureFlag{Name: SSEResume, Enabled: true, UpdatedBy: "admin"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if !svc.Enabled(ctx, SSEResume) {
+		t.Error("sse_resume disabled after enabling")
+	}
+
+	// A write that bypasses the service is invisible until invalidation.
+	if err := s.SaveFeatureFlag(ctx, &store.FeatureFlag{Name: SSEResume, Enabled: false}); err != nil {
+		t.Fatalf("SaveFeatureFlag: %v", err)
+	}
+	if !svc.Enabled(ctx, SSEResume) {
+		t.Error("cache reloaded without invalidation")
+	}
+	svc.Invalidate()
+	if svc.Enabled(ctx, SSEResume) {
+		t.Error("stale cache after Invalidate")
+	}
+
+	err := svc.Set(ctx, &store.FeatureFlag{Name: "no_such_flag", Enabled: true})
+	if !errors.Is(err, ErrUnknownFlag) {
+		t.Errorf("Set unknown flag error = %v, want ErrUnknownFlag", err)
+	}
+}
+
+func TestService_PercentageRolloutIsDeterministic(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	if err := svc.Set(ctx, &store.FeatureFlag{Name: FanOutSends, Enabled: true, Percentage: percent(30)}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	const principals = 2000
+	first := make(map[string]bool, principals)
+	on := 0
+	for i := range principals {
+		id := fmt.Sprintf("principal-%d", i)
+		first[id] = svc.EnabledFor(ctx, FanOutSends, id)
+		if first[id] {
+			on++
+		}
+		if first[id] != (Bucket(FanOutSends, id) < 30) {
+			t.Fatalf("%s: result disagrees with its bucket", id)
+		}
+	}
+	// Roughly 30% of principals, with generous slack for hash variance.
+	if on < principals*22/100 || on > principals*38/100 {
+		t.Errorf("%d of %d principals enabled at 30%%", on, principals)
+	}
+
+	// A fresh service sees the same answers for every principal.
+	svc.Invalidate()
+	for id, want := range first {
+		if got := svc.EnabledFor(ctx, FanOutSends, id); got != want {
+			t.Fatalf("%s flipped from %v to %v across reloads", id, want, got)
+		}
+	}
+
+	// Raising the percentage only adds principals.
+	if err := svc.Set(ctx, &store.FeatureFlag{Name: FanOutSends, Enabled: true, Percentage: percent(60)}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	for id, was := range first {
+		if was && !svc.EnabledFor(ctx, FanOutSends, id) {
+			t.Fatalf("%s dropped out when the rollout grew", id)
+		}
+	}
+}
+
+func TestService_BucketsIndependentPerFlag(t *testing.T) {
+	same := 0
+	for i := range 500 {
+		id := fmt.Sprintf("principal-%d", i)
+		if Bucket(SSEResume, id) == Bucket(FanOutSends, id) {
+			same++
+		}
+	}
+	if same > 50 {
+		t.Errorf("%d of 500 principals share buckets across flags; rollouts are correlated", same)
+	}
+}
+
+func TestService_AllowlistAndAuthContext(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	if err := svc.Set(ctx, &store.FeatureFlag{
+		Name: BatchedLedgerWrites, Enabled: true, Percentage: percent(0), Principals: []string{"vip"},
+	}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if !svc.EnabledFor(ctx, BatchedLedgerWrites, "vip") {
+		t.Error("allowlisted principal not enabled")
+	}
+	if svc.EnabledFor(ctx, BatchedLedgerWrites, "other") {
+		t.Error("non-allowlisted principal enabled at 0%")
+	}
+
+	vipCtx := auth.WithAuth(ctx, &auth.AuthContext{PrincipalID: "vip"})
+	if !svc.Enabled(vipCtx, BatchedLedgerWrites) {
+		t.Error("Enabled did not use the principal from the auth context")
+	}
+	if svc.Enabled(ctx, BatchedLedgerWrites) {
+		t.Error("anonymous request enabled at 0%")
+	}
+
+	if err := svc.Set(ctx, &store.FeatureFlag{Name: BatchedLedgerWrites, Enabled: false, Principals: []string{"vip"}}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if svc.EnabledFor(ctx, BatchedLedgerWrites, "vip") {
+		t.Error("disabled flag still on for allowlisted principal")
+	}
+}
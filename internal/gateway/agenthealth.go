@@ -0,0 +1,96 @@
+// ABOUTME: Wires heartbeat-driven agent health into the gateway: thresholds from config,
+// ABOUTME: a periodic re-check, and a log line plus agent_health ledger event on degradation.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// agentHealthCheckInterval is how often agent health is re-evaluated, so an
+// agent that falls silent is noticed without waiting for its next heartbeat.
+const agentHealthCheckInterval = 10 * time.Second
+
+// agentHealthRecord is the JSON text of an agent_health ledger event.
+type agentHealthRecord struct {
+	State  agent.HealthState `json:"state"`
+	From   agent.HealthState `json:"from"`
+	Reason string            `json:"reason"`
+}
+
+// agentHealthThresholds returns the health thresholds for the configured
+// agents section. Unset silence thresholds follow the heartbeat settings:
+// two missed intervals is degraded and the heartbeat timeout is stale.
+func agentHealthThresholds(cfg config.AgentsConfig) agent.HealthThresholds {
+	t := agent.HealthThresholds{
+		DegradedLatency: cfg.Health.DegradedLatency,
+		DegradedAfter:   cfg.Health.DegradedAfter,
+		StaleAfter:      cfg.Health.StaleAfter,
+	}
+	if t.DegradedAfter == 0 && cfg.HeartbeatInterval > 0 {
+		t.DegradedAfter = 2 * cfg.HeartbeatInterval
+	}
+	if t.StaleAfter == 0 && cfg.HeartbeatTimeout > 0 {
+		t.StaleAfter = max(cfg.HeartbeatTimeout, t.DegradedAfter)
+	}
+	return t
+}
+
+// agentHealthRecorder returns an agent.Manager health observer that logs
+// every state change and saves an agent_health event to the agent's ledger
+// when it stops being healthy.
+func agentHealthRecorder(s store.Store, logger *slog.Logger) func(agentID string, from agent.HealthState, h agent.HeartbeatHealth) {
+	return func(agentID string, from agent.HealthState, h agent.HeartbeatHealth) {
+		if h.State == agent.HealthHealthy {
+			logger.Info("agent health recovered", "agent_id", agentID, "from", from)
+			return
+		}
+		logger.Warn("agent health degraded",
+			"agent_id", agentID,
+			"state", h.State,
+			"from", from,
+			"reason", h.Reason,
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), toolCheckTimeout)
+		defer cancel()
+		data, _ := json.Marshal(agentHealthRecord{State: h.State, From: from, Reason: h.Reason})
+		text := string(data)
+		err := s.SaveEvent(ctx, &store.LedgerEvent{
+			ID:              uuid.New().String(),
+			ConversationKey: agentID,
+			Direction:       store.EventDirectionInbound,
+			Author:          "system",
+			Timestamp:       time.Now().UTC(),
+			Type:            store.EventTypeAgentHealth,
+			Text:            &text,
+		})
+		if err != nil {
+			logger.Warn("failed to record agent health", "agent_id", agentID, "error", err)
+		}
+	}
+}
+
+// watchAgentHealth re-evaluates every connected agent's health until ctx is
+// done.
+func (g *Gateway) watchAgentHealth(ctx context.Context) {
+	ticker := time.NewTicker(agentHealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			g.agentManager.CheckHealth(now)
+		}
+	}
+}
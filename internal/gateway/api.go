@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"slices"
 	"strconv"
@@ -17,9 +18,17 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/2389/coven-gateway/internal/admin"
 	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
 	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/diskmon"
+	"github.com/2389/coven-gateway/internal/httpapi"
+	"github.com/2389/coven-gateway/internal/logctx"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+	"github.com/2389/coven-gateway/internal/usage"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
@@ -31,17 +40,60 @@ type SendMessageRequest struct {
 	AgentID   string `json:"agent_id,omitempty"`
 	Frontend  string `json:"frontend,omitempty"`
 	ChannelID string `json:"channel_id,omitempty"`
+	// Capability routes the send to the least-loaded agent advertising it,
+	// when neither agent_id nor frontend+channel_id is given.
+	Capability string `json:"capability,omitempty"`
+	// Tags route the send, in place of agent_id, to the connected agent
+	// carrying every tag. Several matches are refused unless Any is set, in
+	// which case the least-loaded of them is chosen.
+	Tags map[string]string `json:"tags,omitempty"`
+	Any  bool              `json:"any,omitempty"`
+	// AckMode is "implicit" (default) or "explicit". Explicit mode keeps the
+	// response pending until the bridge acks or nacks its delivery.
+	AckMode string `json:"ack_mode,omitempty"`
+	// MaxResponseSeconds caps this response, overriding the binding and
+	// gateway defaults. Zero keeps them.
+	MaxResponseSeconds int `json:"max_response_seconds,omitempty"`
+	// Workspace picks one of the workspaces the agent registered, overriding
+	// the binding's default. Empty keeps it.
+	Workspace string `json:"workspace,omitempty"`
+	// IdempotencyKey makes retries of this send return the original
+	// request; the Idempotency-Key header overrides it.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// Attachments come from the "attachment" parts of a multipart send.
+	Attachments []agent.Attachment `json:"-"`
 }
 
 // AgentInfoResponse is the JSON response for GET /api/agents.
 type AgentInfoResponse struct {
-	ID           string   `json:"id"`
-	InstanceID   string   `json:"instance_id,omitempty"`
-	Name         string   `json:"name"`
-	Capabilities []string `json:"capabilities"`
-	Workspaces   []string `json:"workspaces,omitempty"`
-	WorkingDir   string   `json:"working_dir,omitempty"`
-	Backend      string   `json:"backend,omitempty"`
+	ID           string            `json:"id"`
+	InstanceID   string            `json:"instance_id,omitempty"`
+	Name         string            `json:"name"`
+	Capabilities []string          `json:"capabilities"`
+	Workspaces   []string          `json:"workspaces,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty"`
+	WorkingDir   string            `json:"working_dir,omitempty"`
+	Backend      string            `json:"backend,omitempty"`
+	Hostname     string            `json:"hostname,omitempty"`
+	OS           string            `json:"os,omitempty"`
+	Git          *agent.GitInfo    `json:"git,omitempty"`
+	Paused       bool              `json:"paused,omitempty"`
+	PausedBy     string            `json:"paused_by,omitempty"`
+	PausedAt     string            `json:"paused_at,omitempty"`
+	// SupersedeCount is how many times a reconnect has replaced the agent's
+	// connection since the gateway started.
+	SupersedeCount int `json:"supersede_count"`
+
+	// Health is healthy, degraded or stale, judged from the agent's
+	// heartbeats; HealthReason says why when it is not healthy.
+	Health        string `json:"health"`
+	HealthReason  string `json:"health_reason,omitempty"`
+	LastHeartbeat string `json:"last_heartbeat,omitempty"`
+	// Heartbeat round-trip latency and clock skew (gateway minus agent) are
+	// only reported by agents that support heartbeat acks.
+	LatencyMsP50 float64 `json:"latency_ms_p50,omitempty"`
+	LatencyMsP95 float64 `json:"latency_ms_p95,omitempty"`
+	ClockSkewMs  int64   `json:"clock_skew_ms,omitempty"`
 }
 
 // CreateBindingRequest is the JSON request body for POST /api/bindings.
@@ -50,6 +102,19 @@ type CreateBindingRequest struct {
 	Frontend   string `json:"frontend"`
 	ChannelID  string `json:"channel_id"`
 	InstanceID string `json:"instance_id"`
+	// Workspace, if set, pins the channel to one of the agent's workspaces.
+	Workspace string `json:"workspace,omitempty"`
+	// Context, if set, replaces the channel context sent to the agent with
+	// each message. Left out, the channel keeps its current context, even
+	// when rebound to another agent.
+	Context *string `json:"context,omitempty"`
+}
+
+// UpdateBindingRequest is the JSON request body for
+// PATCH /api/bindings?frontend=X&channel_id=Y.
+type UpdateBindingRequest struct {
+	// Context replaces the channel context; empty clears it.
+	Context *string `json:"context"`
 }
 
 // CreateBindingResponse is the JSON response for POST /api/bindings.
@@ -57,6 +122,8 @@ type CreateBindingResponse struct {
 	BindingID   string  `json:"binding_id"`
 	AgentName   string  `json:"agent_name"`
 	WorkingDir  string  `json:"working_dir"`
+	Workspace   string  `json:"workspace,omitempty"`
+	Context     string  `json:"context,omitempty"`
 	ReboundFrom *string `json:"rebound_from"`
 }
 
@@ -65,10 +132,21 @@ type BindingResponse struct {
 	Frontend    string `json:"frontend"`
 	ChannelID   string `json:"channel_id"`
 	AgentID     string `json:"agent_id"`
-	AgentName   string `json:"agent_name,omitempty"`
+	AgentName   string `json:"agent_name"`
 	AgentOnline bool   `json:"agent_online"`
+	// AgentStatus is "online", "grace" (disconnected, may still resume its
+	// session), or "offline".
+	AgentStatus string `json:"agent_status"`
 	WorkingDir  string `json:"working_dir"`
+	Workspace   string `json:"workspace,omitempty"`
+	Context     string `json:"context,omitempty"`
 	CreatedAt   string `json:"created_at"`
+	// FallbackAgentIDs are tried in order once the agent is offline past
+	// its grace period.
+	FallbackAgentIDs []string `json:"fallback_agent_ids,omitempty"`
+	// EffectiveAgentID is the agent the channel's messages go to right now:
+	// agent_id, a fallback, or empty when none would take them.
+	EffectiveAgentID string `json:"effective_agent_id"`
 }
 
 // ListBindingsResponse is the JSON response for GET /api/bindings.
@@ -78,10 +156,15 @@ type ListBindingsResponse struct {
 
 // SingleBindingResponse is the JSON response for GET /api/bindings?frontend=X&channel_id=Y.
 type SingleBindingResponse struct {
-	BindingID  string `json:"binding_id"`
-	AgentName  string `json:"agent_name"`
-	WorkingDir string `json:"working_dir"`
-	Online     bool   `json:"online"`
+	BindingID   string `json:"binding_id"`
+	AgentName   string `json:"agent_name"`
+	WorkingDir  string `json:"working_dir"`
+	Workspace   string `json:"workspace,omitempty"`
+	Context     string `json:"context,omitempty"`
+	Online      bool   `json:"online"`
+	AgentStatus string `json:"agent_status"`
+	// EffectiveAgentID is as in BindingResponse.
+	EffectiveAgentID string `json:"effective_agent_id"`
 }
 
 // SendToAgentRequest is the JSON request body for POST /api/agents/{id}/send.
@@ -147,43 +230,86 @@ type SSEEvent struct {
 
 // handleListAgents handles GET /api/agents requests.
 // It returns a JSON array of all connected agents.
-// Supports optional ?workspace=X query parameter to filter by workspace membership.
+// Supports optional ?workspace=X query parameter to filter by workspace membership,
+// and repeated ?tag=key=value parameters to filter by tags.
 func (g *Gateway) handleListAgents(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
+	tags, err := agent.ParseTagFilter(r.URL.Query()["tag"])
+	if err != nil {
+		g.sendJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	sender := g.getSender()
-	agents := sender.ListAgents()
+	response := g.listAgentResponses(r.URL.Query().Get("workspace"), tags)
 
-	// Check for workspace filter
-	workspaceFilter := r.URL.Query().Get("workspace")
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
 
+// listAgentResponses returns the connected agents, sorted by ID, optionally
+// filtered to members of workspace and to agents carrying every tag in tags.
+func (g *Gateway) listAgentResponses(workspace string, tags map[string]string) []AgentInfoResponse {
+	agents := g.getSender().ListAgents()
 	response := make([]AgentInfoResponse, 0, len(agents))
 	for _, a := range agents {
-		// Apply workspace filter if specified
-		if workspaceFilter != "" {
-			if !containsWorkspace(a.Workspaces, workspaceFilter) {
-				continue
-			}
+		if workspace != "" && !containsWorkspace(a.Workspaces, workspace) {
+			continue
 		}
-
-		response = append(response, AgentInfoResponse{
-			ID:           a.ID,
-			InstanceID:   a.InstanceID,
-			Name:         a.Name,
-			Capabilities: a.Capabilities,
-			Workspaces:   a.Workspaces,
-			WorkingDir:   a.WorkingDir,
-			Backend:      a.Backend,
-		})
+		if !a.Metadata.MatchesTags(tags) {
+			continue
+		}
+		response = append(response, agentInfoToResponse(a))
 	}
+	slices.SortFunc(response, func(a, b AgentInfoResponse) int { return strings.Compare(a.ID, b.ID) })
+	return response
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		g.logger.Debug("failed to encode response", "error", err)
+// agentInfoToResponse converts a connected agent to its API form.
+func agentInfoToResponse(a *agent.AgentInfo) AgentInfoResponse {
+	item := AgentInfoResponse{
+		ID:           a.ID,
+		InstanceID:   a.InstanceID,
+		Name:         a.Name,
+		Capabilities: a.Capabilities,
+		Workspaces:   a.Workspaces,
+		WorkingDir:   a.WorkingDir,
+		Backend:      a.Backend,
+
+		SupersedeCount: a.SupersedeCount,
+
+		Health:       string(a.Health.State),
+		HealthReason: a.Health.Reason,
+	}
+	if !a.Health.LastHeartbeat.IsZero() {
+		item.LastHeartbeat = timeparse.Format(a.Health.LastHeartbeat)
+	}
+	if a.Health.Samples > 0 {
+		item.LatencyMsP50 = durationMs(a.Health.LatencyP50)
+		item.LatencyMsP95 = durationMs(a.Health.LatencyP95)
+		item.ClockSkewMs = a.Health.ClockSkew.Milliseconds()
+	}
+	if a.Metadata != nil {
+		item.Hostname = a.Metadata.Hostname
+		item.OS = a.Metadata.OS
+		item.Git = a.Metadata.Git
+		item.Tags = a.Metadata.Tags
+	}
+	if a.Paused != nil {
+		item.Paused = true
+		item.PausedBy = a.Paused.PausedBy
+		item.PausedAt = timeparse.Format(a.Paused.PausedAt)
 	}
+	return item
+}
+
+// durationMs converts d to fractional milliseconds.
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
 }
 
 // containsWorkspace checks if a workspace is in the list of workspaces.
@@ -229,7 +355,7 @@ func eventToHistoryEvent(evt store.LedgerEvent) AgentHistoryEvent {
 		Direction: string(evt.Direction),
 		Author:    evt.Author,
 		Type:      string(evt.Type),
-		Timestamp: evt.Timestamp.Format(time.RFC3339),
+		Timestamp: timeparse.Format(evt.Timestamp),
 	}
 	if evt.ThreadID != nil {
 		e.ThreadID = *evt.ThreadID
@@ -267,7 +393,27 @@ type resolvedTarget struct {
 	ThreadID     string
 	FrontendName string
 	ExternalID   string
-}
+	MaxDuration  time.Duration     // the binding's response limit, if any
+	Agent        *agent.Connection // the connection that will handle the request
+	Selection    string            // how the agent was chosen, see selectionExplicit
+	Route        *agent.Route      // load details for capability selection, else nil
+	FallbackFor  string            // the bound agent a fallback agent stands in for, if any
+	Workspace    string            // the agent workspace to handle the send in, if not its default
+	Context      string            // the binding's channel context, if any
+}
+
+// Agent selection modes reported in the started event.
+const (
+	selectionExplicit   = "explicit"   // the request named the agent
+	selectionBinding    = "binding"    // the channel's binding chose the agent
+	selectionCapability = "capability" // the least-loaded agent with the capability
+	selectionTags       = "tags"       // the agent carrying the requested tags
+	selectionGroup      = "group"      // a member of the agent group a broadcast targeted
+)
+
+// capabilityQueueTimeout bounds how long a capability send waits for an
+// agent below its max_concurrent limit.
+const capabilityQueueTimeout = 30 * time.Second
 
 // resolveTarget resolves agent ID and thread ID from the request.
 // Returns nil with an error message if resolution fails.
@@ -279,7 +425,8 @@ func (g *Gateway) resolveTarget(ctx context.Context, req *SendMessageRequest) (*
 			threadID = uuid.New().String()
 		}
 		// Verify agent exists and is online
-		if _, ok := g.agentManager.GetAgent(req.AgentID); !ok {
+		conn, ok := g.agentManager.GetAgent(req.AgentID)
+		if !ok {
 			return nil, "agent unavailable"
 		}
 		return &resolvedTarget{
@@ -287,9 +434,19 @@ func (g *Gateway) resolveTarget(ctx context.Context, req *SendMessageRequest) (*
 			ThreadID:     threadID,
 			FrontendName: "direct",
 			ExternalID:   threadID,
+			Agent:        conn,
+			Selection:    selectionExplicit,
 		}, ""
 	}
 
+	if len(req.Tags) > 0 && req.Frontend == "" && req.ChannelID == "" {
+		return g.resolveTagTarget(ctx, req)
+	}
+
+	if req.Capability != "" && req.Frontend == "" && req.ChannelID == "" {
+		return g.resolveCapabilityTarget(ctx, req)
+	}
+
 	// Must have frontend + channel_id for binding lookup
 	if req.Frontend == "" || req.ChannelID == "" {
 		return nil, "must specify agent_id or frontend+channel_id"
@@ -302,21 +459,120 @@ func (g *Gateway) resolveTarget(ctx context.Context, req *SendMessageRequest) (*
 		return nil, "channel not bound to agent"
 	}
 	if err != nil {
-		g.logger.Error("failed to resolve binding", "error", err)
+		logctx.FromContext(ctx, g.logger).Error("failed to resolve binding", "error", err)
 		return nil, "internal server error"
 	}
 
-	// Find the online agent matching the binding's principal_id + working_dir
-	agentConn := g.agentManager.GetByPrincipalAndWorkDir(result.AgentID, result.WorkingDir)
+	// Find the online agent matching the binding's principal_id + working_dir,
+	// or a fallback if the bound agent is gone for good
+	agentConn, fallback := g.bindingRoute(ctx, result.AgentID, result.WorkingDir, result.FallbackAgentIDs)
 	if agentConn == nil {
 		return nil, "agent unavailable"
 	}
 
-	return &resolvedTarget{
+	target := &resolvedTarget{
 		AgentID:      agentConn.ID,
 		ThreadID:     result.ThreadID,
 		FrontendName: req.Frontend,
 		ExternalID:   req.ChannelID,
+		MaxDuration:  result.MaxResponseDuration,
+		Agent:        agentConn,
+		Selection:    selectionBinding,
+		Context:      result.Context,
+	}
+	if result.Workspace != "" && !fallback {
+		// The agent may have dropped the workspace since the channel was
+		// pinned to it; its default beats refusing every message.
+		if err := agentConn.CheckWorkspace(result.Workspace); err != nil {
+			logctx.FromContext(ctx, g.logger).Warn("ignoring binding workspace", "frontend", req.Frontend, "channel_id", req.ChannelID, "error", err)
+		} else {
+			target.Workspace = result.Workspace
+		}
+	}
+	if fallback {
+		target.FallbackFor = result.AgentID
+		logctx.FromContext(ctx, g.logger).Info("routing to fallback agent",
+			"frontend", req.Frontend,
+			"channel_id", req.ChannelID,
+			"bound_agent", result.AgentID,
+			"agent_id", agentConn.ID,
+		)
+	}
+	return target, ""
+}
+
+// resolveCapabilityTarget picks the least-loaded agent advertising
+// req.Capability, waiting up to capabilityQueueTimeout if all are busy.
+func (g *Gateway) resolveCapabilityTarget(ctx context.Context, req *SendMessageRequest) (*resolvedTarget, string) {
+	ctx, cancel := context.WithTimeout(ctx, capabilityQueueTimeout)
+	defer cancel()
+	conn, route, err := g.agentManager.SelectByCapability(ctx, req.Capability)
+	if err != nil {
+		logctx.FromContext(ctx, g.logger).Info("capability send not routed",
+			"capability", req.Capability,
+			"candidates", route.Candidates,
+			"eligible", route.Eligible,
+			"error", err,
+		)
+		return nil, err.Error()
+	}
+	logctx.FromContext(ctx, g.logger).Debug("capability send routed",
+		"capability", req.Capability,
+		"agent_id", conn.ID,
+		"candidates", route.Candidates,
+		"eligible", route.Eligible,
+		"in_flight", route.InFlight,
+		"queued", route.Queued,
+	)
+
+	threadID := req.ThreadID
+	if threadID == "" {
+		threadID = uuid.New().String()
+	}
+	return &resolvedTarget{
+		AgentID:      conn.ID,
+		ThreadID:     threadID,
+		FrontendName: "direct",
+		ExternalID:   threadID,
+		Agent:        conn,
+		Selection:    selectionCapability,
+		Route:        &route,
+	}, ""
+}
+
+// resolveTagTarget picks the agent carrying every tag in req.Tags. With
+// req.Any it takes the least-loaded match, waiting up to
+// capabilityQueueTimeout if all are busy.
+func (g *Gateway) resolveTagTarget(ctx context.Context, req *SendMessageRequest) (*resolvedTarget, string) {
+	ctx, cancel := context.WithTimeout(ctx, capabilityQueueTimeout)
+	defer cancel()
+	conn, route, err := g.agentManager.SelectByTags(ctx, req.Tags, req.Any)
+	if err != nil {
+		logctx.FromContext(ctx, g.logger).Info("tag send not routed",
+			"tags", req.Tags,
+			"any", req.Any,
+			"candidates", route.Candidates,
+			"error", err,
+		)
+		return nil, err.Error()
+	}
+	logctx.FromContext(ctx, g.logger).Debug("tag send routed",
+		"tags", req.Tags,
+		"agent_id", conn.ID,
+		"candidates", route.Candidates,
+	)
+
+	threadID := req.ThreadID
+	if threadID == "" {
+		threadID = uuid.New().String()
+	}
+	return &resolvedTarget{
+		AgentID:      conn.ID,
+		ThreadID:     threadID,
+		FrontendName: "direct",
+		ExternalID:   threadID,
+		Agent:        conn,
+		Selection:    selectionTags,
 	}, ""
 }
 
@@ -331,108 +587,273 @@ func (g *Gateway) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
+	if g.agentManager.Draining() {
+		g.sendDrainingError(w)
+		return
+	}
 
-	req, err := parseSendRequest(r.Body)
+	req, err := g.parseSendBody(w, r)
+	if err != nil {
+		g.sendJSONError(w, sendBodyErrorStatus(err), err.Error())
+		return
+	}
+	key, err := sendIdempotencyKey(r, req)
 	if err != nil {
 		g.sendJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if g.idempotency == nil {
+		key = ""
+	}
+	if key != "" {
+		prior, err := g.idempotency.Begin(r.Context(), principalIDOf(r.Context()), key)
+		if err != nil {
+			logctx.FromContext(r.Context(), g.logger).Error("failed to check idempotency key", "error", err)
+			g.sendJSONError(w, http.StatusInternalServerError, "failed to check idempotency key")
+			return
+		}
+		if prior != nil {
+			g.serveDuplicateSend(w, r, prior)
+			return
+		}
+	}
+
+	requestID, flusher := g.acceptSend(w, r, req)
+	if key != "" {
+		g.finishIdempotencyKey(r.Context(), key, requestID)
+	}
+	if requestID == "" {
+		return
+	}
 
+	setSSEHeaders(w)
+	g.serveRequestStream(r.Context(), w, flusher, requestID, 0)
+}
+
+// acceptSend resolves req's target and starts the send, returning its request
+// ID and the response's flusher. On failure it writes the error response and
+// returns an empty request ID.
+func (g *Gateway) acceptSend(w http.ResponseWriter, r *http.Request, req *SendMessageRequest) (string, http.Flusher) {
 	// Resolve agent ID and thread ID using helper
 	target, errMsg := g.resolveTarget(r.Context(), req)
 	if target == nil {
-		// Determine appropriate status code based on error message
-		var status int
-		switch errMsg {
-		case "agent unavailable":
-			status = http.StatusServiceUnavailable
-		case "internal server error":
-			status = http.StatusInternalServerError
-		default:
-			status = http.StatusBadRequest
-		}
-		g.sendJSONError(w, status, errMsg)
-		return
+		status, code := targetError(errMsg)
+		g.sendCodedError(w, status, code, errMsg)
+		return "", nil
+	}
+	if !tokenAllowsAgent(r.Context(), target.AgentID, target.Agent.PrincipalID) {
+		g.sendJSONError(w, http.StatusForbidden, errTokenAgentDenied)
+		return "", nil
+	}
+	if err := resolveWorkspace(req, target); err != nil {
+		g.handleSendError(w, r, err)
+		return "", nil
 	}
-
-	agentID := target.AgentID
-	threadID := target.ThreadID
-	frontendName := target.FrontendName
-	externalID := target.ExternalID
 
 	// Check streaming support before sending (fail fast)
 	flusher, ok := w.(http.Flusher)
 	if !ok {
-		g.logger.Error("streaming not supported")
+		logctx.FromContext(r.Context(), g.logger).Error("streaming not supported")
 		g.sendJSONError(w, http.StatusInternalServerError, "streaming not supported")
-		return
+		return "", nil
 	}
 
-	// Send message via ConversationService
-	// This handles: thread creation, user message persistence, and response persistence
-	convReq := &conversation.SendRequest{
-		ThreadID:     threadID,
-		FrontendName: frontendName,
-		ExternalID:   externalID,
-		AgentID:      agentID,
-		Sender:       req.Sender,
-		Content:      req.Content,
+	// The response outlives this connection so a client that drops can
+	// resume it from GET /api/requests/{request_id}/stream.
+	requestID, err := g.beginSend(context.WithoutCancel(r.Context()), req, target)
+	if err != nil {
+		g.handleSendError(w, r, err)
+		return "", nil
 	}
+	return requestID, flusher
+}
 
-	convResp, err := g.conversation.SendMessage(r.Context(), convReq)
-	if err != nil {
-		if errors.Is(err, agent.ErrAgentNotFound) {
-			g.sendJSONError(w, http.StatusNotFound, "agent not found")
-			return
-		}
-		g.logger.Error("failed to send message", "error", err)
-		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
-		return
+// resolveWorkspace sets target's workspace to the one req names, if any, and
+// checks that the target agent registered it. Failing before the send keeps
+// a refused message out of the thread.
+func resolveWorkspace(req *SendMessageRequest, target *resolvedTarget) error {
+	if req.Workspace != "" {
+		target.Workspace = req.Workspace
 	}
+	return target.Agent.CheckWorkspace(target.Workspace)
+}
 
-	// Set SSE headers
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("X-Accel-Buffering", "no")
+// errTokenAgentDenied is the error for requests whose API token is limited to
+// other agents.
+const errTokenAgentDenied = "token is not permitted for this agent"
 
-	// Send initial "started" event with thread_id so client can track the conversation
-	g.writeSSEEvent(w, "started", map[string]string{"thread_id": convResp.ThreadID})
-	flusher.Flush()
+// tokenAllowsAgent reports whether the request's token may reach the agent
+// known by ids. Unauthenticated requests and tokens without an agent list
+// may reach every agent.
+func tokenAllowsAgent(ctx context.Context, ids ...string) bool {
+	a := auth.FromContext(ctx)
+	return a == nil || a.AllowsAgent(ids...)
+}
 
-	// Stream responses (persistence is handled by ConversationService)
-	g.streamResponses(r.Context(), w, flusher, convResp.Stream)
+// tokenAllowsAgentID is tokenAllowsAgent for an agent named only by its
+// connection ID; the principal ID is looked up while the agent is online.
+func (g *Gateway) tokenAllowsAgentID(ctx context.Context, agentID string) bool {
+	principalID := ""
+	if conn, ok := g.agentManager.GetAgent(agentID); ok {
+		principalID = conn.PrincipalID
+	}
+	return tokenAllowsAgent(ctx, agentID, principalID)
 }
 
-// streamResponses reads from the response channel and writes SSE events.
-// Message persistence is handled by ConversationService which wraps the channel.
-func (g *Gateway) streamResponses(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, respChan <-chan *agent.Response) {
-	for {
-		select {
-		case <-ctx.Done():
-			g.writeSSEEvent(w, "error", map[string]string{"error": "request canceled"})
-			flusher.Flush()
-			return
+// targetError maps a resolveTarget error message to its HTTP status and code.
+func targetError(errMsg string) (int, coverr.Code) {
+	switch {
+	case errMsg == "agent unavailable":
+		return http.StatusServiceUnavailable, coverr.AgentOffline
+	case errMsg == agent.ErrNoCapableAgent.Error(), errMsg == agent.ErrNoTaggedAgent.Error():
+		return http.StatusServiceUnavailable, coverr.NoCapableAgent
+	case errMsg == agent.ErrAgentsAtCapacity.Error():
+		return http.StatusServiceUnavailable, coverr.AgentBusy
+	case strings.HasPrefix(errMsg, agent.ErrAmbiguousTags.Error()):
+		// The message goes on to list the matching agents.
+		return http.StatusConflict, coverr.Conflict
+	case errMsg == "internal server error":
+		return http.StatusInternalServerError, coverr.Internal
+	default:
+		return http.StatusBadRequest, coverr.InvalidRequest
+	}
+}
 
-		case resp, ok := <-respChan:
-			if !ok {
-				return
-			}
+// beginSend hands req to target's agent via ConversationService, which
+// creates the thread and persists the user message and the response. It
+// records the started event and relays the responses into the replay buffer
+// under the returned request ID, for serveRequestStream to follow.
+func (g *Gateway) beginSend(ctx context.Context, req *SendMessageRequest, target *resolvedTarget) (string, error) {
+	convReq := &conversation.SendRequest{
+		ThreadID:       target.ThreadID,
+		FrontendName:   target.FrontendName,
+		ExternalID:     target.ExternalID,
+		AgentID:        target.AgentID,
+		Sender:         req.Sender,
+		Content:        req.Content,
+		Attachments:    req.Attachments,
+		MaxDuration:    target.MaxDuration,
+		Workspace:      target.Workspace,
+		ChannelContext: target.Context,
+	}
+	if a := auth.FromContext(ctx); a != nil {
+		convReq.ActorPrincipalID = a.PrincipalID
+	}
+	if req.MaxResponseSeconds > 0 {
+		convReq.MaxDuration = time.Duration(req.MaxResponseSeconds) * time.Second
+	}
+	var queuePosition int
+	convReq.OnQueued = func(position int) { queuePosition = position }
+
+	convResp, err := g.conversation.SendMessage(ctx, convReq)
+	if err != nil {
+		return "", err
+	}
+
+	started := startedSSE(convResp, target.Agent, target.Selection)
+	if target.Route != nil {
+		started["routing"] = routingSSE(req.Capability, target.Route)
+	}
+	if target.Selection == selectionTags {
+		started["tags"] = req.Tags
+	}
+	if target.Workspace != "" {
+		started["workspace"] = target.Workspace
+	}
+	if queuePosition > 0 {
+		started["queued"] = true
+		started["queue_position"] = queuePosition
+	}
+	if len(convResp.Attachments) > 0 {
+		started["attachments"] = attachmentsSSE(convResp.Attachments)
+	}
+	stream := convResp.Stream
+	if req.AckMode == ackModeExplicit {
+		stream = g.trackDelivery(ctx, target, convResp)
+		started["ack_mode"] = ackModeExplicit
+	}
 
-			event := g.responseToSSEEvent(resp)
-			g.writeSSEEvent(w, event.Event, event.Data)
-			flusher.Flush()
+	// The started event carries thread_id and request_id so the client can
+	// track the conversation and resume the stream.
+	var preamble []SSEEvent
+	if target.FallbackFor != "" {
+		preamble = append(preamble, fallbackSSE(target.Agent, target.FallbackFor))
+	}
+	g.relayResponses(ctx, convResp.MessageID, target.AgentID, started, stream, preamble...)
+	return convResp.MessageID, nil
+}
 
-			if resp.Event == agent.EventDone {
-				return
-			}
+// startedSSE builds the started event payload: the thread used, the request
+// ID to resume the stream with, and which agent handles the request and how
+// it was chosen. instance_id is omitted for agents without one.
+func startedSSE(convResp *conversation.SendResponse, conn *agent.Connection, selection string) map[string]any {
+	started := map[string]any{
+		"thread_id":      convResp.ThreadID,
+		"thread_created": convResp.ThreadCreated,
+		"request_id":     convResp.MessageID,
+		"selection":      selection,
+	}
+	if conn != nil {
+		started["agent_id"] = conn.ID
+		started["agent_name"] = conn.Name
+		if conn.InstanceID != "" {
+			started["instance_id"] = conn.InstanceID
 		}
 	}
+	return started
+}
+
+// routingSSE describes a capability selection for the started event: how
+// many agents could have taken the send and how loaded the chosen one was.
+func routingSSE(capability string, route *agent.Route) map[string]any {
+	return map[string]any{
+		"capability": capability,
+		"candidates": route.Candidates,
+		"eligible":   route.Eligible,
+		"in_flight":  route.InFlight,
+		"latency_ms": route.Latency.Milliseconds(),
+		"queued_ms":  route.Queued.Milliseconds(),
+	}
+}
+
+// trackDelivery records a pending delivery for an explicit-ack send, keyed by
+// the user message ID. Tracking failures are logged and the stream is served
+// as if the send were implicit.
+func (g *Gateway) trackDelivery(ctx context.Context, target *resolvedTarget, convResp *conversation.SendResponse) <-chan *agent.Response {
+	if g.deliveries == nil {
+		return convResp.Stream
+	}
+	err := g.deliveries.store.CreateDelivery(ctx, &store.Delivery{
+		RequestID: convResp.MessageID,
+		ThreadID:  convResp.ThreadID,
+		AgentID:   target.AgentID,
+		Frontend:  target.FrontendName,
+		ChannelID: target.ExternalID,
+	})
+	if err != nil {
+		logctx.FromContext(ctx, g.logger).Error("failed to record pending delivery", "message_id", convResp.MessageID, "error", err)
+		return convResp.Stream
+	}
+	return g.deliveries.track(ctx, convResp.MessageID, convResp.Stream)
+}
+
+// doneSSEData builds the done event payload. sources lists every citation
+// seen during the response, ordered by index, and is omitted when there were none.
+// trace_id names the send's trace and is omitted when it was not traced.
+func doneSSEData(fullResponse, agentID, traceID string, sources []agent.CitationEvent) map[string]any {
+	data := map[string]any{"full_response": fullResponse, "agent_id": agentID}
+	if len(sources) > 0 {
+		data["sources"] = sources
+	}
+	if traceID != "" {
+		data["trace_id"] = traceID
+	}
+	return data
 }
 
 // malformedEvent returns an error SSE event for malformed data.
 func malformedEvent(eventType string) SSEEvent {
-	return SSEEvent{Event: "error", Data: map[string]string{"error": "malformed " + eventType + " event"}}
+	return SSEEvent{Event: "error", Data: coverr.NewBody(coverr.Internal, "malformed "+eventType+" event")}
 }
 
 // toolUseToSSE converts a ToolUse event to SSE format.
@@ -448,7 +869,15 @@ func toolResultToSSE(tr *agent.ToolResultEvent) SSEEvent {
 	if tr == nil {
 		return malformedEvent("tool_result")
 	}
-	return SSEEvent{Event: "tool_result", Data: map[string]any{"id": tr.ID, "output": tr.Output, "is_error": tr.IsError}}
+	data := map[string]any{"id": tr.ID, "output": tr.Output, "is_error": tr.IsError}
+	if tr.Partial {
+		data["partial"] = true
+		data["sequence"] = tr.Sequence
+	}
+	if tr.Cached {
+		data["cached"] = true
+	}
+	return SSEEvent{Event: "tool_result", Data: data}
 }
 
 // fileToSSE converts a File event to SSE format.
@@ -456,7 +885,11 @@ func fileToSSE(f *agent.FileEvent) SSEEvent {
 	if f == nil {
 		return malformedEvent("file")
 	}
-	return SSEEvent{Event: "file", Data: map[string]string{"filename": f.Filename, "mime_type": f.MimeType}}
+	data := map[string]string{"filename": f.Filename, "mime_type": f.MimeType}
+	if f.AttachmentID != "" {
+		data["attachment_id"] = f.AttachmentID
+	}
+	return SSEEvent{Event: "file", Data: data}
 }
 
 // usageToSSE converts a Usage event to SSE format.
@@ -487,6 +920,73 @@ func toolApprovalToSSE(ta *agent.ToolApprovalRequestEvent) SSEEvent {
 	return SSEEvent{Event: "tool_approval", Data: map[string]string{"id": ta.ID, "name": ta.Name, "input_json": ta.InputJSON, "request_id": ta.RequestID}}
 }
 
+// truncatedToSSE converts a Truncated event to SSE format. Durations are in
+// seconds; paused_seconds is time spent waiting for tool approval.
+func truncatedToSSE(t *agent.TruncatedEvent) SSEEvent {
+	if t == nil {
+		return malformedEvent("truncated")
+	}
+	return SSEEvent{Event: "truncated", Data: map[string]any{
+		"reason":          t.Reason,
+		"elapsed_seconds": t.Elapsed.Seconds(),
+		"paused_seconds":  t.Paused.Seconds(),
+		"limit_seconds":   t.Limit.Seconds(),
+	}}
+}
+
+// errorToSSE converts an Error event to SSE format. An error without a code
+// was reported by the agent itself, so it is agent_error.
+func errorToSSE(r *agent.Response) SSEEvent {
+	code := r.Code
+	if code == "" {
+		code = coverr.AgentError
+	}
+	return SSEEvent{Event: "error", Data: coverr.NewBody(code, r.Error)}
+}
+
+// planStepSSE is one step of a plan SSE event.
+type planStepSSE struct {
+	Title  string `json:"title"`
+	Status string `json:"status"`
+}
+
+// planToSSE converts a Plan event to SSE format. text is a numbered-list
+// rendering for clients that do not draw plans.
+func planToSSE(p *agent.PlanEvent) SSEEvent {
+	if p == nil {
+		return malformedEvent("plan")
+	}
+	steps := make([]planStepSSE, len(p.Steps))
+	for i, s := range p.Steps {
+		steps[i] = planStepSSE{Title: s.Title, Status: s.Status}
+	}
+	return SSEEvent{Event: "plan", Data: map[string]any{"steps": steps, "text": p.Text()}}
+}
+
+// planStepUpdateToSSE converts a PlanStepUpdate event to SSE format. index is
+// zero-based; text numbers steps from one.
+func planStepUpdateToSSE(u *agent.PlanStepEvent) SSEEvent {
+	if u == nil {
+		return malformedEvent("plan_step_update")
+	}
+	return SSEEvent{Event: "plan_step_update", Data: map[string]any{
+		"index": u.Index, "status": u.Status, "detail": u.Detail, "text": u.Text(),
+	}}
+}
+
+// citationToSSE converts a Citation event to SSE format. text is a reference
+// line for clients that do not render footnotes.
+func citationToSSE(c *agent.CitationEvent) SSEEvent {
+	if c == nil {
+		return malformedEvent("citation")
+	}
+	data := map[string]any{"index": c.Index, "title": c.Title, "uri": c.URI, "text": c.Text()}
+	if c.Snippet != "" {
+		data["snippet"] = c.Snippet
+	}
+	return SSEEvent{Event: "citation", Data: data}
+}
+
 // responseToSSEEvent converts an agent response to an SSE event.
 // SSE event builders for simple text-based events.
 func textSSE(event, key, value string) SSEEvent {
@@ -504,13 +1004,17 @@ var sseConverters = map[agent.ResponseEvent]responseConverter{
 	agent.EventToolResult:          func(r *agent.Response) SSEEvent { return toolResultToSSE(r.ToolResult) },
 	agent.EventFile:                func(r *agent.Response) SSEEvent { return fileToSSE(r.File) },
 	agent.EventDone:                func(r *agent.Response) SSEEvent { return textSSE("done", "full_response", r.Text) },
-	agent.EventError:               func(r *agent.Response) SSEEvent { return textSSE("error", "error", r.Error) },
+	agent.EventError:               errorToSSE,
 	agent.EventSessionInit:         func(r *agent.Response) SSEEvent { return textSSE("session_init", "session_id", r.SessionID) },
 	agent.EventSessionOrphaned:     func(r *agent.Response) SSEEvent { return textSSE("session_orphaned", "reason", r.Error) },
 	agent.EventUsage:               func(r *agent.Response) SSEEvent { return usageToSSE(r.Usage) },
 	agent.EventToolState:           func(r *agent.Response) SSEEvent { return toolStateToSSE(r.ToolState) },
 	agent.EventCanceled:            func(r *agent.Response) SSEEvent { return textSSE("canceled", "reason", r.Error) },
 	agent.EventToolApprovalRequest: func(r *agent.Response) SSEEvent { return toolApprovalToSSE(r.ToolApprovalRequest) },
+	agent.EventTruncated:           func(r *agent.Response) SSEEvent { return truncatedToSSE(r.Truncated) },
+	agent.EventPlan:                func(r *agent.Response) SSEEvent { return planToSSE(r.Plan) },
+	agent.EventPlanStepUpdate:      func(r *agent.Response) SSEEvent { return planStepUpdateToSSE(r.PlanStep) },
+	agent.EventCitation:            func(r *agent.Response) SSEEvent { return citationToSSE(r.Citation) },
 }
 
 func (g *Gateway) responseToSSEEvent(resp *agent.Response) SSEEvent {
@@ -538,11 +1042,125 @@ func (g *Gateway) writeSSEEvent(w http.ResponseWriter, event string, data any) {
 	_, _ = fmt.Fprintf(w, "data: %s\n\n", dataJSON)
 }
 
-// sendJSONError writes a JSON error response.
+// sendJSONError writes a JSON error response whose code follows from status.
 func (g *Gateway) sendJSONError(w http.ResponseWriter, status int, message string) {
+	g.sendCodedError(w, status, coverr.ForHTTPStatus(status), message)
+}
+
+// sendCodedError writes a JSON error response with an explicit code, for
+// errors more specific than their HTTP status.
+func (g *Gateway) sendCodedError(w http.ResponseWriter, status int, code coverr.Code, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(map[string]string{"error": message}); err != nil {
+	if err := json.NewEncoder(w).Encode(httpapi.ErrorResponse{Error: message, Code: string(code)}); err != nil {
+		g.logger.Debug("failed to encode error response", "error", err)
+	}
+}
+
+// sendTimestampError writes a 400 for an unparseable timestamp input. The body
+// names the offending parameter; see timeparse.Error.
+func (g *Gateway) sendTimestampError(w http.ResponseWriter, err error) {
+	var terr *timeparse.Error
+	if !errors.As(err, &terr) {
+		g.sendJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	if err := json.NewEncoder(w).Encode(terr); err != nil {
+		g.logger.Debug("failed to encode error response", "error", err)
+	}
+}
+
+// AgentPausedResponse is the 409 body returned when sending to a paused agent.
+type AgentPausedResponse struct {
+	Error    string      `json:"error"`
+	Code     coverr.Code `json:"code"`
+	AgentID  string      `json:"agent_id"`
+	PausedBy string      `json:"paused_by"`
+	PausedAt string      `json:"paused_at"`
+}
+
+// sendAgentPausedError writes a 409 naming who paused the agent and when, so
+// callers can tell a deliberate pause apart from a disconnected agent.
+func (g *Gateway) sendAgentPausedError(w http.ResponseWriter, e *agent.PausedError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	if err := json.NewEncoder(w).Encode(AgentPausedResponse{
+		Error:    "agent paused",
+		Code:     coverr.AgentPaused,
+		AgentID:  e.AgentID,
+		PausedBy: e.PausedBy,
+		PausedAt: timeparse.Format(e.PausedAt),
+	}); err != nil {
+		g.logger.Debug("failed to encode error response", "error", err)
+	}
+}
+
+// sendDrainingError writes a 503 for a send refused because the gateway is
+// shutting down. Retry-After covers the drain, after which a restarted
+// gateway can take the send.
+func (g *Gateway) sendDrainingError(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(g.drainTimeout().Seconds()))))
+	w.WriteHeader(http.StatusServiceUnavailable)
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"error": agent.ErrDraining.Error(),
+		"code":  string(coverr.GatewayDraining),
+	}); err != nil {
+		g.logger.Debug("failed to encode error response", "error", err)
+	}
+}
+
+// AgentBusyResponse is the 429 body returned when an agent is at its
+// concurrency limit and its queue is full.
+type AgentBusyResponse struct {
+	Error         string      `json:"error"`
+	Code          coverr.Code `json:"code"`
+	AgentID       string      `json:"agent_id"`
+	MaxConcurrent int         `json:"max_concurrent"`
+}
+
+// sendAgentBusyError writes a 429 for a send the agent has no room for.
+func (g *Gateway) sendAgentBusyError(w http.ResponseWriter, e *agent.BusyError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	if err := json.NewEncoder(w).Encode(AgentBusyResponse{
+		Error:         agent.ErrAgentBusy.Error(),
+		Code:          coverr.AgentBusy,
+		AgentID:       e.AgentID,
+		MaxConcurrent: e.MaxConcurrent,
+	}); err != nil {
+		g.logger.Debug("failed to encode error response", "error", err)
+	}
+}
+
+// UnknownWorkspaceResponse is the 400 body returned when a send or binding
+// names a workspace its agent did not register.
+type UnknownWorkspaceResponse struct {
+	Error           string      `json:"error"`
+	Code            coverr.Code `json:"code"`
+	AgentID         string      `json:"agent_id"`
+	Workspace       string      `json:"workspace"`
+	ValidWorkspaces []string    `json:"valid_workspaces"`
+}
+
+// sendUnknownWorkspaceError writes a 400 listing the agent's workspaces, so
+// clients can offer them instead.
+func (g *Gateway) sendUnknownWorkspaceError(w http.ResponseWriter, e *agent.WorkspaceError) {
+	valid := e.Valid
+	if valid == nil {
+		valid = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	if err := json.NewEncoder(w).Encode(UnknownWorkspaceResponse{
+		Error:           e.Error(),
+		Code:            coverr.InvalidRequest,
+		AgentID:         e.AgentID,
+		Workspace:       e.Workspace,
+		ValidWorkspaces: valid,
+	}); err != nil {
 		g.logger.Debug("failed to encode error response", "error", err)
 	}
 }
@@ -554,7 +1172,11 @@ func parseSendRequest(r io.Reader) (*SendMessageRequest, error) {
 	if err := json.NewDecoder(r).Decode(&req); err != nil {
 		return nil, errors.New("invalid JSON body")
 	}
+	return validateSendRequest(&req)
+}
 
+// validateSendRequest checks the fields of a send request however it was encoded.
+func validateSendRequest(req *SendMessageRequest) (*SendMessageRequest, error) {
 	if req.Content == "" {
 		return nil, errors.New("content is required")
 	}
@@ -563,7 +1185,22 @@ func parseSendRequest(r io.Reader) (*SendMessageRequest, error) {
 		return nil, errors.New("sender is required")
 	}
 
-	return &req, nil
+	if !validAckMode(req.AckMode) {
+		return nil, errors.New("ack_mode must be implicit or explicit")
+	}
+
+	if req.MaxResponseSeconds < 0 {
+		return nil, errors.New("max_response_seconds must not be negative")
+	}
+
+	if len(req.Tags) > 0 && req.Capability != "" {
+		return nil, errors.New("tags and capability cannot be combined")
+	}
+	if req.Any && len(req.Tags) == 0 {
+		return nil, errors.New("any requires tags")
+	}
+
+	return req, nil
 }
 
 // messageSender is an interface for sending messages to agents.
@@ -586,9 +1223,13 @@ var ErrChannelNotBound = errors.New("channel not bound to agent")
 
 // BindingResult contains the resolved thread and agent information.
 type BindingResult struct {
-	ThreadID   string
-	AgentID    string // principal_id from the binding
-	WorkingDir string // working_dir from the binding (needed to find exact agent)
+	ThreadID            string
+	AgentID             string        // principal_id from the binding
+	WorkingDir          string        // working_dir from the binding (needed to find exact agent)
+	MaxResponseDuration time.Duration // the binding's response limit override, if any
+	FallbackAgentIDs    []string      // agents to use once AgentID is gone, in order
+	Workspace           string        // the binding's default agent workspace, if any
+	Context             string        // the binding's channel context, if any
 }
 
 // bindingResolver handles looking up and creating bindings and threads.
@@ -611,8 +1252,12 @@ func (r *bindingResolver) Resolve(ctx context.Context, frontend, channelID, thre
 	}
 
 	result := &BindingResult{
-		AgentID:    binding.AgentID,
-		WorkingDir: binding.WorkingDir,
+		AgentID:             binding.AgentID,
+		WorkingDir:          binding.WorkingDir,
+		MaxResponseDuration: binding.MaxResponseDuration,
+		FallbackAgentIDs:    binding.FallbackAgentIDs,
+		Workspace:           binding.Workspace,
+		Context:             binding.Context,
 	}
 
 	// If thread ID was provided, use it
@@ -624,7 +1269,11 @@ func (r *bindingResolver) Resolve(ctx context.Context, frontend, channelID, thre
 	// Try to find existing thread by frontend/channel
 	thread, err := r.store.GetThreadByFrontendID(ctx, frontend, channelID)
 	if err == nil {
+		// Merged threads keep their frontend mapping as a tombstone
 		result.ThreadID = thread.ID
+		if thread.MergedInto != "" {
+			result.ThreadID = thread.MergedInto
+		}
 		return result, nil
 	}
 
@@ -644,6 +1293,8 @@ func (g *Gateway) handleBindings(w http.ResponseWriter, r *http.Request) {
 		g.handleListBindings(w, r)
 	case http.MethodPost:
 		g.handleCreateBinding(w, r)
+	case http.MethodPatch:
+		g.handleUpdateBinding(w, r)
 	case http.MethodDelete:
 		g.handleDeleteBinding(w, r)
 	default:
@@ -653,7 +1304,7 @@ func (g *Gateway) handleBindings(w http.ResponseWriter, r *http.Request) {
 
 // handleListBindings handles GET /api/bindings.
 // When frontend+channel_id query params are provided, returns a single binding status.
-// Otherwise, lists all bindings.
+// Otherwise, lists all bindings, or only frontend's when that param is given.
 func (g *Gateway) handleListBindings(w http.ResponseWriter, r *http.Request) {
 	frontend := r.URL.Query().Get("frontend")
 	channelID := r.URL.Query().Get("channel_id")
@@ -665,41 +1316,91 @@ func (g *Gateway) handleListBindings(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// List all bindings
-	bindings, err := g.store.ListBindingsV2(r.Context(), store.BindingFilter{})
+	bindings, err := g.listBindingResponses(r.Context(), frontend)
 	if err != nil {
 		g.logger.Error("failed to list bindings", "error", err)
 		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
 		return
 	}
 
-	response := ListBindingsResponse{
-		Bindings: make([]BindingResponse, len(bindings)),
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ListBindingsResponse{Bindings: bindings}); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
 	}
+}
 
-	for i, b := range bindings {
-		agentOnline := false
-		agentName := ""
-		// Use GetByPrincipalAndWorkDir since b.AgentID is principal_id, not connection ID
-		if agent := g.agentManager.GetByPrincipalAndWorkDir(b.AgentID, b.WorkingDir); agent != nil {
-			agentOnline = true
-			agentName = agent.Name
+// listBindingResponses returns the bindings for frontend (all bindings when
+// empty) with their agent's name and status.
+func (g *Gateway) listBindingResponses(ctx context.Context, frontend string) ([]BindingResponse, error) {
+	var filter store.BindingFilter
+	if frontend != "" {
+		filter.Frontend = &frontend
+	}
+	bindings, err := g.store.ListBindingsV2(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]BindingResponse, len(bindings))
+	for i := range bindings {
+		b := &bindings[i]
+		bound := g.bindingAgent(ctx, b)
+		response[i] = BindingResponse{
+			Frontend:         b.Frontend,
+			ChannelID:        b.ChannelID,
+			AgentID:          b.AgentID,
+			AgentName:        bound.Name,
+			AgentOnline:      bound.Status == admin.AgentStatusOnline,
+			AgentStatus:      bound.Status,
+			WorkingDir:       b.WorkingDir,
+			Workspace:        b.Workspace,
+			Context:          b.Context,
+			CreatedAt:        timeparse.Format(b.CreatedAt),
+			FallbackAgentIDs: b.FallbackAgentIDs,
+			EffectiveAgentID: bound.EffectiveID,
 		}
+	}
+	return response, nil
+}
+
+// bindingAgent returns the display name of a binding's agent, whether it is
+// online, within its reconnect grace period, or offline, and which agent its
+// messages go to now. Bindings outlive their principal, so a deleted agent
+// gets a placeholder name.
+func (g *Gateway) bindingAgent(ctx context.Context, b *store.Binding) admin.BoundAgent {
+	// b.AgentID is a principal ID, not a connection ID
+	conn := g.agentManager.GetByPrincipalAndWorkDir(b.AgentID, b.WorkingDir)
 
-		response.Bindings[i] = BindingResponse{
-			Frontend:    b.Frontend,
-			ChannelID:   b.ChannelID,
-			AgentID:     b.AgentID,
-			AgentName:   agentName,
-			AgentOnline: agentOnline,
-			WorkingDir:  b.WorkingDir,
-			CreatedAt:   b.CreatedAt.Format(time.RFC3339),
+	name := b.AgentID
+	if conn != nil {
+		name = conn.Name
+	}
+	if sqlStore, ok := g.store.(*store.SQLStore); ok {
+		principal, err := sqlStore.GetPrincipal(ctx, b.AgentID)
+		switch {
+		case err == nil:
+			name = principal.DisplayName
+		case errors.Is(err, store.ErrPrincipalNotFound):
+			name = admin.DeletedAgentName
+		default:
+			g.logger.Warn("failed to look up bound agent", "agent_id", b.AgentID, "error", err)
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		g.logger.Debug("failed to encode response", "error", err)
+	var status string
+	switch {
+	case conn != nil:
+		status = admin.AgentStatusOnline
+	case g.sessions != nil && g.sessions.inGrace(ctx, b.AgentID):
+		status = admin.AgentStatusGrace
+	default:
+		status = admin.AgentStatusOffline
+	}
+	bound := admin.BoundAgent{Name: name, Status: status}
+	if effective, _ := g.bindingRoute(ctx, b.AgentID, b.WorkingDir, b.FallbackAgentIDs); effective != nil {
+		bound.EffectiveID = effective.PrincipalID
 	}
+	return bound
 }
 
 // handleGetSingleBinding handles GET /api/bindings?frontend=X&channel_id=Y.
@@ -716,19 +1417,16 @@ func (g *Gateway) handleGetSingleBinding(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	// Check if agent is online - use GetByPrincipalAndWorkDir since binding.AgentID is principal_id
-	online := false
-	agentName := ""
-	if agent := g.agentManager.GetByPrincipalAndWorkDir(binding.AgentID, binding.WorkingDir); agent != nil {
-		online = true
-		agentName = agent.Name
-	}
-
+	bound := g.bindingAgent(r.Context(), binding)
 	response := SingleBindingResponse{
-		BindingID:  binding.ID,
-		AgentName:  agentName,
-		WorkingDir: binding.WorkingDir,
-		Online:     online,
+		BindingID:        binding.ID,
+		AgentName:        bound.Name,
+		WorkingDir:       binding.WorkingDir,
+		Workspace:        binding.Workspace,
+		Context:          binding.Context,
+		Online:           bound.Status == admin.AgentStatusOnline,
+		AgentStatus:      bound.Status,
+		EffectiveAgentID: bound.EffectiveID,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -742,6 +1440,9 @@ func validateCreateBindingRequest(req *CreateBindingRequest) string {
 	if req.Frontend == "" || req.ChannelID == "" || req.InstanceID == "" {
 		return "frontend, channel_id, and instance_id are required"
 	}
+	if req.Context != nil && len(*req.Context) > store.MaxBindingContextBytes {
+		return store.ErrBindingContextTooLong.Error()
+	}
 	return ""
 }
 
@@ -788,6 +1489,11 @@ func (g *Gateway) handleCreateBinding(w http.ResponseWriter, r *http.Request) {
 		g.sendJSONError(w, http.StatusNotFound, fmt.Sprintf("no agent online with instance_id '%s'", req.InstanceID))
 		return
 	}
+	var workspaceErr *agent.WorkspaceError
+	if errors.As(agentConn.CheckWorkspace(req.Workspace), &workspaceErr) {
+		g.sendUnknownWorkspaceError(w, workspaceErr)
+		return
+	}
 
 	ctx := r.Context()
 	existingBinding, err := g.store.GetBindingByChannel(ctx, req.Frontend, req.ChannelID)
@@ -797,8 +1503,31 @@ func (g *Gateway) handleCreateBinding(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The channel's context carries over unless the request replaces it.
+	if req.Context == nil && existingBinding != nil {
+		req.Context = &existingBinding.Context
+	}
+	bindingContext := ""
+	if req.Context != nil {
+		bindingContext = *req.Context
+	}
+
 	if bindingMatchesAgent(existingBinding, agentConn) {
-		g.sendBindingResponse(w, existingBinding.ID, agentConn.Name, existingBinding.WorkingDir, nil, http.StatusOK)
+		if existingBinding.Workspace != req.Workspace {
+			if err := g.store.SetBindingWorkspace(ctx, existingBinding.ID, req.Workspace); err != nil {
+				g.logger.Error("failed to update binding workspace", "error", err)
+				g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+				return
+			}
+		}
+		if existingBinding.Context != bindingContext {
+			if err := g.store.SetBindingContext(ctx, existingBinding.ID, bindingContext); err != nil {
+				g.logger.Error("failed to update binding context", "error", err)
+				g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+				return
+			}
+		}
+		g.sendBindingResponse(w, existingBinding.ID, agentConn.Name, existingBinding.WorkingDir, req.Workspace, bindingContext, nil, http.StatusOK)
 		return
 	}
 
@@ -814,7 +1543,7 @@ func (g *Gateway) handleCreateBinding(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	g.sendBindingResponse(w, bindingID, agentConn.Name, agentConn.WorkingDir, reboundFrom, http.StatusCreated)
+	g.sendBindingResponse(w, bindingID, agentConn.Name, agentConn.WorkingDir, req.Workspace, bindingContext, reboundFrom, http.StatusCreated)
 }
 
 // deleteExistingBinding deletes an existing binding and returns the old agent name.
@@ -845,16 +1574,22 @@ func (g *Gateway) createBinding(ctx context.Context, req *CreateBindingRequest,
 		WorkingDir: agentConn.WorkingDir,
 		CreatedAt:  time.Now(),
 		CreatedBy:  nil,
+		Workspace:  req.Workspace,
+	}
+	if req.Context != nil {
+		binding.Context = *req.Context
 	}
 	return bindingID, g.store.CreateBindingV2(ctx, binding)
 }
 
 // sendBindingResponse writes a CreateBindingResponse as JSON.
-func (g *Gateway) sendBindingResponse(w http.ResponseWriter, bindingID, agentName, workDir string, reboundFrom *string, status int) {
+func (g *Gateway) sendBindingResponse(w http.ResponseWriter, bindingID, agentName, workDir, workspace, bindingContext string, reboundFrom *string, status int) {
 	response := CreateBindingResponse{
 		BindingID:   bindingID,
 		AgentName:   agentName,
 		WorkingDir:  workDir,
+		Workspace:   workspace,
+		Context:     bindingContext,
 		ReboundFrom: reboundFrom,
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -864,6 +1599,53 @@ func (g *Gateway) sendBindingResponse(w http.ResponseWriter, bindingID, agentNam
 	}
 }
 
+// handleUpdateBinding handles PATCH /api/bindings?frontend=X&channel_id=Y,
+// which sets the channel's context and returns the binding's status.
+func (g *Gateway) handleUpdateBinding(w http.ResponseWriter, r *http.Request) {
+	frontend := r.URL.Query().Get("frontend")
+	channelID := r.URL.Query().Get("channel_id")
+
+	if frontend == "" || channelID == "" {
+		g.sendJSONError(w, http.StatusBadRequest, "frontend and channel_id query params required")
+		return
+	}
+
+	var req UpdateBindingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.sendJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Context == nil {
+		g.sendJSONError(w, http.StatusBadRequest, "context is required")
+		return
+	}
+	if len(*req.Context) > store.MaxBindingContextBytes {
+		g.sendJSONError(w, http.StatusBadRequest, store.ErrBindingContextTooLong.Error())
+		return
+	}
+
+	binding, err := g.store.GetBindingByChannel(r.Context(), frontend, channelID)
+	if err == nil {
+		err = g.store.SetBindingContext(r.Context(), binding.ID, *req.Context)
+	}
+	if errors.Is(err, store.ErrBindingNotFound) {
+		g.sendJSONError(w, http.StatusNotFound, "binding not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to update binding context", "error", err, "frontend", frontend, "channel_id", channelID)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	g.logger.Info("binding context updated",
+		"frontend", frontend,
+		"channel_id", channelID,
+		"context_hash", store.BindingContextHash(*req.Context),
+	)
+
+	g.handleGetSingleBinding(w, r, frontend, channelID)
+}
+
 // handleDeleteBinding handles DELETE /api/bindings?frontend=X&channel_id=Y.
 func (g *Gateway) handleDeleteBinding(w http.ResponseWriter, r *http.Request) {
 	frontend := r.URL.Query().Get("frontend")
@@ -888,7 +1670,7 @@ func (g *Gateway) handleDeleteBinding(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// handleThreadRoutes routes /api/threads/{id}/... requests to the appropriate handler.
+// handleThreadRoutes routes /api/threads/{id} and /api/threads/{id}/... requests to the appropriate handler.
 func (g *Gateway) handleThreadRoutes(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	if strings.HasSuffix(path, "/messages") {
@@ -899,6 +1681,18 @@ func (g *Gateway) handleThreadRoutes(w http.ResponseWriter, r *http.Request) {
 		g.handleThreadUsage(w, r)
 		return
 	}
+	if strings.HasSuffix(path, "/export") {
+		g.handleThreadExport(w, r)
+		return
+	}
+	if !strings.Contains(strings.TrimPrefix(path, "/api/threads/"), "/") {
+		if r.Method != http.MethodPatch {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		g.handleUpdateThread(w, r)
+		return
+	}
 	g.sendJSONError(w, http.StatusNotFound, "unknown endpoint")
 }
 
@@ -927,12 +1721,7 @@ func (g *Gateway) handleThreadMessages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := g.store.GetThread(r.Context(), threadID); errors.Is(err, store.ErrNotFound) {
-		g.sendJSONError(w, http.StatusNotFound, "thread not found")
-		return
-	} else if err != nil {
-		g.logger.Error("failed to get thread", "error", err)
-		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+	if !g.resolveThreadRoute(w, r, threadID, "/messages") {
 		return
 	}
 
@@ -969,7 +1758,7 @@ func (g *Gateway) eventToMessageResponse(threadID string, evt *store.LedgerEvent
 		Type:      storeMsg.Type,
 		ToolName:  storeMsg.ToolName,
 		ToolID:    storeMsg.ToolID,
-		CreatedAt: storeMsg.CreatedAt.Format(time.RFC3339),
+		CreatedAt: timeparse.Format(storeMsg.CreatedAt),
 	}
 }
 
@@ -1019,6 +1808,10 @@ func (g *Gateway) handleAgentHistoryImpl(w http.ResponseWriter, r *http.Request)
 		g.sendJSONError(w, http.StatusBadRequest, "invalid path or agent_id")
 		return
 	}
+	if !g.tokenAllowsAgentID(r.Context(), agentID) {
+		g.sendJSONError(w, http.StatusForbidden, errTokenAgentDenied)
+		return
+	}
 
 	limit, errMsg := parseLimitParam(r, 50, 500)
 	if errMsg != "" {
@@ -1096,25 +1889,30 @@ func (g *Gateway) handleSendToAgent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, ok := g.agentManager.GetAgent(agentID); !ok {
+	conn, ok := g.agentManager.GetAgent(agentID)
+	if !ok {
 		g.sendJSONError(w, http.StatusNotFound, "agent not found")
 		return
 	}
+	if !tokenAllowsAgent(r.Context(), agentID, conn.PrincipalID) {
+		g.sendJSONError(w, http.StatusForbidden, errTokenAgentDenied)
+		return
+	}
 
 	flusher, ok := w.(http.Flusher)
 	if !ok {
-		g.logger.Error("streaming not supported")
+		logctx.FromContext(r.Context(), g.logger).Error("streaming not supported")
 		g.sendJSONError(w, http.StatusInternalServerError, "streaming not supported")
 		return
 	}
 
-	convResp, err := g.sendAgentMessage(r.Context(), agentID, req.Message)
+	convResp, err := g.sendAgentMessage(context.WithoutCancel(r.Context()), agentID, req.Message)
 	if err != nil {
-		g.handleSendError(w, err)
+		g.handleSendError(w, r, err)
 		return
 	}
 
-	g.startSSEStream(r.Context(), w, flusher, convResp)
+	g.startSSEStream(r.Context(), w, flusher, convResp, conn)
 }
 
 // sendAgentMessage creates and sends a message to an agent via ConversationService.
@@ -1131,24 +1929,43 @@ func (g *Gateway) sendAgentMessage(ctx context.Context, agentID, message string)
 }
 
 // handleSendError sends the appropriate error response for message send failures.
-func (g *Gateway) handleSendError(w http.ResponseWriter, err error) {
+func (g *Gateway) handleSendError(w http.ResponseWriter, r *http.Request, err error) {
 	if errors.Is(err, agent.ErrAgentNotFound) {
-		g.sendJSONError(w, http.StatusNotFound, "agent not found")
+		g.sendCodedError(w, http.StatusNotFound, coverr.AgentOffline, "agent not found")
 		return
 	}
-	g.logger.Error("failed to send message", "error", err)
+	var pausedErr *agent.PausedError
+	if errors.As(err, &pausedErr) {
+		g.sendAgentPausedError(w, pausedErr)
+		return
+	}
+	if errors.Is(err, agent.ErrDraining) {
+		g.sendDrainingError(w)
+		return
+	}
+	var busyErr *agent.BusyError
+	if errors.As(err, &busyErr) {
+		g.sendAgentBusyError(w, busyErr)
+		return
+	}
+	var workspaceErr *agent.WorkspaceError
+	if errors.As(err, &workspaceErr) {
+		g.sendUnknownWorkspaceError(w, workspaceErr)
+		return
+	}
+	if errors.Is(err, diskmon.ErrStorageFull) {
+		g.sendStorageFullError(w)
+		return
+	}
+	logctx.FromContext(r.Context(), g.logger).Error("failed to send message", "error", err)
 	g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
 }
 
 // startSSEStream sets SSE headers and begins streaming responses.
-func (g *Gateway) startSSEStream(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, convResp *conversation.SendResponse) {
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("X-Accel-Buffering", "no")
-	g.writeSSEEvent(w, "started", map[string]string{"thread_id": convResp.ThreadID})
-	flusher.Flush()
-	g.streamResponses(ctx, w, flusher, convResp.Stream)
+func (g *Gateway) startSSEStream(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, convResp *conversation.SendResponse, conn *agent.Connection) {
+	g.relayResponses(ctx, convResp.MessageID, conn.ID, startedSSE(convResp, conn, selectionExplicit), convResp.Stream)
+	setSSEHeaders(w)
+	g.serveRequestStream(ctx, w, flusher, convResp.MessageID, 0)
 }
 
 // UsageStatsResponse is the JSON response for GET /api/stats/usage.
@@ -1162,10 +1979,13 @@ type UsageStatsResponse struct {
 	RequestCount    int64 `json:"request_count"`
 }
 
-// ThreadUsageResponse is the JSON response for GET /api/threads/{id}/usage.
+// ThreadUsageResponse is the JSON response for GET /api/threads/{id}/usage:
+// the raw records, plus totals and a per-request breakdown with estimated
+// cost from usage.pricing.
 type ThreadUsageResponse struct {
 	ThreadID string          `json:"thread_id"`
 	Usage    []UsageResponse `json:"usage"`
+	usage.ThreadUsage
 }
 
 // UsageResponse represents a single usage record.
@@ -1186,8 +2006,8 @@ type UsageResponse struct {
 // Returns aggregate token usage statistics with optional filters.
 // Query parameters:
 //   - agent_id: filter by agent
-//   - since: start time (RFC3339)
-//   - until: end time (RFC3339)
+//   - since: start time (RFC3339 or epoch seconds/milliseconds)
+//   - until: end time (RFC3339 or epoch seconds/milliseconds)
 func (g *Gateway) handleUsageStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -1209,22 +2029,14 @@ func (g *Gateway) handleUsageStats(w http.ResponseWriter, r *http.Request) {
 		filter.AgentID = &agentID
 	}
 
-	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
-		since, err := time.Parse(time.RFC3339, sinceStr)
-		if err != nil {
-			g.sendJSONError(w, http.StatusBadRequest, "invalid 'since' format (use RFC3339)")
-			return
-		}
-		filter.Since = &since
+	var err error
+	if filter.Since, err = timeparse.Query(r.URL.Query(), "since"); err != nil {
+		g.sendTimestampError(w, err)
+		return
 	}
-
-	if untilStr := r.URL.Query().Get("until"); untilStr != "" {
-		until, err := time.Parse(time.RFC3339, untilStr)
-		if err != nil {
-			g.sendJSONError(w, http.StatusBadRequest, "invalid 'until' format (use RFC3339)")
-			return
-		}
-		filter.Until = &until
+	if filter.Until, err = timeparse.Query(r.URL.Query(), "until"); err != nil {
+		g.sendTimestampError(w, err)
+		return
 	}
 
 	stats, err := usageStore.GetUsageStats(r.Context(), filter)
@@ -1251,7 +2063,7 @@ func (g *Gateway) handleUsageStats(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleThreadUsage handles GET /api/threads/{id}/usage requests.
-// Returns token usage records for a specific thread.
+// Returns token usage records for a specific thread with their per-request rollup.
 func (g *Gateway) handleThreadUsage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -1275,8 +2087,7 @@ func (g *Gateway) handleThreadUsage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if errMsg := g.verifyThreadExists(r.Context(), threadID); errMsg != "" {
-		g.sendJSONError(w, http.StatusNotFound, errMsg)
+	if !g.resolveThreadRoute(w, r, threadID, "/usage") {
 		return
 	}
 
@@ -1287,24 +2098,52 @@ func (g *Gateway) handleThreadUsage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := ThreadUsageResponse{ThreadID: threadID, Usage: usagesToResponse(usages)}
+	requests, err := g.usageReports.ListThreadRequestUsage(r.Context(), threadID, store.UsageFilter{})
+	if err != nil {
+		g.logger.Error("failed to get thread request usage", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response := ThreadUsageResponse{
+		ThreadID:    threadID,
+		Usage:       usagesToResponse(usages),
+		ThreadUsage: g.usagePricing.ThreadReport(requests),
+	}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		g.logger.Debug("failed to encode response", "error", err)
 	}
 }
 
-// verifyThreadExists checks if a thread exists and returns an error message if not.
-func (g *Gateway) verifyThreadExists(ctx context.Context, threadID string) string {
-	_, err := g.store.GetThread(ctx, threadID)
+// resolveThreadRoute checks that a thread exists, and that the caller's token
+// may reach its agent, before serving /api/threads/{id}/{suffix}. Threads merged
+// into another are answered with a permanent redirect to the target.
+// Returns false if a response has already been written.
+func (g *Gateway) resolveThreadRoute(w http.ResponseWriter, r *http.Request, threadID, suffix string) bool {
+	thread, err := g.store.GetThread(r.Context(), threadID)
 	if errors.Is(err, store.ErrNotFound) {
-		return "thread not found"
+		g.sendJSONError(w, http.StatusNotFound, "thread not found")
+		return false
 	}
 	if err != nil {
 		g.logger.Error("failed to get thread", "error", err)
-		return "internal server error"
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return false
 	}
-	return ""
+	if !g.tokenAllowsAgentID(r.Context(), thread.AgentID) {
+		g.sendJSONError(w, http.StatusForbidden, errTokenAgentDenied)
+		return false
+	}
+	if thread.MergedInto != "" {
+		target := httpapi.APIPrefix(r) + "/threads/" + thread.MergedInto + suffix
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+		return false
+	}
+	return true
 }
 
 // usagesToResponse converts usage records to response format.
@@ -1321,7 +2160,7 @@ func usagesToResponse(usages []*store.TokenUsage) []UsageResponse {
 			CacheReadTokens:  u.CacheReadTokens,
 			CacheWriteTokens: u.CacheWriteTokens,
 			ThinkingTokens:   u.ThinkingTokens,
-			CreatedAt:        u.CreatedAt.Format(time.RFC3339),
+			CreatedAt:        timeparse.Format(u.CreatedAt),
 		}
 	}
 	return result
@@ -1335,6 +2174,12 @@ type ToolApprovalRequestBody struct {
 	ApproveAll bool   `json:"approve_all,omitempty"`
 }
 
+// ToolApprovalResponse is the JSON response for POST /api/tools/approve.
+type ToolApprovalResponse struct {
+	Success  bool `json:"success"`
+	Approved bool `json:"approved"`
+}
+
 // handleToolApproval handles POST /api/tools/approve requests.
 // Sends a tool approval response to the agent.
 func (g *Gateway) handleToolApproval(w http.ResponseWriter, r *http.Request) {
@@ -1358,6 +2203,10 @@ func (g *Gateway) handleToolApproval(w http.ResponseWriter, r *http.Request) {
 		g.sendJSONError(w, http.StatusBadRequest, "tool_id is required")
 		return
 	}
+	if !g.tokenAllowsAgentID(r.Context(), req.AgentID) {
+		g.sendJSONError(w, http.StatusForbidden, errTokenAgentDenied)
+		return
+	}
 
 	err := g.agentManager.SendToolApproval(req.AgentID, req.ToolID, req.Approved, req.ApproveAll)
 	if errors.Is(err, agent.ErrAgentNotFound) {
@@ -1371,10 +2220,7 @@ func (g *Gateway) handleToolApproval(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]any{
-		"success":  true,
-		"approved": req.Approved,
-	}); err != nil {
+	if err := json.NewEncoder(w).Encode(ToolApprovalResponse{Success: true, Approved: req.Approved}); err != nil {
 		g.logger.Debug("failed to encode response", "error", err)
 	}
 }
@@ -1385,6 +2231,13 @@ type AnswerQuestionRequestBody struct {
 	QuestionID string   `json:"question_id"`
 	Selected   []string `json:"selected"`
 	CustomText string   `json:"custom_text,omitempty"`
+	// ContextVersion is the version of the context snapshot the answerer saw.
+	ContextVersion string `json:"context_version,omitempty"`
+}
+
+// AnswerQuestionResponse is the JSON response for POST /api/questions/answer.
+type AnswerQuestionResponse struct {
+	Success bool `json:"success"`
 }
 
 // validateAnswerQuestionRequest validates the answer question request.
@@ -1418,6 +2271,10 @@ func (g *Gateway) handleAnswerQuestion(w http.ResponseWriter, r *http.Request) {
 		g.sendJSONError(w, http.StatusBadRequest, errMsg)
 		return
 	}
+	if !g.tokenAllowsAgentID(r.Context(), req.AgentID) {
+		g.sendJSONError(w, http.StatusForbidden, errTokenAgentDenied)
+		return
+	}
 	if g.questionRouter == nil {
 		g.sendJSONError(w, http.StatusServiceUnavailable, "question router not configured")
 		return
@@ -1431,15 +2288,25 @@ func (g *Gateway) handleAnswerQuestion(w http.ResponseWriter, r *http.Request) {
 	if req.CustomText != "" {
 		answer.CustomText = &req.CustomText
 	}
+	if req.ContextVersion != "" {
+		answer.ContextVersion = &req.ContextVersion
+	}
+	if pq, ok := g.questionRouter.Lookup(req.QuestionID); ok {
+		g.logger.Info("question answered",
+			"agent_id", req.AgentID,
+			"question_id", req.QuestionID,
+			"context_version", pq.ContextVersion(),
+			"seen_context_version", req.ContextVersion,
+		)
+	}
 
 	if err := g.questionRouter.DeliverAnswer(req.AgentID, req.QuestionID, answer); err != nil {
-		g.logger.Error("failed to deliver question answer", "error", err)
-		g.sendJSONError(w, http.StatusNotFound, "question not found or already answered")
+		g.sendAnswerError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]any{"success": true}); err != nil {
+	if err := json.NewEncoder(w).Encode(AnswerQuestionResponse{Success: true}); err != nil {
 		g.logger.Debug("failed to encode response", "error", err)
 	}
 }
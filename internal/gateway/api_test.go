@@ -18,12 +18,18 @@ import (
 
 	"log/slog"
 
+	"github.com/2389/coven-gateway/internal/admin"
 	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
 	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/httpapi"
 	"github.com/2389/coven-gateway/internal/store"
 	pb "github.com/2389/coven-gateway/proto/coven"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"slices"
 )
 
 func TestHandleSendMessage_NoAgents(t *testing.T) {
@@ -58,6 +64,9 @@ func TestHandleSendMessage_NoAgents(t *testing.T) {
 	if errResp["error"] != "agent unavailable" {
 		t.Errorf("unexpected error message: %s", errResp["error"])
 	}
+	if errResp["code"] != string(coverr.AgentOffline) {
+		t.Errorf("code = %q, want %q", errResp["code"], coverr.AgentOffline)
+	}
 }
 
 func TestHandleSendMessage_MissingAgentContext(t *testing.T) {
@@ -182,6 +191,397 @@ func TestHandleSendMessage_SSEHeaders(t *testing.T) {
 	}
 }
 
+func TestHandleSendMessage_StartedReportsRouting(t *testing.T) {
+	gw := newTestGatewayWithMockManager(t)
+	createTestBindingV2(t, gw, "matrix", "!room", "test-agent")
+	gw.conversation = conversation.New(gw.store.(*store.SQLStore), &scriptedSender{script: []*agent.Response{
+		{Event: agent.EventDone, Text: "ok", Done: true},
+	}}, slog.Default(), nil)
+
+	send := func(req SendMessageRequest) (started, done map[string]any) {
+		t.Helper()
+		body, _ := json.Marshal(req)
+		rec := httptest.NewRecorder()
+		gw.handleSendMessage(rec, httptest.NewRequest(http.MethodPost, "/api/send", bytes.NewReader(body)))
+		events := map[string]map[string]any{}
+		var event string
+		for _, line := range strings.Split(rec.Body.String(), "\n") {
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event = name
+			} else if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var payload map[string]any
+				if err := json.Unmarshal([]byte(data), &payload); err != nil {
+					t.Fatalf("decoding %s: %v", event, err)
+				}
+				events[event] = payload
+			}
+		}
+		return events["started"], events["done"]
+	}
+
+	started, done := send(SendMessageRequest{Sender: "u", Content: "hi", Frontend: "matrix", ChannelID: "!room"})
+	if started["selection"] != "binding" || started["agent_id"] != "test-agent" || started["agent_name"] != "Test" {
+		t.Errorf("binding started = %v", started)
+	}
+	if started["thread_created"] != true {
+		t.Errorf("first send should create the thread: %v", started)
+	}
+	if done["agent_id"] != "test-agent" || done["full_response"] != "ok" {
+		t.Errorf("done = %v, want agent_id repeated", done)
+	}
+
+	started, _ = send(SendMessageRequest{Sender: "u", Content: "again", Frontend: "matrix", ChannelID: "!room"})
+	if started["thread_created"] != false {
+		t.Errorf("second send should reuse the thread: %v", started)
+	}
+
+	started, _ = send(SendMessageRequest{Sender: "u", Content: "hi", AgentID: "test-agent"})
+	if started["selection"] != "explicit" {
+		t.Errorf("explicit started = %v", started)
+	}
+	if _, ok := started["routing"]; ok {
+		t.Errorf("explicit started should not carry routing: %v", started)
+	}
+
+	started, _ = send(SendMessageRequest{Sender: "u", Content: "hi", Capability: "code"})
+	routing, _ := started["routing"].(map[string]any)
+	if started["selection"] != "capability" || started["agent_id"] != "test-agent" || routing == nil {
+		t.Fatalf("capability started = %v", started)
+	}
+	if routing["capability"] != "code" || routing["candidates"] != float64(1) || routing["eligible"] != float64(1) {
+		t.Errorf("routing = %v, want one eligible candidate", routing)
+	}
+}
+
+func TestHandleSendMessage_Workspace(t *testing.T) {
+	gw := newTestGatewayWithMockManager(t)
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: "repo-agent", Name: "Repos", PrincipalID: "repo-agent", InstanceID: "inst-repo",
+		Workspaces: []string{"api", "web"}, Stream: &testMockStream{}, Logger: slog.Default(),
+	})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("failed to register agent: %v", err)
+	}
+	createTestBindingV2(t, gw, "matrix", "!repo", "repo-agent")
+	sqlStore := gw.store.(*store.SQLStore)
+	if err := sqlStore.SetBindingWorkspace(context.Background(), "test-binding-matrix-!repo", "web"); err != nil {
+		t.Fatalf("SetBindingWorkspace: %v", err)
+	}
+	sender := &recordingSender{}
+	gw.conversation = conversation.New(sqlStore, sender, slog.Default(), nil)
+
+	send := func(req SendMessageRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(req)
+		rec := httptest.NewRecorder()
+		gw.handleSendMessage(rec, httptest.NewRequest(http.MethodPost, "/api/send", bytes.NewReader(body)))
+		return rec
+	}
+
+	rec := send(SendMessageRequest{Sender: "u", Content: "hi", AgentID: "repo-agent", Workspace: "docs"})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown workspace status = %d, want 400: %s", rec.Code, rec.Body.String())
+	}
+	var errResp UnknownWorkspaceResponse
+	if err := json.NewDecoder(rec.Body).Decode(&errResp); err != nil {
+		t.Fatalf("decoding error: %v", err)
+	}
+	if errResp.Code != coverr.InvalidRequest || errResp.Workspace != "docs" || !slices.Equal(errResp.ValidWorkspaces, []string{"api", "web"}) {
+		t.Errorf("error response = %+v, want docs refused with api and web valid", errResp)
+	}
+	if len(sender.reqs) != 0 {
+		t.Fatal("refused send reached the agent")
+	}
+
+	rec = send(SendMessageRequest{Sender: "u", Content: "hi", AgentID: "repo-agent", Workspace: "api"})
+	if !strings.Contains(rec.Body.String(), `"workspace":"api"`) {
+		t.Errorf("started event does not name the workspace: %s", rec.Body.String())
+	}
+	send(SendMessageRequest{Sender: "u", Content: "hi", Frontend: "matrix", ChannelID: "!repo"})
+	send(SendMessageRequest{Sender: "u", Content: "hi", Frontend: "matrix", ChannelID: "!repo", Workspace: "api"})
+	send(SendMessageRequest{Sender: "u", Content: "hi", AgentID: "repo-agent"})
+	var got []string
+	for _, req := range sender.reqs {
+		got = append(got, req.Workspace)
+	}
+	if want := []string{"api", "web", "api", ""}; !slices.Equal(got, want) {
+		t.Errorf("workspaces sent = %q, want %q", got, want)
+	}
+}
+
+func TestHandleCreateBinding_Workspace(t *testing.T) {
+	gw := newTestGatewayWithMockManager(t)
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: "repo-agent", Name: "Repos", PrincipalID: "repo-agent", InstanceID: "inst-repo",
+		Workspaces: []string{"api", "web"}, Stream: &testMockStream{}, Logger: slog.Default(),
+	})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("failed to register agent: %v", err)
+	}
+	createTestBindingV2(t, gw, "matrix", "!other", "repo-agent") // creates the principal
+
+	bind := func(workspace string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(CreateBindingRequest{Frontend: "matrix", ChannelID: "!repo", InstanceID: "inst-repo", Workspace: workspace})
+		rec := httptest.NewRecorder()
+		gw.handleCreateBinding(rec, httptest.NewRequest(http.MethodPost, "/api/bindings", bytes.NewReader(body)))
+		return rec
+	}
+
+	if rec := bind("docs"); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"valid_workspaces":["api","web"]`) {
+		t.Fatalf("unknown workspace = %d %s, want 400 listing api and web", rec.Code, rec.Body.String())
+	}
+	if rec := bind("api"); rec.Code != http.StatusCreated {
+		t.Fatalf("create = %d %s", rec.Code, rec.Body.String())
+	}
+	// Binding the same agent again moves the channel to another workspace.
+	if rec := bind("web"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"workspace":"web"`) {
+		t.Fatalf("rebind = %d %s, want 200 with workspace web", rec.Code, rec.Body.String())
+	}
+	binding, err := gw.store.GetBindingByChannel(context.Background(), "matrix", "!repo")
+	if err != nil {
+		t.Fatalf("GetBindingByChannel: %v", err)
+	}
+	if binding.Workspace != "web" {
+		t.Errorf("binding workspace = %q, want web", binding.Workspace)
+	}
+}
+
+func TestHandleSendMessage_BindingContext(t *testing.T) {
+	gw := newTestGatewayWithMockManager(t)
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: "support-agent", Name: "Support", PrincipalID: "support-agent", InstanceID: "inst-support",
+		Stream: &testMockStream{}, Logger: slog.Default(),
+	})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("failed to register agent: %v", err)
+	}
+	createTestBindingV2(t, gw, "slack", "#support", "support-agent")
+	sqlStore := gw.store.(*store.SQLStore)
+	const supportContext = "Answer tersely."
+	if err := sqlStore.SetBindingContext(context.Background(), "test-binding-slack-#support", supportContext); err != nil {
+		t.Fatalf("SetBindingContext: %v", err)
+	}
+	sender := &recordingSender{}
+	gw.conversation = conversation.New(sqlStore, sender, slog.Default(), nil)
+
+	for _, req := range []SendMessageRequest{
+		{Sender: "u", Content: "hi", Frontend: "slack", ChannelID: "#support"},
+		{Sender: "u", Content: "hi", AgentID: "support-agent"},
+	} {
+		body, _ := json.Marshal(req)
+		rec := httptest.NewRecorder()
+		gw.handleSendMessage(rec, httptest.NewRequest(http.MethodPost, "/api/send", bytes.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("send status = %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	if len(sender.reqs) != 2 || sender.reqs[0].ChannelContext != supportContext || sender.reqs[1].ChannelContext != "" {
+		t.Fatalf("channel contexts sent = %+v, want the binding's then none", sender.reqs)
+	}
+	// The context stays out of the message itself.
+	if sender.reqs[0].Content != "hi" {
+		t.Errorf("content = %q, want hi", sender.reqs[0].Content)
+	}
+
+	events, err := sqlStore.GetEventsByThreadID(context.Background(), sender.reqs[0].ThreadID, 10)
+	if err != nil {
+		t.Fatalf("GetEventsByThreadID: %v", err)
+	}
+	if len(events) == 0 || events[0].ChannelContextHash == nil || *events[0].ChannelContextHash != store.BindingContextHash(supportContext) {
+		t.Errorf("user message does not record the context hash: %+v", events)
+	}
+}
+
+func TestHandleUpdateBinding_Context(t *testing.T) {
+	gw := newTestGatewayWithMockManager(t)
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: "support-agent", Name: "Support", PrincipalID: "support-agent", InstanceID: "inst-support",
+		Stream: &testMockStream{}, Logger: slog.Default(),
+	})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("failed to register agent: %v", err)
+	}
+	createTestBindingV2(t, gw, "slack", "#other", "support-agent") // creates the principal
+
+	bind := func(req CreateBindingRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(req)
+		rec := httptest.NewRecorder()
+		gw.handleBindings(rec, httptest.NewRequest(http.MethodPost, "/api/bindings", bytes.NewReader(body)))
+		return rec
+	}
+	patch := func(query, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		gw.handleBindings(rec, httptest.NewRequest(http.MethodPatch, "/api/bindings?"+query, strings.NewReader(body)))
+		return rec
+	}
+	contextOf := func() string {
+		t.Helper()
+		b, err := gw.store.GetBindingByChannel(context.Background(), "slack", "C1")
+		if err != nil {
+			t.Fatalf("GetBindingByChannel: %v", err)
+		}
+		return b.Context
+	}
+
+	tersely := "Answer tersely."
+	if rec := bind(CreateBindingRequest{Frontend: "slack", ChannelID: "C1", InstanceID: "inst-support", Context: &tersely}); rec.Code != http.StatusCreated {
+		t.Fatalf("create = %d %s", rec.Code, rec.Body.String())
+	}
+	// Binding again without a context keeps the channel's.
+	if rec := bind(CreateBindingRequest{Frontend: "slack", ChannelID: "C1", InstanceID: "inst-support"}); rec.Code != http.StatusOK ||
+		!strings.Contains(rec.Body.String(), `"context":"Answer tersely."`) {
+		t.Fatalf("rebind = %d %s, want 200 keeping the context", rec.Code, rec.Body.String())
+	}
+
+	if rec := patch("frontend=slack&channel_id=C1", `{"context":"Use bullet points."}`); rec.Code != http.StatusOK ||
+		!strings.Contains(rec.Body.String(), `"context":"Use bullet points."`) {
+		t.Fatalf("patch = %d %s", rec.Code, rec.Body.String())
+	}
+	if got := contextOf(); got != "Use bullet points." {
+		t.Errorf("context = %q after patch", got)
+	}
+
+	tooLong := `{"context":"` + strings.Repeat("x", store.MaxBindingContextBytes+1) + `"}`
+	tests := []struct {
+		name, query, body string
+		want              int
+	}{
+		{"too long", "frontend=slack&channel_id=C1", tooLong, http.StatusBadRequest},
+		{"no context", "frontend=slack&channel_id=C1", `{}`, http.StatusBadRequest},
+		{"no channel", "frontend=slack", `{"context":""}`, http.StatusBadRequest},
+		{"unknown channel", "frontend=slack&channel_id=C9", `{"context":""}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := patch(tt.query, tt.body); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	if rec := patch("frontend=slack&channel_id=C1", `{"context":""}`); rec.Code != http.StatusOK {
+		t.Fatalf("clear = %d %s", rec.Code, rec.Body.String())
+	}
+	if got := contextOf(); got != "" {
+		t.Errorf("context = %q after clearing", got)
+	}
+}
+
+func TestHandleSendMessage_CapabilityUnavailable(t *testing.T) {
+	gw := newTestGatewayWithMockManager(t)
+	body, _ := json.Marshal(SendMessageRequest{Sender: "u", Content: "hi", Capability: "translate"})
+	rec := httptest.NewRecorder()
+	gw.handleSendMessage(rec, httptest.NewRequest(http.MethodPost, "/api/send", bytes.NewReader(body)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503 when no agent has the capability", rec.Code)
+	}
+}
+
+func TestHandleSendMessage_Tags(t *testing.T) {
+	gw := newTestGatewayWithMockManager(t)
+	for _, a := range []struct {
+		id   string
+		tags map[string]string
+	}{
+		{"prod-1", map[string]string{"env": "prod", "team": "infra"}},
+		{"prod-2", map[string]string{"env": "prod"}},
+	} {
+		conn := agent.NewConnection(agent.ConnectionParams{
+			ID: a.id, Name: a.id, PrincipalID: a.id, Stream: &testMockStream{}, Logger: slog.Default(),
+			Metadata: &agent.Metadata{Tags: a.tags},
+		})
+		if err := gw.agentManager.Register(conn); err != nil {
+			t.Fatalf("register %s: %v", a.id, err)
+		}
+	}
+	sender := &recordingSender{}
+	gw.conversation = conversation.New(gw.store.(*store.SQLStore), sender, slog.Default(), nil)
+
+	send := func(req SendMessageRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(req)
+		rec := httptest.NewRecorder()
+		gw.handleSendMessage(rec, httptest.NewRequest(http.MethodPost, "/api/send", bytes.NewReader(body)))
+		return rec
+	}
+
+	rec := send(SendMessageRequest{Sender: "u", Content: "hi", Tags: map[string]string{"team": "infra"}})
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"selection":"tags"`) ||
+		!strings.Contains(rec.Body.String(), `"agent_id":"prod-1"`) {
+		t.Errorf("single match = %d %s, want routed to prod-1", rec.Code, rec.Body.String())
+	}
+
+	rec = send(SendMessageRequest{Sender: "u", Content: "hi", Tags: map[string]string{"env": "prod"}})
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "prod-1, prod-2") {
+		t.Errorf("ambiguous = %d %s, want 409 listing both agents", rec.Code, rec.Body.String())
+	}
+
+	rec = send(SendMessageRequest{Sender: "u", Content: "hi", Tags: map[string]string{"env": "prod"}, Any: true})
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"agent_id":"prod-`) {
+		t.Errorf("any = %d %s, want routed to a prod agent", rec.Code, rec.Body.String())
+	}
+
+	rec = send(SendMessageRequest{Sender: "u", Content: "hi", Tags: map[string]string{"env": "staging"}})
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("no match = %d, want 503", rec.Code)
+	}
+
+	for name, req := range map[string]SendMessageRequest{
+		"with capability": {Sender: "u", Content: "hi", Tags: map[string]string{"env": "prod"}, Capability: "chat"},
+		"any alone":       {Sender: "u", Content: "hi", AgentID: "prod-1", Any: true},
+	} {
+		if rec := send(req); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", name, rec.Code)
+		}
+	}
+}
+
+func TestHandleListAgents_TagFilter(t *testing.T) {
+	gw := newTestGateway(t)
+	for id, tags := range map[string]map[string]string{
+		"tagged":   {"env": "prod", "team": "infra"},
+		"untagged": nil,
+	} {
+		conn := agent.NewConnection(agent.ConnectionParams{
+			ID: id, Name: id, Stream: &testMockStream{}, Logger: slog.Default(),
+			Metadata: &agent.Metadata{Tags: tags},
+		})
+		if err := gw.agentManager.Register(conn); err != nil {
+			t.Fatalf("register %s: %v", id, err)
+		}
+	}
+
+	list := func(query string) (*httptest.ResponseRecorder, []AgentInfoResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		gw.handleListAgents(rec, httptest.NewRequest(http.MethodGet, "/api/agents"+query, nil))
+		var agents []AgentInfoResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&agents); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return rec, agents
+	}
+
+	if _, agents := list("?tag=env=prod&tag=team=infra"); len(agents) != 1 || agents[0].ID != "tagged" || agents[0].Tags["team"] != "infra" {
+		t.Errorf("agents = %+v, want only tagged with its tags", agents)
+	}
+	if _, agents := list("?tag=env=dev"); len(agents) != 0 {
+		t.Errorf("agents = %+v, want none", agents)
+	}
+	if _, agents := list(""); len(agents) != 2 {
+		t.Errorf("unfiltered = %d agents, want 2", len(agents))
+	}
+	if rec, _ := list("?tag=env"); rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed filter = %d, want 400", rec.Code)
+	}
+}
+
 func TestHandleListAgents_Empty(t *testing.T) {
 	gw := newTestGateway(t)
 
@@ -248,6 +648,10 @@ func TestHandleListAgents_WithAgents(t *testing.T) {
 	if agents[0].WorkingDir != "/projects/website" {
 		t.Errorf("expected working_dir '/projects/website', got %s", agents[0].WorkingDir)
 	}
+	if a := agents[0]; a.Health != "degraded" || a.HealthReason == "" || a.LastHeartbeat != "2026-01-02T03:04:05Z" ||
+		a.LatencyMsP50 != 40 || a.LatencyMsP95 != 1500 || a.ClockSkewMs != -250 {
+		t.Errorf("expected heartbeat health fields, got %+v", a)
+	}
 }
 
 func TestHandleListAgents_MethodNotAllowed(t *testing.T) {
@@ -368,6 +772,27 @@ func TestHandleSendMessage_WithAgentID(t *testing.T) {
 	}
 }
 
+func TestHandleSendMessage_TokenLimitedToOtherAgents(t *testing.T) {
+	gw := newTestGatewayWithMockManager(t)
+
+	body, err := json.Marshal(SendMessageRequest{Sender: "test-user", Content: "Hello", AgentID: "test-agent"})
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/send", bytes.NewReader(body))
+	req = req.WithContext(auth.WithAuth(req.Context(), &auth.AuthContext{
+		PrincipalID: "client-1",
+		AgentIDs:    []string{"other-agent"},
+	}))
+	rec := httptest.NewRecorder()
+
+	gw.handleSendMessage(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403 for an agent outside the token's list", rec.Code)
+	}
+}
+
 func TestHandleSendMessage_AgentNotFound(t *testing.T) {
 	gw := newTestGatewayWithMockManager(t)
 
@@ -402,6 +827,57 @@ func TestHandleSendMessage_AgentNotFound(t *testing.T) {
 	}
 }
 
+func TestHandleSendMessage_AgentPaused(t *testing.T) {
+	gw := newTestGateway(t)
+
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID:     "paused-agent",
+		Name:   "Paused",
+		Stream: &testMockStream{},
+		Logger: slog.Default(),
+	})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("failed to register agent: %v", err)
+	}
+	pausedAt := time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC)
+	gw.agentManager.Pause("paused-agent", agent.PauseInfo{PausedBy: "admin", PausedAt: pausedAt})
+
+	body, err := json.Marshal(SendMessageRequest{Sender: "test-user", Content: "Hello", AgentID: "paused-agent"})
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/send", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	gw.handleSendMessage(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d. Body: %s", http.StatusConflict, rec.Code, rec.Body.String())
+	}
+	var errResp AgentPausedResponse
+	if err := json.NewDecoder(rec.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	want := AgentPausedResponse{
+		Error: "agent paused", Code: "agent_paused", AgentID: "paused-agent",
+		PausedBy: "admin", PausedAt: "2026-01-15T10:30:00Z",
+	}
+	if errResp != want {
+		t.Errorf("error response = %+v, want %+v", errResp, want)
+	}
+
+	listRec := httptest.NewRecorder()
+	gw.handleListAgents(listRec, httptest.NewRequest(http.MethodGet, "/api/agents", nil))
+	var agents []AgentInfoResponse
+	if err := json.NewDecoder(listRec.Body).Decode(&agents); err != nil {
+		t.Fatalf("failed to decode agents: %v", err)
+	}
+	if len(agents) != 1 || !agents[0].Paused || agents[0].PausedBy != "admin" {
+		t.Errorf("agents = %+v, want paused-agent marked paused by admin", agents)
+	}
+}
+
 func TestHandleSendMessage_BindingLookup(t *testing.T) {
 	gw := newTestGatewayWithMockManager(t)
 
@@ -537,10 +1013,10 @@ func createTestBindingV2(t *testing.T, gw *Gateway, frontend, channelID, agentID
 
 	ctx := context.Background()
 
-	// Type assert to SQLiteStore to access V2 methods
-	sqlStore, ok := gw.store.(*store.SQLiteStore)
+	// Type assert to SQLStore to access V2 methods
+	sqlStore, ok := gw.store.(*store.SQLStore)
 	if !ok {
-		t.Fatalf("store is not *SQLiteStore")
+		t.Fatalf("store is not *SQLStore")
 	}
 
 	// Create a principal for the agent first (required by V2 bindings validation)
@@ -592,6 +1068,15 @@ func (m *mockAgentManager) ListAgents() []*agent.AgentInfo {
 		Capabilities: []string{"chat", "code"},
 		Workspaces:   []string{"Code", "Personal"},
 		WorkingDir:   "/projects/website",
+		Health: agent.HeartbeatHealth{
+			State:         agent.HealthDegraded,
+			Reason:        "p95 heartbeat latency 1.5s over 1s",
+			LastHeartbeat: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			LatencyP50:    40 * time.Millisecond,
+			LatencyP95:    1500 * time.Millisecond,
+			Samples:       20,
+			ClockSkew:     -250 * time.Millisecond,
+		},
 	}}
 }
 
@@ -913,6 +1398,67 @@ func TestParseSendRequest_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestParseSendRequest_NegativeMaxResponse(t *testing.T) {
+	body := `{"content": "hello", "sender": "user@test.com", "max_response_seconds": -5}`
+	_, err := parseSendRequest(strings.NewReader(body))
+	if err == nil || err.Error() != "max_response_seconds must not be negative" {
+		t.Errorf("expected negative max_response_seconds error, got %v", err)
+	}
+}
+
+func TestTruncatedToSSE(t *testing.T) {
+	gw := &Gateway{}
+	event := gw.responseToSSEEvent(&agent.Response{
+		Event: agent.EventTruncated,
+		Done:  true,
+		Truncated: &agent.TruncatedEvent{
+			Reason:  agent.TruncatedReasonMaxDuration,
+			Elapsed: 90 * time.Second,
+			Paused:  30 * time.Second,
+			Limit:   time.Minute,
+		},
+	})
+	if event.Event != "truncated" {
+		t.Fatalf("event = %q, want truncated", event.Event)
+	}
+	data := event.Data.(map[string]any)
+	if data["reason"] != agent.TruncatedReasonMaxDuration || data["elapsed_seconds"] != 90.0 ||
+		data["paused_seconds"] != 30.0 || data["limit_seconds"] != 60.0 {
+		t.Errorf("data = %v", data)
+	}
+}
+
+func TestPlanToSSE(t *testing.T) {
+	gw := &Gateway{}
+	event := gw.responseToSSEEvent(&agent.Response{
+		Event: agent.EventPlan,
+		Plan:  &agent.PlanEvent{Steps: []agent.PlanStep{{Title: "check logs", Status: "pending"}}},
+	})
+	if event.Event != "plan" {
+		t.Fatalf("event = %q, want plan", event.Event)
+	}
+	data := event.Data.(map[string]any)
+	steps := data["steps"].([]planStepSSE)
+	if len(steps) != 1 || steps[0].Title != "check logs" || data["text"] != "Plan:\n1. check logs" {
+		t.Errorf("data = %v", data)
+	}
+
+	event = gw.responseToSSEEvent(&agent.Response{
+		Event:    agent.EventPlanStepUpdate,
+		PlanStep: &agent.PlanStepEvent{Index: 0, Status: "completed"},
+	})
+	data = event.Data.(map[string]any)
+	if event.Event != "plan_step_update" || data["index"] != 0 || data["text"] != "Step 1 completed" {
+		t.Errorf("step update = %q %v", event.Event, data)
+	}
+
+	if event := gw.responseToSSEEvent(&agent.Response{Event: agent.EventPlan}); event.Event != "error" {
+		t.Errorf("nil plan: event = %q, want error", event.Event)
+	} else if body := event.Data.(coverr.Body); body.Code != coverr.Internal || body.Error != body.Message {
+		t.Errorf("nil plan: data = %+v, want an internal error", body)
+	}
+}
+
 // Tests for bindingResolver
 
 func TestResolveBinding_ExistingBinding(t *testing.T) {
@@ -924,10 +1470,11 @@ func TestResolveBinding_ExistingBinding(t *testing.T) {
 
 	// Setup existing V2 binding (the resolver now uses GetBindingByChannel which reads V2 bindings)
 	s.AddBindingV2(ctx, &store.Binding{
-		ID:        "binding-1",
-		Frontend:  "test",
-		ChannelID: "channel-1",
-		AgentID:   "agent-1",
+		ID:                  "binding-1",
+		Frontend:            "test",
+		ChannelID:           "channel-1",
+		AgentID:             "agent-1",
+		MaxResponseDuration: 10 * time.Minute,
 	})
 
 	resolver := &bindingResolver{store: s}
@@ -942,6 +1489,9 @@ func TestResolveBinding_ExistingBinding(t *testing.T) {
 	if result.AgentID != "agent-1" {
 		t.Errorf("expected agent ID 'agent-1', got %q", result.AgentID)
 	}
+	if result.MaxResponseDuration != 10*time.Minute {
+		t.Errorf("expected binding response limit 10m, got %v", result.MaxResponseDuration)
+	}
 }
 
 func TestResolveBinding_NoBinding(t *testing.T) {
@@ -1059,9 +1609,9 @@ func newTestGatewayWithAgentForBinding(t *testing.T, instanceID, workingDir, pri
 	}
 
 	// Create the principal in the store so the binding can be created
-	sqlStore, ok := gw.store.(*store.SQLiteStore)
+	sqlStore, ok := gw.store.(*store.SQLStore)
 	if !ok {
-		t.Fatalf("store is not *SQLiteStore")
+		t.Fatalf("store is not *SQLStore")
 	}
 	// Generate a unique pubkey fingerprint based on principal ID
 	pubkeyFP := fmt.Sprintf("%064s", principalID)[:64]
@@ -1231,7 +1781,7 @@ func TestCreateBindingByInstanceID_Rebind(t *testing.T) {
 	}
 
 	// Create principals in store (each needs unique PubkeyFP)
-	sqlStore := gw.store.(*store.SQLiteStore)
+	sqlStore := gw.store.(*store.SQLStore)
 	for _, p := range []struct{ id, name, fp string }{
 		{"agent-1", "first-agent", "aaaa1111aaaa1111aaaa1111aaaa1111aaaa1111aaaa1111aaaa1111aaaa1111"},
 		{"agent-2", "second-agent", "bbbb2222bbbb2222bbbb2222bbbb2222bbbb2222bbbb2222bbbb2222bbbb2222"},
@@ -1448,6 +1998,106 @@ func TestListBindings_IncludesWorkingDir(t *testing.T) {
 	}
 }
 
+// TestListBindings_AgentStatus tests that listed bindings carry the agent's
+// stored name and an online, grace, or offline status.
+func TestListBindings_AgentStatus(t *testing.T) {
+	gw := newTestGatewayWithAgentForBinding(t, "inst-online", "", "agent-online")
+	sqlStore := gw.store.(*store.SQLStore)
+	ctx := context.Background()
+
+	// createTestBindingV2 creates principals with an empty pubkey
+	// fingerprint, which must be unique, so create these up front.
+	for _, id := range []string{"agent-grace", "agent-offline", "agent-deleted"} {
+		if err := sqlStore.CreatePrincipal(ctx, &store.Principal{
+			ID: id, Type: store.PrincipalTypeAgent, PubkeyFP: "fp-" + id, DisplayName: id,
+			Status: store.PrincipalStatusApproved, CreatedAt: time.Now(),
+		}); err != nil {
+			t.Fatalf("CreatePrincipal: %v", err)
+		}
+	}
+	createTestBindingV2(t, gw, "slack", "C-online", "agent-online")
+	createTestBindingV2(t, gw, "slack", "C-grace", "agent-grace")
+	createTestBindingV2(t, gw, "slack", "C-offline", "agent-offline")
+	createTestBindingV2(t, gw, "matrix", "!deleted:server", "agent-deleted")
+
+	sessions := []*store.AgentSession{
+		{ID: "sess-grace", TokenHash: "h-grace", AgentID: "agent-grace", PrincipalID: "agent-grace", LastSeenAt: time.Now().Add(-time.Minute)},
+		{ID: "sess-offline", TokenHash: "h-offline", AgentID: "agent-offline", PrincipalID: "agent-offline", LastSeenAt: time.Now().Add(-time.Hour)},
+	}
+	for _, sess := range sessions {
+		if err := sqlStore.CreateAgentSession(ctx, sess); err != nil {
+			t.Fatalf("CreateAgentSession: %v", err)
+		}
+	}
+	if err := sqlStore.DeletePrincipal(ctx, "agent-deleted"); err != nil {
+		t.Fatalf("DeletePrincipal: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/bindings", nil)
+	w := httptest.NewRecorder()
+	gw.handleBindings(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list bindings: got status %d. Body: %s", w.Code, w.Body.String())
+	}
+	var resp ListBindingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	want := map[string]struct {
+		name   string
+		status string
+	}{
+		"C-online":        {"test-agent", admin.AgentStatusOnline},
+		"C-grace":         {"agent-grace", admin.AgentStatusGrace},
+		"C-offline":       {"agent-offline", admin.AgentStatusOffline},
+		"!deleted:server": {admin.DeletedAgentName, admin.AgentStatusOffline},
+	}
+	if len(resp.Bindings) != len(want) {
+		t.Fatalf("expected %d bindings, got %d", len(want), len(resp.Bindings))
+	}
+	for _, b := range resp.Bindings {
+		w, ok := want[b.ChannelID]
+		if !ok {
+			t.Errorf("unexpected binding %q", b.ChannelID)
+			continue
+		}
+		if b.AgentName != w.name || b.AgentStatus != w.status {
+			t.Errorf("%s: got name %q status %q, want %q %q", b.ChannelID, b.AgentName, b.AgentStatus, w.name, w.status)
+		}
+		if b.AgentOnline != (w.status == admin.AgentStatusOnline) {
+			t.Errorf("%s: agent_online = %v with status %q", b.ChannelID, b.AgentOnline, b.AgentStatus)
+		}
+		if _, err := time.Parse(time.RFC3339, b.CreatedAt); err != nil {
+			t.Errorf("%s: created_at %q is not RFC3339", b.ChannelID, b.CreatedAt)
+		}
+	}
+
+	// frontend alone filters the list
+	req = httptest.NewRequest(http.MethodGet, "/api/bindings?frontend=matrix", nil)
+	w = httptest.NewRecorder()
+	gw.handleBindings(w, req)
+	resp = ListBindingsResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Bindings) != 1 || resp.Bindings[0].ChannelID != "!deleted:server" {
+		t.Errorf("frontend=matrix returned %+v, want only the matrix binding", resp.Bindings)
+	}
+
+	// the single-binding form reports the same status
+	req = httptest.NewRequest(http.MethodGet, "/api/bindings?frontend=slack&channel_id=C-grace", nil)
+	w = httptest.NewRecorder()
+	gw.handleBindings(w, req)
+	var single SingleBindingResponse
+	if err := json.NewDecoder(w.Body).Decode(&single); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if single.AgentStatus != admin.AgentStatusGrace || single.Online || single.AgentName != "agent-grace" {
+		t.Errorf("single binding = %+v, want agent-grace in grace", single)
+	}
+}
+
 // Agent History endpoint tests
 
 func TestHandleAgentHistory_AgentNotConnected(t *testing.T) {
@@ -1473,6 +2123,28 @@ func TestHandleAgentHistory_AgentNotConnected(t *testing.T) {
 	}
 }
 
+func TestHandleAgentHistory_TokenLimitedToOtherAgents(t *testing.T) {
+	gw := newTestGateway(t)
+	scoped := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/agents/agent-2/history", nil)
+		return req.WithContext(auth.WithAuth(req.Context(), &auth.AuthContext{PrincipalID: "client-1", AgentIDs: []string{"agent-1"}}))
+	}
+
+	rec := httptest.NewRecorder()
+	gw.handleAgentHistory(rec, scoped())
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403 for an agent outside the token's list", rec.Code)
+	}
+
+	req := scoped()
+	req.SetPathValue("id", "agent-2")
+	_, err := gw.listAgentHistoryV1(req, httpapi.Page{Limit: 50})
+	var apiErr *httpapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusForbidden {
+		t.Errorf("v1 error = %v, want 403", err)
+	}
+}
+
 func TestHandleAgentHistory_InvalidLimit(t *testing.T) {
 	gw := newTestGatewayWithMockManager(t)
 
@@ -1558,9 +2230,9 @@ func TestHandleAgentHistory_WithEvents(t *testing.T) {
 	gw := newTestGatewayWithMockManagerAndStore(t)
 
 	// Add some events for the test agent using ConversationKey = agent ID
-	sqlStore, ok := gw.store.(*store.SQLiteStore)
+	sqlStore, ok := gw.store.(*store.SQLStore)
 	if !ok {
-		t.Fatalf("store is not *SQLiteStore")
+		t.Fatalf("store is not *SQLStore")
 	}
 
 	ctx := context.Background()
@@ -1719,7 +2391,7 @@ func TestHandleUsageStats_WithData(t *testing.T) {
 	ctx := context.Background()
 
 	// Create a thread for usage records
-	sqlStore := gw.store.(*store.SQLiteStore)
+	sqlStore := gw.store.(*store.SQLStore)
 	thread := &store.Thread{
 		ID:           "thread-usage-api",
 		FrontendName: "test",
@@ -1772,7 +2444,7 @@ func TestHandleUsageStats_FilterByAgent(t *testing.T) {
 	ctx := context.Background()
 
 	// Create a thread
-	sqlStore := gw.store.(*store.SQLiteStore)
+	sqlStore := gw.store.(*store.SQLStore)
 	thread := &store.Thread{
 		ID:           "thread-filter-api",
 		FrontendName: "test",
@@ -1876,7 +2548,7 @@ func TestHandleThreadUsage_Empty(t *testing.T) {
 	ctx := context.Background()
 
 	// Create a thread with no usage
-	sqlStore := gw.store.(*store.SQLiteStore)
+	sqlStore := gw.store.(*store.SQLStore)
 	thread := &store.Thread{
 		ID:           "00000000-0000-0000-0000-000000000002",
 		FrontendName: "test",
@@ -1907,12 +2579,46 @@ func TestHandleThreadUsage_Empty(t *testing.T) {
 	assert.Empty(t, resp.Usage)
 }
 
+func TestHandleThreadRoutes_RedirectsMergedThread(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx := context.Background()
+	sqlStore := gw.store.(*store.SQLStore)
+
+	targetID := "00000000-0000-0000-0000-00000000000a"
+	sourceID := "00000000-0000-0000-0000-00000000000b"
+	for _, id := range []string{targetID, sourceID} {
+		if err := sqlStore.CreateThread(ctx, &store.Thread{
+			ID: id, FrontendName: "test", ExternalID: "ext-" + id, AgentID: "agent-001",
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}); err != nil {
+			t.Fatalf("failed to create thread: %v", err)
+		}
+	}
+	if _, err := sqlStore.MergeThreads(ctx, targetID, []string{sourceID}); err != nil {
+		t.Fatalf("failed to merge threads: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/threads/"+sourceID+"/messages?limit=5", nil)
+	rec := httptest.NewRecorder()
+	gw.handleThreadRoutes(rec, req)
+
+	assert.Equal(t, http.StatusPermanentRedirect, rec.Code)
+	assert.Equal(t, "/api/threads/"+targetID+"/messages?limit=5", rec.Header().Get("Location"))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/threads/"+sourceID+"/usage", nil)
+	rec = httptest.NewRecorder()
+	gw.handleThreadRoutes(rec, req)
+
+	assert.Equal(t, http.StatusPermanentRedirect, rec.Code)
+	assert.Equal(t, "/api/threads/"+targetID+"/usage", rec.Header().Get("Location"))
+}
+
 func TestHandleThreadUsage_WithData(t *testing.T) {
 	gw := newTestGateway(t)
 	ctx := context.Background()
 
 	threadID := "00000000-0000-0000-0000-000000000003"
-	sqlStore := gw.store.(*store.SQLiteStore)
+	sqlStore := gw.store.(*store.SQLStore)
 
 	// Create thread
 	thread := &store.Thread{
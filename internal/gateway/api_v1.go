@@ -0,0 +1,395 @@
+// ABOUTME: The versioned /api/v1 routes, each recorded for the OpenAPI document
+// ABOUTME: List endpoints use the shared httpapi envelope with standard limit/cursor paging
+
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/builtins"
+	"github.com/2389/coven-gateway/internal/client"
+	"github.com/2389/coven-gateway/internal/httpapi"
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/transcript"
+	"github.com/2389/coven-gateway/internal/usage"
+)
+
+// v1Limits is the page size range shared by the v1 list endpoints.
+var v1Limits = httpapi.Limits{Default: 50, Max: 500}
+
+// apiRoutes are the HTTP API registrars, one per middleware stack. All of
+// them record into the same httpapi.Routes, which the document is built from.
+type apiRoutes struct {
+	user   *httpapi.Routes // authenticated, default rate limit
+	admin  *httpapi.Routes // authenticated, default rate limit, admin principals only
+	send   *httpapi.Routes // authenticated, send rate limit
+	ws     *httpapi.Routes // bearer token or web admin session, default rate limit
+	public *httpapi.Routes // default rate limit only
+}
+
+// handleAdmin registers an operation only admin principals may call.
+func (a *apiRoutes) handleAdmin(op httpapi.Operation, h http.Handler) {
+	op.Admin = true
+	a.admin.Handle(op, h)
+}
+
+// v1 serves an /api/v1 operation with the handler of its legacy /api route.
+func v1(h http.HandlerFunc) http.Handler {
+	return httpapi.Unversioned(h)
+}
+
+// Query parameters shared by several operations.
+var (
+	agentIDQuery = httpapi.Param{Name: "agent_id", Description: "Only this agent"}
+	sinceQuery   = httpapi.Param{Name: "since", Description: "Start time, RFC3339 or epoch seconds or milliseconds"}
+	untilQuery   = httpapi.Param{Name: "until", Description: "End time, RFC3339 or epoch seconds or milliseconds"}
+	bindingQuery = []httpapi.Param{
+		{Name: "frontend", Description: "Frontend of the binding", Required: true},
+		{Name: "channel_id", Description: "Channel of the binding", Required: true},
+	}
+)
+
+// registerV1Routes registers the /api/v1 operations. Most are served by the
+// handler of the legacy route they replace; list endpoints use the shared
+// httpapi list envelope.
+func (g *Gateway) registerV1Routes(r *apiRoutes) {
+	httpapi.HandleList(r.user, httpapi.Operation{
+		Path: "/api/v1/agents", Tag: "Agents", Summary: "List connected agents",
+		Query: []httpapi.Param{
+			{Name: "workspace", Description: "Only agents that registered this workspace"},
+			{Name: "tag", Description: "Only agents carrying this key=value tag; repeat to require several"},
+		},
+	}, v1Limits, g.listAgentsV1)
+	httpapi.HandleList(r.user, httpapi.Operation{
+		Path: "/api/v1/agents/{id}/history", Tag: "Agents", Summary: "List an agent's conversation events",
+	}, v1Limits, g.listAgentHistoryV1)
+	r.send.Handle(httpapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/agents/{id}/send", Tag: "Agents", Summary: "Send a message to an agent",
+		Request: SendToAgentRequest{}, Events: sendEvents,
+	}, v1(g.handleAgentRoutes))
+
+	r.send.Handle(httpapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/send", Tag: "Messages", Summary: "Send a message and stream the response",
+		Description: "The agent is chosen by agent_id, by the binding of frontend and channel_id, by tags, or by capability. " +
+			"A multipart/form-data body takes the same fields as form fields, and files as attachment parts.",
+		Request: SendMessageRequest{}, Events: sendEvents,
+	}, v1(g.handleSendMessage))
+	r.send.Handle(httpapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/send/broadcast", Tag: "Messages", Summary: "Send a message to every agent of a group",
+		Description: "The responses of all agents share one stream. Every event's data also carries agent_id, and principal_id when known.",
+		Request:     BroadcastRequest{}, Events: broadcastEvents,
+	}, v1(g.handleBroadcast))
+
+	httpapi.HandleList(r.user, httpapi.Operation{
+		Path: "/api/v1/bindings", Tag: "Bindings", Summary: "List channel bindings",
+		Query: []httpapi.Param{{Name: "frontend", Description: "Only this frontend's bindings"}},
+	}, v1Limits, g.listBindingsV1)
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/bindings/{frontend}/{channel_id}", Tag: "Bindings", Summary: "Get a channel's binding",
+		Response: SingleBindingResponse{},
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.handleGetSingleBinding(w, r, r.PathValue("frontend"), r.PathValue("channel_id"))
+	}))
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/bindings", Tag: "Bindings", Summary: "Bind a channel to an agent",
+		Request: CreateBindingRequest{}, Response: CreateBindingResponse{}, Status: http.StatusCreated,
+	}, v1(g.handleCreateBinding))
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodPatch, Path: "/api/v1/bindings", Tag: "Bindings", Summary: "Update a channel's binding",
+		Query: bindingQuery, Request: UpdateBindingRequest{}, Response: SingleBindingResponse{},
+	}, v1(g.handleUpdateBinding))
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodDelete, Path: "/api/v1/bindings", Tag: "Bindings", Summary: "Unbind a channel",
+		Query: bindingQuery, Status: http.StatusNoContent,
+	}, v1(g.handleDeleteBinding))
+
+	httpapi.HandleList(r.user, httpapi.Operation{
+		Path: "/api/v1/threads", Tag: "Threads", Summary: "List recent threads",
+		Description: "Pinned threads come first, then the most recently active. Merged threads are left out.",
+		Query: []httpapi.Param{
+			agentIDQuery,
+			{Name: "include_archived", Description: "true to list archived threads too"},
+		},
+	}, v1Limits, g.listThreadsV1)
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodPatch, Path: "/api/v1/threads/{id}", Tag: "Threads", Summary: "Update a thread",
+		Request: UpdateThreadRequest{}, Response: ThreadResponse{},
+	}, v1(g.handleThreadRoutes))
+	httpapi.HandleList(r.user, httpapi.Operation{
+		Path: "/api/v1/threads/{id}/messages", Tag: "Threads", Summary: "List a thread's messages",
+		Description: "The first page holds the newest messages. Merged threads redirect to the thread they were merged into.",
+	}, v1Limits, g.listThreadMessagesV1)
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/threads/{id}/export", Tag: "Threads", Summary: "Export a thread's transcript",
+		Description: "format=json returns this document; format=markdown, the default, returns text/markdown with the same content " +
+			"as readable turns. Stored secret values are masked. The response is streamed as an attachment.",
+		Query:    []httpapi.Param{{Name: "format", Description: "markdown (default) or json"}},
+		Response: transcript.Document{},
+	}, v1(g.handleThreadRoutes))
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/threads/{id}/usage", Tag: "Threads", Summary: "Get a thread's token usage",
+		Response: ThreadUsageResponse{},
+	}, v1(g.handleThreadRoutes))
+
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/stats/usage", Tag: "Usage", Summary: "Get aggregate token usage",
+		Query: []httpapi.Param{agentIDQuery, sinceQuery, untilQuery}, Response: UsageStatsResponse{},
+	}, v1(g.handleUsageStats))
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/usage/summary", Tag: "Usage", Summary: "Summarize token usage and cost",
+		Query: []httpapi.Param{
+			{Name: "group_by", Description: "agent (default) or day"}, agentIDQuery, sinceQuery, untilQuery,
+		},
+		Response: usage.Summary{},
+	}, v1(g.handleUsageSummary))
+
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/tools/approve", Tag: "Tools", Summary: "Approve or deny a tool call",
+		Request: ToolApprovalRequestBody{}, Response: ToolApprovalResponse{},
+	}, v1(g.handleToolApproval))
+
+	httpapi.HandleList(r.user, httpapi.Operation{
+		Path: "/api/v1/questions", Tag: "Questions", Summary: "List unanswered questions with their context",
+		Query: []httpapi.Param{agentIDQuery},
+	}, v1Limits, g.listQuestionsV1)
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/questions/pending", Tag: "Questions", Summary: "List stored unanswered questions",
+		Query: []httpapi.Param{agentIDQuery}, Response: QuestionsResponse{},
+	}, v1(g.handlePendingQuestions))
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/questions/answer", Tag: "Questions", Summary: "Answer a question",
+		Request: AnswerQuestionRequestBody{}, Response: AnswerQuestionResponse{},
+	}, v1(g.handleAnswerQuestion))
+
+	httpapi.HandleList(r.user, httpapi.Operation{
+		Path: "/api/v1/deliveries/dead-letter", Tag: "Deliveries", Summary: "List deliveries that ran out of retries",
+		Query: []httpapi.Param{{Name: "frontend", Description: "Only this frontend's deliveries"}},
+	}, v1Limits, g.listDeadLettersV1)
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/deliveries/stats", Tag: "Deliveries", Summary: "Get delivery statistics",
+		Query: []httpapi.Param{sinceQuery}, Response: DeliveryStatsResponse{},
+	}, v1(g.handleDeliveryRoutes))
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/deliveries/{request_id}/ack", Tag: "Deliveries", Summary: "Acknowledge a delivery",
+		Response: DeliveryResponse{},
+	}, v1(g.handleDeliveryRoutes))
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/deliveries/{request_id}/nack", Tag: "Deliveries", Summary: "Report a failed delivery",
+		Request: DeliveryNackRequest{}, Response: DeliveryResponse{},
+	}, v1(g.handleDeliveryRoutes))
+
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/requests/{request_id}/stream", Tag: "Requests", Summary: "Resume a send's stream",
+		Description: "Replays the events after the one named by the Last-Event-ID header, then follows live events.",
+		Query:       []httpapi.Param{{Name: "last_event_id", Type: "integer", Description: "For clients that cannot set Last-Event-ID"}},
+		Events:      sendEvents,
+	}, v1(g.handleRequestRoutes))
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/requests/{request_id}/cancel", Tag: "Requests", Summary: "Cancel a request",
+		Response: CancelRequestResponse{}, Status: http.StatusAccepted,
+	}, v1(g.handleRequestRoutes))
+
+	r.user.Handle(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/attachments/{id}", Tag: "Attachments", Summary: "Download an attachment",
+		ContentType: "application/octet-stream",
+	}, v1(g.handleGetAttachment))
+
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/schedules", Tag: "Schedules", Summary: "List scheduled messages",
+		Query:    []httpapi.Param{{Name: "status", Description: "pending, done or errored"}},
+		Response: ListSchedulesResponse{},
+	}, v1(g.handleSchedules))
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/schedules", Tag: "Schedules", Summary: "Schedule a message",
+		Request: CreateScheduleRequest{}, Response: ScheduleResponse{}, Status: http.StatusCreated,
+	}, v1(g.handleSchedules))
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/schedules/{id}", Tag: "Schedules", Summary: "Get a scheduled message",
+		Response: ScheduleResponse{},
+	}, v1(g.handleSchedules))
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodDelete, Path: "/api/v1/schedules/{id}", Tag: "Schedules", Summary: "Delete a scheduled message",
+		Status: http.StatusNoContent,
+	}, v1(g.handleSchedules))
+
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/groups", Tag: "Groups", Summary: "List agent groups",
+		Response: ListAgentGroupsResponse{},
+	}, v1(g.handleGroups))
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/groups", Tag: "Groups", Summary: "Create an agent group",
+		Request: AgentGroupRequest{}, Response: AgentGroupResponse{}, Status: http.StatusCreated,
+	}, v1(g.handleGroups))
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/groups/{name}", Tag: "Groups", Summary: "Get an agent group",
+		Response: AgentGroupResponse{},
+	}, v1(g.handleGroups))
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodPut, Path: "/api/v1/groups/{name}", Tag: "Groups", Summary: "Replace an agent group's members",
+		Request: AgentGroupRequest{}, Response: AgentGroupResponse{},
+	}, v1(g.handleGroups))
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodDelete, Path: "/api/v1/groups/{name}", Tag: "Groups", Summary: "Delete an agent group",
+		Status: http.StatusNoContent,
+	}, v1(g.handleGroups))
+
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/search", Tag: "Search", Summary: "Search messages",
+		Query: []httpapi.Param{
+			{Name: "q", Description: "Search terms", Required: true},
+			{Name: "thread_id", Description: "Only this thread"},
+			agentIDQuery, sinceQuery, untilQuery,
+			{Name: "limit", Type: "integer", Description: "Results per page, default 50, at most 200"},
+			{Name: "offset", Type: "integer", Description: "Results to skip"},
+		},
+		Response: SearchResponse{},
+	}, v1(g.handleSearch))
+
+	httpapi.HandleList(r.admin, httpapi.Operation{
+		Path: "/api/v1/admin/audit", Tag: "Audit", Summary: "List the admin audit log",
+		Description: "Newest first. Admin principals only.",
+		Query:       auditQuery, Admin: true,
+	}, v1Limits, g.listAuditV1)
+
+	r.handleAdmin(httpapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/admin/maintenance/prune", Tag: "Maintenance", Summary: "Prune old data now",
+		Query:    []httpapi.Param{{Name: "vacuum", Description: "none, incremental or full; overrides retention.vacuum"}},
+		Response: PruneResponse{},
+	}, v1(g.handlePrune))
+
+	r.ws.Handle(httpapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/ws", Tag: "WebSocket", Summary: "Send over a WebSocket",
+		Description: "Clients send send and cancel frames; each send's events come back as frames typed by their SSE event name.",
+		WebSocket:   true, Frame: client.WSFrame{}, Events: sendEvents,
+	}, v1(g.handleWebSocket))
+}
+
+// listAgentsV1 handles GET /api/v1/agents?workspace=X&tag=K=V.
+func (g *Gateway) listAgentsV1(r *http.Request, page httpapi.Page) (httpapi.List[AgentInfoResponse], error) {
+	tags, err := agent.ParseTagFilter(r.URL.Query()["tag"])
+	if err != nil {
+		return httpapi.List[AgentInfoResponse]{}, httpapi.Errorf(http.StatusBadRequest, "%v", err)
+	}
+	return httpapi.Paginate(g.listAgentResponses(r.URL.Query().Get("workspace"), tags), page)
+}
+
+// listAgentHistoryV1 handles GET /api/v1/agents/{id}/history, oldest first.
+// The usage summary from the legacy route is available from /api/stats/usage.
+func (g *Gateway) listAgentHistoryV1(r *http.Request, page httpapi.Page) (httpapi.List[AgentHistoryEvent], error) {
+	if !g.tokenAllowsAgentID(r.Context(), r.PathValue("id")) {
+		return httpapi.List[AgentHistoryEvent]{}, httpapi.Errorf(http.StatusForbidden, "%s", errTokenAgentDenied)
+	}
+	result, err := g.store.GetEvents(r.Context(), store.GetEventsParams{
+		ConversationKey: r.PathValue("id"),
+		Limit:           page.Limit,
+		Cursor:          page.Cursor,
+	})
+	if errors.Is(err, store.ErrInvalidCursor) {
+		return httpapi.List[AgentHistoryEvent]{}, httpapi.ErrInvalidCursor
+	}
+	if err != nil {
+		return httpapi.List[AgentHistoryEvent]{}, fmt.Errorf("getting events: %w", err)
+	}
+
+	list := httpapi.List[AgentHistoryEvent]{Items: make([]AgentHistoryEvent, len(result.Events))}
+	for i, evt := range result.Events {
+		list.Items[i] = eventToHistoryEvent(evt)
+	}
+	if result.HasMore {
+		list.NextCursor = httpapi.EncodeCursor(result.NextCursor)
+	}
+	return list, nil
+}
+
+// listBindingsV1 handles GET /api/v1/bindings.
+func (g *Gateway) listBindingsV1(r *http.Request, page httpapi.Page) (httpapi.List[BindingResponse], error) {
+	bindings, err := g.listBindingResponses(r.Context(), r.URL.Query().Get("frontend"))
+	if err != nil {
+		return httpapi.List[BindingResponse]{}, fmt.Errorf("listing bindings: %w", err)
+	}
+	return httpapi.Paginate(bindings, page)
+}
+
+// listThreadsV1 handles GET /api/v1/threads?agent_id=X&include_archived=true.
+// Like the legacy route it covers the 1000 most recently active threads.
+func (g *Gateway) listThreadsV1(r *http.Request, page httpapi.Page) (httpapi.List[ThreadResponse], error) {
+	agentID := r.URL.Query().Get("agent_id")
+	if agentID != "" && !g.tokenAllowsAgentID(r.Context(), agentID) {
+		return httpapi.List[ThreadResponse]{}, httpapi.Errorf(http.StatusForbidden, "%s", errTokenAgentDenied)
+	}
+	threads, err := g.listThreadResponses(r.Context(), agentID, r.URL.Query().Get("include_archived") == "true")
+	if err != nil {
+		return httpapi.List[ThreadResponse]{}, fmt.Errorf("listing threads: %w", err)
+	}
+	return httpapi.Paginate(threads, page)
+}
+
+// listThreadMessagesV1 handles GET /api/v1/threads/{id}/messages. The first
+// page holds the newest messages; next_cursor pages back through older ones.
+// Each page is in chronological order. Merged threads redirect to their target.
+func (g *Gateway) listThreadMessagesV1(r *http.Request, page httpapi.Page) (httpapi.List[MessageResponse], error) {
+	threadID := r.PathValue("id")
+	if _, err := uuid.Parse(threadID); err != nil {
+		return httpapi.List[MessageResponse]{}, httpapi.Errorf(http.StatusBadRequest, "invalid thread_id format")
+	}
+	thread, err := g.store.GetThread(r.Context(), threadID)
+	if errors.Is(err, store.ErrNotFound) {
+		return httpapi.List[MessageResponse]{}, httpapi.Errorf(http.StatusNotFound, "thread not found")
+	}
+	if err != nil {
+		return httpapi.List[MessageResponse]{}, fmt.Errorf("getting thread: %w", err)
+	}
+	if !g.tokenAllowsAgentID(r.Context(), thread.AgentID) {
+		return httpapi.List[MessageResponse]{}, httpapi.Errorf(http.StatusForbidden, "%s", errTokenAgentDenied)
+	}
+	if thread.MergedInto != "" {
+		return httpapi.List[MessageResponse]{}, httpapi.Redirect("/api/v1/threads/" + thread.MergedInto + "/messages")
+	}
+
+	events, next, err := g.store.GetThreadEventsPage(r.Context(), threadID, page.Limit, page.Cursor)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		return httpapi.List[MessageResponse]{}, httpapi.ErrInvalidCursor
+	}
+	if err != nil {
+		return httpapi.List[MessageResponse]{}, fmt.Errorf("getting thread events: %w", err)
+	}
+
+	list := httpapi.List[MessageResponse]{Items: make([]MessageResponse, len(events))}
+	for i, evt := range events {
+		list.Items[i] = g.eventToMessageResponse(threadID, evt)
+	}
+	if next != "" {
+		list.NextCursor = httpapi.EncodeCursor(next)
+	}
+	return list, nil
+}
+
+// listQuestionsV1 handles GET /api/v1/questions?agent_id=X.
+func (g *Gateway) listQuestionsV1(r *http.Request, page httpapi.Page) (httpapi.List[builtins.PendingQuestion], error) {
+	if g.questionRouter == nil {
+		return httpapi.List[builtins.PendingQuestion]{}, httpapi.Errorf(http.StatusServiceUnavailable, "question router not configured")
+	}
+	return httpapi.Paginate(g.pendingQuestions(r.Context(), r.URL.Query().Get("agent_id")), page)
+}
+
+// listDeadLettersV1 handles GET /api/v1/deliveries/dead-letter?frontend=X,
+// newest first. Like the legacy route it covers the 500 most recent entries.
+func (g *Gateway) listDeadLettersV1(r *http.Request, page httpapi.Page) (httpapi.List[DeliveryResponse], error) {
+	if g.deliveries == nil {
+		return httpapi.List[DeliveryResponse]{}, httpapi.Errorf(http.StatusServiceUnavailable, "delivery tracking not available")
+	}
+	deliveries, err := g.deliveries.store.ListDeadLetters(r.Context(), r.URL.Query().Get("frontend"), 500)
+	if err != nil {
+		return httpapi.List[DeliveryResponse]{}, fmt.Errorf("listing dead letters: %w", err)
+	}
+	items := make([]DeliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		items[i] = deliveryToResponse(d)
+	}
+	return httpapi.Paginate(items, page)
+}
@@ -0,0 +1,179 @@
+// ABOUTME: Conformance tests for the /api/v1 list endpoints
+// ABOUTME: Every list route must use the shared envelope, limit/cursor validation, and have a legacy counterpart
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/httpapi"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// v1TestThreadID is a thread seeded with messages for the conformance tests.
+const v1TestThreadID = "00000000-0000-0000-0000-0000000000f1"
+
+// legacyListRoutes maps each unversioned list route to its v1 successor.
+var legacyListRoutes = map[string]string{
+	"/api/agents":                 "/api/v1/agents",
+	"/api/agents/{id}/history":    "/api/v1/agents/{id}/history",
+	"/api/bindings":               "/api/v1/bindings",
+	"/api/threads":                "/api/v1/threads",
+	"/api/threads/{id}/messages":  "/api/v1/threads/{id}/messages",
+	"/api/questions":              "/api/v1/questions",
+	"/api/deliveries/dead-letter": "/api/v1/deliveries/dead-letter",
+}
+
+// v1OnlyListRoutes are the v1 list routes with no legacy counterpart.
+var v1OnlyListRoutes = []string{"/api/v1/admin/audit"}
+
+// newV1TestServer returns a gateway whose HTTP API routes (auth disabled)
+// are mounted on a fresh mux, with one thread of seven messages.
+func newV1TestServer(t *testing.T) (*Gateway, http.Handler) {
+	t.Helper()
+	gw := newTestGateway(t)
+	sqlStore := gw.store.(*store.SQLStore)
+	ctx := context.Background()
+
+	if err := sqlStore.CreateThread(ctx, &store.Thread{
+		ID: v1TestThreadID, FrontendName: "test", ExternalID: "ext-v1", AgentID: "agent-001",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	base := time.Now().UTC().Truncate(time.Second)
+	threadID := v1TestThreadID
+	for i := range 7 {
+		if err := sqlStore.SaveEvent(ctx, &store.LedgerEvent{
+			ID: fmt.Sprintf("evt-%d", i), ConversationKey: "agent-001", ThreadID: &threadID,
+			Direction: store.EventDirectionInbound, Author: "user", Timestamp: base.Add(time.Duration(i) * time.Second),
+			Type: store.EventTypeMessage, Text: ptrString(fmt.Sprintf("message %d", i)),
+		}); err != nil {
+			t.Fatalf("SaveEvent: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	gw.registerHTTPAPIRoutes(mux, sqlStore, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return gw, mux
+}
+
+func v1Path(pattern string) string {
+	if strings.HasPrefix(pattern, "/api/v1/threads/") || strings.HasPrefix(pattern, "/api/threads/") {
+		return strings.Replace(pattern, "{id}", v1TestThreadID, 1)
+	}
+	return strings.Replace(pattern, "{id}", "agent-001", 1)
+}
+
+func getV1(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestAPIV1_EveryListRouteUsesEnvelope(t *testing.T) {
+	gw, mux := newV1TestServer(t)
+
+	lists := gw.apiRoutes.Lists()
+	for legacy, successor := range legacyListRoutes {
+		if !slices.Contains(lists, successor) {
+			t.Errorf("legacy list route %s has no v1 successor registered through httpapi.HandleList", legacy)
+		}
+	}
+	if len(lists) != len(legacyListRoutes)+len(v1OnlyListRoutes) {
+		t.Errorf("v1 list routes = %v, want one per legacy list route and %v", lists, v1OnlyListRoutes)
+	}
+
+	for _, pattern := range lists {
+		t.Run(pattern, func(t *testing.T) {
+			path := v1Path(pattern)
+
+			rec := getV1(t, mux, path+"?limit=2")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+			var items []json.RawMessage
+			if err := json.Unmarshal(body["items"], &items); err != nil || items == nil {
+				t.Errorf("items = %s, want a JSON array", body["items"])
+			}
+			if len(items) > 2 {
+				t.Errorf("got %d items, want at most limit=2", len(items))
+			}
+			for key := range body {
+				if key != "items" && key != "next_cursor" && key != "total" {
+					t.Errorf("unexpected envelope key %q", key)
+				}
+			}
+
+			for _, bad := range []string{"?limit=0", "?limit=abc", "?cursor=not-a-cursor", "?cursor=" + httpapi.EncodeCursor("bogus")} {
+				if rec := getV1(t, mux, path+bad); rec.Code != http.StatusBadRequest {
+					t.Errorf("GET %s%s = %d, want 400 (%s)", path, bad, rec.Code, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestAPIV1_LegacyListRoutesAreDeprecated(t *testing.T) {
+	_, mux := newV1TestServer(t)
+
+	for legacy, successor := range legacyListRoutes {
+		rec := getV1(t, mux, v1Path(legacy))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, body = %s", legacy, rec.Code, rec.Body.String())
+			continue
+		}
+		if rec.Header().Get("Deprecation") != "true" {
+			t.Errorf("GET %s lacks the Deprecation header", legacy)
+		}
+		if want := "<" + v1Path(successor) + `>; rel="successor-version"`; rec.Header().Get("Link") != want {
+			t.Errorf("GET %s Link = %q, want %q", legacy, rec.Header().Get("Link"), want)
+		}
+	}
+}
+
+func TestAPIV1_ThreadMessagesPageBackwards(t *testing.T) {
+	_, mux := newV1TestServer(t)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	url := srv.URL + "/api/v1/threads/" + v1TestThreadID + "/messages?limit=3"
+	first, err := httpapi.FetchPage[MessageResponse](context.Background(), srv.Client(), url, "", nil)
+	if err != nil {
+		t.Fatalf("FetchPage: %v", err)
+	}
+	if len(first.Items) != 3 || first.Items[0].Content != "message 4" || first.Items[2].Content != "message 6" {
+		t.Fatalf("first page = %+v, want the newest three in order", first.Items)
+	}
+	if first.NextCursor == "" {
+		t.Fatal("first page has no next_cursor")
+	}
+
+	all, err := httpapi.FetchAll[MessageResponse](context.Background(), srv.Client(), url, nil)
+	if err != nil {
+		t.Fatalf("FetchAll: %v", err)
+	}
+	var got []string
+	for _, m := range all {
+		got = append(got, m.Content)
+	}
+	want := "message 4,message 5,message 6,message 1,message 2,message 3,message 0"
+	if strings.Join(got, ",") != want {
+		t.Errorf("pages = %s, want %s", strings.Join(got, ","), want)
+	}
+}
@@ -0,0 +1,222 @@
+// ABOUTME: Restricted AgentStream phase for agents whose principal awaits admin approval
+// ABOUTME: Pushes RegistrationStatus updates and resumes registration once approved
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+const (
+	pendingApprovalHint  = "principal is awaiting admin approval; keep this stream open and registration will complete automatically once approved"
+	approvedHint         = "principal approved; completing registration"
+	revokedHint          = "principal has been revoked"
+	errPendingApproval   = "agent is awaiting admin approval"
+	principalsAdminRoute = "/admin/principals"
+)
+
+// recvResult is a single outcome of stream.Recv.
+type recvResult struct {
+	msg *pb.AgentMessage
+	err error
+}
+
+// recvPump reads the agent stream on its own goroutine so the approval phase
+// can wait on inbound messages and admin decisions at the same time. Once the
+// agent is approved, the message loop keeps reading through the same pump.
+type recvPump struct {
+	ctx     context.Context
+	results chan recvResult
+}
+
+// newRecvPump starts reading from stream until it errors or its context ends.
+func newRecvPump(stream pb.CovenControl_AgentStreamServer) *recvPump {
+	p := &recvPump{
+		ctx:     stream.Context(),
+		results: make(chan recvResult),
+	}
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			select {
+			case p.results <- recvResult{msg: msg, err: err}:
+			case <-p.ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// recv returns the next inbound message, matching stream.Recv semantics.
+func (p *recvPump) recv() (*pb.AgentMessage, error) {
+	select {
+	case r := <-p.results:
+		return r.msg, r.err
+	case <-p.ctx.Done():
+		return nil, status.FromContextError(p.ctx.Err()).Err()
+	}
+}
+
+// awaitApproval keeps a pending agent's stream open in a restricted state until
+// an admin approves or revokes its principal. Returns (true, nil) when the agent
+// may proceed with normal registration; otherwise the stream should end with err.
+func (s *covenControlServer) awaitApproval(stream pb.CovenControl_AgentStreamServer, reg *pb.RegisterAgent, info agentRegistrationInfo, pump *recvPump) (bool, error) {
+	agentID := reg.GetAgentId()
+	principalID := info.principalID
+	authCtx := auth.FromContext(stream.Context())
+	decisions, err := s.gateway.agentManager.AddPending(agent.PendingAgent{
+		AgentID:      agentID,
+		Name:         reg.GetName(),
+		PrincipalID:  principalID,
+		ConnectedAt:  time.Now(),
+		Fingerprint:  s.principalFingerprint(stream.Context(), principalID),
+		Metadata:     info.metadata,
+		NewPrincipal: authCtx != nil && authCtx.NewPrincipal,
+	})
+	if err != nil {
+		if errors.Is(err, agent.ErrAgentAlreadyRegistered) {
+			return false, status.Errorf(codes.AlreadyExists, "agent %s already registered", agentID)
+		}
+		return false, status.Errorf(codes.Internal, "tracking pending agent: %v", err)
+	}
+	defer s.gateway.agentManager.RemovePending(agentID)
+
+	s.logger.Info("agent connected with pending principal, waiting for approval",
+		"agent_id", agentID,
+		"principal_id", principalID,
+	)
+
+	if err := s.sendRegistrationStatus(stream, pb.RegistrationState_REGISTRATION_STATE_PENDING, principalID, pendingApprovalHint); err != nil {
+		return false, err
+	}
+
+	// The principal may have been decided between authentication and AddPending.
+	if decision, ok := s.currentDecision(stream.Context(), principalID); ok {
+		return s.applyDecision(stream, principalID, decision)
+	}
+
+	for {
+		select {
+		case decision := <-decisions:
+			return s.applyDecision(stream, principalID, decision)
+		case r := <-pump.results:
+			if shouldContinue, grpcErr := s.checkRecvError(r.err, agentID); !shouldContinue {
+				return false, grpcErr
+			}
+			s.handleRestrictedMessage(stream, agentID, r.msg)
+		case <-stream.Context().Done():
+			return false, nil
+		}
+	}
+}
+
+// applyDecision tells the agent the outcome and reports whether it may proceed.
+func (s *covenControlServer) applyDecision(stream pb.CovenControl_AgentStreamServer, principalID string, decision agent.ApprovalDecision) (bool, error) {
+	if decision == agent.ApprovalApproved {
+		if err := s.sendRegistrationStatus(stream, pb.RegistrationState_REGISTRATION_STATE_APPROVED, principalID, approvedHint); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if err := s.sendRegistrationStatus(stream, pb.RegistrationState_REGISTRATION_STATE_REVOKED, principalID, revokedHint); err != nil {
+		return false, err
+	}
+	return false, status.Error(codes.PermissionDenied, revokedHint)
+}
+
+// currentDecision reads the principal's stored status, reporting a decision if
+// it is no longer pending.
+func (s *covenControlServer) currentDecision(ctx context.Context, principalID string) (agent.ApprovalDecision, bool) {
+	sqlStore, ok := s.gateway.store.(*store.SQLStore)
+	if !ok {
+		return 0, false
+	}
+	p, err := sqlStore.GetPrincipal(ctx, principalID)
+	if err != nil {
+		if errors.Is(err, store.ErrPrincipalNotFound) {
+			return agent.ApprovalRevoked, true
+		}
+		s.logger.Warn("failed to re-check pending principal", "principal_id", principalID, "error", err)
+		return 0, false
+	}
+	switch p.Status {
+	case store.PrincipalStatusPending:
+		return 0, false
+	case store.PrincipalStatusRevoked:
+		return agent.ApprovalRevoked, true
+	default:
+		return agent.ApprovalApproved, true
+	}
+}
+
+// principalFingerprint returns the SSH key fingerprint of a principal, shown
+// to admins deciding whether to approve it. Returns "" if it cannot be read.
+func (s *covenControlServer) principalFingerprint(ctx context.Context, principalID string) string {
+	sqlStore, ok := s.gateway.store.(*store.SQLStore)
+	if !ok {
+		return ""
+	}
+	p, err := sqlStore.GetPrincipal(ctx, principalID)
+	if err != nil {
+		s.logger.Warn("failed to load pending principal", "principal_id", principalID, "error", err)
+		return ""
+	}
+	return p.PubkeyFP
+}
+
+// handleRestrictedMessage processes traffic from an agent that is not yet approved.
+// Only heartbeats are accepted; work-related messages are refused.
+func (s *covenControlServer) handleRestrictedMessage(stream pb.CovenControl_AgentStreamServer, agentID string, msg *pb.AgentMessage) {
+	switch payload := msg.GetPayload().(type) {
+	case *pb.AgentMessage_Heartbeat:
+		s.logger.Debug("received heartbeat from agent awaiting approval",
+			"agent_id", agentID,
+			"timestamp_ms", payload.Heartbeat.GetTimestampMs(),
+		)
+	case *pb.AgentMessage_ExecutePackTool:
+		s.sendPackToolError(stream, payload.ExecutePackTool.GetRequestId(), errPendingApproval)
+	default:
+		s.logger.Warn("dropping message from agent awaiting approval", "agent_id", agentID)
+	}
+}
+
+// sendRegistrationStatus pushes the principal's approval state to the agent.
+func (s *covenControlServer) sendRegistrationStatus(stream pb.CovenControl_AgentStreamServer, state pb.RegistrationState, principalID, hint string) error {
+	msg := &pb.ServerMessage{
+		Payload: &pb.ServerMessage_RegistrationStatus{
+			RegistrationStatus: &pb.RegistrationStatus{
+				State:       state,
+				PrincipalId: principalID,
+				Hint:        hint,
+				AdminUrl:    s.principalsAdminURL(),
+			},
+		},
+	}
+	if err := stream.Send(msg); err != nil {
+		return status.Errorf(codes.Internal, "sending registration status: %v", err)
+	}
+	return nil
+}
+
+// principalsAdminURL returns where an admin can approve principals, if known.
+func (s *covenControlServer) principalsAdminURL() string {
+	if s.gateway.webAdminBaseURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(s.gateway.webAdminBaseURL, "/") + principalsAdminRoute
+}
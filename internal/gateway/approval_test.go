@@ -0,0 +1,289 @@
+// ABOUTME: Tests for the restricted AgentStream phase used while a principal awaits approval
+// ABOUTME: Drives a real gRPC stream through approve, revoke, and restricted-traffic scenarios
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// pendingAuthStream injects an auth context for a pending principal, standing
+// in for the SSH interceptor admitting an unapproved agent.
+type pendingAuthStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *pendingAuthStream) Context() context.Context { return s.ctx }
+
+// startPendingAgentServer serves CovenControl for gw with every stream
+// authenticated as the given pending principal, and returns a client.
+func startPendingAgentServer(t *testing.T, gw *Gateway, principalID string) pb.CovenControlClient {
+	t.Helper()
+
+	sqlStore, ok := gw.store.(*store.SQLStore)
+	if !ok {
+		t.Fatal("expected SQLStore")
+	}
+	if err := sqlStore.CreatePrincipal(context.Background(), &store.Principal{
+		ID:          principalID,
+		Type:        store.PrincipalTypeAgent,
+		PubkeyFP:    "fp-" + principalID,
+		DisplayName: "pending agent",
+		Status:      store.PrincipalStatusPending,
+		CreatedAt:   time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreatePrincipal: %v", err)
+	}
+
+	interceptor := func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := auth.WithAuth(ss.Context(), &auth.AuthContext{
+			PrincipalID:   principalID,
+			PrincipalType: string(store.PrincipalTypeAgent),
+			Pending:       true,
+		})
+		return handler(srv, &pendingAuthStream{ServerStream: ss, ctx: ctx})
+	}
+
+	server := grpc.NewServer(grpc.StreamInterceptor(interceptor))
+	pb.RegisterCovenControlServer(server, newCovenControlServer(gw, testLogger()))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewCovenControlClient(conn)
+}
+
+// connectPendingAgent registers agentID and expects the PENDING status push.
+func connectPendingAgent(t *testing.T, client pb.CovenControlClient, agentID string) pb.CovenControl_AgentStreamClient {
+	t.Helper()
+
+	stream, err := client.AgentStream(t.Context())
+	if err != nil {
+		t.Fatalf("AgentStream: %v", err)
+	}
+	if err := stream.Send(&pb.AgentMessage{
+		Payload: &pb.AgentMessage_Register{
+			Register: &pb.RegisterAgent{AgentId: agentID, Name: "pending-agent", Capabilities: []string{"chat"}},
+		},
+	}); err != nil {
+		t.Fatalf("Send registration: %v", err)
+	}
+
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	st := msg.GetRegistrationStatus()
+	if st == nil {
+		t.Fatalf("expected RegistrationStatus, got %T", msg.GetPayload())
+	}
+	if st.GetState() != pb.RegistrationState_REGISTRATION_STATE_PENDING {
+		t.Fatalf("state = %v, want PENDING", st.GetState())
+	}
+	if st.GetHint() == "" {
+		t.Error("expected a human-readable hint")
+	}
+	return stream
+}
+
+// waitForPending blocks until the manager reports agentID as waiting.
+func waitForPending(t *testing.T, mgr *agent.Manager, agentID string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, p := range mgr.ListPending() {
+			if p.AgentID == agentID {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("agent %s never appeared as pending", agentID)
+}
+
+// decide updates the principal status and pushes the decision, as the admin UI does.
+func decide(t *testing.T, gw *Gateway, principalID string, s store.PrincipalStatus, d agent.ApprovalDecision) {
+	t.Helper()
+	sqlStore := gw.store.(*store.SQLStore)
+	if err := sqlStore.UpdatePrincipalStatus(context.Background(), principalID, s); err != nil {
+		t.Fatalf("UpdatePrincipalStatus: %v", err)
+	}
+	if n := gw.agentManager.ResolvePending(principalID, d); n != 1 {
+		t.Fatalf("ResolvePending notified %d agents, want 1", n)
+	}
+}
+
+func TestAgentStream_PendingApprovedWhileConnected(t *testing.T) {
+	gw, err := New(testConfig(t), testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer gw.Shutdown(context.Background())
+
+	client := startPendingAgentServer(t, gw, "principal-approve")
+	stream := connectPendingAgent(t, client, "agent-approve")
+	waitForPending(t, gw.agentManager, "agent-approve")
+
+	if len(gw.agentManager.ListAgents()) != 0 {
+		t.Fatal("pending agent must not be routable before approval")
+	}
+	// Admins deciding on the agent see the key it registered with.
+	if p := gw.agentManager.ListPending()[0]; p.Fingerprint != "fp-principal-approve" || p.Metadata == nil {
+		t.Errorf("pending agent = %+v, want fingerprint and metadata", p)
+	}
+
+	decide(t, gw, "principal-approve", store.PrincipalStatusApproved, agent.ApprovalApproved)
+
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if got := msg.GetRegistrationStatus().GetState(); got != pb.RegistrationState_REGISTRATION_STATE_APPROVED {
+		t.Fatalf("state = %v, want APPROVED", got)
+	}
+
+	msg, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	welcome := msg.GetWelcome()
+	if welcome == nil {
+		t.Fatalf("expected Welcome after approval, got %T", msg.GetPayload())
+	}
+	if welcome.GetPrincipalId() != "principal-approve" {
+		t.Errorf("welcome.principal_id = %q, want principal-approve", welcome.GetPrincipalId())
+	}
+
+	// The approved agent now receives normal traffic on the same stream.
+	respCh, err := gw.agentManager.SendMessage(t.Context(), &agent.SendRequest{AgentID: "agent-approve", Content: "hello"})
+	if err != nil {
+		t.Fatalf("SendMessage after approval: %v", err)
+	}
+	msg, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	sm := msg.GetSendMessage()
+	if sm == nil {
+		t.Fatalf("expected SendMessage, got %T", msg.GetPayload())
+	}
+	if err := stream.Send(&pb.AgentMessage{Payload: &pb.AgentMessage_Response{Response: &pb.MessageResponse{
+		RequestId: sm.GetRequestId(),
+		Event:     &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "hi"}},
+	}}}); err != nil {
+		t.Fatalf("Send response: %v", err)
+	}
+	select {
+	case resp := <-respCh:
+		if resp.Event != agent.EventDone {
+			t.Errorf("event = %v, want done", resp.Event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for response")
+	}
+}
+
+func TestAgentStream_PendingRevokedWhileConnected(t *testing.T) {
+	gw, err := New(testConfig(t), testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer gw.Shutdown(context.Background())
+
+	client := startPendingAgentServer(t, gw, "principal-revoke")
+	stream := connectPendingAgent(t, client, "agent-revoke")
+	waitForPending(t, gw.agentManager, "agent-revoke")
+
+	decide(t, gw, "principal-revoke", store.PrincipalStatusRevoked, agent.ApprovalRevoked)
+
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if got := msg.GetRegistrationStatus().GetState(); got != pb.RegistrationState_REGISTRATION_STATE_REVOKED {
+		t.Fatalf("state = %v, want REVOKED", got)
+	}
+
+	_, err = stream.Recv()
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("stream error = %v, want PermissionDenied", err)
+	}
+	if len(gw.agentManager.ListPending()) != 0 {
+		t.Error("revoked agent should no longer be pending")
+	}
+}
+
+func TestAgentStream_PendingStreamIsRestricted(t *testing.T) {
+	gw, err := New(testConfig(t), testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer gw.Shutdown(context.Background())
+
+	client := startPendingAgentServer(t, gw, "principal-restricted")
+	stream := connectPendingAgent(t, client, "agent-restricted")
+	waitForPending(t, gw.agentManager, "agent-restricted")
+
+	// Messages cannot be routed to the agent while it waits.
+	_, err = gw.agentManager.SendMessage(t.Context(), &agent.SendRequest{AgentID: "agent-restricted", Content: "hello"})
+	if !errors.Is(err, agent.ErrAgentNotFound) {
+		t.Errorf("SendMessage error = %v, want ErrAgentNotFound", err)
+	}
+
+	// Heartbeats are tolerated; pack tool calls are refused.
+	if err := stream.Send(&pb.AgentMessage{Payload: &pb.AgentMessage_Heartbeat{Heartbeat: &pb.Heartbeat{TimestampMs: 1}}}); err != nil {
+		t.Fatalf("Send heartbeat: %v", err)
+	}
+	if err := stream.Send(&pb.AgentMessage{Payload: &pb.AgentMessage_ExecutePackTool{ExecutePackTool: &pb.ExecutePackTool{
+		RequestId: "tool-1",
+		ToolName:  "log_entry",
+		InputJson: "{}",
+	}}}); err != nil {
+		t.Fatalf("Send pack tool: %v", err)
+	}
+
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	result := msg.GetPackToolResult()
+	if result == nil {
+		t.Fatalf("expected PackToolResult, got %T", msg.GetPayload())
+	}
+	if result.GetRequestId() != "tool-1" || result.GetError() != errPendingApproval {
+		t.Errorf("pack tool result = %+v, want pending-approval error for tool-1", result)
+	}
+
+	// The agent can still be approved afterwards on the same stream.
+	decide(t, gw, "principal-restricted", store.PrincipalStatusApproved, agent.ApprovalApproved)
+	if msg, err = stream.Recv(); err != nil || msg.GetRegistrationStatus() == nil {
+		t.Fatalf("expected APPROVED status, got %v (err %v)", msg, err)
+	}
+	if msg, err = stream.Recv(); err != nil || msg.GetWelcome() == nil {
+		t.Fatalf("expected Welcome, got %v (err %v)", msg, err)
+	}
+}
@@ -0,0 +1,314 @@
+// ABOUTME: Message attachments: multipart/form-data uploads to POST /api/send and downloads from
+// ABOUTME: GET /api/attachments/{id}, limited to callers who can see the owning thread.
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// Attachment limits used when api.attachments leaves them unset.
+const (
+	defaultAttachmentMaxBytes = 10 << 20
+	defaultAttachmentMaxCount = 10
+)
+
+// defaultAttachmentTypes are accepted when api.attachments.allowed_types is unset.
+var defaultAttachmentTypes = []string{"image/*", "text/*", "application/pdf", "application/json"}
+
+// multipartFieldBytes bounds the non-file form fields of a multipart send.
+const multipartFieldBytes = 1 << 20
+
+var (
+	errAttachmentTooLarge = errors.New("attachment too large")
+	errAttachmentType     = errors.New("attachment content type not allowed")
+)
+
+// attachmentStore is what the attachment endpoints need from storage.
+type attachmentStore interface {
+	GetAttachment(ctx context.Context, id string) (*store.Attachment, error)
+	ThreadHasActor(ctx context.Context, threadID, principalID string) (bool, error)
+}
+
+// attachmentLimits are the effective api.attachments settings.
+type attachmentLimits struct {
+	maxBytes int64
+	maxCount int
+	allowed  []string
+}
+
+// newAttachmentLimits fills in defaults for unset api.attachments fields.
+func newAttachmentLimits(cfg config.AttachmentsConfig) attachmentLimits {
+	l := attachmentLimits{maxBytes: cfg.MaxBytes, maxCount: cfg.MaxCount, allowed: cfg.AllowedTypes}
+	if l.maxBytes == 0 {
+		l.maxBytes = defaultAttachmentMaxBytes
+	}
+	if l.maxCount == 0 {
+		l.maxCount = defaultAttachmentMaxCount
+	}
+	if len(l.allowed) == 0 {
+		l.allowed = defaultAttachmentTypes
+	}
+	return l
+}
+
+// allows reports whether contentType (without parameters) is accepted.
+func (l attachmentLimits) allows(contentType string) bool {
+	for _, pattern := range l.allowed {
+		if family, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(contentType, family+"/") {
+				return true
+			}
+		} else if strings.EqualFold(pattern, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSendBody reads a send from a JSON or multipart/form-data body.
+func (g *Gateway) parseSendBody(w http.ResponseWriter, r *http.Request) (*SendMessageRequest, error) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return parseSendRequest(r.Body)
+	}
+	limits := g.attachmentLimits
+	if limits.maxCount == 0 {
+		limits = newAttachmentLimits(config.AttachmentsConfig{})
+	}
+	body := http.MaxBytesReader(w, r.Body, limits.maxBytes*int64(limits.maxCount)+multipartFieldBytes)
+	return parseMultipartSend(multipart.NewReader(body, params["boundary"]), limits)
+}
+
+// parseMultipartSend reads the /api/send fields from form fields of the same
+// names, tags from repeated key=value "tag" fields, and files from
+// "attachment" parts.
+func parseMultipartSend(mr *multipart.Reader, limits attachmentLimits) (*SendMessageRequest, error) {
+	var req SendMessageRequest
+	fields := map[string]*string{
+		"thread_id":       &req.ThreadID,
+		"sender":          &req.Sender,
+		"content":         &req.Content,
+		"agent_id":        &req.AgentID,
+		"frontend":        &req.Frontend,
+		"channel_id":      &req.ChannelID,
+		"capability":      &req.Capability,
+		"ack_mode":        &req.AckMode,
+		"workspace":       &req.Workspace,
+		"idempotency_key": &req.IdempotencyKey,
+	}
+	var maxSeconds, pickAny string
+	fields["max_response_seconds"] = &maxSeconds
+	fields["any"] = &pickAny
+	var tagFilters []string
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, multipartError(err)
+		}
+		if part.FormName() == "attachment" {
+			if len(req.Attachments) >= limits.maxCount {
+				return nil, fmt.Errorf("%w: at most %d attachments per message", errAttachmentTooLarge, limits.maxCount)
+			}
+			att, err := readAttachmentPart(part, limits)
+			if err != nil {
+				return nil, err
+			}
+			req.Attachments = append(req.Attachments, *att)
+			continue
+		}
+		dst, ok := fields[part.FormName()]
+		if !ok && part.FormName() != "tag" {
+			continue
+		}
+		value, err := io.ReadAll(io.LimitReader(part, multipartFieldBytes))
+		if err != nil {
+			return nil, multipartError(err)
+		}
+		if !ok {
+			tagFilters = append(tagFilters, string(value))
+			continue
+		}
+		*dst = string(value)
+	}
+
+	if maxSeconds != "" {
+		n, err := strconv.Atoi(maxSeconds)
+		if err != nil {
+			return nil, errors.New("max_response_seconds must be a number")
+		}
+		req.MaxResponseSeconds = n
+	}
+	if pickAny != "" {
+		b, err := strconv.ParseBool(pickAny)
+		if err != nil {
+			return nil, errors.New("any must be true or false")
+		}
+		req.Any = b
+	}
+	tags, err := agent.ParseTagFilter(tagFilters)
+	if err != nil {
+		return nil, err
+	}
+	req.Tags = tags
+	return validateSendRequest(&req)
+}
+
+// readAttachmentPart reads one uploaded file, enforcing the size and type
+// limits. A missing or generic content type is sniffed from the data.
+func readAttachmentPart(part *multipart.Part, limits attachmentLimits) (*agent.Attachment, error) {
+	data, err := io.ReadAll(io.LimitReader(part, limits.maxBytes+1))
+	if err != nil {
+		return nil, multipartError(err)
+	}
+	filename := part.FileName()
+	if int64(len(data)) > limits.maxBytes {
+		return nil, fmt.Errorf("%w: %q exceeds %d bytes", errAttachmentTooLarge, filename, limits.maxBytes)
+	}
+	if filename == "" {
+		return nil, errors.New("attachment parts need a filename")
+	}
+
+	contentType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+	if err != nil || contentType == "application/octet-stream" {
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	if !limits.allows(contentType) {
+		return nil, fmt.Errorf("%w: %q is %s", errAttachmentType, filename, contentType)
+	}
+	return &agent.Attachment{Filename: filename, MimeType: contentType, Data: data}, nil
+}
+
+// multipartError reports a body that hit the MaxBytesReader as too large and
+// anything else as malformed.
+func multipartError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return fmt.Errorf("%w: request body exceeds %d bytes", errAttachmentTooLarge, maxErr.Limit)
+	}
+	return errors.New("invalid multipart body")
+}
+
+// sendBodyErrorStatus maps a parseSendBody error to its HTTP status.
+func sendBodyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errAttachmentTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errAttachmentType):
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusBadRequest
+}
+
+// attachmentsSSE describes stored attachments for the started event.
+func attachmentsSSE(atts []agent.Attachment) []map[string]any {
+	out := make([]map[string]any, 0, len(atts))
+	for _, a := range atts {
+		if a.ID == "" {
+			continue
+		}
+		out = append(out, map[string]any{"id": a.ID, "filename": a.Filename, "mime_type": a.MimeType, "size": len(a.Data)})
+	}
+	return out
+}
+
+// handleGetAttachment handles GET /api/attachments/{id}, serving the file as
+// a download. Callers who cannot see the owning thread get 404, the same as
+// for a missing attachment.
+func (g *Gateway) handleGetAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/attachments/")
+	if id == "" || strings.Contains(id, "/") {
+		g.sendJSONError(w, http.StatusNotFound, "attachment not found")
+		return
+	}
+	if g.attachments == nil {
+		g.sendJSONError(w, http.StatusServiceUnavailable, "attachments not available")
+		return
+	}
+
+	att, err := g.attachments.GetAttachment(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		g.sendJSONError(w, http.StatusNotFound, "attachment not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to get attachment", "error", err, "attachment_id", id)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	visible, err := g.canSeeAttachment(r.Context(), att)
+	if err != nil {
+		g.logger.Error("failed to check thread access", "error", err, "thread_id", att.ThreadID)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if !visible {
+		g.sendJSONError(w, http.StatusNotFound, "attachment not found")
+		return
+	}
+
+	contentType := att.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(att.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := w.Write(att.Data); err != nil {
+		g.logger.Debug("failed to write attachment", "error", err)
+	}
+}
+
+// canSeeAttachment reports whether the caller may read att: with auth
+// disabled anyone can; otherwise admins, its uploader, the thread's agent,
+// and principals who have sent messages in the thread. Tokens limited to
+// other agents than the thread's cannot.
+func (g *Gateway) canSeeAttachment(ctx context.Context, att *store.Attachment) (bool, error) {
+	a := auth.FromContext(ctx)
+	if a == nil {
+		return true, nil
+	}
+	uploader := att.UploadedBy != "" && att.UploadedBy == a.PrincipalID
+	if a.AgentIDs == nil && (a.IsAdmin() || uploader) {
+		return true, nil
+	}
+	thread, err := g.store.GetThread(ctx, att.ThreadID)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("getting thread: %w", err)
+	}
+	if !g.tokenAllowsAgentID(ctx, thread.AgentID) {
+		return false, nil
+	}
+	if a.IsAdmin() || uploader || thread.AgentID == a.PrincipalID {
+		return true, nil
+	}
+	if conn, ok := g.agentManager.GetAgent(thread.AgentID); ok && conn.PrincipalID == a.PrincipalID {
+		return true, nil
+	}
+	return g.attachments.ThreadHasActor(ctx, att.ThreadID, a.PrincipalID)
+}
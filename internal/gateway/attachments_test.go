@@ -0,0 +1,244 @@
+// ABOUTME: Tests for message attachments: multipart sends, upload limits, the file event's
+// ABOUTME: attachment_id, and who may download from GET /api/attachments/{id}
+
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// recordingSender answers every send with a finished response and keeps the
+// requests it was given.
+type recordingSender struct {
+	reqs []*agent.SendRequest
+}
+
+func (s *recordingSender) SendMessage(_ context.Context, req *agent.SendRequest) (<-chan *agent.Response, error) {
+	s.reqs = append(s.reqs, req)
+	ch := make(chan *agent.Response, 2)
+	ch <- &agent.Response{Event: agent.EventFile, File: &agent.FileEvent{Filename: "out.txt", MimeType: "text/plain", Data: []byte("result")}}
+	ch <- &agent.Response{Event: agent.EventDone, Done: true}
+	close(ch)
+	return ch, nil
+}
+
+func newAttachmentTestGateway(t *testing.T, limits config.AttachmentsConfig) (*Gateway, *recordingSender, *store.SQLStore) {
+	t.Helper()
+	gw := newTestGateway(t)
+	gw.attachmentLimits = newAttachmentLimits(limits)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: "test-agent", Name: "Test", PrincipalID: "agent-principal", Stream: &testMockStream{}, Logger: logger,
+	})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("registering agent: %v", err)
+	}
+	sqlStore, ok := gw.store.(*store.SQLStore)
+	if !ok {
+		t.Fatal("store is not *SQLStore")
+	}
+	sender := &recordingSender{}
+	gw.conversation = conversation.New(sqlStore, sender, logger, gw.eventBroadcaster)
+	gw.conversation.SetAttachmentStore(sqlStore)
+	return gw, sender, sqlStore
+}
+
+type testUpload struct {
+	filename, contentType string
+	data                  []byte
+}
+
+func multipartSend(t *testing.T, fields map[string]string, files ...testUpload) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range files {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", `form-data; name="attachment"; filename="`+f.filename+`"`)
+		if f.contentType != "" {
+			h.Set("Content-Type", f.contentType)
+		}
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = part.Write(f.data)
+	}
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/send", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+// sseEventData returns the data of the first SSE event named name in body.
+func sseEventData(t *testing.T, body, name string) map[string]any {
+	t.Helper()
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		if line != "event: "+name || i+1 >= len(lines) {
+			continue
+		}
+		var data map[string]any
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[i+1], "data: ")), &data); err != nil {
+			t.Fatalf("decoding %s data: %v", name, err)
+		}
+		return data
+	}
+	t.Fatalf("no %s event in:\n%s", name, body)
+	return nil
+}
+
+func TestSendMultipart_StoresAttachments(t *testing.T) {
+	gw, sender, _ := newAttachmentTestGateway(t, config.AttachmentsConfig{})
+
+	req := multipartSend(t, map[string]string{"sender": "u", "content": "see attached", "agent_id": "test-agent"},
+		testUpload{"notes.txt", "text/plain; charset=utf-8", []byte("hello")},
+		testUpload{"pic.png", "", []byte("\x89PNG\r\n\x1a\n....")})
+	rec := httptest.NewRecorder()
+	gw.handleSendMessage(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if len(sender.reqs) != 1 || len(sender.reqs[0].Attachments) != 2 {
+		t.Fatalf("agent got %+v, want one send with two attachments", sender.reqs)
+	}
+	sent := sender.reqs[0].Attachments
+	if sent[0].ID == "" || sent[0].MimeType != "text/plain" || string(sent[0].Data) != "hello" {
+		t.Errorf("first attachment = %+v", sent[0])
+	}
+	if sent[1].MimeType != "image/png" {
+		t.Errorf("sniffed type = %q, want image/png", sent[1].MimeType)
+	}
+
+	started := sseEventData(t, rec.Body.String(), "started")
+	atts, _ := started["attachments"].([]any)
+	if len(atts) != 2 {
+		t.Fatalf("started attachments = %v", started["attachments"])
+	}
+	if first, _ := atts[0].(map[string]any); first["id"] != sent[0].ID || first["filename"] != "notes.txt" {
+		t.Errorf("started attachment = %v", first)
+	}
+
+	file := sseEventData(t, rec.Body.String(), "file")
+	fileID, _ := file["attachment_id"].(string)
+	if fileID == "" {
+		t.Fatalf("file event = %v, want an attachment_id", file)
+	}
+
+	for id, want := range map[string]string{sent[0].ID: "hello", fileID: "result"} {
+		rec := httptest.NewRecorder()
+		gw.handleGetAttachment(rec, httptest.NewRequest(http.MethodGet, "/api/attachments/"+id, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != want {
+			t.Errorf("GET %s = %d %q, want %q", id, rec.Code, rec.Body.String(), want)
+		}
+		if cd := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") {
+			t.Errorf("Content-Disposition = %q", cd)
+		}
+	}
+}
+
+func TestSendMultipart_Limits(t *testing.T) {
+	gw, sender, _ := newAttachmentTestGateway(t, config.AttachmentsConfig{MaxBytes: 8, MaxCount: 1})
+	fields := map[string]string{"sender": "u", "content": "hi", "agent_id": "test-agent"}
+
+	tests := []struct {
+		name  string
+		files []testUpload
+		want  int
+	}{
+		{"oversized", []testUpload{{"big.txt", "text/plain", []byte("123456789")}}, http.StatusRequestEntityTooLarge},
+		{"too many", []testUpload{{"a.txt", "text/plain", []byte("a")}, {"b.txt", "text/plain", []byte("b")}}, http.StatusRequestEntityTooLarge},
+		{"type not allowed", []testUpload{{"run.exe", "application/x-msdownload", []byte("MZ")}}, http.StatusUnsupportedMediaType},
+		{"no filename", []testUpload{{"", "text/plain", []byte("a")}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			gw.handleSendMessage(rec, multipartSend(t, fields, tt.files...))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+	if len(sender.reqs) != 0 {
+		t.Errorf("agent got %d sends, want none", len(sender.reqs))
+	}
+}
+
+func TestGetAttachment_Visibility(t *testing.T) {
+	gw, _, sqlStore := newAttachmentTestGateway(t, config.AttachmentsConfig{})
+	ctx := context.Background()
+
+	now := time.Now()
+	if err := sqlStore.CreateThread(ctx, &store.Thread{ID: "thread-1", FrontendName: "api", ExternalID: "x", AgentID: "test-agent", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	if err := sqlStore.SaveAttachment(ctx, &store.Attachment{ID: "att-1", ThreadID: "thread-1", Filename: "a.txt", ContentType: "text/plain", Data: []byte("a"), UploadedBy: "uploader"}); err != nil {
+		t.Fatalf("SaveAttachment: %v", err)
+	}
+	threadID, participant, text := "thread-1", "participant", "hi"
+	if err := sqlStore.SaveEvent(ctx, &store.LedgerEvent{
+		ID: "evt-1", ConversationKey: "test-agent", ThreadID: &threadID, Direction: store.EventDirectionInbound,
+		Author: "p", Timestamp: now, Type: store.EventTypeMessage, Text: &text, ActorPrincipalID: &participant,
+	}); err != nil {
+		t.Fatalf("SaveEvent: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		caller *auth.AuthContext
+		want   int
+	}{
+		{"auth disabled", nil, http.StatusOK},
+		{"admin", &auth.AuthContext{PrincipalID: "boss", Roles: []string{"admin"}}, http.StatusOK},
+		{"uploader", &auth.AuthContext{PrincipalID: "uploader"}, http.StatusOK},
+		{"thread participant", &auth.AuthContext{PrincipalID: "participant"}, http.StatusOK},
+		{"thread agent", &auth.AuthContext{PrincipalID: "agent-principal"}, http.StatusOK},
+		{"stranger", &auth.AuthContext{PrincipalID: "stranger"}, http.StatusNotFound},
+		{"participant token for the thread's agent", &auth.AuthContext{PrincipalID: "participant", AgentIDs: []string{"test-agent"}}, http.StatusOK},
+		{"admin token for another agent", &auth.AuthContext{PrincipalID: "boss", Roles: []string{"admin"}, AgentIDs: []string{"other-agent"}}, http.StatusNotFound},
+		{"uploader token for another agent", &auth.AuthContext{PrincipalID: "uploader", AgentIDs: []string{"other-agent"}}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/attachments/att-1", nil)
+			if tt.caller != nil {
+				req = req.WithContext(auth.WithAuth(req.Context(), tt.caller))
+			}
+			rec := httptest.NewRecorder()
+			gw.handleGetAttachment(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	gw.handleGetAttachment(rec, httptest.NewRequest(http.MethodGet, "/api/attachments/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing attachment status = %d, want 404", rec.Code)
+	}
+}
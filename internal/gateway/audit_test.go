@@ -0,0 +1,67 @@
+// ABOUTME: Tests for GET /api/v1/admin/audit.
+// ABOUTME: Covers filtering, offset-cursor paging and parameter validation.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/httpapi"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestListAuditV1(t *testing.T) {
+	gw, mux := newV1TestServer(t)
+	sqlStore := gw.store.(*store.SQLStore)
+	base := time.Now().UTC().Truncate(time.Second)
+	for i, action := range []store.AuditAction{store.AuditCreateBinding, store.AuditDeleteBinding, store.AuditCreateBinding} {
+		if err := sqlStore.AppendAuditLog(context.Background(), &store.AuditEntry{
+			ActorPrincipalID: "admin-1", Action: action, TargetType: "binding",
+			TargetID: fmt.Sprintf("binding-%d", i), Timestamp: base.Add(time.Duration(i) * time.Second),
+		}); err != nil {
+			t.Fatalf("AppendAuditLog: %v", err)
+		}
+	}
+
+	list := func(query string) httpapi.List[AuditEntryResponse] {
+		t.Helper()
+		rec := getV1(t, mux, "/api/v1/admin/audit"+query)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d, body %s", query, rec.Code, rec.Body.String())
+		}
+		var resp httpapi.List[AuditEntryResponse]
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+
+	first := list("?limit=2")
+	if len(first.Items) != 2 || first.Items[0].TargetID != "binding-2" || first.NextCursor == "" {
+		t.Fatalf("first page = %+v, want binding-2 first and a next cursor", first)
+	}
+	second := list("?limit=2&cursor=" + url.QueryEscape(first.NextCursor))
+	if len(second.Items) != 1 || second.Items[0].TargetID != "binding-0" || second.NextCursor != "" {
+		t.Errorf("second page = %+v, want only binding-0", second)
+	}
+
+	deletes := list("?action=delete_binding")
+	if len(deletes.Items) != 1 || deletes.Items[0].TargetID != "binding-1" {
+		t.Errorf("action filter = %+v, want binding-1", deletes.Items)
+	}
+	if recent := list("?since=" + url.QueryEscape(base.Add(2*time.Second).Format(time.RFC3339))); len(recent.Items) != 1 {
+		t.Errorf("since filter returned %d entries, want 1", len(recent.Items))
+	}
+
+	for _, query := range []string{"?action=launch_rockets", "?since=soon"} {
+		if rec := getV1(t, mux, "/api/v1/admin/audit"+query); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want 400", query, rec.Code)
+		}
+	}
+}
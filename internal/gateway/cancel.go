@@ -0,0 +1,72 @@
+// ABOUTME: POST /api/requests/{request_id}/cancel stops an agent's in-flight response from any client
+// ABOUTME: Only the principal that sent the request, or an admin, may cancel it
+
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/conversation"
+)
+
+// CancelRequestResponse is the JSON response for POST /api/requests/{request_id}/cancel.
+type CancelRequestResponse struct {
+	RequestID string `json:"request_id"`
+	AgentID   string `json:"agent_id"`
+	Reason    string `json:"reason"`
+}
+
+// handleCancelRequest handles POST /api/requests/{request_id}/cancel. The
+// agent is told to stop, the request's stream ends with a canceled event
+// whose reason is user_requested, and later output for it is dropped.
+func (g *Gateway) handleCancelRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	requestID, ok := extractPathSegment(r.URL.Path, "/api/requests/", "/cancel")
+	if !ok {
+		g.sendJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	req, ok := g.conversation.InFlight(requestID)
+	if !ok {
+		g.sendJSONError(w, http.StatusNotFound, "request not found or already finished")
+		return
+	}
+	if !mayCancel(auth.FromContext(r.Context()), req) || !g.tokenAllowsAgentID(r.Context(), req.AgentID) {
+		g.sendJSONError(w, http.StatusForbidden, "only the sender or an admin may cancel this request")
+		return
+	}
+
+	err := g.conversation.CancelRequest(requestID, agent.UserRequestedReason)
+	if errors.Is(err, conversation.ErrRequestNotInFlight) {
+		// It finished while we were checking.
+		g.sendJSONError(w, http.StatusNotFound, "request not found or already finished")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(CancelRequestResponse{
+		RequestID: requestID,
+		AgentID:   req.AgentID,
+		Reason:    agent.UserRequestedReason,
+	}); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// mayCancel reports whether the caller may cancel req: its sender or an
+// admin. Without authentication anyone may.
+func mayCancel(a *auth.AuthContext, req conversation.InFlightRequest) bool {
+	if a == nil || a.IsAdmin() {
+		return true
+	}
+	return req.ActorPrincipalID != "" && req.ActorPrincipalID == a.PrincipalID
+}
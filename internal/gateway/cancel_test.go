@@ -0,0 +1,126 @@
+// ABOUTME: Tests for POST /api/requests/{request_id}/cancel
+// ABOUTME: Covers who may cancel, the canceled SSE event, and the CancelRequest sent to the agent
+
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
+)
+
+// withTestPrincipal authenticates each request as the principal named in its
+// X-Test-Principal header, an admin when X-Test-Role is admin.
+func withTestPrincipal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a := &auth.AuthContext{PrincipalID: r.Header.Get("X-Test-Principal")}
+		if role := r.Header.Get("X-Test-Role"); role != "" {
+			a.Roles = []string{role}
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithAuth(r.Context(), a)))
+	})
+}
+
+func TestCancelRequest(t *testing.T) {
+	gw := newTestGateway(t)
+	agentStream := &capturingStream{}
+	conn := agent.NewConnection(agent.ConnectionParams{ID: "test-agent", Name: "Test", PrincipalID: "test-agent", Stream: agentStream, Logger: testLogger()})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("registering agent: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/send", withTestPrincipal(http.HandlerFunc(gw.handleSendMessage)))
+	mux.Handle("/api/requests/", withTestPrincipal(http.HandlerFunc(gw.handleRequestRoutes)))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	post := func(path, principal, role string, body []byte) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, bytes.NewReader(body))
+		req.Header.Set("X-Test-Principal", principal)
+		req.Header.Set("X-Test-Role", role)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		return resp
+	}
+
+	body, _ := json.Marshal(SendMessageRequest{Sender: "alice", Content: "count to a billion", AgentID: "test-agent"})
+	sendResp := post("/api/send", "alice", "", body)
+	defer func() { _ = sendResp.Body.Close() }()
+	stream := bufio.NewReader(sendResp.Body)
+	var started struct {
+		RequestID string `json:"request_id"`
+	}
+	if ev := readSSEEvent(t, stream); ev.event != "started" || json.Unmarshal([]byte(ev.data), &started) != nil {
+		t.Fatalf("first event = %+v, want started", ev)
+	}
+	cancelPath := "/api/requests/" + started.RequestID + "/cancel"
+
+	tests := []struct {
+		name, principal, role string
+		want                  int
+	}{
+		{"another member", "mallory", "member", http.StatusForbidden},
+		{"an admin", "bob", "admin", http.StatusAccepted},
+		{"after it finished", "alice", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		resp := post(cancelPath, tt.principal, tt.role, nil)
+		_ = resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("cancel by %s: status %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+
+	if ev := readSSEEvent(t, stream); ev.event != "canceled" || ev.data != `{"reason":"user_requested"}` {
+		t.Errorf("stream event = %+v, want canceled with reason user_requested", ev)
+	}
+	agentStream.mu.Lock()
+	last := agentStream.sent[len(agentStream.sent)-1]
+	agentStream.mu.Unlock()
+	if last.GetCancelRequest().GetReason() != agent.UserRequestedReason {
+		t.Errorf("last message to agent = %v, want a user_requested cancel", last)
+	}
+}
+
+func TestCancelRequest_BySender(t *testing.T) {
+	gw := newTestGateway(t)
+	conn := agent.NewConnection(agent.ConnectionParams{ID: "test-agent", Name: "Test", PrincipalID: "test-agent", Stream: &capturingStream{}, Logger: testLogger()})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("registering agent: %v", err)
+	}
+	handler := withTestPrincipal(http.HandlerFunc(gw.handleRequestRoutes))
+
+	send := &SendMessageRequest{Sender: "alice", Content: "hi", AgentID: "test-agent"}
+	ctx := auth.WithAuth(context.Background(), &auth.AuthContext{PrincipalID: "alice"})
+	target, errMsg := gw.resolveTarget(ctx, send)
+	if target == nil {
+		t.Fatalf("resolveTarget: %s", errMsg)
+	}
+	requestID, err := gw.beginSend(ctx, send, target)
+	if err != nil {
+		t.Fatalf("beginSend: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/requests/"+requestID+"/cancel", nil)
+	req.Header.Set("X-Test-Principal", "alice")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+	var got CancelRequestResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got.RequestID != requestID || got.Reason != agent.UserRequestedReason {
+		t.Errorf("response = %s, want the request canceled with user_requested", rec.Body.String())
+	}
+}
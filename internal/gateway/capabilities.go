@@ -0,0 +1,109 @@
+// ABOUTME: Resolves a calling agent's capabilities from its principal's grants for the pack router.
+// ABOUTME: Records capability_warning ledger events and pushes changed grants to connected agents.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// capabilityEnforcement returns the packs enforcement mode for the
+// configured packs.capability_enforcement, defaulting to warn.
+func capabilityEnforcement(mode string) string {
+	if mode == packs.CapabilityEnforce {
+		return packs.CapabilityEnforce
+	}
+	return packs.CapabilityWarn
+}
+
+// callerPrincipal resolves the principal behind a tool call. Connected agents
+// carry their principal; MCP bearer callers are identified by it directly.
+func callerPrincipal(m *agent.Manager, agentID string) string {
+	if conn, ok := m.GetAgent(agentID); ok {
+		return conn.PrincipalID
+	}
+	return agentID
+}
+
+// principalCapabilities returns a packs.RouterConfig.Capabilities lookup that
+// answers with the capabilities granted to the caller's principal. Whatever
+// an agent declared when it registered is not consulted.
+func principalCapabilities(m *agent.Manager, s *store.SQLStore, logger *slog.Logger) func(agentID string) []string {
+	return func(agentID string) []string {
+		principalID := callerPrincipal(m, agentID)
+		if principalID == "" {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), toolCheckTimeout)
+		defer cancel()
+		caps, err := s.ListCapabilities(ctx, principalID)
+		if err != nil {
+			logger.Warn("failed to load capability grants", "agent_id", agentID, "principal_id", principalID, "error", err)
+			return nil
+		}
+		return caps
+	}
+}
+
+// capabilityWarningRecorder returns a packs.RouterConfig.OnCapabilityWarning
+// hook that saves each warning to the agent's ledger.
+func capabilityWarningRecorder(m *agent.Manager, s store.Store, logger *slog.Logger) func(agentID, toolName, capability string) {
+	return func(agentID, toolName, capability string) {
+		ctx, cancel := context.WithTimeout(context.Background(), toolCheckTimeout)
+		defer cancel()
+		data, _ := json.Marshal(store.CapabilityWarningDetail{
+			Tool:        toolName,
+			Capability:  capability,
+			PrincipalID: callerPrincipal(m, agentID),
+		})
+		text := string(data)
+		err := s.SaveEvent(ctx, &store.LedgerEvent{
+			ID:              uuid.New().String(),
+			ConversationKey: agentID,
+			Direction:       store.EventDirectionOutbound,
+			Author:          agentID,
+			Timestamp:       time.Now().UTC(),
+			Type:            store.EventTypeCapabilityWarning,
+			Text:            &text,
+		})
+		if err != nil {
+			logger.Warn("failed to record capability warning", "agent_id", agentID, "tool_name", toolName, "error", err)
+		}
+	}
+}
+
+// CapabilitiesChanged applies a principal's new capability grants to its
+// connected agents: routing and agent listings see them at once, their MCP
+// tokens are updated, and agents that handle capability_update are sent the
+// new set with its pack tools. Tool calls need no update, since the pack
+// router reads the grants for every call.
+func (g *Gateway) CapabilitiesChanged(principalID string, capabilities []string) {
+	for _, conn := range g.agentManager.ListByPrincipal(principalID) {
+		if g.mcpTokens != nil {
+			g.mcpTokens.SetAgentCapabilities(conn.ID, capabilities)
+		}
+		tools := g.agentTools(context.Background(), conn.ID, capabilities)
+		pushed, err := conn.UpdateCapabilities(capabilities, tools)
+		switch {
+		case err != nil:
+			g.logger.Warn("failed to send capability update", "agent_id", conn.ID, "principal_id", principalID, "error", err)
+		case !pushed:
+			g.logger.Info("agent does not handle capability updates, new set applies to gateway checks only",
+				"agent_id", conn.ID,
+				"principal_id", principalID,
+				"capabilities", capabilities,
+			)
+		default:
+			g.logger.Info("pushed capability update to agent", "agent_id", conn.ID, "principal_id", principalID, "capabilities", capabilities)
+		}
+	}
+}
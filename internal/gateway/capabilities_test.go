@@ -0,0 +1,148 @@
+// ABOUTME: Tests for pushing a principal's changed capability grants to its connected agents,
+// ABOUTME: including a revocation racing a builtin tool call already in flight.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestCapabilitiesChanged(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx := context.Background()
+	sqlStore := gw.store.(*store.SQLStore)
+	createAgentPrincipal(t, gw, "p-1")
+	for _, c := range []string{"base", "notes"} {
+		if err := sqlStore.GrantCapability(ctx, "p-1", c, "seed"); err != nil {
+			t.Fatalf("GrantCapability: %v", err)
+		}
+	}
+
+	stream, legacyStream := &capturingStream{}, &capturingStream{}
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: "agent-1", Name: "agent-1", PrincipalID: "p-1", Capabilities: []string{"base", "notes"},
+		Metadata: &agent.Metadata{ProtocolFeatures: []string{agent.FeatureCapabilityUpdate}},
+		Stream:   stream, Logger: slog.Default(),
+	})
+	legacy := agent.NewConnection(agent.ConnectionParams{
+		ID: "agent-2", Name: "agent-2", PrincipalID: "p-1", Capabilities: []string{"base", "notes"},
+		Stream: legacyStream, Logger: slog.Default(),
+	})
+	for _, c := range []*agent.Connection{conn, legacy} {
+		if err := gw.agentManager.Register(c); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+	mcpToken := gw.mcpTokens.CreateToken("agent-1", []string{"base", "notes"})
+
+	// A builtin tool that holds its call open until released.
+	started, release := make(chan struct{}), make(chan struct{})
+	registry := packs.NewRegistry(slog.Default())
+	noteTool := func(name string, block bool) *packs.BuiltinTool {
+		return &packs.BuiltinTool{
+			Definition: &pb.ToolDefinition{Name: name, RequiredCapabilities: []string{"notes"}},
+			Handler: func(context.Context, string, json.RawMessage) (json.RawMessage, error) {
+				if block {
+					close(started)
+					<-release
+				}
+				return json.RawMessage(`{}`), nil
+			},
+		}
+	}
+	if err := registry.RegisterBuiltinPack(&packs.BuiltinPack{ID: "builtin:test", Tools: []*packs.BuiltinTool{
+		noteTool("slow_note", true), noteTool("quick_note", false),
+	}}); err != nil {
+		t.Fatalf("RegisterBuiltinPack: %v", err)
+	}
+	router := packs.NewRouter(packs.RouterConfig{
+		Registry:              registry,
+		Logger:                slog.Default(),
+		Capabilities:          principalCapabilities(gw.agentManager, sqlStore, slog.Default()),
+		CapabilityEnforcement: packs.CapabilityEnforce,
+	})
+
+	inFlight := make(chan error, 1)
+	go func() {
+		resp, err := router.RouteToolCall(ctx, "slow_note", `{}`, "req-slow", "agent-1")
+		if err == nil && resp.GetError() != "" {
+			err = errors.New(resp.GetError())
+		}
+		inFlight <- err
+	}()
+	<-started
+
+	// Revoke notes while slow_note runs, with readers of the live set racing it.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := sqlStore.RevokeCapability(ctx, "p-1", "notes"); err != nil {
+			t.Errorf("RevokeCapability: %v", err)
+		}
+		gw.CapabilitiesChanged("p-1", []string{"base"})
+	}()
+	go func() {
+		defer wg.Done()
+		for range 50 {
+			gw.handleListAgents(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/agents", nil))
+			gw.broadcastToolsChanged(0)
+		}
+	}()
+	wg.Wait()
+	close(release)
+
+	if err := <-inFlight; err != nil {
+		t.Errorf("in-flight call = %v, want it to finish as authorized", err)
+	}
+	if _, err := router.RouteToolCall(ctx, "quick_note", `{}`, "req-quick", "agent-1"); !errors.Is(err, packs.ErrCapabilityDenied) {
+		t.Errorf("call after revocation err = %v, want capability denied", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.handleListAgents(rec, httptest.NewRequest(http.MethodGet, "/api/agents", nil))
+	var listed []AgentInfoResponse
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatalf("decoding /api/agents: %v", err)
+	}
+	for _, a := range listed {
+		if !slices.Equal(a.Capabilities, []string{"base"}) {
+			t.Errorf("/api/agents %s capabilities = %v, want [base]", a.ID, a.Capabilities)
+		}
+	}
+	if caps := gw.mcpTokens.GetCapabilities(mcpToken); !slices.Equal(caps, []string{"base"}) {
+		t.Errorf("MCP token capabilities = %v, want [base]", caps)
+	}
+
+	var update *pb.CapabilityUpdate
+	stream.mu.Lock()
+	for _, msg := range stream.sent {
+		if u := msg.GetCapabilityUpdate(); u != nil {
+			update = u
+		}
+	}
+	stream.mu.Unlock()
+	if update == nil || !slices.Equal(update.GetCapabilities(), []string{"base"}) {
+		t.Errorf("capability_update agent got %v, want [base]", update)
+	}
+	legacyStream.mu.Lock()
+	defer legacyStream.mu.Unlock()
+	for _, msg := range legacyStream.sent {
+		if msg.GetCapabilityUpdate() != nil {
+			t.Error("agent without capability_update was sent one")
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// ABOUTME: delegate_task support: runs one agent's prompt on another in a child thread and waits for the reply.
+// ABOUTME: Tracks running delegations to refuse cycles and chains deeper than builtins.MaxDelegationDepth.
+
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/builtins"
+	"github.com/2389/coven-gateway/internal/conversation"
+)
+
+// delegationFrontend is the frontend name of the child threads delegate_task creates.
+const delegationFrontend = "delegate"
+
+// delegationLink is a delegated request still running: the agent handling
+// it, how deep it is, and the agents that delegated along the way.
+type delegationLink struct {
+	agentID string
+	depth   int
+	chain   []string // callers from the outermost to the direct one
+}
+
+// delegationTracker holds running delegations by child thread ID.
+type delegationTracker struct {
+	mu       sync.Mutex
+	byThread map[string]delegationLink
+}
+
+func newDelegationTracker() *delegationTracker {
+	return &delegationTracker{byThread: make(map[string]delegationLink)}
+}
+
+// parent returns the delegation agentID is working on in threadID. If that
+// thread is not a delegation but the agent is handling others, the deepest
+// of them is used, so an agent busy with several requests cannot slip past
+// the depth limit. The zero link means the agent was not delegated to.
+func (t *delegationTracker) parent(agentID, threadID string) delegationLink {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if link, ok := t.byThread[threadID]; ok && link.agentID == agentID {
+		return link
+	}
+	var deepest delegationLink
+	for _, link := range t.byThread {
+		if link.agentID == agentID && link.depth > deepest.depth {
+			deepest = link
+		}
+	}
+	return deepest
+}
+
+func (t *delegationTracker) start(threadID string, link delegationLink) {
+	t.mu.Lock()
+	t.byThread[threadID] = link
+	t.mu.Unlock()
+}
+
+func (t *delegationTracker) finish(threadID string) {
+	t.mu.Lock()
+	delete(t.byThread, threadID)
+	t.mu.Unlock()
+}
+
+// Delegate implements builtins.Delegator. The prompt goes to the target
+// agent in a new thread whose first message records the caller's current
+// request as its parent; the reply is collected until done, an error, the
+// timeout, or the caller going away. Timeouts cancel the child request.
+func (g *Gateway) Delegate(ctx context.Context, req *builtins.DelegationRequest) (*builtins.DelegationResult, error) {
+	var parentThreadID, parentRequestID string
+	if act, ok := g.agentManager.Activity(req.CallerID); ok {
+		parentThreadID = act.ThreadID
+		if parent, ok := g.conversation.InFlightOnThread(req.CallerID, act.ThreadID); ok {
+			parentRequestID = parent.RequestID
+		}
+	}
+
+	parent := g.delegations.parent(req.CallerID, parentThreadID)
+	depth := parent.depth + 1
+	if depth > builtins.MaxDelegationDepth {
+		return nil, fmt.Errorf("delegation depth limit of %d reached", builtins.MaxDelegationDepth)
+	}
+	chain := append(slices.Clone(parent.chain), req.CallerID)
+	if slices.Contains(chain, req.TargetID) {
+		return nil, fmt.Errorf("%s is already part of this delegation (%s)", req.TargetID, strings.Join(chain, " -> "))
+	}
+	if _, ok := g.agentManager.GetAgent(req.TargetID); !ok {
+		return nil, fmt.Errorf("agent %s is not connected", req.TargetID)
+	}
+
+	threadID := uuid.New().String()
+	g.delegations.start(threadID, delegationLink{agentID: req.TargetID, depth: depth, chain: chain})
+	defer g.delegations.finish(threadID)
+
+	// As with /api/send, the child's responses are persisted even if the
+	// caller stops waiting for them.
+	convResp, err := g.conversation.SendMessage(context.WithoutCancel(ctx), &conversation.SendRequest{
+		ThreadID:        threadID,
+		FrontendName:    delegationFrontend,
+		ExternalID:      threadID,
+		AgentID:         req.TargetID,
+		Sender:          "agent:" + req.CallerID,
+		Content:         req.Prompt,
+		MaxDuration:     req.Timeout,
+		ParentRequestID: parentRequestID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("delegating to %s: %w", req.TargetID, err)
+	}
+	g.logger.Info("delegated task",
+		"agent_id", req.CallerID,
+		"target_agent_id", req.TargetID,
+		"thread_id", convResp.ThreadID,
+		"request_id", convResp.MessageID,
+		"parent_request_id", parentRequestID,
+		"depth", depth,
+	)
+
+	result := &builtins.DelegationResult{ThreadID: convResp.ThreadID, ParentRequestID: parentRequestID, Depth: depth}
+	if err := g.awaitDelegation(ctx, req, convResp, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// awaitDelegation reads the child's stream into result, keeping at most
+// req.MaxBytes of its text.
+func (g *Gateway) awaitDelegation(ctx context.Context, req *builtins.DelegationRequest, convResp *conversation.SendResponse, result *builtins.DelegationResult) error {
+	timer := time.NewTimer(req.Timeout)
+	defer timer.Stop()
+
+	var text strings.Builder
+	appendText := func(s string) {
+		room := req.MaxBytes - text.Len()
+		if len(s) > room {
+			s = s[:max(room, 0)]
+			result.Truncated = true
+		}
+		text.WriteString(s)
+	}
+
+	for {
+		select {
+		case resp, ok := <-convResp.Stream:
+			if !ok {
+				result.Response = text.String()
+				return nil
+			}
+			switch resp.Event {
+			case agent.EventText:
+				appendText(resp.Text)
+			case agent.EventDone:
+				// The full response, when the agent sends one, supersedes the chunks.
+				if resp.Text != "" {
+					text.Reset()
+					result.Truncated = false
+					appendText(resp.Text)
+				}
+				result.Response = text.String()
+				go drainResponses(convResp.Stream)
+				return nil
+			case agent.EventError:
+				go drainResponses(convResp.Stream)
+				return fmt.Errorf("agent %s failed: %s", req.TargetID, resp.Error)
+			case agent.EventCanceled:
+				go drainResponses(convResp.Stream)
+				return fmt.Errorf("agent %s canceled the request", req.TargetID)
+			}
+		case <-timer.C:
+			g.stopDelegation(convResp, "delegation timed out")
+			return fmt.Errorf("agent %s did not answer within %s", req.TargetID, req.Timeout)
+		case <-ctx.Done():
+			g.stopDelegation(convResp, "delegating agent stopped waiting")
+			return ctx.Err()
+		}
+	}
+}
+
+// stopDelegation cancels a child request nobody is waiting for any more.
+func (g *Gateway) stopDelegation(convResp *conversation.SendResponse, reason string) {
+	if err := g.conversation.CancelRequest(convResp.MessageID, reason); err != nil {
+		g.logger.Debug("delegated request already finished", "request_id", convResp.MessageID, "error", err)
+	}
+	go drainResponses(convResp.Stream)
+}
+
+// drainResponses reads a stream to its end so its persister can finish.
+func drainResponses(stream <-chan *agent.Response) {
+	for range stream {
+	}
+}
@@ -0,0 +1,151 @@
+// ABOUTME: Tests for delegate_task on the gateway side: the child thread and its ledger link,
+// ABOUTME: depth and cycle limits, unconnected targets, and timeouts that cancel the child request.
+
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/builtins"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// registerDelegationAgent connects an agent whose stream is stream, or one
+// that never answers when stream is nil.
+func registerDelegationAgent(t *testing.T, gw *Gateway, id string, stream *replyingStream) {
+	t.Helper()
+	params := agent.ConnectionParams{ID: id, Name: id, PrincipalID: id, Stream: &testMockStream{}, Logger: slog.Default()}
+	if stream != nil {
+		params.Stream = stream
+	}
+	conn := agent.NewConnection(params)
+	if stream != nil {
+		stream.conn = conn
+	}
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("Register(%s): %v", id, err)
+	}
+}
+
+func delegationRequest(caller, target string, timeout time.Duration) *builtins.DelegationRequest {
+	return &builtins.DelegationRequest{
+		CallerID: caller,
+		TargetID: target,
+		Prompt:   "Summarize the logs",
+		Timeout:  timeout,
+		MaxBytes: builtins.MaxDelegationResultBytes,
+	}
+}
+
+func TestDelegate(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx := context.Background()
+	registerDelegationAgent(t, gw, "agent-a", nil)
+	registerDelegationAgent(t, gw, "agent-b", &replyingStream{reply: "all quiet"})
+
+	// agent-a is working on a request when it delegates.
+	parent, err := gw.conversation.SendMessage(ctx, &conversation.SendRequest{
+		FrontendName: "test", ExternalID: "parent", AgentID: "agent-a", Sender: "alice", Content: "check on agent-b",
+	})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	res, err := gw.Delegate(ctx, delegationRequest("agent-a", "agent-b", 5*time.Second))
+	if err != nil {
+		t.Fatalf("Delegate: %v", err)
+	}
+	if res.Response != "all quiet" || res.Depth != 1 || res.ParentRequestID != parent.MessageID {
+		t.Errorf("result = %+v, want the reply at depth 1 under %s", res, parent.MessageID)
+	}
+
+	thread, err := gw.store.GetThread(ctx, res.ThreadID)
+	if err != nil || thread.FrontendName != delegationFrontend || thread.AgentID != "agent-b" {
+		t.Fatalf("child thread = %+v, %v", thread, err)
+	}
+	events, err := gw.store.GetEventsByThreadID(ctx, res.ThreadID, 10)
+	if err != nil || len(events) == 0 {
+		t.Fatalf("child events = %v, %v", events, err)
+	}
+	var prompt *store.LedgerEvent
+	for _, ev := range events {
+		if ev.Direction == store.EventDirectionInbound {
+			prompt = ev
+		}
+	}
+	if prompt == nil || prompt.Author != "agent:agent-a" || prompt.ParentRequestID == nil || *prompt.ParentRequestID != parent.MessageID {
+		t.Errorf("child prompt = %+v, want one from agent:agent-a under %s", prompt, parent.MessageID)
+	}
+
+	if len(gw.delegations.byThread) != 0 {
+		t.Errorf("delegations still tracked after finishing: %v", gw.delegations.byThread)
+	}
+}
+
+func TestDelegate_Truncates(t *testing.T) {
+	gw := newTestGateway(t)
+	registerDelegationAgent(t, gw, "agent-a", nil)
+	registerDelegationAgent(t, gw, "agent-b", &replyingStream{reply: strings.Repeat("x", 100)})
+
+	req := delegationRequest("agent-a", "agent-b", 5*time.Second)
+	req.MaxBytes = 10
+	res, err := gw.Delegate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Delegate: %v", err)
+	}
+	if !res.Truncated || len(res.Response) != 10 {
+		t.Errorf("result = %d bytes, truncated %v; want 10 bytes, truncated", len(res.Response), res.Truncated)
+	}
+}
+
+func TestDelegate_Limits(t *testing.T) {
+	gw := newTestGateway(t)
+	registerDelegationAgent(t, gw, "agent-a", nil)
+	registerDelegationAgent(t, gw, "agent-b", &replyingStream{reply: "ok"})
+	ctx := context.Background()
+
+	if _, err := gw.Delegate(ctx, delegationRequest("agent-a", "agent-offline", time.Second)); err == nil || !strings.Contains(err.Error(), "not connected") {
+		t.Errorf("offline target: err = %v, want not connected", err)
+	}
+
+	// agent-a is itself handling a delegation from agent-b.
+	gw.delegations.start("thread-from-b", delegationLink{agentID: "agent-a", depth: 1, chain: []string{"agent-b"}})
+	if _, err := gw.Delegate(ctx, delegationRequest("agent-a", "agent-b", time.Second)); err == nil || !strings.Contains(err.Error(), "already part of this delegation") {
+		t.Errorf("cycle: err = %v, want refusal", err)
+	}
+	gw.delegations.finish("thread-from-b")
+
+	gw.delegations.start("thread-deep", delegationLink{agentID: "agent-a", depth: builtins.MaxDelegationDepth, chain: []string{"x", "y", "z"}})
+	if _, err := gw.Delegate(ctx, delegationRequest("agent-a", "agent-b", time.Second)); err == nil || !strings.Contains(err.Error(), "depth limit") {
+		t.Errorf("depth: err = %v, want depth limit", err)
+	}
+}
+
+func TestDelegate_Timeout(t *testing.T) {
+	gw := newTestGateway(t)
+	registerDelegationAgent(t, gw, "agent-a", nil)
+	registerDelegationAgent(t, gw, "agent-silent", nil)
+
+	_, err := gw.Delegate(context.Background(), delegationRequest("agent-a", "agent-silent", 50*time.Millisecond))
+	if err == nil || !strings.Contains(err.Error(), "did not answer within") {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+
+	// The child request was canceled rather than left running.
+	sqlStore := gw.store.(*store.SQLStore)
+	threads, err := sqlStore.ListThreads(context.Background(), 10, true)
+	if err != nil {
+		t.Fatalf("ListThreads: %v", err)
+	}
+	for _, th := range threads {
+		if _, ok := gw.conversation.InFlightOnThread("agent-silent", th.ID); ok {
+			t.Errorf("child request on %s still in flight after timeout", th.ID)
+		}
+	}
+}
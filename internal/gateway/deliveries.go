@@ -0,0 +1,359 @@
+// ABOUTME: Explicit delivery acknowledgment for bridge frontends using ack_mode=explicit.
+// ABOUTME: Tracks pending deliveries, serves ack/nack/dead-letter/stats endpoints, and expires stale ones.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+const (
+	// ackModeImplicit treats a response as delivered once it is written to the SSE stream.
+	ackModeImplicit = "implicit"
+	// ackModeExplicit keeps a response pending until the bridge acks or nacks it.
+	ackModeExplicit = "explicit"
+
+	defaultDeliveryAckTimeout = 2 * time.Minute
+)
+
+// DeliveryNackRequest is the JSON body for POST /api/deliveries/{request_id}/nack.
+type DeliveryNackRequest struct {
+	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
+}
+
+// DeliveryResponse is the JSON representation of a tracked delivery.
+type DeliveryResponse struct {
+	RequestID   string `json:"request_id"`
+	ThreadID    string `json:"thread_id"`
+	AgentID     string `json:"agent_id"`
+	Frontend    string `json:"frontend"`
+	ChannelID   string `json:"channel_id,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	ErrorCode   string `json:"error_code,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	RespondedAt string `json:"responded_at,omitempty"`
+	ResolvedAt  string `json:"resolved_at,omitempty"`
+}
+
+// DeadLetterResponse is the JSON response for GET /api/deliveries/dead-letter.
+type DeadLetterResponse struct {
+	Deliveries []DeliveryResponse `json:"deliveries"`
+}
+
+// FrontendDeliveryStatsResponse holds delivery outcomes for a single frontend.
+type FrontendDeliveryStatsResponse struct {
+	Frontend    string  `json:"frontend"`
+	Pending     int64   `json:"pending"`
+	Acked       int64   `json:"acked"`
+	Nacked      int64   `json:"nacked"`
+	Expired     int64   `json:"expired"`
+	SuccessRate float64 `json:"success_rate"`
+}
+
+// DeliveryStatsResponse is the JSON response for GET /api/deliveries/stats.
+type DeliveryStatsResponse struct {
+	Frontends []FrontendDeliveryStatsResponse `json:"frontends"`
+}
+
+// deliveryTracker records explicit-ack deliveries and expires the ones bridges never acknowledge.
+type deliveryTracker struct {
+	store   *store.SQLStore
+	timeout time.Duration
+	logger  *slog.Logger
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// newDeliveryTracker starts the expiry sweeper. A non-positive timeout uses the default.
+func newDeliveryTracker(s *store.SQLStore, timeout time.Duration, logger *slog.Logger) *deliveryTracker {
+	if timeout <= 0 {
+		timeout = defaultDeliveryAckTimeout
+	}
+	t := &deliveryTracker{
+		store:   s,
+		timeout: timeout,
+		logger:  logger,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go t.sweep(max(timeout/4, time.Second))
+	return t
+}
+
+// sweep periodically dead-letters deliveries that outlived the ack timeout.
+func (t *deliveryTracker) sweep(interval time.Duration) {
+	defer close(t.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			t.expire(context.Background())
+		}
+	}
+}
+
+// expire dead-letters pending deliveries whose response finished before the timeout window.
+func (t *deliveryTracker) expire(ctx context.Context) {
+	n, err := t.store.ExpireDeliveries(ctx, time.Now().Add(-t.timeout))
+	if err != nil {
+		t.logger.Warn("failed to expire deliveries", "error", err)
+		return
+	}
+	if n > 0 {
+		t.logger.Info("deliveries expired without acknowledgment", "count", n)
+	}
+}
+
+// Close stops the sweeper and waits for it to exit.
+func (t *deliveryTracker) Close() {
+	t.stopOnce.Do(func() { close(t.stop) })
+	<-t.done
+}
+
+// track forwards responses unchanged and records when the response finished
+// streaming, which starts the ack timeout. The mark happens before the done
+// event is forwarded so an ack can never race ahead of it.
+func (t *deliveryTracker) track(ctx context.Context, requestID string, in <-chan *agent.Response) <-chan *agent.Response {
+	out := make(chan *agent.Response)
+	go func() {
+		defer close(out)
+		var marked bool
+		markResponded := func() {
+			if marked {
+				return
+			}
+			marked = true
+			// Use a fresh context: the client may already have disconnected.
+			if err := t.store.MarkDeliveryResponded(context.WithoutCancel(ctx), requestID, time.Now()); err != nil {
+				t.logger.Warn("failed to mark delivery responded", "request_id", requestID, "error", err)
+			}
+		}
+		defer markResponded()
+
+		for resp := range in {
+			if resp.Event == agent.EventDone || resp.Event == agent.EventTruncated {
+				markResponded()
+			}
+			select {
+			case out <- resp:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// validAckMode reports whether mode is an accepted ack_mode value.
+func validAckMode(mode string) bool {
+	return mode == "" || mode == ackModeImplicit || mode == ackModeExplicit
+}
+
+// handleDeliveryRoutes routes /api/deliveries/... requests.
+func (g *Gateway) handleDeliveryRoutes(w http.ResponseWriter, r *http.Request) {
+	if g.deliveries == nil {
+		g.sendJSONError(w, http.StatusServiceUnavailable, "delivery tracking not available")
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == "/api/deliveries/dead-letter":
+		g.handleDeadLetters(w, r)
+	case path == "/api/deliveries/stats":
+		g.handleDeliveryStats(w, r)
+	case strings.HasSuffix(path, "/ack"):
+		g.handleDeliveryAck(w, r)
+	case strings.HasSuffix(path, "/nack"):
+		g.handleDeliveryNack(w, r)
+	default:
+		g.sendJSONError(w, http.StatusNotFound, "unknown endpoint")
+	}
+}
+
+// handleDeliveryAck handles POST /api/deliveries/{request_id}/ack.
+func (g *Gateway) handleDeliveryAck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	requestID, ok := extractPathSegment(r.URL.Path, "/api/deliveries/", "/ack")
+	if !ok {
+		g.sendJSONError(w, http.StatusBadRequest, "invalid path")
+		return
+	}
+	g.resolveDelivery(w, r, requestID, store.DeliveryAcked, "", "")
+}
+
+// handleDeliveryNack handles POST /api/deliveries/{request_id}/nack.
+func (g *Gateway) handleDeliveryNack(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	requestID, ok := extractPathSegment(r.URL.Path, "/api/deliveries/", "/nack")
+	if !ok {
+		g.sendJSONError(w, http.StatusBadRequest, "invalid path")
+		return
+	}
+
+	var req DeliveryNackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.sendJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Error == "" {
+		g.sendJSONError(w, http.StatusBadRequest, "error is required")
+		return
+	}
+	g.resolveDelivery(w, r, requestID, store.DeliveryNacked, req.Error, req.Code)
+}
+
+// resolveDelivery applies an ack or nack and writes the updated delivery.
+func (g *Gateway) resolveDelivery(w http.ResponseWriter, r *http.Request, requestID string, status store.DeliveryStatus, errMsg, errCode string) {
+	ctx := r.Context()
+	err := g.deliveries.store.ResolveDelivery(ctx, requestID, status, errMsg, errCode)
+	switch {
+	case errors.Is(err, store.ErrDeliveryNotFound):
+		g.sendJSONError(w, http.StatusNotFound, "delivery not found")
+		return
+	case errors.Is(err, store.ErrDeliveryResolved):
+		g.sendJSONError(w, http.StatusConflict, "delivery already resolved")
+		return
+	case err != nil:
+		g.logger.Error("failed to resolve delivery", "request_id", requestID, "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	d, err := g.deliveries.store.GetDelivery(ctx, requestID)
+	if err != nil {
+		g.logger.Error("failed to load delivery", "request_id", requestID, "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if status == store.DeliveryNacked {
+		g.logger.Warn("bridge failed to deliver response",
+			"request_id", requestID,
+			"frontend", d.Frontend,
+			"channel_id", d.ChannelID,
+			"error", errMsg,
+			"code", errCode,
+		)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(deliveryToResponse(d)); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// handleDeadLetters handles GET /api/deliveries/dead-letter?frontend=X&limit=N.
+func (g *Gateway) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	limit, errMsg := parseLimitParam(r, 100, 500)
+	if errMsg != "" {
+		g.sendJSONError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	deliveries, err := g.deliveries.store.ListDeadLetters(r.Context(), r.URL.Query().Get("frontend"), limit)
+	if err != nil {
+		g.logger.Error("failed to list dead letters", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response := DeadLetterResponse{Deliveries: make([]DeliveryResponse, len(deliveries))}
+	for i, d := range deliveries {
+		response.Deliveries[i] = deliveryToResponse(d)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// handleDeliveryStats handles GET /api/deliveries/stats?since=<timestamp> (RFC3339 or epoch).
+func (g *Gateway) handleDeliveryStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := timeparse.ParseParam("since", v)
+		if err != nil {
+			g.sendTimestampError(w, err)
+			return
+		}
+		since = t
+	}
+
+	stats, err := g.deliveries.store.GetDeliveryStats(r.Context(), since)
+	if err != nil {
+		g.logger.Error("failed to get delivery stats", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response := DeliveryStatsResponse{Frontends: make([]FrontendDeliveryStatsResponse, len(stats))}
+	for i, s := range stats {
+		response.Frontends[i] = FrontendDeliveryStatsResponse{
+			Frontend:    s.Frontend,
+			Pending:     s.Pending,
+			Acked:       s.Acked,
+			Nacked:      s.Nacked,
+			Expired:     s.Expired,
+			SuccessRate: s.SuccessRate(),
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// deliveryToResponse converts a store delivery to its JSON form.
+func deliveryToResponse(d *store.Delivery) DeliveryResponse {
+	resp := DeliveryResponse{
+		RequestID: d.RequestID,
+		ThreadID:  d.ThreadID,
+		AgentID:   d.AgentID,
+		Frontend:  d.Frontend,
+		ChannelID: d.ChannelID,
+		Status:    string(d.Status),
+		Error:     d.Error,
+		ErrorCode: d.ErrorCode,
+		CreatedAt: timeparse.Format(d.CreatedAt),
+	}
+	if d.RespondedAt != nil {
+		resp.RespondedAt = timeparse.Format(*d.RespondedAt)
+	}
+	if d.ResolvedAt != nil {
+		resp.ResolvedAt = timeparse.Format(*d.ResolvedAt)
+	}
+	return resp
+}
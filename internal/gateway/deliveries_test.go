@@ -0,0 +1,148 @@
+// ABOUTME: Tests for explicit delivery acknowledgment over the HTTP API.
+// ABOUTME: Covers ack_mode on /api/send, ack/nack endpoints, dead letters, and stats.
+
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// sendExplicit posts an explicit-ack send via the slack/C001 binding and
+// returns the request_id from the started event.
+func sendExplicit(t *testing.T, gw *Gateway) string {
+	t.Helper()
+
+	body, err := json.Marshal(SendMessageRequest{
+		Sender:    "bridge",
+		Content:   "Hello",
+		Frontend:  "slack",
+		ChannelID: "C001",
+		AckMode:   ackModeExplicit,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/send", bytes.NewReader(body))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	gw.handleSendMessage(rec, req.WithContext(ctx))
+
+	scanner := bufio.NewScanner(strings.NewReader(rec.Body.String()))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var started map[string]any
+			require.NoError(t, json.Unmarshal([]byte(data), &started))
+			assert.Equal(t, ackModeExplicit, started["ack_mode"])
+			require.NotEmpty(t, started["request_id"])
+			return started["request_id"].(string)
+		}
+	}
+	t.Fatalf("no started event in response: %s", rec.Body.String())
+	return ""
+}
+
+func postDelivery(gw *Gateway, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	gw.handleDeliveryRoutes(rec, req)
+	return rec
+}
+
+func TestExplicitAck_RecordsAndAcksDelivery(t *testing.T) {
+	gw := newTestGatewayWithMockManager(t)
+	createTestBindingV2(t, gw, "slack", "C001", "test-agent")
+
+	requestID := sendExplicit(t, gw)
+
+	d, err := gw.deliveries.store.GetDelivery(context.Background(), requestID)
+	require.NoError(t, err)
+	assert.Equal(t, "slack", d.Frontend)
+	assert.Equal(t, "C001", d.ChannelID)
+	assert.Equal(t, store.DeliveryPending, d.Status)
+
+	rec := postDelivery(gw, "/api/deliveries/"+requestID+"/ack", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp DeliveryResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "acked", resp.Status)
+
+	rec = postDelivery(gw, "/api/deliveries/"+requestID+"/ack", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = postDelivery(gw, "/api/deliveries/unknown/ack", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestExplicitAck_NackLandsInDeadLetter(t *testing.T) {
+	gw := newTestGatewayWithMockManager(t)
+	createTestBindingV2(t, gw, "slack", "C001", "test-agent")
+
+	requestID := sendExplicit(t, gw)
+
+	rec := postDelivery(gw, "/api/deliveries/"+requestID+"/nack", `{}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "nack without error should be rejected")
+
+	rec = postDelivery(gw, "/api/deliveries/"+requestID+"/nack", `{"error":"not in room","code":"M_FORBIDDEN"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/deliveries/dead-letter?frontend=slack", nil)
+	rec = httptest.NewRecorder()
+	gw.handleDeliveryRoutes(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var dead DeadLetterResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&dead))
+	require.Len(t, dead.Deliveries, 1)
+	assert.Equal(t, requestID, dead.Deliveries[0].RequestID)
+	assert.Equal(t, "not in room", dead.Deliveries[0].Error)
+	assert.Equal(t, "M_FORBIDDEN", dead.Deliveries[0].ErrorCode)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/deliveries/stats", nil)
+	rec = httptest.NewRecorder()
+	gw.handleDeliveryRoutes(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var stats DeliveryStatsResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+	require.Len(t, stats.Frontends, 1)
+	assert.Equal(t, "slack", stats.Frontends[0].Frontend)
+	assert.Equal(t, int64(1), stats.Frontends[0].Nacked)
+	assert.Zero(t, stats.Frontends[0].SuccessRate)
+}
+
+func TestImplicitSend_DoesNotTrackDelivery(t *testing.T) {
+	gw := newTestGatewayWithMockManager(t)
+	createTestBindingV2(t, gw, "slack", "C001", "test-agent")
+
+	body := `{"sender":"u","content":"hi","frontend":"slack","channel_id":"C001"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/send", strings.NewReader(body))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	gw.handleSendMessage(rec, req.WithContext(ctx))
+
+	assert.NotContains(t, rec.Body.String(), "ack_mode")
+
+	stats, err := gw.deliveries.store.GetDeliveryStats(context.Background(), time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+}
+
+func TestParseSendRequest_RejectsUnknownAckMode(t *testing.T) {
+	_, err := parseSendRequest(strings.NewReader(`{"sender":"u","content":"hi","ack_mode":"sometimes"}`))
+	assert.Error(t, err)
+}
@@ -33,10 +33,15 @@
 //   - POST /api/send - Send message to an agent (SSE streaming response)
 //   - GET /api/agents - List connected agents
 //   - GET /api/threads - List conversation threads
+//   - GET /api/threads/{id}/export - Download a thread's transcript (thread_export.go)
 //   - GET /api/bindings - List channel bindings
 //   - POST /api/bindings - Create a binding
 //   - GET /health - Liveness check
-//   - GET /health/ready - Readiness check
+//   - GET /health/ready - Readiness checks as JSON (?verbose=1 adds listeners and storage status)
+//
+// List endpoints also have paginated successors under /api/v1 (api_v1.go)
+// that use the shared internal/httpapi envelope; the legacy list routes
+// answer with a Deprecation header pointing at them.
 //
 // # SSE Streaming
 //
@@ -52,7 +57,12 @@
 //	data: {"request_id": "..."}
 //
 // Event types: started, thinking, text, tool_use, tool_result, tool_state,
-// tool_approval, usage, done, error, canceled, session_init, session_orphaned.
+// tool_approval, question, usage, done, error, canceled, session_init,
+// session_orphaned.
+//
+// Text and thinking longer than server.sse_chunk_bytes are split on rune
+// boundaries, preferring newlines, into several events; every one after the
+// first carries "continued": true (see package ssechunk).
 //
 // # gRPC Service
 //
@@ -63,21 +73,31 @@
 //	}
 //
 // Agents connect via bidirectional streaming and maintain long-lived connections.
+// Each Welcome carries a session token. An agent that re-registers with it
+// within agents.reconnect_grace_period, even after a gateway restart, resumes
+// its session: requests it had not finished are re-sent as ResumeRequest and
+// their responses persisted to the thread (see sessions.go).
 //
 // # Question Routing
 //
 // The QuestionRouter handles interactive user questions from agents:
 //
-//  1. Agent calls ask_user tool
-//  2. QuestionRouter broadcasts question to connected clients
-//  3. Client answers via /api/questions/answer
-//  4. Answer is delivered back to the agent
+//  1. Agent calls ask_user tool; the Gateway attaches a context snapshot
+//     (thread, recent messages, in-flight tool calls) unless the agent opts out
+//  2. QuestionRouter stores the question and broadcasts it to connected
+//     clients, including a question event on the /api/send stream of the
+//     request the agent is working on; web chat clients that connect later
+//     get it replayed on subscribe
+//  3. Client answers via /api/questions/answer (pending ones: GET /api/questions/pending,
+//     filtered to the agents the caller's token may reach)
+//  4. The first answer is delivered back to the agent with the context
+//     version the answerer saw; later ones get 409
 //
 // # Event Broadcasting
 //
 // EventBroadcaster fans out events to all interested clients:
 //
-//	broadcaster.Subscribe(threadID) -> <-chan Event
+//	broadcaster.Subscribe(ctx, conversationKey) -> *Subscription
 //	broadcaster.Publish(threadID, event)
 //
 // Used for real-time updates in the web admin chat interface.
@@ -93,13 +113,25 @@
 // Graceful shutdown:
 //
 //	cancel()
-//	gw.Shutdown(shutdownCtx)
+//	report, err := gw.Shutdown(shutdownCtx)
+//
+// Shutdown is bounded by shutdownCtx's deadline. Components stop in phases
+// (stop accepting, drain, persist, close the store), concurrently within a
+// phase; any that miss their deadline are force-closed and marked Forced in
+// the returned ShutdownReport, which is also logged.
+//
+// Config reload: after SetConfigReload, Run reloads the config file on
+// SIGHUP. ApplyDynamic applies the settings that can change live under
+// configMu; other changes are logged as requiring a restart, and a file that
+// fails to load or validate changes nothing.
 //
 // # Key Files
 //
 //   - gateway.go: Gateway struct, initialization, Run/Shutdown
 //   - api.go: HTTP handlers and SSE streaming
 //   - grpc.go: gRPC service implementation
+//   - sessions.go: Agent session tokens and resumption
+//   - reload.go: Config reload on SIGHUP
 //   - question_router.go: Interactive question handling
 //   - event_broadcaster.go: Real-time event fanout
 package gateway
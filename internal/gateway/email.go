@@ -0,0 +1,72 @@
+// ABOUTME: Wires the email frontend from frontends.email into the gateway.
+// ABOUTME: Resolves mailboxes through "email" bindings and online agents for inbound mail.
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/email"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// newEmailFrontend builds the email frontend, or returns nil when it is
+// disabled. It does not start it.
+func (g *Gateway) newEmailFrontend(cfg config.EmailConfig, s email.MessageStore, logger *slog.Logger) *email.Frontend {
+	if !cfg.Enabled {
+		return nil
+	}
+	return email.New(email.Config{
+		Mode:       cfg.Mode,
+		Domain:     cfg.Domain,
+		ListenAddr: cfg.ListenAddr,
+		IMAP: email.IMAPConfig{
+			Addr:         cfg.IMAP.Addr,
+			Username:     cfg.IMAP.Username,
+			Password:     cfg.IMAP.Password,
+			Mailbox:      cfg.IMAP.Mailbox,
+			Insecure:     cfg.IMAP.Insecure,
+			PollInterval: cfg.IMAP.PollInterval,
+		},
+		Addresses:          cfg.Addresses,
+		AllowedSenders:     cfg.AllowedSenders,
+		RequireAuth:        cfg.RequireAuth,
+		MaxMessageBytes:    cfg.MaxMessageBytes,
+		MaxAttachmentBytes: cfg.MaxAttachmentBytes,
+		Relay: email.RelayConfig{
+			Addr:     cfg.Relay.Addr,
+			Username: cfg.Relay.Username,
+			Password: cfg.Relay.Password,
+			From:     cfg.Relay.From,
+		},
+	}, email.Deps{
+		Conversation: g.conversation,
+		Store:        s,
+		Lookup:       g.lookupEmailMailbox,
+		Online: func(agentID string) bool {
+			_, ok := g.agentManager.GetAgent(agentID)
+			return ok
+		},
+		Logger: logger,
+	})
+}
+
+// lookupEmailMailbox finds the online agent bound to a mailbox address.
+func (g *Gateway) lookupEmailMailbox(ctx context.Context, mailbox string) (*email.Target, error) {
+	binding, err := g.store.GetBindingByChannel(ctx, email.FrontendName, mailbox)
+	if errors.Is(err, store.ErrBindingNotFound) {
+		return nil, email.ErrUnknownMailbox
+	}
+	if err != nil {
+		return nil, fmt.Errorf("looking up email binding: %w", err)
+	}
+	conn := g.agentManager.GetByPrincipalAndWorkDir(binding.AgentID, binding.WorkingDir)
+	if conn == nil {
+		return nil, email.ErrAgentOffline
+	}
+	return &email.Target{AgentID: conn.ID, MaxDuration: binding.MaxResponseDuration, Context: binding.Context}, nil
+}
@@ -0,0 +1,44 @@
+// ABOUTME: Binding fallback routing: once a bound agent has been offline past its reconnect
+// ABOUTME: grace period, its channel's messages go to the first connected fallback agent
+
+package gateway
+
+import (
+	"context"
+
+	"github.com/2389/coven-gateway/internal/agent"
+)
+
+// bindingRoute picks the connection a binding's messages go to: the bound
+// agent while it is connected, else the first connected fallback agent once
+// the bound agent is past its reconnect grace period. While it may still
+// resume its session nothing is chosen, so its channel waits for it rather
+// than moving mid-conversation. fallback reports whether a fallback was chosen.
+func (g *Gateway) bindingRoute(ctx context.Context, agentID, workingDir string, fallbacks []string) (conn *agent.Connection, fallback bool) {
+	if conn := g.agentManager.GetByPrincipalAndWorkDir(agentID, workingDir); conn != nil {
+		return conn, false
+	}
+	if len(fallbacks) == 0 || (g.sessions != nil && g.sessions.inGrace(ctx, agentID)) {
+		return nil, false
+	}
+	for _, id := range fallbacks {
+		if conn := g.agentManager.GetByPrincipalAndWorkDir(id, workingDir); conn != nil {
+			return conn, true
+		}
+		if conns := g.agentManager.ListByPrincipal(id); len(conns) > 0 {
+			return conns[0], true
+		}
+	}
+	return nil, false
+}
+
+// fallbackSSE is the session_init event sent when a fallback agent takes a
+// binding's message: which agent handled it and which one it stood in for.
+func fallbackSSE(conn *agent.Connection, primaryID string) SSEEvent {
+	return SSEEvent{Event: "session_init", Data: map[string]any{
+		"agent_id":     conn.ID,
+		"agent_name":   conn.Name,
+		"principal_id": conn.PrincipalID,
+		"fallback_for": primaryID,
+	}}
+}
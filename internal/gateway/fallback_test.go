@@ -0,0 +1,106 @@
+// ABOUTME: Tests for binding fallback agents: routing once the bound agent is
+// ABOUTME: offline past its grace period, the session_init notice, and effective_agent_id.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestBindingFallback(t *testing.T) {
+	// The send's reply is persisted concurrently, so back the store with a file.
+	gw, err := New(sessionTestConfig(t), testLogger())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	sqlStore := gw.store.(*store.SQLStore)
+	ctx := context.Background()
+	for _, id := range []string{"agent-grace", "agent-gone", "agent-idle", "agent-backup"} {
+		createAgentPrincipal(t, gw, id)
+	}
+	for _, sess := range []*store.AgentSession{
+		{ID: "sess-grace", TokenHash: "h-grace", AgentID: "agent-grace", PrincipalID: "agent-grace", LastSeenAt: time.Now().Add(-time.Minute)},
+		{ID: "sess-gone", TokenHash: "h-gone", AgentID: "agent-gone", PrincipalID: "agent-gone", LastSeenAt: time.Now().Add(-time.Hour)},
+	} {
+		if err := sqlStore.CreateAgentSession(ctx, sess); err != nil {
+			t.Fatalf("CreateAgentSession: %v", err)
+		}
+	}
+	for channel, primary := range map[string]string{"C-grace": "agent-grace", "C-gone": "agent-gone"} {
+		if err := sqlStore.CreateBindingV2(ctx, &store.Binding{
+			ID: "binding-" + channel, Frontend: "slack", ChannelID: channel, AgentID: primary,
+			CreatedAt: time.Now(), FallbackAgentIDs: []string{"agent-idle", "agent-backup"},
+		}); err != nil {
+			t.Fatalf("CreateBindingV2: %v", err)
+		}
+	}
+
+	// agent-idle is the first fallback but not connected, so agent-backup answers.
+	stream := &replyingStream{reply: "backup here"}
+	stream.conn = agent.NewConnection(agent.ConnectionParams{
+		ID: "agent-backup", Name: "backup", PrincipalID: "agent-backup", Stream: stream, Logger: slog.Default(),
+	})
+	if err := gw.agentManager.Register(stream.conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	send := func(channel string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		body := `{"sender":"alice","content":"hi","frontend":"slack","channel_id":"` + channel + `"}`
+		gw.handleSendMessage(rec, httptest.NewRequest(http.MethodPost, "/api/send", strings.NewReader(body)))
+		return rec
+	}
+
+	// The bound agent may still resume its session, so its channel waits.
+	if rec := send("C-grace"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("send within grace: status %d, want 503", rec.Code)
+	}
+
+	rec := send("C-gone")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("send past grace: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	events := readSSEEvents(t, rec.Body.String())
+	if len(events) < 2 || events[0].name != "started" || events[1].name != "session_init" {
+		t.Fatalf("stream does not open with started and session_init: %+v", events)
+	}
+	if got := events[1].data; got["agent_id"] != "agent-backup" || got["fallback_for"] != "agent-gone" {
+		t.Errorf("session_init = %v, want agent-backup standing in for agent-gone", got)
+	}
+	var text string
+	for _, ev := range events {
+		if ev.name == "text" {
+			text, _ = ev.data["text"].(string)
+		}
+	}
+	if text != "backup here" {
+		t.Errorf("text = %q, want the fallback agent's reply", text)
+	}
+
+	rec = httptest.NewRecorder()
+	gw.handleBindings(rec, httptest.NewRequest(http.MethodGet, "/api/bindings", nil))
+	var list ListBindingsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	effective := map[string]string{}
+	for _, b := range list.Bindings {
+		effective[b.ChannelID] = b.EffectiveAgentID
+		if len(b.FallbackAgentIDs) != 2 {
+			t.Errorf("%s: fallback_agent_ids = %v", b.ChannelID, b.FallbackAgentIDs)
+		}
+	}
+	if effective["C-grace"] != "" || effective["C-gone"] != "agent-backup" {
+		t.Errorf("effective agents = %v, want none for C-grace and agent-backup for C-gone", effective)
+	}
+}
@@ -11,11 +11,15 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/keepalive"
 	"tailscale.com/ipn/ipnstate"
@@ -28,10 +32,21 @@ import (
 	"github.com/2389/coven-gateway/internal/client"
 	"github.com/2389/coven-gateway/internal/config"
 	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/coverr"
 	"github.com/2389/coven-gateway/internal/dedupe"
+	"github.com/2389/coven-gateway/internal/diskmon"
+	"github.com/2389/coven-gateway/internal/email"
+	"github.com/2389/coven-gateway/internal/flags"
+	"github.com/2389/coven-gateway/internal/httpapi"
 	"github.com/2389/coven-gateway/internal/mcp"
+	"github.com/2389/coven-gateway/internal/mcpbridge"
 	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/ratelimit"
+	"github.com/2389/coven-gateway/internal/reliability"
+	"github.com/2389/coven-gateway/internal/sdnotify"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/tracing"
+	"github.com/2389/coven-gateway/internal/usage"
 	"github.com/2389/coven-gateway/internal/webadmin"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
@@ -45,9 +60,12 @@ type Gateway struct {
 	conversation *conversation.Service
 	grpcServer   *grpc.Server
 	httpServer   *http.Server
-	tsnetServer  *tsnet.Server
-	webAdmin     *webadmin.Admin
-	logger       *slog.Logger
+	// adminHTTPServer serves the web admin UI on server.admin_http_addr; nil
+	// when the UI shares httpServer
+	adminHTTPServer *http.Server
+	tsnetServer     *tsnet.Server
+	webAdmin        *webadmin.Admin
+	logger          *slog.Logger
 
 	// serverID identifies this gateway instance
 	serverID string
@@ -61,6 +79,13 @@ type Gateway struct {
 	// packRouter routes tool calls to packs
 	packRouter *packs.Router
 
+	// disabledTools returns the tools an admin disabled for an agent, left
+	// out of the tool lists it is sent
+	disabledTools func(ctx context.Context, agentID string) map[string]string
+
+	// toolCatalog versions pack tool definitions and records their changelog
+	toolCatalog *packs.CatalogTracker
+
 	// mcpTokens maps MCP access tokens to agent capabilities
 	mcpTokens *mcp.TokenStore
 
@@ -70,12 +95,115 @@ type Gateway struct {
 	// mcpEndpoint is the base URL for MCP endpoint (e.g., "http://localhost:8080/mcp")
 	mcpEndpoint string
 
+	// webAdminBaseURL is the externally reachable admin UI URL, used in hints to pending agents
+	webAdminBaseURL string
+
+	// delegations tracks running delegate_task requests for depth and cycle checks
+	delegations *delegationTracker
+
 	// questionRouter handles ask_user tool question routing
 	questionRouter *builtins.InMemoryQuestionRouter
 
 	// eventBroadcaster handles cross-client event push
 	eventBroadcaster *conversation.EventBroadcaster
 
+	// deliveries tracks bridge acknowledgments for ack_mode=explicit sends
+	deliveries *deliveryTracker
+
+	// scheduler sends messages queued through /api/schedules when they come due
+	scheduler *scheduler
+
+	// flags gates rollout of new behaviors; overrides are toggled from the admin UI
+	flags *flags.Service
+
+	// reliability tracks per-tool and per-agent success rates
+	reliability *reliability.Tracker
+
+	// storage watches disk space and database growth; nil when disabled or in-memory
+	storage *diskmon.Monitor
+
+	// mcpBridge imports tools from packs.mcp_servers; nil when none are configured
+	mcpBridge *mcpbridge.Bridge
+
+	// systemd receives sd_notify state updates; nil when not run under systemd
+	systemd  *sdnotify.Notifier
+	watchdog time.Duration
+
+	// metrics is the Prometheus registry served at metrics.path
+	metrics *prometheus.Registry
+	// instruments are the gateway's own collectors on metrics
+	instruments *gatewayMetrics
+	// metricsHTTPServer serves metrics on metrics.addr; nil when metrics are
+	// disabled or share httpServer
+	metricsHTTPServer *http.Server
+
+	// stopTracing flushes and stops the span exporter; nil unless
+	// tracing.enabled
+	stopTracing func(context.Context) error
+
+	// apiRoutes records every HTTP API route registered on the mux, for the
+	// OpenAPI document and the tests that keep it complete
+	apiRoutes *httpapi.Routes
+
+	// email receives mail for agents; nil unless frontends.email is enabled
+	email *email.Frontend
+
+	// sessions issues agent session tokens and resumes sessions on reconnect
+	sessions *agentSessions
+
+	// attachments serves GET /api/attachments/{id}; attachmentLimits bound
+	// multipart uploads to POST /api/send
+	attachments      attachmentStore
+	attachmentLimits attachmentLimits
+
+	// agentGroups backs /api/groups and POST /api/send/broadcast
+	agentGroups agentGroupStore
+
+	// retention prunes rows past their retention period; pruneMu keeps the
+	// scheduled job and POST /api/admin/maintenance/prune from overlapping
+	retention retentionStore
+	pruneMu   sync.Mutex
+
+	// linkCodes is swept of device link codes long expired
+	linkCodes linkCodeStore
+
+	// loginAttempts is swept of failed admin logins no longer counted
+	loginAttempts loginAttemptStore
+
+	// usageReports and usagePricing back the usage rollups on
+	// /api/threads/{id}/usage and /api/usage/summary
+	usageReports usageReportStore
+	usagePricing usage.Pricing
+
+	// auditLog backs the admin audit listing on /api/v1/admin/audit
+	auditLog auditLogStore
+
+	// idempotency tracks send idempotency keys for /api/send and gRPC
+	// SendMessage; idempotencyKeys is swept of the expired ones
+	idempotency     *dedupe.Idempotency
+	idempotencyKeys idempotencyKeyStore
+
+	// rateLimits holds the api.rate_limit token buckets by route group;
+	// groups without a configured limit are absent
+	rateLimits map[string]*ratelimit.Limiter
+
+	// configMu guards what ApplyDynamic changes: rateLimits and the
+	// reloadable settings in config. configPath and applyLogging are set by
+	// SetConfigReload.
+	configMu     sync.RWMutex
+	configPath   string
+	applyLogging func(config.LoggingConfig)
+
+	// listeners tracks the HTTP listeners started by Run
+	listeners listenerSet
+	// grpcServing is set while the gRPC server is serving its listener
+	grpcServing atomic.Bool
+	// stopping is set when Shutdown begins, turning readiness false
+	stopping atomic.Bool
+
+	// metadataLimits bounds the metadata agents send at registration
+	metadataLimits agent.MetadataLimits
+
 	// mockSender is used for testing to inject a mock message sender
 	mockSender messageSender
 }
@@ -92,6 +220,11 @@ func determineWebAdminBaseURL(cfg *config.Config, logger *slog.Logger) string {
 		return envURL
 	}
 
+	// The admin UI is only reachable on its own listener when one is set
+	if cfg.Server.AdminHTTPAddr != "" {
+		return "http://" + cfg.Server.AdminHTTPAddr
+	}
+
 	// Auto-detect based on deployment mode
 	if !cfg.Tailscale.Enabled {
 		return "http://" + cfg.Server.HTTPAddr
@@ -105,20 +238,89 @@ func determineWebAdminBaseURL(cfg *config.Config, logger *slog.Logger) string {
 	return "http://" + cfg.Tailscale.Hostname
 }
 
-// initStore creates and returns a store based on config and environment.
-func initStore(cfg *config.Config) (store.Store, error) {
-	dbPath := cfg.Database.Path
+// webAdminOIDCConfig maps the SSO config block onto the web admin's options.
+func webAdminOIDCConfig(c config.OIDCConfig) webadmin.OIDCConfig {
+	return webadmin.OIDCConfig{
+		Issuer:         c.Issuer,
+		ClientID:       c.ClientID,
+		ClientSecret:   c.ClientSecret,
+		RedirectURL:    c.RedirectURL,
+		Scopes:         c.Scopes,
+		AllowedDomains: c.AllowedDomains,
+		RequiredGroups: c.RequiredGroups,
+		GroupsClaim:    c.GroupsClaim,
+		RoleClaim:      c.RoleClaim,
+		ProviderLogout: c.ProviderLogout,
+	}
+}
+
+// usagePricing converts usage.pricing into the price table usage reports use.
+func usagePricing(c map[string]config.TokenPrice) usage.Pricing {
+	p := make(usage.Pricing, len(c))
+	for key, price := range c {
+		p[key] = usage.Price(price)
+	}
+	return p
+}
+
+// determineAgentServerURL returns the gRPC URL agents should use, combining the
+// host agents reach the web admin on with the configured gRPC port.
+func determineAgentServerURL(cfg *config.Config, webAdminBaseURL string) string {
+	_, port, err := net.SplitHostPort(cfg.Server.GRPCAddr)
+	if err != nil || port == "" {
+		port = "50051"
+	}
+	host := "127.0.0.1"
+	if u, err := url.Parse(webAdminBaseURL); err == nil && u.Hostname() != "" && u.Hostname() != "0.0.0.0" {
+		host = u.Hostname()
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// databasePath returns the configured database path, honoring the
+// COVEN_DB_PATH override.
+func databasePath(cfg *config.Config) string {
 	if envPath := os.Getenv("COVEN_DB_PATH"); envPath != "" {
-		dbPath = envPath
+		return envPath
 	}
+	return cfg.Database.Path
+}
 
-	var s store.Store
-	var err error
-	if dbPath == ":memory:" {
-		s, err = store.NewSQLiteStore(":memory:")
-	} else {
-		s, err = store.NewSQLiteStore(dbPath)
+// agentConcurrency returns the per-agent request limit for the configured
+// agents.max_concurrent: DefaultMaxConcurrent when unset, zero (no limit)
+// when negative.
+func agentConcurrency(configured int) int {
+	switch {
+	case configured == 0:
+		return agent.DefaultMaxConcurrent
+	case configured < 0:
+		return 0
+	}
+	return configured
+}
+
+// agentIdleTimeout returns how long a request may wait on a silent agent
+// for the configured agents.idle_timeout: DefaultIdleTimeout when unset,
+// zero (never) when negative.
+func agentIdleTimeout(configured time.Duration) time.Duration {
+	switch {
+	case configured == 0:
+		return agent.DefaultIdleTimeout
+	case configured < 0:
+		return 0
 	}
+	return configured
+}
+
+// initStore creates and returns a store based on config and environment:
+// the SQLite file at databasePath, or the Postgres database at
+// database.dsn.
+func initStore(cfg *config.Config) (store.Store, error) {
+	source := databasePath(cfg)
+	if cfg.Database.Driver == store.DriverPostgres {
+		source = cfg.Database.DSN
+	}
+	s, err := store.Open(cfg.Database.Driver, source)
 	if err != nil {
 		return nil, fmt.Errorf("initializing store: %w", err)
 	}
@@ -127,17 +329,20 @@ func initStore(cfg *config.Config) (store.Store, error) {
 
 // grpcServerResult holds the result of creating a gRPC server.
 type grpcServerResult struct {
-	server      *grpc.Server
-	jwtVerifier *auth.JWTVerifier
+	server        *grpc.Server
+	jwtVerifier   *auth.JWTVerifier
+	tokenVerifier *auth.TrackedVerifier // checks recorded tokens for revocation and scopes
+	tokenMinter   *auth.TokenMinter
 }
 
 // createAuthenticatedGRPCServer creates a gRPC server with JWT and SSH auth interceptors.
-func createAuthenticatedGRPCServer(cfg *config.Config, sqlStore *store.SQLiteStore, logger *slog.Logger) (*grpcServerResult, error) {
+func createAuthenticatedGRPCServer(cfg *config.Config, sqlStore *store.SQLStore, logger *slog.Logger) (*grpcServerResult, error) {
 	jwtVerifier, err := auth.NewJWTVerifier([]byte(cfg.Auth.JWTSecret))
 	if err != nil {
 		return nil, fmt.Errorf("creating JWT verifier: %w", err)
 	}
 
+	tokenVerifier := auth.NewTrackedVerifier(jwtVerifier, sqlStore, logger.With("component", "tokens"))
 	sshVerifier := auth.NewSSHVerifier()
 
 	authConfig := &auth.AuthConfig{
@@ -147,7 +352,7 @@ func createAuthenticatedGRPCServer(cfg *config.Config, sqlStore *store.SQLiteSto
 		authConfig.AgentAutoRegistration = "disabled"
 	}
 
-	server := grpc.NewServer(
+	server := grpc.NewServer(append(grpcMessageOptions(cfg),
 		grpc.KeepaliveParams(keepalive.ServerParameters{
 			Time:    15 * time.Second,
 			Timeout: 5 * time.Second,
@@ -157,21 +362,28 @@ func createAuthenticatedGRPCServer(cfg *config.Config, sqlStore *store.SQLiteSto
 			PermitWithoutStream: true,
 		}),
 		grpc.ChainUnaryInterceptor(
-			auth.UnaryInterceptor(sqlStore, sqlStore, jwtVerifier, sshVerifier, authConfig, sqlStore, logger),
-			auth.RequireAdmin(logger),
+			coverr.UnaryServerInterceptor(),
+			auth.UnaryInterceptor(sqlStore, sqlStore, tokenVerifier, sshVerifier, authConfig, sqlStore, logger),
+			auth.RequireAdmin(sqlStore, logger),
 		),
 		grpc.ChainStreamInterceptor(
-			auth.StreamInterceptor(sqlStore, sqlStore, jwtVerifier, sshVerifier, authConfig, sqlStore, logger),
-			auth.RequireAdminStream(logger),
+			coverr.StreamServerInterceptor(),
+			auth.StreamInterceptor(sqlStore, sqlStore, tokenVerifier, sshVerifier, authConfig, sqlStore, logger),
+			auth.RequireAdminStream(sqlStore, logger),
 		),
-	)
+	)...)
 	logger.Info("auth interceptors enabled (JWT + SSH)")
-	return &grpcServerResult{server: server, jwtVerifier: jwtVerifier}, nil
+	return &grpcServerResult{
+		server:        server,
+		jwtVerifier:   jwtVerifier,
+		tokenVerifier: tokenVerifier,
+		tokenMinter:   auth.NewTokenMinter(jwtVerifier, sqlStore),
+	}, nil
 }
 
 // createUnauthenticatedGRPCServer creates a gRPC server without auth (anonymous mode).
-func createUnauthenticatedGRPCServer(logger *slog.Logger) *grpc.Server {
-	server := grpc.NewServer(
+func createUnauthenticatedGRPCServer(cfg *config.Config, logger *slog.Logger) *grpc.Server {
+	server := grpc.NewServer(append(grpcMessageOptions(cfg),
 		grpc.KeepaliveParams(keepalive.ServerParameters{
 			Time:    15 * time.Second,
 			Timeout: 5 * time.Second,
@@ -180,33 +392,33 @@ func createUnauthenticatedGRPCServer(logger *slog.Logger) *grpc.Server {
 			MinTime:             5 * time.Second,
 			PermitWithoutStream: true,
 		}),
-		grpc.ChainUnaryInterceptor(auth.NoAuthUnaryInterceptor()),
-		grpc.ChainStreamInterceptor(auth.NoAuthStreamInterceptor()),
-	)
+		grpc.ChainUnaryInterceptor(coverr.UnaryServerInterceptor(), auth.NoAuthUnaryInterceptor()),
+		grpc.ChainStreamInterceptor(coverr.StreamServerInterceptor(), auth.NoAuthStreamInterceptor()),
+	)...)
 	logger.Warn("auth disabled - no jwt_secret configured")
 	return server
 }
 
 // createGRPCServer creates a gRPC server with or without auth based on config.
-func createGRPCServer(cfg *config.Config, sqlStore *store.SQLiteStore, logger *slog.Logger) (*grpcServerResult, error) {
+func createGRPCServer(cfg *config.Config, sqlStore *store.SQLStore, logger *slog.Logger) (*grpcServerResult, error) {
 	if cfg.Auth.JWTSecret != "" {
 		return createAuthenticatedGRPCServer(cfg, sqlStore, logger)
 	}
-	return &grpcServerResult{server: createUnauthenticatedGRPCServer(logger)}, nil
+	return &grpcServerResult{server: createUnauthenticatedGRPCServer(cfg, logger)}, nil
 }
 
 // registerBuiltinPacks registers all builtin packs with the registry.
-func registerBuiltinPacks(registry *packs.Registry, agentMgr *agent.Manager, s store.Store, builtinStore *store.SQLiteStore) error {
-	if err := registry.RegisterBuiltinPack(builtins.BasePack(builtinStore)); err != nil {
+func registerBuiltinPacks(registry *packs.Registry, agentMgr *agent.Manager, s store.Store, builtinStore *store.SQLStore, limits builtins.Limits) error {
+	if err := registry.RegisterBuiltinPack(builtins.BasePackWithLimits(builtinStore, limits)); err != nil {
 		return fmt.Errorf("registering base pack: %w", err)
 	}
 	if err := registry.RegisterBuiltinPack(builtins.AdminPack(agentMgr, s, builtinStore)); err != nil {
 		return fmt.Errorf("registering admin pack: %w", err)
 	}
-	if err := registry.RegisterBuiltinPack(builtins.MailPack(builtinStore)); err != nil {
+	if err := registry.RegisterBuiltinPack(builtins.MailPackWithLimits(builtinStore, limits)); err != nil {
 		return fmt.Errorf("registering mail pack: %w", err)
 	}
-	if err := registry.RegisterBuiltinPack(builtins.NotesPack(builtinStore)); err != nil {
+	if err := registry.RegisterBuiltinPack(builtins.NotesPackWithLimits(builtinStore, limits)); err != nil {
 		return fmt.Errorf("registering notes pack: %w", err)
 	}
 	return nil
@@ -233,17 +445,20 @@ func determineMCPEndpoint(cfg *config.Config, logger *slog.Logger) string {
 
 // registerGRPCServices registers all gRPC services on the server.
 // Returns the clientService for additional configuration.
-func registerGRPCServices(gw *Gateway, grpcServer *grpc.Server, jwtVerifier *auth.JWTVerifier, sqlStore *store.SQLiteStore, dedupeCache *dedupe.Cache, agentMgr *agent.Manager, eventBroadcaster *conversation.EventBroadcaster, logger *slog.Logger) *client.ClientService {
+func registerGRPCServices(gw *Gateway, grpcServer *grpc.Server, grpcResult *grpcServerResult, sqlStore *store.SQLStore, dedupeCache *dedupe.Cache, agentMgr *agent.Manager, eventBroadcaster *conversation.EventBroadcaster, logger *slog.Logger) *client.ClientService {
 	// Register CovenControl service (agent streaming)
 	covenService := newCovenControlServer(gw, logger.With("component", "grpc"))
 	pb.RegisterCovenControlServer(grpcServer, covenService)
 
 	// Register AdminService - PrincipalService if auth enabled, basic otherwise
-	if jwtVerifier != nil {
-		principalService := admin.NewPrincipalService(sqlStore, jwtVerifier)
+	if grpcResult.jwtVerifier != nil {
+		principalService := admin.NewPrincipalService(sqlStore, grpcResult.jwtVerifier)
+		principalService.SetTokenMinter(grpcResult.tokenMinter)
+		principalService.SetAgentLookup(gw.bindingAgent)
 		pb.RegisterAdminServiceServer(grpcServer, principalService)
 	} else {
 		adminService := admin.NewAdminService(sqlStore)
+		adminService.SetAgentLookup(gw.bindingAgent)
 		pb.RegisterAdminServiceServer(grpcServer, adminService)
 	}
 
@@ -251,51 +466,88 @@ func registerGRPCServices(gw *Gateway, grpcServer *grpc.Server, jwtVerifier *aut
 	clientService := client.NewClientServiceWithRouter(sqlStore, sqlStore, dedupeCache, agentMgr, agentMgr)
 	clientService.SetToolApprover(agentMgr)
 	clientService.SetBroadcaster(eventBroadcaster)
+	clientService.SetIdempotency(gw.idempotency)
 	pb.RegisterClientServiceServer(grpcServer, clientService)
 
 	// Register PackService for tool pack support
 	packService := packs.NewPackServiceServer(gw.packRegistry, gw.packRouter, logger.With("component", "pack-service"))
+	packService.SetCatalog(gw.toolCatalog)
+	packService.SetToolsChangedHandler(gw.broadcastToolsChanged)
 	pb.RegisterPackServiceServer(grpcServer, packService)
 
 	return clientService
 }
 
-// registerHTTPAPIRoutes registers API routes on the mux with or without auth middleware.
-func (g *Gateway) registerHTTPAPIRoutes(mux *http.ServeMux, cfg *config.Config, sqlStore *store.SQLiteStore, logger *slog.Logger) error {
-	if cfg.Auth.JWTSecret != "" {
-		httpVerifier, err := auth.NewJWTVerifier([]byte(cfg.Auth.JWTSecret))
-		if err != nil {
-			return fmt.Errorf("creating HTTP JWT verifier: %w", err)
-		}
-		authMiddleware := auth.HTTPAuthMiddleware(sqlStore, sqlStore, httpVerifier, logger)
-		adminMiddleware := auth.RequireAdminHTTP(logger)
-		mux.Handle("/api/agents", authMiddleware(http.HandlerFunc(g.handleListAgents)))
-		mux.Handle("/api/agents/", authMiddleware(http.HandlerFunc(g.handleAgentHistory)))
-		mux.Handle("/api/send", authMiddleware(http.HandlerFunc(g.handleSendMessage)))
-		mux.Handle("/api/threads/", authMiddleware(http.HandlerFunc(g.handleThreadRoutes)))
-		mux.Handle("/api/stats/usage", authMiddleware(http.HandlerFunc(g.handleUsageStats)))
-		mux.Handle("/api/tools/approve", authMiddleware(http.HandlerFunc(g.handleToolApproval)))
-		mux.Handle("/api/questions/answer", authMiddleware(http.HandlerFunc(g.handleAnswerQuestion)))
-		mux.Handle("/api/bindings", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.Method == http.MethodPost || r.Method == http.MethodDelete {
-				adminMiddleware(http.HandlerFunc(g.handleBindings)).ServeHTTP(w, r)
-			} else {
-				g.handleBindings(w, r)
-			}
-		})))
+// registerHTTPAPIRoutes registers API routes on the mux with or without auth
+// middleware. api.rate_limit limits apply inside auth, keyed by principal.
+// The tokenVerifier is nil when auth is disabled.
+// The API lives under /api/v1; the unversioned /api routes it replaced keep
+// their shapes and mark their responses deprecated.
+func (g *Gateway) registerHTTPAPIRoutes(mux *http.ServeMux, sqlStore *store.SQLStore, tokenVerifier *auth.TrackedVerifier, logger *slog.Logger) {
+	limitDefault, limitSend := g.rateLimit(rateGroupDefault), g.rateLimit(rateGroupSend)
+	authenticate := func(next http.Handler) http.Handler { return next }
+	requireAdmin, wsAuth := authenticate, authenticate
+	if tokenVerifier != nil {
+		authenticate = auth.HTTPAuthMiddleware(sqlStore, sqlStore, tokenVerifier, logger)
+		requireAdmin = auth.RequireAdminHTTP(logger)
+		wsAuth = wsAuthMiddleware(sqlStore, authenticate)
 		logger.Info("HTTP auth middleware enabled")
 	} else {
-		mux.HandleFunc("/api/agents", g.handleListAgents)
-		mux.HandleFunc("/api/agents/", g.handleAgentHistory)
-		mux.HandleFunc("/api/send", g.handleSendMessage)
-		mux.HandleFunc("/api/bindings", g.handleBindings)
-		mux.HandleFunc("/api/threads/", g.handleThreadRoutes)
-		mux.HandleFunc("/api/stats/usage", g.handleUsageStats)
-		mux.HandleFunc("/api/tools/approve", g.handleToolApproval)
-		mux.HandleFunc("/api/questions/answer", g.handleAnswerQuestion)
 		logger.Warn("HTTP auth disabled - no jwt_secret configured")
 	}
-	return nil
+
+	user := httpapi.NewRoutes(mux, func(next http.Handler) http.Handler { return authenticate(limitDefault(next)) }, logger)
+	r := &apiRoutes{
+		user:   user,
+		admin:  user.With(func(next http.Handler) http.Handler { return authenticate(limitDefault(requireAdmin(next))) }),
+		send:   user.With(func(next http.Handler) http.Handler { return tracing.HTTPMiddleware(authenticate(limitSend(next))) }),
+		ws:     user.With(func(next http.Handler) http.Handler { return wsAuth(limitDefault(next)) }),
+		public: user.With(limitDefault),
+	}
+	g.registerV1Routes(r)
+	g.registerLegacyRoutes(r, requireAdmin)
+	r.public.HandleDocument(httpapi.Operation{
+		Method: http.MethodGet, Path: openAPIPath, Tag: "Meta", Summary: "Get this OpenAPI document",
+		Description: "The OpenAPI 3.0 document, as JSON.", Public: true,
+	}, openAPIInfo, sseEvents, webadmin.SessionCookieName)
+	g.apiRoutes = user
+}
+
+// registerLegacyRoutes registers the unversioned /api routes, which parse
+// their own paths and methods. Binding reads are open to any principal;
+// binding changes need an admin, as on /api/v1.
+func (g *Gateway) registerLegacyRoutes(r *apiRoutes, requireAdmin func(http.Handler) http.Handler) {
+	r.user.HandleDeprecated("/api/agents", http.HandlerFunc(g.handleListAgents))
+	r.user.HandleDeprecated("/api/agents/", http.HandlerFunc(g.handleAgentHistory))
+	r.send.HandleDeprecated("/api/send", http.HandlerFunc(g.handleSendMessage))
+	r.send.HandleDeprecated("/api/send/broadcast", http.HandlerFunc(g.handleBroadcast))
+	r.user.HandleDeprecated("/api/threads", http.HandlerFunc(g.handleListThreads))
+	r.user.HandleDeprecated("/api/threads/", http.HandlerFunc(g.handleThreadRoutes))
+	r.user.HandleDeprecated("/api/stats/usage", http.HandlerFunc(g.handleUsageStats))
+	r.user.HandleDeprecated("/api/usage/summary", http.HandlerFunc(g.handleUsageSummary))
+	r.user.HandleDeprecated("/api/tools/approve", http.HandlerFunc(g.handleToolApproval))
+	r.user.HandleDeprecated("/api/questions", http.HandlerFunc(g.handleListQuestions))
+	r.user.HandleDeprecated("/api/questions/answer", http.HandlerFunc(g.handleAnswerQuestion))
+	r.user.HandleDeprecated("/api/questions/pending", http.HandlerFunc(g.handlePendingQuestions))
+	r.user.HandleDeprecated("/api/deliveries/", http.HandlerFunc(g.handleDeliveryRoutes))
+	r.user.HandleDeprecated("/api/requests/", http.HandlerFunc(g.handleRequestRoutes))
+	r.user.HandleDeprecated("/api/attachments/", http.HandlerFunc(g.handleGetAttachment))
+	r.admin.HandleDeprecated("/api/schedules", http.HandlerFunc(g.handleSchedules))
+	r.admin.HandleDeprecated("/api/schedules/", http.HandlerFunc(g.handleSchedules))
+	r.admin.HandleDeprecated("/api/groups", http.HandlerFunc(g.handleGroups))
+	r.admin.HandleDeprecated("/api/groups/", http.HandlerFunc(g.handleGroups))
+	r.admin.HandleDeprecated("/api/search", http.HandlerFunc(g.handleSearch))
+	r.admin.HandleDeprecated("/api/admin/maintenance/prune", http.HandlerFunc(g.handlePrune))
+	r.ws.HandleDeprecated("/api/ws", http.HandlerFunc(g.handleWebSocket))
+
+	adminBindings := requireAdmin(http.HandlerFunc(g.handleBindings))
+	r.user.HandleDeprecated("/api/bindings", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost || req.Method == http.MethodPatch || req.Method == http.MethodDelete {
+			adminBindings.ServeHTTP(w, req)
+		} else {
+			g.handleBindings(w, req)
+		}
+	}))
 }
 
 // New creates a new Gateway instance with the given configuration.
@@ -308,9 +560,9 @@ func New(cfg *config.Config, logger *slog.Logger) (*Gateway, error) {
 	agentMgr := agent.NewManager(logger.With("component", "agent-manager"))
 	dedupeCache := dedupe.New(5*time.Minute, 100_000) // TTL 5min, max 100k entries
 
-	sqlStore, ok := s.(*store.SQLiteStore)
+	sqlStore, ok := s.(*store.SQLStore)
 	if !ok {
-		return nil, errors.New("unexpected store type: expected SQLiteStore")
+		return nil, errors.New("unexpected store type: expected SQLStore")
 	}
 
 	grpcResult, err := createGRPCServer(cfg, sqlStore, logger)
@@ -319,14 +571,77 @@ func New(cfg *config.Config, logger *slog.Logger) (*Gateway, error) {
 	}
 
 	eventBroadcaster := conversation.NewEventBroadcaster(logger.With("component", "broadcaster"))
+	if cfg.Server.StreamRetention > 0 {
+		eventBroadcaster.SetRequestRetention(cfg.Server.StreamRetention)
+	}
 	convService := conversation.New(sqlStore, agentMgr, logger.With("component", "conversation"), eventBroadcaster)
+	convService.SetAttachmentStore(sqlStore)
+
+	tracker := reliability.New(reliability.Config(cfg.Metrics.Reliability))
+	agentMgr.SetOutcomeObserver(tracker.RecordAgent)
+	agentMgr.SetDegradedCheck(func(agentID string) bool {
+		st, ok := tracker.Stat(reliability.KindAgent, agentID)
+		return ok && st.BelowThreshold
+	})
+
+	agentMgr.SetMaxResponseDuration(cfg.Agents.MaxResponseDuration)
+	agentMgr.SetIdleTimeout(agentIdleTimeout(cfg.Agents.IdleTimeout))
+	agentMgr.SetConcurrencyLimit(agentConcurrency(cfg.Agents.MaxConcurrent), cfg.Agents.QueueSize)
+	agentMgr.SetHealthThresholds(agentHealthThresholds(cfg.Agents))
+	agentMgr.SetHealthObserver(agentHealthRecorder(s, logger.With("component", "agent-health")))
+	queueDepth := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "coven_agent_queue_depth",
+		Help: "Requests waiting for a free slot at their agent's concurrency limit.",
+	}, []string{"agent"})
+	agentMgr.SetQueueObserver(func(agentID string, depth int) {
+		if depth == 0 {
+			queueDepth.DeleteLabelValues(agentID)
+			return
+		}
+		queueDepth.WithLabelValues(agentID).Set(float64(depth))
+	})
+	truncated := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "coven_responses_truncated_total",
+		Help: "Responses the gateway cut short, by agent and reason.",
+	}, []string{"agent", "reason"})
+	agentMgr.SetTruncationObserver(func(agentID string, ev *agent.TruncatedEvent) {
+		truncated.WithLabelValues(agentID, ev.Reason).Inc()
+	})
+
+	instruments := newGatewayMetrics()
+	sqlStore.SetQueryObserver(instruments.observeStoreQuery)
 
 	packRegistry := packs.NewRegistry(logger.With("component", "pack-registry"))
-	packRouter := packs.NewRouter(packs.RouterConfig{
+	packRegistry.SetHeartbeatMissWindow(cfg.Packs.HeartbeatMissWindow)
+	disabledTools := principalDisabledTools(agentMgr, sqlStore, logger.With("component", "tool-overrides"))
+	routerCfg := packs.RouterConfig{
 		Registry: packRegistry,
 		Logger:   logger.With("component", "pack-router"),
-	})
-	if err := registerBuiltinPacks(packRegistry, agentMgr, s, sqlStore); err != nil {
+		OnResult: func(toolName, _ string, ok bool) { tracker.RecordTool(toolName, ok) },
+		OnCall:   instruments.observeToolCall,
+
+		CacheMaxEntries: cfg.Packs.ToolCacheMaxEntries,
+		OnCacheLookup:   instruments.observeToolCacheLookup,
+
+		Capabilities:          principalCapabilities(agentMgr, sqlStore, logger.With("component", "pack-router")),
+		CapabilityEnforcement: capabilityEnforcement(cfg.Packs.CapabilityEnforcement),
+		OnCapabilityWarning:   capabilityWarningRecorder(agentMgr, s, logger.With("component", "pack-router")),
+		OnCheck:               toolCheckRecorder(s, logger.With("component", "pack-router")),
+		DisabledTools:         disabledTools,
+
+		ToolPolicies: sqlStore,
+		AgentGroups:  principalGroups(agentMgr, sqlStore, logger.With("component", "pack-router")),
+	}
+	if cfg.Agents.BlockPausedToolCalls {
+		routerCfg.CallerCheck = agentMgr.CheckNotPaused
+	}
+	packRouter := packs.NewRouter(routerCfg)
+	agentMgr.SetToolApprovalPolicy(toolApprovalPolicy(packRouter, s, logger.With("component", "tool-policy")))
+	builtinLimits, err := builtins.NewLimits(cfg.Packs.Builtins.MaxFieldBytes, cfg.Packs.Builtins.DailyQuota)
+	if err != nil {
+		return nil, fmt.Errorf("packs.builtins: %w", err)
+	}
+	if err := registerBuiltinPacks(packRegistry, agentMgr, s, sqlStore, builtinLimits); err != nil {
 		return nil, err
 	}
 
@@ -344,72 +659,188 @@ func New(cfg *config.Config, logger *slog.Logger) (*Gateway, error) {
 		dedupe:           dedupeCache,
 		packRegistry:     packRegistry,
 		packRouter:       packRouter,
+		disabledTools:    disabledTools,
+		toolCatalog:      packs.NewCatalogTracker(sqlStore, logger.With("component", "tool-catalog")),
 		mcpTokens:        mcpTokens,
 		mcpEndpoint:      mcpEndpoint,
 		eventBroadcaster: eventBroadcaster,
-	}
+		deliveries:       newDeliveryTracker(sqlStore, cfg.Frontends.DeliveryAckTimeout, logger.With("component", "deliveries")),
+		flags:            flags.New(sqlStore, logger.With("component", "flags")),
+		reliability:      tracker,
+		metrics:          prometheus.NewRegistry(),
+		instruments:      instruments,
+		metadataLimits:   agent.MetadataLimits(cfg.Agents.MetadataLimits),
+		sessions:         newAgentSessions(sqlStore, cfg.Agents.ReconnectGracePeriod, logger.With("component", "agent-sessions")),
+		rateLimits:       newRateLimiters(cfg.API, logger),
+		attachments:      sqlStore,
+		attachmentLimits: newAttachmentLimits(cfg.API.Attachments),
+		agentGroups:      sqlStore,
+		retention:        sqlStore,
+		linkCodes:        sqlStore,
+		loginAttempts:    sqlStore,
+		usageReports:     sqlStore,
+		auditLog:         sqlStore,
+		idempotency:      dedupe.NewIdempotency(sqlStore, cfg.API.IdempotencyTTL),
+		idempotencyKeys:  sqlStore,
+		usagePricing:     usagePricing(cfg.Usage.Pricing),
+		delegations:      newDelegationTracker(),
+	}
+	gw.metrics.MustRegister(tracker, truncated, queueDepth, newLiveCollector(agentMgr, eventBroadcaster))
+	gw.metrics.MustRegister(instruments.collectors()...)
+	gw.scheduler = newScheduler(sqlStore, gw.sendScheduled, logger.With("component", "scheduler"))
+	agentMgr.SetRequestObserver(gw.sessions.recordRequest)
+	agentMgr.SetLateUsageObserver(convService.SaveLateUsage)
 
 	// Register gRPC services
-	clientService := registerGRPCServices(gw, grpcServer, grpcResult.jwtVerifier, sqlStore, dedupeCache, agentMgr, eventBroadcaster, logger)
+	clientService := registerGRPCServices(gw, grpcServer, grpcResult, sqlStore, dedupeCache, agentMgr, eventBroadcaster, logger)
+
+	if b := newMCPBridge(cfg.Packs.MCPServers, packRegistry, sqlStore, logger.With("component", "mcp-bridge")); b != nil {
+		gw.mcpBridge = b
+		b.Start()
+	}
+
+	if f := gw.newEmailFrontend(cfg.Frontends.Email, sqlStore, logger.With("component", "email")); f != nil {
+		if err := f.Start(); err != nil {
+			return nil, fmt.Errorf("starting email frontend: %w", err)
+		}
+		gw.email = f
+	}
+
+	if t := cfg.Tracing; t.Enabled {
+		stop, err := tracing.Setup(context.Background(), tracing.Config{
+			Endpoint:    t.Endpoint,
+			Insecure:    t.Insecure,
+			SampleRatio: t.SampleRatio,
+			ServiceName: t.ServiceName,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("setting up tracing: %w", err)
+		}
+		gw.stopTracing = stop
+		logger.Info("tracing enabled", "endpoint", t.Endpoint, "sample_ratio", t.SampleRatio)
+	}
+
+	// A Postgres database lives on another host, so there is no local
+	// disk to watch.
+	if cfg.Database.Driver == store.DriverPostgres {
+		logger.Info("storage monitor disabled: database is Postgres")
+	} else if m := newStorageMonitor(cfg.Database.Monitor, databasePath(cfg), logger.With("component", "storage")); m != nil {
+		gw.attachStorageMonitor(m)
+		clientService.SetWriteGuard(m.CheckWrite)
+		m.Start()
+	}
 
-	// Create HTTP server for health checks and API
+	// Create HTTP server for health checks and API. The web admin UI shares
+	// it unless server.admin_http_addr gives the UI a listener of its own.
 	mux := http.NewServeMux()
+	adminMux := mux
+	if cfg.Server.AdminHTTPAddr != "" {
+		adminMux = http.NewServeMux()
+	}
 
 	// Health endpoints - no auth required
 	mux.HandleFunc("/health", gw.handleHealth)
 	mux.HandleFunc("/health/ready", gw.handleReady)
 
-	// API endpoints - auth required if JWT secret is configured
-	if err := gw.registerHTTPAPIRoutes(mux, cfg, sqlStore, logger); err != nil {
-		return nil, err
+	if cfg.Metrics.Enabled {
+		gw.registerMetricsHandler(mux)
 	}
 
+	// API endpoints - auth required if JWT secret is configured
+	gw.registerHTTPAPIRoutes(mux, sqlStore, grpcResult.tokenVerifier, logger)
+
 	// Register web admin UI routes
 	// The admin UI has its own session-based auth (separate from JWT)
 	webAdminBaseURL := determineWebAdminBaseURL(cfg, logger)
+	gw.webAdminBaseURL = webAdminBaseURL
 	webAdminCfg := webadmin.NewConfig{
 		Store:        sqlStore,
 		Manager:      gw.agentManager,
 		Conversation: convService,
 		Broadcaster:  eventBroadcaster,
 		Registry:     packRegistry,
+		Flags:        gw.flags,
+		Reliability:  tracker,
+		Storage:      gw.storage,
 		Config: webadmin.Config{
-			BaseURL: webAdminBaseURL,
+			BaseURL:        webAdminBaseURL,
+			AgentServerURL: determineAgentServerURL(cfg, webAdminBaseURL),
+			OIDC:           webAdminOIDCConfig(cfg.WebAdmin.OIDC),
+			BuiltinLimits:  builtinLimits,
+
+			CapabilityEnforcement: capabilityEnforcement(cfg.Packs.CapabilityEnforcement),
+			UsagePricing:          gw.usagePricing,
+			SSEChunkBytes:         cfg.Server.SSEChunkBytes,
+
+			PendingAgentWebhookURL: cfg.WebAdmin.PendingAgentWebhookURL,
 		},
 		PrincipalStore: sqlStore,
 		TokenGenerator: grpcResult.jwtVerifier, // May be nil if auth is disabled
 	}
+	if grpcResult.tokenMinter != nil {
+		webAdminCfg.TokenMinter = grpcResult.tokenMinter
+	}
 	gw.webAdmin = webadmin.NewWithConfig(webAdminCfg)
-	gw.webAdmin.RegisterRoutes(mux)
+	gw.webAdmin.RegisterRoutes(adminMux)
+	if adminMux != mux {
+		// Devices link over the API listener; they can't reach the UI's.
+		gw.webAdmin.RegisterDeviceRoutes(mux)
+	}
 	logger.Info("admin web UI enabled at /admin/", "base_url", webAdminBaseURL)
 
-	// Create question router for ask_user tool (uses webAdmin as ClientStreamer)
-	gw.questionRouter = builtins.NewInMemoryQuestionRouter(gw.webAdmin)
-	if err := packRegistry.RegisterBuiltinPack(builtins.UIPack(gw.questionRouter)); err != nil {
+	// Create question router for ask_user tool. The gateway streams questions
+	// to the asking request's SSE stream and the agent's web chat.
+	gw.questionRouter = builtins.NewInMemoryQuestionRouter(gw)
+	gw.questionRouter.SetStore(sqlStore)
+	// Questions left pending by the previous process have no tool call
+	// waiting on them any more.
+	if n, err := sqlStore.ExpirePendingQuestions(context.Background()); err != nil {
+		logger.Warn("failed to expire questions from previous run", "error", err)
+	} else if n > 0 {
+		logger.Info("expired questions from previous run", "count", n)
+	}
+	if err := packRegistry.RegisterBuiltinPack(builtins.UIPackWithContext(gw.questionRouter, gw)); err != nil {
 		return nil, fmt.Errorf("registering UI pack: %w", err)
 	}
-	// Wire up question answerer to ClientService
+	if err := packRegistry.RegisterBuiltinPack(builtins.DelegatePack(gw, sqlStore)); err != nil {
+		return nil, fmt.Errorf("registering delegate pack: %w", err)
+	}
+	// Wire up question answerer to ClientService and the admin UI
 	clientService.SetQuestionAnswerer(gw.questionRouter)
+	gw.webAdmin.SetQuestionRouter(gw.questionRouter)
+	gw.webAdmin.SetCapabilityNotifier(gw)
+	gw.webAdmin.SetToolOverrideNotifier(gw)
+	gw.agentManager.SetPendingObserver(gw.webAdmin.AgentPending)
 
 	// Register MCP server routes for tool pack access
 	// MCP endpoints allow external agents (like Claude Code) to list and execute pack tools
-	mcpServer, err := mcp.NewServer(mcp.Config{
-		Registry:    packRegistry,
-		Router:      packRouter,
-		TokenStore:  mcpTokens,
-		Logger:      logger.With("component", "mcp"),
-		RequireAuth: false, // MCP endpoints don't require auth for now
-	})
+	mcpConfig := mcp.Config{
+		Registry:      packRegistry,
+		Router:        packRouter,
+		TokenStore:    mcpTokens,
+		Logger:        logger.With("component", "mcp"),
+		RequireAuth:   false, // MCP endpoints don't require auth for now
+		Resources:     sqlStore,
+		Capabilities:  sqlStore.ListCapabilities,
+		DisabledTools: disabledTools,
+		MaxBatchSize:  cfg.MCP.MaxBatchSize,
+		BatchWorkers:  cfg.MCP.BatchWorkers,
+	}
+	if grpcResult.tokenVerifier != nil {
+		// API tokens are accepted as bearer tokens, with their agent and
+		// capability limits applied.
+		mcpConfig.TokenVerifier = grpcResult.tokenVerifier
+	}
+	mcpServer, err := mcp.NewServer(mcpConfig)
 	if err != nil {
 		return nil, fmt.Errorf("creating MCP server: %w", err)
 	}
 	gw.mcpServer = mcpServer
 	gw.mcpServer.RegisterRoutes(mux)
 
-	gw.httpServer = &http.Server{
-		Addr:              cfg.Server.HTTPAddr,
-		Handler:           webadmin.CSPMiddleware(mux),
-		ReadHeaderTimeout: 10 * time.Second,
+	gw.httpServer = newHTTPServer(cfg.Server.HTTPAddr, mux)
+	if adminMux != mux {
+		gw.adminHTTPServer = newHTTPServer(cfg.Server.AdminHTTPAddr, adminMux)
 	}
 
 	return gw, nil
@@ -458,23 +889,28 @@ func (g *Gateway) setupListeners(ctx context.Context) (grpcLn, httpLn net.Listen
 	return g.setupTCPListeners()
 }
 
-// startServers starts gRPC and HTTP servers in goroutines, returning error channel.
-func (g *Gateway) startServers(grpcLn, httpLn net.Listener) chan error {
-	errCh := make(chan error, 2)
+// startServers starts gRPC and HTTP servers in goroutines, returning error
+// channel. adminLn and metricsLn are nil when the admin UI and metrics share
+// the API listener.
+func (g *Gateway) startServers(grpcLn, httpLn, adminLn, metricsLn net.Listener) chan error {
+	errCh := make(chan error, 4)
 
+	g.grpcServing.Store(true)
 	go func() {
+		defer g.grpcServing.Store(false)
 		g.logger.Info("gRPC server listening", "addr", grpcLn.Addr().String())
 		if err := g.grpcServer.Serve(grpcLn); err != nil {
 			errCh <- fmt.Errorf("gRPC server: %w", err)
 		}
 	}()
 
-	go func() {
-		g.logger.Info("HTTP server listening", "addr", httpLn.Addr().String())
-		if err := g.httpServer.Serve(httpLn); err != nil && err != http.ErrServerClosed {
-			errCh <- fmt.Errorf("HTTP server: %w", err)
-		}
-	}()
+	go g.serveHTTP(listenerAPI, g.httpServer, httpLn, errCh)
+	if adminLn != nil {
+		go g.serveHTTP(listenerAdmin, g.adminHTTPServer, adminLn, errCh)
+	}
+	if metricsLn != nil {
+		go g.serveHTTP(listenerMetrics, g.metricsHTTPServer, metricsLn, errCh)
+	}
 
 	return errCh
 }
@@ -506,8 +942,31 @@ func (g *Gateway) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	adminListener, err := g.listenAdmin()
+	if err != nil {
+		_ = grpcListener.Close()
+		_ = httpListener.Close()
+		return err
+	}
+	metricsListener, err := g.listenMetrics()
+	if err != nil {
+		_ = grpcListener.Close()
+		_ = httpListener.Close()
+		if adminListener != nil {
+			_ = adminListener.Close()
+		}
+		return err
+	}
 
-	errCh := g.startServers(grpcListener, httpListener)
+	errCh := g.startServers(grpcListener, httpListener, adminListener, metricsListener)
+	g.scheduler.Start()
+	go g.watchAgentHealth(ctx)
+	go g.watchRetention(ctx)
+	go g.watchLinkCodes(ctx)
+	go g.watchLoginAttempts(ctx)
+	go g.watchIdempotencyKeys(ctx)
+	g.watchReloadSignal(ctx)
+	g.notifyReady(ctx)
 	serverErr := g.waitForShutdownSignal(ctx, errCh)
 
 	shutdownErr := g.gracefulShutdown()
@@ -518,12 +977,14 @@ func (g *Gateway) Run(ctx context.Context) error {
 	return shutdownErr
 }
 
-// gracefulShutdown performs shutdown with a fresh context and timeout.
+// gracefulShutdown performs shutdown with a fresh context and timeout, on
+// top of the time in-flight responses get to drain.
 // Uses context.Background() intentionally since the original context is already canceled.
 func (g *Gateway) gracefulShutdown() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), g.shutdownDelay()+g.drainTimeout()+DefaultShutdownTimeout)
 	defer cancel()
-	return g.Shutdown(ctx)
+	_, err := g.Shutdown(ctx)
+	return err
 }
 
 // resolveTailscaleStateDir returns the state directory, using default if not configured.
@@ -672,75 +1133,30 @@ func (g *Gateway) createTailscaleTLSListener(grpcLn net.Listener) (net.Listener,
 }
 
 // Shutdown gracefully stops all gateway servers and releases resources.
-// shutdownGRPCServer gracefully stops the gRPC server or force-stops on context cancel.
-func (g *Gateway) shutdownGRPCServer(ctx context.Context) {
-	stopped := make(chan struct{})
-	go func() {
-		g.grpcServer.GracefulStop()
-		close(stopped)
-	}()
-
-	select {
-	case <-stopped:
-	case <-ctx.Done():
-		g.grpcServer.Stop()
-	}
-}
-
-// appendCloseError appends an error with label if err is non-nil.
-func appendCloseError(errs []error, label string, err error) []error {
-	if err != nil {
-		return append(errs, fmt.Errorf("%s: %w", label, err))
-	}
-	return errs
-}
-
-// closeOptionalComponents closes optional components that may be nil.
-func (g *Gateway) closeOptionalComponents() {
-	if g.dedupe != nil {
-		g.dedupe.Close()
-	}
-	if g.eventBroadcaster != nil {
-		g.eventBroadcaster.Close()
-	}
-	if g.webAdmin != nil {
-		g.webAdmin.Close()
-	}
-	if g.mcpServer != nil {
-		g.mcpServer.Close()
-	}
-	if g.packRouter != nil {
-		g.packRouter.Close()
-	}
-	if g.packRegistry != nil {
-		g.packRegistry.Close()
-	}
-}
-
-func (g *Gateway) Shutdown(ctx context.Context) error {
+// Readiness turns false at once, and the gateway keeps serving for
+// server.readiness.shutdown_delay so load balancers can stop sending it
+// traffic. Everything after that happens within ctx's deadline, or
+// DefaultShutdownTimeout if it has none. Agents drain first: new sends are
+// refused and in-flight responses get up to agents.drain_timeout to finish,
+// but never the time reserved for the phases after the drain. Components
+// then stop in phases (see shutdownPhases), each with its own share of the
+// time; one that hangs is force-closed so the rest, including the store,
+// still shut down. The report is logged and returned along with any
+// component errors.
+func (g *Gateway) Shutdown(ctx context.Context) (*ShutdownReport, error) {
 	g.logger.Info("shutting down gateway")
-
-	var errs []error
-	if err := g.httpServer.Shutdown(ctx); err != nil {
-		// SSE connections won't close gracefully within the timeout — force close.
-		g.logger.Debug("graceful HTTP shutdown timed out, forcing close", "error", err)
-		errs = append(errs, fmt.Errorf("http shutdown: %w", err))
-		_ = g.httpServer.Close()
-	}
-
-	g.shutdownGRPCServer(ctx)
-
-	if g.tsnetServer != nil {
-		errs = appendCloseError(errs, "tailscale shutdown", g.tsnetServer.Close())
+	g.stopping.Store(true)
+	if err := g.systemd.Stopping(); err != nil {
+		g.logger.Warn("systemd stopping notification failed", "error", err)
 	}
-	errs = appendCloseError(errs, "store close", g.store.Close())
-
-	g.closeOptionalComponents()
+	g.waitShutdownDelay(ctx)
 
-	if len(errs) > 0 {
-		return fmt.Errorf("shutdown errors: %v", errs)
+	report := runShutdown(ctx, g.shutdownPhases())
+	report.log(g.logger)
+	if err := report.Err(); err != nil {
+		return report, fmt.Errorf("shutdown: %w", err)
 	}
-	return nil
+	return report, nil
 }
 
 // handleHealth returns 200 OK if the server is alive.
@@ -749,18 +1165,6 @@ func (g *Gateway) handleHealth(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte("OK"))
 }
 
-// handleReady returns 200 OK if the server has at least one agent connected.
-func (g *Gateway) handleReady(w http.ResponseWriter, r *http.Request) {
-	agents := g.agentManager.ListAgents()
-	if len(agents) == 0 {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		_, _ = w.Write([]byte("no agents connected"))
-		return
-	}
-	w.WriteHeader(http.StatusOK)
-	_, _ = fmt.Fprintf(w, "ready (%d agents)", len(agents))
-}
-
 // generateServerID creates a unique identifier for this gateway instance.
 func generateServerID() string {
 	return fmt.Sprintf("coven-gateway-%d", time.Now().UnixNano()%1000000)
@@ -459,7 +459,7 @@ func TestAgentStream_NoRegistrationFirst(t *testing.T) {
 	}
 }
 
-func TestAgentStream_DuplicateRegistration(t *testing.T) {
+func TestAgentStream_ReconnectSupersedes(t *testing.T) {
 	cfg := testConfig(t)
 	logger := testLogger()
 
@@ -521,7 +521,7 @@ func TestAgentStream_DuplicateRegistration(t *testing.T) {
 		t.Fatalf("welcome 1 failed: %v", err)
 	}
 
-	// Second agent tries to register with same ID
+	// The agent reconnects with the same ID
 	stream2, err := client2.AgentStream(ctx)
 	if err != nil {
 		t.Fatalf("AgentStream() 2 failed: %v", err)
@@ -539,10 +539,24 @@ func TestAgentStream_DuplicateRegistration(t *testing.T) {
 		t.Fatalf("registration 2 failed: %v", err)
 	}
 
-	// Second stream should receive error
-	_, err = stream2.Recv()
-	if err == nil {
-		t.Error("expected error for duplicate agent ID")
+	// The new stream is welcomed and the old one told goodbye
+	msg, err := stream2.Recv()
+	if err != nil || msg.GetWelcome() == nil {
+		t.Fatalf("second stream got %v (err %v), want welcome", msg, err)
+	}
+	msg, err = stream1.Recv()
+	if err != nil || msg.GetGoodbye().GetReason() != agent.GoodbyeReasonSuperseded {
+		t.Fatalf("first stream got %v (err %v), want superseded goodbye", msg, err)
+	}
+
+	// Closing the superseded stream leaves the new connection registered
+	if err := stream1.CloseSend(); err != nil {
+		t.Fatalf("CloseSend: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	agents := gw.agentManager.ListAgents()
+	if len(agents) != 1 || agents[0].Name != "second-agent" || agents[0].SupersedeCount != 1 {
+		t.Errorf("agents = %+v, want second-agent with supersede count 1", agents)
 	}
 }
 
@@ -0,0 +1,391 @@
+// ABOUTME: Agent groups: admins manage named sets of agent principals via /api/groups,
+// ABOUTME: and POST /api/send/broadcast fans one message out to every agent in a group over one SSE stream.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// agentGroupStore is what the group endpoints and broadcast need from storage.
+type agentGroupStore interface {
+	CreateAgentGroup(ctx context.Context, g *store.AgentGroup) error
+	GetAgentGroup(ctx context.Context, name string) (*store.AgentGroup, error)
+	ListAgentGroups(ctx context.Context) ([]*store.AgentGroup, error)
+	SetAgentGroupMembers(ctx context.Context, name string, members []string) error
+	DeleteAgentGroup(ctx context.Context, name string) error
+}
+
+// AgentGroupRequest is the JSON body for POST /api/groups and
+// PUT /api/groups/{name}; PUT ignores Name.
+type AgentGroupRequest struct {
+	Name    string   `json:"name,omitempty"`
+	Members []string `json:"members"`
+}
+
+// AgentGroupResponse is the JSON representation of an agent group.
+type AgentGroupResponse struct {
+	Name      string   `json:"name"`
+	Members   []string `json:"members"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+// ListAgentGroupsResponse is the JSON response for GET /api/groups.
+type ListAgentGroupsResponse struct {
+	Groups []AgentGroupResponse `json:"groups"`
+}
+
+// BroadcastRequest is the JSON body for POST /api/send/broadcast.
+type BroadcastRequest struct {
+	Group              string `json:"group"`
+	Sender             string `json:"sender"`
+	Content            string `json:"content"`
+	MaxResponseSeconds int    `json:"max_response_seconds,omitempty"`
+}
+
+// handleGroups routes /api/groups and /api/groups/{name}.
+func (g *Gateway) handleGroups(w http.ResponseWriter, r *http.Request) {
+	if g.agentGroups == nil {
+		g.sendJSONError(w, http.StatusServiceUnavailable, "agent groups not available")
+		return
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/groups"), "/")
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		g.handleListGroups(w, r)
+	case name == "" && r.Method == http.MethodPost:
+		g.handleCreateGroup(w, r)
+	case name == "":
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	case strings.Contains(name, "/"):
+		g.sendJSONError(w, http.StatusNotFound, "unknown endpoint")
+	case r.Method == http.MethodGet:
+		g.handleGetGroup(w, r, name)
+	case r.Method == http.MethodPut:
+		g.handleSetGroupMembers(w, r, name)
+	case r.Method == http.MethodDelete:
+		g.handleDeleteGroup(w, r, name)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCreateGroup handles POST /api/groups.
+func (g *Gateway) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req AgentGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.sendJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || strings.Contains(req.Name, "/") {
+		g.sendJSONError(w, http.StatusBadRequest, "name is required and must not contain /")
+		return
+	}
+
+	group := &store.AgentGroup{Name: req.Name, Members: req.Members}
+	err := g.agentGroups.CreateAgentGroup(r.Context(), group)
+	switch {
+	case errors.Is(err, store.ErrAgentGroupExists):
+		g.sendJSONError(w, http.StatusConflict, "group already exists")
+		return
+	case errors.Is(err, store.ErrPrincipalNotFound):
+		g.sendJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		g.logger.Error("failed to create agent group", "group", req.Name, "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	g.logger.Info("agent group created", "group", group.Name, "members", len(group.Members))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(groupToResponse(group)); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// handleListGroups handles GET /api/groups.
+func (g *Gateway) handleListGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := g.agentGroups.ListAgentGroups(r.Context())
+	if err != nil {
+		g.logger.Error("failed to list agent groups", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	response := ListAgentGroupsResponse{Groups: make([]AgentGroupResponse, len(groups))}
+	for i, group := range groups {
+		response.Groups[i] = groupToResponse(group)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// handleGetGroup handles GET /api/groups/{name}.
+func (g *Gateway) handleGetGroup(w http.ResponseWriter, r *http.Request, name string) {
+	group, ok := g.lookupGroup(w, r.Context(), name)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(groupToResponse(group)); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// handleSetGroupMembers handles PUT /api/groups/{name}, replacing its members.
+func (g *Gateway) handleSetGroupMembers(w http.ResponseWriter, r *http.Request, name string) {
+	var req AgentGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.sendJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	err := g.agentGroups.SetAgentGroupMembers(r.Context(), name, req.Members)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		g.sendJSONError(w, http.StatusNotFound, "group not found")
+		return
+	case errors.Is(err, store.ErrPrincipalNotFound):
+		g.sendJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		g.logger.Error("failed to set agent group members", "group", name, "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	g.logger.Info("agent group updated", "group", name, "members", len(req.Members))
+	g.handleGetGroup(w, r, name)
+}
+
+// handleDeleteGroup handles DELETE /api/groups/{name}.
+func (g *Gateway) handleDeleteGroup(w http.ResponseWriter, r *http.Request, name string) {
+	err := g.agentGroups.DeleteAgentGroup(r.Context(), name)
+	if errors.Is(err, store.ErrNotFound) {
+		g.sendJSONError(w, http.StatusNotFound, "group not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to delete agent group", "group", name, "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	g.logger.Info("agent group deleted", "group", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lookupGroup fetches a group, writing a 404 or 500 when it can't.
+func (g *Gateway) lookupGroup(w http.ResponseWriter, ctx context.Context, name string) (*store.AgentGroup, bool) {
+	group, err := g.agentGroups.GetAgentGroup(ctx, name)
+	if errors.Is(err, store.ErrNotFound) {
+		g.sendJSONError(w, http.StatusNotFound, "group not found")
+		return nil, false
+	}
+	if err != nil {
+		g.logger.Error("failed to get agent group", "group", name, "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return nil, false
+	}
+	return group, true
+}
+
+func groupToResponse(group *store.AgentGroup) AgentGroupResponse {
+	members := group.Members
+	if members == nil {
+		members = []string{}
+	}
+	return AgentGroupResponse{
+		Name:      group.Name,
+		Members:   members,
+		CreatedAt: timeparse.Format(group.CreatedAt),
+		UpdatedAt: timeparse.Format(group.UpdatedAt),
+	}
+}
+
+// broadcastWriter serializes the SSE events of a broadcast's per-agent
+// streams onto one response, tagging each with the agent it came from.
+type broadcastWriter struct {
+	g       *Gateway
+	w       http.ResponseWriter
+	flusher http.Flusher
+	mu      sync.Mutex
+}
+
+// write sends one event with agent_id (and principal_id, when known) added
+// to its data object.
+func (b *broadcastWriter) write(event, agentID, principalID string, data json.RawMessage) {
+	fields := map[string]any{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &fields); err != nil {
+			fields = map[string]any{"data": data}
+		}
+	}
+	fields["agent_id"] = agentID
+	if principalID != "" {
+		fields["principal_id"] = principalID
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.g.writeSSEEvent(b.w, event, fields)
+	b.flusher.Flush()
+}
+
+// writeError reports that the broadcast could not reach one agent.
+func (b *broadcastWriter) writeError(agentID, principalID, msg string) {
+	data, _ := json.Marshal(map[string]string{"error": msg})
+	b.write("error", agentID, principalID, data)
+}
+
+// handleBroadcast handles POST /api/send/broadcast. It sends the message to
+// every online agent of each group member, each in a thread of its own, and
+// streams all of their responses as one SSE stream whose events carry
+// agent_id. Members with no agent online, and sends that fail, get an
+// error event instead of failing the request. The stream ends with a
+// broadcast_done event once every agent has finished.
+func (g *Gateway) handleBroadcast(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if g.agentGroups == nil {
+		g.sendJSONError(w, http.StatusServiceUnavailable, "agent groups not available")
+		return
+	}
+	if g.agentManager.Draining() {
+		g.sendDrainingError(w)
+		return
+	}
+
+	var body BroadcastRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		g.sendJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if body.Group == "" {
+		g.sendJSONError(w, http.StatusBadRequest, "group is required")
+		return
+	}
+	req, err := validateSendRequest(&SendMessageRequest{
+		Sender:             body.Sender,
+		Content:            body.Content,
+		MaxResponseSeconds: body.MaxResponseSeconds,
+	})
+	if err != nil {
+		g.sendJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	group, ok := g.lookupGroup(w, r.Context(), body.Group)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		g.logger.Error("streaming not supported")
+		g.sendJSONError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	setSSEHeaders(w)
+	out := &broadcastWriter{g: g, w: w, flusher: flusher}
+
+	var (
+		wg     sync.WaitGroup
+		failMu sync.Mutex
+		failed int
+		sent   int
+	)
+	fail := func() {
+		failMu.Lock()
+		failed++
+		failMu.Unlock()
+	}
+	for _, principalID := range group.Members {
+		if !tokenAllowsAgent(r.Context(), principalID) {
+			out.writeError("", principalID, errTokenAgentDenied)
+			fail()
+			continue
+		}
+		conns := g.agentManager.ListByPrincipal(principalID)
+		if len(conns) == 0 {
+			out.writeError("", principalID, "agent unavailable")
+			fail()
+			continue
+		}
+		for _, conn := range conns {
+			sent++
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if !g.broadcastTo(r.Context(), out, req, conn) {
+					fail()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	g.logger.Info("broadcast finished", "group", group.Name, "agents", sent, "failed", failed)
+	done, _ := json.Marshal(map[string]any{"group": group.Name, "members": len(group.Members), "agents": sent, "failed": failed})
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	_, _ = fmt.Fprint(w, formatSSEEvent("broadcast_done", string(done)))
+	flusher.Flush()
+}
+
+// broadcastTo sends req to conn in a new thread and relays its events to
+// out until the response finishes or the client goes away. It reports
+// whether the message reached the agent.
+func (g *Gateway) broadcastTo(ctx context.Context, out *broadcastWriter, req *SendMessageRequest, conn *agent.Connection) bool {
+	threadID := uuid.New().String()
+	target := &resolvedTarget{
+		AgentID:      conn.ID,
+		ThreadID:     threadID,
+		FrontendName: "direct",
+		ExternalID:   threadID,
+		Agent:        conn,
+		Selection:    selectionGroup,
+	}
+	// As with /api/send, responses are persisted even if the client drops.
+	requestID, err := g.beginSend(context.WithoutCancel(ctx), req, target)
+	if err != nil {
+		g.logger.Warn("broadcast send failed", "agent_id", conn.ID, "error", err)
+		out.writeError(conn.ID, conn.PrincipalID, err.Error())
+		return false
+	}
+
+	var lastEventID uint64
+	for {
+		events, done, err := g.eventBroadcaster.WaitRequestEvents(ctx, requestID, lastEventID)
+		if errors.Is(err, conversation.ErrRequestOrphaned) {
+			out.writeError(conn.ID, conn.PrincipalID, "stream no longer buffered")
+			return true
+		}
+		if err != nil {
+			return true
+		}
+		for _, ev := range events {
+			out.write(ev.Event, conn.ID, "", ev.Data)
+			lastEventID = ev.ID
+		}
+		if done {
+			return true
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// ABOUTME: Tests for agent groups: the /api/groups admin endpoints and the
+// ABOUTME: multiplexed SSE stream of POST /api/send/broadcast.
+
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// replyingStream answers every message sent to its agent with a text event
+// and a done event.
+type replyingStream struct {
+	testMockStream
+	conn  *agent.Connection
+	reply string
+}
+
+func (s *replyingStream) Send(msg *pb.ServerMessage) error {
+	send := msg.GetSendMessage()
+	if send == nil {
+		return nil
+	}
+	go func() {
+		s.conn.HandleResponse(&pb.MessageResponse{
+			RequestId: send.GetRequestId(),
+			Event:     &pb.MessageResponse_Text{Text: s.reply},
+		})
+		s.conn.HandleResponse(&pb.MessageResponse{
+			RequestId: send.GetRequestId(),
+			Event:     &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: s.reply}},
+		})
+	}()
+	return nil
+}
+
+func createAgentPrincipal(t *testing.T, gw *Gateway, id string) {
+	t.Helper()
+	sqlStore := gw.store.(*store.SQLStore)
+	if err := sqlStore.CreatePrincipal(context.Background(), &store.Principal{
+		ID:          id,
+		Type:        store.PrincipalTypeAgent,
+		PubkeyFP:    strings.Repeat("0", 64-len(id)) + id,
+		DisplayName: id,
+		Status:      store.PrincipalStatusOnline,
+		CreatedAt:   time.Now(),
+	}); err != nil {
+		t.Fatalf("CreatePrincipal(%s): %v", id, err)
+	}
+}
+
+type sseEvent struct {
+	name string
+	data map[string]any
+}
+
+func readSSEEvents(t *testing.T, body string) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var name string
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			var data map[string]any
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data); err != nil {
+				t.Fatalf("bad SSE data %q: %v", line, err)
+			}
+			events = append(events, sseEvent{name: name, data: data})
+		}
+	}
+	return events
+}
+
+func TestHandleGroups_CRUD(t *testing.T) {
+	gw := newTestGateway(t)
+	createAgentPrincipal(t, gw, "agent-a")
+	createAgentPrincipal(t, gw, "agent-b")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		gw.handleGroups(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/groups", `{"name":"ops","members":["agent-a"]}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/api/groups", `{"name":"ops","members":[]}`); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate create: status %d, want 409", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/groups", `{"name":"bad","members":["nobody"]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown member: status %d, want 400", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/groups", `{"name":"a/b"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("slash in name: status %d, want 400", rec.Code)
+	}
+
+	rec := do(http.MethodPut, "/api/groups/ops", `{"members":["agent-b","agent-a"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("set members: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	var group AgentGroupResponse
+	if err := json.NewDecoder(rec.Body).Decode(&group); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if strings.Join(group.Members, ",") != "agent-a,agent-b" {
+		t.Errorf("members = %v, want [agent-a agent-b]", group.Members)
+	}
+
+	rec = do(http.MethodGet, "/api/groups", "")
+	var list ListAgentGroupsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(list.Groups) != 1 || list.Groups[0].Name != "ops" {
+		t.Errorf("list = %+v, want just ops", list.Groups)
+	}
+
+	if rec := do(http.MethodDelete, "/api/groups/ops", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("delete: status %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/groups/ops", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("get after delete: status %d, want 404", rec.Code)
+	}
+}
+
+func TestHandleBroadcast(t *testing.T) {
+	// Sends run concurrently, so back the store with a file: each pooled
+	// connection to :memory: would see a database of its own.
+	gw, err := New(sessionTestConfig(t), testLogger())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for _, id := range []string{"agent-a", "agent-b", "agent-offline"} {
+		createAgentPrincipal(t, gw, id)
+	}
+	if err := gw.agentGroups.CreateAgentGroup(context.Background(), &store.AgentGroup{
+		Name:    "ops",
+		Members: []string{"agent-a", "agent-b", "agent-offline"},
+	}); err != nil {
+		t.Fatalf("CreateAgentGroup: %v", err)
+	}
+	for _, id := range []string{"agent-a", "agent-b"} {
+		stream := &replyingStream{reply: "hello from " + id}
+		stream.conn = agent.NewConnection(agent.ConnectionParams{
+			ID: id, Name: id, PrincipalID: id, Stream: stream, Logger: slog.Default(),
+		})
+		if err := gw.agentManager.Register(stream.conn); err != nil {
+			t.Fatalf("Register(%s): %v", id, err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	body := `{"group":"ops","sender":"alice","content":"status?"}`
+	gw.handleBroadcast(rec, httptest.NewRequest(http.MethodPost, "/api/send/broadcast", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	events := readSSEEvents(t, rec.Body.String())
+	if len(events) == 0 || events[len(events)-1].name != "broadcast_done" {
+		t.Fatalf("stream does not end with broadcast_done: %+v", events)
+	}
+	done := events[len(events)-1].data
+	if done["agents"] != float64(2) || done["failed"] != float64(1) {
+		t.Errorf("broadcast_done = %v, want 2 agents and 1 failed", done)
+	}
+
+	texts := map[string]string{}
+	threads := map[string]string{}
+	var offline bool
+	for _, ev := range events[:len(events)-1] {
+		agentID, ok := ev.data["agent_id"].(string)
+		if !ok {
+			t.Errorf("%s event without agent_id: %v", ev.name, ev.data)
+			continue
+		}
+		switch ev.name {
+		case "started":
+			threads[agentID], _ = ev.data["thread_id"].(string)
+		case "text":
+			texts[agentID], _ = ev.data["text"].(string)
+		case "error":
+			if ev.data["principal_id"] == "agent-offline" {
+				offline = true
+			} else {
+				t.Errorf("error event for %s: %v", agentID, ev.data)
+			}
+		}
+	}
+	if !offline {
+		t.Error("no error event for the offline member")
+	}
+	for _, id := range []string{"agent-a", "agent-b"} {
+		if texts[id] != "hello from "+id {
+			t.Errorf("text for %s = %q", id, texts[id])
+		}
+	}
+	if threads["agent-a"] == "" || threads["agent-a"] == threads["agent-b"] {
+		t.Errorf("threads = %v, want one distinct thread per agent", threads)
+	}
+	for id, threadID := range threads {
+		thread, err := gw.store.GetThread(context.Background(), threadID)
+		if err != nil {
+			t.Fatalf("GetThread(%s): %v", threadID, err)
+		}
+		if thread.AgentID != id {
+			t.Errorf("thread %s agent = %q, want %q", threadID, thread.AgentID, id)
+		}
+	}
+}
+
+func TestHandleBroadcast_UnknownGroup(t *testing.T) {
+	gw := newTestGateway(t)
+	rec := httptest.NewRecorder()
+	body := `{"group":"nope","sender":"alice","content":"hi"}`
+	gw.handleBroadcast(rec, httptest.NewRequest(http.MethodPost, "/api/send/broadcast", strings.NewReader(body)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404", rec.Code)
+	}
+}
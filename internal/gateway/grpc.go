@@ -17,7 +17,10 @@ import (
 
 	"github.com/2389/coven-gateway/internal/agent"
 	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/logctx"
+	"github.com/2389/coven-gateway/internal/packs"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/tracing"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
@@ -26,13 +29,16 @@ type covenControlServer struct {
 	pb.UnimplementedCovenControlServer
 	gateway *Gateway
 	logger  *slog.Logger
+	// maxRecvBytes is the largest agent message handled; see refuseOversized.
+	maxRecvBytes int
 }
 
 // newCovenControlServer creates a new CovenControl service instance.
 func newCovenControlServer(gw *Gateway, logger *slog.Logger) *covenControlServer {
 	return &covenControlServer{
-		gateway: gw,
-		logger:  logger,
+		gateway:      gw,
+		logger:       logger,
+		maxRecvBytes: grpcMaxRecvBytes(gw.config),
 	}
 }
 
@@ -43,9 +49,11 @@ type agentRegistrationInfo struct {
 	workingDir  string
 	backend     string
 	instanceID  string
+	metadata    *agent.Metadata
 }
 
 // extractRegistrationInfo extracts info from registration message and auth context.
+// Agent-supplied metadata is normalized first; anything dropped or cut short is logged.
 func (s *covenControlServer) extractRegistrationInfo(ctx context.Context, reg *pb.RegisterAgent) agentRegistrationInfo {
 	info := agentRegistrationInfo{
 		instanceID: uuid.New().String()[:12],
@@ -53,17 +61,25 @@ func (s *covenControlServer) extractRegistrationInfo(ctx context.Context, reg *p
 	if authCtx := auth.FromContext(ctx); authCtx != nil {
 		info.principalID = authCtx.PrincipalID
 	}
-	if metadata := reg.GetMetadata(); metadata != nil {
-		info.workspaces = metadata.GetWorkspaces()
-		info.workingDir = metadata.GetWorkingDirectory()
-		info.backend = metadata.GetBackend()
+	metadata, issues := agent.NormalizeMetadata(reg, s.gateway.metadataLimits)
+	for _, issue := range issues {
+		s.logger.Warn("normalized agent metadata",
+			"agent_id", reg.GetAgentId(),
+			"field", issue.Field,
+			"kind", issue.Kind,
+			"reason", issue.Reason,
+		)
 	}
+	info.metadata = metadata
+	info.workspaces = metadata.Workspaces
+	info.workingDir = metadata.WorkingDir
+	info.backend = metadata.Backend
 	return info
 }
 
 // loadAgentSecrets resolves effective secrets for an agent.
 func (s *covenControlServer) loadAgentSecrets(ctx context.Context, agentID string) map[string]string {
-	sqlStore, ok := s.gateway.store.(*store.SQLiteStore)
+	sqlStore, ok := s.gateway.store.(*store.SQLStore)
 	if !ok {
 		return make(map[string]string)
 	}
@@ -85,16 +101,55 @@ func (s *covenControlServer) createMCPToken(agentID string, capabilities []strin
 	return token
 }
 
-// getAgentTools returns available pack tools filtered by agent's capabilities.
+// getAgentTools returns available pack tools filtered by agent's capabilities
+// and tool overrides.
 func (s *covenControlServer) getAgentTools(agentID string, capabilities []string) []*pb.ToolDefinition {
 	if s.gateway.packRegistry == nil {
 		return nil
 	}
-	tools := s.gateway.packRegistry.GetToolsForCapabilities(capabilities)
+	tools := s.gateway.agentTools(context.Background(), agentID, capabilities)
 	s.logger.Debug("filtered pack tools for agent", "agent_id", agentID, "capabilities", capabilities, "tool_count", len(tools))
 	return tools
 }
 
+// getCatalogVersion returns the current tool catalog version, or 0 if unavailable.
+func (s *covenControlServer) getCatalogVersion(ctx context.Context) int64 {
+	if s.gateway.toolCatalog == nil {
+		return 0
+	}
+	version, err := s.gateway.toolCatalog.Version(ctx)
+	if err != nil {
+		s.logger.Warn("failed to read tool catalog version", "error", err)
+		return 0
+	}
+	return version
+}
+
+// broadcastToolsChanged pushes each connected agent its updated pack tool list.
+func (g *Gateway) broadcastToolsChanged(catalogVersion int64) {
+	if g.packRegistry == nil {
+		return
+	}
+	for _, info := range g.agentManager.ListAgents() {
+		conn, ok := g.agentManager.GetAgent(info.ID)
+		if !ok {
+			continue
+		}
+		msg := &pb.ServerMessage{
+			Payload: &pb.ServerMessage_ToolsChanged{
+				ToolsChanged: &pb.ToolsChanged{
+					CatalogVersion: catalogVersion,
+					AvailableTools: g.agentTools(context.Background(), conn.ID, conn.Capabilities()),
+				},
+			},
+		}
+		if err := conn.Send(msg); err != nil {
+			g.logger.Warn("failed to send tools changed", "agent_id", info.ID, "error", err)
+		}
+	}
+	g.logger.Debug("broadcast tools changed", "catalog_version", catalogVersion)
+}
+
 // receiveRegistration waits for and validates the registration message.
 // Returns (reg, cleanDisconnect, grpcError).
 func (s *covenControlServer) receiveRegistration(stream pb.CovenControl_AgentStreamServer) (*pb.RegisterAgent, bool, error) {
@@ -161,12 +216,23 @@ func (s *covenControlServer) dispatchMessage(stream pb.CovenControl_AgentStreamS
 }
 
 // runMessageLoop handles the main receive loop for an agent connection.
-func (s *covenControlServer) runMessageLoop(stream pb.CovenControl_AgentStreamServer, conn *agent.Connection) error {
+// recv supplies inbound messages; it is stream.Recv unless an earlier phase
+// (such as waiting for approval) already owns the stream's reader.
+func (s *covenControlServer) runMessageLoop(stream pb.CovenControl_AgentStreamServer, conn *agent.Connection, recv func() (*pb.AgentMessage, error)) error {
+	lastBeat := time.Now()
 	for {
-		msg, err := stream.Recv()
+		msg, err := recv()
 		if shouldContinue, grpcErr := s.checkRecvError(err, conn.ID); !shouldContinue {
 			return grpcErr
 		}
+		if msg.GetHeartbeat() != nil {
+			now := time.Now()
+			s.gateway.instruments.observeHeartbeat(conn.ID, now.Sub(lastBeat), s.gateway.heartbeatInterval())
+			lastBeat = now
+		}
+		if s.refuseOversized(stream, conn, msg) {
+			continue
+		}
 		s.dispatchMessage(stream, conn, msg)
 	}
 }
@@ -177,6 +243,8 @@ func (s *covenControlServer) runMessageLoop(stream pb.CovenControl_AgentStreamSe
 // 2. Server responds with Welcome message
 // 3. Agent sends Heartbeat or MessageResponse messages
 // 4. Server sends SendMessage or Shutdown messages.
+// A registration for an agent ID that is already connected supersedes the
+// older connection, which is sent Goodbye.
 func (s *covenControlServer) AgentStream(stream pb.CovenControl_AgentStreamServer) error {
 	s.logger.Debug("AgentStream handler invoked, waiting for registration")
 
@@ -196,6 +264,17 @@ func (s *covenControlServer) AgentStream(stream pb.CovenControl_AgentStreamServe
 
 	// Extract registration info and create connection
 	info := s.extractRegistrationInfo(stream.Context(), reg)
+
+	// Hold pending principals in a restricted state until an admin decides
+	recv := stream.Recv
+	if authCtx := auth.FromContext(stream.Context()); authCtx != nil && authCtx.Pending {
+		pump := newRecvPump(stream)
+		approved, err := s.awaitApproval(stream, reg, info, pump)
+		if !approved {
+			return err
+		}
+		recv = pump.recv
+	}
 	conn := agent.NewConnection(agent.ConnectionParams{
 		ID:           reg.GetAgentId(),
 		Name:         reg.GetName(),
@@ -205,14 +284,36 @@ func (s *covenControlServer) AgentStream(stream pb.CovenControl_AgentStreamServe
 		WorkingDir:   info.workingDir,
 		InstanceID:   info.instanceID,
 		Backend:      info.backend,
+		Metadata:     info.metadata,
 		Stream:       stream,
 		Logger:       s.logger.With("agent_id", reg.GetAgentId()),
 	})
 
+	// Restore any admin pause before the agent becomes routable
+	s.restorePause(stream.Context(), conn.ID, info.principalID)
+
 	// Register the agent with the manager
 	if err := s.registerAgent(conn); err != nil {
 		return err
 	}
+	s.gateway.instruments.streamRegistrations.Inc()
+	defer s.gateway.instruments.streamDisconnects.Inc()
+
+	// Resume the agent's previous session if it presented a valid token
+	sess, err := s.gateway.sessions.open(stream.Context(), reg.GetSessionToken(), conn.ID, info.principalID, info.metadata.ProtocolFeatures)
+	if err != nil {
+		s.gateway.agentManager.UnregisterConnection(conn)
+		return err
+	}
+	s.gateway.sessions.attach(conn.ID, sess)
+	defer func() {
+		// A superseded connection's session now belongs to its successor.
+		if !conn.Superseded() {
+			s.gateway.sessions.detach(context.WithoutCancel(stream.Context()), conn.ID, sess)
+		}
+	}()
+
+	s.recordPrincipalSeen(stream.Context(), conn)
 
 	// Auto-update bindings that match this agent's workspace name
 	s.maybeUpdateBindingsForWorkspace(stream.Context(), conn.Name, conn.ID)
@@ -222,7 +323,7 @@ func (s *covenControlServer) AgentStream(stream pb.CovenControl_AgentStreamServe
 
 	// Ensure we unregister on exit and invalidate MCP token
 	defer func() {
-		s.gateway.agentManager.Unregister(conn.ID)
+		s.gateway.agentManager.UnregisterConnection(conn)
 		if s.gateway.mcpTokens != nil && mcpToken != "" {
 			s.gateway.mcpTokens.InvalidateToken(mcpToken)
 			s.logger.Debug("invalidated MCP token for agent", "agent_id", conn.ID)
@@ -245,18 +346,69 @@ func (s *covenControlServer) AgentStream(stream pb.CovenControl_AgentStreamServe
 				McpToken:       mcpToken,
 				McpEndpoint:    s.gateway.mcpEndpoint,
 				Secrets:        secretsMap,
+				CatalogVersion: s.getCatalogVersion(stream.Context()),
+				SessionToken:   sess.token,
+				Resumed:        sess.resumed,
 			},
 		},
 	}
+	if sess.resumed {
+		welcome.GetWelcome().AckedRequestIds = sess.AckedRequestIDs
+	}
 
 	if err := stream.Send(welcome); err != nil {
 		return status.Errorf(codes.Internal, "sending welcome: %v", err)
 	}
+	if n := s.gateway.agentManager.ResumeHandedOver(conn); n > 0 {
+		s.logger.Info("resumed requests handed over by superseded connection", "agent_id", conn.ID, "count", n)
+	}
+	s.resumeInFlight(stream.Context(), conn, sess)
 
 	// Auto-grant leader role if agent has "leader" capability
 	s.maybeGrantLeaderRole(stream.Context(), info.principalID, reg.GetCapabilities())
 
-	return s.runMessageLoop(stream, conn)
+	return s.runMessageLoop(stream, conn, recv)
+}
+
+// resumeInFlight re-sends the requests a resumed session still had in flight.
+// Their original callers are gone, so responses are persisted to the thread
+// and reach clients through the event broadcaster.
+func (s *covenControlServer) resumeInFlight(ctx context.Context, conn *agent.Connection, sess *openedSession) {
+	if len(sess.inFlight) == 0 {
+		return
+	}
+	if !conn.Metadata.HasFeature(agent.FeatureResume) {
+		s.logger.Warn("agent no longer supports resume, dropping in-flight requests",
+			"agent_id", conn.ID,
+			"count", len(sess.inFlight),
+		)
+		return
+	}
+
+	// Responses outlive the stream context only until the agent disconnects,
+	// which closes their channels.
+	ctx = context.WithoutCancel(ctx)
+	for _, r := range sess.inFlight {
+		if conn.HasRequest(r.RequestID) {
+			// Handed over by the connection this one superseded; its caller
+			// is still waiting and it has already been re-sent.
+			continue
+		}
+		respChan, err := s.gateway.agentManager.ResumeRequest(ctx, r.RequestID, &agent.SendRequest{
+			ThreadID:       r.ThreadID,
+			Sender:         r.Sender,
+			Content:        r.Content,
+			AgentID:        conn.ID,
+			Workspace:      r.Workspace,
+			ChannelContext: r.ChannelContext,
+		})
+		if err != nil {
+			s.logger.Warn("failed to resume request", "agent_id", conn.ID, "request_id", r.RequestID, "error", err)
+			continue
+		}
+		s.gateway.conversation.PersistResumed(ctx, r.ThreadID, conn.ID, respChan)
+		s.logger.Info("resumed in-flight request", "agent_id", conn.ID, "request_id", r.RequestID, "thread_id", r.ThreadID)
+	}
 }
 
 // handleHeartbeat processes a heartbeat message from an agent.
@@ -265,6 +417,16 @@ func (s *covenControlServer) handleHeartbeat(conn *agent.Connection, hb *pb.Hear
 		"agent_id", conn.ID,
 		"timestamp_ms", hb.GetTimestampMs(),
 	)
+	now := time.Now()
+	conn.Heartbeat()
+	conn.ObserveHeartbeat(hb, now)
+	if conn.Metadata.HasFeature(agent.FeatureHeartbeatAck) {
+		if err := conn.Send(conn.AckHeartbeat(hb, now)); err != nil {
+			s.logger.Debug("failed to ack heartbeat", "agent_id", conn.ID, "error", err)
+		}
+	}
+	s.gateway.agentManager.CheckAgentHealth(conn, now)
+	s.gateway.sessions.touchAgent(context.Background(), conn.ID)
 }
 
 // handleResponse routes a message response to the appropriate request handler.
@@ -274,17 +436,33 @@ func (s *covenControlServer) handleResponse(conn *agent.Connection, resp *pb.Mes
 		"request_id", resp.GetRequestId(),
 	)
 
-	conn.HandleResponse(resp)
+	if !conn.HandleResponse(resp) && !s.gateway.agentManager.HandleLateResponse(conn.ID, resp) {
+		s.logger.Warn("received response for unknown request",
+			"request_id", resp.GetRequestId(),
+			"agent_id", conn.ID,
+		)
+	}
+
+	// A request the agent finished no longer needs resuming.
+	switch resp.GetEvent().(type) {
+	case *pb.MessageResponse_Done, *pb.MessageResponse_Error, *pb.MessageResponse_Cancelled:
+		s.gateway.sessions.complete(context.Background(), resp.GetRequestId())
+	}
 }
 
 // handleExecutePackTool routes a pack tool execution request through the pack router
 // and sends the result back to the agent.
 func (s *covenControlServer) handleExecutePackTool(stream pb.CovenControl_AgentStreamServer, conn *agent.Connection, req *pb.ExecutePackTool) {
 	started := time.Now()
+	// The agent echoes the trace context of the send it is serving, so the
+	// tool span joins that trace and the tool's log lines carry the send's
+	// request ID.
+	ctx := logctx.Extract(tracing.Extract(stream.Context(), req.GetTraceContext()), req.GetTraceContext())
+	log := logctx.FromContext(ctx, s.logger)
 
-	s.logger.Info("→ pack tool request",
+	log.Info("→ pack tool request",
 		"agent_id", conn.ID,
-		"request_id", req.GetRequestId(),
+		"tool_request_id", req.GetRequestId(),
 		"tool_name", req.GetToolName(),
 	)
 
@@ -294,21 +472,23 @@ func (s *covenControlServer) handleExecutePackTool(stream pb.CovenControl_AgentS
 		return
 	}
 
-	// Route the tool call (this blocks until the pack responds or timeout)
-	resp, err := s.gateway.packRouter.RouteToolCall(
-		stream.Context(),
+	// Route the tool call (this blocks until the pack responds or timeout).
+	// A streaming pack's chunks reach clients as partial tool results.
+	resp, err := s.gateway.packRouter.RouteToolCallStream(
+		ctx,
 		req.GetToolName(),
 		req.GetInputJson(),
 		req.GetRequestId(),
 		conn.ID,
+		func(c packs.Chunk) { s.forwardToolChunk(conn, req.GetRequestId(), c) },
 	)
 
 	elapsed := time.Since(started)
 
 	if err != nil {
-		s.logger.Warn("✗ pack tool failed",
+		log.Warn("✗ pack tool failed",
 			"agent_id", conn.ID,
-			"request_id", req.GetRequestId(),
+			"tool_request_id", req.GetRequestId(),
 			"tool_name", req.GetToolName(),
 			"duration_ms", elapsed.Milliseconds(),
 			"error", err,
@@ -322,6 +502,7 @@ func (s *covenControlServer) handleExecutePackTool(stream pb.CovenControl_AgentS
 		Payload: &pb.ServerMessage_PackToolResult{
 			PackToolResult: &pb.PackToolResult{
 				RequestId: req.GetRequestId(),
+				Cached:    resp.GetCached(),
 			},
 		},
 	}
@@ -336,22 +517,48 @@ func (s *covenControlServer) handleExecutePackTool(stream pb.CovenControl_AgentS
 	}
 
 	if err := stream.Send(result); err != nil {
-		s.logger.Error("failed to send pack tool result",
+		log.Error("failed to send pack tool result",
 			"agent_id", conn.ID,
-			"request_id", req.GetRequestId(),
+			"tool_request_id", req.GetRequestId(),
 			"error", err,
 		)
 	} else {
-		s.logger.Info("← pack tool result",
+		log.Info("← pack tool result",
 			"agent_id", conn.ID,
-			"request_id", req.GetRequestId(),
+			"tool_request_id", req.GetRequestId(),
 			"tool_name", req.GetToolName(),
 			"status", status,
+			"cached", resp.GetCached(),
 			"duration_ms", elapsed.Milliseconds(),
 		)
 	}
 }
 
+// forwardToolChunk feeds a chunk of a pack tool's output into the agent's
+// current response as a partial tool result, so clients see it as the tool
+// runs. The result's id is the ExecutePackTool request ID. Chunks arriving
+// while the agent has no request in flight are dropped.
+func (s *covenControlServer) forwardToolChunk(conn *agent.Connection, toolRequestID string, c packs.Chunk) {
+	act, ok := s.gateway.agentManager.Activity(conn.ID)
+	if !ok {
+		s.logger.Debug("dropping tool chunk with no request in flight",
+			"agent_id", conn.ID,
+			"request_id", toolRequestID,
+			"sequence", c.Sequence,
+		)
+		return
+	}
+	conn.HandleResponse(&pb.MessageResponse{
+		RequestId: act.RequestID,
+		Event: &pb.MessageResponse_ToolResult{ToolResult: &pb.ToolResult{
+			Id:       toolRequestID,
+			Output:   c.Output,
+			Partial:  true,
+			Sequence: c.Sequence,
+		}},
+	})
+}
+
 // sendPackToolError sends an error result for a pack tool execution request.
 func (s *covenControlServer) sendPackToolError(stream pb.CovenControl_AgentStreamServer, requestID, errMsg string) {
 	result := &pb.ServerMessage{
@@ -384,10 +591,10 @@ func (s *covenControlServer) maybeGrantLeaderRole(ctx context.Context, principal
 		return
 	}
 
-	// Type assert to SQLiteStore to access AddRole
-	sqlStore, ok := s.gateway.store.(*store.SQLiteStore)
+	// Type assert to SQLStore to access AddRole
+	sqlStore, ok := s.gateway.store.(*store.SQLStore)
 	if !ok {
-		s.logger.Error("cannot grant leader role: store is not SQLiteStore")
+		s.logger.Error("cannot grant leader role: store is not SQLStore")
 		return
 	}
 
@@ -406,6 +613,46 @@ func (s *covenControlServer) maybeGrantLeaderRole(ctx context.Context, principal
 	)
 }
 
+// recordPrincipalSeen stamps the agent's principal with its last connection
+// time, normalized metadata and tags.
+func (s *covenControlServer) recordPrincipalSeen(ctx context.Context, conn *agent.Connection) {
+	if conn.PrincipalID == "" {
+		return
+	}
+	sqlStore, ok := s.gateway.store.(*store.SQLStore)
+	if !ok {
+		return
+	}
+	if err := sqlStore.RecordPrincipalSeen(ctx, conn.PrincipalID, time.Now(), conn.Metadata); err != nil {
+		s.logger.Warn("failed to record agent metadata", "agent_id", conn.ID, "principal_id", conn.PrincipalID, "error", err)
+	}
+	if err := sqlStore.SetPrincipalTags(ctx, conn.PrincipalID, conn.Metadata.Tags); err != nil {
+		s.logger.Warn("failed to record agent tags", "agent_id", conn.ID, "principal_id", conn.PrincipalID, "error", err)
+	}
+}
+
+// restorePause syncs the manager's paused state with the agent's principal so
+// a pause survives reconnects and gateway restarts.
+func (s *covenControlServer) restorePause(ctx context.Context, agentID, principalID string) {
+	if principalID == "" {
+		return
+	}
+	sqlStore, ok := s.gateway.store.(*store.SQLStore)
+	if !ok {
+		return
+	}
+	pause, err := sqlStore.GetPrincipalPause(ctx, principalID)
+	if err != nil {
+		s.logger.Warn("failed to load agent pause state", "agent_id", agentID, "principal_id", principalID, "error", err)
+		return
+	}
+	if pause == nil {
+		s.gateway.agentManager.Resume(agentID)
+		return
+	}
+	s.gateway.agentManager.Pause(agentID, agent.PauseInfo{PausedBy: pause.PausedBy, PausedAt: pause.PausedAt})
+}
+
 // maybeUpdateBindingsForWorkspace updates bindings that match an agent's workspace
 // name to point to the newly registered agent. This handles device prefix changes
 // (e.g., when "m_notes" reconnects as "magic_notes", bindings update automatically).
@@ -416,10 +663,10 @@ func (s *covenControlServer) maybeUpdateBindingsForWorkspace(ctx context.Context
 		return
 	}
 
-	// Type assert to SQLiteStore to access UpdateBindingsByWorkspace
-	sqlStore, ok := s.gateway.store.(*store.SQLiteStore)
+	// Type assert to SQLStore to access UpdateBindingsByWorkspace
+	sqlStore, ok := s.gateway.store.(*store.SQLStore)
 	if !ok {
-		s.logger.Error("cannot update bindings: store is not SQLiteStore")
+		s.logger.Error("cannot update bindings: store is not SQLStore")
 		return
 	}
 
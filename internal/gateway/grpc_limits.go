@@ -0,0 +1,75 @@
+// ABOUTME: gRPC message size limits and compression for the gateway's server
+// ABOUTME: Agent messages over server.grpc_max_recv_bytes fail their request, not the stream
+
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"google.golang.org/grpc"
+	// Registers the gzip compressor so agents can negotiate compression.
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/config"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// defaultGRPCMaxRecvBytes is the receive limit when server.grpc_max_recv_bytes
+// is unset: gRPC's own default.
+const defaultGRPCMaxRecvBytes = 4 << 20
+
+// grpcRecvHeadroom is how many times the receive limit the transport accepts.
+// gRPC ends the stream on a message over its limit, so the transport's is set
+// higher and the gateway refuses messages between the two itself, keeping the
+// stream open. Only messages over the transport's limit end the stream.
+const grpcRecvHeadroom = 4
+
+// grpcMaxRecvBytes returns the largest agent message cfg accepts.
+func grpcMaxRecvBytes(cfg *config.Config) int {
+	if cfg != nil && cfg.Server.GRPCMaxRecvBytes > 0 {
+		return cfg.Server.GRPCMaxRecvBytes
+	}
+	return defaultGRPCMaxRecvBytes
+}
+
+// grpcMessageOptions returns the server options for cfg's message size
+// limits.
+func grpcMessageOptions(cfg *config.Config) []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(min(grpcMaxRecvBytes(cfg)*grpcRecvHeadroom, math.MaxInt32)),
+	}
+	if cfg != nil && cfg.Server.GRPCMaxSendBytes > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(cfg.Server.GRPCMaxSendBytes))
+	}
+	return opts
+}
+
+// refuseOversized reports whether msg is over the receive limit. An oversized
+// response fails its request with payload_too_large and an oversized pack tool
+// call gets an error result; anything else is dropped. The stream carries on.
+func (s *covenControlServer) refuseOversized(stream pb.CovenControl_AgentStreamServer, conn *agent.Connection, msg *pb.AgentMessage) bool {
+	size := proto.Size(msg)
+	if size <= s.maxRecvBytes {
+		return false
+	}
+	s.logger.Warn("agent message over the size limit",
+		"agent_id", conn.ID,
+		"size", size,
+		"limit", s.maxRecvBytes,
+	)
+	switch payload := msg.GetPayload().(type) {
+	case *pb.AgentMessage_Response:
+		requestID := payload.Response.GetRequestId()
+		if conn.RejectOversized(requestID, size, s.maxRecvBytes) {
+			s.gateway.sessions.complete(context.Background(), requestID)
+		}
+	case *pb.AgentMessage_ExecutePackTool:
+		s.sendPackToolError(stream, payload.ExecutePackTool.GetRequestId(),
+			fmt.Sprintf("%s: pack tool call of %d bytes is over the %d byte limit", agent.ErrorCodePayloadTooLarge, size, s.maxRecvBytes))
+	}
+	return true
+}
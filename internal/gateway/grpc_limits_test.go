@@ -0,0 +1,95 @@
+// ABOUTME: Tests for gRPC message size limits: a fake agent sends responses just under
+// ABOUTME: and just over server.grpc_max_recv_bytes over a gzip-compressed stream
+
+package gateway
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// sizedResponse returns a text response for requestID whose AgentMessage
+// encodes to exactly size bytes.
+func sizedResponse(t *testing.T, requestID string, size int) *pb.MessageResponse {
+	t.Helper()
+	resp := &pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Text{}}
+	msg := &pb.AgentMessage{Payload: &pb.AgentMessage_Response{Response: resp}}
+	for n := size - proto.Size(msg); n > 0; n-- {
+		resp.Event = &pb.MessageResponse_Text{Text: strings.Repeat("x", n)}
+		if proto.Size(msg) == size {
+			return resp
+		}
+	}
+	t.Fatalf("no text response encodes to %d bytes", size)
+	return nil
+}
+
+// nextResponse waits for the next response on ch.
+func nextResponse(t *testing.T, ch <-chan *agent.Response) *agent.Response {
+	t.Helper()
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			t.Fatal("response channel closed")
+		}
+		return resp
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a response")
+		return nil
+	}
+}
+
+func TestGRPCLimits_OversizedResponseFailsRequest(t *testing.T) {
+	const limit = 64 << 10
+	cfg := sessionTestConfig(t)
+	cfg.Server.GRPCMaxRecvBytes = limit
+
+	g := startSessionGateway(t, cfg, "principal-1")
+	a, err := g.connect(t, "agent-1", "", grpc.UseCompressor(gzip.Name))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	respCh, err := g.gw.agentManager.SendMessage(t.Context(), &agent.SendRequest{AgentID: "agent-1", Content: "show the diff"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	requestID := a.recv(t).GetSendMessage().GetRequestId()
+
+	a.respond(t, sizedResponse(t, requestID, limit))
+	if resp := nextResponse(t, respCh); resp.Event != agent.EventText || len(resp.Text) < limit-100 {
+		t.Fatalf("under the limit: event %q with %d bytes of text, want the text", resp.Event, len(resp.Text))
+	}
+
+	a.respond(t, sizedResponse(t, requestID, limit+1))
+	resp := nextResponse(t, respCh)
+	if resp.Event != agent.EventError || resp.Code != agent.ErrorCodePayloadTooLarge || !resp.Done {
+		t.Fatalf("over the limit: %+v, want a final payload_too_large error", resp)
+	}
+	if !strings.Contains(resp.Error, "65537 bytes") {
+		t.Errorf("error = %q, want the response size", resp.Error)
+	}
+	cancel := a.recv(t).GetCancelRequest()
+	if cancel.GetRequestId() != requestID || cancel.GetReason() != string(agent.ErrorCodePayloadTooLarge) {
+		t.Errorf("cancel = %+v, want request %s canceled for payload_too_large", cancel, requestID)
+	}
+
+	// The stream survives: the agent's next request goes through.
+	respCh, err = g.gw.agentManager.SendMessage(t.Context(), &agent.SendRequest{AgentID: "agent-1", Content: "again"})
+	if err != nil {
+		t.Fatalf("SendMessage after the oversized response: %v", err)
+	}
+	requestID = a.recv(t).GetSendMessage().GetRequestId()
+	a.respond(t, &pb.MessageResponse{RequestId: requestID, Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "ok"}}})
+	if resp := nextResponse(t, respCh); resp.Event != agent.EventDone {
+		t.Errorf("after the oversized response: event %q, want done", resp.Event)
+	}
+}
@@ -0,0 +1,138 @@
+// ABOUTME: Tests for CovenControl registration and heartbeat handling
+// ABOUTME: Covers metadata normalization, recorded last-seen metadata and heartbeat acks
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestRegistration_NormalizesAndRecordsMetadata(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Agents.MetadataLimits.MaxFieldBytes = 128
+	gw, err := New(cfg, testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer gw.Shutdown(context.Background())
+
+	sqlStore := gw.store.(*store.SQLStore)
+	ctx := context.Background()
+	if err := sqlStore.CreatePrincipal(ctx, &store.Principal{
+		ID: "principal-meta", Type: store.PrincipalTypeAgent, PubkeyFP: "fp-meta",
+		DisplayName: "meta agent", Status: store.PrincipalStatusApproved, CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreatePrincipal: %v", err)
+	}
+
+	srv := newCovenControlServer(gw, testLogger())
+	authCtx := auth.WithAuth(ctx, &auth.AuthContext{PrincipalID: "principal-meta"})
+	info := srv.extractRegistrationInfo(authCtx, &pb.RegisterAgent{
+		AgentId: "agent-meta",
+		Metadata: &pb.AgentMetadata{
+			WorkingDirectory: "/srv/" + strings.Repeat("d", 4096),
+			Hostname:         "host\x00name",
+			Workspaces:       []string{"dev"},
+		},
+	})
+
+	if len(info.workingDir) > 128 || !strings.HasSuffix(info.workingDir, agent.TruncationMarker) {
+		t.Errorf("workingDir = %d bytes, want ≤128 ending in the marker", len(info.workingDir))
+	}
+	if info.metadata.Hostname != "" {
+		t.Errorf("hostname = %q, want rejected", info.metadata.Hostname)
+	}
+
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: "agent-meta", PrincipalID: info.principalID, WorkingDir: info.workingDir, Metadata: info.metadata,
+	})
+	srv.recordPrincipalSeen(ctx, conn)
+
+	p, err := sqlStore.GetPrincipal(ctx, "principal-meta")
+	if err != nil {
+		t.Fatalf("GetPrincipal: %v", err)
+	}
+	if p.LastSeen == nil {
+		t.Error("last_seen not recorded")
+	}
+	recorded, ok := p.Metadata["agent"].(map[string]any)
+	if !ok {
+		t.Fatalf("principal metadata = %v, want an agent entry", p.Metadata)
+	}
+	if recorded["working_dir"] != info.workingDir || recorded["hostname"] != nil {
+		t.Errorf("recorded = %v, want the normalized form", recorded)
+	}
+	if truncated, _ := recorded["truncated"].([]any); len(truncated) != 1 || truncated[0] != "working_directory" {
+		t.Errorf("recorded truncated = %v, want [working_directory]", recorded["truncated"])
+	}
+}
+
+func TestHandleHeartbeat_AcksAgentsThatSupportIt(t *testing.T) {
+	gw := newTestGateway(t)
+	srv := newCovenControlServer(gw, testLogger())
+
+	acking := &capturingStream{}
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: "agent-ack", Stream: acking, Logger: testLogger(),
+		Metadata: &agent.Metadata{ProtocolFeatures: []string{agent.FeatureHeartbeatAck}},
+	})
+	legacy := &capturingStream{}
+	old := agent.NewConnection(agent.ConnectionParams{ID: "agent-old", Stream: legacy, Logger: testLogger()})
+
+	srv.handleHeartbeat(conn, &pb.Heartbeat{TimestampMs: 1234})
+	srv.handleHeartbeat(old, &pb.Heartbeat{TimestampMs: 1234})
+
+	if len(acking.sent) != 1 || acking.sent[0].GetHeartbeatAck().GetTimestampMs() != 1234 || acking.sent[0].GetHeartbeatAck().GetServerTimestampMs() == 0 {
+		t.Errorf("sent to acking agent = %v, want one HeartbeatAck echoing 1234", acking.sent)
+	}
+	if len(legacy.sent) != 0 {
+		t.Errorf("sent to legacy agent = %v, want nothing", legacy.sent)
+	}
+}
+
+func TestAgentHealthRecorder_SavesDegradation(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx := context.Background()
+	record := agentHealthRecorder(gw.store, testLogger())
+
+	record("agent-1", agent.HealthHealthy, agent.HeartbeatHealth{State: agent.HealthDegraded, Reason: "no heartbeat for 1m5s"})
+	record("agent-1", agent.HealthDegraded, agent.HeartbeatHealth{State: agent.HealthHealthy})
+
+	res, err := gw.store.GetEvents(ctx, store.GetEventsParams{ConversationKey: "agent-1"})
+	if err != nil {
+		t.Fatalf("GetEvents: %v", err)
+	}
+	if len(res.Events) != 1 || res.Events[0].Type != store.EventTypeAgentHealth {
+		t.Fatalf("events = %+v, want one agent_health event", res.Events)
+	}
+	var got agentHealthRecord
+	if err := json.Unmarshal([]byte(*res.Events[0].Text), &got); err != nil {
+		t.Fatalf("decoding event: %v", err)
+	}
+	if got.State != agent.HealthDegraded || got.From != agent.HealthHealthy || got.Reason == "" {
+		t.Errorf("event = %+v, want healthy -> degraded with a reason", got)
+	}
+}
+
+func TestAgentHealthThresholds_FollowHeartbeatSettings(t *testing.T) {
+	got := agentHealthThresholds(config.AgentsConfig{HeartbeatInterval: 30 * time.Second, HeartbeatTimeout: 90 * time.Second})
+	if got.DegradedAfter != time.Minute || got.StaleAfter != 90*time.Second {
+		t.Errorf("thresholds = %+v, want degraded after 1m and stale after 90s", got)
+	}
+
+	cfg := config.AgentsConfig{HeartbeatInterval: 30 * time.Second, HeartbeatTimeout: 90 * time.Second}
+	cfg.Health.DegradedAfter = 2 * time.Minute
+	if got := agentHealthThresholds(cfg); got.DegradedAfter != 2*time.Minute || got.StaleAfter != 2*time.Minute {
+		t.Errorf("thresholds = %+v, want stale no sooner than degraded", got)
+	}
+}
@@ -0,0 +1,128 @@
+// ABOUTME: Idempotency keys on POST /api/send: answering duplicates and sweeping expired keys.
+// ABOUTME: Keys are tracked by dedupe.Idempotency, shared with gRPC ClientService.SendMessage.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/dedupe"
+	"github.com/2389/coven-gateway/internal/logctx"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+const (
+	// idempotencyKeyHeader carries a send's idempotency key; it wins over the
+	// idempotency_key body field.
+	idempotencyKeyHeader = "Idempotency-Key"
+
+	// idempotentReplayedHeader marks a duplicate send answered with a replay
+	// of the original request's stream.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+
+	// idempotencyKeySweepInterval is the time between idempotency key sweeps.
+	idempotencyKeySweepInterval = 10 * time.Minute
+)
+
+// idempotencyKeyStore is what the idempotency key sweep needs from storage.
+type idempotencyKeyStore interface {
+	DeleteExpiredIdempotencyKeys(ctx context.Context, cutoff time.Time) error
+}
+
+// DuplicateSendResponse is the JSON response to a duplicate send whose
+// original stream is no longer buffered.
+type DuplicateSendResponse struct {
+	Duplicate bool   `json:"duplicate"`
+	RequestID string `json:"request_id"`
+}
+
+// sendIdempotencyKey returns the send's idempotency key, from the
+// Idempotency-Key header or else the body, or "" when it has none.
+func sendIdempotencyKey(r *http.Request, req *SendMessageRequest) (string, error) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" {
+		key = req.IdempotencyKey
+	}
+	if len(key) > dedupe.MaxKeyLength {
+		return "", fmt.Errorf("idempotency key longer than %d characters", dedupe.MaxKeyLength)
+	}
+	return key, nil
+}
+
+// principalIDOf returns the request's principal ID, or "" when auth is
+// disabled.
+func principalIDOf(ctx context.Context) string {
+	if a := auth.FromContext(ctx); a != nil {
+		return a.PrincipalID
+	}
+	return ""
+}
+
+// serveDuplicateSend answers a send whose idempotency key prior already
+// used. The original stream is replayed while it is buffered from its first
+// event; otherwise the response only names the original request.
+func (g *Gateway) serveDuplicateSend(w http.ResponseWriter, r *http.Request, prior *store.IdempotencyKey) {
+	if prior.RequestID == "" {
+		g.sendCodedError(w, http.StatusConflict, coverr.Conflict, "a send with this idempotency key is still being accepted")
+		return
+	}
+	if flusher, ok := w.(http.Flusher); ok && g.eventBroadcaster.CanReplayRequest(prior.RequestID) {
+		w.Header().Set(idempotentReplayedHeader, "true")
+		setSSEHeaders(w)
+		g.serveRequestStream(r.Context(), w, flusher, prior.RequestID, 0)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(DuplicateSendResponse{Duplicate: true, RequestID: prior.RequestID}); err != nil {
+		g.logger.Debug("failed to encode duplicate send response", "error", err)
+	}
+}
+
+// finishIdempotencyKey records the request a held key's send started, or
+// releases the key when the send was refused so a retry is processed.
+func (g *Gateway) finishIdempotencyKey(ctx context.Context, key, requestID string) {
+	principalID := principalIDOf(ctx)
+	logger := logctx.FromContext(ctx, g.logger)
+	ctx = context.WithoutCancel(ctx)
+	var err error
+	if requestID == "" {
+		err = g.idempotency.Abandon(ctx, principalID, key)
+	} else {
+		err = g.idempotency.Complete(ctx, principalID, key, requestID)
+	}
+	if err != nil {
+		logger.Error("failed to record idempotency key", "error", err)
+	}
+}
+
+// watchIdempotencyKeys sweeps expired idempotency keys every
+// idempotencyKeySweepInterval until ctx is done.
+func (g *Gateway) watchIdempotencyKeys(ctx context.Context) {
+	if g.idempotency == nil || g.idempotencyKeys == nil {
+		return
+	}
+	ticker := time.NewTicker(idempotencyKeySweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			g.sweepIdempotencyKeys(ctx, now)
+		}
+	}
+}
+
+// sweepIdempotencyKeys deletes the idempotency keys first used more than the
+// idempotency window before now.
+func (g *Gateway) sweepIdempotencyKeys(ctx context.Context, now time.Time) {
+	if err := g.idempotencyKeys.DeleteExpiredIdempotencyKeys(ctx, now.Add(-g.idempotency.Window())); err != nil && ctx.Err() == nil {
+		g.logger.Warn("idempotency key sweep failed", "error", err)
+	}
+}
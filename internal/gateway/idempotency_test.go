@@ -0,0 +1,157 @@
+// ABOUTME: Tests for idempotency keys on POST /api/send
+// ABOUTME: Covers replaying a duplicate's stream, the duplicate response once it is gone, and rejected keys
+
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// newIdempotencyTestServer serves /api/send from a gateway whose agent's
+// responses are fed through the returned sender.
+func newIdempotencyTestServer(t *testing.T) (*Gateway, *httptest.Server, *channelSender) {
+	t.Helper()
+	gw := newTestGateway(t)
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: "test-agent", Name: "Test", PrincipalID: "test-agent",
+		Stream: &testMockStream{}, Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("registering agent: %v", err)
+	}
+	sqlStore, ok := gw.store.(*store.SQLStore)
+	if !ok {
+		t.Fatal("store is not *SQLStore")
+	}
+	sender := &channelSender{ch: make(chan *agent.Response, 8)}
+	gw.conversation = conversation.New(sqlStore, sender, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/send", gw.handleSendMessage)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return gw, srv, sender
+}
+
+// postIdempotentSend posts a send carrying key in the Idempotency-Key header.
+func postIdempotentSend(t *testing.T, srv *httptest.Server, key string) *http.Response {
+	t.Helper()
+	body, _ := json.Marshal(SendMessageRequest{Sender: "u", Content: "hello", AgentID: "test-agent"})
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/send", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(idempotencyKeyHeader, key)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /api/send: %v", err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestSendMessage_IdempotencyKeyReplaysStream(t *testing.T) {
+	gw, srv, sender := newIdempotencyTestServer(t)
+
+	first := postIdempotentSend(t, srv, "retry-1")
+	stream := bufio.NewReader(first.Body)
+	started := readSSEEvent(t, stream)
+	sender.ch <- &agent.Response{Event: agent.EventText, Text: "one"}
+	sender.ch <- &agent.Response{Event: agent.EventDone, Text: "one", Done: true}
+	if _, err := io.ReadAll(stream); err != nil {
+		t.Fatalf("reading first stream: %v", err)
+	}
+
+	// The retry gets the original stream again, from its first event.
+	retry := postIdempotentSend(t, srv, "retry-1")
+	if got := retry.Header.Get(idempotentReplayedHeader); got != "true" {
+		t.Errorf("%s = %q, want true", idempotentReplayedHeader, got)
+	}
+	stream = bufio.NewReader(retry.Body)
+	if ev := readSSEEvent(t, stream); ev != started {
+		t.Fatalf("replayed first event = %+v, want %+v", ev, started)
+	}
+	if ev := readSSEEvent(t, stream); ev.data != `{"text":"one"}` {
+		t.Errorf("replayed second event = %+v", ev)
+	}
+	if ev := readSSEEvent(t, stream); ev.event != "done" {
+		t.Errorf("replayed last event = %+v, want done", ev)
+	}
+
+	var startedData struct {
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal([]byte(started.data), &startedData); err != nil {
+		t.Fatalf("started data = %s", started.data)
+	}
+
+	// Once the stream is no longer buffered, the retry only names it.
+	gw.eventBroadcaster.SetRequestRetention(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	late := postIdempotentSend(t, srv, "retry-1")
+	if late.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", late.StatusCode)
+	}
+	var dup DuplicateSendResponse
+	if err := json.NewDecoder(late.Body).Decode(&dup); err != nil {
+		t.Fatalf("decoding duplicate response: %v", err)
+	}
+	if !dup.Duplicate || dup.RequestID != startedData.RequestID {
+		t.Errorf("duplicate response = %+v, want request %s", dup, startedData.RequestID)
+	}
+}
+
+func TestSendMessage_IdempotencyKeyStillAccepting(t *testing.T) {
+	gw, srv, _ := newIdempotencyTestServer(t)
+
+	// Another send holds the key but has not been accepted yet.
+	if prior, err := gw.idempotency.Begin(context.Background(), "", "held"); err != nil || prior != nil {
+		t.Fatalf("Begin = %+v, %v", prior, err)
+	}
+	resp := postIdempotentSend(t, srv, "held")
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("status = %d, want 409", resp.StatusCode)
+	}
+}
+
+func TestSendMessage_IdempotencyKeyTooLong(t *testing.T) {
+	_, srv, _ := newIdempotencyTestServer(t)
+
+	resp := postIdempotentSend(t, srv, strings.Repeat("k", 101))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestSendMessage_RefusedSendReleasesIdempotencyKey(t *testing.T) {
+	gw, srv, _ := newIdempotencyTestServer(t)
+
+	body, _ := json.Marshal(SendMessageRequest{
+		Sender: "u", Content: "hello", AgentID: "no-such-agent", IdempotencyKey: "body-key",
+	})
+	resp, err := http.Post(srv.URL+"/api/send", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /api/send: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		t.Fatal("send to an unknown agent was accepted")
+	}
+
+	prior, err := gw.idempotency.Begin(context.Background(), "", "body-key")
+	if err != nil || prior != nil {
+		t.Errorf("Begin after refused send = %+v, %v; want the key free", prior, err)
+	}
+}
@@ -0,0 +1,48 @@
+// ABOUTME: Background sweep that deletes device link codes some time after they expire.
+// ABOUTME: Expired codes linger for linkCodeRetention so a late approval is told the code expired.
+
+package gateway
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// linkCodeSweepInterval is the time between link code sweeps.
+	linkCodeSweepInterval = time.Minute
+	// linkCodeRetention is how long an expired link code is kept. Until it
+	// is deleted, approving it answers 410 rather than 404.
+	linkCodeRetention = time.Hour
+)
+
+// linkCodeStore is what the link code sweep needs from storage.
+type linkCodeStore interface {
+	DeleteExpiredLinkCodes(ctx context.Context, cutoff time.Time) error
+}
+
+// watchLinkCodes sweeps expired link codes every linkCodeSweepInterval until
+// ctx is done.
+func (g *Gateway) watchLinkCodes(ctx context.Context) {
+	if g.linkCodes == nil {
+		return
+	}
+	ticker := time.NewTicker(linkCodeSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			g.sweepLinkCodes(ctx, now)
+		}
+	}
+}
+
+// sweepLinkCodes deletes the pending link codes that expired more than
+// linkCodeRetention before now.
+func (g *Gateway) sweepLinkCodes(ctx context.Context, now time.Time) {
+	if err := g.linkCodes.DeleteExpiredLinkCodes(ctx, now.Add(-linkCodeRetention)); err != nil && ctx.Err() == nil {
+		g.logger.Warn("link code sweep failed", "error", err)
+	}
+}
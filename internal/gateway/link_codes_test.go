@@ -0,0 +1,41 @@
+// ABOUTME: Tests for the link code sweep
+// ABOUTME: Checks that only codes expired longer than the retention are deleted
+
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestSweepLinkCodes(t *testing.T) {
+	gw := newTestGateway(t)
+	sqlStore := gw.store.(*store.SQLStore)
+	ctx := context.Background()
+	now := time.Now()
+
+	for id, expiresAt := range map[string]time.Time{
+		"live":   now.Add(5 * time.Minute),
+		"recent": now.Add(-time.Minute),
+		"old":    now.Add(-linkCodeRetention - time.Minute),
+	} {
+		if err := sqlStore.CreateLinkCode(ctx, &store.LinkCode{
+			ID: id, Code: "C-" + id, Fingerprint: "fp-" + id, DeviceName: "laptop",
+			Status: store.LinkCodeStatusPending, CreatedAt: expiresAt.Add(-10 * time.Minute), ExpiresAt: expiresAt,
+		}); err != nil {
+			t.Fatalf("CreateLinkCode(%s): %v", id, err)
+		}
+	}
+
+	gw.sweepLinkCodes(ctx, now)
+
+	for id, want := range map[string]bool{"live": true, "recent": true, "old": false} {
+		_, err := sqlStore.GetLinkCode(ctx, id)
+		if kept := err == nil; kept != want {
+			t.Errorf("code %s kept = %v, want %v (err %v)", id, kept, want, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// ABOUTME: HTTP listener layout: one listener for everything, or the web admin UI split onto server.admin_http_addr
+// ABOUTME: Tracks whether each HTTP listener is serving so readiness can report them separately
+
+package gateway
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/logctx"
+	"github.com/2389/coven-gateway/internal/webadmin"
+)
+
+// HTTP listener names, as reported by the readiness probe.
+const (
+	listenerAPI   = "api"
+	listenerAdmin = "admin"
+)
+
+// ListenerStatus reports one HTTP listener in the verbose readiness response.
+type ListenerStatus struct {
+	Name    string `json:"name"`
+	Addr    string `json:"addr"`
+	Serving bool   `json:"serving"`
+}
+
+// listenerSet records the HTTP listeners Run has started.
+type listenerSet struct {
+	mu       sync.Mutex
+	statuses []ListenerStatus
+}
+
+func (l *listenerSet) set(name, addr string, serving bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range l.statuses {
+		if l.statuses[i].Name == name {
+			l.statuses[i].Addr = addr
+			l.statuses[i].Serving = serving
+			return
+		}
+	}
+	l.statuses = append(l.statuses, ListenerStatus{Name: name, Addr: addr, Serving: serving})
+}
+
+// snapshot returns the listeners and whether all of them are serving.
+func (l *listenerSet) snapshot() ([]ListenerStatus, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	allServing := true
+	for _, s := range l.statuses {
+		allServing = allServing && s.Serving
+	}
+	return append([]ListenerStatus(nil), l.statuses...), allServing
+}
+
+// newHTTPServer wraps a mux the way every gateway HTTP listener is served:
+// each request gets a request ID for its log lines and the CSP headers.
+func newHTTPServer(addr string, mux *http.ServeMux) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           logctx.HTTPMiddleware(webadmin.CSPMiddleware(mux)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// listenAdmin opens the admin UI listener. It returns nil when the UI shares
+// the API listener.
+func (g *Gateway) listenAdmin() (net.Listener, error) {
+	if g.adminHTTPServer == nil {
+		return nil, nil
+	}
+	ln, err := net.Listen("tcp", g.config.Server.AdminHTTPAddr)
+	if err != nil {
+		return nil, fmt.Errorf("listening on admin HTTP address: %w", err)
+	}
+	return ln, nil
+}
+
+// listenMetrics opens the metrics listener. It returns nil when metrics are
+// disabled or served on the API listener.
+func (g *Gateway) listenMetrics() (net.Listener, error) {
+	if g.metricsHTTPServer == nil {
+		return nil, nil
+	}
+	ln, err := net.Listen("tcp", g.config.Metrics.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("listening on metrics address: %w", err)
+	}
+	return ln, nil
+}
+
+// serveHTTP serves srv on ln until it is shut down, reporting failures on errCh.
+func (g *Gateway) serveHTTP(name string, srv *http.Server, ln net.Listener, errCh chan<- error) {
+	addr := ln.Addr().String()
+	g.listeners.set(name, addr, true)
+	defer g.listeners.set(name, addr, false)
+
+	g.logger.Info("HTTP server listening", "listener", name, "addr", addr)
+	if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+		if name == listenerAPI {
+			errCh <- fmt.Errorf("HTTP server: %w", err)
+		} else {
+			errCh <- fmt.Errorf("%s HTTP server: %w", name, err)
+		}
+	}
+}
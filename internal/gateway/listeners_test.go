@@ -0,0 +1,152 @@
+// ABOUTME: Tests for the HTTP listener layout with and without server.admin_http_addr
+// ABOUTME: Runs a real gateway and checks which routes each listener answers
+
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// freeAddr returns a loopback address with a port nothing is listening on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find available port: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestHTTPListeners(t *testing.T) {
+	// Don't follow redirects: an admin route must 404 on the API listener,
+	// not bounce the browser to the login page.
+	client := &http.Client{
+		Timeout:       5 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	// get reads and closes the body right away, so no connection is still
+	// busy when the gateway shuts down; the returned body is an in-memory copy.
+	get := func(t *testing.T, url string) *http.Response {
+		t.Helper()
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("GET %s: %v", url, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("GET %s: reading body: %v", url, err)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp
+	}
+	adminRoutes := []string{"/", "/login", "/admin/", "/static/app.js"}
+
+	t.Run("single listener", func(t *testing.T) {
+		cfg := testConfig(t)
+		gw, err := New(cfg, testLogger())
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		go func() { _ = gw.Run(t.Context()) }()
+		time.Sleep(100 * time.Millisecond)
+
+		api := "http://" + cfg.Server.HTTPAddr
+		if resp := get(t, api+"/health"); resp.StatusCode != http.StatusOK {
+			t.Errorf("/health = %d, want 200", resp.StatusCode)
+		}
+		for _, path := range adminRoutes[:3] {
+			if resp := get(t, api+path); resp.StatusCode == http.StatusNotFound {
+				t.Errorf("%s = 404 on the shared listener", path)
+			}
+		}
+
+		var ready ReadyResponse
+		if err := json.NewDecoder(get(t, api+"/health/ready?verbose=1").Body).Decode(&ready); err != nil {
+			t.Fatalf("decode ready: %v", err)
+		}
+		if len(ready.Listeners) != 1 || ready.Listeners[0].Name != listenerAPI || !ready.Listeners[0].Serving {
+			t.Errorf("listeners = %+v, want only a serving api listener", ready.Listeners)
+		}
+	})
+
+	t.Run("split listeners", func(t *testing.T) {
+		cfg := testConfig(t)
+		cfg.Server.AdminHTTPAddr = freeAddr(t)
+		gw, err := New(cfg, testLogger())
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		if gw.webAdminBaseURL != "http://"+cfg.Server.AdminHTTPAddr {
+			t.Errorf("webAdminBaseURL = %q, want the admin listener", gw.webAdminBaseURL)
+		}
+		go func() { _ = gw.Run(t.Context()) }()
+		time.Sleep(100 * time.Millisecond)
+
+		api := "http://" + cfg.Server.HTTPAddr
+		admin := "http://" + cfg.Server.AdminHTTPAddr
+		for _, path := range adminRoutes {
+			if resp := get(t, api+path); resp.StatusCode != http.StatusNotFound {
+				t.Errorf("API listener %s = %d, want 404", path, resp.StatusCode)
+			}
+		}
+		for _, path := range adminRoutes[:3] {
+			if resp := get(t, admin+path); resp.StatusCode == http.StatusNotFound {
+				t.Errorf("admin listener %s = 404", path)
+			}
+		}
+		if resp := get(t, api+"/health"); resp.StatusCode != http.StatusOK {
+			t.Errorf("API listener /health = %d, want 200", resp.StatusCode)
+		}
+		if resp := get(t, admin+"/health"); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("admin listener /health = %d, want 404", resp.StatusCode)
+		}
+		// Devices link over the API listener; an empty body is a 400, not a 404.
+		resp, err := client.Post(api+"/api/link/request", "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			t.Fatalf("POST /api/link/request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("API listener /api/link/request = %d, want 400", resp.StatusCode)
+		}
+
+		var ready ReadyResponse
+		if err := json.NewDecoder(get(t, api+"/health/ready?verbose=1").Body).Decode(&ready); err != nil {
+			t.Fatalf("decode ready: %v", err)
+		}
+		serving := map[string]bool{}
+		for _, l := range ready.Listeners {
+			serving[l.Name] = l.Serving
+		}
+		if len(ready.Listeners) != 2 || !serving[listenerAPI] || !serving[listenerAdmin] {
+			t.Errorf("listeners = %+v, want serving api and admin listeners", ready.Listeners)
+		}
+
+		client.CloseIdleConnections()
+		report, err := gw.Shutdown(t.Context())
+		if err != nil {
+			t.Fatalf("Shutdown: %v", err)
+		}
+		var stopped bool
+		for _, c := range report.Components {
+			stopped = stopped || c.Name == "admin-http"
+		}
+		if !stopped {
+			t.Error("shutdown report does not include the admin listener")
+		}
+		if resp, err := client.Get(admin + "/login"); err == nil {
+			resp.Body.Close()
+			t.Error("admin listener still accepting after shutdown")
+		}
+	})
+}
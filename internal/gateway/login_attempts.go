@@ -0,0 +1,49 @@
+// ABOUTME: Background sweep that forgets failed admin logins once they no longer matter.
+// ABOUTME: Rows idle for loginAttemptRetention, and not still locked out, are deleted.
+
+package gateway
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// loginAttemptSweepInterval is the time between login attempt sweeps.
+	loginAttemptSweepInterval = 10 * time.Minute
+	// loginAttemptRetention is how long a username and IP's failures and
+	// lockout count are kept after the last failure. Once they are gone,
+	// the next lockout is back to the shortest.
+	loginAttemptRetention = 24 * time.Hour
+)
+
+// loginAttemptStore is what the login attempt sweep needs from storage.
+type loginAttemptStore interface {
+	DeleteStaleLoginAttempts(ctx context.Context, cutoff time.Time) error
+}
+
+// watchLoginAttempts sweeps stale login attempts every
+// loginAttemptSweepInterval until ctx is done.
+func (g *Gateway) watchLoginAttempts(ctx context.Context) {
+	if g.loginAttempts == nil {
+		return
+	}
+	ticker := time.NewTicker(loginAttemptSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			g.sweepLoginAttempts(ctx, now)
+		}
+	}
+}
+
+// sweepLoginAttempts deletes the login attempts last updated more than
+// loginAttemptRetention before now.
+func (g *Gateway) sweepLoginAttempts(ctx context.Context, now time.Time) {
+	if err := g.loginAttempts.DeleteStaleLoginAttempts(ctx, now.Add(-loginAttemptRetention)); err != nil && ctx.Err() == nil {
+		g.logger.Warn("login attempt sweep failed", "error", err)
+	}
+}
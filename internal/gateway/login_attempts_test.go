@@ -0,0 +1,33 @@
+// ABOUTME: Tests for the login attempt sweep
+// ABOUTME: Checks that failures are kept until loginAttemptRetention has passed
+
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestSweepLoginAttempts(t *testing.T) {
+	gw := newTestGateway(t)
+	sqlStore := gw.store.(*store.SQLStore)
+	ctx := context.Background()
+	now := time.Now()
+
+	if _, err := sqlStore.RecordLoginFailure(ctx, "alice", "10.0.0.1", now, time.Minute); err != nil {
+		t.Fatalf("RecordLoginFailure: %v", err)
+	}
+
+	gw.sweepLoginAttempts(ctx, now)
+	if _, err := sqlStore.GetLoginAttempt(ctx, "alice", "10.0.0.1"); err != nil {
+		t.Fatalf("recent failure swept: %v", err)
+	}
+
+	gw.sweepLoginAttempts(ctx, now.Add(loginAttemptRetention+time.Minute))
+	if _, err := sqlStore.GetLoginAttempt(ctx, "alice", "10.0.0.1"); err == nil {
+		t.Error("failure kept past the retention")
+	}
+}
@@ -0,0 +1,48 @@
+// ABOUTME: Tests for the gateway's MCP endpoint wiring
+// ABOUTME: Checks that the mcp config section reaches the MCP server
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/mcp"
+)
+
+func TestMCPBatchLimitFromConfig(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MCP.MaxBatchSize = 2
+	gw, err := New(cfg, testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer gw.Shutdown(context.Background())
+
+	post := func(entries int) mcp.JSONRPCResponse {
+		t.Helper()
+		entry := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+		body := "[" + strings.TrimSuffix(strings.Repeat(entry+",", entries), ",") + "]"
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		gw.httpServer.Handler.ServeHTTP(rec, req)
+		var resp mcp.JSONRPCResponse
+		// A batch under the limit is answered with an array or an auth error;
+		// only the single-object rejection decodes here.
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+
+	resp := post(3)
+	if resp.Error == nil || resp.Error.Code != mcp.JSONRPCInvalidRequest || !strings.Contains(resp.Error.Message, "at most 2") {
+		t.Errorf("3-request batch error = %+v, want %d naming the configured limit", resp.Error, mcp.JSONRPCInvalidRequest)
+	}
+	if resp := post(2); resp.Error != nil && strings.Contains(resp.Error.Message, "batch too large") {
+		t.Errorf("2-request batch rejected as too large: %+v", resp.Error)
+	}
+}
@@ -0,0 +1,34 @@
+// ABOUTME: Wires upstream MCP servers from packs.mcp_servers into the pack registry.
+// ABOUTME: Translates config entries into mcpbridge upstreams; auth secrets resolve through the store.
+
+package gateway
+
+import (
+	"log/slog"
+
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/mcpbridge"
+	"github.com/2389/coven-gateway/internal/packs"
+)
+
+// newMCPBridge builds the bridge for the configured upstream MCP servers,
+// or returns nil when none are configured. It does not start the bridge.
+func newMCPBridge(servers []config.MCPServerConfig, registry *packs.Registry, secrets mcpbridge.SecretSource, logger *slog.Logger) *mcpbridge.Bridge {
+	if len(servers) == 0 {
+		return nil
+	}
+	upstreams := make([]mcpbridge.Upstream, 0, len(servers))
+	for _, s := range servers {
+		upstreams = append(upstreams, mcpbridge.Upstream{
+			Name:         s.Name,
+			URL:          s.URL,
+			AuthHeader:   s.AuthHeader,
+			AuthSecret:   s.AuthSecret,
+			AuthScheme:   s.AuthScheme,
+			Capability:   s.Capability,
+			PollInterval: s.PollInterval,
+			Timeout:      s.Timeout,
+		})
+	}
+	return mcpbridge.New(registry, upstreams, secrets, nil, logger)
+}
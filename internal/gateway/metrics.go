@@ -0,0 +1,170 @@
+// ABOUTME: Prometheus instruments for gateway internals: agents, SSE subscribers, tool calls, store queries, agent streams
+// ABOUTME: Registered on the Gateway's own registry and served at metrics.path, on metrics.addr when set
+
+package gateway
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/conversation"
+)
+
+// listenerMetrics names the metrics listener in the readiness response.
+const listenerMetrics = "metrics"
+
+// gatewayMetrics holds the instruments the gateway updates as events happen.
+// Gauges for current state are read at scrape time by liveCollector.
+type gatewayMetrics struct {
+	toolCalls           *prometheus.CounterVec
+	toolDuration        *prometheus.HistogramVec
+	toolCacheLookups    *prometheus.CounterVec
+	storeQueries        *prometheus.HistogramVec
+	heartbeatMisses     *prometheus.CounterVec
+	streamRegistrations prometheus.Counter
+	streamDisconnects   prometheus.Counter
+}
+
+func newGatewayMetrics() *gatewayMetrics {
+	return &gatewayMetrics{
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "coven_tool_calls_total",
+			Help: "Tool calls routed through the pack router, by tool and outcome.",
+		}, []string{"tool", "outcome"}),
+		toolDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "coven_tool_call_duration_seconds",
+			Help:    "Time from routing a tool call to its final response, by tool.",
+			Buckets: prometheus.ExponentialBuckets(0.005, 4, 8),
+		}, []string{"tool"}),
+		toolCacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "coven_tool_cache_lookups_total",
+			Help: "Result cache lookups for cacheable pack tools, by tool and result (hit or miss).",
+		}, []string{"tool", "result"}),
+		storeQueries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "coven_store_query_duration_seconds",
+			Help:    "Store statements run outside a transaction, by leading SQL keyword.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8),
+		}, []string{"op"}),
+		heartbeatMisses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "coven_agent_heartbeat_misses_total",
+			Help: "Heartbeat intervals that passed without a heartbeat from a connected agent.",
+		}, []string{"agent"}),
+		streamRegistrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "coven_grpc_stream_registrations_total",
+			Help: "Agent streams that completed registration.",
+		}),
+		streamDisconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "coven_grpc_stream_disconnects_total",
+			Help: "Registered agent streams that ended.",
+		}),
+	}
+}
+
+func (m *gatewayMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.toolCalls, m.toolDuration, m.toolCacheLookups, m.storeQueries,
+		m.heartbeatMisses, m.streamRegistrations, m.streamDisconnects,
+	}
+}
+
+// observeToolCall is the pack router's OnCall hook.
+func (m *gatewayMetrics) observeToolCall(toolName, outcome string, d time.Duration) {
+	m.toolCalls.WithLabelValues(toolName, outcome).Inc()
+	m.toolDuration.WithLabelValues(toolName).Observe(d.Seconds())
+}
+
+// observeToolCacheLookup is the pack router's OnCacheLookup hook.
+func (m *gatewayMetrics) observeToolCacheLookup(toolName string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.toolCacheLookups.WithLabelValues(toolName, result).Inc()
+}
+
+// observeStoreQuery is the store's query observer.
+func (m *gatewayMetrics) observeStoreQuery(op string, d time.Duration) {
+	m.storeQueries.WithLabelValues(op).Observe(d.Seconds())
+}
+
+// observeHeartbeat counts the heartbeats an agent missed since its last one:
+// every full interval beyond the first in the gap between them.
+func (m *gatewayMetrics) observeHeartbeat(agentID string, gap, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	if missed := int(gap/interval) - 1; missed > 0 {
+		m.heartbeatMisses.WithLabelValues(agentID).Add(float64(missed))
+	}
+}
+
+// liveCollector reports gauges read from the agent manager and event
+// broadcaster at scrape time.
+type liveCollector struct {
+	agents      *agent.Manager
+	broadcaster *conversation.EventBroadcaster
+
+	connected        *prometheus.Desc
+	inFlight         *prometheus.Desc
+	subscribers      *prometheus.Desc
+	droppedEvents    *prometheus.Desc
+	slowUnsubscribes *prometheus.Desc
+}
+
+func newLiveCollector(agents *agent.Manager, broadcaster *conversation.EventBroadcaster) *liveCollector {
+	return &liveCollector{
+		agents:      agents,
+		broadcaster: broadcaster,
+		connected: prometheus.NewDesc("coven_agents_connected",
+			"Agents currently registered with the gateway.", nil, nil),
+		inFlight: prometheus.NewDesc("coven_agent_inflight_requests",
+			"Requests each connected agent is handling.", []string{"agent"}, nil),
+		subscribers: prometheus.NewDesc("coven_sse_subscribers",
+			"Live event broadcaster subscriptions.", nil, nil),
+		droppedEvents: prometheus.NewDesc("coven_broadcast_dropped_events_total",
+			"Events dropped from full event broadcaster subscriber buffers.", nil, nil),
+		slowUnsubscribes: prometheus.NewDesc("coven_broadcast_slow_unsubscribes_total",
+			"Event broadcaster subscribers removed for overflowing their buffer too many times in a row.", nil, nil),
+	}
+}
+
+func (c *liveCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.connected
+	ch <- c.inFlight
+	ch <- c.subscribers
+	ch <- c.droppedEvents
+	ch <- c.slowUnsubscribes
+}
+
+func (c *liveCollector) Collect(ch chan<- prometheus.Metric) {
+	agents := c.agents.ListAgents()
+	ch <- prometheus.MustNewConstMetric(c.connected, prometheus.GaugeValue, float64(len(agents)))
+	for _, a := range agents {
+		ch <- prometheus.MustNewConstMetric(c.inFlight, prometheus.GaugeValue, float64(c.agents.InFlight(a.ID)), a.ID)
+	}
+	stats := c.broadcaster.Stats()
+	ch <- prometheus.MustNewConstMetric(c.subscribers, prometheus.GaugeValue, float64(stats.Subscribers))
+	ch <- prometheus.MustNewConstMetric(c.droppedEvents, prometheus.CounterValue, float64(stats.Dropped))
+	ch <- prometheus.MustNewConstMetric(c.slowUnsubscribes, prometheus.CounterValue, float64(stats.SlowUnsubscribes))
+}
+
+// registerMetricsHandler serves the registry at metrics.path, on mux or on
+// a listener of its own when metrics.addr is set.
+func (g *Gateway) registerMetricsHandler(mux *http.ServeMux) {
+	path := g.config.Metrics.Path
+	if path == "" {
+		path = "/metrics"
+	}
+	handler := promhttp.HandlerFor(g.metrics, promhttp.HandlerOpts{})
+	if g.config.Metrics.Addr == "" {
+		mux.Handle(path, handler)
+		return
+	}
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle(path, handler)
+	g.metricsHTTPServer = newHTTPServer(g.config.Metrics.Addr, metricsMux)
+}
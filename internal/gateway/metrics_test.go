@@ -0,0 +1,165 @@
+// ABOUTME: Tests for the gateway's Prometheus instruments and the metrics listener
+// ABOUTME: Drives a real agent stream and scrapes /metrics on the API or metrics.addr listener
+
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/2389/coven-gateway/internal/packs"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// scrape fetches url and returns the status code and body.
+func scrape(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading %s: %v", url, err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestGatewayMetrics_AgentStream(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Metrics.Enabled = true
+	cfg.Agents.HeartbeatInterval = 20 * time.Millisecond
+
+	gw, err := New(cfg, testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx := t.Context()
+	go func() { _ = gw.Run(ctx) }()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := grpc.NewClient(cfg.Server.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	stream, err := pb.NewCovenControlClient(conn).AgentStream(ctx)
+	if err != nil {
+		t.Fatalf("AgentStream() failed: %v", err)
+	}
+	if err := stream.Send(&pb.AgentMessage{Payload: &pb.AgentMessage_Register{
+		Register: &pb.RegisterAgent{AgentId: "metrics-agent", Name: "metrics-agent"},
+	}}); err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv() welcome failed: %v", err)
+	}
+
+	// Skip several heartbeat intervals before the first beat.
+	time.Sleep(90 * time.Millisecond)
+	if err := stream.Send(&pb.AgentMessage{Payload: &pb.AgentMessage_Heartbeat{
+		Heartbeat: &pb.Heartbeat{TimestampMs: time.Now().UnixMilli()},
+	}}); err != nil {
+		t.Fatalf("heartbeat send failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(gw.instruments.heartbeatMisses.WithLabelValues("metrics-agent")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if misses := testutil.ToFloat64(gw.instruments.heartbeatMisses.WithLabelValues("metrics-agent")); misses < 1 {
+		t.Errorf("heartbeat misses = %v, want at least 1", misses)
+	}
+
+	code, body := scrape(t, "http://"+cfg.Server.HTTPAddr+"/metrics")
+	if code != http.StatusOK {
+		t.Fatalf("metrics status = %d", code)
+	}
+	for _, want := range []string{
+		"coven_agents_connected 1",
+		`coven_agent_inflight_requests{agent="metrics-agent"} 0`,
+		"coven_sse_subscribers 0",
+		"coven_broadcast_dropped_events_total 0",
+		"coven_broadcast_slow_unsubscribes_total 0",
+		"coven_grpc_stream_registrations_total 1",
+		"coven_grpc_stream_disconnects_total 0",
+		`coven_store_query_duration_seconds_count{op="insert"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("CloseSend: %v", err)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(gw.instruments.streamDisconnects) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := testutil.ToFloat64(gw.instruments.streamDisconnects); got != 1 {
+		t.Errorf("stream disconnects = %v, want 1", got)
+	}
+}
+
+func TestGatewayMetrics_SeparateListener(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Metrics.Enabled = true
+	cfg.Metrics.Addr = freeAddr(t)
+
+	gw, err := New(cfg, testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx := t.Context()
+	go func() { _ = gw.Run(ctx) }()
+	time.Sleep(100 * time.Millisecond)
+
+	if code, body := scrape(t, "http://"+cfg.Metrics.Addr+"/metrics"); code != http.StatusOK || !strings.Contains(body, "coven_agents_connected 0") {
+		t.Errorf("metrics listener status = %d, body missing coven_agents_connected 0", code)
+	}
+	if code, _ := scrape(t, "http://"+cfg.Server.HTTPAddr+"/metrics"); code != http.StatusNotFound {
+		t.Errorf("API listener /metrics status = %d, want 404", code)
+	}
+}
+
+func TestGatewayMetrics_ObserveToolCall(t *testing.T) {
+	m := newGatewayMetrics()
+	m.observeToolCall("echo", packs.CallOK, 10*time.Millisecond)
+	m.observeToolCall("echo", packs.CallOK, 20*time.Millisecond)
+	m.observeToolCall("echo", packs.CallError, time.Millisecond)
+
+	if got := testutil.ToFloat64(m.toolCalls.WithLabelValues("echo", packs.CallOK)); got != 2 {
+		t.Errorf("ok calls = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.toolCalls.WithLabelValues("echo", packs.CallError)); got != 1 {
+		t.Errorf("error calls = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.toolDuration); got != 1 {
+		t.Errorf("duration series = %d, want 1", got)
+	}
+
+	m.observeToolCacheLookup("lookup", true)
+	m.observeToolCacheLookup("lookup", true)
+	m.observeToolCacheLookup("lookup", false)
+	if got := testutil.ToFloat64(m.toolCacheLookups.WithLabelValues("lookup", "hit")); got != 2 {
+		t.Errorf("cache hits = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.toolCacheLookups.WithLabelValues("lookup", "miss")); got != 1 {
+		t.Errorf("cache misses = %v, want 1", got)
+	}
+
+	m.observeHeartbeat("a", 25*time.Second, 30*time.Second)
+	m.observeHeartbeat("a", 95*time.Second, 30*time.Second)
+	if got := testutil.ToFloat64(m.heartbeatMisses.WithLabelValues("a")); got != 2 {
+		t.Errorf("heartbeat misses = %v, want 2", got)
+	}
+}
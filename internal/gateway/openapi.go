@@ -0,0 +1,200 @@
+// ABOUTME: OpenAPI document for the HTTP API, served at GET /api/openapi.json.
+// ABOUTME: Describes the data of every SSE event with structs shaped like the maps the handlers write.
+
+package gateway
+
+import (
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/builtins"
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/httpapi"
+)
+
+// openAPIPath serves the OpenAPI document. It is unversioned so clients can
+// find the versions on offer.
+const openAPIPath = "/api/openapi.json"
+
+// openAPIInfo is the info object of the OpenAPI document.
+var openAPIInfo = httpapi.Info{
+	Title:   "coven-gateway HTTP API",
+	Version: "1.0",
+	Description: "The stable API is under /api/v1. The unversioned /api paths it replaced still work, " +
+		"and their responses carry Deprecation and Link headers naming the /api/v1 successor.",
+}
+
+// The types below document SSE event data. The handlers build the data as
+// maps; TestOpenAPI_SSEEventsMatchConverters keeps the two in step.
+
+// sseStarted is the data of the started event.
+type sseStarted struct {
+	ThreadID      string          `json:"thread_id"`
+	ThreadCreated bool            `json:"thread_created"`
+	RequestID     string          `json:"request_id"`
+	Selection     string          `json:"selection"`
+	AgentID       string          `json:"agent_id,omitempty"`
+	AgentName     string          `json:"agent_name,omitempty"`
+	InstanceID    string          `json:"instance_id,omitempty"`
+	Routing       *sseRouting     `json:"routing,omitempty"`
+	Workspace     string          `json:"workspace,omitempty"`
+	Queued        bool            `json:"queued,omitempty"`
+	QueuePosition int             `json:"queue_position,omitempty"`
+	Attachments   []sseAttachment `json:"attachments,omitempty"`
+	AckMode       string          `json:"ack_mode,omitempty"`
+	HTTPRequestID string          `json:"http_request_id,omitempty"`
+}
+
+// sseRouting describes a capability selection.
+type sseRouting struct {
+	Capability string `json:"capability"`
+	Candidates int    `json:"candidates"`
+	Eligible   int    `json:"eligible"`
+	InFlight   int    `json:"in_flight"`
+	LatencyMS  int64  `json:"latency_ms"`
+	QueuedMS   int64  `json:"queued_ms"`
+}
+
+// sseAttachment is a stored attachment of the send.
+type sseAttachment struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	MimeType string `json:"mime_type"`
+	Size     int    `json:"size"`
+}
+
+// sseSessionInit carries session_id from the agent, or the other fields
+// when a fallback agent took the message.
+type sseSessionInit struct {
+	SessionID   string `json:"session_id,omitempty"`
+	AgentID     string `json:"agent_id,omitempty"`
+	AgentName   string `json:"agent_name,omitempty"`
+	PrincipalID string `json:"principal_id,omitempty"`
+	FallbackFor string `json:"fallback_for,omitempty"`
+}
+
+type sseText struct {
+	Text      string `json:"text"`
+	Continued bool   `json:"continued,omitempty"`
+}
+
+type sseReason struct {
+	Reason string `json:"reason"`
+}
+
+type sseToolUse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	InputJSON string `json:"input_json"`
+}
+
+// sseToolResult sets partial and sequence on chunks of a streaming tool's output.
+type sseToolResult struct {
+	ID       string `json:"id"`
+	Output   string `json:"output"`
+	IsError  bool   `json:"is_error"`
+	Partial  bool   `json:"partial,omitempty"`
+	Sequence uint64 `json:"sequence,omitempty"`
+	Cached   bool   `json:"cached,omitempty"`
+}
+
+type sseFile struct {
+	Filename     string `json:"filename"`
+	MimeType     string `json:"mime_type"`
+	AttachmentID string `json:"attachment_id,omitempty"`
+}
+
+type sseDone struct {
+	FullResponse string                `json:"full_response"`
+	AgentID      string                `json:"agent_id"`
+	Sources      []agent.CitationEvent `json:"sources,omitempty"`
+	TraceID      string                `json:"trace_id,omitempty"`
+}
+
+type sseUsage struct {
+	InputTokens      int32 `json:"input_tokens"`
+	OutputTokens     int32 `json:"output_tokens"`
+	CacheReadTokens  int32 `json:"cache_read_tokens"`
+	CacheWriteTokens int32 `json:"cache_write_tokens"`
+	ThinkingTokens   int32 `json:"thinking_tokens"`
+}
+
+type sseToolState struct {
+	ID     string `json:"id"`
+	State  string `json:"state"`
+	Detail string `json:"detail"`
+}
+
+type sseToolApproval struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	InputJSON string `json:"input_json"`
+	RequestID string `json:"request_id"`
+}
+
+type sseTruncated struct {
+	Reason         string  `json:"reason"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	PausedSeconds  float64 `json:"paused_seconds"`
+	LimitSeconds   float64 `json:"limit_seconds"`
+}
+
+type ssePlan struct {
+	Steps []planStepSSE `json:"steps"`
+	Text  string        `json:"text"`
+}
+
+type ssePlanStepUpdate struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Text   string `json:"text"`
+}
+
+type sseCitation struct {
+	Index   int    `json:"index"`
+	Title   string `json:"title"`
+	URI     string `json:"uri"`
+	Text    string `json:"text"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+type sseBroadcastDone struct {
+	Group   string `json:"group"`
+	Members int    `json:"members"`
+	Agents  int    `json:"agents"`
+	Failed  int    `json:"failed"`
+}
+
+// sseEvents documents every SSE event the API streams.
+var sseEvents = []httpapi.Event{
+	{Name: "started", Description: "First event of a send: the thread, the request ID to resume the stream with, and the agent chosen.", Data: sseStarted{}},
+	{Name: "session_init", Description: "The agent's session, or the fallback agent standing in for the bound one.", Data: sseSessionInit{}},
+	{Name: "thinking", Description: "A chunk of the agent's reasoning. Long text is split over several events; continued marks the later ones.", Data: sseText{}},
+	{Name: "text", Description: "A chunk of the response text. Long text is split over several events; continued marks the later ones.", Data: sseText{}},
+	{Name: "tool_use", Description: "The agent called a tool.", Data: sseToolUse{}},
+	{Name: "tool_result", Description: "A tool's output.", Data: sseToolResult{}},
+	{Name: "file", Description: "A file the agent produced.", Data: sseFile{}},
+	{Name: "done", Description: "The response finished.", Data: sseDone{}},
+	{Name: "error", Description: "The response failed.", Data: coverr.Body{}},
+	{Name: "session_orphaned", Description: "The stream can no longer be followed.", Data: sseReason{}},
+	{Name: "usage", Description: "Token usage of the response.", Data: sseUsage{}},
+	{Name: "tool_state", Description: "A tool call changed state.", Data: sseToolState{}},
+	{Name: "canceled", Description: "The request was canceled.", Data: sseReason{}},
+	{Name: "tool_approval", Description: "A tool call waits for POST /api/v1/tools/approve.", Data: sseToolApproval{}},
+	{Name: "question", Description: "The agent asks the user a question; answer it with POST /api/v1/questions/answer.", Data: builtins.PendingQuestion{}},
+	{Name: "truncated", Description: "The response was cut off at its time limit. Durations are in seconds.", Data: sseTruncated{}},
+	{Name: "plan", Description: "The agent's plan; text numbers the steps for clients that do not draw plans.", Data: ssePlan{}},
+	{Name: "plan_step_update", Description: "One plan step changed; index is zero-based.", Data: ssePlanStepUpdate{}},
+	{Name: "citation", Description: "A source the response cites.", Data: sseCitation{}},
+	{Name: "unknown", Description: "An agent event this gateway version does not know.", Data: sseText{}},
+	{Name: "broadcast_done", Description: "Last event of a broadcast, once every agent has finished.", Data: sseBroadcastDone{}},
+}
+
+// sendEvents are the events of a send's stream.
+var sendEvents = []string{
+	"started", "session_init", "thinking", "text", "tool_use", "tool_result", "file", "done", "error",
+	"session_orphaned", "usage", "tool_state", "canceled", "tool_approval", "question", "truncated", "plan",
+	"plan_step_update", "citation", "unknown",
+}
+
+// broadcastEvents are the events of a broadcast's stream.
+var broadcastEvents = append(sendEvents[:len(sendEvents):len(sendEvents)], "broadcast_done")
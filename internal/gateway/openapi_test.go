@@ -0,0 +1,217 @@
+// ABOUTME: Tests that the OpenAPI document covers every registered route and every SSE event
+// ABOUTME: the handlers emit, so the document cannot drift from the mux or the converters.
+
+package gateway
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/httpapi"
+)
+
+// fetchOpenAPI requests the document from h and decodes it.
+func fetchOpenAPI(t *testing.T, h http.Handler) *httpapi.Document {
+	t.Helper()
+	rec := getV1(t, h, openAPIPath)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d, body = %s", openAPIPath, rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var doc httpapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decoding document: %v", err)
+	}
+	return &doc
+}
+
+var pathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+func TestOpenAPI_CoversEveryRoute(t *testing.T) {
+	gw, mux := newV1TestServer(t)
+	doc := fetchOpenAPI(t, mux)
+	if doc.OpenAPI != httpapi.OpenAPIVersion {
+		t.Errorf("openapi = %q, want %q", doc.OpenAPI, httpapi.OpenAPIVersion)
+	}
+
+	deprecated := map[string]bool{}
+	for _, pattern := range gw.apiRoutes.Deprecated() {
+		deprecated[pattern] = true
+	}
+	for _, pattern := range gw.apiRoutes.Patterns() {
+		if deprecated[pattern] {
+			// A legacy route's successor is the same path under /api/v1;
+			// subtree patterns need at least one operation below it.
+			successor := httpapi.V1Prefix + strings.TrimPrefix(pattern, "/api")
+			found := false
+			for p := range doc.Paths {
+				if p == successor || (strings.HasSuffix(successor, "/") && strings.HasPrefix(p, successor)) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("legacy route %s has no %s successor in the document", pattern, successor)
+			}
+			continue
+		}
+		method, path, _ := strings.Cut(pattern, " ")
+		item := doc.Paths[path]
+		if item == nil || (*item)[strings.ToLower(method)] == nil {
+			t.Errorf("route %q is missing from the document", pattern)
+		}
+	}
+
+	// Every documented operation reaches the route it was registered as.
+	for path, item := range doc.Paths {
+		for method, op := range *item {
+			concrete := pathParam.ReplaceAllString(path, "x")
+			req := httptest.NewRequest(strings.ToUpper(method), concrete, nil)
+			if _, pattern := mux.(*http.ServeMux).Handler(req); pattern != strings.ToUpper(method)+" "+path {
+				t.Errorf("%s %s is served by %q", strings.ToUpper(method), path, pattern)
+			}
+			if op.OperationID == "" || op.Responses["default"] == nil {
+				t.Errorf("%s %s: operationId %q, default response %v", method, path, op.OperationID, op.Responses["default"])
+			}
+			if op.Security == nil && path != openAPIPath {
+				t.Errorf("%s %s has no security requirement", method, path)
+			}
+		}
+	}
+}
+
+func TestOpenAPI_LegacyRoutesAreDeprecated(t *testing.T) {
+	_, mux := newV1TestServer(t)
+	for _, path := range []string{"/api/stats/usage", "/api/usage/summary", "/api/deliveries/stats"} {
+		rec := getV1(t, mux, path)
+		if rec.Header().Get("Deprecation") == "" {
+			t.Errorf("GET %s: no Deprecation header", path)
+		}
+		successor := httpapi.V1Prefix + strings.TrimPrefix(path, "/api")
+		if link := rec.Header().Get("Link"); !strings.Contains(link, successor) {
+			t.Errorf("GET %s: Link = %q, want %s", path, link, successor)
+		}
+		if rec := getV1(t, mux, successor); rec.Header().Get("Deprecation") != "" {
+			t.Errorf("GET %s is marked deprecated", successor)
+		}
+	}
+}
+
+// eventKeys returns the keys of an SSE event's data object.
+func eventKeys(t *testing.T, data any) []string {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		t.Fatalf("event data %s is not an object: %v", raw, err)
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// checkEventDocumented fails unless event has a component whose properties
+// include every key of data.
+func checkEventDocumented(t *testing.T, doc *httpapi.Document, event string, keys []string) {
+	t.Helper()
+	schema := doc.Components.Schemas[httpapi.EventComponent(event)]
+	if schema == nil {
+		t.Errorf("SSE event %q has no component schema", event)
+		return
+	}
+	for _, k := range keys {
+		if schema.Properties[k] == nil {
+			t.Errorf("SSE event %q: data key %q is not documented", event, k)
+		}
+	}
+}
+
+func TestOpenAPI_SSEEventsMatchConverters(t *testing.T) {
+	_, mux := newV1TestServer(t)
+	doc := fetchOpenAPI(t, mux)
+
+	full := &agent.Response{
+		Text:                "text",
+		SessionID:           "session-1",
+		Error:               "failed",
+		ToolUse:             &agent.ToolUseEvent{ID: "t1", Name: "bash", InputJSON: "{}"},
+		ToolResult:          &agent.ToolResultEvent{ID: "t1", Output: "ok", Partial: true, Sequence: 2, Cached: true},
+		File:                &agent.FileEvent{Filename: "a.txt", MimeType: "text/plain", AttachmentID: "att-1"},
+		Usage:               &agent.UsageEvent{InputTokens: 1},
+		ToolState:           &agent.ToolStateEvent{ID: "t1", State: "running"},
+		ToolApprovalRequest: &agent.ToolApprovalRequestEvent{ID: "t1", Name: "bash", RequestID: "req-1"},
+		Truncated:           &agent.TruncatedEvent{Reason: "limit", Limit: time.Minute},
+		Plan:                &agent.PlanEvent{Steps: []agent.PlanStep{{Title: "Look", Status: "pending"}}},
+		PlanStep:            &agent.PlanStepEvent{Status: "completed"},
+		Citation:            &agent.CitationEvent{Index: 1, Title: "Go", URI: "https://go.dev", Snippet: "Go"},
+	}
+	for kind, convert := range sseConverters {
+		resp := *full
+		resp.Event = kind
+		ev := convert(&resp)
+		checkEventDocumented(t, doc, ev.Event, eventKeys(t, ev.Data))
+	}
+
+	conn := agent.NewConnection(agent.ConnectionParams{ID: "agent-1", Name: "Agent", PrincipalID: "p-1", InstanceID: "i-1", Stream: &testMockStream{}, Logger: slog.Default()})
+	started := startedSSE(&conversation.SendResponse{ThreadID: "thread-1", MessageID: "req-1"}, conn, "explicit")
+	started["routing"] = routingSSE("code", &agent.Route{})
+	started["workspace"], started["queued"], started["queue_position"], started["ack_mode"] = "main", true, 1, ackModeExplicit
+	started["attachments"] = attachmentsSSE([]agent.Attachment{{ID: "att-1"}})
+	checkEventDocumented(t, doc, "started", eventKeys(t, started))
+	fallback := fallbackSSE(conn, "agent-0")
+	checkEventDocumented(t, doc, fallback.Event, eventKeys(t, fallback.Data))
+	checkEventDocumented(t, doc, "done", eventKeys(t, doneSSEData("text", "agent-1", "trace-1", []agent.CitationEvent{{Index: 1}})))
+
+	for _, name := range broadcastEvents {
+		checkEventDocumented(t, doc, name, nil)
+	}
+}
+
+func TestOpenAPI_GoldenSSEEventsDocumented(t *testing.T) {
+	_, mux := newV1TestServer(t)
+	doc := fetchOpenAPI(t, mux)
+
+	files, err := filepath.Glob(filepath.Join("testdata", "sse", "*.golden"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no golden files: %v", err)
+	}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		var event string
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event = name
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				checkEventDocumented(t, doc, event, eventKeys(t, json.RawMessage(data)))
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			t.Fatalf("reading %s: %v", file, err)
+		}
+		_ = f.Close()
+	}
+}
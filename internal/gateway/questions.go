@@ -0,0 +1,221 @@
+// ABOUTME: Context snapshots for ask_user questions and the pending questions listings.
+// ABOUTME: Questions also go out on the asking agent's in-flight /api/send stream.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/2389/coven-gateway/internal/builtins"
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// questionContextHistory is how many recent thread events are scanned for
+// message exchanges when building a snapshot.
+const questionContextHistory = 50
+
+// QuestionContext implements builtins.QuestionContextProvider. It returns nil
+// when the agent has no request in flight.
+func (g *Gateway) QuestionContext(ctx context.Context, agentID string) *builtins.QuestionContext {
+	act, ok := g.agentManager.Activity(agentID)
+	if !ok {
+		return nil
+	}
+
+	snap := &builtins.QuestionContext{ThreadID: act.ThreadID}
+	for _, call := range act.ToolCalls {
+		snap.ToolCalls = append(snap.ToolCalls, builtins.SummarizeToolCall(call.Name, call.InputJSON))
+	}
+
+	if act.ThreadID != "" {
+		events, err := g.store.GetEventsByThreadID(ctx, act.ThreadID, questionContextHistory)
+		if err != nil {
+			g.logger.Warn("loading thread history for question context", "agent_id", agentID, "thread_id", act.ThreadID, "error", err)
+		}
+		for _, evt := range events {
+			if evt.Type != store.EventTypeMessage || evt.Text == nil {
+				continue
+			}
+			direction := "inbound"
+			if evt.Direction == store.EventDirectionOutbound {
+				direction = "outbound"
+			}
+			snap.Exchanges = append(snap.Exchanges, builtins.ExchangeSummary{
+				Author:    evt.Author,
+				Direction: direction,
+				Text:      *evt.Text,
+				Timestamp: timeparse.Format(evt.Timestamp),
+			})
+		}
+	}
+
+	// Secrets pushed to the agent are never shown in the admin UI, so they must
+	// not leak through a snapshot of its tool inputs either.
+	if sqlStore, ok := g.store.(*store.SQLStore); ok {
+		secrets, err := sqlStore.GetEffectiveSecrets(ctx, agentID)
+		if err != nil {
+			g.logger.Warn("loading secrets for question context redaction", "agent_id", agentID, "error", err)
+		}
+		for _, v := range secrets {
+			snap.Redact = append(snap.Redact, v)
+		}
+	}
+	return snap
+}
+
+// SendUserQuestion implements builtins.ClientStreamer. The question goes out
+// as a question event on the SSE stream of the request the agent is working
+// on, when that request came in through /api/send, and to the agent's web
+// chat. It fails only when neither reached anyone.
+func (g *Gateway) SendUserQuestion(agentID string, req *pb.UserQuestionRequest) error {
+	streamed := g.streamQuestion(agentID, req)
+	var err error
+	if g.webAdmin != nil {
+		err = g.webAdmin.SendUserQuestion(agentID, req)
+	} else {
+		err = fmt.Errorf("no connected clients for agent %s", agentID)
+	}
+	if streamed {
+		return nil
+	}
+	return err
+}
+
+// streamQuestion records req on the stream of the agent's in-flight request
+// and reports whether that request was still streaming.
+func (g *Gateway) streamQuestion(agentID string, req *pb.UserQuestionRequest) bool {
+	act, ok := g.agentManager.Activity(agentID)
+	if !ok || g.questionRouter == nil {
+		return false
+	}
+	pq, ok := g.questionRouter.Lookup(req.GetQuestionId())
+	if !ok {
+		return false
+	}
+	data, err := json.Marshal(pq)
+	if err != nil {
+		g.logger.Error("failed to marshal question event", "question_id", req.GetQuestionId(), "error", err)
+		return false
+	}
+	if !g.eventBroadcaster.RecordRunningRequestEvent(act.RequestID, "question", data) {
+		return false
+	}
+	g.logger.Debug("user question sent on request stream", "agent_id", agentID, "question_id", req.GetQuestionId(), "request_id", act.RequestID)
+	return true
+}
+
+// QuestionsResponse is the JSON response for the question listings.
+type QuestionsResponse struct {
+	Questions []builtins.PendingQuestion `json:"questions"`
+}
+
+// handleListQuestions handles GET /api/questions, listing unanswered ask_user
+// questions with their context snapshots. Optional ?agent_id= filters by
+// agent; questions of agents the caller's token may not reach are left out.
+func (g *Gateway) handleListQuestions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if g.questionRouter == nil {
+		g.sendJSONError(w, http.StatusServiceUnavailable, "question router not configured")
+		return
+	}
+
+	agentID := r.URL.Query().Get("agent_id")
+	if agentID != "" && !g.tokenAllowsAgentID(r.Context(), agentID) {
+		g.sendJSONError(w, http.StatusForbidden, errTokenAgentDenied)
+		return
+	}
+	items := g.pendingQuestions(r.Context(), agentID)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(QuestionsResponse{Questions: items}); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// handlePendingQuestions handles GET /api/questions/pending, listing the
+// unanswered questions recorded in the store, so a client that connects
+// after a question was asked can still answer it. Optional ?agent_id=
+// filters by agent; questions of agents the caller's token may not reach are
+// left out.
+func (g *Gateway) handlePendingQuestions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if g.questionRouter == nil {
+		g.sendJSONError(w, http.StatusServiceUnavailable, "question router not configured")
+		return
+	}
+	agentID := r.URL.Query().Get("agent_id")
+	if agentID != "" && !g.tokenAllowsAgentID(r.Context(), agentID) {
+		g.sendJSONError(w, http.StatusForbidden, errTokenAgentDenied)
+		return
+	}
+
+	items, err := g.questionRouter.ListPending(r.Context(), agentID)
+	if err != nil {
+		g.logger.Error("failed to list pending questions", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	items = g.visibleQuestions(r.Context(), items)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(QuestionsResponse{Questions: items}); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// sendAnswerError writes the response for an answer that could not be
+// delivered: 404 for an unknown question, 409 for one already answered or
+// expired, and 422 with the violations for one that breaks the question's
+// constraints.
+func (g *Gateway) sendAnswerError(w http.ResponseWriter, err error) {
+	var invalid *builtins.InvalidAnswerError
+	switch {
+	case errors.As(err, &invalid):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		if err := json.NewEncoder(w).Encode(invalid); err != nil {
+			g.logger.Debug("failed to encode error response", "error", err)
+		}
+	case errors.Is(err, builtins.ErrQuestionAnswered), errors.Is(err, builtins.ErrQuestionExpired):
+		g.sendCodedError(w, http.StatusConflict, coverr.CodeOf(err), err.Error())
+	case errors.Is(err, builtins.ErrQuestionNotFound):
+		g.sendCodedError(w, http.StatusNotFound, coverr.CodeOf(err), err.Error())
+	default:
+		g.logger.Error("failed to deliver question answer", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+// pendingQuestions returns the unanswered questions the request's token may
+// see, oldest first, optionally filtered to one agent.
+func (g *Gateway) pendingQuestions(ctx context.Context, agentID string) []builtins.PendingQuestion {
+	items := []builtins.PendingQuestion{}
+	for _, pq := range g.questionRouter.Pending() {
+		if agentID == "" || pq.Request.GetAgentId() == agentID {
+			items = append(items, pq)
+		}
+	}
+	return g.visibleQuestions(ctx, items)
+}
+
+// visibleQuestions drops the questions of agents the request's token may not
+// reach.
+func (g *Gateway) visibleQuestions(ctx context.Context, items []builtins.PendingQuestion) []builtins.PendingQuestion {
+	return slices.DeleteFunc(items, func(pq builtins.PendingQuestion) bool {
+		return !g.tokenAllowsAgentID(ctx, pq.Request.GetAgentId())
+	})
+}
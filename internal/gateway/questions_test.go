@@ -0,0 +1,338 @@
+// ABOUTME: Tests for ask_user context snapshots, question streaming, the pending question listings, and answer errors.
+// ABOUTME: Drives a real agent connection so the snapshot reflects in-flight work.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// capturingStream records messages sent to the agent.
+type capturingStream struct {
+	testMockStream
+	mu   sync.Mutex
+	sent []*pb.ServerMessage
+}
+
+func (c *capturingStream) Send(msg *pb.ServerMessage) error {
+	c.mu.Lock()
+	c.sent = append(c.sent, msg)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *capturingStream) lastRequestID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sent[len(c.sent)-1].GetSendMessage().GetRequestId()
+}
+
+func TestGatewayQuestionContext(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx := context.Background()
+
+	if snap := gw.QuestionContext(ctx, "idle-agent"); snap != nil {
+		t.Errorf("idle agent snapshot = %+v, want nil", snap)
+	}
+
+	sqlStore := gw.store.(*store.SQLStore)
+	if err := sqlStore.CreateThread(ctx, &store.Thread{ID: "thread-1", FrontendName: "slack", ExternalID: "C1", AgentID: "agent-1", CreatedAt: time.Now(), UpdatedAt: time.Now()}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	threadID := "thread-1"
+	for i, text := range []string{"please deploy", "deploying now"} {
+		direction := store.EventDirectionInbound
+		if i == 1 {
+			direction = store.EventDirectionOutbound
+		}
+		if err := sqlStore.SaveEvent(ctx, &store.LedgerEvent{
+			ID: "e-" + text, ConversationKey: "slack:C1", ThreadID: &threadID, Direction: direction,
+			Author: "alice", Timestamp: time.Now().Add(time.Duration(i) * time.Second), Type: store.EventTypeMessage, Text: &text,
+		}); err != nil {
+			t.Fatalf("SaveEvent: %v", err)
+		}
+	}
+	if err := sqlStore.CreateSecret(ctx, &store.Secret{Key: "DEPLOY_TOKEN", Value: "tok-secret-123"}); err != nil {
+		t.Fatalf("CreateSecret: %v", err)
+	}
+
+	stream := &capturingStream{}
+	conn := agent.NewConnection(agent.ConnectionParams{ID: "agent-1", Name: "Agent", Stream: stream, Logger: slog.Default()})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	respChan, err := gw.agentManager.SendMessage(ctx, &agent.SendRequest{ThreadID: threadID, Sender: "alice", Content: "please deploy", AgentID: "agent-1"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	conn.HandleResponse(&pb.MessageResponse{
+		RequestId: stream.lastRequestID(),
+		Event: &pb.MessageResponse_ToolUse{ToolUse: &pb.ToolUse{
+			Id: "t1", Name: "bash", InputJson: `{"command":"deploy --token tok-secret-123"}`,
+		}},
+	})
+	<-respChan
+
+	snap := gw.QuestionContext(ctx, "agent-1")
+	if snap == nil {
+		t.Fatal("no snapshot for busy agent")
+	}
+	body, err := snap.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize: %v", err)
+	}
+	if snap.ThreadID != threadID || len(snap.Exchanges) != 2 || len(snap.ToolCalls) != 1 {
+		t.Errorf("snapshot = %+v, want thread-1 with 2 exchanges and 1 tool call", snap)
+	}
+	if snap.Exchanges[1].Direction != "outbound" {
+		t.Errorf("last exchange direction = %q, want outbound", snap.Exchanges[1].Direction)
+	}
+	if strings.Contains(body, "tok-secret-123") {
+		t.Errorf("snapshot leaks agent secret: %s", body)
+	}
+}
+
+func TestSendUserQuestion_StreamsToRequest(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := &capturingStream{}
+	conn := agent.NewConnection(agent.ConnectionParams{ID: "agent-1", Name: "Agent", Stream: stream, Logger: slog.Default()})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, err := gw.agentManager.SendMessage(ctx, &agent.SendRequest{Sender: "alice", Content: "deploy", AgentID: "agent-1"}); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	requestID := stream.lastRequestID()
+	gw.recordSSEEvent(requestID, "started", map[string]string{"request_id": requestID})
+
+	if _, err := gw.questionRouter.SendQuestion(ctx, "agent-1", &pb.UserQuestionRequest{
+		AgentId: "agent-1", QuestionId: "q-1", Question: "Deploy now?",
+		Options: []*pb.QuestionOption{{Label: "Yes"}, {Label: "No"}}, TimeoutSeconds: 60,
+	}); err != nil {
+		t.Fatalf("SendQuestion: %v", err)
+	}
+
+	events, _, err := gw.eventBroadcaster.WaitRequestEvents(ctx, requestID, 1)
+	if err != nil {
+		t.Fatalf("WaitRequestEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].Event != "question" {
+		t.Fatalf("events = %+v, want one question event", events)
+	}
+	var data struct {
+		AgentID    string `json:"agent_id"`
+		QuestionID string `json:"question_id"`
+		Options    []struct {
+			Label string `json:"label"`
+		} `json:"options"`
+		ExpiresAt string `json:"expires_at"`
+	}
+	if err := json.Unmarshal(events[0].Data, &data); err != nil {
+		t.Fatalf("decode question event: %v", err)
+	}
+	if data.AgentID != "agent-1" || data.QuestionID != "q-1" || len(data.Options) != 2 || data.ExpiresAt == "" {
+		t.Errorf("question event = %+v, want q-1 from agent-1 with its options", data)
+	}
+
+	// With no request streaming and no web chat open, the stored question
+	// still waits to be answered.
+	gw.eventBroadcaster.FinishRequest(requestID)
+	if err := gw.SendUserQuestion("agent-1", &pb.UserQuestionRequest{AgentId: "agent-1", QuestionId: "q-2"}); err == nil {
+		t.Error("SendUserQuestion with nobody listening succeeded, want error")
+	}
+}
+
+func TestHandleQuestions_TokenScope(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, agentID := range []string{"agent-1", "agent-2"} {
+		if _, err := gw.questionRouter.SendQuestion(ctx, agentID, &pb.UserQuestionRequest{
+			AgentId: agentID, QuestionId: "q-" + agentID, Question: "Deploy now?", TimeoutSeconds: 60,
+		}); err != nil {
+			t.Fatalf("SendQuestion: %v", err)
+		}
+	}
+
+	scoped := func(target string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		return req.WithContext(auth.WithAuth(req.Context(), &auth.AuthContext{PrincipalID: "tui", AgentIDs: []string{"agent-1"}}))
+	}
+	for name, handler := range map[string]http.HandlerFunc{
+		"/api/questions":         gw.handleListQuestions,
+		"/api/questions/pending": gw.handlePendingQuestions,
+	} {
+		rec := httptest.NewRecorder()
+		handler(rec, scoped(name))
+		var resp struct {
+			Questions []struct {
+				QuestionID string `json:"question_id"`
+			} `json:"questions"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: decode: %v", name, err)
+		}
+		if len(resp.Questions) != 1 || resp.Questions[0].QuestionID != "q-agent-1" {
+			t.Errorf("%s = %+v, want only the question of agent-1", name, resp.Questions)
+		}
+
+		rec = httptest.NewRecorder()
+		handler(rec, scoped(name+"?agent_id=agent-2"))
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s?agent_id=agent-2 status = %d, want 403", name, rec.Code)
+		}
+	}
+}
+
+func TestHandleListQuestions(t *testing.T) {
+	gw := newTestGateway(t)
+
+	rec := httptest.NewRecorder()
+	gw.handleListQuestions(rec, httptest.NewRequest(http.MethodPost, "/api/questions", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	gw.handleListQuestions(rec, httptest.NewRequest(http.MethodGet, "/api/questions", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Questions []json.RawMessage `json:"questions"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Questions == nil || len(resp.Questions) != 0 {
+		t.Errorf("questions = %v, want empty list", resp.Questions)
+	}
+}
+
+func TestHandlePendingQuestions(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Nobody has the chat open, so the question waits in the store.
+	answers, err := gw.questionRouter.SendQuestion(ctx, "agent-1", &pb.UserQuestionRequest{
+		AgentId: "agent-1", QuestionId: "q-1", Question: "Deploy now?",
+		Options: []*pb.QuestionOption{{Label: "Yes"}, {Label: "No"}}, TimeoutSeconds: 60,
+	})
+	if err != nil {
+		t.Fatalf("SendQuestion: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.handlePendingQuestions(rec, httptest.NewRequest(http.MethodGet, "/api/questions/pending?agent_id=agent-1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Questions []struct {
+			QuestionID string `json:"question_id"`
+			ExpiresAt  string `json:"expires_at"`
+		} `json:"questions"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Questions) != 1 || resp.Questions[0].QuestionID != "q-1" || resp.Questions[0].ExpiresAt == "" {
+		t.Fatalf("questions = %+v, want q-1 with expires_at", resp.Questions)
+	}
+
+	answer := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		gw.handleAnswerQuestion(rec, httptest.NewRequest(http.MethodPost, "/api/questions/answer",
+			strings.NewReader(`{"agent_id":"agent-1","question_id":"q-1","selected":["Yes"]}`)))
+		return rec
+	}
+	if rec := answer(); rec.Code != http.StatusOK {
+		t.Fatalf("answer status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if a := <-answers; a.GetSelected()[0] != "Yes" {
+		t.Errorf("answer = %v, want Yes", a)
+	}
+
+	rec = answer()
+	var errResp struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&errResp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.Code != http.StatusConflict || errResp.Code != "conflict" || !strings.Contains(errResp.Error, "already answered") {
+		t.Errorf("second answer = %d %+v, want 409 conflict already answered", rec.Code, errResp)
+	}
+
+	rec = httptest.NewRecorder()
+	gw.handlePendingQuestions(rec, httptest.NewRequest(http.MethodGet, "/api/questions/pending", nil))
+	resp.Questions = nil
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || len(resp.Questions) != 0 {
+		t.Errorf("pending after answer = %+v (err %v), want none", resp.Questions, err)
+	}
+}
+
+func TestHandleAnswerQuestion_InvalidAnswer(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	closed := false
+	if _, err := gw.questionRouter.SendQuestion(ctx, "agent-1", &pb.UserQuestionRequest{
+		AgentId: "agent-1", QuestionId: "q-1", Question: "Deploy now?",
+		Options: []*pb.QuestionOption{{Label: "Yes"}, {Label: "No"}}, AllowFreeText: &closed, TimeoutSeconds: 60,
+	}); err != nil {
+		t.Fatalf("SendQuestion: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.handleAnswerQuestion(rec, httptest.NewRequest(http.MethodPost, "/api/questions/answer",
+		strings.NewReader(`{"agent_id":"agent-1","question_id":"q-1","selected":["Yes","Maybe"],"custom_text":"soon"}`)))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Code       string `json:"code"`
+		Violations []struct {
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"violations"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	fields := map[string]bool{}
+	for _, v := range resp.Violations {
+		fields[v.Field] = true
+	}
+	if resp.Code != "invalid_request" || len(resp.Violations) != 3 || !fields["selected"] || !fields["custom_text"] {
+		t.Errorf("response = %+v, want invalid_request with selected and custom_text violations", resp)
+	}
+
+	// The question is still open for a valid answer.
+	rec = httptest.NewRecorder()
+	gw.handleAnswerQuestion(rec, httptest.NewRequest(http.MethodPost, "/api/questions/answer",
+		strings.NewReader(`{"agent_id":"agent-1","question_id":"q-1","selected":["No"]}`)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("valid answer status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
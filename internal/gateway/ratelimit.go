@@ -0,0 +1,126 @@
+// ABOUTME: Per-principal token-bucket rate limiting for the HTTP API, configured by route group
+// ABOUTME: under api.rate_limit. Unauthenticated requests are limited by remote IP instead.
+
+package gateway
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/ratelimit"
+)
+
+// Route groups for api.rate_limit.
+const (
+	rateGroupDefault = "default"
+	rateGroupSend    = "send"
+)
+
+// newRateLimiters builds a limiter for each group in api.rate_limit. The
+// config has already been validated, so rates parse.
+func newRateLimiters(cfg config.APIConfig, logger *slog.Logger) map[string]*ratelimit.Limiter {
+	limiters := make(map[string]*ratelimit.Limiter, len(cfg.RateLimit))
+	for group, raw := range cfg.RateLimit {
+		rate, err := ratelimit.ParseRate(raw)
+		if err != nil {
+			logger.Error("ignoring invalid rate limit", "group", group, "error", err)
+			continue
+		}
+		limiters[group] = ratelimit.New(rate, cfg.RateLimitMaxKeys)
+		logger.Info("HTTP API rate limit enabled", "group", group, "rate", rate.String())
+	}
+	return limiters
+}
+
+// rateLimitKey identifies who a request counts against: its authenticated
+// principal, or else the remote IP.
+func rateLimitKey(r *http.Request) string {
+	if a := auth.FromContext(r.Context()); a != nil && a.PrincipalID != "" {
+		return "principal:" + a.PrincipalID
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// rateLimiter returns group's limiter, or nil when the group has no limit.
+// A config reload may replace the limiters.
+func (g *Gateway) rateLimiter(group string) *ratelimit.Limiter {
+	g.configMu.RLock()
+	defer g.configMu.RUnlock()
+	return g.rateLimits[group]
+}
+
+// allowRequest takes a token for key from group's limiter. Groups without a
+// configured limit always allow.
+func (g *Gateway) allowRequest(group, key string) (bool, time.Duration) {
+	l := g.rateLimiter(group)
+	if l == nil {
+		return true, 0
+	}
+	return l.Allow(key)
+}
+
+// rateLimit returns middleware applying group's limit. It must run inside
+// the auth middleware so the principal is known. The limit is looked up per
+// request, so one added or removed by a config reload applies at once.
+func (g *Gateway) rateLimit(group string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := g.rateLimiter(group)
+			if l == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := rateLimitKey(r)
+			if ok, retry := l.Allow(key); !ok {
+				g.logger.Debug("rate limited", "group", group, "key", key, "path", r.URL.Path)
+				g.sendRateLimitedError(w, group, l.Rate(), retry)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimitedResponse is the 429 body returned when a principal or IP has
+// used up its rate limit.
+type RateLimitedResponse struct {
+	Error             string      `json:"error"`
+	Code              coverr.Code `json:"code"`
+	Group             string      `json:"group"`
+	Limit             string      `json:"limit"`
+	RetryAfterSeconds int         `json:"retry_after_seconds"`
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds, at least one.
+func retryAfterSeconds(retry time.Duration) int {
+	return max(1, int(math.Ceil(retry.Seconds())))
+}
+
+// sendRateLimitedError writes a 429 whose Retry-After says when a token frees up.
+func (g *Gateway) sendRateLimitedError(w http.ResponseWriter, group string, rate ratelimit.Rate, retry time.Duration) {
+	seconds := retryAfterSeconds(retry)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	if err := json.NewEncoder(w).Encode(RateLimitedResponse{
+		Error:             "rate limit exceeded",
+		Code:              coverr.RateLimited,
+		Group:             group,
+		Limit:             rate.String(),
+		RetryAfterSeconds: seconds,
+	}); err != nil {
+		g.logger.Debug("failed to encode error response", "error", err)
+	}
+}
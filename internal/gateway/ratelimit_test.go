@@ -0,0 +1,106 @@
+// ABOUTME: Tests for the HTTP API rate limit middleware: 429 responses with Retry-After,
+// ABOUTME: per-principal buckets, the remote IP fallback, and unconfigured groups.
+
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/config"
+)
+
+func newRateLimitedGateway(t *testing.T, limits map[string]string) *Gateway {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return &Gateway{logger: logger, rateLimits: newRateLimiters(config.APIConfig{RateLimit: limits}, logger)}
+}
+
+// limitedRequest sends a request through group's middleware as principal
+// (or anonymously from remoteAddr when principal is empty).
+func limitedRequest(gw *Gateway, group, principal, remoteAddr string) *httptest.ResponseRecorder {
+	h := gw.rateLimit(group)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/api/send", nil)
+	req.RemoteAddr = remoteAddr
+	if principal != "" {
+		req = req.WithContext(auth.WithAuth(req.Context(), &auth.AuthContext{PrincipalID: principal}))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimit_BurstThen429(t *testing.T) {
+	gw := newRateLimitedGateway(t, map[string]string{"send": "2/min"})
+
+	for i := range 2 {
+		if rec := limitedRequest(gw, rateGroupSend, "alice", "10.0.0.1:1234"); rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d status = %d, want it allowed", i+1, rec.Code)
+		}
+	}
+	rec := limitedRequest(gw, rateGroupSend, "alice", "10.0.0.1:1234")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+	var body RateLimitedResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if body.Code != "rate_limited" || body.Group != "send" || body.Limit != "2/min" || body.RetryAfterSeconds != 30 {
+		t.Errorf("body = %+v", body)
+	}
+}
+
+func TestRateLimit_PrincipalsDoNotInterfere(t *testing.T) {
+	gw := newRateLimitedGateway(t, map[string]string{"default": "1/min"})
+
+	// Same IP, different principals: each has its own bucket.
+	if rec := limitedRequest(gw, rateGroupDefault, "alice", "10.0.0.1:1"); rec.Code != http.StatusNoContent {
+		t.Fatalf("alice status = %d", rec.Code)
+	}
+	if rec := limitedRequest(gw, rateGroupDefault, "alice", "10.0.0.1:1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("alice's second status = %d, want 429", rec.Code)
+	}
+	if rec := limitedRequest(gw, rateGroupDefault, "bob", "10.0.0.1:1"); rec.Code != http.StatusNoContent {
+		t.Errorf("bob status = %d, want allowed despite alice's limit", rec.Code)
+	}
+	// Anonymous requests from that IP are counted separately too.
+	if rec := limitedRequest(gw, rateGroupDefault, "", "10.0.0.1:2"); rec.Code != http.StatusNoContent {
+		t.Errorf("anonymous status = %d, want allowed", rec.Code)
+	}
+}
+
+func TestRateLimit_FallsBackToRemoteIP(t *testing.T) {
+	gw := newRateLimitedGateway(t, map[string]string{"default": "1/min"})
+
+	if rec := limitedRequest(gw, rateGroupDefault, "", "10.0.0.1:1111"); rec.Code != http.StatusNoContent {
+		t.Fatalf("first status = %d", rec.Code)
+	}
+	// A new connection from the same host shares the bucket.
+	if rec := limitedRequest(gw, rateGroupDefault, "", "10.0.0.1:2222"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("same IP status = %d, want 429", rec.Code)
+	}
+	if rec := limitedRequest(gw, rateGroupDefault, "", "10.0.0.2:1111"); rec.Code != http.StatusNoContent {
+		t.Errorf("other IP status = %d, want allowed", rec.Code)
+	}
+}
+
+func TestRateLimit_UnconfiguredGroupIsUnlimited(t *testing.T) {
+	gw := newRateLimitedGateway(t, map[string]string{"send": "1/min"})
+
+	for i := range 5 {
+		if rec := limitedRequest(gw, rateGroupDefault, "alice", "10.0.0.1:1"); rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d status = %d, want default group unlimited", i+1, rec.Code)
+		}
+	}
+}
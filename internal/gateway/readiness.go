@@ -0,0 +1,240 @@
+// ABOUTME: GET /health/ready: dependency checks with per-check timeouts, reported as JSON
+// ABOUTME: Readiness turns false when shutdown begins, before any listener closes
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/diskmon"
+)
+
+// defaultReadyCheckTimeout bounds each readiness check when
+// server.readiness.check_timeout is not set.
+const defaultReadyCheckTimeout = time.Second
+
+// Readiness statuses. Every status but ready answers 503.
+const (
+	readyStatusReady = "ready"
+	// readyStatusDegraded: the gateway is healthy but fewer agents than
+	// server.readiness.min_agents are connected.
+	readyStatusDegraded = "degraded"
+	// readyStatusNotReady: a dependency check failed.
+	readyStatusNotReady = "not_ready"
+	// readyStatusDraining: shutdown has begun.
+	readyStatusDraining = "draining"
+)
+
+// checkOK is the result of a passing check.
+const checkOK = "ok"
+
+// ReadyResponse is the body of GET /health/ready.
+type ReadyResponse struct {
+	Status string `json:"status"`
+	// Checks maps each check run to "ok" or why it failed; the agents
+	// check reads connected/required either way.
+	Checks map[string]string `json:"checks"`
+	Ready  bool              `json:"ready"`
+	Agents int               `json:"agents"`
+	// Draining is true once shutdown has started.
+	Draining bool `json:"draining,omitempty"`
+	// Storage is the latest storage check, with ?verbose=1.
+	Storage *diskmon.Status `json:"storage,omitempty"`
+	// Listeners lists each HTTP listener (api, and admin when split), with
+	// ?verbose=1 once the gateway is running.
+	Listeners []ListenerStatus `json:"listeners,omitempty"`
+}
+
+// readyCheck is one readiness check: the detail to report and whether it
+// passed.
+type readyCheck func(ctx context.Context) (string, bool)
+
+// readinessConfig returns server.readiness.
+func (g *Gateway) readinessConfig() config.ReadinessConfig {
+	if g.config == nil {
+		return config.ReadinessConfig{}
+	}
+	return g.config.Server.Readiness
+}
+
+// shutdownDelay is how long Shutdown keeps serving after readiness turns
+// false.
+func (g *Gateway) shutdownDelay() time.Duration {
+	return g.readinessConfig().ShutdownDelay
+}
+
+// waitShutdownDelay waits out the shutdown delay, or until ctx is done.
+func (g *Gateway) waitShutdownDelay(ctx context.Context) {
+	delay := g.shutdownDelay()
+	if delay <= 0 {
+		return
+	}
+	g.logger.Info("not ready; waiting before closing listeners", "delay", delay)
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// readyChecks returns the configured checks by name.
+func (g *Gateway) readyChecks(agents int) map[string]readyCheck {
+	cfg := g.readinessConfig()
+	all := map[string]readyCheck{
+		"store": g.checkStoreReady,
+		"grpc": func(context.Context) (string, bool) {
+			if !g.grpcServing.Load() {
+				return "not serving", false
+			}
+			return checkOK, true
+		},
+		"http": func(context.Context) (string, bool) {
+			listeners, serving := g.listeners.snapshot()
+			if serving {
+				return checkOK, true
+			}
+			var down []string
+			for _, l := range listeners {
+				if !l.Serving {
+					down = append(down, l.Name)
+				}
+			}
+			return strings.Join(down, ", ") + " not serving", false
+		},
+		"packs": func(context.Context) (string, bool) {
+			if g.packRegistry == nil || len(g.packRegistry.ListBuiltinPacks()) == 0 {
+				return "not initialized", false
+			}
+			return checkOK, true
+		},
+		"agents": func(context.Context) (string, bool) {
+			minAgents := max(cfg.MinAgents, 1)
+			return fmt.Sprintf("%d/%d", agents, minAgents), agents >= minAgents
+		},
+	}
+	names := cfg.Checks
+	if len(names) == 0 {
+		names = config.ReadinessChecks
+	}
+	checks := make(map[string]readyCheck, len(names))
+	for _, name := range names {
+		if check, ok := all[name]; ok {
+			checks[name] = check
+		}
+	}
+	return checks
+}
+
+// checkStoreReady runs a query against the store.
+func (g *Gateway) checkStoreReady(ctx context.Context) (string, bool) {
+	p, ok := g.store.(pinger)
+	if !ok {
+		return checkOK, true
+	}
+	if err := p.Ping(ctx); err != nil {
+		return err.Error(), false
+	}
+	return checkOK, true
+}
+
+// runReadyChecks runs checks concurrently, each bounded by timeout. A check
+// that overruns is reported as timed out and left to finish on its own.
+func runReadyChecks(ctx context.Context, checks map[string]readyCheck, timeout time.Duration) (map[string]string, map[string]bool) {
+	type result struct {
+		name, detail string
+		ok           bool
+	}
+	results := make(chan result, len(checks))
+	for name, check := range checks {
+		go func() {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			done := make(chan result, 1)
+			go func() {
+				detail, ok := check(ctx)
+				done <- result{name, detail, ok}
+			}()
+			select {
+			case r := <-done:
+				results <- r
+			case <-ctx.Done():
+				msg := fmt.Sprintf("timed out after %s", timeout)
+				if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					msg = ctx.Err().Error()
+				}
+				results <- result{name, msg, false}
+			}
+		}()
+	}
+	details := make(map[string]string, len(checks))
+	passed := make(map[string]bool, len(checks))
+	for range checks {
+		r := <-results
+		details[r.name], passed[r.name] = r.detail, r.ok
+	}
+	return details, passed
+}
+
+// readiness runs the configured checks and builds the response.
+func (g *Gateway) readiness(ctx context.Context) ReadyResponse {
+	agents := len(g.agentManager.ListAgents())
+	timeout := g.readinessConfig().CheckTimeout
+	if timeout <= 0 {
+		timeout = defaultReadyCheckTimeout
+	}
+	details, passed := runReadyChecks(ctx, g.readyChecks(agents), timeout)
+
+	resp := ReadyResponse{
+		Status:   readyStatusReady,
+		Checks:   details,
+		Agents:   agents,
+		Draining: g.stopping.Load() || g.agentManager.Draining(),
+	}
+	failed := make([]string, 0, len(passed))
+	for name, ok := range passed {
+		if !ok {
+			failed = append(failed, name)
+		}
+	}
+	switch {
+	case resp.Draining:
+		resp.Status = readyStatusDraining
+	case slices.ContainsFunc(failed, func(name string) bool { return name != "agents" }):
+		resp.Status = readyStatusNotReady
+	case len(failed) > 0:
+		resp.Status = readyStatusDegraded
+	}
+	resp.Ready = resp.Status == readyStatusReady
+	return resp
+}
+
+// handleReady answers 200 when every configured check passes and shutdown
+// has not begun, 503 otherwise, with the checks as JSON. With ?verbose=1 it
+// adds each listener and the latest storage check; storage never affects
+// the status code, since reads still work when the disk is full.
+func (g *Gateway) handleReady(w http.ResponseWriter, r *http.Request) {
+	resp := g.readiness(r.Context())
+	if v := r.URL.Query().Get("verbose"); v != "" && v != "0" && v != "false" {
+		resp.Listeners, _ = g.listeners.snapshot()
+		if g.storage != nil {
+			st := g.storage.Status()
+			resp.Storage = &st
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		g.logger.Debug("failed to encode ready response", "error", err)
+	}
+}
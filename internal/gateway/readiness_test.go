@@ -0,0 +1,166 @@
+// ABOUTME: Tests for GET /health/ready: check selection, statuses, per-check timeouts,
+// ABOUTME: and readiness turning false during shutdown while the listeners still serve
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// getReady calls handleReady and decodes the response.
+func getReady(t *testing.T, gw *Gateway) (int, ReadyResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	gw.handleReady(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	var resp ReadyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return rec.Code, resp
+}
+
+func registerReadyAgent(t *testing.T, gw *Gateway, id string) {
+	t.Helper()
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: id, Name: id, PrincipalID: id, Stream: &testMockStream{},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("registering agent: %v", err)
+	}
+}
+
+func TestHandleReady_Statuses(t *testing.T) {
+	gw := newTestGateway(t)
+
+	if code, resp := getReady(t, gw); code != http.StatusServiceUnavailable || resp.Status != readyStatusNotReady || resp.Checks["grpc"] != "not serving" {
+		t.Errorf("before serving: %d %+v, want not_ready on grpc", code, resp)
+	}
+
+	gw.grpcServing.Store(true)
+	code, resp := getReady(t, gw)
+	if code != http.StatusServiceUnavailable || resp.Status != readyStatusDegraded || resp.Checks["agents"] != "0/1" {
+		t.Errorf("no agents: %d %+v, want degraded with agents 0/1", code, resp)
+	}
+	for _, name := range []string{"store", "grpc", "http", "packs"} {
+		if resp.Checks[name] != checkOK {
+			t.Errorf("check %s = %q, want ok", name, resp.Checks[name])
+		}
+	}
+
+	registerReadyAgent(t, gw, "agent-1")
+	if code, resp := getReady(t, gw); code != http.StatusOK || resp.Status != readyStatusReady || !resp.Ready || resp.Checks["agents"] != "1/1" {
+		t.Errorf("one agent: %d %+v, want ready", code, resp)
+	}
+
+	gw.config.Server.Readiness.MinAgents = 2
+	if code, resp := getReady(t, gw); code != http.StatusServiceUnavailable || resp.Status != readyStatusDegraded || resp.Checks["agents"] != "1/2" {
+		t.Errorf("min_agents 2: %d %+v, want degraded with agents 1/2", code, resp)
+	}
+
+	gw.config.Server.Readiness.Checks = []string{"store", "grpc"}
+	code, resp = getReady(t, gw)
+	if code != http.StatusOK || len(resp.Checks) != 2 {
+		t.Errorf("store and grpc only: %d %+v, want ready with two checks", code, resp)
+	}
+
+	gw.stopping.Store(true)
+	if code, resp := getReady(t, gw); code != http.StatusServiceUnavailable || resp.Status != readyStatusDraining || !resp.Draining {
+		t.Errorf("stopping: %d %+v, want draining", code, resp)
+	}
+}
+
+// stuckStore is a store whose Ping hangs like a query on a dead mount.
+type stuckStore struct {
+	store.Store
+	release chan struct{}
+}
+
+func (s *stuckStore) Ping(context.Context) error {
+	<-s.release
+	return nil
+}
+
+func TestHandleReady_CheckTimeout(t *testing.T) {
+	gw := newTestGateway(t)
+	gw.grpcServing.Store(true)
+	registerReadyAgent(t, gw, "agent-1")
+	stuck := &stuckStore{Store: gw.store, release: make(chan struct{})}
+	t.Cleanup(func() { close(stuck.release) })
+	gw.store = stuck
+	gw.config.Server.Readiness.CheckTimeout = 50 * time.Millisecond
+
+	start := time.Now()
+	code, resp := getReady(t, gw)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("readiness took %s with a hung store", elapsed)
+	}
+	if code != http.StatusServiceUnavailable || resp.Status != readyStatusNotReady || resp.Checks["store"] != "timed out after 50ms" {
+		t.Errorf("hung store: %d %+v, want not_ready with the store timed out", code, resp)
+	}
+	if resp.Checks["agents"] != "1/1" {
+		t.Errorf("agents = %q, want the other checks still reported", resp.Checks["agents"])
+	}
+}
+
+func TestShutdown_ReadinessFalseBeforeListenersClose(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Server.Readiness.Checks = []string{"store", "grpc", "http", "packs"}
+	cfg.Server.Readiness.ShutdownDelay = 500 * time.Millisecond
+	gw, err := New(cfg, testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- gw.Run(ctx) }()
+
+	ready := "http://" + cfg.Server.HTTPAddr + "/health/ready"
+	status := func() int {
+		resp, err := http.Get(ready)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for status() != http.StatusOK {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatal("gateway never became ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	deadline = time.Now().Add(400 * time.Millisecond)
+	for status() != http.StatusServiceUnavailable {
+		if time.Now().After(deadline) {
+			t.Fatal("readiness did not turn false while the listener was still serving")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return")
+	}
+	if status() != 0 {
+		t.Error("HTTP listener still serving after shutdown")
+	}
+}
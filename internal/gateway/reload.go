@@ -0,0 +1,160 @@
+// ABOUTME: Config hot reload: on SIGHUP the gateway re-reads its config file and applies the
+// ABOUTME: settings that can change live. Other changes are logged as requiring a restart.
+
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/config"
+)
+
+// SetConfigReload makes Run reload the config file at path on SIGHUP.
+// applyLogging, if set, is given the logging section of each reloaded
+// config, since the logger is built by the caller.
+func (g *Gateway) SetConfigReload(path string, applyLogging func(config.LoggingConfig)) {
+	g.configPath = path
+	g.applyLogging = applyLogging
+}
+
+// watchReloadSignal reloads the config on every SIGHUP until ctx is done.
+// The signal is subscribed before it returns, so a SIGHUP sent once Run has
+// reported ready cannot kill the process.
+func (g *Gateway) watchReloadSignal(ctx context.Context) {
+	if g.configPath == "" {
+		return
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				_ = g.ReloadConfig()
+			}
+		}
+	}()
+}
+
+// ReloadConfig re-reads the config file and applies its dynamic settings
+// (see config.IsDynamic). Other settings that changed are logged as
+// requiring a restart. If the file fails to load or validate, the config in
+// effect is kept whole and the error is returned.
+func (g *Gateway) ReloadConfig() error {
+	if err := g.systemd.Reloading(); err != nil {
+		g.logger.Warn("systemd reloading notification failed", "error", err)
+	}
+	defer func() {
+		if g.systemd == nil {
+			return
+		}
+		if err := g.systemd.Ready(g.systemdStatus()); err != nil {
+			g.logger.Warn("systemd ready notification failed", "error", err)
+		}
+	}()
+
+	cfg, err := config.Load(g.configPath)
+	if err != nil {
+		g.logger.Error("config reload failed, keeping current config", "path", g.configPath, "error", err)
+		return err
+	}
+
+	g.configMu.RLock()
+	changes := config.Diff(g.config, cfg)
+	g.configMu.RUnlock()
+
+	var applied []string
+	for _, c := range changes {
+		if c.Dynamic {
+			applied = append(applied, c.Key)
+			continue
+		}
+		g.logger.Warn("config change requires restart", "key", c.Key)
+	}
+	if err := g.ApplyDynamic(cfg); err != nil {
+		g.logger.Error("config reload failed, keeping current config", "path", g.configPath, "error", err)
+		return err
+	}
+	g.logger.Info("config reloaded", "path", g.configPath, "applied", applied)
+	return nil
+}
+
+// ApplyDynamic applies the settings of cfg that can change while the gateway
+// runs: logging, heartbeat and health timings, the reconnect grace period,
+// rate limits, retention, capability enforcement and blocking of paused
+// agents' tool calls. Everything else in cfg is ignored. cfg is validated
+// first; if it is invalid nothing changes. Rate limit buckets survive when
+// their group's rate is unchanged.
+func (g *Gateway) ApplyDynamic(cfg *config.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("validating config: %w", err)
+	}
+	limiters := newRateLimiters(cfg.API, g.logger)
+
+	g.configMu.Lock()
+	defer g.configMu.Unlock()
+
+	cur := g.config
+	for group := range limiters {
+		if prev := g.rateLimits[group]; prev != nil && cur.API.RateLimitMaxKeys == cfg.API.RateLimitMaxKeys &&
+			cur.API.RateLimit[group] == cfg.API.RateLimit[group] {
+			limiters[group] = prev
+		}
+	}
+	g.rateLimits = limiters
+
+	cur.Logging = cfg.Logging
+	cur.API.RateLimit = cfg.API.RateLimit
+	cur.API.RateLimitMaxKeys = cfg.API.RateLimitMaxKeys
+	cur.Retention = cfg.Retention
+	cur.Packs.CapabilityEnforcement = cfg.Packs.CapabilityEnforcement
+
+	agents, next := &cur.Agents, cfg.Agents
+	agents.HeartbeatInterval, agents.HeartbeatIntervalRaw = next.HeartbeatInterval, next.HeartbeatIntervalRaw
+	agents.HeartbeatTimeout, agents.HeartbeatTimeoutRaw = next.HeartbeatTimeout, next.HeartbeatTimeoutRaw
+	agents.ReconnectGracePeriod, agents.ReconnectGracePeriodRaw = next.ReconnectGracePeriod, next.ReconnectGracePeriodRaw
+	agents.Health = next.Health
+	agents.BlockPausedToolCalls = next.BlockPausedToolCalls
+
+	g.agentManager.SetHealthThresholds(agentHealthThresholds(*agents))
+	g.sessions.setGrace(agents.ReconnectGracePeriod)
+
+	enforcement := capabilityEnforcement(cur.Packs.CapabilityEnforcement)
+	var callerCheck func(agentID string) error
+	if agents.BlockPausedToolCalls {
+		callerCheck = g.agentManager.CheckNotPaused
+	}
+	g.packRouter.SetCapabilityEnforcement(enforcement)
+	g.packRouter.SetCallerCheck(callerCheck)
+	g.webAdmin.SetCapabilityEnforcement(enforcement)
+
+	if g.applyLogging != nil {
+		g.applyLogging(cur.Logging)
+	}
+	return nil
+}
+
+// retentionConfig returns the retention settings in effect.
+func (g *Gateway) retentionConfig() config.RetentionConfig {
+	g.configMu.RLock()
+	defer g.configMu.RUnlock()
+	return g.config.Retention
+}
+
+// heartbeatInterval returns the agents.heartbeat_interval in effect.
+func (g *Gateway) heartbeatInterval() time.Duration {
+	g.configMu.RLock()
+	defer g.configMu.RUnlock()
+	if g.config == nil {
+		return 0
+	}
+	return g.config.Agents.HeartbeatInterval
+}
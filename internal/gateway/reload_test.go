@@ -0,0 +1,181 @@
+// ABOUTME: Tests for config hot reload: dynamic settings apply, restart-only ones do not,
+// ABOUTME: and a config that fails to load or validate leaves the old one fully in effect.
+
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/config"
+)
+
+// baseReloadConfig matches newTestGateway's config, so only what a test
+// appends differs on reload.
+const baseReloadConfig = `
+server:
+  grpc_addr: "localhost:0"
+  http_addr: "localhost:0"
+database:
+  path: ":memory:"
+`
+
+// newReloadGateway returns a test gateway that reloads from a temp file,
+// and the logging sections it was asked to apply.
+func newReloadGateway(t *testing.T) (*Gateway, string, *[]config.LoggingConfig) {
+	t.Helper()
+	gw := newTestGateway(t)
+	path := filepath.Join(t.TempDir(), "gateway.yaml")
+	var logging []config.LoggingConfig
+	gw.SetConfigReload(path, func(l config.LoggingConfig) { logging = append(logging, l) })
+	return gw, path, &logging
+}
+
+func writeReloadConfig(t *testing.T, path, extra string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(baseReloadConfig+extra), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+}
+
+const dynamicReloadConfig = `
+logging:
+  level: debug
+  format: json
+agents:
+  heartbeat_interval: 10s
+  reconnect_grace_period: 30s
+  block_paused_tool_calls: true
+api:
+  rate_limit:
+    send: 2/min
+retention:
+  logs: 720h
+  interval: 10m
+packs:
+  capability_enforcement: enforce
+`
+
+func TestReloadConfig_AppliesDynamicSettings(t *testing.T) {
+	gw, path, logging := newReloadGateway(t)
+	writeReloadConfig(t, path, dynamicReloadConfig+`
+webadmin:
+  base_url: "https://admin.example.com"
+`)
+
+	if err := gw.ReloadConfig(); err != nil {
+		t.Fatalf("ReloadConfig: %v", err)
+	}
+
+	if len(*logging) != 1 || (*logging)[0] != (config.LoggingConfig{Level: "debug", Format: "json"}) {
+		t.Errorf("logging applied = %+v, want one debug/json", *logging)
+	}
+	if got := gw.heartbeatInterval(); got != 10*time.Second {
+		t.Errorf("heartbeat interval = %v, want 10s", got)
+	}
+	if got := gw.sessions.gracePeriod(); got != 30*time.Second {
+		t.Errorf("grace period = %v, want 30s", got)
+	}
+	if l := gw.rateLimiter(rateGroupSend); l == nil || l.Rate().String() != "2/min" {
+		t.Errorf("send rate limiter = %v, want 2/min", l)
+	}
+	if r := gw.retentionConfig(); r.Logs != 720*time.Hour || r.Interval != 10*time.Minute {
+		t.Errorf("retention = %+v, want logs 720h every 10m", r)
+	}
+	if gw.config.Packs.CapabilityEnforcement != "enforce" || !gw.config.Agents.BlockPausedToolCalls {
+		t.Errorf("tool call settings = %q, %v; want enforce, true",
+			gw.config.Packs.CapabilityEnforcement, gw.config.Agents.BlockPausedToolCalls)
+	}
+	if gw.config.WebAdmin.BaseURL != "" {
+		t.Errorf("webadmin.base_url = %q, want it left for a restart", gw.config.WebAdmin.BaseURL)
+	}
+
+	// Buckets carry over when their rate is unchanged.
+	limiter := gw.rateLimiter(rateGroupSend)
+	if err := gw.ReloadConfig(); err != nil {
+		t.Fatalf("second ReloadConfig: %v", err)
+	}
+	if gw.rateLimiter(rateGroupSend) != limiter {
+		t.Error("unchanged send rate limit was replaced on reload")
+	}
+}
+
+func TestReloadConfig_RestartRequiredSettingsKept(t *testing.T) {
+	gw, path, _ := newReloadGateway(t)
+	if err := os.WriteFile(path, []byte(`
+server:
+  grpc_addr: "localhost:50051"
+  http_addr: "localhost:8080"
+database:
+  path: "/tmp/other.db"
+logging:
+  level: warn
+`), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+
+	if err := gw.ReloadConfig(); err != nil {
+		t.Fatalf("ReloadConfig: %v", err)
+	}
+	if gw.config.Server.GRPCAddr != "localhost:0" || gw.config.Server.HTTPAddr != "localhost:0" || gw.config.Database.Path != ":memory:" {
+		t.Errorf("restart-only settings changed: server %+v, database %q", gw.config.Server, gw.config.Database.Path)
+	}
+	if gw.config.Logging.Level != "warn" {
+		t.Errorf("logging.level = %q, want warn", gw.config.Logging.Level)
+	}
+}
+
+func TestReloadConfig_BadConfigKeepsOld(t *testing.T) {
+	for name, bad := range map[string]string{
+		"invalid yaml":     "logging: [level: debug\n",
+		"invalid duration": "agents:\n  reconnect_grace_period: soon\n",
+		"invalid rate":     "api:\n  rate_limit:\n    send: lots\n",
+		"unknown group":    "api:\n  rate_limit:\n    uploads: 1/min\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			gw, path, logging := newReloadGateway(t)
+			writeReloadConfig(t, path, dynamicReloadConfig)
+			if err := gw.ReloadConfig(); err != nil {
+				t.Fatalf("ReloadConfig: %v", err)
+			}
+			before := *gw.config
+			limiter := gw.rateLimiter(rateGroupSend)
+
+			// The bad file also asks for different dynamic settings, none
+			// of which may take effect.
+			writeReloadConfig(t, path, "logging:\n  level: error\nretention:\n  logs: 1h\n"+bad)
+			if err := gw.ReloadConfig(); err == nil {
+				t.Fatal("ReloadConfig succeeded, want an error")
+			}
+
+			if changes := config.Diff(&before, gw.config); len(changes) != 0 {
+				t.Errorf("config changed by a bad reload: %+v", changes)
+			}
+			if gw.rateLimiter(rateGroupSend) != limiter {
+				t.Error("send rate limiter replaced by a bad reload")
+			}
+			if got := gw.sessions.gracePeriod(); got != 30*time.Second {
+				t.Errorf("grace period = %v, want 30s", got)
+			}
+			if len(*logging) != 1 {
+				t.Errorf("logging applied %d times, want only for the good reload", len(*logging))
+			}
+		})
+	}
+}
+
+func TestApplyDynamic_InvalidConfig(t *testing.T) {
+	gw := newTestGateway(t)
+	cfg := *gw.config
+	cfg.Logging.Level = "debug"
+	cfg.API.RateLimit = map[string]string{rateGroupSend: "often"}
+
+	if err := gw.ApplyDynamic(&cfg); err == nil {
+		t.Fatal("ApplyDynamic succeeded, want a validation error")
+	}
+	if gw.config.Logging.Level != "" || gw.rateLimiter(rateGroupSend) != nil {
+		t.Errorf("invalid config applied: logging %+v, send limiter %v", gw.config.Logging, gw.rateLimiter(rateGroupSend))
+	}
+}
@@ -0,0 +1,202 @@
+// ABOUTME: Resumable SSE streams: agent responses are relayed into the broadcaster's replay buffer
+// ABOUTME: GET /api/requests/{request_id}/stream lets the sender or an admin resume a dropped stream from its Last-Event-ID
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/logctx"
+	"github.com/2389/coven-gateway/internal/ssechunk"
+	"github.com/2389/coven-gateway/internal/tracing"
+)
+
+// relayResponses records the started event, any preamble events, and then
+// every response on respChan as numbered SSE events under requestID. It keeps reading respChan
+// when no client is listening, so a client that drops can resume the stream.
+// The done event repeats agentID for clients that missed started and carries
+// the send's trace ID when it was traced. started and error events carry the
+// send's X-Request-ID, taken from ctx, as http_request_id. Text and thinking
+// longer than server.sse_chunk_bytes are recorded as several events, each
+// with its own ID, so a resumed stream picks up mid-way through the text.
+// The stream is owned by the caller in ctx, who alone may resume it.
+func (g *Gateway) relayResponses(ctx context.Context, requestID, agentID string, started map[string]any, respChan <-chan *agent.Response, preamble ...SSEEvent) {
+	traceID, httpRequestID := tracing.TraceID(ctx), logctx.RequestID(ctx)
+	if httpRequestID != "" {
+		started["http_request_id"] = httpRequestID
+	}
+	owner := conversation.RequestOwner{AgentID: agentID}
+	if a := auth.FromContext(ctx); a != nil {
+		owner.PrincipalID = a.PrincipalID
+	}
+	if conn, ok := g.agentManager.GetAgent(agentID); ok {
+		owner.AgentPrincipalID = conn.PrincipalID
+	}
+	g.eventBroadcaster.SetRequestOwner(requestID, owner)
+	g.recordSSEEvent(requestID, "started", started)
+	for _, ev := range preamble {
+		g.recordSSEEvent(requestID, ev.Event, ev.Data)
+	}
+	go func() {
+		defer g.eventBroadcaster.FinishRequest(requestID)
+		var sources agent.Sources
+		for resp := range respChan {
+			if resp.Event == agent.EventCitation {
+				sources.Add(resp.Citation)
+			}
+			event := g.responseToSSEEvent(resp)
+			if resp.Event == agent.EventText || resp.Event == agent.EventThinking {
+				for _, chunk := range ssechunk.Events(resp.Text, g.sseChunkBytes()) {
+					g.recordSSEEvent(requestID, event.Event, chunk)
+				}
+				continue
+			}
+			if resp.Event == agent.EventDone {
+				event.Data = doneSSEData(resp.Text, agentID, traceID, sources.List())
+			}
+			if body, ok := event.Data.(coverr.Body); ok {
+				body.HTTPRequestID = httpRequestID
+				event.Data = body
+			}
+			g.recordSSEEvent(requestID, event.Event, event.Data)
+			if resp.Event == agent.EventDone {
+				return
+			}
+		}
+	}()
+}
+
+// sseChunkBytes returns the largest text one text or thinking event carries.
+func (g *Gateway) sseChunkBytes() int {
+	if g.config == nil {
+		return ssechunk.DefaultMaxBytes
+	}
+	return ssechunk.MaxBytes(g.config.Server.SSEChunkBytes)
+}
+
+// recordSSEEvent appends an event to requestID's replay buffer.
+func (g *Gateway) recordSSEEvent(requestID, event string, data any) {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		g.logger.Error("failed to marshal SSE data", "error", err)
+		return
+	}
+	g.eventBroadcaster.RecordRequestEvent(requestID, event, dataJSON)
+}
+
+// serveRequestStream writes requestID's events after lastEventID, then live
+// events until the request finishes or the client goes away. When the missed
+// events are no longer buffered it writes a single session_orphaned event.
+func (g *Gateway) serveRequestStream(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, requestID string, lastEventID uint64) {
+	for {
+		events, done, err := g.eventBroadcaster.WaitRequestEvents(ctx, requestID, lastEventID)
+		if errors.Is(err, conversation.ErrRequestOrphaned) {
+			g.writeSSEEvent(w, "session_orphaned", map[string]string{"reason": "stream no longer buffered"})
+			flusher.Flush()
+			return
+		}
+		if err != nil {
+			g.writeSSEEvent(w, "error", coverr.NewBody(coverr.Canceled, "request canceled"))
+			flusher.Flush()
+			return
+		}
+
+		for _, ev := range events {
+			_, _ = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Event, ev.Data)
+			lastEventID = ev.ID
+		}
+		flusher.Flush()
+
+		if done {
+			return
+		}
+	}
+}
+
+// handleRequestRoutes dispatches /api/requests/{request_id}/... to the
+// stream and cancel handlers.
+func (g *Gateway) handleRequestRoutes(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/stream"):
+		g.handleRequestStream(w, r)
+	case strings.HasSuffix(r.URL.Path, "/cancel"):
+		g.handleCancelRequest(w, r)
+	default:
+		g.sendJSONError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleRequestStream handles GET /api/requests/{request_id}/stream. It
+// resumes the SSE stream of a send after the event named by the Last-Event-ID
+// header (or the last_event_id query parameter, for clients that cannot set
+// headers), replaying what the client missed before following live events.
+// Like cancel, only the sender or an admin may resume it, with a token that
+// may reach the request's agent.
+func (g *Gateway) handleRequestStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	requestID, ok := extractPathSegment(r.URL.Path, "/api/requests/", "/stream")
+	if !ok {
+		g.sendJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	var lastEventID uint64
+	if raw != "" {
+		var err error
+		lastEventID, err = strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			g.sendJSONError(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+	}
+	if owner, ok := g.eventBroadcaster.RequestOwner(requestID); ok && !mayResume(r.Context(), owner) {
+		g.sendJSONError(w, http.StatusForbidden, "only the sender or an admin may resume this stream")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		g.logger.Error("streaming not supported")
+		g.sendJSONError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	setSSEHeaders(w)
+	g.serveRequestStream(r.Context(), w, flusher, requestID, lastEventID)
+}
+
+// mayResume reports whether the caller may follow a stream owned by owner:
+// its sender or an admin, as for mayCancel, whose token may reach the agent.
+func mayResume(ctx context.Context, owner conversation.RequestOwner) bool {
+	a := auth.FromContext(ctx)
+	if a != nil && !a.IsAdmin() && (owner.PrincipalID == "" || owner.PrincipalID != a.PrincipalID) {
+		return false
+	}
+	return tokenAllowsAgent(ctx, owner.AgentID, owner.AgentPrincipalID)
+}
+
+// setSSEHeaders prepares w for an event stream.
+func setSSEHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+}
@@ -0,0 +1,259 @@
+// ABOUTME: Tests for resumable SSE streams via GET /api/requests/{request_id}/stream
+// ABOUTME: Drops a live /api/send stream, resumes it with Last-Event-ID, and checks the orphaned case and who may resume
+
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// channelSender is a fake agent whose responses are fed by the test.
+type channelSender struct {
+	ch chan *agent.Response
+}
+
+func (s *channelSender) SendMessage(_ context.Context, _ *agent.SendRequest) (<-chan *agent.Response, error) {
+	return s.ch, nil
+}
+
+type sseTestEvent struct {
+	id    uint64
+	event string
+	data  string
+}
+
+// readSSEEvent reads the next event from an SSE stream.
+func readSSEEvent(t *testing.T, r *bufio.Reader) sseTestEvent {
+	t.Helper()
+	var ev sseTestEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading SSE stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if ev.event != "" {
+				return ev
+			}
+		case strings.HasPrefix(line, "id: "):
+			ev.id, _ = strconv.ParseUint(strings.TrimPrefix(line, "id: "), 10, 64)
+		case strings.HasPrefix(line, "event: "):
+			ev.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestRequestStream_ResumesAfterDrop(t *testing.T) {
+	gw := newTestGateway(t)
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: "test-agent", Name: "Test", PrincipalID: "test-agent",
+		Stream: &testMockStream{}, Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("registering agent: %v", err)
+	}
+	sqlStore, ok := gw.store.(*store.SQLStore)
+	if !ok {
+		t.Fatal("store is not *SQLStore")
+	}
+	sender := &channelSender{ch: make(chan *agent.Response)}
+	gw.conversation = conversation.New(sqlStore, sender, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/send", gw.handleSendMessage)
+	mux.HandleFunc("/api/requests/", gw.handleRequestStream)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	body, _ := json.Marshal(SendMessageRequest{Sender: "u", Content: "hello", AgentID: "test-agent"})
+	resp, err := http.Post(srv.URL+"/api/send", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /api/send: %v", err)
+	}
+	stream := bufio.NewReader(resp.Body)
+
+	started := readSSEEvent(t, stream)
+	if started.id != 1 || started.event != "started" {
+		t.Fatalf("first event = %+v, want started with id 1", started)
+	}
+	var startedData struct {
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal([]byte(started.data), &startedData); err != nil || startedData.RequestID == "" {
+		t.Fatalf("started data = %s, want a request_id", started.data)
+	}
+
+	sender.ch <- &agent.Response{Event: agent.EventText, Text: "one"}
+	if ev := readSSEEvent(t, stream); ev.id != 2 || ev.data != `{"text":"one"}` {
+		t.Fatalf("second event = %+v", ev)
+	}
+
+	// The network drops; the agent keeps answering.
+	_ = resp.Body.Close()
+	sender.ch <- &agent.Response{Event: agent.EventText, Text: "two"}
+	sender.ch <- &agent.Response{Event: agent.EventText, Text: "three"}
+
+	streamURL := srv.URL + "/api/requests/" + startedData.RequestID + "/stream"
+	req, _ := http.NewRequest(http.MethodGet, streamURL, nil)
+	req.Header.Set("Last-Event-ID", "2")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET stream: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	stream = bufio.NewReader(resp.Body)
+
+	for _, want := range []sseTestEvent{
+		{3, "text", `{"text":"two"}`},
+		{4, "text", `{"text":"three"}`},
+	} {
+		if got := readSSEEvent(t, stream); got != want {
+			t.Fatalf("replayed event = %+v, want %+v", got, want)
+		}
+	}
+
+	sender.ch <- &agent.Response{Event: agent.EventDone, Text: "one two three", Done: true}
+	if ev := readSSEEvent(t, stream); ev.id != 5 || ev.event != "done" {
+		t.Fatalf("live event = %+v, want done with id 5", ev)
+	}
+	if rest, _ := io.ReadAll(stream); len(rest) != 0 {
+		t.Errorf("stream continued after done: %q", rest)
+	}
+
+	// After the retention window the stream can no longer be resumed.
+	gw.eventBroadcaster.SetRequestRetention(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	resp, err = http.Get(streamURL + "?last_event_id=2")
+	if err != nil {
+		t.Fatalf("GET stream: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if ev := readSSEEvent(t, bufio.NewReader(resp.Body)); ev.event != "session_orphaned" {
+		t.Fatalf("event after retention = %+v, want session_orphaned", ev)
+	}
+}
+
+func TestRequestStream_InvalidLastEventID(t *testing.T) {
+	gw := newTestGateway(t)
+	req := httptest.NewRequest(http.MethodGet, "/api/requests/abc/stream", nil)
+	req.Header.Set("Last-Event-ID", "not-a-number")
+	rec := httptest.NewRecorder()
+	gw.handleRequestStream(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestRequestStream_OnlySenderOrAdminMayResume(t *testing.T) {
+	gw := newTestGateway(t)
+	gw.eventBroadcaster.SetRequestOwner("req-1", conversation.RequestOwner{PrincipalID: "alice", AgentID: "agent-1"})
+	gw.eventBroadcaster.RecordRequestEvent("req-1", "started", json.RawMessage(`{}`))
+	gw.eventBroadcaster.FinishRequest("req-1")
+
+	tests := []struct {
+		name   string
+		caller *auth.AuthContext
+		want   int
+	}{
+		{"auth disabled", nil, http.StatusOK},
+		{"sender", &auth.AuthContext{PrincipalID: "alice"}, http.StatusOK},
+		{"admin", &auth.AuthContext{PrincipalID: "boss", Roles: []string{"admin"}}, http.StatusOK},
+		{"another principal", &auth.AuthContext{PrincipalID: "mallory"}, http.StatusForbidden},
+		{"sender token for another agent", &auth.AuthContext{PrincipalID: "alice", AgentIDs: []string{"agent-2"}}, http.StatusForbidden},
+		{"admin token for another agent", &auth.AuthContext{PrincipalID: "boss", Roles: []string{"admin"}, AgentIDs: []string{"agent-2"}}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/requests/req-1/stream", nil)
+			if tt.caller != nil {
+				req = req.WithContext(auth.WithAuth(req.Context(), tt.caller))
+			}
+			rec := httptest.NewRecorder()
+			gw.handleRequestStream(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusOK && !strings.Contains(rec.Body.String(), "event: started") {
+				t.Errorf("body = %q, want the replayed started event", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRelayResponses_SplitsLongText(t *testing.T) {
+	gw := newTestGateway(t)
+	gw.config.Server.SSEChunkBytes = 10
+
+	text := "日本語\n😀😀😀 and 漢字"
+	respChan := make(chan *agent.Response, 2)
+	respChan <- &agent.Response{Event: agent.EventText, Text: text}
+	respChan <- &agent.Response{Event: agent.EventDone, Text: text, Done: true}
+	close(respChan)
+	gw.relayResponses(context.Background(), "req-chunks", "agent-1", map[string]any{}, respChan)
+
+	var events []conversation.RequestEvent
+	for {
+		batch, done, err := gw.eventBroadcaster.WaitRequestEvents(context.Background(), "req-chunks", uint64(len(events)))
+		if err != nil {
+			t.Fatalf("WaitRequestEvents: %v", err)
+		}
+		events = append(events, batch...)
+		if done {
+			break
+		}
+	}
+
+	var joined strings.Builder
+	var chunks int
+	for _, ev := range events {
+		if ev.Event != "text" {
+			continue
+		}
+		var data struct {
+			Text      string `json:"text"`
+			Continued bool   `json:"continued"`
+		}
+		if err := json.Unmarshal(ev.Data, &data); err != nil {
+			t.Fatalf("text data %s: %v", ev.Data, err)
+		}
+		if !utf8.ValidString(data.Text) || len(data.Text) > 10 {
+			t.Errorf("chunk %q is not valid UTF-8 within 10 bytes", data.Text)
+		}
+		if data.Continued != (chunks > 0) {
+			t.Errorf("chunk %d continued = %v", chunks, data.Continued)
+		}
+		joined.WriteString(data.Text)
+		chunks++
+	}
+	if chunks < 3 || joined.String() != text {
+		t.Errorf("%d chunks joined to %q, want several joining to %q", chunks, joined.String(), text)
+	}
+	if last := events[len(events)-1]; last.Event != "done" || last.ID != uint64(len(events)) {
+		t.Errorf("last event = %s #%d, want done #%d", last.Event, last.ID, len(events))
+	}
+}
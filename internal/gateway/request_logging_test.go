@@ -0,0 +1,163 @@
+// ABOUTME: Tests that one send's log lines share its request ID from the HTTP handler through to pack tools.
+// ABOUTME: Captures every component's JSON logs while a send runs against a real agent connection.
+
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/logctx"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// logCapture collects JSON log lines written from many goroutines.
+type logCapture struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (c *logCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Write(p)
+}
+
+// records returns the decoded lines whose msg is msg.
+func (c *logCapture) records(t *testing.T, msg string) []map[string]any {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(c.buf.Bytes()))
+	for scanner.Scan() {
+		var rec map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("decode log line %q: %v", scanner.Text(), err)
+		}
+		if rec["msg"] == msg {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+// packToolStream is an agent stream with a context, as handleExecutePackTool needs.
+type packToolStream struct {
+	*capturingStream
+}
+
+func (packToolStream) Context() context.Context { return context.Background() }
+
+func TestSend_RequestIDAcrossLayers(t *testing.T) {
+	logs := &logCapture{}
+	gw, err := New(&config.Config{
+		Server:   config.ServerConfig{GRPCAddr: "localhost:0", HTTPAddr: "localhost:0"},
+		Database: config.DatabaseConfig{Path: ":memory:"},
+	}, slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	stream := &capturingStream{}
+	conn := agent.NewConnection(agent.ConnectionParams{ID: "agent-1", Name: "Agent", Stream: stream, Logger: slog.Default()})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	srv := httptest.NewServer(gw.httpServer.Handler)
+	defer srv.Close()
+
+	type result struct {
+		header string
+		body   string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/send", strings.NewReader(`{"agent_id":"agent-1","sender":"alice","content":"hi"}`))
+		req.Header.Set(logctx.Header, "req-log-1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		done <- result{header: resp.Header.Get(logctx.Header), body: string(body), err: err}
+	}()
+
+	var sent *pb.SendMessage
+	for deadline := time.Now().Add(5 * time.Second); sent == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("agent never received the message")
+		}
+		stream.mu.Lock()
+		if len(stream.sent) > 0 {
+			sent = stream.sent[0].GetSendMessage()
+		}
+		stream.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := sent.GetTraceContext()[logctx.CarrierKey]; got != "req-log-1" {
+		t.Errorf("SendMessage trace context %s = %q, want req-log-1", logctx.CarrierKey, got)
+	}
+
+	// The agent echoes the trace context on a pack tool call, then fails.
+	control := newCovenControlServer(gw, gw.logger.With("component", "grpc"))
+	control.handleExecutePackTool(packToolStream{stream}, conn, &pb.ExecutePackTool{
+		RequestId: "tool-1", ToolName: "no-such-tool", InputJson: "{}", TraceContext: sent.GetTraceContext(),
+	})
+	conn.HandleResponse(&pb.MessageResponse{RequestId: sent.GetRequestId(), Event: &pb.MessageResponse_Error{Error: "boom"}})
+
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("send: %v", res.err)
+	}
+	if res.header != "req-log-1" {
+		t.Errorf("response %s = %q, want req-log-1", logctx.Header, res.header)
+	}
+	for _, event := range []string{"started", "error"} {
+		if !sseEventHas(res.body, event, `"http_request_id":"req-log-1"`) {
+			t.Errorf("%s event lacks http_request_id; body:\n%s", event, res.body)
+		}
+	}
+
+	for _, msg := range []string{
+		"user message recorded",      // conversation
+		"message sent to agent",      // agent manager
+		"→ pack tool request",        // gRPC handler
+		"tool not found in registry", // pack router
+	} {
+		recs := logs.records(t, msg)
+		if len(recs) == 0 {
+			t.Errorf("no %q log line", msg)
+		}
+		for _, rec := range recs {
+			if rec["request_id"] != "req-log-1" {
+				t.Errorf("%q logged request_id %v, want req-log-1", msg, rec["request_id"])
+			}
+		}
+	}
+}
+
+// sseEventHas reports whether body has an SSE event named event whose data
+// contains want.
+func sseEventHas(body, event, want string) bool {
+	for block := range strings.SplitSeq(body, "\n\n") {
+		if strings.Contains(block, "event: "+event+"\n") && strings.Contains(block, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+// ABOUTME: Retention job that prunes expired ledger events, messages and agent logs on a schedule
+// ABOUTME: POST /api/admin/maintenance/prune runs it on demand and reports rows deleted per table
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+const (
+	// defaultRetentionInterval is the time between pruning runs when
+	// retention.interval is unset.
+	defaultRetentionInterval = time.Hour
+	// defaultPruneBatchPause is slept between DELETE batches when
+	// retention.batch_pause is unset.
+	defaultPruneBatchPause = 100 * time.Millisecond
+)
+
+// errPruneRunning is returned when a prune starts while another is running.
+var errPruneRunning = errors.New("a prune is already running")
+
+// retentionStore is what pruning needs from storage.
+type retentionStore interface {
+	Prune(ctx context.Context, opts store.PruneOptions) (store.PruneResult, error)
+	Vacuum(ctx context.Context, mode store.VacuumMode) error
+}
+
+// PruneResponse is the JSON response for POST /api/admin/maintenance/prune.
+type PruneResponse struct {
+	Deleted    map[string]int64 `json:"deleted"` // rows deleted per table with a retention period
+	Batches    int              `json:"batches"`
+	Vacuum     string           `json:"vacuum,omitempty"` // the vacuum that ran, if any
+	DurationMS int64            `json:"duration_ms"`
+}
+
+// prune deletes the rows older than their retention period. Vacuum runs
+// afterwards when rows were deleted, or always when forceVacuum is set.
+// Only one prune runs at a time; a second gets errPruneRunning.
+func (g *Gateway) prune(ctx context.Context, vacuum store.VacuumMode, forceVacuum bool) (*PruneResponse, error) {
+	if !g.pruneMu.TryLock() {
+		return nil, errPruneRunning
+	}
+	defer g.pruneMu.Unlock()
+
+	r := g.retentionConfig()
+	now := time.Now()
+	opts := store.PruneOptions{BatchSize: r.BatchSize, BatchPause: r.BatchPause}
+	if r.BatchPauseRaw == "" {
+		opts.BatchPause = defaultPruneBatchPause
+	}
+	if r.LedgerEvents > 0 {
+		opts.LedgerEventsBefore = now.Add(-r.LedgerEvents)
+	}
+	if r.Messages > 0 {
+		opts.MessagesBefore = now.Add(-r.Messages)
+	}
+	if r.Logs > 0 {
+		opts.LogsBefore = now.Add(-r.Logs)
+	}
+
+	result, err := g.retention.Prune(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	resp := &PruneResponse{Deleted: result.Deleted, Batches: result.Batches}
+	var total int64
+	for _, n := range result.Deleted {
+		total += n
+	}
+	if vacuum != store.VacuumNone && (total > 0 || forceVacuum) {
+		if err := g.retention.Vacuum(ctx, vacuum); err != nil {
+			return nil, err
+		}
+		resp.Vacuum = string(vacuum)
+	}
+	resp.DurationMS = time.Since(now).Milliseconds()
+
+	if total > 0 || resp.Vacuum != "" {
+		g.logger.Info("pruned expired rows",
+			"deleted", result.Deleted,
+			"batches", result.Batches,
+			"vacuum", resp.Vacuum,
+			"duration_ms", resp.DurationMS)
+	}
+	return resp, nil
+}
+
+// watchRetention prunes once at startup and then every retention.interval
+// until ctx is done. Runs are skipped while no table has a retention period.
+// Settings are read before each run, so a config reload applies to the next
+// one.
+func (g *Gateway) watchRetention(ctx context.Context) {
+	if g.retention == nil {
+		return
+	}
+	for {
+		r := g.retentionConfig()
+		if r.Enabled() {
+			if _, err := g.prune(ctx, store.VacuumMode(r.Vacuum), false); err != nil && ctx.Err() == nil {
+				g.logger.Warn("retention prune failed", "error", err)
+			}
+		}
+		interval := r.Interval
+		if interval <= 0 {
+			interval = defaultRetentionInterval
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// handlePrune handles POST /api/admin/maintenance/prune. The optional
+// vacuum query parameter (none, incremental or full) overrides
+// retention.vacuum for this run; naming a mode vacuums even if nothing was
+// deleted.
+func (g *Gateway) handlePrune(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if g.retention == nil {
+		g.sendJSONError(w, http.StatusServiceUnavailable, "pruning not available")
+		return
+	}
+
+	vacuum := store.VacuumMode(g.retentionConfig().Vacuum)
+	force := false
+	if v := r.URL.Query().Get("vacuum"); v != "" {
+		switch v {
+		case "none":
+			vacuum = store.VacuumNone
+		case string(store.VacuumIncremental), string(store.VacuumFull):
+			vacuum, force = store.VacuumMode(v), true
+		default:
+			g.sendJSONError(w, http.StatusBadRequest, "vacuum must be none, incremental or full")
+			return
+		}
+	}
+
+	resp, err := g.prune(r.Context(), vacuum, force)
+	if errors.Is(err, errPruneRunning) {
+		g.sendJSONError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to prune", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
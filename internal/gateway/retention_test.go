@@ -0,0 +1,80 @@
+// ABOUTME: Tests for the retention job and POST /api/admin/maintenance/prune
+// ABOUTME: Checks the per-table report, vacuum overrides, and that runs don't overlap
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestHandlePrune(t *testing.T) {
+	gw := newTestGateway(t)
+	gw.config.Retention.LedgerEvents = 24 * time.Hour
+	gw.config.Retention.BatchSize = 2
+	gw.config.Retention.BatchPauseRaw = "0s"
+	sqlStore := gw.store.(*store.SQLStore)
+
+	old := time.Now().Add(-48 * time.Hour)
+	for i := range 3 {
+		text := "old"
+		if err := sqlStore.SaveEvent(context.Background(), &store.LedgerEvent{
+			ID: fmt.Sprintf("event-%d", i), ConversationKey: "agent-1", Direction: store.EventDirectionInbound,
+			Author: "alice", Timestamp: old, Type: store.EventTypeMessage, Text: &text,
+		}); err != nil {
+			t.Fatalf("SaveEvent: %v", err)
+		}
+	}
+
+	post := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		gw.handlePrune(rec, httptest.NewRequest(http.MethodPost, target, nil))
+		return rec
+	}
+
+	rec := post("/api/admin/maintenance/prune?vacuum=incremental")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp PruneResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Deleted["ledger_events"] != 3 || resp.Batches != 2 || resp.Vacuum != "incremental" {
+		t.Errorf("response = %+v, want 3 ledger_events deleted in 2 batches and an incremental vacuum", resp)
+	}
+	if _, ok := resp.Deleted["log_entries"]; ok {
+		t.Errorf("deleted = %v, want only tables with a retention period", resp.Deleted)
+	}
+
+	// Nothing deleted and no vacuum asked for: no vacuum.
+	rec = post("/api/admin/maintenance/prune")
+	resp = PruneResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Vacuum != "" || resp.Deleted["ledger_events"] != 0 {
+		t.Errorf("second prune = %s, want nothing deleted and no vacuum", rec.Body.String())
+	}
+
+	if rec := post("/api/admin/maintenance/prune?vacuum=sometimes"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad vacuum: status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	gw.pruneMu.Lock()
+	rec = post("/api/admin/maintenance/prune")
+	gw.pruneMu.Unlock()
+	if rec.Code != http.StatusConflict {
+		t.Errorf("overlapping prune: status %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	rec = httptest.NewRecorder()
+	gw.handlePrune(rec, httptest.NewRequest(http.MethodGet, "/api/admin/maintenance/prune", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -131,7 +131,7 @@ func (e *errorBindingStore) GetBindingByChannel(ctx context.Context, frontend, c
 
 // End-to-End Scenario Tests with real SQLite
 
-func setupTestStoreForRouter(t *testing.T) *store.SQLiteStore {
+func setupTestStoreForRouter(t *testing.T) *store.SQLStore {
 	t.Helper()
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -147,7 +147,7 @@ func setupTestStoreForRouter(t *testing.T) *store.SQLiteStore {
 }
 
 // createTestAgentForRouter creates a principal with type="agent" for binding tests.
-func createTestAgentForRouter(t *testing.T, s *store.SQLiteStore, id string) {
+func createTestAgentForRouter(t *testing.T, s *store.SQLStore, id string) {
 	t.Helper()
 	fp := id
 	for len(fp) < 64 {
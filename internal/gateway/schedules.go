@@ -0,0 +1,527 @@
+// ABOUTME: Scheduled messages: admins queue a message for an agent or binding via /api/schedules,
+// ABOUTME: and the scheduler sends it through the conversation service when due, retrying with backoff.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/recurrence"
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+const (
+	// schedulePollInterval is how often the scheduler looks for due
+	// messages, and so how late one may run.
+	schedulePollInterval = 15 * time.Second
+	// scheduleMaxAttempts is how many consecutive failed sends a schedule
+	// gets before it is marked errored.
+	scheduleMaxAttempts = 5
+	// scheduleRetryBase and scheduleRetryMax bound the backoff between
+	// failed attempts, which doubles from base up to max.
+	scheduleRetryBase = 30 * time.Second
+	scheduleRetryMax  = 10 * time.Minute
+	// scheduleDefaultSender is the sender recorded when a schedule names none.
+	scheduleDefaultSender = "scheduler"
+)
+
+// CreateScheduleRequest is the JSON body for POST /api/schedules. It targets
+// either agent_id or the agent bound to frontend+channel_id.
+type CreateScheduleRequest struct {
+	AgentID   string `json:"agent_id,omitempty"`
+	Frontend  string `json:"frontend,omitempty"`
+	ChannelID string `json:"channel_id,omitempty"`
+	Sender    string `json:"sender,omitempty"`
+	Content   string `json:"content"`
+	// RunAt is the first run; it defaults to the recurrence's next time.
+	RunAt      string `json:"run_at,omitempty"`
+	Recurrence string `json:"recurrence,omitempty"`
+}
+
+// ScheduleResponse is the JSON representation of a scheduled message.
+type ScheduleResponse struct {
+	ID           string  `json:"id"`
+	AgentID      string  `json:"agent_id,omitempty"`
+	Frontend     string  `json:"frontend,omitempty"`
+	ChannelID    string  `json:"channel_id,omitempty"`
+	Sender       string  `json:"sender"`
+	Content      string  `json:"content"`
+	RunAt        string  `json:"run_at"`
+	Recurrence   string  `json:"recurrence,omitempty"`
+	CreatedBy    string  `json:"created_by,omitempty"`
+	Status       string  `json:"status"`
+	Attempts     int     `json:"attempts"`
+	LastError    string  `json:"last_error,omitempty"`
+	LastThreadID string  `json:"last_thread_id,omitempty"`
+	LastRunAt    *string `json:"last_run_at,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+	UpdatedAt    string  `json:"updated_at"`
+}
+
+// ListSchedulesResponse is the JSON response for GET /api/schedules.
+type ListSchedulesResponse struct {
+	Schedules []ScheduleResponse `json:"schedules"`
+}
+
+// scheduleSendFunc delivers one run of a scheduled message. It returns the
+// thread the message landed in, or "" if it never reached the agent; an
+// error alongside a thread ID means the agent answered with an error.
+type scheduleSendFunc func(ctx context.Context, m *store.ScheduledMessage) (threadID string, err error)
+
+// scheduler polls the store for due scheduled messages and sends each one.
+// Schedules live in the store, so a restart picks up where it left off.
+type scheduler struct {
+	store  *store.SQLStore
+	send   scheduleSendFunc
+	logger *slog.Logger
+	now    func() time.Time
+
+	interval    time.Duration
+	maxAttempts int
+	retryBase   time.Duration
+	retryMax    time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[string]bool // schedule IDs with a send in flight
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+func newScheduler(s *store.SQLStore, send scheduleSendFunc, logger *slog.Logger) *scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &scheduler{
+		store:       s,
+		send:        send,
+		logger:      logger,
+		now:         time.Now,
+		interval:    schedulePollInterval,
+		maxAttempts: scheduleMaxAttempts,
+		retryBase:   scheduleRetryBase,
+		retryMax:    scheduleRetryMax,
+		ctx:         ctx,
+		cancel:      cancel,
+		running:     make(map[string]bool),
+	}
+}
+
+// Start loads the pending schedules and begins polling. The first poll
+// waits one interval so agents have a chance to reconnect after a restart.
+func (s *scheduler) Start() {
+	s.startOnce.Do(func() {
+		pending, err := s.store.ListSchedules(s.ctx, store.SchedulePending)
+		if err != nil {
+			s.logger.Warn("failed to load pending schedules", "error", err)
+		} else {
+			overdue := 0
+			for _, m := range pending {
+				if !m.RunAt.After(s.now()) {
+					overdue++
+				}
+			}
+			s.logger.Info("scheduler started", "pending", len(pending), "overdue", overdue)
+		}
+
+		// Close may run on another goroutine; mu orders the loop's Add
+		// before its Wait, and a closed scheduler does not start polling.
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.ctx.Err() != nil {
+			return
+		}
+		s.wg.Add(1)
+		go s.loop()
+	})
+}
+
+func (s *scheduler) loop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.dispatch()
+		}
+	}
+}
+
+// Close stops polling and waits for sends in flight to finish.
+func (s *scheduler) Close() {
+	s.stopOnce.Do(func() {
+		s.mu.Lock()
+		s.cancel()
+		s.mu.Unlock()
+	})
+	s.wg.Wait()
+}
+
+// dispatch starts a send for every due schedule not already running.
+func (s *scheduler) dispatch() {
+	due, err := s.store.ListDueSchedules(s.ctx, s.now())
+	if err != nil {
+		if s.ctx.Err() == nil {
+			s.logger.Warn("failed to list due schedules", "error", err)
+		}
+		return
+	}
+	for _, m := range due {
+		s.mu.Lock()
+		if s.running[m.ID] || s.ctx.Err() != nil {
+			s.mu.Unlock()
+			continue
+		}
+		s.running[m.ID] = true
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.run(m)
+	}
+}
+
+// run sends one schedule and records the outcome. Sends refused because the
+// gateway is shutting down are left pending for the next start.
+func (s *scheduler) run(m *store.ScheduledMessage) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, m.ID)
+		s.mu.Unlock()
+	}()
+
+	threadID, err := s.send(s.ctx, m)
+	if threadID == "" && (errors.Is(err, agent.ErrDraining) || s.ctx.Err() != nil) {
+		return
+	}
+	s.record(m, threadID, err)
+}
+
+// record applies a run's outcome to m and saves it. A run that reached the
+// agent counts even if the agent answered with an error; one that didn't
+// is retried with backoff until maxAttempts.
+func (s *scheduler) record(m *store.ScheduledMessage, threadID string, err error) {
+	now := s.now()
+	m.LastRunAt = &now
+	logger := s.logger.With("schedule_id", m.ID)
+
+	switch {
+	case threadID == "":
+		m.Attempts++
+		m.LastError = err.Error()
+		if m.Attempts >= s.maxAttempts {
+			m.Status = store.ScheduleErrored
+			logger.Warn("scheduled message failed, giving up", "attempts", m.Attempts, "error", err)
+		} else {
+			m.RunAt = now.Add(s.backoff(m.Attempts))
+			logger.Info("scheduled message failed, will retry", "attempts", m.Attempts, "retry_at", m.RunAt, "error", err)
+		}
+	default:
+		m.Attempts = 0
+		m.LastThreadID = threadID
+		m.LastError = ""
+		if err != nil {
+			m.LastError = err.Error()
+		}
+		s.advance(m, now)
+		logger.Info("scheduled message sent", "thread_id", threadID, "status", m.Status, "next_run_at", m.RunAt)
+	}
+
+	// The outcome is saved even when shutdown canceled the send's context.
+	if err := s.store.UpdateScheduleRun(context.WithoutCancel(s.ctx), m); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			logger.Debug("schedule deleted while running")
+			return
+		}
+		logger.Error("failed to record scheduled message run", "error", err)
+	}
+}
+
+// advance moves m to its next recurrence after now, or marks a one-off (or
+// exhausted) schedule done.
+func (s *scheduler) advance(m *store.ScheduledMessage, now time.Time) {
+	if m.Recurrence == "" {
+		m.Status = store.ScheduleDone
+		return
+	}
+	rule, err := recurrence.Parse(m.Recurrence)
+	if err != nil {
+		m.Status = store.ScheduleErrored
+		m.LastError = err.Error()
+		return
+	}
+	next := rule.Next(now.UTC())
+	if next.IsZero() {
+		m.Status = store.ScheduleDone
+		return
+	}
+	m.RunAt = next
+}
+
+// backoff is the delay before retry attempt n (1-based): retryBase doubled
+// per earlier failure, capped at retryMax.
+func (s *scheduler) backoff(n int) time.Duration {
+	d := s.retryBase
+	for i := 1; i < n && d < s.retryMax; i++ {
+		d *= 2
+	}
+	return min(d, s.retryMax)
+}
+
+// sendScheduled delivers a scheduled message the way POST /api/send would:
+// resolving its agent or binding and sending through the conversation
+// service, so the exchange is recorded in a thread and the ledger. Runs of
+// a direct-to-agent schedule continue the thread of the previous run.
+func (g *Gateway) sendScheduled(ctx context.Context, m *store.ScheduledMessage) (string, error) {
+	if g.agentManager.Draining() {
+		return "", agent.ErrDraining
+	}
+	req := &SendMessageRequest{
+		Sender:    m.Sender,
+		Content:   m.Content,
+		AgentID:   m.AgentID,
+		Frontend:  m.Frontend,
+		ChannelID: m.ChannelID,
+	}
+	if m.AgentID != "" {
+		req.ThreadID = m.LastThreadID
+	}
+	target, errMsg := g.resolveTarget(ctx, req)
+	if target == nil {
+		return "", errors.New(errMsg)
+	}
+
+	convResp, err := g.conversation.SendMessage(ctx, &conversation.SendRequest{
+		ThreadID:         target.ThreadID,
+		FrontendName:     target.FrontendName,
+		ExternalID:       target.ExternalID,
+		AgentID:          target.AgentID,
+		Sender:           m.Sender,
+		Content:          m.Content,
+		MaxDuration:      target.MaxDuration,
+		ChannelContext:   target.Context,
+		ActorPrincipalID: m.CreatedBy,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// The conversation service persists the response as it streams.
+	var agentErr error
+	for resp := range convResp.Stream {
+		if resp.Event == agent.EventError {
+			agentErr = errors.New(resp.Error)
+		}
+	}
+	return convResp.ThreadID, agentErr
+}
+
+// handleSchedules routes /api/schedules and /api/schedules/{id}.
+func (g *Gateway) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	if g.scheduler == nil {
+		g.sendJSONError(w, http.StatusServiceUnavailable, "scheduler not available")
+		return
+	}
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/schedules"), "/")
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		g.handleListSchedules(w, r)
+	case id == "" && r.Method == http.MethodPost:
+		g.handleCreateSchedule(w, r)
+	case id == "":
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	case strings.Contains(id, "/"):
+		g.sendJSONError(w, http.StatusNotFound, "unknown endpoint")
+	case r.Method == http.MethodGet:
+		g.handleGetSchedule(w, r, id)
+	case r.Method == http.MethodDelete:
+		g.handleDeleteSchedule(w, r, id)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCreateSchedule handles POST /api/schedules.
+func (g *Gateway) handleCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req CreateScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.sendJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Content == "" {
+		g.sendJSONError(w, http.StatusBadRequest, "content is required")
+		return
+	}
+	byAgent, byBinding := req.AgentID != "", req.Frontend != "" || req.ChannelID != ""
+	if byAgent == byBinding || (byBinding && (req.Frontend == "" || req.ChannelID == "")) {
+		g.sendJSONError(w, http.StatusBadRequest, "specify either agent_id or frontend+channel_id")
+		return
+	}
+	if byBinding {
+		_, err := g.scheduler.store.GetBindingByChannel(r.Context(), req.Frontend, req.ChannelID)
+		if errors.Is(err, store.ErrBindingNotFound) {
+			g.sendJSONError(w, http.StatusBadRequest, "channel not bound to agent")
+			return
+		}
+		if err != nil {
+			g.logger.Error("failed to get binding", "error", err)
+			g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+
+	now := time.Now()
+	var runAt time.Time
+	if req.Recurrence != "" {
+		rule, err := recurrence.Parse(req.Recurrence)
+		if err != nil {
+			g.sendJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if runAt = rule.Next(now.UTC()); runAt.IsZero() {
+			g.sendJSONError(w, http.StatusBadRequest, "recurrence never runs")
+			return
+		}
+	}
+	if req.RunAt != "" {
+		t, err := timeparse.ParseParam("run_at", req.RunAt)
+		if err != nil {
+			g.sendTimestampError(w, err)
+			return
+		}
+		runAt = t
+	}
+	if runAt.IsZero() {
+		g.sendJSONError(w, http.StatusBadRequest, "run_at or recurrence is required")
+		return
+	}
+
+	m := &store.ScheduledMessage{
+		ID:         uuid.New().String(),
+		AgentID:    req.AgentID,
+		Frontend:   req.Frontend,
+		ChannelID:  req.ChannelID,
+		Sender:     req.Sender,
+		Content:    req.Content,
+		RunAt:      runAt,
+		Recurrence: req.Recurrence,
+	}
+	if m.Sender == "" {
+		m.Sender = scheduleDefaultSender
+	}
+	if a := auth.FromContext(r.Context()); a != nil {
+		m.CreatedBy = a.PrincipalID
+	}
+	if err := g.scheduler.store.CreateSchedule(r.Context(), m); err != nil {
+		g.logger.Error("failed to create schedule", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	g.logger.Info("message scheduled", "schedule_id", m.ID, "run_at", m.RunAt, "recurrence", m.Recurrence, "created_by", m.CreatedBy)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(scheduleToResponse(m)); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// handleListSchedules handles GET /api/schedules?status=pending|done|errored.
+func (g *Gateway) handleListSchedules(w http.ResponseWriter, r *http.Request) {
+	status := store.ScheduleStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", store.SchedulePending, store.ScheduleDone, store.ScheduleErrored:
+	default:
+		g.sendJSONError(w, http.StatusBadRequest, "status must be pending, done, or errored")
+		return
+	}
+	schedules, err := g.scheduler.store.ListSchedules(r.Context(), status)
+	if err != nil {
+		g.logger.Error("failed to list schedules", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response := ListSchedulesResponse{Schedules: make([]ScheduleResponse, len(schedules))}
+	for i, m := range schedules {
+		response.Schedules[i] = scheduleToResponse(m)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// handleGetSchedule handles GET /api/schedules/{id}.
+func (g *Gateway) handleGetSchedule(w http.ResponseWriter, r *http.Request, id string) {
+	m, err := g.scheduler.store.GetSchedule(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		g.sendJSONError(w, http.StatusNotFound, "schedule not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to get schedule", "schedule_id", id, "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(scheduleToResponse(m)); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// handleDeleteSchedule handles DELETE /api/schedules/{id}. A send already
+// in flight finishes, but the schedule does not run again.
+func (g *Gateway) handleDeleteSchedule(w http.ResponseWriter, r *http.Request, id string) {
+	err := g.scheduler.store.DeleteSchedule(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		g.sendJSONError(w, http.StatusNotFound, "schedule not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to delete schedule", "schedule_id", id, "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	g.logger.Info("schedule deleted", "schedule_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func scheduleToResponse(m *store.ScheduledMessage) ScheduleResponse {
+	return ScheduleResponse{
+		ID:           m.ID,
+		AgentID:      m.AgentID,
+		Frontend:     m.Frontend,
+		ChannelID:    m.ChannelID,
+		Sender:       m.Sender,
+		Content:      m.Content,
+		RunAt:        timeparse.Format(m.RunAt),
+		Recurrence:   m.Recurrence,
+		CreatedBy:    m.CreatedBy,
+		Status:       string(m.Status),
+		Attempts:     m.Attempts,
+		LastError:    m.LastError,
+		LastThreadID: m.LastThreadID,
+		LastRunAt:    timeparse.FormatPtr(m.LastRunAt),
+		CreatedAt:    timeparse.Format(m.CreatedAt),
+		UpdatedAt:    timeparse.Format(m.UpdatedAt),
+	}
+}
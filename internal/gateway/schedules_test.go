@@ -0,0 +1,253 @@
+// ABOUTME: Tests for scheduled messages: run outcomes and retry backoff, sending through the
+// ABOUTME: conversation service, and the /api/schedules endpoints.
+
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// scriptedSend answers scheduler sends with the next queued result. Sends
+// run concurrently, so calls is guarded by mu.
+type scriptedSend struct {
+	mu      sync.Mutex
+	results []sendResult
+	calls   int
+}
+
+type sendResult struct {
+	threadID string
+	err      error
+}
+
+func (s *scriptedSend) send(context.Context, *store.ScheduledMessage) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.results[min(s.calls, len(s.results)-1)]
+	s.calls++
+	return r.threadID, r.err
+}
+
+func newTestScheduler(t *testing.T, results ...sendResult) (*scheduler, *scriptedSend, *store.SQLStore, *time.Time) {
+	t.Helper()
+	sqlStore, ok := newTestGateway(t).store.(*store.SQLStore)
+	if !ok {
+		t.Fatal("store is not *SQLStore")
+	}
+	script := &scriptedSend{results: results}
+	s := newScheduler(sqlStore, script.send, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(s.Close)
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, script, sqlStore, &now
+}
+
+// runDue dispatches due schedules and waits for their sends to be recorded.
+func runDue(s *scheduler) {
+	s.dispatch()
+	s.wg.Wait()
+}
+
+func createTestSchedule(t *testing.T, sqlStore *store.SQLStore, m *store.ScheduledMessage) {
+	t.Helper()
+	if err := sqlStore.CreateSchedule(context.Background(), m); err != nil {
+		t.Fatalf("CreateSchedule: %v", err)
+	}
+}
+
+func getTestSchedule(t *testing.T, sqlStore *store.SQLStore, id string) *store.ScheduledMessage {
+	t.Helper()
+	m, err := sqlStore.GetSchedule(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetSchedule: %v", err)
+	}
+	return m
+}
+
+func TestScheduler_OneOffAndRecurring(t *testing.T) {
+	s, script, sqlStore, now := newTestScheduler(t, sendResult{threadID: "thread-1"})
+	createTestSchedule(t, sqlStore, &store.ScheduledMessage{ID: "once", AgentID: "a", Sender: "ops", Content: "hi", RunAt: now.Add(-time.Minute)})
+	createTestSchedule(t, sqlStore, &store.ScheduledMessage{ID: "daily", AgentID: "a", Sender: "ops", Content: "standup", RunAt: *now, Recurrence: "0 9 * * *"})
+	createTestSchedule(t, sqlStore, &store.ScheduledMessage{ID: "future", AgentID: "a", Sender: "ops", Content: "later", RunAt: now.Add(time.Hour)})
+
+	runDue(s)
+	if script.calls != 2 {
+		t.Fatalf("sends = %d, want 2 (future schedule not due)", script.calls)
+	}
+
+	once := getTestSchedule(t, sqlStore, "once")
+	if once.Status != store.ScheduleDone || once.LastThreadID != "thread-1" || once.LastRunAt == nil {
+		t.Errorf("one-off after run = %+v", once)
+	}
+	daily := getTestSchedule(t, sqlStore, "daily")
+	if want := now.Add(24 * time.Hour); daily.Status != store.SchedulePending || !daily.RunAt.Equal(want) {
+		t.Errorf("recurring after run: status %s, run_at %v; want pending at %v", daily.Status, daily.RunAt, want)
+	}
+
+	runDue(s)
+	if script.calls != 2 {
+		t.Errorf("sends = %d after second poll, want nothing new due", script.calls)
+	}
+}
+
+func TestScheduler_RetriesWithBackoffThenErrors(t *testing.T) {
+	s, script, sqlStore, now := newTestScheduler(t, sendResult{err: errors.New("agent unavailable")})
+	s.maxAttempts = 3
+	createTestSchedule(t, sqlStore, &store.ScheduledMessage{ID: "s1", AgentID: "offline", Sender: "ops", Content: "hi", RunAt: *now})
+
+	for attempt, wantDelay := range []time.Duration{30 * time.Second, time.Minute} {
+		runDue(s)
+		m := getTestSchedule(t, sqlStore, "s1")
+		if m.Status != store.SchedulePending || m.Attempts != attempt+1 || m.LastError != "agent unavailable" {
+			t.Fatalf("after attempt %d: %+v", attempt+1, m)
+		}
+		if want := now.Add(wantDelay); !m.RunAt.Equal(want) {
+			t.Fatalf("after attempt %d: run_at %v, want %v", attempt+1, m.RunAt, want)
+		}
+		runDue(s)
+		if script.calls != attempt+1 {
+			t.Fatalf("sends = %d, want retry to wait for its backoff", script.calls)
+		}
+		*now = m.RunAt
+	}
+
+	runDue(s)
+	if m := getTestSchedule(t, sqlStore, "s1"); m.Status != store.ScheduleErrored || m.Attempts != 3 {
+		t.Errorf("after max attempts: %+v", m)
+	}
+}
+
+func TestScheduler_AgentErrorCountsAsRun(t *testing.T) {
+	s, _, sqlStore, now := newTestScheduler(t, sendResult{threadID: "thread-1", err: errors.New("model overloaded")})
+	createTestSchedule(t, sqlStore, &store.ScheduledMessage{ID: "s1", AgentID: "a", Sender: "ops", Content: "hi", RunAt: *now})
+
+	runDue(s)
+	m := getTestSchedule(t, sqlStore, "s1")
+	if m.Status != store.ScheduleDone || m.Attempts != 0 || m.LastError != "model overloaded" {
+		t.Errorf("after agent error: %+v, want done without a retry", m)
+	}
+}
+
+func TestScheduler_DrainingLeavesPending(t *testing.T) {
+	s, _, sqlStore, now := newTestScheduler(t, sendResult{err: agent.ErrDraining})
+	createTestSchedule(t, sqlStore, &store.ScheduledMessage{ID: "s1", AgentID: "a", Sender: "ops", Content: "hi", RunAt: *now})
+
+	runDue(s)
+	m := getTestSchedule(t, sqlStore, "s1")
+	if m.Status != store.SchedulePending || m.Attempts != 0 || !m.RunAt.Equal(*now) || m.LastRunAt != nil {
+		t.Errorf("after draining: %+v, want it untouched for the next start", m)
+	}
+}
+
+func TestSendScheduled_GoesThroughConversation(t *testing.T) {
+	gw, sender, sqlStore := newAttachmentTestGateway(t, config.AttachmentsConfig{})
+	ctx := context.Background()
+	m := &store.ScheduledMessage{ID: "s1", AgentID: "test-agent", Sender: "ops", Content: "daily report", CreatedBy: "admin-1"}
+
+	threadID, err := gw.sendScheduled(ctx, m)
+	if err != nil || threadID == "" {
+		t.Fatalf("sendScheduled = %q, %v", threadID, err)
+	}
+	if len(sender.reqs) != 1 || sender.reqs[0].Content != "daily report" || sender.reqs[0].ThreadID != threadID {
+		t.Fatalf("agent got %+v", sender.reqs)
+	}
+	events, err := sqlStore.GetEventsByThreadID(ctx, threadID, 0)
+	if err != nil || len(events) == 0 || events[0].Text == nil || *events[0].Text != "daily report" {
+		t.Fatalf("thread events = %+v, %v", events, err)
+	}
+	if ok, _ := sqlStore.ThreadHasActor(ctx, threadID, "admin-1"); !ok {
+		t.Error("schedule creator not recorded as the message's actor")
+	}
+
+	// The next run continues the same thread.
+	m.LastThreadID = threadID
+	if again, err := gw.sendScheduled(ctx, m); err != nil || again != threadID {
+		t.Errorf("second run = %q, %v; want thread %q", again, err, threadID)
+	}
+
+	if id, err := gw.sendScheduled(ctx, &store.ScheduledMessage{AgentID: "offline", Sender: "ops", Content: "hi"}); id != "" || err == nil {
+		t.Errorf("offline agent = %q, %v; want an undelivered error", id, err)
+	}
+}
+
+func scheduleRequest(t *testing.T, gw *Gateway, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec := httptest.NewRecorder()
+	gw.handleSchedules(rec, httptest.NewRequest(method, path, &buf))
+	return rec
+}
+
+func TestScheduleHandlers(t *testing.T) {
+	gw := newTestGateway(t)
+
+	rec := scheduleRequest(t, gw, http.MethodPost, "/api/schedules", CreateScheduleRequest{
+		AgentID: "agent-1", Content: "standup", Recurrence: "@daily",
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var created ScheduleResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" || created.Status != "pending" || created.Sender != scheduleDefaultSender || created.RunAt == "" {
+		t.Errorf("created = %+v", created)
+	}
+
+	for name, body := range map[string]CreateScheduleRequest{
+		"no content":       {AgentID: "a", RunAt: "2030-01-01T00:00:00Z"},
+		"no target":        {Content: "x", RunAt: "2030-01-01T00:00:00Z"},
+		"both targets":     {AgentID: "a", Frontend: "slack", ChannelID: "C1", Content: "x", RunAt: "2030-01-01T00:00:00Z"},
+		"unbound channel":  {Frontend: "slack", ChannelID: "C1", Content: "x", RunAt: "2030-01-01T00:00:00Z"},
+		"no time":          {AgentID: "a", Content: "x"},
+		"bad run_at":       {AgentID: "a", Content: "x", RunAt: "tomorrow"},
+		"bad recurrence":   {AgentID: "a", Content: "x", Recurrence: "every day"},
+		"never recurrence": {AgentID: "a", Content: "x", Recurrence: "0 0 31 2 *"},
+	} {
+		if rec := scheduleRequest(t, gw, http.MethodPost, "/api/schedules", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+
+	rec = scheduleRequest(t, gw, http.MethodGet, "/api/schedules?status=pending", nil)
+	var list ListSchedulesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || len(list.Schedules) != 1 || list.Schedules[0].ID != created.ID {
+		t.Fatalf("list = %+v, %v", list, err)
+	}
+	if rec := scheduleRequest(t, gw, http.MethodGet, "/api/schedules?status=bogus", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad status filter = %d, want 400", rec.Code)
+	}
+	if rec := scheduleRequest(t, gw, http.MethodGet, "/api/schedules/"+created.ID, nil); rec.Code != http.StatusOK {
+		t.Errorf("get status = %d", rec.Code)
+	}
+
+	if rec := scheduleRequest(t, gw, http.MethodDelete, "/api/schedules/"+created.ID, nil); rec.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d", rec.Code)
+	}
+	if rec := scheduleRequest(t, gw, http.MethodGet, "/api/schedules/"+created.ID, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("get after delete = %d, want 404", rec.Code)
+	}
+	if rec := scheduleRequest(t, gw, http.MethodDelete, "/api/schedules/"+created.ID, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("second delete = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,110 @@
+// ABOUTME: GET /api/search: admin full-text search over conversation messages and ledger events
+// ABOUTME: Thin HTTP layer over Store.SearchMessages with thread, agent and time filters
+
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// SearchResultResponse is one match in a GET /api/search response.
+type SearchResultResponse struct {
+	EventID   string `json:"event_id"`
+	ThreadID  string `json:"thread_id,omitempty"`
+	AgentID   string `json:"agent_id"`
+	Author    string `json:"author"`
+	Direction string `json:"direction"`
+	Type      string `json:"type"`
+	Timestamp string `json:"timestamp"`
+	Snippet   string `json:"snippet"` // HTML-escaped, matches wrapped in <mark>
+}
+
+// SearchResponse is the JSON response for GET /api/search.
+type SearchResponse struct {
+	Query   string                 `json:"query"`
+	Results []SearchResultResponse `json:"results"`
+}
+
+// handleSearch handles GET /api/search?q=...&thread_id=&agent_id=&since=&until=&limit=&offset=.
+// Matches in conversations of agents the caller's token may not reach are left
+// out, so a page can hold fewer than limit results.
+func (g *Gateway) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	query := strings.TrimSpace(q.Get("q"))
+	if query == "" {
+		g.sendJSONError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	filter := store.SearchFilter{ThreadID: q.Get("thread_id"), AgentID: q.Get("agent_id")}
+	if filter.AgentID != "" && !g.tokenAllowsAgentID(r.Context(), filter.AgentID) {
+		g.sendJSONError(w, http.StatusForbidden, errTokenAgentDenied)
+		return
+	}
+	limit, errMsg := parseLimitParam(r, 50, 200)
+	if errMsg != "" {
+		g.sendJSONError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+	filter.Limit = limit
+	if v := q.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			g.sendJSONError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		filter.Offset = offset
+	}
+	var err error
+	if filter.Since, err = timeparse.Query(q, "since"); err != nil {
+		g.sendTimestampError(w, err)
+		return
+	}
+	if filter.Until, err = timeparse.Query(q, "until"); err != nil {
+		g.sendTimestampError(w, err)
+		return
+	}
+
+	results, err := g.store.SearchMessages(r.Context(), query, filter)
+	if errors.Is(err, store.ErrSearchUnavailable) {
+		g.sendJSONError(w, http.StatusNotImplemented, err.Error())
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to search messages", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response := SearchResponse{Query: query, Results: make([]SearchResultResponse, 0, len(results))}
+	for _, res := range results {
+		if !g.tokenAllowsAgentID(r.Context(), res.AgentID) {
+			continue
+		}
+		response.Results = append(response.Results, SearchResultResponse{
+			EventID:   res.EventID,
+			ThreadID:  res.ThreadID,
+			AgentID:   res.AgentID,
+			Author:    res.Author,
+			Direction: string(res.Direction),
+			Type:      string(res.Type),
+			Timestamp: timeparse.Format(res.Timestamp),
+			Snippet:   res.Snippet,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,76 @@
+// ABOUTME: Tests for GET /api/search.
+// ABOUTME: Covers filtered matches, token agent limits and parameter validation.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestHandleSearch(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx := context.Background()
+	for _, ev := range []struct{ id, agentID, text string }{
+		{"evt-1", "agent-a", "deploy the staging cluster"},
+		{"evt-2", "agent-b", "deploy production"},
+		{"evt-3", "agent-a", "lunch plans"},
+	} {
+		if err := gw.store.SaveEvent(ctx, &store.LedgerEvent{
+			ID: ev.id, ConversationKey: ev.agentID, Direction: store.EventDirectionInbound,
+			Author: "alice", Timestamp: time.Now(), Type: store.EventTypeMessage, Text: &ev.text,
+		}); err != nil {
+			t.Fatalf("SaveEvent: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	gw.handleSearch(rec, httptest.NewRequest(http.MethodGet, "/api/search?q=deploy&agent_id=agent-a", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", rec.Code, rec.Body.String())
+	}
+	var resp SearchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].EventID != "evt-1" {
+		t.Fatalf("results = %+v, want evt-1", resp.Results)
+	}
+	if got := resp.Results[0].Snippet; got != "<mark>deploy</mark> the staging cluster" {
+		t.Errorf("snippet = %q", got)
+	}
+
+	scoped := func(target string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		return req.WithContext(auth.WithAuth(req.Context(), &auth.AuthContext{PrincipalID: "boss", Roles: []string{"admin"}, AgentIDs: []string{"agent-a"}}))
+	}
+	rec = httptest.NewRecorder()
+	gw.handleSearch(rec, scoped("/api/search?q=deploy"))
+	resp = SearchResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].EventID != "evt-1" {
+		t.Errorf("token limited to agent-a: results = %+v, want only evt-1", resp.Results)
+	}
+	rec = httptest.NewRecorder()
+	gw.handleSearch(rec, scoped("/api/search?q=deploy&agent_id=agent-b"))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("token limited to agent-a searching agent-b: status %d, want 403", rec.Code)
+	}
+
+	for _, path := range []string{"/api/search", "/api/search?q=x&offset=-1", "/api/search?q=x&until=soon"} {
+		rec := httptest.NewRecorder()
+		gw.handleSearch(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", path, rec.Code)
+		}
+	}
+}
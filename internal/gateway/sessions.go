@@ -0,0 +1,266 @@
+// ABOUTME: Agent session resumption: issues session tokens at registration and, when an agent
+// ABOUTME: reconnects within the grace period, restores its session and in-flight requests.
+
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// agentSessions issues and restores agent sessions. A session outlives the
+// connection (and the gateway process): an agent that reconnects within the
+// grace period with its token gets the same session back, along with the
+// requests it had not finished.
+type agentSessions struct {
+	store *store.SQLStore
+	// grace is the reconnect grace period in nanoseconds; a config reload
+	// may change it.
+	grace  atomic.Int64
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu sync.Mutex
+	// live maps connected agents to their session.
+	live map[string]liveSession
+}
+
+type liveSession struct {
+	id string
+	// resumable is set for agents that advertised the resume feature; only
+	// their requests go in the in-flight ledger.
+	resumable bool
+}
+
+// defaultReconnectGrace applies when agents.reconnect_grace_period is unset.
+const defaultReconnectGrace = 5 * time.Minute
+
+func newAgentSessions(s *store.SQLStore, grace time.Duration, logger *slog.Logger) *agentSessions {
+	a := &agentSessions{
+		store:  s,
+		logger: logger,
+		now:    time.Now,
+		live:   make(map[string]liveSession),
+	}
+	a.setGrace(grace)
+	return a
+}
+
+// setGrace sets the reconnect grace period, or the default when grace is
+// not positive.
+func (a *agentSessions) setGrace(grace time.Duration) {
+	if grace <= 0 {
+		grace = defaultReconnectGrace
+	}
+	a.grace.Store(int64(grace))
+}
+
+func (a *agentSessions) gracePeriod() time.Duration {
+	return time.Duration(a.grace.Load())
+}
+
+// openedSession is the outcome of a registration's session handshake.
+type openedSession struct {
+	*store.AgentSession
+	token   string // new token to hand the agent
+	resumed bool
+	// inFlight holds requests to re-send, oldest first (resumed sessions only).
+	inFlight []*store.InFlightRequest
+}
+
+// open resumes the session named by token, or starts a new one. A token held
+// by another principal or agent is refused with PermissionDenied; an unknown
+// or expired token just gets a fresh session.
+func (a *agentSessions) open(ctx context.Context, token, agentID, principalID string, features []string) (*openedSession, error) {
+	now := a.now()
+	a.sweep(ctx, now)
+
+	if token != "" {
+		sess, err := a.store.GetAgentSessionByTokenHash(ctx, hashSessionToken(token))
+		switch {
+		case errors.Is(err, store.ErrAgentSessionNotFound):
+			a.logger.Info("unknown session token, starting a new session", "agent_id", agentID)
+		case err != nil:
+			return nil, status.Errorf(codes.Internal, "loading session: %v", err)
+		case sess.PrincipalID != principalID || sess.AgentID != agentID:
+			a.logger.Warn("rejected session token presented by another identity",
+				"agent_id", agentID,
+				"principal_id", principalID,
+				"session_agent_id", sess.AgentID,
+				"session_principal_id", sess.PrincipalID,
+			)
+			return nil, status.Error(codes.PermissionDenied, "session token was issued to a different agent")
+		case now.Sub(sess.LastSeenAt) > a.gracePeriod():
+			a.logger.Info("session expired, starting a new session", "agent_id", agentID, "last_seen", sess.LastSeenAt)
+			if err := a.store.DeleteAgentSession(ctx, sess.ID); err != nil {
+				a.logger.Warn("failed to delete expired session", "session_id", sess.ID, "error", err)
+			}
+		default:
+			return a.resume(ctx, sess, features, now)
+		}
+	}
+
+	newToken, err := newSessionToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "generating session token: %v", err)
+	}
+	sess := &store.AgentSession{
+		ID:               uuid.New().String(),
+		TokenHash:        hashSessionToken(newToken),
+		AgentID:          agentID,
+		PrincipalID:      principalID,
+		ProtocolFeatures: features,
+		CreatedAt:        now,
+		LastSeenAt:       now,
+	}
+	if err := a.store.CreateAgentSession(ctx, sess); err != nil {
+		return nil, status.Errorf(codes.Internal, "creating session: %v", err)
+	}
+	return &openedSession{AgentSession: sess, token: newToken}, nil
+}
+
+// resume rotates the session's token so the presented one can't be used
+// again, and loads what was still in flight.
+func (a *agentSessions) resume(ctx context.Context, sess *store.AgentSession, features []string, now time.Time) (*openedSession, error) {
+	newToken, err := newSessionToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "generating session token: %v", err)
+	}
+	if err := a.store.ResumeAgentSession(ctx, sess.ID, hashSessionToken(newToken), features, now); err != nil {
+		return nil, status.Errorf(codes.Internal, "resuming session: %v", err)
+	}
+	inFlight, err := a.store.ListInFlightRequests(ctx, sess.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "loading in-flight requests: %v", err)
+	}
+	sess.ProtocolFeatures = features
+	sess.LastSeenAt = now
+	a.logger.Info("resumed agent session",
+		"agent_id", sess.AgentID,
+		"session_id", sess.ID,
+		"in_flight", len(inFlight),
+	)
+	return &openedSession{AgentSession: sess, token: newToken, resumed: true, inFlight: inFlight}, nil
+}
+
+// attach marks the session's agent as connected.
+func (a *agentSessions) attach(agentID string, sess *openedSession) {
+	a.mu.Lock()
+	a.live[agentID] = liveSession{id: sess.ID, resumable: slices.Contains(sess.ProtocolFeatures, agent.FeatureResume)}
+	a.mu.Unlock()
+}
+
+// detach records when the agent went away; the grace period starts here.
+func (a *agentSessions) detach(ctx context.Context, agentID string, sess *openedSession) {
+	a.mu.Lock()
+	if a.live[agentID].id == sess.ID {
+		delete(a.live, agentID)
+	}
+	a.mu.Unlock()
+	a.touch(ctx, sess.ID)
+}
+
+// inGrace reports whether a disconnected agent held by principalID can still
+// resume its session.
+func (a *agentSessions) inGrace(ctx context.Context, principalID string) bool {
+	seen, err := a.store.LastAgentSessionSeen(ctx, principalID)
+	if err != nil {
+		if !errors.Is(err, store.ErrAgentSessionNotFound) {
+			a.logger.Warn("failed to look up agent session", "principal_id", principalID, "error", err)
+		}
+		return false
+	}
+	return a.now().Sub(seen) <= a.gracePeriod()
+}
+
+// touchAgent records that a connected agent is still around.
+func (a *agentSessions) touchAgent(ctx context.Context, agentID string) {
+	a.mu.Lock()
+	live, ok := a.live[agentID]
+	a.mu.Unlock()
+	if ok {
+		a.touch(ctx, live.id)
+	}
+}
+
+func (a *agentSessions) touch(ctx context.Context, sessionID string) {
+	if err := a.store.TouchAgentSession(ctx, sessionID, a.now()); err != nil {
+		a.logger.Warn("failed to touch agent session", "session_id", sessionID, "error", err)
+	}
+}
+
+// recordRequest adds a request to the in-flight ledger of the agent's session.
+// It is the agent manager's request observer.
+func (a *agentSessions) recordRequest(agentID, requestID string, req *agent.SendRequest) {
+	a.mu.Lock()
+	live := a.live[agentID]
+	a.mu.Unlock()
+	if !live.resumable {
+		return
+	}
+	err := a.store.RecordInFlightRequest(context.Background(), &store.InFlightRequest{
+		RequestID:      requestID,
+		SessionID:      live.id,
+		AgentID:        agentID,
+		ThreadID:       req.ThreadID,
+		Sender:         req.Sender,
+		Content:        req.Content,
+		Workspace:      req.Workspace,
+		ChannelContext: req.ChannelContext,
+		StartedAt:      a.now(),
+	})
+	if err != nil {
+		a.logger.Warn("failed to record in-flight request", "agent_id", agentID, "request_id", requestID, "error", err)
+	}
+}
+
+// complete moves a finished request from the in-flight ledger to the acked list.
+func (a *agentSessions) complete(ctx context.Context, requestID string) {
+	if err := a.store.CompleteInFlightRequest(ctx, requestID); err != nil {
+		a.logger.Warn("failed to complete in-flight request", "request_id", requestID, "error", err)
+	}
+}
+
+// sweep drops sessions whose agent has been gone longer than the grace period.
+func (a *agentSessions) sweep(ctx context.Context, now time.Time) {
+	n, err := a.store.DeleteAgentSessionsSeenBefore(ctx, now.Add(-a.gracePeriod()))
+	if err != nil {
+		a.logger.Warn("failed to sweep expired agent sessions", "error", err)
+		return
+	}
+	if n > 0 {
+		a.logger.Debug("swept expired agent sessions", "count", n)
+	}
+}
+
+// newSessionToken returns a random, URL-safe session token.
+func newSessionToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("reading random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashSessionToken is what the store keeps in place of the token.
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,328 @@
+// ABOUTME: Integration tests for agent session resumption across gateway restarts
+// ABOUTME: Restarts a gateway on the same database and reconnects agents with their tokens
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// sessionTestGateway is one run of a gateway process, serving agents as a
+// fixed principal.
+type sessionTestGateway struct {
+	gw       *Gateway
+	client   pb.CovenControlClient
+	server   *grpc.Server
+	stopOnce sync.Once
+}
+
+// startSessionGateway starts a gateway on cfg's database whose agent streams
+// are all authenticated as principalID.
+func startSessionGateway(t *testing.T, cfg *config.Config, principalID string) *sessionTestGateway {
+	t.Helper()
+
+	gw, err := New(cfg, testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	interceptor := func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := auth.WithAuth(ss.Context(), &auth.AuthContext{
+			PrincipalID:   principalID,
+			PrincipalType: string(store.PrincipalTypeAgent),
+		})
+		return handler(srv, &pendingAuthStream{ServerStream: ss, ctx: ctx})
+	}
+	server := grpc.NewServer(append(grpcMessageOptions(cfg), grpc.StreamInterceptor(interceptor))...)
+	pb.RegisterCovenControlServer(server, newCovenControlServer(gw, testLogger()))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = server.Serve(lis) }()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	g := &sessionTestGateway{gw: gw, client: pb.NewCovenControlClient(conn), server: server}
+	t.Cleanup(g.stop)
+	return g
+}
+
+// stop shuts the gateway down the way a restart would: agent streams end
+// (recording when each agent was last seen), then the store closes.
+func (g *sessionTestGateway) stop() {
+	g.stopOnce.Do(func() {
+		g.server.GracefulStop()
+		_, _ = g.gw.Shutdown(context.Background())
+	})
+}
+
+// sessionAgent is a connected agent stream and the Welcome it received.
+type sessionAgent struct {
+	stream  pb.CovenControl_AgentStreamClient
+	cancel  context.CancelFunc
+	welcome *pb.Welcome
+}
+
+// connect registers agentID, presenting token if set, and returns the
+// stream or the registration error.
+func (g *sessionTestGateway) connect(t *testing.T, agentID, token string, opts ...grpc.CallOption) (*sessionAgent, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	stream, err := g.client.AgentStream(ctx, opts...)
+	if err != nil {
+		cancel()
+		t.Fatalf("AgentStream: %v", err)
+	}
+	if err := stream.Send(&pb.AgentMessage{Payload: &pb.AgentMessage_Register{Register: &pb.RegisterAgent{
+		AgentId:          agentID,
+		Name:             agentID,
+		Capabilities:     []string{"chat"},
+		ProtocolFeatures: []string{agent.FeatureResume},
+		SessionToken:     token,
+	}}}); err != nil {
+		cancel()
+		t.Fatalf("Send registration: %v", err)
+	}
+	msg, err := stream.Recv()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if msg.GetWelcome() == nil {
+		cancel()
+		t.Fatalf("expected Welcome, got %T", msg.GetPayload())
+	}
+	return &sessionAgent{stream: stream, cancel: cancel, welcome: msg.GetWelcome()}, nil
+}
+
+// disconnect ends the stream from the agent's side.
+func (a *sessionAgent) disconnect() {
+	_ = a.stream.CloseSend()
+	a.cancel()
+}
+
+func (a *sessionAgent) recv(t *testing.T) *pb.ServerMessage {
+	t.Helper()
+	msg, err := a.stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	return msg
+}
+
+func (a *sessionAgent) respond(t *testing.T, resp *pb.MessageResponse) {
+	t.Helper()
+	if err := a.stream.Send(&pb.AgentMessage{Payload: &pb.AgentMessage_Response{Response: resp}}); err != nil {
+		t.Fatalf("Send response: %v", err)
+	}
+}
+
+// sessionTestConfig returns a config backed by a database file that
+// survives gateway restarts.
+func sessionTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := testConfig(t)
+	cfg.Database.Path = filepath.Join(t.TempDir(), "gateway.db")
+	return cfg
+}
+
+// sendUnanswered starts a request the agent never answers before the
+// gateway goes down, and returns its request ID and thread.
+func sendUnanswered(t *testing.T, g *sessionTestGateway, a *sessionAgent, agentID string) (string, string) {
+	t.Helper()
+	resp, err := g.gw.conversation.SendMessage(t.Context(), &conversation.SendRequest{
+		FrontendName: "test",
+		ExternalID:   "resume-thread",
+		AgentID:      agentID,
+		Sender:       "user",
+		Content:      "summarize the logs",
+	})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	go func() {
+		for range resp.Stream {
+		}
+	}()
+	sm := a.recv(t).GetSendMessage()
+	if sm == nil {
+		t.Fatal("expected SendMessage")
+	}
+	return sm.GetRequestId(), resp.ThreadID
+}
+
+func TestAgentSession_RestartWithResume(t *testing.T) {
+	cfg := sessionTestConfig(t)
+
+	first := startSessionGateway(t, cfg, "principal-1")
+	a, err := first.connect(t, "agent-1", "")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if a.welcome.GetSessionToken() == "" || a.welcome.GetResumed() {
+		t.Fatalf("first welcome = token %q resumed %v, want a new session", a.welcome.GetSessionToken(), a.welcome.GetResumed())
+	}
+	token := a.welcome.GetSessionToken()
+
+	// One request finishes before the restart, one is still running.
+	respCh, err := first.gw.agentManager.SendMessage(t.Context(), &agent.SendRequest{AgentID: "agent-1", Content: "quick"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	quickID := a.recv(t).GetSendMessage().GetRequestId()
+	a.respond(t, &pb.MessageResponse{RequestId: quickID, Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "ok"}}})
+	for range respCh {
+	}
+	slowID, threadID := sendUnanswered(t, first, a, "agent-1")
+
+	a.disconnect()
+	first.stop()
+
+	second := startSessionGateway(t, cfg, "principal-1")
+	b, err := second.connect(t, "agent-1", token)
+	if err != nil {
+		t.Fatalf("reconnect: %v", err)
+	}
+	if !b.welcome.GetResumed() {
+		t.Fatal("welcome.resumed = false, want the session restored")
+	}
+	if b.welcome.GetSessionToken() == "" || b.welcome.GetSessionToken() == token {
+		t.Error("resumed session should hand out a fresh token")
+	}
+	if acked := b.welcome.GetAckedRequestIds(); len(acked) != 1 || acked[0] != quickID {
+		t.Errorf("acked = %v, want [%s]", acked, quickID)
+	}
+
+	resume := b.recv(t).GetResumeRequest()
+	if resume == nil || resume.GetRequestId() != slowID || resume.GetThreadId() != threadID || resume.GetContent() != "summarize the logs" {
+		t.Fatalf("resume = %+v, want request %s on thread %s", resume, slowID, threadID)
+	}
+	b.respond(t, &pb.MessageResponse{RequestId: slowID, Event: &pb.MessageResponse_Text{Text: "all quiet"}})
+	b.respond(t, &pb.MessageResponse{RequestId: slowID, Event: &pb.MessageResponse_Done{Done: &pb.Done{FullResponse: "all quiet"}}})
+
+	// The resumed reply lands in the thread, and the ledger forgets the request.
+	sqlStore := second.gw.store.(*store.SQLStore)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		events, err := sqlStore.GetEventsByThreadID(context.Background(), threadID, 50)
+		if err != nil {
+			t.Fatalf("GetEventsByThreadID: %v", err)
+		}
+		if slices.ContainsFunc(events, func(e *store.LedgerEvent) bool {
+			return e.Author == "agent:agent-1" && e.Text != nil && *e.Text == "all quiet"
+		}) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("resumed reply never persisted; thread has %d events", len(events))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	sess, err := sqlStore.GetAgentSessionByTokenHash(context.Background(), hashSessionToken(b.welcome.GetSessionToken()))
+	if err != nil {
+		t.Fatalf("GetAgentSessionByTokenHash: %v", err)
+	}
+	if inflight, _ := sqlStore.ListInFlightRequests(context.Background(), sess.ID); len(inflight) != 0 {
+		t.Errorf("in flight after completion = %d, want 0", len(inflight))
+	}
+}
+
+func TestAgentSession_RestartPastGrace(t *testing.T) {
+	cfg := sessionTestConfig(t)
+
+	first := startSessionGateway(t, cfg, "principal-1")
+	a, err := first.connect(t, "agent-1", "")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	token := a.welcome.GetSessionToken()
+	sendUnanswered(t, first, a, "agent-1")
+	a.disconnect()
+	first.stop()
+
+	second := startSessionGateway(t, cfg, "principal-1")
+	second.gw.sessions.now = func() time.Time { return time.Now().Add(cfg.Agents.ReconnectGracePeriod + time.Minute) }
+	b, err := second.connect(t, "agent-1", token)
+	if err != nil {
+		t.Fatalf("reconnect: %v", err)
+	}
+	if b.welcome.GetResumed() || len(b.welcome.GetAckedRequestIds()) != 0 {
+		t.Fatalf("welcome = resumed %v acked %v, want a fresh session", b.welcome.GetResumed(), b.welcome.GetAckedRequestIds())
+	}
+
+	// Nothing is re-sent: the next message the agent sees is a new request.
+	respCh, err := second.gw.agentManager.SendMessage(t.Context(), &agent.SendRequest{AgentID: "agent-1", Content: "next"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if msg := b.recv(t); msg.GetSendMessage() == nil {
+		t.Fatalf("expected the new SendMessage, got %T", msg.GetPayload())
+	}
+	go func() {
+		for range respCh {
+		}
+	}()
+
+	sqlStore := second.gw.store.(*store.SQLStore)
+	if _, err := sqlStore.GetAgentSessionByTokenHash(context.Background(), hashSessionToken(token)); !errors.Is(err, store.ErrAgentSessionNotFound) {
+		t.Errorf("expired session lookup err = %v, want ErrAgentSessionNotFound", err)
+	}
+}
+
+func TestAgentSession_TokenReplayFromOtherPrincipal(t *testing.T) {
+	cfg := sessionTestConfig(t)
+
+	first := startSessionGateway(t, cfg, "principal-1")
+	a, err := first.connect(t, "agent-1", "")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	token := a.welcome.GetSessionToken()
+	a.disconnect()
+	first.stop()
+
+	// Another principal presenting the stolen token is turned away.
+	thief := startSessionGateway(t, cfg, "principal-2")
+	if _, err := thief.connect(t, "agent-1", token); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("replay err = %v, want PermissionDenied", err)
+	}
+	if thief.gw.agentManager.IsOnline("agent-1") {
+		t.Error("rejected agent was left registered")
+	}
+	thief.stop()
+
+	// The rightful owner can still resume.
+	owner := startSessionGateway(t, cfg, "principal-1")
+	b, err := owner.connect(t, "agent-1", token)
+	if err != nil {
+		t.Fatalf("owner reconnect: %v", err)
+	}
+	if !b.welcome.GetResumed() {
+		t.Error("owner's session was not resumed after the rejected replay")
+	}
+}
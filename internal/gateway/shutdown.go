@@ -0,0 +1,322 @@
+// ABOUTME: Bounded gateway shutdown: phased, concurrent component teardown with
+// ABOUTME: per-phase deadlines, time reserved past the agent drain, forced closes, and a report.
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultShutdownTimeout bounds Shutdown when its context has no deadline.
+const DefaultShutdownTimeout = 5 * time.Second
+
+// DefaultDrainTimeout bounds how long Shutdown lets in-flight agent responses
+// finish when agents.drain_timeout is not set.
+const DefaultDrainTimeout = 10 * time.Second
+
+// minPhaseBudget is the least time a phase gets even when earlier phases
+// used up the overall deadline, so the store always gets a chance to close.
+const minPhaseBudget = 50 * time.Millisecond
+
+// ComponentShutdown records how one component stopped.
+type ComponentShutdown struct {
+	Name     string
+	Phase    string
+	Duration time.Duration
+	// Forced is true when the component missed its deadline and was
+	// force-closed (or, with no way to force it, abandoned).
+	Forced bool
+	Err    error
+}
+
+// ShutdownReport is returned by Shutdown and logged when it finishes.
+type ShutdownReport struct {
+	Duration   time.Duration
+	Components []ComponentShutdown
+}
+
+// Forced returns the names of components that missed their deadline.
+func (r *ShutdownReport) Forced() []string {
+	var names []string
+	for _, c := range r.Components {
+		if c.Forced {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// Err joins the component errors, counting a forced close as an error.
+func (r *ShutdownReport) Err() error {
+	var errs []error
+	for _, c := range r.Components {
+		switch {
+		case c.Err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name, c.Err))
+		case c.Forced:
+			errs = append(errs, fmt.Errorf("%s: forced close after %s", c.Name, c.Duration.Round(time.Millisecond)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// log writes one line per component and a summary.
+func (r *ShutdownReport) log(logger *slog.Logger) {
+	for _, c := range r.Components {
+		attrs := []any{"component", c.Name, "phase", c.Phase, "duration", c.Duration.Round(time.Millisecond), "forced", c.Forced}
+		switch {
+		case c.Err != nil:
+			logger.Warn("component shutdown failed", append(attrs, "error", c.Err)...)
+		case c.Forced:
+			logger.Warn("component missed shutdown deadline", attrs...)
+		default:
+			logger.Debug("component shut down", attrs...)
+		}
+	}
+	logger.Info("shutdown complete",
+		"duration", r.Duration.Round(time.Millisecond),
+		"components", len(r.Components),
+		"forced", r.Forced(),
+	)
+}
+
+// shutdownStep stops one component. stop should return once the component
+// is down or ctx ends; force, if set, tears it down when stop runs late.
+type shutdownStep struct {
+	name  string
+	stop  func(ctx context.Context) error
+	force func()
+}
+
+// shutdownPhase is a set of steps that may stop concurrently. Phases run in
+// order, each getting a share of the remaining time in proportion to weight.
+// A phase with a limit instead gets up to limit, less laterPhaseReserve.
+type shutdownPhase struct {
+	name   string
+	weight int
+	limit  time.Duration
+	steps  []shutdownStep
+}
+
+// laterPhaseReserve is the time a limited phase leaves to the phases after
+// it: a third of what remains, at most DefaultShutdownTimeout. A long drain
+// thus cannot starve persisting and closing the store.
+func laterPhaseReserve(remaining time.Duration) time.Duration {
+	return min(remaining/3, DefaultShutdownTimeout)
+}
+
+// closer adapts a Close method without a context or error to a step.
+func closer(name string, closeFn func()) shutdownStep {
+	return shutdownStep{name: name, stop: func(context.Context) error {
+		closeFn()
+		return nil
+	}}
+}
+
+// runShutdown runs the phases in order within ctx's deadline (or
+// DefaultShutdownTimeout). A step that hangs is force-closed at its phase's
+// deadline and left behind, so later phases still run.
+func runShutdown(ctx context.Context, phases []shutdownPhase) *ShutdownReport {
+	start := time.Now()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = start.Add(DefaultShutdownTimeout)
+	}
+	// Components get their own deadlines; a canceled parent must not cut
+	// them short before they have had a chance to stop.
+	base := context.WithoutCancel(ctx)
+
+	remainingWeight := 0
+	for _, p := range phases {
+		remainingWeight += p.weight
+	}
+
+	report := &ShutdownReport{}
+	for i, p := range phases {
+		remaining := time.Until(deadline)
+		budget := remaining
+		last := i == len(phases)-1
+		switch {
+		case p.limit > 0 && last:
+			budget = min(p.limit, remaining)
+		case p.limit > 0:
+			budget = min(p.limit, remaining-laterPhaseReserve(remaining))
+		case !last && remainingWeight > 0:
+			budget = remaining * time.Duration(p.weight) / time.Duration(remainingWeight)
+		}
+		remainingWeight -= p.weight
+		budget = max(budget, minPhaseBudget)
+
+		phaseCtx, cancel := context.WithTimeout(base, budget)
+		report.Components = append(report.Components, runPhase(phaseCtx, p)...)
+		cancel()
+	}
+	report.Duration = time.Since(start)
+	return report
+}
+
+// runPhase stops a phase's steps concurrently and waits for each to finish
+// or miss the phase deadline.
+func runPhase(ctx context.Context, p shutdownPhase) []ComponentShutdown {
+	results := make([]ComponentShutdown, len(p.steps))
+	var wg sync.WaitGroup
+	for i, step := range p.steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runStep(ctx, p.name, step)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func runStep(ctx context.Context, phase string, step shutdownStep) ComponentShutdown {
+	start := time.Now()
+	res := ComponentShutdown{Name: step.name, Phase: phase}
+
+	done := make(chan error, 1)
+	go func() { done <- step.stop(ctx) }()
+
+	select {
+	case err := <-done:
+		// Stops that honor ctx (like http.Server.Shutdown) report the
+		// deadline themselves; that is a miss, not a failure.
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil {
+			res.Forced = true
+		} else {
+			res.Err = err
+		}
+	case <-ctx.Done():
+		res.Forced = true
+	}
+	if res.Forced && step.force != nil {
+		step.force()
+	}
+	res.Duration = time.Since(start)
+	return res
+}
+
+// drainTimeout returns agents.drain_timeout, or DefaultDrainTimeout.
+func (g *Gateway) drainTimeout() time.Duration {
+	if g.config != nil && g.config.Agents.DrainTimeout > 0 {
+		return g.config.Agents.DrainTimeout
+	}
+	return DefaultDrainTimeout
+}
+
+// shutdownPhases lists the gateway's components in teardown order: drain
+// in-flight agent responses, stop accepting connections, drain in-flight
+// work, let background jobs persist what they hold, and close the store last.
+//
+// The agent drain runs before any listener closes and refuses new sends.
+// Responses still running at its deadline, agents.drain_timeout or less when
+// the shutdown deadline is short, are canceled, so their SSE streams end with
+// a canceled event instead of being cut off mid-stream.
+func (g *Gateway) shutdownPhases() []shutdownPhase {
+	drainAgents := shutdownPhase{name: "drain-agents", limit: g.drainTimeout(), steps: []shutdownStep{
+		{name: "agents", stop: g.agentManager.Drain},
+	}}
+
+	accept := shutdownPhase{name: "accept", weight: 4}
+	accept.steps = append(accept.steps,
+		shutdownStep{
+			name: "http",
+			stop: g.httpServer.Shutdown,
+			// SSE connections won't close gracefully within the deadline.
+			force: func() { _ = g.httpServer.Close() },
+		},
+		shutdownStep{
+			// Agent streams end with the gRPC server, which unregisters
+			// them from the agent manager.
+			name: "grpc",
+			stop: func(context.Context) error {
+				g.grpcServer.GracefulStop()
+				return nil
+			},
+			force: g.grpcServer.Stop,
+		},
+	)
+
+	if g.adminHTTPServer != nil {
+		accept.steps = append(accept.steps, shutdownStep{
+			name:  "admin-http",
+			stop:  g.adminHTTPServer.Shutdown,
+			force: func() { _ = g.adminHTTPServer.Close() },
+		})
+	}
+	if g.metricsHTTPServer != nil {
+		accept.steps = append(accept.steps, shutdownStep{
+			name:  "metrics-http",
+			stop:  g.metricsHTTPServer.Shutdown,
+			force: func() { _ = g.metricsHTTPServer.Close() },
+		})
+	}
+	if g.email != nil {
+		accept.steps = append(accept.steps, closer("email", g.email.Stop))
+	}
+	if g.scheduler != nil {
+		// Runs already sent have finished draining; no new ones start.
+		accept.steps = append(accept.steps, closer("scheduler", g.scheduler.Close))
+	}
+
+	drain := shutdownPhase{name: "drain", weight: 3}
+	if g.tsnetServer != nil {
+		drain.steps = append(drain.steps, shutdownStep{
+			name: "tailscale",
+			stop: func(context.Context) error { return g.tsnetServer.Close() },
+		})
+	}
+	if g.packRouter != nil {
+		drain.steps = append(drain.steps, closer("pack-router", g.packRouter.Close))
+	}
+	if g.email != nil {
+		// Replies still being written go out before the store closes.
+		drain.steps = append(drain.steps, closer("email-replies", g.email.Close))
+	}
+	if g.mcpBridge != nil {
+		drain.steps = append(drain.steps, closer("mcp-bridge", g.mcpBridge.Close))
+	}
+	if g.mcpServer != nil {
+		drain.steps = append(drain.steps, closer("mcp-server", g.mcpServer.Close))
+	}
+	if g.webAdmin != nil {
+		drain.steps = append(drain.steps, closer("webadmin", g.webAdmin.Close))
+	}
+	if g.eventBroadcaster != nil {
+		drain.steps = append(drain.steps, closer("event-broadcaster", g.eventBroadcaster.Close))
+	}
+	if g.dedupe != nil {
+		drain.steps = append(drain.steps, closer("dedupe", g.dedupe.Close))
+	}
+	if g.idempotency != nil {
+		drain.steps = append(drain.steps, closer("idempotency", g.idempotency.Close))
+	}
+	if g.packRegistry != nil {
+		drain.steps = append(drain.steps, closer("pack-registry", g.packRegistry.Close))
+	}
+
+	persist := shutdownPhase{name: "persist", weight: 1}
+	if g.deliveries != nil {
+		persist.steps = append(persist.steps, closer("deliveries", g.deliveries.Close))
+	}
+	if g.storage != nil {
+		persist.steps = append(persist.steps, closer("storage-monitor", g.storage.Close))
+	}
+	if g.stopTracing != nil {
+		// Spans of the drained sends are still in the export queue.
+		persist.steps = append(persist.steps, shutdownStep{name: "tracing", stop: g.stopTracing})
+	}
+
+	closeStore := shutdownPhase{name: "store", weight: 2, steps: []shutdownStep{{
+		name: "store",
+		stop: func(context.Context) error { return g.store.Close() },
+	}}}
+
+	return []shutdownPhase{drainAgents, accept, drain, persist, closeStore}
+}
@@ -0,0 +1,251 @@
+// ABOUTME: Tests for bounded shutdown: hanging components are force-closed
+// ABOUTME: within the deadline, agents drain first without starving later phases, and the store closes cleanly last.
+
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+)
+
+// hangingStep blocks until forced (or the test ends, if it has no force).
+func hangingStep(t *testing.T, name string, withForce bool) (shutdownStep, *atomic.Bool) {
+	t.Helper()
+	release := make(chan struct{})
+	forced := &atomic.Bool{}
+	var closed atomic.Bool
+	closeRelease := func() {
+		if closed.CompareAndSwap(false, true) {
+			close(release)
+		}
+	}
+	t.Cleanup(closeRelease)
+
+	step := shutdownStep{name: name, stop: func(context.Context) error {
+		<-release
+		return nil
+	}}
+	if withForce {
+		step.force = func() {
+			forced.Store(true)
+			closeRelease()
+		}
+	}
+	return step, forced
+}
+
+func TestRunShutdown_HangingComponentsStayBounded(t *testing.T) {
+	httpStep, httpForced := hangingStep(t, "http", true)
+	routerStep, _ := hangingStep(t, "pack-router", false)
+
+	var storeClosed atomic.Bool
+	phases := []shutdownPhase{
+		{name: "accept", weight: 4, steps: []shutdownStep{httpStep, closer("grpc", func() {})}},
+		{name: "drain", weight: 3, steps: []shutdownStep{routerStep}},
+		{name: "persist", weight: 1},
+		{name: "store", weight: 2, steps: []shutdownStep{closer("store", func() { storeClosed.Store(true) })}},
+	}
+
+	const bound = 400 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), bound)
+	defer cancel()
+
+	start := time.Now()
+	report := runShutdown(ctx, phases)
+	elapsed := time.Since(start)
+
+	// Every phase may round up to minPhaseBudget; nothing waits longer.
+	if limit := bound + 4*minPhaseBudget; elapsed > limit {
+		t.Errorf("shutdown took %s, want at most %s", elapsed, limit)
+	}
+	if !httpForced.Load() {
+		t.Error("hanging http step was not force-closed")
+	}
+	if got := report.Forced(); !slices.Equal(got, []string{"http", "pack-router"}) {
+		t.Errorf("forced = %v, want http and pack-router", got)
+	}
+	if !storeClosed.Load() {
+		t.Fatal("store was not closed")
+	}
+	last := report.Components[len(report.Components)-1]
+	if last.Name != "store" || last.Forced || last.Err != nil {
+		t.Errorf("store result = %+v, want a clean close", last)
+	}
+	if report.Err() == nil {
+		t.Error("report with forced components has no error")
+	}
+}
+
+func TestRunShutdown_LimitedPhaseLeavesTimeForLaterPhases(t *testing.T) {
+	drainStep, _ := hangingStep(t, "agents", false)
+	var storeBudget time.Duration
+	phases := []shutdownPhase{
+		{name: "drain-agents", limit: time.Minute, steps: []shutdownStep{drainStep}},
+		{name: "persist", weight: 1, steps: []shutdownStep{closer("deliveries", func() {})}},
+		{name: "store", weight: 2, steps: []shutdownStep{{name: "store", stop: func(ctx context.Context) error {
+			deadline, _ := ctx.Deadline()
+			storeBudget = time.Until(deadline)
+			return nil
+		}}}},
+	}
+
+	const bound = 600 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), bound)
+	defer cancel()
+	report := runShutdown(ctx, phases)
+
+	drain := report.Components[0]
+	if !drain.Forced || drain.Duration > bound*3/4 {
+		t.Errorf("drain = %+v, want it forced after about two thirds of %s", drain, bound)
+	}
+	// The drain leaves a third of the deadline; persist returns at once.
+	if want := bound / 4; storeBudget < want {
+		t.Errorf("store got %s, want at least %s after a drain that never finishes", storeBudget, want)
+	}
+}
+
+func TestRunShutdown_PhasesOrderedStepsConcurrent(t *testing.T) {
+	var firstDone atomic.Int32
+	slow := func(name string) shutdownStep {
+		return closer(name, func() {
+			time.Sleep(100 * time.Millisecond)
+			firstDone.Add(1)
+		})
+	}
+	var sawFirst int32
+	phases := []shutdownPhase{
+		{name: "accept", weight: 1, steps: []shutdownStep{slow("a"), slow("b"), slow("c")}},
+		{name: "store", weight: 1, steps: []shutdownStep{closer("store", func() { sawFirst = firstDone.Load() })}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report := runShutdown(ctx, phases)
+
+	if sawFirst != 3 {
+		t.Errorf("store closed after %d of 3 first-phase steps, want all", sawFirst)
+	}
+	if report.Duration >= 250*time.Millisecond {
+		t.Errorf("duration = %s, want the first phase's steps to run concurrently", report.Duration)
+	}
+	if err := report.Err(); err != nil {
+		t.Errorf("clean shutdown reported %v", err)
+	}
+}
+
+func TestGatewayShutdown_StuckHTTPClientIsForced(t *testing.T) {
+	gw, err := New(testConfig(t), testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	grpcLn, httpLn, err := gw.setupListeners(context.Background())
+	if err != nil {
+		t.Fatalf("setupListeners: %v", err)
+	}
+	gw.startServers(grpcLn, httpLn, nil, nil)
+
+	// A client that stalls halfway through its request headers keeps the
+	// HTTP server from shutting down gracefully.
+	conn, err := net.Dial("tcp", httpLn.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("GET /health HTTP/1.1\r\nHost: gateway\r\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	const bound = 500 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), bound)
+	defer cancel()
+	start := time.Now()
+	report, err := gw.Shutdown(ctx)
+	if elapsed := time.Since(start); elapsed > bound+4*minPhaseBudget {
+		t.Errorf("Shutdown took %s, want about %s", elapsed, bound)
+	}
+	if err == nil {
+		t.Error("Shutdown() error = nil, want the forced http close reported")
+	}
+	if got := report.Forced(); !slices.Equal(got, []string{"http"}) {
+		t.Errorf("forced = %v, want only http", got)
+	}
+	for _, c := range report.Components {
+		if c.Name == "store" && (c.Forced || c.Err != nil || c.Phase != "store") {
+			t.Errorf("store = %+v, want a clean close in the last phase", c)
+		}
+	}
+}
+
+func TestGatewayShutdown_DrainsAgentThatNeverFinishes(t *testing.T) {
+	gw := newTestGateway(t)
+	gw.config.Agents.DrainTimeout = 100 * time.Millisecond
+	conn := agent.NewConnection(agent.ConnectionParams{ID: "agent-1", Name: "Stuck", Stream: &testMockStream{}, Logger: testLogger()})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	send := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/send", bytes.NewReader([]byte(`{"sender":"u","content":"hi","agent_id":"agent-1"}`)))
+		gw.handleSendMessage(rec, req)
+		return rec
+	}
+
+	// The agent accepts the request and never sends Done.
+	streamed := make(chan *httptest.ResponseRecorder, 1)
+	go func() { streamed <- send() }()
+	waitFor(t, func() bool { return gw.agentManager.InFlight("agent-1") == 1 })
+
+	type result struct {
+		report *ShutdownReport
+		err    error
+	}
+	shutdown := make(chan result, 1)
+	go func() {
+		report, err := gw.Shutdown(context.Background())
+		shutdown <- result{report, err}
+	}()
+	waitFor(t, gw.agentManager.Draining)
+
+	refused := send()
+	if refused.Code != http.StatusServiceUnavailable || refused.Header().Get("Retry-After") == "" {
+		t.Errorf("send while draining = %d (Retry-After %q), want 503 with Retry-After", refused.Code, refused.Header().Get("Retry-After"))
+	}
+
+	rec := <-streamed
+	body := rec.Body.String()
+	if !strings.Contains(body, "event: canceled") || !strings.Contains(body, agent.DrainReason) {
+		t.Errorf("stream = %q, want a canceled event with reason %s", body, agent.DrainReason)
+	}
+
+	res := <-shutdown
+	if len(res.report.Components) == 0 || res.report.Components[0].Name != "agents" || !res.report.Components[0].Forced {
+		t.Errorf("first component = %+v, want the agents drain forced at its deadline", res.report.Components)
+	}
+	if res.err == nil {
+		t.Error("Shutdown() error = nil, want the canceled drain reported")
+	}
+}
+
+// waitFor polls cond for up to a second.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,176 @@
+// ABOUTME: Golden-file contract tests for the /api/send SSE wire format.
+// ABOUTME: Run `go test ./internal/gateway -run TestSSEGolden -update` to rewrite testdata/sse after a deliberate change.
+
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite SSE golden files in testdata/sse")
+
+// scriptedSender is a fake agent that replays a fixed response script for
+// every message.
+type scriptedSender struct {
+	script []*agent.Response
+}
+
+func (s *scriptedSender) SendMessage(ctx context.Context, req *agent.SendRequest) (<-chan *agent.Response, error) {
+	ch := make(chan *agent.Response, len(s.script))
+	for _, resp := range s.script {
+		ch <- resp
+	}
+	close(ch)
+	return ch, nil
+}
+
+// sseVolatile matches values that differ between runs: generated UUIDs and
+// RFC 3339 timestamps.
+var sseVolatile = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`), "<timestamp>"},
+}
+
+func normalizeSSE(b []byte) []byte {
+	for _, v := range sseVolatile {
+		b = v.re.ReplaceAll(b, []byte(v.repl))
+	}
+	return b
+}
+
+// sseGoldenScenarios are representative /api/send exchanges. Each is written
+// to testdata/sse/<name>.golden, which doubles as wire format reference for
+// client authors.
+var sseGoldenScenarios = []struct {
+	name   string
+	script []*agent.Response
+}{
+	{"text_response", []*agent.Response{
+		{Event: agent.EventSessionInit, SessionID: "session-1"},
+		{Event: agent.EventThinking, Text: "Considering the greeting."},
+		{Event: agent.EventText, Text: "Hello"},
+		{Event: agent.EventText, Text: ", world!"},
+		{Event: agent.EventDone, Text: "Hello, world!", Done: true},
+	}},
+	{"tool_use_with_approval", []*agent.Response{
+		{Event: agent.EventToolUse, ToolUse: &agent.ToolUseEvent{ID: "tool-1", Name: "bash", InputJSON: `{"command":"ls"}`}},
+		{Event: agent.EventToolState, ToolState: &agent.ToolStateEvent{ID: "tool-1", State: "awaiting_approval"}},
+		{Event: agent.EventToolApprovalRequest, ToolApprovalRequest: &agent.ToolApprovalRequestEvent{
+			ID: "tool-1", Name: "bash", InputJSON: `{"command":"ls"}`, RequestID: "request-1",
+		}},
+		{Event: agent.EventToolState, ToolState: &agent.ToolStateEvent{ID: "tool-1", State: "running"}},
+		{Event: agent.EventToolResult, ToolResult: &agent.ToolResultEvent{ID: "tool-1", Output: "README.md\n"}},
+		{Event: agent.EventToolState, ToolState: &agent.ToolStateEvent{ID: "tool-1", State: "completed"}},
+		{Event: agent.EventText, Text: "The directory has a README."},
+		{Event: agent.EventDone, Text: "The directory has a README.", Done: true},
+	}},
+	{"error", []*agent.Response{
+		{Event: agent.EventText, Text: "Working on it"},
+		{Event: agent.EventError, Error: "model overloaded", Done: true},
+	}},
+	{"agent_timeout", []*agent.Response{
+		{Event: agent.EventThinking, Text: "Looking into it."},
+		{Event: agent.EventError, Error: "agent sent nothing for 2m0s", Code: agent.ErrorCodeAgentTimeout, Done: true},
+	}},
+	{"cancellation", []*agent.Response{
+		{Event: agent.EventText, Text: "Partial answer"},
+		{Event: agent.EventCanceled, Error: "user requested", Done: true},
+	}},
+	{"usage", []*agent.Response{
+		{Event: agent.EventText, Text: "Done."},
+		{Event: agent.EventUsage, Usage: &agent.UsageEvent{
+			InputTokens: 120, OutputTokens: 8, CacheReadTokens: 100, CacheWriteTokens: 20, ThinkingTokens: 4,
+		}},
+		{Event: agent.EventDone, Text: "Done.", Done: true},
+	}},
+	{"citations", []*agent.Response{
+		{Event: agent.EventCitation, Citation: &agent.CitationEvent{Index: 1, Title: "Go spec", URI: "https://go.dev/ref/spec"}},
+		{Event: agent.EventText, Text: "Slices share arrays [1]"},
+		{Event: agent.EventCitation, Citation: &agent.CitationEvent{
+			Index: 2, Title: "Go blog", URI: "https://go.dev/blog/slices-intro", Snippet: "A slice is a descriptor of an array segment.",
+		}},
+		{Event: agent.EventText, Text: " and grow with append [2]."},
+		{Event: agent.EventCitation, Citation: &agent.CitationEvent{Index: 3, URI: "https://go.dev/doc/effective_go"}},
+		{Event: agent.EventDone, Text: "Slices share arrays [1] and grow with append [2].", Done: true},
+	}},
+	{"question_round_trip", []*agent.Response{
+		{Event: agent.EventToolUse, ToolUse: &agent.ToolUseEvent{
+			ID: "tool-q", Name: "ask_user", InputJSON: `{"question":"Which branch?","options":[{"label":"main"},{"label":"dev"}]}`,
+		}},
+		{Event: agent.EventToolState, ToolState: &agent.ToolStateEvent{ID: "tool-q", State: "running", Detail: "waiting for user"}},
+		{Event: agent.EventToolResult, ToolResult: &agent.ToolResultEvent{ID: "tool-q", Output: `{"selected":["main"]}`}},
+		{Event: agent.EventText, Text: "Deploying main."},
+		{Event: agent.EventDone, Text: "Deploying main.", Done: true},
+	}},
+}
+
+func TestSSEGolden(t *testing.T) {
+	for _, sc := range sseGoldenScenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			gw := newTestGateway(t)
+			conn := agent.NewConnection(agent.ConnectionParams{
+				ID: "test-agent", Name: "Test", PrincipalID: "test-agent",
+				Stream: &testMockStream{}, Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			})
+			if err := gw.agentManager.Register(conn); err != nil {
+				t.Fatalf("registering agent: %v", err)
+			}
+			sqlStore, ok := gw.store.(*store.SQLStore)
+			if !ok {
+				t.Fatal("store is not *SQLStore")
+			}
+			gw.conversation = conversation.New(sqlStore, &scriptedSender{script: sc.script}, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+
+			body, _ := json.Marshal(SendMessageRequest{Sender: "golden-user", Content: "hello", AgentID: "test-agent"})
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req := httptest.NewRequest(http.MethodPost, "/api/send", bytes.NewReader(body)).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			gw.handleSendMessage(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+			got := normalizeSSE(rec.Body.Bytes())
+			path := filepath.Join("testdata", "sse", sc.name+".golden")
+
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatalf("creating testdata: %v", err)
+				}
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatalf("writing golden: %v", err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading golden (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("SSE wire format changed for %s.\n--- want\n%s\n--- got\n%s\n"+
+					"If the change is deliberate, rerun with -update and commit the golden file.", sc.name, want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// ABOUTME: Wires the disk space and database growth monitor into the gateway.
+// ABOUTME: Guards sends while storage is below its floor and maps ErrStorageFull to HTTP 507.
+
+package gateway
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/2389/coven-gateway/internal/config"
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/diskmon"
+)
+
+// StorageFullResponse is the 507 body returned when a send is refused
+// because free disk space is below the configured floor.
+type StorageFullResponse struct {
+	Error string      `json:"error"`
+	Code  coverr.Code `json:"code"`
+}
+
+// newStorageMonitor builds the storage monitor for the database at dbPath,
+// or returns nil when monitoring is disabled or the database is in memory.
+func newStorageMonitor(cfg config.StorageMonitorConfig, dbPath string, logger *slog.Logger) *diskmon.Monitor {
+	if cfg.Disabled || dbPath == ":memory:" {
+		return nil
+	}
+	mcfg := diskmon.Config{
+		Interval:        cfg.Interval,
+		WarnFreePercent: cfg.WarnFreePercent,
+		WarnDBSize:      cfg.WarnDBSizeMB << 20,
+		MinFree:         cfg.MinFreeMB << 20,
+	}
+	if cfg.WebhookURL != "" {
+		mcfg.Notify = diskmon.WebhookNotifier(cfg.WebhookURL, nil, logger)
+	}
+	return diskmon.New(dbPath, mcfg, logger)
+}
+
+// attachStorageMonitor makes m the gateway's storage monitor: HTTP sends are
+// refused while it is degraded and its gauges join the metrics registry.
+// It does not start the monitor.
+func (g *Gateway) attachStorageMonitor(m *diskmon.Monitor) {
+	g.storage = m
+	g.conversation.SetWriteGuard(m.CheckWrite)
+	g.metrics.MustRegister(m)
+}
+
+// sendStorageFullError writes a 507 telling the client the gateway is out
+// of disk space. Reads keep working while the gateway is in this state.
+func (g *Gateway) sendStorageFullError(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInsufficientStorage)
+	if err := json.NewEncoder(w).Encode(StorageFullResponse{
+		Error: "storage full",
+		Code:  coverr.StorageFull,
+	}); err != nil {
+		g.logger.Debug("failed to encode error response", "error", err)
+	}
+}
@@ -0,0 +1,135 @@
+// ABOUTME: Tests for storage-full degraded mode and verbose readiness output.
+// ABOUTME: Drives the storage monitor with a fake disk probe.
+
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/diskmon"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// newStorageTestGateway returns a gateway with one scripted agent and a
+// storage monitor whose free space is controlled through the returned pointer.
+func newStorageTestGateway(t *testing.T) (*Gateway, *uint64) {
+	t.Helper()
+	gw := newTestGateway(t)
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: "test-agent", Name: "Test", PrincipalID: "test-agent", Stream: &testMockStream{}, Logger: discard,
+	})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("registering agent: %v", err)
+	}
+	sqlStore, ok := gw.store.(*store.SQLStore)
+	if !ok {
+		t.Fatal("store is not *SQLStore")
+	}
+	gw.conversation = conversation.New(sqlStore, &scriptedSender{script: []*agent.Response{
+		{Event: agent.EventText, Text: "ok"},
+		{Event: agent.EventDone, Text: "ok", Done: true},
+	}}, discard, nil)
+
+	free := uint64(1 << 30)
+	probe := diskmon.ProbeFunc(func(string) (diskmon.DiskUsage, error) {
+		return diskmon.DiskUsage{Total: 4 << 30, Free: free}, nil
+	})
+	gw.attachStorageMonitor(diskmon.New(filepath.Join(t.TempDir(), "gateway.db"),
+		diskmon.Config{Probe: probe, MinFree: 100 << 20}, discard))
+	return gw, &free
+}
+
+func postSend(t *testing.T, gw *Gateway, threadID string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(SendMessageRequest{Sender: "user", Content: "hello", AgentID: "test-agent", ThreadID: threadID})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodPost, "/api/send", bytes.NewReader(body)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	gw.handleSendMessage(rec, req)
+	return rec
+}
+
+func TestHandleSendMessage_StorageFull(t *testing.T) {
+	gw, free := newStorageTestGateway(t)
+	threadID := "0f8fad5b-d9cb-469f-a165-70867728950e"
+
+	if rec := postSend(t, gw, threadID); rec.Code != http.StatusOK {
+		t.Fatalf("healthy send status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	*free = 10 << 20
+	gw.storage.Check(context.Background())
+
+	rec := postSend(t, gw, threadID)
+	if rec.Code != http.StatusInsufficientStorage {
+		t.Fatalf("degraded send status = %d, want 507", rec.Code)
+	}
+	var resp StorageFullResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Code != "storage_full" {
+		t.Errorf("code = %q, want storage_full", resp.Code)
+	}
+
+	// Reads keep working while degraded
+	req := httptest.NewRequest(http.MethodGet, "/api/threads/"+threadID+"/messages", nil)
+	read := httptest.NewRecorder()
+	gw.handleThreadMessages(read, req)
+	if read.Code != http.StatusOK {
+		t.Errorf("read while degraded status = %d, want 200", read.Code)
+	}
+
+	// Degraded mode ends on its own once space recovers
+	*free = 1 << 30
+	gw.storage.Check(context.Background())
+	if rec := postSend(t, gw, threadID); rec.Code != http.StatusOK {
+		t.Errorf("send after recovery status = %d, want 200", rec.Code)
+	}
+}
+
+func TestHandleReady_Verbose(t *testing.T) {
+	gw, free := newStorageTestGateway(t)
+	*free = 200 << 20
+	gw.storage.Check(context.Background())
+	gw.grpcServing.Store(true)
+
+	rec := httptest.NewRecorder()
+	gw.handleReady(rec, httptest.NewRequest(http.MethodGet, "/health/ready?verbose=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (storage warnings do not fail readiness)", rec.Code)
+	}
+	var resp ReadyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.Ready || resp.Agents != 1 {
+		t.Errorf("ready/agents = %v/%d, want true/1", resp.Ready, resp.Agents)
+	}
+	if resp.Storage == nil || resp.Storage.Level != diskmon.LevelWarning || len(resp.Storage.Warnings) == 0 {
+		t.Errorf("storage = %+v, want warning with messages", resp.Storage)
+	}
+
+	plain := httptest.NewRecorder()
+	gw.handleReady(plain, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	var brief ReadyResponse
+	if err := json.NewDecoder(plain.Body).Decode(&brief); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if brief.Status != readyStatusReady || brief.Storage != nil {
+		t.Errorf("plain response = %+v, want ready without storage", brief)
+	}
+}
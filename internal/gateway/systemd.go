@@ -0,0 +1,120 @@
+// ABOUTME: systemd integration for the gateway: readiness, stopping, status, and watchdog pings.
+// ABOUTME: Watchdog pings are sent only while a liveness self-check passes, so a wedged process gets restarted.
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/sdnotify"
+)
+
+// statusInterval is how often the systemd status line is refreshed when the
+// watchdog is disabled.
+const statusInterval = 15 * time.Second
+
+// livenessTimeout bounds one liveness self-check.
+const livenessTimeout = 5 * time.Second
+
+// SetSystemdNotifier enables systemd notifications. Run sends READY=1 once
+// all listeners are bound, Shutdown sends STOPPING=1, and a status line with
+// the connected agent count is kept current. When watchdog is positive,
+// WATCHDOG=1 is sent every watchdog/2 while the liveness check passes. A nil
+// notifier (NOTIFY_SOCKET unset) leaves all of this off.
+func (g *Gateway) SetSystemdNotifier(n *sdnotify.Notifier, watchdog time.Duration) {
+	g.systemd = n
+	g.watchdog = watchdog
+}
+
+// systemdStatus is the STATUS= line shown by systemctl status.
+func (g *Gateway) systemdStatus() string {
+	n := len(g.agentManager.ListAgents())
+	if n == 1 {
+		return "1 agent connected"
+	}
+	return fmt.Sprintf("%d agents connected", n)
+}
+
+// notifyReady tells systemd startup is complete and starts the status and
+// watchdog loop, which runs until ctx is canceled.
+func (g *Gateway) notifyReady(ctx context.Context) {
+	if g.systemd == nil {
+		return
+	}
+	status := g.systemdStatus()
+	if err := g.systemd.Ready(status); err != nil {
+		g.logger.Warn("systemd ready notification failed", "error", err)
+	}
+	go g.superviseSystemd(ctx, status)
+}
+
+// superviseSystemd refreshes the status line and, when the watchdog is
+// enabled, pings it after each successful liveness check. A failed check
+// skips the ping so systemd restarts the process once the timeout lapses.
+func (g *Gateway) superviseSystemd(ctx context.Context, lastStatus string) {
+	interval := statusInterval
+	if g.watchdog > 0 {
+		interval = g.watchdog / 2
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var states []string
+		if g.watchdog > 0 {
+			if err := g.checkLiveness(ctx); err != nil {
+				g.logger.Error("liveness check failed, withholding watchdog ping", "error", err)
+				continue
+			}
+			states = append(states, sdnotify.StateWatchdog)
+		}
+		if status := g.systemdStatus(); status != lastStatus {
+			states = append(states, "STATUS="+status)
+			lastStatus = status
+		}
+		if len(states) == 0 {
+			continue
+		}
+		if err := g.systemd.Notify(states...); err != nil {
+			g.logger.Warn("systemd notification failed", "error", err)
+		}
+	}
+}
+
+// pinger is implemented by stores that can confirm they are reachable.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// checkLiveness confirms the gateway is not wedged: the agent manager must
+// answer and the store must run a query, both within livenessTimeout.
+func (g *Gateway) checkLiveness(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, livenessTimeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		g.agentManager.ListAgents()
+		if p, ok := g.store.(pinger); ok {
+			result <- p.Ping(ctx)
+			return
+		}
+		result <- nil
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return errors.New("liveness check timed out")
+	}
+}
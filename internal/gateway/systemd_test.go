@@ -0,0 +1,120 @@
+// ABOUTME: Tests for the gateway's systemd notifications using a fake NOTIFY_SOCKET.
+// ABOUTME: Asserts READY, watchdog pings, status refreshes, and STOPPING across a Run.
+
+package gateway
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/sdnotify"
+)
+
+// fakeNotifySocket collects datagrams sent to a temporary NOTIFY_SOCKET.
+type fakeNotifySocket struct {
+	path string
+	msgs chan string
+}
+
+func newFakeNotifySocket(t *testing.T) *fakeNotifySocket {
+	t.Helper()
+	// Unix socket paths are limited to ~100 bytes; t.TempDir can exceed that
+	dir, err := os.MkdirTemp("", "sdn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	f := &fakeNotifySocket{path: filepath.Join(dir, "notify.sock"), msgs: make(chan string, 64)}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: f.path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			f.msgs <- string(buf[:n])
+		}
+	}()
+	return f
+}
+
+// waitFor returns the first message containing want, failing after a timeout.
+func (f *fakeNotifySocket) waitFor(t *testing.T, want string) string {
+	t.Helper()
+	deadline := time.After(3 * time.Second)
+	for {
+		select {
+		case msg := <-f.msgs:
+			if strings.Contains(msg, want) {
+				return msg
+			}
+		case <-deadline:
+			t.Fatalf("no %q notification", want)
+			return ""
+		}
+	}
+}
+
+func TestRun_SystemdNotifications(t *testing.T) {
+	sock := newFakeNotifySocket(t)
+	gw, err := New(testConfig(t), testLogger())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	gw.SetSystemdNotifier(sdnotify.New(sock.path), 40*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- gw.Run(ctx) }()
+
+	if msg := sock.waitFor(t, "READY=1"); msg != "READY=1\nSTATUS=0 agents connected" {
+		t.Errorf("ready message = %q", msg)
+	}
+	sock.waitFor(t, "WATCHDOG=1")
+	sock.waitFor(t, "WATCHDOG=1")
+
+	cancel()
+	sock.waitFor(t, "STOPPING=1")
+	if err := <-done; err != nil {
+		t.Errorf("Run() = %v", err)
+	}
+}
+
+func TestSuperviseSystemd_WithholdsPingWhenUnhealthy(t *testing.T) {
+	sock := newFakeNotifySocket(t)
+	gw := newTestGateway(t)
+	gw.SetSystemdNotifier(sdnotify.New(sock.path), 20*time.Millisecond)
+
+	// A closed store fails the liveness ping
+	if err := gw.store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go gw.superviseSystemd(ctx, gw.systemdStatus())
+
+	select {
+	case msg := <-sock.msgs:
+		t.Errorf("unexpected notification while unhealthy: %q", msg)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+func TestRun_NoSystemdNotifier(t *testing.T) {
+	gw := newTestGateway(t)
+	// Without a notifier these are no-ops and must not panic
+	gw.notifyReady(context.Background())
+	if err := gw.systemd.Stopping(); err != nil {
+		t.Errorf("nil Stopping = %v", err)
+	}
+}
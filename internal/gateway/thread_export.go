@@ -0,0 +1,92 @@
+// ABOUTME: GET /api/threads/{id}/export streams a thread's transcript as markdown or JSON.
+// ABOUTME: Stored secret values are masked and the thread's token usage closes the document.
+
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/transcript"
+)
+
+// handleThreadExport handles GET /api/threads/{id}/export?format=markdown|json.
+// The transcript is streamed as a download; merged threads redirect to their target.
+func (g *Gateway) handleThreadExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	threadID, ok := extractPathSegment(r.URL.Path, "/api/threads/", "/export")
+	if !ok {
+		g.sendJSONError(w, http.StatusBadRequest, "invalid path")
+		return
+	}
+	if _, err := uuid.Parse(threadID); err != nil {
+		g.sendJSONError(w, http.StatusBadRequest, "invalid thread_id format")
+		return
+	}
+	format, err := transcript.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		g.sendJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !g.resolveThreadRoute(w, r, threadID, "/export") {
+		return
+	}
+	thread, err := g.store.GetThread(r.Context(), threadID)
+	if err != nil {
+		g.logger.Error("failed to get thread", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	requests, err := g.usageReports.ListThreadRequestUsage(r.Context(), threadID, store.UsageFilter{})
+	if err != nil {
+		g.logger.Error("failed to get thread request usage", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	secrets, err := g.secretValues(r.Context())
+	if err != nil {
+		// Exporting without them could leak a secret into a ticket.
+		g.logger.Error("failed to load secrets for export redaction", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	report := g.usagePricing.ThreadReport(requests)
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", format.Filename(threadID)))
+	err = transcript.Write(r.Context(), w, g.store, thread, transcript.Options{
+		Format:  format,
+		Usage:   &report,
+		Secrets: secrets,
+	})
+	if err != nil {
+		// The headers are gone; the client sees a truncated document.
+		g.logger.Error("failed to export thread", "thread_id", threadID, "error", err)
+	}
+}
+
+// secretValues returns the value of every stored secret, global or per agent.
+func (g *Gateway) secretValues(ctx context.Context) ([]string, error) {
+	sqlStore, ok := g.store.(*store.SQLStore)
+	if !ok {
+		return nil, nil
+	}
+	secrets, err := sqlStore.ListAllSecrets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing secrets: %w", err)
+	}
+	values := make([]string, len(secrets))
+	for i, s := range secrets {
+		values[i] = s.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,70 @@
+// ABOUTME: Tests for GET /api/threads/{id}/export: markdown and JSON transcripts,
+// ABOUTME: secret masking, the usage summary, and request validation.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/transcript"
+)
+
+func TestHandleThreadExport(t *testing.T) {
+	gw := newTestGateway(t)
+	threadID := seedGatewayUsage(t, gw)
+	ctx := context.Background()
+	sqlStore := gw.store.(*store.SQLStore)
+	require.NoError(t, sqlStore.CreateSecret(ctx, &store.Secret{ID: "s1", Key: "API_KEY", Value: "hunter2-secret"}))
+	text := func(s string) *string { return &s }
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i, e := range []*store.LedgerEvent{
+		{Direction: store.EventDirectionInbound, Author: "alice", Type: store.EventTypeMessage, Text: text("my key is hunter2-secret")},
+		{Direction: store.EventDirectionOutbound, Author: "agent-1", Type: store.EventTypeMessage, Text: text("Noted.")},
+	} {
+		e.ID = "exp-" + string(rune('a'+i))
+		e.ConversationKey = "agent-1"
+		e.ThreadID = &threadID
+		e.Timestamp = base.Add(time.Duration(i) * time.Second)
+		require.NoError(t, sqlStore.SaveEvent(ctx, e))
+	}
+
+	export := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		gw.handleThreadRoutes(rec, httptest.NewRequest(http.MethodGet, "/api/threads/"+threadID+"/export"+query, nil))
+		return rec
+	}
+
+	rec := export("")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "text/markdown; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "thread-"+threadID+".md")
+	body := rec.Body.String()
+	assert.Contains(t, body, "## User: alice")
+	assert.Contains(t, body, "my key is [redacted]")
+	assert.Contains(t, body, "## Token usage")
+	assert.NotContains(t, body, "hunter2")
+
+	rec = export("?format=json")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var doc transcript.Document
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&doc))
+	require.Len(t, doc.Events, 2)
+	assert.Equal(t, "my key is [redacted]", doc.Events[0].Text)
+	require.NotNil(t, doc.Usage)
+	assert.Equal(t, int64(2_000_000), doc.Usage.Totals.InputTokens)
+
+	assert.Equal(t, http.StatusBadRequest, export("?format=pdf").Code)
+	rec = httptest.NewRecorder()
+	gw.handleThreadRoutes(rec, httptest.NewRequest(http.MethodGet, "/api/threads/"+strings.Repeat("0", 8)+"-0000-0000-0000-000000000000/export", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
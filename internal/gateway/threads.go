@@ -0,0 +1,167 @@
+// ABOUTME: GET /api/threads lists recent threads; PATCH /api/threads/{id} archives and pins one.
+// ABOUTME: Archived threads drop out of thread listings until unarchived or sent a new message.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// UpdateThreadRequest is the JSON body for PATCH /api/threads/{id}. Fields
+// left out are not changed.
+type UpdateThreadRequest struct {
+	Archived *bool `json:"archived,omitempty"`
+	Pinned   *bool `json:"pinned,omitempty"`
+}
+
+// ThreadResponse is the JSON representation of a thread.
+type ThreadResponse struct {
+	ID           string `json:"id"`
+	FrontendName string `json:"frontend_name"`
+	ExternalID   string `json:"external_id"`
+	AgentID      string `json:"agent_id"`
+	Archived     bool   `json:"archived"`
+	Pinned       bool   `json:"pinned"`
+	MergedInto   string `json:"merged_into,omitempty"`
+	SplitFrom    string `json:"split_from,omitempty"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+}
+
+// ListThreadsResponse is the JSON response for GET /api/threads.
+type ListThreadsResponse struct {
+	Threads []ThreadResponse `json:"threads"`
+}
+
+// maxListedThreads is how many of the most recently active threads a
+// listing covers.
+const maxListedThreads = 1000
+
+// handleListThreads handles GET /api/threads?agent_id=X&include_archived=true&limit=N,
+// listing threads pinned first, then most recently active first.
+func (g *Gateway) handleListThreads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	limit, errMsg := parseLimitParam(r, 50, maxListedThreads)
+	if errMsg != "" {
+		g.sendJSONError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+	agentID := r.URL.Query().Get("agent_id")
+	if agentID != "" && !g.tokenAllowsAgentID(r.Context(), agentID) {
+		g.sendJSONError(w, http.StatusForbidden, errTokenAgentDenied)
+		return
+	}
+
+	threads, err := g.listThreadResponses(r.Context(), agentID, r.URL.Query().Get("include_archived") == "true")
+	if err != nil {
+		g.logger.Error("failed to list threads", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if len(threads) > limit {
+		threads = threads[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ListThreadsResponse{Threads: threads}); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// listThreadResponses lists threads in ListThreads order, optionally only
+// agentID's. Merged threads are left out, since their messages now live in
+// the thread they were merged into, as are threads of agents the caller's
+// token may not reach.
+func (g *Gateway) listThreadResponses(ctx context.Context, agentID string, includeArchived bool) ([]ThreadResponse, error) {
+	threads, err := g.store.ListThreads(ctx, maxListedThreads, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]ThreadResponse, 0, len(threads))
+	for _, t := range threads {
+		if t.MergedInto != "" || (agentID != "" && t.AgentID != agentID) || !g.tokenAllowsAgentID(ctx, t.AgentID) {
+			continue
+		}
+		items = append(items, threadToResponse(t))
+	}
+	return items, nil
+}
+
+// handleUpdateThread handles PATCH /api/threads/{id}.
+func (g *Gateway) handleUpdateThread(w http.ResponseWriter, r *http.Request) {
+	threadID := strings.TrimPrefix(r.URL.Path, "/api/threads/")
+	if _, err := uuid.Parse(threadID); err != nil {
+		g.sendJSONError(w, http.StatusBadRequest, "invalid thread_id format")
+		return
+	}
+
+	var req UpdateThreadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.sendJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Archived == nil && req.Pinned == nil {
+		g.sendJSONError(w, http.StatusBadRequest, "archived or pinned is required")
+		return
+	}
+
+	ctx := r.Context()
+	if current, err := g.store.GetThread(ctx, threadID); err == nil && !g.tokenAllowsAgentID(ctx, current.AgentID) {
+		g.sendJSONError(w, http.StatusForbidden, errTokenAgentDenied)
+		return
+	}
+	var err error
+	if req.Archived != nil {
+		err = g.store.ArchiveThread(ctx, threadID, *req.Archived)
+	}
+	if err == nil && req.Pinned != nil {
+		err = g.store.SetThreadPinned(ctx, threadID, *req.Pinned)
+	}
+	var thread *store.Thread
+	if err == nil {
+		thread, err = g.store.GetThread(ctx, threadID)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		g.sendJSONError(w, http.StatusNotFound, "thread not found")
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to update thread", "thread_id", threadID, "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	g.logger.Info("thread updated", "thread_id", threadID, "archived", thread.Archived, "pinned", thread.Pinned)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(threadToResponse(thread)); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// threadToResponse converts a store thread to its JSON representation.
+func threadToResponse(t *store.Thread) ThreadResponse {
+	return ThreadResponse{
+		ID:           t.ID,
+		FrontendName: t.FrontendName,
+		ExternalID:   t.ExternalID,
+		AgentID:      t.AgentID,
+		Archived:     t.Archived,
+		Pinned:       t.Pinned,
+		MergedInto:   t.MergedInto,
+		SplitFrom:    t.SplitFrom,
+		CreatedAt:    timeparse.Format(t.CreatedAt),
+		UpdatedAt:    timeparse.Format(t.UpdatedAt),
+	}
+}
@@ -0,0 +1,196 @@
+// ABOUTME: Tests for GET /api/threads and PATCH /api/threads/{id}: listing, archiving and pinning threads,
+// ABOUTME: validation of the body and ID, token scoping of every thread route, and routing alongside the sub-resources.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/httpapi"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestHandleListThreads(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+	for i, th := range []*store.Thread{
+		{ID: "00000000-0000-0000-0000-0000000000a1", AgentID: "agent-1"},
+		{ID: "00000000-0000-0000-0000-0000000000a2", AgentID: "agent-2"},
+		{ID: "00000000-0000-0000-0000-0000000000a3", AgentID: "agent-1", MergedInto: "00000000-0000-0000-0000-0000000000a1"},
+		{ID: "00000000-0000-0000-0000-0000000000a4", AgentID: "agent-1", Archived: true},
+	} {
+		th.FrontendName, th.ExternalID = "tui", th.ID
+		th.CreatedAt, th.UpdatedAt = base, base.Add(time.Duration(i)*time.Minute)
+		if err := gw.store.CreateThread(ctx, th); err != nil {
+			t.Fatalf("CreateThread: %v", err)
+		}
+	}
+
+	list := func(req *http.Request) (int, []string) {
+		rec := httptest.NewRecorder()
+		gw.handleListThreads(rec, req)
+		var resp ListThreadsResponse
+		_ = json.NewDecoder(rec.Body).Decode(&resp)
+		var ids []string
+		for _, th := range resp.Threads {
+			ids = append(ids, th.ID[len(th.ID)-2:])
+		}
+		return rec.Code, ids
+	}
+	get := func(target string) *http.Request { return httptest.NewRequest(http.MethodGet, target, nil) }
+	scoped := func(target string) *http.Request {
+		req := get(target)
+		return req.WithContext(auth.WithAuth(req.Context(), &auth.AuthContext{PrincipalID: "tui", AgentIDs: []string{"agent-1"}}))
+	}
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		wantCode int
+		wantIDs  []string
+	}{
+		{"most recent first", get("/api/threads"), http.StatusOK, []string{"a2", "a1"}},
+		{"by agent", get("/api/threads?agent_id=agent-1"), http.StatusOK, []string{"a1"}},
+		{"with archived", get("/api/threads?include_archived=true"), http.StatusOK, []string{"a4", "a2", "a1"}},
+		{"limit", get("/api/threads?limit=1"), http.StatusOK, []string{"a2"}},
+		{"token scope", scoped("/api/threads"), http.StatusOK, []string{"a1"}},
+		{"token scope denies agent", scoped("/api/threads?agent_id=agent-2"), http.StatusForbidden, nil},
+		{"bad limit", get("/api/threads?limit=0"), http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ids := list(tt.req)
+			if code != tt.wantCode || strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("got %d %v, want %d %v", code, ids, tt.wantCode, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestHandleUpdateThread(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx := context.Background()
+	threadID := "00000000-0000-0000-0000-00000000000c"
+	if err := gw.store.CreateThread(ctx, &store.Thread{
+		ID: threadID, FrontendName: "test", ExternalID: "ext-1", AgentID: "agent-001",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+
+	patch := func(id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		gw.handleThreadRoutes(rec, httptest.NewRequest(http.MethodPatch, "/api/threads/"+id, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := patch(threadID, `{"archived":true,"pinned":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp ThreadResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.ID != threadID || !resp.Archived || !resp.Pinned {
+		t.Errorf("response = %+v, want archived and pinned", resp)
+	}
+
+	// Fields left out stay as they are.
+	rec = patch(threadID, `{"archived":false}`)
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Archived || !resp.Pinned {
+		t.Errorf("response = %+v, want unarchived and still pinned", resp)
+	}
+
+	tests := []struct {
+		name, id, body string
+		want           int
+	}{
+		{"no fields", threadID, `{}`, http.StatusBadRequest},
+		{"bad JSON", threadID, `{`, http.StatusBadRequest},
+		{"bad ID", "not-a-uuid", `{"pinned":true}`, http.StatusBadRequest},
+		{"unknown thread", "00000000-0000-0000-0000-0000000000ff", `{"pinned":true}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := patch(tt.id, tt.body); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	rec = httptest.NewRecorder()
+	gw.handleThreadRoutes(rec, httptest.NewRequest(http.MethodGet, "/api/threads/"+threadID, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want 405", rec.Code)
+	}
+}
+
+func TestThreadReads_TokenScope(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx := context.Background()
+	threadID := "00000000-0000-0000-0000-0000000000b1"
+	if err := gw.store.CreateThread(ctx, &store.Thread{
+		ID: threadID, FrontendName: "tui", ExternalID: "ext-b1", AgentID: "agent-2",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+
+	scoped := func(method, target string, agentIDs ...string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(`{"archived":true}`))
+		return req.WithContext(auth.WithAuth(req.Context(), &auth.AuthContext{PrincipalID: "tui", AgentIDs: agentIDs}))
+	}
+	v1Messages := func(req *http.Request) int {
+		req.SetPathValue("id", threadID)
+		_, err := gw.listThreadMessagesV1(req, httpapi.Page{Limit: 50})
+		var apiErr *httpapi.Error
+		if errors.As(err, &apiErr) {
+			return apiErr.Status
+		}
+		if err != nil {
+			t.Fatalf("listThreadMessagesV1: %v", err)
+		}
+		return http.StatusOK
+	}
+	legacy := func(req *http.Request) int {
+		rec := httptest.NewRecorder()
+		gw.handleThreadRoutes(rec, req)
+		return rec.Code
+	}
+
+	for _, tt := range []struct {
+		name   string
+		method string
+		suffix string
+		serve  func(*http.Request) int
+	}{
+		{"messages", http.MethodGet, "/messages", legacy},
+		{"usage", http.MethodGet, "/usage", legacy},
+		{"export", http.MethodGet, "/export", legacy},
+		{"update", http.MethodPatch, "", legacy},
+		{"v1 messages", http.MethodGet, "/messages", v1Messages},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "/api/threads/" + threadID + tt.suffix
+			if code := tt.serve(scoped(tt.method, target, "agent-1")); code != http.StatusForbidden {
+				t.Errorf("token for another agent: status = %d, want 403", code)
+			}
+			if code := tt.serve(scoped(tt.method, target, "agent-2")); code != http.StatusOK {
+				t.Errorf("token for the thread's agent: status = %d, want 200", code)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// ABOUTME: Tests timestamp handling across the HTTP API.
+// ABOUTME: Every time input accepts RFC3339 and epoch forms; every time output is RFC3339 UTC.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// usageAt is when the seeded usage record was created.
+var usageAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func seedUsageAt(t *testing.T, gw *Gateway) {
+	t.Helper()
+	ctx := context.Background()
+	sqlStore := gw.store.(*store.SQLStore)
+	require.NoError(t, sqlStore.CreateThread(ctx, &store.Thread{
+		ID: "thread-ts", FrontendName: "test", ExternalID: "ext-ts", AgentID: "agent-001",
+		CreatedAt: usageAt, UpdatedAt: usageAt,
+	}))
+	require.NoError(t, sqlStore.SaveUsage(ctx, &store.TokenUsage{
+		ID: "usage-ts", ThreadID: "thread-ts", RequestID: "req-ts", AgentID: "agent-001",
+		InputTokens: 10, OutputTokens: 5, CreatedAt: usageAt,
+	}))
+}
+
+func TestTimestampInputs(t *testing.T) {
+	before := usageAt.Add(-time.Hour)
+	after := usageAt.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		path      string
+		handler   func(*Gateway) http.HandlerFunc
+		wantCode  int
+		wantParam string // for 400s
+		wantCount int64  // usage request count for 200s
+	}{
+		{"usage since rfc3339", "/api/stats/usage?since=" + before.Format(time.RFC3339), usageHandler, http.StatusOK, "", 1},
+		{"usage since rfc3339nano", "/api/stats/usage?since=" + after.Format(time.RFC3339Nano), usageHandler, http.StatusOK, "", 0},
+		{"usage since offset", "/api/stats/usage?since=" + strings.ReplaceAll(before.In(time.FixedZone("", 3600)).Format(time.RFC3339), "+", "%2B"), usageHandler, http.StatusOK, "", 1},
+		{"usage since epoch seconds", fmt.Sprintf("/api/stats/usage?since=%d", after.Unix()), usageHandler, http.StatusOK, "", 0},
+		{"usage until epoch millis", fmt.Sprintf("/api/stats/usage?until=%d", after.UnixMilli()), usageHandler, http.StatusOK, "", 1},
+		{"usage until epoch millis before", fmt.Sprintf("/api/stats/usage?until=%d", before.UnixMilli()), usageHandler, http.StatusOK, "", 0},
+		{"usage bad since", "/api/stats/usage?since=yesterday", usageHandler, http.StatusBadRequest, "since", 0},
+		{"usage bad until", "/api/stats/usage?until=2026-03-01", usageHandler, http.StatusBadRequest, "until", 0},
+		{"deliveries since epoch", fmt.Sprintf("/api/deliveries/stats?since=%d", before.Unix()), deliveryHandler, http.StatusOK, "", -1},
+		{"deliveries bad since", "/api/deliveries/stats?since=soon", deliveryHandler, http.StatusBadRequest, "since", 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gw := newTestGateway(t)
+			seedUsageAt(t, gw)
+
+			rec := httptest.NewRecorder()
+			tc.handler(gw)(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			require.Equal(t, tc.wantCode, rec.Code, rec.Body.String())
+
+			if tc.wantCode == http.StatusBadRequest {
+				var body map[string]string
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+				assert.Equal(t, timeparse.ErrorCode, body["code"])
+				assert.Equal(t, tc.wantParam, body["param"])
+				assert.Contains(t, body["error"], tc.wantParam)
+				return
+			}
+			if tc.wantCount >= 0 {
+				var stats UsageStatsResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+				assert.Equal(t, tc.wantCount, stats.RequestCount)
+			}
+		})
+	}
+}
+
+func usageHandler(gw *Gateway) http.HandlerFunc    { return gw.handleUsageStats }
+func deliveryHandler(gw *Gateway) http.HandlerFunc { return gw.handleDeliveryRoutes }
+
+func TestTimestampOutputs_RFC3339UTC(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx := context.Background()
+	sqlStore := gw.store.(*store.SQLStore)
+
+	// Stored with a non-UTC offset; must come back as UTC.
+	created := time.Date(2026, 3, 1, 9, 30, 0, 0, time.FixedZone("EST", -5*3600))
+	require.NoError(t, sqlStore.CreatePrincipal(ctx, &store.Principal{
+		ID: "agent-ts", Type: store.PrincipalTypeAgent, PubkeyFP: strings.Repeat("c", 64),
+		DisplayName: "agent-ts", Status: store.PrincipalStatusApproved, CreatedAt: created,
+	}))
+	require.NoError(t, sqlStore.CreateBindingV2(ctx, &store.Binding{
+		ID: "b-ts", Frontend: "slack", ChannelID: "CTS", AgentID: "agent-ts", CreatedAt: created,
+	}))
+
+	rec := httptest.NewRecorder()
+	gw.handleBindings(rec, httptest.NewRequest(http.MethodGet, "/api/bindings", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var list ListBindingsResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	require.Len(t, list.Bindings, 1)
+	assert.Equal(t, "2026-03-01T14:30:00Z", list.Bindings[0].CreatedAt)
+}
@@ -0,0 +1,77 @@
+// ABOUTME: Resolves the tools an admin disabled for a calling agent's principal, for the router, MCP and tool lists.
+// ABOUTME: Pushes connected agents their pack tools again when their overrides change.
+
+package gateway
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// principalDisabledTools returns a lookup of the tools disabled for the
+// caller's principal, each with its reason, for packs.RouterConfig and
+// mcp.Config. A store error is logged and disables nothing.
+func principalDisabledTools(m *agent.Manager, s *store.SQLStore, logger *slog.Logger) func(ctx context.Context, agentID string) map[string]string {
+	return func(ctx context.Context, agentID string) map[string]string {
+		principalID := callerPrincipal(m, agentID)
+		if principalID == "" {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(ctx, toolCheckTimeout)
+		defer cancel()
+		disabled, err := s.DisabledTools(ctx, principalID)
+		if err != nil {
+			logger.Warn("failed to load tool overrides", "agent_id", agentID, "principal_id", principalID, "error", err)
+			return nil
+		}
+		return disabled
+	}
+}
+
+// agentTools returns the pack tools offered to a connected agent: those its
+// capabilities cover, minus any an admin disabled for it.
+func (g *Gateway) agentTools(ctx context.Context, agentID string, capabilities []string) []*pb.ToolDefinition {
+	if g.packRegistry == nil {
+		return nil
+	}
+	tools := g.packRegistry.GetToolsForCapabilities(capabilities)
+	if g.disabledTools != nil {
+		tools = packs.WithoutDisabled(tools, g.disabledTools(ctx, agentID))
+	}
+	return tools
+}
+
+// ToolOverridesChanged sends a principal's connected agents their pack tool
+// list again after an admin turned tools on or off for it. Tool calls need
+// no update, since the pack router reads the overrides for every call.
+func (g *Gateway) ToolOverridesChanged(principalID string) {
+	if g.packRegistry == nil {
+		return
+	}
+	var catalogVersion int64
+	if g.toolCatalog != nil {
+		if v, err := g.toolCatalog.Version(context.Background()); err == nil {
+			catalogVersion = v
+		}
+	}
+	for _, conn := range g.agentManager.ListByPrincipal(principalID) {
+		msg := &pb.ServerMessage{
+			Payload: &pb.ServerMessage_ToolsChanged{
+				ToolsChanged: &pb.ToolsChanged{
+					CatalogVersion: catalogVersion,
+					AvailableTools: g.agentTools(context.Background(), conn.ID, conn.Capabilities()),
+				},
+			},
+		}
+		if err := conn.Send(msg); err != nil {
+			g.logger.Warn("failed to send tools changed", "agent_id", conn.ID, "principal_id", principalID, "error", err)
+			continue
+		}
+		g.logger.Info("pushed tool overrides to agent", "agent_id", conn.ID, "principal_id", principalID)
+	}
+}
@@ -0,0 +1,59 @@
+// ABOUTME: Tests for per-agent tool overrides in the gateway: pushing a principal's
+// ABOUTME: connected agents their tool list without the tools an admin disabled.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestToolOverridesChanged(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx := context.Background()
+	sqlStore := gw.store.(*store.SQLStore)
+	createAgentPrincipal(t, gw, "p-1")
+
+	handler := func(context.Context, string, json.RawMessage) (json.RawMessage, error) {
+		return json.RawMessage(`{}`), nil
+	}
+	if err := gw.packRegistry.RegisterBuiltinPack(&packs.BuiltinPack{ID: "builtin:overrides-test", Tools: []*packs.BuiltinTool{
+		{Definition: &pb.ToolDefinition{Name: "noisy_tool"}, Handler: handler},
+		{Definition: &pb.ToolDefinition{Name: "quiet_tool"}, Handler: handler},
+	}}); err != nil {
+		t.Fatalf("RegisterBuiltinPack: %v", err)
+	}
+
+	stream := &capturingStream{}
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: "agent-1", Name: "agent-1", PrincipalID: "p-1", Stream: stream, Logger: slog.Default(),
+	})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	if err := sqlStore.SetToolOverride(ctx, &store.ToolOverride{PrincipalID: "p-1", ToolName: "noisy_tool", Reason: "too noisy"}); err != nil {
+		t.Fatalf("SetToolOverride: %v", err)
+	}
+	gw.ToolOverridesChanged("p-1")
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if len(stream.sent) != 1 || stream.sent[0].GetToolsChanged() == nil {
+		t.Fatalf("sent = %v, want one tools_changed", stream.sent)
+	}
+	offered := map[string]bool{}
+	for _, tool := range stream.sent[0].GetToolsChanged().GetAvailableTools() {
+		offered[tool.GetName()] = true
+	}
+	if offered["noisy_tool"] || !offered["quiet_tool"] {
+		t.Errorf("offered tools = %v, want quiet_tool without noisy_tool", offered)
+	}
+}
@@ -0,0 +1,41 @@
+// ABOUTME: Records dry-run tool call verdicts as tool_check ledger events.
+// ABOUTME: Lets operators see which agents probe tools and what they were told.
+
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// toolCheckTimeout bounds recording one verdict; a slow store must not hold
+// up the agent waiting for it.
+const toolCheckTimeout = 5 * time.Second
+
+// toolCheckRecorder returns a packs.RouterConfig.OnCheck hook that saves each
+// verdict to the agent's ledger.
+func toolCheckRecorder(s store.Store, logger *slog.Logger) func(agentID string, v *packs.ToolCallVerdict) {
+	return func(agentID string, v *packs.ToolCallVerdict) {
+		ctx, cancel := context.WithTimeout(context.Background(), toolCheckTimeout)
+		defer cancel()
+		text := v.JSON()
+		err := s.SaveEvent(ctx, &store.LedgerEvent{
+			ID:              uuid.New().String(),
+			ConversationKey: agentID,
+			Direction:       store.EventDirectionOutbound,
+			Author:          agentID,
+			Timestamp:       time.Now(),
+			Type:            store.EventTypeToolCheck,
+			Text:            &text,
+		})
+		if err != nil {
+			logger.Warn("failed to record tool check", "agent_id", agentID, "tool_name", v.Tool, "error", err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// ABOUTME: Applies the tool approval policy to agents' approval requests and records each decision
+// ABOUTME: as a tool_policy ledger event naming the rule that matched.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// toolPolicyRecord is the text of a tool_policy ledger event.
+type toolPolicyRecord struct {
+	packs.PolicyDecision
+	ToolID    string `json:"tool_id"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// principalGroups returns a packs.RouterConfig.AgentGroups lookup that
+// answers with the agent groups the caller's principal is a member of.
+func principalGroups(m *agent.Manager, groups agentGroupStore, logger *slog.Logger) func(ctx context.Context, agentID string) []string {
+	return func(ctx context.Context, agentID string) []string {
+		principalID := callerPrincipal(m, agentID)
+		all, err := groups.ListAgentGroups(ctx)
+		if err != nil {
+			logger.Warn("failed to load agent groups", "agent_id", agentID, "error", err)
+			return nil
+		}
+		var names []string
+		for _, g := range all {
+			if slices.Contains(g.Members, principalID) {
+				names = append(names, g.Name)
+			}
+		}
+		return names
+	}
+}
+
+// toolApprovalPolicy returns an agent.Manager tool approval policy that
+// decides each request with the router's tool policy and saves the decision
+// to the agent's ledger. Requests the policy asks about go to the client.
+func toolApprovalPolicy(router *packs.Router, s store.Store, logger *slog.Logger) func(agentID, threadID string, req *agent.ToolApprovalRequestEvent) (bool, bool) {
+	return func(agentID, threadID string, req *agent.ToolApprovalRequestEvent) (bool, bool) {
+		ctx, cancel := context.WithTimeout(context.Background(), toolCheckTimeout)
+		defer cancel()
+		d, err := router.EvaluateToolPolicy(ctx, agentID, req.Name, req.InputJSON)
+		if err != nil {
+			logger.Warn("failed to evaluate tool policy, asking instead", "agent_id", agentID, "tool_name", req.Name, "error", err)
+		}
+
+		data, _ := json.Marshal(toolPolicyRecord{PolicyDecision: d, ToolID: req.ID, RequestID: req.RequestID})
+		text := string(data)
+		event := &store.LedgerEvent{
+			ID:              uuid.New().String(),
+			ConversationKey: agentID,
+			Direction:       store.EventDirectionInbound,
+			Author:          "system",
+			Timestamp:       time.Now(),
+			Type:            store.EventTypeToolPolicy,
+			Text:            &text,
+		}
+		if threadID != "" {
+			event.ThreadID = &threadID
+		}
+		if err := s.SaveEvent(ctx, event); err != nil {
+			logger.Warn("failed to record tool policy decision", "agent_id", agentID, "tool_name", req.Name, "error", err)
+		}
+
+		return d.Action == store.ToolPolicyAllow, d.Action != store.ToolPolicyAsk
+	}
+}
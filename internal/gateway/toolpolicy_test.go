@@ -0,0 +1,72 @@
+// ABOUTME: Tests for applying the tool approval policy to agents' approval requests:
+// ABOUTME: group and parameter rules decide, the default asks, and each decision is recorded.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestToolApprovalPolicy(t *testing.T) {
+	gw := newTestGateway(t)
+	ctx := context.Background()
+	sqlStore := gw.store.(*store.SQLStore)
+	createAgentPrincipal(t, gw, "agent-a")
+	createAgentPrincipal(t, gw, "agent-b")
+	if err := sqlStore.CreateAgentGroup(ctx, &store.AgentGroup{Name: "ops", Members: []string{"agent-a"}}); err != nil {
+		t.Fatalf("CreateAgentGroup: %v", err)
+	}
+	for _, p := range []*store.ToolPolicy{
+		{ID: "ops-no-shell", Group: "ops", ToolPattern: "shell", Action: store.ToolPolicyDeny},
+		{ID: "tmp-writes", ToolPattern: "fs_*", Action: store.ToolPolicyAllow, Matchers: []store.ToolPolicyMatcher{{Param: "path", Op: "startswith", Value: "/tmp/"}}},
+	} {
+		if err := sqlStore.CreateToolPolicy(ctx, p); err != nil {
+			t.Fatalf("CreateToolPolicy(%s): %v", p.ID, err)
+		}
+	}
+
+	decide := toolApprovalPolicy(gw.packRouter, gw.store, slog.Default())
+	tests := []struct {
+		agentID, tool, input  string
+		wantApproved, decided bool
+		wantRule              string
+	}{
+		{"agent-a", "shell", `{"cmd":"ls"}`, false, true, "ops-no-shell"},
+		{"agent-b", "shell", `{"cmd":"ls"}`, false, false, ""},
+		{"agent-b", "fs_write", `{"path":"/tmp/out"}`, true, true, "tmp-writes"},
+		{"agent-b", "fs_write", `{"path":"/etc/hosts"}`, false, false, ""},
+	}
+	for i, tt := range tests {
+		req := &agent.ToolApprovalRequestEvent{ID: "tool-" + tt.tool, Name: tt.tool, InputJSON: tt.input, RequestID: "req-1"}
+		approved, decided := decide(tt.agentID, "thread-1", req)
+		if approved != tt.wantApproved || decided != tt.decided {
+			t.Errorf("case %d: %s %s = approved %v decided %v, want %v %v", i, tt.agentID, tt.tool, approved, decided, tt.wantApproved, tt.decided)
+		}
+	}
+
+	events, err := sqlStore.GetEventsByThreadID(ctx, "thread-1", 10)
+	if err != nil {
+		t.Fatalf("GetEventsByThreadID: %v", err)
+	}
+	if len(events) != len(tests) {
+		t.Fatalf("recorded %d decisions, want %d", len(events), len(tests))
+	}
+	for _, e := range events {
+		if e.ConversationKey != "agent-a" {
+			continue
+		}
+		var got toolPolicyRecord
+		if err := json.Unmarshal([]byte(*e.Text), &got); err != nil {
+			t.Fatalf("decoding decision: %v", err)
+		}
+		if e.Type != store.EventTypeToolPolicy || got.RuleID != "ops-no-shell" || got.Action != store.ToolPolicyDeny || got.ToolID != "tool-shell" {
+			t.Errorf("agent-a decision = %s %+v, want tool_policy deny by ops-no-shell", e.Type, got)
+		}
+	}
+}
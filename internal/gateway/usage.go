@@ -0,0 +1,65 @@
+// ABOUTME: GET /api/usage/summary: token usage in a window grouped by agent or day,
+// ABOUTME: with estimated cost from the usage.pricing table.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+	"github.com/2389/coven-gateway/internal/usage"
+)
+
+// usageReportStore is what the usage rollups need from storage.
+type usageReportStore interface {
+	usage.Source
+	ListThreadRequestUsage(ctx context.Context, threadID string, filter store.UsageFilter) ([]*store.RequestUsage, error)
+}
+
+// handleUsageSummary handles GET /api/usage/summary.
+// Query parameters:
+//   - group_by: "agent" (default) or "day"
+//   - agent_id: filter by agent
+//   - since: start time (RFC3339 or epoch seconds/milliseconds)
+//   - until: end time (RFC3339 or epoch seconds/milliseconds)
+func (g *Gateway) handleUsageSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := store.UsageFilter{}
+	if agentID := query.Get("agent_id"); agentID != "" {
+		filter.AgentID = &agentID
+	}
+	var err error
+	if filter.Since, err = timeparse.Query(query, "since"); err != nil {
+		g.sendTimestampError(w, err)
+		return
+	}
+	if filter.Until, err = timeparse.Query(query, "until"); err != nil {
+		g.sendTimestampError(w, err)
+		return
+	}
+
+	summary, err := g.usagePricing.Summarize(r.Context(), g.usageReports, query.Get("group_by"), filter)
+	if errors.Is(err, usage.ErrUnknownGroupBy) {
+		g.sendJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		g.logger.Error("failed to summarize usage", "error", err)
+		g.sendJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		g.logger.Debug("failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,116 @@
+// ABOUTME: Tests for the usage rollups: per-request totals and cost on
+// ABOUTME: /api/threads/{id}/usage, and /api/usage/summary by agent and day.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/usage"
+)
+
+// seedGatewayUsage stores a thread with two requests from two agents on
+// different days; agent-2's request reported usage twice.
+func seedGatewayUsage(t *testing.T, gw *Gateway) string {
+	t.Helper()
+	ctx := context.Background()
+	sqlStore := gw.store.(*store.SQLStore)
+	threadID := "00000000-0000-0000-0000-0000000000c1"
+	require.NoError(t, sqlStore.CreateThread(ctx, &store.Thread{
+		ID: threadID, FrontendName: "test", ExternalID: "ext-usage", AgentID: "agent-1",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	day1 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, u := range []*store.TokenUsage{
+		{ID: "u1", RequestID: "req-1", AgentID: "agent-1", InputTokens: 1_000_000, OutputTokens: 100_000, CreatedAt: day1},
+		{ID: "u2", RequestID: "req-2", AgentID: "agent-2", InputTokens: 500_000, CreatedAt: day1.Add(24 * time.Hour)},
+		{ID: "u3", RequestID: "req-2", AgentID: "agent-2", InputTokens: 500_000, CreatedAt: day1.Add(25 * time.Hour)},
+	} {
+		u.ThreadID = threadID
+		require.NoError(t, sqlStore.SaveUsage(ctx, u))
+	}
+	return threadID
+}
+
+func getUsageJSON(t *testing.T, handler http.HandlerFunc, target string, out any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(out))
+	}
+	return rec.Code
+}
+
+func TestHandleThreadUsage_Rollup(t *testing.T) {
+	gw := newTestGateway(t)
+	gw.usagePricing = usage.Pricing{usage.DefaultPriceKey: {Input: 3, Output: 15}, "agent-2": {Input: 1}}
+	threadID := seedGatewayUsage(t, gw)
+
+	var resp ThreadUsageResponse
+	require.Equal(t, http.StatusOK, getUsageJSON(t, gw.handleThreadUsage, "/api/threads/"+threadID+"/usage", &resp))
+
+	assert.Len(t, resp.Usage, 3)
+	assert.Equal(t, int64(2_000_000), resp.Totals.InputTokens)
+	assert.Equal(t, int64(100_000), resp.Totals.OutputTokens)
+	require.NotNil(t, resp.Totals.EstimatedCostUSD)
+	assert.InDelta(t, 5.5, *resp.Totals.EstimatedCostUSD, 1e-9)
+
+	require.Len(t, resp.Requests, 2)
+	assert.Equal(t, "req-1", resp.Requests[0].RequestID)
+	assert.Equal(t, "req-2", resp.Requests[1].RequestID)
+	assert.Equal(t, int64(2), resp.Requests[1].RequestCount)
+	require.NotNil(t, resp.Requests[1].EstimatedCostUSD)
+	assert.InDelta(t, 1.0, *resp.Requests[1].EstimatedCostUSD, 1e-9)
+}
+
+func TestHandleThreadUsage_NoPricingOmitsCost(t *testing.T) {
+	gw := newTestGateway(t)
+	threadID := seedGatewayUsage(t, gw)
+
+	var resp map[string]any
+	require.Equal(t, http.StatusOK, getUsageJSON(t, gw.handleThreadUsage, "/api/threads/"+threadID+"/usage", &resp))
+	totals, ok := resp["totals"].(map[string]any)
+	require.True(t, ok, "totals missing: %v", resp)
+	assert.NotContains(t, totals, "estimated_cost_usd")
+	assert.InDelta(t, 2_100_000, totals["total_tokens"], 0)
+}
+
+func TestHandleUsageSummary(t *testing.T) {
+	gw := newTestGateway(t)
+	gw.usagePricing = usage.Pricing{"agent-1": {Input: 2}}
+	seedGatewayUsage(t, gw)
+
+	var byAgent usage.Summary
+	require.Equal(t, http.StatusOK, getUsageJSON(t, gw.handleUsageSummary, "/api/usage/summary", &byAgent))
+	assert.Equal(t, usage.GroupByAgent, byAgent.GroupBy)
+	require.Len(t, byAgent.Groups, 2)
+	assert.Equal(t, "agent-1", byAgent.Groups[0].Key)
+	require.NotNil(t, byAgent.Groups[0].EstimatedCostUSD)
+	assert.InDelta(t, 2.0, *byAgent.Groups[0].EstimatedCostUSD, 1e-9)
+	assert.Nil(t, byAgent.Groups[1].EstimatedCostUSD, "agent-2 has no price")
+	assert.Nil(t, byAgent.Totals.EstimatedCostUSD, "totals include an unpriced agent")
+
+	var byDay usage.Summary
+	require.Equal(t, http.StatusOK, getUsageJSON(t, gw.handleUsageSummary, "/api/usage/summary?group_by=day&agent_id=agent-2", &byDay))
+	require.Len(t, byDay.Groups, 1)
+	assert.Equal(t, "2026-03-02", byDay.Groups[0].Key)
+	assert.Equal(t, int64(1_000_000), byDay.Totals.InputTokens)
+
+	var windowed usage.Summary
+	require.Equal(t, http.StatusOK, getUsageJSON(t, gw.handleUsageSummary, "/api/usage/summary?group_by=day&until=2026-03-02T00:00:00Z", &windowed))
+	require.Len(t, windowed.Groups, 1)
+	assert.Equal(t, "2026-03-01", windowed.Groups[0].Key)
+
+	assert.Equal(t, http.StatusBadRequest, getUsageJSON(t, gw.handleUsageSummary, "/api/usage/summary?group_by=week", nil))
+	assert.Equal(t, http.StatusBadRequest, getUsageJSON(t, gw.handleUsageSummary, "/api/usage/summary?since=yesterday", nil))
+}
@@ -0,0 +1,235 @@
+// ABOUTME: WebSocket transport for sends: GET /api/ws carries send and cancel frames from the client
+// ABOUTME: and streams back the same events as /api/send, read from the broadcaster's replay buffer.
+
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/client"
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/webadmin"
+)
+
+// defaultWSMaxSends caps a WebSocket connection's in-flight sends when
+// server.websocket_max_sends is unset.
+const defaultWSMaxSends = 8
+
+// wsReadLimit bounds a single client frame.
+const wsReadLimit = 1 << 20
+
+// adminSessionStore looks up web admin sessions for cookie authentication.
+type adminSessionStore interface {
+	GetAdminSession(ctx context.Context, id string) (*store.AdminSession, error)
+}
+
+// wsAuthMiddleware admits requests with a valid web admin session cookie,
+// which browsers send on a WebSocket upgrade where they cannot set headers,
+// and passes the rest to bearer for token authentication.
+func wsAuthMiddleware(sessions adminSessionStore, bearer func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withToken := bearer(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				if cookie, err := r.Cookie(webadmin.SessionCookieName); err == nil {
+					if _, err := sessions.GetAdminSession(r.Context(), cookie.Value); err == nil {
+						next.ServeHTTP(w, r)
+						return
+					}
+				}
+			}
+			withToken.ServeHTTP(w, r)
+		})
+	}
+}
+
+// wsConn is one /api/ws connection and the sends it has in flight.
+type wsConn struct {
+	g        *Gateway
+	conn     *websocket.Conn
+	maxSends int
+	limitKey string // who sends count against for api.rate_limit.send
+
+	mu    sync.Mutex
+	sends map[string]context.CancelCauseFunc // by request ID
+}
+
+// handleWebSocket handles GET /api/ws. After the upgrade the client sends
+// JSON send and cancel frames; each send's events come back as frames typed
+// by their SSE event name. Sends outlive the connection like /api/send
+// streams do, and can be resumed from GET /api/requests/{request_id}/stream.
+func (g *Gateway) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if g.agentManager.Draining() {
+		g.sendDrainingError(w)
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		// Accept has already written the error response.
+		g.logger.Debug("websocket upgrade failed", "error", err)
+		return
+	}
+	defer func() { _ = conn.CloseNow() }()
+	conn.SetReadLimit(wsReadLimit)
+
+	maxSends := defaultWSMaxSends
+	if g.config != nil && g.config.Server.WebSocketMaxSends > 0 {
+		maxSends = g.config.Server.WebSocketMaxSends
+	}
+	c := &wsConn{g: g, conn: conn, maxSends: maxSends, limitKey: rateLimitKey(r), sends: make(map[string]context.CancelCauseFunc)}
+	c.serve(r.Context())
+}
+
+// serve reads client frames until the connection closes.
+func (c *wsConn) serve(ctx context.Context) {
+	for {
+		var frame client.WSFrame
+		if err := wsjson.Read(ctx, c.conn, &frame); err != nil {
+			if websocket.CloseStatus(err) == -1 && ctx.Err() == nil {
+				c.g.logger.Debug("websocket read failed", "error", err)
+			}
+			return
+		}
+		switch frame.Type {
+		case client.FrameSend:
+			c.send(ctx, &frame)
+		case client.FrameCancel:
+			c.cancel(ctx, &frame)
+		default:
+			c.reject(ctx, &frame, coverr.InvalidRequest, "unknown frame type "+frame.Type)
+		}
+	}
+}
+
+// send starts the request in frame and follows its events.
+func (c *wsConn) send(ctx context.Context, frame *client.WSFrame) {
+	if c.g.agentManager.Draining() {
+		c.reject(ctx, frame, coverr.GatewayDraining, agent.ErrDraining.Error())
+		return
+	}
+	if ok, retry := c.g.allowRequest(rateGroupSend, c.limitKey); !ok {
+		c.reject(ctx, frame, coverr.RateLimited, fmt.Sprintf("rate limit exceeded, retry in %ds", retryAfterSeconds(retry)))
+		return
+	}
+	c.mu.Lock()
+	full := len(c.sends) >= c.maxSends
+	c.mu.Unlock()
+	if full {
+		c.reject(ctx, frame, coverr.RateLimited, "too many sends in flight on this connection")
+		return
+	}
+
+	req, err := parseSendRequest(bytes.NewReader(frame.Data))
+	if err != nil {
+		c.reject(ctx, frame, coverr.InvalidRequest, err.Error())
+		return
+	}
+	target, errMsg := c.g.resolveTarget(ctx, req)
+	if target == nil {
+		_, code := targetError(errMsg)
+		c.reject(ctx, frame, code, errMsg)
+		return
+	}
+	if err := resolveWorkspace(req, target); err != nil {
+		c.reject(ctx, frame, coverr.CodeOf(err), err.Error())
+		return
+	}
+
+	// Like /api/send, the response is not tied to this connection; only a
+	// cancel frame stops it.
+	sendCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	requestID, err := c.g.beginSend(sendCtx, req, target)
+	if err != nil {
+		cancel(err)
+		code, msg := wsSendError(err)
+		if code == coverr.Internal {
+			c.g.logger.Error("failed to send message", "error", err)
+		}
+		c.reject(ctx, frame, code, msg)
+		return
+	}
+
+	c.mu.Lock()
+	c.sends[requestID] = cancel
+	c.mu.Unlock()
+	go c.follow(ctx, frame.Ref, requestID)
+}
+
+// follow writes requestID's events as frames until it finishes or the
+// connection closes.
+func (c *wsConn) follow(ctx context.Context, ref, requestID string) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.sends, requestID)
+		c.mu.Unlock()
+	}()
+
+	var lastEventID uint64
+	for {
+		events, done, err := c.g.eventBroadcaster.WaitRequestEvents(ctx, requestID, lastEventID)
+		if err != nil {
+			return
+		}
+		for _, ev := range events {
+			out := &client.WSFrame{Type: ev.Event, Ref: ref, RequestID: requestID, EventID: ev.ID, Data: json.RawMessage(ev.Data)}
+			if err := wsjson.Write(ctx, c.conn, out); err != nil {
+				return
+			}
+			lastEventID = ev.ID
+		}
+		if done {
+			return
+		}
+	}
+}
+
+// cancel cancels the in-flight send named in frame.
+func (c *wsConn) cancel(ctx context.Context, frame *client.WSFrame) {
+	c.mu.Lock()
+	cancel, ok := c.sends[frame.RequestID]
+	c.mu.Unlock()
+	if !ok {
+		c.reject(ctx, frame, coverr.NotFound, "no such request in flight on this connection")
+		return
+	}
+	cancel(agent.ErrRequestCanceled)
+}
+
+// reject tells the client a frame was refused.
+func (c *wsConn) reject(ctx context.Context, frame *client.WSFrame, code coverr.Code, msg string) {
+	out := &client.WSFrame{
+		Type: client.FrameRejected, Ref: frame.Ref, RequestID: frame.RequestID,
+		Code: code, Error: msg, Retriable: code.Retriable(),
+	}
+	if err := wsjson.Write(ctx, c.conn, out); err != nil {
+		c.g.logger.Debug("failed to write websocket frame", "error", err)
+	}
+}
+
+// wsSendError maps a send error to a rejected frame's code and message, the
+// WebSocket counterpart of handleSendError.
+func wsSendError(err error) (coverr.Code, string) {
+	switch code := coverr.CodeOf(err); code {
+	case coverr.Internal:
+		return code, "internal server error"
+	case coverr.AgentOffline:
+		return code, "agent not found"
+	default:
+		return code, err.Error()
+	}
+}
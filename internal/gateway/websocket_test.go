@@ -0,0 +1,141 @@
+// ABOUTME: Tests for the /api/ws WebSocket transport using client.WSClient
+// ABOUTME: Covers streaming a send's events, the per-connection send limit, and rejected frames
+
+package gateway
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/client"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// newWSTestServer serves /api/ws for a gateway whose agent is fed by sender.
+func newWSTestServer(t *testing.T, maxSends int) (*channelSender, *httptest.Server) {
+	t.Helper()
+	gw := newTestGateway(t)
+	gw.config.Server.WebSocketMaxSends = maxSends
+	conn := agent.NewConnection(agent.ConnectionParams{
+		ID: "test-agent", Name: "Test", PrincipalID: "test-agent",
+		Stream: &testMockStream{}, Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := gw.agentManager.Register(conn); err != nil {
+		t.Fatalf("registering agent: %v", err)
+	}
+	sqlStore, ok := gw.store.(*store.SQLStore)
+	if !ok {
+		t.Fatal("store is not *SQLStore")
+	}
+	sender := &channelSender{ch: make(chan *agent.Response)}
+	gw.conversation = conversation.New(sqlStore, sender, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/ws", gw.handleWebSocket)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return sender, srv
+}
+
+func dialTestWS(t *testing.T, ctx context.Context, srv *httptest.Server) *client.WSClient {
+	t.Helper()
+	ws, err := client.DialWS(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"/api/ws", "")
+	if err != nil {
+		t.Fatalf("DialWS: %v", err)
+	}
+	t.Cleanup(func() { _ = ws.Close() })
+	return ws
+}
+
+func TestWebSocket_StreamsSendEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sender, srv := newWSTestServer(t, 0)
+	ws := dialTestWS(t, ctx, srv)
+
+	if err := ws.Send(ctx, "r1", &client.WSSendRequest{Sender: "u", Content: "hello", AgentID: "test-agent"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	started, err := ws.Next(ctx)
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if started.Type != "started" || started.Ref != "r1" || started.RequestID == "" || started.EventID != 1 {
+		t.Fatalf("first frame = %+v, want started for r1", started)
+	}
+
+	sender.ch <- &agent.Response{Event: agent.EventText, Text: "hi"}
+	sender.ch <- &agent.Response{Event: agent.EventDone, Text: "hi", Done: true}
+	for _, want := range []struct {
+		typ  string
+		data string
+	}{
+		{"text", `{"text":"hi"}`},
+		{"done", ""},
+	} {
+		frame, err := ws.Next(ctx)
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if frame.Type != want.typ || frame.RequestID != started.RequestID || frame.Ref != "r1" {
+			t.Fatalf("frame = %+v, want %s for %s", frame, want.typ, started.RequestID)
+		}
+		if want.data != "" && string(frame.Data) != want.data {
+			t.Errorf("%s data = %s, want %s", want.typ, frame.Data, want.data)
+		}
+	}
+}
+
+func TestWebSocket_SendLimit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, srv := newWSTestServer(t, 1)
+	ws := dialTestWS(t, ctx, srv)
+
+	if err := ws.Send(ctx, "r1", &client.WSSendRequest{Sender: "u", Content: "one", AgentID: "test-agent"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if frame, err := ws.Next(ctx); err != nil || frame.Type != "started" {
+		t.Fatalf("first frame = %+v, %v, want started", frame, err)
+	}
+	if err := ws.Send(ctx, "r2", &client.WSSendRequest{Sender: "u", Content: "two", AgentID: "test-agent"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	frame, err := ws.Next(ctx)
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if frame.Type != client.FrameRejected || frame.Ref != "r2" || frame.Code != coverr.RateLimited {
+		t.Errorf("frame = %+v, want r2 rejected with rate_limited", frame)
+	}
+}
+
+func TestWebSocket_RejectsBadFrames(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, srv := newWSTestServer(t, 0)
+	ws := dialTestWS(t, ctx, srv)
+
+	if err := ws.Send(ctx, "r1", &client.WSSendRequest{Sender: "u"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if frame, err := ws.Next(ctx); err != nil || frame.Type != client.FrameRejected || frame.Code != "invalid_request" {
+		t.Errorf("send without content = %+v, %v, want invalid_request", frame, err)
+	}
+
+	if err := ws.Cancel(ctx, "nope"); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if frame, err := ws.Next(ctx); err != nil || frame.Type != client.FrameRejected || frame.Code != "not_found" || frame.RequestID != "nope" {
+		t.Errorf("cancel of unknown request = %+v, %v, want not_found", frame, err)
+	}
+}
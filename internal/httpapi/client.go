@@ -0,0 +1,73 @@
+// ABOUTME: Client-side helpers for reading v1 list endpoints.
+// ABOUTME: FetchPage decodes one List envelope; FetchAll follows next_cursor to the end.
+
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// maxFetchPages stops FetchAll from looping forever on a misbehaving server.
+const maxFetchPages = 1000
+
+// FetchPage GETs one page of a list endpoint. rawURL may already carry
+// filters; cursor, when non-empty, is added as the cursor parameter.
+func FetchPage[T any](ctx context.Context, c *http.Client, rawURL, cursor string, header http.Header) (List[T], error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return List[T]{}, fmt.Errorf("parsing url: %w", err)
+	}
+	if cursor != "" {
+		q := u.Query()
+		q.Set(CursorParam, cursor)
+		u.RawQuery = q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return List[T]{}, fmt.Errorf("creating request: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return List[T]{}, fmt.Errorf("requesting %s: %w", u.Path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return List[T]{}, fmt.Errorf("%s: %s: %s", u.Path, resp.Status, body.Error)
+	}
+	var list List[T]
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return List[T]{}, fmt.Errorf("decoding %s: %w", u.Path, err)
+	}
+	return list, nil
+}
+
+// FetchAll GETs every page of a list endpoint and returns the items in order.
+func FetchAll[T any](ctx context.Context, c *http.Client, rawURL string, header http.Header) ([]T, error) {
+	var items []T
+	cursor := ""
+	for range maxFetchPages {
+		page, err := FetchPage[T](ctx, c, rawURL, cursor, header)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		if page.NextCursor == "" {
+			return items, nil
+		}
+		cursor = page.NextCursor
+	}
+	return nil, fmt.Errorf("%s: more than %d pages", rawURL, maxFetchPages)
+}
@@ -0,0 +1,46 @@
+// Package httpapi holds the conventions shared by the versioned JSON HTTP
+// API under /api/v1.
+//
+// # List envelope
+//
+// Every v1 list endpoint answers with the same envelope:
+//
+//	{"items": [...], "next_cursor": "…", "total": 12}
+//
+// next_cursor is omitted on the last page and total is omitted where
+// counting would need an extra query. Requests take two query parameters:
+//
+//   - limit: page size, a positive integer; values over the endpoint's
+//     maximum are capped
+//   - cursor: the next_cursor of the previous page, passed back unchanged
+//
+// Cursors are opaque. A malformed or foreign cursor is a 400, never a 500.
+//
+// # Handlers
+//
+// Routes registers every endpoint: Handle for one method and path,
+// HandleList for list endpoints, which parses the page, calls a ListFunc,
+// and writes the envelope or a {"error": "..."} body. In-memory lists use
+// Paginate (offset cursors, with total); store-backed lists wrap the
+// store's own cursor with EncodeCursor.
+//
+// # OpenAPI document
+//
+// Each registration carries an Operation naming the Go types of its request
+// and response bodies. BuildDocument derives their schemas by reflection
+// over json struct tags, so the document follows the types rather than a
+// hand-written copy. SSE event payloads are Event components, and types
+// with custom MarshalJSON describe themselves through Shaper.
+//
+// # Clients
+//
+// Go callers read list endpoints with FetchPage, or FetchAll to follow
+// next_cursor until the last page.
+//
+// # Legacy routes
+//
+// The unversioned /api/... routes keep their original shapes for existing
+// TUIs and bridges. They register through HandleDeprecated, which adds a
+// Deprecation header and a Link to the /api/v1 successor. Unversioned lets
+// a v1 operation reuse a legacy handler that parses its own path.
+package httpapi
@@ -0,0 +1,182 @@
+// ABOUTME: Shared list envelope and cursor pagination for the versioned JSON HTTP API.
+// ABOUTME: List routes parse limit/cursor with ParsePage and answer with a List envelope.
+
+package httpapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+)
+
+// Query parameter names shared by every list endpoint.
+const (
+	LimitParam  = "limit"
+	CursorParam = "cursor"
+)
+
+// cursorVersion prefixes every encoded cursor so the format can change
+// without misreading cursors issued by an older gateway.
+const cursorVersion = "c1:"
+
+// offsetPrefix marks cursors produced by Paginate.
+const offsetPrefix = "o:"
+
+// ErrInvalidCursor is returned for a cursor this API did not issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Limits bounds the page size of a list endpoint.
+type Limits struct {
+	Default int
+	Max     int
+}
+
+// Page is a parsed list request. Cursor holds the decoded position, which
+// only the handler that issued it knows how to interpret.
+type Page struct {
+	Limit  int
+	Cursor string
+}
+
+// List is the envelope every versioned list endpoint returns. NextCursor is
+// empty on the last page; Total is set only when it is cheap to compute.
+type List[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+}
+
+// EncodeCursor wraps a handler-specific position in an opaque cursor.
+func EncodeCursor(position string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorVersion + position))
+}
+
+// DecodeCursor returns the position inside a cursor made by EncodeCursor.
+func DecodeCursor(cursor string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorVersion) {
+		return "", ErrInvalidCursor
+	}
+	return strings.TrimPrefix(string(raw), cursorVersion), nil
+}
+
+// ParsePage reads the limit and cursor query parameters. A missing limit
+// uses limits.Default and a larger one is capped at limits.Max.
+func ParsePage(r *http.Request, limits Limits) (Page, error) {
+	q := r.URL.Query()
+	page := Page{Limit: limits.Default}
+	if s := q.Get(LimitParam); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return Page{}, Errorf(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		page.Limit = min(n, limits.Max)
+	}
+	if c := q.Get(CursorParam); c != "" {
+		pos, err := DecodeCursor(c)
+		if err != nil {
+			return Page{}, Errorf(http.StatusBadRequest, "invalid cursor")
+		}
+		page.Cursor = pos
+	}
+	return page, nil
+}
+
+// Paginate returns one page of an in-memory list using offset cursors, with
+// Total set to len(all). Callers must pass items in a stable order.
+func Paginate[T any](all []T, page Page) (List[T], error) {
+	offset := 0
+	if page.Cursor != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(page.Cursor, offsetPrefix))
+		if !strings.HasPrefix(page.Cursor, offsetPrefix) || err != nil || n < 0 {
+			return List[T]{}, Errorf(http.StatusBadRequest, "invalid cursor")
+		}
+		offset = min(n, len(all))
+	}
+	end := min(offset+page.Limit, len(all))
+
+	total := len(all)
+	list := List[T]{Items: all[offset:end], Total: &total}
+	if end < len(all) {
+		list.NextCursor = EncodeCursor(offsetPrefix + strconv.Itoa(end))
+	}
+	if list.Items == nil {
+		list.Items = []T{}
+	}
+	return list, nil
+}
+
+// Error is a list failure with the HTTP status to report. Location, when
+// set, answers with a permanent redirect instead of an error body.
+type Error struct {
+	Status   int
+	Message  string
+	Location string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Errorf returns an *Error with the given status.
+func Errorf(status int, format string, args ...any) error {
+	return &Error{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// Redirect returns an error that answers with a permanent redirect.
+func Redirect(location string) error {
+	return &Error{Status: http.StatusPermanentRedirect, Location: location}
+}
+
+// WriteError writes err as {"error": message, "code": code}, the code
+// following from the status. Errors other than *Error and ErrInvalidCursor
+// are logged and reported as 500.
+func WriteError(w http.ResponseWriter, r *http.Request, err error, logger *slog.Logger) {
+	var apiErr *Error
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Location != "":
+		target := apiErr.Location
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, apiErr.Status)
+		return
+	case errors.As(err, &apiErr):
+	case errors.Is(err, ErrInvalidCursor):
+		apiErr = &Error{Status: http.StatusBadRequest, Message: "invalid cursor"}
+	default:
+		logger.Error("list request failed", "path", r.URL.Path, "error", err)
+		apiErr = &Error{Status: http.StatusInternalServerError, Message: "internal server error"}
+	}
+	writeJSON(w, apiErr.Status, ErrorResponse{
+		Error: apiErr.Message,
+		Code:  string(coverr.ForHTTPStatus(apiErr.Status)),
+	}, logger)
+}
+
+// WriteList writes list as the 200 response.
+func WriteList[T any](w http.ResponseWriter, list List[T], logger *slog.Logger) {
+	writeJSON(w, http.StatusOK, list, logger)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Debug("failed to encode response", "error", err)
+	}
+}
+
+// MarkDeprecated flags a legacy /api/... response as superseded by the
+// matching /api/v1/... route.
+func MarkDeprecated(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Deprecation", "true")
+	if rest, ok := strings.CutPrefix(r.URL.Path, "/api/"); ok {
+		w.Header().Set("Link", fmt.Sprintf(`<%s/%s>; rel="successor-version"`, V1Prefix, rest))
+	}
+}
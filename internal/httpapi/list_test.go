@@ -0,0 +1,101 @@
+// ABOUTME: Tests for the list envelope, page parsing, and offset pagination.
+// ABOUTME: Exercises HandleList end to end through FetchAll.
+
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestParsePage(t *testing.T) {
+	limits := Limits{Default: 20, Max: 100}
+	for query, want := range map[string]int{"": 20, "?limit=5": 5, "?limit=1000": 100} {
+		page, err := ParsePage(httptest.NewRequest(http.MethodGet, "/x"+query, nil), limits)
+		if err != nil || page.Limit != want {
+			t.Errorf("ParsePage(%q) = %+v, %v; want limit %d", query, page, err, want)
+		}
+	}
+
+	for _, query := range []string{"?limit=0", "?limit=-1", "?limit=ten", "?cursor=!!!", "?cursor=YWJj"} {
+		_, err := ParsePage(httptest.NewRequest(http.MethodGet, "/x"+query, nil), limits)
+		var apiErr *Error
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+			t.Errorf("ParsePage(%q) error = %v, want a 400", query, err)
+		}
+	}
+
+	page, err := ParsePage(httptest.NewRequest(http.MethodGet, "/x?cursor="+EncodeCursor("pos-7"), nil), limits)
+	if err != nil || page.Cursor != "pos-7" {
+		t.Errorf("cursor round trip = %+v, %v", page, err)
+	}
+}
+
+func TestPaginate_WalksAllPages(t *testing.T) {
+	all := []int{1, 2, 3, 4, 5}
+	var got []int
+	page := Page{Limit: 2}
+	for range 10 {
+		list, err := Paginate(all, page)
+		if err != nil {
+			t.Fatalf("Paginate: %v", err)
+		}
+		if list.Total == nil || *list.Total != 5 {
+			t.Errorf("total = %v, want 5", list.Total)
+		}
+		got = append(got, list.Items...)
+		if list.NextCursor == "" {
+			break
+		}
+		if page.Cursor, err = DecodeCursor(list.NextCursor); err != nil {
+			t.Fatalf("DecodeCursor: %v", err)
+		}
+	}
+	if !slices.Equal(got, all) {
+		t.Errorf("items = %v, want %v", got, all)
+	}
+
+	empty, err := Paginate([]int(nil), Page{Limit: 2})
+	if err != nil || empty.Items == nil || len(empty.Items) != 0 {
+		t.Errorf("empty list = %+v, %v; want non-nil empty items", empty, err)
+	}
+	if _, err := Paginate(all, Page{Limit: 2, Cursor: "k:abc"}); err == nil {
+		t.Error("Paginate accepted a cursor it did not issue")
+	}
+}
+
+func TestHandleList_FetchAll(t *testing.T) {
+	mux := http.NewServeMux()
+	rs := NewRoutes(mux, nil, nil)
+	names := []string{"a", "b", "c", "d", "e", "f", "g"}
+	HandleList(rs, Operation{Path: "/api/v1/names"}, Limits{Default: 3, Max: 3}, func(_ *http.Request, page Page) (List[string], error) {
+		return Paginate(names, page)
+	})
+	HandleList(rs, Operation{Path: "/api/v1/broken"}, Limits{Default: 3, Max: 3}, func(*http.Request, Page) (List[string], error) {
+		return List[string]{}, Errorf(http.StatusServiceUnavailable, "not configured")
+	})
+	if !slices.Equal(rs.Lists(), []string{"/api/v1/names", "/api/v1/broken"}) {
+		t.Errorf("Lists() = %v", rs.Lists())
+	}
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	got, err := FetchAll[string](context.Background(), srv.Client(), srv.URL+"/api/v1/names", nil)
+	if err != nil || !slices.Equal(got, names) {
+		t.Errorf("FetchAll = %v, %v; want %v", got, err, names)
+	}
+	if _, err := FetchAll[string](context.Background(), srv.Client(), srv.URL+"/api/v1/broken", nil); err == nil {
+		t.Error("FetchAll succeeded against a failing endpoint")
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/names", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,500 @@
+// ABOUTME: OpenAPI 3.0 document for the operations registered through Routes.
+// ABOUTME: Schemas come from the Go types on the wire, by reflection over their json struct tags.
+
+package httpapi
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification the document follows.
+const OpenAPIVersion = "3.0.3"
+
+// Security scheme names used in the document.
+const (
+	BearerAuth  = "bearerAuth"  // Authorization: Bearer <JWT or API token>
+	SessionAuth = "sessionAuth" // the web admin session cookie, accepted on WebSocket upgrades
+)
+
+// Operation describes one endpoint for the OpenAPI document. Request and
+// Response are values of the Go types encoded on the wire, usually zero
+// values; the document derives their schemas, so a field added to a type
+// appears without editing the route.
+type Operation struct {
+	Method      string
+	Path        string // the ServeMux path, e.g. /api/v1/threads/{id}
+	Summary     string
+	Description string
+	Tag         string
+	Query       []Param
+	Request     any // JSON request body, nil for none
+	Response    any // JSON body of the success response, nil for none
+	Status      int // success status; 0 means 200
+	// Events names the SSE events of a text/event-stream response, each
+	// documented by the Event registered under that name.
+	Events []string
+	// ContentType is the success content type of a body that is neither
+	// JSON nor SSE, such as an attachment download.
+	ContentType string
+	Admin       bool // only admin principals may call it
+	Public      bool // no authentication
+	WebSocket   bool // upgrades to a WebSocket, which also accepts SessionAuth
+	// Frame is the JSON message exchanged on a WebSocket in both directions.
+	Frame any
+}
+
+// Param is a query parameter.
+type Param struct {
+	Name        string
+	Description string
+	Type        string // JSON schema type; empty means string
+	Required    bool
+}
+
+// Event is one Server-Sent Event, documented as a component schema named
+// by EventComponent. Data is a struct value shaped like the event's data.
+type Event struct {
+	Name        string
+	Description string
+	Data        any
+}
+
+// EventComponent names the component schema of an SSE event: SSEToolUse
+// for tool_use.
+func EventComponent(event string) string {
+	return "SSE" + camel(event)
+}
+
+// Shaper is implemented by types whose MarshalJSON does not follow their
+// fields. The document describes the value JSONShape returns instead.
+type Shaper interface {
+	JSONShape() any
+}
+
+// ErrorResponse is the body of JSON error responses.
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// Document is an OpenAPI 3.0 document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info is the document's info object.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations on one path by lowercase method.
+type PathItem map[string]*OperationObject
+
+// OperationObject is one operation in the document.
+type OperationObject struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	// Events maps each SSE event the response may carry to its schema.
+	Events map[string]*Schema `json:"x-sse-events,omitempty"`
+	Frame  *Schema            `json:"x-websocket-frame,omitempty"`
+}
+
+// Parameter is a path or query parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is an operation's request body.
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response is one response of an operation.
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a body in one content type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the reusable schemas and security schemes.
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme is an authentication scheme.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Schema is the subset of the OpenAPI schema object the document uses. The
+// zero Schema accepts any value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// BuildDocument builds the document for ops, with events as SSE<Name>
+// component schemas. sessionCookie is the cookie SessionAuth reads.
+func BuildDocument(info Info, ops []Operation, events []Event, sessionCookie string) *Document {
+	gen := newSchemaGen()
+	doc := &Document{
+		OpenAPI: OpenAPIVersion,
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas: gen.components,
+			SecuritySchemes: map[string]*SecurityScheme{
+				BearerAuth: {
+					Type: "http", Scheme: "bearer", BearerFormat: "JWT",
+					Description: "A principal's JWT or API token. Not checked when the gateway runs without auth.",
+				},
+				SessionAuth: {
+					Type: "apiKey", In: "cookie", Name: sessionCookie,
+					Description: "A web admin session, accepted on WebSocket upgrades where browsers cannot set headers.",
+				},
+			},
+		},
+	}
+	errorRef := gen.schema(reflect.TypeFor[ErrorResponse]())
+
+	eventRefs := make(map[string]*Schema, len(events))
+	for _, ev := range events {
+		s := gen.structSchema(reflect.TypeOf(ev.Data))
+		s.Description = ev.Description
+		name := EventComponent(ev.Name)
+		gen.components[name] = s
+		eventRefs[ev.Name] = &Schema{Ref: componentPrefix + name}
+	}
+
+	for _, op := range ops {
+		specPath, params := pathParams(op.Path)
+		item := doc.Paths[specPath]
+		if item == nil {
+			item = &PathItem{}
+			doc.Paths[specPath] = item
+		}
+		obj := &OperationObject{
+			OperationID: operationID(op.Method, specPath),
+			Summary:     op.Summary,
+			Description: op.Description,
+			Parameters:  params,
+			Responses:   map[string]*Response{"default": {Description: "Error", Content: jsonContent(errorRef)}},
+		}
+		if op.Tag != "" {
+			obj.Tags = []string{op.Tag}
+		}
+		if op.Admin {
+			obj.Description = strings.TrimSpace(obj.Description + "\n\nRequires an admin principal.")
+		}
+		for _, q := range op.Query {
+			typ := q.Type
+			if typ == "" {
+				typ = "string"
+			}
+			obj.Parameters = append(obj.Parameters, &Parameter{
+				Name: q.Name, In: "query", Description: q.Description, Required: q.Required, Schema: &Schema{Type: typ},
+			})
+		}
+		if op.Request != nil {
+			obj.RequestBody = &RequestBody{Required: true, Content: jsonContent(gen.schema(reflect.TypeOf(op.Request)))}
+		}
+		if !op.Public {
+			obj.Security = []map[string][]string{{BearerAuth: {}}}
+			if op.WebSocket {
+				obj.Security = append(obj.Security, map[string][]string{SessionAuth: {}})
+			}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := &Response{Description: http.StatusText(status)}
+		if len(op.Events) > 0 {
+			obj.Events = make(map[string]*Schema, len(op.Events))
+			for _, name := range op.Events {
+				obj.Events[name] = eventRefs[name]
+			}
+		}
+		switch {
+		case op.WebSocket:
+			status = http.StatusSwitchingProtocols
+			success.Description = "Switched to the WebSocket protocol"
+			if op.Frame != nil {
+				obj.Frame = gen.schema(reflect.TypeOf(op.Frame))
+			}
+		case len(op.Events) > 0:
+			success.Content = map[string]*MediaType{"text/event-stream": {Schema: &Schema{
+				Type:        "string",
+				Description: "Server-Sent Events: " + strings.Join(op.Events, ", ") + ". x-sse-events has the data of each.",
+			}}}
+		case op.ContentType != "":
+			success.Content = map[string]*MediaType{op.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+		case op.Response != nil:
+			success.Content = jsonContent(gen.schema(reflect.TypeOf(op.Response)))
+		}
+		obj.Responses[strconv.Itoa(status)] = success
+		(*item)[strings.ToLower(op.Method)] = obj
+	}
+	return doc
+}
+
+func jsonContent(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: s}}
+}
+
+// pathParams converts a ServeMux path to the document's form, dropping the
+// "..." of rest wildcards, and returns its path parameters.
+func pathParams(muxPath string) (string, []*Parameter) {
+	var params []*Parameter
+	segments := strings.Split(muxPath, "/")
+	for i, seg := range segments {
+		if !strings.HasPrefix(seg, "{") || !strings.HasSuffix(seg, "}") {
+			continue
+		}
+		name := strings.TrimSuffix(seg[1:len(seg)-1], "...")
+		segments[i] = "{" + name + "}"
+		params = append(params, &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID derives a stable ID such as getThreadsIdUsage from the method
+// and path, leaving out the /api/v1 prefix.
+func operationID(method, specPath string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	rest := strings.TrimPrefix(strings.TrimPrefix(specPath, "/api/v1"), "/api")
+	for seg := range strings.SplitSeq(rest, "/") {
+		b.WriteString(camel(strings.Trim(seg, "{}")))
+	}
+	return b.String()
+}
+
+// camel turns snake, kebab and dotted names into CamelCase.
+func camel(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if r == '_' || r == '-' || r == '.' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+const componentPrefix = "#/components/schemas/"
+
+// schemaGen derives schemas from Go types. Exported named structs become
+// components, referenced wherever they appear; other types are inlined.
+type schemaGen struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaGen() *schemaGen {
+	return &schemaGen{components: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	rawJSONType   = reflect.TypeFor[json.RawMessage]()
+	shaperType    = reflect.TypeFor[Shaper]()
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+func (g *schemaGen) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawJSONType:
+		return &Schema{}
+	}
+	if t.Kind() == reflect.Struct && isComponent(t) {
+		return g.component(t)
+	}
+	if shape, ok := jsonShape(t); ok {
+		return g.schema(shape)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	default:
+		return &Schema{}
+	}
+}
+
+// isComponent reports whether t gets a schema of its own: exported, named,
+// and not generic.
+func isComponent(t reflect.Type) bool {
+	name := t.Name()
+	return name != "" && unicode.IsUpper(rune(name[0])) && !strings.Contains(name, "[")
+}
+
+// jsonShape returns the type a Shaper marshals as.
+func jsonShape(t reflect.Type) (reflect.Type, bool) {
+	v := reflect.New(t)
+	if !t.Implements(shaperType) && !v.Type().Implements(shaperType) {
+		return nil, false
+	}
+	return reflect.TypeOf(v.Interface().(Shaper).JSONShape()), true
+}
+
+// component returns a reference to t's component, adding it on first use.
+// Types from different packages that share a name are told apart by
+// package: the second Thread from store becomes StoreThread.
+func (g *schemaGen) component(t reflect.Type) *Schema {
+	if name, ok := g.names[t]; ok {
+		return &Schema{Ref: componentPrefix + name}
+	}
+	name := t.Name()
+	if _, taken := g.components[name]; taken {
+		name = camel(path.Base(t.PkgPath())) + name
+	}
+	g.names[t] = name
+	g.components[name] = &Schema{} // placeholder for self-referencing types
+
+	var s *Schema
+	if shape, ok := jsonShape(t); ok {
+		s = g.schema(shape)
+	} else {
+		s = g.structSchema(t)
+	}
+	g.components[name] = s
+	return &Schema{Ref: componentPrefix + name}
+}
+
+// structSchema follows encoding/json: json tags name and omit fields, and
+// the fields of untagged embedded structs are promoted.
+func (g *schemaGen) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		s.Description = "Custom JSON encoding."
+		return s
+	}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := g.structSchema(ft)
+				for k, v := range embedded.Properties {
+					s.Properties[k] = v
+				}
+				s.Required = append(s.Required, embedded.Required...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := g.schema(f.Type)
+		optional := slices.Contains(strings.Split(opts, ","), "omitempty") || slices.Contains(strings.Split(opts, ","), "omitzero")
+		if f.Type.Kind() == reflect.Pointer && !optional && prop.Ref == "" {
+			copied := *prop
+			copied.Nullable = true
+			prop = &copied
+		}
+		s.Properties[name] = prop
+		if !optional {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// ServeDocument serves doc as JSON. It is encoded once, when called.
+func ServeDocument(doc *Document, logger *slog.Logger) http.Handler {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		logger.Error("failed to encode OpenAPI document", "error", err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			WriteError(w, r, err, logger)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+}
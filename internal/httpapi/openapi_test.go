@@ -0,0 +1,194 @@
+// ABOUTME: Tests for the OpenAPI document: schemas derived from Go types, operations,
+// ABOUTME: the document served through Routes, and /api/v1 requests served by legacy handlers.
+
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type testNode struct {
+	testBase
+	Name     string          `json:"name"`
+	Note     string          `json:"note,omitempty"`
+	Parent   *string         `json:"parent"`
+	Children []*TestNode     `json:"children,omitempty"`
+	Meta     json.RawMessage `json:"meta,omitempty"`
+	Hidden   string          `json:"-"`
+}
+
+// TestNode is exported so it becomes a component.
+type TestNode testNode
+
+type shaped struct{}
+
+func (shaped) MarshalJSON() ([]byte, error) { return []byte(`{"kind":"x"}`), nil }
+func (shaped) JSONShape() any {
+	return struct {
+		Kind string `json:"kind"`
+	}{}
+}
+
+type opaque struct{}
+
+func (opaque) MarshalJSON() ([]byte, error) { return []byte(`{}`), nil }
+
+type testEvent struct {
+	Text string `json:"text"`
+}
+
+func TestSchemaGen(t *testing.T) {
+	gen := newSchemaGen()
+	ref := gen.schema(reflect.TypeOf(TestNode{}))
+	if ref.Ref != componentPrefix+"TestNode" {
+		t.Fatalf("ref = %+v, want a TestNode component", ref)
+	}
+	node := gen.components["TestNode"]
+	for _, name := range []string{"id", "created", "name", "note", "parent", "children", "meta"} {
+		if node.Properties[name] == nil {
+			t.Errorf("property %q missing from %v", name, node.Properties)
+		}
+	}
+	for _, name := range []string{"Hidden", "testBase"} {
+		if node.Properties[name] != nil {
+			t.Errorf("property %q should not be documented", name)
+		}
+	}
+	if !slices.Equal(node.Required, []string{"id", "created", "name", "parent"}) {
+		t.Errorf("required = %v", node.Required)
+	}
+	if created := node.Properties["created"]; created.Format != "date-time" {
+		t.Errorf("created = %+v, want a date-time", created)
+	}
+	if !node.Properties["parent"].Nullable {
+		t.Error("parent should be nullable")
+	}
+	if meta := node.Properties["meta"]; meta.Type != "" {
+		t.Errorf("meta = %+v, want any value", meta)
+	}
+
+	if items := node.Properties["children"].Items; items.Ref != componentPrefix+"TestNode" {
+		t.Errorf("children items = %+v, want a reference back to TestNode", items)
+	}
+
+	if s := gen.schema(reflect.TypeOf(shaped{})); s.Properties["kind"] == nil {
+		t.Errorf("shaped = %+v, want the JSONShape fields", s)
+	}
+	if s := gen.schema(reflect.TypeOf(opaque{})); s.Type != "object" || len(s.Properties) != 0 {
+		t.Errorf("opaque = %+v, want an undescribed object", s)
+	}
+}
+
+func TestBuildDocument(t *testing.T) {
+	ops := []Operation{
+		{Method: http.MethodGet, Path: "/api/v1/nodes/{id}", Response: TestNode{}, Query: []Param{{Name: "depth", Type: "integer"}}},
+		{Method: http.MethodPost, Path: "/api/v1/nodes", Request: TestNode{}, Status: http.StatusCreated, Admin: true},
+		{Method: http.MethodPost, Path: "/api/v1/nodes/{id}/stream", Events: []string{"text"}},
+		{Method: http.MethodGet, Path: "/api/v1/ws", WebSocket: true, Frame: testEvent{}},
+		{Method: http.MethodGet, Path: "/api/v1/files/{path...}", ContentType: "application/octet-stream", Public: true},
+	}
+	doc := BuildDocument(Info{Title: "test", Version: "1"}, ops, []Event{{Name: "text", Data: testEvent{}}}, "session")
+
+	get := (*doc.Paths["/api/v1/nodes/{id}"])["get"]
+	if get == nil || get.OperationID != "getNodesId" {
+		t.Fatalf("GET /nodes/{id} = %+v", get)
+	}
+	if len(get.Parameters) != 2 || get.Parameters[0].In != "path" || get.Parameters[1].Schema.Type != "integer" {
+		t.Errorf("parameters = %+v", get.Parameters)
+	}
+	if s := get.Responses["200"].Content["application/json"].Schema; s.Ref != componentPrefix+"TestNode" {
+		t.Errorf("200 schema = %+v", s)
+	}
+	if s := get.Responses["default"].Content["application/json"].Schema; s.Ref != componentPrefix+"ErrorResponse" {
+		t.Errorf("default schema = %+v", s)
+	}
+
+	post := (*doc.Paths["/api/v1/nodes"])["post"]
+	if post.RequestBody == nil || post.Responses["201"] == nil || post.Description != "Requires an admin principal." {
+		t.Errorf("POST /nodes = %+v", post)
+	}
+
+	stream := (*doc.Paths["/api/v1/nodes/{id}/stream"])["post"]
+	if stream.Events["text"].Ref != componentPrefix+EventComponent("text") || stream.Responses["200"].Content["text/event-stream"] == nil {
+		t.Errorf("stream = %+v", stream)
+	}
+	if doc.Components.Schemas["SSEText"].Properties["text"] == nil {
+		t.Errorf("SSEText = %+v", doc.Components.Schemas["SSEText"])
+	}
+
+	ws := (*doc.Paths["/api/v1/ws"])["get"]
+	if ws.Responses["101"] == nil || ws.Frame == nil || len(ws.Security) != 2 {
+		t.Errorf("ws = %+v", ws)
+	}
+	files := (*doc.Paths["/api/v1/files/{path}"])["get"]
+	if files == nil || files.Security != nil || files.Responses["200"].Content["application/octet-stream"] == nil {
+		t.Errorf("files = %+v", files)
+	}
+	if doc.Components.SecuritySchemes[SessionAuth].Name != "session" {
+		t.Errorf("session scheme = %+v", doc.Components.SecuritySchemes[SessionAuth])
+	}
+	if _, err := json.Marshal(doc); err != nil {
+		t.Errorf("marshal: %v", err)
+	}
+}
+
+func TestRoutes_HandleDocument(t *testing.T) {
+	mux := http.NewServeMux()
+	rs := NewRoutes(mux, nil, nil)
+	rs.HandleDocument(Operation{Method: http.MethodGet, Path: "/api/openapi.json", Public: true}, Info{Title: "test"}, nil, "session")
+	rs.Handle(Operation{Method: http.MethodGet, Path: "/api/v1/later"}, http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	var doc Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+	if doc.Paths["/api/v1/later"] == nil || doc.Paths["/api/openapi.json"] == nil {
+		t.Errorf("paths = %v, want both operations", doc.Paths)
+	}
+}
+
+func TestUnversioned(t *testing.T) {
+	var gotPath, gotPrefix string
+	h := Unversioned(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		gotPath, gotPrefix = r.URL.Path, APIPrefix(r)
+	}))
+	for path, want := range map[string][2]string{
+		"/api/v1/threads/t1/usage": {"/api/threads/t1/usage", "/api/v1"},
+		"/api/threads/t1/usage":    {"/api/threads/t1/usage", "/api"},
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		if gotPath != want[0] || gotPrefix != want[1] {
+			t.Errorf("%s: path %s, prefix %s; want %s, %s", path, gotPath, gotPrefix, want[0], want[1])
+		}
+	}
+}
+
+func TestRoutes_HandleDeprecated(t *testing.T) {
+	mux := http.NewServeMux()
+	rs := NewRoutes(mux, nil, nil)
+	rs.HandleDeprecated("/api/nodes", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/nodes", nil))
+	if rec.Header().Get("Deprecation") == "" || rec.Header().Get("Link") == "" {
+		t.Errorf("headers = %v, want Deprecation and Link", rec.Header())
+	}
+	if !slices.Equal(rs.Deprecated(), []string{"/api/nodes"}) || len(rs.Operations()) != 0 {
+		t.Errorf("deprecated = %v, operations = %v", rs.Deprecated(), rs.Operations())
+	}
+}
@@ -0,0 +1,175 @@
+// ABOUTME: Registration of versioned API routes, recorded for the OpenAPI document.
+// ABOUTME: Routes remembers every pattern so tests can check the document and the mux agree.
+
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// V1Prefix is the path prefix of the versioned API.
+const V1Prefix = "/api/v1"
+
+// ListFunc serves one page of a list endpoint.
+type ListFunc[T any] func(r *http.Request, page Page) (List[T], error)
+
+// Routes registers API routes on a mux. Every handler is passed through
+// wrap (typically auth middleware) before registration, and every route is
+// recorded: operations for the OpenAPI document, patterns for tests.
+type Routes struct {
+	mux    *http.ServeMux
+	wrap   func(http.Handler) http.Handler
+	logger *slog.Logger
+	rec    *record
+}
+
+// record is shared by a Routes and every registrar made from it by With.
+type record struct {
+	ops        []Operation
+	lists      []string
+	patterns   []string
+	deprecated []string
+}
+
+// NewRoutes creates a route registrar. A nil wrap registers handlers as-is.
+func NewRoutes(mux *http.ServeMux, wrap func(http.Handler) http.Handler, logger *slog.Logger) *Routes {
+	if wrap == nil {
+		wrap = func(h http.Handler) http.Handler { return h }
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Routes{mux: mux, wrap: wrap, logger: logger, rec: &record{}}
+}
+
+// With returns a registrar for the same mux and record whose handlers are
+// wrapped by wrap instead, for routes needing different middleware.
+func (rs *Routes) With(wrap func(http.Handler) http.Handler) *Routes {
+	return &Routes{mux: rs.mux, wrap: wrap, logger: rs.logger, rec: rs.rec}
+}
+
+// Handle registers h for op.Method on op.Path and adds op to the document.
+func (rs *Routes) Handle(op Operation, h http.Handler) {
+	pattern := op.Method + " " + op.Path
+	rs.mux.Handle(pattern, rs.wrap(h))
+	rs.rec.ops = append(rs.rec.ops, op)
+	rs.rec.patterns = append(rs.rec.patterns, pattern)
+}
+
+// HandleDeprecated registers h on an unversioned /api/... pattern kept for
+// existing clients. Every response is marked with MarkDeprecated. The route
+// stays out of the document, which describes its /api/v1 successor.
+func (rs *Routes) HandleDeprecated(pattern string, h http.Handler) {
+	rs.mux.Handle(pattern, rs.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		MarkDeprecated(w, r)
+		h.ServeHTTP(w, r)
+	})))
+	rs.rec.patterns = append(rs.rec.patterns, pattern)
+	rs.rec.deprecated = append(rs.rec.deprecated, pattern)
+}
+
+// HandleList registers a GET list endpoint at op.Path (a ServeMux path).
+// The handler parses the page, calls fn, and writes the List envelope or an
+// error; the document gains the limit and cursor parameters.
+func HandleList[T any](rs *Routes, op Operation, limits Limits, fn ListFunc[T]) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, err := ParsePage(r, limits)
+		if err != nil {
+			WriteError(w, r, err, rs.logger)
+			return
+		}
+		list, err := fn(r, page)
+		if err != nil {
+			WriteError(w, r, err, rs.logger)
+			return
+		}
+		if list.Items == nil {
+			list.Items = []T{}
+		}
+		WriteList(w, list, rs.logger)
+	})
+	op.Method = http.MethodGet
+	op.Response = List[T]{}
+	op.Query = append(slices.Clone(op.Query),
+		Param{Name: LimitParam, Type: "integer", Description: pageSizeDescription(limits)},
+		Param{Name: CursorParam, Description: "The next_cursor of the previous page"},
+	)
+	rs.Handle(op, h)
+	rs.rec.lists = append(rs.rec.lists, op.Path)
+}
+
+func pageSizeDescription(limits Limits) string {
+	return fmt.Sprintf("Page size, default %d, at most %d", limits.Default, limits.Max)
+}
+
+// Lists returns the paths registered with HandleList.
+func (rs *Routes) Lists() []string {
+	return slices.Clone(rs.rec.lists)
+}
+
+// Operations returns the operations registered with Handle and HandleList.
+func (rs *Routes) Operations() []Operation {
+	return slices.Clone(rs.rec.ops)
+}
+
+// Patterns returns every ServeMux pattern registered, in order.
+func (rs *Routes) Patterns() []string {
+	return slices.Clone(rs.rec.patterns)
+}
+
+// Deprecated returns the patterns registered with HandleDeprecated.
+func (rs *Routes) Deprecated() []string {
+	return slices.Clone(rs.rec.deprecated)
+}
+
+// Document builds the OpenAPI document for the registered operations.
+func (rs *Routes) Document(info Info, events []Event, sessionCookie string) *Document {
+	return BuildDocument(info, rs.rec.ops, events, sessionCookie)
+}
+
+// HandleDocument registers op to serve the OpenAPI document of every
+// operation registered on rs, including ones registered after it. The
+// document is built on the first request.
+func (rs *Routes) HandleDocument(op Operation, info Info, events []Event, sessionCookie string) {
+	serve := sync.OnceValue(func() http.Handler {
+		return ServeDocument(rs.Document(info, events, sessionCookie), rs.logger)
+	})
+	rs.Handle(op, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serve().ServeHTTP(w, r)
+	}))
+}
+
+// versionedKey marks requests Unversioned rewrote.
+type versionedKey struct{}
+
+// Unversioned serves /api/v1/... requests with h, a handler that parses the
+// matching unversioned /api/... path, by rewriting the path it sees.
+// APIPrefix still reports the prefix the client used.
+func Unversioned(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, V1Prefix+"/")
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		r2 := r.Clone(context.WithValue(r.Context(), versionedKey{}, true))
+		r2.URL.Path = "/api/" + rest
+		r2.URL.RawPath = ""
+		h.ServeHTTP(w, r2)
+	})
+}
+
+// APIPrefix returns the prefix r was sent to, /api/v1 or the legacy /api,
+// for links that should keep the client on the same version.
+func APIPrefix(r *http.Request) string {
+	if r.Context().Value(versionedKey{}) != nil || strings.HasPrefix(r.URL.Path, V1Prefix+"/") {
+		return V1Prefix
+	}
+	return "/api"
+}
@@ -0,0 +1,29 @@
+// Package logctx carries a request's ID and log attributes through a
+// context, so every layer that handles the request logs the same request_id.
+//
+// # Request IDs
+//
+// HTTPMiddleware gives each HTTP request an ID: the caller's X-Request-ID
+// header when it is a sensible token, otherwise a new UUID. The ID is echoed
+// in the response's X-Request-ID header and stored in the request context,
+// where RequestID reads it back.
+//
+// # Loggers
+//
+// Components keep their own *slog.Logger, tagged with their component name.
+// For work done on behalf of a request they log through FromContext instead,
+// which adds the request's attributes (request_id, and principal_id once the
+// caller is authenticated) to that logger:
+//
+//	logctx.FromContext(ctx, s.logger).Info("thread created", "thread_id", id)
+//
+// With adds further attributes for the rest of the request. Outside a request
+// FromContext returns the component's logger unchanged.
+//
+// # Agents
+//
+// The agent stream is long-lived, so a request ID cannot ride in gRPC
+// metadata. Inject adds it to the string map the gateway already sends as
+// SendMessage.trace_context, under the x-request-id key; agents echo that map
+// on ExecutePackTool, and Extract restores the ID for the tool call.
+package logctx
@@ -0,0 +1,121 @@
+// ABOUTME: Request-scoped logging: a request ID and log attributes carried in a context
+// ABOUTME: HTTPMiddleware assigns the ID; FromContext adds the attributes to a component's logger
+
+package logctx
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Header is the HTTP header a request ID arrives in and is echoed in.
+	Header = "X-Request-ID"
+	// CarrierKey is the key a request ID travels under in the trace context
+	// map sent to agents.
+	CarrierKey = "x-request-id"
+
+	// maxRequestIDLen bounds the caller-supplied request IDs that are kept.
+	maxRequestIDLen = 128
+)
+
+type ctxKey struct{}
+
+// scope is what a context carries for its request.
+type scope struct {
+	requestID string
+	args      []any
+}
+
+func fromContext(ctx context.Context) *scope {
+	s, _ := ctx.Value(ctxKey{}).(*scope)
+	return s
+}
+
+// WithRequestID returns ctx starting a request with ID id, logged as
+// request_id. Attributes of a request ctx was already part of are dropped.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &scope{requestID: id, args: []any{"request_id", id}})
+}
+
+// RequestID returns ctx's request ID, or "" outside a request.
+func RequestID(ctx context.Context) string {
+	if s := fromContext(ctx); s != nil {
+		return s.requestID
+	}
+	return ""
+}
+
+// With returns ctx with args added to the attributes its request logs with.
+// Outside a request ctx is returned unchanged.
+func With(ctx context.Context, args ...any) context.Context {
+	s := fromContext(ctx)
+	if s == nil || len(args) == 0 {
+		return ctx
+	}
+	next := &scope{requestID: s.requestID, args: append(s.args[:len(s.args):len(s.args)], args...)}
+	return context.WithValue(ctx, ctxKey{}, next)
+}
+
+// FromContext returns logger with ctx's request attributes added, or logger
+// itself when ctx is not part of a request.
+func FromContext(ctx context.Context, logger *slog.Logger) *slog.Logger {
+	if s := fromContext(ctx); s != nil {
+		return logger.With(s.args...)
+	}
+	return logger
+}
+
+// Inject returns carrier with ctx's request ID added under CarrierKey,
+// allocating a map if carrier is nil. Without a request ID carrier is
+// returned as is.
+func Inject(ctx context.Context, carrier map[string]string) map[string]string {
+	id := RequestID(ctx)
+	if id == "" {
+		return carrier
+	}
+	if carrier == nil {
+		carrier = make(map[string]string, 1)
+	}
+	carrier[CarrierKey] = id
+	return carrier
+}
+
+// Extract returns ctx carrying the request ID Inject put in carrier, if any.
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if id := carrier[CarrierKey]; validRequestID(id) {
+		return WithRequestID(ctx, id)
+	}
+	return ctx
+}
+
+// HTTPMiddleware assigns each request an ID, taken from the X-Request-ID
+// header when it is valid and generated otherwise, echoes it in the response
+// header, and stores it in the request context.
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID reports whether id is a non-empty token of printable ASCII
+// short enough to log.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
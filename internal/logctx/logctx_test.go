@@ -0,0 +1,108 @@
+// ABOUTME: Tests for request-scoped logging: request ID assignment, log attributes, and agent carriers.
+// ABOUTME: Captures JSON log output to check which attributes each line carries.
+
+package logctx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// lastLine decodes the last JSON log line written to buf.
+func lastLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &rec); err != nil {
+		t.Fatalf("decode log line: %v", err)
+	}
+	return rec
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	var seen string
+	h := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		generate bool
+	}{
+		{name: "honors caller ID", header: "client-req-42"},
+		{name: "generates when absent", generate: true},
+		{name: "replaces ID with spaces", header: "two words", generate: true},
+		{name: "replaces overlong ID", header: strings.Repeat("x", maxRequestIDLen+1), generate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get(Header); got != seen || seen == "" {
+				t.Fatalf("response header = %q, context ID = %q; want the same non-empty ID", got, seen)
+			}
+			if tt.generate == (seen == tt.header) {
+				t.Errorf("request ID = %q for header %q, generated = %v", seen, tt.header, tt.generate)
+			}
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	base := slog.New(slog.NewJSONHandler(&buf, nil)).With("component", "test")
+
+	FromContext(context.Background(), base).Info("outside")
+	if rec := lastLine(t, &buf); rec["request_id"] != nil || rec["component"] != "test" {
+		t.Errorf("outside a request = %v, want the component logger unchanged", rec)
+	}
+
+	ctx := With(WithRequestID(context.Background(), "req-1"), "principal_id", "p-1")
+	FromContext(ctx, base).Info("inside")
+	rec := lastLine(t, &buf)
+	if rec["request_id"] != "req-1" || rec["principal_id"] != "p-1" || rec["component"] != "test" {
+		t.Errorf("inside a request = %v, want request_id, principal_id and component", rec)
+	}
+
+	// Attributes added later do not leak into the parent context.
+	_ = With(ctx, "agent_id", "a-1")
+	FromContext(ctx, base).Info("parent")
+	if rec := lastLine(t, &buf); rec["agent_id"] != nil {
+		t.Errorf("parent context logged %v, want no agent_id", rec)
+	}
+
+	if got := With(context.Background(), "k", "v"); got != context.Background() {
+		t.Error("With outside a request returned a new context")
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	if got := Inject(context.Background(), nil); got != nil {
+		t.Errorf("Inject without a request = %v, want nil", got)
+	}
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	carrier := Inject(ctx, map[string]string{"traceparent": "00-abc-def-01"})
+	if carrier[CarrierKey] != "req-1" || carrier["traceparent"] == "" {
+		t.Fatalf("carrier = %v, want the trace context plus %s", carrier, CarrierKey)
+	}
+
+	if got := RequestID(Extract(context.Background(), carrier)); got != "req-1" {
+		t.Errorf("extracted request ID = %q, want req-1", got)
+	}
+	if got := RequestID(Extract(context.Background(), map[string]string{CarrierKey: "bad id"})); got != "" {
+		t.Errorf("extracted invalid request ID = %q, want none", got)
+	}
+}
@@ -0,0 +1,150 @@
+// ABOUTME: JSON-RPC batch support for the MCP endpoint: an array of requests handled
+// ABOUTME: concurrently by a bounded worker pool, answered with an array in request order.
+
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Batch defaults, used when the Config fields are unset.
+const (
+	DefaultMaxBatchSize = 50
+	DefaultBatchWorkers = 4
+)
+
+// isBatchBody reports whether a request body holds a JSON array.
+func isBatchBody(body []byte) bool {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// handleBatch answers a batch of JSON-RPC requests. Every entry runs against
+// the session named in the request headers; entries fail independently, and
+// notifications get no response. A batch of only notifications is answered
+// with 202 Accepted, like a single notification.
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(body, &entries); err != nil {
+		s.sendJSONRPCError(w, nil, JSONRPCParseError, "invalid JSON")
+		return
+	}
+	if len(entries) == 0 {
+		s.sendJSONRPCError(w, nil, JSONRPCInvalidRequest, "empty batch")
+		return
+	}
+	if len(entries) > s.maxBatchSize {
+		s.sendJSONRPCError(w, nil, JSONRPCInvalidRequest,
+			fmt.Sprintf("batch too large: %d requests, at most %d allowed", len(entries), s.maxBatchSize))
+		return
+	}
+
+	sessionID := r.Header.Get("Mcp-Session-Id")
+	auth, ok := s.validateMCPAuth(w, r, false, sessionID)
+	if !ok {
+		return
+	}
+
+	s.logger.Debug("MCP batch",
+		"size", len(entries),
+		"session_id", sessionID,
+	)
+
+	responses := make([]json.RawMessage, len(entries))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(s.batchWorkers, len(entries)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				responses[i] = s.handleBatchEntry(r, entries[i], auth)
+			}
+		}()
+	}
+	for i := range entries {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	out := make([]json.RawMessage, 0, len(responses))
+	for _, resp := range responses {
+		if resp != nil {
+			out = append(out, resp)
+		}
+	}
+	if len(out) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		s.logger.Warn("failed to encode JSON-RPC batch response", "error", err)
+	}
+}
+
+// handleBatchEntry runs one batch entry and returns its encoded response, or
+// nil for a notification. A panicking handler fails only its own entry.
+func (s *Server) handleBatchEntry(r *http.Request, raw json.RawMessage, auth authInfo) (resp json.RawMessage) {
+	var req JSONRPCRequest
+	rec := &batchEntryRecorder{header: make(http.Header)}
+	defer func() {
+		if p := recover(); p != nil {
+			s.logger.Error("MCP batch entry panicked", "method", req.Method, "panic", p)
+			rec.body.Reset()
+			s.sendJSONRPCError(rec, req.ID, JSONRPCInternalError, "internal error")
+			resp = rec.response()
+		}
+	}()
+
+	if err := json.Unmarshal(raw, &req); err != nil {
+		s.sendJSONRPCError(rec, nil, JSONRPCInvalidRequest, "invalid request")
+		return rec.response()
+	}
+	if req.JSONRPC != "2.0" {
+		s.sendJSONRPCError(rec, req.ID, JSONRPCInvalidRequest, "invalid JSON-RPC version")
+		return rec.response()
+	}
+	if len(req.ID) == 0 || string(req.ID) == "null" {
+		if strings.HasPrefix(req.Method, "notifications/") {
+			s.logger.Debug("accepted MCP notification", "method", req.Method)
+		} else {
+			s.logger.Warn("received notification for non-notification method", "method", req.Method)
+		}
+		return nil
+	}
+	if req.Method == "initialize" {
+		s.sendJSONRPCError(rec, req.ID, JSONRPCInvalidRequest, "initialize must not be part of a batch")
+		return rec.response()
+	}
+
+	handler, exists := s.getMCPMethodHandlers()[req.Method]
+	if !exists {
+		s.sendJSONRPCError(rec, req.ID, JSONRPCMethodNotFound, "method not found")
+		return rec.response()
+	}
+	handler(rec, r, req, auth)
+	return rec.response()
+}
+
+// batchEntryRecorder captures what a method handler writes so its response
+// can be placed in the batch array.
+type batchEntryRecorder struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (b *batchEntryRecorder) Header() http.Header         { return b.header }
+func (b *batchEntryRecorder) Write(p []byte) (int, error) { return b.body.Write(p) }
+func (b *batchEntryRecorder) WriteHeader(int)             {}
+
+// response returns the captured JSON-RPC response without its trailing newline.
+func (b *batchEntryRecorder) response() json.RawMessage {
+	return json.RawMessage(bytes.TrimSpace(b.body.Bytes()))
+}
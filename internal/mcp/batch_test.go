@@ -0,0 +1,134 @@
+// ABOUTME: Tests for JSON-RPC batches on the MCP endpoint.
+// ABOUTME: Covers mixed tools/list and tools/call batches, ordering, notifications, and size limits.
+
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/packs"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestBatchRequests(t *testing.T) {
+	registry := setupTestRegistry(t)
+	if err := registry.RegisterBuiltinPack(&packs.BuiltinPack{
+		ID: "builtin:echo",
+		Tools: []*packs.BuiltinTool{{
+			Definition: &pb.ToolDefinition{Name: "echo", InputSchemaJson: `{"type":"object"}`},
+			Handler: func(_ context.Context, _ string, input json.RawMessage) (json.RawMessage, error) {
+				return input, nil
+			},
+		}},
+	}); err != nil {
+		t.Fatalf("failed to register echo pack: %v", err)
+	}
+	server, err := NewServer(Config{
+		Registry:     registry,
+		Router:       setupTestRouter(t, registry),
+		Logger:       slog.Default(),
+		MaxBatchSize: 6,
+		BatchWorkers: 2,
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	sessionID := initializeSession(t, mux, "")
+
+	post := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Mcp-Session-Id", sessionID)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("answers mixed entries in request order", func(t *testing.T) {
+		rr := post(`[
+			{"jsonrpc":"2.0","id":1,"method":"tools/list"},
+			{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"say":"hi"}}},
+			{"jsonrpc":"2.0","method":"notifications/initialized"},
+			{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"nonexistent-tool"}},
+			42,
+			{"jsonrpc":"2.0","id":"last","method":"tools/call","params":{"name":"echo","arguments":{"say":"bye"}}}
+		]`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rr.Code, rr.Body.String())
+		}
+		var resps []JSONRPCResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resps); err != nil {
+			t.Fatalf("failed to decode batch response: %v", err)
+		}
+
+		wantIDs := []string{"1", "2", "3", "null", `"last"`}
+		if len(resps) != len(wantIDs) {
+			t.Fatalf("got %d responses, want %d (the notification gets none)", len(resps), len(wantIDs))
+		}
+		for i, want := range wantIDs {
+			id := string(resps[i].ID)
+			if id == "" {
+				id = "null"
+			}
+			if id != want {
+				t.Errorf("response %d has id %s, want %s", i, id, want)
+			}
+		}
+		if resps[0].Error != nil || resps[1].Error != nil || resps[4].Error != nil {
+			t.Errorf("successful entries failed: %+v %+v %+v", resps[0].Error, resps[1].Error, resps[4].Error)
+		}
+		if resps[2].Error == nil || resps[2].Error.Code != JSONRPCInvalidParams {
+			t.Errorf("unknown tool entry error = %+v, want invalid params", resps[2].Error)
+		}
+		if resps[3].Error == nil || resps[3].Error.Code != JSONRPCInvalidRequest {
+			t.Errorf("malformed entry error = %+v, want invalid request", resps[3].Error)
+		}
+		if out, _ := json.Marshal(resps[4].Result); !strings.Contains(string(out), "bye") {
+			t.Errorf("last echo result = %s, want it to contain bye", out)
+		}
+	})
+
+	t.Run("acknowledges a batch of notifications", func(t *testing.T) {
+		rr := post(`[{"jsonrpc":"2.0","method":"notifications/initialized"}]`)
+		if rr.Code != http.StatusAccepted || rr.Body.Len() != 0 {
+			t.Errorf("status = %d body %q, want 202 with no body", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("rejects empty and oversized batches", func(t *testing.T) {
+		entry := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+		for name, body := range map[string]string{
+			"empty":     `[]`,
+			"oversized": "[" + strings.Repeat(entry+",", 6) + entry + "]",
+		} {
+			var resp JSONRPCResponse
+			if err := json.NewDecoder(post(body).Body).Decode(&resp); err != nil {
+				t.Fatalf("%s: failed to decode response: %v", name, err)
+			}
+			if resp.Error == nil || resp.Error.Code != JSONRPCInvalidRequest {
+				t.Errorf("%s batch error = %+v, want %d", name, resp.Error, JSONRPCInvalidRequest)
+			}
+		}
+	})
+
+	t.Run("does not allow initialize in a batch", func(t *testing.T) {
+		var resps []JSONRPCResponse
+		if err := json.NewDecoder(post(`[{"jsonrpc":"2.0","id":1,"method":"initialize"}]`).Body).Decode(&resps); err != nil {
+			t.Fatalf("failed to decode batch response: %v", err)
+		}
+		if len(resps) != 1 || resps[0].Error == nil || resps[0].Error.Code != JSONRPCInvalidRequest {
+			t.Errorf("batched initialize = %+v, want an invalid request error", resps)
+		}
+	})
+}
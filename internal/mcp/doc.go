@@ -16,6 +16,14 @@
 // The server does not support server-initiated SSE streams (GET returns 405).
 // Responses are returned directly in the POST response body.
 //
+// A POST body may also be a JSON-RPC batch: an array of requests on one
+// session (initialize cannot be batched). Entries run concurrently on a small
+// worker pool (Config.BatchWorkers) and are answered with an array in request
+// order; notifications get no entry, and an entry's failure does not affect
+// the others. Empty batches and batches over Config.MaxBatchSize are rejected
+// with -32600. The gateway sets both from mcp.max_batch_size and
+// mcp.batch_workers in its config.
+//
 // # Authentication
 //
 // The server uses token-based authentication:
@@ -25,6 +33,10 @@
 // Tokens are managed via the TokenStore and map to principals with specific
 // capabilities. Only tools matching the principal's capabilities are exposed.
 //
+// Bearer API tokens may be scoped when created: a capability subset narrows
+// the principal's granted capabilities, and an agent list refuses tools/call
+// with a "permission denied" error for any other agent.
+//
 // # Tool Discovery
 //
 // Clients call tools/list to discover available tools:
@@ -35,7 +47,13 @@
 //	  "id": 1
 //	}
 //
-// Response includes tool schemas in JSON Schema format.
+// Response includes tool schemas in JSON Schema format, sorted by name and
+// paged (Config.ToolsPageSize, 50 by default). When more tools follow, the
+// result carries a nextCursor; send it back as params.cursor for the next
+// page. A cursor is tied to the pack registry as it was when issued: once a
+// pack registers or unregisters, the cursor is rejected with an invalid params
+// error and the client lists again from the start. After such a change,
+// initialize advertises tools.listChanged.
 //
 // # Tool Execution
 //
@@ -53,6 +71,20 @@
 //
 // Results are returned in the response body.
 //
+// # Resources
+//
+// When Config.Resources is set, the server also implements resources/list and
+// resources/read over the caller's own data:
+//
+//   - coven://threads/{id} - a thread transcript (text/plain); needs "base"
+//   - coven://notes/{agent}/{key} - an agent note (application/json when the
+//     value is a JSON object or array, text/plain otherwise); needs "notes"
+//
+// Only threads and notes of the token's agent are listed, and reading another
+// principal's resource fails with "access denied". resources/list is paged
+// like tools/list, but its cursor is the last URI returned, so it stays valid
+// as resources come and go.
+//
 // # Architecture
 //
 // Components:
@@ -0,0 +1,299 @@
+// ABOUTME: MCP resources backed by gateway data: thread transcripts and agent notes.
+// ABOUTME: Implements resources/list (paged) and resources/read, scoped to the caller's own data.
+
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// JSONRPCResourceNotFound is the MCP error code for an unknown resource URI.
+const JSONRPCResourceNotFound = -32002
+
+// Resource URI prefixes.
+const (
+	threadResourcePrefix = "coven://threads/"
+	noteResourcePrefix   = "coven://notes/"
+)
+
+// Capabilities needed to see each kind of resource, matching the tools that
+// expose the same data.
+const (
+	threadResourceCapability = "base"
+	noteResourceCapability   = "notes"
+)
+
+// Limits on how much is read for resources.
+const (
+	maxListedThreads    = 1000
+	maxTranscriptEvents = 500
+)
+
+// MIME types of resource contents.
+const (
+	noteJSONMIMEType     = "application/json"
+	resourceTextMIMEType = "text/plain"
+)
+
+// ResourceStore is the gateway data exposed as MCP resources.
+type ResourceStore interface {
+	GetThread(ctx context.Context, id string) (*store.Thread, error)
+	ListAgentThreads(ctx context.Context, agentID string, limit int) ([]*store.Thread, error)
+	GetEventsByThreadID(ctx context.Context, threadID string, limit int) ([]*store.LedgerEvent, error)
+	ListNotes(ctx context.Context, agentID, prefix string) ([]*store.AgentNote, error)
+	GetNote(ctx context.Context, agentID, key string) (*store.AgentNote, error)
+}
+
+// MCPResourceInfo describes a resource in resources/list.
+type MCPResourceInfo struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType,omitempty"`
+}
+
+// MCPListResourcesParams are the params for resources/list.
+type MCPListResourcesParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// MCPListResourcesResult is the result for resources/list.
+type MCPListResourcesResult struct {
+	Resources  []MCPResourceInfo `json:"resources"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+// MCPReadResourceParams are the params for resources/read.
+type MCPReadResourceParams struct {
+	URI string `json:"uri"`
+}
+
+// MCPResourceContents is one item of a resources/read result.
+type MCPResourceContents struct {
+	URI      string `json:"uri"`
+	MIMEType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// MCPReadResourceResult is the result for resources/read.
+type MCPReadResourceResult struct {
+	Contents []MCPResourceContents `json:"contents"`
+}
+
+// errResourceDenied is returned for resources that exist but belong to
+// another principal, or that the caller lacks the capability for.
+var errResourceDenied = errors.New("access denied")
+
+// handleResourcesList handles resources/list requests. Resources are the
+// caller's own threads and notes, sorted by URI. The cursor is the last URI
+// of the previous page, so resources added or removed between pages never
+// cause others to be skipped or repeated.
+func (s *Server) handleResourcesList(w http.ResponseWriter, r *http.Request, req JSONRPCRequest, auth authInfo) {
+	var params MCPListResourcesParams
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			s.sendJSONRPCError(w, req.ID, JSONRPCInvalidParams, "invalid params")
+			return
+		}
+	}
+	after := ""
+	if params.Cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(params.Cursor)
+		if err != nil || !isResourceURI(string(raw)) {
+			s.sendJSONRPCError(w, req.ID, JSONRPCInvalidParams, "invalid cursor")
+			return
+		}
+		after = string(raw)
+	}
+
+	resources, err := s.listResources(r.Context(), auth)
+	if err != nil {
+		s.logger.Error("failed to list MCP resources", "agent_id", auth.agentID, "error", err)
+		s.sendJSONRPCError(w, req.ID, JSONRPCInternalError, "failed to list resources")
+		return
+	}
+	start := sort.Search(len(resources), func(i int) bool { return resources[i].URI > after })
+	end := min(start+s.pageSize, len(resources))
+
+	result := MCPListResourcesResult{Resources: resources[start:end]}
+	if end < len(resources) {
+		result.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(resources[end-1].URI))
+	}
+
+	s.logger.Debug("resources/list",
+		"count", end-start,
+		"total", len(resources),
+		"agent_id", auth.agentID,
+	)
+
+	s.sendJSONRPCResult(w, req.ID, result)
+}
+
+// listResources returns every resource the caller may read, sorted by URI.
+// Callers without an identity own nothing and see no resources.
+func (s *Server) listResources(ctx context.Context, auth authInfo) ([]MCPResourceInfo, error) {
+	if auth.agentID == "" {
+		return []MCPResourceInfo{}, nil
+	}
+
+	resources := []MCPResourceInfo{}
+	if packs.HasCapability(auth.capabilities, threadResourceCapability) {
+		threads, err := s.resources.ListAgentThreads(ctx, auth.agentID, maxListedThreads)
+		if err != nil {
+			return nil, fmt.Errorf("listing threads: %w", err)
+		}
+		for _, t := range threads {
+			resources = append(resources, MCPResourceInfo{
+				URI:         threadResourcePrefix + t.ID,
+				Name:        t.FrontendName + ":" + t.ExternalID,
+				Description: "Conversation transcript, last updated " + timeparse.Format(t.UpdatedAt),
+				MIMEType:    resourceTextMIMEType,
+			})
+		}
+	}
+	if packs.HasCapability(auth.capabilities, noteResourceCapability) {
+		notes, err := s.resources.ListNotes(ctx, auth.agentID, "")
+		if err != nil {
+			return nil, fmt.Errorf("listing notes: %w", err)
+		}
+		for _, n := range notes {
+			resources = append(resources, MCPResourceInfo{
+				URI:         noteResourceURI(n.AgentID, n.Key),
+				Name:        n.Key,
+				Description: "Agent note",
+				MIMEType:    noteMIMEType(n.Value),
+			})
+		}
+	}
+
+	sort.Slice(resources, func(i, j int) bool { return resources[i].URI < resources[j].URI })
+	return resources, nil
+}
+
+// handleResourcesRead handles resources/read requests.
+func (s *Server) handleResourcesRead(w http.ResponseWriter, r *http.Request, req JSONRPCRequest, auth authInfo) {
+	var params MCPReadResourceParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		s.sendJSONRPCError(w, req.ID, JSONRPCInvalidParams, "uri is required")
+		return
+	}
+
+	contents, err := s.readResource(r.Context(), auth, params.URI)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		s.sendJSONRPCError(w, req.ID, JSONRPCResourceNotFound, "resource not found")
+		return
+	case errors.Is(err, errResourceDenied):
+		s.logger.Warn("MCP resource access denied", "uri", params.URI, "agent_id", auth.agentID)
+		s.sendJSONRPCError(w, req.ID, JSONRPCInvalidRequest, "access denied")
+		return
+	case err != nil:
+		s.logger.Error("failed to read MCP resource", "uri", params.URI, "agent_id", auth.agentID, "error", err)
+		s.sendJSONRPCError(w, req.ID, JSONRPCInternalError, "failed to read resource")
+		return
+	}
+
+	s.sendJSONRPCResult(w, req.ID, MCPReadResourceResult{Contents: []MCPResourceContents{contents}})
+}
+
+// readResource loads one resource, checking the caller's capabilities and
+// that the thread or note is its own.
+func (s *Server) readResource(ctx context.Context, auth authInfo, uri string) (MCPResourceContents, error) {
+	switch {
+	case strings.HasPrefix(uri, threadResourcePrefix):
+		if !packs.HasCapability(auth.capabilities, threadResourceCapability) {
+			return MCPResourceContents{}, errResourceDenied
+		}
+		thread, err := s.resources.GetThread(ctx, strings.TrimPrefix(uri, threadResourcePrefix))
+		if err != nil {
+			return MCPResourceContents{}, err
+		}
+		if auth.agentID == "" || thread.AgentID != auth.agentID {
+			return MCPResourceContents{}, errResourceDenied
+		}
+		events, err := s.resources.GetEventsByThreadID(ctx, thread.ID, maxTranscriptEvents)
+		if err != nil {
+			return MCPResourceContents{}, fmt.Errorf("loading thread events: %w", err)
+		}
+		return MCPResourceContents{URI: uri, MIMEType: resourceTextMIMEType, Text: threadTranscript(thread, events)}, nil
+
+	case strings.HasPrefix(uri, noteResourcePrefix):
+		agentID, key, ok := parseNoteResourceURI(uri)
+		if !ok {
+			return MCPResourceContents{}, store.ErrNotFound
+		}
+		if !packs.HasCapability(auth.capabilities, noteResourceCapability) || auth.agentID == "" || agentID != auth.agentID {
+			return MCPResourceContents{}, errResourceDenied
+		}
+		note, err := s.resources.GetNote(ctx, agentID, key)
+		if err != nil {
+			return MCPResourceContents{}, err
+		}
+		return MCPResourceContents{URI: uri, MIMEType: noteMIMEType(note.Value), Text: note.Value}, nil
+	}
+	return MCPResourceContents{}, store.ErrNotFound
+}
+
+// threadTranscript renders a thread's messages as plain text, one message per
+// paragraph, oldest first.
+func threadTranscript(thread *store.Thread, events []*store.LedgerEvent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Thread %s (%s:%s)\n", thread.ID, thread.FrontendName, thread.ExternalID)
+	for _, evt := range events {
+		if evt.Type != store.EventTypeMessage || evt.Text == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "\n[%s] %s:\n%s\n", timeparse.Format(evt.Timestamp), evt.Author, *evt.Text)
+	}
+	return b.String()
+}
+
+// noteResourceURI returns the URI of an agent's note. Both parts are escaped
+// so keys containing slashes stay one path segment.
+func noteResourceURI(agentID, key string) string {
+	return noteResourcePrefix + url.PathEscape(agentID) + "/" + url.PathEscape(key)
+}
+
+// parseNoteResourceURI reverses noteResourceURI.
+func parseNoteResourceURI(uri string) (agentID, key string, ok bool) {
+	rawAgent, rawKey, found := strings.Cut(strings.TrimPrefix(uri, noteResourcePrefix), "/")
+	if !found {
+		return "", "", false
+	}
+	agentID, err := url.PathUnescape(rawAgent)
+	if err != nil {
+		return "", "", false
+	}
+	key, err = url.PathUnescape(rawKey)
+	if err != nil || agentID == "" || key == "" {
+		return "", "", false
+	}
+	return agentID, key, true
+}
+
+// noteMIMEType reports a note holding a JSON object or array as JSON and
+// anything else as plain text.
+func noteMIMEType(value string) string {
+	trimmed := strings.TrimSpace(value)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return noteJSONMIMEType
+	}
+	return resourceTextMIMEType
+}
+
+// isResourceURI reports whether uri names a thread or note resource.
+func isResourceURI(uri string) bool {
+	return strings.HasPrefix(uri, threadResourcePrefix) || strings.HasPrefix(uri, noteResourcePrefix)
+}
@@ -0,0 +1,170 @@
+// ABOUTME: Tests for MCP resources backed by threads and notes.
+// ABOUTME: Covers capability filtering, ownership checks, pagination, and MIME types.
+
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// rpcCall sends a JSON-RPC request on a session and decodes the result into out.
+func rpcCall(t *testing.T, mux http.Handler, sessionID, method string, params, out any) *JSONRPCError {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewReader(makeJSONRPCRequest(method, params)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *JSONRPCError   `json:"error"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("%s: failed to decode response: %v", method, err)
+	}
+	if resp.Error == nil && out != nil {
+		if err := json.Unmarshal(resp.Result, out); err != nil {
+			t.Fatalf("%s: failed to decode result: %v", method, err)
+		}
+	}
+	return resp.Error
+}
+
+func TestResources(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, th := range []*store.Thread{
+		{ID: "thread-mine", FrontendName: "slack", ExternalID: "C1", AgentID: "agent-a"},
+		{ID: "thread-theirs", FrontendName: "slack", ExternalID: "C2", AgentID: "agent-b"},
+	} {
+		th.CreatedAt, th.UpdatedAt = now, now
+		if err := s.CreateThread(ctx, th); err != nil {
+			t.Fatalf("CreateThread: %v", err)
+		}
+	}
+	threadID, hello := "thread-mine", "hello there"
+	if err := s.SaveEvent(ctx, &store.LedgerEvent{
+		ID: "evt-1", ConversationKey: "agent-a", ThreadID: &threadID, Direction: store.EventDirectionInbound,
+		Author: "alice", Timestamp: now, Type: store.EventTypeMessage, Text: &hello,
+	}); err != nil {
+		t.Fatalf("SaveEvent: %v", err)
+	}
+	for _, n := range []*store.AgentNote{
+		{ID: "n1", AgentID: "agent-a", Key: "plan", Value: "ship it"},
+		{ID: "n2", AgentID: "agent-a", Key: "config/prod", Value: `{"replicas": 3}`},
+		{ID: "n3", AgentID: "agent-b", Key: "secret", Value: "not yours"},
+	} {
+		if err := s.SetNote(ctx, n); err != nil {
+			t.Fatalf("SetNote: %v", err)
+		}
+	}
+
+	registry := setupTestRegistry(t)
+	tokenStore := NewTokenStore()
+	server, err := NewServer(Config{
+		Registry:      registry,
+		Router:        setupTestRouter(t, registry),
+		TokenStore:    tokenStore,
+		Logger:        slog.Default(),
+		ToolsPageSize: 2,
+		Resources:     s,
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+
+	full := initializeSession(t, mux, tokenStore.CreateToken("agent-a", []string{"base", "notes"}))
+	notesOnly := initializeSession(t, mux, tokenStore.CreateToken("agent-a", []string{"notes"}))
+
+	t.Run("lists own resources page by page", func(t *testing.T) {
+		var uris []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > 3 {
+				t.Fatal("pagination did not terminate")
+			}
+			var params any
+			if cursor != "" {
+				params = MCPListResourcesParams{Cursor: cursor}
+			}
+			var page MCPListResourcesResult
+			if rpcErr := rpcCall(t, mux, full, "resources/list", params, &page); rpcErr != nil {
+				t.Fatalf("resources/list: %v", rpcErr)
+			}
+			for _, res := range page.Resources {
+				uris = append(uris, res.URI)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		want := []string{"coven://notes/agent-a/config%2Fprod", "coven://notes/agent-a/plan", "coven://threads/thread-mine"}
+		if strings.Join(uris, " ") != strings.Join(want, " ") {
+			t.Errorf("listed %v, want %v", uris, want)
+		}
+	})
+
+	t.Run("filters by capability", func(t *testing.T) {
+		var page MCPListResourcesResult
+		if rpcErr := rpcCall(t, mux, notesOnly, "resources/list", nil, &page); rpcErr != nil {
+			t.Fatalf("resources/list: %v", rpcErr)
+		}
+		for _, res := range page.Resources {
+			if strings.HasPrefix(res.URI, threadResourcePrefix) {
+				t.Errorf("notes-only token listed thread %s", res.URI)
+			}
+		}
+		if rpcErr := rpcCall(t, mux, notesOnly, "resources/read", MCPReadResourceParams{URI: "coven://threads/thread-mine"}, nil); rpcErr == nil || rpcErr.Message != "access denied" {
+			t.Errorf("reading a thread without base = %+v, want access denied", rpcErr)
+		}
+	})
+
+	t.Run("reads with MIME types", func(t *testing.T) {
+		for uri, want := range map[string]struct{ mime, text string }{
+			"coven://threads/thread-mine":         {"text/plain", "alice:\nhello there"},
+			"coven://notes/agent-a/plan":          {"text/plain", "ship it"},
+			"coven://notes/agent-a/config%2Fprod": {"application/json", `"replicas"`},
+		} {
+			var got MCPReadResourceResult
+			if rpcErr := rpcCall(t, mux, full, "resources/read", MCPReadResourceParams{URI: uri}, &got); rpcErr != nil {
+				t.Fatalf("read %s: %v", uri, rpcErr)
+			}
+			if len(got.Contents) != 1 || got.Contents[0].MIMEType != want.mime || !strings.Contains(got.Contents[0].Text, want.text) {
+				t.Errorf("read %s = %+v, want %s containing %q", uri, got.Contents, want.mime, want.text)
+			}
+		}
+	})
+
+	t.Run("denies other principals' resources", func(t *testing.T) {
+		for _, uri := range []string{"coven://threads/thread-theirs", "coven://notes/agent-b/secret"} {
+			if rpcErr := rpcCall(t, mux, full, "resources/read", MCPReadResourceParams{URI: uri}, nil); rpcErr == nil || rpcErr.Message != "access denied" {
+				t.Errorf("read %s = %+v, want access denied", uri, rpcErr)
+			}
+		}
+		if rpcErr := rpcCall(t, mux, full, "resources/read", MCPReadResourceParams{URI: "coven://threads/missing"}, nil); rpcErr == nil || rpcErr.Code != JSONRPCResourceNotFound {
+			t.Errorf("read missing thread = %+v, want resource not found", rpcErr)
+		}
+	})
+}
@@ -5,11 +5,15 @@ package mcp
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -17,6 +21,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/coverr"
 	"github.com/2389/coven-gateway/internal/packs"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
@@ -33,6 +38,10 @@ const latestProtocolVersion = "2025-11-25"
 // MaxRequestBodySize is the maximum allowed size for request bodies (1MB).
 const MaxRequestBodySize = 1 << 20
 
+// DefaultToolsPageSize is how many tools a tools/list page holds when
+// Config.ToolsPageSize is unset.
+const DefaultToolsPageSize = 50
+
 // JSON-RPC 2.0 types
 
 // JSONRPCRequest represents a JSON-RPC 2.0 request.
@@ -74,11 +83,31 @@ type MCPToolInfo struct {
 	Name        string          `json:"name"`
 	Description string          `json:"description"`
 	InputSchema json.RawMessage `json:"inputSchema"`
+	Meta        *MCPToolMeta    `json:"_meta,omitempty"`
 }
 
-// MCPListToolsResult is the result for tools/list.
+// MCPToolMeta is the gateway's _meta on a listed tool or a tools/call result.
+type MCPToolMeta struct {
+	// Unavailable is set while the pack that owns the tool is unhealthy.
+	// The tool stays listed, but calls to it fail with pack_unavailable
+	// until the pack recovers.
+	Unavailable bool `json:"coven/unavailable,omitempty"`
+
+	// Cached is set on a tools/call result served from the gateway's
+	// result cache rather than by running the tool.
+	Cached bool `json:"coven/cached,omitempty"`
+}
+
+// MCPListToolsParams are the params for tools/list.
+type MCPListToolsParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// MCPListToolsResult is the result for tools/list. NextCursor is set when
+// more tools follow; pass it back as the cursor param to get the next page.
 type MCPListToolsResult struct {
-	Tools []MCPToolInfo `json:"tools"`
+	Tools      []MCPToolInfo `json:"tools"`
+	NextCursor string        `json:"nextCursor,omitempty"`
 }
 
 // MCPCallToolParams are the params for tools/call.
@@ -91,6 +120,7 @@ type MCPCallToolParams struct {
 type MCPCallToolResult struct {
 	Content []MCPContent `json:"content"`
 	IsError bool         `json:"isError,omitempty"`
+	Meta    *MCPToolMeta `json:"_meta,omitempty"`
 }
 
 // MCPContent represents content in a tool result.
@@ -104,8 +134,9 @@ type mcpSession struct {
 	id              string
 	protocolVersion string
 	capabilities    []string
-	agentID         string // agent ID for builtin tool calls
-	ownerToken      string // auth token hash used to verify session ownership on DELETE
+	agentID         string   // agent ID for builtin tool calls
+	tokenAgentIDs   []string // agents the session's API token is limited to; nil means any
+	ownerToken      string   // auth token hash used to verify session ownership on DELETE
 	createdAt       time.Time
 }
 
@@ -113,6 +144,7 @@ type mcpSession struct {
 type authInfo struct {
 	agentID      string
 	capabilities []string
+	tokenAgents  []string // agents a scoped API token may act as; nil means any
 }
 
 // DefaultSessionTTL is the default session expiration time (1 hour).
@@ -180,6 +212,7 @@ func (s *sessionStore) create(protocolVersion string, auth authInfo, ownerToken
 		protocolVersion: protocolVersion,
 		capabilities:    auth.capabilities,
 		agentID:         auth.agentID,
+		tokenAgentIDs:   auth.tokenAgents,
 		ownerToken:      ownerToken,
 		createdAt:       time.Now(),
 	}
@@ -211,8 +244,18 @@ type Config struct {
 	Logger        *slog.Logger
 	TokenVerifier auth.TokenVerifier
 	TokenStore    *TokenStore // Token-based auth (URL query param)
-	RequireAuth   bool        // If true, reject requests without valid auth
-	DefaultCaps   []string    // Capabilities to use when no auth is provided
+	// Capabilities looks up a bearer token principal's granted capabilities.
+	// When nil, a bearer principal holds only a capability named after its ID.
+	Capabilities func(ctx context.Context, principalID string) ([]string, error)
+	// DisabledTools returns the tools an admin disabled for a caller, which
+	// tools/list leaves out. The router refuses calls to them either way.
+	DisabledTools func(ctx context.Context, agentID string) map[string]string
+	RequireAuth   bool          // If true, reject requests without valid auth
+	DefaultCaps   []string      // Capabilities to use when no auth is provided
+	ToolsPageSize int           // Items per tools/list and resources/list page (default DefaultToolsPageSize)
+	Resources     ResourceStore // Optional: enables resources/list and resources/read
+	MaxBatchSize  int           // Most requests in one JSON-RPC batch (default DefaultMaxBatchSize)
+	BatchWorkers  int           // Batch entries handled at once (default DefaultBatchWorkers)
 }
 
 // Server implements MCP-compatible HTTP endpoints for external agents.
@@ -223,9 +266,17 @@ type Server struct {
 	logger      *slog.Logger
 	verifier    auth.TokenVerifier
 	tokenStore  *TokenStore
+	grantedCaps func(ctx context.Context, principalID string) ([]string, error)
+	disabled    func(ctx context.Context, agentID string) map[string]string
 	requireAuth bool
 	defaultCaps []string
 	sessions    *sessionStore
+	pageSize    int
+	startGen    uint64 // registry generation when the server was created
+	resources   ResourceStore
+
+	maxBatchSize int
+	batchWorkers int
 }
 
 // NewServer creates a new MCP server with the given configuration.
@@ -251,15 +302,36 @@ func NewServer(cfg Config) (*Server, error) {
 		copy(defaultCaps, cfg.DefaultCaps)
 	}
 
+	pageSize := cfg.ToolsPageSize
+	if pageSize <= 0 {
+		pageSize = DefaultToolsPageSize
+	}
+	maxBatchSize := cfg.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = DefaultMaxBatchSize
+	}
+	batchWorkers := cfg.BatchWorkers
+	if batchWorkers <= 0 {
+		batchWorkers = DefaultBatchWorkers
+	}
+
 	return &Server{
 		registry:    cfg.Registry,
 		router:      cfg.Router,
 		logger:      logger,
 		verifier:    cfg.TokenVerifier,
 		tokenStore:  cfg.TokenStore,
+		grantedCaps: cfg.Capabilities,
+		disabled:    cfg.DisabledTools,
 		requireAuth: cfg.RequireAuth,
 		defaultCaps: defaultCaps,
 		sessions:    newSessionStore(),
+		pageSize:    pageSize,
+		startGen:    cfg.Registry.Generation(),
+		resources:   cfg.Resources,
+
+		maxBatchSize: maxBatchSize,
+		batchWorkers: batchWorkers,
 	}, nil
 }
 
@@ -391,11 +463,16 @@ type mcpMethodHandler func(w http.ResponseWriter, r *http.Request, req JSONRPCRe
 
 // getMCPMethodHandlers returns the method routing map.
 func (s *Server) getMCPMethodHandlers() map[string]mcpMethodHandler {
-	return map[string]mcpMethodHandler{
+	handlers := map[string]mcpMethodHandler{
 		"initialize": s.handleInitialize,
 		"tools/list": s.handleToolsList,
 		"tools/call": s.handleToolsCall,
 	}
+	if s.resources != nil {
+		handlers["resources/list"] = s.handleResourcesList
+		handlers["resources/read"] = s.handleResourcesRead
+	}
+	return handlers
 }
 
 func (s *Server) handlePost(w http.ResponseWriter, r *http.Request) {
@@ -407,6 +484,15 @@ func (s *Server) handlePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isBatchBody(body) {
+		if protoVersion != "" && !supportedProtocolVersions[protoVersion] {
+			http.Error(w, "Bad Request: unsupported MCP-Protocol-Version", http.StatusBadRequest)
+			return
+		}
+		s.handleBatch(w, r, body)
+		return
+	}
+
 	req, ok := s.parseJSONRPCRequest(w, body)
 	if !ok {
 		return
@@ -461,11 +547,23 @@ func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request, req JS
 	// Set the session ID header so the client can use it on subsequent requests
 	w.Header().Set("Mcp-Session-Id", sess.id)
 
+	// Once packs have come or gone since startup, tell clients the tool
+	// list is not fixed so they list again instead of caching it.
+	toolsCaps := map[string]any{}
+	if s.registry.Generation() != s.startGen {
+		toolsCaps["listChanged"] = true
+	}
+
+	capabilities := map[string]any{
+		"tools": toolsCaps,
+	}
+	if s.resources != nil {
+		capabilities["resources"] = map[string]any{}
+	}
+
 	result := map[string]any{
 		"protocolVersion": latestProtocolVersion,
-		"capabilities": map[string]any{
-			"tools": map[string]any{},
-		},
+		"capabilities":    capabilities,
 		"serverInfo": map[string]any{
 			"name":    "coven-gateway",
 			"version": "1.0.0",
@@ -474,8 +572,38 @@ func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request, req JS
 	s.sendJSONRPCResult(w, req.ID, result)
 }
 
-// handleToolsList handles tools/list requests.
-func (s *Server) handleToolsList(w http.ResponseWriter, _ *http.Request, req JSONRPCRequest, auth authInfo) {
+// handleToolsList handles tools/list requests, one page at a time. Tools are
+// sorted by name and paged after capability filtering and the caller's tool
+// overrides. A cursor records the
+// registry generation it was issued under; once the registry changes, the
+// cursor is refused so the client starts over instead of skipping or
+// repeating tools.
+func (s *Server) handleToolsList(w http.ResponseWriter, r *http.Request, req JSONRPCRequest, auth authInfo) {
+	var params MCPListToolsParams
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			s.sendJSONRPCError(w, req.ID, JSONRPCInvalidParams, "invalid params")
+			return
+		}
+	}
+
+	// Read the generation before listing: if the registry changes in
+	// between, the cursor is already stale rather than silently wrong.
+	gen := s.registry.Generation()
+	offset := 0
+	if params.Cursor != "" {
+		cursorGen, cursorOffset, err := decodeToolsCursor(params.Cursor)
+		if err != nil {
+			s.sendJSONRPCError(w, req.ID, JSONRPCInvalidParams, "invalid cursor")
+			return
+		}
+		if cursorGen != gen {
+			s.sendJSONRPCError(w, req.ID, JSONRPCInvalidParams, "stale cursor: the tool list changed, call tools/list without a cursor to start over")
+			return
+		}
+		offset = cursorOffset
+	}
+
 	var tools []*pb.ToolDefinition
 	if len(auth.capabilities) == 0 {
 		allTools := s.registry.GetAllTools()
@@ -486,27 +614,71 @@ func (s *Server) handleToolsList(w http.ResponseWriter, _ *http.Request, req JSO
 	} else {
 		tools = s.registry.GetToolsForCapabilities(auth.capabilities)
 	}
+	if s.disabled != nil {
+		tools = packs.WithoutDisabled(tools, s.disabled(r.Context(), auth.agentID))
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].GetName() < tools[j].GetName()
+	})
 
-	result := MCPListToolsResult{
-		Tools: make([]MCPToolInfo, len(tools)),
+	if offset > len(tools) {
+		s.sendJSONRPCError(w, req.ID, JSONRPCInvalidParams, "invalid cursor")
+		return
 	}
+	end := min(offset+s.pageSize, len(tools))
+	page := tools[offset:end]
 
-	for i, tool := range tools {
+	unhealthy := s.registry.UnhealthyTools()
+	result := MCPListToolsResult{
+		Tools: make([]MCPToolInfo, len(page)),
+	}
+	for i, tool := range page {
 		result.Tools[i] = MCPToolInfo{
 			Name:        tool.GetName(),
 			Description: tool.GetDescription(),
 			InputSchema: json.RawMessage(tool.GetInputSchemaJson()),
 		}
+		if unhealthy[tool.GetName()] {
+			result.Tools[i].Meta = &MCPToolMeta{Unavailable: true}
+		}
+	}
+	if end < len(tools) {
+		result.NextCursor = encodeToolsCursor(gen, end)
 	}
 
 	s.logger.Debug("tools/list",
-		"count", len(tools),
+		"count", len(page),
+		"offset", offset,
+		"total", len(tools),
 		"capabilities", auth.capabilities,
 	)
 
 	s.sendJSONRPCResult(w, req.ID, result)
 }
 
+// encodeToolsCursor returns the opaque tools/list cursor for the page that
+// starts at offset in the tool list of registry generation gen.
+func encodeToolsCursor(gen uint64, offset int) string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%d", gen, offset))
+}
+
+// decodeToolsCursor reverses encodeToolsCursor.
+func decodeToolsCursor(cursor string) (uint64, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, err
+	}
+	var gen uint64
+	var offset int
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &gen, &offset); err != nil {
+		return 0, 0, err
+	}
+	if offset < 0 {
+		return 0, 0, errors.New("negative offset")
+	}
+	return gen, offset, nil
+}
+
 // handleToolsCall handles tools/call requests.
 func (s *Server) handleToolsCall(w http.ResponseWriter, r *http.Request, req JSONRPCRequest, auth authInfo) {
 	// Parse params
@@ -523,6 +695,23 @@ func (s *Server) handleToolsCall(w http.ResponseWriter, r *http.Request, req JSO
 		return
 	}
 
+	// A token limited to some agents may only call tools as one of them.
+	if auth.tokenAgents != nil && !slices.Contains(auth.tokenAgents, auth.agentID) {
+		s.logger.Warn("MCP tool call denied by token scope", "tool_name", params.Name, "agent_id", auth.agentID)
+		s.sendJSONRPCError(w, req.ID, JSONRPCInvalidRequest, "permission denied: token is not permitted for this agent")
+		return
+	}
+
+	// A dry run answers with the router's verdict, including denials, as
+	// the tool result; nothing is executed.
+	if input, ok := packs.ParseDryRun(string(params.Arguments)); ok {
+		verdict := s.router.CheckToolCall(r.Context(), params.Name, input, auth.agentID, auth.capabilities)
+		s.sendJSONRPCResult(w, req.ID, MCPCallToolResult{
+			Content: []MCPContent{{Type: "text", Text: verdict.JSON()}},
+		})
+		return
+	}
+
 	// Get tool definition to check capabilities
 	toolDef := s.router.GetToolDefinition(params.Name)
 	if toolDef == nil {
@@ -571,6 +760,9 @@ func (s *Server) handleToolsCall(w http.ResponseWriter, r *http.Request, req JSO
 		result = MCPCallToolResult{
 			Content: []MCPContent{{Type: "text", Text: resp.GetOutputJson()}},
 		}
+		if resp.GetCached() {
+			result.Meta = &MCPToolMeta{Cached: true}
+		}
 	}
 
 	s.logger.Debug("tools/call complete",
@@ -614,7 +806,7 @@ func (s *Server) validateSessionAuth(sessionID string) (authInfo, int, string) {
 	if !ok {
 		return authInfo{}, http.StatusNotFound, "Not Found"
 	}
-	return authInfo{agentID: sess.agentID, capabilities: sess.capabilities}, 0, ""
+	return authInfo{agentID: sess.agentID, capabilities: sess.capabilities, tokenAgents: sess.tokenAgentIDs}, 0, ""
 }
 
 // extractAuth extracts authentication info (agent ID and capabilities) from the request.
@@ -663,12 +855,34 @@ func (s *Server) extractBearerAuth(r *http.Request) (authInfo, error) {
 		return authInfo{}, errors.New("empty token")
 	}
 
-	principalID, err := s.verifier.Verify(token)
+	claims, err := s.verifyBearer(token)
 	if err != nil {
 		return authInfo{}, err
 	}
 
-	return authInfo{agentID: principalID, capabilities: []string{principalID}}, nil
+	caps := []string{claims.PrincipalID}
+	if s.grantedCaps != nil {
+		if caps, err = s.grantedCaps(r.Context(), claims.PrincipalID); err != nil {
+			return authInfo{}, fmt.Errorf("loading capabilities: %w", err)
+		}
+	}
+	if claims.Capabilities != nil {
+		caps = auth.IntersectCapabilities(caps, claims.Capabilities)
+	}
+	return authInfo{agentID: claims.PrincipalID, capabilities: caps, tokenAgents: claims.AgentIDs}, nil
+}
+
+// verifyBearer verifies a bearer token, keeping the token's agent and
+// capability limits when the verifier records them.
+func (s *Server) verifyBearer(token string) (*auth.Claims, error) {
+	if cv, ok := s.verifier.(auth.ClaimsVerifier); ok {
+		return cv.VerifyClaims(token)
+	}
+	principalID, err := s.verifier.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.Claims{PrincipalID: principalID}, nil
 }
 
 func (s *Server) extractAuth(r *http.Request) (authInfo, error) {
@@ -752,13 +966,17 @@ func (s *Server) handleToolError(w http.ResponseWriter, id json.RawMessage, tool
 		message = "tool pack unavailable"
 	case errors.Is(err, packs.ErrDuplicateRequestID):
 		message = "duplicate request ID"
+	case errors.Is(err, packs.ErrCallerRejected), errors.Is(err, packs.ErrCapabilityDenied):
+		code = JSONRPCInvalidRequest
+		message = err.Error()
 	case errors.Is(err, context.DeadlineExceeded):
 		message = "tool execution timed out"
 	case errors.Is(err, context.Canceled):
 		message = "request canceled"
 	}
 
-	s.sendJSONRPCError(w, id, code, message)
+	errCode := coverr.CodeOf(err)
+	s.sendJSONRPCErrorData(w, id, code, message, map[string]any{"code": errCode, "retriable": errCode.Retriable()})
 }
 
 // sendJSONRPCResult sends a successful JSON-RPC response.
@@ -776,12 +994,18 @@ func (s *Server) sendJSONRPCResult(w http.ResponseWriter, id json.RawMessage, re
 
 // sendJSONRPCError sends a JSON-RPC error response.
 func (s *Server) sendJSONRPCError(w http.ResponseWriter, id json.RawMessage, code int, message string) {
+	s.sendJSONRPCErrorData(w, id, code, message, nil)
+}
+
+// sendJSONRPCErrorData sends a JSON-RPC error response with error data.
+func (s *Server) sendJSONRPCErrorData(w http.ResponseWriter, id json.RawMessage, code int, message string, data any) {
 	resp := JSONRPCResponse{
 		JSONRPC: "2.0",
 		ID:      id,
 		Error: &JSONRPCError{
 			Code:    code,
 			Message: message,
+			Data:    data,
 		},
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -5,18 +5,40 @@ package mcp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/2389/coven-gateway/internal/auth"
 	"github.com/2389/coven-gateway/internal/packs"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
-// mockTokenVerifier implements auth.TokenVerifier for testing.
+// mockTokenVerifier implements auth.TokenVerifier and auth.ClaimsVerifier for
+// testing, accepting the tokens in its map.
+type mockTokenVerifier map[string]*auth.Claims
+
+func (m mockTokenVerifier) Verify(token string) (string, error) {
+	claims, err := m.VerifyClaims(token)
+	if err != nil {
+		return "", err
+	}
+	return claims.PrincipalID, nil
+}
+
+func (m mockTokenVerifier) VerifyClaims(token string) (*auth.Claims, error) {
+	if claims, ok := m[token]; ok {
+		return claims, nil
+	}
+	return nil, errors.New("unknown token")
+}
 
 // setupTestRegistry creates a registry with test tools.
 func setupTestRegistry(t *testing.T) *packs.Registry {
@@ -290,6 +312,51 @@ func TestHandleToolsList(t *testing.T) {
 		}
 	})
 
+	t.Run("leaves out tools disabled for the agent", func(t *testing.T) {
+		registry := setupTestRegistry(t)
+		router := setupTestRouter(t, registry)
+
+		tokenStore := NewTokenStore()
+		token := tokenStore.CreateToken("test-agent", []string{"admin"})
+
+		server, err := NewServer(Config{
+			Registry:   registry,
+			Router:     router,
+			TokenStore: tokenStore,
+			Logger:     slog.Default(),
+			DisabledTools: func(_ context.Context, agentID string) map[string]string {
+				if agentID == "test-agent" {
+					return map[string]string{"admin-tool": "not for this agent"}
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to create server: %v", err)
+		}
+
+		mux := http.NewServeMux()
+		server.RegisterRoutes(mux)
+		sessionID := initializeSession(t, mux, token)
+
+		body := makeJSONRPCRequest("tools/list", nil)
+		req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Mcp-Session-Id", sessionID)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		var resp struct {
+			Result MCPListToolsResult `json:"result"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Result.Tools) != 1 || resp.Result.Tools[0].Name != "public-tool" {
+			t.Errorf("tools = %+v, want only public-tool", resp.Result.Tools)
+		}
+	})
+
 	t.Run("filters tools by path-based token", func(t *testing.T) {
 		registry := setupTestRegistry(t)
 		router := setupTestRouter(t, registry)
@@ -407,6 +474,161 @@ func TestHandleToolsList(t *testing.T) {
 	})
 }
 
+// listToolsPage sends tools/list with the given cursor and decodes the reply.
+func listToolsPage(t *testing.T, mux http.Handler, sessionID, cursor string) (MCPListToolsResult, *JSONRPCError) {
+	t.Helper()
+	var params any
+	if cursor != "" {
+		params = MCPListToolsParams{Cursor: cursor}
+	}
+	req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewReader(makeJSONRPCRequest("tools/list", params)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	var resp struct {
+		Result MCPListToolsResult `json:"result"`
+		Error  *JSONRPCError      `json:"error"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp.Result, resp.Error
+}
+
+func TestToolsListFlagsUnhealthyPackTools(t *testing.T) {
+	registry := setupTestRegistry(t)
+	if err := registry.RegisterPack("quiet-pack", &pb.PackManifest{PackId: "quiet-pack", Tools: []*pb.ToolDefinition{
+		{Name: "quiet-tool", InputSchemaJson: `{"type":"object"}`},
+	}}); err != nil {
+		t.Fatalf("failed to register quiet pack: %v", err)
+	}
+	// quiet-pack heartbeats once and then goes silent past the miss window.
+	registry.SetHeartbeatMissWindow(time.Millisecond)
+	if err := registry.Heartbeat("quiet-pack"); err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	server, err := NewServer(Config{Registry: registry, Router: setupTestRouter(t, registry), Logger: slog.Default()})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+
+	page, rpcErr := listToolsPage(t, mux, initializeSession(t, mux, ""), "")
+	if rpcErr != nil {
+		t.Fatalf("tools/list: %v", rpcErr)
+	}
+	if len(page.Tools) != 4 {
+		t.Fatalf("listed %d tools, want the unhealthy pack's tool still listed among 4", len(page.Tools))
+	}
+	for _, tool := range page.Tools {
+		flagged := tool.Meta != nil && tool.Meta.Unavailable
+		if want := tool.Name == "quiet-tool"; flagged != want {
+			t.Errorf("%s flagged unavailable = %v, want %v", tool.Name, flagged, want)
+		}
+	}
+}
+
+func TestToolsListPagination(t *testing.T) {
+	registry := setupTestRegistry(t)
+	router := setupTestRouter(t, registry)
+	extra := make([]*pb.ToolDefinition, 0, 5)
+	for i := range 5 {
+		extra = append(extra, &pb.ToolDefinition{Name: fmt.Sprintf("extra-%d", i), InputSchemaJson: `{"type":"object"}`})
+	}
+	extra = append(extra, &pb.ToolDefinition{Name: "extra-secret", InputSchemaJson: `{"type":"object"}`, RequiredCapabilities: []string{"secret"}})
+	if err := registry.RegisterPack("extra-pack", &pb.PackManifest{PackId: "extra-pack", Tools: extra}); err != nil {
+		t.Fatalf("failed to register extra pack: %v", err)
+	}
+
+	tokenStore := NewTokenStore()
+	token := tokenStore.CreateToken("test-agent", []string{"admin"})
+	server, err := NewServer(Config{
+		Registry:      registry,
+		Router:        router,
+		TokenStore:    tokenStore,
+		Logger:        slog.Default(),
+		ToolsPageSize: 3,
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	sessionID := initializeSession(t, mux, token)
+
+	t.Run("pages through the filtered tools once each", func(t *testing.T) {
+		var names []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > 5 {
+				t.Fatal("pagination did not terminate")
+			}
+			page, rpcErr := listToolsPage(t, mux, sessionID, cursor)
+			if rpcErr != nil {
+				t.Fatalf("page %d: %v", pages, rpcErr)
+			}
+			if len(page.Tools) > 3 {
+				t.Errorf("page %d has %d tools, want at most 3", pages, len(page.Tools))
+			}
+			for _, tool := range page.Tools {
+				names = append(names, tool.Name)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		// admin sees public-tool, admin-tool and the five open extras, but
+		// neither multi-cap-tool nor extra-secret.
+		want := []string{"admin-tool", "extra-0", "extra-1", "extra-2", "extra-3", "extra-4", "public-tool"}
+		if fmt.Sprint(names) != fmt.Sprint(want) {
+			t.Errorf("listed %v, want %v", names, want)
+		}
+	})
+
+	t.Run("rejects a stale cursor after the registry changes", func(t *testing.T) {
+		first, rpcErr := listToolsPage(t, mux, sessionID, "")
+		if rpcErr != nil || first.NextCursor == "" {
+			t.Fatalf("first page = %+v, %v; want a next cursor", first, rpcErr)
+		}
+		registry.UnregisterPack("extra-pack")
+
+		_, rpcErr = listToolsPage(t, mux, sessionID, first.NextCursor)
+		if rpcErr == nil || rpcErr.Code != JSONRPCInvalidParams || !strings.Contains(rpcErr.Message, "stale cursor") {
+			t.Fatalf("stale cursor error = %+v, want invalid params about a stale cursor", rpcErr)
+		}
+
+		fresh, rpcErr := listToolsPage(t, mux, sessionID, "")
+		if rpcErr != nil || len(fresh.Tools) != 2 || fresh.NextCursor != "" {
+			t.Errorf("fresh list = %+v, %v; want the two remaining admin tools on one page", fresh, rpcErr)
+		}
+	})
+
+	t.Run("rejects a malformed cursor", func(t *testing.T) {
+		for _, cursor := range []string{"not base64!", encodeToolsCursor(registry.Generation(), 99)} {
+			if _, rpcErr := listToolsPage(t, mux, sessionID, cursor); rpcErr == nil || rpcErr.Message != "invalid cursor" {
+				t.Errorf("cursor %q error = %+v, want invalid cursor", cursor, rpcErr)
+			}
+		}
+	})
+
+	t.Run("initialize advertises listChanged once the registry changed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewReader(makeJSONRPCRequest("initialize", nil)))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if !strings.Contains(rr.Body.String(), `"tools":{"listChanged":true}`) {
+			t.Errorf("initialize = %s, want tools.listChanged", rr.Body.String())
+		}
+	})
+}
+
 func TestHandleToolsCall(t *testing.T) {
 	t.Run("returns error for unknown tool", func(t *testing.T) {
 		registry := setupTestRegistry(t)
@@ -492,6 +714,68 @@ func TestHandleToolsCall(t *testing.T) {
 	})
 }
 
+func TestHandleToolsCall_DryRun(t *testing.T) {
+	registry := setupTestRegistry(t)
+	router := setupTestRouter(t, registry)
+	server, err := NewServer(Config{
+		Registry:    registry,
+		Router:      router,
+		Logger:      slog.Default(),
+		RequireAuth: false,
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	sessionID := initializeSession(t, mux, "")
+
+	callDry := func(name string, args map[string]any) packs.ToolCallVerdict {
+		t.Helper()
+		args[packs.DryRunParam] = true
+		body := makeJSONRPCRequest("tools/call", map[string]any{"name": name, "arguments": args})
+		req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Mcp-Session-Id", sessionID)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		var resp struct {
+			Result *MCPCallToolResult `json:"result"`
+			Error  *JSONRPCError      `json:"error"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Error != nil || resp.Result == nil || len(resp.Result.Content) != 1 {
+			t.Fatalf("dry run should return a verdict result, got error %+v", resp.Error)
+		}
+		var v packs.ToolCallVerdict
+		if err := json.Unmarshal([]byte(resp.Result.Content[0].Text), &v); err != nil {
+			t.Fatalf("decoding verdict: %v", err)
+		}
+		return v
+	}
+
+	if v := callDry("public-tool", map[string]any{"input": "x"}); v.Verdict != packs.VerdictAllowed {
+		t.Errorf("public-tool verdict = %+v, want allowed", v)
+	}
+	if v := callDry("admin-tool", map[string]any{}); v.Verdict != packs.VerdictDenied || v.Check != packs.CheckCapability {
+		t.Errorf("admin-tool verdict = %+v, want capability denial", v)
+	}
+	if v := callDry("nonexistent-tool", map[string]any{}); v.Verdict != packs.VerdictDenied || v.Check != packs.CheckTool {
+		t.Errorf("unknown tool verdict = %+v, want tool denial", v)
+	}
+	if v := callDry("public-tool", map[string]any{"input": 5}); v.Verdict != packs.VerdictDenied || v.Check != packs.CheckSchema {
+		t.Errorf("bad input verdict = %+v, want schema denial", v)
+	}
+
+	// Nothing reached the pack.
+	if pack := registry.GetPack("test-pack"); len(pack.Channel) != 0 {
+		t.Errorf("pack received %d requests during dry runs", len(pack.Channel))
+	}
+}
+
 func TestTokenStore(t *testing.T) {
 	t.Run("create and retrieve token", func(t *testing.T) {
 		store := NewTokenStore()
@@ -553,6 +837,22 @@ func TestTokenStore(t *testing.T) {
 			t.Error("token should not exist after invalidation")
 		}
 	})
+
+	t.Run("set agent capabilities", func(t *testing.T) {
+		store := NewTokenStore()
+		mine := store.CreateToken("test-agent", []string{"base", "admin"})
+		other := store.CreateToken("other-agent", []string{"base", "admin"})
+
+		if n := store.SetAgentCapabilities("test-agent", []string{"base"}); n != 1 {
+			t.Errorf("updated %d tokens, want 1", n)
+		}
+		if caps := store.GetCapabilities(mine); len(caps) != 1 || caps[0] != "base" {
+			t.Errorf("test-agent capabilities = %v, want [base]", caps)
+		}
+		if caps := store.GetCapabilities(other); len(caps) != 2 {
+			t.Errorf("other-agent capabilities = %v, want unchanged", caps)
+		}
+	})
 }
 
 func TestMethodNotAllowed(t *testing.T) {
@@ -731,3 +1031,75 @@ func TestUnknownMethod(t *testing.T) {
 		t.Errorf("expected error code %d, got %d", JSONRPCMethodNotFound, resp.Error.Code)
 	}
 }
+
+func TestBearerTokenLimits(t *testing.T) {
+	registry := setupTestRegistry(t)
+	if err := registry.RegisterBuiltinPack(&packs.BuiltinPack{
+		ID: "builtin:echo",
+		Tools: []*packs.BuiltinTool{{
+			Definition: &pb.ToolDefinition{Name: "echo", InputSchemaJson: `{"type":"object"}`},
+			Handler: func(_ context.Context, _ string, input json.RawMessage) (json.RawMessage, error) {
+				return input, nil
+			},
+		}},
+	}); err != nil {
+		t.Fatalf("failed to register echo pack: %v", err)
+	}
+	server, err := NewServer(Config{
+		Registry: registry,
+		Router:   setupTestRouter(t, registry),
+		Logger:   slog.Default(),
+		TokenVerifier: mockTokenVerifier{
+			"own":   {PrincipalID: "agent-a", AgentIDs: []string{"agent-a"}, Capabilities: []string{"notes", "admin"}},
+			"other": {PrincipalID: "agent-a", AgentIDs: []string{"agent-b"}},
+			"plain": {PrincipalID: "agent-a"},
+		},
+		Capabilities: func(_ context.Context, _ string) ([]string, error) {
+			return []string{"base", "notes"}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+
+	bearerSession := func(token string) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewReader(makeJSONRPCRequest("initialize", nil)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("initialize with %s token failed with status %d: %s", token, rr.Code, rr.Body.String())
+		}
+		return rr.Header().Get("Mcp-Session-Id")
+	}
+
+	t.Run("intersects granted capabilities with the token subset", func(t *testing.T) {
+		for token, want := range map[string]string{"own": "notes", "plain": "base,notes"} {
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			info, err := server.extractBearerAuth(req)
+			if err != nil {
+				t.Fatalf("extractBearerAuth(%s): %v", token, err)
+			}
+			if got := strings.Join(info.capabilities, ","); got != want {
+				t.Errorf("%s token capabilities = %s, want %s", token, got, want)
+			}
+		}
+	})
+
+	t.Run("refuses tool calls for agents outside the token list", func(t *testing.T) {
+		call := map[string]any{"name": "echo", "arguments": map[string]string{"say": "hi"}}
+		if rpcErr := rpcCall(t, mux, bearerSession("own"), "tools/call", call, nil); rpcErr != nil {
+			t.Errorf("call with a token listing the agent failed: %v", rpcErr)
+		}
+		rpcErr := rpcCall(t, mux, bearerSession("other"), "tools/call", call, nil)
+		if rpcErr == nil || !strings.Contains(rpcErr.Message, "permission denied") {
+			t.Errorf("call with a token for another agent = %+v, want a permission error", rpcErr)
+		}
+	})
+}
@@ -78,6 +78,25 @@ func (s *TokenStore) GetCapabilities(token string) []string {
 	return info.Capabilities
 }
 
+// SetAgentCapabilities replaces the capabilities of every token issued to
+// agentID, after its principal's grants change. Returns how many tokens were
+// updated.
+func (s *TokenStore) SetAgentCapabilities(agentID string, capabilities []string) int {
+	caps := make([]string, len(capabilities))
+	copy(caps, capabilities)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for token, info := range s.tokens {
+		if info.AgentID == agentID {
+			s.tokens[token] = &TokenInfo{AgentID: agentID, Capabilities: caps}
+			n++
+		}
+	}
+	return n
+}
+
 // InvalidateToken removes a token from the store.
 // Called when an agent disconnects.
 func (s *TokenStore) InvalidateToken(token string) {
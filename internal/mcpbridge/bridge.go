@@ -0,0 +1,483 @@
+// ABOUTME: Imports tools from upstream MCP servers into the pack registry as mcp:<name> packs.
+// ABOUTME: Keeps each upstream's tool list fresh, proxies calls through tools/call, and tracks health.
+
+package mcpbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/packs"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// Defaults for Upstream fields left zero.
+const (
+	DefaultPollInterval = 5 * time.Minute
+	DefaultTimeout      = 30 * time.Second
+	DefaultAuthHeader   = "Authorization"
+)
+
+// PackPrefix starts the pack ID and tool names of imported upstreams.
+const PackPrefix = "mcp:"
+
+// Upstream describes one MCP server whose tools the gateway imports.
+type Upstream struct {
+	Name string // pack is mcp:<Name>, tools are mcp:<Name>:<tool>
+	URL  string // Streamable HTTP endpoint
+
+	// AuthHeader is sent on every request with the value of the AuthSecret
+	// secret, prefixed by AuthScheme and a space when that is set.
+	AuthHeader string
+	AuthSecret string
+	AuthScheme string
+
+	// Capability, if set, is required of agents to see and call the tools.
+	Capability string
+
+	PollInterval time.Duration // how often to re-list tools and check health
+	Timeout      time.Duration // per request, including tool calls
+}
+
+// SecretSource resolves auth header secrets. Global secrets (agent ID "")
+// are used; the SQL store satisfies it.
+type SecretSource interface {
+	GetEffectiveSecrets(ctx context.Context, agentID string) (map[string]string, error)
+}
+
+// Status is the bridge's view of one upstream.
+type Status struct {
+	Name      string
+	URL       string
+	Healthy   bool
+	LastError string
+	Tools     int
+	CheckedAt time.Time
+}
+
+// Bridge connects to upstream MCP servers and mirrors their tools into a
+// registry.
+type Bridge struct {
+	registry *packs.Registry
+	secrets  SecretSource
+	http     *http.Client
+	logger   *slog.Logger
+
+	upstreams []*upstream
+
+	stop     chan struct{}
+	done     sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// New creates a bridge for the given upstreams. secrets may be nil when no
+// upstream uses AuthSecret; httpClient may be nil for the default client.
+func New(registry *packs.Registry, upstreams []Upstream, secrets SecretSource, httpClient *http.Client, logger *slog.Logger) *Bridge {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	b := &Bridge{
+		registry: registry,
+		secrets:  secrets,
+		http:     httpClient,
+		logger:   logger,
+		stop:     make(chan struct{}),
+	}
+	for _, cfg := range upstreams {
+		if cfg.PollInterval <= 0 {
+			cfg.PollInterval = DefaultPollInterval
+		}
+		if cfg.Timeout <= 0 {
+			cfg.Timeout = DefaultTimeout
+		}
+		if cfg.AuthHeader == "" {
+			cfg.AuthHeader = DefaultAuthHeader
+		}
+		b.upstreams = append(b.upstreams, &upstream{
+			cfg:     cfg,
+			bridge:  b,
+			changed: make(chan struct{}, 1),
+			logger:  logger.With("upstream", cfg.Name),
+		})
+	}
+	return b
+}
+
+// Start connects to every upstream and keeps them in sync in the
+// background. An upstream that cannot be reached is marked degraded and
+// retried on each poll.
+func (b *Bridge) Start() {
+	for _, u := range b.upstreams {
+		b.done.Add(1)
+		go func() {
+			defer b.done.Done()
+			u.run(b.stop)
+		}()
+	}
+}
+
+// Sync connects to or refreshes every upstream once, synchronously.
+func (b *Bridge) Sync(ctx context.Context) {
+	for _, u := range b.upstreams {
+		u.sync(ctx)
+	}
+}
+
+// Close stops background syncing, ends upstream sessions, and removes the
+// imported packs. It is safe to call more than once.
+func (b *Bridge) Close() {
+	b.stopOnce.Do(func() { close(b.stop) })
+	b.done.Wait()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, u := range b.upstreams {
+		u.closeSession(ctx)
+		b.registry.UnregisterBuiltinPack(u.packID())
+	}
+}
+
+// Statuses reports every upstream's health, in configuration order.
+func (b *Bridge) Statuses() []Status {
+	out := make([]Status, 0, len(b.upstreams))
+	for _, u := range b.upstreams {
+		out = append(out, u.status())
+	}
+	return out
+}
+
+// upstream is the live state of one configured server.
+type upstream struct {
+	cfg    Upstream
+	bridge *Bridge
+	logger *slog.Logger
+
+	// changed is signaled by list_changed notifications.
+	changed chan struct{}
+
+	mu         sync.Mutex
+	conn       *client
+	listenStop context.CancelFunc
+	st         Status
+}
+
+func (u *upstream) packID() string { return PackPrefix + u.cfg.Name }
+
+func (u *upstream) toolName(remote string) string {
+	return u.packID() + ":" + remote
+}
+
+func (u *upstream) run(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	u.sync(ctx)
+	ticker := time.NewTicker(u.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-u.changed:
+			u.logger.Info("upstream tool list changed")
+		}
+		u.sync(ctx)
+	}
+}
+
+// sync (re)connects if needed, re-lists tools, and updates the registry and
+// health status.
+func (u *upstream) sync(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, u.cfg.Timeout)
+	defer cancel()
+
+	tools, err := u.fetchTools(ctx)
+	if err != nil {
+		u.markDegraded(err)
+		return
+	}
+	if err := u.bridge.registry.ReplaceBuiltinPack(u.buildPack(tools)); err != nil {
+		u.markDegraded(fmt.Errorf("registering tools: %w", err))
+		return
+	}
+	u.markHealthy(len(tools))
+}
+
+// fetchTools lists tools, starting a new session first when there is none
+// or the old one expired.
+func (u *upstream) fetchTools(ctx context.Context) ([]remoteTool, error) {
+	conn, err := u.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tools, err := conn.listTools(ctx)
+	if errors.Is(err, errSessionExpired) {
+		u.dropSession()
+		if conn, err = u.session(ctx); err != nil {
+			return nil, err
+		}
+		tools, err = conn.listTools(ctx)
+	}
+	return tools, err
+}
+
+// session returns the current client, initializing a new one if needed.
+func (u *upstream) session(ctx context.Context) (*client, error) {
+	u.mu.Lock()
+	conn := u.conn
+	u.mu.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+
+	header, err := u.authHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn = newClient(u.cfg.URL, u.bridge.http, header, u.onNotify)
+	info, err := conn.initialize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	u.logger.Info("connected to MCP upstream",
+		"server", info.ServerInfo.Name,
+		"server_version", info.ServerInfo.Version,
+		"protocol_version", info.ProtocolVersion,
+	)
+
+	var listenStop context.CancelFunc
+	if info.Capabilities.Tools != nil && info.Capabilities.Tools.ListChanged {
+		var listenCtx context.Context
+		listenCtx, listenStop = context.WithCancel(context.Background())
+		go func() {
+			if err := conn.listen(listenCtx); err != nil && listenCtx.Err() == nil {
+				u.logger.Debug("upstream event stream ended", "error", err)
+			}
+		}()
+	}
+
+	u.mu.Lock()
+	u.conn = conn
+	u.listenStop = listenStop
+	u.mu.Unlock()
+	return conn, nil
+}
+
+func (u *upstream) dropSession() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.listenStop != nil {
+		u.listenStop()
+		u.listenStop = nil
+	}
+	u.conn = nil
+}
+
+func (u *upstream) closeSession(ctx context.Context) {
+	u.mu.Lock()
+	conn := u.conn
+	u.mu.Unlock()
+	u.dropSession()
+	if conn != nil {
+		conn.close(ctx)
+	}
+}
+
+func (u *upstream) onNotify(method string) {
+	if method != methodToolsListChanged {
+		return
+	}
+	select {
+	case u.changed <- struct{}{}:
+	default:
+	}
+}
+
+// authHeader resolves the configured auth secret into request headers.
+func (u *upstream) authHeader(ctx context.Context) (http.Header, error) {
+	header := http.Header{}
+	if u.cfg.AuthSecret == "" {
+		return header, nil
+	}
+	if u.bridge.secrets == nil {
+		return nil, errors.New("auth_secret is set but no secrets store is available")
+	}
+	secrets, err := u.bridge.secrets.GetEffectiveSecrets(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("resolving auth secret: %w", err)
+	}
+	value, ok := secrets[u.cfg.AuthSecret]
+	if !ok {
+		return nil, fmt.Errorf("auth secret %q not found", u.cfg.AuthSecret)
+	}
+	if u.cfg.AuthScheme != "" {
+		value = u.cfg.AuthScheme + " " + value
+	}
+	header.Set(u.cfg.AuthHeader, value)
+	return header, nil
+}
+
+// buildPack turns the upstream's tools into a builtin pack whose handlers
+// proxy to tools/call.
+func (u *upstream) buildPack(tools []remoteTool) *packs.BuiltinPack {
+	pack := &packs.BuiltinPack{ID: u.packID(), Origin: u.cfg.URL}
+	var caps []string
+	if u.cfg.Capability != "" {
+		caps = []string{u.cfg.Capability}
+	}
+	for _, t := range tools {
+		schema := string(t.InputSchema)
+		if strings.TrimSpace(schema) == "" || schema == "null" {
+			schema = `{"type":"object"}`
+		}
+		pack.Tools = append(pack.Tools, &packs.BuiltinTool{
+			Definition: &pb.ToolDefinition{
+				Name:                 u.toolName(t.Name),
+				Description:          t.Description,
+				InputSchemaJson:      schema,
+				RequiredCapabilities: caps,
+				TimeoutSeconds:       int32(u.cfg.Timeout / time.Second),
+			},
+			Handler: u.handler(t.Name),
+		})
+	}
+	return pack
+}
+
+// handler returns the ToolHandler that forwards one tool to the upstream.
+func (u *upstream) handler(remote string) packs.ToolHandler {
+	return func(ctx context.Context, _ string, input json.RawMessage) (json.RawMessage, error) {
+		args, err := toolArguments(input)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(ctx, u.cfg.Timeout)
+		defer cancel()
+
+		res, err := u.callTool(ctx, remote, args)
+		if err != nil {
+			return nil, u.mapError(ctx, remote, err)
+		}
+		return toolOutput(res)
+	}
+}
+
+// callTool calls the tool, reconnecting once if the session is gone.
+func (u *upstream) callTool(ctx context.Context, remote string, args json.RawMessage) (*callToolResult, error) {
+	conn, err := u.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := conn.callTool(ctx, remote, args)
+	if errors.Is(err, errSessionExpired) {
+		u.dropSession()
+		if conn, err = u.session(ctx); err != nil {
+			return nil, err
+		}
+		res, err = conn.callTool(ctx, remote, args)
+	}
+	return res, err
+}
+
+// mapError turns a failed call into the error the agent sees, marking the
+// upstream degraded when it could not be reached.
+func (u *upstream) mapError(ctx context.Context, remote string, err error) error {
+	var rpcErr *RPCError
+	switch {
+	case errors.As(err, &rpcErr) && rpcErr.Code == rpcInvalidParams:
+		return fmt.Errorf("invalid arguments for %s: %s", u.toolName(remote), rpcErr.Message)
+	case errors.As(err, &rpcErr) && rpcErr.Code == rpcMethodNotFound:
+		return fmt.Errorf("MCP upstream %s no longer offers %s", u.cfg.Name, remote)
+	case errors.As(err, &rpcErr):
+		return fmt.Errorf("MCP upstream %s: %w", u.cfg.Name, rpcErr)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		u.markDegraded(fmt.Errorf("tools/call %s timed out", remote))
+		return fmt.Errorf("MCP upstream %s timed out after %s", u.cfg.Name, u.cfg.Timeout)
+	default:
+		u.markDegraded(err)
+		return fmt.Errorf("MCP upstream %s unreachable: %w", u.cfg.Name, err)
+	}
+}
+
+// toolArguments validates tool input as the JSON object tools/call expects.
+func toolArguments(input json.RawMessage) (json.RawMessage, error) {
+	trimmed := strings.TrimSpace(string(input))
+	if trimmed == "" || trimmed == "null" {
+		return json.RawMessage(`{}`), nil
+	}
+	if !strings.HasPrefix(trimmed, "{") || !json.Valid(input) {
+		return nil, errors.New("tool arguments must be a JSON object")
+	}
+	return input, nil
+}
+
+// toolOutput translates a tools/call result into tool output JSON.
+// Structured content is returned as is. Text-only results become the JSON
+// they contain, or a JSON string of the text; anything else is returned as
+// {"content": [...]} in MCP form. Results flagged isError become errors.
+func toolOutput(res *callToolResult) (json.RawMessage, error) {
+	texts, allText := parseContent(res.Content)
+	if res.IsError {
+		msg := strings.Join(texts, "\n")
+		if msg == "" {
+			msg = "tool reported an error"
+		}
+		return nil, errors.New(msg)
+	}
+	if len(res.StructuredContent) > 0 && string(res.StructuredContent) != "null" {
+		return res.StructuredContent, nil
+	}
+	if !allText {
+		return json.Marshal(map[string]any{"content": res.Content})
+	}
+	if len(texts) == 1 {
+		if t := strings.TrimSpace(texts[0]); json.Valid([]byte(t)) && (strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[")) {
+			return json.RawMessage(t), nil
+		}
+	}
+	return json.Marshal(strings.Join(texts, "\n"))
+}
+
+func (u *upstream) markHealthy(tools int) {
+	u.mu.Lock()
+	wasHealthy := u.st.Healthy
+	u.st.Healthy, u.st.LastError, u.st.Tools, u.st.CheckedAt = true, "", tools, time.Now()
+	u.mu.Unlock()
+
+	u.bridge.registry.SetBuiltinPackDegraded(u.packID(), "")
+	if !wasHealthy {
+		u.logger.Info("MCP upstream healthy", "tools", tools)
+	}
+}
+
+func (u *upstream) markDegraded(err error) {
+	u.mu.Lock()
+	wasHealthy := u.st.Healthy || u.st.CheckedAt.IsZero()
+	u.st.Healthy, u.st.LastError, u.st.CheckedAt = false, err.Error(), time.Now()
+	u.mu.Unlock()
+
+	u.dropSession()
+	u.bridge.registry.SetBuiltinPackDegraded(u.packID(), err.Error())
+	if wasHealthy {
+		u.logger.Warn("MCP upstream degraded", "error", err)
+	}
+}
+
+func (u *upstream) status() Status {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	st := u.st
+	st.Name, st.URL = u.cfg.Name, u.cfg.URL
+	return st
+}
@@ -0,0 +1,412 @@
+// ABOUTME: Tests for the MCP upstream bridge against an httptest-hosted fake MCP server.
+// ABOUTME: Covers tool import, call translation, error mapping, timeouts, auth, list changes, and health.
+
+package mcpbridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/packs"
+)
+
+// fakeMCP is a small Streamable HTTP MCP server. Tools are served two per
+// page so listing exercises pagination.
+type fakeMCP struct {
+	t *testing.T
+
+	mu         sync.Mutex
+	tools      []string
+	sessions   map[string]bool
+	nextID     int
+	authSeen   string
+	down       bool
+	sseReplies bool
+	notify     chan string // methods pushed on the GET stream
+	slowDelay  time.Duration
+}
+
+func newFakeMCP(t *testing.T, tools ...string) (*fakeMCP, *httptest.Server) {
+	f := &fakeMCP{t: t, tools: tools, sessions: map[string]bool{}, notify: make(chan string, 4)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeMCP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	down := f.down
+	f.mu.Unlock()
+	if down {
+		http.Error(w, "upstream down", http.StatusBadGateway)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		f.serveStream(w, r)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		f.mu.Lock()
+		delete(f.sessions, r.Header.Get("Mcp-Session-Id"))
+		f.mu.Unlock()
+		return
+	}
+
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+
+	if req.Method == "initialize" {
+		f.mu.Lock()
+		f.nextID++
+		sid := fmt.Sprintf("session-%d", f.nextID)
+		f.sessions[sid] = true
+		f.authSeen = r.Header.Get("Authorization")
+		f.mu.Unlock()
+		w.Header().Set("Mcp-Session-Id", sid)
+		f.reply(w, req.ID, map[string]any{
+			"protocolVersion": protocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]bool{"listChanged": true}},
+			"serverInfo":      map[string]string{"name": "fake", "version": "0.1"},
+		}, nil)
+		return
+	}
+
+	f.mu.Lock()
+	known := f.sessions[r.Header.Get("Mcp-Session-Id")]
+	f.mu.Unlock()
+	if !known {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+
+	switch req.Method {
+	case "notifications/initialized":
+		w.WriteHeader(http.StatusAccepted)
+	case "tools/list":
+		var params struct {
+			Cursor string `json:"cursor"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		f.reply(w, req.ID, f.page(params.Cursor), nil)
+	case "tools/call":
+		f.call(w, req.ID, req.Params)
+	default:
+		f.reply(w, req.ID, nil, &RPCError{Code: rpcMethodNotFound, Message: "no such method"})
+	}
+}
+
+func (f *fakeMCP) page(cursor string) map[string]any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	start := 0
+	if cursor != "" {
+		_, _ = fmt.Sscanf(cursor, "page-%d", &start)
+	}
+	end := min(start+2, len(f.tools))
+	var tools []map[string]any
+	for _, name := range f.tools[start:end] {
+		tools = append(tools, map[string]any{
+			"name":        name,
+			"description": "fake " + name,
+			"inputSchema": map[string]any{"type": "object", "properties": map[string]any{"text": map[string]string{"type": "string"}}},
+		})
+	}
+	res := map[string]any{"tools": tools}
+	if end < len(f.tools) {
+		res["nextCursor"] = fmt.Sprintf("page-%d", end)
+	}
+	return res
+}
+
+func (f *fakeMCP) call(w http.ResponseWriter, id json.RawMessage, raw json.RawMessage) {
+	var params struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	}
+	_ = json.Unmarshal(raw, &params)
+	text := func(s string) []map[string]string { return []map[string]string{{"type": "text", "text": s}} }
+
+	switch params.Name {
+	case "echo":
+		out, _ := json.Marshal(params.Arguments)
+		f.reply(w, id, map[string]any{"content": text(string(out))}, nil)
+	case "greet":
+		f.reply(w, id, map[string]any{"content": text(fmt.Sprintf("hello, %v", params.Arguments["text"]))}, nil)
+	case "stats":
+		f.reply(w, id, map[string]any{"content": text("ignored"), "structuredContent": map[string]int{"files": 3}}, nil)
+	case "fail":
+		f.reply(w, id, map[string]any{"content": text("repository not found"), "isError": true}, nil)
+	case "strict":
+		f.reply(w, id, nil, &RPCError{Code: rpcInvalidParams, Message: "text is required"})
+	case "slow":
+		f.mu.Lock()
+		delay := f.slowDelay
+		f.mu.Unlock()
+		time.Sleep(delay)
+		f.reply(w, id, map[string]any{"content": text("late")}, nil)
+	default:
+		f.reply(w, id, nil, &RPCError{Code: rpcMethodNotFound, Message: "unknown tool"})
+	}
+}
+
+// reply writes a JSON-RPC response, as an SSE stream when sseReplies is
+// set. SSE replies lead with a stray notification, as real servers may.
+func (f *fakeMCP) reply(w http.ResponseWriter, id json.RawMessage, result any, rpcErr *RPCError) {
+	msg := map[string]any{"jsonrpc": "2.0", "id": id}
+	if rpcErr != nil {
+		msg["error"] = rpcErr
+	} else {
+		msg["result"] = result
+	}
+	data, _ := json.Marshal(msg)
+
+	f.mu.Lock()
+	sse := f.sseReplies
+	f.mu.Unlock()
+	if !sse {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	_, _ = fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+	_, _ = fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+}
+
+func (f *fakeMCP) serveStream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case method := <-f.notify:
+			_, _ = fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"method\":%q}\n\n", method)
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+func (f *fakeMCP) set(fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn()
+}
+
+type staticSecrets map[string]string
+
+func (s staticSecrets) GetEffectiveSecrets(context.Context, string) (map[string]string, error) {
+	return s, nil
+}
+
+func newTestBridge(t *testing.T, up Upstream, secrets SecretSource) (*Bridge, *packs.Registry) {
+	t.Helper()
+	registry := packs.NewRegistry(slog.Default())
+	b := New(registry, []Upstream{up}, secrets, nil, slog.Default())
+	t.Cleanup(b.Close)
+	return b, registry
+}
+
+func callTool(t *testing.T, registry *packs.Registry, name, input string) (json.RawMessage, error) {
+	t.Helper()
+	tool := registry.GetBuiltinTool(name)
+	if tool == nil {
+		t.Fatalf("tool %s not registered", name)
+	}
+	return tool.Handler(context.Background(), "agent-1", json.RawMessage(input))
+}
+
+func TestBridge_ImportsTools(t *testing.T) {
+	fake, srv := newFakeMCP(t, "echo", "greet", "stats")
+	b, registry := newTestBridge(t, Upstream{
+		Name: "fake", URL: srv.URL, Capability: "code",
+		AuthSecret: "FAKE_TOKEN", AuthScheme: "Bearer",
+	}, staticSecrets{"FAKE_TOKEN": "s3cret"})
+
+	b.Sync(context.Background())
+
+	for _, name := range []string{"mcp:fake:echo", "mcp:fake:greet", "mcp:fake:stats"} {
+		tool := registry.GetBuiltinTool(name)
+		if tool == nil {
+			t.Fatalf("%s not imported", name)
+		}
+		def := tool.Definition
+		if def.GetRequiredCapabilities()[0] != "code" || !strings.Contains(def.GetInputSchemaJson(), `"text"`) {
+			t.Errorf("%s definition = %v", name, def)
+		}
+	}
+	if got := registry.GetToolsForCapabilities(nil); len(got) != 0 {
+		t.Errorf("agents without the capability see %d tools, want 0", len(got))
+	}
+	fake.set(func() {
+		if fake.authSeen != "Bearer s3cret" {
+			t.Errorf("auth header = %q, want the resolved secret", fake.authSeen)
+		}
+	})
+
+	st := b.Statuses()[0]
+	if !st.Healthy || st.Tools != 3 {
+		t.Errorf("status = %+v, want healthy with 3 tools", st)
+	}
+	info := registry.ListBuiltinPacks()[0]
+	if info.ID != "mcp:fake" || info.Origin != srv.URL {
+		t.Errorf("pack info = %+v, want the upstream origin", info)
+	}
+}
+
+func TestBridge_CallTranslation(t *testing.T) {
+	for _, sse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("sse=%v", sse), func(t *testing.T) {
+			fake, srv := newFakeMCP(t, "echo", "greet", "stats", "fail", "strict")
+			fake.set(func() { fake.sseReplies = sse })
+			b, registry := newTestBridge(t, Upstream{Name: "fake", URL: srv.URL}, nil)
+			b.Sync(context.Background())
+
+			out, err := callTool(t, registry, "mcp:fake:echo", `{"text":"hi"}`)
+			if err != nil || string(out) != `{"text":"hi"}` {
+				t.Errorf("echo = %s, %v; want the JSON text as is", out, err)
+			}
+			out, err = callTool(t, registry, "mcp:fake:greet", `{"text":"bob"}`)
+			if err != nil || string(out) != `"hello, bob"` {
+				t.Errorf("greet = %s, %v; want a JSON string", out, err)
+			}
+			out, err = callTool(t, registry, "mcp:fake:stats", ``)
+			if err != nil || string(out) != `{"files":3}` {
+				t.Errorf("stats = %s, %v; want structured content", out, err)
+			}
+
+			if _, err := callTool(t, registry, "mcp:fake:fail", `{}`); err == nil || err.Error() != "repository not found" {
+				t.Errorf("fail err = %v, want the tool's error text", err)
+			}
+			if _, err := callTool(t, registry, "mcp:fake:strict", `{}`); err == nil ||
+				!strings.Contains(err.Error(), "invalid arguments for mcp:fake:strict: text is required") {
+				t.Errorf("strict err = %v, want invalid arguments", err)
+			}
+			if _, err := callTool(t, registry, "mcp:fake:echo", `[1,2]`); err == nil {
+				t.Error("non-object arguments should be rejected")
+			}
+			if !b.Statuses()[0].Healthy {
+				t.Error("tool-level errors should not degrade the upstream")
+			}
+		})
+	}
+}
+
+func TestBridge_Timeout(t *testing.T) {
+	fake, srv := newFakeMCP(t, "slow")
+	b, registry := newTestBridge(t, Upstream{Name: "fake", URL: srv.URL, Timeout: 50 * time.Millisecond}, nil)
+	b.Sync(context.Background())
+	fake.set(func() { fake.slowDelay = 300 * time.Millisecond })
+
+	_, err := callTool(t, registry, "mcp:fake:slow", `{}`)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+	if st := b.Statuses()[0]; st.Healthy {
+		t.Errorf("status = %+v, want degraded after a timeout", st)
+	}
+}
+
+func TestBridge_HealthAndRecovery(t *testing.T) {
+	fake, srv := newFakeMCP(t, "echo")
+	b, registry := newTestBridge(t, Upstream{Name: "fake", URL: srv.URL}, nil)
+	b.Sync(context.Background())
+
+	fake.set(func() { fake.down = true })
+	b.Sync(context.Background())
+	st := b.Statuses()[0]
+	if st.Healthy || !strings.Contains(st.LastError, "502") {
+		t.Errorf("status = %+v, want degraded with the HTTP error", st)
+	}
+	if info := registry.ListBuiltinPacks()[0]; info.Degraded == "" {
+		t.Error("registry should show the pack as degraded")
+	}
+	if _, err := callTool(t, registry, "mcp:fake:echo", `{}`); err == nil || !strings.Contains(err.Error(), "unreachable") {
+		t.Errorf("call while down = %v, want unreachable", err)
+	}
+
+	// The upstream restarts and forgets every session.
+	fake.set(func() {
+		fake.down = false
+		fake.sessions = map[string]bool{}
+	})
+	b.Sync(context.Background())
+	if !b.Statuses()[0].Healthy || registry.ListBuiltinPacks()[0].Degraded != "" {
+		t.Errorf("status = %+v, want healthy after recovery", b.Statuses()[0])
+	}
+	if out, err := callTool(t, registry, "mcp:fake:echo", `{"a":1}`); err != nil || string(out) != `{"a":1}` {
+		t.Errorf("call after recovery = %s, %v", out, err)
+	}
+}
+
+func TestBridge_SessionExpiryIsTransparent(t *testing.T) {
+	fake, srv := newFakeMCP(t, "echo")
+	b, registry := newTestBridge(t, Upstream{Name: "fake", URL: srv.URL}, nil)
+	b.Sync(context.Background())
+
+	fake.set(func() { fake.sessions = map[string]bool{} })
+	if out, err := callTool(t, registry, "mcp:fake:echo", `{"x":true}`); err != nil || string(out) != `{"x":true}` {
+		t.Errorf("call after expiry = %s, %v; want a silent re-initialize", out, err)
+	}
+}
+
+func TestBridge_RefreshesOnListChanged(t *testing.T) {
+	fake, srv := newFakeMCP(t, "echo")
+	b, registry := newTestBridge(t, Upstream{Name: "fake", URL: srv.URL, PollInterval: time.Hour}, nil)
+	b.Start()
+
+	waitFor(t, func() bool { return registry.IsBuiltin("mcp:fake:echo") })
+
+	fake.set(func() { fake.tools = []string{"greet"} })
+	fake.notify <- methodToolsListChanged
+
+	waitFor(t, func() bool { return registry.IsBuiltin("mcp:fake:greet") && !registry.IsBuiltin("mcp:fake:echo") })
+
+	b.Close()
+	if registry.IsBuiltin("mcp:fake:greet") {
+		t.Error("Close should remove the imported pack")
+	}
+}
+
+func TestBridge_MissingSecretDegrades(t *testing.T) {
+	_, srv := newFakeMCP(t, "echo")
+	b, registry := newTestBridge(t, Upstream{Name: "fake", URL: srv.URL, AuthSecret: "NOPE"}, staticSecrets{})
+	b.Sync(context.Background())
+
+	st := b.Statuses()[0]
+	if st.Healthy || !strings.Contains(st.LastError, `"NOPE" not found`) {
+		t.Errorf("status = %+v, want degraded on the missing secret", st)
+	}
+	packsInfo := registry.ListBuiltinPacks()
+	if len(packsInfo) != 1 || packsInfo[0].ID != "mcp:fake" || len(packsInfo[0].Tools) != 0 {
+		t.Errorf("packs = %+v, want the degraded pack listed without tools", packsInfo)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 2s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
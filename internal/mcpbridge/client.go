@@ -0,0 +1,406 @@
+// ABOUTME: Minimal MCP client over the Streamable HTTP transport (JSON-RPC POSTs, SSE replies).
+// ABOUTME: Speaks just enough of the protocol to initialize, list tools, call tools, and hear list changes.
+
+package mcpbridge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// protocolVersion is the MCP revision the bridge asks upstreams for.
+const protocolVersion = "2025-11-25"
+
+// methodToolsListChanged is the notification an upstream sends when its
+// tool set changes.
+const methodToolsListChanged = "notifications/tools/list_changed"
+
+// maxResponseBytes bounds a single JSON-RPC response read from an upstream.
+const maxResponseBytes = 16 << 20
+
+// errSessionExpired indicates the upstream no longer knows our session and
+// the client must initialize again.
+var errSessionExpired = errors.New("mcp session expired")
+
+// RPCError is a JSON-RPC error returned by an upstream.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Standard JSON-RPC error codes the bridge maps to friendlier messages.
+const (
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// rpcMessage is any message an upstream sends: a response when ID is set,
+// otherwise a notification.
+type rpcMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *RPCError       `json:"error,omitempty"`
+}
+
+type initializeResult struct {
+	ProtocolVersion string `json:"protocolVersion"`
+	Capabilities    struct {
+		Tools *struct {
+			ListChanged bool `json:"listChanged"`
+		} `json:"tools,omitempty"`
+	} `json:"capabilities"`
+	ServerInfo struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"serverInfo"`
+}
+
+// remoteTool is a tool as an upstream describes it in tools/list.
+type remoteTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+type listToolsResult struct {
+	Tools      []remoteTool `json:"tools"`
+	NextCursor string       `json:"nextCursor,omitempty"`
+}
+
+// contentBlock is one item of a tools/call result. Only text is interpreted;
+// other kinds are passed through untouched.
+type contentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+type callToolResult struct {
+	Content           []json.RawMessage `json:"content"`
+	StructuredContent json.RawMessage   `json:"structuredContent,omitempty"`
+	IsError           bool              `json:"isError,omitempty"`
+}
+
+// client is one MCP session with an upstream server.
+type client struct {
+	url    string
+	http   *http.Client
+	header http.Header // extra headers on every request, e.g. auth
+
+	// onNotify receives notification methods seen on any stream.
+	onNotify func(method string)
+
+	nextID atomic.Int64
+
+	mu        sync.Mutex
+	sessionID string
+	version   string
+}
+
+func newClient(url string, httpClient *http.Client, header http.Header, onNotify func(string)) *client {
+	return &client{url: url, http: httpClient, header: header, onNotify: onNotify}
+}
+
+// initialize runs the MCP handshake and returns what the server offers.
+func (c *client) initialize(ctx context.Context) (*initializeResult, error) {
+	c.mu.Lock()
+	c.sessionID, c.version = "", ""
+	c.mu.Unlock()
+
+	params := map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]string{"name": "coven-gateway", "version": "1.0"},
+	}
+	var res initializeResult
+	if err := c.call(ctx, "initialize", params, &res); err != nil {
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	c.mu.Lock()
+	c.version = res.ProtocolVersion
+	c.mu.Unlock()
+
+	if err := c.notify(ctx, "notifications/initialized"); err != nil {
+		return nil, fmt.Errorf("initialized notification: %w", err)
+	}
+	return &res, nil
+}
+
+// listTools pages through tools/list.
+func (c *client) listTools(ctx context.Context) ([]remoteTool, error) {
+	var tools []remoteTool
+	cursor := ""
+	for {
+		var params any
+		if cursor != "" {
+			params = map[string]string{"cursor": cursor}
+		}
+		var page listToolsResult
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, fmt.Errorf("tools/list: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// callTool invokes tools/call.
+func (c *client) callTool(ctx context.Context, name string, args json.RawMessage) (*callToolResult, error) {
+	var res callToolResult
+	params := map[string]any{"name": name, "arguments": args}
+	if err := c.call(ctx, "tools/call", params, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// call sends a request and decodes the matching response's result into out.
+func (c *client) call(ctx context.Context, method string, params, out any) error {
+	id := c.nextID.Add(1)
+	resp, err := c.post(ctx, rpcRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if method == "initialize" {
+		if sid := resp.Header.Get("Mcp-Session-Id"); sid != "" {
+			c.mu.Lock()
+			c.sessionID = sid
+			c.mu.Unlock()
+		}
+	}
+
+	msg, err := c.readResponse(resp, id)
+	if err != nil {
+		return err
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+	if out == nil || len(msg.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(msg.Result, out); err != nil {
+		return fmt.Errorf("decoding %s result: %w", method, err)
+	}
+	return nil
+}
+
+// notify sends a notification, which has no response.
+func (c *client) notify(ctx context.Context, method string) error {
+	resp, err := c.post(ctx, rpcRequest{JSONRPC: "2.0", Method: method})
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+	return resp.Body.Close()
+}
+
+// post sends one JSON-RPC message and returns the successful HTTP response.
+func (c *client) post(ctx context.Context, msg rpcRequest) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s: %w", msg.Method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	c.setSessionHeaders(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode == http.StatusNotFound && c.hasSession() {
+			return nil, errSessionExpired
+		}
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return resp, nil
+}
+
+func (c *client) setSessionHeaders(req *http.Request) {
+	for k, vs := range c.header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", c.sessionID)
+	}
+	if c.version != "" {
+		req.Header.Set("MCP-Protocol-Version", c.version)
+	}
+}
+
+func (c *client) hasSession() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionID != ""
+}
+
+// readResponse finds the response to request id in a plain JSON body or an
+// SSE stream, passing any notifications along the way to onNotify.
+func (c *client) readResponse(resp *http.Response, id int64) (*rpcMessage, error) {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		var msg rpcMessage
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&msg); err != nil {
+			return nil, fmt.Errorf("decoding response: %w", err)
+		}
+		return &msg, nil
+	}
+
+	want := fmt.Sprint(id)
+	var found *rpcMessage
+	err := readSSE(resp.Body, func(data []byte) bool {
+		var msg rpcMessage
+		if json.Unmarshal(data, &msg) != nil {
+			return true
+		}
+		if len(msg.ID) == 0 {
+			c.dispatch(msg.Method)
+			return true
+		}
+		if string(msg.ID) == want {
+			found = &msg
+			return false
+		}
+		return true
+	})
+	if found != nil {
+		return found, nil
+	}
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, fmt.Errorf("reading event stream: %w", err)
+}
+
+// listen holds open the server-to-client event stream and relays
+// notifications until ctx ends or the stream closes. It returns nil at once
+// when the server does not offer the stream.
+func (c *client) listen(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	c.setSessionHeaders(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("event stream: HTTP %d", resp.StatusCode)
+	}
+	return readSSE(resp.Body, func(data []byte) bool {
+		var msg rpcMessage
+		if json.Unmarshal(data, &msg) == nil && len(msg.ID) == 0 {
+			c.dispatch(msg.Method)
+		}
+		return true
+	})
+}
+
+func (c *client) dispatch(method string) {
+	if method != "" && c.onNotify != nil {
+		c.onNotify(method)
+	}
+}
+
+// readSSE calls fn with the data of each event until fn returns false or
+// the stream ends.
+func readSSE(r io.Reader, fn func(data []byte) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxResponseBytes)
+	var data []byte
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 && !fn(data) {
+				return nil
+			}
+			data = data[:0]
+		case strings.HasPrefix(line, "data:"):
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")...)
+		}
+	}
+	if len(data) > 0 {
+		fn(data)
+	}
+	return scanner.Err()
+}
+
+// close ends the session on the upstream, best effort.
+func (c *client) close(ctx context.Context) {
+	if !c.hasSession() {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.url, nil)
+	if err != nil {
+		return
+	}
+	c.setSessionHeaders(req)
+	if resp, err := c.http.Do(req); err == nil {
+		_ = resp.Body.Close()
+	}
+}
+
+// parseContent decodes the text blocks of a result, reporting whether every
+// block was text.
+func parseContent(raw []json.RawMessage) (texts []string, allText bool) {
+	allText = true
+	for _, item := range raw {
+		var block contentBlock
+		if json.Unmarshal(item, &block) != nil || block.Type != "text" {
+			allText = false
+			continue
+		}
+		texts = append(texts, block.Text)
+	}
+	return texts, allText
+}
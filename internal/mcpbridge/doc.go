@@ -0,0 +1,33 @@
+// Package mcpbridge imports tools from external MCP servers into the gateway.
+//
+// # Overview
+//
+// Each configured upstream (packs.mcp_servers in the gateway config) is an
+// MCP server reachable over the Streamable HTTP transport. The bridge acts as
+// an MCP client: it runs initialize and tools/list against the upstream and
+// registers the tools in the pack registry as an in-process pack named
+// mcp:<name>, with tool names mcp:<name>:<tool>. Agents see and call them like
+// any other tool; calls are forwarded with tools/call.
+//
+// # Results and Errors
+//
+// Structured content is returned to the agent as is. Text results become the
+// JSON they contain, or a JSON string of the text. Results flagged isError,
+// JSON-RPC errors, timeouts, and transport failures all surface as tool
+// errors naming the upstream.
+//
+// # Refresh and Health
+//
+// The tool list is refreshed when the upstream sends
+// notifications/tools/list_changed and on every poll interval. A failed poll
+// or an unreachable upstream marks the pack degraded (shown on the tools
+// page) and drops the session; the next poll reconnects. Expired sessions are
+// re-initialized transparently.
+//
+// # Authentication
+//
+// An upstream may name a secret (see the Secrets page) whose value is sent in
+// an auth header, optionally prefixed by a scheme such as "Bearer". Secrets
+// are resolved on every connect, so rotating one takes effect at the next
+// reconnect.
+package mcpbridge
@@ -19,12 +19,20 @@ type ToolHandler func(ctx context.Context, agentID string, input json.RawMessage
 type BuiltinTool struct {
 	Definition *pb.ToolDefinition
 	Handler    ToolHandler
+
+	// Precheck, if set, reports why a call by agentID would be refused right
+	// now (such as a spent quota) without side effects. Dry runs use it.
+	Precheck func(ctx context.Context, agentID string) error
 }
 
 // BuiltinPack is a collection of built-in tools with a pack ID.
 type BuiltinPack struct {
 	ID    string
 	Tools []*BuiltinTool
+
+	// Origin, if set, names where the tools really run, such as the URL of
+	// an upstream MCP server whose tools the gateway proxies in-process.
+	Origin string
 }
 
 // builtinEntry stores a builtin tool with its pack ID for registry lookup.
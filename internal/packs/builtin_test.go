@@ -491,3 +491,54 @@ func TestBuiltinCollision(t *testing.T) {
 		}
 	})
 }
+
+func TestReplaceBuiltinPack(t *testing.T) {
+	tools := func(names ...string) []*BuiltinTool {
+		var out []*BuiltinTool
+		for _, n := range names {
+			out = append(out, &BuiltinTool{Definition: &pb.ToolDefinition{Name: n}})
+		}
+		return out
+	}
+
+	registry := NewRegistry(slog.Default())
+	if err := registry.RegisterBuiltinPack(&BuiltinPack{ID: "builtin:base", Tools: tools("log_entry")}); err != nil {
+		t.Fatalf("RegisterBuiltinPack: %v", err)
+	}
+	if err := registry.ReplaceBuiltinPack(&BuiltinPack{ID: "mcp:fs", Origin: "http://fs", Tools: tools("mcp:fs:read", "mcp:fs:list")}); err != nil {
+		t.Fatalf("first ReplaceBuiltinPack: %v", err)
+	}
+	if err := registry.ReplaceBuiltinPack(&BuiltinPack{ID: "mcp:fs", Origin: "http://fs", Tools: tools("mcp:fs:read", "mcp:fs:write")}); err != nil {
+		t.Fatalf("second ReplaceBuiltinPack: %v", err)
+	}
+	if registry.IsBuiltin("mcp:fs:list") || !registry.IsBuiltin("mcp:fs:write") || !registry.IsBuiltin("mcp:fs:read") {
+		t.Error("replace should drop removed tools and add new ones")
+	}
+
+	err := registry.ReplaceBuiltinPack(&BuiltinPack{ID: "mcp:fs", Tools: tools("log_entry")})
+	if !errors.Is(err, ErrToolCollision) {
+		t.Errorf("err = %v, want ErrToolCollision for another pack's tool", err)
+	}
+	if !registry.IsBuiltin("mcp:fs:read") {
+		t.Error("a failed replace should leave the old tools in place")
+	}
+
+	registry.SetBuiltinPackDegraded("mcp:fs", "connection refused")
+	var info BuiltinPackInfo
+	for _, p := range registry.ListBuiltinPacks() {
+		if p.ID == "mcp:fs" {
+			info = p
+		}
+	}
+	if info.Origin != "http://fs" || info.Degraded != "connection refused" || len(info.Tools) != 2 {
+		t.Errorf("pack info = %+v, want origin, degraded reason, and two tools", info)
+	}
+
+	registry.UnregisterBuiltinPack("mcp:fs")
+	if registry.IsBuiltin("mcp:fs:read") || len(registry.ListBuiltinPacks()) != 1 {
+		t.Error("unregister should remove the pack and its health mark")
+	}
+	if !registry.IsBuiltin("log_entry") {
+		t.Error("unregister should leave other packs alone")
+	}
+}
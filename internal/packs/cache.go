@@ -0,0 +1,156 @@
+// ABOUTME: Response cache for pack tools that declare themselves cacheable, keyed by tool, input, and capabilities.
+// ABOUTME: Entries expire after the tool's TTL; the least recently used is evicted once the cache is full.
+
+package packs
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NoCacheParam is the reserved input parameter that bypasses the result
+// cache for one call. It is removed from the input before the call is
+// routed; the fresh result still replaces any cached one.
+const NoCacheParam = "_no_cache"
+
+// DefaultCacheMaxEntries bounds the result cache when
+// RouterConfig.CacheMaxEntries is zero.
+const DefaultCacheMaxEntries = 1000
+
+// ParseNoCache reports whether inputJSON asks to bypass the result cache,
+// returning the input with the reserved parameter removed.
+func ParseNoCache(inputJSON string) (string, bool) {
+	if !strings.Contains(inputJSON, NoCacheParam) {
+		return inputJSON, false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(inputJSON), &fields); err != nil {
+		return inputJSON, false
+	}
+	raw, ok := fields[NoCacheParam]
+	if !ok {
+		return inputJSON, false
+	}
+	var noCache bool
+	if err := json.Unmarshal(raw, &noCache); err != nil {
+		return inputJSON, false
+	}
+	delete(fields, NoCacheParam)
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return inputJSON, false
+	}
+	return string(stripped), noCache
+}
+
+// canonicalJSON re-encodes inputJSON with object keys sorted and
+// insignificant whitespace dropped, so inputs that differ only in key order
+// or spacing compare equal. Numbers keep their literal form.
+func canonicalJSON(inputJSON string) (string, bool) {
+	dec := json.NewDecoder(strings.NewReader(inputJSON))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return "", false
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", false
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), true
+}
+
+// cacheKey identifies a cacheable call: the tool, its canonical input, and
+// the caller's capabilities, since a tool may answer differently depending
+// on what the caller holds. It reports false for input that is not JSON.
+func cacheKey(toolName, inputJSON string, caps []string) (string, bool) {
+	input, ok := canonicalJSON(inputJSON)
+	if !ok {
+		return "", false
+	}
+	caps = slices.Clone(caps)
+	slices.Sort(caps)
+	caps = slices.Compact(caps)
+	return toolName + "\x00" + input + "\x00" + strings.Join(caps, ","), true
+}
+
+// resultCache is an LRU cache of successful tool outputs.
+type resultCache struct {
+	max int
+	now func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key     string
+	output  string
+	expires time.Time
+}
+
+func newResultCache(maxEntries int) *resultCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheMaxEntries
+	}
+	return &resultCache{
+		max:     maxEntries,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the unexpired output cached under key, dropping it if it has
+// expired.
+func (c *resultCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	e := el.Value.(*cacheEntry)
+	if !c.now().Before(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return "", false
+	}
+	c.order.MoveToFront(el)
+	return e.output, true
+}
+
+// put caches output under key for ttl, evicting the least recently used
+// entry if the cache is full.
+func (c *resultCache) put(key, output string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.now().Add(ttl)
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		e.output, e.expires = output, expires
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, output: output, expires: expires})
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// len returns the number of entries, expired or not.
+func (c *resultCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
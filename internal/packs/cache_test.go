@@ -0,0 +1,187 @@
+// ABOUTME: Tests for the tool result cache: input canonicalization, TTL expiry, LRU eviction,
+// ABOUTME: the _no_cache bypass, and the router answering repeat calls from the cache.
+
+package packs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestCacheKeyCanonicalizesInput(t *testing.T) {
+	a, ok := cacheKey("search", `{"q": "cats", "opts": {"limit": 10, "lang": "en"}}`, []string{"web", "files"})
+	if !ok {
+		t.Fatal("cacheKey rejected JSON input")
+	}
+	b, _ := cacheKey("search", `{"opts":{"lang":"en","limit":10},"q":"cats"}`, []string{"files", "web", "web"})
+	if a != b {
+		t.Errorf("keys differ for reordered input and capabilities:\n%q\n%q", a, b)
+	}
+
+	for name, other := range map[string][]any{
+		"tool":         {"fetch", `{"q":"cats","opts":{"lang":"en","limit":10}}`, []string{"web", "files"}},
+		"input":        {"search", `{"q":"dogs","opts":{"lang":"en","limit":10}}`, []string{"web", "files"}},
+		"number form":  {"search", `{"q":"cats","opts":{"lang":"en","limit":10.0}}`, []string{"web", "files"}},
+		"capabilities": {"search", `{"q":"cats","opts":{"lang":"en","limit":10}}`, []string{"web"}},
+	} {
+		key, _ := cacheKey(other[0].(string), other[1].(string), other[2].([]string))
+		if key == a {
+			t.Errorf("%s: key matches a different call", name)
+		}
+	}
+
+	if _, ok := cacheKey("search", `{"q":`, nil); ok {
+		t.Error("cacheKey accepted malformed input")
+	}
+	if _, ok := cacheKey("search", `{} {}`, nil); ok {
+		t.Error("cacheKey accepted trailing input")
+	}
+}
+
+func TestParseNoCache(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		noCache bool
+	}{
+		{`{"q":"cats"}`, `{"q":"cats"}`, false},
+		{`{"q":"cats","_no_cache":true}`, `{"q":"cats"}`, true},
+		{`{"q":"cats","_no_cache":false}`, `{"q":"cats"}`, false},
+		{`{"q":"_no_cache"}`, `{"q":"_no_cache"}`, false},
+		{`{"_no_cache":"yes"}`, `{"_no_cache":"yes"}`, false},
+		{`not json _no_cache`, `not json _no_cache`, false},
+	}
+	for _, tt := range tests {
+		got, noCache := ParseNoCache(tt.input)
+		if got != tt.want || noCache != tt.noCache {
+			t.Errorf("ParseNoCache(%s) = %s, %v; want %s, %v", tt.input, got, noCache, tt.want, tt.noCache)
+		}
+	}
+}
+
+func TestResultCacheTTL(t *testing.T) {
+	c := newResultCache(10)
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+
+	c.put("k", "v", time.Minute)
+	if got, ok := c.get("k"); !ok || got != "v" {
+		t.Fatalf("get = %q, %v; want v", got, ok)
+	}
+	now = now.Add(59 * time.Second)
+	if _, ok := c.get("k"); !ok {
+		t.Error("entry expired before its TTL")
+	}
+	now = now.Add(time.Second)
+	if _, ok := c.get("k"); ok {
+		t.Error("entry served after its TTL")
+	}
+	if n := c.len(); n != 0 {
+		t.Errorf("len = %d after expiry, want the expired entry dropped", n)
+	}
+}
+
+func TestResultCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newResultCache(2)
+	c.put("a", "1", time.Hour)
+	c.put("b", "2", time.Hour)
+	c.get("a") // b is now the least recently used
+	c.put("c", "3", time.Hour)
+
+	if _, ok := c.get("b"); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.get(k); !ok {
+			t.Errorf("entry %q evicted, want it kept", k)
+		}
+	}
+	if n := c.len(); n != 2 {
+		t.Errorf("len = %d, want 2", n)
+	}
+}
+
+func TestRouterResultCache(t *testing.T) {
+	registry := NewRegistry(slog.Default())
+	var caps atomic.Value
+	caps.Store([]string{"web"})
+	var hits, misses atomic.Int32
+	router := NewRouter(RouterConfig{
+		Registry:     registry,
+		Logger:       slog.Default(),
+		Timeout:      5 * time.Second,
+		Capabilities: func(string) []string { return caps.Load().([]string) },
+		OnCacheLookup: func(_ string, hit bool) {
+			if hit {
+				hits.Add(1)
+			} else {
+				misses.Add(1)
+			}
+		},
+	})
+	cacheable := createTestTool("lookup", "A cacheable tool")
+	cacheable.Cacheable = true
+	cacheable.CacheTtlSeconds = 60
+	pack := registerTestPack(t, registry, "cache-pack", cacheable, createTestTool("plain", "Not cacheable"))
+
+	var executed atomic.Int32
+	go func() {
+		for req := range pack.Channel {
+			n := executed.Add(1)
+			router.HandleToolResponse(&pb.ExecuteToolResponse{
+				RequestId: req.RequestId,
+				Result:    &pb.ExecuteToolResponse_OutputJson{OutputJson: fmt.Sprintf(`{"run":%d}`, n)},
+			})
+		}
+	}()
+
+	ctx := context.Background()
+	call := func(tool, input string) *pb.ExecuteToolResponse {
+		t.Helper()
+		resp, err := router.RouteToolCall(ctx, tool, input, fmt.Sprintf("req-%d", executed.Load()+hits.Load()+100), "agent")
+		if err != nil {
+			t.Fatalf("RouteToolCall(%s, %s): %v", tool, input, err)
+		}
+		return resp
+	}
+
+	first := call("lookup", `{"a":1,"b":2}`)
+	if first.GetCached() || first.GetOutputJson() != `{"run":1}` {
+		t.Fatalf("first call = %v, want a fresh result", first)
+	}
+	second := call("lookup", `{"b":2, "a":1}`)
+	if !second.GetCached() || second.GetOutputJson() != `{"run":1}` {
+		t.Errorf("repeat call = %v, want the first result marked cached", second)
+	}
+
+	bypass := call("lookup", `{"a":1,"b":2,"_no_cache":true}`)
+	if bypass.GetCached() || bypass.GetOutputJson() != `{"run":2}` {
+		t.Errorf("_no_cache call = %v, want a fresh result", bypass)
+	}
+	if refreshed := call("lookup", `{"a":1,"b":2}`); refreshed.GetOutputJson() != `{"run":2}` {
+		t.Errorf("call after bypass = %v, want the bypass's fresh result", refreshed)
+	}
+
+	caps.Store([]string{"web", "files"})
+	if other := call("lookup", `{"a":1,"b":2}`); other.GetCached() {
+		t.Error("call with other capabilities was answered from the cache")
+	}
+
+	call("plain", `{}`)
+	if plain := call("plain", `{}`); plain.GetCached() {
+		t.Error("tool without cacheable was answered from the cache")
+	}
+
+	if executed.Load() != 5 {
+		t.Errorf("pack executed %d calls, want 5", executed.Load())
+	}
+	if hits.Load() != 2 || misses.Load() != 2 {
+		t.Errorf("cache lookups = %d hits, %d misses; want 2 and 2", hits.Load(), misses.Load())
+	}
+}
@@ -0,0 +1,129 @@
+// ABOUTME: Capability checks on tool calls: every builtin and pack tool is checked against the caller's grants.
+// ABOUTME: In warn mode a missing capability is logged and reported instead of rejecting the call.
+// ABOUTME: Grants may be patterns: "web.*" covers every capability under "web", "*" covers all of them.
+
+package packs
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// Capability enforcement modes, see RouterConfig.CapabilityEnforcement.
+const (
+	CapabilityEnforce = "enforce"
+	CapabilityWarn    = "warn"
+)
+
+// WildcardCapability is the grant that covers every capability. Only
+// trusted admin principals should hold it.
+const WildcardCapability = "*"
+
+// ErrInvalidCapability indicates a malformed capability or capability pattern.
+var ErrInvalidCapability = errors.New("invalid capability")
+
+// ErrCapabilityDenied indicates the caller lacks a capability the tool requires.
+var ErrCapabilityDenied = coverr.New(coverr.CapabilityDenied, string(coverr.CapabilityDenied))
+
+// CapabilityDeniedError names the tool and the capability the caller lacked.
+// It wraps ErrCapabilityDenied.
+type CapabilityDeniedError struct {
+	Tool       string
+	Capability string
+}
+
+func (e *CapabilityDeniedError) Error() string {
+	return fmt.Sprintf("%s: tool %q requires capability %q", ErrCapabilityDenied, e.Tool, e.Capability)
+}
+
+// Unwrap returns ErrCapabilityDenied, so the error matches it and carries its code.
+func (e *CapabilityDeniedError) Unwrap() error {
+	return ErrCapabilityDenied
+}
+
+// MatchCapability reports whether the granted capability or pattern covers
+// required. Capabilities are dot-separated segments. "*" covers everything,
+// and a trailing ".*" covers the prefix's descendants only on a segment
+// boundary, so "web.*" covers "web.search" but not "web" or "webhook.fire".
+func MatchCapability(granted, required string) bool {
+	if granted == WildcardCapability {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(granted, ".*"); ok {
+		return strings.HasPrefix(required, prefix+".")
+	}
+	return granted == required
+}
+
+// HasCapability reports whether any of held covers required.
+func HasCapability(held []string, required string) bool {
+	return slices.ContainsFunc(held, func(granted string) bool {
+		return MatchCapability(granted, required)
+	})
+}
+
+// ValidateCapability checks that c is a capability or capability pattern:
+// "*", or dot-separated non-empty segments of which only the last may be "*".
+func ValidateCapability(c string) error {
+	if c == WildcardCapability {
+		return nil
+	}
+	segments := strings.Split(c, ".")
+	for i, seg := range segments {
+		switch {
+		case seg == "":
+			return fmt.Errorf("%w %q: empty segment", ErrInvalidCapability, c)
+		case seg == WildcardCapability && i != len(segments)-1:
+			return fmt.Errorf("%w %q: \"*\" must be the last segment", ErrInvalidCapability, c)
+		case seg != WildcardCapability && strings.ContainsAny(seg, "* \t\n"):
+			return fmt.Errorf("%w %q: segments may not contain \"*\" or whitespace", ErrInvalidCapability, c)
+		}
+	}
+	return nil
+}
+
+// missingCapabilities returns the capabilities def requires that held lacks.
+func missingCapabilities(def *pb.ToolDefinition, held []string) []string {
+	var missing []string
+	for _, required := range def.GetRequiredCapabilities() {
+		if !HasCapability(held, required) {
+			missing = append(missing, required)
+		}
+	}
+	return missing
+}
+
+// checkCapabilities checks agentID's grants against the capabilities def
+// requires. It rejects the call with a CapabilityDeniedError, or in warn mode
+// logs and reports each missing capability and lets the call through.
+func (r *Router) checkCapabilities(def *pb.ToolDefinition, toolName, requestID, agentID string) error {
+	if r.capabilities == nil || len(def.GetRequiredCapabilities()) == 0 {
+		return nil
+	}
+	for _, capability := range missingCapabilities(def, r.capabilities(agentID)) {
+		if r.enforcementMode() != CapabilityWarn {
+			r.logger.Warn("tool call denied: missing capability",
+				"tool_name", toolName,
+				"request_id", requestID,
+				"agent_id", agentID,
+				"capability", capability,
+			)
+			return &CapabilityDeniedError{Tool: toolName, Capability: capability}
+		}
+		r.logger.Warn("tool call allowed without capability grant",
+			"tool_name", toolName,
+			"request_id", requestID,
+			"agent_id", agentID,
+			"capability", capability,
+		)
+		if r.onCapabilityWarning != nil {
+			r.onCapabilityWarning(agentID, toolName, capability)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// ABOUTME: Tests for capability checks on tool calls across builtin and pack tools.
+// ABOUTME: Covers every combination of tool kind, granted or missing capability, and enforcement mode, plus pattern matching.
+
+package packs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestRouterCapabilityEnforcement(t *testing.T) {
+	type warning struct{ agent, tool, capability string }
+
+	tests := []struct {
+		name     string
+		tool     string
+		granted  bool
+		mode     string
+		wantDeny bool
+	}{
+		{"builtin granted enforce", "log_entry", true, CapabilityEnforce, false},
+		{"builtin granted warn", "log_entry", true, CapabilityWarn, false},
+		{"builtin missing enforce", "log_entry", false, CapabilityEnforce, true},
+		{"builtin missing warn", "log_entry", false, CapabilityWarn, false},
+		{"builtin missing default mode", "log_entry", false, "", true},
+		{"pack granted enforce", "deploy", true, CapabilityEnforce, false},
+		{"pack granted warn", "deploy", true, CapabilityWarn, false},
+		{"pack missing enforce", "deploy", false, CapabilityEnforce, true},
+		{"pack missing warn", "deploy", false, CapabilityWarn, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var invoked, results int
+			var warnings []warning
+
+			registry := NewRegistry(slog.Default())
+			err := registry.RegisterBuiltinPack(&BuiltinPack{ID: "builtin:test", Tools: []*BuiltinTool{{
+				Definition: &pb.ToolDefinition{Name: "log_entry", RequiredCapabilities: []string{"base"}},
+				Handler: func(context.Context, string, json.RawMessage) (json.RawMessage, error) {
+					invoked++
+					return json.RawMessage(`{}`), nil
+				},
+			}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			pack := registerTestPack(t, registry, "ops", &pb.ToolDefinition{Name: "deploy", RequiredCapabilities: []string{"ops"}})
+			router := NewRouter(RouterConfig{
+				Registry: registry,
+				Logger:   slog.Default(),
+				OnResult: func(string, string, bool) { results++ },
+				Capabilities: func(agentID string) []string {
+					if tt.granted {
+						return []string{"base", "ops"}
+					}
+					return []string{"chat"}
+				},
+				CapabilityEnforcement: tt.mode,
+				OnCapabilityWarning: func(agentID, toolName, capability string) {
+					warnings = append(warnings, warning{agentID, toolName, capability})
+				},
+			})
+			go func() {
+				for req := range pack.Channel {
+					invoked++
+					router.HandleToolResponse(&pb.ExecuteToolResponse{
+						RequestId: req.GetRequestId(),
+						Result:    &pb.ExecuteToolResponse_OutputJson{OutputJson: `{}`},
+					})
+				}
+			}()
+			t.Cleanup(func() { registry.UnregisterPack("ops") })
+
+			resp, err := router.RouteToolCall(context.Background(), tt.tool, `{}`, "req-1", "agent-1")
+
+			if tt.wantDeny {
+				var denied *CapabilityDeniedError
+				if !errors.Is(err, ErrCapabilityDenied) || !errors.As(err, &denied) || denied.Tool != tt.tool {
+					t.Fatalf("err = %v, want a capability_denied error for %s", err, tt.tool)
+				}
+				if invoked != 0 || results != 0 {
+					t.Errorf("denied call invoked=%d results=%d, want 0, 0", invoked, results)
+				}
+				return
+			}
+			if err != nil || resp.GetError() != "" {
+				t.Fatalf("RouteToolCall = %v, %v; want success", resp, err)
+			}
+			if invoked != 1 {
+				t.Errorf("invoked = %d, want 1", invoked)
+			}
+			wantWarnings := 0
+			if !tt.granted {
+				wantWarnings = 1
+			}
+			if len(warnings) != wantWarnings {
+				t.Fatalf("warnings = %v, want %d", warnings, wantWarnings)
+			}
+			if wantWarnings == 1 && (warnings[0].agent != "agent-1" || warnings[0].tool != tt.tool) {
+				t.Errorf("warning = %+v", warnings[0])
+			}
+		})
+	}
+
+	t.Run("tools without required capabilities are not checked", func(t *testing.T) {
+		registry := NewRegistry(slog.Default())
+		err := registry.RegisterBuiltinPack(&BuiltinPack{ID: "builtin:test", Tools: []*BuiltinTool{{
+			Definition: &pb.ToolDefinition{Name: "ping"},
+			Handler: func(context.Context, string, json.RawMessage) (json.RawMessage, error) {
+				return json.RawMessage(`{}`), nil
+			},
+		}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		router := NewRouter(RouterConfig{
+			Registry:     registry,
+			Logger:       slog.Default(),
+			Capabilities: func(string) []string { return nil },
+		})
+		if _, err := router.RouteToolCall(context.Background(), "ping", `{}`, "req-1", "agent-1"); err != nil {
+			t.Fatalf("RouteToolCall: %v", err)
+		}
+	})
+}
+
+func TestMatchCapability(t *testing.T) {
+	tests := []struct {
+		granted  string
+		required string
+		want     bool
+	}{
+		{"web.search", "web.search", true},
+		{"web.search", "web.fetch", false},
+		{"web", "web.search", false},
+		{"web.*", "web.search", true},
+		{"web.*", "web.fetch", true},
+		{"web.*", "web.search.deep", true},
+		{"web.*", "web", false},
+		{"web.*", "webhook.fire", false},
+		{"web.*", "webhook", false},
+		{"web.search.*", "web.search.deep", true},
+		{"web.search.*", "web.fetch", false},
+		{"*", "web.search", true},
+		{"*", "admin", true},
+		{"*", "*", true},
+		{"base", "base", true},
+		{"base", "base.extra", false},
+		{"", "base", false},
+		{"web.*", "", false},
+	}
+	for _, tt := range tests {
+		if got := MatchCapability(tt.granted, tt.required); got != tt.want {
+			t.Errorf("MatchCapability(%q, %q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestHasCapability(t *testing.T) {
+	held := []string{"base", "web.*"}
+	for required, want := range map[string]bool{
+		"base":         true,
+		"web.search":   true,
+		"webhook.fire": false,
+		"notes":        false,
+	} {
+		if got := HasCapability(held, required); got != want {
+			t.Errorf("HasCapability(%v, %q) = %v, want %v", held, required, got, want)
+		}
+	}
+	if HasCapability(nil, "base") {
+		t.Error("HasCapability(nil) = true, want false")
+	}
+}
+
+func TestValidateCapability(t *testing.T) {
+	for _, c := range []string{"base", "web.search", "web.*", "web.search.*", "*", "mcp:github"} {
+		if err := ValidateCapability(c); err != nil {
+			t.Errorf("ValidateCapability(%q) = %v, want nil", c, err)
+		}
+	}
+	for _, c := range []string{"", "web.", ".web", "web..search", "*.search", "web.*.search", "web*", "we b", "web.se*"} {
+		if err := ValidateCapability(c); !errors.Is(err, ErrInvalidCapability) {
+			t.Errorf("ValidateCapability(%q) = %v, want ErrInvalidCapability", c, err)
+		}
+	}
+}
+
+func TestRouterCapabilityPatterns(t *testing.T) {
+	registry := NewRegistry(slog.Default())
+	err := registry.RegisterBuiltinPack(&BuiltinPack{ID: "builtin:test", Tools: []*BuiltinTool{
+		{
+			Definition: &pb.ToolDefinition{Name: "search", RequiredCapabilities: []string{"web.search"}},
+			Handler: func(context.Context, string, json.RawMessage) (json.RawMessage, error) {
+				return json.RawMessage(`{}`), nil
+			},
+		},
+		{
+			Definition: &pb.ToolDefinition{Name: "fire", RequiredCapabilities: []string{"webhook.fire"}},
+			Handler: func(context.Context, string, json.RawMessage) (json.RawMessage, error) {
+				return json.RawMessage(`{}`), nil
+			},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	grants := map[string][]string{"agent-web": {"web.*"}, "agent-admin": {"*"}}
+	router := NewRouter(RouterConfig{
+		Registry:              registry,
+		Logger:                slog.Default(),
+		Capabilities:          func(agentID string) []string { return grants[agentID] },
+		CapabilityEnforcement: CapabilityEnforce,
+	})
+
+	tests := []struct {
+		agent, tool string
+		wantDeny    bool
+	}{
+		{"agent-web", "search", false},
+		{"agent-web", "fire", true},
+		{"agent-admin", "search", false},
+		{"agent-admin", "fire", false},
+	}
+	for _, tt := range tests {
+		_, err := router.RouteToolCall(context.Background(), tt.tool, `{}`, "req-1", tt.agent)
+		if denied := errors.Is(err, ErrCapabilityDenied); denied != tt.wantDeny {
+			t.Errorf("%s calling %s: err = %v, want denied=%v", tt.agent, tt.tool, err, tt.wantDeny)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// ABOUTME: Tool catalog versioning: diffs pack manifests against stored snapshots.
+// ABOUTME: Produces added/removed/modified changelog entries with field-level schema diffs.
+
+package packs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// CatalogStore persists tool definition snapshots and the tool changelog.
+type CatalogStore interface {
+	GetToolSnapshots(ctx context.Context, packID string) (map[string]string, error)
+	SaveToolCatalog(ctx context.Context, packID string, snapshots map[string]string, changes []*store.ToolChange) (int64, error)
+	GetToolCatalogVersion(ctx context.Context) (int64, error)
+}
+
+// CatalogTracker records how each pack's tools change across registrations.
+type CatalogTracker struct {
+	store  CatalogStore
+	logger *slog.Logger
+	mu     sync.Mutex // serializes Record so snapshot reads and writes don't interleave
+}
+
+// NewCatalogTracker creates a CatalogTracker backed by the given store.
+func NewCatalogTracker(s CatalogStore, logger *slog.Logger) *CatalogTracker {
+	return &CatalogTracker{store: s, logger: logger}
+}
+
+// Record diffs the manifest against the pack's previous snapshot and persists
+// any changes. Returns the resulting catalog version and the recorded changes;
+// a byte-identical re-registration records nothing and keeps the version.
+func (c *CatalogTracker) Record(ctx context.Context, manifest *pb.PackManifest) (int64, []*store.ToolChange, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	packID := manifest.GetPackId()
+	previous, err := c.store.GetToolSnapshots(ctx, packID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	incoming := make(map[string]string, len(manifest.GetTools()))
+	for _, def := range manifest.GetTools() {
+		snapshot, err := snapshotTool(def)
+		if err != nil {
+			return 0, nil, err
+		}
+		incoming[def.GetName()] = snapshot
+	}
+
+	changes := DiffToolSnapshots(previous, incoming)
+	for _, change := range changes {
+		change.PackVersion = manifest.GetVersion()
+	}
+
+	version, err := c.store.SaveToolCatalog(ctx, packID, incoming, changes)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(changes) > 0 {
+		c.logger.Info("tool catalog changed",
+			"pack_id", packID,
+			"pack_version", manifest.GetVersion(),
+			"changes", len(changes),
+			"catalog_version", version,
+		)
+	}
+	return version, changes, nil
+}
+
+// Version returns the current catalog version.
+func (c *CatalogTracker) Version(ctx context.Context) (int64, error) {
+	return c.store.GetToolCatalogVersion(ctx)
+}
+
+// toolSnapshot is the canonical stored form of a tool definition.
+// The tool name is the snapshot key, so it is not repeated here.
+type toolSnapshot struct {
+	Description          string   `json:"description"`
+	InputSchema          string   `json:"input_schema"`
+	RequiredCapabilities []string `json:"required_capabilities"`
+	TimeoutSeconds       int32    `json:"timeout_seconds"`
+}
+
+// snapshotTool encodes a tool definition into its canonical snapshot string.
+func snapshotTool(def *pb.ToolDefinition) (string, error) {
+	data, err := json.Marshal(toolSnapshot{
+		Description:          def.GetDescription(),
+		InputSchema:          def.GetInputSchemaJson(),
+		RequiredCapabilities: def.GetRequiredCapabilities(),
+		TimeoutSeconds:       def.GetTimeoutSeconds(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding snapshot for tool %s: %w", def.GetName(), err)
+	}
+	return string(data), nil
+}
+
+// DiffToolSnapshots compares two snapshot sets keyed by tool name and returns
+// the changes sorted by tool name. Identical snapshots produce no changes.
+func DiffToolSnapshots(previous, incoming map[string]string) []*store.ToolChange {
+	var changes []*store.ToolChange
+	for name, snapshot := range incoming {
+		old, existed := previous[name]
+		switch {
+		case !existed:
+			changes = append(changes, &store.ToolChange{ToolName: name, ChangeType: store.ToolAdded})
+		case old != snapshot:
+			changes = append(changes, &store.ToolChange{
+				ToolName:   name,
+				ChangeType: store.ToolModified,
+				Diff:       diffSnapshots(old, snapshot),
+			})
+		}
+	}
+	for name := range previous {
+		if _, ok := incoming[name]; !ok {
+			changes = append(changes, &store.ToolChange{ToolName: name, ChangeType: store.ToolRemoved})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ToolName < changes[j].ToolName })
+	return changes
+}
+
+// diffSnapshots returns the field-level differences between two snapshots.
+func diffSnapshots(oldRaw, newRaw string) []store.ToolFieldChange {
+	var oldSnap, newSnap toolSnapshot
+	if json.Unmarshal([]byte(oldRaw), &oldSnap) != nil || json.Unmarshal([]byte(newRaw), &newSnap) != nil {
+		return []store.ToolFieldChange{{Path: "definition", Old: oldRaw, New: newRaw}}
+	}
+
+	var diff []store.ToolFieldChange
+	if oldSnap.Description != newSnap.Description {
+		diff = append(diff, store.ToolFieldChange{Path: "description", Old: oldSnap.Description, New: newSnap.Description})
+	}
+	if oldSnap.TimeoutSeconds != newSnap.TimeoutSeconds {
+		diff = append(diff, store.ToolFieldChange{Path: "timeout_seconds", Old: oldSnap.TimeoutSeconds, New: newSnap.TimeoutSeconds})
+	}
+	if !slices.Equal(oldSnap.RequiredCapabilities, newSnap.RequiredCapabilities) {
+		diff = append(diff, store.ToolFieldChange{
+			Path: "required_capabilities",
+			Old:  oldSnap.RequiredCapabilities,
+			New:  newSnap.RequiredCapabilities,
+		})
+	}
+	if oldSnap.InputSchema != newSnap.InputSchema {
+		oldSchema, oldErr := parseSchema(oldSnap.InputSchema)
+		newSchema, newErr := parseSchema(newSnap.InputSchema)
+		if oldErr != nil || newErr != nil {
+			diff = append(diff, store.ToolFieldChange{Path: "input_schema", Old: oldSnap.InputSchema, New: newSnap.InputSchema})
+		} else {
+			diffJSON("input_schema", oldSchema, newSchema, &diff)
+		}
+	}
+	return diff
+}
+
+// parseSchema decodes a JSON schema string; an empty string is a nil schema.
+func parseSchema(raw string) (any, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var v any
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// diffJSON walks two decoded JSON values and appends a change for every path
+// whose value was added, removed, or replaced. Objects are compared key by key;
+// any other differing values (including arrays) are reported whole.
+func diffJSON(path string, oldVal, newVal any, out *[]store.ToolFieldChange) {
+	oldObj, oldIsObj := oldVal.(map[string]any)
+	newObj, newIsObj := newVal.(map[string]any)
+	if !oldIsObj || !newIsObj {
+		if !reflect.DeepEqual(oldVal, newVal) {
+			*out = append(*out, store.ToolFieldChange{Path: path, Old: oldVal, New: newVal})
+		}
+		return
+	}
+
+	keys := make([]string, 0, len(oldObj)+len(newObj))
+	for k := range oldObj {
+		keys = append(keys, k)
+	}
+	for k := range newObj {
+		if _, ok := oldObj[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		diffJSON(path+"."+k, oldObj[k], newObj[k], out)
+	}
+}
@@ -0,0 +1,189 @@
+// ABOUTME: Tests for tool catalog diffing and changelog recording.
+// ABOUTME: Covers added/removed/modified tools and idempotent re-registration.
+
+package packs
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func newTestCatalog(t *testing.T) (*CatalogTracker, *store.SQLStore) {
+	t.Helper()
+	s, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return NewCatalogTracker(s, slog.Default()), s
+}
+
+func catalogManifest(version string, tools ...*pb.ToolDefinition) *pb.PackManifest {
+	return &pb.PackManifest{PackId: "pack-1", Version: version, Tools: tools}
+}
+
+func TestCatalogTracker_RecordsAddedRemovedModified(t *testing.T) {
+	catalog, s := newTestCatalog(t)
+	ctx := context.Background()
+
+	v1, changes, err := catalog.Record(ctx, catalogManifest("1.0.0",
+		&pb.ToolDefinition{Name: "search", InputSchemaJson: `{"type":"object","properties":{"query":{"type":"string"}}}`},
+		&pb.ToolDefinition{Name: "fetch", Description: "Fetch a URL"},
+	))
+	if err != nil {
+		t.Fatalf("Record v1: %v", err)
+	}
+	if v1 != 1 || len(changes) != 2 {
+		t.Fatalf("first registration: version %d, %d changes; want 1, 2", v1, len(changes))
+	}
+	for _, c := range changes {
+		if c.ChangeType != store.ToolAdded {
+			t.Errorf("%s change = %s, want added", c.ToolName, c.ChangeType)
+		}
+	}
+
+	v2, changes, err := catalog.Record(ctx, catalogManifest("1.1.0",
+		&pb.ToolDefinition{Name: "search", InputSchemaJson: `{"type":"object","properties":{"query":{"type":"integer"},"limit":{"type":"integer"}}}`},
+		&pb.ToolDefinition{Name: "summarize"},
+	))
+	if err != nil {
+		t.Fatalf("Record v2: %v", err)
+	}
+	if v2 != 2 {
+		t.Errorf("version = %d, want 2", v2)
+	}
+
+	byName := make(map[string]*store.ToolChange)
+	for _, c := range changes {
+		byName[c.ToolName] = c
+	}
+	if len(byName) != 3 {
+		t.Fatalf("changes = %d, want 3 (fetch removed, search modified, summarize added)", len(byName))
+	}
+	if byName["fetch"].ChangeType != store.ToolRemoved {
+		t.Errorf("fetch change = %s, want removed", byName["fetch"].ChangeType)
+	}
+	if byName["summarize"].ChangeType != store.ToolAdded {
+		t.Errorf("summarize change = %s, want added", byName["summarize"].ChangeType)
+	}
+
+	search := byName["search"]
+	if search.ChangeType != store.ToolModified {
+		t.Fatalf("search change = %s, want modified", search.ChangeType)
+	}
+	paths := make(map[string]store.ToolFieldChange)
+	for _, d := range search.Diff {
+		paths[d.Path] = d
+	}
+	if d, ok := paths["input_schema.properties.query.type"]; !ok || d.Old != "string" || d.New != "integer" {
+		t.Errorf("query type diff = %+v, want string -> integer", d)
+	}
+	if d, ok := paths["input_schema.properties.limit"]; !ok || d.Old != nil || d.New == nil {
+		t.Errorf("limit diff = %+v, want added property", d)
+	}
+	if len(search.Diff) != 2 {
+		t.Errorf("search diff = %+v, want exactly 2 field changes", search.Diff)
+	}
+
+	stored, err := s.ListToolChanges(ctx, store.ToolChangeFilter{PackID: "pack-1"})
+	if err != nil {
+		t.Fatalf("ListToolChanges: %v", err)
+	}
+	if len(stored) != 5 {
+		t.Errorf("stored changes = %d, want 5", len(stored))
+	}
+	if stored[0].CatalogVersion != 2 || stored[0].PackVersion != "1.1.0" {
+		t.Errorf("newest change = %+v, want catalog version 2 from pack 1.1.0", stored[0])
+	}
+}
+
+func TestCatalogTracker_IdenticalReregistrationRecordsNothing(t *testing.T) {
+	catalog, s := newTestCatalog(t)
+	ctx := context.Background()
+
+	manifest := catalogManifest("1.0.0",
+		&pb.ToolDefinition{
+			Name:                 "search",
+			Description:          "Search things",
+			InputSchemaJson:      `{"type":"object"}`,
+			RequiredCapabilities: []string{"web"},
+			TimeoutSeconds:       10,
+		},
+	)
+	v1, _, err := catalog.Record(ctx, manifest)
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	v2, changes, err := catalog.Record(ctx, manifest)
+	if err != nil {
+		t.Fatalf("Record again: %v", err)
+	}
+	if len(changes) != 0 || v2 != v1 {
+		t.Errorf("re-registration: version %d -> %d with %d changes, want unchanged", v1, v2, len(changes))
+	}
+
+	stored, err := s.ListToolChanges(ctx, store.ToolChangeFilter{})
+	if err != nil {
+		t.Fatalf("ListToolChanges: %v", err)
+	}
+	if len(stored) != 1 {
+		t.Errorf("stored changes = %d, want only the initial add", len(stored))
+	}
+}
+
+func TestDiffToolSnapshots_ScalarFields(t *testing.T) {
+	oldSnap, _ := snapshotTool(&pb.ToolDefinition{Name: "t", Description: "a", TimeoutSeconds: 5})
+	newSnap, _ := snapshotTool(&pb.ToolDefinition{Name: "t", Description: "b", TimeoutSeconds: 5, RequiredCapabilities: []string{"admin"}})
+
+	changes := DiffToolSnapshots(map[string]string{"t": oldSnap}, map[string]string{"t": newSnap})
+	if len(changes) != 1 || changes[0].ChangeType != store.ToolModified {
+		t.Fatalf("changes = %+v, want one modification", changes)
+	}
+	var paths []string
+	for _, d := range changes[0].Diff {
+		paths = append(paths, d.Path)
+	}
+	if len(paths) != 2 || paths[0] != "description" || paths[1] != "required_capabilities" {
+		t.Errorf("diff paths = %v, want [description required_capabilities]", paths)
+	}
+}
+
+func TestPackServiceRegister_NotifiesToolsChanged(t *testing.T) {
+	service, _ := createTestService()
+	catalog, _ := newTestCatalog(t)
+	service.SetCatalog(catalog)
+
+	versions := make(chan int64, 2)
+	service.SetToolsChangedHandler(func(v int64) { versions <- v })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := newMockRegisterStream(ctx)
+	done := make(chan struct{})
+	go func() {
+		_ = service.Register(catalogManifest("1.0.0", &pb.ToolDefinition{Name: "tool-a"}), stream)
+		close(done)
+	}()
+
+	for i, want := range []int64{1, 1} {
+		select {
+		case v := <-versions:
+			if v != want {
+				t.Errorf("notification %d version = %d, want %d", i, v, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("notification %d not received", i)
+		}
+		if i == 0 {
+			cancel() // disconnect the pack
+		}
+	}
+	<-done
+}
@@ -16,13 +16,14 @@
 //
 // # Built-in Packs
 //
-// The gateway provides 5 built-in packs with 21 tools:
+// The gateway provides 6 built-in packs with 26 tools:
 //
 //	builtin:base   - Logging, todos, BBS (requires "base" capability)
 //	builtin:notes  - Key-value notes storage (requires "notes" capability)
 //	builtin:mail   - Inter-agent messaging (requires "mail" capability)
 //	builtin:admin  - Administrative tools (requires "admin" capability)
 //	builtin:ui     - User interaction (requires "ui" capability)
+//	builtin:delegate - Synchronous agent-to-agent requests (requires "delegate" capability)
 //
 // # External Packs
 //
@@ -31,10 +32,23 @@
 // through a bidirectional stream (ExecuteToolRequest/ExecuteToolResponse).
 //
 // External packs can be:
-//   - MCP servers (via HTTP bridge)
 //   - Custom gRPC services
 //   - Sidecar processes
 //
+// Registering under an ID already connected replaces the old registration:
+// its stream ends and its pending calls fail. A pack may also call Heartbeat;
+// once it has, it must keep heartbeating within the registry's miss window
+// (SetHeartbeatMissWindow). A pack past the window stays registered but is
+// unhealthy: the router fails its tool calls with ErrPackUnavailable and
+// UnhealthyTools reports its tools so listings can flag them.
+//
+// # Imported MCP Servers
+//
+// Upstream MCP servers configured under packs.mcp_servers are imported by
+// internal/mcpbridge. Their tools register as in-process packs (mcp:<name>)
+// whose handlers forward calls with tools/call; ReplaceBuiltinPack swaps in a
+// refreshed tool list and SetBuiltinPackDegraded marks unreachable upstreams.
+//
 // # Tool Routing
 //
 // When an agent calls a tool, the router:
@@ -44,6 +58,16 @@
 //  3. Routes the call to the pack
 //  4. Returns the result to the agent
 //
+// A call whose input sets "_dry_run": true (DryRunParam) stops before step 3:
+// CheckToolCall runs the caller, capability, input schema, quota and approval
+// checks and the agent gets a ToolCallVerdict instead of a result.
+//
+// A pack tool that declares cacheable with a cache TTL may also stop before
+// step 3: a successful result is cached under the tool name, the input in
+// canonical form and the caller's capabilities, and a repeat call within the
+// TTL is answered from the cache with ExecuteToolResponse.cached set. Input
+// setting "_no_cache": true (NoCacheParam) always reaches the pack.
+//
 // Tool names are globally unique. Built-in tools use simple names (e.g., "todo_add"),
 // while external tools may use qualified names (e.g., "mypack:search").
 //
@@ -51,6 +75,8 @@
 //
 // Tools require capabilities to use. Agents must have the required capability
 // in their principal record. This provides fine-grained access control.
+// A grant of "web.*" covers every capability under "web" (segment-aligned,
+// so not "webhook.fire"), and "*" covers all capabilities.
 //
 // # Usage
 //
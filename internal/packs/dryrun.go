@@ -0,0 +1,155 @@
+// ABOUTME: Dry-run tool calls: runs every pre-dispatch check and returns a verdict instead of executing.
+// ABOUTME: Triggered by the reserved "_dry_run": true input parameter; never invokes a tool or spends quota.
+
+package packs
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// DryRunParam is the reserved input parameter that turns a tool call into a
+// dry run. It is removed from the input before the input is checked.
+const DryRunParam = "_dry_run"
+
+// Dry-run verdicts.
+const (
+	VerdictAllowed          = "allowed"
+	VerdictRequiresApproval = "would_require_approval"
+	VerdictDenied           = "denied"
+)
+
+// Checks a dry run can be denied at, in the order they run.
+const (
+	CheckCaller     = "caller"
+	CheckDisabled   = "disabled"
+	CheckTool       = "tool"
+	CheckCapability = "capability"
+	CheckSchema     = "schema"
+	CheckQuota      = "quota"
+)
+
+// ToolCallVerdict is the result of a dry run.
+type ToolCallVerdict struct {
+	Verdict string `json:"verdict"`
+	Tool    string `json:"tool"`
+	// Check names the check that denied the call.
+	Check  string `json:"check,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	// Policy names the approval policy that would hold the call.
+	Policy string `json:"policy,omitempty"`
+}
+
+// JSON returns the verdict as the output of a tool call.
+func (v *ToolCallVerdict) JSON() string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// ParseDryRun reports whether inputJSON asks for a dry run, returning the
+// input with the reserved parameter removed.
+func ParseDryRun(inputJSON string) (string, bool) {
+	if !strings.Contains(inputJSON, DryRunParam) {
+		return inputJSON, false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(inputJSON), &fields); err != nil {
+		return inputJSON, false
+	}
+	var dryRun bool
+	if err := json.Unmarshal(fields[DryRunParam], &dryRun); err != nil || !dryRun {
+		return inputJSON, false
+	}
+	delete(fields, DryRunParam)
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return inputJSON, false
+	}
+	return string(stripped), true
+}
+
+// CheckToolCall runs the checks a call to toolName would pass through before
+// dispatch, for an agent holding caps, without calling the tool. Checks
+// stop at the first denial.
+func (r *Router) CheckToolCall(ctx context.Context, toolName, inputJSON, agentID string, caps []string) *ToolCallVerdict {
+	inputJSON, _ = ParseNoCache(inputJSON)
+	v := r.checkToolCall(ctx, toolName, inputJSON, agentID, caps)
+	r.logger.Info("tool call dry run",
+		"tool_name", toolName,
+		"agent_id", agentID,
+		"verdict", v.Verdict,
+		"check", v.Check,
+	)
+	if r.onCheck != nil {
+		r.onCheck(agentID, v)
+	}
+	return v
+}
+
+func (r *Router) checkToolCall(ctx context.Context, toolName, inputJSON, agentID string, caps []string) *ToolCallVerdict {
+	deny := func(check, reason string) *ToolCallVerdict {
+		return &ToolCallVerdict{Verdict: VerdictDenied, Tool: toolName, Check: check, Reason: reason}
+	}
+
+	if check := r.callerCheck(); check != nil {
+		if err := check(agentID); err != nil {
+			return deny(CheckCaller, err.Error())
+		}
+	}
+	if r.disabledTools != nil {
+		if reason, off := r.disabledTools(ctx, agentID)[toolName]; off {
+			return deny(CheckDisabled, (&ToolDisabledError{Tool: toolName, Reason: reason}).Error())
+		}
+	}
+
+	var def *pb.ToolDefinition
+	builtin := r.registry.GetBuiltinTool(toolName)
+	if builtin != nil {
+		def = builtin.Definition
+	} else if tool, pack := r.registry.GetToolByName(toolName); tool != nil && pack != nil {
+		def = tool.Definition
+	} else {
+		return deny(CheckTool, ErrToolNotFound.Error())
+	}
+
+	var warning string
+	if missing := missingCapabilities(def, caps); len(missing) > 0 {
+		if r.enforcementMode() != CapabilityWarn {
+			return deny(CheckCapability, "missing capability "+missing[0])
+		}
+		warning = "allowed in warn mode without capability " + strings.Join(missing, ", ")
+	}
+
+	if err := validateInput(def.GetInputSchemaJson(), inputJSON); err != nil {
+		return deny(CheckSchema, err.Error())
+	}
+
+	if builtin != nil && builtin.Precheck != nil {
+		if err := builtin.Precheck(ctx, agentID); err != nil {
+			return deny(CheckQuota, err.Error())
+		}
+	}
+
+	if r.approval != nil {
+		if policy := r.approval(agentID, toolName); policy != "" {
+			return &ToolCallVerdict{Verdict: VerdictRequiresApproval, Tool: toolName, Policy: policy}
+		}
+	}
+	return &ToolCallVerdict{Verdict: VerdictAllowed, Tool: toolName, Reason: warning}
+}
+
+// dryRunResponse answers a dry-run call routed like a real one.
+func (r *Router) dryRunResponse(ctx context.Context, toolName, inputJSON, requestID, agentID string) *pb.ExecuteToolResponse {
+	var caps []string
+	if r.capabilities != nil {
+		caps = r.capabilities(agentID)
+	}
+	v := r.CheckToolCall(ctx, toolName, inputJSON, agentID, caps)
+	return &pb.ExecuteToolResponse{
+		RequestId: requestID,
+		Result:    &pb.ExecuteToolResponse_OutputJson{OutputJson: v.JSON()},
+	}
+}
@@ -0,0 +1,221 @@
+// ABOUTME: Tests for dry-run tool calls: one case per verdict branch and check.
+// ABOUTME: Verifies tools are never invoked and results are never counted during a dry run.
+
+package packs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestParseDryRun(t *testing.T) {
+	tests := []struct {
+		input    string
+		want     string
+		wantDry  bool
+		scenario string
+	}{
+		{`{"message":"hi","_dry_run":true}`, `{"message":"hi"}`, true, "flag set"},
+		{`{"message":"hi","_dry_run":false}`, `{"message":"hi","_dry_run":false}`, false, "flag false"},
+		{`{"message":"hi"}`, `{"message":"hi"}`, false, "no flag"},
+		{`{"_dry_run":"yes"}`, `{"_dry_run":"yes"}`, false, "not a boolean"},
+		{`not json _dry_run`, `not json _dry_run`, false, "invalid JSON"},
+	}
+	for _, tt := range tests {
+		got, dry := ParseDryRun(tt.input)
+		if got != tt.want || dry != tt.wantDry {
+			t.Errorf("%s: ParseDryRun(%s) = %s, %v; want %s, %v", tt.scenario, tt.input, got, dry, tt.want, tt.wantDry)
+		}
+	}
+}
+
+func TestValidateInput(t *testing.T) {
+	schema := `{"type":"object","properties":{"id":{"type":"string"},"limit":{"type":"integer"},"status":{"type":"string","enum":["open","closed"]},"tags":{"type":"array","items":{"type":"string"}}},"required":["id"]}`
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{`{"id":"a"}`, false},
+		{`{"id":"a","limit":3,"status":"open","tags":["x","y"]}`, false},
+		{`{}`, true},
+		{`{"id":1}`, true},
+		{`{"id":"a","limit":2.5}`, true},
+		{`{"id":"a","status":"pending"}`, true},
+		{`{"id":"a","tags":["x",2]}`, true},
+		{`[]`, true},
+		{`{`, true},
+	}
+	for _, tt := range tests {
+		err := validateInput(schema, tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateInput(%s) = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+	}
+	if err := validateInput("", `{"anything":true}`); err != nil {
+		t.Errorf("empty schema should accept any input: %v", err)
+	}
+}
+
+func TestRouterDryRun(t *testing.T) {
+	schema := `{"type":"object","properties":{"message":{"type":"string"}},"required":["message"]}`
+	var invoked, results int
+	var recorded []*ToolCallVerdict
+
+	setup := func(t *testing.T, cfg RouterConfig, precheck func(context.Context, string) error) *Router {
+		t.Helper()
+		invoked, results, recorded = 0, 0, nil
+		registry := NewRegistry(slog.Default())
+		err := registry.RegisterBuiltinPack(&BuiltinPack{ID: "builtin:test", Tools: []*BuiltinTool{{
+			Definition: &pb.ToolDefinition{Name: "log_entry", InputSchemaJson: schema, RequiredCapabilities: []string{"base"}},
+			Handler: func(context.Context, string, json.RawMessage) (json.RawMessage, error) {
+				invoked++
+				return json.RawMessage(`{}`), nil
+			},
+			Precheck: precheck,
+		}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		registerTestPack(t, registry, "ext", &pb.ToolDefinition{Name: "deploy", InputSchemaJson: `{"type":"object"}`})
+		cfg.Registry = registry
+		cfg.Logger = slog.Default()
+		cfg.OnResult = func(string, string, bool) { results++ }
+		cfg.OnCheck = func(_ string, v *ToolCallVerdict) { recorded = append(recorded, v) }
+		if cfg.Capabilities == nil {
+			cfg.Capabilities = func(string) []string { return []string{"base"} }
+		}
+		return NewRouter(cfg)
+	}
+
+	dryRun := func(t *testing.T, r *Router, tool, input string) *ToolCallVerdict {
+		t.Helper()
+		resp, err := r.RouteToolCall(context.Background(), tool, input, "req-1", "agent-1")
+		if err != nil {
+			t.Fatalf("RouteToolCall: %v", err)
+		}
+		var v ToolCallVerdict
+		if err := json.Unmarshal([]byte(resp.GetOutputJson()), &v); err != nil {
+			t.Fatalf("verdict output %q: %v", resp.GetOutputJson(), err)
+		}
+		if invoked != 0 || results != 0 {
+			t.Errorf("dry run invoked the tool %d times and reported %d results", invoked, results)
+		}
+		if len(recorded) != 1 || recorded[0].Verdict != v.Verdict {
+			t.Errorf("OnCheck saw %v, want the returned verdict", recorded)
+		}
+		return &v
+	}
+
+	tests := []struct {
+		name      string
+		cfg       RouterConfig
+		precheck  func(context.Context, string) error
+		tool      string
+		input     string
+		wantVerd  string
+		wantCheck string
+	}{
+		{
+			name:     "allowed builtin",
+			tool:     "log_entry",
+			input:    `{"message":"hi","_dry_run":true}`,
+			wantVerd: VerdictAllowed,
+		},
+		{
+			name:     "allowed pack tool",
+			tool:     "deploy",
+			input:    `{"_dry_run":true}`,
+			wantVerd: VerdictAllowed,
+		},
+		{
+			name: "would require approval",
+			cfg: RouterConfig{ApprovalPolicy: func(_, tool string) string {
+				if tool == "deploy" {
+					return "deploys-need-a-human"
+				}
+				return ""
+			}},
+			tool:     "deploy",
+			input:    `{"_dry_run":true}`,
+			wantVerd: VerdictRequiresApproval,
+		},
+		{
+			name:      "caller rejected",
+			cfg:       RouterConfig{CallerCheck: func(string) error { return errors.New("agent is paused") }},
+			tool:      "log_entry",
+			input:     `{"message":"hi","_dry_run":true}`,
+			wantVerd:  VerdictDenied,
+			wantCheck: CheckCaller,
+		},
+		{
+			name:      "unknown tool",
+			tool:      "nope",
+			input:     `{"_dry_run":true}`,
+			wantVerd:  VerdictDenied,
+			wantCheck: CheckTool,
+		},
+		{
+			name:      "missing capability",
+			cfg:       RouterConfig{Capabilities: func(string) []string { return []string{"chat"} }},
+			tool:      "log_entry",
+			input:     `{"message":"hi","_dry_run":true}`,
+			wantVerd:  VerdictDenied,
+			wantCheck: CheckCapability,
+		},
+		{
+			name: "missing capability in warn mode",
+			cfg: RouterConfig{
+				Capabilities:          func(string) []string { return []string{"chat"} },
+				CapabilityEnforcement: CapabilityWarn,
+			},
+			tool:     "log_entry",
+			input:    `{"message":"hi","_dry_run":true}`,
+			wantVerd: VerdictAllowed,
+		},
+		{
+			name:      "invalid input",
+			tool:      "log_entry",
+			input:     `{"msg":"hi","_dry_run":true}`,
+			wantVerd:  VerdictDenied,
+			wantCheck: CheckSchema,
+		},
+		{
+			name:      "quota spent",
+			precheck:  func(context.Context, string) error { return errors.New("quota_exceeded: logs") },
+			tool:      "log_entry",
+			input:     `{"message":"hi","_dry_run":true}`,
+			wantVerd:  VerdictDenied,
+			wantCheck: CheckQuota,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := setup(t, tt.cfg, tt.precheck)
+			v := dryRun(t, r, tt.tool, tt.input)
+			if v.Verdict != tt.wantVerd || v.Check != tt.wantCheck || v.Tool != tt.tool {
+				t.Errorf("got %+v, want verdict %q check %q", v, tt.wantVerd, tt.wantCheck)
+			}
+			if tt.wantVerd == VerdictDenied && v.Reason == "" {
+				t.Error("denied verdict should carry a reason")
+			}
+			if tt.wantVerd == VerdictRequiresApproval && v.Policy != "deploys-need-a-human" {
+				t.Errorf("Policy = %q", v.Policy)
+			}
+		})
+	}
+
+	t.Run("without the flag the tool runs", func(t *testing.T) {
+		r := setup(t, RouterConfig{}, nil)
+		if _, err := r.RouteToolCall(context.Background(), "log_entry", `{"message":"hi"}`, "req-2", "agent-1"); err != nil {
+			t.Fatal(err)
+		}
+		if invoked != 1 || results != 1 || len(recorded) != 0 {
+			t.Errorf("invoked=%d results=%d checks=%d, want 1, 1, 0", invoked, results, len(recorded))
+		}
+	})
+}
@@ -0,0 +1,68 @@
+// ABOUTME: Per-agent tool overrides: tools an admin disabled for an agent are refused before capability checks.
+// ABOUTME: The same overrides filter the tool lists agents and MCP clients are shown.
+
+package packs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// ErrToolDisabled indicates an admin disabled the tool for the calling agent.
+var ErrToolDisabled = coverr.New(coverr.ToolDisabled, string(coverr.ToolDisabled))
+
+// ToolDisabledError names the disabled tool and the reason the admin gave.
+// It wraps ErrToolDisabled.
+type ToolDisabledError struct {
+	Tool   string
+	Reason string
+}
+
+func (e *ToolDisabledError) Error() string {
+	msg := fmt.Sprintf("%s: tool %q is disabled for this agent", ErrToolDisabled, e.Tool)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// Unwrap returns ErrToolDisabled, so the error matches it and carries its code.
+func (e *ToolDisabledError) Unwrap() error {
+	return ErrToolDisabled
+}
+
+// WithoutDisabled returns tools minus those named in disabled. tools is
+// returned as is when nothing is disabled.
+func WithoutDisabled(tools []*pb.ToolDefinition, disabled map[string]string) []*pb.ToolDefinition {
+	if len(disabled) == 0 {
+		return tools
+	}
+	kept := make([]*pb.ToolDefinition, 0, len(tools))
+	for _, t := range tools {
+		if _, off := disabled[t.GetName()]; !off {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// checkDisabled refuses a call to a tool an admin disabled for agentID.
+func (r *Router) checkDisabled(ctx context.Context, toolName, requestID, agentID string) error {
+	if r.disabledTools == nil {
+		return nil
+	}
+	reason, off := r.disabledTools(ctx, agentID)[toolName]
+	if !off {
+		return nil
+	}
+	r.logger.Warn("tool call denied: tool disabled for agent",
+		"tool_name", toolName,
+		"request_id", requestID,
+		"agent_id", agentID,
+		"reason", reason,
+	)
+	return &ToolDisabledError{Tool: toolName, Reason: reason}
+}
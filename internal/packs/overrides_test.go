@@ -0,0 +1,88 @@
+// ABOUTME: Tests for per-agent tool overrides on tool calls, dry runs and tool lists.
+// ABOUTME: A disabled tool is refused before capability checks, with the admin's reason.
+
+package packs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestRouterDisabledTools(t *testing.T) {
+	var invoked, results int
+	registry := NewRegistry(slog.Default())
+	err := registry.RegisterBuiltinPack(&BuiltinPack{ID: "builtin:test", Tools: []*BuiltinTool{
+		{
+			Definition: &pb.ToolDefinition{Name: "bbs_create_thread", RequiredCapabilities: []string{"bbs"}},
+			Handler: func(context.Context, string, json.RawMessage) (json.RawMessage, error) {
+				invoked++
+				return json.RawMessage(`{}`), nil
+			},
+		},
+		{
+			Definition: &pb.ToolDefinition{Name: "bbs_list_threads"},
+			Handler: func(context.Context, string, json.RawMessage) (json.RawMessage, error) {
+				invoked++
+				return json.RawMessage(`{}`), nil
+			},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := NewRouter(RouterConfig{
+		Registry: registry,
+		Logger:   slog.Default(),
+		OnResult: func(string, string, bool) { results++ },
+		// Without the capability the call would be capability_denied; the
+		// override is checked first.
+		Capabilities: func(string) []string { return nil },
+		DisabledTools: func(_ context.Context, agentID string) map[string]string {
+			if agentID == "noisy" {
+				return map[string]string{"bbs_create_thread": "floods the board"}
+			}
+			return nil
+		},
+	})
+
+	_, err = router.RouteToolCall(context.Background(), "bbs_create_thread", `{}`, "req-1", "noisy")
+	var disabled *ToolDisabledError
+	if !errors.Is(err, ErrToolDisabled) || !errors.As(err, &disabled) || disabled.Reason != "floods the board" {
+		t.Fatalf("err = %v, want tool_disabled with the reason", err)
+	}
+	if !strings.Contains(err.Error(), "floods the board") {
+		t.Errorf("error %q does not carry the reason", err)
+	}
+	if invoked != 0 || results != 0 {
+		t.Errorf("disabled call invoked=%d results=%d, want 0, 0", invoked, results)
+	}
+
+	if _, err := router.RouteToolCall(context.Background(), "bbs_list_threads", `{}`, "req-2", "noisy"); err != nil {
+		t.Errorf("other tool: %v", err)
+	}
+	if _, err := router.RouteToolCall(context.Background(), "bbs_create_thread", `{}`, "req-3", "quiet"); !errors.Is(err, ErrCapabilityDenied) {
+		t.Errorf("other agent: err = %v, want the usual capability check", err)
+	}
+
+	v := router.CheckToolCall(context.Background(), "bbs_create_thread", `{}`, "noisy", nil)
+	if v.Verdict != VerdictDenied || v.Check != CheckDisabled {
+		t.Errorf("dry run verdict = %+v, want denied at %s", v, CheckDisabled)
+	}
+}
+
+func TestWithoutDisabled(t *testing.T) {
+	tools := []*pb.ToolDefinition{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	got := WithoutDisabled(tools, map[string]string{"b": ""})
+	if len(got) != 2 || got[0].GetName() != "a" || got[1].GetName() != "c" {
+		t.Errorf("WithoutDisabled = %v, want a and c", got)
+	}
+	if got := WithoutDisabled(tools, nil); len(got) != 3 {
+		t.Errorf("WithoutDisabled with nothing disabled = %v", got)
+	}
+}
@@ -0,0 +1,248 @@
+// ABOUTME: Tool approval policy evaluation: ordered store-backed rules decide allow, deny or ask.
+// ABOUTME: Rules match on agent or group, a tool name glob, and optional input parameter matchers.
+
+package packs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// Parameter matcher operators.
+const (
+	MatchEquals     = "eq"
+	MatchNotEquals  = "ne"
+	MatchStartsWith = "startswith"
+	MatchEndsWith   = "endswith"
+	MatchContains   = "contains"
+	MatchGlob       = "glob"
+)
+
+// ToolPolicySource is what tool policy evaluation needs from storage.
+type ToolPolicySource interface {
+	ListToolPolicies(ctx context.Context) ([]*store.ToolPolicy, error)
+	GetToolPolicyDefault(ctx context.Context) (string, error)
+}
+
+// PolicyDecision is the outcome of the tool policy for one call.
+type PolicyDecision struct {
+	Action string `json:"action"`
+	Tool   string `json:"tool"`
+	// RuleID is the first rule that matched; empty when the default applied.
+	RuleID string `json:"rule_id,omitempty"`
+}
+
+// ValidToolPolicyAction reports whether action is allow, deny or ask.
+func ValidToolPolicyAction(action string) bool {
+	switch action {
+	case store.ToolPolicyAllow, store.ToolPolicyDeny, store.ToolPolicyAsk:
+		return true
+	}
+	return false
+}
+
+// ValidateToolPolicy checks a rule before it is stored: it may name an agent
+// or a group but not both, needs a well-formed tool pattern and a valid
+// action, and each matcher needs a parameter and a known operator.
+func ValidateToolPolicy(p *store.ToolPolicy) error {
+	if p.AgentID != "" && p.Group != "" {
+		return errors.New("a rule may name an agent or a group, not both")
+	}
+	if p.ToolPattern == "" {
+		return errors.New("tool pattern is required")
+	}
+	if _, err := path.Match(p.ToolPattern, ""); err != nil {
+		return fmt.Errorf("tool pattern %q: %w", p.ToolPattern, err)
+	}
+	if !ValidToolPolicyAction(p.Action) {
+		return fmt.Errorf("action must be %s, %s or %s", store.ToolPolicyAllow, store.ToolPolicyDeny, store.ToolPolicyAsk)
+	}
+	for _, m := range p.Matchers {
+		if m.Param == "" {
+			return errors.New("matcher parameter is required")
+		}
+		switch m.Op {
+		case MatchEquals, MatchNotEquals, MatchStartsWith, MatchEndsWith, MatchContains:
+		case MatchGlob:
+			if _, err := path.Match(m.Value, ""); err != nil {
+				return fmt.Errorf("matcher on %s: pattern %q: %w", m.Param, m.Value, err)
+			}
+		default:
+			return fmt.Errorf("matcher on %s: unknown operator %q", m.Param, m.Op)
+		}
+	}
+	return nil
+}
+
+// EvaluateToolPolicies returns the action of the first rule in policies that
+// matches a call by agentID to toolName with inputJSON, or defaultAction
+// when none does. groups returns the agent groups agentID belongs to; it is
+// only called if a rule names a group.
+func EvaluateToolPolicies(policies []*store.ToolPolicy, defaultAction, agentID string, groups func() []string, toolName, inputJSON string) PolicyDecision {
+	call := policyCall{agentID: agentID, groups: groups, tool: toolName, inputJSON: inputJSON}
+	for _, p := range policies {
+		if call.matches(p) {
+			return PolicyDecision{Action: p.Action, Tool: toolName, RuleID: p.ID}
+		}
+	}
+	return PolicyDecision{Action: defaultAction, Tool: toolName}
+}
+
+// EvaluateToolPolicy decides a tool approval request by agentID for
+// toolName. Without a policy configured, or when it cannot be loaded, the
+// call is asked about, as it would be without policies; the error is
+// returned alongside.
+func (r *Router) EvaluateToolPolicy(ctx context.Context, agentID, toolName, inputJSON string) (PolicyDecision, error) {
+	ask := PolicyDecision{Action: store.ToolPolicyAsk, Tool: toolName}
+	if r.policies == nil {
+		return ask, nil
+	}
+	policies, err := r.policies.ListToolPolicies(ctx)
+	if err != nil {
+		return ask, fmt.Errorf("loading tool policies: %w", err)
+	}
+	defaultAction, err := r.policies.GetToolPolicyDefault(ctx)
+	if err != nil {
+		return ask, fmt.Errorf("loading tool policy default: %w", err)
+	}
+	groups := func() []string {
+		if r.agentGroups == nil {
+			return nil
+		}
+		return r.agentGroups(ctx, agentID)
+	}
+	return EvaluateToolPolicies(policies, defaultAction, agentID, groups, toolName, inputJSON), nil
+}
+
+// policyCall is a call being matched against rules. The agent's groups and
+// the decoded input are looked up at most once, and only when a rule needs
+// them.
+type policyCall struct {
+	agentID   string
+	groups    func() []string
+	tool      string
+	inputJSON string
+
+	groupNames []string
+	gotGroups  bool
+	input      map[string]any
+	decoded    bool
+}
+
+func (c *policyCall) matches(p *store.ToolPolicy) bool {
+	if p.AgentID != "" && p.AgentID != c.agentID {
+		return false
+	}
+	if p.Group != "" && !c.inGroup(p.Group) {
+		return false
+	}
+	if ok, _ := path.Match(p.ToolPattern, c.tool); !ok {
+		return false
+	}
+	for _, m := range p.Matchers {
+		if !c.holds(m, p.Action) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *policyCall) inGroup(group string) bool {
+	if !c.gotGroups {
+		c.gotGroups = true
+		if c.groups != nil {
+			c.groupNames = c.groups()
+		}
+	}
+	for _, g := range c.groupNames {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// holds tests m, on a rule with action, against the call's input. A matcher
+// on a parameter the input lacks, or one that is not a string, number or
+// boolean, never holds. startswith and glob compare paths after cleaning
+// them; a relative path that climbs above its start could be anywhere, so
+// for it they hold on deny and ask rules but not on allow rules.
+func (c *policyCall) holds(m store.ToolPolicyMatcher, action string) bool {
+	value, ok := c.param(m.Param)
+	if !ok {
+		return false
+	}
+	if m.Op == MatchStartsWith || m.Op == MatchGlob {
+		if value, ok = cleanPath(value); !ok {
+			return action != store.ToolPolicyAllow
+		}
+	}
+	switch m.Op {
+	case MatchEquals:
+		return value == m.Value
+	case MatchNotEquals:
+		return value != m.Value
+	case MatchStartsWith:
+		return strings.HasPrefix(value, m.Value)
+	case MatchEndsWith:
+		return strings.HasSuffix(value, m.Value)
+	case MatchContains:
+		return strings.Contains(value, m.Value)
+	case MatchGlob:
+		matched, _ := path.Match(m.Value, value)
+		return matched
+	}
+	return false
+}
+
+// cleanPath resolves the "." and ".." elements of a value containing a
+// slash, so "/tmp/../etc/passwd" is compared as "/etc/passwd". A trailing
+// slash is kept. ok is false for a relative path that climbs above its start.
+func cleanPath(value string) (string, bool) {
+	if !strings.Contains(value, "/") {
+		return value, value != ".."
+	}
+	cleaned := path.Clean(value)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return cleaned, false
+	}
+	if strings.HasSuffix(value, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned, true
+}
+
+// param returns the input parameter at name, a dotted path into nested
+// objects such as "options.mode", as a string.
+func (c *policyCall) param(name string) (string, bool) {
+	if !c.decoded {
+		c.decoded = true
+		_ = json.Unmarshal([]byte(c.inputJSON), &c.input)
+	}
+	var value any = c.input
+	for key := range strings.SplitSeq(name, ".") {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		if value, ok = obj[key]; !ok {
+			return "", false
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
@@ -0,0 +1,227 @@
+// ABOUTME: Tests for tool approval policy evaluation.
+// ABOUTME: Covers rule order deciding conflicts, agent and group scoping, parameter matchers, path traversal, and validation.
+
+package packs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestEvaluateToolPolicies_ConflictingRules(t *testing.T) {
+	tmp := []store.ToolPolicyMatcher{{Param: "path", Op: MatchStartsWith, Value: "/tmp/"}}
+	policies := []*store.ToolPolicy{
+		{ID: "allow-tmp", ToolPattern: "fs_*", Action: store.ToolPolicyAllow, Matchers: tmp},
+		{ID: "deny-fs", ToolPattern: "fs_*", Action: store.ToolPolicyDeny},
+		{ID: "allow-all", ToolPattern: "*", Action: store.ToolPolicyAllow},
+	}
+
+	tests := []struct {
+		tool, input string
+		wantAction  string
+		wantRule    string
+	}{
+		{"fs_write", `{"path":"/tmp/out.txt"}`, store.ToolPolicyAllow, "allow-tmp"},
+		{"fs_write", `{"path":"/etc/passwd"}`, store.ToolPolicyDeny, "deny-fs"},
+		{"fs_read", `{}`, store.ToolPolicyDeny, "deny-fs"},
+		{"web_fetch", `{"url":"https://example.com"}`, store.ToolPolicyAllow, "allow-all"},
+	}
+	for _, tt := range tests {
+		got := EvaluateToolPolicies(policies, store.ToolPolicyAsk, "agent-a", nil, tt.tool, tt.input)
+		if got.Action != tt.wantAction || got.RuleID != tt.wantRule {
+			t.Errorf("%s %s = %s by %q, want %s by %q", tt.tool, tt.input, got.Action, got.RuleID, tt.wantAction, tt.wantRule)
+		}
+	}
+
+	// The same rules in the opposite order: the broad deny now shadows the
+	// narrower allow.
+	reversed := []*store.ToolPolicy{policies[1], policies[0], policies[2]}
+	if got := EvaluateToolPolicies(reversed, store.ToolPolicyAsk, "agent-a", nil, "fs_write", `{"path":"/tmp/out.txt"}`); got.RuleID != "deny-fs" {
+		t.Errorf("reversed order matched %q, want deny-fs", got.RuleID)
+	}
+
+	if got := EvaluateToolPolicies(policies[:2], store.ToolPolicyAsk, "agent-a", nil, "web_fetch", `{}`); got.Action != store.ToolPolicyAsk || got.RuleID != "" {
+		t.Errorf("unmatched call = %s by %q, want the default ask with no rule", got.Action, got.RuleID)
+	}
+}
+
+func TestEvaluateToolPolicies_Scope(t *testing.T) {
+	policies := []*store.ToolPolicy{
+		{ID: "agent-b-deny", AgentID: "agent-b", ToolPattern: "*", Action: store.ToolPolicyDeny},
+		{ID: "ops-allow", Group: "ops", ToolPattern: "shell", Action: store.ToolPolicyAllow},
+	}
+	lookups := 0
+	groups := map[string][]string{"agent-a": {"ops"}}
+	groupsOf := func(agentID string) func() []string {
+		return func() []string {
+			lookups++
+			return groups[agentID]
+		}
+	}
+
+	if got := EvaluateToolPolicies(policies, store.ToolPolicyAsk, "agent-b", groupsOf("agent-b"), "shell", `{}`); got.RuleID != "agent-b-deny" {
+		t.Errorf("agent-b matched %q, want agent-b-deny", got.RuleID)
+	}
+	if lookups != 0 {
+		t.Errorf("groups looked up %d times before a group rule was reached", lookups)
+	}
+	if got := EvaluateToolPolicies(policies, store.ToolPolicyAsk, "agent-a", groupsOf("agent-a"), "shell", `{}`); got.RuleID != "ops-allow" {
+		t.Errorf("agent-a in ops matched %q, want ops-allow", got.RuleID)
+	}
+	if got := EvaluateToolPolicies(policies, store.ToolPolicyAsk, "agent-c", groupsOf("agent-c"), "shell", `{}`); got.RuleID != "" {
+		t.Errorf("agent-c outside ops matched %q, want no rule", got.RuleID)
+	}
+}
+
+func TestEvaluateToolPolicies_ParameterMatchers(t *testing.T) {
+	input := `{"path":"/tmp/a/b.txt","mode":"0644","recursive":true,"depth":3,"options":{"encoding":"utf-8"},"tags":["x"]}`
+	tests := []struct {
+		matcher store.ToolPolicyMatcher
+		want    bool
+	}{
+		{store.ToolPolicyMatcher{Param: "path", Op: MatchEquals, Value: "/tmp/a/b.txt"}, true},
+		{store.ToolPolicyMatcher{Param: "path", Op: MatchEquals, Value: "/tmp"}, false},
+		{store.ToolPolicyMatcher{Param: "path", Op: MatchNotEquals, Value: "/tmp"}, true},
+		{store.ToolPolicyMatcher{Param: "path", Op: MatchStartsWith, Value: "/tmp/"}, true},
+		{store.ToolPolicyMatcher{Param: "path", Op: MatchStartsWith, Value: "/etc/"}, false},
+		{store.ToolPolicyMatcher{Param: "path", Op: MatchEndsWith, Value: ".txt"}, true},
+		{store.ToolPolicyMatcher{Param: "path", Op: MatchContains, Value: "/a/"}, true},
+		{store.ToolPolicyMatcher{Param: "path", Op: MatchGlob, Value: "/tmp/*/*.txt"}, true},
+		{store.ToolPolicyMatcher{Param: "path", Op: MatchGlob, Value: "/tmp/*.txt"}, false},
+		{store.ToolPolicyMatcher{Param: "recursive", Op: MatchEquals, Value: "true"}, true},
+		{store.ToolPolicyMatcher{Param: "depth", Op: MatchEquals, Value: "3"}, true},
+		{store.ToolPolicyMatcher{Param: "options.encoding", Op: MatchEquals, Value: "utf-8"}, true},
+		{store.ToolPolicyMatcher{Param: "options.missing", Op: MatchNotEquals, Value: "x"}, false},
+		{store.ToolPolicyMatcher{Param: "missing", Op: MatchNotEquals, Value: "x"}, false},
+		{store.ToolPolicyMatcher{Param: "tags", Op: MatchContains, Value: "x"}, false},
+		{store.ToolPolicyMatcher{Param: "options", Op: MatchNotEquals, Value: ""}, false},
+	}
+	for _, tt := range tests {
+		policies := []*store.ToolPolicy{{ID: "rule", ToolPattern: "*", Action: store.ToolPolicyAllow, Matchers: []store.ToolPolicyMatcher{tt.matcher}}}
+		got := EvaluateToolPolicies(policies, store.ToolPolicyAsk, "agent-a", nil, "fs_write", input).RuleID == "rule"
+		if got != tt.want {
+			t.Errorf("%s %s %q matched = %v, want %v", tt.matcher.Param, tt.matcher.Op, tt.matcher.Value, got, tt.want)
+		}
+	}
+
+	// Every matcher on a rule must hold.
+	both := []*store.ToolPolicy{{ID: "rule", ToolPattern: "*", Action: store.ToolPolicyAllow, Matchers: []store.ToolPolicyMatcher{
+		{Param: "path", Op: MatchStartsWith, Value: "/tmp/"},
+		{Param: "mode", Op: MatchEquals, Value: "0600"},
+	}}}
+	if got := EvaluateToolPolicies(both, store.ToolPolicyAsk, "agent-a", nil, "fs_write", input); got.RuleID != "" {
+		t.Errorf("rule matched with one of two matchers failing")
+	}
+	if got := EvaluateToolPolicies(both, store.ToolPolicyAsk, "agent-a", nil, "fs_write", `not json`); got.RuleID != "" {
+		t.Errorf("rule with matchers matched input that is not JSON")
+	}
+}
+
+func TestEvaluateToolPolicies_PathTraversal(t *testing.T) {
+	policies := []*store.ToolPolicy{
+		{ID: "deny-etc", ToolPattern: "fs_*", Action: store.ToolPolicyDeny, Matchers: []store.ToolPolicyMatcher{{Param: "path", Op: MatchGlob, Value: "/etc/*"}}},
+		{ID: "allow-tmp", ToolPattern: "fs_*", Action: store.ToolPolicyAllow, Matchers: []store.ToolPolicyMatcher{{Param: "path", Op: MatchStartsWith, Value: "/tmp"}}},
+	}
+	tests := []struct {
+		path, want string
+	}{
+		{"/tmp/a.txt", "allow-tmp"},
+		{"/tmp/./a/../b.txt", "allow-tmp"},
+		{"/tmp/../etc/passwd", "deny-etc"},
+		{"/tmp/../../home/me/.ssh/id_rsa", ""},
+		{"//etc//passwd", "deny-etc"},
+		{"../etc/passwd", "deny-etc"},
+		{"a/../../tmp/x", "deny-etc"},
+	}
+	for _, tt := range tests {
+		input := fmt.Sprintf(`{"path":%q}`, tt.path)
+		if got := EvaluateToolPolicies(policies, store.ToolPolicyAsk, "agent-a", nil, "fs_read", input).RuleID; got != tt.want {
+			t.Errorf("path %q matched %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	// An allow rule never matches a relative path escaping its start.
+	allowOnly := policies[1:]
+	if got := EvaluateToolPolicies(allowOnly, store.ToolPolicyAsk, "agent-a", nil, "fs_read", `{"path":"../tmp/x"}`); got.RuleID != "" {
+		t.Errorf("allow rule matched an escaping relative path")
+	}
+}
+
+func TestValidateToolPolicy(t *testing.T) {
+	tests := []struct {
+		policy  store.ToolPolicy
+		wantErr bool
+	}{
+		{store.ToolPolicy{ToolPattern: "fs_*", Action: store.ToolPolicyAllow}, false},
+		{store.ToolPolicy{AgentID: "a", ToolPattern: "*", Action: store.ToolPolicyDeny, Matchers: []store.ToolPolicyMatcher{{Param: "path", Op: MatchGlob, Value: "/tmp/*"}}}, false},
+		{store.ToolPolicy{AgentID: "a", Group: "ops", ToolPattern: "*", Action: store.ToolPolicyAsk}, true},
+		{store.ToolPolicy{Action: store.ToolPolicyAsk}, true},
+		{store.ToolPolicy{ToolPattern: "[", Action: store.ToolPolicyAsk}, true},
+		{store.ToolPolicy{ToolPattern: "*", Action: "maybe"}, true},
+		{store.ToolPolicy{ToolPattern: "*", Action: store.ToolPolicyAsk, Matchers: []store.ToolPolicyMatcher{{Op: MatchEquals}}}, true},
+		{store.ToolPolicy{ToolPattern: "*", Action: store.ToolPolicyAsk, Matchers: []store.ToolPolicyMatcher{{Param: "path", Op: "regex"}}}, true},
+		{store.ToolPolicy{ToolPattern: "*", Action: store.ToolPolicyAsk, Matchers: []store.ToolPolicyMatcher{{Param: "path", Op: MatchGlob, Value: "["}}}, true},
+	}
+	for i, tt := range tests {
+		if err := ValidateToolPolicy(&tt.policy); (err != nil) != tt.wantErr {
+			t.Errorf("case %d: ValidateToolPolicy err = %v, wantErr %v", i, err, tt.wantErr)
+		}
+	}
+}
+
+type fakePolicySource struct {
+	policies      []*store.ToolPolicy
+	defaultAction string
+	err           error
+}
+
+func (f *fakePolicySource) ListToolPolicies(context.Context) ([]*store.ToolPolicy, error) {
+	return f.policies, f.err
+}
+
+func (f *fakePolicySource) GetToolPolicyDefault(context.Context) (string, error) {
+	return f.defaultAction, nil
+}
+
+func TestRouterEvaluateToolPolicy(t *testing.T) {
+	ctx := context.Background()
+	newRouter := func(src ToolPolicySource) *Router {
+		return NewRouter(RouterConfig{
+			Registry:     NewRegistry(slog.Default()),
+			Logger:       slog.Default(),
+			ToolPolicies: src,
+			AgentGroups: func(_ context.Context, agentID string) []string {
+				if agentID == "agent-a" {
+					return []string{"ops"}
+				}
+				return nil
+			},
+		})
+	}
+
+	if got, err := newRouter(nil).EvaluateToolPolicy(ctx, "agent-a", "shell", `{}`); err != nil || got.Action != store.ToolPolicyAsk {
+		t.Errorf("without policies = %+v, %v; want ask", got, err)
+	}
+
+	src := &fakePolicySource{
+		policies:      []*store.ToolPolicy{{ID: "ops-deny", Group: "ops", ToolPattern: "shell", Action: store.ToolPolicyDeny}},
+		defaultAction: store.ToolPolicyAllow,
+	}
+	r := newRouter(src)
+	if got, err := r.EvaluateToolPolicy(ctx, "agent-a", "shell", `{}`); err != nil || got.Action != store.ToolPolicyDeny || got.RuleID != "ops-deny" {
+		t.Errorf("agent-a = %+v, %v; want deny by ops-deny", got, err)
+	}
+	if got, err := r.EvaluateToolPolicy(ctx, "agent-b", "shell", `{}`); err != nil || got.Action != store.ToolPolicyAllow || got.RuleID != "" {
+		t.Errorf("agent-b = %+v, %v; want the default allow", got, err)
+	}
+
+	src.err = errors.New("database is locked")
+	if got, err := r.EvaluateToolPolicy(ctx, "agent-a", "shell", `{}`); err == nil || got.Action != store.ToolPolicyAsk {
+		t.Errorf("failed load = %+v, %v; want ask with the error", got, err)
+	}
+}
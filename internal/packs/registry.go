@@ -10,13 +10,11 @@ import (
 	"log/slog"
 	"sort"
 	"sync"
+	"time"
 
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
-// ErrPackAlreadyRegistered indicates a pack with the same ID is already connected.
-var ErrPackAlreadyRegistered = errors.New("pack already registered")
-
 // ErrPackNotFound indicates the specified pack was not found.
 var ErrPackNotFound = errors.New("pack not found")
 
@@ -26,6 +24,10 @@ var ErrToolCollision = errors.New("tool name collision")
 // ErrPackClosed indicates the pack's channel has been closed.
 var ErrPackClosed = errors.New("pack channel closed")
 
+// DefaultHeartbeatMissWindow is how long a pack that heartbeats may go
+// without one before it is marked unhealthy.
+const DefaultHeartbeatMissWindow = 30 * time.Second
+
 // Tool wraps a tool definition with its owning pack ID.
 type Tool struct {
 	Definition *pb.ToolDefinition
@@ -39,8 +41,11 @@ type Pack struct {
 	Tools   map[string]*Tool            // by tool name
 	Channel chan *pb.ExecuteToolRequest // for sending tool calls to pack
 
-	closeMu sync.Mutex // protects closed and Channel close
-	closed  bool       // true after Channel is closed
+	closeMu sync.Mutex    // protects closed and Channel close
+	closed  bool          // true after Channel is closed
+	done    chan struct{} // closed with Channel, to wake calls awaiting a response
+
+	lastHeartbeat time.Time // zero until the pack first heartbeats; guarded by Registry.mu
 }
 
 // Send sends a request to the pack's channel if not closed.
@@ -70,16 +75,31 @@ func (p *Pack) Close() {
 	if !p.closed {
 		p.closed = true
 		close(p.Channel)
+		if p.done != nil {
+			close(p.done)
+		}
 	}
 }
 
+// Done returns a channel that is closed once the pack is closed, whether it
+// disconnected or was replaced by a newer registration.
+func (p *Pack) Done() <-chan struct{} {
+	return p.done
+}
+
 // Registry maintains the registry of connected packs and their tools.
 type Registry struct {
 	mu       sync.RWMutex
 	packs    map[string]*Pack
 	tools    map[string]*Tool         // global tool name -> tool (for collision detection)
 	builtins map[string]*builtinEntry // builtin tool name -> builtin entry
+	origins  map[string]string        // builtin pack ID -> origin, for packs that set one
+	degraded map[string]string        // builtin pack ID -> reason it is degraded
+	gen      uint64                   // bumped whenever the tool set changes
 	logger   *slog.Logger
+
+	missWindow time.Duration // see SetHeartbeatMissWindow
+	now        func() time.Time
 }
 
 // NewRegistry creates a new Registry instance.
@@ -88,40 +108,68 @@ func NewRegistry(logger *slog.Logger) *Registry {
 		packs:    make(map[string]*Pack),
 		tools:    make(map[string]*Tool),
 		builtins: make(map[string]*builtinEntry),
+		origins:  make(map[string]string),
+		degraded: make(map[string]string),
 		logger:   logger,
+
+		missWindow: DefaultHeartbeatMissWindow,
+		now:        time.Now,
 	}
 }
 
+// SetHeartbeatMissWindow sets how long a pack that heartbeats may go without
+// one before it is unhealthy (default DefaultHeartbeatMissWindow). Zero or
+// less restores the default.
+func (r *Registry) SetHeartbeatMissWindow(d time.Duration) {
+	if d <= 0 {
+		d = DefaultHeartbeatMissWindow
+	}
+	r.mu.Lock()
+	r.missWindow = d
+	r.mu.Unlock()
+}
+
 // RegisterPack validates and stores a pack and its tools.
-// Returns ErrPackAlreadyRegistered if a pack with the same ID exists.
-// Returns ErrToolCollision if any tool name already exists from another pack.
+// A pack already registered under the same ID is replaced: its tools are
+// swapped for the new manifest's and it is closed, failing its pending calls.
+// Returns ErrToolCollision if any tool name already exists from another pack,
+// in which case nothing changes.
 func (r *Registry) RegisterPack(packID string, manifest *pb.PackManifest) error {
+	_, err := r.registerPack(packID, manifest)
+	return err
+}
+
+// registerPack is RegisterPack returning the pack it stored, so a caller can
+// later remove that registration and not a newer one.
+func (r *Registry) registerPack(packID string, manifest *pb.PackManifest) (*Pack, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Check if pack already registered
-	if _, exists := r.packs[packID]; exists {
-		return ErrPackAlreadyRegistered
-	}
-
-	// Check for tool name collisions before registering
+	// Check for tool name collisions before registering; the pack being
+	// replaced may keep its own names
 	for _, toolDef := range manifest.GetTools() {
-		if existingTool, exists := r.tools[toolDef.GetName()]; exists {
-			return fmt.Errorf("%w: tool '%s' already registered by pack '%s'",
+		if existingTool, exists := r.tools[toolDef.GetName()]; exists && existingTool.PackID != packID {
+			return nil, fmt.Errorf("%w: tool '%s' already registered by pack '%s'",
 				ErrToolCollision, toolDef.GetName(), existingTool.PackID)
 		}
 		if _, exists := r.builtins[toolDef.GetName()]; exists {
-			return fmt.Errorf("%w: tool '%s' already registered as builtin",
+			return nil, fmt.Errorf("%w: tool '%s' already registered as builtin",
 				ErrToolCollision, toolDef.GetName())
 		}
 	}
 
+	old, replacing := r.packs[packID]
+	if replacing {
+		r.removePackLocked(old)
+	}
+
 	// Create the pack
 	pack := &Pack{
 		ID:      packID,
 		Version: manifest.GetVersion(),
 		Tools:   make(map[string]*Tool),
 		Channel: make(chan *pb.ExecuteToolRequest, 16),
+		done:    make(chan struct{}),
 	}
 
 	// Register all tools
@@ -135,16 +183,18 @@ func (r *Registry) RegisterPack(packID string, manifest *pb.PackManifest) error
 	}
 
 	r.packs[packID] = pack
+	r.gen++
 
 	r.logger.Info("=== PACK REGISTERED ===",
 		"pack_id", packID,
 		"version", manifest.GetVersion(),
 		"tool_count", len(manifest.GetTools()),
+		"replaced", replacing,
 		"total_packs", len(r.packs),
 		"total_tools", len(r.tools),
 	)
 
-	return nil
+	return pack, nil
 }
 
 // UnregisterPack removes a pack and all its tools from the registry.
@@ -156,24 +206,104 @@ func (r *Registry) UnregisterPack(packID string) {
 	if !exists {
 		return
 	}
+	r.removePackLocked(pack)
+	r.gen++
 
-	// Remove all tools belonging to this pack
-	for toolName := range pack.Tools {
-		delete(r.tools, toolName)
-	}
+	r.logger.Info("=== PACK UNREGISTERED ===",
+		"pack_id", packID,
+		"total_packs", len(r.packs),
+		"total_tools", len(r.tools),
+	)
+}
 
-	// Close the pack's channel safely
-	pack.Close()
+// removePack unregisters pack if it is still the registration for its ID.
+// A pack whose ID was since registered again is left alone.
+func (r *Registry) removePack(pack *Pack) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	delete(r.packs, packID)
+	if r.packs[pack.ID] != pack {
+		return
+	}
+	r.removePackLocked(pack)
+	r.gen++
 
 	r.logger.Info("=== PACK UNREGISTERED ===",
-		"pack_id", packID,
+		"pack_id", pack.ID,
 		"total_packs", len(r.packs),
 		"total_tools", len(r.tools),
 	)
 }
 
+// removePackLocked drops pack and its tools and closes it. r.mu must be held.
+func (r *Registry) removePackLocked(pack *Pack) {
+	for toolName := range pack.Tools {
+		if tool, ok := r.tools[toolName]; ok && tool.PackID == pack.ID {
+			delete(r.tools, toolName)
+		}
+	}
+	pack.Close()
+	delete(r.packs, pack.ID)
+}
+
+// Heartbeat records that the pack is alive. Once a pack has heartbeated it
+// must keep doing so: a pack silent for longer than the miss window is
+// unhealthy until its next heartbeat. Returns ErrPackNotFound if no pack is
+// registered under packID.
+func (r *Registry) Heartbeat(packID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pack, exists := r.packs[packID]
+	if !exists {
+		return ErrPackNotFound
+	}
+	now := r.now()
+	if !r.healthyLocked(pack, now) {
+		r.logger.Info("pack healthy again",
+			"pack_id", packID,
+			"silent_for", now.Sub(pack.lastHeartbeat).Round(time.Second),
+		)
+	}
+	pack.lastHeartbeat = now
+	return nil
+}
+
+// PackHealthy reports whether pack is healthy: it has not heartbeated yet,
+// and so is judged by its stream alone, or its last heartbeat is within the
+// miss window.
+func (r *Registry) PackHealthy(pack *Pack) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.healthyLocked(pack, r.now())
+}
+
+// healthyLocked is PackHealthy at now. r.mu must be held.
+func (r *Registry) healthyLocked(pack *Pack, now time.Time) bool {
+	return pack.lastHeartbeat.IsZero() || now.Sub(pack.lastHeartbeat) <= r.missWindow
+}
+
+// UnhealthyTools returns the names of the tools whose packs are unhealthy.
+// The tools stay registered; callers listing tools flag them as temporarily
+// unavailable.
+func (r *Registry) UnhealthyTools() map[string]bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := r.now()
+	unhealthy := make(map[string]bool)
+	for _, pack := range r.packs {
+		if r.healthyLocked(pack, now) {
+			continue
+		}
+		for name := range pack.Tools {
+			unhealthy[name] = true
+		}
+	}
+	return unhealthy
+}
+
 // GetPack retrieves a pack by its ID.
 func (r *Registry) GetPack(packID string) *Pack {
 	r.mu.RLock()
@@ -202,31 +332,103 @@ func (r *Registry) RegisterBuiltinPack(pack *BuiltinPack) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Check for collisions
+	if err := r.checkBuiltinCollisions(pack, false); err != nil {
+		return err
+	}
+	r.addBuiltinPack(pack)
+	r.gen++
+
+	r.logger.Info("=== BUILTIN PACK REGISTERED ===",
+		"pack_id", pack.ID,
+		"tool_count", len(pack.Tools),
+	)
+
+	return nil
+}
+
+// ReplaceBuiltinPack swaps in a new tool set for a builtin pack, registering
+// it if absent. Tools the old set had and the new one lacks are removed. On a
+// collision with another pack nothing changes.
+func (r *Registry) ReplaceBuiltinPack(pack *BuiltinPack) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkBuiltinCollisions(pack, true); err != nil {
+		return err
+	}
+	r.removeBuiltinTools(pack.ID)
+	r.addBuiltinPack(pack)
+	r.gen++
+
+	r.logger.Info("builtin pack replaced",
+		"pack_id", pack.ID,
+		"tool_count", len(pack.Tools),
+	)
+
+	return nil
+}
+
+// UnregisterBuiltinPack removes a builtin pack and all its tools.
+func (r *Registry) UnregisterBuiltinPack(packID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.removeBuiltinTools(packID)
+	delete(r.origins, packID)
+	delete(r.degraded, packID)
+	r.gen++
+}
+
+// SetBuiltinPackDegraded records why a builtin pack is currently unhealthy,
+// or clears the mark when reason is empty. It is informational: the pack's
+// tools stay registered.
+func (r *Registry) SetBuiltinPackDegraded(packID, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if reason == "" {
+		delete(r.degraded, packID)
+		return
+	}
+	r.degraded[packID] = reason
+}
+
+// checkBuiltinCollisions reports tool names in pack already taken elsewhere.
+// With replacing set, names owned by the same pack ID are not collisions.
+// r.mu must be held.
+func (r *Registry) checkBuiltinCollisions(pack *BuiltinPack, replacing bool) error {
 	for _, tool := range pack.Tools {
 		name := tool.Definition.GetName()
 		if _, exists := r.tools[name]; exists {
 			return fmt.Errorf("%w: tool '%s' already registered by external pack", ErrToolCollision, name)
 		}
-		if _, exists := r.builtins[name]; exists {
+		if entry, exists := r.builtins[name]; exists && (!replacing || entry.PackID != pack.ID) {
 			return fmt.Errorf("%w: tool '%s' already registered as builtin", ErrToolCollision, name)
 		}
 	}
+	return nil
+}
 
-	// Register all tools
+// addBuiltinPack stores the pack's tools. r.mu must be held.
+func (r *Registry) addBuiltinPack(pack *BuiltinPack) {
 	for _, tool := range pack.Tools {
 		r.builtins[tool.Definition.GetName()] = &builtinEntry{
 			Tool:   tool,
 			PackID: pack.ID,
 		}
 	}
+	if pack.Origin != "" {
+		r.origins[pack.ID] = pack.Origin
+	}
+}
 
-	r.logger.Info("=== BUILTIN PACK REGISTERED ===",
-		"pack_id", pack.ID,
-		"tool_count", len(pack.Tools),
-	)
-
-	return nil
+// removeBuiltinTools drops every builtin tool owned by packID. r.mu must be held.
+func (r *Registry) removeBuiltinTools(packID string) {
+	for name, entry := range r.builtins {
+		if entry.PackID == packID {
+			delete(r.builtins, name)
+		}
+	}
 }
 
 // GetBuiltinTool returns a builtin tool by name, or nil if not found.
@@ -250,8 +452,10 @@ func (r *Registry) IsBuiltin(name string) bool {
 
 // BuiltinPackInfo contains information about a registered builtin pack for display.
 type BuiltinPackInfo struct {
-	ID    string
-	Tools []*BuiltinTool
+	ID       string
+	Tools    []*BuiltinTool
+	Origin   string // where the tools run, empty for in-process builtins
+	Degraded string // why the pack is unhealthy, empty when healthy
 }
 
 // ListBuiltinPacks returns information about all registered builtin packs.
@@ -274,10 +478,18 @@ func (r *Registry) ListBuiltinPacks() []BuiltinPackInfo {
 			return tools[i].Definition.GetName() < tools[j].Definition.GetName()
 		})
 		result = append(result, BuiltinPackInfo{
-			ID:    packID,
-			Tools: tools,
+			ID:       packID,
+			Tools:    tools,
+			Origin:   r.origins[packID],
+			Degraded: r.degraded[packID],
 		})
 	}
+	// Degraded packs with no tools yet still show up, so their error is visible
+	for packID, reason := range r.degraded {
+		if _, listed := packTools[packID]; !listed {
+			result = append(result, BuiltinPackInfo{ID: packID, Origin: r.origins[packID], Degraded: reason})
+		}
+	}
 	// Sort packs by ID for stable ordering
 	sort.Slice(result, func(i, j int) bool {
 		return result[i].ID < result[j].ID
@@ -285,6 +497,16 @@ func (r *Registry) ListBuiltinPacks() []BuiltinPackInfo {
 	return result
 }
 
+// Generation returns a counter that changes whenever packs or builtin packs
+// are registered, replaced or removed. Callers that read it before listing
+// tools can tell later whether that list may be out of date.
+func (r *Registry) Generation() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.gen
+}
+
 // GetAllTools returns all registered tools.
 func (r *Registry) GetAllTools() []*Tool {
 	r.mu.RLock()
@@ -299,29 +521,24 @@ func (r *Registry) GetAllTools() []*Tool {
 
 // GetToolsForCapabilities returns tools where the agent has ALL required capabilities.
 // If a tool has no required capabilities, it is always included.
-// Includes both external pack tools and builtin tools.
+// Includes both external pack tools and builtin tools. Held capabilities may
+// be patterns, see MatchCapability.
 func (r *Registry) GetToolsForCapabilities(caps []string) []*pb.ToolDefinition {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	// Build a set of agent capabilities for fast lookup
-	capSet := make(map[string]struct{}, len(caps))
-	for _, cap := range caps {
-		capSet[cap] = struct{}{}
-	}
-
 	var result []*pb.ToolDefinition
 
 	// External pack tools
 	for _, tool := range r.tools {
-		if r.hasAllCapabilities(tool.Definition.GetRequiredCapabilities(), capSet) {
+		if hasAllCapabilities(tool.Definition.GetRequiredCapabilities(), caps) {
 			result = append(result, tool.Definition)
 		}
 	}
 
 	// Builtin tools
 	for _, entry := range r.builtins {
-		if r.hasAllCapabilities(entry.Tool.Definition.GetRequiredCapabilities(), capSet) {
+		if hasAllCapabilities(entry.Tool.Definition.GetRequiredCapabilities(), caps) {
 			result = append(result, entry.Tool.Definition)
 		}
 	}
@@ -329,10 +546,10 @@ func (r *Registry) GetToolsForCapabilities(caps []string) []*pb.ToolDefinition {
 	return result
 }
 
-// hasAllCapabilities checks if the capability set contains all required capabilities.
-func (r *Registry) hasAllCapabilities(required []string, capSet map[string]struct{}) bool {
+// hasAllCapabilities checks if the held capabilities cover all required capabilities.
+func hasAllCapabilities(required []string, held []string) bool {
 	for _, req := range required {
-		if _, has := capSet[req]; !has {
+		if !HasCapability(held, req) {
 			return false
 		}
 	}
@@ -344,6 +561,7 @@ func (r *Registry) ListPacks() []*PackInfo {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	now := r.now()
 	packs := make([]*PackInfo, 0, len(r.packs))
 	for _, pack := range r.packs {
 		toolNames := make([]string, 0, len(pack.Tools))
@@ -351,9 +569,11 @@ func (r *Registry) ListPacks() []*PackInfo {
 			toolNames = append(toolNames, name)
 		}
 		packs = append(packs, &PackInfo{
-			ID:        pack.ID,
-			Version:   pack.Version,
-			ToolNames: toolNames,
+			ID:            pack.ID,
+			Version:       pack.Version,
+			ToolNames:     toolNames,
+			Healthy:       r.healthyLocked(pack, now),
+			LastHeartbeat: pack.lastHeartbeat,
 		})
 	}
 	return packs
@@ -361,9 +581,11 @@ func (r *Registry) ListPacks() []*PackInfo {
 
 // PackInfo contains public information about a registered pack.
 type PackInfo struct {
-	ID        string
-	Version   string
-	ToolNames []string
+	ID            string
+	Version       string
+	ToolNames     []string
+	Healthy       bool
+	LastHeartbeat time.Time // zero if the pack has never heartbeated
 }
 
 // Close closes all registered packs and clears the registry.
@@ -385,6 +607,9 @@ func (r *Registry) Close() {
 	r.packs = make(map[string]*Pack)
 	r.tools = make(map[string]*Tool)
 	r.builtins = make(map[string]*builtinEntry)
+	r.origins = make(map[string]string)
+	r.degraded = make(map[string]string)
+	r.gen++
 
 	r.logger.Info("registry closed", "packs_closed", packCount, "builtins_cleared", builtinCount)
 }
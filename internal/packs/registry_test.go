@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"sync"
 	"testing"
+	"time"
 
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
@@ -61,27 +62,57 @@ func TestRegistryRegisterPack(t *testing.T) {
 		}
 	})
 
-	t.Run("returns error for duplicate pack ID", func(t *testing.T) {
+	t.Run("replaces a pack registered again under the same ID", func(t *testing.T) {
 		registry := NewRegistry(slog.Default())
-		manifest := createTestManifest("pack-1", "1.0.0",
+		registry.RegisterPack("pack-1", createTestManifest("pack-1", "1.0.0",
 			createTestTool("tool-a", "Tool A"),
-		)
+			createTestTool("tool-b", "Tool B"),
+		))
+		old := registry.GetPack("pack-1")
 
-		err := registry.RegisterPack("pack-1", manifest)
+		// tool-a stays with the pack; tool-b goes and tool-c arrives.
+		err := registry.RegisterPack("pack-1", createTestManifest("pack-1", "2.0.0",
+			createTestTool("tool-a", "Tool A"),
+			createTestTool("tool-c", "Tool C"),
+		))
 		if err != nil {
-			t.Fatalf("unexpected error on first register: %v", err)
+			t.Fatalf("re-registering: %v", err)
 		}
 
-		// Try to register again with same ID
-		manifest2 := createTestManifest("pack-1", "2.0.0",
-			createTestTool("tool-c", "Tool C"),
-		)
-		err = registry.RegisterPack("pack-1", manifest2)
-		if err == nil {
-			t.Error("expected error for duplicate pack ID")
+		pack := registry.GetPack("pack-1")
+		if pack == old || pack.Version != "2.0.0" {
+			t.Fatalf("pack = %+v, want the 2.0.0 registration", pack)
+		}
+		select {
+		case <-old.Done():
+		default:
+			t.Error("replaced pack was not closed")
+		}
+		for name, want := range map[string]bool{"tool-a": true, "tool-b": false, "tool-c": true} {
+			if _, owner := registry.GetToolByName(name); (owner == pack) != want {
+				t.Errorf("%s owned by the new pack = %v, want %v", name, owner == pack, want)
+			}
 		}
-		if !errors.Is(err, ErrPackAlreadyRegistered) {
-			t.Errorf("expected ErrPackAlreadyRegistered, got %v", err)
+
+		// The old registration's cleanup leaves the new one alone.
+		registry.removePack(old)
+		if registry.GetPack("pack-1") != pack {
+			t.Error("removing the replaced pack removed its replacement")
+		}
+	})
+
+	t.Run("keeps the old pack when the replacement collides", func(t *testing.T) {
+		registry := NewRegistry(slog.Default())
+		registry.RegisterPack("pack-1", createTestManifest("pack-1", "1.0.0", createTestTool("tool-a", "Tool A")))
+		registry.RegisterPack("pack-2", createTestManifest("pack-2", "1.0.0", createTestTool("tool-b", "Tool B")))
+		old := registry.GetPack("pack-1")
+
+		err := registry.RegisterPack("pack-1", createTestManifest("pack-1", "2.0.0", createTestTool("tool-b", "Tool B")))
+		if !errors.Is(err, ErrToolCollision) {
+			t.Fatalf("expected ErrToolCollision, got %v", err)
+		}
+		if registry.GetPack("pack-1") != old {
+			t.Error("a failed replacement changed the registered pack")
 		}
 	})
 
@@ -340,6 +371,46 @@ func TestRegistryGetAllTools(t *testing.T) {
 	})
 }
 
+func TestRegistryGeneration(t *testing.T) {
+	registry := NewRegistry(slog.Default())
+	gen := registry.Generation()
+	changed := func(what string) {
+		t.Helper()
+		if next := registry.Generation(); next == gen {
+			t.Errorf("generation unchanged after %s", what)
+		} else {
+			gen = next
+		}
+	}
+
+	if err := registry.RegisterPack("pack-1", createTestManifest("pack-1", "1.0.0", createTestTool("tool-a", "Tool A"))); err != nil {
+		t.Fatalf("RegisterPack: %v", err)
+	}
+	changed("RegisterPack")
+
+	if err := registry.RegisterPack("pack-2", createTestManifest("pack-2", "1.0.0", createTestTool("tool-a", "Tool A"))); err == nil {
+		t.Fatal("expected colliding registration to fail")
+	}
+	registry.GetToolsForCapabilities(nil)
+	if registry.Generation() != gen {
+		t.Error("generation changed without a change to the tool set")
+	}
+
+	if err := registry.RegisterPack("pack-1", createTestManifest("pack-1", "2.0.0", createTestTool("tool-a", "Tool A"))); err != nil {
+		t.Fatalf("re-registering: %v", err)
+	}
+	changed("re-registering a pack")
+
+	if err := registry.RegisterBuiltinPack(&BuiltinPack{ID: "builtin", Tools: []*BuiltinTool{{Definition: createTestTool("tool-b", "Tool B")}}}); err != nil {
+		t.Fatalf("RegisterBuiltinPack: %v", err)
+	}
+	changed("RegisterBuiltinPack")
+	registry.UnregisterBuiltinPack("builtin")
+	changed("UnregisterBuiltinPack")
+	registry.UnregisterPack("pack-1")
+	changed("UnregisterPack")
+}
+
 func TestRegistryCapabilityFiltering(t *testing.T) {
 	t.Run("returns tools with no required capabilities", func(t *testing.T) {
 		registry := NewRegistry(slog.Default())
@@ -451,6 +522,26 @@ func TestRegistryCapabilityFiltering(t *testing.T) {
 			t.Errorf("expected 3 tools with cap1+cap2, got %d", len(tools))
 		}
 	})
+
+	t.Run("matches capability patterns", func(t *testing.T) {
+		registry := NewRegistry(slog.Default())
+		manifest := createTestManifest("pack-1", "1.0.0",
+			createTestTool("search", "Search", "web.search"),
+			createTestTool("fetch", "Fetch", "web.fetch"),
+			createTestTool("fire", "Fire", "webhook.fire"),
+		)
+
+		registry.RegisterPack("pack-1", manifest)
+
+		tools := registry.GetToolsForCapabilities([]string{"web.*"})
+		if len(tools) != 2 {
+			t.Errorf("expected 2 tools with web.*, got %d", len(tools))
+		}
+		tools = registry.GetToolsForCapabilities([]string{"*"})
+		if len(tools) != 3 {
+			t.Errorf("expected 3 tools with *, got %d", len(tools))
+		}
+	})
 }
 
 func TestRegistryListPacks(t *testing.T) {
@@ -617,3 +708,48 @@ func TestRegistryConcurrentAccess(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+func TestRegistryHeartbeat(t *testing.T) {
+	registry := NewRegistry(slog.Default())
+	now := time.Now()
+	registry.now = func() time.Time { return now }
+	registry.SetHeartbeatMissWindow(10 * time.Second)
+	registry.RegisterPack("pack-1", createTestManifest("pack-1", "1.0.0", createTestTool("tool-a", "Tool A")))
+	pack := registry.GetPack("pack-1")
+
+	// A pack that never heartbeats is judged by its stream alone.
+	now = now.Add(time.Hour)
+	if !registry.PackHealthy(pack) {
+		t.Error("pack without heartbeats is unhealthy")
+	}
+
+	if err := registry.Heartbeat("pack-1"); err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+	now = now.Add(10 * time.Second)
+	if !registry.PackHealthy(pack) || len(registry.UnhealthyTools()) != 0 {
+		t.Error("pack unhealthy within the miss window")
+	}
+
+	now = now.Add(time.Second)
+	if registry.PackHealthy(pack) {
+		t.Error("pack healthy after missing the window")
+	}
+	if unhealthy := registry.UnhealthyTools(); !unhealthy["tool-a"] {
+		t.Errorf("UnhealthyTools = %v, want tool-a", unhealthy)
+	}
+	if info := registry.ListPacks(); len(info) != 1 || info[0].Healthy || info[0].LastHeartbeat.IsZero() {
+		t.Errorf("ListPacks = %+v, want one unhealthy pack with its last heartbeat", info)
+	}
+	// The tools stay registered.
+	if tool, _ := registry.GetToolByName("tool-a"); tool == nil {
+		t.Error("unhealthy pack's tool was removed")
+	}
+
+	if err := registry.Heartbeat("pack-1"); err != nil || !registry.PackHealthy(pack) {
+		t.Errorf("after another heartbeat: err %v, healthy %v; want healthy", err, registry.PackHealthy(pack))
+	}
+	if err := registry.Heartbeat("missing"); !errors.Is(err, ErrPackNotFound) {
+		t.Errorf("Heartbeat for an unknown pack = %v, want ErrPackNotFound", err)
+	}
+}
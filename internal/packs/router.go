@@ -7,21 +7,40 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+	"github.com/2389/coven-gateway/internal/logctx"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
+// tracer records a span per routed tool call.
+var tracer = otel.Tracer("github.com/2389/coven-gateway/internal/packs")
+
 // ErrToolNotFound indicates the requested tool is not registered.
-var ErrToolNotFound = errors.New("tool not found")
+var ErrToolNotFound = coverr.New(coverr.ToolNotFound, "tool not found")
 
 // ErrPackDisconnected indicates the pack that owns the tool has disconnected.
-var ErrPackDisconnected = errors.New("pack disconnected")
+var ErrPackDisconnected = coverr.New(coverr.ToolUnavailable, "pack disconnected")
+
+// ErrPackUnavailable indicates the pack that owns the tool is unhealthy: it
+// stopped heartbeating, so the call fails at once instead of waiting out the
+// tool timeout.
+var ErrPackUnavailable = coverr.New(coverr.PackUnavailable, "pack unavailable")
 
 // ErrDuplicateRequestID indicates the request ID is already in use.
-var ErrDuplicateRequestID = errors.New("duplicate request ID")
+var ErrDuplicateRequestID = coverr.New(coverr.InvalidRequest, "duplicate request ID")
+
+// ErrCallerRejected indicates the caller check refused the calling agent.
+var ErrCallerRejected = coverr.New(coverr.PermissionDenied, "caller not permitted to call tools")
 
 // DefaultTimeout is the default timeout for tool execution.
 const DefaultTimeout = 30 * time.Second
@@ -31,10 +50,37 @@ type Router struct {
 	registry *Registry
 	logger   *slog.Logger
 	timeout  time.Duration
+	stall    time.Duration
+	onResult func(toolName, agentID string, ok bool)
+	onCall   func(toolName, outcome string, d time.Duration)
+
+	// capability checks, see RouterConfig
+	capabilities        func(agentID string) []string
+	onCapabilityWarning func(agentID, toolName, capability string)
+
+	// settingsMu guards the settings that may change on a config reload.
+	settingsMu  sync.RWMutex
+	check       func(agentID string) error
+	enforcement string
+
+	// per-agent tool overrides, see RouterConfig
+	disabledTools func(ctx context.Context, agentID string) map[string]string
+
+	// dry-run hooks, see RouterConfig
+	approval func(agentID, toolName string) string
+	onCheck  func(agentID string, v *ToolCallVerdict)
+
+	// tool approval policy, see RouterConfig
+	policies    ToolPolicySource
+	agentGroups func(ctx context.Context, agentID string) []string
+
+	// result cache for cacheable pack tools, see RouterConfig
+	cache         *resultCache
+	onCacheLookup func(toolName string, hit bool)
 
 	// pending tracks outstanding tool requests awaiting responses
 	mu      sync.RWMutex
-	pending map[string]chan *pb.ExecuteToolResponse
+	pending map[string]*pendingCall
 }
 
 // RouterConfig contains configuration options for the Router.
@@ -42,6 +88,66 @@ type RouterConfig struct {
 	Registry *Registry
 	Logger   *slog.Logger
 	Timeout  time.Duration
+
+	// StallTimeout is how long a streaming tool call may go between chunks
+	// once its first chunk arrives (default DefaultStallTimeout). The call's
+	// timeout applies only until then.
+	StallTimeout time.Duration
+
+	// CallerCheck, if set, runs before every tool call. A non-nil error rejects
+	// the call with ErrCallerRejected wrapping the returned error.
+	CallerCheck func(agentID string) error
+
+	// OnResult, if set, is called after each tool call that reached a builtin
+	// or pack, with ok false for tool errors, timeouts, and disconnects.
+	OnResult func(toolName, agentID string, ok bool)
+
+	// OnCall, if set, is called after every tool call that is not a dry run
+	// with its outcome (CallOK, CallError, or CallRejected) and duration.
+	OnCall func(toolName, outcome string, d time.Duration)
+
+	// Capabilities, if set, returns the capabilities granted to a calling
+	// agent. Every call to a tool that requires capabilities, builtin or
+	// pack, is checked against it; when nil no capability check is made.
+	Capabilities func(agentID string) []string
+
+	// CapabilityEnforcement is CapabilityEnforce (the default), which rejects
+	// a call missing a capability with a CapabilityDeniedError, or
+	// CapabilityWarn, which lets the call through and reports it.
+	CapabilityEnforcement string
+
+	// OnCapabilityWarning, if set, is called in warn mode for each required
+	// capability a call was let through without.
+	OnCapabilityWarning func(agentID, toolName, capability string)
+
+	// DisabledTools, if set, returns the tools an admin disabled for a
+	// calling agent, each with its reason. A call to one of them is refused
+	// with a ToolDisabledError before any capability check.
+	DisabledTools func(ctx context.Context, agentID string) map[string]string
+
+	// ApprovalPolicy, if set, names the policy that would hold a call by
+	// agentID to toolName for approval, or returns "" when none applies.
+	ApprovalPolicy func(agentID, toolName string) string
+
+	// OnCheck, if set, is called with the verdict of every dry run.
+	OnCheck func(agentID string, v *ToolCallVerdict)
+
+	// ToolPolicies, if set, holds the ordered rules and default action
+	// EvaluateToolPolicy applies to tool approval requests.
+	ToolPolicies ToolPolicySource
+
+	// AgentGroups, if set, returns the agent groups a calling agent belongs
+	// to, for tool policy rules that name a group.
+	AgentGroups func(ctx context.Context, agentID string) []string
+
+	// CacheMaxEntries bounds the results cached for pack tools that declare
+	// cacheable with a cache TTL (default DefaultCacheMaxEntries); the least
+	// recently used result is evicted first.
+	CacheMaxEntries int
+
+	// OnCacheLookup, if set, is called for every cache lookup of a cacheable
+	// tool's call, with hit reporting whether a cached result answered it.
+	OnCacheLookup func(toolName string, hit bool)
 }
 
 // NewRouter creates a new Router with the given configuration.
@@ -50,31 +156,78 @@ func NewRouter(cfg RouterConfig) *Router {
 	if timeout == 0 {
 		timeout = DefaultTimeout
 	}
+	stall := cfg.StallTimeout
+	if stall == 0 {
+		stall = DefaultStallTimeout
+	}
 
 	return &Router{
 		registry: cfg.Registry,
 		logger:   cfg.Logger,
 		timeout:  timeout,
-		pending:  make(map[string]chan *pb.ExecuteToolResponse),
+		stall:    stall,
+		onResult: cfg.OnResult,
+		onCall:   cfg.OnCall,
+		pending:  make(map[string]*pendingCall),
+
+		capabilities:        cfg.Capabilities,
+		onCapabilityWarning: cfg.OnCapabilityWarning,
+		check:               cfg.CallerCheck,
+		enforcement:         cfg.CapabilityEnforcement,
+		disabledTools:       cfg.DisabledTools,
+
+		approval: cfg.ApprovalPolicy,
+		onCheck:  cfg.OnCheck,
+
+		policies:    cfg.ToolPolicies,
+		agentGroups: cfg.AgentGroups,
+
+		cache:         newResultCache(cfg.CacheMaxEntries),
+		onCacheLookup: cfg.OnCacheLookup,
 	}
 }
 
-// RouteToolCall routes a tool call to the appropriate pack or builtin handler.
-// Returns the ExecuteToolResponse or an error if the tool is not found, pack disconnected,
-// context canceled, or timeout exceeded.
+// SetCallerCheck replaces RouterConfig.CallerCheck for later calls; nil
+// removes the check.
+func (r *Router) SetCallerCheck(check func(agentID string) error) {
+	r.settingsMu.Lock()
+	r.check = check
+	r.settingsMu.Unlock()
+}
+
+// SetCapabilityEnforcement replaces RouterConfig.CapabilityEnforcement for
+// later calls.
+func (r *Router) SetCapabilityEnforcement(mode string) {
+	r.settingsMu.Lock()
+	r.enforcement = mode
+	r.settingsMu.Unlock()
+}
+
+func (r *Router) callerCheck() func(agentID string) error {
+	r.settingsMu.RLock()
+	defer r.settingsMu.RUnlock()
+	return r.check
+}
+
+func (r *Router) enforcementMode() string {
+	r.settingsMu.RLock()
+	defer r.settingsMu.RUnlock()
+	return r.enforcement
+}
+
 // handleBuiltinTool executes a builtin tool and returns the response.
 func (r *Router) handleBuiltinTool(ctx context.Context, builtin *BuiltinTool, toolName, inputJSON, requestID, agentID string) *pb.ExecuteToolResponse {
-	r.logger.Info("→ dispatching to builtin",
+	logctx.FromContext(ctx, r.logger).Info("→ dispatching to builtin",
 		"tool_name", toolName,
-		"request_id", requestID,
+		"tool_request_id", requestID,
 		"agent_id", agentID,
 	)
 
 	result, err := builtin.Handler(ctx, agentID, json.RawMessage(inputJSON))
 	if err != nil {
-		r.logger.Warn("builtin tool error",
+		logctx.FromContext(ctx, r.logger).Warn("builtin tool error",
 			"tool_name", toolName,
-			"request_id", requestID,
+			"tool_request_id", requestID,
 			"error", err,
 		)
 		return &pb.ExecuteToolResponse{
@@ -83,9 +236,9 @@ func (r *Router) handleBuiltinTool(ctx context.Context, builtin *BuiltinTool, to
 		}
 	}
 
-	r.logger.Info("← builtin responded",
+	logctx.FromContext(ctx, r.logger).Info("← builtin responded",
 		"tool_name", toolName,
-		"request_id", requestID,
+		"tool_request_id", requestID,
 	)
 	return &pb.ExecuteToolResponse{
 		RequestId: requestID,
@@ -93,21 +246,144 @@ func (r *Router) handleBuiltinTool(ctx context.Context, builtin *BuiltinTool, to
 	}
 }
 
+// RouteToolCall routes a tool call to the appropriate pack or builtin handler.
+// Returns the ExecuteToolResponse or an error if the tool is not found, pack disconnected,
+// context canceled, or timeout exceeded. A dry run (see DryRunParam) is answered
+// with its ToolCallVerdict as output and never reaches the tool. A call to a
+// cacheable pack tool may be answered from the result cache, marked Cached,
+// unless its input sets NoCacheParam.
 func (r *Router) RouteToolCall(ctx context.Context, toolName, inputJSON, requestID string, agentID string) (*pb.ExecuteToolResponse, error) {
+	return r.RouteToolCallStream(ctx, toolName, inputJSON, requestID, agentID, nil)
+}
+
+// RouteToolCallStream is RouteToolCall for callers that want a streaming
+// pack's partial output: onChunk, if set, is called with each chunk in
+// sequence before the final response is returned. Builtins never stream.
+func (r *Router) RouteToolCallStream(ctx context.Context, toolName, inputJSON, requestID string, agentID string, onChunk func(Chunk)) (*pb.ExecuteToolResponse, error) {
+	inputJSON, noCache := ParseNoCache(inputJSON)
+	if stripped, ok := ParseDryRun(inputJSON); ok {
+		return r.dryRunResponse(ctx, toolName, stripped, requestID, agentID), nil
+	}
+	started := time.Now()
+	ctx, span := tracer.Start(ctx, "packs.execute_tool", trace.WithAttributes(
+		attribute.String("tool.name", toolName),
+		attribute.String("agent.id", agentID),
+		attribute.String("tool.request_id", requestID),
+	))
+	defer span.End()
+	resp, err := r.routeToolCall(ctx, toolName, inputJSON, requestID, agentID, noCache, onChunk)
+	outcome := callOutcome(resp, err)
+	span.SetAttributes(attribute.String("tool.outcome", outcome), attribute.Bool("tool.cached", resp.GetCached()))
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case resp.GetError() != "":
+		span.SetStatus(codes.Error, resp.GetError())
+	}
+	// A cached result says nothing new about the tool's reliability.
+	if r.onResult != nil && countsTowardReliability(err) && !resp.GetCached() {
+		r.onResult(toolName, agentID, err == nil && resp.GetError() == "")
+	}
+	if r.onCall != nil {
+		r.onCall(toolName, outcome, time.Since(started))
+	}
+	return resp, err
+}
+
+// Tool call outcomes reported to RouterConfig.OnCall.
+const (
+	CallOK    = "ok"
+	CallError = "error"
+	// CallRejected is a call that never reached the tool or that the
+	// caller abandoned.
+	CallRejected = "rejected"
+)
+
+// callOutcome classifies a routed call for RouterConfig.OnCall.
+func callOutcome(resp *pb.ExecuteToolResponse, err error) string {
+	switch {
+	case !countsTowardReliability(err):
+		return CallRejected
+	case err != nil || resp.GetError() != "":
+		return CallError
+	}
+	return CallOK
+}
+
+// countsTowardReliability reports whether a routing error reflects on the
+// tool itself. Calls that never reached the tool, or that the caller
+// abandoned, are not the tool's fault.
+func countsTowardReliability(err error) bool {
+	switch {
+	case errors.Is(err, ErrToolNotFound),
+		errors.Is(err, ErrCallerRejected),
+		errors.Is(err, ErrCapabilityDenied),
+		errors.Is(err, ErrToolDisabled),
+		errors.Is(err, ErrDuplicateRequestID),
+		errors.Is(err, context.Canceled):
+		return false
+	}
+	return true
+}
+
+func (r *Router) routeToolCall(ctx context.Context, toolName, inputJSON, requestID string, agentID string, noCache bool, onChunk func(Chunk)) (*pb.ExecuteToolResponse, error) {
+	if check := r.callerCheck(); check != nil {
+		if err := check(agentID); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCallerRejected, err)
+		}
+	}
+	if err := r.checkDisabled(ctx, toolName, requestID, agentID); err != nil {
+		return nil, err
+	}
+
 	// Check if it's a builtin tool first
 	if builtin := r.registry.GetBuiltinTool(toolName); builtin != nil {
+		if err := r.checkCapabilities(builtin.Definition, toolName, requestID, agentID); err != nil {
+			return nil, err
+		}
 		return r.handleBuiltinTool(ctx, builtin, toolName, inputJSON, requestID, agentID), nil
 	}
 
 	// Look up the tool and its pack (external pack routing)
 	tool, pack := r.registry.GetToolByName(toolName)
 	if tool == nil || pack == nil {
-		r.logger.Debug("tool not found in registry",
+		logctx.FromContext(ctx, r.logger).Debug("tool not found in registry",
 			"tool_name", toolName,
-			"request_id", requestID,
+			"tool_request_id", requestID,
 		)
 		return nil, ErrToolNotFound
 	}
+	if err := r.checkCapabilities(tool.Definition, toolName, requestID, agentID); err != nil {
+		return nil, err
+	}
+	key, ttl, cacheable := r.cacheKeyFor(tool.Definition, toolName, inputJSON, agentID)
+	if cacheable && !noCache {
+		output, hit := r.cache.get(key)
+		if r.onCacheLookup != nil {
+			r.onCacheLookup(toolName, hit)
+		}
+		if hit {
+			logctx.FromContext(ctx, r.logger).Info("← answered from tool result cache",
+				"tool_name", toolName,
+				"pack_id", pack.ID,
+				"tool_request_id", requestID,
+			)
+			return &pb.ExecuteToolResponse{
+				RequestId: requestID,
+				Result:    &pb.ExecuteToolResponse_OutputJson{OutputJson: output},
+				Cached:    true,
+			}, nil
+		}
+	}
+	if !r.registry.PackHealthy(pack) {
+		logctx.FromContext(ctx, r.logger).Warn("pack unhealthy, failing tool call",
+			"tool_name", toolName,
+			"pack_id", pack.ID,
+			"tool_request_id", requestID,
+		)
+		return nil, ErrPackUnavailable
+	}
 
 	// Create the request
 	req := &pb.ExecuteToolRequest{
@@ -129,45 +405,110 @@ func (r *Router) RouteToolCall(ctx context.Context, toolName, inputJSON, request
 		timeout = time.Duration(tool.Definition.GetTimeoutSeconds()) * time.Second
 	}
 
-	// Create timeout context
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
 	// Send request to pack's channel (with panic recovery for closed channels)
-	if err := r.sendToPackChannel(ctx, pack, req, toolName, requestID); err != nil {
+	sendCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := r.sendToPackChannel(sendCtx, pack, req, toolName, requestID); err != nil {
 		return nil, err
 	}
 
-	return r.waitForPackResponse(ctx, respCh, pack, toolName, requestID, timeout)
+	resp, err := r.waitForPackResponse(ctx, respCh, pack, toolName, requestID, timeout, onChunk)
+	if err == nil && cacheable && resp.GetError() == "" {
+		r.cache.put(key, resp.GetOutputJson(), ttl)
+	}
+	return resp, err
 }
 
-// waitForPackResponse waits for a response from the pack or timeout.
-func (r *Router) waitForPackResponse(ctx context.Context, respCh <-chan *pb.ExecuteToolResponse, pack *Pack, toolName, requestID string, timeout time.Duration) (*pb.ExecuteToolResponse, error) {
-	select {
-	case resp, ok := <-respCh:
-		if !ok {
-			r.logger.Warn("response channel closed unexpectedly",
+// cacheKeyFor returns the result cache key and TTL for a call to a pack
+// tool, or false if the tool does not declare itself cacheable with a TTL
+// or the input is not JSON.
+func (r *Router) cacheKeyFor(def *pb.ToolDefinition, toolName, inputJSON, agentID string) (string, time.Duration, bool) {
+	if !def.GetCacheable() || def.GetCacheTtlSeconds() <= 0 {
+		return "", 0, false
+	}
+	var caps []string
+	if r.capabilities != nil {
+		caps = r.capabilities(agentID)
+	}
+	key, ok := cacheKey(toolName, inputJSON, caps)
+	if !ok {
+		return "", 0, false
+	}
+	return key, time.Duration(def.GetCacheTtlSeconds()) * time.Second, true
+}
+
+// waitForPackResponse waits for the pack's final response, passing any
+// chunks before it to onChunk. It gives up after timeout, or once chunks
+// have started, after the stall timeout passes without a new one.
+func (r *Router) waitForPackResponse(ctx context.Context, respCh <-chan *pb.ExecuteToolResponse, pack *Pack, toolName, requestID string, timeout time.Duration, onChunk func(Chunk)) (*pb.ExecuteToolResponse, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	chunks := 0
+
+	for {
+		select {
+		case resp, ok := <-respCh:
+			if !ok {
+				logctx.FromContext(ctx, r.logger).Warn("response channel closed unexpectedly",
+					"tool_name", toolName,
+					"pack_id", pack.ID,
+					"tool_request_id", requestID,
+				)
+				return nil, ErrPackDisconnected
+			}
+			if chunk := resp.GetChunk(); chunk != nil {
+				chunks++
+				if onChunk != nil {
+					onChunk(Chunk{Sequence: chunk.GetSequence(), Output: chunk.GetOutput()})
+				}
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(r.stall)
+				continue
+			}
+			logctx.FromContext(ctx, r.logger).Info("  ← pack responded",
+				"tool_name", toolName,
+				"pack_id", pack.ID,
+				"tool_request_id", requestID,
+				"chunks", chunks,
+			)
+			return resp, nil
+		case <-pack.Done():
+			logctx.FromContext(ctx, r.logger).Warn("pack closed while awaiting response",
 				"tool_name", toolName,
 				"pack_id", pack.ID,
-				"request_id", requestID,
+				"tool_request_id", requestID,
+				"chunks", chunks,
 			)
 			return nil, ErrPackDisconnected
+		case <-timer.C:
+			err := fmt.Errorf("%w: %w", ErrToolTimeout, context.DeadlineExceeded)
+			if chunks > 0 {
+				err = ErrToolStalled
+			}
+			logctx.FromContext(ctx, r.logger).Warn("tool call timed out",
+				"tool_name", toolName,
+				"pack_id", pack.ID,
+				"tool_request_id", requestID,
+				"timeout", timeout,
+				"chunks", chunks,
+				"error", err,
+			)
+			return nil, err
+		case <-ctx.Done():
+			logctx.FromContext(ctx, r.logger).Warn("tool call timed out or canceled",
+				"tool_name", toolName,
+				"pack_id", pack.ID,
+				"tool_request_id", requestID,
+				"timeout", timeout,
+				"error", ctx.Err(),
+			)
+			return nil, ctx.Err()
 		}
-		r.logger.Info("  ← pack responded",
-			"tool_name", toolName,
-			"pack_id", pack.ID,
-			"request_id", requestID,
-		)
-		return resp, nil
-	case <-ctx.Done():
-		r.logger.Warn("tool call timed out or canceled",
-			"tool_name", toolName,
-			"pack_id", pack.ID,
-			"request_id", requestID,
-			"timeout", timeout,
-			"error", ctx.Err(),
-		)
-		return nil, ctx.Err()
 	}
 }
 
@@ -176,52 +517,57 @@ func (r *Router) waitForPackResponse(ctx context.Context, respCh <-chan *pb.Exec
 func (r *Router) sendToPackChannel(ctx context.Context, pack *Pack, req *pb.ExecuteToolRequest, toolName, requestID string) error {
 	err := pack.Send(ctx, req)
 	if errors.Is(err, ErrPackClosed) {
-		r.logger.Warn("pack channel closed while sending request",
+		logctx.FromContext(ctx, r.logger).Warn("pack channel closed while sending request",
 			"tool_name", toolName,
 			"pack_id", pack.ID,
-			"request_id", requestID,
+			"tool_request_id", requestID,
 		)
 		return ErrPackDisconnected
 	}
 	if err != nil {
 		return err
 	}
-	r.logger.Info("  → routed to pack",
+	logctx.FromContext(ctx, r.logger).Info("  → routed to pack",
 		"tool_name", toolName,
 		"pack_id", pack.ID,
-		"request_id", requestID,
+		"tool_request_id", requestID,
 	)
 	return nil
 }
 
 // HandleToolResponse routes an incoming tool response to the waiting caller.
 // This should be called by the pack service when it receives a response from a pack.
-func (r *Router) HandleToolResponse(resp *pb.ExecuteToolResponse) {
+// It returns an error only for a chunk the caller will not receive: one out
+// of sequence (ErrChunkOutOfOrder) or one the caller has no room for yet
+// (ErrChunkBacklogFull), which the pack may send again.
+func (r *Router) HandleToolResponse(resp *pb.ExecuteToolResponse) error {
 	requestID := resp.GetRequestId()
 
 	// Hold the lock while sending to prevent the channel from being closed
 	// by closePendingRequest between lookup and send
 	r.mu.Lock()
-	ch, ok := r.pending[requestID]
+	defer r.mu.Unlock()
+	call, ok := r.pending[requestID]
 	if !ok {
-		r.mu.Unlock()
 		r.logger.Warn("received response for unknown request",
 			"request_id", requestID,
 		)
-		return
+		return nil
+	}
+	if chunk := resp.GetChunk(); chunk != nil {
+		return call.acceptChunk(requestID, chunk, resp)
 	}
 
 	// Non-blocking send to avoid deadlock if channel is full.
 	// Since we hold the write lock, the channel cannot be closed during this send.
 	select {
-	case ch <- resp:
-		r.mu.Unlock()
+	case call.responses <- resp:
 	default:
-		r.mu.Unlock()
 		r.logger.Warn("response channel full, dropping tool response",
 			"request_id", requestID,
 		)
 	}
+	return nil
 }
 
 // HasTool checks if a tool with the given name exists in the registry (external or builtin).
@@ -260,9 +606,9 @@ func (r *Router) createPendingRequest(requestID string) (chan *pb.ExecuteToolRes
 		return nil, ErrDuplicateRequestID
 	}
 
-	ch := make(chan *pb.ExecuteToolResponse, 1)
-	r.pending[requestID] = ch
-	return ch, nil
+	call := newPendingCall()
+	r.pending[requestID] = call
+	return call.responses, nil
 }
 
 // closePendingRequest closes and removes the response channel for a request.
@@ -270,8 +616,8 @@ func (r *Router) closePendingRequest(requestID string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if ch, ok := r.pending[requestID]; ok {
-		close(ch)
+	if call, ok := r.pending[requestID]; ok {
+		close(call.responses)
 		delete(r.pending, requestID)
 	}
 }
@@ -293,8 +639,8 @@ func (r *Router) Close() {
 	pendingCount := len(r.pending)
 
 	// Close all pending response channels to unblock waiters
-	for requestID, ch := range r.pending {
-		close(ch)
+	for requestID, call := range r.pending {
+		close(call.responses)
 		delete(r.pending, requestID)
 	}
 
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -847,3 +848,161 @@ func TestRouterGetToolDefinitionWithBuiltins(t *testing.T) {
 		}
 	})
 }
+
+func TestRouterCallerCheck(t *testing.T) {
+	reg := NewRegistry(slog.Default())
+	pack := &BuiltinPack{
+		ID: "builtin:test",
+		Tools: []*BuiltinTool{{
+			Definition: &pb.ToolDefinition{Name: "echo"},
+			Handler: func(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
+				return input, nil
+			},
+		}},
+	}
+	if err := reg.RegisterBuiltinPack(pack); err != nil {
+		t.Fatalf("RegisterBuiltinPack: %v", err)
+	}
+
+	errBlocked := errors.New("agent paused")
+	router := NewRouter(RouterConfig{
+		Registry: reg,
+		Logger:   slog.Default(),
+		CallerCheck: func(agentID string) error {
+			if agentID == "blocked" {
+				return errBlocked
+			}
+			return nil
+		},
+	})
+
+	_, err := router.RouteToolCall(context.Background(), "echo", `{}`, "req-1", "blocked")
+	if !errors.Is(err, ErrCallerRejected) || !errors.Is(err, errBlocked) {
+		t.Errorf("blocked caller error = %v, want ErrCallerRejected wrapping the check error", err)
+	}
+
+	if _, err := router.RouteToolCall(context.Background(), "echo", `{}`, "req-2", "allowed"); err != nil {
+		t.Errorf("allowed caller: %v", err)
+	}
+}
+
+func TestRouterOnResult(t *testing.T) {
+	reg := NewRegistry(slog.Default())
+	pack := &BuiltinPack{
+		ID: "builtin:test",
+		Tools: []*BuiltinTool{
+			{
+				Definition: &pb.ToolDefinition{Name: "echo"},
+				Handler: func(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
+					return input, nil
+				},
+			},
+			{
+				Definition: &pb.ToolDefinition{Name: "fail"},
+				Handler: func(ctx context.Context, agentID string, input json.RawMessage) (json.RawMessage, error) {
+					return nil, errors.New("boom")
+				},
+			},
+		},
+	}
+	if err := reg.RegisterBuiltinPack(pack); err != nil {
+		t.Fatalf("RegisterBuiltinPack: %v", err)
+	}
+
+	type result struct {
+		tool string
+		ok   bool
+	}
+	var results []result
+	var outcomes []string
+	router := NewRouter(RouterConfig{
+		Registry: reg,
+		Logger:   slog.Default(),
+		CallerCheck: func(agentID string) error {
+			if agentID == "blocked" {
+				return errors.New("blocked")
+			}
+			return nil
+		},
+		OnResult: func(toolName, agentID string, ok bool) {
+			results = append(results, result{toolName, ok})
+		},
+		OnCall: func(toolName, outcome string, d time.Duration) {
+			outcomes = append(outcomes, toolName+":"+outcome)
+		},
+	})
+
+	ctx := context.Background()
+	_, _ = router.RouteToolCall(ctx, "echo", `{}`, "r1", "agent")
+	_, _ = router.RouteToolCall(ctx, "fail", `{}`, "r2", "agent")
+	_, _ = router.RouteToolCall(ctx, "missing", `{}`, "r3", "agent")
+	_, _ = router.RouteToolCall(ctx, "echo", `{}`, "r4", "blocked")
+
+	want := []result{{"echo", true}, {"fail", false}}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v, want %+v (unknown tools and rejected callers not counted)", results, want)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, results[i], want[i])
+		}
+	}
+
+	wantOutcomes := []string{"echo:" + CallOK, "fail:" + CallError, "missing:" + CallRejected, "echo:" + CallRejected}
+	if strings.Join(outcomes, ",") != strings.Join(wantOutcomes, ",") {
+		t.Errorf("outcomes = %v, want %v", outcomes, wantOutcomes)
+	}
+}
+
+func TestRouterUnhealthyPack(t *testing.T) {
+	t.Run("fails fast with ErrPackUnavailable", func(t *testing.T) {
+		registry, router := setupRouterTest(t)
+		registry.SetHeartbeatMissWindow(time.Millisecond)
+		pack := registerTestPack(t, registry, "quiet-pack", createTestTool("quiet-tool", "A tool"))
+		if err := registry.Heartbeat("quiet-pack"); err != nil {
+			t.Fatalf("Heartbeat: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		started := time.Now()
+		_, err := router.RouteToolCall(context.Background(), "quiet-tool", `{}`, "req-quiet", "test-agent")
+		if !errors.Is(err, ErrPackUnavailable) {
+			t.Fatalf("err = %v, want ErrPackUnavailable", err)
+		}
+		if d := time.Since(started); d > time.Second {
+			t.Errorf("call took %v, want it to fail without waiting out the timeout", d)
+		}
+		if len(pack.Channel) != 0 {
+			t.Error("call to an unhealthy pack reached its channel")
+		}
+	})
+
+	t.Run("re-registration fails the old pack's pending calls", func(t *testing.T) {
+		registry, router := setupRouterTest(t)
+		registerTestPack(t, registry, "flaky-pack", createTestTool("flaky-tool", "A tool"))
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := router.RouteToolCall(context.Background(), "flaky-tool", `{}`, "req-flaky", "test-agent")
+			done <- err
+		}()
+		deadline := time.Now().Add(time.Second)
+		for router.PendingCount() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("request did not register in time")
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		registerTestPack(t, registry, "flaky-pack", createTestTool("flaky-tool", "A tool"))
+
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrPackDisconnected) {
+				t.Errorf("err = %v, want ErrPackDisconnected", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("pending call was not canceled by the re-registration")
+		}
+	})
+}
@@ -0,0 +1,119 @@
+// ABOUTME: Checks tool input against the JSON Schema subset tool definitions use.
+// ABOUTME: Covers type, required, properties, enum and array items; other keywords are ignored.
+
+package packs
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// schemaNode is the part of a JSON Schema that validateInput understands.
+type schemaNode struct {
+	Type       any                    `json:"type"` // a type name or a list of them
+	Required   []string               `json:"required"`
+	Properties map[string]*schemaNode `json:"properties"`
+	Enum       []any                  `json:"enum"`
+	Items      *schemaNode            `json:"items"`
+}
+
+// validateInput reports the first way inputJSON breaks schemaJSON. An empty
+// or unparseable schema accepts any JSON input.
+func validateInput(schemaJSON, inputJSON string) error {
+	var input any
+	if err := json.Unmarshal([]byte(inputJSON), &input); err != nil {
+		return fmt.Errorf("input is not valid JSON: %w", err)
+	}
+	if strings.TrimSpace(schemaJSON) == "" {
+		return nil
+	}
+	var schema schemaNode
+	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
+		return nil
+	}
+	return schema.validate("input", input)
+}
+
+func (s *schemaNode) validate(path string, v any) error {
+	if types := s.types(); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return jsonTypeIs(v, t) }) {
+		return fmt.Errorf("%s: expected %s", path, strings.Join(types, " or "))
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return jsonEqual(e, v) }) {
+		return fmt.Errorf("%s: not one of the allowed values", path)
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				return fmt.Errorf("%s: missing required field %q", path, name)
+			}
+		}
+		for name, prop := range s.Properties {
+			if field, ok := val[name]; ok && prop != nil {
+				if err := prop.validate(path+"."+name, field); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range val {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *schemaNode) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var types []string
+		for _, v := range t {
+			if name, ok := v.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// jsonTypeIs reports whether a decoded JSON value has the named schema type.
+func jsonTypeIs(v any, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == float64(int64(f))
+	case "null":
+		return v == nil
+	}
+	return true // unknown type names are not enforced
+}
+
+func jsonEqual(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
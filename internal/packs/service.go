@@ -16,6 +16,7 @@ import (
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	"github.com/2389/coven-gateway/internal/coverr"
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
@@ -23,10 +24,10 @@ import (
 const DefaultToolTimeout = 30 * time.Second
 
 // ErrToolTimeout indicates a tool execution timed out waiting for a response.
-var ErrToolTimeout = errors.New("tool execution timed out")
+var ErrToolTimeout = coverr.New(coverr.ToolTimeout, "tool execution timed out")
 
 // ErrPackChannelClosed indicates the pack's request channel was closed.
-var ErrPackChannelClosed = errors.New("pack channel closed")
+var ErrPackChannelClosed = coverr.New(coverr.ToolUnavailable, "pack channel closed")
 
 // PackServiceServer implements the PackService gRPC service.
 type PackServiceServer struct {
@@ -39,6 +40,11 @@ type PackServiceServer struct {
 	// Used to correlate ToolResult calls with DispatchTool waiters.
 	pendingMu       sync.RWMutex
 	pendingRequests map[string]chan *pb.ExecuteToolResponse
+
+	// catalog records tool definition changes; nil disables the changelog.
+	catalog *CatalogTracker
+	// onToolsChanged is called with the catalog version after a pack connects or disconnects.
+	onToolsChanged func(catalogVersion int64)
 }
 
 // NewPackServiceServer creates a new PackService with the given registry and router.
@@ -52,14 +58,54 @@ func NewPackServiceServer(registry *Registry, router *Router, logger *slog.Logge
 	}
 }
 
-// Register handles a pack connecting with its manifest.
-// The pack stays connected and receives tool execution requests via the stream.
-// When the stream closes, the pack is unregistered.
-// handleRegisterError converts registration errors to gRPC status.
-func handleRegisterError(err error, packID string) error {
-	if errors.Is(err, ErrPackAlreadyRegistered) {
-		return status.Errorf(codes.AlreadyExists, "pack %s already registered", packID)
+// SetCatalog enables tool changelog tracking on pack registration.
+// Must be called before the service starts handling requests.
+func (s *PackServiceServer) SetCatalog(catalog *CatalogTracker) {
+	s.catalog = catalog
+}
+
+// SetToolsChangedHandler sets the callback invoked when the set of pack tools changes.
+// Must be called before the service starts handling requests.
+func (s *PackServiceServer) SetToolsChangedHandler(fn func(catalogVersion int64)) {
+	s.onToolsChanged = fn
+}
+
+// recordCatalog diffs the manifest into the changelog and returns the catalog
+// version. Failures are logged rather than rejecting the pack.
+func (s *PackServiceServer) recordCatalog(ctx context.Context, manifest *pb.PackManifest) int64 {
+	if s.catalog == nil {
+		return 0
+	}
+	version, _, err := s.catalog.Record(ctx, manifest)
+	if err != nil {
+		s.logger.Error("failed to record tool catalog", "pack_id", manifest.GetPackId(), "error", err)
+		return s.catalogVersion(ctx)
 	}
+	return version
+}
+
+// catalogVersion returns the current catalog version, or 0 if unavailable.
+func (s *PackServiceServer) catalogVersion(ctx context.Context) int64 {
+	if s.catalog == nil {
+		return 0
+	}
+	version, err := s.catalog.Version(ctx)
+	if err != nil {
+		s.logger.Warn("failed to read tool catalog version", "error", err)
+		return 0
+	}
+	return version
+}
+
+// notifyToolsChanged invokes the tools-changed callback if one is set.
+func (s *PackServiceServer) notifyToolsChanged(catalogVersion int64) {
+	if s.onToolsChanged != nil {
+		s.onToolsChanged(catalogVersion)
+	}
+}
+
+// handleRegisterError converts registration errors to gRPC status.
+func handleRegisterError(err error) error {
 	if errors.Is(err, ErrToolCollision) {
 		return status.Errorf(codes.AlreadyExists, "tool collision: %v", err)
 	}
@@ -94,6 +140,11 @@ func (s *PackServiceServer) forwardToolRequests(ctx context.Context, stream grpc
 	}
 }
 
+// Register handles a pack connecting with its manifest.
+// The pack stays connected and receives tool execution requests via the stream.
+// A pack registering under an ID already connected replaces the old
+// registration, whose stream then ends. When the stream closes, the pack is
+// unregistered unless it has been replaced.
 func (s *PackServiceServer) Register(manifest *pb.PackManifest, stream grpc.ServerStreamingServer[pb.ExecuteToolRequest]) error {
 	packID := manifest.GetPackId()
 	if packID == "" {
@@ -106,19 +157,18 @@ func (s *PackServiceServer) Register(manifest *pb.PackManifest, stream grpc.Serv
 		"tool_count", len(manifest.GetTools()),
 	)
 
-	if err := s.registry.RegisterPack(packID, manifest); err != nil {
-		return handleRegisterError(err, packID)
+	pack, err := s.registry.registerPack(packID, manifest)
+	if err != nil {
+		return handleRegisterError(err)
 	}
 
 	defer func() {
-		s.registry.UnregisterPack(packID)
+		s.registry.removePack(pack)
 		s.logger.Info("pack disconnected", "pack_id", packID)
+		s.notifyToolsChanged(s.catalogVersion(context.WithoutCancel(stream.Context())))
 	}()
 
-	pack := s.registry.GetPack(packID)
-	if pack == nil {
-		return status.Error(codes.Internal, "pack disappeared after registration")
-	}
+	s.notifyToolsChanged(s.recordCatalog(stream.Context(), manifest))
 
 	s.logger.Info("pack connected", "pack_id", packID)
 	return s.forwardToolRequests(stream.Context(), stream, pack, packID)
@@ -129,19 +179,37 @@ func (s *PackServiceServer) Register(manifest *pb.PackManifest, stream grpc.Serv
 func (s *PackServiceServer) ToolResult(ctx context.Context, resp *pb.ExecuteToolResponse) (*emptypb.Empty, error) {
 	requestID := resp.GetRequestId()
 
-	status := "success"
+	if chunk := resp.GetChunk(); chunk != nil {
+		s.logger.Debug("  ← tool result chunk from pack",
+			"request_id", requestID,
+			"sequence", chunk.GetSequence(),
+			"output_bytes", len(chunk.GetOutput()),
+		)
+		if s.router == nil {
+			return nil, status.Error(codes.FailedPrecondition, "streaming tool results need a router")
+		}
+		if err := s.router.HandleToolResponse(resp); err != nil {
+			if errors.Is(err, ErrChunkBacklogFull) {
+				return nil, status.Error(codes.ResourceExhausted, err.Error())
+			}
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return &emptypb.Empty{}, nil
+	}
+
+	resultStatus := "success"
 	if resp.GetError() != "" {
-		status = "error"
+		resultStatus = "error"
 	}
 	s.logger.Info("  ← tool result from pack",
 		"request_id", requestID,
-		"status", status,
+		"status", resultStatus,
 		"output_bytes", len(resp.GetOutputJson()),
 	)
 
 	// Route the response to the waiting caller via the Router
 	if s.router != nil {
-		s.router.HandleToolResponse(resp)
+		_ = s.router.HandleToolResponse(resp) // only chunks can be refused
 	} else {
 		// Fallback to local pending requests (for backward compatibility)
 		s.pendingMu.Lock()
@@ -173,6 +241,21 @@ func (s *PackServiceServer) ToolResult(ctx context.Context, resp *pb.ExecuteTool
 	return &emptypb.Empty{}, nil
 }
 
+// Heartbeat records that a registered pack is alive. A pack that heartbeats
+// once must keep heartbeating within the registry's miss window, or its tool
+// calls fail fast until it does. An unknown pack_id is NotFound: the pack
+// must register again.
+func (s *PackServiceServer) Heartbeat(ctx context.Context, hb *pb.PackHeartbeat) (*emptypb.Empty, error) {
+	packID := hb.GetPackId()
+	if packID == "" {
+		return nil, status.Error(codes.InvalidArgument, "pack_id is required")
+	}
+	if err := s.registry.Heartbeat(packID); err != nil {
+		return nil, status.Errorf(codes.NotFound, "pack %s is not registered", packID)
+	}
+	return &emptypb.Empty{}, nil
+}
+
 // CreatePendingRequest creates a pending request channel for the given request ID.
 // Returns a channel that will receive the ExecuteToolResponse when ToolResult is called.
 // The channel has buffer size 1 to prevent blocking.
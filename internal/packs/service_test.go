@@ -13,7 +13,9 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/2389/coven-gateway/proto/coven"
 )
@@ -201,9 +203,8 @@ func TestPackServiceRegister(t *testing.T) {
 		}
 	})
 
-	t.Run("handles duplicate pack registration", func(t *testing.T) {
+	t.Run("replaces an earlier registration of the same pack", func(t *testing.T) {
 		service, registry := createTestService()
-
 		manifest := &pb.PackManifest{
 			PackId:  "dup-pack",
 			Version: "1.0.0",
@@ -212,23 +213,69 @@ func TestPackServiceRegister(t *testing.T) {
 			},
 		}
 
-		// Pre-register the pack
-		err := registry.RegisterPack("dup-pack", manifest)
-		if err != nil {
-			t.Fatalf("failed to pre-register pack: %v", err)
+		register := func(ctx context.Context) chan error {
+			errCh := make(chan error, 1)
+			go func() { errCh <- service.Register(manifest, newMockRegisterStream(ctx)) }()
+			return errCh
+		}
+		waitForPack := func(not *Pack) *Pack {
+			deadline := time.Now().Add(time.Second)
+			for {
+				if pack := registry.GetPack("dup-pack"); pack != nil && pack != not {
+					return pack
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("pack did not register in time")
+				}
+				time.Sleep(time.Millisecond)
+			}
 		}
 
-		ctx := t.Context()
-		stream := newMockRegisterStream(ctx)
+		firstDone := register(t.Context())
+		first := waitForPack(nil)
 
-		// Try to register again via service
-		err = service.Register(manifest, stream)
-		if err == nil {
-			t.Error("expected error for duplicate pack registration")
+		ctx, cancel := context.WithCancel(t.Context())
+		secondDone := register(ctx)
+		second := waitForPack(first)
+
+		// The first stream ends without taking the new registration with it.
+		select {
+		case <-firstDone:
+		case <-time.After(time.Second):
+			t.Fatal("replaced registration's stream did not end")
+		}
+		if registry.GetPack("dup-pack") != second {
+			t.Error("the replaced stream's cleanup unregistered its replacement")
+		}
+
+		cancel()
+		<-secondDone
+		if registry.GetPack("dup-pack") != nil {
+			t.Error("pack still registered after its stream closed")
 		}
 	})
 }
 
+func TestPackServiceHeartbeat(t *testing.T) {
+	service, registry := createTestService()
+	if err := registry.RegisterPack("hb-pack", &pb.PackManifest{PackId: "hb-pack"}); err != nil {
+		t.Fatalf("RegisterPack: %v", err)
+	}
+
+	if _, err := service.Heartbeat(t.Context(), &pb.PackHeartbeat{PackId: "hb-pack"}); err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+	if info := registry.ListPacks(); len(info) != 1 || info[0].LastHeartbeat.IsZero() {
+		t.Errorf("ListPacks = %+v, want the heartbeat recorded", info)
+	}
+
+	for packID, want := range map[string]codes.Code{"": codes.InvalidArgument, "gone": codes.NotFound} {
+		if _, err := service.Heartbeat(t.Context(), &pb.PackHeartbeat{PackId: packID}); status.Code(err) != want {
+			t.Errorf("Heartbeat(%q) = %v, want %s", packID, err, want)
+		}
+	}
+}
+
 func TestPackServiceToolResult(t *testing.T) {
 	t.Run("delivers result to waiting request", func(t *testing.T) {
 		service, _ := createTestService()
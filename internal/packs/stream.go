@@ -0,0 +1,65 @@
+// ABOUTME: Streaming tool results: packs send numbered chunks of output before the final result.
+// ABOUTME: Tracks each call's next expected sequence and rejects chunks that arrive out of order.
+
+package packs
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/coverr"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// ErrChunkOutOfOrder indicates a pack sent a chunk whose sequence is not the
+// next one expected for its call.
+var ErrChunkOutOfOrder = errors.New("tool result chunk out of order")
+
+// ErrChunkBacklogFull indicates the caller has not yet taken a call's earlier
+// chunks. The pack may send the same chunk again.
+var ErrChunkBacklogFull = errors.New("tool result chunk backlog full")
+
+// ErrToolStalled indicates a streaming tool call went quiet for longer than
+// the stall timeout between chunks.
+var ErrToolStalled = coverr.New(coverr.ToolTimeout, "tool result stream stalled")
+
+// DefaultStallTimeout is the default time allowed between a streaming tool
+// call's chunks.
+const DefaultStallTimeout = 30 * time.Second
+
+// chunkBacklog bounds the responses buffered for one call.
+const chunkBacklog = 64
+
+// Chunk is a piece of a streaming tool call's output.
+type Chunk struct {
+	Sequence uint64
+	Output   string
+}
+
+// pendingCall is a tool call waiting on its pack.
+type pendingCall struct {
+	// responses carries the call's chunks in sequence, then its final result.
+	responses chan *pb.ExecuteToolResponse
+	// nextSeq is the sequence the next chunk must carry.
+	nextSeq uint64
+}
+
+func newPendingCall() *pendingCall {
+	return &pendingCall{responses: make(chan *pb.ExecuteToolResponse, chunkBacklog), nextSeq: 1}
+}
+
+// acceptChunk queues resp, which carries chunk, if chunk is next in
+// sequence. The router's lock must be held.
+func (c *pendingCall) acceptChunk(requestID string, chunk *pb.ToolResultChunk, resp *pb.ExecuteToolResponse) error {
+	if chunk.GetSequence() != c.nextSeq {
+		return fmt.Errorf("%w: request %s got sequence %d, want %d", ErrChunkOutOfOrder, requestID, chunk.GetSequence(), c.nextSeq)
+	}
+	// Keep the last slot free for the final result.
+	if len(c.responses) >= cap(c.responses)-1 {
+		return fmt.Errorf("%w: request %s", ErrChunkBacklogFull, requestID)
+	}
+	c.responses <- resp
+	c.nextSeq++
+	return nil
+}
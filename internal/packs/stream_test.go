@@ -0,0 +1,133 @@
+// ABOUTME: Tests for streaming tool results: in-order chunk delivery, out-of-order rejection,
+// ABOUTME: backlog limits, and the stall timeout between chunks.
+
+package packs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func chunkResponse(requestID string, seq uint64, output string) *pb.ExecuteToolResponse {
+	return &pb.ExecuteToolResponse{
+		RequestId: requestID,
+		Result:    &pb.ExecuteToolResponse_Chunk{Chunk: &pb.ToolResultChunk{Sequence: seq, Output: output}},
+	}
+}
+
+func TestRouterStreamsChunks(t *testing.T) {
+	registry, router := setupRouterTest(t)
+	pack := registerTestPack(t, registry, "search-pack", createTestTool("search", "Searches"))
+
+	go func() {
+		req := <-pack.Channel
+		for i, out := range []string{"a", "b"} {
+			if err := router.HandleToolResponse(chunkResponse(req.RequestId, uint64(i+1), out)); err != nil {
+				t.Errorf("chunk %d: %v", i+1, err)
+			}
+		}
+		_ = router.HandleToolResponse(&pb.ExecuteToolResponse{
+			RequestId: req.RequestId,
+			Result:    &pb.ExecuteToolResponse_OutputJson{OutputJson: `{"matches":2}`},
+		})
+	}()
+
+	var got []Chunk
+	resp, err := router.RouteToolCallStream(context.Background(), "search", `{}`, "req-1", "agent-1", func(c Chunk) { got = append(got, c) })
+	if err != nil {
+		t.Fatalf("RouteToolCallStream: %v", err)
+	}
+	if resp.GetOutputJson() != `{"matches":2}` {
+		t.Errorf("final output = %q", resp.GetOutputJson())
+	}
+	want := []Chunk{{Sequence: 1, Output: "a"}, {Sequence: 2, Output: "b"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("chunks = %+v, want %+v", got, want)
+	}
+}
+
+func TestRouterRejectsOutOfOrderChunks(t *testing.T) {
+	registry, router := setupRouterTest(t)
+	pack := registerTestPack(t, registry, "search-pack", createTestTool("search", "Searches"))
+
+	errs := make(chan error, 3)
+	go func() {
+		req := <-pack.Channel
+		errs <- router.HandleToolResponse(chunkResponse(req.RequestId, 2, "skipped ahead"))
+		errs <- router.HandleToolResponse(chunkResponse(req.RequestId, 1, "first"))
+		errs <- router.HandleToolResponse(chunkResponse(req.RequestId, 1, "repeated"))
+		_ = router.HandleToolResponse(&pb.ExecuteToolResponse{
+			RequestId: req.RequestId,
+			Result:    &pb.ExecuteToolResponse_OutputJson{OutputJson: `{}`},
+		})
+	}()
+
+	var got []Chunk
+	if _, err := router.RouteToolCallStream(context.Background(), "search", `{}`, "req-1", "agent-1", func(c Chunk) { got = append(got, c) }); err != nil {
+		t.Fatalf("RouteToolCallStream: %v", err)
+	}
+	if err := <-errs; !errors.Is(err, ErrChunkOutOfOrder) {
+		t.Errorf("chunk 2 before 1 = %v, want ErrChunkOutOfOrder", err)
+	}
+	if err := <-errs; err != nil {
+		t.Errorf("chunk 1 = %v, want accepted", err)
+	}
+	if err := <-errs; !errors.Is(err, ErrChunkOutOfOrder) {
+		t.Errorf("repeated chunk 1 = %v, want ErrChunkOutOfOrder", err)
+	}
+	if len(got) != 1 || got[0].Output != "first" {
+		t.Errorf("chunks = %+v, want only the in-order one", got)
+	}
+}
+
+func TestRouterChunkBacklog(t *testing.T) {
+	call := newPendingCall()
+	var err error
+	seq := uint64(1)
+	for ; err == nil; seq++ {
+		err = call.acceptChunk("req-1", &pb.ToolResultChunk{Sequence: seq}, chunkResponse("req-1", seq, ""))
+	}
+	if !errors.Is(err, ErrChunkBacklogFull) {
+		t.Fatalf("err = %v, want ErrChunkBacklogFull", err)
+	}
+	if len(call.responses) != chunkBacklog-1 {
+		t.Errorf("buffered %d chunks, want room left for the final result", len(call.responses))
+	}
+	// The refused chunk did not advance the sequence, so it can be resent.
+	if call.nextSeq != seq-1 {
+		t.Errorf("nextSeq = %d, want %d", call.nextSeq, seq-1)
+	}
+}
+
+func TestRouterStalledStream(t *testing.T) {
+	registry := NewRegistry(slog.Default())
+	router := NewRouter(RouterConfig{
+		Registry:     registry,
+		Logger:       slog.Default(),
+		Timeout:      time.Second,
+		StallTimeout: 50 * time.Millisecond,
+	})
+	pack := registerTestPack(t, registry, "search-pack", createTestTool("search", "Searches"))
+
+	go func() {
+		req := <-pack.Channel
+		_ = router.HandleToolResponse(chunkResponse(req.RequestId, 1, "then nothing"))
+	}()
+
+	start := time.Now()
+	_, err := router.RouteToolCallStream(context.Background(), "search", `{}`, "req-1", "agent-1", nil)
+	if !errors.Is(err, ErrToolStalled) {
+		t.Fatalf("err = %v, want ErrToolStalled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("gave up after %v, want about the stall timeout", elapsed)
+	}
+	if n := router.PendingCount(); n != 0 {
+		t.Errorf("PendingCount = %d after stall, want 0", n)
+	}
+}
@@ -0,0 +1,170 @@
+// ABOUTME: Keyed token-bucket rate limiter with a bounded number of tracked keys.
+// ABOUTME: Idle buckets are evicted once they would have refilled, so memory stays proportional to active keys.
+
+package ratelimit
+
+import (
+	"container/list"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxKeys bounds the buckets a Limiter tracks when MaxKeys is unset.
+const DefaultMaxKeys = 10_000
+
+// Rate is a bucket's size and refill: up to Tokens requests in a burst,
+// refilled evenly over Per.
+type Rate struct {
+	Tokens int
+	Per    time.Duration
+}
+
+// String formats the rate the way ParseRate reads it.
+func (r Rate) String() string {
+	switch r.Per {
+	case time.Second:
+		return strconv.Itoa(r.Tokens) + "/sec"
+	case time.Minute:
+		return strconv.Itoa(r.Tokens) + "/min"
+	case time.Hour:
+		return strconv.Itoa(r.Tokens) + "/hour"
+	}
+	return strconv.Itoa(r.Tokens) + "/" + r.Per.String()
+}
+
+// ParseRate parses a rate such as "10/min", "5/s", "1000/hour" or "30/10s".
+func ParseRate(s string) (Rate, error) {
+	count, per, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("rate %q must be <count>/<period>", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n <= 0 {
+		return Rate{}, fmt.Errorf("rate %q must have a positive count", s)
+	}
+	var d time.Duration
+	switch strings.TrimSpace(per) {
+	case "s", "sec", "second":
+		d = time.Second
+	case "m", "min", "minute":
+		d = time.Minute
+	case "h", "hour":
+		d = time.Hour
+	default:
+		d, err = time.ParseDuration(strings.TrimSpace(per))
+		if err != nil || d <= 0 {
+			return Rate{}, fmt.Errorf("rate %q has an unknown period", s)
+		}
+	}
+	return Rate{Tokens: n, Per: d}, nil
+}
+
+// bucket is one key's token bucket, as of last.
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+	elem   *list.Element
+}
+
+// Limiter applies one Rate independently to each key.
+type Limiter struct {
+	rate     Rate
+	perToken time.Duration
+	maxKeys  int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	order   *list.List // buckets by last use, least recent at front
+}
+
+// New creates a Limiter for rate tracking at most maxKeys buckets (0 for
+// DefaultMaxKeys). When full, the least recently used bucket is dropped,
+// which only ever errs toward letting that key through.
+func New(rate Rate, maxKeys int) *Limiter {
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+	return &Limiter{
+		rate:     rate,
+		perToken: rate.Per / time.Duration(rate.Tokens),
+		maxKeys:  maxKeys,
+		now:      time.Now,
+		buckets:  make(map[string]*bucket),
+		order:    list.New(),
+	}
+}
+
+// Rate returns the limiter's rate.
+func (l *Limiter) Rate() Rate {
+	return l.rate
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it
+// returns false and how long until a token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.evictIdleLocked(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= l.maxKeys {
+			l.removeLocked(l.order.Front())
+		}
+		b = &bucket{key: key, tokens: float64(l.rate.Tokens), last: now}
+		b.elem = l.order.PushBack(b)
+		l.buckets[key] = b
+	} else {
+		l.refillLocked(b, now)
+		l.order.MoveToBack(b.elem)
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) * float64(l.perToken))
+}
+
+// Len returns the number of keys being tracked.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// refillLocked credits b with the tokens earned since it was last used.
+func (l *Limiter) refillLocked(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(float64(l.rate.Tokens), b.tokens+float64(elapsed)/float64(l.perToken))
+		b.last = now
+	}
+}
+
+// evictIdleLocked drops buckets unused for a full period. They would have
+// refilled by now, so forgetting them is the same as keeping them.
+func (l *Limiter) evictIdleLocked(now time.Time) {
+	for e := l.order.Front(); e != nil; e = l.order.Front() {
+		b, _ := e.Value.(*bucket)
+		if now.Sub(b.last) < l.rate.Per {
+			return
+		}
+		l.removeLocked(e)
+	}
+}
+
+func (l *Limiter) removeLocked(e *list.Element) {
+	if e == nil {
+		return
+	}
+	b, _ := e.Value.(*bucket)
+	l.order.Remove(e)
+	delete(l.buckets, b.key)
+}
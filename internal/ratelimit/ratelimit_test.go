@@ -0,0 +1,135 @@
+// ABOUTME: Tests for the keyed token-bucket limiter: parsing, bursts, refill,
+// ABOUTME: independent keys, and bounded memory through idle and LRU eviction.
+
+package ratelimit
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for deterministic tests.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(t *testing.T, rate string, maxKeys int) (*Limiter, *fakeClock) {
+	t.Helper()
+	r, err := ParseRate(rate)
+	if err != nil {
+		t.Fatalf("ParseRate(%q): %v", rate, err)
+	}
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	l := New(r, maxKeys)
+	l.now = clock.now
+	return l, clock
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rate
+	}{
+		{"10/min", Rate{10, time.Minute}},
+		{"5/s", Rate{5, time.Second}},
+		{" 120 / minute ", Rate{120, time.Minute}},
+		{"1000/hour", Rate{1000, time.Hour}},
+		{"30/10s", Rate{30, 10 * time.Second}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRate(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "10", "0/min", "-1/min", "x/min", "10/fortnight", "10/-1s"} {
+		if _, err := ParseRate(bad); err == nil {
+			t.Errorf("ParseRate(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestLimiterBurstThenRefill(t *testing.T) {
+	l, clock := newTestLimiter(t, "3/min", 0)
+
+	for i := range 3 {
+		if ok, _ := l.Allow("alice"); !ok {
+			t.Fatalf("request %d of the burst was refused", i+1)
+		}
+	}
+	ok, retry := l.Allow("alice")
+	if ok {
+		t.Fatal("request past the burst was allowed")
+	}
+	if retry != 20*time.Second {
+		t.Errorf("retry after = %v, want 20s (one token's refill)", retry)
+	}
+
+	clock.advance(10 * time.Second)
+	if ok, retry := l.Allow("alice"); ok || retry != 10*time.Second {
+		t.Errorf("half a token in: ok=%v retry=%v, want refused with 10s left", ok, retry)
+	}
+	clock.advance(10 * time.Second)
+	if ok, _ := l.Allow("alice"); !ok {
+		t.Error("request after one token refilled was refused")
+	}
+	if ok, _ := l.Allow("alice"); ok {
+		t.Error("second request after one token refilled was allowed")
+	}
+}
+
+func TestLimiterKeysAreIndependent(t *testing.T) {
+	l, _ := newTestLimiter(t, "2/min", 0)
+
+	for range 2 {
+		l.Allow("alice")
+	}
+	if ok, _ := l.Allow("alice"); ok {
+		t.Fatal("alice was allowed past her burst")
+	}
+	for i := range 2 {
+		if ok, _ := l.Allow("bob"); !ok {
+			t.Errorf("bob's request %d was refused because of alice", i+1)
+		}
+	}
+}
+
+func TestLimiterEvictsIdleKeys(t *testing.T) {
+	l, clock := newTestLimiter(t, "2/min", 0)
+
+	l.Allow("alice")
+	l.Allow("alice")
+	clock.advance(30 * time.Second)
+	l.Allow("bob")
+	if n := l.Len(); n != 2 {
+		t.Fatalf("Len = %d, want 2", n)
+	}
+
+	clock.advance(30 * time.Second)
+	l.Allow("carol")
+	if n := l.Len(); n != 2 {
+		t.Errorf("Len = %d after alice idled a full period, want 2 (bob, carol)", n)
+	}
+	// Alice comes back with a full bucket, as if she had been tracked.
+	for i := range 2 {
+		if ok, _ := l.Allow("alice"); !ok {
+			t.Errorf("alice's request %d after eviction was refused", i+1)
+		}
+	}
+}
+
+func TestLimiterBoundsKeys(t *testing.T) {
+	l, _ := newTestLimiter(t, "1/hour", 3)
+
+	for i := range 10 {
+		l.Allow("key-" + strconv.Itoa(i))
+	}
+	if n := l.Len(); n != 3 {
+		t.Errorf("Len = %d, want the 3 most recent keys", n)
+	}
+	if ok, _ := l.Allow("key-9"); ok {
+		t.Error("recently used key-9 lost its bucket")
+	}
+}
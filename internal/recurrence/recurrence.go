@@ -0,0 +1,213 @@
+// ABOUTME: Cron-ish recurrence rules for scheduled messages: five-field cron expressions,
+// ABOUTME: @hourly/@daily/@weekly/@monthly/@yearly shorthands, and @every <duration>.
+
+package recurrence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinEvery is the shortest interval @every accepts, so a typo can't make a
+// schedule message an agent every few seconds.
+const MinEvery = time.Minute
+
+// searchYears bounds how far Next looks ahead for a matching time, which
+// only matters for expressions like "0 0 31 2 *" that never match.
+const searchYears = 5
+
+// Rule produces the times a recurring schedule runs.
+type Rule interface {
+	// Next returns the first run time strictly after t, or the zero time if
+	// the rule never matches again.
+	Next(t time.Time) time.Time
+}
+
+var shorthands = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse reads a recurrence rule. Accepted forms:
+//
+//   - a cron expression: minute hour day-of-month month day-of-week, each a
+//     "*", number, range "a-b", or list "a,b", optionally with a "/step";
+//     day-of-week runs 0-7 with both 0 and 7 meaning Sunday
+//   - @yearly, @annually, @monthly, @weekly, @daily, @midnight, @hourly
+//   - @every <duration>, such as "@every 90m", at least MinEvery
+//
+// Cron fields are matched in the location of the time passed to Next.
+func Parse(spec string) (Rule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil {
+			return nil, fmt.Errorf("recurrence %q: %w", spec, err)
+		}
+		if interval < MinEvery {
+			return nil, fmt.Errorf("recurrence %q: interval must be at least %s", spec, MinEvery)
+		}
+		return every(interval), nil
+	}
+	if strings.HasPrefix(spec, "@") {
+		expr, ok := shorthands[spec]
+		if !ok {
+			return nil, fmt.Errorf("recurrence %q: unknown shorthand", spec)
+		}
+		spec = expr
+	}
+	return parseCron(spec)
+}
+
+// every runs at a fixed interval after the previous run.
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// cron matches a five-field cron expression. Each field is a bitset of the
+// values it allows.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	// A restricted day-of-month and day-of-week match when either does, as
+	// in standard cron; otherwise both must.
+	domAny, dowAny bool
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day-of-month", 1, 31},
+	{"month", 1, 12},
+	{"day-of-week", 0, 7},
+}
+
+func parseCron(spec string) (*cron, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("recurrence %q: want 5 fields (minute hour day-of-month month day-of-week), got %d", spec, len(parts))
+	}
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("recurrence %q: %w", spec, err)
+		}
+		sets[i] = set
+	}
+	c := &cron{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: strings.HasPrefix(parts[2], "*"),
+		dowAny: strings.HasPrefix(parts[4], "*"),
+	}
+	// Sunday is both 0 and 7.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+// parseField reads a comma-separated list of "*", "n", or "a-b" items, each
+// with an optional "/step".
+func parseField(s string, f field) (uint64, error) {
+	var set uint64
+	for item := range strings.SplitSeq(s, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s step %q must be a positive number", f.name, stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = fieldValue(a, f); err != nil {
+				return 0, err
+			}
+			if hi, err = fieldValue(b, f); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("%s range %q is backwards", f.name, rangePart)
+			}
+		default:
+			n, err := fieldValue(rangePart, f)
+			if err != nil {
+				return 0, err
+			}
+			lo = n
+			if !hasStep {
+				hi = n
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func fieldValue(s string, f field) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s %q must be a number from %d to %d", f.name, s, f.min, f.max)
+	}
+	return n, nil
+}
+
+// Next walks forward from the minute after t, skipping a whole month, day,
+// or hour at a time when that unit can't match.
+func (c *cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.Year() + searchYears
+
+	for t.Year() <= limit {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
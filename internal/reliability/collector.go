@@ -0,0 +1,74 @@
+// ABOUTME: Prometheus collector exporting the tracker's reliability gauges.
+// ABOUTME: Values are computed at scrape time from the current windows.
+
+package reliability
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	successRateDesc = prometheus.NewDesc(
+		"coven_reliability_success_rate",
+		"Success rate over the most recent outcomes in the window.",
+		[]string{"kind", "name"}, nil,
+	)
+	samplesDesc = prometheus.NewDesc(
+		"coven_reliability_samples",
+		"Number of outcomes currently in the window.",
+		[]string{"kind", "name"}, nil,
+	)
+	failureStreakDesc = prometheus.NewDesc(
+		"coven_reliability_failure_streak",
+		"Consecutive failures ending at the most recent outcome.",
+		[]string{"kind", "name"}, nil,
+	)
+	sinceSuccessDesc = prometheus.NewDesc(
+		"coven_reliability_seconds_since_success",
+		"Seconds since the last successful outcome; -1 if there has never been one.",
+		[]string{"kind", "name"}, nil,
+	)
+	belowThresholdDesc = prometheus.NewDesc(
+		"coven_reliability_below_threshold",
+		"1 if the success rate is below the configured threshold with enough samples, else 0.",
+		[]string{"kind", "name"}, nil,
+	)
+	thresholdDesc = prometheus.NewDesc(
+		"coven_reliability_threshold",
+		"Configured success-rate threshold used for below_threshold.",
+		nil, nil,
+	)
+)
+
+// Describe implements prometheus.Collector.
+func (t *Tracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- successRateDesc
+	ch <- samplesDesc
+	ch <- failureStreakDesc
+	ch <- sinceSuccessDesc
+	ch <- belowThresholdDesc
+	ch <- thresholdDesc
+}
+
+// Collect implements prometheus.Collector.
+func (t *Tracker) Collect(ch chan<- prometheus.Metric) {
+	now := t.now()
+	ch <- prometheus.MustNewConstMetric(thresholdDesc, prometheus.GaugeValue, t.cfg.Threshold)
+
+	for _, s := range t.Stats("", 0) {
+		kind, name := string(s.Kind), s.Name
+		since := -1.0
+		if d := s.SinceLastSuccess(now); d >= 0 {
+			since = d.Seconds()
+		}
+		below := 0.0
+		if s.BelowThreshold {
+			below = 1
+		}
+		ch <- prometheus.MustNewConstMetric(successRateDesc, prometheus.GaugeValue, s.SuccessRate, kind, name)
+		ch <- prometheus.MustNewConstMetric(samplesDesc, prometheus.GaugeValue, float64(s.Samples), kind, name)
+		ch <- prometheus.MustNewConstMetric(failureStreakDesc, prometheus.GaugeValue, float64(s.FailureStreak), kind, name)
+		ch <- prometheus.MustNewConstMetric(sinceSuccessDesc, prometheus.GaugeValue, since, kind, name)
+		ch <- prometheus.MustNewConstMetric(belowThresholdDesc, prometheus.GaugeValue, below, kind, name)
+	}
+}
@@ -0,0 +1,29 @@
+// Package reliability tracks per-tool and per-agent success rates for error
+// budgets and alerting.
+//
+// # Overview
+//
+// The Tracker keeps a fixed-size ring buffer of recent outcomes for every
+// tool and agent it hears about. From the buffer it derives:
+//
+//   - success rate over the newest N outcomes (N up to the window size)
+//   - the current consecutive-failure streak
+//   - time since the last success
+//   - whether the rate is below the configured threshold
+//
+// Outcomes arrive from packs.Router (tool calls) and agent.Manager (agent
+// requests). Tool calls rejected before reaching a pack, and requests
+// abandoned by the caller, are not counted against the tool or agent.
+//
+// # Memory bounds
+//
+// Each entity costs one ring buffer of WindowSize booleans. The number of
+// entities is capped at MaxEntities; when a new entity arrives at the cap,
+// the least recently updated one is evicted.
+//
+// # Export
+//
+// Tracker implements prometheus.Collector, exposing coven_reliability_*
+// gauges labeled by kind and name. The web admin serves the same data as
+// JSON at GET /api/admin/reliability.
+package reliability
@@ -0,0 +1,241 @@
+// ABOUTME: Sliding-window reliability signals per tool and per agent.
+// ABOUTME: Tracks success rates, failure streaks, and time since last success with bounded memory.
+
+package reliability
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kind distinguishes the entities the tracker follows.
+type Kind string
+
+const (
+	KindTool  Kind = "tool"
+	KindAgent Kind = "agent"
+)
+
+// Defaults applied when Config fields are zero.
+const (
+	DefaultWindowSize  = 100
+	DefaultMinSamples  = 5
+	DefaultThreshold   = 0.95
+	DefaultMaxEntities = 1000
+)
+
+// Config controls window sizes, alert thresholds, and memory bounds.
+type Config struct {
+	// WindowSize is the number of most recent outcomes kept per entity.
+	WindowSize int
+	// MinSamples is how many outcomes an entity needs before it can be
+	// flagged, so a single early failure doesn't raise an alarm.
+	MinSamples int
+	// Threshold is the success rate below which an entity is flagged.
+	Threshold float64
+	// MaxEntities caps tracked entities across all kinds; the least recently
+	// updated entity is evicted to make room for a new one.
+	MaxEntities int
+}
+
+// Stat is a point-in-time reliability summary for one entity.
+type Stat struct {
+	Kind           Kind      `json:"kind"`
+	Name           string    `json:"name"`
+	Samples        int       `json:"samples"`
+	SuccessRate    float64   `json:"success_rate"`
+	FailureStreak  int       `json:"failure_streak"`
+	LastSuccess    time.Time `json:"last_success,omitzero"`
+	LastOutcome    time.Time `json:"last_outcome"`
+	BelowThreshold bool      `json:"below_threshold"`
+}
+
+// SinceLastSuccess returns how long ago the entity last succeeded, or -1 if it
+// never has.
+func (s Stat) SinceLastSuccess(now time.Time) time.Duration {
+	if s.LastSuccess.IsZero() {
+		return -1
+	}
+	return now.Sub(s.LastSuccess)
+}
+
+type entityKey struct {
+	kind Kind
+	name string
+}
+
+// entity holds one ring buffer of outcomes.
+type entity struct {
+	key         entityKey
+	outcomes    []bool // ring buffer, len == WindowSize
+	next        int    // index of the next write
+	count       int    // filled slots, up to len(outcomes)
+	streak      int    // consecutive failures ending at the newest outcome
+	lastSuccess time.Time
+	lastOutcome time.Time
+	elem        *list.Element
+}
+
+// rate returns the success rate over the newest n outcomes.
+func (e *entity) rate(n int) (float64, int) {
+	if n <= 0 || n > e.count {
+		n = e.count
+	}
+	if n == 0 {
+		return 1, 0
+	}
+	ok := 0
+	size := len(e.outcomes)
+	for i := 1; i <= n; i++ {
+		if e.outcomes[(e.next-i+size)%size] {
+			ok++
+		}
+	}
+	return float64(ok) / float64(n), n
+}
+
+// Tracker records outcomes and computes reliability signals. It is safe for
+// concurrent use.
+type Tracker struct {
+	cfg Config
+	now func() time.Time
+
+	mu       sync.Mutex
+	entities map[entityKey]*entity
+	lru      *list.List // front is most recently updated
+}
+
+// New creates a Tracker, filling zero Config fields with defaults.
+func New(cfg Config) *Tracker {
+	if cfg.WindowSize <= 0 {
+		cfg.WindowSize = DefaultWindowSize
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = DefaultMinSamples
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = DefaultThreshold
+	}
+	if cfg.MaxEntities <= 0 {
+		cfg.MaxEntities = DefaultMaxEntities
+	}
+	return &Tracker{
+		cfg:      cfg,
+		now:      time.Now,
+		entities: make(map[entityKey]*entity),
+		lru:      list.New(),
+	}
+}
+
+// Config returns the effective configuration.
+func (t *Tracker) Config() Config {
+	return t.cfg
+}
+
+// RecordTool records the outcome of a tool call.
+func (t *Tracker) RecordTool(name string, ok bool) {
+	t.record(entityKey{KindTool, name}, ok)
+}
+
+// RecordAgent records the outcome of a request handled by an agent.
+func (t *Tracker) RecordAgent(id string, ok bool) {
+	t.record(entityKey{KindAgent, id}, ok)
+}
+
+func (t *Tracker) record(key entityKey, ok bool) {
+	if key.name == "" {
+		return
+	}
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, exists := t.entities[key]
+	if !exists {
+		if len(t.entities) >= t.cfg.MaxEntities {
+			t.evictOldest()
+		}
+		e = &entity{key: key, outcomes: make([]bool, t.cfg.WindowSize)}
+		e.elem = t.lru.PushFront(e)
+		t.entities[key] = e
+	} else {
+		t.lru.MoveToFront(e.elem)
+	}
+
+	e.outcomes[e.next] = ok
+	e.next = (e.next + 1) % len(e.outcomes)
+	if e.count < len(e.outcomes) {
+		e.count++
+	}
+	e.lastOutcome = now
+	if ok {
+		e.streak = 0
+		e.lastSuccess = now
+	} else {
+		e.streak++
+	}
+}
+
+// evictOldest drops the least recently updated entity. Caller holds t.mu.
+func (t *Tracker) evictOldest() {
+	oldest := t.lru.Back()
+	if oldest == nil {
+		return
+	}
+	e := oldest.Value.(*entity)
+	t.lru.Remove(oldest)
+	delete(t.entities, e.key)
+}
+
+// Stats returns summaries for every tracked entity of the given kind (or all
+// kinds when kind is empty), computed over the newest window outcomes. A
+// window of 0 or larger than WindowSize uses the full window. Results are
+// sorted by kind, then name.
+func (t *Tracker) Stats(kind Kind, window int) []Stat {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := make([]Stat, 0, len(t.entities))
+	for key, e := range t.entities {
+		if kind != "" && key.kind != kind {
+			continue
+		}
+		stats = append(stats, t.statLocked(e, window))
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Kind != stats[j].Kind {
+			return stats[i].Kind < stats[j].Kind
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// Stat returns the summary for one entity over the full window.
+func (t *Tracker) Stat(kind Kind, name string) (Stat, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entities[entityKey{kind, name}]
+	if !ok {
+		return Stat{}, false
+	}
+	return t.statLocked(e, 0), true
+}
+
+func (t *Tracker) statLocked(e *entity, window int) Stat {
+	rate, n := e.rate(window)
+	return Stat{
+		Kind:           e.key.kind,
+		Name:           e.key.name,
+		Samples:        n,
+		SuccessRate:    rate,
+		FailureStreak:  e.streak,
+		LastSuccess:    e.lastSuccess,
+		LastOutcome:    e.lastOutcome,
+		BelowThreshold: n >= t.cfg.MinSamples && rate < t.cfg.Threshold,
+	}
+}
@@ -0,0 +1,161 @@
+// ABOUTME: Tests for the reliability tracker and its Prometheus collector.
+// ABOUTME: Drives synthetic outcome sequences and asserts rates, streaks, and eviction.
+
+package reliability
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeClock returns a controllable time source.
+func fakeClock(tr *Tracker) *time.Time {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr.now = func() time.Time { return now }
+	return &now
+}
+
+func recordSeq(tr *Tracker, name, seq string) {
+	for _, c := range seq {
+		tr.RecordTool(name, c == '+')
+	}
+}
+
+func TestTracker_RateAndStreak(t *testing.T) {
+	tr := New(Config{WindowSize: 10, MinSamples: 4, Threshold: 0.8})
+	recordSeq(tr, "search", "++++-+--")
+
+	s, ok := tr.Stat(KindTool, "search")
+	if !ok {
+		t.Fatal("search not tracked")
+	}
+	if s.Samples != 8 || s.SuccessRate != 5.0/8 {
+		t.Errorf("samples/rate = %d/%v, want 8/0.625", s.Samples, s.SuccessRate)
+	}
+	if s.FailureStreak != 2 {
+		t.Errorf("streak = %d, want 2", s.FailureStreak)
+	}
+	if !s.BelowThreshold {
+		t.Error("0.625 should be below threshold 0.8")
+	}
+}
+
+func TestTracker_ThresholdTransitions(t *testing.T) {
+	tr := New(Config{WindowSize: 5, MinSamples: 3, Threshold: 0.6})
+
+	below := func() bool {
+		s, _ := tr.Stat(KindTool, "fetch")
+		return s.BelowThreshold
+	}
+
+	recordSeq(tr, "fetch", "--")
+	if below() {
+		t.Error("flagged before MinSamples reached")
+	}
+	recordSeq(tr, "fetch", "-")
+	if !below() {
+		t.Error("0/3 not flagged")
+	}
+	// The 5-outcome window slides the early failures out as successes arrive.
+	recordSeq(tr, "fetch", "++")
+	if !below() {
+		t.Error("2/5 not flagged")
+	}
+	recordSeq(tr, "fetch", "+")
+	if below() {
+		t.Error("3/5 = 0.6 flagged, want recovered at threshold")
+	}
+	recordSeq(tr, "fetch", "++")
+	s, _ := tr.Stat(KindTool, "fetch")
+	if s.SuccessRate != 1 || s.Samples != 5 || s.FailureStreak != 0 {
+		t.Errorf("after full recovery = %+v, want rate 1 over 5 samples", s)
+	}
+}
+
+func TestTracker_WindowParameter(t *testing.T) {
+	tr := New(Config{WindowSize: 10})
+	recordSeq(tr, "t", "++++++----")
+
+	for _, tc := range []struct {
+		window  int
+		samples int
+		rate    float64
+	}{
+		{0, 10, 0.6},
+		{4, 4, 0},
+		{5, 5, 0.2},
+		{50, 10, 0.6},
+	} {
+		stats := tr.Stats(KindTool, tc.window)
+		if len(stats) != 1 {
+			t.Fatalf("stats = %d, want 1", len(stats))
+		}
+		if stats[0].Samples != tc.samples || stats[0].SuccessRate != tc.rate {
+			t.Errorf("window %d: %d samples rate %v, want %d rate %v",
+				tc.window, stats[0].Samples, stats[0].SuccessRate, tc.samples, tc.rate)
+		}
+	}
+}
+
+func TestTracker_SinceLastSuccess(t *testing.T) {
+	tr := New(Config{})
+	now := fakeClock(tr)
+
+	tr.RecordAgent("a1", false)
+	s, _ := tr.Stat(KindAgent, "a1")
+	if d := s.SinceLastSuccess(*now); d != -1 {
+		t.Errorf("never succeeded: since = %v, want -1", d)
+	}
+
+	tr.RecordAgent("a1", true)
+	*now = now.Add(90 * time.Second)
+	tr.RecordAgent("a1", false)
+	s, _ = tr.Stat(KindAgent, "a1")
+	if d := s.SinceLastSuccess(*now); d != 90*time.Second {
+		t.Errorf("since = %v, want 90s", d)
+	}
+}
+
+func TestTracker_EvictsLeastRecentlyUpdated(t *testing.T) {
+	tr := New(Config{MaxEntities: 2})
+	tr.RecordTool("a", true)
+	tr.RecordTool("b", true)
+	tr.RecordTool("a", true) // a is now most recent
+	tr.RecordAgent("c", true)
+
+	if _, ok := tr.Stat(KindTool, "b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if _, ok := tr.Stat(KindTool, "a"); !ok {
+		t.Error("a evicted despite recent update")
+	}
+	if got := len(tr.Stats("", 0)); got != 2 {
+		t.Errorf("tracked = %d, want 2", got)
+	}
+}
+
+func TestTracker_Collector(t *testing.T) {
+	tr := New(Config{WindowSize: 4, MinSamples: 2, Threshold: 0.9})
+	fakeClock(tr)
+	recordSeq(tr, "search", "+--")
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(tr)
+
+	expected := `
+# HELP coven_reliability_failure_streak Consecutive failures ending at the most recent outcome.
+# TYPE coven_reliability_failure_streak gauge
+coven_reliability_failure_streak{kind="tool",name="search"} 2
+# HELP coven_reliability_below_threshold 1 if the success rate is below the configured threshold with enough samples, else 0.
+# TYPE coven_reliability_below_threshold gauge
+coven_reliability_below_threshold{kind="tool",name="search"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"coven_reliability_failure_streak", "coven_reliability_below_threshold"); err != nil {
+		t.Error(err)
+	}
+}
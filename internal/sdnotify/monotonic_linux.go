@@ -0,0 +1,16 @@
+// ABOUTME: CLOCK_MONOTONIC reading for the MONOTONIC_USEC field sent with RELOADING=1.
+// ABOUTME: systemd compares it against its own monotonic clock, so wall time will not do.
+
+//go:build linux
+
+package sdnotify
+
+import "golang.org/x/sys/unix"
+
+func monotonicUsec() int64 {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return 0
+	}
+	return ts.Nano() / 1000
+}
@@ -0,0 +1,8 @@
+// ABOUTME: MONOTONIC_USEC stub for platforms without systemd.
+// ABOUTME: Returning zero makes Reloading omit the field.
+
+//go:build !linux
+
+package sdnotify
+
+func monotonicUsec() int64 { return 0 }
@@ -0,0 +1,119 @@
+// ABOUTME: Pure-Go sd_notify client that reports service state to systemd over NOTIFY_SOCKET.
+// ABOUTME: A nil or unconfigured Notifier is a no-op, so callers never need to check.
+
+package sdnotify
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Well-known states understood by systemd.
+const (
+	StateReady     = "READY=1"
+	StateReloading = "RELOADING=1"
+	StateStopping  = "STOPPING=1"
+	StateWatchdog  = "WATCHDOG=1"
+)
+
+// Notifier sends state updates to the socket systemd passed in
+// NOTIFY_SOCKET. Methods on a nil Notifier do nothing.
+type Notifier struct {
+	addr *net.UnixAddr
+}
+
+// FromEnv returns a Notifier for NOTIFY_SOCKET, or nil when the variable is
+// unset (the process is not running under systemd with Type=notify).
+func FromEnv() *Notifier {
+	return New(os.Getenv("NOTIFY_SOCKET"))
+}
+
+// New returns a Notifier for the given socket path, or nil if path is empty.
+// A leading "@" selects the Linux abstract namespace.
+func New(path string) *Notifier {
+	if path == "" {
+		return nil
+	}
+	if path[0] == '@' {
+		path = "\x00" + path[1:]
+	}
+	return &Notifier{addr: &net.UnixAddr{Name: path, Net: "unixgram"}}
+}
+
+// Notify sends one datagram containing the given newline-joined states.
+func (n *Notifier) Notify(states ...string) error {
+	if n == nil {
+		return nil
+	}
+	conn, err := net.DialUnix("unixgram", nil, n.addr)
+	if err != nil {
+		return fmt.Errorf("dialing notify socket: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(strings.Join(states, "\n"))); err != nil {
+		return fmt.Errorf("writing notify socket: %w", err)
+	}
+	return nil
+}
+
+// Ready reports that startup has finished, optionally with a status line.
+func (n *Notifier) Ready(status string) error {
+	return n.Notify(withStatus(StateReady, status)...)
+}
+
+// Reloading reports that configuration is being reloaded. Send Ready when
+// the reload has finished. systemd 253+ also expects MONOTONIC_USEC.
+func (n *Notifier) Reloading() error {
+	if usec := monotonicUsec(); usec > 0 {
+		return n.Notify(StateReloading, "MONOTONIC_USEC="+strconv.FormatInt(usec, 10))
+	}
+	return n.Notify(StateReloading)
+}
+
+// Stopping reports that shutdown has begun.
+func (n *Notifier) Stopping() error {
+	return n.Notify(StateStopping)
+}
+
+// Watchdog pings the service watchdog.
+func (n *Notifier) Watchdog() error {
+	return n.Notify(StateWatchdog)
+}
+
+// Status sets the free-form status line shown by systemctl status.
+func (n *Notifier) Status(status string) error {
+	return n.Notify("STATUS=" + status)
+}
+
+func withStatus(state, status string) []string {
+	if status == "" {
+		return []string{state}
+	}
+	return []string{state, "STATUS=" + status}
+}
+
+// WatchdogInterval returns the watchdog timeout systemd configured through
+// WATCHDOG_USEC, or zero when the watchdog is disabled or meant for another
+// process (WATCHDOG_PID).
+func WatchdogInterval() (time.Duration, error) {
+	raw := os.Getenv("WATCHDOG_USEC")
+	if raw == "" {
+		return 0, nil
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+	usec, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing WATCHDOG_USEC %q: %w", raw, err)
+	}
+	if usec <= 0 {
+		return 0, errors.New("WATCHDOG_USEC must be positive")
+	}
+	return time.Duration(usec) * time.Microsecond, nil
+}
@@ -0,0 +1,123 @@
+// ABOUTME: Tests for the sd_notify client against a fake notify socket.
+// ABOUTME: Covers message framing, the nil no-op, and WATCHDOG_USEC parsing.
+
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listen opens a fake NOTIFY_SOCKET and returns a function that reads the
+// next datagram.
+func listen(t *testing.T) (string, func() string) {
+	t.Helper()
+	// Unix socket paths are limited to ~100 bytes; t.TempDir can exceed that
+	dir, err := os.MkdirTemp("", "sdn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return path, func() string {
+		t.Helper()
+		buf := make([]byte, 4096)
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("reading notify socket: %v", err)
+		}
+		return string(buf[:n])
+	}
+}
+
+func TestNotifier_Sequence(t *testing.T) {
+	path, next := listen(t)
+	n := New(path)
+
+	if err := n.Ready("2 agents connected"); err != nil {
+		t.Fatal(err)
+	}
+	if got := next(); got != "READY=1\nSTATUS=2 agents connected" {
+		t.Errorf("ready = %q", got)
+	}
+
+	if err := n.Reloading(); err != nil {
+		t.Fatal(err)
+	}
+	if got := next(); !strings.HasPrefix(got, "RELOADING=1") {
+		t.Errorf("reloading = %q", got)
+	}
+
+	for _, tc := range []struct {
+		send func() error
+		want string
+	}{
+		{func() error { return n.Ready("") }, "READY=1"},
+		{n.Watchdog, "WATCHDOG=1"},
+		{func() error { return n.Status("draining") }, "STATUS=draining"},
+		{n.Stopping, "STOPPING=1"},
+	} {
+		if err := tc.send(); err != nil {
+			t.Fatal(err)
+		}
+		if got := next(); got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+}
+
+func TestNotifier_NilIsNoop(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	n := FromEnv()
+	if n != nil {
+		t.Fatal("FromEnv without NOTIFY_SOCKET should return nil")
+	}
+	if err := n.Ready("x"); err != nil {
+		t.Errorf("nil Ready = %v", err)
+	}
+	if err := n.Stopping(); err != nil {
+		t.Errorf("nil Stopping = %v", err)
+	}
+}
+
+func TestNotifier_MissingSocket(t *testing.T) {
+	n := New(filepath.Join(t.TempDir(), "absent.sock"))
+	if err := n.Ready(""); err == nil {
+		t.Error("Ready to a missing socket succeeded, want error")
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_PID", "")
+	t.Setenv("WATCHDOG_USEC", "")
+	if d, err := WatchdogInterval(); d != 0 || err != nil {
+		t.Errorf("unset = %v, %v", d, err)
+	}
+
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	if d, err := WatchdogInterval(); d != 30*time.Second || err != nil {
+		t.Errorf("30s = %v, %v", d, err)
+	}
+
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+	if d, _ := WatchdogInterval(); d != 0 {
+		t.Errorf("other pid = %v, want 0", d)
+	}
+
+	t.Setenv("WATCHDOG_PID", "")
+	t.Setenv("WATCHDOG_USEC", "soon")
+	if _, err := WatchdogInterval(); err == nil {
+		t.Error("invalid WATCHDOG_USEC accepted")
+	}
+}
@@ -0,0 +1,157 @@
+// ABOUTME: Slack bridge: relays Slack messages to agents through the gateway and streams replies back.
+// ABOUTME: Channels reach agents through the gateway's slack bindings; Slack redeliveries are deduplicated.
+
+package slackbridge
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/client"
+	"github.com/2389/coven-gateway/internal/dedupe"
+)
+
+// Frontend is the frontend name the bridge sends under; bindings for Slack
+// channels use it.
+const Frontend = "slack"
+
+// Slack redelivers events it thinks were missed for a few minutes; seen
+// messages are remembered a while longer.
+const (
+	dedupeTTL     = 15 * time.Minute
+	dedupeMaxSize = 10_000
+)
+
+// Bridge connects a Slack workspace to a coven gateway.
+type Bridge struct {
+	cfg     *Config
+	slack   *slackAPI
+	gateway *gatewayConn
+	seen    *dedupe.Cache
+	logger  *slog.Logger
+
+	botUserID string // set by Run
+	wg        sync.WaitGroup
+}
+
+// New creates a bridge for cfg.
+func New(cfg *Config, logger *slog.Logger) *Bridge {
+	return &Bridge{
+		cfg: cfg,
+		slack: &slackAPI{
+			http:     &http.Client{Timeout: 30 * time.Second},
+			baseURL:  defaultSlackAPI,
+			appToken: cfg.Slack.AppToken,
+			botToken: cfg.Slack.BotToken,
+		},
+		gateway: newGatewayConn(cfg.wsURL(), cfg.Gateway.Token, logger.With("component", "gateway")),
+		seen:    dedupe.New(dedupeTTL, dedupeMaxSize),
+		logger:  logger,
+	}
+}
+
+// Run relays messages until ctx is done, then waits for replies in progress
+// to finish.
+func (b *Bridge) Run(ctx context.Context) error {
+	defer b.seen.Close()
+	defer b.gateway.close()
+
+	id, err := b.slack.authTest(ctx)
+	if err != nil {
+		return fmt.Errorf("checking slack bot token: %w", err)
+	}
+	b.botUserID = id
+	b.logger.Info("slack bridge starting", "bot_user_id", id, "gateway", b.cfg.Gateway.URL)
+
+	b.slack.runSocketMode(ctx, b.logger, func(ev *messageEvent) { b.handle(ctx, ev) })
+	b.wg.Wait()
+	return nil
+}
+
+// handle relays ev to its channel's agent if it is a new message from a
+// person that the bridge should answer.
+func (b *Bridge) handle(ctx context.Context, ev *messageEvent) {
+	// Edits, deletions, joins and the like carry a subtype; bots include
+	// this bridge's own replies.
+	if (ev.Subtype != "" && ev.Subtype != "thread_broadcast") || ev.BotID != "" || ev.User == "" || ev.User == b.botUserID {
+		return
+	}
+	if !b.cfg.channelAllowed(ev.Channel) {
+		b.logger.Debug("ignoring message from channel not in allowed_channels", "channel", ev.Channel)
+		return
+	}
+	content, ok := b.content(ev)
+	if !ok {
+		return
+	}
+	// A mention arrives as both a message and an app_mention event, and
+	// Slack retries events it thinks were missed; all share the ts.
+	if b.seen.CheckAndMark("slack:" + ev.Channel + ":" + ev.TS) {
+		b.logger.Debug("duplicate slack message ignored", "channel", ev.Channel, "ts", ev.TS)
+		return
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.relay(ctx, ev, content)
+	}()
+}
+
+// content returns the text to send for ev, without mentions of the bot or
+// the command prefix. It reports false when the message is not for the
+// bridge: with a command_prefix set, channel messages must start with it
+// unless they mention the bot.
+func (b *Bridge) content(ev *messageEvent) (string, bool) {
+	text := ev.Text
+	mention := "<@" + b.botUserID + ">"
+	addressed := ev.ChannelType == "im" || strings.Contains(text, mention)
+	text = strings.TrimSpace(strings.ReplaceAll(text, mention, ""))
+
+	if prefix := b.cfg.Bridge.CommandPrefix; prefix != "" {
+		stripped, found := strings.CutPrefix(text, prefix)
+		if !found && !addressed {
+			return "", false
+		}
+		text = strings.TrimSpace(stripped)
+	}
+	return text, text != ""
+}
+
+// relay sends content to the gateway and streams the response into a reply
+// in the message's thread.
+func (b *Bridge) relay(ctx context.Context, ev *messageEvent, content string) {
+	threadTS := ev.ThreadTS
+	if threadTS == "" {
+		threadTS = ev.TS
+	}
+	r := &reply{
+		slack:    b.slack,
+		channel:  ev.Channel,
+		threadTS: threadTS,
+		interval: b.cfg.editInterval(),
+		logger:   b.logger,
+	}
+	r.flush(ctx)
+
+	frames, err := b.gateway.send(ctx, uuid.NewString(), &client.WSSendRequest{
+		Sender:    ev.User,
+		Content:   content,
+		Frontend:  Frontend,
+		ChannelID: ev.Channel,
+	})
+	if err != nil {
+		b.logger.Error("failed to send message to gateway", "channel", ev.Channel, "error", err)
+		r.fail("could not reach the gateway")
+		r.flush(ctx)
+		return
+	}
+	r.stream(ctx, frames)
+}
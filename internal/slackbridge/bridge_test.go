@@ -0,0 +1,381 @@
+// ABOUTME: End-to-end tests for the Slack bridge against a fake Slack API and a fake gateway WebSocket.
+// ABOUTME: Verifies threaded streaming replies, tool status lines, prefix handling and dedupe of redelivered events.
+
+package slackbridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/2389/coven-gateway/internal/client"
+)
+
+// fakeSlack serves the Web API methods the bridge calls and a Socket Mode
+// endpoint that delivers the envelopes queued on events.
+type fakeSlack struct {
+	srv    *httptest.Server
+	events chan map[string]any
+
+	mu    sync.Mutex
+	calls []slackCall
+	acks  []string
+	posts int
+}
+
+type slackCall struct {
+	method string
+	body   map[string]string
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack {
+	t.Helper()
+	f := &fakeSlack{events: make(chan map[string]any, 16)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/", f.serveAPI)
+	mux.HandleFunc("/socket", f.serveSocket)
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeSlack) serveAPI(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+	var body map[string]string
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	f.mu.Lock()
+	f.calls = append(f.calls, slackCall{method: method, body: body})
+	resp := map[string]any{"ok": true}
+	switch method {
+	case "auth.test":
+		resp["user_id"] = "UBOT"
+	case "apps.connections.open":
+		resp["url"] = "ws" + strings.TrimPrefix(f.srv.URL, "http") + "/socket"
+	case "chat.postMessage":
+		f.posts++
+		resp["ts"] = fmt.Sprintf("1700000100.%06d", f.posts)
+	}
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeSlack) serveSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.CloseNow() }()
+	ctx := r.Context()
+
+	go func() {
+		for {
+			var ack struct {
+				EnvelopeID string `json:"envelope_id"`
+			}
+			if err := wsjson.Read(ctx, conn, &ack); err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.acks = append(f.acks, ack.EnvelopeID)
+			f.mu.Unlock()
+		}
+	}()
+
+	if err := wsjson.Write(ctx, conn, map[string]any{"type": "hello"}); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case env := <-f.events:
+			if err := wsjson.Write(ctx, conn, env); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// message queues an events_api envelope carrying event.
+func (f *fakeSlack) message(envelopeID string, event map[string]any) {
+	f.events <- map[string]any{
+		"type":        "events_api",
+		"envelope_id": envelopeID,
+		"payload":     map[string]any{"event": event},
+	}
+}
+
+func (f *fakeSlack) snapshot() ([]slackCall, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]slackCall(nil), f.calls...), append([]string(nil), f.acks...)
+}
+
+// replyText returns the latest text of the reply message with ts.
+func (f *fakeSlack) replyText(ts string) string {
+	calls, _ := f.snapshot()
+	text := ""
+	posted := 0
+	for _, c := range calls {
+		switch c.method {
+		case "chat.postMessage":
+			posted++
+			if fmt.Sprintf("1700000100.%06d", posted) == ts {
+				text = c.body["text"]
+			}
+		case "chat.update":
+			if c.body["ts"] == ts {
+				text = c.body["text"]
+			}
+		}
+	}
+	return text
+}
+
+// fakeGateway accepts /api/v1/ws connections and answers each send with the
+// frames script returns for it.
+type fakeGateway struct {
+	srv    *httptest.Server
+	script func(req client.WSSendRequest) []client.WSFrame
+
+	mu    sync.Mutex
+	sends []client.WSSendRequest
+	auth  string
+}
+
+func newFakeGateway(t *testing.T, script func(req client.WSSendRequest) []client.WSFrame) *fakeGateway {
+	t.Helper()
+	g := &fakeGateway{script: script}
+	g.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/ws" {
+			http.NotFound(w, r)
+			return
+		}
+		g.mu.Lock()
+		g.auth = r.Header.Get("Authorization")
+		g.mu.Unlock()
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.CloseNow() }()
+		for {
+			var in client.WSFrame
+			if err := wsjson.Read(r.Context(), conn, &in); err != nil {
+				return
+			}
+			var req client.WSSendRequest
+			_ = json.Unmarshal(in.Data, &req)
+			g.mu.Lock()
+			g.sends = append(g.sends, req)
+			g.mu.Unlock()
+			for _, out := range g.script(req) {
+				if out.Type == "pause" {
+					time.Sleep(100 * time.Millisecond)
+					continue
+				}
+				out.Ref = in.Ref
+				if err := wsjson.Write(r.Context(), conn, out); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(g.srv.Close)
+	return g
+}
+
+func (g *fakeGateway) received() []client.WSSendRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]client.WSSendRequest(nil), g.sends...)
+}
+
+func event(typ string, data string) client.WSFrame {
+	return client.WSFrame{Type: typ, Data: json.RawMessage(data)}
+}
+
+func startBridge(t *testing.T, slack *fakeSlack, gateway *fakeGateway, bridge BridgeConfig) (cancel func() error) {
+	t.Helper()
+	bridge.EditInterval = 10 * time.Millisecond
+	cfg := &Config{
+		Slack:   SlackConfig{AppToken: "xapp-test", BotToken: "xoxb-test"},
+		Gateway: GatewayConfig{URL: gateway.srv.URL, Token: "gw-token"},
+		Bridge:  bridge,
+	}
+	require.NoError(t, cfg.Validate())
+
+	b := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.slack.baseURL = slack.srv.URL + "/api/"
+
+	ctx, stop := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- b.Run(ctx) }()
+	return func() error {
+		stop()
+		select {
+		case err := <-errc:
+			return err
+		case <-time.After(5 * time.Second):
+			return fmt.Errorf("bridge did not stop")
+		}
+	}
+}
+
+func TestBridge_StreamsThreadedReply(t *testing.T) {
+	slack := newFakeSlack(t)
+	gateway := newFakeGateway(t, func(req client.WSSendRequest) []client.WSFrame {
+		return []client.WSFrame{
+			event("started", `{"thread_id":"t1"}`),
+			event("text", `{"text":"Hello "}`),
+			event("tool_use", `{"id":"tool-1","name":"read_file","input_json":"{}"}`),
+			{Type: "pause"},
+			event("text", `{"text":"world"}`),
+			event("done", `{"full_response":"Hello world"}`),
+		}
+	})
+	stop := startBridge(t, slack, gateway, BridgeConfig{})
+
+	slack.message("env-1", map[string]any{
+		"type": "message", "channel": "C1", "channel_type": "channel",
+		"user": "U1", "text": "what's up?", "ts": "1700000000.000001",
+	})
+
+	const replyTS = "1700000100.000001"
+	require.Eventually(t, func() bool { return slack.replyText(replyTS) == "Hello world" },
+		5*time.Second, 10*time.Millisecond)
+	require.NoError(t, stop())
+
+	calls, acks := slack.snapshot()
+	assert.Contains(t, acks, "env-1")
+
+	var posts, texts []string
+	for _, c := range calls {
+		switch c.method {
+		case "chat.postMessage":
+			posts = append(posts, c.body["thread_ts"])
+			texts = append(texts, c.body["text"])
+		case "chat.update":
+			texts = append(texts, c.body["text"])
+		}
+	}
+	assert.Equal(t, []string{"1700000000.000001"}, posts, "one reply, threaded under the message")
+	assert.Equal(t, workingText, texts[0])
+	assert.Contains(t, texts, "Hello\n\n_:gear: Using `read_file`…_", "tool call shown as a status line while streaming")
+
+	sends := gateway.received()
+	require.Len(t, sends, 1)
+	assert.Equal(t, client.WSSendRequest{Sender: "U1", Content: "what's up?", Frontend: "slack", ChannelID: "C1"}, sends[0])
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+	assert.Equal(t, "Bearer gw-token", gateway.auth)
+}
+
+func TestBridge_FiltersAndDeduplicates(t *testing.T) {
+	slack := newFakeSlack(t)
+	gateway := newFakeGateway(t, func(req client.WSSendRequest) []client.WSFrame {
+		return []client.WSFrame{event("done", `{"full_response":"re: `+req.Content+`"}`)}
+	})
+	stop := startBridge(t, slack, gateway, BridgeConfig{
+		CommandPrefix:   "!coven",
+		AllowedChannels: []string{"C1", "D1"},
+	})
+
+	msg := func(channel, user, text, ts string, extra map[string]any) map[string]any {
+		ev := map[string]any{"type": "message", "channel": channel, "channel_type": "channel", "user": user, "text": text, "ts": ts}
+		for k, v := range extra {
+			ev[k] = v
+		}
+		return ev
+	}
+	slack.message("e1", msg("C1", "U1", "no prefix here", "1.1", nil))
+	slack.message("e2", msg("C2", "U1", "!coven wrong channel", "1.2", nil))
+	slack.message("e3", msg("C1", "", "!coven bot", "1.3", map[string]any{"bot_id": "B1"}))
+	slack.message("e4", msg("C1", "U1", "!coven edited", "1.4", map[string]any{"subtype": "message_changed"}))
+	slack.message("e5", msg("C1", "U1", "!coven first", "1.5", nil))
+	// Slack redelivers e5 under a new envelope.
+	slack.message("e6", msg("C1", "U1", "!coven first", "1.5", nil))
+	// A mention arrives as a message and as an app_mention with the same ts.
+	slack.message("e7", msg("C1", "U2", "<@UBOT> second", "1.6", map[string]any{"thread_ts": "1.0"}))
+	slack.message("e8", msg("C1", "U2", "<@UBOT> second", "1.6", map[string]any{"type": "app_mention", "thread_ts": "1.0"}))
+	slack.message("e9", msg("D1", "U3", "third", "1.7", map[string]any{"channel_type": "im"}))
+
+	answered := func() int {
+		calls, _ := slack.snapshot()
+		n := 0
+		for _, c := range calls {
+			if c.method == "chat.update" && strings.HasPrefix(c.body["text"], "re: ") {
+				n++
+			}
+		}
+		return n
+	}
+	require.Eventually(t, func() bool { return answered() == 3 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, stop())
+
+	var contents []string
+	for _, s := range gateway.received() {
+		contents = append(contents, s.Content)
+	}
+	assert.ElementsMatch(t, []string{"first", "second", "third"}, contents)
+
+	calls, acks := slack.snapshot()
+	assert.Equal(t, []string{"e1", "e2", "e3", "e4", "e5", "e6", "e7", "e8", "e9"}, acks)
+	threads := map[string]bool{}
+	for _, c := range calls {
+		if c.method == "chat.postMessage" {
+			threads[c.body["thread_ts"]] = true
+		}
+	}
+	assert.Equal(t, map[string]bool{"1.5": true, "1.0": true, "1.7": true}, threads, "replies stay in the message's thread")
+}
+
+func TestBridge_GatewayUnreachable(t *testing.T) {
+	slack := newFakeSlack(t)
+	gateway := newFakeGateway(t, nil)
+	gateway.srv.Close()
+	stop := startBridge(t, slack, gateway, BridgeConfig{})
+
+	slack.message("env-1", map[string]any{
+		"type": "message", "channel": "C1", "user": "U1", "text": "hi", "ts": "2.1",
+	})
+
+	const replyTS = "1700000100.000001"
+	require.Eventually(t, func() bool { return slack.replyText(replyTS) == ":warning: could not reach the gateway" },
+		5*time.Second, 10*time.Millisecond)
+	require.NoError(t, stop())
+}
+
+func TestBridge_BadBotToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"ok":false,"error":"invalid_auth"}`)
+	}))
+	defer srv.Close()
+
+	cfg := &Config{
+		Slack:   SlackConfig{AppToken: "xapp-test", BotToken: "xoxb-test"},
+		Gateway: GatewayConfig{URL: "http://127.0.0.1:1"},
+	}
+	b := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.slack.baseURL = srv.URL + "/"
+
+	err := b.Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_auth")
+}
@@ -0,0 +1,127 @@
+// ABOUTME: Configuration for the coven-slack bridge: Slack tokens, gateway URL and bridge behavior.
+// ABOUTME: Loaded from YAML with ${VAR} expansion, like the gateway's own config.
+
+package slackbridge
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/2389/coven-gateway/internal/client"
+)
+
+// defaultEditInterval is the least time between edits of a streaming reply
+// when bridge.edit_interval is unset. Slack allows about one chat.update per
+// second per channel.
+const defaultEditInterval = time.Second
+
+// Config is the coven-slack configuration file.
+type Config struct {
+	Slack   SlackConfig   `yaml:"slack"`
+	Gateway GatewayConfig `yaml:"gateway"`
+	Bridge  BridgeConfig  `yaml:"bridge"`
+}
+
+// SlackConfig holds the Slack app's tokens. The app needs Socket Mode
+// enabled, the message.channels, message.groups, message.im and
+// app_mention events, and the chat:write scope.
+type SlackConfig struct {
+	AppToken string `yaml:"app_token"` // xapp-..., opens Socket Mode connections
+	BotToken string `yaml:"bot_token"` // xoxb-..., posts and edits replies
+}
+
+// GatewayConfig locates the coven gateway.
+type GatewayConfig struct {
+	URL   string `yaml:"url"`   // HTTP base URL, e.g. http://localhost:8080
+	Token string `yaml:"token"` // API token, if the gateway requires auth
+}
+
+// BridgeConfig controls which messages are bridged and how replies look.
+type BridgeConfig struct {
+	// AllowedChannels limits the bridge to these channel IDs; empty allows
+	// every channel the app is in.
+	AllowedChannels []string `yaml:"allowed_channels"`
+	// CommandPrefix, if set, is required at the start of a message for it
+	// to reach an agent, and is stripped first. Direct messages and
+	// mentions of the bot need no prefix.
+	CommandPrefix string `yaml:"command_prefix"`
+
+	EditInterval    time.Duration `yaml:"-"`
+	EditIntervalRaw string        `yaml:"edit_interval"` // least time between reply edits (default 1s)
+}
+
+// envVarPattern matches ${VAR_NAME} references.
+var envVarPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// LoadConfig reads the configuration file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	expanded := envVarPattern.ReplaceAllStringFunc(string(data), func(match string) string {
+		return os.Getenv(envVarPattern.FindStringSubmatch(match)[1])
+	})
+
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(expanded), &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file: %w", err)
+	}
+	if cfg.Bridge.EditIntervalRaw != "" {
+		d, err := time.ParseDuration(cfg.Bridge.EditIntervalRaw)
+		if err != nil {
+			return nil, fmt.Errorf("bridge.edit_interval: %w", err)
+		}
+		cfg.Bridge.EditInterval = d
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("validating config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Validate checks that the tokens and gateway URL are present and plausible.
+func (c *Config) Validate() error {
+	if !strings.HasPrefix(c.Slack.AppToken, "xapp-") {
+		return errors.New("slack.app_token must be an app-level token (xapp-...)")
+	}
+	if !strings.HasPrefix(c.Slack.BotToken, "xoxb-") {
+		return errors.New("slack.bot_token must be a bot token (xoxb-...)")
+	}
+	if c.Gateway.URL == "" {
+		return errors.New("gateway.url is required")
+	}
+	if !strings.HasPrefix(c.Gateway.URL, "http://") && !strings.HasPrefix(c.Gateway.URL, "https://") {
+		return fmt.Errorf("gateway.url must be an http or https URL, got %q", c.Gateway.URL)
+	}
+	if c.Bridge.EditInterval < 0 {
+		return errors.New("bridge.edit_interval must not be negative")
+	}
+	return nil
+}
+
+// channelAllowed reports whether messages in channel may be bridged.
+func (c *Config) channelAllowed(channel string) bool {
+	return len(c.Bridge.AllowedChannels) == 0 || slices.Contains(c.Bridge.AllowedChannels, channel)
+}
+
+// editInterval returns bridge.edit_interval or its default.
+func (c *Config) editInterval() time.Duration {
+	if c.Bridge.EditInterval > 0 {
+		return c.Bridge.EditInterval
+	}
+	return defaultEditInterval
+}
+
+// wsURL returns the gateway's /api/v1/ws endpoint.
+func (c *Config) wsURL() string {
+	return client.WSURL(c.Gateway.URL)
+}
@@ -0,0 +1,113 @@
+// ABOUTME: Tests for loading and validating the coven-slack configuration.
+// ABOUTME: Covers env expansion, edit_interval parsing, token checks and the derived gateway WebSocket URL.
+
+package slackbridge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "slack-bridge.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TEST_SLACK_BOT_TOKEN", "xoxb-from-env")
+	path := writeConfig(t, `
+slack:
+  app_token: xapp-1-abc
+  bot_token: ${TEST_SLACK_BOT_TOKEN}
+gateway:
+  url: https://coven.example.com/
+  token: secret
+bridge:
+  allowed_channels: [C1, C2]
+  command_prefix: "!coven"
+  edit_interval: 2s
+`)
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, "xoxb-from-env", cfg.Slack.BotToken)
+	assert.Equal(t, "!coven", cfg.Bridge.CommandPrefix)
+	assert.Equal(t, 2*time.Second, cfg.editInterval())
+	assert.Equal(t, "wss://coven.example.com/api/v1/ws", cfg.wsURL())
+	assert.True(t, cfg.channelAllowed("C2"))
+	assert.False(t, cfg.channelAllowed("C3"))
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	path := writeConfig(t, `
+slack:
+  app_token: xapp-1-abc
+  bot_token: xoxb-abc
+gateway:
+  url: http://localhost:8080
+`)
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, defaultEditInterval, cfg.editInterval())
+	assert.Equal(t, "ws://localhost:8080/api/v1/ws", cfg.wsURL())
+	assert.True(t, cfg.channelAllowed("anything"))
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "bad yaml",
+			content: "slack: [",
+			wantErr: "parsing config file",
+		},
+		{
+			name:    "bot token in app token",
+			content: "slack: {app_token: xoxb-a, bot_token: xoxb-b}\ngateway: {url: http://x}",
+			wantErr: "slack.app_token",
+		},
+		{
+			name:    "missing bot token",
+			content: "slack: {app_token: xapp-a}\ngateway: {url: http://x}",
+			wantErr: "slack.bot_token",
+		},
+		{
+			name:    "missing gateway url",
+			content: "slack: {app_token: xapp-a, bot_token: xoxb-b}",
+			wantErr: "gateway.url is required",
+		},
+		{
+			name:    "gateway url without scheme",
+			content: "slack: {app_token: xapp-a, bot_token: xoxb-b}\ngateway: {url: localhost:8080}",
+			wantErr: "gateway.url must be an http or https URL",
+		},
+		{
+			name:    "bad edit interval",
+			content: "slack: {app_token: xapp-a, bot_token: xoxb-b}\ngateway: {url: http://x}\nbridge: {edit_interval: soon}",
+			wantErr: "bridge.edit_interval",
+		},
+		{
+			name:    "negative edit interval",
+			content: "slack: {app_token: xapp-a, bot_token: xoxb-b}\ngateway: {url: http://x}\nbridge: {edit_interval: -1s}",
+			wantErr: "must not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+// Package slackbridge relays messages between Slack and coven agents for
+// the coven-slack command.
+//
+// # Overview
+//
+// The bridge receives Slack events over Socket Mode, so it needs no public
+// URL. Each message from a person is sent to the gateway's /api/v1/ws endpoint
+// with frontend "slack" and the Slack channel ID as channel_id, so the
+// gateway picks the agent from its bindings:
+//
+//	coven-admin bindings create --frontend slack --channel C0123ABCD --agent <agent-id>
+//
+// # Replies
+//
+// The agent's response is posted as a reply in the message's thread and
+// edited as text arrives, at most once per bridge.edit_interval. While the
+// agent is thinking or calling tools, a status line under the text says so.
+// Replies longer than one Slack message continue in further messages.
+//
+// # Which Messages Are Bridged
+//
+//   - Messages from bots, including the bridge itself, are ignored, as are
+//     edits, deletions and other message subtypes.
+//   - With bridge.allowed_channels set, other channels are ignored.
+//   - With bridge.command_prefix set, channel messages must start with the
+//     prefix, which is stripped. Direct messages and mentions of the bot
+//     need no prefix.
+//
+// Slack redelivers events it believes were missed, and a mention arrives
+// both as a message and as an app_mention event; both are deduplicated by
+// channel and message ts with a dedupe.Cache.
+package slackbridge
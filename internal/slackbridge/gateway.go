@@ -0,0 +1,134 @@
+// ABOUTME: The bridge's connection to the gateway's /api/v1/ws endpoint, shared by every relayed message.
+// ABOUTME: Frames are routed to each send by its ref; the connection is redialed on the next send after a drop.
+
+package slackbridge
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/2389/coven-gateway/internal/client"
+)
+
+// gatewayConn multiplexes sends over one WebSocket to the gateway.
+type gatewayConn struct {
+	url    string
+	token  string
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	ws      *client.WSClient
+	streams map[string]*gatewayStream // by ref
+}
+
+// gatewayStream is one send's frames, closed after its last frame.
+type gatewayStream struct {
+	ws     *client.WSClient
+	frames chan *client.WSFrame
+}
+
+func newGatewayConn(url, token string, logger *slog.Logger) *gatewayConn {
+	return &gatewayConn{url: url, token: token, logger: logger, streams: make(map[string]*gatewayStream)}
+}
+
+// isFinal reports whether a frame of this type ends its send.
+func isFinal(frameType string) bool {
+	switch frameType {
+	case "done", "error", "canceled", client.FrameRejected:
+		return true
+	}
+	return false
+}
+
+// send starts req under ref and returns the channel its frames arrive on.
+// The channel is closed after a done, error, canceled or rejected frame, or
+// early if the connection drops.
+func (g *gatewayConn) send(ctx context.Context, ref string, req *client.WSSendRequest) (<-chan *client.WSFrame, error) {
+	g.mu.Lock()
+	ws := g.ws
+	if ws == nil {
+		var err error
+		if ws, err = client.DialWS(ctx, g.url, g.token); err != nil {
+			g.mu.Unlock()
+			return nil, err
+		}
+		g.ws = ws
+		go g.read(ws)
+	}
+	stream := &gatewayStream{ws: ws, frames: make(chan *client.WSFrame, 16)}
+	g.streams[ref] = stream
+	g.mu.Unlock()
+
+	if err := ws.Send(ctx, ref, req); err != nil {
+		g.mu.Lock()
+		delete(g.streams, ref)
+		g.mu.Unlock()
+		close(stream.frames)
+		// Closing makes read notice, drop the connection and end the other
+		// sends on it.
+		_ = ws.Close()
+		return nil, err
+	}
+	return stream.frames, nil
+}
+
+// read routes ws's frames to their streams until the connection fails,
+// then ends every stream still open on it.
+func (g *gatewayConn) read(ws *client.WSClient) {
+	for {
+		frame, err := ws.Next(context.Background())
+		if err != nil {
+			g.drop(ws, err)
+			return
+		}
+		g.mu.Lock()
+		stream := g.streams[frame.Ref]
+		if stream != nil && isFinal(frame.Type) {
+			delete(g.streams, frame.Ref)
+		}
+		g.mu.Unlock()
+		if stream == nil {
+			continue
+		}
+		stream.frames <- frame
+		if isFinal(frame.Type) {
+			close(stream.frames)
+		}
+	}
+}
+
+// drop forgets ws and closes the streams that were waiting on it.
+func (g *gatewayConn) drop(ws *client.WSClient, err error) {
+	g.mu.Lock()
+	if g.ws == ws {
+		g.ws = nil
+	}
+	var lost []*gatewayStream
+	for ref, stream := range g.streams {
+		if stream.ws == ws {
+			lost = append(lost, stream)
+			delete(g.streams, ref)
+		}
+	}
+	g.mu.Unlock()
+
+	if len(lost) > 0 {
+		g.logger.Warn("gateway connection lost", "error", err, "sends_lost", len(lost))
+	} else {
+		g.logger.Debug("gateway connection closed", "error", err)
+	}
+	for _, stream := range lost {
+		close(stream.frames)
+	}
+}
+
+// close closes the current connection, if any.
+func (g *gatewayConn) close() {
+	g.mu.Lock()
+	ws := g.ws
+	g.mu.Unlock()
+	if ws != nil {
+		_ = ws.Close()
+	}
+}
@@ -0,0 +1,241 @@
+// ABOUTME: Streams one agent response into a threaded Slack reply, editing it as text arrives.
+// ABOUTME: Edits are throttled; tool calls show as a status line, and long replies continue in new messages.
+
+package slackbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/client"
+)
+
+// maxMessageRunes is where a reply moves on to a new message. Slack
+// truncates message text at 40,000 characters.
+const maxMessageRunes = 39_000
+
+// Placeholder and status text shown while a response streams.
+const (
+	workingText  = "_:hourglass_flowing_sand: Working…_"
+	thinkingText = "_:thought_balloon: Thinking…_"
+	emptyText    = "_(no response)_"
+)
+
+// reply is one agent response as Slack messages in a thread. It is driven
+// by a single goroutine.
+type reply struct {
+	slack    *slackAPI
+	channel  string
+	threadTS string
+	interval time.Duration
+	logger   *slog.Logger
+
+	text   strings.Builder
+	status string   // status line under the text while streaming
+	tools  int      // tool calls so far
+	notice string   // error or cancellation shown after the text
+	done   bool     // no more events will come
+	posts  []posted // the messages the reply occupies, in order
+
+	lastEdit time.Time
+}
+
+// posted is one message of a reply and the text it last showed.
+type posted struct {
+	ts   string
+	text string
+}
+
+// apply updates the reply with a gateway frame and reports whether the
+// response is over.
+func (r *reply) apply(frame *client.WSFrame) bool {
+	var data map[string]any
+	_ = json.Unmarshal(frame.Data, &data)
+	str := func(key string) string {
+		s, _ := data[key].(string)
+		return s
+	}
+
+	switch frame.Type {
+	case "thinking":
+		if r.status == "" {
+			r.status = thinkingText
+		}
+	case "text":
+		r.text.WriteString(str("text"))
+		r.status = ""
+	case "tool_use":
+		r.tools++
+		r.status = toolStatus(str("name"), r.tools)
+	case "done":
+		if full := str("full_response"); full != "" {
+			r.text.Reset()
+			r.text.WriteString(full)
+		}
+		r.done = true
+	case "error":
+		r.notice = ":warning: " + firstNonEmpty(str("message"), str("error"), "the agent reported an error")
+		r.done = true
+	case "canceled":
+		r.notice = ":no_entry_sign: Canceled" + suffix(str("reason"))
+		r.done = true
+	case client.FrameRejected:
+		r.notice = ":warning: " + firstNonEmpty(frame.Error, "the gateway refused the message")
+		r.done = true
+	}
+	if r.done {
+		r.status = ""
+	}
+	return r.done
+}
+
+// fail ends the reply with notice, for failures outside the response.
+func (r *reply) fail(notice string) {
+	r.notice = ":warning: " + notice
+	r.status = ""
+	r.done = true
+}
+
+// toolStatus is the status line for the nth tool call, to name.
+func toolStatus(name string, n int) string {
+	if name == "" {
+		name = "a tool"
+	} else {
+		name = "`" + name + "`"
+	}
+	if n == 1 {
+		return "_:gear: Using " + name + "…_"
+	}
+	return fmt.Sprintf("_:gear: Using %s… (%d tools so far)_", name, n)
+}
+
+// render returns the reply's full text as it should look now.
+func (r *reply) render() string {
+	var parts []string
+	if text := strings.TrimSpace(r.text.String()); text != "" {
+		parts = append(parts, text)
+	}
+	if r.status != "" {
+		parts = append(parts, r.status)
+	}
+	if r.notice != "" {
+		parts = append(parts, r.notice)
+	}
+	if len(parts) == 0 {
+		if r.done {
+			return emptyText
+		}
+		return workingText
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// stream applies frames until the response is over, editing the reply at
+// most once per interval, then makes the final edit.
+func (r *reply) stream(ctx context.Context, frames <-chan *client.WSFrame) {
+	timer := time.NewTimer(0)
+	<-timer.C
+	armed := false
+	defer timer.Stop()
+
+	for !r.done {
+		select {
+		case <-ctx.Done():
+			return
+		case frame, ok := <-frames:
+			if !ok {
+				r.fail("lost the connection to the gateway before the response finished")
+				break
+			}
+			if r.apply(frame) || armed {
+				continue
+			}
+			if wait := r.interval - time.Since(r.lastEdit); wait > 0 {
+				timer.Reset(wait)
+				armed = true
+				continue
+			}
+			r.flush(ctx)
+		case <-timer.C:
+			armed = false
+			r.flush(ctx)
+		}
+	}
+	r.flush(ctx)
+}
+
+// flush brings the reply's messages up to date with render, posting new
+// messages for text past the first message's limit. A rate-limited edit is
+// left for the next flush.
+func (r *reply) flush(ctx context.Context) {
+	r.lastEdit = time.Now()
+	for i, part := range splitMessage(r.render(), maxMessageRunes) {
+		if i < len(r.posts) {
+			if r.posts[i].text == part {
+				continue
+			}
+			if err := r.slack.updateMessage(ctx, r.channel, r.posts[i].ts, part); err != nil {
+				r.logFailure("failed to edit slack reply", err)
+				return
+			}
+			r.posts[i].text = part
+			continue
+		}
+		ts, err := r.slack.postMessage(ctx, r.channel, r.threadTS, part)
+		if err != nil {
+			r.logFailure("failed to post slack reply", err)
+			return
+		}
+		r.posts = append(r.posts, posted{ts: ts, text: part})
+	}
+}
+
+func (r *reply) logFailure(msg string, err error) {
+	var limited *RateLimitedError
+	if errors.As(err, &limited) && !r.done {
+		r.logger.Debug(msg, "channel", r.channel, "error", err)
+		return
+	}
+	r.logger.Warn(msg, "channel", r.channel, "error", err)
+}
+
+// splitMessage cuts text into pieces of at most limit runes, preferring to
+// break at a newline in the second half of a piece.
+func splitMessage(text string, limit int) []string {
+	var parts []string
+	runes := []rune(text)
+	for len(runes) > limit {
+		cut := limit
+		for i := limit - 1; i > limit/2; i-- {
+			if runes[i] == '\n' {
+				cut = i + 1
+				break
+			}
+		}
+		parts = append(parts, strings.TrimRight(string(runes[:cut]), "\n"))
+		runes = runes[cut:]
+	}
+	return append(parts, string(runes))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// suffix returns ": s", or nothing when s is empty.
+func suffix(s string) string {
+	if s == "" {
+		return ""
+	}
+	return ": " + s
+}
@@ -0,0 +1,110 @@
+// ABOUTME: Tests for rendering streamed agent responses as Slack reply text.
+// ABOUTME: Covers the tool status line, terminal notices and splitting long replies across messages.
+
+package slackbridge
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2389/coven-gateway/internal/client"
+)
+
+func frame(t *testing.T, typ string, data map[string]any) *client.WSFrame {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &client.WSFrame{Type: typ, Data: raw}
+}
+
+func TestReply_Render(t *testing.T) {
+	r := &reply{}
+	assert.Equal(t, workingText, r.render())
+
+	assert.False(t, r.apply(frame(t, "thinking", map[string]any{"text": "hmm"})))
+	assert.Equal(t, thinkingText, r.render())
+
+	r.apply(frame(t, "text", map[string]any{"text": "Let me check. "}))
+	assert.Equal(t, "Let me check.", r.render())
+
+	r.apply(frame(t, "tool_use", map[string]any{"id": "t1", "name": "read_file"}))
+	assert.Equal(t, "Let me check.\n\n_:gear: Using `read_file`…_", r.render())
+
+	r.apply(frame(t, "tool_use", map[string]any{"id": "t2", "name": "grep"}))
+	assert.Contains(t, r.render(), "Using `grep`… (2 tools so far)")
+
+	r.apply(frame(t, "text", map[string]any{"text": "Found it."}))
+	assert.Equal(t, "Let me check. Found it.", r.render())
+
+	assert.True(t, r.apply(frame(t, "done", map[string]any{"full_response": "The answer is 42."})))
+	assert.Equal(t, "The answer is 42.", r.render())
+}
+
+func TestReply_RenderEndings(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame *client.WSFrame
+		want  string
+	}{
+		{
+			name:  "done without text",
+			frame: frame(t, "done", map[string]any{}),
+			want:  emptyText,
+		},
+		{
+			name:  "error",
+			frame: frame(t, "error", map[string]any{"error": "boom", "message": "agent went away"}),
+			want:  ":warning: agent went away",
+		},
+		{
+			name:  "canceled",
+			frame: frame(t, "canceled", map[string]any{"reason": "timeout"}),
+			want:  ":no_entry_sign: Canceled: timeout",
+		},
+		{
+			name:  "rejected",
+			frame: &client.WSFrame{Type: client.FrameRejected, Error: "no agent bound to this channel"},
+			want:  ":warning: no agent bound to this channel",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &reply{}
+			r.apply(frame(t, "tool_use", map[string]any{"name": "bash"}))
+			assert.True(t, r.apply(tt.frame))
+			assert.Equal(t, tt.want, r.render())
+		})
+	}
+}
+
+func TestReply_ErrorKeepsPartialText(t *testing.T) {
+	r := &reply{}
+	r.apply(frame(t, "text", map[string]any{"text": "Half an answer"}))
+	r.apply(frame(t, "error", map[string]any{"error": "agent disconnected"}))
+	assert.Equal(t, "Half an answer\n\n:warning: agent disconnected", r.render())
+}
+
+func TestToolStatus(t *testing.T) {
+	assert.Equal(t, "_:gear: Using a tool…_", toolStatus("", 1))
+	assert.Equal(t, "_:gear: Using `bash`… (3 tools so far)_", toolStatus("bash", 3))
+}
+
+func TestSplitMessage(t *testing.T) {
+	assert.Equal(t, []string{"short"}, splitMessage("short", 10))
+
+	// Breaks after the last newline in the second half of the limit.
+	assert.Equal(t, []string{"aaaa\nbbb", "cc"}, splitMessage("aaaa\nbbb\ncc", 10))
+
+	// Without a usable newline, cuts at the limit.
+	assert.Equal(t, []string{"aaaaaaaaaa", "aaaaa"}, splitMessage(strings.Repeat("a", 15), 10))
+
+	// Counts runes, not bytes.
+	parts := splitMessage(strings.Repeat("é", 25), 10)
+	assert.Len(t, parts, 3)
+	assert.Equal(t, strings.Repeat("é", 10), parts[0])
+}
@@ -0,0 +1,226 @@
+// ABOUTME: Minimal Slack client: the Web API methods the bridge calls and a Socket Mode event loop.
+// ABOUTME: Socket Mode envelopes are acknowledged as soon as they arrive so Slack does not redeliver them.
+
+package slackbridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// defaultSlackAPI is the Slack Web API base URL.
+const defaultSlackAPI = "https://slack.com/api/"
+
+// socketRetryDelay is the wait before reopening a Socket Mode connection
+// that failed.
+const socketRetryDelay = 5 * time.Second
+
+// RateLimitedError is returned when Slack answers a call with 429.
+type RateLimitedError struct {
+	Method     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("slack %s: rate limited, retry after %s", e.Method, e.RetryAfter)
+}
+
+// slackAPI calls Slack Web API methods.
+type slackAPI struct {
+	http     *http.Client
+	baseURL  string // ends in "/"
+	appToken string
+	botToken string
+}
+
+// call POSTs body as JSON to method with token and decodes the reply into
+// out. Replies with "ok": false become errors naming Slack's error code.
+func (s *slackAPI) call(ctx context.Context, method, token string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encoding %s request: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+method, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("creating %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack %s: %w", method, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return &RateLimitedError{Method: method, RetryAfter: time.Duration(max(seconds, 1)) * time.Second}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack %s: %s", method, resp.Status)
+	}
+
+	raw, err := decodeJSON(resp)
+	if err != nil {
+		return fmt.Errorf("decoding %s response: %w", method, err)
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return fmt.Errorf("decoding %s response: %w", method, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("slack %s: %s", method, status.Error)
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return fmt.Errorf("decoding %s response: %w", method, err)
+		}
+	}
+	return nil
+}
+
+func decodeJSON(resp *http.Response) (json.RawMessage, error) {
+	var raw json.RawMessage
+	err := json.NewDecoder(resp.Body).Decode(&raw)
+	return raw, err
+}
+
+// authTest returns the bot's own user ID.
+func (s *slackAPI) authTest(ctx context.Context) (string, error) {
+	var out struct {
+		UserID string `json:"user_id"`
+	}
+	if err := s.call(ctx, "auth.test", s.botToken, struct{}{}, &out); err != nil {
+		return "", err
+	}
+	return out.UserID, nil
+}
+
+// postMessage posts text to channel, as a reply in threadTS when it is set,
+// and returns the new message's ts.
+func (s *slackAPI) postMessage(ctx context.Context, channel, threadTS, text string) (string, error) {
+	var out struct {
+		TS string `json:"ts"`
+	}
+	body := map[string]string{"channel": channel, "text": text}
+	if threadTS != "" {
+		body["thread_ts"] = threadTS
+	}
+	if err := s.call(ctx, "chat.postMessage", s.botToken, body, &out); err != nil {
+		return "", err
+	}
+	return out.TS, nil
+}
+
+// updateMessage replaces the text of the message at ts.
+func (s *slackAPI) updateMessage(ctx context.Context, channel, ts, text string) error {
+	return s.call(ctx, "chat.update", s.botToken, map[string]string{"channel": channel, "ts": ts, "text": text}, nil)
+}
+
+// openConnection returns a Socket Mode WebSocket URL.
+func (s *slackAPI) openConnection(ctx context.Context) (string, error) {
+	var out struct {
+		URL string `json:"url"`
+	}
+	if err := s.call(ctx, "apps.connections.open", s.appToken, struct{}{}, &out); err != nil {
+		return "", err
+	}
+	return out.URL, nil
+}
+
+// messageEvent is a Slack message or app_mention event.
+type messageEvent struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type"`
+	User        string `json:"user"`
+	BotID       string `json:"bot_id"`
+	Text        string `json:"text"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+}
+
+// socketEnvelope is one Socket Mode message from Slack.
+type socketEnvelope struct {
+	Type       string `json:"type"` // hello, events_api, disconnect, ...
+	EnvelopeID string `json:"envelope_id"`
+	Payload    struct {
+		Event messageEvent `json:"event"`
+	} `json:"payload"`
+	Reason string `json:"reason"` // disconnect only
+}
+
+// runSocketMode delivers message events to handle until ctx is done,
+// reopening the connection whenever Slack asks or it fails.
+func (s *slackAPI) runSocketMode(ctx context.Context, logger *slog.Logger, handle func(*messageEvent)) {
+	for ctx.Err() == nil {
+		err := s.socketSession(ctx, logger, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Warn("slack socket mode connection failed, retrying", "error", err, "retry_in", socketRetryDelay)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(socketRetryDelay):
+			}
+		}
+	}
+}
+
+// socketSession runs one Socket Mode connection. It returns nil when Slack
+// asks for a new connection, and an error when the connection fails.
+func (s *slackAPI) socketSession(ctx context.Context, logger *slog.Logger, handle func(*messageEvent)) error {
+	url, err := s.openConnection(ctx)
+	if err != nil {
+		return err
+	}
+	conn, resp, err := websocket.Dial(ctx, url, nil)
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("dialing socket mode: %w", err)
+	}
+	defer func() { _ = conn.CloseNow() }()
+	conn.SetReadLimit(1 << 20)
+
+	for {
+		var env socketEnvelope
+		if err := wsjson.Read(ctx, conn, &env); err != nil {
+			return fmt.Errorf("reading socket mode: %w", err)
+		}
+		if env.EnvelopeID != "" {
+			if err := wsjson.Write(ctx, conn, map[string]string{"envelope_id": env.EnvelopeID}); err != nil {
+				return fmt.Errorf("acknowledging envelope: %w", err)
+			}
+		}
+		switch env.Type {
+		case "hello":
+			logger.Info("connected to slack socket mode")
+		case "disconnect":
+			logger.Info("slack socket mode connection ending", "reason", env.Reason)
+			return nil
+		case "events_api":
+			ev := env.Payload.Event
+			if ev.Type == "message" || ev.Type == "app_mention" {
+				handle(&ev)
+			}
+		}
+	}
+}
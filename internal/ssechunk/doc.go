@@ -0,0 +1,12 @@
+// Package ssechunk splits large SSE text payloads into events a client can
+// take in one piece.
+//
+// A text or thinking event whose text is longer than the limit
+// (server.sse_chunk_bytes, default DefaultMaxBytes) is sent as several events
+// of the same type. Split cuts only between runes, so no chunk carries half
+// of a multi-byte character, and prefers to cut just after the last newline
+// that fits. Every chunk after the first is sent with "continued": true;
+// clients append a continued chunk to the previous event's text instead of
+// starting a new block. Concatenating the chunks gives back the original
+// text exactly.
+package ssechunk
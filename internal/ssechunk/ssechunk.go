@@ -0,0 +1,75 @@
+// ABOUTME: Splits oversized SSE text payloads into chunks on rune boundaries,
+// ABOUTME: preferring newlines, so clients can rejoin them with "continued" chunks.
+
+package ssechunk
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxBytes is the chunk size when server.sse_chunk_bytes is unset.
+const DefaultMaxBytes = 32 << 10
+
+// MaxBytes returns configured, or DefaultMaxBytes when it is not positive.
+func MaxBytes(configured int) int {
+	if configured > 0 {
+		return configured
+	}
+	return DefaultMaxBytes
+}
+
+// Split cuts text into chunks of at most maxBytes bytes. Each chunk ends just
+// after the last newline that fits, or else at the last rune boundary that
+// fits; a chunk is only ever longer than maxBytes when maxBytes is smaller
+// than a single rune. Text that fits comes back as a single chunk.
+func Split(text string, maxBytes int) []string {
+	if maxBytes <= 0 || len(text) <= maxBytes {
+		return []string{text}
+	}
+	var chunks []string
+	for len(text) > maxBytes {
+		n := cut(text, maxBytes)
+		chunks = append(chunks, text[:n])
+		text = text[n:]
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+// cut returns where to end the next chunk of text, which is longer than
+// maxBytes.
+func cut(text string, maxBytes int) int {
+	if i := strings.LastIndexByte(text[:maxBytes], '\n'); i >= 0 {
+		return i + 1
+	}
+	n := maxBytes
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	if n == 0 {
+		// maxBytes is inside the first rune; take the whole rune.
+		_, size := utf8.DecodeRuneInString(text)
+		n = size
+	}
+	return n
+}
+
+// Data is the JSON data of one chunk of a text or thinking event.
+type Data struct {
+	Text      string `json:"text"`
+	Continued bool   `json:"continued,omitempty"`
+}
+
+// Events splits text and returns the data of each resulting event, marking
+// all but the first as continued.
+func Events(text string, maxBytes int) []Data {
+	chunks := Split(text, maxBytes)
+	events := make([]Data, len(chunks))
+	for i, c := range chunks {
+		events[i] = Data{Text: c, Continued: i > 0}
+	}
+	return events
+}
@@ -0,0 +1,110 @@
+// ABOUTME: Tests for ssechunk: rune-safe and newline-preferring splits of
+// ABOUTME: ASCII, emoji and CJK text, and the continued flags on events.
+
+package ssechunk
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// checkChunks fails unless chunks rejoin to text, each is valid UTF-8 and
+// none is over maxBytes.
+func checkChunks(t *testing.T, text string, chunks []string, maxBytes int) {
+	t.Helper()
+	if got := strings.Join(chunks, ""); got != text {
+		t.Fatalf("joined chunks = %q, want %q", got, text)
+	}
+	for i, c := range chunks {
+		if !utf8.ValidString(c) {
+			t.Errorf("chunk %d = %q is not valid UTF-8", i, c)
+		}
+		if len(c) > maxBytes {
+			t.Errorf("chunk %d is %d bytes, over %d", i, len(c), maxBytes)
+		}
+		if c == "" {
+			t.Errorf("chunk %d is empty", i)
+		}
+	}
+}
+
+func TestSplit_FitsInOneChunk(t *testing.T) {
+	for _, text := range []string{"", "hello", strings.Repeat("a", 16)} {
+		if chunks := Split(text, 16); len(chunks) != 1 || chunks[0] != text {
+			t.Errorf("Split(%q) = %q, want it unchanged", text, chunks)
+		}
+	}
+}
+
+func TestSplit_RuneBoundaries(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+	}{
+		// "ab" then a 4-byte emoji straddling byte 4.
+		{"emoji straddles split", "ab😀cd😀😀ef", 4},
+		{"emoji run", strings.Repeat("😀", 9), 6},
+		// 3-byte CJK runes straddling every split of 4 and 5.
+		{"CJK straddles split", "漢字かな交じり文", 4},
+		{"CJK with ascii offset", "x漢字かな交じり文", 5},
+		{"mixed", "héllo 世界 👋🏽 mañana 日本語", 7},
+		{"limit under one rune", "😀漢a", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := Split(tt.text, tt.max)
+			if len(chunks) < 2 {
+				t.Fatalf("Split = %q, want several chunks", chunks)
+			}
+			maxBytes := max(tt.max, utf8.UTFMax)
+			checkChunks(t, tt.text, chunks, maxBytes)
+		})
+	}
+}
+
+func TestSplit_FillsChunksWithoutNewlines(t *testing.T) {
+	// Three-byte runes with a limit of 8 fit two per chunk.
+	chunks := Split("漢字かな交じ", 8)
+	want := []string{"漢字", "かな", "交じ"}
+	if strings.Join(chunks, "|") != strings.Join(want, "|") {
+		t.Errorf("Split = %q, want %q", chunks, want)
+	}
+}
+
+func TestSplit_PrefersNewlines(t *testing.T) {
+	text := "first line\nsecond 😀\nthird line that runs on"
+	chunks := Split(text, 24)
+	checkChunks(t, text, chunks, 24)
+	if chunks[0] != "first line\nsecond 😀\n" {
+		t.Errorf("first chunk = %q, want it to end after the emoji line", chunks[0])
+	}
+}
+
+func TestEvents_MarksContinuations(t *testing.T) {
+	events := Events(strings.Repeat("世界\n", 10), 8)
+	if len(events) != 10 {
+		t.Fatalf("got %d events, want 10", len(events))
+	}
+	for i, ev := range events {
+		if ev.Continued != (i > 0) {
+			t.Errorf("event %d continued = %v", i, ev.Continued)
+		}
+		if ev.Text != "世界\n" {
+			t.Errorf("event %d text = %q", i, ev.Text)
+		}
+	}
+	if single := Events("short", 8); len(single) != 1 || single[0].Continued {
+		t.Errorf("Events(short) = %+v, want one uncontinued event", single)
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	if got := MaxBytes(0); got != DefaultMaxBytes {
+		t.Errorf("MaxBytes(0) = %d, want %d", got, DefaultMaxBytes)
+	}
+	if got := MaxBytes(1024); got != 1024 {
+		t.Errorf("MaxBytes(1024) = %d", got)
+	}
+}
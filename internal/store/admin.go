@@ -37,14 +37,18 @@ type AdminUser struct {
 	PasswordHash string // bcrypt hash, empty if passkey-only
 	DisplayName  string
 	CreatedAt    time.Time
+	PrincipalID  string // client principal used for this admin's API tokens, empty until first minted
 }
 
 // AdminSession represents an authenticated admin session.
 type AdminSession struct {
-	ID        string
-	UserID    string
-	CreatedAt time.Time
-	ExpiresAt time.Time
+	ID         string
+	UserID     string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastSeenAt time.Time // last authenticated request, updated at most once a minute
+	UserAgent  string    // captured at sign-in
+	IP         string    // captured at sign-in
 }
 
 // AdminInvite represents a signup invitation link.
@@ -84,6 +88,16 @@ type AdminStore interface {
 	GetAdminSession(ctx context.Context, id string) (*AdminSession, error)
 	DeleteAdminSession(ctx context.Context, id string) error
 	DeleteExpiredAdminSessions(ctx context.Context) error
+	ListAdminSessions(ctx context.Context, userID string) ([]*AdminSession, error)
+	TouchAdminSession(ctx context.Context, id string, seenAt time.Time) error
+	DeleteAdminSessionsForUser(ctx context.Context, userID, exceptID string) (int64, error)
+
+	// Failed password logins
+	GetLoginAttempt(ctx context.Context, username, ip string) (*LoginAttempt, error)
+	RecordLoginFailure(ctx context.Context, username, ip string, now time.Time, window time.Duration) (*LoginAttempt, error)
+	LockLogin(ctx context.Context, username, ip string, until time.Time) error
+	ClearLoginAttempts(ctx context.Context, username, ip string) (int64, error)
+	ListLoginLockouts(ctx context.Context, now time.Time) ([]*LoginAttempt, error)
 
 	// Invites
 	CreateAdminInvite(ctx context.Context, invite *AdminInvite) error
@@ -98,11 +112,11 @@ type AdminStore interface {
 	DeleteWebAuthnCredential(ctx context.Context, id string) error
 }
 
-// Ensure SQLiteStore implements AdminStore.
-var _ AdminStore = (*SQLiteStore)(nil)
+// Ensure SQLStore implements AdminStore.
+var _ AdminStore = (*SQLStore)(nil)
 
 // CreateAdminUser creates a new admin user.
-func (s *SQLiteStore) CreateAdminUser(ctx context.Context, user *AdminUser) error {
+func (s *SQLStore) CreateAdminUser(ctx context.Context, user *AdminUser) error {
 	query := `
 		INSERT INTO admin_users (id, username, password_hash, display_name, created_at)
 		VALUES (?, ?, ?, ?, ?)
@@ -128,15 +142,15 @@ func (s *SQLiteStore) CreateAdminUser(ctx context.Context, user *AdminUser) erro
 }
 
 // GetAdminUser retrieves an admin user by ID.
-func (s *SQLiteStore) GetAdminUser(ctx context.Context, id string) (*AdminUser, error) {
+func (s *SQLStore) GetAdminUser(ctx context.Context, id string) (*AdminUser, error) {
 	query := `
-		SELECT id, username, password_hash, display_name, created_at
+		SELECT id, username, password_hash, display_name, created_at, principal_id
 		FROM admin_users
 		WHERE id = ?
 	`
 
 	var user AdminUser
-	var passwordHash sql.NullString
+	var passwordHash, principalID sql.NullString
 	var createdAtStr string
 
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
@@ -145,6 +159,7 @@ func (s *SQLiteStore) GetAdminUser(ctx context.Context, id string) (*AdminUser,
 		&passwordHash,
 		&user.DisplayName,
 		&createdAtStr,
+		&principalID,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -155,6 +170,7 @@ func (s *SQLiteStore) GetAdminUser(ctx context.Context, id string) (*AdminUser,
 	}
 
 	user.PasswordHash = passwordHash.String
+	user.PrincipalID = principalID.String
 	user.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
 	if err != nil {
 		return nil, fmt.Errorf("parsing created_at: %w", err)
@@ -164,15 +180,15 @@ func (s *SQLiteStore) GetAdminUser(ctx context.Context, id string) (*AdminUser,
 }
 
 // GetAdminUserByUsername retrieves an admin user by username.
-func (s *SQLiteStore) GetAdminUserByUsername(ctx context.Context, username string) (*AdminUser, error) {
+func (s *SQLStore) GetAdminUserByUsername(ctx context.Context, username string) (*AdminUser, error) {
 	query := `
-		SELECT id, username, password_hash, display_name, created_at
+		SELECT id, username, password_hash, display_name, created_at, principal_id
 		FROM admin_users
 		WHERE username = ?
 	`
 
 	var user AdminUser
-	var passwordHash sql.NullString
+	var passwordHash, principalID sql.NullString
 	var createdAtStr string
 
 	err := s.db.QueryRowContext(ctx, query, username).Scan(
@@ -181,6 +197,7 @@ func (s *SQLiteStore) GetAdminUserByUsername(ctx context.Context, username strin
 		&passwordHash,
 		&user.DisplayName,
 		&createdAtStr,
+		&principalID,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -191,6 +208,7 @@ func (s *SQLiteStore) GetAdminUserByUsername(ctx context.Context, username strin
 	}
 
 	user.PasswordHash = passwordHash.String
+	user.PrincipalID = principalID.String
 	user.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
 	if err != nil {
 		return nil, fmt.Errorf("parsing created_at: %w", err)
@@ -200,7 +218,7 @@ func (s *SQLiteStore) GetAdminUserByUsername(ctx context.Context, username strin
 }
 
 // UpdateAdminUserPassword updates an admin user's password hash.
-func (s *SQLiteStore) UpdateAdminUserPassword(ctx context.Context, id, passwordHash string) error {
+func (s *SQLStore) UpdateAdminUserPassword(ctx context.Context, id, passwordHash string) error {
 	query := `UPDATE admin_users SET password_hash = ? WHERE id = ?`
 
 	result, err := s.db.ExecContext(ctx, query, passwordHash, id)
@@ -222,9 +240,9 @@ func (s *SQLiteStore) UpdateAdminUserPassword(ctx context.Context, id, passwordH
 }
 
 // ListAdminUsers returns all admin users.
-func (s *SQLiteStore) ListAdminUsers(ctx context.Context) ([]*AdminUser, error) {
+func (s *SQLStore) ListAdminUsers(ctx context.Context) ([]*AdminUser, error) {
 	query := `
-		SELECT id, username, password_hash, display_name, created_at
+		SELECT id, username, password_hash, display_name, created_at, principal_id
 		FROM admin_users
 		ORDER BY created_at ASC
 	`
@@ -238,14 +256,15 @@ func (s *SQLiteStore) ListAdminUsers(ctx context.Context) ([]*AdminUser, error)
 	var users []*AdminUser
 	for rows.Next() {
 		var user AdminUser
-		var passwordHash sql.NullString
+		var passwordHash, principalID sql.NullString
 		var createdAtStr string
 
-		if err := rows.Scan(&user.ID, &user.Username, &passwordHash, &user.DisplayName, &createdAtStr); err != nil {
+		if err := rows.Scan(&user.ID, &user.Username, &passwordHash, &user.DisplayName, &createdAtStr, &principalID); err != nil {
 			return nil, fmt.Errorf("scanning admin user: %w", err)
 		}
 
 		user.PasswordHash = passwordHash.String
+		user.PrincipalID = principalID.String
 		user.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
 		if err != nil {
 			return nil, fmt.Errorf("parsing created_at: %w", err)
@@ -261,7 +280,7 @@ func (s *SQLiteStore) ListAdminUsers(ctx context.Context) ([]*AdminUser, error)
 }
 
 // CountAdminUsers returns the number of admin users.
-func (s *SQLiteStore) CountAdminUsers(ctx context.Context) (int, error) {
+func (s *SQLStore) CountAdminUsers(ctx context.Context) (int, error) {
 	var count int
 	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM admin_users").Scan(&count)
 	if err != nil {
@@ -271,17 +290,24 @@ func (s *SQLiteStore) CountAdminUsers(ctx context.Context) (int, error) {
 }
 
 // CreateAdminSession creates a new admin session.
-func (s *SQLiteStore) CreateAdminSession(ctx context.Context, session *AdminSession) error {
+func (s *SQLStore) CreateAdminSession(ctx context.Context, session *AdminSession) error {
 	query := `
-		INSERT INTO admin_sessions (id, user_id, created_at, expires_at)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO admin_sessions (id, user_id, created_at, expires_at, last_seen_at, user_agent, ip)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
+	lastSeen := session.LastSeenAt
+	if lastSeen.IsZero() {
+		lastSeen = session.CreatedAt
+	}
 	_, err := s.db.ExecContext(ctx, query,
 		session.ID,
 		session.UserID,
 		session.CreatedAt.UTC().Format(time.RFC3339),
 		session.ExpiresAt.UTC().Format(time.RFC3339),
+		lastSeen.UTC().Format(time.RFC3339),
+		nullString(session.UserAgent),
+		nullString(session.IP),
 	)
 	if err != nil {
 		return fmt.Errorf("inserting admin session: %w", err)
@@ -292,46 +318,119 @@ func (s *SQLiteStore) CreateAdminSession(ctx context.Context, session *AdminSess
 }
 
 // GetAdminSession retrieves a valid (non-expired) admin session.
-func (s *SQLiteStore) GetAdminSession(ctx context.Context, id string) (*AdminSession, error) {
-	query := `
-		SELECT id, user_id, created_at, expires_at
+func (s *SQLStore) GetAdminSession(ctx context.Context, id string) (*AdminSession, error) {
+	query := `SELECT ` + adminSessionColumns + `
 		FROM admin_sessions
 		WHERE id = ? AND expires_at > ?
 	`
 
+	now := time.Now().UTC().Format(time.RFC3339)
+	session, err := scanAdminSession(s.db.QueryRowContext(ctx, query, id, now))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAdminSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying admin session: %w", err)
+	}
+	return session, nil
+}
+
+// adminSessionColumns are the columns scanAdminSession reads, in order.
+const adminSessionColumns = `id, user_id, created_at, expires_at, last_seen_at, user_agent, ip`
+
+// scanAdminSession reads one row of adminSessionColumns. Sessions created
+// before last_seen_at existed report their creation time.
+func scanAdminSession(row interface{ Scan(...any) error }) (*AdminSession, error) {
 	var session AdminSession
 	var createdAtStr, expiresAtStr string
-	now := time.Now().UTC().Format(time.RFC3339)
+	var lastSeenStr, userAgent, ip sql.NullString
 
-	err := s.db.QueryRowContext(ctx, query, id, now).Scan(
+	if err := row.Scan(
 		&session.ID,
 		&session.UserID,
 		&createdAtStr,
 		&expiresAtStr,
-	)
-
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, ErrAdminSessionNotFound
-	}
-	if err != nil {
-		return nil, fmt.Errorf("querying admin session: %w", err)
+		&lastSeenStr,
+		&userAgent,
+		&ip,
+	); err != nil {
+		return nil, err
 	}
 
+	var err error
 	session.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
 	if err != nil {
 		return nil, fmt.Errorf("parsing created_at: %w", err)
 	}
-
 	session.ExpiresAt, err = time.Parse(time.RFC3339, expiresAtStr)
 	if err != nil {
 		return nil, fmt.Errorf("parsing expires_at: %w", err)
 	}
-
+	session.LastSeenAt = session.CreatedAt
+	if lastSeenStr.Valid {
+		session.LastSeenAt, err = time.Parse(time.RFC3339, lastSeenStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("parsing last_seen_at: %w", err)
+		}
+	}
+	session.UserAgent = userAgent.String
+	session.IP = ip.String
 	return &session, nil
 }
 
+// ListAdminSessions returns the unexpired sessions of userID, or of every
+// user when userID is empty, most recently seen first.
+func (s *SQLStore) ListAdminSessions(ctx context.Context, userID string) ([]*AdminSession, error) {
+	query := `SELECT ` + adminSessionColumns + `
+		FROM admin_sessions
+		WHERE expires_at > ? AND (? = '' OR user_id = ?)
+		ORDER BY COALESCE(last_seen_at, created_at) DESC, created_at DESC
+	`
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	rows, err := s.db.QueryContext(ctx, query, now, userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying admin sessions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sessions []*AdminSession
+	for rows.Next() {
+		session, err := scanAdminSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning admin session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating admin sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// TouchAdminSession records that the session was used at seenAt.
+func (s *SQLStore) TouchAdminSession(ctx context.Context, id string, seenAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE admin_sessions SET last_seen_at = ? WHERE id = ?",
+		seenAt.UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return fmt.Errorf("updating admin session last_seen_at: %w", err)
+	}
+	return nil
+}
+
+// DeleteAdminSessionsForUser deletes every session of userID except
+// exceptID, which may be empty, and returns how many were deleted.
+func (s *SQLStore) DeleteAdminSessionsForUser(ctx context.Context, userID, exceptID string) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM admin_sessions WHERE user_id = ? AND id != ?", userID, exceptID)
+	if err != nil {
+		return 0, fmt.Errorf("deleting admin sessions: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
+
 // DeleteAdminSession deletes an admin session.
-func (s *SQLiteStore) DeleteAdminSession(ctx context.Context, id string) error {
+func (s *SQLStore) DeleteAdminSession(ctx context.Context, id string) error {
 	_, err := s.db.ExecContext(ctx, "DELETE FROM admin_sessions WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("deleting admin session: %w", err)
@@ -340,7 +439,7 @@ func (s *SQLiteStore) DeleteAdminSession(ctx context.Context, id string) error {
 }
 
 // DeleteExpiredAdminSessions removes all expired sessions.
-func (s *SQLiteStore) DeleteExpiredAdminSessions(ctx context.Context) error {
+func (s *SQLStore) DeleteExpiredAdminSessions(ctx context.Context) error {
 	now := time.Now().UTC().Format(time.RFC3339)
 	result, err := s.db.ExecContext(ctx, "DELETE FROM admin_sessions WHERE expires_at <= ?", now)
 	if err != nil {
@@ -355,7 +454,7 @@ func (s *SQLiteStore) DeleteExpiredAdminSessions(ctx context.Context) error {
 }
 
 // CreateAdminInvite creates a new admin invite.
-func (s *SQLiteStore) CreateAdminInvite(ctx context.Context, invite *AdminInvite) error {
+func (s *SQLStore) CreateAdminInvite(ctx context.Context, invite *AdminInvite) error {
 	query := `
 		INSERT INTO admin_invites (id, created_by, created_at, expires_at)
 		VALUES (?, ?, ?, ?)
@@ -366,7 +465,13 @@ func (s *SQLiteStore) CreateAdminInvite(ctx context.Context, invite *AdminInvite
 		createdBy = sql.NullString{String: invite.CreatedBy, Valid: true}
 	}
 
-	_, err := s.db.ExecContext(ctx, query,
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, query,
 		invite.ID,
 		createdBy,
 		invite.CreatedAt.UTC().Format(time.RFC3339),
@@ -376,12 +481,16 @@ func (s *SQLiteStore) CreateAdminInvite(ctx context.Context, invite *AdminInvite
 		return fmt.Errorf("inserting admin invite: %w", err)
 	}
 
+	if err := commitAuditedTx(ctx, tx); err != nil {
+		return err
+	}
+
 	s.logger.Info("created admin invite", "id", invite.ID, "expires_at", invite.ExpiresAt)
 	return nil
 }
 
 // GetAdminInvite retrieves an admin invite by ID.
-func (s *SQLiteStore) GetAdminInvite(ctx context.Context, id string) (*AdminInvite, error) {
+func (s *SQLStore) GetAdminInvite(ctx context.Context, id string) (*AdminInvite, error) {
 	query := `
 		SELECT id, created_by, created_at, expires_at, used_at, used_by
 		FROM admin_invites
@@ -437,7 +546,7 @@ func (s *SQLiteStore) GetAdminInvite(ctx context.Context, id string) (*AdminInvi
 // This prevents race conditions where the same invite could be used twice.
 // Returns ErrAdminInviteUsed if already used, ErrAdminInviteExpired if expired,
 // or ErrAdminInviteNotFound if the invite doesn't exist.
-func (s *SQLiteStore) UseAdminInvite(ctx context.Context, inviteID, userID string) error {
+func (s *SQLStore) UseAdminInvite(ctx context.Context, inviteID, userID string) error {
 	now := time.Now().UTC().Format(time.RFC3339)
 
 	// Atomic update: only succeeds if invite exists, is not used, and not expired
@@ -485,7 +594,7 @@ func (s *SQLiteStore) UseAdminInvite(ctx context.Context, inviteID, userID strin
 }
 
 // CreateWebAuthnCredential stores a new WebAuthn credential.
-func (s *SQLiteStore) CreateWebAuthnCredential(ctx context.Context, cred *WebAuthnCredential) error {
+func (s *SQLStore) CreateWebAuthnCredential(ctx context.Context, cred *WebAuthnCredential) error {
 	query := `
 		INSERT INTO webauthn_credentials (id, user_id, credential_id, public_key, attestation_type, transports, sign_count, created_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
@@ -510,7 +619,7 @@ func (s *SQLiteStore) CreateWebAuthnCredential(ctx context.Context, cred *WebAut
 }
 
 // GetWebAuthnCredentialsByUser retrieves all WebAuthn credentials for a user.
-func (s *SQLiteStore) GetWebAuthnCredentialsByUser(ctx context.Context, userID string) ([]*WebAuthnCredential, error) {
+func (s *SQLStore) GetWebAuthnCredentialsByUser(ctx context.Context, userID string) ([]*WebAuthnCredential, error) {
 	query := `
 		SELECT id, user_id, credential_id, public_key, attestation_type, transports, sign_count, created_at
 		FROM webauthn_credentials
@@ -559,7 +668,7 @@ func (s *SQLiteStore) GetWebAuthnCredentialsByUser(ctx context.Context, userID s
 }
 
 // GetWebAuthnCredentialByCredentialID retrieves a WebAuthn credential by its credential ID.
-func (s *SQLiteStore) GetWebAuthnCredentialByCredentialID(ctx context.Context, credentialID []byte) (*WebAuthnCredential, error) {
+func (s *SQLStore) GetWebAuthnCredentialByCredentialID(ctx context.Context, credentialID []byte) (*WebAuthnCredential, error) {
 	query := `
 		SELECT id, user_id, credential_id, public_key, attestation_type, transports, sign_count, created_at
 		FROM webauthn_credentials
@@ -598,7 +707,7 @@ func (s *SQLiteStore) GetWebAuthnCredentialByCredentialID(ctx context.Context, c
 }
 
 // UpdateWebAuthnCredentialSignCount updates the sign count for a credential.
-func (s *SQLiteStore) UpdateWebAuthnCredentialSignCount(ctx context.Context, id string, signCount uint32) error {
+func (s *SQLStore) UpdateWebAuthnCredentialSignCount(ctx context.Context, id string, signCount uint32) error {
 	query := `UPDATE webauthn_credentials SET sign_count = ? WHERE id = ?`
 
 	result, err := s.db.ExecContext(ctx, query, signCount, id)
@@ -619,7 +728,7 @@ func (s *SQLiteStore) UpdateWebAuthnCredentialSignCount(ctx context.Context, id
 }
 
 // DeleteWebAuthnCredential deletes a WebAuthn credential.
-func (s *SQLiteStore) DeleteWebAuthnCredential(ctx context.Context, id string) error {
+func (s *SQLStore) DeleteWebAuthnCredential(ctx context.Context, id string) error {
 	result, err := s.db.ExecContext(ctx, "DELETE FROM webauthn_credentials WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("deleting webauthn credential: %w", err)
@@ -640,6 +749,7 @@ func (s *SQLiteStore) DeleteWebAuthnCredential(ctx context.Context, id string) e
 
 // isUniqueConstraintError checks if an error is a unique constraint violation.
 func isUniqueConstraintError(err error) bool {
-	// SQLite returns "UNIQUE constraint failed" in the error message
+	// SQLite returns "UNIQUE constraint failed" in the error message;
+	// Postgres says "violates unique constraint".
 	return err != nil && (strings.Contains(err.Error(), "UNIQUE constraint failed") || strings.Contains(err.Error(), "unique constraint"))
 }
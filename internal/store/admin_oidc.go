@@ -0,0 +1,83 @@
+// ABOUTME: Links admin users to OpenID Connect identities (issuer + subject)
+// ABOUTME: SSO users are provisioned on first login together with their identity link
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrOIDCIdentityExists is returned when an OIDC identity is already linked
+// to an admin user, typically because two first logins raced.
+var ErrOIDCIdentityExists = errors.New("oidc identity already linked")
+
+// AdminOIDCIdentity links an admin user to a subject at an OIDC provider.
+type AdminOIDCIdentity struct {
+	Issuer    string
+	Subject   string
+	UserID    string
+	Email     string
+	CreatedAt time.Time
+}
+
+// GetAdminUserByOIDCIdentity returns the admin user linked to the given
+// issuer and subject, or ErrAdminUserNotFound.
+func (s *SQLStore) GetAdminUserByOIDCIdentity(ctx context.Context, issuer, subject string) (*AdminUser, error) {
+	var userID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id FROM admin_oidc_identities WHERE issuer = ? AND subject = ?
+	`, issuer, subject).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAdminUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying oidc identity: %w", err)
+	}
+	return s.GetAdminUser(ctx, userID)
+}
+
+// CreateOIDCAdminUser creates a passwordless admin user and links it to an
+// OIDC identity in one transaction. Returns ErrUsernameExists if the username
+// is taken and ErrOIDCIdentityExists if the identity is already linked.
+func (s *SQLStore) CreateOIDCAdminUser(ctx context.Context, user *AdminUser, identity *AdminOIDCIdentity) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO admin_users (id, username, password_hash, display_name, created_at)
+		VALUES (?, ?, '', ?, ?)
+	`, user.ID, user.Username, user.DisplayName, user.CreatedAt.UTC().Format(time.RFC3339))
+	if isUniqueConstraintError(err) {
+		return ErrUsernameExists
+	}
+	if err != nil {
+		return fmt.Errorf("inserting admin user: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO admin_oidc_identities (issuer, subject, user_id, email, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, identity.Issuer, identity.Subject, user.ID, identity.Email, identity.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil && strings.Contains(err.Error(), "admin_oidc_identities") && isUniqueConstraintError(err) {
+		return ErrOIDCIdentityExists
+	}
+	if err != nil {
+		return fmt.Errorf("inserting oidc identity: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing oidc admin user: %w", err)
+	}
+	identity.UserID = user.ID
+
+	s.logger.Info("created admin user from oidc login", "id", user.ID, "username", user.Username, "issuer", identity.Issuer)
+	return nil
+}
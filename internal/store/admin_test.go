@@ -0,0 +1,83 @@
+// ABOUTME: Tests for admin session persistence
+// ABOUTME: Covers listing, last-seen updates, client details, and signing a user out everywhere
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminSessions_ListTouchAndDelete(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for _, u := range []string{"alice", "bob"} {
+		require.NoError(t, store.CreateAdminUser(ctx, &AdminUser{ID: u, Username: u, DisplayName: u, CreatedAt: now}))
+	}
+	sessions := []*AdminSession{
+		{ID: "a1", UserID: "alice", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(time.Hour), UserAgent: "Firefox", IP: "203.0.113.7"},
+		{ID: "a2", UserID: "alice", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+		{ID: "a3", UserID: "alice", CreatedAt: now.Add(-3 * time.Hour), ExpiresAt: now.Add(-time.Minute)},
+		{ID: "b1", UserID: "bob", CreatedAt: now.Add(-30 * time.Minute), ExpiresAt: now.Add(time.Hour)},
+	}
+	for _, s := range sessions {
+		require.NoError(t, store.CreateAdminSession(ctx, s))
+	}
+
+	got, err := store.GetAdminSession(ctx, "a1")
+	require.NoError(t, err)
+	assert.Equal(t, "Firefox", got.UserAgent)
+	assert.Equal(t, "203.0.113.7", got.IP)
+	assert.Equal(t, now.Add(-2*time.Hour), got.LastSeenAt, "last seen starts at creation")
+
+	require.NoError(t, store.TouchAdminSession(ctx, "a1", now))
+
+	all, err := store.ListAdminSessions(ctx, "")
+	require.NoError(t, err)
+	var ids []string
+	for _, s := range all {
+		ids = append(ids, s.ID)
+	}
+	assert.Equal(t, []string{"a1", "b1", "a2"}, ids, "unexpired only, most recently seen first")
+	assert.Equal(t, now, all[0].LastSeenAt)
+
+	alice, err := store.ListAdminSessions(ctx, "alice")
+	require.NoError(t, err)
+	assert.Len(t, alice, 2)
+
+	n, err := store.DeleteAdminSessionsForUser(ctx, "alice", "a2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n, "a1 and the expired a3")
+
+	alice, err = store.ListAdminSessions(ctx, "alice")
+	require.NoError(t, err)
+	require.Len(t, alice, 1)
+	assert.Equal(t, "a2", alice[0].ID)
+
+	_, err = store.GetAdminSession(ctx, "b1")
+	assert.NoError(t, err, "other users keep their sessions")
+}
+
+func TestAdminSessions_LegacyRowsWithoutClientDetails(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	require.NoError(t, store.CreateAdminUser(ctx, &AdminUser{ID: "alice", Username: "alice", DisplayName: "Alice", CreatedAt: now}))
+	_, err := store.db.ExecContext(ctx,
+		`INSERT INTO admin_sessions (id, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)`,
+		"old", "alice", now.Add(-time.Hour).Format(time.RFC3339), now.Add(time.Hour).Format(time.RFC3339))
+	require.NoError(t, err)
+
+	got, err := store.GetAdminSession(ctx, "old")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-time.Hour), got.LastSeenAt)
+	assert.Empty(t, got.UserAgent)
+	assert.Empty(t, got.IP)
+}
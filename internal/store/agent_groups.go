@@ -0,0 +1,218 @@
+// ABOUTME: Named agent groups that map a group name to a set of agent principals
+// ABOUTME: Used to fan one message out to every agent in a group via /api/send/broadcast
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrAgentGroupExists indicates an agent group with the same name exists.
+var ErrAgentGroupExists = errors.New("agent group already exists")
+
+// AgentGroup is a named set of agent principals.
+type AgentGroup struct {
+	Name      string
+	Members   []string // principal IDs, sorted
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// CreateAgentGroup stores a new group and its members. Returns
+// ErrAgentGroupExists if the name is taken and ErrPrincipalNotFound if a
+// member is not a principal.
+func (s *SQLStore) CreateAgentGroup(ctx context.Context, g *AgentGroup) error {
+	if g.CreatedAt.IsZero() {
+		g.CreatedAt = time.Now()
+	}
+	g.UpdatedAt = g.CreatedAt
+
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO agent_groups (name, created_at, updated_at) VALUES (?, ?, ?)
+	`, g.Name, g.CreatedAt.UTC().Format(time.RFC3339), g.UpdatedAt.UTC().Format(time.RFC3339))
+	if isUniqueConstraintError(err) {
+		return ErrAgentGroupExists
+	}
+	if err != nil {
+		return fmt.Errorf("inserting agent group: %w", err)
+	}
+	if err := insertAgentGroupMembersTx(ctx, tx, g.Name, g.Members); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing agent group: %w", err)
+	}
+	g.Members = sortedMembers(g.Members)
+	return nil
+}
+
+// GetAgentGroup returns a group with its members, or ErrNotFound.
+func (s *SQLStore) GetAgentGroup(ctx context.Context, name string) (*AgentGroup, error) {
+	var g AgentGroup
+	var createdAt, updatedAt string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT name, created_at, updated_at FROM agent_groups WHERE name = ?
+	`, name).Scan(&g.Name, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying agent group: %w", err)
+	}
+	g.CreatedAt = parseTimeWithWarning(createdAt, "agent_group", g.Name, "created_at")
+	g.UpdatedAt = parseTimeWithWarning(updatedAt, "agent_group", g.Name, "updated_at")
+
+	members, err := s.listAgentGroupMembers(ctx, `WHERE group_name = ?`, name)
+	if err != nil {
+		return nil, err
+	}
+	g.Members = members[name]
+	return &g, nil
+}
+
+// ListAgentGroups returns every group with its members, ordered by name.
+func (s *SQLStore) ListAgentGroups(ctx context.Context) ([]*AgentGroup, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name, created_at, updated_at FROM agent_groups ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("querying agent groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*AgentGroup
+	for rows.Next() {
+		var g AgentGroup
+		var createdAt, updatedAt string
+		if err := rows.Scan(&g.Name, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("scanning agent group: %w", err)
+		}
+		g.CreatedAt = parseTimeWithWarning(createdAt, "agent_group", g.Name, "created_at")
+		g.UpdatedAt = parseTimeWithWarning(updatedAt, "agent_group", g.Name, "updated_at")
+		groups = append(groups, &g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating agent groups: %w", err)
+	}
+
+	members, err := s.listAgentGroupMembers(ctx, ``)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		g.Members = members[g.Name]
+	}
+	return groups, nil
+}
+
+// SetAgentGroupMembers replaces a group's members. Returns ErrNotFound if
+// the group does not exist and ErrPrincipalNotFound if a member is not a
+// principal.
+func (s *SQLStore) SetAgentGroupMembers(ctx context.Context, name string, members []string) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, `UPDATE agent_groups SET updated_at = ? WHERE name = ?`,
+		time.Now().UTC().Format(time.RFC3339), name)
+	if err != nil {
+		return fmt.Errorf("updating agent group: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM agent_group_members WHERE group_name = ?`, name); err != nil {
+		return fmt.Errorf("clearing agent group members: %w", err)
+	}
+	if err := insertAgentGroupMembersTx(ctx, tx, name, members); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing agent group members: %w", err)
+	}
+	return nil
+}
+
+// DeleteAgentGroup removes a group and its memberships, or returns ErrNotFound.
+func (s *SQLStore) DeleteAgentGroup(ctx context.Context, name string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM agent_groups WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("deleting agent group: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// insertAgentGroupMembersTx adds members to group within tx, ignoring
+// duplicates.
+func insertAgentGroupMembersTx(ctx context.Context, tx *dbTx, group string, members []string) error {
+	for _, principalID := range sortedMembers(members) {
+		var exists int
+		err := tx.QueryRowContext(ctx, `SELECT 1 FROM principals WHERE principal_id = ?`, principalID).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrPrincipalNotFound, principalID)
+		}
+		if err != nil {
+			return fmt.Errorf("checking principal: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO agent_group_members (group_name, principal_id) VALUES (?, ?)
+		`, group, principalID); err != nil {
+			return fmt.Errorf("inserting agent group member: %w", err)
+		}
+	}
+	return nil
+}
+
+// listAgentGroupMembers returns sorted principal IDs by group name for the
+// membership rows matching where.
+func (s *SQLStore) listAgentGroupMembers(ctx context.Context, where string, args ...any) (map[string][]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT group_name, principal_id FROM agent_group_members `+where+` ORDER BY group_name, principal_id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying agent group members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make(map[string][]string)
+	for rows.Next() {
+		var group, principalID string
+		if err := rows.Scan(&group, &principalID); err != nil {
+			return nil, fmt.Errorf("scanning agent group member: %w", err)
+		}
+		members[group] = append(members[group], principalID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating agent group members: %w", err)
+	}
+	return members, nil
+}
+
+// sortedMembers returns members sorted with duplicates removed.
+func sortedMembers(members []string) []string {
+	out := slices.Clone(members)
+	slices.Sort(out)
+	return slices.Compact(out)
+}
@@ -0,0 +1,81 @@
+// ABOUTME: Tests for agent groups
+// ABOUTME: Covers create/get/list, member replacement and validation, and cascading deletes
+
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAgentGroups(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	for _, id := range []string{"p-a", "p-b", "p-c"} {
+		if err := s.CreatePrincipal(ctx, &Principal{
+			ID: id, Type: PrincipalTypeAgent, PubkeyFP: "fp-" + id, DisplayName: id,
+			Status: PrincipalStatusApproved, CreatedAt: time.Now(),
+		}); err != nil {
+			t.Fatalf("CreatePrincipal(%s): %v", id, err)
+		}
+	}
+
+	g := &AgentGroup{Name: "repos", Members: []string{"p-b", "p-a", "p-b"}}
+	if err := s.CreateAgentGroup(ctx, g); err != nil {
+		t.Fatalf("CreateAgentGroup: %v", err)
+	}
+	if !slices.Equal(g.Members, []string{"p-a", "p-b"}) {
+		t.Errorf("created members = %v, want [p-a p-b]", g.Members)
+	}
+	if err := s.CreateAgentGroup(ctx, &AgentGroup{Name: "repos"}); !errors.Is(err, ErrAgentGroupExists) {
+		t.Errorf("duplicate CreateAgentGroup err = %v, want ErrAgentGroupExists", err)
+	}
+	if err := s.CreateAgentGroup(ctx, &AgentGroup{Name: "ghosts", Members: []string{"nobody"}}); !errors.Is(err, ErrPrincipalNotFound) {
+		t.Errorf("unknown member err = %v, want ErrPrincipalNotFound", err)
+	}
+	if _, err := s.GetAgentGroup(ctx, "ghosts"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("failed create left group behind: %v", err)
+	}
+
+	got, err := s.GetAgentGroup(ctx, "repos")
+	if err != nil || !slices.Equal(got.Members, []string{"p-a", "p-b"}) {
+		t.Fatalf("GetAgentGroup = %+v, %v", got, err)
+	}
+
+	if err := s.SetAgentGroupMembers(ctx, "repos", []string{"p-c", "p-a"}); err != nil {
+		t.Fatalf("SetAgentGroupMembers: %v", err)
+	}
+	if err := s.SetAgentGroupMembers(ctx, "repos", []string{"nobody"}); !errors.Is(err, ErrPrincipalNotFound) {
+		t.Errorf("unknown member err = %v, want ErrPrincipalNotFound", err)
+	}
+	if err := s.SetAgentGroupMembers(ctx, "missing", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing group err = %v, want ErrNotFound", err)
+	}
+	if err := s.CreateAgentGroup(ctx, &AgentGroup{Name: "empty"}); err != nil {
+		t.Fatalf("CreateAgentGroup(empty): %v", err)
+	}
+
+	groups, err := s.ListAgentGroups(ctx)
+	if err != nil || len(groups) != 2 || groups[0].Name != "empty" || len(groups[0].Members) != 0 ||
+		!slices.Equal(groups[1].Members, []string{"p-a", "p-c"}) {
+		t.Fatalf("ListAgentGroups = %+v, %v", groups, err)
+	}
+
+	// Deleting a principal drops it from its groups.
+	if err := s.DeletePrincipal(ctx, "p-c"); err != nil {
+		t.Fatalf("DeletePrincipal: %v", err)
+	}
+	if got, err := s.GetAgentGroup(ctx, "repos"); err != nil || !slices.Equal(got.Members, []string{"p-a"}) {
+		t.Errorf("after principal delete = %+v, %v; want [p-a]", got, err)
+	}
+
+	if err := s.DeleteAgentGroup(ctx, "repos"); err != nil {
+		t.Fatalf("DeleteAgentGroup: %v", err)
+	}
+	if err := s.DeleteAgentGroup(ctx, "repos"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second DeleteAgentGroup err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,281 @@
+// ABOUTME: Persisted agent sessions that let a reconnecting agent resume after a gateway restart
+// ABOUTME: Stores hashed session tokens, negotiated features, and the in-flight request ledger
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrAgentSessionNotFound is returned when no session matches a token.
+var ErrAgentSessionNotFound = errors.New("agent session not found")
+
+// MaxAckedRequestIDs caps how many finished request IDs a session remembers.
+const MaxAckedRequestIDs = 64
+
+// AgentSession is the durable half of an agent connection. The token itself
+// is never stored, only its hash.
+type AgentSession struct {
+	ID               string
+	TokenHash        string
+	AgentID          string
+	PrincipalID      string // empty when the gateway runs without auth
+	ProtocolFeatures []string
+	// AckedRequestIDs lists the most recent requests the gateway saw the
+	// agent finish, oldest first.
+	AckedRequestIDs []string
+	CreatedAt       time.Time
+	LastSeenAt      time.Time
+}
+
+// InFlightRequest is a request sent to an agent that has not yet finished.
+type InFlightRequest struct {
+	RequestID string
+	SessionID string
+	AgentID   string
+	ThreadID  string
+	Sender    string
+	Content   string
+	Workspace string // agent workspace the request targets, empty for its default
+	// ChannelContext is the binding context the request was sent with.
+	ChannelContext string
+	StartedAt      time.Time
+}
+
+// CreateAgentSession records a new session.
+func (s *SQLStore) CreateAgentSession(ctx context.Context, sess *AgentSession) error {
+	now := time.Now()
+	if sess.CreatedAt.IsZero() {
+		sess.CreatedAt = now
+	}
+	if sess.LastSeenAt.IsZero() {
+		sess.LastSeenAt = sess.CreatedAt
+	}
+	features, err := json.Marshal(sess.ProtocolFeatures)
+	if err != nil {
+		return fmt.Errorf("marshaling protocol features: %w", err)
+	}
+	acked, err := json.Marshal(sess.AckedRequestIDs)
+	if err != nil {
+		return fmt.Errorf("marshaling acked request ids: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO agent_sessions (session_id, token_hash, agent_id, principal_id, protocol_features, acked_request_ids, created_at, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, sess.ID, sess.TokenHash, sess.AgentID, nullString(sess.PrincipalID), string(features), string(acked),
+		sess.CreatedAt.UTC().Format(time.RFC3339), sess.LastSeenAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("inserting agent session: %w", err)
+	}
+	return nil
+}
+
+// GetAgentSessionByTokenHash looks up a session by the hash of its token.
+func (s *SQLStore) GetAgentSessionByTokenHash(ctx context.Context, tokenHash string) (*AgentSession, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT session_id, token_hash, agent_id, principal_id, protocol_features, acked_request_ids, created_at, last_seen_at
+		FROM agent_sessions WHERE token_hash = ?
+	`, tokenHash)
+
+	var sess AgentSession
+	var principalID sql.NullString
+	var features, acked, createdAt, lastSeenAt string
+	err := row.Scan(&sess.ID, &sess.TokenHash, &sess.AgentID, &principalID, &features, &acked, &createdAt, &lastSeenAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAgentSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning agent session: %w", err)
+	}
+	sess.PrincipalID = principalID.String
+	if err := json.Unmarshal([]byte(features), &sess.ProtocolFeatures); err != nil {
+		return nil, fmt.Errorf("decoding protocol features: %w", err)
+	}
+	if err := json.Unmarshal([]byte(acked), &sess.AckedRequestIDs); err != nil {
+		return nil, fmt.Errorf("decoding acked request ids: %w", err)
+	}
+	sess.CreatedAt = parseTimeWithWarning(createdAt, "agent_session", sess.ID, "created_at")
+	sess.LastSeenAt = parseTimeWithWarning(lastSeenAt, "agent_session", sess.ID, "last_seen_at")
+	return &sess, nil
+}
+
+// LastAgentSessionSeen returns when an agent holding any session for
+// principalID was last seen.
+func (s *SQLStore) LastAgentSessionSeen(ctx context.Context, principalID string) (time.Time, error) {
+	var lastSeen sql.NullString
+	err := s.db.QueryRowContext(ctx,
+		`SELECT MAX(last_seen_at) FROM agent_sessions WHERE principal_id = ?`, principalID).Scan(&lastSeen)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("querying agent sessions: %w", err)
+	}
+	if !lastSeen.Valid {
+		return time.Time{}, ErrAgentSessionNotFound
+	}
+	return parseTimeWithWarning(lastSeen.String, "agent_session", principalID, "last_seen_at"), nil
+}
+
+// ResumeAgentSession swaps in a new token hash and features for a resumed
+// session and marks it seen. The old token stops working.
+func (s *SQLStore) ResumeAgentSession(ctx context.Context, sessionID, tokenHash string, features []string, at time.Time) error {
+	encoded, err := json.Marshal(features)
+	if err != nil {
+		return fmt.Errorf("marshaling protocol features: %w", err)
+	}
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE agent_sessions SET token_hash = ?, protocol_features = ?, last_seen_at = ? WHERE session_id = ?`,
+		tokenHash, string(encoded), at.UTC().Format(time.RFC3339), sessionID)
+	if err != nil {
+		return fmt.Errorf("resuming agent session: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrAgentSessionNotFound
+	}
+	return nil
+}
+
+// TouchAgentSession records that the session's agent was seen at the given time.
+func (s *SQLStore) TouchAgentSession(ctx context.Context, sessionID string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE agent_sessions SET last_seen_at = ? WHERE session_id = ?`,
+		at.UTC().Format(time.RFC3339), sessionID)
+	if err != nil {
+		return fmt.Errorf("touching agent session: %w", err)
+	}
+	return nil
+}
+
+// DeleteAgentSession removes a session and its in-flight requests.
+func (s *SQLStore) DeleteAgentSession(ctx context.Context, sessionID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM agent_inflight_requests WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("deleting in-flight requests: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM agent_sessions WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("deleting agent session: %w", err)
+	}
+	return nil
+}
+
+// DeleteAgentSessionsByPrincipal removes every session held by a principal,
+// returning how many were removed.
+func (s *SQLStore) DeleteAgentSessionsByPrincipal(ctx context.Context, principalID string) (int64, error) {
+	return s.deleteAgentSessionsWhere(ctx, `principal_id = ?`, principalID)
+}
+
+// DeleteAgentSessionsSeenBefore removes sessions whose agent has not been
+// seen since cutoff, returning how many were removed.
+func (s *SQLStore) DeleteAgentSessionsSeenBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return s.deleteAgentSessionsWhere(ctx, `last_seen_at < ?`, cutoff.UTC().Format(time.RFC3339))
+}
+
+func (s *SQLStore) deleteAgentSessionsWhere(ctx context.Context, where string, arg any) (int64, error) {
+	return deleteAgentSessionsWhere(ctx, s.db, where, arg)
+}
+
+// deleteAgentSessionsWhere removes matching sessions and their in-flight
+// requests through db, the store's database or a transaction.
+func deleteAgentSessionsWhere(ctx context.Context, db execer, where string, arg any) (int64, error) {
+	if _, err := db.ExecContext(ctx,
+		`DELETE FROM agent_inflight_requests WHERE session_id IN (SELECT session_id FROM agent_sessions WHERE `+where+`)`, arg); err != nil {
+		return 0, fmt.Errorf("deleting in-flight requests: %w", err)
+	}
+	result, err := db.ExecContext(ctx, `DELETE FROM agent_sessions WHERE `+where, arg)
+	if err != nil {
+		return 0, fmt.Errorf("deleting agent sessions: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
+
+// RecordInFlightRequest adds a request to its session's in-flight ledger.
+func (s *SQLStore) RecordInFlightRequest(ctx context.Context, req *InFlightRequest) error {
+	if req.StartedAt.IsZero() {
+		req.StartedAt = time.Now()
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO agent_inflight_requests (request_id, session_id, agent_id, thread_id, sender, content, started_at, workspace, channel_context)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (request_id) DO UPDATE SET
+			session_id = excluded.session_id, agent_id = excluded.agent_id, thread_id = excluded.thread_id,
+			sender = excluded.sender, content = excluded.content, started_at = excluded.started_at,
+			workspace = excluded.workspace, channel_context = excluded.channel_context
+	`, req.RequestID, req.SessionID, req.AgentID, req.ThreadID, req.Sender, req.Content,
+		req.StartedAt.UTC().Format(time.RFC3339Nano), nullString(req.Workspace), nullString(req.ChannelContext))
+	if err != nil {
+		return fmt.Errorf("recording in-flight request: %w", err)
+	}
+	return nil
+}
+
+// CompleteInFlightRequest removes a finished request from the ledger and
+// appends it to its session's acked list. Unknown requests are ignored.
+func (s *SQLStore) CompleteInFlightRequest(ctx context.Context, requestID string) error {
+	var sessionID, acked string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT s.session_id, s.acked_request_ids
+		FROM agent_inflight_requests r JOIN agent_sessions s ON s.session_id = r.session_id
+		WHERE r.request_id = ?
+	`, requestID).Scan(&sessionID, &acked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("looking up in-flight request: %w", err)
+	}
+
+	var ids []string
+	if err := json.Unmarshal([]byte(acked), &ids); err != nil {
+		return fmt.Errorf("decoding acked request ids: %w", err)
+	}
+	ids = append(ids, requestID)
+	if len(ids) > MaxAckedRequestIDs {
+		ids = ids[len(ids)-MaxAckedRequestIDs:]
+	}
+	encoded, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("marshaling acked request ids: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `UPDATE agent_sessions SET acked_request_ids = ? WHERE session_id = ?`,
+		string(encoded), sessionID); err != nil {
+		return fmt.Errorf("updating acked request ids: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM agent_inflight_requests WHERE request_id = ?`, requestID); err != nil {
+		return fmt.Errorf("deleting in-flight request: %w", err)
+	}
+	return nil
+}
+
+// ListInFlightRequests returns a session's unfinished requests, oldest first.
+func (s *SQLStore) ListInFlightRequests(ctx context.Context, sessionID string) ([]*InFlightRequest, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT request_id, session_id, agent_id, thread_id, sender, content, started_at, workspace, channel_context
+		FROM agent_inflight_requests WHERE session_id = ? ORDER BY started_at
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("querying in-flight requests: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var reqs []*InFlightRequest
+	for rows.Next() {
+		var r InFlightRequest
+		var startedAt string
+		var workspace, channelContext sql.NullString
+		if err := rows.Scan(&r.RequestID, &r.SessionID, &r.AgentID, &r.ThreadID, &r.Sender, &r.Content, &startedAt, &workspace, &channelContext); err != nil {
+			return nil, fmt.Errorf("scanning in-flight request: %w", err)
+		}
+		r.Workspace = workspace.String
+		r.ChannelContext = channelContext.String
+		r.StartedAt, err = time.Parse(time.RFC3339Nano, startedAt)
+		if err != nil {
+			s.logger.Warn("failed to parse timestamp", "entity_type", "inflight_request", "entity_id", r.RequestID, "error", err)
+		}
+		reqs = append(reqs, &r)
+	}
+	return reqs, rows.Err()
+}
@@ -0,0 +1,123 @@
+// ABOUTME: Tests for persisted agent sessions and the in-flight request ledger
+// ABOUTME: Covers token rotation, acked-ID capping, expiry sweeps, and revocation
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAgentSessions_InFlightLedger(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	sess := &AgentSession{ID: "sess-1", TokenHash: "hash-1", AgentID: "agent-1", ProtocolFeatures: []string{"resume"}}
+	if err := s.CreateAgentSession(ctx, sess); err != nil {
+		t.Fatalf("CreateAgentSession: %v", err)
+	}
+
+	start := time.Now()
+	for i := range MaxAckedRequestIDs + 2 {
+		if err := s.RecordInFlightRequest(ctx, &InFlightRequest{
+			RequestID: fmt.Sprintf("req-%d", i), SessionID: "sess-1", AgentID: "agent-1",
+			ThreadID: "t1", Sender: "user", Content: "hi", Workspace: "api", ChannelContext: "be brief", StartedAt: start.Add(time.Duration(i) * time.Millisecond),
+		}); err != nil {
+			t.Fatalf("RecordInFlightRequest: %v", err)
+		}
+	}
+	// Finish all but the last; the acked list keeps only the newest.
+	for i := range MaxAckedRequestIDs + 1 {
+		if err := s.CompleteInFlightRequest(ctx, fmt.Sprintf("req-%d", i)); err != nil {
+			t.Fatalf("CompleteInFlightRequest: %v", err)
+		}
+	}
+	if err := s.CompleteInFlightRequest(ctx, "unknown"); err != nil {
+		t.Errorf("completing an unknown request: %v", err)
+	}
+
+	inflight, err := s.ListInFlightRequests(ctx, "sess-1")
+	if err != nil {
+		t.Fatalf("ListInFlightRequests: %v", err)
+	}
+	last := fmt.Sprintf("req-%d", MaxAckedRequestIDs+1)
+	if len(inflight) != 1 || inflight[0].RequestID != last || inflight[0].Content != "hi" || inflight[0].Workspace != "api" || inflight[0].ChannelContext != "be brief" {
+		t.Fatalf("in flight = %+v, want only %s", inflight, last)
+	}
+
+	got, err := s.GetAgentSessionByTokenHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("GetAgentSessionByTokenHash: %v", err)
+	}
+	if len(got.AckedRequestIDs) != MaxAckedRequestIDs || got.AckedRequestIDs[0] != "req-1" {
+		t.Errorf("acked = %d ids starting %q, want %d starting req-1", len(got.AckedRequestIDs), got.AckedRequestIDs[0], MaxAckedRequestIDs)
+	}
+
+	// Resuming rotates the token.
+	if err := s.ResumeAgentSession(ctx, "sess-1", "hash-2", []string{"resume", "cancellation"}, time.Now()); err != nil {
+		t.Fatalf("ResumeAgentSession: %v", err)
+	}
+	if _, err := s.GetAgentSessionByTokenHash(ctx, "hash-1"); !errors.Is(err, ErrAgentSessionNotFound) {
+		t.Errorf("old token lookup err = %v, want ErrAgentSessionNotFound", err)
+	}
+	got, err = s.GetAgentSessionByTokenHash(ctx, "hash-2")
+	if err != nil {
+		t.Fatalf("lookup by new token: %v", err)
+	}
+	if len(got.ProtocolFeatures) != 2 {
+		t.Errorf("features = %v, want the renegotiated pair", got.ProtocolFeatures)
+	}
+}
+
+func TestAgentSessions_ExpiryAndRevocation(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := s.CreatePrincipal(ctx, &Principal{
+		ID: "p1", Type: PrincipalTypeAgent, PubkeyFP: "fp-p1", DisplayName: "agent", Status: PrincipalStatusApproved, CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreatePrincipal: %v", err)
+	}
+	sessions := []*AgentSession{
+		{ID: "stale", TokenHash: "h-stale", AgentID: "a1", LastSeenAt: now.Add(-time.Hour)},
+		{ID: "fresh", TokenHash: "h-fresh", AgentID: "a2", LastSeenAt: now},
+		{ID: "owned", TokenHash: "h-owned", AgentID: "a3", PrincipalID: "p1", LastSeenAt: now},
+	}
+	for _, sess := range sessions {
+		if err := s.CreateAgentSession(ctx, sess); err != nil {
+			t.Fatalf("CreateAgentSession: %v", err)
+		}
+	}
+	if err := s.RecordInFlightRequest(ctx, &InFlightRequest{RequestID: "r1", SessionID: "stale", AgentID: "a1", ThreadID: "t", Sender: "u", Content: "c"}); err != nil {
+		t.Fatalf("RecordInFlightRequest: %v", err)
+	}
+
+	n, err := s.DeleteAgentSessionsSeenBefore(ctx, now.Add(-time.Minute))
+	if err != nil || n != 1 {
+		t.Fatalf("DeleteAgentSessionsSeenBefore = %d, %v; want 1", n, err)
+	}
+	if inflight, _ := s.ListInFlightRequests(ctx, "stale"); len(inflight) != 0 {
+		t.Errorf("expired session kept %d in-flight requests", len(inflight))
+	}
+
+	if seen, err := s.LastAgentSessionSeen(ctx, "p1"); err != nil || !seen.Equal(now.Truncate(time.Second)) {
+		t.Errorf("LastAgentSessionSeen = %v, %v; want %v", seen, err, now.Truncate(time.Second))
+	}
+	if _, err := s.LastAgentSessionSeen(ctx, "nobody"); !errors.Is(err, ErrAgentSessionNotFound) {
+		t.Errorf("LastAgentSessionSeen for a principal without sessions err = %v, want ErrAgentSessionNotFound", err)
+	}
+
+	if err := s.UpdatePrincipalStatus(ctx, "p1", PrincipalStatusRevoked); err != nil {
+		t.Fatalf("UpdatePrincipalStatus: %v", err)
+	}
+	if _, err := s.GetAgentSessionByTokenHash(ctx, "h-owned"); !errors.Is(err, ErrAgentSessionNotFound) {
+		t.Errorf("revoked principal's session lookup err = %v, want ErrAgentSessionNotFound", err)
+	}
+	if _, err := s.GetAgentSessionByTokenHash(ctx, "h-fresh"); err != nil {
+		t.Errorf("unrelated session was removed: %v", err)
+	}
+}
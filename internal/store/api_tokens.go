@@ -0,0 +1,229 @@
+// ABOUTME: Records of issued API tokens for listing, revocation, and last-use tracking
+// ABOUTME: Only metadata is stored; the signed token value is never persisted
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrAPITokenNotFound is returned when a token record doesn't exist.
+var ErrAPITokenNotFound = errors.New("api token not found")
+
+// APIToken is the record of an issued API token. The token itself is a JWT
+// whose jti claim is ID.
+type APIToken struct {
+	ID          string
+	PrincipalID string
+	Scopes      []string
+	CreatedBy   string // admin user or principal that minted the token
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time
+	RevokedBy   string
+
+	// AgentIDs, if set, limits the token to these agents (connection or
+	// principal IDs). Capabilities, if set, limits it to this subset of its
+	// principal's capabilities. Nil means unscoped; tokens recorded before
+	// these fields existed are unscoped.
+	AgentIDs     []string
+	Capabilities []string
+}
+
+// Scoped reports whether the token is limited to some agents or capabilities.
+func (t *APIToken) Scoped() bool {
+	return t.AgentIDs != nil || t.Capabilities != nil
+}
+
+// Active reports whether the token is neither revoked nor expired at now.
+func (t *APIToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// CreateAPIToken records a newly issued token.
+func (s *SQLStore) CreateAPIToken(ctx context.Context, t *APIToken) error {
+	scopes, err := json.Marshal(t.Scopes)
+	if err != nil {
+		return fmt.Errorf("encoding scopes: %w", err)
+	}
+	agentIDs, err := nullableJSONList(t.AgentIDs)
+	if err != nil {
+		return fmt.Errorf("encoding agent ids: %w", err)
+	}
+	capabilities, err := nullableJSONList(t.Capabilities)
+	if err != nil {
+		return fmt.Errorf("encoding capabilities: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO api_tokens (token_id, principal_id, scopes, created_by, created_at, expires_at, agent_ids, capabilities)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.ID, t.PrincipalID, string(scopes), nullString(t.CreatedBy),
+		t.CreatedAt.UTC().Format(time.RFC3339), t.ExpiresAt.UTC().Format(time.RFC3339), agentIDs, capabilities)
+	if err != nil {
+		return fmt.Errorf("inserting api token: %w", err)
+	}
+	return nil
+}
+
+// GetAPIToken retrieves a token record by ID.
+func (s *SQLStore) GetAPIToken(ctx context.Context, id string) (*APIToken, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT token_id, principal_id, scopes, created_by, created_at, expires_at, last_used_at, revoked_at, revoked_by,
+		       agent_ids, capabilities
+		FROM api_tokens
+		WHERE token_id = ?
+	`, id)
+	t, err := scanAPIToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPITokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListAPITokens returns token records, newest first. An empty principalID
+// lists tokens for every principal.
+func (s *SQLStore) ListAPITokens(ctx context.Context, principalID string) ([]*APIToken, error) {
+	query := `
+		SELECT token_id, principal_id, scopes, created_by, created_at, expires_at, last_used_at, revoked_at, revoked_by,
+		       agent_ids, capabilities
+		FROM api_tokens`
+	var args []any
+	if principalID != "" {
+		query += ` WHERE principal_id = ?`
+		args = append(args, principalID)
+	}
+	query += ` ORDER BY created_at DESC, token_id`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying api tokens: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var tokens []*APIToken
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating api tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken marks a token revoked. Revoking an already revoked token is
+// a no-op that keeps the original revocation time.
+func (s *SQLStore) RevokeAPIToken(ctx context.Context, id, revokedBy string) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE api_tokens SET revoked_at = ?, revoked_by = ?
+		WHERE token_id = ? AND revoked_at IS NULL
+	`, time.Now().UTC().Format(time.RFC3339), nullString(revokedBy), id)
+	if err != nil {
+		return fmt.Errorf("revoking api token: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		if _, err := s.GetAPIToken(ctx, id); err != nil {
+			return err
+		}
+		return nil // already revoked, nothing to audit
+	}
+	return commitAuditedTx(ctx, tx)
+}
+
+// TouchAPIToken records that a token was used at the given time.
+func (s *SQLStore) TouchAPIToken(ctx context.Context, id string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE api_tokens SET last_used_at = ? WHERE token_id = ?`,
+		at.UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return fmt.Errorf("updating api token last use: %w", err)
+	}
+	return nil
+}
+
+// SetAdminUserPrincipal links an admin user to the client principal their
+// API tokens are minted for.
+func (s *SQLStore) SetAdminUserPrincipal(ctx context.Context, userID, principalID string) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE admin_users SET principal_id = ? WHERE id = ?`, principalID, userID)
+	if err != nil {
+		return fmt.Errorf("linking admin user principal: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrAdminUserNotFound
+	}
+	return nil
+}
+
+// nullableJSONList encodes list as JSON, or NULL when it is nil so that an
+// unset token scope stays distinct from an empty one.
+func nullableJSONList(list []string) (any, error) {
+	if list == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func scanAPIToken(row interface{ Scan(...any) error }) (*APIToken, error) {
+	var (
+		t                              APIToken
+		scopes, createdAt, expiresAt   string
+		createdBy, lastUsed, revokedAt sql.NullString
+		revokedBy                      sql.NullString
+		agentIDs, capabilities         sql.NullString
+	)
+	err := row.Scan(&t.ID, &t.PrincipalID, &scopes, &createdBy, &createdAt, &expiresAt, &lastUsed, &revokedAt, &revokedBy,
+		&agentIDs, &capabilities)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scanning api token: %w", err)
+	}
+	if err := json.Unmarshal([]byte(scopes), &t.Scopes); err != nil {
+		return nil, fmt.Errorf("decoding scopes for token %s: %w", t.ID, err)
+	}
+	if agentIDs.Valid {
+		if err := json.Unmarshal([]byte(agentIDs.String), &t.AgentIDs); err != nil {
+			return nil, fmt.Errorf("decoding agent ids for token %s: %w", t.ID, err)
+		}
+	}
+	if capabilities.Valid {
+		if err := json.Unmarshal([]byte(capabilities.String), &t.Capabilities); err != nil {
+			return nil, fmt.Errorf("decoding capabilities for token %s: %w", t.ID, err)
+		}
+	}
+	t.CreatedBy = createdBy.String
+	t.RevokedBy = revokedBy.String
+	t.CreatedAt = parseTimeWithWarning(createdAt, "api_token", t.ID, "created_at")
+	t.ExpiresAt = parseTimeWithWarning(expiresAt, "api_token", t.ID, "expires_at")
+	if lastUsed.Valid {
+		at := parseTimeWithWarning(lastUsed.String, "api_token", t.ID, "last_used_at")
+		t.LastUsedAt = &at
+	}
+	if revokedAt.Valid {
+		at := parseTimeWithWarning(revokedAt.String, "api_token", t.ID, "revoked_at")
+		t.RevokedAt = &at
+	}
+	return &t, nil
+}
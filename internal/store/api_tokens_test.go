@@ -0,0 +1,113 @@
+// ABOUTME: Tests for API token record persistence
+// ABOUTME: Covers listing by principal, revocation, last-use updates, and admin user linking
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokens_CreateListRevoke(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, store.CreateAPIToken(ctx, &APIToken{
+		ID: "tok-1", PrincipalID: "p1", Scopes: []string{"client"}, CreatedBy: "alice",
+		CreatedAt: base, ExpiresAt: base.Add(24 * time.Hour),
+	}))
+	require.NoError(t, store.CreateAPIToken(ctx, &APIToken{
+		ID: "tok-2", PrincipalID: "p2", Scopes: []string{"client", "admin"},
+		CreatedAt: base.Add(time.Hour), ExpiresAt: base.Add(48 * time.Hour),
+	}))
+
+	all, err := store.ListAPITokens(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "tok-2", all[0].ID, "newest first")
+	assert.Equal(t, []string{"client", "admin"}, all[0].Scopes)
+
+	mine, err := store.ListAPITokens(ctx, "p1")
+	require.NoError(t, err)
+	require.Len(t, mine, 1)
+	tok := mine[0]
+	assert.Equal(t, "alice", tok.CreatedBy)
+	assert.Equal(t, base, tok.CreatedAt)
+	assert.Nil(t, tok.LastUsedAt)
+	assert.True(t, tok.Active(base.Add(time.Hour)))
+	assert.False(t, tok.Active(base.Add(25*time.Hour)), "expired")
+
+	used := base.Add(2 * time.Hour)
+	require.NoError(t, store.TouchAPIToken(ctx, "tok-1", used))
+	require.NoError(t, store.RevokeAPIToken(ctx, "tok-1", "bob"))
+
+	tok, err = store.GetAPIToken(ctx, "tok-1")
+	require.NoError(t, err)
+	require.NotNil(t, tok.LastUsedAt)
+	assert.Equal(t, used, *tok.LastUsedAt)
+	require.NotNil(t, tok.RevokedAt)
+	assert.Equal(t, "bob", tok.RevokedBy)
+	assert.False(t, tok.Active(base))
+
+	// Revoking twice keeps the first revocation.
+	require.NoError(t, store.RevokeAPIToken(ctx, "tok-1", "carol"))
+	tok, err = store.GetAPIToken(ctx, "tok-1")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", tok.RevokedBy)
+
+	assert.ErrorIs(t, store.RevokeAPIToken(ctx, "missing", "bob"), ErrAPITokenNotFound)
+	_, err = store.GetAPIToken(ctx, "missing")
+	assert.ErrorIs(t, err, ErrAPITokenNotFound)
+}
+
+func TestAPITokens_Scope(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, store.CreateAPIToken(ctx, &APIToken{
+		ID: "scoped", PrincipalID: "p1", Scopes: []string{"client"},
+		AgentIDs: []string{"agent-a"}, Capabilities: []string{"notes"},
+		CreatedAt: base, ExpiresAt: base.Add(time.Hour),
+	}))
+	require.NoError(t, store.CreateAPIToken(ctx, &APIToken{
+		ID: "unscoped", PrincipalID: "p1", Scopes: []string{"client"},
+		CreatedAt: base, ExpiresAt: base.Add(time.Hour),
+	}))
+
+	tok, err := store.GetAPIToken(ctx, "scoped")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"agent-a"}, tok.AgentIDs)
+	assert.Equal(t, []string{"notes"}, tok.Capabilities)
+	assert.True(t, tok.Scoped())
+
+	tok, err = store.GetAPIToken(ctx, "unscoped")
+	require.NoError(t, err)
+	assert.Nil(t, tok.AgentIDs)
+	assert.Nil(t, tok.Capabilities)
+	assert.False(t, tok.Scoped())
+}
+
+func TestSetAdminUserPrincipal(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.CreateAdminUser(ctx, &AdminUser{
+		ID: "u1", Username: "alice", DisplayName: "Alice", CreatedAt: time.Now(),
+	}))
+	user, err := store.GetAdminUser(ctx, "u1")
+	require.NoError(t, err)
+	assert.Empty(t, user.PrincipalID)
+
+	require.NoError(t, store.SetAdminUserPrincipal(ctx, "u1", "p1"))
+	user, err = store.GetAdminUserByUsername(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "p1", user.PrincipalID)
+
+	assert.ErrorIs(t, store.SetAdminUserPrincipal(ctx, "missing", "p1"), ErrAdminUserNotFound)
+}
@@ -0,0 +1,80 @@
+// ABOUTME: Files attached to thread messages: uploads sent with /api/send and files agents return
+// ABOUTME: Stored as blobs keyed by ID so clients can download them through /api/attachments/{id}
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Attachment is a file belonging to a thread, either uploaded with a user
+// message or produced by the agent.
+type Attachment struct {
+	ID          string
+	ThreadID    string
+	MessageID   string // user message it was sent with; empty for agent files
+	Filename    string
+	ContentType string
+	Size        int64
+	Data        []byte
+	UploadedBy  string // principal that uploaded it; empty for agent files and unauthenticated sends
+	CreatedAt   time.Time
+}
+
+// SaveAttachment stores an attachment. Size is taken from Data.
+func (s *SQLStore) SaveAttachment(ctx context.Context, a *Attachment) error {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	a.Size = int64(len(a.Data))
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO attachments (id, thread_id, message_id, filename, content_type, size, data, uploaded_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, a.ID, a.ThreadID, nullString(a.MessageID), a.Filename, a.ContentType, a.Size, a.Data, nullString(a.UploadedBy),
+		a.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("inserting attachment: %w", err)
+	}
+	return nil
+}
+
+// GetAttachment returns an attachment with its data, or ErrNotFound.
+func (s *SQLStore) GetAttachment(ctx context.Context, id string) (*Attachment, error) {
+	var a Attachment
+	var messageID, uploadedBy sql.NullString
+	var createdAt string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, thread_id, message_id, filename, content_type, size, data, uploaded_by, created_at
+		FROM attachments WHERE id = ?
+	`, id).Scan(&a.ID, &a.ThreadID, &messageID, &a.Filename, &a.ContentType, &a.Size, &a.Data, &uploadedBy, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying attachment: %w", err)
+	}
+	a.MessageID = messageID.String
+	a.UploadedBy = uploadedBy.String
+	a.CreatedAt = parseTimeWithWarning(createdAt, "attachment", a.ID, "created_at")
+	return &a, nil
+}
+
+// ThreadHasActor reports whether principalID authored any ledger event in
+// threadID, which is how a client shows it took part in a thread.
+func (s *SQLStore) ThreadHasActor(ctx context.Context, threadID, principalID string) (bool, error) {
+	var one int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT 1 FROM ledger_events WHERE thread_id = ? AND actor_principal_id = ? LIMIT 1
+	`, threadID, principalID).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("querying thread actors: %w", err)
+	}
+	return true, nil
+}
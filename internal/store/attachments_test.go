@@ -0,0 +1,79 @@
+// ABOUTME: Tests for thread attachments
+// ABOUTME: Covers round-tripping data and metadata, missing IDs, and thread actor lookups
+
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAttachments(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	a := &Attachment{
+		ID:          "att-1",
+		ThreadID:    "thread-1",
+		MessageID:   "msg-1",
+		Filename:    "notes.txt",
+		ContentType: "text/plain",
+		Data:        []byte("hello"),
+		UploadedBy:  "principal-1",
+	}
+	if err := s.SaveAttachment(ctx, a); err != nil {
+		t.Fatalf("SaveAttachment: %v", err)
+	}
+
+	got, err := s.GetAttachment(ctx, "att-1")
+	if err != nil {
+		t.Fatalf("GetAttachment: %v", err)
+	}
+	if got.ThreadID != "thread-1" || got.MessageID != "msg-1" || got.Filename != "notes.txt" ||
+		got.ContentType != "text/plain" || got.Size != 5 || !bytes.Equal(got.Data, []byte("hello")) ||
+		got.UploadedBy != "principal-1" || got.CreatedAt.IsZero() {
+		t.Errorf("got %+v", got)
+	}
+
+	if _, err := s.GetAttachment(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetAttachment(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestThreadHasActor(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	threadID, actor := "thread-1", "principal-1"
+	text := "hi"
+	if err := s.SaveEvent(ctx, &LedgerEvent{
+		ID:               "evt-1",
+		ConversationKey:  "agent-1",
+		ThreadID:         &threadID,
+		Direction:        EventDirectionInbound,
+		Author:           "alice",
+		Timestamp:        time.Now(),
+		Type:             EventTypeMessage,
+		Text:             &text,
+		ActorPrincipalID: &actor,
+	}); err != nil {
+		t.Fatalf("SaveEvent: %v", err)
+	}
+
+	for _, tt := range []struct {
+		thread, principal string
+		want              bool
+	}{
+		{"thread-1", "principal-1", true},
+		{"thread-1", "principal-2", false},
+		{"thread-2", "principal-1", false},
+	} {
+		got, err := s.ThreadHasActor(ctx, tt.thread, tt.principal)
+		if err != nil || got != tt.want {
+			t.Errorf("ThreadHasActor(%s, %s) = %v, %v; want %v", tt.thread, tt.principal, got, err, tt.want)
+		}
+	}
+}
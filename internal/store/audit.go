@@ -1,5 +1,6 @@
 // ABOUTME: Audit log entity and store methods for tracking administrative actions
 // ABOUTME: Records who did what to which resource for compliance and debugging
+// ABOUTME: Entries carried by a context are written in the same transaction as the audited change
 
 package store
 
@@ -16,16 +17,38 @@ import (
 type AuditAction string
 
 const (
-	AuditApprovePrincipal AuditAction = "approve_principal"
-	AuditRevokePrincipal  AuditAction = "revoke_principal"
-	AuditGrantCapability  AuditAction = "grant_capability"
-	AuditRevokeCapability AuditAction = "revoke_capability"
-	AuditCreateBinding    AuditAction = "create_binding"
-	AuditUpdateBinding    AuditAction = "update_binding"
-	AuditDeleteBinding    AuditAction = "delete_binding"
-	AuditCreateToken      AuditAction = "create_token"
-	AuditCreatePrincipal  AuditAction = "create_principal"
-	AuditDeletePrincipal  AuditAction = "delete_principal"
+	AuditApprovePrincipal       AuditAction = "approve_principal"
+	AuditRevokePrincipal        AuditAction = "revoke_principal"
+	AuditGrantCapability        AuditAction = "grant_capability"
+	AuditRevokeCapability       AuditAction = "revoke_capability"
+	AuditCreateBinding          AuditAction = "create_binding"
+	AuditUpdateBinding          AuditAction = "update_binding"
+	AuditDeleteBinding          AuditAction = "delete_binding"
+	AuditCreateToken            AuditAction = "create_token"
+	AuditCreatePrincipal        AuditAction = "create_principal"
+	AuditDeletePrincipal        AuditAction = "delete_principal"
+	AuditMergeThreads           AuditAction = "merge_threads"
+	AuditSplitThread            AuditAction = "split_thread"
+	AuditPauseAgent             AuditAction = "pause_agent"
+	AuditResumeAgent            AuditAction = "resume_agent"
+	AuditUpdateFlag             AuditAction = "update_feature_flag"
+	AuditAnswerQuestion         AuditAction = "answer_question"
+	AuditRevokeToken            AuditAction = "revoke_token"
+	AuditCreateSecret           AuditAction = "create_secret"
+	AuditUpdateSecret           AuditAction = "update_secret"
+	AuditDeleteSecret           AuditAction = "delete_secret"
+	AuditCreateInvite           AuditAction = "create_invite"
+	AuditCreateToolPolicy       AuditAction = "create_tool_policy"
+	AuditUpdateToolPolicy       AuditAction = "update_tool_policy"
+	AuditDeleteToolPolicy       AuditAction = "delete_tool_policy"
+	AuditRevokeSession          AuditAction = "revoke_session"
+	AuditAddProjectMember       AuditAction = "add_project_member"
+	AuditRemoveProjectMember    AuditAction = "remove_project_member"
+	AuditAddDelegationTarget    AuditAction = "add_delegation_target"
+	AuditRemoveDelegationTarget AuditAction = "remove_delegation_target"
+	AuditDenyAdminCall          AuditAction = "deny_admin_call"
+	AuditClearLoginLockout      AuditAction = "clear_login_lockout"
+	AuditSetToolOverride        AuditAction = "set_tool_override"
 )
 
 // ValidAuditActions lists all valid audit actions.
@@ -40,6 +63,28 @@ var ValidAuditActions = []AuditAction{
 	AuditCreateToken,
 	AuditCreatePrincipal,
 	AuditDeletePrincipal,
+	AuditMergeThreads,
+	AuditSplitThread,
+	AuditPauseAgent,
+	AuditResumeAgent,
+	AuditUpdateFlag,
+	AuditAnswerQuestion,
+	AuditRevokeToken,
+	AuditCreateSecret,
+	AuditUpdateSecret,
+	AuditDeleteSecret,
+	AuditCreateInvite,
+	AuditCreateToolPolicy,
+	AuditUpdateToolPolicy,
+	AuditDeleteToolPolicy,
+	AuditRevokeSession,
+	AuditAddProjectMember,
+	AuditRemoveProjectMember,
+	AuditAddDelegationTarget,
+	AuditRemoveDelegationTarget,
+	AuditDenyAdminCall,
+	AuditClearLoginLockout,
+	AuditSetToolOverride,
 }
 
 // AuditEntry represents a single audit log entry.
@@ -48,10 +93,11 @@ type AuditEntry struct {
 	ActorPrincipalID string         // who performed the action
 	ActorMemberID    *string        // associated member (nil in v1)
 	Action           AuditAction    // what action was performed
-	TargetType       string         // "principal", "capability", "binding"
+	TargetType       string         // "principal", "capability", "binding", "thread", "rpc"
 	TargetID         string         // ID of the affected resource
 	Timestamp        time.Time      // when it happened
 	Detail           map[string]any // additional context (max 64KB JSON)
+	SourceIP         string         // address the request came from, if known
 }
 
 // AuditFilter specifies filtering options for listing audit entries.
@@ -63,11 +109,58 @@ type AuditFilter struct {
 	TargetType       *string      // filter by target type
 	TargetID         *string      // filter by target ID
 	Limit            int          // max results (default 100, max 1000)
+	Offset           int          // pagination offset
+}
+
+type auditContextKey struct{}
+
+// WithAuditEntry returns a context carrying e. The store mutations behind
+// audited admin actions (principal status, creation and deletion; binding,
+// secret and invite changes; token revocation) append e in the same
+// transaction as their change, so a crash cannot keep one without the other.
+func WithAuditEntry(ctx context.Context, e *AuditEntry) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, e)
+}
+
+// appendContextAuditTx appends the entry carried by ctx, if any, within tx.
+func appendContextAuditTx(ctx context.Context, tx *dbTx) error {
+	e, _ := ctx.Value(auditContextKey{}).(*AuditEntry)
+	if e == nil {
+		return nil
+	}
+	return insertAuditEntry(ctx, tx, e)
+}
+
+// commitAuditedTx appends the entry carried by ctx, if any, and commits tx.
+func commitAuditedTx(ctx context.Context, tx *dbTx) error {
+	if err := appendContextAuditTx(ctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
 }
 
 // AppendAuditLog appends a new entry to the audit log.
 // Generates ID and Timestamp if not set.
-func (s *SQLiteStore) AppendAuditLog(ctx context.Context, e *AuditEntry) error {
+func (s *SQLStore) AppendAuditLog(ctx context.Context, e *AuditEntry) error {
+	if err := insertAuditEntry(ctx, s.db, e); err != nil {
+		return err
+	}
+
+	s.logger.Debug("appended audit log",
+		"id", e.ID,
+		"actor", e.ActorPrincipalID,
+		"action", e.Action,
+		"target", e.TargetType+"/"+e.TargetID,
+	)
+	return nil
+}
+
+// insertAuditEntry writes e through db, the store's database or a
+// transaction, generating its ID and Timestamp if not set.
+func insertAuditEntry(ctx context.Context, db execer, e *AuditEntry) error {
 	// Generate ID if not set
 	if e.ID == "" {
 		e.ID = uuid.New().String()
@@ -89,11 +182,11 @@ func (s *SQLiteStore) AppendAuditLog(ctx context.Context, e *AuditEntry) error {
 	}
 
 	query := `
-		INSERT INTO audit_log (audit_id, actor_principal_id, actor_member_id, action, target_type, target_id, ts, detail_json)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO audit_log (audit_id, actor_principal_id, actor_member_id, action, target_type, target_id, ts, detail_json, source_ip)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.ExecContext(ctx, query,
+	_, err := db.ExecContext(ctx, query,
 		e.ID,
 		e.ActorPrincipalID,
 		e.ActorMemberID,
@@ -102,17 +195,11 @@ func (s *SQLiteStore) AppendAuditLog(ctx context.Context, e *AuditEntry) error {
 		e.TargetID,
 		e.Timestamp.UTC().Format(time.RFC3339),
 		detailJSON,
+		nullString(e.SourceIP),
 	)
 	if err != nil {
 		return fmt.Errorf("inserting audit entry: %w", err)
 	}
-
-	s.logger.Debug("appended audit log",
-		"id", e.ID,
-		"actor", e.ActorPrincipalID,
-		"action", e.Action,
-		"target", e.TargetType+"/"+e.TargetID,
-	)
 	return nil
 }
 
@@ -157,7 +244,7 @@ func buildAuditQueryArgs(f AuditFilter) auditQueryArgs {
 func scanAuditEntry(scanner interface{ Scan(dest ...any) error }) (AuditEntry, error) {
 	var e AuditEntry
 	var actionStr, tsStr string
-	var detailJSON *string
+	var detailJSON, sourceIP *string
 
 	if err := scanner.Scan(
 		&e.ID,
@@ -168,11 +255,15 @@ func scanAuditEntry(scanner interface{ Scan(dest ...any) error }) (AuditEntry, e
 		&e.TargetID,
 		&tsStr,
 		&detailJSON,
+		&sourceIP,
 	); err != nil {
 		return e, fmt.Errorf("scanning audit entry: %w", err)
 	}
 
 	e.Action = AuditAction(actionStr)
+	if sourceIP != nil {
+		e.SourceIP = *sourceIP
+	}
 	var err error
 	e.Timestamp, err = time.Parse(time.RFC3339, tsStr)
 	if err != nil {
@@ -187,22 +278,24 @@ func scanAuditEntry(scanner interface{ Scan(dest ...any) error }) (AuditEntry, e
 	return e, nil
 }
 
+// auditLogQuery casts the parameters it only tests for NULL, since Postgres
+// cannot infer a type for them.
 const auditLogQuery = `
-	SELECT audit_id, actor_principal_id, actor_member_id, action, target_type, target_id, ts, detail_json
+	SELECT audit_id, actor_principal_id, actor_member_id, action, target_type, target_id, ts, detail_json, source_ip
 	FROM audit_log
-	WHERE (? IS NULL OR ts >= ?)
-	  AND (? IS NULL OR ts <= ?)
-	  AND (? IS NULL OR actor_principal_id = ?)
-	  AND (? IS NULL OR action = ?)
-	  AND (? IS NULL OR target_type = ?)
-	  AND (? IS NULL OR target_id = ?)
-	ORDER BY ts DESC
-	LIMIT ?
+	WHERE (CAST(? AS TEXT) IS NULL OR ts >= ?)
+	  AND (CAST(? AS TEXT) IS NULL OR ts <= ?)
+	  AND (CAST(? AS TEXT) IS NULL OR actor_principal_id = ?)
+	  AND (CAST(? AS TEXT) IS NULL OR action = ?)
+	  AND (CAST(? AS TEXT) IS NULL OR target_type = ?)
+	  AND (CAST(? AS TEXT) IS NULL OR target_id = ?)
+	ORDER BY ts DESC, rowid DESC
+	LIMIT ? OFFSET ?
 `
 
 // ListAuditLog returns audit entries matching the filter criteria.
 // Results are returned newest first (DESC by timestamp).
-func (s *SQLiteStore) ListAuditLog(ctx context.Context, f AuditFilter) ([]AuditEntry, error) {
+func (s *SQLStore) ListAuditLog(ctx context.Context, f AuditFilter) ([]AuditEntry, error) {
 	limit := normalizeAuditLimit(f.Limit)
 	args := buildAuditQueryArgs(f)
 
@@ -213,7 +306,7 @@ func (s *SQLiteStore) ListAuditLog(ctx context.Context, f AuditFilter) ([]AuditE
 		args.actionStr, args.actionStr,
 		f.TargetType, f.TargetType,
 		f.TargetID, f.TargetID,
-		limit,
+		limit, max(f.Offset, 0),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("querying audit log: %w", err)
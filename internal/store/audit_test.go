@@ -1,5 +1,5 @@
 // ABOUTME: Tests for audit log store operations
-// ABOUTME: Covers Append and List with filtering for the audit_log table
+// ABOUTME: Covers Append and List with filtering for the audit_log table, and entries written with their mutation
 
 package store
 
@@ -185,6 +185,15 @@ func TestAuditStore_List_Pagination(t *testing.T) {
 	entries, err := store.ListAuditLog(ctx, AuditFilter{Limit: 2})
 	require.NoError(t, err)
 	assert.Len(t, entries, 2)
+
+	// Pages do not overlap and the last one is short
+	next, err := store.ListAuditLog(ctx, AuditFilter{Limit: 2, Offset: 2})
+	require.NoError(t, err)
+	require.Len(t, next, 2)
+	assert.NotEqual(t, entries[1].ID, next[0].ID)
+	last, err := store.ListAuditLog(ctx, AuditFilter{Limit: 2, Offset: 4})
+	require.NoError(t, err)
+	assert.Len(t, last, 1)
 }
 
 func TestAuditStore_Append_WithMemberID(t *testing.T) {
@@ -209,3 +218,77 @@ func TestAuditStore_Append_WithMemberID(t *testing.T) {
 	require.NotNil(t, entries[0].ActorMemberID)
 	assert.Equal(t, memberID, *entries[0].ActorMemberID)
 }
+
+func TestAuditStore_Append_SourceIP(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.AppendAuditLog(ctx, &AuditEntry{
+		ActorPrincipalID: "principal-123",
+		Action:           AuditDeletePrincipal,
+		TargetType:       "principal",
+		TargetID:         "principal-456",
+		SourceIP:         "203.0.113.7",
+	}))
+
+	entries, err := store.ListAuditLog(ctx, AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "203.0.113.7", entries[0].SourceIP)
+}
+
+func TestAuditStore_WithAuditEntry(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	principal := &Principal{
+		ID: "agent-1", Type: PrincipalTypeAgent, PubkeyFP: "fp-1", DisplayName: "Agent",
+		Status: PrincipalStatusPending, CreatedAt: time.Now(),
+	}
+	require.NoError(t, store.CreatePrincipal(ctx, principal))
+
+	t.Run("written with the mutation", func(t *testing.T) {
+		auditCtx := WithAuditEntry(ctx, &AuditEntry{
+			ActorPrincipalID: "admin-1",
+			Action:           AuditApprovePrincipal,
+			TargetType:       "principal",
+			TargetID:         "agent-1",
+		})
+		require.NoError(t, store.UpdatePrincipalStatus(auditCtx, "agent-1", PrincipalStatusApproved))
+
+		action := AuditApprovePrincipal
+		entries, err := store.ListAuditLog(ctx, AuditFilter{Action: &action})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "agent-1", entries[0].TargetID)
+	})
+
+	t.Run("not written when the mutation fails", func(t *testing.T) {
+		auditCtx := WithAuditEntry(ctx, &AuditEntry{
+			ActorPrincipalID: "admin-1",
+			Action:           AuditDeletePrincipal,
+			TargetType:       "principal",
+			TargetID:         "missing",
+		})
+		require.ErrorIs(t, store.DeletePrincipal(auditCtx, "missing"), ErrPrincipalNotFound)
+
+		action := AuditDeletePrincipal
+		entries, err := store.ListAuditLog(ctx, AuditFilter{Action: &action})
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("mutation rolled back when the entry fails", func(t *testing.T) {
+		auditCtx := WithAuditEntry(ctx, &AuditEntry{
+			ActorPrincipalID: "admin-1",
+			Action:           AuditAction("not_an_action"),
+			TargetType:       "principal",
+			TargetID:         "agent-1",
+		})
+		require.Error(t, store.UpdatePrincipalStatus(auditCtx, "agent-1", PrincipalStatusRevoked))
+
+		got, err := store.GetPrincipal(ctx, "agent-1")
+		require.NoError(t, err)
+		assert.Equal(t, PrincipalStatusApproved, got.Status)
+	})
+}
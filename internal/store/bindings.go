@@ -5,7 +5,10 @@ package store
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -14,11 +17,16 @@ import (
 
 // Binding errors.
 var (
-	ErrBindingNotFound  = errors.New("binding not found")
-	ErrDuplicateChannel = errors.New("duplicate frontend+channel_id combination")
-	ErrAgentNotFound    = errors.New("agent not found or not of type agent")
+	ErrBindingNotFound       = errors.New("binding not found")
+	ErrDuplicateChannel      = errors.New("duplicate frontend+channel_id combination")
+	ErrAgentNotFound         = errors.New("agent not found or not of type agent")
+	ErrBindingContextTooLong = fmt.Errorf("binding context exceeds %d bytes", MaxBindingContextBytes)
 )
 
+// MaxBindingContextBytes bounds a binding's channel context, which is sent
+// with every message through the binding.
+const MaxBindingContextBytes = 4096
+
 // Binding represents a channel-to-agent mapping for message routing.
 type Binding struct {
 	ID         string    // UUID v4
@@ -28,6 +36,29 @@ type Binding struct {
 	WorkingDir string    // filesystem path where the agent operates (optional, empty string if not set)
 	CreatedAt  time.Time // when the binding was created
 	CreatedBy  *string   // principal_id who created it (optional)
+	// MaxResponseDuration overrides the gateway's response limit for messages
+	// routed through this binding (zero means use the default).
+	MaxResponseDuration time.Duration
+	// FallbackAgentIDs are tried in order when AgentID has been offline
+	// longer than its reconnect grace period.
+	FallbackAgentIDs []string
+	// Workspace is the agent workspace messages through this binding target
+	// unless the send names one (empty means the agent's default).
+	Workspace string
+	// Context is channel-specific guidance (e.g. "answer tersely") sent to
+	// the agent alongside each message through this binding. Empty for none.
+	Context string
+}
+
+// BindingContextHash identifies a version of a binding's context, so the
+// ledger can record which one was in effect for an exchange without
+// copying it. It is empty for an empty context.
+func BindingContextHash(text string) string {
+	if text == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
 }
 
 // BindingFilter specifies filtering options for listing bindings.
@@ -37,7 +68,7 @@ type BindingFilter struct {
 }
 
 // validateAgent checks that the given ID exists in principals with type='agent'.
-func (s *SQLiteStore) validateAgent(ctx context.Context, agentID string) error {
+func (s *SQLStore) validateAgent(ctx context.Context, agentID string) error {
 	query := `SELECT type FROM principals WHERE principal_id = ?`
 
 	var principalType string
@@ -59,15 +90,24 @@ func (s *SQLiteStore) validateAgent(ctx context.Context, agentID string) error {
 // CreateBindingV2 creates a new channel binding in the database.
 // The agent_id must exist in principals with type='agent'.
 // Named V2 to distinguish from legacy CreateBinding method.
-func (s *SQLiteStore) CreateBindingV2(ctx context.Context, b *Binding) error {
-	// Validate that the agent exists and is of type agent
-	if err := s.validateAgent(ctx, b.AgentID); err != nil {
+func (s *SQLStore) CreateBindingV2(ctx context.Context, b *Binding) error {
+	if len(b.Context) > MaxBindingContextBytes {
+		return ErrBindingContextTooLong
+	}
+	// Validate that the agent and its fallbacks exist and are of type agent
+	for _, agentID := range append([]string{b.AgentID}, b.FallbackAgentIDs...) {
+		if err := s.validateAgent(ctx, agentID); err != nil {
+			return err
+		}
+	}
+	fallbacks, err := fallbackAgentsJSON(b.FallbackAgentIDs)
+	if err != nil {
 		return err
 	}
 
 	query := `
-		INSERT INTO bindings (binding_id, frontend, channel_id, agent_id, working_dir, created_at, created_by)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO bindings (binding_id, frontend, channel_id, agent_id, working_dir, created_at, created_by, max_response_seconds, fallback_agents, workspace, context)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	// Convert empty string to NULL for working_dir
@@ -76,7 +116,13 @@ func (s *SQLiteStore) CreateBindingV2(ctx context.Context, b *Binding) error {
 		workingDir = b.WorkingDir
 	}
 
-	_, err := s.db.ExecContext(ctx, query,
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, query,
 		b.ID,
 		b.Frontend,
 		b.ChannelID,
@@ -84,6 +130,10 @@ func (s *SQLiteStore) CreateBindingV2(ctx context.Context, b *Binding) error {
 		workingDir,
 		b.CreatedAt.UTC().Format(time.RFC3339),
 		b.CreatedBy,
+		maxResponseSeconds(b.MaxResponseDuration),
+		fallbacks,
+		nullString(b.Workspace),
+		nullString(b.Context),
 	)
 	if err != nil {
 		if isDuplicateChannelError(err) {
@@ -91,15 +141,18 @@ func (s *SQLiteStore) CreateBindingV2(ctx context.Context, b *Binding) error {
 		}
 		return fmt.Errorf("inserting binding: %w", err)
 	}
+	if err := commitAuditedTx(ctx, tx); err != nil {
+		return err
+	}
 
 	s.logger.Debug("created binding", "id", b.ID, "frontend", b.Frontend, "channel", b.ChannelID)
 	return nil
 }
 
 // GetBindingByID retrieves a binding by its ID.
-func (s *SQLiteStore) GetBindingByID(ctx context.Context, id string) (*Binding, error) {
+func (s *SQLStore) GetBindingByID(ctx context.Context, id string) (*Binding, error) {
 	query := `
-		SELECT binding_id, frontend, channel_id, agent_id, working_dir, created_at, created_by
+		SELECT binding_id, frontend, channel_id, agent_id, working_dir, created_at, created_by, max_response_seconds, fallback_agents, workspace, context
 		FROM bindings
 		WHERE binding_id = ?
 	`
@@ -108,9 +161,9 @@ func (s *SQLiteStore) GetBindingByID(ctx context.Context, id string) (*Binding,
 }
 
 // GetBindingByChannel retrieves a binding by frontend and channel_id.
-func (s *SQLiteStore) GetBindingByChannel(ctx context.Context, frontend, channelID string) (*Binding, error) {
+func (s *SQLStore) GetBindingByChannel(ctx context.Context, frontend, channelID string) (*Binding, error) {
 	query := `
-		SELECT binding_id, frontend, channel_id, agent_id, working_dir, created_at, created_by
+		SELECT binding_id, frontend, channel_id, agent_id, working_dir, created_at, created_by, max_response_seconds, fallback_agents, workspace, context
 		FROM bindings
 		WHERE frontend = ? AND channel_id = ?
 	`
@@ -120,7 +173,7 @@ func (s *SQLiteStore) GetBindingByChannel(ctx context.Context, frontend, channel
 
 // UpdateBinding updates a binding's agent_id.
 // The new agent_id must exist in principals with type='agent'.
-func (s *SQLiteStore) UpdateBinding(ctx context.Context, id, agentID string) error {
+func (s *SQLStore) UpdateBinding(ctx context.Context, id, agentID string) error {
 	// Validate that the new agent exists
 	if err := s.validateAgent(ctx, agentID); err != nil {
 		return err
@@ -128,7 +181,13 @@ func (s *SQLiteStore) UpdateBinding(ctx context.Context, id, agentID string) err
 
 	query := `UPDATE bindings SET agent_id = ? WHERE binding_id = ?`
 
-	result, err := s.db.ExecContext(ctx, query, agentID, id)
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, query, agentID, id)
 	if err != nil {
 		return fmt.Errorf("updating binding: %w", err)
 	}
@@ -141,16 +200,112 @@ func (s *SQLiteStore) UpdateBinding(ctx context.Context, id, agentID string) err
 	if rowsAffected == 0 {
 		return ErrBindingNotFound
 	}
+	if err := commitAuditedTx(ctx, tx); err != nil {
+		return err
+	}
 
 	s.logger.Debug("updated binding", "id", id, "agent_id", agentID)
 	return nil
 }
 
+// SetBindingMaxResponseDuration sets a binding's response limit override.
+// Zero clears it so the gateway default applies.
+func (s *SQLStore) SetBindingMaxResponseDuration(ctx context.Context, id string, d time.Duration) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE bindings SET max_response_seconds = ? WHERE binding_id = ?`, maxResponseSeconds(d), id)
+	if err != nil {
+		return fmt.Errorf("updating binding response limit: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrBindingNotFound
+	}
+
+	s.logger.Debug("updated binding response limit", "id", id, "max_response_duration", d)
+	return nil
+}
+
+// SetBindingWorkspace sets the workspace a binding's messages target.
+// Empty clears it so the agent's default workspace applies.
+func (s *SQLStore) SetBindingWorkspace(ctx context.Context, id, workspace string) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE bindings SET workspace = ? WHERE binding_id = ?`, nullString(workspace), id)
+	if err != nil {
+		return fmt.Errorf("updating binding workspace: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrBindingNotFound
+	}
+
+	s.logger.Debug("updated binding workspace", "id", id, "workspace", workspace)
+	return nil
+}
+
+// SetBindingContext sets the channel context sent with a binding's
+// messages. Empty clears it.
+func (s *SQLStore) SetBindingContext(ctx context.Context, id, text string) error {
+	if len(text) > MaxBindingContextBytes {
+		return ErrBindingContextTooLong
+	}
+	result, err := s.db.ExecContext(ctx, `UPDATE bindings SET context = ? WHERE binding_id = ?`, nullString(text), id)
+	if err != nil {
+		return fmt.Errorf("updating binding context: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrBindingNotFound
+	}
+
+	s.logger.Debug("updated binding context", "id", id, "context_hash", BindingContextHash(text))
+	return nil
+}
+
+// fallbackAgentsJSON stores fallback agent IDs as a JSON array, NULL when
+// there are none.
+func fallbackAgentsJSON(ids []string) (any, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(ids)
+	if err != nil {
+		return nil, fmt.Errorf("encoding fallback agents: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// maxResponseSeconds stores a response limit as whole seconds, NULL when unset.
+func maxResponseSeconds(d time.Duration) any {
+	if d <= 0 {
+		return nil
+	}
+	return int64(d / time.Second)
+}
+
 // DeleteBindingByID deletes a binding by its ID.
-func (s *SQLiteStore) DeleteBindingByID(ctx context.Context, id string) error {
+func (s *SQLStore) DeleteBindingByID(ctx context.Context, id string) error {
 	query := `DELETE FROM bindings WHERE binding_id = ?`
 
-	result, err := s.db.ExecContext(ctx, query, id)
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("deleting binding: %w", err)
 	}
@@ -163,13 +318,16 @@ func (s *SQLiteStore) DeleteBindingByID(ctx context.Context, id string) error {
 	if rowsAffected == 0 {
 		return ErrBindingNotFound
 	}
+	if err := commitAuditedTx(ctx, tx); err != nil {
+		return err
+	}
 
 	s.logger.Debug("deleted binding", "id", id)
 	return nil
 }
 
 // DeleteBindingByChannel deletes a binding by frontend and channel_id.
-func (s *SQLiteStore) DeleteBindingByChannel(ctx context.Context, frontend, channelID string) error {
+func (s *SQLStore) DeleteBindingByChannel(ctx context.Context, frontend, channelID string) error {
 	query := `DELETE FROM bindings WHERE frontend = ? AND channel_id = ?`
 
 	result, err := s.db.ExecContext(ctx, query, frontend, channelID)
@@ -192,12 +350,12 @@ func (s *SQLiteStore) DeleteBindingByChannel(ctx context.Context, frontend, chan
 
 // ListBindingsV2 returns bindings matching the filter criteria.
 // Named V2 to avoid collision with existing ListBindings method.
-func (s *SQLiteStore) ListBindingsV2(ctx context.Context, f BindingFilter) ([]Binding, error) {
+func (s *SQLStore) ListBindingsV2(ctx context.Context, f BindingFilter) ([]Binding, error) {
 	query := `
-		SELECT binding_id, frontend, channel_id, agent_id, working_dir, created_at, created_by
+		SELECT binding_id, frontend, channel_id, agent_id, working_dir, created_at, created_by, max_response_seconds, fallback_agents, workspace, context
 		FROM bindings
-		WHERE (? IS NULL OR frontend = ?)
-		  AND (? IS NULL OR agent_id = ?)
+		WHERE (CAST(? AS TEXT) IS NULL OR frontend = ?)
+		  AND (CAST(? AS TEXT) IS NULL OR agent_id = ?)
 		ORDER BY created_at DESC
 	`
 
@@ -235,11 +393,13 @@ func (s *SQLiteStore) ListBindingsV2(ctx context.Context, f BindingFilter) ([]Bi
 }
 
 // scanBinding scans a single binding row from a sql.Row.
-func (s *SQLiteStore) scanBinding(row *sql.Row) (*Binding, error) {
+func (s *SQLStore) scanBinding(row *sql.Row) (*Binding, error) {
 	var b Binding
 	var createdAtStr string
 	var createdBy *string
 	var workingDir sql.NullString
+	var maxSeconds sql.NullInt64
+	var fallbacks, workspace, bindingContext sql.NullString
 
 	err := row.Scan(
 		&b.ID,
@@ -249,6 +409,10 @@ func (s *SQLiteStore) scanBinding(row *sql.Row) (*Binding, error) {
 		&workingDir,
 		&createdAtStr,
 		&createdBy,
+		&maxSeconds,
+		&fallbacks,
+		&workspace,
+		&bindingContext,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -267,16 +431,26 @@ func (s *SQLiteStore) scanBinding(row *sql.Row) (*Binding, error) {
 	if workingDir.Valid {
 		b.WorkingDir = workingDir.String
 	}
+	b.MaxResponseDuration = time.Duration(maxSeconds.Int64) * time.Second
+	b.Workspace = workspace.String
+	b.Context = bindingContext.String
+	if fallbacks.Valid {
+		if err := json.Unmarshal([]byte(fallbacks.String), &b.FallbackAgentIDs); err != nil {
+			return nil, fmt.Errorf("parsing fallback_agents: %w", err)
+		}
+	}
 
 	return &b, nil
 }
 
 // scanBindingRow scans a binding from sql.Rows (for list queries).
-func (s *SQLiteStore) scanBindingRow(rows *sql.Rows) (*Binding, error) {
+func (s *SQLStore) scanBindingRow(rows *sql.Rows) (*Binding, error) {
 	var b Binding
 	var createdAtStr string
 	var createdBy *string
 	var workingDir sql.NullString
+	var maxSeconds sql.NullInt64
+	var fallbacks, workspace, bindingContext sql.NullString
 
 	err := rows.Scan(
 		&b.ID,
@@ -286,6 +460,10 @@ func (s *SQLiteStore) scanBindingRow(rows *sql.Rows) (*Binding, error) {
 		&workingDir,
 		&createdAtStr,
 		&createdBy,
+		&maxSeconds,
+		&fallbacks,
+		&workspace,
+		&bindingContext,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("scanning binding row: %w", err)
@@ -300,6 +478,14 @@ func (s *SQLiteStore) scanBindingRow(rows *sql.Rows) (*Binding, error) {
 	if workingDir.Valid {
 		b.WorkingDir = workingDir.String
 	}
+	b.MaxResponseDuration = time.Duration(maxSeconds.Int64) * time.Second
+	b.Workspace = workspace.String
+	b.Context = bindingContext.String
+	if fallbacks.Valid {
+		if err := json.Unmarshal([]byte(fallbacks.String), &b.FallbackAgentIDs); err != nil {
+			return nil, fmt.Errorf("parsing fallback_agents: %w", err)
+		}
+	}
 
 	return &b, nil
 }
@@ -313,7 +499,8 @@ func isDuplicateChannelError(err error) bool {
 	errStr := err.Error()
 	return strings.Contains(errStr, "UNIQUE constraint failed") &&
 		(strings.Contains(errStr, "bindings.frontend") ||
-			strings.Contains(errStr, "frontend, channel_id"))
+			strings.Contains(errStr, "frontend, channel_id")) ||
+		isPostgresConstraintViolation(err, "bindings_frontend_channel_id_key")
 }
 
 // UpdateBindingsByWorkspace updates all bindings whose agent_id ends with "_<workspace>"
@@ -321,12 +508,12 @@ func isDuplicateChannelError(err error) bool {
 // update bindings when an agent reconnects with a different prefix (e.g., when a device
 // reconnects with a new prefix like "magic_notes" instead of "m_notes").
 // Returns the number of bindings updated.
-func (s *SQLiteStore) UpdateBindingsByWorkspace(ctx context.Context, workspace, newAgentID string) (int64, error) {
+func (s *SQLStore) UpdateBindingsByWorkspace(ctx context.Context, workspace, newAgentID string) (int64, error) {
 	// Match agent_ids that end with "_<workspace>"
 	// e.g., workspace="notes" matches "m_notes", "magic_notes", etc.
 	pattern := "%_" + workspace
 
-	query := `UPDATE bindings SET agent_id = ? WHERE agent_id LIKE ? AND agent_id != ?`
+	query := `UPDATE bindings SET agent_id = ? WHERE agent_id ` + s.dialect.like() + ` ? AND agent_id != ?`
 
 	result, err := s.db.ExecContext(ctx, query, newAgentID, pattern, newAgentID)
 	if err != nil {
@@ -348,3 +535,12 @@ func (s *SQLiteStore) UpdateBindingsByWorkspace(ctx context.Context, workspace,
 
 	return rowsAffected, nil
 }
+
+// CountBindings returns the total number of channel bindings.
+func (s *SQLStore) CountBindings(ctx context.Context) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM bindings`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("counting bindings: %w", err)
+	}
+	return count, nil
+}
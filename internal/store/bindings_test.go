@@ -14,7 +14,7 @@ import (
 )
 
 // createTestAgent creates a principal with type="agent" for binding tests.
-func createTestAgent(t *testing.T, store *SQLiteStore, id string) {
+func createTestAgent(t *testing.T, store *SQLStore, id string) {
 	t.Helper()
 	// Create a unique 64-char hex fingerprint from the full ID
 	// Pad the ID with the ID repeated until we have 64 chars
@@ -221,6 +221,103 @@ func TestBindingStore_Update(t *testing.T) {
 	assert.Equal(t, "agent-002", retrieved.AgentID)
 }
 
+func TestBindingStore_MaxResponseDuration(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	createTestAgent(t, store, "agent-001")
+
+	binding := &Binding{
+		ID:                  "binding-limit",
+		Frontend:            "slack",
+		ChannelID:           "C777",
+		AgentID:             "agent-001",
+		CreatedAt:           time.Now().UTC().Truncate(time.Second),
+		MaxResponseDuration: 10 * time.Minute,
+	}
+	require.NoError(t, store.CreateBindingV2(ctx, binding))
+
+	retrieved, err := store.GetBindingByChannel(ctx, "slack", "C777")
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, retrieved.MaxResponseDuration)
+
+	require.NoError(t, store.SetBindingMaxResponseDuration(ctx, "binding-limit", 0))
+	retrieved, err = store.GetBindingByID(ctx, "binding-limit")
+	require.NoError(t, err)
+	assert.Zero(t, retrieved.MaxResponseDuration)
+
+	assert.ErrorIs(t, store.SetBindingMaxResponseDuration(ctx, "nonexistent", time.Minute), ErrBindingNotFound)
+}
+
+func TestBindingStore_Workspace(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	createTestAgent(t, store, "agent-001")
+
+	binding := &Binding{
+		ID:        "binding-workspace",
+		Frontend:  "matrix",
+		ChannelID: "!room:example.org",
+		AgentID:   "agent-001",
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Workspace: "api",
+	}
+	require.NoError(t, store.CreateBindingV2(ctx, binding))
+
+	retrieved, err := store.GetBindingByChannel(ctx, "matrix", "!room:example.org")
+	require.NoError(t, err)
+	assert.Equal(t, "api", retrieved.Workspace)
+
+	require.NoError(t, store.SetBindingWorkspace(ctx, "binding-workspace", ""))
+	bindings, err := store.ListBindingsV2(ctx, BindingFilter{})
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	assert.Empty(t, bindings[0].Workspace)
+
+	assert.ErrorIs(t, store.SetBindingWorkspace(ctx, "nonexistent", "api"), ErrBindingNotFound)
+}
+
+func TestBindingStore_Context(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	createTestAgent(t, store, "agent-001")
+
+	binding := &Binding{
+		ID:        "binding-context",
+		Frontend:  "slack",
+		ChannelID: "#support",
+		AgentID:   "agent-001",
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Context:   "Answer tersely.",
+	}
+	require.NoError(t, store.CreateBindingV2(ctx, binding))
+
+	retrieved, err := store.GetBindingByID(ctx, "binding-context")
+	require.NoError(t, err)
+	assert.Equal(t, "Answer tersely.", retrieved.Context)
+
+	require.NoError(t, store.SetBindingContext(ctx, "binding-context", ""))
+	bindings, err := store.ListBindingsV2(ctx, BindingFilter{})
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	assert.Empty(t, bindings[0].Context)
+
+	tooLong := strings.Repeat("x", MaxBindingContextBytes+1)
+	assert.ErrorIs(t, store.SetBindingContext(ctx, "binding-context", tooLong), ErrBindingContextTooLong)
+	binding.ID, binding.ChannelID, binding.Context = "binding-long", "#long", tooLong
+	assert.ErrorIs(t, store.CreateBindingV2(ctx, binding), ErrBindingContextTooLong)
+	assert.ErrorIs(t, store.SetBindingContext(ctx, "nonexistent", "hi"), ErrBindingNotFound)
+}
+
+func TestBindingContextHash(t *testing.T) {
+	assert.Empty(t, BindingContextHash(""))
+	assert.Len(t, BindingContextHash("Answer tersely."), 64)
+	assert.Equal(t, BindingContextHash("a"), BindingContextHash("a"))
+	assert.NotEqual(t, BindingContextHash("a"), BindingContextHash("b"))
+}
+
 func TestBindingStore_Update_NotFound(t *testing.T) {
 	store := setupTestStore(t)
 	ctx := context.Background()
@@ -542,3 +639,41 @@ func TestUpdateBindingsByWorkspace_AlreadyCurrent(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, int64(0), count, "should not update binding that already has correct agent")
 }
+
+func TestBindingStore_FallbackAgents(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"agent-001", "agent-002", "agent-003"} {
+		createTestAgent(t, store, id)
+	}
+
+	binding := &Binding{
+		ID:               "binding-fallback",
+		Frontend:         "slack",
+		ChannelID:        "C888",
+		AgentID:          "agent-001",
+		CreatedAt:        time.Now().UTC().Truncate(time.Second),
+		FallbackAgentIDs: []string{"agent-003", "agent-002"},
+	}
+	require.NoError(t, store.CreateBindingV2(ctx, binding))
+
+	retrieved, err := store.GetBindingByChannel(ctx, "slack", "C888")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"agent-003", "agent-002"}, retrieved.FallbackAgentIDs)
+
+	list, err := store.ListBindingsV2(ctx, BindingFilter{})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, []string{"agent-003", "agent-002"}, list[0].FallbackAgentIDs)
+
+	binding = &Binding{
+		ID:               "binding-bad-fallback",
+		Frontend:         "slack",
+		ChannelID:        "C889",
+		AgentID:          "agent-001",
+		CreatedAt:        time.Now().UTC().Truncate(time.Second),
+		FallbackAgentIDs: []string{"no-such-agent"},
+	}
+	assert.ErrorIs(t, store.CreateBindingV2(ctx, binding), ErrAgentNotFound)
+}
@@ -1,4 +1,4 @@
-// ABOUTME: SQLite implementation of BuiltinStore for built-in tool pack data.
+// ABOUTME: SQLStore implementation of BuiltinStore for built-in tool pack data.
 // ABOUTME: Handles log entries, todos, BBS posts, mail, and notes persistence.
 
 package store
@@ -10,13 +10,15 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
-// Ensure SQLiteStore implements BuiltinStore.
-var _ BuiltinStore = (*SQLiteStore)(nil)
+// Ensure SQLStore implements BuiltinStore.
+var _ BuiltinStore = (*SQLStore)(nil)
 
 // parseTimeWithWarning parses a time string, logging a warning on failure.
 // Returns zero time on parse error (callers should handle appropriately).
@@ -29,7 +31,7 @@ func parseTimeWithWarning(s, entityType, entityID, field string) time.Time {
 }
 
 // CreateLogEntry creates a new log entry.
-func (s *SQLiteStore) CreateLogEntry(ctx context.Context, entry *LogEntry) error {
+func (s *SQLStore) CreateLogEntry(ctx context.Context, entry *LogEntry) error {
 	if entry.ID == "" {
 		entry.ID = uuid.New().String()
 	}
@@ -57,7 +59,7 @@ func (s *SQLiteStore) CreateLogEntry(ctx context.Context, entry *LogEntry) error
 
 // SearchLogEntries searches log entries by message content.
 // If agentID is non-empty, results are scoped to that agent.
-func (s *SQLiteStore) SearchLogEntries(ctx context.Context, agentID string, query string, since *time.Time, limit int) ([]*LogEntry, error) {
+func (s *SQLStore) SearchLogEntries(ctx context.Context, agentID string, query string, since *time.Time, limit int) ([]*LogEntry, error) {
 	if limit <= 0 {
 		limit = 50
 	}
@@ -70,7 +72,7 @@ func (s *SQLiteStore) SearchLogEntries(ctx context.Context, agentID string, quer
 		args = append(args, agentID)
 	}
 	if query != "" {
-		sqlQuery += ` AND message LIKE ?`
+		sqlQuery += ` AND message ` + s.dialect.like() + ` ?`
 		args = append(args, "%"+query+"%")
 	}
 	if since != nil {
@@ -106,8 +108,11 @@ func (s *SQLiteStore) SearchLogEntries(ctx context.Context, agentID string, quer
 	return entries, rows.Err()
 }
 
+// todoColumns lists the todo columns in the order scanTodo reads them.
+const todoColumns = `id, agent_id, description, status, priority, notes, due_date, assignee_agent_id, project, created_at, updated_at`
+
 // CreateTodo creates a new todo.
-func (s *SQLiteStore) CreateTodo(ctx context.Context, todo *Todo) error {
+func (s *SQLStore) CreateTodo(ctx context.Context, todo *Todo) error {
 	if todo.ID == "" {
 		todo.ID = uuid.New().String()
 	}
@@ -123,65 +128,87 @@ func (s *SQLiteStore) CreateTodo(ctx context.Context, todo *Todo) error {
 		todo.Priority = "medium"
 	}
 
-	var dueDate *string
-	if todo.DueDate != nil {
-		d := todo.DueDate.Format(time.RFC3339)
-		dueDate = &d
-	}
-
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO todos (id, agent_id, description, status, priority, notes, due_date, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, todo.ID, todo.AgentID, todo.Description, todo.Status, todo.Priority, todo.Notes, dueDate,
+		INSERT INTO todos (`+todoColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, todo.ID, todo.AgentID, todo.Description, todo.Status, todo.Priority, todo.Notes, formatTodoDueDate(todo.DueDate),
+		nullString(todo.AssigneeAgentID), nullString(todo.Project),
 		todo.CreatedAt.Format(time.RFC3339), todo.UpdatedAt.Format(time.RFC3339))
 
 	return err
 }
 
-// GetTodo retrieves a todo by ID.
-func (s *SQLiteStore) GetTodo(ctx context.Context, id string) (*Todo, error) {
+// formatTodoDueDate returns the due_date column value for d.
+func formatTodoDueDate(d *time.Time) *string {
+	if d == nil {
+		return nil
+	}
+	formatted := d.Format(time.RFC3339)
+	return &formatted
+}
+
+// scanTodo reads a row of todoColumns.
+func scanTodo(row interface{ Scan(...any) error }) (*Todo, error) {
 	var t Todo
-	var notes, dueDate sql.NullString
+	var notes, dueDate, assignee, project sql.NullString
 	var createdAt, updatedAt string
-
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, agent_id, description, status, priority, notes, due_date, created_at, updated_at
-		FROM todos WHERE id = ?
-	`, id).Scan(&t.ID, &t.AgentID, &t.Description, &t.Status, &t.Priority, &notes, &dueDate, &createdAt, &updatedAt)
-
-	if err == sql.ErrNoRows {
-		return nil, ErrNotFound
-	}
-	if err != nil {
+	if err := row.Scan(&t.ID, &t.AgentID, &t.Description, &t.Status, &t.Priority, &notes, &dueDate, &assignee, &project, &createdAt, &updatedAt); err != nil {
 		return nil, err
 	}
-
 	t.CreatedAt = parseTimeWithWarning(createdAt, "todo", t.ID, "created_at")
 	t.UpdatedAt = parseTimeWithWarning(updatedAt, "todo", t.ID, "updated_at")
 	t.Notes = notes.String
+	t.AssigneeAgentID = assignee.String
+	t.Project = project.String
 	if dueDate.Valid {
 		parsed := parseTimeWithWarning(dueDate.String, "todo", t.ID, "due_date")
 		if !parsed.IsZero() {
 			t.DueDate = &parsed
 		}
 	}
-
 	return &t, nil
 }
 
-// ListTodos lists todos for an agent with optional filters.
-func (s *SQLiteStore) ListTodos(ctx context.Context, agentID string, status, priority string) ([]*Todo, error) {
+// GetTodo retrieves a todo by ID.
+func (s *SQLStore) GetTodo(ctx context.Context, id string) (*Todo, error) {
+	t, err := scanTodo(s.db.QueryRowContext(ctx, `SELECT `+todoColumns+` FROM todos WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListTodos lists the todos matching filter, newest first.
+func (s *SQLStore) ListTodos(ctx context.Context, filter TodoFilter) ([]*Todo, error) {
 	var args []any
-	sqlQuery := `SELECT id, agent_id, description, status, priority, notes, due_date, created_at, updated_at FROM todos WHERE agent_id = ?`
-	args = append(args, agentID)
+	sqlQuery := `SELECT ` + todoColumns + ` FROM todos WHERE 1 = 1`
 
-	if status != "" {
+	if filter.AgentID != "" {
+		sqlQuery += ` AND agent_id = ?`
+		args = append(args, filter.AgentID)
+	}
+	if filter.AssigneeAgentID != "" {
+		sqlQuery += ` AND assignee_agent_id = ?`
+		args = append(args, filter.AssigneeAgentID)
+	}
+	if filter.Project != "" {
+		sqlQuery += ` AND project = ?`
+		args = append(args, filter.Project)
+	}
+	if filter.Status != "" {
 		sqlQuery += ` AND status = ?`
-		args = append(args, status)
+		args = append(args, filter.Status)
 	}
-	if priority != "" {
+	if filter.Priority != "" {
 		sqlQuery += ` AND priority = ?`
-		args = append(args, priority)
+		args = append(args, filter.Priority)
+	}
+	if filter.VisibleTo != "" {
+		sqlQuery += ` AND (agent_id = ? OR project IN (SELECT project FROM project_members WHERE agent_id = ?))`
+		args = append(args, filter.VisibleTo, filter.VisibleTo)
 	}
 	sqlQuery += ` ORDER BY created_at DESC`
 
@@ -193,34 +220,23 @@ func (s *SQLiteStore) ListTodos(ctx context.Context, agentID string, status, pri
 
 	var todos []*Todo
 	for rows.Next() {
-		var t Todo
-		var notes, dueDate sql.NullString
-		var createdAt, updatedAt string
-		if err := rows.Scan(&t.ID, &t.AgentID, &t.Description, &t.Status, &t.Priority, &notes, &dueDate, &createdAt, &updatedAt); err != nil {
+		t, err := scanTodo(rows)
+		if err != nil {
 			return nil, err
 		}
-		t.CreatedAt = parseTimeWithWarning(createdAt, "todo", t.ID, "created_at")
-		t.UpdatedAt = parseTimeWithWarning(updatedAt, "todo", t.ID, "updated_at")
-		t.Notes = notes.String
-		if dueDate.Valid {
-			parsed := parseTimeWithWarning(dueDate.String, "todo", t.ID, "due_date")
-			if !parsed.IsZero() {
-				t.DueDate = &parsed
-			}
-		}
-		todos = append(todos, &t)
+		todos = append(todos, t)
 	}
 	return todos, rows.Err()
 }
 
 // ListAllTodos lists all todos across all agents.
-func (s *SQLiteStore) ListAllTodos(ctx context.Context, limit int) ([]*Todo, error) {
+func (s *SQLStore) ListAllTodos(ctx context.Context, limit int) ([]*Todo, error) {
 	if limit <= 0 {
 		limit = 100
 	}
 
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, agent_id, description, status, priority, notes, due_date, created_at, updated_at
+		SELECT `+todoColumns+`
 		FROM todos ORDER BY created_at DESC LIMIT ?
 	`, limit)
 	if err != nil {
@@ -230,40 +246,24 @@ func (s *SQLiteStore) ListAllTodos(ctx context.Context, limit int) ([]*Todo, err
 
 	var todos []*Todo
 	for rows.Next() {
-		var t Todo
-		var notes, dueDate sql.NullString
-		var createdAt, updatedAt string
-		if err := rows.Scan(&t.ID, &t.AgentID, &t.Description, &t.Status, &t.Priority, &notes, &dueDate, &createdAt, &updatedAt); err != nil {
+		t, err := scanTodo(rows)
+		if err != nil {
 			return nil, err
 		}
-		t.CreatedAt = parseTimeWithWarning(createdAt, "todo", t.ID, "created_at")
-		t.UpdatedAt = parseTimeWithWarning(updatedAt, "todo", t.ID, "updated_at")
-		t.Notes = notes.String
-		if dueDate.Valid {
-			parsed := parseTimeWithWarning(dueDate.String, "todo", t.ID, "due_date")
-			if !parsed.IsZero() {
-				t.DueDate = &parsed
-			}
-		}
-		todos = append(todos, &t)
+		todos = append(todos, t)
 	}
 	return todos, rows.Err()
 }
 
 // UpdateTodo updates an existing todo.
-func (s *SQLiteStore) UpdateTodo(ctx context.Context, todo *Todo) error {
+func (s *SQLStore) UpdateTodo(ctx context.Context, todo *Todo) error {
 	todo.UpdatedAt = time.Now()
 
-	var dueDate *string
-	if todo.DueDate != nil {
-		d := todo.DueDate.Format(time.RFC3339)
-		dueDate = &d
-	}
-
 	result, err := s.db.ExecContext(ctx, `
-		UPDATE todos SET description = ?, status = ?, priority = ?, notes = ?, due_date = ?, updated_at = ?
+		UPDATE todos SET description = ?, status = ?, priority = ?, notes = ?, due_date = ?, assignee_agent_id = ?, project = ?, updated_at = ?
 		WHERE id = ?
-	`, todo.Description, todo.Status, todo.Priority, todo.Notes, dueDate, todo.UpdatedAt.Format(time.RFC3339), todo.ID)
+	`, todo.Description, todo.Status, todo.Priority, todo.Notes, formatTodoDueDate(todo.DueDate),
+		nullString(todo.AssigneeAgentID), nullString(todo.Project), todo.UpdatedAt.Format(time.RFC3339), todo.ID)
 
 	if err != nil {
 		return err
@@ -276,7 +276,7 @@ func (s *SQLiteStore) UpdateTodo(ctx context.Context, todo *Todo) error {
 }
 
 // DeleteTodo deletes a todo by ID.
-func (s *SQLiteStore) DeleteTodo(ctx context.Context, id string) error {
+func (s *SQLStore) DeleteTodo(ctx context.Context, id string) error {
 	result, err := s.db.ExecContext(ctx, `DELETE FROM todos WHERE id = ?`, id)
 	if err != nil {
 		return err
@@ -288,8 +288,130 @@ func (s *SQLiteStore) DeleteTodo(ctx context.Context, id string) error {
 	return nil
 }
 
+// AddProjectMember lets agentID see and work on the project's todos. Adding
+// an existing member is a no-op.
+func (s *SQLStore) AddProjectMember(ctx context.Context, project, agentID string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO project_members (project, agent_id, added_at) VALUES (?, ?, ?)
+		ON CONFLICT(project, agent_id) DO NOTHING
+	`, project, agentID, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("adding project member: %w", err)
+	}
+	return nil
+}
+
+// RemoveProjectMember removes agentID from the project. The agent's own
+// todos in the project are kept. Returns ErrNotFound if it was not a member.
+func (s *SQLStore) RemoveProjectMember(ctx context.Context, project, agentID string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM project_members WHERE project = ? AND agent_id = ?`, project, agentID)
+	if err != nil {
+		return fmt.Errorf("removing project member: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// IsProjectMember reports whether agentID is a member of the project.
+func (s *SQLStore) IsProjectMember(ctx context.Context, project, agentID string) (bool, error) {
+	var one int
+	err := s.db.QueryRowContext(ctx, `SELECT 1 FROM project_members WHERE project = ? AND agent_id = ?`, project, agentID).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("checking project membership: %w", err)
+	}
+	return true, nil
+}
+
+// ListProjectMembers lists every project membership, ordered by project and
+// then agent.
+func (s *SQLStore) ListProjectMembers(ctx context.Context) ([]*ProjectMember, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT project, agent_id, added_at FROM project_members ORDER BY project, agent_id`)
+	if err != nil {
+		return nil, fmt.Errorf("listing project members: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var members []*ProjectMember
+	for rows.Next() {
+		var m ProjectMember
+		var addedAt string
+		if err := rows.Scan(&m.Project, &m.AgentID, &addedAt); err != nil {
+			return nil, fmt.Errorf("scanning project member: %w", err)
+		}
+		m.AddedAt = parseTimeWithWarning(addedAt, "project_member", m.Project+"/"+m.AgentID, "added_at")
+		members = append(members, &m)
+	}
+	return members, rows.Err()
+}
+
+// AddDelegationTarget lets agentID delegate tasks to targetAgentID. Adding
+// an existing target is a no-op.
+func (s *SQLStore) AddDelegationTarget(ctx context.Context, agentID, targetAgentID string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO delegation_targets (agent_id, target_agent_id, added_at) VALUES (?, ?, ?)
+		ON CONFLICT(agent_id, target_agent_id) DO NOTHING
+	`, agentID, targetAgentID, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("adding delegation target: %w", err)
+	}
+	return nil
+}
+
+// RemoveDelegationTarget stops agentID delegating to targetAgentID. Returns
+// ErrNotFound if it was not allowed to.
+func (s *SQLStore) RemoveDelegationTarget(ctx context.Context, agentID, targetAgentID string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM delegation_targets WHERE agent_id = ? AND target_agent_id = ?`, agentID, targetAgentID)
+	if err != nil {
+		return fmt.Errorf("removing delegation target: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CanDelegate reports whether agentID may delegate tasks to targetAgentID.
+func (s *SQLStore) CanDelegate(ctx context.Context, agentID, targetAgentID string) (bool, error) {
+	var one int
+	err := s.db.QueryRowContext(ctx, `SELECT 1 FROM delegation_targets WHERE agent_id = ? AND target_agent_id = ?`, agentID, targetAgentID).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("checking delegation target: %w", err)
+	}
+	return true, nil
+}
+
+// ListDelegationTargets lists every allowed delegation, ordered by agent and
+// then target.
+func (s *SQLStore) ListDelegationTargets(ctx context.Context) ([]*DelegationTarget, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT agent_id, target_agent_id, added_at FROM delegation_targets ORDER BY agent_id, target_agent_id`)
+	if err != nil {
+		return nil, fmt.Errorf("listing delegation targets: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var targets []*DelegationTarget
+	for rows.Next() {
+		var d DelegationTarget
+		var addedAt string
+		if err := rows.Scan(&d.AgentID, &d.TargetAgentID, &addedAt); err != nil {
+			return nil, fmt.Errorf("scanning delegation target: %w", err)
+		}
+		d.AddedAt = parseTimeWithWarning(addedAt, "delegation_target", d.AgentID+"/"+d.TargetAgentID, "added_at")
+		targets = append(targets, &d)
+	}
+	return targets, rows.Err()
+}
+
 // CreateBBSPost creates a new BBS post or reply.
-func (s *SQLiteStore) CreateBBSPost(ctx context.Context, post *BBSPost) error {
+func (s *SQLStore) CreateBBSPost(ctx context.Context, post *BBSPost) error {
 	if post.ID == "" {
 		post.ID = uuid.New().String()
 	}
@@ -306,50 +428,58 @@ func (s *SQLiteStore) CreateBBSPost(ctx context.Context, post *BBSPost) error {
 		subject = &post.Subject
 	}
 
+	if post.AuthorKind == "" {
+		post.AuthorKind = BBSAuthorAgent
+	}
+	var authorName *string
+	if post.AuthorName != "" {
+		authorName = &post.AuthorName
+	}
+
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO bbs_posts (id, agent_id, thread_id, subject, content, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, post.ID, post.AgentID, threadID, subject, post.Content, post.CreatedAt.Format(time.RFC3339))
+		INSERT INTO bbs_posts (id, agent_id, thread_id, subject, content, author_kind, author_name, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, post.ID, post.AgentID, threadID, subject, post.Content, post.AuthorKind, authorName, post.CreatedAt.Format(time.RFC3339))
 
 	return err
 }
 
 // GetBBSPost retrieves a single post by ID.
-func (s *SQLiteStore) GetBBSPost(ctx context.Context, id string) (*BBSPost, error) {
-	var p BBSPost
-	var threadID, subject sql.NullString
-	var createdAt string
-
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, agent_id, thread_id, subject, content, created_at
+func (s *SQLStore) GetBBSPost(ctx context.Context, id string) (*BBSPost, error) {
+	p, err := scanBBSPost(s.db.QueryRowContext(ctx, `
+		SELECT `+bbsPostColumns+`
 		FROM bbs_posts WHERE id = ?
-	`, id).Scan(&p.ID, &p.AgentID, &threadID, &subject, &p.Content, &createdAt)
-
+	`, id))
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
+	return p, nil
+}
+
+// bbsPostColumns is the column list scanBBSPost expects.
+const bbsPostColumns = `id, agent_id, thread_id, subject, content, author_kind, author_name, pinned, created_at`
 
+// scanBBSPost reads one bbs_posts row selected with bbsPostColumns.
+func scanBBSPost(row interface{ Scan(dest ...any) error }) (*BBSPost, error) {
+	var p BBSPost
+	var threadID, subject, authorName sql.NullString
+	var createdAt string
+	if err := row.Scan(&p.ID, &p.AgentID, &threadID, &subject, &p.Content, &p.AuthorKind, &authorName, &p.Pinned, &createdAt); err != nil {
+		return nil, err
+	}
 	p.ThreadID = threadID.String
 	p.Subject = subject.String
+	p.AuthorName = authorName.String
 	p.CreatedAt = parseTimeWithWarning(createdAt, "bbs_post", p.ID, "created_at")
-
 	return &p, nil
 }
 
-// ListBBSThreads lists top-level threads (posts with no thread_id).
-func (s *SQLiteStore) ListBBSThreads(ctx context.Context, limit int) ([]*BBSPost, error) {
-	if limit <= 0 {
-		limit = 50
-	}
-
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, agent_id, thread_id, subject, content, created_at
-		FROM bbs_posts WHERE thread_id IS NULL
-		ORDER BY created_at DESC LIMIT ?
-	`, limit)
+// queryBBSPosts runs a query selecting bbsPostColumns and scans every row.
+func (s *SQLStore) queryBBSPosts(ctx context.Context, query string, args ...any) ([]*BBSPost, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -357,119 +487,194 @@ func (s *SQLiteStore) ListBBSThreads(ctx context.Context, limit int) ([]*BBSPost
 
 	var posts []*BBSPost
 	for rows.Next() {
-		var p BBSPost
-		var threadID, subject sql.NullString
-		var createdAt string
-		if err := rows.Scan(&p.ID, &p.AgentID, &threadID, &subject, &p.Content, &createdAt); err != nil {
+		p, err := scanBBSPost(rows)
+		if err != nil {
 			return nil, err
 		}
-		p.ThreadID = threadID.String
-		p.Subject = subject.String
-		p.CreatedAt = parseTimeWithWarning(createdAt, "bbs_post", p.ID, "created_at")
-		posts = append(posts, &p)
+		posts = append(posts, p)
 	}
 	return posts, rows.Err()
 }
 
+// ListBBSThreads lists top-level threads (posts with no thread_id), pinned
+// threads first and then newest first.
+func (s *SQLStore) ListBBSThreads(ctx context.Context, filter BBSThreadFilter) ([]*BBSPost, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := `SELECT ` + bbsPostColumns + ` FROM bbs_posts WHERE thread_id IS NULL`
+	var args []any
+	if filter.Since != nil {
+		query += ` AND created_at >= ?`
+		args = append(args, filter.Since.UTC().Format(time.RFC3339))
+	}
+	query += ` ORDER BY pinned DESC, created_at DESC, id DESC LIMIT ? OFFSET ?`
+	args = append(args, limit, max(filter.Offset, 0))
+
+	return s.queryBBSPosts(ctx, query, args...)
+}
+
 // GetBBSThread retrieves a thread with all its replies.
-func (s *SQLiteStore) GetBBSThread(ctx context.Context, threadID string) (*BBSThread, error) {
+func (s *SQLStore) GetBBSThread(ctx context.Context, threadID string) (*BBSThread, error) {
 	// Get the thread (top-level post)
 	post, err := s.GetBBSPost(ctx, threadID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get replies
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, agent_id, thread_id, subject, content, created_at
+	replies, err := s.queryBBSPosts(ctx, `
+		SELECT `+bbsPostColumns+`
 		FROM bbs_posts WHERE thread_id = ?
-		ORDER BY created_at ASC
+		ORDER BY created_at ASC, id ASC
 	`, threadID)
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = rows.Close() }()
+	return &BBSThread{Post: post, Replies: replies}, nil
+}
 
-	var replies []*BBSPost
-	for rows.Next() {
-		var p BBSPost
-		var tid, subject sql.NullString
-		var createdAt string
-		if err := rows.Scan(&p.ID, &p.AgentID, &tid, &subject, &p.Content, &createdAt); err != nil {
+// GetBBSThreadPage retrieves a thread with up to limit of its replies,
+// starting after the reply afterReplyID or, when it is empty, at the first.
+// A limit of 0 means 50. HasMore reports replies past the page. It returns
+// ErrNotFound when afterReplyID is not a reply in the thread.
+func (s *SQLStore) GetBBSThreadPage(ctx context.Context, threadID, afterReplyID string, limit int) (*BBSThread, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	post, err := s.GetBBSPost(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + bbsPostColumns + ` FROM bbs_posts WHERE thread_id = ?`
+	args := []any{threadID}
+	if afterReplyID != "" {
+		after, err := s.GetBBSPost(ctx, afterReplyID)
+		if err != nil {
 			return nil, err
 		}
-		p.ThreadID = tid.String
-		p.Subject = subject.String
-		p.CreatedAt = parseTimeWithWarning(createdAt, "bbs_post", p.ID, "created_at")
-		replies = append(replies, &p)
+		if after.ThreadID != threadID {
+			return nil, ErrNotFound
+		}
+		createdAt := after.CreatedAt.UTC().Format(time.RFC3339)
+		query += ` AND (created_at > ? OR (created_at = ? AND id > ?))`
+		args = append(args, createdAt, createdAt, after.ID)
 	}
+	query += ` ORDER BY created_at ASC, id ASC LIMIT ?`
+	args = append(args, limit+1)
 
-	return &BBSThread{Post: post, Replies: replies}, rows.Err()
+	replies, err := s.queryBBSPosts(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	thread := &BBSThread{Post: post, Replies: replies}
+	if len(replies) > limit {
+		thread.Replies, thread.HasMore = replies[:limit], true
+	}
+	return thread, nil
 }
 
-// SendMail creates a new mail message.
-func (s *SQLiteStore) SendMail(ctx context.Context, mail *AgentMail) error {
+// SearchBBSPosts returns up to limit threads and replies whose subject or
+// content contains query, ignoring ASCII case, newest first.
+func (s *SQLStore) SearchBBSPosts(ctx context.Context, query string, limit int) ([]*BBSPost, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	like := s.dialect.like()
+	pattern := "%" + query + "%"
+	return s.queryBBSPosts(ctx, `
+		SELECT `+bbsPostColumns+`
+		FROM bbs_posts WHERE subject `+like+` ? OR content `+like+` ?
+		ORDER BY created_at DESC, id DESC LIMIT ?
+	`, pattern, pattern, limit)
+}
+
+// SetBBSThreadPinned sets or clears a thread's pinned flag. It returns
+// ErrNotFound when threadID is not a top-level thread.
+func (s *SQLStore) SetBBSThreadPinned(ctx context.Context, threadID string, pinned bool) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE bbs_posts SET pinned = ? WHERE id = ? AND thread_id IS NULL`, pinned, threadID)
+	if err != nil {
+		return fmt.Errorf("updating bbs thread pin: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SendMail creates a new mail message. A mail without a ThreadID starts a
+// new thread named after its own ID.
+func (s *SQLStore) SendMail(ctx context.Context, mail *AgentMail) error {
 	if mail.ID == "" {
 		mail.ID = uuid.New().String()
 	}
 	if mail.CreatedAt.IsZero() {
 		mail.CreatedAt = time.Now()
 	}
+	if mail.ThreadID == "" {
+		mail.ThreadID = mail.ID
+	}
 
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO agent_mail (id, from_agent_id, to_agent_id, subject, content, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, mail.ID, mail.FromAgentID, mail.ToAgentID, mail.Subject, mail.Content, mail.CreatedAt.Format(time.RFC3339))
+		INSERT INTO agent_mail (id, from_agent_id, to_agent_id, subject, content, in_reply_to, thread_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, mail.ID, mail.FromAgentID, mail.ToAgentID, mail.Subject, mail.Content, nullString(mail.InReplyTo), mail.ThreadID,
+		mail.CreatedAt.Format(time.RFC3339))
 
 	return err
 }
 
-// GetMail retrieves a mail message by ID.
-func (s *SQLiteStore) GetMail(ctx context.Context, id string) (*AgentMail, error) {
-	var m AgentMail
-	var readAt sql.NullString
-	var createdAt string
-
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, from_agent_id, to_agent_id, subject, content, read_at, created_at
-		FROM agent_mail WHERE id = ?
-	`, id).Scan(&m.ID, &m.FromAgentID, &m.ToAgentID, &m.Subject, &m.Content, &readAt, &createdAt)
+const mailColumns = `id, from_agent_id, to_agent_id, subject, content, in_reply_to, thread_id, read_at, created_at`
 
+// GetMail retrieves a mail message by ID.
+func (s *SQLStore) GetMail(ctx context.Context, id string) (*AgentMail, error) {
+	m, err := scanMail(s.db.QueryRowContext(ctx, `SELECT `+mailColumns+` FROM agent_mail WHERE id = ?`, id))
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
-
-	m.CreatedAt = parseTimeWithWarning(createdAt, "mail", m.ID, "created_at")
-	if readAt.Valid {
-		parsed := parseTimeWithWarning(readAt.String, "mail", m.ID, "read_at")
-		if !parsed.IsZero() {
-			m.ReadAt = &parsed
-		}
-	}
-
-	return &m, nil
+	return m, nil
 }
 
-// ListInbox lists mail for an agent.
-func (s *SQLiteStore) ListInbox(ctx context.Context, agentID string, unreadOnly bool, limit int) ([]*AgentMail, error) {
+// ListInbox lists mail for an agent, newest first, optionally only unread
+// mail or mail in one thread.
+func (s *SQLStore) ListInbox(ctx context.Context, agentID string, unreadOnly bool, threadID string, limit int) ([]*AgentMail, error) {
 	if limit <= 0 {
 		limit = 50
 	}
 
-	args := make([]any, 0, 2)
-	sqlQuery := `SELECT id, from_agent_id, to_agent_id, subject, content, read_at, created_at FROM agent_mail WHERE to_agent_id = ?`
+	args := make([]any, 0, 3)
+	sqlQuery := `SELECT ` + mailColumns + ` FROM agent_mail WHERE to_agent_id = ?`
 	args = append(args, agentID)
 
 	if unreadOnly {
 		sqlQuery += ` AND read_at IS NULL`
 	}
+	if threadID != "" {
+		sqlQuery += ` AND thread_id = ?`
+		args = append(args, threadID)
+	}
 	sqlQuery += ` ORDER BY created_at DESC LIMIT ?`
 	args = append(args, limit)
 
-	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	return s.queryMail(ctx, sqlQuery, args...)
+}
+
+// GetMailThread returns every mail in a thread, oldest first.
+func (s *SQLStore) GetMailThread(ctx context.Context, threadID string) ([]*AgentMail, error) {
+	return s.queryMail(ctx, `SELECT `+mailColumns+` FROM agent_mail WHERE thread_id = ? ORDER BY created_at, rowid`, threadID)
+}
+
+func (s *SQLStore) queryMail(ctx context.Context, query string, args ...any) ([]*AgentMail, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -477,26 +682,39 @@ func (s *SQLiteStore) ListInbox(ctx context.Context, agentID string, unreadOnly
 
 	var messages []*AgentMail
 	for rows.Next() {
-		var m AgentMail
-		var readAt sql.NullString
-		var createdAt string
-		if err := rows.Scan(&m.ID, &m.FromAgentID, &m.ToAgentID, &m.Subject, &m.Content, &readAt, &createdAt); err != nil {
+		m, err := scanMail(rows)
+		if err != nil {
 			return nil, err
 		}
-		m.CreatedAt = parseTimeWithWarning(createdAt, "mail", m.ID, "created_at")
-		if readAt.Valid {
-			parsed := parseTimeWithWarning(readAt.String, "mail", m.ID, "read_at")
-			if !parsed.IsZero() {
-				m.ReadAt = &parsed
-			}
-		}
-		messages = append(messages, &m)
+		messages = append(messages, m)
 	}
 	return messages, rows.Err()
 }
 
+func scanMail(row interface{ Scan(...any) error }) (*AgentMail, error) {
+	var m AgentMail
+	var inReplyTo, threadID, readAt sql.NullString
+	var createdAt string
+	if err := row.Scan(&m.ID, &m.FromAgentID, &m.ToAgentID, &m.Subject, &m.Content, &inReplyTo, &threadID, &readAt, &createdAt); err != nil {
+		return nil, err
+	}
+	m.InReplyTo = inReplyTo.String
+	m.ThreadID = threadID.String
+	if m.ThreadID == "" {
+		m.ThreadID = m.ID
+	}
+	m.CreatedAt = parseTimeWithWarning(createdAt, "mail", m.ID, "created_at")
+	if readAt.Valid {
+		parsed := parseTimeWithWarning(readAt.String, "mail", m.ID, "read_at")
+		if !parsed.IsZero() {
+			m.ReadAt = &parsed
+		}
+	}
+	return &m, nil
+}
+
 // MarkMailRead marks a mail message as read.
-func (s *SQLiteStore) MarkMailRead(ctx context.Context, id string) error {
+func (s *SQLStore) MarkMailRead(ctx context.Context, id string) error {
 	result, err := s.db.ExecContext(ctx, `
 		UPDATE agent_mail SET read_at = ? WHERE id = ? AND read_at IS NULL
 	`, time.Now().Format(time.RFC3339), id)
@@ -518,35 +736,81 @@ func (s *SQLiteStore) MarkMailRead(ctx context.Context, id string) error {
 	return nil
 }
 
-// SetNote creates or updates a note.
-func (s *SQLiteStore) SetNote(ctx context.Context, note *AgentNote) error {
+// SetNote creates or updates a note. A nil ExpiresAt stores the note without
+// an expiry, clearing any the previous value had. The agent's expired notes
+// are swept first.
+func (s *SQLStore) SetNote(ctx context.Context, note *AgentNote) error {
 	if note.ID == "" {
 		note.ID = uuid.New().String()
 	}
-	now := time.Now()
+	now := s.clock()
 	if note.CreatedAt.IsZero() {
 		note.CreatedAt = now
 	}
 	note.UpdatedAt = now
 
+	if err := s.sweepExpiredNotes(ctx, note.AgentID, now); err != nil {
+		return err
+	}
+
+	var expiresAt *string
+	if note.ExpiresAt != nil {
+		e := note.ExpiresAt.UTC().Format(time.RFC3339)
+		expiresAt = &e
+	}
+
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO agent_notes (id, agent_id, key, value, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-		ON CONFLICT(agent_id, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
-	`, note.ID, note.AgentID, note.Key, note.Value, note.CreatedAt.Format(time.RFC3339), note.UpdatedAt.Format(time.RFC3339))
+		INSERT INTO agent_notes (id, agent_id, key, value, created_at, updated_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(agent_id, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at, expires_at = excluded.expires_at
+	`, note.ID, note.AgentID, note.Key, note.Value, note.CreatedAt.Format(time.RFC3339), note.UpdatedAt.Format(time.RFC3339), expiresAt)
 
 	return err
 }
 
-// GetNote retrieves a note by agent and key.
-func (s *SQLiteStore) GetNote(ctx context.Context, agentID, key string) (*AgentNote, error) {
+// sweepExpiredNotes deletes the agent's notes whose expiry is at or before
+// now. Notes are swept lazily on access, so one that has expired stays gone
+// even if the clock later moves backwards.
+func (s *SQLStore) sweepExpiredNotes(ctx context.Context, agentID string, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM agent_notes
+		WHERE agent_id = ? AND expires_at IS NOT NULL AND expires_at <= ?
+	`, agentID, now.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("sweeping expired notes: %w", err)
+	}
+	return nil
+}
+
+// scanNote reads a row of id, agent_id, key, value, created_at, updated_at
+// and expires_at.
+func scanNote(row interface{ Scan(...any) error }) (*AgentNote, error) {
 	var n AgentNote
 	var createdAt, updatedAt string
+	var expiresAt sql.NullString
+	if err := row.Scan(&n.ID, &n.AgentID, &n.Key, &n.Value, &createdAt, &updatedAt, &expiresAt); err != nil {
+		return nil, err
+	}
+	n.CreatedAt = parseTimeWithWarning(createdAt, "note", n.ID, "created_at")
+	n.UpdatedAt = parseTimeWithWarning(updatedAt, "note", n.ID, "updated_at")
+	if expiresAt.Valid {
+		parsed := parseTimeWithWarning(expiresAt.String, "note", n.ID, "expires_at")
+		n.ExpiresAt = &parsed
+	}
+	return &n, nil
+}
 
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, agent_id, key, value, created_at, updated_at
+// GetNote retrieves a note by agent and key. An expired note is swept and
+// reported as ErrNotFound.
+func (s *SQLStore) GetNote(ctx context.Context, agentID, key string) (*AgentNote, error) {
+	if err := s.sweepExpiredNotes(ctx, agentID, s.clock()); err != nil {
+		return nil, err
+	}
+
+	n, err := scanNote(s.db.QueryRowContext(ctx, `
+		SELECT id, agent_id, key, value, created_at, updated_at, expires_at
 		FROM agent_notes WHERE agent_id = ? AND key = ?
-	`, agentID, key).Scan(&n.ID, &n.AgentID, &n.Key, &n.Value, &createdAt, &updatedAt)
+	`, agentID, key))
 
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
@@ -554,20 +818,21 @@ func (s *SQLiteStore) GetNote(ctx context.Context, agentID, key string) (*AgentN
 	if err != nil {
 		return nil, err
 	}
-
-	n.CreatedAt = parseTimeWithWarning(createdAt, "note", n.ID, "created_at")
-	n.UpdatedAt = parseTimeWithWarning(updatedAt, "note", n.ID, "updated_at")
-
-	return &n, nil
+	return n, nil
 }
 
-// ListNotes lists all notes for an agent.
-func (s *SQLiteStore) ListNotes(ctx context.Context, agentID string) ([]*AgentNote, error) {
+// ListNotes lists an agent's unexpired notes whose key starts with prefix,
+// ordered by key. An empty prefix lists them all.
+func (s *SQLStore) ListNotes(ctx context.Context, agentID, prefix string) ([]*AgentNote, error) {
+	if err := s.sweepExpiredNotes(ctx, agentID, s.clock()); err != nil {
+		return nil, err
+	}
+
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, agent_id, key, value, created_at, updated_at
-		FROM agent_notes WHERE agent_id = ?
+		SELECT id, agent_id, key, value, created_at, updated_at, expires_at
+		FROM agent_notes WHERE agent_id = ? AND substr(key, 1, ?) = ?
 		ORDER BY key ASC
-	`, agentID)
+	`, agentID, utf8.RuneCountInString(prefix), prefix)
 	if err != nil {
 		return nil, err
 	}
@@ -575,20 +840,17 @@ func (s *SQLiteStore) ListNotes(ctx context.Context, agentID string) ([]*AgentNo
 
 	var notes []*AgentNote
 	for rows.Next() {
-		var n AgentNote
-		var createdAt, updatedAt string
-		if err := rows.Scan(&n.ID, &n.AgentID, &n.Key, &n.Value, &createdAt, &updatedAt); err != nil {
+		n, err := scanNote(rows)
+		if err != nil {
 			return nil, err
 		}
-		n.CreatedAt = parseTimeWithWarning(createdAt, "note", n.ID, "created_at")
-		n.UpdatedAt = parseTimeWithWarning(updatedAt, "note", n.ID, "updated_at")
-		notes = append(notes, &n)
+		notes = append(notes, n)
 	}
 	return notes, rows.Err()
 }
 
 // DeleteNote deletes a note by agent and key.
-func (s *SQLiteStore) DeleteNote(ctx context.Context, agentID, key string) error {
+func (s *SQLStore) DeleteNote(ctx context.Context, agentID, key string) error {
 	result, err := s.db.ExecContext(ctx, `DELETE FROM agent_notes WHERE agent_id = ? AND key = ?`, agentID, key)
 	if err != nil {
 		return err
@@ -599,3 +861,67 @@ func (s *SQLiteStore) DeleteNote(ctx context.Context, agentID, key string) error
 	}
 	return nil
 }
+
+// quotaRetentionDays is how many past days of quota counters are kept.
+const quotaRetentionDays = 7
+
+// ConsumeBuiltinQuota counts one write of kind by the agent on day (YYYY-MM-DD)
+// and returns the new count. If the agent has already made limit writes that
+// day it returns ErrQuotaExceeded and counts nothing. A limit of zero or less
+// means unlimited; the write is still counted.
+func (s *SQLStore) ConsumeBuiltinQuota(ctx context.Context, agentID, kind, day string, limit int) (int, error) {
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO builtin_write_quota (agent_id, kind, day, count) VALUES (?, ?, ?, 1)
+		ON CONFLICT (agent_id, kind, day) DO UPDATE SET count = builtin_write_quota.count + 1
+		WHERE builtin_write_quota.count < ?
+		RETURNING count`, agentID, kind, day, limit).Scan(&count)
+	if errors.Is(err, sql.ErrNoRows) {
+		return limit, ErrQuotaExceeded
+	}
+	if err != nil {
+		return 0, fmt.Errorf("consuming %s quota: %w", kind, err)
+	}
+
+	// The first write of a day is a cheap moment to drop stale counters.
+	if count == 1 {
+		cutoff := dayBefore(day, quotaRetentionDays)
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM builtin_write_quota WHERE agent_id = ? AND day < ?`, agentID, cutoff); err != nil {
+			s.logger.Warn("pruning builtin quota counters", "agent_id", agentID, "error", err)
+		}
+	}
+	return count, nil
+}
+
+// GetBuiltinQuotaUsage returns the agent's write counts by kind for day.
+func (s *SQLStore) GetBuiltinQuotaUsage(ctx context.Context, agentID, day string) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT kind, count FROM builtin_write_quota WHERE agent_id = ? AND day = ?`, agentID, day)
+	if err != nil {
+		return nil, fmt.Errorf("querying builtin quota usage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	usage := make(map[string]int)
+	for rows.Next() {
+		var kind string
+		var count int
+		if err := rows.Scan(&kind, &count); err != nil {
+			return nil, fmt.Errorf("scanning builtin quota usage: %w", err)
+		}
+		usage[kind] = count
+	}
+	return usage, rows.Err()
+}
+
+// dayBefore returns the YYYY-MM-DD date n days before day, or day itself if
+// it does not parse.
+func dayBefore(day string, n int) string {
+	t, err := time.Parse(time.DateOnly, day)
+	if err != nil {
+		return day
+	}
+	return t.AddDate(0, 0, -n).Format(time.DateOnly)
+}
@@ -6,6 +6,7 @@ package store
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 	"time"
 )
@@ -147,7 +148,7 @@ func TestTodos(t *testing.T) {
 	}
 
 	// List with filter
-	todos, err := s.ListTodos(ctx, "agent-1", "completed", "")
+	todos, err := s.ListTodos(ctx, TodoFilter{AgentID: "agent-1", Status: "completed"})
 	if err != nil {
 		t.Fatalf("ListTodos: %v", err)
 	}
@@ -165,6 +166,100 @@ func TestTodos(t *testing.T) {
 	}
 }
 
+func TestTodosProjectVisibility(t *testing.T) {
+	s := newBuiltinTestStore(t)
+	ctx := context.Background()
+
+	if err := s.AddProjectMember(ctx, "apollo", "agent-2"); err != nil {
+		t.Fatalf("AddProjectMember: %v", err)
+	}
+	// Adding twice is a no-op.
+	if err := s.AddProjectMember(ctx, "apollo", "agent-2"); err != nil {
+		t.Fatalf("AddProjectMember again: %v", err)
+	}
+
+	shared := &Todo{AgentID: "agent-1", Description: "shared", Project: "apollo", AssigneeAgentID: "agent-2"}
+	private := &Todo{AgentID: "agent-1", Description: "private", AssigneeAgentID: "agent-2"}
+	for _, todo := range []*Todo{shared, private} {
+		if err := s.CreateTodo(ctx, todo); err != nil {
+			t.Fatalf("CreateTodo: %v", err)
+		}
+	}
+
+	got, err := s.GetTodo(ctx, shared.ID)
+	if err != nil {
+		t.Fatalf("GetTodo: %v", err)
+	}
+	if got.Project != "apollo" || got.AssigneeAgentID != "agent-2" {
+		t.Errorf("GetTodo = %+v, want project and assignee", got)
+	}
+
+	// agent-2 sees the todo in its project but not agent-1's private one,
+	// even though both name it as assignee.
+	todos, err := s.ListTodos(ctx, TodoFilter{AssigneeAgentID: "agent-2", VisibleTo: "agent-2"})
+	if err != nil {
+		t.Fatalf("ListTodos: %v", err)
+	}
+	if len(todos) != 1 || todos[0].ID != shared.ID {
+		t.Errorf("visible todos = %v, want only the shared one", todos)
+	}
+
+	ok, err := s.IsProjectMember(ctx, "apollo", "agent-2")
+	if err != nil || !ok {
+		t.Errorf("IsProjectMember = %v, %v; want true", ok, err)
+	}
+	members, err := s.ListProjectMembers(ctx)
+	if err != nil || len(members) != 1 || members[0].Project != "apollo" || members[0].AgentID != "agent-2" {
+		t.Errorf("ListProjectMembers = %v, %v", members, err)
+	}
+
+	if err := s.RemoveProjectMember(ctx, "apollo", "agent-2"); err != nil {
+		t.Fatalf("RemoveProjectMember: %v", err)
+	}
+	if err := s.RemoveProjectMember(ctx, "apollo", "agent-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RemoveProjectMember again: err = %v, want ErrNotFound", err)
+	}
+	todos, err = s.ListTodos(ctx, TodoFilter{AssigneeAgentID: "agent-2", VisibleTo: "agent-2"})
+	if err != nil || len(todos) != 0 {
+		t.Errorf("visible todos after leaving = %v, %v; want none", todos, err)
+	}
+}
+
+func TestDelegationTargets(t *testing.T) {
+	s := newBuiltinTestStore(t)
+	ctx := context.Background()
+
+	if err := s.AddDelegationTarget(ctx, "agent-1", "agent-2"); err != nil {
+		t.Fatalf("AddDelegationTarget: %v", err)
+	}
+	// Adding twice is a no-op.
+	if err := s.AddDelegationTarget(ctx, "agent-1", "agent-2"); err != nil {
+		t.Fatalf("AddDelegationTarget again: %v", err)
+	}
+
+	if ok, err := s.CanDelegate(ctx, "agent-1", "agent-2"); err != nil || !ok {
+		t.Errorf("CanDelegate(agent-1, agent-2) = %v, %v; want true", ok, err)
+	}
+	// The allowlist is one-way.
+	if ok, err := s.CanDelegate(ctx, "agent-2", "agent-1"); err != nil || ok {
+		t.Errorf("CanDelegate(agent-2, agent-1) = %v, %v; want false", ok, err)
+	}
+	targets, err := s.ListDelegationTargets(ctx)
+	if err != nil || len(targets) != 1 || targets[0].AgentID != "agent-1" || targets[0].TargetAgentID != "agent-2" {
+		t.Errorf("ListDelegationTargets = %v, %v", targets, err)
+	}
+
+	if err := s.RemoveDelegationTarget(ctx, "agent-1", "agent-2"); err != nil {
+		t.Fatalf("RemoveDelegationTarget: %v", err)
+	}
+	if err := s.RemoveDelegationTarget(ctx, "agent-1", "agent-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RemoveDelegationTarget again: err = %v, want ErrNotFound", err)
+	}
+	if ok, _ := s.CanDelegate(ctx, "agent-1", "agent-2"); ok {
+		t.Error("CanDelegate after removal = true, want false")
+	}
+}
+
 func TestTodosWithDueDate(t *testing.T) {
 	s := newBuiltinTestStore(t)
 	ctx := context.Background()
@@ -237,7 +332,7 @@ func TestBBS(t *testing.T) {
 	}
 
 	// List threads
-	threads, err := s.ListBBSThreads(ctx, 10)
+	threads, err := s.ListBBSThreads(ctx, BBSThreadFilter{Limit: 10})
 	if err != nil {
 		t.Fatalf("ListBBSThreads: %v", err)
 	}
@@ -258,6 +353,96 @@ func TestBBS(t *testing.T) {
 	}
 }
 
+func TestBBSPagingSearchAndPins(t *testing.T) {
+	s := newBuiltinTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var threads []*BBSPost
+	for i, subject := range []string{"Deploy notes", "Lunch", "Deploy rollback"} {
+		p := &BBSPost{AgentID: "agent-1", Subject: subject, Content: "post " + subject, CreatedAt: base.Add(time.Duration(i) * time.Hour)}
+		if err := s.CreateBBSPost(ctx, p); err != nil {
+			t.Fatalf("CreateBBSPost: %v", err)
+		}
+		threads = append(threads, p)
+	}
+	var replies []*BBSPost
+	for i := range 3 {
+		// Replies share a timestamp, so paging must break ties by ID.
+		p := &BBSPost{AgentID: "agent-2", ThreadID: threads[0].ID, Content: "reply", CreatedAt: base.Add(time.Minute)}
+		if i == 2 {
+			p.Content = "the DEPLOY worked"
+		}
+		if err := s.CreateBBSPost(ctx, p); err != nil {
+			t.Fatalf("CreateBBSPost reply: %v", err)
+		}
+		replies = append(replies, p)
+	}
+
+	if err := s.SetBBSThreadPinned(ctx, threads[0].ID, true); err != nil {
+		t.Fatalf("SetBBSThreadPinned: %v", err)
+	}
+	if err := s.SetBBSThreadPinned(ctx, replies[0].ID, true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("pinning a reply err = %v, want ErrNotFound", err)
+	}
+
+	listed, err := s.ListBBSThreads(ctx, BBSThreadFilter{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListBBSThreads: %v", err)
+	}
+	if len(listed) != 2 || listed[0].ID != threads[0].ID || !listed[0].Pinned || listed[1].ID != threads[2].ID {
+		t.Errorf("first page = %+v, want the pinned thread then the newest", listed)
+	}
+	listed, err = s.ListBBSThreads(ctx, BBSThreadFilter{Offset: 2, Limit: 2})
+	if err != nil || len(listed) != 1 || listed[0].ID != threads[1].ID {
+		t.Errorf("second page = %+v, %v; want Lunch", listed, err)
+	}
+	since := base.Add(90 * time.Minute)
+	listed, err = s.ListBBSThreads(ctx, BBSThreadFilter{Since: &since})
+	if err != nil || len(listed) != 1 || listed[0].ID != threads[2].ID {
+		t.Errorf("threads since = %+v, %v; want Deploy rollback", listed, err)
+	}
+
+	var seen []string
+	after := ""
+	for {
+		page, err := s.GetBBSThreadPage(ctx, threads[0].ID, after, 2)
+		if err != nil {
+			t.Fatalf("GetBBSThreadPage: %v", err)
+		}
+		for _, r := range page.Replies {
+			seen = append(seen, r.ID)
+		}
+		if !page.HasMore {
+			break
+		}
+		after = page.Replies[len(page.Replies)-1].ID
+	}
+	full, err := s.GetBBSThread(ctx, threads[0].ID)
+	if err != nil {
+		t.Fatalf("GetBBSThread: %v", err)
+	}
+	if len(seen) != 3 || len(full.Replies) != 3 {
+		t.Fatalf("paged replies = %v, want 3", seen)
+	}
+	for i, r := range full.Replies {
+		if seen[i] != r.ID {
+			t.Errorf("paged reply %d = %s, want %s", i, seen[i], r.ID)
+		}
+	}
+	if _, err := s.GetBBSThreadPage(ctx, threads[1].ID, replies[0].ID, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("paging after another thread's reply err = %v, want ErrNotFound", err)
+	}
+
+	found, err := s.SearchBBSPosts(ctx, "deploy", 10)
+	if err != nil {
+		t.Fatalf("SearchBBSPosts: %v", err)
+	}
+	if len(found) != 3 || found[0].ID != threads[2].ID {
+		t.Errorf("search = %+v, want both deploy threads and the reply, newest first", found)
+	}
+}
+
 func TestBBSNotFound(t *testing.T) {
 	s := newBuiltinTestStore(t)
 	ctx := context.Background()
@@ -289,7 +474,7 @@ func TestMail(t *testing.T) {
 	}
 
 	// List inbox
-	inbox, err := s.ListInbox(ctx, "agent-2", false, 10)
+	inbox, err := s.ListInbox(ctx, "agent-2", false, "", 10)
 	if err != nil {
 		t.Fatalf("ListInbox: %v", err)
 	}
@@ -315,7 +500,7 @@ func TestMail(t *testing.T) {
 	}
 
 	// Unread only filter
-	unread, err := s.ListInbox(ctx, "agent-2", true, 10)
+	unread, err := s.ListInbox(ctx, "agent-2", true, "", 10)
 	if err != nil {
 		t.Fatalf("ListInbox unread: %v", err)
 	}
@@ -396,7 +581,7 @@ func TestNotes(t *testing.T) {
 	}
 
 	// List
-	notes, err := s.ListNotes(ctx, "agent-1")
+	notes, err := s.ListNotes(ctx, "agent-1", "")
 	if err != nil {
 		t.Fatalf("ListNotes: %v", err)
 	}
@@ -454,7 +639,7 @@ func TestNotesMultipleKeys(t *testing.T) {
 	}
 
 	// List should return all, sorted by key
-	list, err := s.ListNotes(ctx, "agent-1")
+	list, err := s.ListNotes(ctx, "agent-1", "")
 	if err != nil {
 		t.Fatalf("ListNotes: %v", err)
 	}
@@ -467,7 +652,122 @@ func TestNotesMultipleKeys(t *testing.T) {
 	}
 }
 
-func newBuiltinTestStore(t *testing.T) *SQLiteStore {
+func TestNotesExpiry(t *testing.T) {
+	s := newBuiltinTestStore(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	expiresAt := now.Add(time.Minute)
+	if err := s.SetNote(ctx, &AgentNote{AgentID: "agent-1", Key: "temp", Value: "soon gone", ExpiresAt: &expiresAt}); err != nil {
+		t.Fatalf("SetNote: %v", err)
+	}
+	if err := s.SetNote(ctx, &AgentNote{AgentID: "agent-1", Key: "kept", Value: "forever"}); err != nil {
+		t.Fatalf("SetNote: %v", err)
+	}
+
+	// One second before expiry the note is still there.
+	now = expiresAt.Add(-time.Second)
+	got, err := s.GetNote(ctx, "agent-1", "temp")
+	if err != nil {
+		t.Fatalf("GetNote before expiry: %v", err)
+	}
+	if got.ExpiresAt == nil || !got.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, expiresAt)
+	}
+
+	// Clock moving backwards before expiry does not hide the note.
+	now = expiresAt.Add(-time.Hour)
+	if _, err := s.GetNote(ctx, "agent-1", "temp"); err != nil {
+		t.Fatalf("GetNote after clock moved back: %v", err)
+	}
+
+	// Exactly at expires_at the note is expired.
+	now = expiresAt
+	if _, err := s.GetNote(ctx, "agent-1", "temp"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetNote at expiry: err = %v, want ErrNotFound", err)
+	}
+	list, err := s.ListNotes(ctx, "agent-1", "")
+	if err != nil {
+		t.Fatalf("ListNotes: %v", err)
+	}
+	if len(list) != 1 || list[0].Key != "kept" || list[0].ExpiresAt != nil {
+		t.Errorf("ListNotes at expiry = %v, want only kept", list)
+	}
+
+	// The expired note was swept, so a clock moving backwards does not bring
+	// it back.
+	now = expiresAt.Add(-time.Hour)
+	if _, err := s.GetNote(ctx, "agent-1", "temp"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetNote after clock moved back past expiry: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestNotesExpiryClearedOnUpdate(t *testing.T) {
+	s := newBuiltinTestStore(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	expiresAt := now.Add(time.Minute)
+	if err := s.SetNote(ctx, &AgentNote{AgentID: "agent-1", Key: "k", Value: "v1", ExpiresAt: &expiresAt}); err != nil {
+		t.Fatalf("SetNote: %v", err)
+	}
+	// Setting the note again without a TTL makes it permanent.
+	if err := s.SetNote(ctx, &AgentNote{AgentID: "agent-1", Key: "k", Value: "v2"}); err != nil {
+		t.Fatalf("SetNote update: %v", err)
+	}
+
+	now = expiresAt.Add(time.Hour)
+	got, err := s.GetNote(ctx, "agent-1", "k")
+	if err != nil {
+		t.Fatalf("GetNote: %v", err)
+	}
+	if got.Value != "v2" || got.ExpiresAt != nil {
+		t.Errorf("note = %+v, want v2 without expiry", got)
+	}
+}
+
+func TestNotesListPrefix(t *testing.T) {
+	s := newBuiltinTestStore(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"project/a", "project/b", "projects", "other/a", "Project/c", "%wild"} {
+		if err := s.SetNote(ctx, &AgentNote{AgentID: "agent-1", Key: key, Value: "v"}); err != nil {
+			t.Fatalf("SetNote %s: %v", key, err)
+		}
+	}
+	if err := s.SetNote(ctx, &AgentNote{AgentID: "agent-2", Key: "project/z", Value: "v"}); err != nil {
+		t.Fatalf("SetNote: %v", err)
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"project/", []string{"project/a", "project/b"}},
+		{"project", []string{"project/a", "project/b", "projects"}},
+		{"%", []string{"%wild"}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			list, err := s.ListNotes(ctx, "agent-1", tt.prefix)
+			if err != nil {
+				t.Fatalf("ListNotes: %v", err)
+			}
+			var keys []string
+			for _, n := range list {
+				keys = append(keys, n.Key)
+			}
+			if !slices.Equal(keys, tt.want) {
+				t.Errorf("keys = %v, want %v", keys, tt.want)
+			}
+		})
+	}
+}
+
+func newBuiltinTestStore(t *testing.T) *SQLStore {
 	t.Helper()
 	s, err := NewSQLiteStore(":memory:")
 	if err != nil {
@@ -476,3 +776,80 @@ func newBuiltinTestStore(t *testing.T) *SQLiteStore {
 	t.Cleanup(func() { s.Close() })
 	return s
 }
+
+func TestBuiltinQuota(t *testing.T) {
+	s := newBuiltinTestStore(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		n, err := s.ConsumeBuiltinQuota(ctx, "agent-1", "logs", "2026-03-10", 3)
+		if err != nil || n != i {
+			t.Fatalf("write %d: count %d, err %v", i, n, err)
+		}
+	}
+	if _, err := s.ConsumeBuiltinQuota(ctx, "agent-1", "logs", "2026-03-10", 3); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("fourth write err = %v, want ErrQuotaExceeded", err)
+	}
+	if n, err := s.ConsumeBuiltinQuota(ctx, "agent-2", "logs", "2026-03-10", 3); err != nil || n != 1 {
+		t.Errorf("other agent: count %d, err %v; want its own counter", n, err)
+	}
+	if n, err := s.ConsumeBuiltinQuota(ctx, "agent-1", "mail", "2026-03-10", 0); err != nil || n != 1 {
+		t.Errorf("unlimited kind: count %d, err %v", n, err)
+	}
+
+	usage, err := s.GetBuiltinQuotaUsage(ctx, "agent-1", "2026-03-10")
+	if err != nil {
+		t.Fatalf("GetBuiltinQuotaUsage: %v", err)
+	}
+	if usage["logs"] != 3 || usage["mail"] != 1 {
+		t.Errorf("usage = %v, want logs 3 and mail 1", usage)
+	}
+
+	// A new day starts a fresh counter and prunes counters past retention.
+	if n, err := s.ConsumeBuiltinQuota(ctx, "agent-1", "logs", "2026-03-20", 3); err != nil || n != 1 {
+		t.Errorf("next window: count %d, err %v; want a reset counter", n, err)
+	}
+	old, err := s.GetBuiltinQuotaUsage(ctx, "agent-1", "2026-03-10")
+	if err != nil || len(old) != 0 {
+		t.Errorf("old usage = %v (err %v), want pruned", old, err)
+	}
+}
+
+func TestMailThreading(t *testing.T) {
+	s := newBuiltinTestStore(t)
+	ctx := context.Background()
+
+	root := &AgentMail{FromAgentID: "agent-1", ToAgentID: "agent-2", Subject: "Plan", Content: "Start?"}
+	if err := s.SendMail(ctx, root); err != nil {
+		t.Fatalf("SendMail: %v", err)
+	}
+	if root.ThreadID != root.ID {
+		t.Errorf("root ThreadID = %q, want its own ID %q", root.ThreadID, root.ID)
+	}
+	reply := &AgentMail{FromAgentID: "agent-2", ToAgentID: "agent-1", Subject: "Re: Plan", Content: "Yes", InReplyTo: root.ID, ThreadID: root.ThreadID}
+	if err := s.SendMail(ctx, reply); err != nil {
+		t.Fatalf("SendMail reply: %v", err)
+	}
+	other := &AgentMail{FromAgentID: "agent-3", ToAgentID: "agent-1", Subject: "Other", Content: "Hi"}
+	if err := s.SendMail(ctx, other); err != nil {
+		t.Fatalf("SendMail other: %v", err)
+	}
+
+	got, err := s.GetMail(ctx, reply.ID)
+	if err != nil || got.InReplyTo != root.ID || got.ThreadID != root.ID {
+		t.Fatalf("GetMail(reply) = %+v, %v", got, err)
+	}
+
+	thread, err := s.GetMailThread(ctx, root.ID)
+	if err != nil {
+		t.Fatalf("GetMailThread: %v", err)
+	}
+	if len(thread) != 2 || thread[0].ID != root.ID || thread[1].ID != reply.ID {
+		t.Errorf("thread = %v, want root then reply", thread)
+	}
+
+	inbox, err := s.ListInbox(ctx, "agent-1", false, root.ID, 10)
+	if err != nil || len(inbox) != 1 || inbox[0].ID != reply.ID {
+		t.Errorf("ListInbox(thread) = %v, %v; want only the reply", inbox, err)
+	}
+}
@@ -0,0 +1,142 @@
+// ABOUTME: Capability grants held by principals, and the warnings recorded for calls made without one
+// ABOUTME: Grants are the only source of a principal's capabilities when tool calls are checked
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// GrantCapability grants a capability to a principal. This operation is
+// idempotent - granting a held capability succeeds silently.
+func (s *SQLStore) GrantCapability(ctx context.Context, principalID, capability, grantedBy string) error {
+	query := `
+		INSERT INTO principal_capabilities (principal_id, capability, granted_by, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (principal_id, capability) DO NOTHING
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		principalID,
+		capability,
+		nullString(grantedBy),
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("granting capability: %w", err)
+	}
+
+	s.logger.Debug("granted capability", "principal_id", principalID, "capability", capability)
+	return nil
+}
+
+// RevokeCapability removes a capability from a principal. This operation is
+// idempotent - revoking a capability that is not held succeeds silently.
+func (s *SQLStore) RevokeCapability(ctx context.Context, principalID, capability string) error {
+	query := `DELETE FROM principal_capabilities WHERE principal_id = ? AND capability = ?`
+
+	if _, err := s.db.ExecContext(ctx, query, principalID, capability); err != nil {
+		return fmt.Errorf("revoking capability: %w", err)
+	}
+
+	s.logger.Debug("revoked capability", "principal_id", principalID, "capability", capability)
+	return nil
+}
+
+// ListCapabilities returns the capabilities granted to a principal, sorted.
+func (s *SQLStore) ListCapabilities(ctx context.Context, principalID string) ([]string, error) {
+	query := `SELECT capability FROM principal_capabilities WHERE principal_id = ? ORDER BY capability`
+
+	rows, err := s.db.QueryContext(ctx, query, principalID)
+	if err != nil {
+		return nil, fmt.Errorf("listing capabilities: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	caps := []string{}
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, fmt.Errorf("scanning capability row: %w", err)
+		}
+		caps = append(caps, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating capability rows: %w", err)
+	}
+	return caps, nil
+}
+
+// CapabilityWarningDetail is the JSON text of a capability_warning ledger event.
+type CapabilityWarningDetail struct {
+	Tool        string `json:"tool"`
+	Capability  string `json:"capability"`
+	PrincipalID string `json:"principal_id,omitempty"`
+}
+
+// CapabilityWarning summarizes the capability_warning events one agent
+// triggered for one tool and capability.
+type CapabilityWarning struct {
+	AgentID     string    `json:"agent_id"`
+	PrincipalID string    `json:"principal_id"`
+	Tool        string    `json:"tool"`
+	Capability  string    `json:"capability"`
+	Count       int       `json:"count"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// ListCapabilityWarnings returns the capability warnings recorded since the
+// given time, most recent first.
+func (s *SQLStore) ListCapabilityWarnings(ctx context.Context, since time.Time) ([]CapabilityWarning, error) {
+	query := `
+		SELECT event_id, conversation_key, thread_id, direction, author, timestamp, type, text,
+		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id, channel_context_hash, parent_request_id
+		FROM ledger_events
+		WHERE type = ?
+		ORDER BY timestamp ASC
+	`
+
+	events, err := s.queryEvents(ctx, query, string(EventTypeCapabilityWarning))
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct{ agent, tool, capability string }
+	byKey := make(map[key]*CapabilityWarning)
+	for _, ev := range events {
+		if ev.Timestamp.Before(since) || ev.Text == nil {
+			continue
+		}
+		var d CapabilityWarningDetail
+		if err := json.Unmarshal([]byte(*ev.Text), &d); err != nil {
+			continue
+		}
+		k := key{ev.ConversationKey, d.Tool, d.Capability}
+		w, ok := byKey[k]
+		if !ok {
+			w = &CapabilityWarning{AgentID: ev.ConversationKey, Tool: d.Tool, Capability: d.Capability}
+			byKey[k] = w
+		}
+		w.Count++
+		w.LastSeen = ev.Timestamp
+		if d.PrincipalID != "" {
+			w.PrincipalID = d.PrincipalID
+		}
+	}
+
+	warnings := make([]CapabilityWarning, 0, len(byKey))
+	for _, w := range byKey {
+		warnings = append(warnings, *w)
+	}
+	sort.Slice(warnings, func(i, j int) bool {
+		if !warnings[i].LastSeen.Equal(warnings[j].LastSeen) {
+			return warnings[i].LastSeen.After(warnings[j].LastSeen)
+		}
+		return warnings[i].AgentID+warnings[i].Tool < warnings[j].AgentID+warnings[j].Tool
+	})
+	return warnings, nil
+}
@@ -0,0 +1,97 @@
+// ABOUTME: Tests for principal capability grants and capability warning reports
+// ABOUTME: Covers grant idempotency, revocation, principal deletion, and warning aggregation
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilityGrants(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.GrantCapability(ctx, "principal-1", "notes", "admin-1"))
+	require.NoError(t, store.GrantCapability(ctx, "principal-1", "notes", "admin-1"), "granting twice should be idempotent")
+	require.NoError(t, store.GrantCapability(ctx, "principal-1", "base", ""))
+	require.NoError(t, store.GrantCapability(ctx, "principal-2", "admin", ""))
+
+	caps, err := store.ListCapabilities(ctx, "principal-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"base", "notes"}, caps)
+
+	require.NoError(t, store.RevokeCapability(ctx, "principal-1", "notes"))
+	require.NoError(t, store.RevokeCapability(ctx, "principal-1", "notes"), "revoking twice should be idempotent")
+	caps, err = store.ListCapabilities(ctx, "principal-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"base"}, caps)
+
+	caps, err = store.ListCapabilities(ctx, "nobody")
+	require.NoError(t, err)
+	assert.Empty(t, caps)
+}
+
+func TestCapabilityGrants_DeletedWithPrincipal(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	p := &Principal{
+		ID:          "principal-del",
+		Type:        PrincipalTypeAgent,
+		PubkeyFP:    "fp-del",
+		DisplayName: "Doomed",
+		Status:      PrincipalStatusApproved,
+		CreatedAt:   time.Now(),
+	}
+	require.NoError(t, store.CreatePrincipal(ctx, p))
+	require.NoError(t, store.GrantCapability(ctx, p.ID, "notes", ""))
+	require.NoError(t, store.DeletePrincipal(ctx, p.ID))
+
+	caps, err := store.ListCapabilities(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Empty(t, caps, "grants must not outlive the principal")
+}
+
+func TestListCapabilityWarnings(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	save := func(agentID, tool, capability string, at time.Time) {
+		t.Helper()
+		data, err := json.Marshal(CapabilityWarningDetail{Tool: tool, Capability: capability, PrincipalID: "p-" + agentID})
+		require.NoError(t, err)
+		text := string(data)
+		require.NoError(t, store.SaveEvent(ctx, &LedgerEvent{
+			ID:              uuid.New().String(),
+			ConversationKey: agentID,
+			Direction:       EventDirectionOutbound,
+			Author:          agentID,
+			Timestamp:       at,
+			Type:            EventTypeCapabilityWarning,
+			Text:            &text,
+		}))
+	}
+	save("agent-a", "log_entry", "base", now.Add(-2*time.Hour))
+	save("agent-a", "log_entry", "base", now.Add(-time.Hour))
+	save("agent-b", "deploy", "ops", now.Add(-30*time.Minute))
+	save("agent-c", "log_entry", "base", now.Add(-10*24*time.Hour))
+
+	warnings, err := store.ListCapabilityWarnings(ctx, now.Add(-7*24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, warnings, 2, "warnings older than the window are excluded")
+
+	assert.Equal(t, "agent-b", warnings[0].AgentID, "most recent first")
+	assert.Equal(t, "agent-a", warnings[1].AgentID)
+	assert.Equal(t, 2, warnings[1].Count)
+	assert.Equal(t, "p-agent-a", warnings[1].PrincipalID)
+	assert.Equal(t, "base", warnings[1].Capability)
+	assert.True(t, warnings[1].LastSeen.Equal(now.Add(-time.Hour)))
+}
@@ -0,0 +1,256 @@
+// ABOUTME: Delivery tracking for responses relayed by bridge frontends
+// ABOUTME: Records pending deliveries, bridge acks/nacks, timeouts, and per-frontend stats
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrDeliveryNotFound is returned when no delivery exists for a request ID.
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	// ErrDeliveryResolved is returned when acking or nacking a delivery that is no longer pending.
+	ErrDeliveryResolved = errors.New("delivery already resolved")
+)
+
+// DeliveryStatus is the lifecycle state of a bridged response.
+type DeliveryStatus string
+
+const (
+	DeliveryPending DeliveryStatus = "pending" // waiting for the bridge to ack or nack
+	DeliveryAcked   DeliveryStatus = "acked"   // bridge posted the response
+	DeliveryNacked  DeliveryStatus = "nacked"  // bridge reported a failure
+	DeliveryExpired DeliveryStatus = "expired" // no ack arrived before the timeout
+)
+
+// Delivery tracks whether a bridge delivered an agent response to its frontend.
+type Delivery struct {
+	RequestID   string
+	ThreadID    string
+	AgentID     string
+	Frontend    string
+	ChannelID   string
+	Status      DeliveryStatus
+	Error       string // frontend error reported with a nack, or the expiry reason
+	ErrorCode   string // frontend-specific error code (e.g., M_LIMIT_EXCEEDED)
+	CreatedAt   time.Time
+	RespondedAt *time.Time // when the gateway finished streaming the response
+	ResolvedAt  *time.Time // when the delivery left the pending state
+}
+
+// FrontendDeliveryStats summarizes delivery outcomes for one frontend.
+type FrontendDeliveryStats struct {
+	Frontend string
+	Pending  int64
+	Acked    int64
+	Nacked   int64
+	Expired  int64
+}
+
+// SuccessRate returns the fraction of resolved deliveries that were acked.
+func (f FrontendDeliveryStats) SuccessRate() float64 {
+	resolved := f.Acked + f.Nacked + f.Expired
+	if resolved == 0 {
+		return 0
+	}
+	return float64(f.Acked) / float64(resolved)
+}
+
+// CreateDelivery records a new pending delivery.
+func (s *SQLStore) CreateDelivery(ctx context.Context, d *Delivery) error {
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+	d.Status = DeliveryPending
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO deliveries (request_id, thread_id, agent_id, frontend, channel_id, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, d.RequestID, d.ThreadID, d.AgentID, d.Frontend, nullString(d.ChannelID), string(d.Status),
+		d.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("inserting delivery: %w", err)
+	}
+	return nil
+}
+
+// MarkDeliveryResponded records that the response finished streaming to the bridge.
+// The ack timeout is measured from this point.
+func (s *SQLStore) MarkDeliveryResponded(ctx context.Context, requestID string, at time.Time) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE deliveries SET responded_at = ? WHERE request_id = ?`,
+		at.UTC().Format(time.RFC3339), requestID)
+	if err != nil {
+		return fmt.Errorf("marking delivery responded: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// ResolveDelivery moves a pending delivery to acked or nacked.
+// Returns ErrDeliveryResolved if it already left the pending state.
+func (s *SQLStore) ResolveDelivery(ctx context.Context, requestID string, status DeliveryStatus, errMsg, errCode string) error {
+	if status != DeliveryAcked && status != DeliveryNacked {
+		return fmt.Errorf("cannot resolve delivery to %q", status)
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE deliveries SET status = ?, error = ?, error_code = ?, resolved_at = ?
+		WHERE request_id = ? AND status = ?
+	`, string(status), nullString(errMsg), nullString(errCode), time.Now().UTC().Format(time.RFC3339),
+		requestID, string(DeliveryPending))
+	if err != nil {
+		return fmt.Errorf("resolving delivery: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	if _, err := s.GetDelivery(ctx, requestID); err != nil {
+		return err
+	}
+	return ErrDeliveryResolved
+}
+
+// ExpireDeliveries marks pending deliveries whose response finished before the
+// cutoff as expired. Returns the number of deliveries expired.
+func (s *SQLStore) ExpireDeliveries(ctx context.Context, respondedBefore time.Time) (int64, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE deliveries SET status = ?, error = ?, resolved_at = ?
+		WHERE status = ? AND responded_at IS NOT NULL AND responded_at < ?
+	`, string(DeliveryExpired), "no acknowledgment before timeout", now,
+		string(DeliveryPending), respondedBefore.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, fmt.Errorf("expiring deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetDelivery retrieves a delivery by request ID.
+func (s *SQLStore) GetDelivery(ctx context.Context, requestID string) (*Delivery, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT request_id, thread_id, agent_id, frontend, channel_id, status, error, error_code,
+		       created_at, responded_at, resolved_at
+		FROM deliveries WHERE request_id = ?
+	`, requestID)
+	d, err := scanDelivery(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	return d, err
+}
+
+// ListDeadLetters returns nacked and expired deliveries, newest first.
+// An empty frontend matches all frontends.
+func (s *SQLStore) ListDeadLetters(ctx context.Context, frontend string, limit int) ([]*Delivery, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	query := `
+		SELECT request_id, thread_id, agent_id, frontend, channel_id, status, error, error_code,
+		       created_at, responded_at, resolved_at
+		FROM deliveries
+		WHERE status IN (?, ?)`
+	args := []any{string(DeliveryNacked), string(DeliveryExpired)}
+	if frontend != "" {
+		query += ` AND frontend = ?`
+		args = append(args, frontend)
+	}
+	query += ` ORDER BY resolved_at DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying dead letters: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var deliveries []*Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating dead letters: %w", err)
+	}
+	return deliveries, nil
+}
+
+// GetDeliveryStats returns per-frontend delivery counts for deliveries created
+// at or after since (zero means all time), ordered by frontend.
+func (s *SQLStore) GetDeliveryStats(ctx context.Context, since time.Time) ([]FrontendDeliveryStats, error) {
+	query := `
+		SELECT frontend,
+		       SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN status = 'acked' THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN status = 'nacked' THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN status = 'expired' THEN 1 ELSE 0 END)
+		FROM deliveries`
+	var args []any
+	if !since.IsZero() {
+		query += ` WHERE created_at >= ?`
+		args = append(args, since.UTC().Format(time.RFC3339))
+	}
+	query += ` GROUP BY frontend ORDER BY frontend`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying delivery stats: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var stats []FrontendDeliveryStats
+	for rows.Next() {
+		var f FrontendDeliveryStats
+		if err := rows.Scan(&f.Frontend, &f.Pending, &f.Acked, &f.Nacked, &f.Expired); err != nil {
+			return nil, fmt.Errorf("scanning delivery stats: %w", err)
+		}
+		stats = append(stats, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating delivery stats: %w", err)
+	}
+	return stats, nil
+}
+
+// scanDelivery scans a delivery row from a *sql.Row or *sql.Rows.
+func scanDelivery(row interface{ Scan(...any) error }) (*Delivery, error) {
+	var d Delivery
+	var status string
+	var channelID, errMsg, errCode, respondedAt, resolvedAt sql.NullString
+	var createdAt string
+	if err := row.Scan(&d.RequestID, &d.ThreadID, &d.AgentID, &d.Frontend, &channelID, &status,
+		&errMsg, &errCode, &createdAt, &respondedAt, &resolvedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scanning delivery: %w", err)
+	}
+
+	d.Status = DeliveryStatus(status)
+	d.ChannelID = channelID.String
+	d.Error = errMsg.String
+	d.ErrorCode = errCode.String
+	d.CreatedAt = parseTimeWithWarning(createdAt, "delivery", d.RequestID, "created_at")
+	if respondedAt.Valid {
+		t := parseTimeWithWarning(respondedAt.String, "delivery", d.RequestID, "responded_at")
+		d.RespondedAt = &t
+	}
+	if resolvedAt.Valid {
+		t := parseTimeWithWarning(resolvedAt.String, "delivery", d.RequestID, "resolved_at")
+		d.ResolvedAt = &t
+	}
+	return &d, nil
+}
@@ -0,0 +1,126 @@
+// ABOUTME: Tests for bridge delivery tracking
+// ABOUTME: Covers ack/nack transitions, expiry, dead letters, and per-frontend stats
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeliveries_AckAndNack(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"req-ok", "req-fail"} {
+		if err := s.CreateDelivery(ctx, &Delivery{RequestID: id, ThreadID: "t1", AgentID: "a1", Frontend: "matrix", ChannelID: "!room"}); err != nil {
+			t.Fatalf("CreateDelivery: %v", err)
+		}
+	}
+
+	if err := s.ResolveDelivery(ctx, "req-ok", DeliveryAcked, "", ""); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+	if err := s.ResolveDelivery(ctx, "req-fail", DeliveryNacked, "rate limited", "M_LIMIT_EXCEEDED"); err != nil {
+		t.Fatalf("nack: %v", err)
+	}
+
+	if err := s.ResolveDelivery(ctx, "req-ok", DeliveryNacked, "late", ""); !errors.Is(err, ErrDeliveryResolved) {
+		t.Errorf("second resolve error = %v, want ErrDeliveryResolved", err)
+	}
+	if err := s.ResolveDelivery(ctx, "missing", DeliveryAcked, "", ""); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("missing resolve error = %v, want ErrDeliveryNotFound", err)
+	}
+
+	dead, err := s.ListDeadLetters(ctx, "", 10)
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %v", err)
+	}
+	if len(dead) != 1 || dead[0].RequestID != "req-fail" || dead[0].ErrorCode != "M_LIMIT_EXCEEDED" || dead[0].ChannelID != "!room" {
+		t.Errorf("dead letters = %+v, want req-fail with frontend error", dead)
+	}
+}
+
+func TestDeliveries_ExpireOnlyAfterResponse(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	if err := s.CreateDelivery(ctx, &Delivery{RequestID: "streaming", ThreadID: "t1", AgentID: "a1", Frontend: "matrix"}); err != nil {
+		t.Fatalf("CreateDelivery: %v", err)
+	}
+	if err := s.CreateDelivery(ctx, &Delivery{RequestID: "stale", ThreadID: "t1", AgentID: "a1", Frontend: "matrix"}); err != nil {
+		t.Fatalf("CreateDelivery: %v", err)
+	}
+	if err := s.MarkDeliveryResponded(ctx, "stale", time.Now().Add(-10*time.Minute)); err != nil {
+		t.Fatalf("MarkDeliveryResponded: %v", err)
+	}
+	if err := s.MarkDeliveryResponded(ctx, "missing", time.Now()); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("MarkDeliveryResponded(missing) = %v, want ErrDeliveryNotFound", err)
+	}
+
+	n, err := s.ExpireDeliveries(ctx, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("ExpireDeliveries: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expired %d deliveries, want 1", n)
+	}
+
+	d, err := s.GetDelivery(ctx, "stale")
+	if err != nil {
+		t.Fatalf("GetDelivery: %v", err)
+	}
+	if d.Status != DeliveryExpired || d.ResolvedAt == nil {
+		t.Errorf("stale delivery = %+v, want expired with resolved_at", d)
+	}
+	d, err = s.GetDelivery(ctx, "streaming")
+	if err != nil {
+		t.Fatalf("GetDelivery: %v", err)
+	}
+	if d.Status != DeliveryPending {
+		t.Errorf("streaming delivery status = %s, want pending", d.Status)
+	}
+}
+
+func TestDeliveries_Stats(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	seed := []struct {
+		id, frontend string
+		status       DeliveryStatus
+	}{
+		{"m1", "matrix", DeliveryAcked},
+		{"m2", "matrix", DeliveryAcked},
+		{"m3", "matrix", DeliveryNacked},
+		{"m4", "matrix", DeliveryPending},
+		{"s1", "slack", DeliveryAcked},
+	}
+	for _, d := range seed {
+		if err := s.CreateDelivery(ctx, &Delivery{RequestID: d.id, ThreadID: "t", AgentID: "a", Frontend: d.frontend}); err != nil {
+			t.Fatalf("CreateDelivery: %v", err)
+		}
+		if d.status != DeliveryPending {
+			if err := s.ResolveDelivery(ctx, d.id, d.status, "err", ""); err != nil {
+				t.Fatalf("ResolveDelivery: %v", err)
+			}
+		}
+	}
+
+	stats, err := s.GetDeliveryStats(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("GetDeliveryStats: %v", err)
+	}
+	if len(stats) != 2 || stats[0].Frontend != "matrix" || stats[1].Frontend != "slack" {
+		t.Fatalf("stats = %+v, want matrix and slack", stats)
+	}
+	m := stats[0]
+	if m.Acked != 2 || m.Nacked != 1 || m.Pending != 1 {
+		t.Errorf("matrix stats = %+v", m)
+	}
+	if rate := m.SuccessRate(); rate < 0.66 || rate > 0.67 {
+		t.Errorf("matrix success rate = %v, want 2/3", rate)
+	}
+}
@@ -1,4 +1,5 @@
-// Package store provides persistent storage for the gateway using SQLite.
+// Package store provides persistent storage for the gateway using SQLite or
+// Postgres.
 //
 // # Architecture
 //
@@ -12,8 +13,9 @@
 //   - SecretsStore: Secret management
 //   - LinkCodeStore: Device linking codes
 //
-// SQLiteStore implements all interfaces in a single struct, allowing easy
-// composition while maintaining clear interface boundaries.
+// SQLStore implements all interfaces in a single struct, allowing easy
+// composition while maintaining clear interface boundaries. The same type
+// serves both databases; Open picks one by driver name.
 //
 // # Data Models
 //
@@ -53,6 +55,25 @@
 //   - Development: ~/.local/share/coven/gateway.db
 //   - Testing: :memory: (in-memory database)
 //
+// # Postgres Configuration
+//
+// NewPostgresStore connects through lib/pq. Queries are written once with ?
+// placeholders, which each connection rewrites as $1, $2...; the few that
+// differ by database switch on the store's dialect. The schema mirrors
+// SQLite's, with TEXT timestamps and INTEGER flags, so rows round-trip
+// through ExportState and ImportState between the two. Full-text search uses
+// a GIN index on ledger_events.text instead of FTS5.
+//
+// # Transactions
+//
+// WithTx runs several store calls as one transaction: they all commit when
+// the callback returns nil and none do when it returns an error, panics, or
+// its context ends. On SQLite it holds one connection and starts with BEGIN
+// IMMEDIATE, so the transaction has the write lock before it writes. Store
+// methods that open a transaction of their own become savepoints inside it,
+// and so do nested WithTx calls. WithSQLTx hands the callback the full
+// *SQLStore rather than Store.
+//
 // # Error Handling
 //
 // Common errors:
@@ -70,9 +91,13 @@
 //	// store implements all Store interfaces
 //
 // Use NewSQLiteStore(":memory:") for integration tests with real SQLite.
+// With TEST_POSTGRES_DSN set, the store integration tests run against that
+// Postgres database instead, each in a schema of its own.
 //
 // # Migrations
 //
 // Migrations are embedded and run automatically on store initialization.
-// Migration files are in internal/store/migrations/ with numeric prefixes.
+// SQLite migrations live in sqlite.go; Postgres migration files are in
+// internal/store/migrations/postgres/ with numeric prefixes, and each schema
+// change needs both.
 package store
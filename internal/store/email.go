@@ -0,0 +1,78 @@
+// ABOUTME: Inbound email records: who sent a message to which agent and how it authenticated
+// ABOUTME: Ledger events for email point here through raw_payload_ref ("email:<Message-ID>")
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDuplicateEmail is returned when an email with the same Message-ID was
+// already recorded.
+var ErrDuplicateEmail = errors.New("email already received")
+
+// EmailPayloadRefPrefix prefixes the Message-ID in the raw_payload_ref of
+// ledger events that arrived by email.
+const EmailPayloadRefPrefix = "email:"
+
+// EmailMessage records an inbound email accepted for an agent, including the
+// SPF and DKIM results seen when it was received.
+type EmailMessage struct {
+	MessageID string // Message-ID header, angle brackets included
+	AgentID   string
+	From      string // bare sender address
+	To        string // recipient address that mapped to the agent
+	Subject   string
+	// SPF and DKIM hold RFC 8601 result keywords: pass, fail, softfail,
+	// neutral, none, temperror, permerror (SPF) or pass, fail, none,
+	// neutral, temperror, permerror (DKIM).
+	SPF         string
+	DKIM        string
+	AuthDetails string // human-readable detail, e.g. "dkim=pass header.d=example.com"
+	ReceivedAt  time.Time
+}
+
+// SaveEmailMessage records an inbound email. Saving a Message-ID twice fails
+// with ErrDuplicateEmail, which callers use to drop redelivered mail.
+func (s *SQLStore) SaveEmailMessage(ctx context.Context, m *EmailMessage) error {
+	if m.ReceivedAt.IsZero() {
+		m.ReceivedAt = time.Now()
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO email_messages (message_id, agent_id, from_addr, to_addr, subject, spf, dkim, auth_details, received_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, m.MessageID, m.AgentID, m.From, m.To, nullString(m.Subject), m.SPF, m.DKIM, nullString(m.AuthDetails),
+		m.ReceivedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		if isUniqueConstraintError(err) {
+			return ErrDuplicateEmail
+		}
+		return fmt.Errorf("inserting email message: %w", err)
+	}
+	return nil
+}
+
+// GetEmailMessage returns the record for a Message-ID, or ErrNotFound.
+func (s *SQLStore) GetEmailMessage(ctx context.Context, messageID string) (*EmailMessage, error) {
+	var m EmailMessage
+	var subject, details sql.NullString
+	var receivedAt string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT message_id, agent_id, from_addr, to_addr, subject, spf, dkim, auth_details, received_at
+		FROM email_messages WHERE message_id = ?
+	`, messageID).Scan(&m.MessageID, &m.AgentID, &m.From, &m.To, &subject, &m.SPF, &m.DKIM, &details, &receivedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying email message: %w", err)
+	}
+	m.Subject = subject.String
+	m.AuthDetails = details.String
+	m.ReceivedAt = parseTimeWithWarning(receivedAt, "email_message", m.MessageID, "received_at")
+	return &m, nil
+}
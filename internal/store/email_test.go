@@ -0,0 +1,44 @@
+// ABOUTME: Tests for inbound email records
+// ABOUTME: Covers round-tripping auth results and refusing a repeated Message-ID
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEmailMessages(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	msg := &EmailMessage{
+		MessageID:   "<m1@example.com>",
+		AgentID:     "agent-1",
+		From:        "alice@example.com",
+		To:          "helper@agents.example.org",
+		Subject:     "Weekly report",
+		SPF:         "pass",
+		DKIM:        "none",
+		AuthDetails: "spf=pass smtp.mailfrom=example.com; dkim=none",
+	}
+	if err := s.SaveEmailMessage(ctx, msg); err != nil {
+		t.Fatalf("SaveEmailMessage: %v", err)
+	}
+	if err := s.SaveEmailMessage(ctx, msg); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("saving twice: got %v, want ErrDuplicateEmail", err)
+	}
+
+	got, err := s.GetEmailMessage(ctx, "<m1@example.com>")
+	if err != nil {
+		t.Fatalf("GetEmailMessage: %v", err)
+	}
+	if got.AgentID != "agent-1" || got.SPF != "pass" || got.AuthDetails != msg.AuthDetails || got.ReceivedAt.IsZero() {
+		t.Errorf("got %+v", got)
+	}
+
+	if _, err := s.GetEmailMessage(ctx, "<missing@example.com>"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing message: got %v, want ErrNotFound", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 )
@@ -17,6 +18,9 @@ import (
 // ErrEventNotFound is returned when a requested event does not exist.
 var ErrEventNotFound = errors.New("event not found")
 
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 // EventDirection indicates whether an event is inbound (to agent) or outbound (from agent).
 type EventDirection string
 
@@ -35,6 +39,13 @@ const (
 	EventTypeToolResult EventType = "tool_result"
 	EventTypeSystem     EventType = "system"
 	EventTypeError      EventType = "error"
+	EventTypePlan       EventType = "plan"
+	EventTypeCitation   EventType = "citation"
+	EventTypeToolCheck  EventType = "tool_check" // verdict of a dry-run tool call
+
+	EventTypeCapabilityWarning EventType = "capability_warning" // call let through without a granted capability
+	EventTypeToolPolicy        EventType = "tool_policy"        // tool approval decided by a policy rule
+	EventTypeAgentHealth       EventType = "agent_health"       // agent's heartbeat health became degraded
 )
 
 // GetEventsParams specifies the parameters for retrieving events from the history store.
@@ -70,15 +81,23 @@ type LedgerEvent struct {
 	// Actor attribution - who originated this event
 	ActorPrincipalID *string // principal_id of the authenticated entity
 	ActorMemberID    *string // member_id if principal is linked to a member (nullable in v1)
+
+	// ChannelContextHash identifies the binding context sent to the agent
+	// with an inbound message (see BindingContextHash), if there was one.
+	ChannelContextHash *string
+
+	// ParentRequestID is the request that delegated this message to the
+	// agent through delegate_task, on the inbound message of a child thread.
+	ParentRequestID *string
 }
 
 // SaveEvent persists a ledger event to the database.
-func (s *SQLiteStore) SaveEvent(ctx context.Context, event *LedgerEvent) error {
+func (s *SQLStore) SaveEvent(ctx context.Context, event *LedgerEvent) error {
 	query := `
 		INSERT INTO ledger_events (
 			event_id, conversation_key, thread_id, direction, author, timestamp, type, text,
-			raw_transport, raw_payload_ref, actor_principal_id, actor_member_id
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			raw_transport, raw_payload_ref, actor_principal_id, actor_member_id, channel_context_hash, parent_request_id
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := s.db.ExecContext(ctx, query,
@@ -94,6 +113,8 @@ func (s *SQLiteStore) SaveEvent(ctx context.Context, event *LedgerEvent) error {
 		event.RawPayloadRef,
 		event.ActorPrincipalID,
 		event.ActorMemberID,
+		event.ChannelContextHash,
+		event.ParentRequestID,
 	)
 	if err != nil {
 		return fmt.Errorf("inserting event: %w", err)
@@ -109,10 +130,10 @@ func (s *SQLiteStore) SaveEvent(ctx context.Context, event *LedgerEvent) error {
 }
 
 // GetEvent retrieves a single event by ID.
-func (s *SQLiteStore) GetEvent(ctx context.Context, id string) (*LedgerEvent, error) {
+func (s *SQLStore) GetEvent(ctx context.Context, id string) (*LedgerEvent, error) {
 	query := `
 		SELECT event_id, conversation_key, thread_id, direction, author, timestamp, type, text,
-		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id
+		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id, channel_context_hash, parent_request_id
 		FROM ledger_events
 		WHERE event_id = ?
 	`
@@ -134,6 +155,8 @@ func (s *SQLiteStore) GetEvent(ctx context.Context, id string) (*LedgerEvent, er
 		&event.RawPayloadRef,
 		&event.ActorPrincipalID,
 		&event.ActorMemberID,
+		&event.ChannelContextHash,
+		&event.ParentRequestID,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -154,7 +177,7 @@ func (s *SQLiteStore) GetEvent(ctx context.Context, id string) (*LedgerEvent, er
 }
 
 // ListEventsByConversation retrieves events for a conversation key, ordered by timestamp ASC.
-func (s *SQLiteStore) ListEventsByConversation(ctx context.Context, conversationKey string, limit int) ([]*LedgerEvent, error) {
+func (s *SQLStore) ListEventsByConversation(ctx context.Context, conversationKey string, limit int) ([]*LedgerEvent, error) {
 	if limit <= 0 {
 		limit = 100
 	}
@@ -164,7 +187,7 @@ func (s *SQLiteStore) ListEventsByConversation(ctx context.Context, conversation
 
 	query := `
 		SELECT event_id, conversation_key, thread_id, direction, author, timestamp, type, text,
-		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id
+		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id, channel_context_hash, parent_request_id
 		FROM ledger_events
 		WHERE conversation_key = ?
 		ORDER BY timestamp ASC
@@ -175,7 +198,7 @@ func (s *SQLiteStore) ListEventsByConversation(ctx context.Context, conversation
 }
 
 // ListEventsByActor retrieves events created by a specific principal.
-func (s *SQLiteStore) ListEventsByActor(ctx context.Context, principalID string, limit int) ([]*LedgerEvent, error) {
+func (s *SQLStore) ListEventsByActor(ctx context.Context, principalID string, limit int) ([]*LedgerEvent, error) {
 	if limit <= 0 {
 		limit = 100
 	}
@@ -185,7 +208,7 @@ func (s *SQLiteStore) ListEventsByActor(ctx context.Context, principalID string,
 
 	query := `
 		SELECT event_id, conversation_key, thread_id, direction, author, timestamp, type, text,
-		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id
+		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id, channel_context_hash, parent_request_id
 		FROM ledger_events
 		WHERE actor_principal_id = ?
 		ORDER BY timestamp ASC
@@ -196,7 +219,7 @@ func (s *SQLiteStore) ListEventsByActor(ctx context.Context, principalID string,
 }
 
 // ListEventsByActorDesc retrieves events created by a specific principal, ordered newest first.
-func (s *SQLiteStore) ListEventsByActorDesc(ctx context.Context, principalID string, limit int) ([]*LedgerEvent, error) {
+func (s *SQLStore) ListEventsByActorDesc(ctx context.Context, principalID string, limit int) ([]*LedgerEvent, error) {
 	if limit <= 0 {
 		limit = 100
 	}
@@ -206,7 +229,7 @@ func (s *SQLiteStore) ListEventsByActorDesc(ctx context.Context, principalID str
 
 	query := `
 		SELECT event_id, conversation_key, thread_id, direction, author, timestamp, type, text,
-		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id
+		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id, channel_context_hash, parent_request_id
 		FROM ledger_events
 		WHERE actor_principal_id = ?
 		ORDER BY timestamp DESC
@@ -217,7 +240,7 @@ func (s *SQLiteStore) ListEventsByActorDesc(ctx context.Context, principalID str
 }
 
 // queryEvents is a helper that executes a query and returns events.
-func (s *SQLiteStore) queryEvents(ctx context.Context, query string, args ...any) ([]*LedgerEvent, error) {
+func (s *SQLStore) queryEvents(ctx context.Context, query string, args ...any) ([]*LedgerEvent, error) {
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying events: %w", err)
@@ -243,6 +266,8 @@ func (s *SQLiteStore) queryEvents(ctx context.Context, query string, args ...any
 			&event.RawPayloadRef,
 			&event.ActorPrincipalID,
 			&event.ActorMemberID,
+			&event.ChannelContextHash,
+			&event.ParentRequestID,
 		); err != nil {
 			return nil, fmt.Errorf("scanning event row: %w", err)
 		}
@@ -303,7 +328,7 @@ func buildEventsQuery(p GetEventsParams, cursorTS time.Time, cursorID string) (s
 	b := &eventsQueryBuilder{}
 	b.query = `
 		SELECT event_id, conversation_key, thread_id, direction, author, timestamp, type, text,
-		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id
+		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id, channel_context_hash, parent_request_id
 		FROM ledger_events
 		WHERE conversation_key = ?
 	`
@@ -356,6 +381,8 @@ func scanLedgerEvent(scanner interface {
 		&event.RawPayloadRef,
 		&event.ActorPrincipalID,
 		&event.ActorMemberID,
+		&event.ChannelContextHash,
+		&event.ParentRequestID,
 	); err != nil {
 		return event, fmt.Errorf("scanning event row: %w", err)
 	}
@@ -373,7 +400,7 @@ func scanLedgerEvent(scanner interface {
 
 // GetEvents retrieves events for a conversation with pagination support.
 // Events are returned in chronological order (oldest first).
-func (s *SQLiteStore) GetEvents(ctx context.Context, p GetEventsParams) (*GetEventsResult, error) {
+func (s *SQLStore) GetEvents(ctx context.Context, p GetEventsParams) (*GetEventsResult, error) {
 	if p.ConversationKey == "" {
 		return nil, errors.New("conversation_key required")
 	}
@@ -407,7 +434,7 @@ func parseCursor(cursor string) (time.Time, string, error) {
 	}
 	ts, id, err := decodeCursor(cursor)
 	if err != nil {
-		return time.Time{}, "", fmt.Errorf("invalid cursor: %w", err)
+		return time.Time{}, "", fmt.Errorf("%w: %w", ErrInvalidCursor, err)
 	}
 	return ts, id, nil
 }
@@ -431,7 +458,7 @@ func collectEventRows(rows *sql.Rows) ([]LedgerEvent, error) {
 // GetEventsByThreadID retrieves the most recent events for a thread, ordered
 // chronologically (ASC). Uses a DESC subquery to pick the N most recent rows,
 // then re-orders ASC so callers receive events in conversation order.
-func (s *SQLiteStore) GetEventsByThreadID(ctx context.Context, threadID string, limit int) ([]*LedgerEvent, error) {
+func (s *SQLStore) GetEventsByThreadID(ctx context.Context, threadID string, limit int) ([]*LedgerEvent, error) {
 	if limit <= 0 {
 		limit = 100
 	}
@@ -441,10 +468,10 @@ func (s *SQLiteStore) GetEventsByThreadID(ctx context.Context, threadID string,
 
 	query := `
 		SELECT event_id, conversation_key, thread_id, direction, author, timestamp, type, text,
-		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id
+		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id, channel_context_hash, parent_request_id
 		FROM (
 			SELECT event_id, conversation_key, thread_id, direction, author, timestamp, type, text,
-			       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id
+			       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id, channel_context_hash, parent_request_id
 			FROM ledger_events
 			WHERE thread_id = ?
 			ORDER BY timestamp DESC, event_id DESC
@@ -456,6 +483,95 @@ func (s *SQLiteStore) GetEventsByThreadID(ctx context.Context, threadID string,
 	return s.queryEvents(ctx, query, threadID, limit)
 }
 
+// GetThreadEventsPage returns up to limit events of a thread older than the
+// before cursor (or the newest events when before is empty), in chronological
+// order. The returned cursor fetches the next older page and is empty when
+// there is none.
+func (s *SQLStore) GetThreadEventsPage(ctx context.Context, threadID string, limit int, before string) ([]*LedgerEvent, string, error) {
+	limit = normalizeLimit(limit)
+	cursorTS, cursorID, err := parseCursor(before)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := `
+		SELECT event_id, conversation_key, thread_id, direction, author, timestamp, type, text,
+		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id, channel_context_hash, parent_request_id
+		FROM ledger_events
+		WHERE thread_id = ?
+	`
+	args := []any{threadID}
+	if before != "" {
+		tsStr := cursorTS.Format(time.RFC3339)
+		query += ` AND (timestamp < ? OR (timestamp = ? AND event_id < ?))`
+		args = append(args, tsStr, tsStr, cursorID)
+	}
+	query += ` ORDER BY timestamp DESC, event_id DESC LIMIT ?`
+	args = append(args, limit+1)
+
+	events, err := s.queryEvents(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(events) > limit {
+		events = events[:limit]
+		oldest := events[limit-1]
+		next = encodeCursor(oldest.Timestamp, oldest.ID)
+	}
+	slices.Reverse(events)
+	return events, next, nil
+}
+
+// threadEventBatch is how many events ForEachThreadEvent reads per query.
+const threadEventBatch = 500
+
+// ForEachThreadEvent calls fn with each of a thread's events in
+// chronological order. Events are read a batch at a time, so a long thread
+// is never held in memory whole and no query stays open while fn runs. It
+// stops at the first error from fn and returns it.
+func (s *SQLStore) ForEachThreadEvent(ctx context.Context, threadID string, fn func(*LedgerEvent) error) error {
+	query := `
+		SELECT event_id, conversation_key, thread_id, direction, author, timestamp, type, text,
+		       raw_transport, raw_payload_ref, actor_principal_id, actor_member_id, channel_context_hash, parent_request_id
+		FROM ledger_events
+		WHERE thread_id = ? AND (timestamp > ? OR (timestamp = ? AND event_id > ?))
+		ORDER BY timestamp ASC, event_id ASC
+		LIMIT ?
+	`
+	var afterTS, afterID string
+	for {
+		events, err := s.queryEvents(ctx, query, threadID, afterTS, afterTS, afterID, threadEventBatch)
+		if err != nil {
+			return err
+		}
+		for _, evt := range events {
+			if err := fn(evt); err != nil {
+				return err
+			}
+		}
+		if len(events) < threadEventBatch {
+			return nil
+		}
+		last := events[len(events)-1]
+		afterTS, afterID = last.Timestamp.Format(time.RFC3339), last.ID
+	}
+}
+
+// HasAgentReply reports whether any agent has ever sent a message back
+// through the gateway.
+func (s *SQLStore) HasAgentReply(ctx context.Context) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM ledger_events WHERE direction = ? AND type = ?)
+	`, string(EventDirectionOutbound), string(EventTypeMessage)).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("checking for agent replies: %w", err)
+	}
+	return exists, nil
+}
+
 // EventToMessage converts a LedgerEvent to the legacy Message format.
 // This provides a single conversion point for all code that needs to
 // display events as messages.
@@ -504,6 +620,8 @@ func EventToMessage(evt *LedgerEvent) *Message {
 				msg.Content = resultData.Output
 			}
 		}
+	case EventTypeCitation:
+		msg.Type = MessageTypeCitation
 	default:
 		msg.Type = MessageTypeMessage
 	}
@@ -0,0 +1,66 @@
+// ABOUTME: Benchmarks for ledger event inserts.
+// ABOUTME: Tracks SaveEvent throughput sequentially and under concurrent writers.
+
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newBenchStore(b *testing.B) *SQLStore {
+	b.Helper()
+	s, err := NewSQLiteStore(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	b.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func benchEvent() *LedgerEvent {
+	text := "benchmark response chunk"
+	return &LedgerEvent{
+		ID:              uuid.New().String(),
+		ConversationKey: "bench-agent",
+		Direction:       EventDirectionOutbound,
+		Author:          "bench-agent",
+		Timestamp:       time.Now(),
+		Type:            EventTypeMessage,
+		Text:            &text,
+	}
+}
+
+// BenchmarkSaveEvent measures a single ledger insert.
+func BenchmarkSaveEvent(b *testing.B) {
+	s := newBenchStore(b)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := s.SaveEvent(ctx, benchEvent()); err != nil {
+			b.Fatalf("SaveEvent: %v", err)
+		}
+	}
+}
+
+// BenchmarkSaveEventBatch measures inserting a batch of events, as a single
+// streamed response produces (user message, tool call/result, final message).
+func BenchmarkSaveEventBatch(b *testing.B) {
+	const batchSize = 4
+	s := newBenchStore(b)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		for range batchSize {
+			if err := s.SaveEvent(ctx, benchEvent()); err != nil {
+				b.Fatalf("SaveEvent: %v", err)
+			}
+		}
+	}
+}
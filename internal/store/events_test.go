@@ -5,6 +5,8 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -38,6 +40,29 @@ func TestEventStore_SaveEvent(t *testing.T) {
 	assert.Equal(t, "Hello, world!", *retrieved.Text)
 }
 
+func TestEventStore_SaveEvent_ParentRequestID(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	parent := "request-parent"
+	event := &LedgerEvent{
+		ID:              "event-child",
+		ConversationKey: "agent-b",
+		Direction:       EventDirectionInbound,
+		Author:          "agent:agent-a",
+		Timestamp:       time.Now().UTC().Truncate(time.Second),
+		Type:            EventTypeMessage,
+		Text:            strPtr("Summarize the logs"),
+		ParentRequestID: &parent,
+	}
+	require.NoError(t, store.SaveEvent(ctx, event))
+
+	retrieved, err := store.GetEvent(ctx, "event-child")
+	require.NoError(t, err)
+	require.NotNil(t, retrieved.ParentRequestID)
+	assert.Equal(t, parent, *retrieved.ParentRequestID)
+}
+
 func TestEventStore_SaveEvent_WithActorPrincipal(t *testing.T) {
 	store := setupTestStore(t)
 	ctx := context.Background()
@@ -678,3 +703,94 @@ func TestGetEvents_DefaultLimit(t *testing.T) {
 	assert.Len(t, result.Events, 50)
 	assert.True(t, result.HasMore)
 }
+
+func TestGetThreadEventsPage_PagesBackwards(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	threadID := "thread-page"
+	baseTime := time.Now().UTC().Truncate(time.Second)
+	for i := range 5 {
+		require.NoError(t, store.SaveEvent(ctx, &LedgerEvent{
+			ID:              fmt.Sprintf("page-evt-%d", i),
+			ConversationKey: "test:conversation:page",
+			ThreadID:        &threadID,
+			Direction:       EventDirectionInbound,
+			Author:          "user",
+			Timestamp:       baseTime.Add(time.Duration(i) * time.Second),
+			Type:            EventTypeMessage,
+			Text:            strPtr(fmt.Sprintf("Message %d", i)),
+		}))
+	}
+
+	// First page: the newest two, in chronological order
+	events, next, err := store.GetThreadEventsPage(ctx, threadID, 2, "")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "page-evt-3", events[0].ID)
+	assert.Equal(t, "page-evt-4", events[1].ID)
+	require.NotEmpty(t, next)
+
+	events, next, err = store.GetThreadEventsPage(ctx, threadID, 2, next)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "page-evt-1", events[0].ID)
+	assert.Equal(t, "page-evt-2", events[1].ID)
+	require.NotEmpty(t, next)
+
+	// Last page has no further cursor
+	events, next, err = store.GetThreadEventsPage(ctx, threadID, 2, next)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "page-evt-0", events[0].ID)
+	assert.Empty(t, next)
+
+	_, _, err = store.GetThreadEventsPage(ctx, threadID, 2, "not-valid-base64!!!")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestForEachThreadEvent_ReadsEveryBatchInOrder(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	threadID := "thread-foreach"
+	other := "thread-other"
+	baseTime := time.Now().UTC().Truncate(time.Second)
+	// More than two batches, with runs of equal timestamps across batch edges.
+	total := 2*threadEventBatch + 3
+	for i := range total {
+		require.NoError(t, store.SaveEvent(ctx, &LedgerEvent{
+			ID:              fmt.Sprintf("each-evt-%04d", i),
+			ConversationKey: "test:conversation:each",
+			ThreadID:        &threadID,
+			Direction:       EventDirectionInbound,
+			Author:          "user",
+			Timestamp:       baseTime.Add(time.Duration(i/7) * time.Second),
+			Type:            EventTypeMessage,
+			Text:            strPtr("hi"),
+		}))
+	}
+	require.NoError(t, store.SaveEvent(ctx, &LedgerEvent{
+		ID: "other-evt", ConversationKey: "test:conversation:each", ThreadID: &other,
+		Direction: EventDirectionInbound, Author: "user", Timestamp: baseTime, Type: EventTypeMessage,
+	}))
+
+	var ids []string
+	require.NoError(t, store.ForEachThreadEvent(ctx, threadID, func(e *LedgerEvent) error {
+		ids = append(ids, e.ID)
+		return nil
+	}))
+	require.Len(t, ids, total)
+	for i, id := range ids {
+		require.Equal(t, fmt.Sprintf("each-evt-%04d", i), id)
+	}
+
+	stop := errors.New("stop")
+	seen := 0
+	err := store.ForEachThreadEvent(ctx, threadID, func(*LedgerEvent) error {
+		seen++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, seen)
+}
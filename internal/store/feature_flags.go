@@ -0,0 +1,101 @@
+// ABOUTME: Persistence for runtime feature flag overrides
+// ABOUTME: Rows only exist for flags an admin has changed; compiled-in defaults cover the rest
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// FeatureFlag is a stored override for a feature flag.
+type FeatureFlag struct {
+	Name       string
+	Enabled    bool
+	Percentage *int     // nil means every principal; otherwise 0-100 rollout
+	Principals []string // always enabled for these principals when Enabled
+	UpdatedAt  time.Time
+	UpdatedBy  string
+}
+
+// ListFeatureFlags returns every stored flag override, ordered by name.
+func (s *SQLStore) ListFeatureFlags(ctx context.Context) ([]*FeatureFlag, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, enabled, percentage, principals, updated_at, updated_by
+		FROM feature_flags
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("querying feature flags: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var flags []*FeatureFlag
+	for rows.Next() {
+		var (
+			f          FeatureFlag
+			percentage sql.NullInt64
+			principals sql.NullString
+			updatedAt  string
+			updatedBy  sql.NullString
+		)
+		if err := rows.Scan(&f.Name, &f.Enabled, &percentage, &principals, &updatedAt, &updatedBy); err != nil {
+			return nil, fmt.Errorf("scanning feature flag: %w", err)
+		}
+		if percentage.Valid {
+			p := int(percentage.Int64)
+			f.Percentage = &p
+		}
+		if principals.Valid && principals.String != "" {
+			if err := json.Unmarshal([]byte(principals.String), &f.Principals); err != nil {
+				return nil, fmt.Errorf("decoding principals for flag %s: %w", f.Name, err)
+			}
+		}
+		f.UpdatedAt = parseTimeWithWarning(updatedAt, "feature_flag", f.Name, "updated_at")
+		f.UpdatedBy = updatedBy.String
+		flags = append(flags, &f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating feature flags: %w", err)
+	}
+	return flags, nil
+}
+
+// SaveFeatureFlag inserts or replaces a flag override. UpdatedAt is set to now.
+func (s *SQLStore) SaveFeatureFlag(ctx context.Context, f *FeatureFlag) error {
+	if f.Percentage != nil && (*f.Percentage < 0 || *f.Percentage > 100) {
+		return fmt.Errorf("percentage %d out of range 0-100", *f.Percentage)
+	}
+
+	var percentage sql.NullInt64
+	if f.Percentage != nil {
+		percentage = sql.NullInt64{Int64: int64(*f.Percentage), Valid: true}
+	}
+	var principals sql.NullString
+	if len(f.Principals) > 0 {
+		data, err := json.Marshal(f.Principals)
+		if err != nil {
+			return fmt.Errorf("encoding principals: %w", err)
+		}
+		principals = sql.NullString{String: string(data), Valid: true}
+	}
+
+	f.UpdatedAt = time.Now().UTC()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO feature_flags (name, enabled, percentage, principals, updated_at, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			enabled = excluded.enabled,
+			percentage = excluded.percentage,
+			principals = excluded.principals,
+			updated_at = excluded.updated_at,
+			updated_by = excluded.updated_by
+	`, f.Name, f.Enabled, percentage, principals, f.UpdatedAt.Format(time.RFC3339), nullString(f.UpdatedBy))
+	if err != nil {
+		return fmt.Errorf("saving feature flag %s: %w", f.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// ABOUTME: Tests for feature flag override persistence
+// ABOUTME: Covers upsert round-trips and percentage validation
+
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatureFlags_SaveAndList(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	flags, err := store.ListFeatureFlags(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, flags)
+
+	pct := 40
+	require.NoError(t, store.SaveFeatureFlag(ctx, &FeatureFlag{
+		Name: "sse_resume", Enabled: true, Percentage: &pct, Principals: []string{"p1", "p2"}, UpdatedBy: "admin",
+	}))
+	require.NoError(t, store.SaveFeatureFlag(ctx, &FeatureFlag{Name: "fan_out_sends"}))
+
+	flags, err = store.ListFeatureFlags(ctx)
+	require.NoError(t, err)
+	require.Len(t, flags, 2)
+	assert.Equal(t, "fan_out_sends", flags[0].Name)
+	assert.False(t, flags[0].Enabled)
+	assert.Nil(t, flags[0].Percentage)
+	assert.Empty(t, flags[0].Principals)
+
+	sse := flags[1]
+	assert.True(t, sse.Enabled)
+	require.NotNil(t, sse.Percentage)
+	assert.Equal(t, 40, *sse.Percentage)
+	assert.Equal(t, []string{"p1", "p2"}, sse.Principals)
+	assert.Equal(t, "admin", sse.UpdatedBy)
+	assert.False(t, sse.UpdatedAt.IsZero())
+
+	// Upsert replaces the row, including clearing the percentage.
+	require.NoError(t, store.SaveFeatureFlag(ctx, &FeatureFlag{Name: "sse_resume", Enabled: false}))
+	flags, err = store.ListFeatureFlags(ctx)
+	require.NoError(t, err)
+	require.Len(t, flags, 2)
+	assert.False(t, flags[1].Enabled)
+	assert.Nil(t, flags[1].Percentage)
+
+	bad := 150
+	assert.Error(t, store.SaveFeatureFlag(ctx, &FeatureFlag{Name: "sse_resume", Percentage: &bad}))
+}
@@ -0,0 +1,101 @@
+// ABOUTME: Idempotency keys of sends, per principal, with the request each one started
+// ABOUTME: A claim is a single upsert, so concurrent retries of one key cannot both win
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IdempotencyKey records the send a principal made under a key. RequestID is
+// empty while the send is still being accepted.
+type IdempotencyKey struct {
+	PrincipalID string
+	Key         string
+	RequestID   string
+	CreatedAt   time.Time
+}
+
+// idempotencyKeyColumns are the columns scanIdempotencyKey reads, in order.
+const idempotencyKeyColumns = `principal_id, idem_key, request_id, created_at`
+
+func scanIdempotencyKey(row interface{ Scan(...any) error }) (*IdempotencyKey, error) {
+	var k IdempotencyKey
+	var createdAt string
+	if err := row.Scan(&k.PrincipalID, &k.Key, &k.RequestID, &createdAt); err != nil {
+		return nil, err
+	}
+	k.CreatedAt = parseTimeWithWarning(createdAt, "idempotency_key", k.Key, "created_at")
+	return &k, nil
+}
+
+// ClaimIdempotencyKey claims key for principalID at now, with no request
+// yet. It reports false, claiming nothing, when the key is already held by a
+// claim made after expiredBefore; an older claim is taken over.
+func (s *SQLStore) ClaimIdempotencyKey(ctx context.Context, principalID, key string, now, expiredBefore time.Time) (bool, error) {
+	_, err := scanIdempotencyKey(s.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (principal_id, idem_key, request_id, created_at)
+		VALUES (?, ?, '', ?)
+		ON CONFLICT (principal_id, idem_key) DO UPDATE SET
+			request_id = '', created_at = excluded.created_at
+		WHERE idempotency_keys.created_at <= ?
+		RETURNING `+idempotencyKeyColumns,
+		principalID, key, now.UTC().Format(time.RFC3339), expiredBefore.UTC().Format(time.RFC3339)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("claiming idempotency key: %w", err)
+	}
+	return true, nil
+}
+
+// GetIdempotencyKey returns principalID's claim on key, or ErrNotFound.
+func (s *SQLStore) GetIdempotencyKey(ctx context.Context, principalID, key string) (*IdempotencyKey, error) {
+	k, err := scanIdempotencyKey(s.db.QueryRowContext(ctx,
+		`SELECT `+idempotencyKeyColumns+` FROM idempotency_keys WHERE principal_id = ? AND idem_key = ?`, principalID, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying idempotency key: %w", err)
+	}
+	return k, nil
+}
+
+// SetIdempotencyKeyRequest records the request a claimed key started.
+func (s *SQLStore) SetIdempotencyKeyRequest(ctx context.Context, principalID, key, requestID string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET request_id = ? WHERE principal_id = ? AND idem_key = ?`, requestID, principalID, key)
+	if err != nil {
+		return fmt.Errorf("setting idempotency key request: %w", err)
+	}
+	return nil
+}
+
+// DeleteIdempotencyKey releases principalID's claim on key.
+func (s *SQLStore) DeleteIdempotencyKey(ctx context.Context, principalID, key string) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE principal_id = ? AND idem_key = ?`, principalID, key)
+	if err != nil {
+		return fmt.Errorf("deleting idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys removes the claims made at or before cutoff.
+func (s *SQLStore) DeleteExpiredIdempotencyKeys(ctx context.Context, cutoff time.Time) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE created_at <= ?`, cutoff.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("deleting expired idempotency keys: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		s.logger.Debug("deleted expired idempotency keys", "count", n)
+	}
+	return nil
+}
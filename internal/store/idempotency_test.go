@@ -0,0 +1,73 @@
+// ABOUTME: Tests for send idempotency key storage
+// ABOUTME: Covers claiming, taking over expired claims, per-principal scope and the expiry sweep
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClaimIdempotencyKey(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	expired := now.Add(-time.Hour)
+
+	if ok, err := s.ClaimIdempotencyKey(ctx, "alice", "key-1", now, expired); err != nil || !ok {
+		t.Fatalf("first claim = %v, %v; want claimed", ok, err)
+	}
+	if ok, err := s.ClaimIdempotencyKey(ctx, "alice", "key-1", now, expired); err != nil || ok {
+		t.Errorf("second claim = %v, %v; want refused", ok, err)
+	}
+	if ok, _ := s.ClaimIdempotencyKey(ctx, "bob", "key-1", now, expired); !ok {
+		t.Error("another principal could not claim the same key")
+	}
+
+	if err := s.SetIdempotencyKeyRequest(ctx, "alice", "key-1", "req-1"); err != nil {
+		t.Fatalf("SetIdempotencyKeyRequest: %v", err)
+	}
+	k, err := s.GetIdempotencyKey(ctx, "alice", "key-1")
+	if err != nil || k.RequestID != "req-1" {
+		t.Fatalf("GetIdempotencyKey = %+v, %v; want req-1", k, err)
+	}
+
+	// Once the claim is older than the window, a new one takes it over.
+	later := now.Add(2 * time.Hour)
+	if ok, err := s.ClaimIdempotencyKey(ctx, "alice", "key-1", later, later.Add(-time.Hour)); err != nil || !ok {
+		t.Fatalf("claim after expiry = %v, %v; want claimed", ok, err)
+	}
+	if k, _ := s.GetIdempotencyKey(ctx, "alice", "key-1"); k.RequestID != "" {
+		t.Errorf("taken-over claim kept request %q", k.RequestID)
+	}
+
+	if err := s.DeleteIdempotencyKey(ctx, "alice", "key-1"); err != nil {
+		t.Fatalf("DeleteIdempotencyKey: %v", err)
+	}
+	if _, err := s.GetIdempotencyKey(ctx, "alice", "key-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("after delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for key, at := range map[string]time.Time{"old": now.Add(-2 * time.Hour), "new": now} {
+		if _, err := s.ClaimIdempotencyKey(ctx, "alice", key, at, at.Add(-time.Hour)); err != nil {
+			t.Fatalf("ClaimIdempotencyKey: %v", err)
+		}
+	}
+	if err := s.DeleteExpiredIdempotencyKeys(ctx, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("DeleteExpiredIdempotencyKeys: %v", err)
+	}
+	if _, err := s.GetIdempotencyKey(ctx, "alice", "old"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expired key survived the sweep: %v", err)
+	}
+	if _, err := s.GetIdempotencyKey(ctx, "alice", "new"); err != nil {
+		t.Errorf("live key was swept: %v", err)
+	}
+}
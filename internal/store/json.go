@@ -0,0 +1,82 @@
+// ABOUTME: JSON encoding for store models that are returned directly by API handlers.
+// ABOUTME: Emits timestamps as RFC3339 UTC regardless of how they were loaded or stored.
+
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// Field names stay as the Go names (the shape clients already consume); only
+// the timestamp rendering changes. Each method marshals a method-less alias of
+// the model with its time fields shadowed by formatted strings.
+
+// MarshalJSON implements json.Marshaler.
+func (t Thread) MarshalJSON() ([]byte, error) {
+	type thread Thread
+	return json.Marshal(struct {
+		thread
+		CreatedAt string
+		UpdatedAt string
+	}{thread(t), timeparse.Format(t.CreatedAt), timeparse.Format(t.UpdatedAt)})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message
+	return json.Marshal(struct {
+		message
+		CreatedAt string
+	}{message(m), timeparse.Format(m.CreatedAt)})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p Principal) MarshalJSON() ([]byte, error) {
+	type principal Principal
+	return json.Marshal(struct {
+		principal
+		CreatedAt string
+		LastSeen  *string
+	}{principal(p), timeparse.Format(p.CreatedAt), timeparse.FormatPtr(p.LastSeen)})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (e LogEntry) MarshalJSON() ([]byte, error) {
+	type logEntry LogEntry
+	return json.Marshal(struct {
+		logEntry
+		CreatedAt string
+	}{logEntry(e), timeparse.Format(e.CreatedAt)})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t Todo) MarshalJSON() ([]byte, error) {
+	type todo Todo
+	return json.Marshal(struct {
+		todo
+		DueDate   *string
+		CreatedAt string
+		UpdatedAt string
+	}{todo(t), timeparse.FormatPtr(t.DueDate), timeparse.Format(t.CreatedAt), timeparse.Format(t.UpdatedAt)})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p BBSPost) MarshalJSON() ([]byte, error) {
+	type post BBSPost
+	return json.Marshal(struct {
+		post
+		CreatedAt string
+	}{post(p), timeparse.Format(p.CreatedAt)})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m AgentMail) MarshalJSON() ([]byte, error) {
+	type mail AgentMail
+	return json.Marshal(struct {
+		mail
+		ReadAt    *string
+		CreatedAt string
+	}{mail(m), timeparse.FormatPtr(m.ReadAt), timeparse.Format(m.CreatedAt)})
+}
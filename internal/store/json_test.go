@@ -0,0 +1,56 @@
+// ABOUTME: Tests JSON encoding of store models returned by the API.
+// ABOUTME: Timestamps must be RFC3339 UTC and other fields keep their Go names.
+
+package store
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelJSON_TimestampsRFC3339UTC(t *testing.T) {
+	ts := time.Date(2026, 3, 1, 9, 30, 0, 123, time.FixedZone("EST", -5*3600))
+	const want = "2026-03-01T14:30:00Z"
+
+	tests := []struct {
+		name   string
+		model  any
+		fields []string
+	}{
+		{"thread", &Thread{ID: "t", CreatedAt: ts, UpdatedAt: ts}, []string{"CreatedAt", "UpdatedAt"}},
+		{"message", Message{ID: "m", CreatedAt: ts}, []string{"CreatedAt"}},
+		{"principal", Principal{ID: "p", CreatedAt: ts, LastSeen: &ts}, []string{"CreatedAt", "LastSeen"}},
+		{"log entry", &LogEntry{ID: "l", CreatedAt: ts}, []string{"CreatedAt"}},
+		{"todo", &Todo{ID: "td", DueDate: &ts, CreatedAt: ts, UpdatedAt: ts}, []string{"DueDate", "CreatedAt", "UpdatedAt"}},
+		{"bbs post", &BBSPost{ID: "b", CreatedAt: ts}, []string{"CreatedAt"}},
+		{"mail", &AgentMail{ID: "am", ReadAt: &ts, CreatedAt: ts}, []string{"ReadAt", "CreatedAt"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := json.Marshal(tc.model)
+			require.NoError(t, err)
+
+			var got map[string]any
+			require.NoError(t, json.Unmarshal(b, &got))
+			assert.NotEmpty(t, got["ID"], "non-time fields keep their names: %s", b)
+			for _, f := range tc.fields {
+				assert.Equal(t, want, got[f], "field %s in %s", f, b)
+			}
+		})
+	}
+
+	// Nested posts go through BBSPost's marshaler.
+	b, err := json.Marshal(&BBSThread{Post: &BBSPost{ID: "b", CreatedAt: ts}})
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"CreatedAt":"`+want+`"`)
+
+	// Unset optional times are null, not the zero time.
+	b, err = json.Marshal(Principal{ID: "p", CreatedAt: ts})
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"LastSeen":null`)
+}
@@ -5,9 +5,13 @@ package store
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrLinkCodeExpired is returned when approving a link code that expired.
+var ErrLinkCodeExpired = errors.New("link code expired")
+
 // LinkCodeStatus represents the state of a link code.
 type LinkCodeStatus string
 
@@ -23,6 +27,7 @@ type LinkCode struct {
 	Code        string // 6-character alphanumeric code
 	Fingerprint string // SSH key fingerprint of requesting device
 	DeviceName  string // User-provided device name
+	Platform    string // Device-reported platform, e.g. "macos" or "ios"; may be empty
 	Status      LinkCodeStatus
 	CreatedAt   time.Time
 	ExpiresAt   time.Time
@@ -43,12 +48,13 @@ type LinkCodeStore interface {
 	// GetLinkCodeByCode retrieves a link code by its short code
 	GetLinkCodeByCode(ctx context.Context, code string) (*LinkCode, error)
 
-	// ApproveLinkCode marks a code as approved and stores the principal/token
+	// ApproveLinkCode marks a code as approved and stores the principal/token.
+	// Returns ErrLinkCodeExpired if the code expired before the approval.
 	ApproveLinkCode(ctx context.Context, id string, approvedBy string, principalID string, token string) error
 
 	// ListPendingLinkCodes returns all pending (non-expired) link codes
 	ListPendingLinkCodes(ctx context.Context) ([]*LinkCode, error)
 
-	// DeleteExpiredLinkCodes removes expired link codes
-	DeleteExpiredLinkCodes(ctx context.Context) error
+	// DeleteExpiredLinkCodes removes pending codes that expired at or before cutoff
+	DeleteExpiredLinkCodes(ctx context.Context, cutoff time.Time) error
 }
@@ -0,0 +1,122 @@
+// ABOUTME: Tests for device link codes
+// ABOUTME: Covers the platform field, approval racing expiry, and sweeping codes past a cutoff
+
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// seedLinkApprover creates the admin user and principal an approval refers to.
+func seedLinkApprover(t *testing.T, s *SQLStore) {
+	t.Helper()
+	ctx := context.Background()
+	if err := s.CreateAdminUser(ctx, &AdminUser{ID: "admin-1", Username: "admin", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("CreateAdminUser: %v", err)
+	}
+	if err := s.CreatePrincipal(ctx, &Principal{
+		ID: "p-1", Type: PrincipalTypeAgent, PubkeyFP: "fp-1", DisplayName: "laptop",
+		Status: PrincipalStatusApproved, CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreatePrincipal: %v", err)
+	}
+}
+
+func createTestLinkCode(t *testing.T, s *SQLStore, id string, expiresAt time.Time) {
+	t.Helper()
+	if err := s.CreateLinkCode(context.Background(), &LinkCode{
+		ID: id, Code: "C-" + id, Fingerprint: "fp-" + id, DeviceName: "laptop", Platform: "macos",
+		Status: LinkCodeStatusPending, CreatedAt: expiresAt.Add(-10 * time.Minute), ExpiresAt: expiresAt,
+	}); err != nil {
+		t.Fatalf("CreateLinkCode(%s): %v", id, err)
+	}
+}
+
+func TestLinkCodes_PlatformAndSweep(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	createTestLinkCode(t, s, "live", now.Add(5*time.Minute))
+	createTestLinkCode(t, s, "recent", now.Add(-5*time.Minute))
+	createTestLinkCode(t, s, "old", now.Add(-2*time.Hour))
+
+	lc, err := s.GetLinkCodeByCode(ctx, "C-live")
+	if err != nil || lc.Platform != "macos" || lc.DeviceName != "laptop" {
+		t.Fatalf("GetLinkCodeByCode = %+v, %v; want the stored device and platform", lc, err)
+	}
+	pending, err := s.ListPendingLinkCodes(ctx)
+	if err != nil || len(pending) != 1 || pending[0].ID != "live" || pending[0].Platform != "macos" {
+		t.Fatalf("ListPendingLinkCodes = %v, %v; want only the live code", pending, err)
+	}
+
+	if err := s.DeleteExpiredLinkCodes(ctx, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("DeleteExpiredLinkCodes: %v", err)
+	}
+	for id, want := range map[string]bool{"live": true, "recent": true, "old": false} {
+		_, err := s.GetLinkCode(ctx, id)
+		if kept := err == nil; kept != want {
+			t.Errorf("code %s kept = %v, want %v (err %v)", id, kept, want, err)
+		}
+	}
+}
+
+func TestApproveLinkCode_Expiry(t *testing.T) {
+	s := newTestStore(t)
+	seedLinkApprover(t, s)
+	ctx := context.Background()
+	createTestLinkCode(t, s, "expired", time.Now().Add(-time.Minute))
+	createTestLinkCode(t, s, "live", time.Now().Add(time.Minute))
+
+	if err := s.ApproveLinkCode(ctx, "expired", "admin-1", "p-1", "tok"); !errors.Is(err, ErrLinkCodeExpired) {
+		t.Errorf("approving an expired code err = %v, want ErrLinkCodeExpired", err)
+	}
+	if lc, _ := s.GetLinkCode(ctx, "expired"); lc.Status != LinkCodeStatusPending || lc.Token != nil {
+		t.Errorf("expired code = %+v, want it left pending without a token", lc)
+	}
+	if err := s.ApproveLinkCode(ctx, "live", "admin-1", "p-1", "tok"); err != nil {
+		t.Fatalf("ApproveLinkCode: %v", err)
+	}
+	if err := s.ApproveLinkCode(ctx, "live", "admin-1", "p-1", "tok"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("approving twice err = %v, want ErrNotFound", err)
+	}
+	if err := s.ApproveLinkCode(ctx, "missing", "admin-1", "p-1", "tok"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("approving a missing code err = %v, want ErrNotFound", err)
+	}
+}
+
+// TestApproveLinkCode_RacesSweep approves an expired code while the sweep
+// deletes it: every approval fails, as expired or as gone, whichever wins.
+func TestApproveLinkCode_RacesSweep(t *testing.T) {
+	s := newTestStore(t)
+	seedLinkApprover(t, s)
+	ctx := context.Background()
+	createTestLinkCode(t, s, "racing", time.Now().Add(-time.Second))
+
+	var approved atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			err := s.ApproveLinkCode(ctx, "racing", "admin-1", "p-1", "tok")
+			switch {
+			case err == nil:
+				approved.Add(1)
+			case !errors.Is(err, ErrLinkCodeExpired) && !errors.Is(err, ErrNotFound):
+				t.Errorf("ApproveLinkCode err = %v, want ErrLinkCodeExpired or ErrNotFound", err)
+			}
+		})
+	}
+	wg.Go(func() {
+		if err := s.DeleteExpiredLinkCodes(ctx, time.Now()); err != nil {
+			t.Errorf("DeleteExpiredLinkCodes: %v", err)
+		}
+	})
+	wg.Wait()
+	if n := approved.Load(); n != 0 {
+		t.Errorf("%d approvals of an expired code succeeded, want none", n)
+	}
+}
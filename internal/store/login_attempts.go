@@ -0,0 +1,157 @@
+// ABOUTME: Failed web admin password logins, counted per username and client IP
+// ABOUTME: Rows carry the failure window and any lockout, so throttling survives restarts
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LoginAttempt counts failed password logins for one username from one IP.
+type LoginAttempt struct {
+	Username    string
+	IP          string
+	Failures    int        // failures since WindowStart
+	WindowStart time.Time  // first failure of the current window
+	Lockouts    int        // lockouts so far; each lasts longer than the last
+	LockedUntil *time.Time // set while, and after, the pair is locked out
+	UpdatedAt   time.Time
+}
+
+// Locked reports whether the pair is locked out at now.
+func (a *LoginAttempt) Locked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
+
+// loginAttemptColumns are the columns scanLoginAttempt reads, in order.
+const loginAttemptColumns = `username, ip, failures, window_start, lockouts, locked_until, updated_at`
+
+func scanLoginAttempt(row interface{ Scan(...any) error }) (*LoginAttempt, error) {
+	var a LoginAttempt
+	var windowStart, updatedAt string
+	var lockedUntil sql.NullString
+	if err := row.Scan(&a.Username, &a.IP, &a.Failures, &windowStart, &a.Lockouts, &lockedUntil, &updatedAt); err != nil {
+		return nil, err
+	}
+	a.WindowStart = parseTimeWithWarning(windowStart, "login_attempt", a.Username, "window_start")
+	a.UpdatedAt = parseTimeWithWarning(updatedAt, "login_attempt", a.Username, "updated_at")
+	if lockedUntil.Valid {
+		t := parseTimeWithWarning(lockedUntil.String, "login_attempt", a.Username, "locked_until")
+		a.LockedUntil = &t
+	}
+	return &a, nil
+}
+
+// GetLoginAttempt returns the failures recorded for username from ip, or
+// ErrNotFound if there are none.
+func (s *SQLStore) GetLoginAttempt(ctx context.Context, username, ip string) (*LoginAttempt, error) {
+	a, err := scanLoginAttempt(s.db.QueryRowContext(ctx,
+		`SELECT `+loginAttemptColumns+` FROM login_attempts WHERE username = ? AND ip = ?`, username, ip))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying login attempt: %w", err)
+	}
+	return a, nil
+}
+
+// RecordLoginFailure counts one failed login for username from ip at now and
+// returns the updated row. A failure more than window after the first one
+// of the current window starts a new window. The count is a single
+// statement, so concurrent failures are never lost.
+func (s *SQLStore) RecordLoginFailure(ctx context.Context, username, ip string, now time.Time, window time.Duration) (*LoginAttempt, error) {
+	nowStr := now.UTC().Format(time.RFC3339)
+	windowOpen := now.Add(-window).UTC().Format(time.RFC3339)
+	a, err := scanLoginAttempt(s.db.QueryRowContext(ctx, `
+		INSERT INTO login_attempts (username, ip, failures, window_start, lockouts, updated_at)
+		VALUES (?, ?, 1, ?, 0, ?)
+		ON CONFLICT (username, ip) DO UPDATE SET
+			failures = CASE WHEN login_attempts.window_start <= ? THEN 1 ELSE login_attempts.failures + 1 END,
+			window_start = CASE WHEN login_attempts.window_start <= ? THEN excluded.window_start ELSE login_attempts.window_start END,
+			updated_at = excluded.updated_at
+		RETURNING `+loginAttemptColumns,
+		username, ip, nowStr, nowStr, windowOpen, windowOpen))
+	if err != nil {
+		return nil, fmt.Errorf("recording login failure: %w", err)
+	}
+	return a, nil
+}
+
+// LockLogin locks username out from ip until until, counts the lockout and
+// starts a fresh failure window.
+func (s *SQLStore) LockLogin(ctx context.Context, username, ip string, until time.Time) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE login_attempts
+		SET failures = 0, window_start = ?, lockouts = lockouts + 1, locked_until = ?, updated_at = ?
+		WHERE username = ? AND ip = ?`,
+		now, until.UTC().Format(time.RFC3339), now, username, ip)
+	if err != nil {
+		return fmt.Errorf("locking login: %w", err)
+	}
+	return nil
+}
+
+// ClearLoginAttempts forgets the failures and lockouts of username from ip,
+// or from every IP if ip is empty, and returns how many rows it deleted.
+func (s *SQLStore) ClearLoginAttempts(ctx context.Context, username, ip string) (int64, error) {
+	query := `DELETE FROM login_attempts WHERE username = ?`
+	args := []any{username}
+	if ip != "" {
+		query += ` AND ip = ?`
+		args = append(args, ip)
+	}
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("clearing login attempts: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
+
+// ListLoginLockouts returns the pairs locked out at now, the latest to
+// unlock first.
+func (s *SQLStore) ListLoginLockouts(ctx context.Context, now time.Time) ([]*LoginAttempt, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+loginAttemptColumns+` FROM login_attempts
+		WHERE locked_until > ?
+		ORDER BY locked_until DESC, username, ip`, now.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("listing login lockouts: %w", err)
+	}
+	defer rows.Close()
+
+	var lockouts []*LoginAttempt
+	for rows.Next() {
+		a, err := scanLoginAttempt(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning login attempt: %w", err)
+		}
+		lockouts = append(lockouts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating login lockouts: %w", err)
+	}
+	return lockouts, nil
+}
+
+// DeleteStaleLoginAttempts removes the rows last updated at or before cutoff
+// that are not locked out past it.
+func (s *SQLStore) DeleteStaleLoginAttempts(ctx context.Context, cutoff time.Time) error {
+	c := cutoff.UTC().Format(time.RFC3339)
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM login_attempts
+		WHERE updated_at <= ? AND (locked_until IS NULL OR locked_until <= ?)`, c, c)
+	if err != nil {
+		return fmt.Errorf("deleting stale login attempts: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		s.logger.Debug("deleted stale login attempts", "count", n)
+	}
+	return nil
+}
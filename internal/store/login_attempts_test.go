@@ -0,0 +1,96 @@
+// ABOUTME: Tests for failed admin login tracking
+// ABOUTME: Covers the failure window, lockouts, clearing, and sweeping stale rows
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecordLoginFailure_Window(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+
+	for i := 1; i <= 3; i++ {
+		a, err := s.RecordLoginFailure(ctx, "alice", "10.0.0.1", start.Add(time.Duration(i)*time.Minute), 10*time.Minute)
+		if err != nil {
+			t.Fatalf("RecordLoginFailure: %v", err)
+		}
+		if a.Failures != i {
+			t.Errorf("failure %d counted as %d", i, a.Failures)
+		}
+	}
+	// A failure after the window closes starts a new one.
+	a, err := s.RecordLoginFailure(ctx, "alice", "10.0.0.1", start.Add(20*time.Minute), 10*time.Minute)
+	if err != nil || a.Failures != 1 || !a.WindowStart.Equal(start.Add(20*time.Minute).Truncate(time.Second)) {
+		t.Errorf("late failure = %+v, %v; want a new window of one failure", a, err)
+	}
+	// Other IPs are counted separately.
+	if a, _ := s.RecordLoginFailure(ctx, "alice", "10.0.0.2", start, 10*time.Minute); a.Failures != 1 {
+		t.Errorf("failure from another IP counted as %d", a.Failures)
+	}
+}
+
+func TestLoginLockouts(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	if _, err := s.GetLoginAttempt(ctx, "alice", "10.0.0.1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetLoginAttempt before any failure err = %v, want ErrNotFound", err)
+	}
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		if _, err := s.RecordLoginFailure(ctx, "alice", ip, now, 10*time.Minute); err != nil {
+			t.Fatalf("RecordLoginFailure: %v", err)
+		}
+	}
+	if err := s.LockLogin(ctx, "alice", "10.0.0.1", now.Add(time.Minute)); err != nil {
+		t.Fatalf("LockLogin: %v", err)
+	}
+	a, err := s.GetLoginAttempt(ctx, "alice", "10.0.0.1")
+	if err != nil || !a.Locked(now) || a.Locked(now.Add(2*time.Minute)) || a.Lockouts != 1 || a.Failures != 0 {
+		t.Fatalf("locked attempt = %+v, %v", a, err)
+	}
+	lockouts, err := s.ListLoginLockouts(ctx, now)
+	if err != nil || len(lockouts) != 1 || lockouts[0].IP != "10.0.0.1" {
+		t.Fatalf("ListLoginLockouts = %v, %v; want the locked IP only", lockouts, err)
+	}
+
+	if n, err := s.ClearLoginAttempts(ctx, "alice", ""); err != nil || n != 2 {
+		t.Errorf("ClearLoginAttempts every IP = %d, %v; want 2", n, err)
+	}
+}
+
+func TestDeleteStaleLoginAttempts(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, ip := range []string{"stale", "locked", "recent"} {
+		if _, err := s.RecordLoginFailure(ctx, "alice", ip, now, 10*time.Minute); err != nil {
+			t.Fatalf("RecordLoginFailure: %v", err)
+		}
+	}
+	if err := s.LockLogin(ctx, "alice", "locked", now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("LockLogin: %v", err)
+	}
+	// Make stale and locked look a day old.
+	old := now.Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+	if _, err := s.db.ExecContext(ctx, `UPDATE login_attempts SET updated_at = ? WHERE ip IN ('stale', 'locked')`, old); err != nil {
+		t.Fatalf("aging rows: %v", err)
+	}
+
+	if err := s.DeleteStaleLoginAttempts(ctx, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("DeleteStaleLoginAttempts: %v", err)
+	}
+	for ip, want := range map[string]bool{"stale": false, "locked": true, "recent": true} {
+		_, err := s.GetLoginAttempt(ctx, "alice", ip)
+		if kept := err == nil; kept != want {
+			t.Errorf("%s kept = %v, want %v (err %v)", ip, kept, want, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+-- The Postgres schema as of SQLite schema version 42. Columns keep their
+-- SQLite types where Postgres has them: timestamps are RFC 3339 TEXT and
+-- flags are integers. Tables whose queries order or batch by SQLite's
+-- implicit rowid declare an identity column of that name. Foreign keys are
+-- DEFERRABLE so ImportState can load tables in any order, and
+-- idx_ledger_text stands in for the SQLite FTS5 index.
+
+CREATE TABLE threads (id TEXT PRIMARY KEY, frontend_name TEXT NOT NULL, external_id TEXT NOT NULL, agent_id TEXT NOT NULL, created_at TEXT NOT NULL, updated_at TEXT NOT NULL, merged_into TEXT, split_from TEXT, archived BIGINT NOT NULL DEFAULT 0, pinned BIGINT NOT NULL DEFAULT 0);
+CREATE UNIQUE INDEX idx_threads_frontend_external ON threads(frontend_name, external_id);
+CREATE TABLE messages (rowid BIGINT GENERATED BY DEFAULT AS IDENTITY UNIQUE, id TEXT PRIMARY KEY, thread_id TEXT NOT NULL, sender TEXT NOT NULL, content TEXT NOT NULL, type TEXT NOT NULL DEFAULT 'message', tool_name TEXT, tool_id TEXT, created_at TEXT NOT NULL, FOREIGN KEY (thread_id) REFERENCES threads(id) DEFERRABLE);
+CREATE INDEX idx_messages_thread_id ON messages(thread_id);
+CREATE INDEX idx_messages_thread_created ON messages(thread_id, created_at);
+CREATE TABLE agent_state (agent_id TEXT PRIMARY KEY, state BYTEA NOT NULL, updated_at TEXT NOT NULL);
+CREATE TABLE channel_bindings (frontend TEXT NOT NULL, channel_id TEXT NOT NULL, agent_id TEXT NOT NULL, created_at TEXT NOT NULL, updated_at TEXT NOT NULL, PRIMARY KEY (frontend, channel_id));
+CREATE TABLE principals (principal_id TEXT PRIMARY KEY, type TEXT NOT NULL, pubkey_fingerprint TEXT NOT NULL UNIQUE, display_name TEXT NOT NULL, status TEXT NOT NULL, created_at TEXT NOT NULL, last_seen TEXT, metadata_json TEXT, paused_at TEXT, paused_by TEXT, CHECK (type IN ('client', 'agent', 'pack')), CHECK (status IN ('pending', 'approved', 'revoked', 'offline', 'online')));
+CREATE INDEX idx_principals_status ON principals(status);
+CREATE INDEX idx_principals_type ON principals(type);
+CREATE INDEX idx_principals_pubkey ON principals(pubkey_fingerprint);
+CREATE TABLE roles (subject_type TEXT NOT NULL, subject_id TEXT NOT NULL, role TEXT NOT NULL, created_at TEXT NOT NULL, PRIMARY KEY (subject_type, subject_id, role), CHECK (subject_type IN ('principal', 'member')), CHECK (role IN ('owner', 'admin', 'member', 'leader')));
+CREATE INDEX idx_roles_subject ON roles(subject_type, subject_id);
+CREATE TABLE principal_capabilities (principal_id TEXT NOT NULL, capability TEXT NOT NULL, granted_by TEXT, created_at TEXT NOT NULL, PRIMARY KEY (principal_id, capability));
+CREATE TABLE audit_log (rowid BIGINT GENERATED BY DEFAULT AS IDENTITY UNIQUE, audit_id TEXT PRIMARY KEY, actor_principal_id TEXT NOT NULL, actor_member_id TEXT, action TEXT NOT NULL, target_type TEXT NOT NULL, target_id TEXT NOT NULL, ts TEXT NOT NULL, detail_json TEXT, source_ip TEXT, CHECK (action IN ('approve_principal', 'revoke_principal', 'grant_capability', 'revoke_capability', 'create_binding', 'update_binding', 'delete_binding', 'create_token', 'create_principal', 'delete_principal', 'merge_threads', 'split_thread', 'pause_agent', 'resume_agent', 'update_feature_flag', 'answer_question', 'revoke_token', 'create_secret', 'update_secret', 'delete_secret', 'create_invite', 'create_tool_policy', 'update_tool_policy', 'delete_tool_policy', 'revoke_session', 'add_project_member', 'remove_project_member', 'add_delegation_target', 'remove_delegation_target')));
+CREATE INDEX idx_audit_ts ON audit_log(ts DESC);
+CREATE INDEX idx_audit_actor ON audit_log(actor_principal_id);
+CREATE INDEX idx_audit_target ON audit_log(target_type, target_id);
+CREATE TABLE api_tokens (token_id TEXT PRIMARY KEY, principal_id TEXT NOT NULL, scopes TEXT NOT NULL, created_by TEXT, created_at TEXT NOT NULL, expires_at TEXT NOT NULL, last_used_at TEXT, revoked_at TEXT, revoked_by TEXT, agent_ids TEXT, capabilities TEXT);
+CREATE INDEX idx_api_tokens_principal ON api_tokens(principal_id, created_at);
+CREATE TABLE ledger_events (rowid BIGINT GENERATED BY DEFAULT AS IDENTITY UNIQUE, event_id TEXT PRIMARY KEY, conversation_key TEXT NOT NULL, thread_id TEXT, direction TEXT NOT NULL, author TEXT NOT NULL, timestamp TEXT NOT NULL, type TEXT NOT NULL, text TEXT, raw_transport TEXT, raw_payload_ref TEXT, actor_principal_id TEXT, actor_member_id TEXT, channel_context_hash TEXT, parent_request_id TEXT, CHECK (direction IN ('inbound_to_agent', 'outbound_from_agent')), CHECK (type IN ('message', 'tool_call', 'tool_result', 'system', 'error', 'plan', 'citation', 'tool_check', 'capability_warning', 'tool_policy', 'agent_health')));
+CREATE INDEX idx_ledger_conversation ON ledger_events(conversation_key, timestamp);
+CREATE INDEX idx_ledger_actor ON ledger_events(actor_principal_id);
+CREATE INDEX idx_ledger_timestamp ON ledger_events(timestamp);
+CREATE INDEX idx_ledger_thread ON ledger_events(thread_id) WHERE thread_id IS NOT NULL;
+CREATE INDEX idx_ledger_text ON ledger_events USING GIN (to_tsvector('simple', COALESCE(text, '')));
+CREATE TABLE bindings (binding_id TEXT PRIMARY KEY, frontend TEXT NOT NULL, channel_id TEXT NOT NULL, agent_id TEXT NOT NULL, working_dir TEXT, created_at TEXT NOT NULL, created_by TEXT, max_response_seconds BIGINT, fallback_agents TEXT, workspace TEXT, context TEXT, UNIQUE(frontend, channel_id));
+CREATE INDEX idx_bindings_frontend ON bindings(frontend);
+CREATE INDEX idx_bindings_agent ON bindings(agent_id);
+CREATE TABLE admin_users (id TEXT PRIMARY KEY, username TEXT UNIQUE NOT NULL, password_hash TEXT, display_name TEXT NOT NULL, created_at TEXT NOT NULL, principal_id TEXT);
+CREATE INDEX idx_admin_users_username ON admin_users(username);
+CREATE TABLE admin_sessions (id TEXT PRIMARY KEY, user_id TEXT NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE DEFERRABLE, created_at TEXT NOT NULL, expires_at TEXT NOT NULL, last_seen_at TEXT, user_agent TEXT, ip TEXT);
+CREATE INDEX idx_admin_sessions_user ON admin_sessions(user_id);
+CREATE INDEX idx_admin_sessions_expires ON admin_sessions(expires_at);
+CREATE TABLE admin_invites (id TEXT PRIMARY KEY, created_by TEXT REFERENCES admin_users(id) DEFERRABLE, created_at TEXT NOT NULL, expires_at TEXT NOT NULL, used_at TEXT, used_by TEXT REFERENCES admin_users(id) DEFERRABLE);
+CREATE INDEX idx_admin_invites_expires ON admin_invites(expires_at);
+CREATE TABLE link_codes (id TEXT PRIMARY KEY, code TEXT UNIQUE NOT NULL, fingerprint TEXT NOT NULL, device_name TEXT NOT NULL, status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'expired')), created_at TEXT NOT NULL, expires_at TEXT NOT NULL, approved_by TEXT REFERENCES admin_users(id) DEFERRABLE, approved_at TEXT, principal_id TEXT REFERENCES principals(principal_id) DEFERRABLE, token TEXT);
+CREATE INDEX idx_link_codes_code ON link_codes(code);
+CREATE INDEX idx_link_codes_expires ON link_codes(expires_at);
+CREATE INDEX idx_link_codes_status ON link_codes(status);
+CREATE TABLE webauthn_credentials (id TEXT PRIMARY KEY, user_id TEXT NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE DEFERRABLE, credential_id BYTEA UNIQUE NOT NULL, public_key BYTEA NOT NULL, attestation_type TEXT, transports TEXT, sign_count BIGINT DEFAULT 0, created_at TEXT NOT NULL);
+CREATE INDEX idx_webauthn_user ON webauthn_credentials(user_id);
+CREATE TABLE admin_oidc_identities (issuer TEXT NOT NULL, subject TEXT NOT NULL, user_id TEXT NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE DEFERRABLE, email TEXT, created_at TEXT NOT NULL, PRIMARY KEY (issuer, subject));
+CREATE INDEX idx_admin_oidc_user ON admin_oidc_identities(user_id);
+CREATE TABLE log_entries (rowid BIGINT GENERATED BY DEFAULT AS IDENTITY UNIQUE, id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, message TEXT NOT NULL, tags TEXT, created_at TEXT NOT NULL);
+CREATE INDEX idx_log_entries_agent ON log_entries(agent_id);
+CREATE INDEX idx_log_entries_created ON log_entries(created_at);
+CREATE TABLE todos (id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, description TEXT NOT NULL, status TEXT DEFAULT 'pending', priority TEXT DEFAULT 'medium', notes TEXT, due_date TEXT, assignee_agent_id TEXT, project TEXT, created_at TEXT NOT NULL, updated_at TEXT NOT NULL);
+CREATE INDEX idx_todos_agent ON todos(agent_id);
+CREATE INDEX idx_todos_status ON todos(status);
+CREATE TABLE project_members (project TEXT NOT NULL, agent_id TEXT NOT NULL, added_at TEXT NOT NULL, PRIMARY KEY (project, agent_id));
+CREATE INDEX idx_project_members_agent ON project_members(agent_id);
+CREATE TABLE delegation_targets (agent_id TEXT NOT NULL, target_agent_id TEXT NOT NULL, added_at TEXT NOT NULL, PRIMARY KEY (agent_id, target_agent_id));
+CREATE TABLE bbs_posts (id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, thread_id TEXT, subject TEXT, content TEXT NOT NULL, created_at TEXT NOT NULL, author_kind TEXT NOT NULL DEFAULT 'agent', author_name TEXT);
+CREATE INDEX idx_bbs_posts_thread ON bbs_posts(thread_id);
+CREATE INDEX idx_bbs_posts_created ON bbs_posts(created_at);
+CREATE TABLE agent_mail (rowid BIGINT GENERATED BY DEFAULT AS IDENTITY UNIQUE, id TEXT PRIMARY KEY, from_agent_id TEXT NOT NULL, to_agent_id TEXT NOT NULL, subject TEXT NOT NULL, content TEXT NOT NULL, read_at TEXT, created_at TEXT NOT NULL, in_reply_to TEXT, thread_id TEXT);
+CREATE INDEX idx_agent_mail_to ON agent_mail(to_agent_id);
+CREATE INDEX idx_agent_mail_unread ON agent_mail(to_agent_id, read_at);
+CREATE INDEX idx_agent_mail_thread ON agent_mail(thread_id, created_at);
+CREATE INDEX idx_agent_mail_reply ON agent_mail(in_reply_to) WHERE in_reply_to IS NOT NULL;
+CREATE TABLE agent_notes (id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, key TEXT NOT NULL, value TEXT NOT NULL, created_at TEXT NOT NULL, updated_at TEXT NOT NULL, expires_at TEXT, UNIQUE(agent_id, key));
+CREATE INDEX idx_agent_notes_agent ON agent_notes(agent_id);
+CREATE TABLE builtin_write_quota (agent_id TEXT NOT NULL, kind TEXT NOT NULL, day TEXT NOT NULL, count BIGINT NOT NULL DEFAULT 0, PRIMARY KEY (agent_id, kind, day));
+CREATE TABLE message_usage (id TEXT PRIMARY KEY, thread_id TEXT NOT NULL, message_id TEXT, request_id TEXT NOT NULL, agent_id TEXT NOT NULL, input_tokens BIGINT NOT NULL DEFAULT 0, output_tokens BIGINT NOT NULL DEFAULT 0, cache_read_tokens BIGINT NOT NULL DEFAULT 0, cache_write_tokens BIGINT NOT NULL DEFAULT 0, thinking_tokens BIGINT NOT NULL DEFAULT 0, created_at TEXT NOT NULL, FOREIGN KEY (thread_id) REFERENCES threads(id) DEFERRABLE);
+CREATE INDEX idx_message_usage_thread ON message_usage(thread_id);
+CREATE INDEX idx_message_usage_agent ON message_usage(agent_id);
+CREATE INDEX idx_message_usage_created ON message_usage(created_at);
+CREATE INDEX idx_message_usage_request ON message_usage(request_id);
+CREATE INDEX idx_message_usage_agent_window ON message_usage(agent_id, created_at, thread_id, input_tokens, output_tokens, cache_read_tokens, cache_write_tokens, thinking_tokens);
+CREATE INDEX idx_message_usage_thread_window ON message_usage(thread_id, created_at);
+CREATE TABLE usage_agent_hourly (agent_id TEXT NOT NULL, hour TEXT NOT NULL, input_tokens BIGINT NOT NULL DEFAULT 0, output_tokens BIGINT NOT NULL DEFAULT 0, cache_read_tokens BIGINT NOT NULL DEFAULT 0, cache_write_tokens BIGINT NOT NULL DEFAULT 0, thinking_tokens BIGINT NOT NULL DEFAULT 0, request_count BIGINT NOT NULL DEFAULT 0, last_at TEXT NOT NULL, PRIMARY KEY (agent_id, hour));
+CREATE TABLE secrets (id TEXT PRIMARY KEY, key TEXT NOT NULL, value TEXT NOT NULL, agent_id TEXT, created_at TEXT NOT NULL, updated_at TEXT NOT NULL, created_by TEXT);
+CREATE UNIQUE INDEX idx_secrets_unique_global ON secrets(key) WHERE agent_id IS NULL;
+CREATE UNIQUE INDEX idx_secrets_unique_agent ON secrets(key, agent_id) WHERE agent_id IS NOT NULL;
+CREATE INDEX idx_secrets_agent ON secrets(agent_id);
+CREATE TABLE deliveries (request_id TEXT PRIMARY KEY, thread_id TEXT NOT NULL, agent_id TEXT NOT NULL, frontend TEXT NOT NULL, channel_id TEXT, status TEXT NOT NULL, error TEXT, error_code TEXT, created_at TEXT NOT NULL, responded_at TEXT, resolved_at TEXT, CHECK (status IN ('pending', 'acked', 'nacked', 'expired')));
+CREATE INDEX idx_deliveries_status ON deliveries(status, responded_at);
+CREATE INDEX idx_deliveries_frontend ON deliveries(frontend, created_at);
+CREATE TABLE tool_snapshots (pack_id TEXT NOT NULL, tool_name TEXT NOT NULL, definition TEXT NOT NULL, updated_at TEXT NOT NULL, PRIMARY KEY (pack_id, tool_name));
+CREATE TABLE tool_changes (id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY, catalog_version BIGINT NOT NULL, pack_id TEXT NOT NULL, pack_version TEXT, tool_name TEXT NOT NULL, change_type TEXT NOT NULL, diff TEXT, created_at TEXT NOT NULL, CHECK (change_type IN ('added', 'removed', 'modified')));
+CREATE INDEX idx_tool_changes_pack ON tool_changes(pack_id, created_at);
+CREATE INDEX idx_tool_changes_created ON tool_changes(created_at);
+CREATE TABLE agent_sessions (session_id TEXT PRIMARY KEY, token_hash TEXT NOT NULL UNIQUE, agent_id TEXT NOT NULL, principal_id TEXT, protocol_features TEXT NOT NULL, acked_request_ids TEXT NOT NULL, created_at TEXT NOT NULL, last_seen_at TEXT NOT NULL);
+CREATE INDEX idx_agent_sessions_principal ON agent_sessions(principal_id);
+CREATE INDEX idx_agent_sessions_last_seen ON agent_sessions(last_seen_at);
+CREATE TABLE agent_inflight_requests (request_id TEXT PRIMARY KEY, session_id TEXT NOT NULL, agent_id TEXT NOT NULL, thread_id TEXT NOT NULL, sender TEXT NOT NULL, content TEXT NOT NULL, started_at TEXT NOT NULL, workspace TEXT, channel_context TEXT);
+CREATE INDEX idx_agent_inflight_session ON agent_inflight_requests(session_id, started_at);
+CREATE TABLE email_messages (message_id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, from_addr TEXT NOT NULL, to_addr TEXT NOT NULL, subject TEXT, spf TEXT NOT NULL, dkim TEXT NOT NULL, auth_details TEXT, received_at TEXT NOT NULL);
+CREATE INDEX idx_email_messages_agent ON email_messages(agent_id, received_at);
+CREATE TABLE feature_flags (name TEXT PRIMARY KEY, enabled BIGINT NOT NULL DEFAULT 0, percentage BIGINT, principals TEXT, updated_at TEXT NOT NULL, updated_by TEXT, CHECK (percentage IS NULL OR (percentage >= 0 AND percentage <= 100)));
+CREATE TABLE attachments (id TEXT PRIMARY KEY, thread_id TEXT NOT NULL, message_id TEXT, filename TEXT NOT NULL, content_type TEXT NOT NULL, size BIGINT NOT NULL, data BYTEA NOT NULL, uploaded_by TEXT, created_at TEXT NOT NULL);
+CREATE INDEX idx_attachments_thread ON attachments(thread_id, created_at);
+CREATE TABLE scheduled_messages (id TEXT PRIMARY KEY, agent_id TEXT, frontend TEXT, channel_id TEXT, sender TEXT NOT NULL, content TEXT NOT NULL, run_at TEXT NOT NULL, recurrence TEXT, created_by TEXT, status TEXT NOT NULL, attempts BIGINT NOT NULL DEFAULT 0, last_error TEXT, last_thread_id TEXT, last_run_at TEXT, created_at TEXT NOT NULL, updated_at TEXT NOT NULL, CHECK (status IN ('pending', 'done', 'errored')));
+CREATE INDEX idx_scheduled_messages_due ON scheduled_messages(status, run_at);
+CREATE TABLE agent_groups (name TEXT PRIMARY KEY, created_at TEXT NOT NULL, updated_at TEXT NOT NULL);
+CREATE TABLE agent_group_members (group_name TEXT NOT NULL REFERENCES agent_groups(name) ON DELETE CASCADE DEFERRABLE, principal_id TEXT NOT NULL REFERENCES principals(principal_id) ON DELETE CASCADE DEFERRABLE, PRIMARY KEY (group_name, principal_id));
+CREATE INDEX idx_agent_group_members_principal ON agent_group_members(principal_id);
+CREATE TABLE tool_policies (id TEXT PRIMARY KEY, position BIGINT NOT NULL, agent_id TEXT, group_name TEXT, tool_pattern TEXT NOT NULL, action TEXT NOT NULL, matchers TEXT, description TEXT, created_at TEXT NOT NULL, updated_at TEXT NOT NULL, created_by TEXT, CHECK (action IN ('allow', 'deny', 'ask')));
+CREATE INDEX idx_tool_policies_position ON tool_policies(position);
+CREATE TABLE tool_policy_settings (id BIGINT PRIMARY KEY CHECK (id = 1), default_action TEXT NOT NULL, updated_at TEXT NOT NULL, updated_by TEXT, CHECK (default_action IN ('allow', 'deny', 'ask')));
+CREATE TABLE questions (id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, question TEXT NOT NULL, header TEXT, options TEXT NOT NULL, multi_select BIGINT NOT NULL DEFAULT 0, timeout_seconds BIGINT NOT NULL, context_json TEXT, allow_free_text BIGINT, pattern TEXT, max_length BIGINT, status TEXT NOT NULL, asked_at TEXT NOT NULL, expires_at TEXT NOT NULL, resolved_at TEXT, CHECK (status IN ('pending', 'answered', 'expired')));
+CREATE INDEX idx_questions_pending ON questions(status, agent_id, asked_at);
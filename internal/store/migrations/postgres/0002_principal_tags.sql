@@ -0,0 +1,3 @@
+-- Agent tags (key=value labels) as a JSON object, from SQLite schema
+-- version 43.
+ALTER TABLE principals ADD COLUMN tags TEXT;
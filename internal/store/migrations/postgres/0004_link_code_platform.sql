@@ -0,0 +1,3 @@
+-- Platform reported by a device requesting a link code, from SQLite schema
+-- version 44.
+ALTER TABLE link_codes ADD COLUMN platform TEXT NOT NULL DEFAULT '';
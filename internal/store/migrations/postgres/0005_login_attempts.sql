@@ -0,0 +1,7 @@
+-- Failed web admin password logins and lockouts, per username and IP.
+CREATE TABLE login_attempts (username TEXT NOT NULL, ip TEXT NOT NULL, failures INTEGER NOT NULL DEFAULT 0, window_start TEXT NOT NULL, lockouts INTEGER NOT NULL DEFAULT 0, locked_until TEXT, updated_at TEXT NOT NULL, PRIMARY KEY (username, ip));
+CREATE INDEX idx_login_attempts_locked ON login_attempts(locked_until);
+
+-- Audit action for clearing a login lockout from the admin API.
+ALTER TABLE audit_log DROP CONSTRAINT audit_log_action_check;
+ALTER TABLE audit_log ADD CONSTRAINT audit_log_action_check CHECK (action IN ('approve_principal', 'revoke_principal', 'grant_capability', 'revoke_capability', 'create_binding', 'update_binding', 'delete_binding', 'create_token', 'create_principal', 'delete_principal', 'merge_threads', 'split_thread', 'pause_agent', 'resume_agent', 'update_feature_flag', 'answer_question', 'revoke_token', 'create_secret', 'update_secret', 'delete_secret', 'create_invite', 'create_tool_policy', 'update_tool_policy', 'delete_tool_policy', 'revoke_session', 'add_project_member', 'remove_project_member', 'add_delegation_target', 'remove_delegation_target', 'deny_admin_call', 'clear_login_lockout'));
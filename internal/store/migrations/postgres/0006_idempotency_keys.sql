@@ -0,0 +1,3 @@
+-- Idempotency keys of sends per principal, with the request each started.
+CREATE TABLE idempotency_keys (principal_id TEXT NOT NULL, idem_key TEXT NOT NULL, request_id TEXT NOT NULL DEFAULT '', created_at TEXT NOT NULL, PRIMARY KEY (principal_id, idem_key));
+CREATE INDEX idx_idempotency_keys_created ON idempotency_keys(created_at);
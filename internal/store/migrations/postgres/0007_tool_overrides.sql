@@ -0,0 +1,7 @@
+-- Per-agent tool overrides: an admin turns single tools on or off for an
+-- agent's principal, on top of its capability grants.
+CREATE TABLE tool_overrides (principal_id TEXT NOT NULL, tool_name TEXT NOT NULL, enabled BIGINT NOT NULL DEFAULT 1, reason TEXT NOT NULL DEFAULT '', updated_by TEXT, updated_at TEXT NOT NULL, PRIMARY KEY (principal_id, tool_name));
+
+-- Audit action for changing a tool override from the admin API.
+ALTER TABLE audit_log DROP CONSTRAINT audit_log_action_check;
+ALTER TABLE audit_log ADD CONSTRAINT audit_log_action_check CHECK (action IN ('approve_principal', 'revoke_principal', 'grant_capability', 'revoke_capability', 'create_binding', 'update_binding', 'delete_binding', 'create_token', 'create_principal', 'delete_principal', 'merge_threads', 'split_thread', 'pause_agent', 'resume_agent', 'update_feature_flag', 'answer_question', 'revoke_token', 'create_secret', 'update_secret', 'delete_secret', 'create_invite', 'create_tool_policy', 'update_tool_policy', 'delete_tool_policy', 'revoke_session', 'add_project_member', 'remove_project_member', 'add_delegation_target', 'remove_delegation_target', 'deny_admin_call', 'clear_login_lockout', 'set_tool_override'));
@@ -0,0 +1,5 @@
+-- Pinned BBS threads and the indexes behind board and reply paging, from
+-- SQLite schema version 46.
+ALTER TABLE bbs_posts ADD COLUMN pinned BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX idx_bbs_posts_board ON bbs_posts(pinned, created_at) WHERE thread_id IS NULL;
+CREATE INDEX idx_bbs_posts_replies ON bbs_posts(thread_id, created_at, id);
@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -43,7 +44,7 @@ func NewMockStore() *MockStore {
 
 // CreateThread stores a new thread.
 // Returns ErrDuplicateThread if a thread with the same (FrontendName, ExternalID) exists.
-// This matches SQLiteStore's UNIQUE constraint on (frontend_name, external_id).
+// This matches SQLStore's UNIQUE constraint on (frontend_name, external_id).
 func (m *MockStore) CreateThread(ctx context.Context, thread *Thread) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -115,8 +116,9 @@ func (m *MockStore) UpdateThread(ctx context.Context, thread *Thread) error {
 	return nil
 }
 
-// ListThreads retrieves threads ordered by most recent activity.
-func (m *MockStore) ListThreads(ctx context.Context, limit int) ([]*Thread, error) {
+// ListThreads retrieves threads with pinned threads first, then by most
+// recent activity, leaving out archived threads unless includeArchived is set.
+func (m *MockStore) ListThreads(ctx context.Context, limit int, includeArchived bool) ([]*Thread, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -130,18 +132,20 @@ func (m *MockStore) ListThreads(ctx context.Context, limit int) ([]*Thread, erro
 	// Collect all threads
 	threads := make([]*Thread, 0, len(m.threads))
 	for _, t := range m.threads {
+		if t.Archived && !includeArchived {
+			continue
+		}
 		threadCopy := *t
 		threads = append(threads, &threadCopy)
 	}
 
-	// Sort by UpdatedAt descending
-	for i := range len(threads) - 1 {
-		for j := i + 1; j < len(threads); j++ {
-			if threads[j].UpdatedAt.After(threads[i].UpdatedAt) {
-				threads[i], threads[j] = threads[j], threads[i]
-			}
+	// Sort pinned first, then by UpdatedAt descending
+	sort.SliceStable(threads, func(i, j int) bool {
+		if threads[i].Pinned != threads[j].Pinned {
+			return threads[i].Pinned
 		}
-	}
+		return threads[i].UpdatedAt.After(threads[j].UpdatedAt)
+	})
 
 	// Apply limit
 	if len(threads) > limit {
@@ -151,6 +155,32 @@ func (m *MockStore) ListThreads(ctx context.Context, limit int) ([]*Thread, erro
 	return threads, nil
 }
 
+// ArchiveThread sets or clears a thread's archived flag.
+func (m *MockStore) ArchiveThread(ctx context.Context, id string, archived bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.threads[id]
+	if !ok {
+		return ErrNotFound
+	}
+	t.Archived = archived
+	return nil
+}
+
+// SetThreadPinned sets or clears a thread's pinned flag.
+func (m *MockStore) SetThreadPinned(ctx context.Context, id string, pinned bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.threads[id]
+	if !ok {
+		return ErrNotFound
+	}
+	t.Pinned = pinned
+	return nil
+}
+
 // SaveMessage stores a message.
 func (m *MockStore) SaveMessage(ctx context.Context, msg *Message) error {
 	m.mu.Lock()
@@ -323,6 +353,37 @@ func (m *MockStore) DeleteBindingByID(ctx context.Context, id string) error {
 	return ErrBindingNotFound
 }
 
+// SetBindingWorkspace sets the workspace of a V2 binding found by ID.
+func (m *MockStore) SetBindingWorkspace(ctx context.Context, id, workspace string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, b := range m.bindingsV2 {
+		if b.ID == id {
+			b.Workspace = workspace
+			return nil
+		}
+	}
+	return ErrBindingNotFound
+}
+
+// SetBindingContext sets the channel context of a V2 binding found by ID.
+func (m *MockStore) SetBindingContext(ctx context.Context, id, text string) error {
+	if len(text) > MaxBindingContextBytes {
+		return ErrBindingContextTooLong
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, b := range m.bindingsV2 {
+		if b.ID == id {
+			b.Context = text
+			return nil
+		}
+	}
+	return ErrBindingNotFound
+}
+
 // ListBindingsV2 returns V2 bindings matching the filter criteria.
 func (m *MockStore) ListBindingsV2(ctx context.Context, filter BindingFilter) ([]Binding, error) {
 	m.mu.RLock()
@@ -406,7 +467,7 @@ func (m *MockStore) ListEventsByConversation(ctx context.Context, conversationKe
 		}
 	}
 
-	// Sort by timestamp ASC to match SQLiteStore behavior
+	// Sort by timestamp ASC to match SQLStore behavior
 	sort.Slice(result, func(i, j int) bool {
 		return result[i].Timestamp.Before(result[j].Timestamp)
 	})
@@ -510,6 +571,76 @@ func (m *MockStore) GetEventsByThreadID(ctx context.Context, threadID string, li
 	return result, nil
 }
 
+// GetThreadEventsPage returns a page of a thread's events older than the
+// before cursor, in chronological order, mirroring the SQLite implementation.
+func (m *MockStore) GetThreadEventsPage(ctx context.Context, threadID string, limit int, before string) ([]*LedgerEvent, string, error) {
+	limit = normalizeLimit(limit)
+	var cursorTS time.Time
+	var cursorID string
+	if before != "" {
+		var err error
+		if cursorTS, cursorID, err = decodeCursor(before); err != nil {
+			return nil, "", fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+		}
+	}
+
+	m.mu.RLock()
+	var result []*LedgerEvent
+	for _, e := range m.events {
+		if e.ThreadID == nil || *e.ThreadID != threadID {
+			continue
+		}
+		if before != "" && !e.Timestamp.Before(cursorTS) && !(e.Timestamp.Equal(cursorTS) && e.ID < cursorID) {
+			continue
+		}
+		eventCopy := *e
+		result = append(result, &eventCopy)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Timestamp.Equal(result[j].Timestamp) {
+			return result[i].Timestamp.After(result[j].Timestamp)
+		}
+		return result[i].ID > result[j].ID
+	})
+
+	var next string
+	if len(result) > limit {
+		result = result[:limit]
+		next = encodeCursor(result[limit-1].Timestamp, result[limit-1].ID)
+	}
+	slices.Reverse(result)
+	return result, next, nil
+}
+
+// ForEachThreadEvent calls fn with each of a thread's events in
+// chronological order, stopping at the first error fn returns.
+func (m *MockStore) ForEachThreadEvent(ctx context.Context, threadID string, fn func(*LedgerEvent) error) error {
+	m.mu.RLock()
+	var result []*LedgerEvent
+	for _, e := range m.events {
+		if e.ThreadID != nil && *e.ThreadID == threadID {
+			eventCopy := *e
+			result = append(result, &eventCopy)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Timestamp.Equal(result[j].Timestamp) {
+			return result[i].Timestamp.Before(result[j].Timestamp)
+		}
+		return result[i].ID < result[j].ID
+	})
+	for _, e := range result {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // normalizeLimit applies default (50) and cap (500) to pagination limit.
 func normalizeLimit(limit int) int {
 	if limit <= 0 {
@@ -621,6 +752,17 @@ func (m *MockStore) GetEvents(ctx context.Context, p GetEventsParams) (*GetEvent
 	return buildPaginatedResult(matching, p.Limit), nil
 }
 
+// SearchMessages is unsupported by MockStore, which has no full-text index.
+func (m *MockStore) SearchMessages(ctx context.Context, query string, filter SearchFilter) ([]*SearchResult, error) {
+	return nil, ErrSearchUnavailable
+}
+
+// WithTx runs fn against the mock itself; its writes are not rolled back
+// when fn fails.
+func (m *MockStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	return fn(m)
+}
+
 // Close is a no-op for MockStore.
 func (m *MockStore) Close() error {
 	return nil
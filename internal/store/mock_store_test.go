@@ -1,4 +1,4 @@
-// ABOUTME: Unit tests for MockStore to ensure behavior matches SQLiteStore
+// ABOUTME: Unit tests for MockStore to ensure behavior matches SQLStore
 // ABOUTME: Focuses on duplicate detection and edge cases specific to in-memory implementation
 
 package store
@@ -0,0 +1,258 @@
+// ABOUTME: Postgres backend of SQLStore using lib/pq, for gateways sharing one database
+// ABOUTME: Rewrites ? placeholders per connection and runs the embedded migrations/postgres files
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// postgresMigrationFiles holds the Postgres schema as numbered migrations,
+// NNNN_description.sql, applied in order and recorded in schema_migrations.
+//
+//go:embed migrations/postgres/*.sql
+var postgresMigrationFiles embed.FS
+
+// postgresMigrationLock is the advisory lock key gateways hold while
+// migrating, so replicas starting together apply each migration once.
+const postgresMigrationLock = 0x636f76656e // "coven"
+
+// NewPostgresStore connects to the Postgres database dsn names, in any form
+// lib/pq accepts, and brings its schema up to date.
+func NewPostgresStore(dsn string) (*SQLStore, error) {
+	logger := slog.Default().With("component", "store")
+
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parsing postgres DSN: %w", err)
+	}
+	db := sql.OpenDB(rebindConnector{connector})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("connecting to postgres: %w", err)
+	}
+
+	s := &SQLStore{
+		db:      &timedDB{DB: db},
+		dialect: dialectPostgres,
+		logger:  logger,
+		// The full-text index is part of the schema.
+		searchEnabled: true,
+	}
+	if err := s.migratePostgres(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("running migrations: %w", err)
+	}
+
+	logger.Info("Postgres store initialized")
+	return s, nil
+}
+
+// postgresMigration is one file of migrations/postgres.
+type postgresMigration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// postgresMigrations returns the embedded migrations in version order. The
+// versions must run 1, 2, 3... without gaps.
+func postgresMigrations() ([]postgresMigration, error) {
+	paths, err := fs.Glob(postgresMigrationFiles, "migrations/postgres/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(paths)
+	migrations := make([]postgresMigration, 0, len(paths))
+	for i, p := range paths {
+		name := strings.TrimSuffix(path.Base(p), ".sql")
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version != i+1 {
+			return nil, fmt.Errorf("migration %s: want version %04d", p, i+1)
+		}
+		data, err := postgresMigrationFiles.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", p, err)
+		}
+		migrations = append(migrations, postgresMigration{version: version, name: name, sql: string(data)})
+	}
+	return migrations, nil
+}
+
+// migratePostgres applies the migrations the database has not seen, all in
+// one transaction under an advisory lock.
+func (s *SQLStore) migratePostgres(ctx context.Context) error {
+	migrations, err := postgresMigrations()
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(?)`, postgresMigrationLock); err != nil {
+		return fmt.Errorf("taking migration lock: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT PRIMARY KEY, name TEXT NOT NULL, applied_at TEXT NOT NULL)`); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	var current int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if current > len(migrations) {
+		return fmt.Errorf("database is at migration %d, this build knows %d", current, len(migrations))
+	}
+
+	for _, m := range migrations[current:] {
+		if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+			return fmt.Errorf("applying %s: %w", m.name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.version, m.name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			return fmt.Errorf("recording %s: %w", m.name, err)
+		}
+		s.logger.Info("applied migration", "migration", m.name)
+	}
+	return tx.Commit()
+}
+
+// isPostgresConstraintViolation reports whether err is a Postgres integrity
+// constraint violation (SQLSTATE class 23). A non-empty constraint narrows
+// it to unique violations of that constraint.
+func isPostgresConstraintViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	if constraint == "" {
+		return pqErr.Code.Class() == "23"
+	}
+	return pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+// rebind rewrites the ? and ?NNN placeholders of query as Postgres $n
+// parameters, leaving quoted strings and identifiers alone.
+func rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			b.WriteByte('$')
+			if j > i+1 {
+				b.WriteString(query[i+1 : j])
+			} else {
+				n++
+				b.WriteString(strconv.Itoa(n))
+			}
+			i = j - 1
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// rebindConnector opens lib/pq connections that accept the ? placeholders
+// the shared queries are written with.
+type rebindConnector struct {
+	driver.Connector
+}
+
+// pqConn is what rebindConn needs of a lib/pq connection.
+type pqConn interface {
+	driver.Conn
+	driver.ConnPrepareContext
+	driver.ConnBeginTx
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+func (c rebindConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pc, ok := conn.(pqConn)
+	if !ok {
+		_ = conn.Close()
+		return nil, fmt.Errorf("unexpected postgres connection type %T", conn)
+	}
+	return rebindConn{pc}, nil
+}
+
+// rebindConn rebinds every statement before lib/pq sees it, and passes
+// bools as 1 and 0: the schema keeps SQLite's INTEGER flags, and Postgres
+// reads those as integers or booleans alike.
+type rebindConn struct {
+	pqConn
+}
+
+func (c rebindConn) Prepare(query string) (driver.Stmt, error) {
+	return c.pqConn.Prepare(rebind(query))
+}
+
+func (c rebindConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.pqConn.PrepareContext(ctx, rebind(query))
+}
+
+func (c rebindConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.pqConn.ExecContext(ctx, rebind(query), args)
+}
+
+func (c rebindConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.pqConn.QueryContext(ctx, rebind(query), args)
+}
+
+func (c rebindConn) CheckNamedValue(nv *driver.NamedValue) error {
+	v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	if b, ok := v.(bool); ok {
+		v = int64(0)
+		if b {
+			v = int64(1)
+		}
+	}
+	nv.Value = v
+	return nil
+}
@@ -63,14 +63,15 @@ var ValidPrincipalStatuses = []PrincipalStatus{
 
 // Principal represents a cryptographic identity in the system.
 type Principal struct {
-	ID          string          // UUID v4, generated by gateway
-	Type        PrincipalType   // "client" | "agent" | "pack"
-	PubkeyFP    string          // hex SHA-256 of public key (64 chars)
-	DisplayName string          // human-readable name (1-100 chars)
-	Status      PrincipalStatus // "pending" | "approved" | "revoked" | "offline" | "online"
-	CreatedAt   time.Time       // when the principal was created
-	LastSeen    *time.Time      // last activity timestamp (nil if never seen)
-	Metadata    map[string]any  // arbitrary JSON metadata (max 64KB)
+	ID          string            // UUID v4, generated by gateway
+	Type        PrincipalType     // "client" | "agent" | "pack"
+	PubkeyFP    string            // hex SHA-256 of public key (64 chars)
+	DisplayName string            // human-readable name (1-100 chars)
+	Status      PrincipalStatus   // "pending" | "approved" | "revoked" | "offline" | "online"
+	CreatedAt   time.Time         // when the principal was created
+	LastSeen    *time.Time        // last activity timestamp (nil if never seen)
+	Metadata    map[string]any    // arbitrary JSON metadata (max 64KB)
+	Tags        map[string]string // key=value labels the agent last registered with
 }
 
 // PrincipalFilter specifies filtering options for listing principals.
@@ -87,7 +88,7 @@ func isValidStatus(status PrincipalStatus) bool {
 }
 
 // CreatePrincipal creates a new principal in the database.
-func (s *SQLiteStore) CreatePrincipal(ctx context.Context, p *Principal) error {
+func (s *SQLStore) CreatePrincipal(ctx context.Context, p *Principal) error {
 	var metadataJSON *string
 	if p.Metadata != nil {
 		data, err := json.Marshal(p.Metadata)
@@ -112,7 +113,13 @@ func (s *SQLiteStore) CreatePrincipal(ctx context.Context, p *Principal) error {
 		lastSeenStr = &s
 	}
 
-	_, err := s.db.ExecContext(ctx, query,
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, query,
 		p.ID,
 		p.Type,
 		p.PubkeyFP,
@@ -129,15 +136,18 @@ func (s *SQLiteStore) CreatePrincipal(ctx context.Context, p *Principal) error {
 		}
 		return fmt.Errorf("inserting principal: %w", err)
 	}
+	if err := commitAuditedTx(ctx, tx); err != nil {
+		return err
+	}
 
 	s.logger.Debug("created principal", "id", p.ID, "type", p.Type)
 	return nil
 }
 
 // GetPrincipal retrieves a principal by ID.
-func (s *SQLiteStore) GetPrincipal(ctx context.Context, id string) (*Principal, error) {
+func (s *SQLStore) GetPrincipal(ctx context.Context, id string) (*Principal, error) {
 	query := `
-		SELECT principal_id, type, pubkey_fingerprint, display_name, status, created_at, last_seen, metadata_json
+		SELECT principal_id, type, pubkey_fingerprint, display_name, status, created_at, last_seen, metadata_json, tags
 		FROM principals
 		WHERE principal_id = ?
 	`
@@ -146,9 +156,9 @@ func (s *SQLiteStore) GetPrincipal(ctx context.Context, id string) (*Principal,
 }
 
 // GetPrincipalByPubkey retrieves a principal by pubkey fingerprint.
-func (s *SQLiteStore) GetPrincipalByPubkey(ctx context.Context, fp string) (*Principal, error) {
+func (s *SQLStore) GetPrincipalByPubkey(ctx context.Context, fp string) (*Principal, error) {
 	query := `
-		SELECT principal_id, type, pubkey_fingerprint, display_name, status, created_at, last_seen, metadata_json
+		SELECT principal_id, type, pubkey_fingerprint, display_name, status, created_at, last_seen, metadata_json, tags
 		FROM principals
 		WHERE pubkey_fingerprint = ?
 	`
@@ -157,14 +167,20 @@ func (s *SQLiteStore) GetPrincipalByPubkey(ctx context.Context, fp string) (*Pri
 }
 
 // UpdatePrincipalStatus updates a principal's status.
-func (s *SQLiteStore) UpdatePrincipalStatus(ctx context.Context, id string, status PrincipalStatus) error {
+func (s *SQLStore) UpdatePrincipalStatus(ctx context.Context, id string, status PrincipalStatus) error {
 	if !isValidStatus(status) {
 		return ErrInvalidStatus
 	}
 
 	query := `UPDATE principals SET status = ? WHERE principal_id = ?`
 
-	result, err := s.db.ExecContext(ctx, query, status, id)
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, query, status, id)
 	if err != nil {
 		return fmt.Errorf("updating principal status: %w", err)
 	}
@@ -178,12 +194,68 @@ func (s *SQLiteStore) UpdatePrincipalStatus(ctx context.Context, id string, stat
 		return ErrPrincipalNotFound
 	}
 
+	// A revoked principal must not resume an agent session later.
+	if status == PrincipalStatusRevoked {
+		if _, err := deleteAgentSessionsWhere(ctx, tx, `principal_id = ?`, id); err != nil {
+			return err
+		}
+	}
+	if err := commitAuditedTx(ctx, tx); err != nil {
+		return err
+	}
+
 	s.logger.Debug("updated principal status", "id", id, "status", status)
 	return nil
 }
 
+// PrincipalPause records that an admin paused an agent principal.
+type PrincipalPause struct {
+	PausedBy string    // admin username that paused the agent
+	PausedAt time.Time // when the agent was paused
+}
+
+// SetPrincipalPause persists the paused state of a principal. A nil pause clears it.
+func (s *SQLStore) SetPrincipalPause(ctx context.Context, id string, pause *PrincipalPause) error {
+	var pausedAt, pausedBy sql.NullString
+	if pause != nil {
+		pausedAt = sql.NullString{String: pause.PausedAt.UTC().Format(time.RFC3339), Valid: true}
+		pausedBy = sql.NullString{String: pause.PausedBy, Valid: true}
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE principals SET paused_at = ?, paused_by = ? WHERE principal_id = ?`,
+		pausedAt, pausedBy, id)
+	if err != nil {
+		return fmt.Errorf("updating principal pause: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrPrincipalNotFound
+	}
+	return nil
+}
+
+// GetPrincipalPause returns the paused state of a principal, or nil if it is not paused.
+func (s *SQLStore) GetPrincipalPause(ctx context.Context, id string) (*PrincipalPause, error) {
+	var pausedAt, pausedBy sql.NullString
+	err := s.db.QueryRowContext(ctx,
+		`SELECT paused_at, paused_by FROM principals WHERE principal_id = ?`, id).Scan(&pausedAt, &pausedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPrincipalNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying principal pause: %w", err)
+	}
+	if !pausedAt.Valid {
+		return nil, nil
+	}
+	return &PrincipalPause{
+		PausedBy: pausedBy.String,
+		PausedAt: parseTimeWithWarning(pausedAt.String, "principal", id, "paused_at"),
+	}, nil
+}
+
 // UpdatePrincipalLastSeen updates a principal's last_seen timestamp.
-func (s *SQLiteStore) UpdatePrincipalLastSeen(ctx context.Context, id string, t time.Time) error {
+func (s *SQLStore) UpdatePrincipalLastSeen(ctx context.Context, id string, t time.Time) error {
 	query := `UPDATE principals SET last_seen = ? WHERE principal_id = ?`
 
 	result, err := s.db.ExecContext(ctx, query, t.UTC().Format(time.RFC3339), id)
@@ -203,13 +275,72 @@ func (s *SQLiteStore) UpdatePrincipalLastSeen(ctx context.Context, id string, t
 	return nil
 }
 
+// RecordPrincipalSeen updates a principal's last_seen timestamp and stores
+// meta under the "agent" key of its metadata, leaving other keys intact.
+// Returns ErrMetadataTooLarge if meta does not fit MaxMetadataSize.
+func (s *SQLStore) RecordPrincipalSeen(ctx context.Context, id string, t time.Time, meta any) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("marshaling metadata: %w", err)
+	}
+	if len(data) > MaxMetadataSize {
+		return ErrMetadataTooLarge
+	}
+
+	query := `
+		UPDATE principals
+		SET last_seen = ?, metadata_json = json_set(COALESCE(metadata_json, '{}'), '$.agent', json(?))
+		WHERE principal_id = ?
+	`
+	result, err := s.db.ExecContext(ctx, query, t.UTC().Format(time.RFC3339), string(data), id)
+	if err != nil {
+		return fmt.Errorf("recording principal seen: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrPrincipalNotFound
+	}
+	return nil
+}
+
+// SetPrincipalTags replaces a principal's tags. Nil or empty tags clear them.
+func (s *SQLStore) SetPrincipalTags(ctx context.Context, id string, tags map[string]string) error {
+	var tagsJSON sql.NullString
+	if len(tags) > 0 {
+		data, err := json.Marshal(tags)
+		if err != nil {
+			return fmt.Errorf("marshaling tags: %w", err)
+		}
+		tagsJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	result, err := s.db.ExecContext(ctx, `UPDATE principals SET tags = ? WHERE principal_id = ?`, tagsJSON, id)
+	if err != nil {
+		return fmt.Errorf("updating principal tags: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrPrincipalNotFound
+	}
+	return nil
+}
+
 // DeletePrincipal removes a principal by ID. Returns ErrPrincipalNotFound if
 // the principal does not exist. Note: associated roles in the roles table are
 // not automatically deleted and should be removed separately if needed.
-func (s *SQLiteStore) DeletePrincipal(ctx context.Context, id string) error {
+func (s *SQLStore) DeletePrincipal(ctx context.Context, id string) error {
 	query := `DELETE FROM principals WHERE principal_id = ?`
 
-	result, err := s.db.ExecContext(ctx, query, id)
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("deleting principal: %w", err)
 	}
@@ -223,12 +354,22 @@ func (s *SQLiteStore) DeletePrincipal(ctx context.Context, id string) error {
 		return ErrPrincipalNotFound
 	}
 
+	if _, err := deleteAgentSessionsWhere(ctx, tx, `principal_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM principal_capabilities WHERE principal_id = ?`, id); err != nil {
+		return fmt.Errorf("deleting principal capabilities: %w", err)
+	}
+	if err := commitAuditedTx(ctx, tx); err != nil {
+		return err
+	}
+
 	s.logger.Debug("deleted principal", "id", id)
 	return nil
 }
 
 // ListPrincipals returns principals matching the filter criteria.
-func (s *SQLiteStore) ListPrincipals(ctx context.Context, f PrincipalFilter) ([]Principal, error) {
+func (s *SQLStore) ListPrincipals(ctx context.Context, f PrincipalFilter) ([]Principal, error) {
 	// Apply defaults
 	limit := f.Limit
 	if limit <= 0 {
@@ -239,10 +380,10 @@ func (s *SQLiteStore) ListPrincipals(ctx context.Context, f PrincipalFilter) ([]
 	}
 
 	query := `
-		SELECT principal_id, type, pubkey_fingerprint, display_name, status, created_at, last_seen, metadata_json
+		SELECT principal_id, type, pubkey_fingerprint, display_name, status, created_at, last_seen, metadata_json, tags
 		FROM principals
-		WHERE (? IS NULL OR type = ?)
-		  AND (? IS NULL OR status = ?)
+		WHERE (CAST(? AS TEXT) IS NULL OR type = ?)
+		  AND (CAST(? AS TEXT) IS NULL OR status = ?)
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
 	`
@@ -284,12 +425,12 @@ func (s *SQLiteStore) ListPrincipals(ctx context.Context, f PrincipalFilter) ([]
 }
 
 // CountPrincipals returns the count of principals matching the filter criteria.
-func (s *SQLiteStore) CountPrincipals(ctx context.Context, f PrincipalFilter) (int, error) {
+func (s *SQLStore) CountPrincipals(ctx context.Context, f PrincipalFilter) (int, error) {
 	query := `
 		SELECT COUNT(*)
 		FROM principals
-		WHERE (? IS NULL OR type = ?)
-		  AND (? IS NULL OR status = ?)
+		WHERE (CAST(? AS TEXT) IS NULL OR type = ?)
+		  AND (CAST(? AS TEXT) IS NULL OR status = ?)
 	`
 
 	var typeFilter, statusFilter *string
@@ -315,11 +456,11 @@ func (s *SQLiteStore) CountPrincipals(ctx context.Context, f PrincipalFilter) (i
 }
 
 // scanPrincipal scans a single principal row from a sql.Row.
-func (s *SQLiteStore) scanPrincipal(row *sql.Row) (*Principal, error) {
+func (s *SQLStore) scanPrincipal(row *sql.Row) (*Principal, error) {
 	var p Principal
 	var typeStr, statusStr string
 	var createdAtStr string
-	var lastSeenStr, metadataJSON *string
+	var lastSeenStr, metadataJSON, tagsJSON *string
 
 	err := row.Scan(
 		&p.ID,
@@ -330,6 +471,7 @@ func (s *SQLiteStore) scanPrincipal(row *sql.Row) (*Principal, error) {
 		&createdAtStr,
 		&lastSeenStr,
 		&metadataJSON,
+		&tagsJSON,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -360,16 +502,21 @@ func (s *SQLiteStore) scanPrincipal(row *sql.Row) (*Principal, error) {
 			return nil, fmt.Errorf("unmarshaling metadata: %w", err)
 		}
 	}
+	if tagsJSON != nil {
+		if err := json.Unmarshal([]byte(*tagsJSON), &p.Tags); err != nil {
+			return nil, fmt.Errorf("unmarshaling tags: %w", err)
+		}
+	}
 
 	return &p, nil
 }
 
 // scanPrincipalRow scans a principal from sql.Rows (for list queries).
-func (s *SQLiteStore) scanPrincipalRow(rows *sql.Rows) (*Principal, error) {
+func (s *SQLStore) scanPrincipalRow(rows *sql.Rows) (*Principal, error) {
 	var p Principal
 	var typeStr, statusStr string
 	var createdAtStr string
-	var lastSeenStr, metadataJSON *string
+	var lastSeenStr, metadataJSON, tagsJSON *string
 
 	err := rows.Scan(
 		&p.ID,
@@ -380,6 +527,7 @@ func (s *SQLiteStore) scanPrincipalRow(rows *sql.Rows) (*Principal, error) {
 		&createdAtStr,
 		&lastSeenStr,
 		&metadataJSON,
+		&tagsJSON,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("scanning principal row: %w", err)
@@ -406,6 +554,11 @@ func (s *SQLiteStore) scanPrincipalRow(rows *sql.Rows) (*Principal, error) {
 			return nil, fmt.Errorf("unmarshaling metadata: %w", err)
 		}
 	}
+	if tagsJSON != nil {
+		if err := json.Unmarshal([]byte(*tagsJSON), &p.Tags); err != nil {
+			return nil, fmt.Errorf("unmarshaling tags: %w", err)
+		}
+	}
 
 	return &p, nil
 }
@@ -417,5 +570,6 @@ func isDuplicatePubkeyError(err error) bool {
 	}
 	errStr := err.Error()
 	return strings.Contains(errStr, "UNIQUE constraint failed") &&
-		strings.Contains(errStr, "pubkey_fingerprint")
+		strings.Contains(errStr, "pubkey_fingerprint") ||
+		isPostgresConstraintViolation(err, "principals_pubkey_fingerprint_key")
 }
@@ -5,6 +5,7 @@ package store
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -173,6 +174,43 @@ func TestPrincipalStore_UpdateStatus_NotFound(t *testing.T) {
 	assert.ErrorIs(t, err, ErrPrincipalNotFound)
 }
 
+func TestPrincipalStore_Pause(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	p := &Principal{
+		ID:          "agent-123",
+		Type:        PrincipalTypeAgent,
+		PubkeyFP:    "abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234",
+		DisplayName: "Test Agent",
+		Status:      PrincipalStatusApproved,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+	require.NoError(t, store.CreatePrincipal(ctx, p))
+
+	pause, err := store.GetPrincipalPause(ctx, "agent-123")
+	require.NoError(t, err)
+	assert.Nil(t, pause)
+
+	pausedAt := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, store.SetPrincipalPause(ctx, "agent-123", &PrincipalPause{PausedBy: "admin", PausedAt: pausedAt}))
+
+	pause, err = store.GetPrincipalPause(ctx, "agent-123")
+	require.NoError(t, err)
+	require.NotNil(t, pause)
+	assert.Equal(t, "admin", pause.PausedBy)
+	assert.True(t, pause.PausedAt.Equal(pausedAt))
+
+	require.NoError(t, store.SetPrincipalPause(ctx, "agent-123", nil))
+	pause, err = store.GetPrincipalPause(ctx, "agent-123")
+	require.NoError(t, err)
+	assert.Nil(t, pause)
+
+	assert.ErrorIs(t, store.SetPrincipalPause(ctx, "nonexistent", nil), ErrPrincipalNotFound)
+	_, err = store.GetPrincipalPause(ctx, "nonexistent")
+	assert.ErrorIs(t, err, ErrPrincipalNotFound)
+}
+
 func TestPrincipalStore_UpdateLastSeen(t *testing.T) {
 	store := setupTestStore(t)
 	ctx := context.Background()
@@ -197,6 +235,70 @@ func TestPrincipalStore_UpdateLastSeen(t *testing.T) {
 	assert.Equal(t, lastSeen, *retrieved.LastSeen)
 }
 
+func TestPrincipalStore_RecordSeen(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	p := &Principal{
+		ID:          "principal-123",
+		Type:        PrincipalTypeAgent,
+		PubkeyFP:    "abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234abcd1234",
+		DisplayName: "Test Agent",
+		Status:      PrincipalStatusApproved,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+		Metadata:    map[string]any{"team": "infra"},
+	}
+	require.NoError(t, store.CreatePrincipal(ctx, p))
+
+	seen := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, store.RecordPrincipalSeen(ctx, p.ID, seen, map[string]any{"hostname": "build-01"}))
+	require.NoError(t, store.RecordPrincipalSeen(ctx, p.ID, seen, map[string]any{"hostname": "build-02"}))
+
+	retrieved, err := store.GetPrincipal(ctx, p.ID)
+	require.NoError(t, err)
+	require.NotNil(t, retrieved.LastSeen)
+	assert.Equal(t, seen, *retrieved.LastSeen)
+	assert.Equal(t, "infra", retrieved.Metadata["team"], "other metadata keys are preserved")
+	assert.Equal(t, map[string]any{"hostname": "build-02"}, retrieved.Metadata["agent"])
+
+	assert.ErrorIs(t, store.RecordPrincipalSeen(ctx, p.ID, seen, strings.Repeat("x", MaxMetadataSize)), ErrMetadataTooLarge)
+	assert.ErrorIs(t, store.RecordPrincipalSeen(ctx, "missing", seen, nil), ErrPrincipalNotFound)
+}
+
+func TestPrincipalStore_SetTags(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+
+	p := &Principal{
+		ID:          "principal-tags",
+		Type:        PrincipalTypeAgent,
+		PubkeyFP:    "feed1234feed1234feed1234feed1234feed1234feed1234feed1234feed1234",
+		DisplayName: "Tagged Agent",
+		Status:      PrincipalStatusApproved,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+	require.NoError(t, store.CreatePrincipal(ctx, p))
+
+	tags := map[string]string{"env": "prod", "team": "infra"}
+	require.NoError(t, store.SetPrincipalTags(ctx, p.ID, tags))
+	retrieved, err := store.GetPrincipal(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, tags, retrieved.Tags)
+
+	agentType := PrincipalTypeAgent
+	listed, err := store.ListPrincipals(ctx, PrincipalFilter{Type: &agentType})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, tags, listed[0].Tags)
+
+	require.NoError(t, store.SetPrincipalTags(ctx, p.ID, nil))
+	retrieved, err = store.GetPrincipal(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Nil(t, retrieved.Tags)
+
+	assert.ErrorIs(t, store.SetPrincipalTags(ctx, "missing", tags), ErrPrincipalNotFound)
+}
+
 func TestPrincipalStore_List_NoFilter(t *testing.T) {
 	store := setupTestStore(t)
 	ctx := context.Background()
@@ -0,0 +1,72 @@
+// ABOUTME: Thin *sql.DB wrapper that reports how long each statement took
+// ABOUTME: Lets the gateway export store query durations without the store depending on Prometheus
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// QueryObserver is told the leading SQL keyword (select, insert, ...) and
+// duration of each statement the store runs outside a transaction.
+type QueryObserver func(op string, d time.Duration)
+
+// timedDB is a *sql.DB whose context methods report to observe. Other
+// methods, including transactions, pass straight through. In a store handed
+// out by WithTx, tx is set and the context methods run in it, untimed.
+type timedDB struct {
+	*sql.DB
+	observe QueryObserver
+	tx      *activeTx
+}
+
+func (db *timedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if db.tx != nil {
+		return db.tx.conn.ExecContext(ctx, query, args...)
+	}
+	defer db.timed(query)()
+	return db.DB.ExecContext(ctx, query, args...)
+}
+
+func (db *timedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if db.tx != nil {
+		return db.tx.conn.QueryContext(ctx, query, args...)
+	}
+	defer db.timed(query)()
+	return db.DB.QueryContext(ctx, query, args...)
+}
+
+func (db *timedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if db.tx != nil {
+		return db.tx.conn.QueryRowContext(ctx, query, args...)
+	}
+	defer db.timed(query)()
+	return db.DB.QueryRowContext(ctx, query, args...)
+}
+
+// timed starts timing query and returns the func that reports it.
+func (db *timedDB) timed(query string) func() {
+	if db.observe == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() { db.observe(queryOp(query), time.Since(start)) }
+}
+
+// queryOp returns the statement's leading keyword, lowercased.
+func queryOp(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	return strings.ToLower(fields[0])
+}
+
+// SetQueryObserver reports the duration of every statement run outside a
+// transaction to fn. Call it before the store is shared.
+func (s *SQLStore) SetQueryObserver(fn QueryObserver) {
+	s.db.observe = fn
+}
@@ -0,0 +1,198 @@
+// ABOUTME: Persistence for ask_user questions so they outlive the client that missed them
+// ABOUTME: Questions move from pending to answered or expired exactly once
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrQuestionNotFound is returned when no question exists for an ID.
+	ErrQuestionNotFound = errors.New("question not found")
+	// ErrQuestionResolved is returned when resolving a question that is no longer pending.
+	ErrQuestionResolved = errors.New("question already resolved")
+)
+
+// QuestionStatus is the lifecycle state of an ask_user question.
+type QuestionStatus string
+
+const (
+	QuestionPending  QuestionStatus = "pending"  // waiting for the user to answer
+	QuestionAnswered QuestionStatus = "answered" // an answer reached the agent
+	QuestionExpired  QuestionStatus = "expired"  // the agent stopped waiting
+)
+
+// QuestionOption is one choice offered with a question.
+type QuestionOption struct {
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
+}
+
+// Question is an ask_user question put to the user on an agent's behalf.
+type Question struct {
+	ID             string
+	AgentID        string
+	Question       string
+	Header         string
+	Options        []QuestionOption
+	MultiSelect    bool
+	TimeoutSeconds int32
+	ContextJSON    string // context snapshot shown with the question, if any
+	AllowFreeText  *bool  // whether custom text is accepted; nil means yes
+	Pattern        string // regular expression custom text must match, if any
+	MaxLength      int32  // maximum custom text length in characters, 0 for none
+	Status         QuestionStatus
+	AskedAt        time.Time
+	ExpiresAt      time.Time
+	ResolvedAt     *time.Time // when the question left the pending state
+}
+
+// CreateQuestion records a new pending question.
+func (s *SQLStore) CreateQuestion(ctx context.Context, q *Question) error {
+	if q.AskedAt.IsZero() {
+		q.AskedAt = time.Now()
+	}
+	q.Status = QuestionPending
+
+	options, err := json.Marshal(q.Options)
+	if err != nil {
+		return fmt.Errorf("marshaling question options: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO questions (id, agent_id, question, header, options, multi_select, timeout_seconds,
+		                       context_json, allow_free_text, pattern, max_length, status, asked_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, q.ID, q.AgentID, q.Question, nullString(q.Header), string(options), q.MultiSelect, q.TimeoutSeconds,
+		nullString(q.ContextJSON), q.AllowFreeText, nullString(q.Pattern), sql.NullInt32{Int32: q.MaxLength, Valid: q.MaxLength > 0}, string(q.Status),
+		q.AskedAt.UTC().Format(time.RFC3339), q.ExpiresAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("inserting question: %w", err)
+	}
+	return nil
+}
+
+// GetQuestion retrieves a question by ID.
+func (s *SQLStore) GetQuestion(ctx context.Context, id string) (*Question, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, agent_id, question, header, options, multi_select, timeout_seconds, context_json,
+		       allow_free_text, pattern, max_length, status, asked_at, expires_at, resolved_at
+		FROM questions WHERE id = ?
+	`, id)
+	q, err := scanQuestion(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrQuestionNotFound
+	}
+	return q, err
+}
+
+// ListPendingQuestions returns pending questions that have not yet expired,
+// oldest first. An empty agentID matches all agents.
+func (s *SQLStore) ListPendingQuestions(ctx context.Context, agentID string) ([]*Question, error) {
+	query := `
+		SELECT id, agent_id, question, header, options, multi_select, timeout_seconds, context_json,
+		       allow_free_text, pattern, max_length, status, asked_at, expires_at, resolved_at
+		FROM questions
+		WHERE status = ? AND expires_at > ?`
+	args := []any{string(QuestionPending), time.Now().UTC().Format(time.RFC3339)}
+	if agentID != "" {
+		query += ` AND agent_id = ?`
+		args = append(args, agentID)
+	}
+	query += ` ORDER BY asked_at, id`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying pending questions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var questions []*Question
+	for rows.Next() {
+		q, err := scanQuestion(rows)
+		if err != nil {
+			return nil, err
+		}
+		questions = append(questions, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating pending questions: %w", err)
+	}
+	return questions, nil
+}
+
+// ResolveQuestion moves a pending question to answered or expired.
+// Returns ErrQuestionResolved if it already left the pending state.
+func (s *SQLStore) ResolveQuestion(ctx context.Context, id string, status QuestionStatus) error {
+	if status != QuestionAnswered && status != QuestionExpired {
+		return fmt.Errorf("cannot resolve question to %q", status)
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE questions SET status = ?, resolved_at = ? WHERE id = ? AND status = ?
+	`, string(status), time.Now().UTC().Format(time.RFC3339), id, string(QuestionPending))
+	if err != nil {
+		return fmt.Errorf("resolving question: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	if _, err := s.GetQuestion(ctx, id); err != nil {
+		return err
+	}
+	return ErrQuestionResolved
+}
+
+// ExpirePendingQuestions marks every pending question expired. The tool calls
+// waiting on them ended with the previous process, so it runs at startup.
+// Returns the number of questions expired.
+func (s *SQLStore) ExpirePendingQuestions(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE questions SET status = ?, resolved_at = ? WHERE status = ?
+	`, string(QuestionExpired), time.Now().UTC().Format(time.RFC3339), string(QuestionPending))
+	if err != nil {
+		return 0, fmt.Errorf("expiring questions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// scanQuestion scans a question row from a *sql.Row or *sql.Rows.
+func scanQuestion(row interface{ Scan(...any) error }) (*Question, error) {
+	var q Question
+	var status, options, askedAt, expiresAt string
+	var header, contextJSON, pattern, resolvedAt sql.NullString
+	var allowFreeText sql.NullBool
+	var maxLength sql.NullInt32
+	if err := row.Scan(&q.ID, &q.AgentID, &q.Question, &header, &options, &q.MultiSelect, &q.TimeoutSeconds,
+		&contextJSON, &allowFreeText, &pattern, &maxLength, &status, &askedAt, &expiresAt, &resolvedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scanning question: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(options), &q.Options); err != nil {
+		return nil, fmt.Errorf("decoding options of question %s: %w", q.ID, err)
+	}
+	q.Status = QuestionStatus(status)
+	q.Header = header.String
+	q.ContextJSON = contextJSON.String
+	if allowFreeText.Valid {
+		q.AllowFreeText = &allowFreeText.Bool
+	}
+	q.Pattern = pattern.String
+	q.MaxLength = maxLength.Int32
+	q.AskedAt = parseTimeWithWarning(askedAt, "question", q.ID, "asked_at")
+	q.ExpiresAt = parseTimeWithWarning(expiresAt, "question", q.ID, "expires_at")
+	if resolvedAt.Valid {
+		t := parseTimeWithWarning(resolvedAt.String, "question", q.ID, "resolved_at")
+		q.ResolvedAt = &t
+	}
+	return &q, nil
+}
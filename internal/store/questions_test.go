@@ -0,0 +1,144 @@
+// ABOUTME: Tests for persisted ask_user questions
+// ABOUTME: Covers the pending listing, answer constraints, answer/expiry transitions, and expiry at startup
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuestions_ResolveAndList(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	for i, id := range []string{"q-old", "q-new", "q-other"} {
+		agentID := "a1"
+		if id == "q-other" {
+			agentID = "a2"
+		}
+		q := &Question{
+			ID: id, AgentID: agentID, Question: "Deploy?", Header: "Release",
+			Options:     []QuestionOption{{Label: "Yes"}, {Label: "No", Description: "wait a day"}},
+			MultiSelect: true, TimeoutSeconds: 60, ContextJSON: `{"version":"v1"}`,
+			AskedAt: now.Add(time.Duration(i-3) * time.Minute), ExpiresAt: now.Add(time.Minute),
+		}
+		if err := s.CreateQuestion(ctx, q); err != nil {
+			t.Fatalf("CreateQuestion: %v", err)
+		}
+	}
+	if err := s.CreateQuestion(ctx, &Question{
+		ID: "q-stale", AgentID: "a1", Question: "Too late?", Options: []QuestionOption{{Label: "Yes"}},
+		TimeoutSeconds: 1, ExpiresAt: now.Add(-time.Minute),
+	}); err != nil {
+		t.Fatalf("CreateQuestion: %v", err)
+	}
+
+	pending, err := s.ListPendingQuestions(ctx, "a1")
+	if err != nil {
+		t.Fatalf("ListPendingQuestions: %v", err)
+	}
+	if len(pending) != 2 || pending[0].ID != "q-old" || pending[1].ID != "q-new" {
+		t.Fatalf("pending for a1 = %v, want q-old then q-new", questionIDs(pending))
+	}
+	q := pending[0]
+	if q.Header != "Release" || !q.MultiSelect || len(q.Options) != 2 || q.Options[1].Description != "wait a day" ||
+		q.ContextJSON != `{"version":"v1"}` || q.Status != QuestionPending ||
+		q.AllowFreeText != nil || q.Pattern != "" || q.MaxLength != 0 {
+		t.Errorf("question round trip = %+v", q)
+	}
+
+	if err := s.ResolveQuestion(ctx, "q-old", QuestionAnswered); err != nil {
+		t.Fatalf("answer: %v", err)
+	}
+	if err := s.ResolveQuestion(ctx, "q-old", QuestionExpired); !errors.Is(err, ErrQuestionResolved) {
+		t.Errorf("second resolve error = %v, want ErrQuestionResolved", err)
+	}
+	if err := s.ResolveQuestion(ctx, "missing", QuestionAnswered); !errors.Is(err, ErrQuestionNotFound) {
+		t.Errorf("missing resolve error = %v, want ErrQuestionNotFound", err)
+	}
+	if err := s.ResolveQuestion(ctx, "q-new", QuestionPending); err == nil {
+		t.Error("resolving to pending succeeded, want an error")
+	}
+
+	got, err := s.GetQuestion(ctx, "q-old")
+	if err != nil {
+		t.Fatalf("GetQuestion: %v", err)
+	}
+	if got.Status != QuestionAnswered || got.ResolvedAt == nil {
+		t.Errorf("answered question = %+v, want answered with resolved_at", got)
+	}
+
+	pending, err = s.ListPendingQuestions(ctx, "")
+	if err != nil {
+		t.Fatalf("ListPendingQuestions: %v", err)
+	}
+	if len(pending) != 2 || pending[0].ID != "q-new" || pending[1].ID != "q-other" {
+		t.Errorf("pending for all = %v, want q-new then q-other", questionIDs(pending))
+	}
+}
+
+func TestQuestions_ExpirePending(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"q1", "q2"} {
+		if err := s.CreateQuestion(ctx, &Question{
+			ID: id, AgentID: "a1", Question: "Continue?", Options: []QuestionOption{{Label: "Yes"}},
+			TimeoutSeconds: 60, ExpiresAt: time.Now().Add(time.Minute),
+		}); err != nil {
+			t.Fatalf("CreateQuestion: %v", err)
+		}
+	}
+	if err := s.ResolveQuestion(ctx, "q1", QuestionAnswered); err != nil {
+		t.Fatalf("answer: %v", err)
+	}
+
+	n, err := s.ExpirePendingQuestions(ctx)
+	if err != nil {
+		t.Fatalf("ExpirePendingQuestions: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expired %d questions, want 1", n)
+	}
+	if q, _ := s.GetQuestion(ctx, "q1"); q.Status != QuestionAnswered {
+		t.Errorf("q1 status = %s, want answered", q.Status)
+	}
+	if q, _ := s.GetQuestion(ctx, "q2"); q.Status != QuestionExpired {
+		t.Errorf("q2 status = %s, want expired", q.Status)
+	}
+	if _, err := s.GetQuestion(ctx, "missing"); !errors.Is(err, ErrQuestionNotFound) {
+		t.Errorf("GetQuestion(missing) error = %v, want ErrQuestionNotFound", err)
+	}
+}
+
+func TestQuestions_AnswerConstraints(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	closed := false
+	if err := s.CreateQuestion(ctx, &Question{
+		ID: "q-strict", AgentID: "a1", Question: "Ticket number?", Options: []QuestionOption{{Label: "None"}},
+		TimeoutSeconds: 60, AllowFreeText: &closed, Pattern: `^\d+$`, MaxLength: 6, ExpiresAt: time.Now().Add(time.Minute),
+	}); err != nil {
+		t.Fatalf("CreateQuestion: %v", err)
+	}
+	q, err := s.GetQuestion(ctx, "q-strict")
+	if err != nil {
+		t.Fatalf("GetQuestion: %v", err)
+	}
+	if q.AllowFreeText == nil || *q.AllowFreeText || q.Pattern != `^\d+$` || q.MaxLength != 6 {
+		t.Errorf("constraints = %v %q %d, want false, ^\\d+$, 6", q.AllowFreeText, q.Pattern, q.MaxLength)
+	}
+}
+
+func questionIDs(questions []*Question) []string {
+	ids := make([]string, len(questions))
+	for i, q := range questions {
+		ids[i] = q.ID
+	}
+	return ids
+}
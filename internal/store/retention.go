@@ -0,0 +1,188 @@
+// ABOUTME: Retention pruning: deletes expired ledger events, messages and log entries in small batches
+// ABOUTME: Keeps every event of a thread updated within the window, and can vacuum afterwards
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultPruneBatchSize is how many rows one DELETE removes when
+// PruneOptions.BatchSize is unset.
+const DefaultPruneBatchSize = 500
+
+// PruneOptions says what Prune deletes. A zero cutoff keeps that table's
+// rows forever.
+type PruneOptions struct {
+	// LedgerEventsBefore deletes events older than this, except those of a
+	// thread updated at or after it.
+	LedgerEventsBefore time.Time
+	// MessagesBefore deletes legacy messages older than this, with the same
+	// exception for recently updated threads.
+	MessagesBefore time.Time
+	// LogsBefore deletes agent log entries older than this.
+	LogsBefore time.Time
+
+	// BatchSize caps the rows each DELETE removes, so no single write holds
+	// the WAL lock for long. Defaults to DefaultPruneBatchSize.
+	BatchSize int
+	// BatchPause is slept between batches to let other writers in.
+	BatchPause time.Duration
+}
+
+// PruneResult reports what Prune deleted.
+type PruneResult struct {
+	Deleted map[string]int64 // rows deleted per table
+	Batches int              // DELETE statements run
+}
+
+// pruneTarget is one table Prune deletes from.
+type pruneTarget struct {
+	table string
+	// where selects the expired rows; its single parameter is the cutoff.
+	where string
+}
+
+// Events and messages of threads updated at or after the cutoff are kept
+// even when they are older, so an active conversation never loses its
+// history.
+var (
+	pruneLedgerEvents = pruneTarget{"ledger_events", `timestamp < ?1 AND (thread_id IS NULL OR thread_id NOT IN (SELECT id FROM threads WHERE updated_at >= ?1))`}
+	pruneMessages     = pruneTarget{"messages", `created_at < ?1 AND thread_id NOT IN (SELECT id FROM threads WHERE updated_at >= ?1)`}
+	pruneLogs         = pruneTarget{"log_entries", `created_at < ?1`}
+)
+
+// Prune deletes expired rows in batches of opts.BatchSize, pausing
+// opts.BatchPause between them. If ctx is canceled it stops and returns
+// what it deleted so far along with the error.
+func (s *SQLStore) Prune(ctx context.Context, opts PruneOptions) (PruneResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultPruneBatchSize
+	}
+	result := PruneResult{Deleted: make(map[string]int64)}
+	for _, t := range []struct {
+		target pruneTarget
+		before time.Time
+	}{
+		{pruneLedgerEvents, opts.LedgerEventsBefore},
+		{pruneMessages, opts.MessagesBefore},
+		{pruneLogs, opts.LogsBefore},
+	} {
+		if t.before.IsZero() {
+			continue
+		}
+		if err := s.pruneTable(ctx, t.target, t.before, opts, &result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (s *SQLStore) pruneTable(ctx context.Context, t pruneTarget, before time.Time, opts PruneOptions, result *PruneResult) error {
+	cutoff := before.UTC().Format(time.RFC3339)
+	query := `DELETE FROM ` + t.table + ` WHERE rowid IN (SELECT rowid FROM ` + t.table + ` WHERE ` + t.where + ` LIMIT ?2)`
+	result.Deleted[t.table] = 0
+	for {
+		res, err := s.db.ExecContext(ctx, query, cutoff, opts.BatchSize)
+		if err != nil {
+			return fmt.Errorf("pruning %s: %w", t.table, err)
+		}
+		result.Batches++
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("pruning %s: %w", t.table, err)
+		}
+		result.Deleted[t.table] += n
+		if n < int64(opts.BatchSize) {
+			return nil
+		}
+		if opts.BatchPause > 0 {
+			timer := time.NewTimer(opts.BatchPause)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// VacuumMode selects how Vacuum returns freed pages to the filesystem.
+type VacuumMode string
+
+const (
+	// VacuumNone leaves freed pages in the database file for reuse.
+	VacuumNone VacuumMode = ""
+	// VacuumIncremental releases free pages without rewriting the file. It
+	// needs auto_vacuum=INCREMENTAL, which Vacuum turns on the first time
+	// with a full VACUUM.
+	VacuumIncremental VacuumMode = "incremental"
+	// VacuumFull rewrites the whole database. It blocks writers while it
+	// runs and needs free disk space about the size of the database.
+	VacuumFull VacuumMode = "full"
+)
+
+// ErrInvalidVacuumMode is returned for a VacuumMode Vacuum doesn't know.
+var ErrInvalidVacuumMode = errors.New("vacuum mode must be incremental or full")
+
+// Vacuum shrinks the database file after rows were deleted. On Postgres,
+// incremental runs a plain VACUUM, which marks dead rows reusable without
+// locking tables, and full runs VACUUM FULL.
+func (s *SQLStore) Vacuum(ctx context.Context, mode VacuumMode) error {
+	if s.dialect == dialectPostgres {
+		return s.vacuumPostgres(ctx, mode)
+	}
+	switch mode {
+	case VacuumNone:
+		return nil
+	case VacuumFull:
+		if _, err := s.db.ExecContext(ctx, `VACUUM`); err != nil {
+			return fmt.Errorf("vacuuming: %w", err)
+		}
+		return nil
+	case VacuumIncremental:
+		var autoVacuum int
+		if err := s.db.QueryRowContext(ctx, `PRAGMA auto_vacuum`).Scan(&autoVacuum); err != nil {
+			return fmt.Errorf("reading auto_vacuum: %w", err)
+		}
+		// 2 is INCREMENTAL. Switching to it only takes effect after a VACUUM.
+		if autoVacuum != 2 {
+			s.logger.Info("enabling incremental vacuum; rewriting database once")
+			if _, err := s.db.ExecContext(ctx, `PRAGMA auto_vacuum = INCREMENTAL`); err != nil {
+				return fmt.Errorf("enabling incremental vacuum: %w", err)
+			}
+			if _, err := s.db.ExecContext(ctx, `VACUUM`); err != nil {
+				return fmt.Errorf("vacuuming: %w", err)
+			}
+			return nil
+		}
+		if _, err := s.db.ExecContext(ctx, `PRAGMA incremental_vacuum`); err != nil {
+			return fmt.Errorf("incremental vacuum: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidVacuumMode, mode)
+	}
+}
+
+func (s *SQLStore) vacuumPostgres(ctx context.Context, mode VacuumMode) error {
+	var stmt string
+	switch mode {
+	case VacuumNone:
+		return nil
+	case VacuumFull:
+		stmt = `VACUUM FULL`
+	case VacuumIncremental:
+		stmt = `VACUUM`
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidVacuumMode, mode)
+	}
+	if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("vacuuming: %w", err)
+	}
+	return nil
+}
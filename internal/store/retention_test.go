@@ -0,0 +1,129 @@
+// ABOUTME: Tests for retention pruning and vacuuming
+// ABOUTME: Covers batching, keeping history of recently updated threads, and vacuum modes
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func saveEventAt(t *testing.T, s *SQLStore, id string, threadID *string, at time.Time) {
+	t.Helper()
+	text := "event " + id
+	require.NoError(t, s.SaveEvent(context.Background(), &LedgerEvent{
+		ID: id, ConversationKey: "agent-1", ThreadID: threadID, Direction: EventDirectionInbound,
+		Author: "alice", Timestamp: at, Type: EventTypeMessage, Text: &text,
+	}))
+}
+
+func countRows(t *testing.T, s *SQLStore, table string) int {
+	t.Helper()
+	var n int
+	require.NoError(t, s.db.QueryRow(`SELECT COUNT(*) FROM `+table).Scan(&n))
+	return n
+}
+
+func TestPrune_Batches(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	defer s.Close()
+
+	now := time.Now().UTC()
+	old := now.Add(-48 * time.Hour)
+	for i := range 25 {
+		saveEventAt(t, s, fmt.Sprintf("old-%d", i), nil, old)
+	}
+	saveEventAt(t, s, "recent", nil, now)
+
+	result, err := s.Prune(ctx, PruneOptions{
+		LedgerEventsBefore: now.Add(-24 * time.Hour),
+		BatchSize:          10,
+		BatchPause:         time.Millisecond,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"ledger_events": 25}, result.Deleted)
+	assert.Equal(t, 3, result.Batches, "25 rows in batches of 10")
+	assert.Equal(t, 1, countRows(t, s, "ledger_events"))
+
+	// Nothing left to delete takes one empty batch.
+	result, err = s.Prune(ctx, PruneOptions{LedgerEventsBefore: now.Add(-24 * time.Hour), BatchSize: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Deleted["ledger_events"])
+	assert.Equal(t, 1, result.Batches)
+}
+
+func TestPrune_KeepsRecentlyUpdatedThreads(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	defer s.Close()
+
+	now := time.Now().UTC()
+	old := now.Add(-48 * time.Hour)
+	for _, th := range []struct {
+		id      string
+		updated time.Time
+	}{{"active", now}, {"stale", old}} {
+		require.NoError(t, s.CreateThread(ctx, &Thread{ID: th.id, FrontendName: "tui", ExternalID: th.id, AgentID: "agent-1", CreatedAt: old, UpdatedAt: th.updated}))
+		threadID := th.id
+		saveEventAt(t, s, th.id+"-event", &threadID, old)
+		require.NoError(t, s.SaveMessage(ctx, &Message{ID: th.id + "-message", ThreadID: th.id, Sender: "alice", Content: "hi", CreatedAt: old}))
+	}
+	saveEventAt(t, s, "loose-event", nil, old)
+	require.NoError(t, s.CreateLogEntry(ctx, &LogEntry{ID: "log-old", AgentID: "agent-1", Message: "old", CreatedAt: old}))
+	require.NoError(t, s.CreateLogEntry(ctx, &LogEntry{ID: "log-new", AgentID: "agent-1", Message: "new", CreatedAt: now}))
+
+	cutoff := now.Add(-24 * time.Hour)
+	result, err := s.Prune(ctx, PruneOptions{LedgerEventsBefore: cutoff, MessagesBefore: cutoff, LogsBefore: cutoff})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"ledger_events": 2, "messages": 1, "log_entries": 1}, result.Deleted)
+
+	events, err := s.GetEvents(ctx, GetEventsParams{ConversationKey: "agent-1"})
+	require.NoError(t, err)
+	require.Len(t, events.Events, 1)
+	assert.Equal(t, "active-event", events.Events[0].ID, "the active thread's old event is kept")
+
+	messages, err := s.GetThreadMessages(ctx, "active", 0)
+	require.NoError(t, err)
+	assert.Len(t, messages, 1)
+	messages, err = s.GetThreadMessages(ctx, "stale", 0)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+func TestPrune_ZeroCutoffKeepsEverything(t *testing.T) {
+	s := newTestStore(t)
+	defer s.Close()
+
+	saveEventAt(t, s, "ancient", nil, time.Unix(0, 0))
+	result, err := s.Prune(context.Background(), PruneOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, result.Deleted)
+	assert.Equal(t, 1, countRows(t, s, "ledger_events"))
+}
+
+func TestVacuum(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	defer s.Close()
+
+	require.NoError(t, s.Vacuum(ctx, VacuumNone))
+	require.NoError(t, s.Vacuum(ctx, VacuumFull))
+
+	// The first incremental vacuum switches the database over; later ones
+	// just release free pages.
+	require.NoError(t, s.Vacuum(ctx, VacuumIncremental))
+	if s.dialect == dialectSQLite {
+		var autoVacuum int
+		require.NoError(t, s.db.QueryRow(`PRAGMA auto_vacuum`).Scan(&autoVacuum))
+		assert.Equal(t, 2, autoVacuum)
+	}
+	require.NoError(t, s.Vacuum(ctx, VacuumIncremental))
+
+	assert.ErrorIs(t, s.Vacuum(ctx, "sometimes"), ErrInvalidVacuumMode)
+}
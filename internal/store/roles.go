@@ -45,10 +45,11 @@ type Role struct {
 
 // AddRole adds a role to a subject. This operation is idempotent - adding an
 // existing role succeeds silently.
-func (s *SQLiteStore) AddRole(ctx context.Context, subjectType RoleSubjectType, subjectID string, role RoleName) error {
+func (s *SQLStore) AddRole(ctx context.Context, subjectType RoleSubjectType, subjectID string, role RoleName) error {
 	query := `
-		INSERT OR IGNORE INTO roles (subject_type, subject_id, role, created_at)
+		INSERT INTO roles (subject_type, subject_id, role, created_at)
 		VALUES (?, ?, ?, ?)
+		ON CONFLICT (subject_type, subject_id, role) DO NOTHING
 	`
 
 	_, err := s.db.ExecContext(ctx, query,
@@ -67,7 +68,7 @@ func (s *SQLiteStore) AddRole(ctx context.Context, subjectType RoleSubjectType,
 
 // RemoveRole removes a role from a subject. This operation is idempotent -
 // removing a non-existent role succeeds silently.
-func (s *SQLiteStore) RemoveRole(ctx context.Context, subjectType RoleSubjectType, subjectID string, role RoleName) error {
+func (s *SQLStore) RemoveRole(ctx context.Context, subjectType RoleSubjectType, subjectID string, role RoleName) error {
 	query := `DELETE FROM roles WHERE subject_type = ? AND subject_id = ? AND role = ?`
 
 	_, err := s.db.ExecContext(ctx, query, subjectType, subjectID, role)
@@ -81,7 +82,7 @@ func (s *SQLiteStore) RemoveRole(ctx context.Context, subjectType RoleSubjectTyp
 
 // HasRole checks if a subject has a specific role. Returns false for
 // non-existent subjects (not an error).
-func (s *SQLiteStore) HasRole(ctx context.Context, subjectType RoleSubjectType, subjectID string, role RoleName) (bool, error) {
+func (s *SQLStore) HasRole(ctx context.Context, subjectType RoleSubjectType, subjectID string, role RoleName) (bool, error) {
 	query := `
 		SELECT COUNT(*) FROM roles
 		WHERE subject_type = ? AND subject_id = ? AND role = ?
@@ -98,7 +99,7 @@ func (s *SQLiteStore) HasRole(ctx context.Context, subjectType RoleSubjectType,
 
 // ListRoles returns all roles assigned to a subject. Returns an empty slice
 // if the subject has no roles.
-func (s *SQLiteStore) ListRoles(ctx context.Context, subjectType RoleSubjectType, subjectID string) ([]RoleName, error) {
+func (s *SQLStore) ListRoles(ctx context.Context, subjectType RoleSubjectType, subjectID string) ([]RoleName, error) {
 	query := `
 		SELECT role FROM roles
 		WHERE subject_type = ? AND subject_id = ?
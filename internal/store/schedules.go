@@ -0,0 +1,195 @@
+// ABOUTME: Scheduled messages that admins queue for later delivery to an agent or binding
+// ABOUTME: Holds each schedule's next run time, recurrence, retry attempts, and last outcome
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ScheduleStatus is the lifecycle state of a scheduled message.
+type ScheduleStatus string
+
+const (
+	SchedulePending ScheduleStatus = "pending" // waiting for RunAt
+	ScheduleDone    ScheduleStatus = "done"    // a one-off schedule that has been sent
+	ScheduleErrored ScheduleStatus = "errored" // gave up after repeated failures
+)
+
+// ScheduledMessage is a message the gateway sends on a schedule. It targets
+// either AgentID directly or the agent bound to Frontend+ChannelID.
+type ScheduledMessage struct {
+	ID         string
+	AgentID    string
+	Frontend   string
+	ChannelID  string
+	Sender     string
+	Content    string
+	RunAt      time.Time // next time it is due, including retry backoff
+	Recurrence string    // recurrence rule; empty for a one-off message
+	CreatedBy  string    // principal that created it; empty with auth disabled
+	Status     ScheduleStatus
+	Attempts   int    // consecutive failed attempts at the current run
+	LastError  string // error from the last run, if it failed
+	// LastThreadID is the thread the last successful run landed in; later
+	// runs of a direct-to-agent schedule continue it.
+	LastThreadID string
+	LastRunAt    *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+const scheduleColumns = `id, agent_id, frontend, channel_id, sender, content, run_at, recurrence, created_by,
+	status, attempts, last_error, last_thread_id, last_run_at, created_at, updated_at`
+
+// CreateSchedule stores a new pending scheduled message.
+func (s *SQLStore) CreateSchedule(ctx context.Context, m *ScheduledMessage) error {
+	now := time.Now()
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = now
+	}
+	m.UpdatedAt = m.CreatedAt
+	m.Status = SchedulePending
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO scheduled_messages (id, agent_id, frontend, channel_id, sender, content, run_at, recurrence, created_by, status, attempts, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?)
+	`, m.ID, nullString(m.AgentID), nullString(m.Frontend), nullString(m.ChannelID), m.Sender, m.Content,
+		m.RunAt.UTC().Format(time.RFC3339), nullString(m.Recurrence), nullString(m.CreatedBy), string(m.Status),
+		m.CreatedAt.UTC().Format(time.RFC3339), m.UpdatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("inserting scheduled message: %w", err)
+	}
+	return nil
+}
+
+// GetSchedule returns a scheduled message, or ErrNotFound.
+func (s *SQLStore) GetSchedule(ctx context.Context, id string) (*ScheduledMessage, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+scheduleColumns+` FROM scheduled_messages WHERE id = ?`, id)
+	m, err := scanSchedule(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying scheduled message: %w", err)
+	}
+	return m, nil
+}
+
+// ListSchedules returns scheduled messages ordered by next run time. An
+// empty status lists them all.
+func (s *SQLStore) ListSchedules(ctx context.Context, status ScheduleStatus) ([]*ScheduledMessage, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM scheduled_messages`
+	var args []any
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, string(status))
+	}
+	query += ` ORDER BY run_at, id`
+	return s.querySchedules(ctx, query, args...)
+}
+
+// ListDueSchedules returns pending scheduled messages whose run time is at
+// or before now, oldest first.
+func (s *SQLStore) ListDueSchedules(ctx context.Context, now time.Time) ([]*ScheduledMessage, error) {
+	return s.querySchedules(ctx, `
+		SELECT `+scheduleColumns+` FROM scheduled_messages
+		WHERE status = ? AND run_at <= ?
+		ORDER BY run_at, id
+	`, string(SchedulePending), now.UTC().Format(time.RFC3339))
+}
+
+// UpdateScheduleRun records the outcome of a run: the next run time, status,
+// attempts, and last error/thread/run time. Returns ErrNotFound if the
+// schedule was deleted meanwhile.
+func (s *SQLStore) UpdateScheduleRun(ctx context.Context, m *ScheduledMessage) error {
+	m.UpdatedAt = time.Now()
+	var lastRunAt any
+	if m.LastRunAt != nil {
+		lastRunAt = m.LastRunAt.UTC().Format(time.RFC3339)
+	}
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE scheduled_messages
+		SET run_at = ?, status = ?, attempts = ?, last_error = ?, last_thread_id = ?, last_run_at = ?, updated_at = ?
+		WHERE id = ?
+	`, m.RunAt.UTC().Format(time.RFC3339), string(m.Status), m.Attempts, nullString(m.LastError),
+		nullString(m.LastThreadID), lastRunAt, m.UpdatedAt.UTC().Format(time.RFC3339), m.ID)
+	if err != nil {
+		return fmt.Errorf("updating scheduled message: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteSchedule removes a scheduled message, or returns ErrNotFound.
+func (s *SQLStore) DeleteSchedule(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM scheduled_messages WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("deleting scheduled message: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLStore) querySchedules(ctx context.Context, query string, args ...any) ([]*ScheduledMessage, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying scheduled messages: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*ScheduledMessage
+	for rows.Next() {
+		m, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning scheduled message: %w", err)
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating scheduled messages: %w", err)
+	}
+	return out, nil
+}
+
+func scanSchedule(row interface{ Scan(...any) error }) (*ScheduledMessage, error) {
+	var m ScheduledMessage
+	var agentID, frontend, channelID, recurrence, createdBy, lastError, lastThreadID, lastRunAt sql.NullString
+	var status, runAt, createdAt, updatedAt string
+	if err := row.Scan(&m.ID, &agentID, &frontend, &channelID, &m.Sender, &m.Content, &runAt, &recurrence, &createdBy,
+		&status, &m.Attempts, &lastError, &lastThreadID, &lastRunAt, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	m.AgentID = agentID.String
+	m.Frontend = frontend.String
+	m.ChannelID = channelID.String
+	m.Recurrence = recurrence.String
+	m.CreatedBy = createdBy.String
+	m.Status = ScheduleStatus(status)
+	m.LastError = lastError.String
+	m.LastThreadID = lastThreadID.String
+	m.RunAt = parseTimeWithWarning(runAt, "scheduled_message", m.ID, "run_at")
+	if lastRunAt.Valid {
+		t := parseTimeWithWarning(lastRunAt.String, "scheduled_message", m.ID, "last_run_at")
+		m.LastRunAt = &t
+	}
+	m.CreatedAt = parseTimeWithWarning(createdAt, "scheduled_message", m.ID, "created_at")
+	m.UpdatedAt = parseTimeWithWarning(updatedAt, "scheduled_message", m.ID, "updated_at")
+	return &m, nil
+}
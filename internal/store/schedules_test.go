@@ -0,0 +1,73 @@
+// ABOUTME: Tests for scheduled messages
+// ABOUTME: Covers create/get/list, due selection, recording run outcomes, and deletion
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedules(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	due := &ScheduledMessage{ID: "s-due", AgentID: "agent-1", Sender: "ops", Content: "standup", RunAt: now.Add(-time.Minute), Recurrence: "@daily", CreatedBy: "admin-1"}
+	later := &ScheduledMessage{ID: "s-later", Frontend: "slack", ChannelID: "C1", Sender: "ops", Content: "later", RunAt: now.Add(time.Hour)}
+	for _, m := range []*ScheduledMessage{later, due} {
+		if err := s.CreateSchedule(ctx, m); err != nil {
+			t.Fatalf("CreateSchedule(%s): %v", m.ID, err)
+		}
+	}
+
+	got, err := s.GetSchedule(ctx, "s-due")
+	if err != nil {
+		t.Fatalf("GetSchedule: %v", err)
+	}
+	if got.AgentID != "agent-1" || got.Content != "standup" || !got.RunAt.Equal(due.RunAt) || got.Recurrence != "@daily" ||
+		got.CreatedBy != "admin-1" || got.Status != SchedulePending || got.LastRunAt != nil {
+		t.Errorf("got %+v", got)
+	}
+
+	all, err := s.ListSchedules(ctx, "")
+	if err != nil || len(all) != 2 || all[0].ID != "s-due" || all[1].Frontend != "slack" {
+		t.Fatalf("ListSchedules = %v, %v", all, err)
+	}
+	dueList, err := s.ListDueSchedules(ctx, now)
+	if err != nil || len(dueList) != 1 || dueList[0].ID != "s-due" {
+		t.Fatalf("ListDueSchedules = %v, %v", dueList, err)
+	}
+
+	ran := now
+	got.Status = ScheduleErrored
+	got.Attempts = 3
+	got.LastError = "agent unavailable"
+	got.LastRunAt = &ran
+	if err := s.UpdateScheduleRun(ctx, got); err != nil {
+		t.Fatalf("UpdateScheduleRun: %v", err)
+	}
+	if dueList, _ := s.ListDueSchedules(ctx, now); len(dueList) != 0 {
+		t.Errorf("errored schedule still due: %v", dueList)
+	}
+	errored, err := s.ListSchedules(ctx, ScheduleErrored)
+	if err != nil || len(errored) != 1 || errored[0].Attempts != 3 || errored[0].LastError != "agent unavailable" ||
+		errored[0].LastRunAt == nil || !errored[0].LastRunAt.Equal(ran) {
+		t.Fatalf("ListSchedules(errored) = %+v, %v", errored, err)
+	}
+
+	if err := s.DeleteSchedule(ctx, "s-due"); err != nil {
+		t.Fatalf("DeleteSchedule: %v", err)
+	}
+	if _, err := s.GetSchedule(ctx, "s-due"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetSchedule after delete = %v, want ErrNotFound", err)
+	}
+	if err := s.DeleteSchedule(ctx, "s-due"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second DeleteSchedule = %v, want ErrNotFound", err)
+	}
+	if err := s.UpdateScheduleRun(ctx, got); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateScheduleRun after delete = %v, want ErrNotFound", err)
+	}
+}
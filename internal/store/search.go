@@ -0,0 +1,192 @@
+// ABOUTME: Full-text search over ledger event text: FTS5 kept in sync by triggers, or a Postgres GIN index
+// ABOUTME: Degrades to ErrSearchUnavailable when the SQLite build has no FTS5 module
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// ErrSearchUnavailable is returned by SearchMessages when the SQLite build
+// lacks the FTS5 module. Postgres stores always search.
+var ErrSearchUnavailable = errors.New("full-text search unavailable: SQLite was built without FTS5")
+
+// schemaSearchSQL indexes ledger_events.text. The index is external-content:
+// it stores only the terms and reads text back from ledger_events by rowid.
+const schemaSearchSQL = `
+CREATE VIRTUAL TABLE IF NOT EXISTS ledger_events_fts USING fts5(text, content='ledger_events', content_rowid='rowid');
+CREATE TRIGGER IF NOT EXISTS ledger_events_fts_insert AFTER INSERT ON ledger_events BEGIN
+  INSERT INTO ledger_events_fts(rowid, text) VALUES (new.rowid, new.text);
+END;
+CREATE TRIGGER IF NOT EXISTS ledger_events_fts_delete AFTER DELETE ON ledger_events BEGIN
+  INSERT INTO ledger_events_fts(ledger_events_fts, rowid, text) VALUES ('delete', old.rowid, old.text);
+END;
+CREATE TRIGGER IF NOT EXISTS ledger_events_fts_update AFTER UPDATE OF text ON ledger_events BEGIN
+  INSERT INTO ledger_events_fts(ledger_events_fts, rowid, text) VALUES ('delete', old.rowid, old.text);
+  INSERT INTO ledger_events_fts(rowid, text) VALUES (new.rowid, new.text);
+END;
+`
+
+// Snippet highlight markers. Control characters cannot appear in the HTML
+// that SearchResult.Snippet is built from, so they split it unambiguously.
+const (
+	snippetOpen  = "\x02"
+	snippetClose = "\x03"
+)
+
+// SearchFilter narrows SearchMessages results.
+type SearchFilter struct {
+	ThreadID string     // only events in this thread
+	AgentID  string     // only events in this agent's conversation
+	Since    *time.Time // only events at or after this time
+	Until    *time.Time // only events at or before this time
+	Limit    int        // 1-200, defaults to 50
+	Offset   int
+}
+
+// SearchResult is one ledger event matching a search.
+type SearchResult struct {
+	EventID   string
+	ThreadID  string // empty for events outside a thread
+	AgentID   string
+	Author    string
+	Direction EventDirection
+	Type      EventType
+	Timestamp time.Time
+	// Snippet is an HTML-escaped excerpt of the text with matching terms
+	// wrapped in <mark> elements.
+	Snippet string
+}
+
+// initSearch creates the FTS5 index and its triggers, backfilling existing
+// events the first time. Without FTS5 it logs a warning and leaves search
+// disabled rather than failing the store.
+func (s *SQLStore) initSearch() error {
+	var exists int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'ledger_events_fts'`).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("checking search index: %w", err)
+	}
+	if _, err := s.db.Exec(schemaSearchSQL); err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			s.logger.Warn("full-text search disabled: SQLite was built without FTS5")
+			return nil
+		}
+		return fmt.Errorf("creating search index: %w", err)
+	}
+	if exists == 0 {
+		if _, err := s.db.Exec(`INSERT INTO ledger_events_fts(ledger_events_fts) VALUES ('rebuild')`); err != nil {
+			return fmt.Errorf("backfilling search index: %w", err)
+		}
+		s.logger.Info("search index built from existing ledger events")
+	}
+	s.searchEnabled = true
+	return nil
+}
+
+// SearchMessages returns ledger events whose text matches query, best match
+// first. Every whitespace-separated term must appear; terms are matched as
+// words, so FTS5 query syntax in query is treated as plain text. Returns
+// ErrSearchUnavailable when the SQLite build lacks FTS5.
+func (s *SQLStore) SearchMessages(ctx context.Context, query string, f SearchFilter) ([]*SearchResult, error) {
+	if !s.searchEnabled {
+		return nil, ErrSearchUnavailable
+	}
+	match := ftsMatchQuery(query)
+	if match == "" {
+		return []*SearchResult{}, nil
+	}
+	if f.Limit <= 0 || f.Limit > 200 {
+		f.Limit = 50
+	}
+
+	q := `
+		SELECT e.event_id, e.thread_id, e.conversation_key, e.author, e.direction, e.type, e.timestamp,
+		       snippet(ledger_events_fts, 0, ?, ?, '…', 16)
+		FROM ledger_events_fts
+		JOIN ledger_events e ON e.rowid = ledger_events_fts.rowid
+		WHERE ledger_events_fts MATCH ?`
+	args := []any{snippetOpen, snippetClose, match}
+	order := `rank`
+	if s.dialect == dialectPostgres {
+		// The to_tsvector expression must match idx_ledger_text for the
+		// index to be used. plainto_tsquery ANDs the words of query and
+		// ignores operators, as the quoted FTS5 terms do.
+		q = `
+			SELECT e.event_id, e.thread_id, e.conversation_key, e.author, e.direction, e.type, e.timestamp,
+			       ts_headline('simple', COALESCE(e.text, ''), tsq, ?)
+			FROM ledger_events e, plainto_tsquery('simple', ?) AS tsq
+			WHERE to_tsvector('simple', COALESCE(e.text, '')) @@ tsq`
+		args = []any{`StartSel="` + snippetOpen + `", StopSel="` + snippetClose + `", MaxWords=16, MinWords=4`, query}
+		order = `ts_rank(to_tsvector('simple', COALESCE(e.text, '')), tsq) DESC`
+	}
+	if f.ThreadID != "" {
+		q += ` AND e.thread_id = ?`
+		args = append(args, f.ThreadID)
+	}
+	if f.AgentID != "" {
+		q += ` AND e.conversation_key = ?`
+		args = append(args, f.AgentID)
+	}
+	if f.Since != nil {
+		q += ` AND e.timestamp >= ?`
+		args = append(args, f.Since.Format(time.RFC3339))
+	}
+	if f.Until != nil {
+		q += ` AND e.timestamp <= ?`
+		args = append(args, f.Until.Format(time.RFC3339))
+	}
+	q += ` ORDER BY ` + order + `, e.timestamp DESC LIMIT ? OFFSET ?`
+	args = append(args, f.Limit, max(f.Offset, 0))
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("searching events: %w", err)
+	}
+	defer rows.Close()
+
+	results := []*SearchResult{}
+	for rows.Next() {
+		var r SearchResult
+		var threadID *string
+		var direction, eventType, ts, snippet string
+		if err := rows.Scan(&r.EventID, &threadID, &r.AgentID, &r.Author, &direction, &eventType, &ts, &snippet); err != nil {
+			return nil, fmt.Errorf("scanning search result: %w", err)
+		}
+		if threadID != nil {
+			r.ThreadID = *threadID
+		}
+		r.Direction = EventDirection(direction)
+		r.Type = EventType(eventType)
+		r.Timestamp = parseTimeWithWarning(ts, "ledger_event", r.EventID, "timestamp")
+		r.Snippet = highlightSnippet(snippet)
+		results = append(results, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating search results: %w", err)
+	}
+	return results, nil
+}
+
+// ftsMatchQuery turns free text into an FTS5 query that requires every term,
+// quoting each so operators and punctuation are matched literally.
+func ftsMatchQuery(query string) string {
+	terms := strings.Fields(query)
+	for i, t := range terms {
+		terms[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " ")
+}
+
+// highlightSnippet escapes a raw FTS5 or ts_headline snippet and turns its markers into
+// <mark> elements.
+func highlightSnippet(raw string) string {
+	escaped := html.EscapeString(raw)
+	escaped = strings.ReplaceAll(escaped, snippetOpen, "<mark>")
+	return strings.ReplaceAll(escaped, snippetClose, "</mark>")
+}
@@ -0,0 +1,119 @@
+// ABOUTME: Tests for full-text search over ledger events
+// ABOUTME: Covers ranking filters, snippet escaping, index backfill on upgrade, and the no-FTS5 fallback
+
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func saveSearchEvent(t *testing.T, s *SQLStore, id, agentID, threadID, text string, ts time.Time) {
+	t.Helper()
+	if err := s.SaveEvent(context.Background(), &LedgerEvent{
+		ID: id, ConversationKey: agentID, ThreadID: &threadID, Direction: EventDirectionInbound,
+		Author: "alice", Timestamp: ts, Type: EventTypeMessage, Text: &text,
+	}); err != nil {
+		t.Fatalf("SaveEvent(%s): %v", id, err)
+	}
+}
+
+func searchIDs(results []*SearchResult) string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.EventID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestSearchMessages(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	saveSearchEvent(t, s, "e1", "agent-a", "t1", "deploy the <staging> cluster", base)
+	saveSearchEvent(t, s, "e2", "agent-a", "t2", "rollback the production deploy", base.Add(time.Hour))
+	saveSearchEvent(t, s, "e3", "agent-b", "t3", "deploying docs", base.Add(2*time.Hour))
+	saveSearchEvent(t, s, "e4", "agent-b", "t3", "lunch plans", base.Add(3*time.Hour))
+
+	results, err := s.SearchMessages(ctx, "deploy", SearchFilter{})
+	if err != nil {
+		t.Fatalf("SearchMessages: %v", err)
+	}
+	if got := searchIDs(results); got != "e1,e2" && got != "e2,e1" {
+		t.Errorf("deploy matched %q, want e1 and e2", got)
+	}
+	for _, r := range results {
+		if r.EventID == "e1" && r.Snippet != "<mark>deploy</mark> the &lt;staging&gt; cluster" {
+			t.Errorf("snippet = %q, want escaped text with the term marked", r.Snippet)
+		}
+	}
+
+	midway := base.Add(30 * time.Minute)
+	for name, tc := range map[string]struct {
+		query  string
+		filter SearchFilter
+		want   string
+	}{
+		"all terms":   {"deploy production", SearchFilter{}, "e2"},
+		"by agent":    {"deploy", SearchFilter{AgentID: "agent-a", ThreadID: "t1"}, "e1"},
+		"since":       {"deploy", SearchFilter{Since: &midway}, "e2"},
+		"until":       {"deploy", SearchFilter{Until: &midway}, "e1"},
+		"syntax":      {`deploy" OR lunch`, SearchFilter{}, ""},
+		"no match":    {"kubernetes", SearchFilter{}, ""},
+		"blank query": {"  ", SearchFilter{}, ""},
+	} {
+		results, err := s.SearchMessages(ctx, tc.query, tc.filter)
+		if err != nil {
+			t.Errorf("%s: SearchMessages: %v", name, err)
+			continue
+		}
+		if got := searchIDs(results); got != tc.want {
+			t.Errorf("%s: matched %q, want %q", name, got, tc.want)
+		}
+	}
+
+	// Merging threads moves events; the index follows without reindexing.
+	if _, err := s.db.ExecContext(ctx, `UPDATE ledger_events SET thread_id = 't1' WHERE event_id = 'e2'`); err != nil {
+		t.Fatalf("moving event: %v", err)
+	}
+	results, err = s.SearchMessages(ctx, "rollback", SearchFilter{ThreadID: "t1"})
+	if err != nil || searchIDs(results) != "e2" {
+		t.Errorf("after move matched %q (err %v), want e2", searchIDs(results), err)
+	}
+}
+
+func TestSearchMessages_BackfillsExistingEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "search.db")
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	saveSearchEvent(t, s, "old", "agent-a", "t1", "written before search existed", time.Now())
+	// Simulate a database from before the index was added.
+	if _, err := s.db.Exec(`DROP TABLE ledger_events_fts; DROP TRIGGER ledger_events_fts_insert; DROP TRIGGER ledger_events_fts_delete; DROP TRIGGER ledger_events_fts_update`); err != nil {
+		t.Fatalf("dropping index: %v", err)
+	}
+	_ = s.Close()
+
+	s, err = NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	defer s.Close()
+	results, err := s.SearchMessages(context.Background(), "before", SearchFilter{})
+	if err != nil || searchIDs(results) != "old" {
+		t.Errorf("after upgrade matched %q (err %v), want old", searchIDs(results), err)
+	}
+}
+
+func TestSearchMessages_Unavailable(t *testing.T) {
+	s := newTestStore(t)
+	s.searchEnabled = false
+	if _, err := s.SearchMessages(context.Background(), "anything", SearchFilter{}); !errors.Is(err, ErrSearchUnavailable) {
+		t.Errorf("err = %v, want ErrSearchUnavailable", err)
+	}
+}
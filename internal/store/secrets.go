@@ -37,7 +37,7 @@ type SecretsStore interface {
 
 // CreateSecret creates a new secret in the database.
 // Returns an error if a secret with the same key and scope already exists.
-func (s *SQLiteStore) CreateSecret(ctx context.Context, secret *Secret) error {
+func (s *SQLStore) CreateSecret(ctx context.Context, secret *Secret) error {
 	if secret.ID == "" {
 		secret.ID = uuid.New().String()
 	}
@@ -54,7 +54,13 @@ func (s *SQLiteStore) CreateSecret(ctx context.Context, secret *Secret) error {
 		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.ExecContext(ctx, query,
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, query,
 		secret.ID,
 		secret.Key,
 		secret.Value,
@@ -70,13 +76,17 @@ func (s *SQLiteStore) CreateSecret(ctx context.Context, secret *Secret) error {
 		return fmt.Errorf("inserting secret: %w", err)
 	}
 
+	if err := commitAuditedTx(ctx, tx); err != nil {
+		return err
+	}
+
 	s.logger.Debug("created secret", "id", secret.ID, "key", secret.Key, "agent_id", secret.AgentID)
 	return nil
 }
 
 // GetSecret retrieves a secret by ID.
 // Returns ErrNotFound if the secret doesn't exist.
-func (s *SQLiteStore) GetSecret(ctx context.Context, id string) (*Secret, error) {
+func (s *SQLStore) GetSecret(ctx context.Context, id string) (*Secret, error) {
 	query := `
 		SELECT id, key, value, agent_id, created_at, updated_at, created_by
 		FROM secrets
@@ -125,7 +135,7 @@ func (s *SQLiteStore) GetSecret(ctx context.Context, id string) (*Secret, error)
 
 // UpdateSecret updates an existing secret's value.
 // Returns ErrNotFound if the secret doesn't exist.
-func (s *SQLiteStore) UpdateSecret(ctx context.Context, secret *Secret) error {
+func (s *SQLStore) UpdateSecret(ctx context.Context, secret *Secret) error {
 	secret.UpdatedAt = time.Now().UTC()
 
 	query := `
@@ -134,7 +144,13 @@ func (s *SQLiteStore) UpdateSecret(ctx context.Context, secret *Secret) error {
 		WHERE id = ?
 	`
 
-	result, err := s.db.ExecContext(ctx, query,
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, query,
 		secret.Value,
 		secret.UpdatedAt.Format(time.RFC3339),
 		secret.ID,
@@ -151,16 +167,26 @@ func (s *SQLiteStore) UpdateSecret(ctx context.Context, secret *Secret) error {
 		return ErrNotFound
 	}
 
+	if err := commitAuditedTx(ctx, tx); err != nil {
+		return err
+	}
+
 	s.logger.Debug("updated secret", "id", secret.ID, "key", secret.Key)
 	return nil
 }
 
 // DeleteSecret removes a secret by ID.
 // Returns ErrNotFound if the secret doesn't exist.
-func (s *SQLiteStore) DeleteSecret(ctx context.Context, id string) error {
+func (s *SQLStore) DeleteSecret(ctx context.Context, id string) error {
 	query := `DELETE FROM secrets WHERE id = ?`
 
-	result, err := s.db.ExecContext(ctx, query, id)
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("deleting secret: %w", err)
 	}
@@ -173,13 +199,17 @@ func (s *SQLiteStore) DeleteSecret(ctx context.Context, id string) error {
 		return ErrNotFound
 	}
 
+	if err := commitAuditedTx(ctx, tx); err != nil {
+		return err
+	}
+
 	s.logger.Debug("deleted secret", "id", id)
 	return nil
 }
 
 // ListAllSecrets returns all secrets (both global and agent-specific).
 // Secrets are ordered by key, then by scope (global first, then agent-specific).
-func (s *SQLiteStore) ListAllSecrets(ctx context.Context) ([]*Secret, error) {
+func (s *SQLStore) ListAllSecrets(ctx context.Context) ([]*Secret, error) {
 	query := `
 		SELECT id, key, value, agent_id, created_at, updated_at, created_by
 		FROM secrets
@@ -240,7 +270,7 @@ func (s *SQLiteStore) ListAllSecrets(ctx context.Context) ([]*Secret, error) {
 // GetEffectiveSecrets returns the resolved secrets for a specific agent.
 // Agent-specific overrides take precedence over global defaults.
 // Returns a map of key -> value suitable for setting as environment variables.
-func (s *SQLiteStore) GetEffectiveSecrets(ctx context.Context, agentID string) (map[string]string, error) {
+func (s *SQLStore) GetEffectiveSecrets(ctx context.Context, agentID string) (map[string]string, error) {
 	// Query all secrets relevant to this agent (global + agent-specific)
 	// Use a CTE to merge and resolve overrides
 	query := `
@@ -284,5 +314,5 @@ func ptrToString(s *string) string {
 	return *s
 }
 
-// Ensure SQLiteStore implements SecretsStore.
-var _ SecretsStore = (*SQLiteStore)(nil)
+// Ensure SQLStore implements SecretsStore.
+var _ SecretsStore = (*SQLStore)(nil)
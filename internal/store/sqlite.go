@@ -1,5 +1,5 @@
-// ABOUTME: SQLite implementation of the Store interface using modernc.org/sqlite
-// ABOUTME: Provides thread/message persistence with automatic schema creation
+// ABOUTME: SQLite backend of SQLStore using modernc.org/sqlite, plus the thread/message methods
+// ABOUTME: Creates the schema and runs the SQLite migrations when the store is opened
 
 package store
 
@@ -17,16 +17,10 @@ import (
 	_ "modernc.org/sqlite"
 )
 
-// SQLiteStore implements the Store interface using SQLite.
-type SQLiteStore struct {
-	db     *sql.DB
-	logger *slog.Logger
-}
-
-// NewSQLiteStore creates a new SQLite store at the given path.
+// NewSQLiteStore opens the SQLite database at the given path.
 // The schema is automatically created if it doesn't exist.
 // Parent directories are created if needed.
-func NewSQLiteStore(path string) (*SQLiteStore, error) {
+func NewSQLiteStore(path string) (*SQLStore, error) {
 	logger := slog.Default().With("component", "store")
 
 	// Ensure parent directory exists
@@ -35,7 +29,13 @@ func NewSQLiteStore(path string) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("creating database directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", path)
+	// Writers wait for each other instead of failing with SQLITE_BUSY; the
+	// pragma is in the DSN so every pooled connection gets it.
+	dsn := path
+	if path != ":memory:" {
+		dsn += "?_pragma=busy_timeout(5000)"
+	}
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
@@ -52,9 +52,10 @@ func NewSQLiteStore(path string) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("enabling foreign keys: %w", err)
 	}
 
-	s := &SQLiteStore{
-		db:     db,
-		logger: logger,
+	s := &SQLStore{
+		db:      &timedDB{DB: db},
+		dialect: dialectSQLite,
+		logger:  logger,
 	}
 
 	if err := s.createSchema(); err != nil {
@@ -67,6 +68,12 @@ func NewSQLiteStore(path string) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("running migrations: %w", err)
 	}
 
+	// After migrations, so the first build indexes migrated messages too.
+	if err := s.initSearch(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
 	logger.Info("SQLite store initialized", "path", path)
 	return s, nil
 }
@@ -74,7 +81,7 @@ func NewSQLiteStore(path string) (*SQLiteStore, error) {
 // Schema segments split for maintainability.
 var (
 	schemaCoreSQL = `
-CREATE TABLE IF NOT EXISTS threads (id TEXT PRIMARY KEY, frontend_name TEXT NOT NULL, external_id TEXT NOT NULL, agent_id TEXT NOT NULL, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL);
+CREATE TABLE IF NOT EXISTS threads (id TEXT PRIMARY KEY, frontend_name TEXT NOT NULL, external_id TEXT NOT NULL, agent_id TEXT NOT NULL, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL, merged_into TEXT, split_from TEXT, archived INTEGER NOT NULL DEFAULT 0, pinned INTEGER NOT NULL DEFAULT 0);
 CREATE UNIQUE INDEX IF NOT EXISTS idx_threads_frontend_external ON threads(frontend_name, external_id);
 CREATE TABLE IF NOT EXISTS messages (id TEXT PRIMARY KEY, thread_id TEXT NOT NULL, sender TEXT NOT NULL, content TEXT NOT NULL, type TEXT NOT NULL DEFAULT 'message', tool_name TEXT, tool_id TEXT, created_at DATETIME NOT NULL, FOREIGN KEY (thread_id) REFERENCES threads(id));
 CREATE INDEX IF NOT EXISTS idx_messages_thread_id ON messages(thread_id);
@@ -83,57 +90,71 @@ CREATE TABLE IF NOT EXISTS agent_state (agent_id TEXT PRIMARY KEY, state BLOB NO
 CREATE TABLE IF NOT EXISTS channel_bindings (frontend TEXT NOT NULL, channel_id TEXT NOT NULL, agent_id TEXT NOT NULL, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL, PRIMARY KEY (frontend, channel_id));
 `
 	schemaAuthSQL = `
-CREATE TABLE IF NOT EXISTS principals (principal_id TEXT PRIMARY KEY, type TEXT NOT NULL, pubkey_fingerprint TEXT NOT NULL UNIQUE, display_name TEXT NOT NULL, status TEXT NOT NULL, created_at TEXT NOT NULL, last_seen TEXT, metadata_json TEXT, CHECK (type IN ('client', 'agent', 'pack')), CHECK (status IN ('pending', 'approved', 'revoked', 'offline', 'online')));
+CREATE TABLE IF NOT EXISTS principals (principal_id TEXT PRIMARY KEY, type TEXT NOT NULL, pubkey_fingerprint TEXT NOT NULL UNIQUE, display_name TEXT NOT NULL, status TEXT NOT NULL, created_at TEXT NOT NULL, last_seen TEXT, metadata_json TEXT, paused_at TEXT, paused_by TEXT, tags TEXT, CHECK (type IN ('client', 'agent', 'pack')), CHECK (status IN ('pending', 'approved', 'revoked', 'offline', 'online')));
 CREATE INDEX IF NOT EXISTS idx_principals_status ON principals(status);
 CREATE INDEX IF NOT EXISTS idx_principals_type ON principals(type);
 CREATE INDEX IF NOT EXISTS idx_principals_pubkey ON principals(pubkey_fingerprint);
 CREATE TABLE IF NOT EXISTS roles (subject_type TEXT NOT NULL, subject_id TEXT NOT NULL, role TEXT NOT NULL, created_at TEXT NOT NULL, PRIMARY KEY (subject_type, subject_id, role), CHECK (subject_type IN ('principal', 'member')), CHECK (role IN ('owner', 'admin', 'member', 'leader')));
 CREATE INDEX IF NOT EXISTS idx_roles_subject ON roles(subject_type, subject_id);
-CREATE TABLE IF NOT EXISTS audit_log (audit_id TEXT PRIMARY KEY, actor_principal_id TEXT NOT NULL, actor_member_id TEXT, action TEXT NOT NULL, target_type TEXT NOT NULL, target_id TEXT NOT NULL, ts TEXT NOT NULL, detail_json TEXT, CHECK (action IN ('approve_principal', 'revoke_principal', 'grant_capability', 'revoke_capability', 'create_binding', 'update_binding', 'delete_binding', 'create_token', 'create_principal', 'delete_principal')));
+CREATE TABLE IF NOT EXISTS principal_capabilities (principal_id TEXT NOT NULL, capability TEXT NOT NULL, granted_by TEXT, created_at TEXT NOT NULL, PRIMARY KEY (principal_id, capability));
+CREATE TABLE IF NOT EXISTS tool_overrides (principal_id TEXT NOT NULL, tool_name TEXT NOT NULL, enabled INTEGER NOT NULL DEFAULT 1, reason TEXT NOT NULL DEFAULT '', updated_by TEXT, updated_at TEXT NOT NULL, PRIMARY KEY (principal_id, tool_name));
+CREATE TABLE IF NOT EXISTS audit_log (audit_id TEXT PRIMARY KEY, actor_principal_id TEXT NOT NULL, actor_member_id TEXT, action TEXT NOT NULL, target_type TEXT NOT NULL, target_id TEXT NOT NULL, ts TEXT NOT NULL, detail_json TEXT, source_ip TEXT, CHECK (action IN ('approve_principal', 'revoke_principal', 'grant_capability', 'revoke_capability', 'create_binding', 'update_binding', 'delete_binding', 'create_token', 'create_principal', 'delete_principal', 'merge_threads', 'split_thread', 'pause_agent', 'resume_agent', 'update_feature_flag', 'answer_question', 'revoke_token', 'create_secret', 'update_secret', 'delete_secret', 'create_invite', 'create_tool_policy', 'update_tool_policy', 'delete_tool_policy', 'revoke_session', 'add_project_member', 'remove_project_member', 'add_delegation_target', 'remove_delegation_target', 'deny_admin_call', 'clear_login_lockout', 'set_tool_override')));
 CREATE INDEX IF NOT EXISTS idx_audit_ts ON audit_log(ts DESC);
 CREATE INDEX IF NOT EXISTS idx_audit_actor ON audit_log(actor_principal_id);
 CREATE INDEX IF NOT EXISTS idx_audit_target ON audit_log(target_type, target_id);
+CREATE TABLE IF NOT EXISTS api_tokens (token_id TEXT PRIMARY KEY, principal_id TEXT NOT NULL, scopes TEXT NOT NULL, created_by TEXT, created_at TEXT NOT NULL, expires_at TEXT NOT NULL, last_used_at TEXT, revoked_at TEXT, revoked_by TEXT, agent_ids TEXT, capabilities TEXT);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_principal ON api_tokens(principal_id, created_at);
 `
 	schemaLedgerSQL = `
-CREATE TABLE IF NOT EXISTS ledger_events (event_id TEXT PRIMARY KEY, conversation_key TEXT NOT NULL, thread_id TEXT, direction TEXT NOT NULL, author TEXT NOT NULL, timestamp TEXT NOT NULL, type TEXT NOT NULL, text TEXT, raw_transport TEXT, raw_payload_ref TEXT, actor_principal_id TEXT, actor_member_id TEXT, CHECK (direction IN ('inbound_to_agent', 'outbound_from_agent')), CHECK (type IN ('message', 'tool_call', 'tool_result', 'system', 'error')));
+CREATE TABLE IF NOT EXISTS ledger_events (event_id TEXT PRIMARY KEY, conversation_key TEXT NOT NULL, thread_id TEXT, direction TEXT NOT NULL, author TEXT NOT NULL, timestamp TEXT NOT NULL, type TEXT NOT NULL, text TEXT, raw_transport TEXT, raw_payload_ref TEXT, actor_principal_id TEXT, actor_member_id TEXT, channel_context_hash TEXT, parent_request_id TEXT, CHECK (direction IN ('inbound_to_agent', 'outbound_from_agent')), CHECK (type IN ('message', 'tool_call', 'tool_result', 'system', 'error', 'plan', 'citation', 'tool_check', 'capability_warning', 'tool_policy', 'agent_health')));
 CREATE INDEX IF NOT EXISTS idx_ledger_conversation ON ledger_events(conversation_key, timestamp);
 CREATE INDEX IF NOT EXISTS idx_ledger_actor ON ledger_events(actor_principal_id);
 CREATE INDEX IF NOT EXISTS idx_ledger_timestamp ON ledger_events(timestamp);
 CREATE INDEX IF NOT EXISTS idx_ledger_thread ON ledger_events(thread_id) WHERE thread_id IS NOT NULL;
-CREATE TABLE IF NOT EXISTS bindings (binding_id TEXT PRIMARY KEY, frontend TEXT NOT NULL, channel_id TEXT NOT NULL, agent_id TEXT NOT NULL, working_dir TEXT, created_at TEXT NOT NULL, created_by TEXT, UNIQUE(frontend, channel_id));
+CREATE TABLE IF NOT EXISTS bindings (binding_id TEXT PRIMARY KEY, frontend TEXT NOT NULL, channel_id TEXT NOT NULL, agent_id TEXT NOT NULL, working_dir TEXT, created_at TEXT NOT NULL, created_by TEXT, max_response_seconds INTEGER, fallback_agents TEXT, workspace TEXT, context TEXT, UNIQUE(frontend, channel_id));
 CREATE INDEX IF NOT EXISTS idx_bindings_frontend ON bindings(frontend);
 CREATE INDEX IF NOT EXISTS idx_bindings_agent ON bindings(agent_id);
 `
 	schemaAdminSQL = `
-CREATE TABLE IF NOT EXISTS admin_users (id TEXT PRIMARY KEY, username TEXT UNIQUE NOT NULL, password_hash TEXT, display_name TEXT NOT NULL, created_at TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS admin_users (id TEXT PRIMARY KEY, username TEXT UNIQUE NOT NULL, password_hash TEXT, display_name TEXT NOT NULL, created_at TEXT NOT NULL, principal_id TEXT);
 CREATE INDEX IF NOT EXISTS idx_admin_users_username ON admin_users(username);
-CREATE TABLE IF NOT EXISTS admin_sessions (id TEXT PRIMARY KEY, user_id TEXT NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE, created_at TEXT NOT NULL, expires_at TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS admin_sessions (id TEXT PRIMARY KEY, user_id TEXT NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE, created_at TEXT NOT NULL, expires_at TEXT NOT NULL, last_seen_at TEXT, user_agent TEXT, ip TEXT);
 CREATE INDEX IF NOT EXISTS idx_admin_sessions_user ON admin_sessions(user_id);
 CREATE INDEX IF NOT EXISTS idx_admin_sessions_expires ON admin_sessions(expires_at);
+CREATE TABLE IF NOT EXISTS login_attempts (username TEXT NOT NULL, ip TEXT NOT NULL, failures INTEGER NOT NULL DEFAULT 0, window_start TEXT NOT NULL, lockouts INTEGER NOT NULL DEFAULT 0, locked_until TEXT, updated_at TEXT NOT NULL, PRIMARY KEY (username, ip));
+CREATE INDEX IF NOT EXISTS idx_login_attempts_locked ON login_attempts(locked_until);
+CREATE TABLE IF NOT EXISTS idempotency_keys (principal_id TEXT NOT NULL, idem_key TEXT NOT NULL, request_id TEXT NOT NULL DEFAULT '', created_at TEXT NOT NULL, PRIMARY KEY (principal_id, idem_key));
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(created_at);
 CREATE TABLE IF NOT EXISTS admin_invites (id TEXT PRIMARY KEY, created_by TEXT REFERENCES admin_users(id), created_at TEXT NOT NULL, expires_at TEXT NOT NULL, used_at TEXT, used_by TEXT REFERENCES admin_users(id));
 CREATE INDEX IF NOT EXISTS idx_admin_invites_expires ON admin_invites(expires_at);
-CREATE TABLE IF NOT EXISTS link_codes (id TEXT PRIMARY KEY, code TEXT UNIQUE NOT NULL, fingerprint TEXT NOT NULL, device_name TEXT NOT NULL, status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'expired')), created_at TEXT NOT NULL, expires_at TEXT NOT NULL, approved_by TEXT REFERENCES admin_users(id), approved_at TEXT, principal_id TEXT REFERENCES principals(principal_id), token TEXT);
+CREATE TABLE IF NOT EXISTS link_codes (id TEXT PRIMARY KEY, code TEXT UNIQUE NOT NULL, fingerprint TEXT NOT NULL, device_name TEXT NOT NULL, status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'expired')), created_at TEXT NOT NULL, expires_at TEXT NOT NULL, approved_by TEXT REFERENCES admin_users(id), approved_at TEXT, principal_id TEXT REFERENCES principals(principal_id), token TEXT, platform TEXT NOT NULL DEFAULT '');
 CREATE INDEX IF NOT EXISTS idx_link_codes_code ON link_codes(code);
 CREATE INDEX IF NOT EXISTS idx_link_codes_expires ON link_codes(expires_at);
 CREATE INDEX IF NOT EXISTS idx_link_codes_status ON link_codes(status);
 CREATE TABLE IF NOT EXISTS webauthn_credentials (id TEXT PRIMARY KEY, user_id TEXT NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE, credential_id BLOB UNIQUE NOT NULL, public_key BLOB NOT NULL, attestation_type TEXT, transports TEXT, sign_count INTEGER DEFAULT 0, created_at TEXT NOT NULL);
 CREATE INDEX IF NOT EXISTS idx_webauthn_user ON webauthn_credentials(user_id);
+CREATE TABLE IF NOT EXISTS admin_oidc_identities (issuer TEXT NOT NULL, subject TEXT NOT NULL, user_id TEXT NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE, email TEXT, created_at TEXT NOT NULL, PRIMARY KEY (issuer, subject));
+CREATE INDEX IF NOT EXISTS idx_admin_oidc_user ON admin_oidc_identities(user_id);
 `
 	schemaToolsSQL = `
 CREATE TABLE IF NOT EXISTS log_entries (id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, message TEXT NOT NULL, tags TEXT, created_at TEXT NOT NULL);
 CREATE INDEX IF NOT EXISTS idx_log_entries_agent ON log_entries(agent_id);
 CREATE INDEX IF NOT EXISTS idx_log_entries_created ON log_entries(created_at);
-CREATE TABLE IF NOT EXISTS todos (id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, description TEXT NOT NULL, status TEXT DEFAULT 'pending', priority TEXT DEFAULT 'medium', notes TEXT, due_date TEXT, created_at TEXT NOT NULL, updated_at TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS todos (id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, description TEXT NOT NULL, status TEXT DEFAULT 'pending', priority TEXT DEFAULT 'medium', notes TEXT, due_date TEXT, assignee_agent_id TEXT, project TEXT, created_at TEXT NOT NULL, updated_at TEXT NOT NULL);
 CREATE INDEX IF NOT EXISTS idx_todos_agent ON todos(agent_id);
 CREATE INDEX IF NOT EXISTS idx_todos_status ON todos(status);
-CREATE TABLE IF NOT EXISTS bbs_posts (id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, thread_id TEXT, subject TEXT, content TEXT NOT NULL, created_at TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS project_members (project TEXT NOT NULL, agent_id TEXT NOT NULL, added_at TEXT NOT NULL, PRIMARY KEY (project, agent_id));
+CREATE INDEX IF NOT EXISTS idx_project_members_agent ON project_members(agent_id);
+CREATE TABLE IF NOT EXISTS delegation_targets (agent_id TEXT NOT NULL, target_agent_id TEXT NOT NULL, added_at TEXT NOT NULL, PRIMARY KEY (agent_id, target_agent_id));
+CREATE TABLE IF NOT EXISTS bbs_posts (id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, thread_id TEXT, subject TEXT, content TEXT NOT NULL, created_at TEXT NOT NULL, author_kind TEXT NOT NULL DEFAULT 'agent', author_name TEXT, pinned INTEGER NOT NULL DEFAULT 0);
 CREATE INDEX IF NOT EXISTS idx_bbs_posts_thread ON bbs_posts(thread_id);
 CREATE INDEX IF NOT EXISTS idx_bbs_posts_created ON bbs_posts(created_at);
-CREATE TABLE IF NOT EXISTS agent_mail (id TEXT PRIMARY KEY, from_agent_id TEXT NOT NULL, to_agent_id TEXT NOT NULL, subject TEXT NOT NULL, content TEXT NOT NULL, read_at TEXT, created_at TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS agent_mail (id TEXT PRIMARY KEY, from_agent_id TEXT NOT NULL, to_agent_id TEXT NOT NULL, subject TEXT NOT NULL, content TEXT NOT NULL, read_at TEXT, created_at TEXT NOT NULL, in_reply_to TEXT, thread_id TEXT);
 CREATE INDEX IF NOT EXISTS idx_agent_mail_to ON agent_mail(to_agent_id);
 CREATE INDEX IF NOT EXISTS idx_agent_mail_unread ON agent_mail(to_agent_id, read_at);
-CREATE TABLE IF NOT EXISTS agent_notes (id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, key TEXT NOT NULL, value TEXT NOT NULL, created_at TEXT NOT NULL, updated_at TEXT NOT NULL, UNIQUE(agent_id, key));
+CREATE TABLE IF NOT EXISTS agent_notes (id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, key TEXT NOT NULL, value TEXT NOT NULL, created_at TEXT NOT NULL, updated_at TEXT NOT NULL, expires_at TEXT, UNIQUE(agent_id, key));
 CREATE INDEX IF NOT EXISTS idx_agent_notes_agent ON agent_notes(agent_id);
+CREATE TABLE IF NOT EXISTS builtin_write_quota (agent_id TEXT NOT NULL, kind TEXT NOT NULL, day TEXT NOT NULL, count INTEGER NOT NULL DEFAULT 0, PRIMARY KEY (agent_id, kind, day));
 `
 	schemaUsageSQL = `
 CREATE TABLE IF NOT EXISTS message_usage (id TEXT PRIMARY KEY, thread_id TEXT NOT NULL, message_id TEXT, request_id TEXT NOT NULL, agent_id TEXT NOT NULL, input_tokens INTEGER NOT NULL DEFAULT 0, output_tokens INTEGER NOT NULL DEFAULT 0, cache_read_tokens INTEGER NOT NULL DEFAULT 0, cache_write_tokens INTEGER NOT NULL DEFAULT 0, thinking_tokens INTEGER NOT NULL DEFAULT 0, created_at TEXT NOT NULL, FOREIGN KEY (thread_id) REFERENCES threads(id));
@@ -141,16 +162,66 @@ CREATE INDEX IF NOT EXISTS idx_message_usage_thread ON message_usage(thread_id);
 CREATE INDEX IF NOT EXISTS idx_message_usage_agent ON message_usage(agent_id);
 CREATE INDEX IF NOT EXISTS idx_message_usage_created ON message_usage(created_at);
 CREATE INDEX IF NOT EXISTS idx_message_usage_request ON message_usage(request_id);
+CREATE INDEX IF NOT EXISTS idx_message_usage_agent_window ON message_usage(agent_id, created_at, thread_id, input_tokens, output_tokens, cache_read_tokens, cache_write_tokens, thinking_tokens);
+CREATE INDEX IF NOT EXISTS idx_message_usage_thread_window ON message_usage(thread_id, created_at);
+CREATE TABLE IF NOT EXISTS usage_agent_hourly (agent_id TEXT NOT NULL, hour TEXT NOT NULL, input_tokens INTEGER NOT NULL DEFAULT 0, output_tokens INTEGER NOT NULL DEFAULT 0, cache_read_tokens INTEGER NOT NULL DEFAULT 0, cache_write_tokens INTEGER NOT NULL DEFAULT 0, thinking_tokens INTEGER NOT NULL DEFAULT 0, request_count INTEGER NOT NULL DEFAULT 0, last_at TEXT NOT NULL, PRIMARY KEY (agent_id, hour));
 CREATE TABLE IF NOT EXISTS secrets (id TEXT PRIMARY KEY, key TEXT NOT NULL, value TEXT NOT NULL, agent_id TEXT, created_at TEXT NOT NULL, updated_at TEXT NOT NULL, created_by TEXT);
 CREATE UNIQUE INDEX IF NOT EXISTS idx_secrets_unique_global ON secrets(key) WHERE agent_id IS NULL;
 CREATE UNIQUE INDEX IF NOT EXISTS idx_secrets_unique_agent ON secrets(key, agent_id) WHERE agent_id IS NOT NULL;
 CREATE INDEX IF NOT EXISTS idx_secrets_agent ON secrets(agent_id);
+`
+	schemaDeliverySQL = `
+CREATE TABLE IF NOT EXISTS deliveries (request_id TEXT PRIMARY KEY, thread_id TEXT NOT NULL, agent_id TEXT NOT NULL, frontend TEXT NOT NULL, channel_id TEXT, status TEXT NOT NULL, error TEXT, error_code TEXT, created_at TEXT NOT NULL, responded_at TEXT, resolved_at TEXT, CHECK (status IN ('pending', 'acked', 'nacked', 'expired')));
+CREATE INDEX IF NOT EXISTS idx_deliveries_status ON deliveries(status, responded_at);
+CREATE INDEX IF NOT EXISTS idx_deliveries_frontend ON deliveries(frontend, created_at);
+`
+	schemaToolCatalogSQL = `
+CREATE TABLE IF NOT EXISTS tool_snapshots (pack_id TEXT NOT NULL, tool_name TEXT NOT NULL, definition TEXT NOT NULL, updated_at TEXT NOT NULL, PRIMARY KEY (pack_id, tool_name));
+CREATE TABLE IF NOT EXISTS tool_changes (id INTEGER PRIMARY KEY AUTOINCREMENT, catalog_version INTEGER NOT NULL, pack_id TEXT NOT NULL, pack_version TEXT, tool_name TEXT NOT NULL, change_type TEXT NOT NULL, diff TEXT, created_at TEXT NOT NULL, CHECK (change_type IN ('added', 'removed', 'modified')));
+CREATE INDEX IF NOT EXISTS idx_tool_changes_pack ON tool_changes(pack_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_tool_changes_created ON tool_changes(created_at);
+`
+	schemaSessionsSQL = `
+CREATE TABLE IF NOT EXISTS agent_sessions (session_id TEXT PRIMARY KEY, token_hash TEXT NOT NULL UNIQUE, agent_id TEXT NOT NULL, principal_id TEXT, protocol_features TEXT NOT NULL, acked_request_ids TEXT NOT NULL, created_at TEXT NOT NULL, last_seen_at TEXT NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_agent_sessions_principal ON agent_sessions(principal_id);
+CREATE INDEX IF NOT EXISTS idx_agent_sessions_last_seen ON agent_sessions(last_seen_at);
+CREATE TABLE IF NOT EXISTS agent_inflight_requests (request_id TEXT PRIMARY KEY, session_id TEXT NOT NULL, agent_id TEXT NOT NULL, thread_id TEXT NOT NULL, sender TEXT NOT NULL, content TEXT NOT NULL, started_at TEXT NOT NULL, workspace TEXT, channel_context TEXT);
+CREATE INDEX IF NOT EXISTS idx_agent_inflight_session ON agent_inflight_requests(session_id, started_at);
+`
+	schemaEmailSQL = `
+CREATE TABLE IF NOT EXISTS email_messages (message_id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, from_addr TEXT NOT NULL, to_addr TEXT NOT NULL, subject TEXT, spf TEXT NOT NULL, dkim TEXT NOT NULL, auth_details TEXT, received_at TEXT NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_email_messages_agent ON email_messages(agent_id, received_at);
+`
+	schemaFlagsSQL = `
+CREATE TABLE IF NOT EXISTS feature_flags (name TEXT PRIMARY KEY, enabled INTEGER NOT NULL DEFAULT 0, percentage INTEGER, principals TEXT, updated_at TEXT NOT NULL, updated_by TEXT, CHECK (percentage IS NULL OR (percentage >= 0 AND percentage <= 100)));
+`
+	schemaAttachmentsSQL = `
+CREATE TABLE IF NOT EXISTS attachments (id TEXT PRIMARY KEY, thread_id TEXT NOT NULL, message_id TEXT, filename TEXT NOT NULL, content_type TEXT NOT NULL, size INTEGER NOT NULL, data BLOB NOT NULL, uploaded_by TEXT, created_at TEXT NOT NULL);
+CREATE INDEX IF NOT EXISTS idx_attachments_thread ON attachments(thread_id, created_at);
+`
+	schemaSchedulesSQL = `
+CREATE TABLE IF NOT EXISTS scheduled_messages (id TEXT PRIMARY KEY, agent_id TEXT, frontend TEXT, channel_id TEXT, sender TEXT NOT NULL, content TEXT NOT NULL, run_at TEXT NOT NULL, recurrence TEXT, created_by TEXT, status TEXT NOT NULL, attempts INTEGER NOT NULL DEFAULT 0, last_error TEXT, last_thread_id TEXT, last_run_at TEXT, created_at TEXT NOT NULL, updated_at TEXT NOT NULL, CHECK (status IN ('pending', 'done', 'errored')));
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due ON scheduled_messages(status, run_at);
+`
+	schemaAgentGroupsSQL = `
+CREATE TABLE IF NOT EXISTS agent_groups (name TEXT PRIMARY KEY, created_at TEXT NOT NULL, updated_at TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS agent_group_members (group_name TEXT NOT NULL REFERENCES agent_groups(name) ON DELETE CASCADE, principal_id TEXT NOT NULL REFERENCES principals(principal_id) ON DELETE CASCADE, PRIMARY KEY (group_name, principal_id));
+CREATE INDEX IF NOT EXISTS idx_agent_group_members_principal ON agent_group_members(principal_id);
+`
+	schemaToolPoliciesSQL = `
+CREATE TABLE IF NOT EXISTS tool_policies (id TEXT PRIMARY KEY, position INTEGER NOT NULL, agent_id TEXT, group_name TEXT, tool_pattern TEXT NOT NULL, action TEXT NOT NULL, matchers TEXT, description TEXT, created_at TEXT NOT NULL, updated_at TEXT NOT NULL, created_by TEXT, CHECK (action IN ('allow', 'deny', 'ask')));
+CREATE INDEX IF NOT EXISTS idx_tool_policies_position ON tool_policies(position);
+CREATE TABLE IF NOT EXISTS tool_policy_settings (id INTEGER PRIMARY KEY CHECK (id = 1), default_action TEXT NOT NULL, updated_at TEXT NOT NULL, updated_by TEXT, CHECK (default_action IN ('allow', 'deny', 'ask')));
+`
+	schemaQuestionsSQL = `
+CREATE TABLE IF NOT EXISTS questions (id TEXT PRIMARY KEY, agent_id TEXT NOT NULL, question TEXT NOT NULL, header TEXT, options TEXT NOT NULL, multi_select INTEGER NOT NULL DEFAULT 0, timeout_seconds INTEGER NOT NULL, context_json TEXT, allow_free_text INTEGER, pattern TEXT, max_length INTEGER, status TEXT NOT NULL, asked_at TEXT NOT NULL, expires_at TEXT NOT NULL, resolved_at TEXT, CHECK (status IN ('pending', 'answered', 'expired')));
+CREATE INDEX IF NOT EXISTS idx_questions_pending ON questions(status, agent_id, asked_at);
 `
 )
 
 // createSchema creates the database tables if they don't exist.
-func (s *SQLiteStore) createSchema() error {
-	schemas := []string{schemaCoreSQL, schemaAuthSQL, schemaLedgerSQL, schemaAdminSQL, schemaToolsSQL, schemaUsageSQL}
+func (s *SQLStore) createSchema() error {
+	schemas := []string{schemaCoreSQL, schemaAuthSQL, schemaLedgerSQL, schemaAdminSQL, schemaToolsSQL, schemaUsageSQL, schemaDeliverySQL, schemaToolCatalogSQL, schemaSessionsSQL, schemaEmailSQL, schemaFlagsSQL, schemaAttachmentsSQL, schemaSchedulesSQL, schemaAgentGroupsSQL, schemaToolPoliciesSQL, schemaQuestionsSQL}
 	for _, sql := range schemas {
 		if _, err := s.db.Exec(sql); err != nil {
 			return err
@@ -168,7 +239,7 @@ type columnMigration struct {
 }
 
 // applyColumnMigration applies a single column migration if needed.
-func (s *SQLiteStore) applyColumnMigration(m columnMigration) error {
+func (s *SQLStore) applyColumnMigration(m columnMigration) error {
 	var exists int
 	if err := s.db.QueryRow(m.check).Scan(&exists); err == nil {
 		return nil // Column already exists
@@ -181,7 +252,7 @@ func (s *SQLiteStore) applyColumnMigration(m columnMigration) error {
 }
 
 // migrateThreadIDColumn adds thread_id to ledger_events with its index.
-func (s *SQLiteStore) migrateThreadIDColumn() error {
+func (s *SQLStore) migrateThreadIDColumn() error {
 	migration := columnMigration{
 		check:  `SELECT 1 FROM pragma_table_info('ledger_events') WHERE name = 'thread_id'`,
 		apply:  `ALTER TABLE ledger_events ADD COLUMN thread_id TEXT`,
@@ -202,45 +273,118 @@ func (s *SQLiteStore) migrateThreadIDColumn() error {
 	return nil
 }
 
-// runMigrations applies schema migrations for existing databases.
-// These are idempotent - safe to run multiple times.
-func (s *SQLiteStore) runMigrations() error {
-	// Migration: Add tool-related columns to messages table
-	messageMigrations := []columnMigration{
-		{`SELECT 1 FROM pragma_table_info('messages') WHERE name = 'type'`, `ALTER TABLE messages ADD COLUMN type TEXT NOT NULL DEFAULT 'message'`, "type", "messages"},
-		{`SELECT 1 FROM pragma_table_info('messages') WHERE name = 'tool_name'`, `ALTER TABLE messages ADD COLUMN tool_name TEXT`, "tool_name", "messages"},
-		{`SELECT 1 FROM pragma_table_info('messages') WHERE name = 'tool_id'`, `ALTER TABLE messages ADD COLUMN tool_id TEXT`, "tool_id", "messages"},
-		{`SELECT 1 FROM pragma_table_info('bindings') WHERE name = 'working_dir'`, `ALTER TABLE bindings ADD COLUMN working_dir TEXT`, "working_dir", "bindings"},
+// migrateBBSIndexes indexes the board listing, pinned threads first, and
+// reply pages, after the pinned column may have just been added.
+func (s *SQLStore) migrateBBSIndexes() error {
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_bbs_posts_board ON bbs_posts(pinned, created_at) WHERE thread_id IS NULL`); err != nil {
+		return fmt.Errorf("creating idx_bbs_posts_board index: %w", err)
 	}
-
-	for _, m := range messageMigrations {
-		if err := s.applyColumnMigration(m); err != nil {
-			return err
-		}
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_bbs_posts_replies ON bbs_posts(thread_id, created_at, id)`); err != nil {
+		return fmt.Errorf("creating idx_bbs_posts_replies index: %w", err)
 	}
+	return nil
+}
 
-	if err := s.migrateThreadIDColumn(); err != nil {
-		return err
+// migrateMailThreads starts a thread at every mail sent before threading
+// existed and indexes the thread columns, which may have just been added.
+func (s *SQLStore) migrateMailThreads() error {
+	if _, err := s.db.Exec(`UPDATE agent_mail SET thread_id = id WHERE thread_id IS NULL`); err != nil {
+		return fmt.Errorf("backfilling agent_mail thread_id: %w", err)
 	}
-
-	if err := s.migrateMessagesToEvents(); err != nil {
-		return fmt.Errorf("migrating messages to events: %w", err)
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_agent_mail_thread ON agent_mail(thread_id, created_at)`); err != nil {
+		return fmt.Errorf("creating idx_agent_mail_thread index: %w", err)
 	}
-
-	if err := s.migrateConversationKeysToAgentID(); err != nil {
-		return fmt.Errorf("migrating conversation keys to agent_id: %w", err)
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_agent_mail_reply ON agent_mail(in_reply_to) WHERE in_reply_to IS NOT NULL`); err != nil {
+		return fmt.Errorf("creating idx_agent_mail_reply index: %w", err)
 	}
+	return nil
+}
 
-	if err := s.migrateAuditLogCheckConstraint(); err != nil {
-		return fmt.Errorf("migrating audit_log check constraint: %w", err)
-	}
+// columnMigrations add columns to tables created before the columns existed.
+var columnMigrations = []columnMigration{
+	{`SELECT 1 FROM pragma_table_info('messages') WHERE name = 'type'`, `ALTER TABLE messages ADD COLUMN type TEXT NOT NULL DEFAULT 'message'`, "type", "messages"},
+	{`SELECT 1 FROM pragma_table_info('messages') WHERE name = 'tool_name'`, `ALTER TABLE messages ADD COLUMN tool_name TEXT`, "tool_name", "messages"},
+	{`SELECT 1 FROM pragma_table_info('messages') WHERE name = 'tool_id'`, `ALTER TABLE messages ADD COLUMN tool_id TEXT`, "tool_id", "messages"},
+	{`SELECT 1 FROM pragma_table_info('bindings') WHERE name = 'working_dir'`, `ALTER TABLE bindings ADD COLUMN working_dir TEXT`, "working_dir", "bindings"},
+	{`SELECT 1 FROM pragma_table_info('bindings') WHERE name = 'max_response_seconds'`, `ALTER TABLE bindings ADD COLUMN max_response_seconds INTEGER`, "max_response_seconds", "bindings"},
+	{`SELECT 1 FROM pragma_table_info('bindings') WHERE name = 'fallback_agents'`, `ALTER TABLE bindings ADD COLUMN fallback_agents TEXT`, "fallback_agents", "bindings"},
+	{`SELECT 1 FROM pragma_table_info('threads') WHERE name = 'merged_into'`, `ALTER TABLE threads ADD COLUMN merged_into TEXT`, "merged_into", "threads"},
+	{`SELECT 1 FROM pragma_table_info('threads') WHERE name = 'split_from'`, `ALTER TABLE threads ADD COLUMN split_from TEXT`, "split_from", "threads"},
+	{`SELECT 1 FROM pragma_table_info('threads') WHERE name = 'archived'`, `ALTER TABLE threads ADD COLUMN archived INTEGER NOT NULL DEFAULT 0`, "archived", "threads"},
+	{`SELECT 1 FROM pragma_table_info('threads') WHERE name = 'pinned'`, `ALTER TABLE threads ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0`, "pinned", "threads"},
+	{`SELECT 1 FROM pragma_table_info('principals') WHERE name = 'paused_at'`, `ALTER TABLE principals ADD COLUMN paused_at TEXT`, "paused_at", "principals"},
+	{`SELECT 1 FROM pragma_table_info('principals') WHERE name = 'paused_by'`, `ALTER TABLE principals ADD COLUMN paused_by TEXT`, "paused_by", "principals"},
+	{`SELECT 1 FROM pragma_table_info('admin_users') WHERE name = 'principal_id'`, `ALTER TABLE admin_users ADD COLUMN principal_id TEXT`, "principal_id", "admin_users"},
+	{`SELECT 1 FROM pragma_table_info('bbs_posts') WHERE name = 'author_kind'`, `ALTER TABLE bbs_posts ADD COLUMN author_kind TEXT NOT NULL DEFAULT 'agent'`, "author_kind", "bbs_posts"},
+	{`SELECT 1 FROM pragma_table_info('bbs_posts') WHERE name = 'author_name'`, `ALTER TABLE bbs_posts ADD COLUMN author_name TEXT`, "author_name", "bbs_posts"},
+	{`SELECT 1 FROM pragma_table_info('agent_mail') WHERE name = 'in_reply_to'`, `ALTER TABLE agent_mail ADD COLUMN in_reply_to TEXT`, "in_reply_to", "agent_mail"},
+	{`SELECT 1 FROM pragma_table_info('agent_mail') WHERE name = 'thread_id'`, `ALTER TABLE agent_mail ADD COLUMN thread_id TEXT`, "thread_id", "agent_mail"},
+	{`SELECT 1 FROM pragma_table_info('agent_notes') WHERE name = 'expires_at'`, `ALTER TABLE agent_notes ADD COLUMN expires_at TEXT`, "expires_at", "agent_notes"},
+	{`SELECT 1 FROM pragma_table_info('todos') WHERE name = 'assignee_agent_id'`, `ALTER TABLE todos ADD COLUMN assignee_agent_id TEXT`, "assignee_agent_id", "todos"},
+	{`SELECT 1 FROM pragma_table_info('todos') WHERE name = 'project'`, `ALTER TABLE todos ADD COLUMN project TEXT`, "project", "todos"},
+	{`SELECT 1 FROM pragma_table_info('audit_log') WHERE name = 'source_ip'`, `ALTER TABLE audit_log ADD COLUMN source_ip TEXT`, "source_ip", "audit_log"},
+	{`SELECT 1 FROM pragma_table_info('api_tokens') WHERE name = 'agent_ids'`, `ALTER TABLE api_tokens ADD COLUMN agent_ids TEXT`, "agent_ids", "api_tokens"},
+	{`SELECT 1 FROM pragma_table_info('api_tokens') WHERE name = 'capabilities'`, `ALTER TABLE api_tokens ADD COLUMN capabilities TEXT`, "capabilities", "api_tokens"},
+	{`SELECT 1 FROM pragma_table_info('questions') WHERE name = 'allow_free_text'`, `ALTER TABLE questions ADD COLUMN allow_free_text INTEGER`, "allow_free_text", "questions"},
+	{`SELECT 1 FROM pragma_table_info('questions') WHERE name = 'pattern'`, `ALTER TABLE questions ADD COLUMN pattern TEXT`, "pattern", "questions"},
+	{`SELECT 1 FROM pragma_table_info('questions') WHERE name = 'max_length'`, `ALTER TABLE questions ADD COLUMN max_length INTEGER`, "max_length", "questions"},
+	{`SELECT 1 FROM pragma_table_info('bindings') WHERE name = 'workspace'`, `ALTER TABLE bindings ADD COLUMN workspace TEXT`, "workspace", "bindings"},
+	{`SELECT 1 FROM pragma_table_info('agent_inflight_requests') WHERE name = 'workspace'`, `ALTER TABLE agent_inflight_requests ADD COLUMN workspace TEXT`, "workspace", "agent_inflight_requests"},
+	{`SELECT 1 FROM pragma_table_info('admin_sessions') WHERE name = 'last_seen_at'`, `ALTER TABLE admin_sessions ADD COLUMN last_seen_at TEXT`, "last_seen_at", "admin_sessions"},
+	{`SELECT 1 FROM pragma_table_info('admin_sessions') WHERE name = 'user_agent'`, `ALTER TABLE admin_sessions ADD COLUMN user_agent TEXT`, "user_agent", "admin_sessions"},
+	{`SELECT 1 FROM pragma_table_info('admin_sessions') WHERE name = 'ip'`, `ALTER TABLE admin_sessions ADD COLUMN ip TEXT`, "ip", "admin_sessions"},
+	{`SELECT 1 FROM pragma_table_info('bindings') WHERE name = 'context'`, `ALTER TABLE bindings ADD COLUMN context TEXT`, "context", "bindings"},
+	{`SELECT 1 FROM pragma_table_info('agent_inflight_requests') WHERE name = 'channel_context'`, `ALTER TABLE agent_inflight_requests ADD COLUMN channel_context TEXT`, "channel_context", "agent_inflight_requests"},
+	{`SELECT 1 FROM pragma_table_info('ledger_events') WHERE name = 'channel_context_hash'`, `ALTER TABLE ledger_events ADD COLUMN channel_context_hash TEXT`, "channel_context_hash", "ledger_events"},
+	{`SELECT 1 FROM pragma_table_info('ledger_events') WHERE name = 'parent_request_id'`, `ALTER TABLE ledger_events ADD COLUMN parent_request_id TEXT`, "parent_request_id", "ledger_events"},
+	{`SELECT 1 FROM pragma_table_info('principals') WHERE name = 'tags'`, `ALTER TABLE principals ADD COLUMN tags TEXT`, "tags", "principals"},
+	{`SELECT 1 FROM pragma_table_info('link_codes') WHERE name = 'platform'`, `ALTER TABLE link_codes ADD COLUMN platform TEXT NOT NULL DEFAULT ''`, "platform", "link_codes"},
+	{`SELECT 1 FROM pragma_table_info('bbs_posts') WHERE name = 'pinned'`, `ALTER TABLE bbs_posts ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0`, "pinned", "bbs_posts"},
+}
 
+// migrationSteps run in order after columnMigrations. Each checks whether
+// it is still needed, so running them again is harmless.
+var migrationSteps = []struct {
+	name string
+	run  func(*SQLStore) error
+}{
+	{"adding ledger_events thread_id", (*SQLStore).migrateThreadIDColumn},
+	{"migrating mail threads", (*SQLStore).migrateMailThreads},
+	{"migrating messages to events", (*SQLStore).migrateMessagesToEvents},
+	{"migrating conversation keys to agent_id", (*SQLStore).migrateConversationKeysToAgentID},
+	{"migrating audit_log check constraint", (*SQLStore).migrateAuditLogCheckConstraint},
+	{"migrating ledger_events check constraint", (*SQLStore).migrateLedgerEventsCheckConstraint},
+	{"backfilling usage rollups", (*SQLStore).migrateUsageRollups},
+	{"indexing bbs_posts for paging", (*SQLStore).migrateBBSIndexes},
+}
+
+// SchemaVersion is the number of migrations this build knows. It grows
+// whenever one is added, so a state export made by a newer build carries a
+// higher version. Each schema change also needs a file in
+// migrations/postgres; 0001_initial.sql is the schema as of version 42.
+func SchemaVersion() int {
+	return len(columnMigrations) + len(migrationSteps)
+}
+
+// runMigrations applies schema migrations for existing databases.
+// These are idempotent - safe to run multiple times.
+func (s *SQLStore) runMigrations() error {
+	for _, m := range columnMigrations {
+		if err := s.applyColumnMigration(m); err != nil {
+			return err
+		}
+	}
+	for _, step := range migrationSteps {
+		if err := step.run(s); err != nil {
+			return fmt.Errorf("%s: %w", step.name, err)
+		}
+	}
 	return nil
 }
 
 // migrateMessagesToEvents copies existing messages from the messages table to ledger_events.
 // This is idempotent - it only copies messages that don't already exist in ledger_events.
-func (s *SQLiteStore) migrateMessagesToEvents() error {
+func (s *SQLStore) migrateMessagesToEvents() error {
 	// Check how many messages need migration
 	var messageCount, eventCount int
 	err := s.db.QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&messageCount)
@@ -303,7 +447,7 @@ func (s *SQLiteStore) migrateMessagesToEvents() error {
 // migrateConversationKeysToAgentID updates existing ledger_events that use
 // "thread:{thread_id}" format to use agent_id directly. This enables cross-client
 // history sync since TUI, web, and mobile all query by agent_id.
-func (s *SQLiteStore) migrateConversationKeysToAgentID() error {
+func (s *SQLStore) migrateConversationKeysToAgentID() error {
 	// Count events that need migration (have thread: prefix and a linked thread)
 	var needsMigration int
 	err := s.db.QueryRow(`
@@ -345,14 +489,14 @@ func (s *SQLiteStore) migrateConversationKeysToAgentID() error {
 }
 
 // migrateAuditLogCheckConstraint updates the audit_log table CHECK constraint
-// to include 'create_principal' and 'delete_principal' actions for existing databases.
+// to include every action in ValidAuditActions for existing databases.
 // SQLite doesn't support ALTER TABLE for CHECK constraints, so we recreate the table.
-func (s *SQLiteStore) migrateAuditLogCheckConstraint() error {
+func (s *SQLStore) migrateAuditLogCheckConstraint() error {
 	if !s.needsAuditLogMigration() {
 		return nil
 	}
 
-	s.logger.Info("migrating audit_log check constraint to include create_principal and delete_principal")
+	s.logger.Info("migrating audit_log check constraint to include new audit actions")
 
 	if err := s.recreateAuditLogTable(); err != nil {
 		return err
@@ -363,22 +507,24 @@ func (s *SQLiteStore) migrateAuditLogCheckConstraint() error {
 }
 
 // needsAuditLogMigration checks if the audit_log table needs constraint migration.
-func (s *SQLiteStore) needsAuditLogMigration() bool {
+func (s *SQLStore) needsAuditLogMigration() bool {
 	var tableSQL string
 	err := s.db.QueryRow(`SELECT sql FROM sqlite_master WHERE type='table' AND name='audit_log'`).Scan(&tableSQL)
 	if err != nil {
 		// Table doesn't exist - will be created by schema
 		return false
 	}
-	// Check if constraint already includes the new actions
-	if strings.Contains(tableSQL, "create_principal") && strings.Contains(tableSQL, "delete_principal") {
-		return false
+	// Check if constraint already includes every action
+	for _, action := range ValidAuditActions {
+		if !strings.Contains(tableSQL, "'"+string(action)+"'") {
+			return true
+		}
 	}
-	return true
+	return false
 }
 
 // recreateAuditLogTable recreates the audit_log table with updated CHECK constraint.
-func (s *SQLiteStore) recreateAuditLogTable() error {
+func (s *SQLStore) recreateAuditLogTable() error {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("beginning transaction: %w", err)
@@ -399,7 +545,8 @@ func (s *SQLiteStore) recreateAuditLogTable() error {
 			target_id TEXT NOT NULL,
 			ts TEXT NOT NULL,
 			detail_json TEXT,
-			CHECK (action IN ('approve_principal', 'revoke_principal', 'grant_capability', 'revoke_capability', 'create_binding', 'update_binding', 'delete_binding', 'create_token', 'create_principal', 'delete_principal'))
+			source_ip TEXT,
+			CHECK (action IN ('approve_principal', 'revoke_principal', 'grant_capability', 'revoke_capability', 'create_binding', 'update_binding', 'delete_binding', 'create_token', 'create_principal', 'delete_principal', 'merge_threads', 'split_thread', 'pause_agent', 'resume_agent', 'update_feature_flag', 'answer_question', 'revoke_token', 'create_secret', 'update_secret', 'delete_secret', 'create_invite', 'create_tool_policy', 'update_tool_policy', 'delete_tool_policy', 'revoke_session', 'add_project_member', 'remove_project_member', 'add_delegation_target', 'remove_delegation_target', 'deny_admin_call', 'clear_login_lockout', 'set_tool_override'))
 		)`, "creating new audit_log table"},
 		{`INSERT INTO audit_log_new SELECT * FROM audit_log`, "copying audit_log data"},
 		{`DROP TABLE audit_log`, "dropping old audit_log table"},
@@ -418,8 +565,68 @@ func (s *SQLiteStore) recreateAuditLogTable() error {
 	return tx.Commit()
 }
 
+// migrateLedgerEventsCheckConstraint brings the ledger_events CHECK
+// constraint of existing databases up to date with the current event types
+// (plan, citation, tool_check, capability_warning, tool_policy, then
+// agent_health).
+// Checking for the newest type covers all. Rowids are copied so the search
+// index still points at the right events.
+func (s *SQLStore) migrateLedgerEventsCheckConstraint() error {
+	var tableSQL string
+	err := s.db.QueryRow(`SELECT sql FROM sqlite_master WHERE type='table' AND name='ledger_events'`).Scan(&tableSQL)
+	if err != nil || strings.Contains(tableSQL, "'"+string(EventTypeAgentHealth)+"'") {
+		return nil
+	}
+
+	s.logger.Info("migrating ledger_events check constraint to include new event types")
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Columns are listed explicitly: databases that gained thread_id by
+	// ALTER TABLE have it last rather than third.
+	const columns = `event_id, conversation_key, thread_id, direction, author, timestamp, type, text, raw_transport, raw_payload_ref, actor_principal_id, actor_member_id, channel_context_hash, parent_request_id`
+	stmts := []struct {
+		sql string
+		msg string
+	}{
+		{`CREATE TABLE ledger_events_new (event_id TEXT PRIMARY KEY, conversation_key TEXT NOT NULL, thread_id TEXT, direction TEXT NOT NULL, author TEXT NOT NULL, timestamp TEXT NOT NULL, type TEXT NOT NULL, text TEXT, raw_transport TEXT, raw_payload_ref TEXT, actor_principal_id TEXT, actor_member_id TEXT, channel_context_hash TEXT, parent_request_id TEXT, CHECK (direction IN ('inbound_to_agent', 'outbound_from_agent')), CHECK (type IN ('message', 'tool_call', 'tool_result', 'system', 'error', 'plan', 'citation', 'tool_check', 'capability_warning', 'tool_policy', 'agent_health')))`, "creating new ledger_events table"},
+		{`INSERT INTO ledger_events_new (rowid, ` + columns + `) SELECT rowid, ` + columns + ` FROM ledger_events`, "copying ledger_events data"},
+		{`DROP TABLE ledger_events`, "dropping old ledger_events table"},
+		{`ALTER TABLE ledger_events_new RENAME TO ledger_events`, "renaming ledger_events table"},
+		{`CREATE INDEX IF NOT EXISTS idx_ledger_conversation ON ledger_events(conversation_key, timestamp)`, "creating idx_ledger_conversation index"},
+		{`CREATE INDEX IF NOT EXISTS idx_ledger_actor ON ledger_events(actor_principal_id)`, "creating idx_ledger_actor index"},
+		{`CREATE INDEX IF NOT EXISTS idx_ledger_timestamp ON ledger_events(timestamp)`, "creating idx_ledger_timestamp index"},
+		{`CREATE INDEX IF NOT EXISTS idx_ledger_thread ON ledger_events(thread_id) WHERE thread_id IS NOT NULL`, "creating idx_ledger_thread index"},
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt.sql); err != nil {
+			return fmt.Errorf("%s: %w", stmt.msg, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.logger.Info("migrated ledger_events check constraint")
+	return nil
+}
+
+// Ping runs a trivial query to confirm the database is reachable and not wedged.
+func (s *SQLStore) Ping(ctx context.Context) error {
+	var one int
+	if err := s.db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		return fmt.Errorf("pinging database: %w", err)
+	}
+	return nil
+}
+
 // Close closes the database connection.
-func (s *SQLiteStore) Close() error {
+func (s *SQLStore) Close() error {
 	s.logger.Info("closing SQLite store")
 	return s.db.Close()
 }
@@ -427,10 +634,10 @@ func (s *SQLiteStore) Close() error {
 // CreateThread creates a new thread in the database.
 // If a thread with the same frontend_name and external_id already exists,
 // it returns ErrDuplicateThread.
-func (s *SQLiteStore) CreateThread(ctx context.Context, thread *Thread) error {
+func (s *SQLStore) CreateThread(ctx context.Context, thread *Thread) error {
 	query := `
-		INSERT INTO threads (id, frontend_name, external_id, agent_id, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO threads (id, frontend_name, external_id, agent_id, created_at, updated_at, merged_into, split_from, archived, pinned)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := s.db.ExecContext(ctx, query,
@@ -440,6 +647,10 @@ func (s *SQLiteStore) CreateThread(ctx context.Context, thread *Thread) error {
 		thread.AgentID,
 		thread.CreatedAt.UTC().Format(time.RFC3339),
 		thread.UpdatedAt.UTC().Format(time.RFC3339),
+		nullString(thread.MergedInto),
+		nullString(thread.SplitFrom),
+		thread.Archived,
+		thread.Pinned,
 	)
 	if err != nil {
 		// Check for UNIQUE constraint violation
@@ -453,27 +664,29 @@ func (s *SQLiteStore) CreateThread(ctx context.Context, thread *Thread) error {
 	return nil
 }
 
-// isConstraintViolation checks if the error is a SQLite UNIQUE constraint violation.
+// isConstraintViolation checks if the error is a constraint violation.
 func isConstraintViolation(err error) bool {
 	if err == nil {
 		return false
 	}
 	errStr := err.Error()
 	return strings.Contains(errStr, "UNIQUE constraint failed") ||
-		strings.Contains(errStr, "constraint failed")
+		strings.Contains(errStr, "constraint failed") ||
+		isPostgresConstraintViolation(err, "")
 }
 
 // GetThread retrieves a thread by ID.
 // Returns ErrNotFound if the thread doesn't exist.
-func (s *SQLiteStore) GetThread(ctx context.Context, id string) (*Thread, error) {
+func (s *SQLStore) GetThread(ctx context.Context, id string) (*Thread, error) {
 	query := `
-		SELECT id, frontend_name, external_id, agent_id, created_at, updated_at
+		SELECT id, frontend_name, external_id, agent_id, created_at, updated_at, merged_into, split_from, archived, pinned
 		FROM threads
 		WHERE id = ?
 	`
 
 	var thread Thread
 	var createdAtStr, updatedAtStr string
+	var mergedInto, splitFrom sql.NullString
 
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&thread.ID,
@@ -482,6 +695,10 @@ func (s *SQLiteStore) GetThread(ctx context.Context, id string) (*Thread, error)
 		&thread.AgentID,
 		&createdAtStr,
 		&updatedAtStr,
+		&mergedInto,
+		&splitFrom,
+		&thread.Archived,
+		&thread.Pinned,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -500,6 +717,8 @@ func (s *SQLiteStore) GetThread(ctx context.Context, id string) (*Thread, error)
 	if err != nil {
 		return nil, fmt.Errorf("parsing updated_at: %w", err)
 	}
+	thread.MergedInto = mergedInto.String
+	thread.SplitFrom = splitFrom.String
 
 	return &thread, nil
 }
@@ -507,15 +726,16 @@ func (s *SQLiteStore) GetThread(ctx context.Context, id string) (*Thread, error)
 // GetThreadByFrontendID retrieves a thread by frontend name and external ID.
 // This uses the idx_threads_frontend_external index for efficient lookups.
 // Returns ErrNotFound if no thread exists for the given frontend/external ID combination.
-func (s *SQLiteStore) GetThreadByFrontendID(ctx context.Context, frontendName, externalID string) (*Thread, error) {
+func (s *SQLStore) GetThreadByFrontendID(ctx context.Context, frontendName, externalID string) (*Thread, error) {
 	query := `
-		SELECT id, frontend_name, external_id, agent_id, created_at, updated_at
+		SELECT id, frontend_name, external_id, agent_id, created_at, updated_at, merged_into, split_from, archived, pinned
 		FROM threads
 		WHERE frontend_name = ? AND external_id = ?
 	`
 
 	var thread Thread
 	var createdAtStr, updatedAtStr string
+	var mergedInto, splitFrom sql.NullString
 
 	err := s.db.QueryRowContext(ctx, query, frontendName, externalID).Scan(
 		&thread.ID,
@@ -524,6 +744,10 @@ func (s *SQLiteStore) GetThreadByFrontendID(ctx context.Context, frontendName, e
 		&thread.AgentID,
 		&createdAtStr,
 		&updatedAtStr,
+		&mergedInto,
+		&splitFrom,
+		&thread.Archived,
+		&thread.Pinned,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -542,13 +766,15 @@ func (s *SQLiteStore) GetThreadByFrontendID(ctx context.Context, frontendName, e
 	if err != nil {
 		return nil, fmt.Errorf("parsing updated_at: %w", err)
 	}
+	thread.MergedInto = mergedInto.String
+	thread.SplitFrom = splitFrom.String
 
 	return &thread, nil
 }
 
 // UpdateThread updates an existing thread.
 // Returns ErrNotFound if the thread doesn't exist.
-func (s *SQLiteStore) UpdateThread(ctx context.Context, thread *Thread) error {
+func (s *SQLStore) UpdateThread(ctx context.Context, thread *Thread) error {
 	query := `
 		UPDATE threads
 		SET frontend_name = ?, external_id = ?, agent_id = ?, updated_at = ?
@@ -579,9 +805,10 @@ func (s *SQLiteStore) UpdateThread(ctx context.Context, thread *Thread) error {
 	return nil
 }
 
-// ListThreads retrieves threads ordered by most recent activity.
+// ListThreads retrieves threads with pinned threads first, then by most
+// recent activity. Archived threads are left out unless includeArchived is set.
 // If limit is 0 or negative, a default limit of 100 is used.
-func (s *SQLiteStore) ListThreads(ctx context.Context, limit int) ([]*Thread, error) {
+func (s *SQLStore) ListThreads(ctx context.Context, limit int, includeArchived bool) ([]*Thread, error) {
 	if limit <= 0 {
 		limit = 100
 	}
@@ -589,14 +816,71 @@ func (s *SQLiteStore) ListThreads(ctx context.Context, limit int) ([]*Thread, er
 		limit = 1000
 	}
 
-	query := `
-		SELECT id, frontend_name, external_id, agent_id, created_at, updated_at
+	return s.queryThreads(ctx, `
+		SELECT id, frontend_name, external_id, agent_id, created_at, updated_at, merged_into, split_from, archived, pinned
+		FROM threads
+		WHERE ? OR archived = 0
+		ORDER BY pinned DESC, updated_at DESC
+		LIMIT ?
+	`, includeArchived, limit)
+}
+
+// ArchiveThread sets or clears a thread's archived flag. Archived threads are
+// hidden from ListThreads by default but otherwise unchanged.
+// Returns ErrNotFound if the thread doesn't exist.
+func (s *SQLStore) ArchiveThread(ctx context.Context, id string, archived bool) error {
+	return s.setThreadFlag(ctx, id, `UPDATE threads SET archived = ? WHERE id = ?`, archived)
+}
+
+// SetThreadPinned sets or clears a thread's pinned flag. Pinned threads sort
+// ahead of the rest in ListThreads.
+// Returns ErrNotFound if the thread doesn't exist.
+func (s *SQLStore) SetThreadPinned(ctx context.Context, id string, pinned bool) error {
+	return s.setThreadFlag(ctx, id, `UPDATE threads SET pinned = ? WHERE id = ?`, pinned)
+}
+
+// setThreadFlag runs query, an UPDATE setting one of a thread's boolean flags.
+func (s *SQLStore) setThreadFlag(ctx context.Context, id, query string, value bool) error {
+	result, err := s.db.ExecContext(ctx, query, value, id)
+	if err != nil {
+		return fmt.Errorf("updating thread flag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	s.logger.Debug("updated thread flag", "id", id, "value", value)
+	return nil
+}
+
+// ListAgentThreads retrieves an agent's threads ordered by most recent
+// activity, leaving out tombstones of merged threads. Limits are as for
+// ListThreads.
+func (s *SQLStore) ListAgentThreads(ctx context.Context, agentID string, limit int) ([]*Thread, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	return s.queryThreads(ctx, `
+		SELECT id, frontend_name, external_id, agent_id, created_at, updated_at, merged_into, split_from, archived, pinned
 		FROM threads
+		WHERE agent_id = ? AND merged_into IS NULL
 		ORDER BY updated_at DESC
 		LIMIT ?
-	`
+	`, agentID, limit)
+}
 
-	rows, err := s.db.QueryContext(ctx, query, limit)
+// queryThreads runs a query selecting full thread rows and scans the result.
+func (s *SQLStore) queryThreads(ctx context.Context, query string, args ...any) ([]*Thread, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying threads: %w", err)
 	}
@@ -606,6 +890,7 @@ func (s *SQLiteStore) ListThreads(ctx context.Context, limit int) ([]*Thread, er
 	for rows.Next() {
 		var thread Thread
 		var createdAtStr, updatedAtStr string
+		var mergedInto, splitFrom sql.NullString
 
 		if err := rows.Scan(
 			&thread.ID,
@@ -614,6 +899,10 @@ func (s *SQLiteStore) ListThreads(ctx context.Context, limit int) ([]*Thread, er
 			&thread.AgentID,
 			&createdAtStr,
 			&updatedAtStr,
+			&mergedInto,
+			&splitFrom,
+			&thread.Archived,
+			&thread.Pinned,
 		); err != nil {
 			return nil, fmt.Errorf("scanning thread row: %w", err)
 		}
@@ -627,6 +916,8 @@ func (s *SQLiteStore) ListThreads(ctx context.Context, limit int) ([]*Thread, er
 		if err != nil {
 			return nil, fmt.Errorf("parsing updated_at: %w", err)
 		}
+		thread.MergedInto = mergedInto.String
+		thread.SplitFrom = splitFrom.String
 
 		threads = append(threads, &thread)
 	}
@@ -639,7 +930,7 @@ func (s *SQLiteStore) ListThreads(ctx context.Context, limit int) ([]*Thread, er
 }
 
 // SaveMessage saves a message to the database.
-func (s *SQLiteStore) SaveMessage(ctx context.Context, msg *Message) error {
+func (s *SQLStore) SaveMessage(ctx context.Context, msg *Message) error {
 	// Default to "message" type if not specified
 	msgType := msg.Type
 	if msgType == "" {
@@ -669,6 +960,11 @@ func (s *SQLiteStore) SaveMessage(ctx context.Context, msg *Message) error {
 	return nil
 }
 
+// execer runs statements on the store's database or within a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
 // nullString returns nil for empty strings, otherwise the string pointer.
 func nullString(s string) any {
 	if s == "" {
@@ -680,7 +976,7 @@ func nullString(s string) any {
 // GetThreadMessages retrieves messages for a thread, limited to the most recent `limit` messages.
 // Messages are returned in chronological order (oldest first).
 // If limit is 0 or negative, all messages are returned.
-func (s *SQLiteStore) GetThreadMessages(ctx context.Context, threadID string, limit int) ([]*Message, error) {
+func (s *SQLStore) GetThreadMessages(ctx context.Context, threadID string, limit int) ([]*Message, error) {
 	var query string
 	var args []any
 
@@ -749,11 +1045,12 @@ func (s *SQLiteStore) GetThreadMessages(ctx context.Context, threadID string, li
 }
 
 // SaveAgentState saves or updates agent state.
-// Uses INSERT OR REPLACE to handle both insert and update cases.
-func (s *SQLiteStore) SaveAgentState(ctx context.Context, agentID string, state []byte) error {
+// Upserts on agent_id to handle both insert and update cases.
+func (s *SQLStore) SaveAgentState(ctx context.Context, agentID string, state []byte) error {
 	query := `
-		INSERT OR REPLACE INTO agent_state (agent_id, state, updated_at)
+		INSERT INTO agent_state (agent_id, state, updated_at)
 		VALUES (?, ?, ?)
+		ON CONFLICT (agent_id) DO UPDATE SET state = excluded.state, updated_at = excluded.updated_at
 	`
 
 	_, err := s.db.ExecContext(ctx, query,
@@ -771,7 +1068,7 @@ func (s *SQLiteStore) SaveAgentState(ctx context.Context, agentID string, state
 
 // GetAgentState retrieves agent state.
 // Returns ErrNotFound if the agent has no saved state.
-func (s *SQLiteStore) GetAgentState(ctx context.Context, agentID string) ([]byte, error) {
+func (s *SQLStore) GetAgentState(ctx context.Context, agentID string) ([]byte, error) {
 	query := `SELECT state FROM agent_state WHERE agent_id = ?`
 
 	var state []byte
@@ -789,7 +1086,7 @@ func (s *SQLiteStore) GetAgentState(ctx context.Context, agentID string) ([]byte
 
 // CreateBinding creates a new channel binding.
 // Returns an error if a binding already exists for this frontend/channel combination.
-func (s *SQLiteStore) CreateBinding(ctx context.Context, binding *ChannelBinding) error {
+func (s *SQLStore) CreateBinding(ctx context.Context, binding *ChannelBinding) error {
 	query := `
 		INSERT INTO channel_bindings (frontend, channel_id, agent_id, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?)
@@ -812,7 +1109,7 @@ func (s *SQLiteStore) CreateBinding(ctx context.Context, binding *ChannelBinding
 
 // GetBinding retrieves a binding by frontend and channel ID.
 // Returns ErrNotFound if no binding exists.
-func (s *SQLiteStore) GetBinding(ctx context.Context, frontend, channelID string) (*ChannelBinding, error) {
+func (s *SQLStore) GetBinding(ctx context.Context, frontend, channelID string) (*ChannelBinding, error) {
 	query := `
 		SELECT frontend, channel_id, agent_id, created_at, updated_at
 		FROM channel_bindings
@@ -851,7 +1148,7 @@ func (s *SQLiteStore) GetBinding(ctx context.Context, frontend, channelID string
 }
 
 // ListBindings returns all channel bindings.
-func (s *SQLiteStore) ListBindings(ctx context.Context) ([]*ChannelBinding, error) {
+func (s *SQLStore) ListBindings(ctx context.Context) ([]*ChannelBinding, error) {
 	query := `
 		SELECT frontend, channel_id, agent_id, created_at, updated_at
 		FROM channel_bindings
@@ -892,7 +1189,7 @@ func (s *SQLiteStore) ListBindings(ctx context.Context) ([]*ChannelBinding, erro
 
 // DeleteBinding removes a channel binding.
 // Returns ErrNotFound if the binding doesn't exist.
-func (s *SQLiteStore) DeleteBinding(ctx context.Context, frontend, channelID string) error {
+func (s *SQLStore) DeleteBinding(ctx context.Context, frontend, channelID string) error {
 	query := `DELETE FROM channel_bindings WHERE frontend = ? AND channel_id = ?`
 
 	result, err := s.db.ExecContext(ctx, query, frontend, channelID)
@@ -913,18 +1210,18 @@ func (s *SQLiteStore) DeleteBinding(ctx context.Context, frontend, channelID str
 	return nil
 }
 
-// Ensure SQLiteStore implements Store interface.
-var _ Store = (*SQLiteStore)(nil)
+// Ensure SQLStore implements Store interface.
+var _ Store = (*SQLStore)(nil)
 
-// Ensure SQLiteStore implements LinkCodeStore interface.
-var _ LinkCodeStore = (*SQLiteStore)(nil)
+// Ensure SQLStore implements LinkCodeStore interface.
+var _ LinkCodeStore = (*SQLStore)(nil)
 
 // CreateLinkCode creates a new pending link code.
-func (s *SQLiteStore) CreateLinkCode(ctx context.Context, code *LinkCode) error {
+func (s *SQLStore) CreateLinkCode(ctx context.Context, code *LinkCode) error {
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO link_codes (id, code, fingerprint, device_name, status, created_at, expires_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, code.ID, code.Code, code.Fingerprint, code.DeviceName, code.Status,
+		INSERT INTO link_codes (id, code, fingerprint, device_name, platform, status, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, code.ID, code.Code, code.Fingerprint, code.DeviceName, code.Platform, code.Status,
 		code.CreatedAt.UTC().Format(time.RFC3339),
 		code.ExpiresAt.UTC().Format(time.RFC3339))
 	if err != nil {
@@ -935,9 +1232,9 @@ func (s *SQLiteStore) CreateLinkCode(ctx context.Context, code *LinkCode) error
 }
 
 // GetLinkCode retrieves a link code by ID.
-func (s *SQLiteStore) GetLinkCode(ctx context.Context, id string) (*LinkCode, error) {
+func (s *SQLStore) GetLinkCode(ctx context.Context, id string) (*LinkCode, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, code, fingerprint, device_name, status, created_at, expires_at,
+		SELECT id, code, fingerprint, device_name, platform, status, created_at, expires_at,
 		       approved_by, approved_at, principal_id, token
 		FROM link_codes WHERE id = ?
 	`, id)
@@ -945,23 +1242,23 @@ func (s *SQLiteStore) GetLinkCode(ctx context.Context, id string) (*LinkCode, er
 }
 
 // GetLinkCodeByCode retrieves a link code by its short code.
-func (s *SQLiteStore) GetLinkCodeByCode(ctx context.Context, code string) (*LinkCode, error) {
+func (s *SQLStore) GetLinkCodeByCode(ctx context.Context, code string) (*LinkCode, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, code, fingerprint, device_name, status, created_at, expires_at,
+		SELECT id, code, fingerprint, device_name, platform, status, created_at, expires_at,
 		       approved_by, approved_at, principal_id, token
 		FROM link_codes WHERE code = ?
 	`, code)
 	return s.scanLinkCode(row)
 }
 
-func (s *SQLiteStore) scanLinkCode(row *sql.Row) (*LinkCode, error) {
+func (s *SQLStore) scanLinkCode(row interface{ Scan(...any) error }) (*LinkCode, error) {
 	var lc LinkCode
 	var createdAt, expiresAt string
 	var approvedBy, approvedAt, principalID, token sql.NullString
 
-	err := row.Scan(&lc.ID, &lc.Code, &lc.Fingerprint, &lc.DeviceName, &lc.Status,
+	err := row.Scan(&lc.ID, &lc.Code, &lc.Fingerprint, &lc.DeviceName, &lc.Platform, &lc.Status,
 		&createdAt, &expiresAt, &approvedBy, &approvedAt, &principalID, &token)
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrNotFound
 	}
 	if err != nil {
@@ -999,18 +1296,25 @@ func (s *SQLiteStore) scanLinkCode(row *sql.Row) (*LinkCode, error) {
 }
 
 // ApproveLinkCode marks a code as approved and stores the principal/token.
-func (s *SQLiteStore) ApproveLinkCode(ctx context.Context, id string, approvedBy string, principalID string, token string) error {
+// The code must still be pending and unexpired when the update runs, so an
+// approval racing the code's expiry has exactly one outcome. Returns
+// ErrLinkCodeExpired if the code expired first, or ErrNotFound if it is gone
+// or no longer pending.
+func (s *SQLStore) ApproveLinkCode(ctx context.Context, id string, approvedBy string, principalID string, token string) error {
 	now := time.Now().UTC().Format(time.RFC3339)
 	result, err := s.db.ExecContext(ctx, `
 		UPDATE link_codes
 		SET status = ?, approved_by = ?, approved_at = ?, principal_id = ?, token = ?
-		WHERE id = ? AND status = ?
-	`, LinkCodeStatusApproved, approvedBy, now, principalID, token, id, LinkCodeStatusPending)
+		WHERE id = ? AND status = ? AND expires_at > ?
+	`, LinkCodeStatusApproved, approvedBy, now, principalID, token, id, LinkCodeStatusPending, now)
 	if err != nil {
 		return fmt.Errorf("approving link code: %w", err)
 	}
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
+		if lc, err := s.GetLinkCode(ctx, id); err == nil && lc.Status == LinkCodeStatusPending {
+			return ErrLinkCodeExpired
+		}
 		return ErrNotFound
 	}
 	s.logger.Info("approved link code", "id", id, "approved_by", approvedBy)
@@ -1018,10 +1322,10 @@ func (s *SQLiteStore) ApproveLinkCode(ctx context.Context, id string, approvedBy
 }
 
 // ListPendingLinkCodes returns all pending (non-expired) link codes.
-func (s *SQLiteStore) ListPendingLinkCodes(ctx context.Context) ([]*LinkCode, error) {
+func (s *SQLStore) ListPendingLinkCodes(ctx context.Context) ([]*LinkCode, error) {
 	now := time.Now().UTC().Format(time.RFC3339)
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, code, fingerprint, device_name, status, created_at, expires_at,
+		SELECT id, code, fingerprint, device_name, platform, status, created_at, expires_at,
 		       approved_by, approved_at, principal_id, token
 		FROM link_codes
 		WHERE status = ? AND expires_at > ?
@@ -1034,29 +1338,21 @@ func (s *SQLiteStore) ListPendingLinkCodes(ctx context.Context) ([]*LinkCode, er
 
 	var codes []*LinkCode
 	for rows.Next() {
-		var lc LinkCode
-		var createdAt, expiresAt string
-		var approvedBy, approvedAt, principalID, token sql.NullString
-
-		err := rows.Scan(&lc.ID, &lc.Code, &lc.Fingerprint, &lc.DeviceName, &lc.Status,
-			&createdAt, &expiresAt, &approvedBy, &approvedAt, &principalID, &token)
+		lc, err := s.scanLinkCode(rows)
 		if err != nil {
-			return nil, fmt.Errorf("scanning link code row: %w", err)
+			return nil, err
 		}
-
-		lc.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-		lc.ExpiresAt, _ = time.Parse(time.RFC3339, expiresAt)
-		codes = append(codes, &lc)
+		codes = append(codes, lc)
 	}
 	return codes, rows.Err()
 }
 
-// DeleteExpiredLinkCodes removes expired link codes.
-func (s *SQLiteStore) DeleteExpiredLinkCodes(ctx context.Context) error {
-	now := time.Now().UTC().Format(time.RFC3339)
+// DeleteExpiredLinkCodes removes pending link codes that expired at or
+// before cutoff.
+func (s *SQLStore) DeleteExpiredLinkCodes(ctx context.Context, cutoff time.Time) error {
 	result, err := s.db.ExecContext(ctx, `
 		DELETE FROM link_codes WHERE expires_at <= ? AND status = ?
-	`, now, LinkCodeStatusPending)
+	`, cutoff.UTC().Format(time.RFC3339), LinkCodeStatusPending)
 	if err != nil {
 		return fmt.Errorf("deleting expired link codes: %w", err)
 	}
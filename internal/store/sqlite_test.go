@@ -46,6 +46,18 @@ func TestNewSQLiteStore_CreatesDirectory(t *testing.T) {
 	}
 }
 
+func TestSQLiteStore_Ping(t *testing.T) {
+	store := newTestStore(t)
+
+	if err := store.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping on open store: %v", err)
+	}
+	store.Close()
+	if err := store.Ping(context.Background()); err == nil {
+		t.Error("Ping on closed store succeeded, want error")
+	}
+}
+
 func TestCreateAndGetThread(t *testing.T) {
 	store := newTestStore(t)
 	defer store.Close()
@@ -100,6 +112,93 @@ func TestGetThread_NotFound(t *testing.T) {
 	}
 }
 
+func TestListAgentThreads(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	base := time.Now().UTC().Truncate(time.Second)
+	for i, th := range []*Thread{
+		{ID: "old", AgentID: "agent-001"},
+		{ID: "new", AgentID: "agent-001"},
+		{ID: "merged", AgentID: "agent-001", MergedInto: "new"},
+		{ID: "other", AgentID: "agent-002"},
+	} {
+		th.FrontendName = "slack"
+		th.ExternalID = th.ID
+		th.CreatedAt = base
+		th.UpdatedAt = base.Add(time.Duration(i) * time.Minute)
+		if err := store.CreateThread(ctx, th); err != nil {
+			t.Fatalf("CreateThread(%s) failed: %v", th.ID, err)
+		}
+	}
+
+	threads, err := store.ListAgentThreads(ctx, "agent-001", 0)
+	if err != nil {
+		t.Fatalf("ListAgentThreads failed: %v", err)
+	}
+	if len(threads) != 2 || threads[0].ID != "new" || threads[1].ID != "old" {
+		t.Errorf("ListAgentThreads = %v, want [new old]", threads)
+	}
+}
+
+func TestListThreads_ArchivedAndPinned(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	base := time.Now().UTC().Truncate(time.Second)
+	for i, id := range []string{"oldest", "pinned", "archived", "newest"} {
+		th := &Thread{ID: id, FrontendName: "slack", ExternalID: id, AgentID: "agent-001", CreatedAt: base, UpdatedAt: base.Add(time.Duration(i) * time.Minute)}
+		if err := store.CreateThread(ctx, th); err != nil {
+			t.Fatalf("CreateThread(%s) failed: %v", id, err)
+		}
+	}
+	if err := store.SetThreadPinned(ctx, "pinned", true); err != nil {
+		t.Fatalf("SetThreadPinned failed: %v", err)
+	}
+	if err := store.ArchiveThread(ctx, "archived", true); err != nil {
+		t.Fatalf("ArchiveThread failed: %v", err)
+	}
+
+	ids := func(threads []*Thread) []string {
+		out := make([]string, len(threads))
+		for i, th := range threads {
+			out[i] = th.ID
+		}
+		return out
+	}
+	threads, err := store.ListThreads(ctx, 0, false)
+	if err != nil {
+		t.Fatalf("ListThreads failed: %v", err)
+	}
+	if got := fmt.Sprint(ids(threads)); got != "[pinned newest oldest]" {
+		t.Errorf("ListThreads = %s, want [pinned newest oldest]", got)
+	}
+	threads, err = store.ListThreads(ctx, 0, true)
+	if err != nil {
+		t.Fatalf("ListThreads(includeArchived) failed: %v", err)
+	}
+	if got := fmt.Sprint(ids(threads)); got != "[pinned newest archived oldest]" {
+		t.Errorf("ListThreads(includeArchived) = %s, want [pinned newest archived oldest]", got)
+	}
+
+	got, err := store.GetThread(ctx, "archived")
+	if err != nil {
+		t.Fatalf("GetThread failed: %v", err)
+	}
+	if !got.Archived || got.Pinned {
+		t.Errorf("archived thread = %+v, want archived and unpinned", got)
+	}
+
+	if err := store.ArchiveThread(ctx, "missing", true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ArchiveThread(missing) err = %v, want ErrNotFound", err)
+	}
+	if err := store.SetThreadPinned(ctx, "missing", true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetThreadPinned(missing) err = %v, want ErrNotFound", err)
+	}
+}
+
 func TestUpdateThread(t *testing.T) {
 	store := newTestStore(t)
 	defer store.Close()
@@ -567,9 +666,13 @@ func TestDeleteBinding(t *testing.T) {
 	}
 }
 
-// newTestStore creates a new SQLite store in a temporary directory for testing.
-func newTestStore(t *testing.T) *SQLiteStore {
+// newTestStore creates a new SQLite store in a temporary directory for
+// testing, or a Postgres store when TEST_POSTGRES_DSN is set.
+func newTestStore(t *testing.T) *SQLStore {
 	t.Helper()
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		return newPostgresTestStore(t, dsn)
+	}
 
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -675,6 +778,174 @@ func TestMigrateAuditLogCheckConstraint(t *testing.T) {
 	}
 }
 
+func TestMigrateLedgerEventsCheckConstraint(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := openRawSQLDB(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open raw db: %v", err)
+	}
+	// Old ledger_events with thread_id added last by ALTER TABLE
+	oldSchema := `
+		CREATE TABLE ledger_events (event_id TEXT PRIMARY KEY, conversation_key TEXT NOT NULL, direction TEXT NOT NULL, author TEXT NOT NULL, timestamp TEXT NOT NULL, type TEXT NOT NULL, text TEXT, raw_transport TEXT, raw_payload_ref TEXT, actor_principal_id TEXT, actor_member_id TEXT, CHECK (direction IN ('inbound_to_agent', 'outbound_from_agent')), CHECK (type IN ('message', 'tool_call', 'tool_result', 'system', 'error')));
+		ALTER TABLE ledger_events ADD COLUMN thread_id TEXT;
+		INSERT INTO ledger_events (event_id, conversation_key, direction, author, timestamp, type, text, thread_id)
+		VALUES ('evt-1', 'agent-1', 'outbound_from_agent', 'agent', '2024-01-01T00:00:00Z', 'message', 'hello', 'thread-1');
+	`
+	if _, err := db.Exec(oldSchema); err != nil {
+		t.Fatalf("failed to create old schema: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close raw db: %v", err)
+	}
+
+	store, err := NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	evt, err := store.GetEvent(ctx, "evt-1")
+	if err != nil {
+		t.Fatalf("existing event not preserved: %v", err)
+	}
+	if evt.ThreadID == nil || *evt.ThreadID != "thread-1" || evt.Text == nil || *evt.Text != "hello" {
+		t.Errorf("existing event columns shuffled: %+v", evt)
+	}
+
+	text := "Plan:\n1. check logs"
+	plan := &LedgerEvent{
+		ID:              "evt-2",
+		ConversationKey: "agent-1",
+		Direction:       EventDirectionOutbound,
+		Author:          "agent",
+		Timestamp:       time.Now(),
+		Type:            EventTypePlan,
+		Text:            &text,
+	}
+	if err := store.SaveEvent(ctx, plan); err != nil {
+		t.Fatalf("SaveEvent with plan type failed after migration: %v", err)
+	}
+}
+
+func TestMigrateLedgerEventsCheckConstraint_AddsCitation(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := openRawSQLDB(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open raw db: %v", err)
+	}
+	// Schema from before citations: plan is allowed, citation is not
+	planSchema := `CREATE TABLE ledger_events (event_id TEXT PRIMARY KEY, conversation_key TEXT NOT NULL, thread_id TEXT, direction TEXT NOT NULL, author TEXT NOT NULL, timestamp TEXT NOT NULL, type TEXT NOT NULL, text TEXT, raw_transport TEXT, raw_payload_ref TEXT, actor_principal_id TEXT, actor_member_id TEXT, CHECK (direction IN ('inbound_to_agent', 'outbound_from_agent')), CHECK (type IN ('message', 'tool_call', 'tool_result', 'system', 'error', 'plan')));`
+	if _, err := db.Exec(planSchema); err != nil {
+		t.Fatalf("failed to create old schema: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close raw db: %v", err)
+	}
+
+	store, err := NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	text := `{"index":1,"title":"RFC 9110","uri":"https://www.rfc-editor.org/rfc/rfc9110"}`
+	citation := &LedgerEvent{
+		ID:              "evt-1",
+		ConversationKey: "agent-1",
+		Direction:       EventDirectionOutbound,
+		Author:          "agent",
+		Timestamp:       time.Now(),
+		Type:            EventTypeCitation,
+		Text:            &text,
+	}
+	if err := store.SaveEvent(context.Background(), citation); err != nil {
+		t.Fatalf("SaveEvent with citation type failed after migration: %v", err)
+	}
+}
+
+func TestMigrateLedgerEventsCheckConstraint_AddsToolCheck(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := openRawSQLDB(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open raw db: %v", err)
+	}
+	// Schema from before dry runs: citation is allowed, tool_check is not
+	citationSchema := `CREATE TABLE ledger_events (event_id TEXT PRIMARY KEY, conversation_key TEXT NOT NULL, thread_id TEXT, direction TEXT NOT NULL, author TEXT NOT NULL, timestamp TEXT NOT NULL, type TEXT NOT NULL, text TEXT, raw_transport TEXT, raw_payload_ref TEXT, actor_principal_id TEXT, actor_member_id TEXT, CHECK (direction IN ('inbound_to_agent', 'outbound_from_agent')), CHECK (type IN ('message', 'tool_call', 'tool_result', 'system', 'error', 'plan', 'citation')));`
+	if _, err := db.Exec(citationSchema); err != nil {
+		t.Fatalf("failed to create old schema: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close raw db: %v", err)
+	}
+
+	store, err := NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	text := `{"verdict":"allowed","tool":"log_entry"}`
+	check := &LedgerEvent{
+		ID:              "evt-1",
+		ConversationKey: "agent-1",
+		Direction:       EventDirectionOutbound,
+		Author:          "agent-1",
+		Timestamp:       time.Now(),
+		Type:            EventTypeToolCheck,
+		Text:            &text,
+	}
+	if err := store.SaveEvent(context.Background(), check); err != nil {
+		t.Fatalf("SaveEvent with tool_check type failed after migration: %v", err)
+	}
+}
+
+func TestMigrateLedgerEventsCheckConstraint_AddsToolPolicyKeepingSearch(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := openRawSQLDB(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open raw db: %v", err)
+	}
+	// Schema from before tool policies, already indexed for search, with
+	// rowid gaps a plain copy would close up.
+	stmts := []string{
+		`CREATE TABLE ledger_events (event_id TEXT PRIMARY KEY, conversation_key TEXT NOT NULL, thread_id TEXT, direction TEXT NOT NULL, author TEXT NOT NULL, timestamp TEXT NOT NULL, type TEXT NOT NULL, text TEXT, raw_transport TEXT, raw_payload_ref TEXT, actor_principal_id TEXT, actor_member_id TEXT, CHECK (direction IN ('inbound_to_agent', 'outbound_from_agent')), CHECK (type IN ('message', 'tool_call', 'tool_result', 'system', 'error', 'plan', 'citation', 'tool_check', 'capability_warning')))`,
+		schemaSearchSQL,
+		`INSERT INTO ledger_events (rowid, event_id, conversation_key, direction, author, timestamp, type, text) VALUES (3, 'evt-a', 'agent-1', 'inbound_to_agent', 'alice', '2026-01-01T00:00:00Z', 'message', 'alpha')`,
+		`INSERT INTO ledger_events (rowid, event_id, conversation_key, direction, author, timestamp, type, text) VALUES (7, 'evt-b', 'agent-1', 'inbound_to_agent', 'alice', '2026-01-01T00:01:00Z', 'message', 'bravo')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("setting up old schema: %v", err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close raw db: %v", err)
+	}
+
+	store, err := NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	text := `{"action":"allow","tool":"log_entry","rule_id":"tp-1"}`
+	if err := store.SaveEvent(context.Background(), &LedgerEvent{
+		ID: "evt-c", ConversationKey: "agent-1", Direction: EventDirectionOutbound, Author: "agent-1",
+		Timestamp: time.Now(), Type: EventTypeToolPolicy, Text: &text,
+	}); err != nil {
+		t.Fatalf("SaveEvent with tool_policy type failed after migration: %v", err)
+	}
+	results, err := store.SearchMessages(context.Background(), "bravo", SearchFilter{})
+	if err != nil || len(results) != 1 || results[0].EventID != "evt-b" {
+		t.Errorf("search after migration = %+v, %v; want evt-b", results, err)
+	}
+}
+
 // openRawSQLDB opens a raw sql.DB connection for test setup.
 func openRawSQLDB(path string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite", path)
@@ -690,3 +961,42 @@ func openRawSQLDB(path string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+func TestMigrateMailThreads(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := openRawSQLDB(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open raw db: %v", err)
+	}
+	oldSchema := `
+		CREATE TABLE agent_mail (id TEXT PRIMARY KEY, from_agent_id TEXT NOT NULL, to_agent_id TEXT NOT NULL, subject TEXT NOT NULL, content TEXT NOT NULL, read_at TEXT, created_at TEXT NOT NULL);
+		INSERT INTO agent_mail (id, from_agent_id, to_agent_id, subject, content, created_at)
+		VALUES ('mail-1', 'agent-1', 'agent-2', 'Hello', 'World', '2024-01-01T00:00:00Z');
+	`
+	if _, err := db.Exec(oldSchema); err != nil {
+		t.Fatalf("failed to create old schema: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close raw db: %v", err)
+	}
+
+	store, err := NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	mail, err := store.GetMail(ctx, "mail-1")
+	if err != nil {
+		t.Fatalf("existing mail not preserved: %v", err)
+	}
+	if mail.ThreadID != "mail-1" || mail.InReplyTo != "" {
+		t.Errorf("existing mail = %+v, want it to start its own thread", mail)
+	}
+	thread, err := store.GetMailThread(ctx, "mail-1")
+	if err != nil || len(thread) != 1 {
+		t.Errorf("GetMailThread = %v, %v", thread, err)
+	}
+}
@@ -0,0 +1,75 @@
+// ABOUTME: SQLStore, the database/sql implementation of every store interface
+// ABOUTME: One type serves SQLite and Postgres; dialect picks the queries that differ
+
+package store
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Database drivers Open accepts.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// dialect is the SQL flavor of the database behind a SQLStore.
+type dialect int
+
+const (
+	dialectSQLite dialect = iota
+	dialectPostgres
+)
+
+// like returns the case-insensitive LIKE operator. SQLite's LIKE ignores
+// ASCII case; Postgres needs ILIKE for the same matches.
+func (d dialect) like() string {
+	if d == dialectPostgres {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// SQLStore implements the store interfaces over database/sql. NewSQLiteStore
+// and NewPostgresStore open one; most queries are shared, and the few whose
+// syntax differs between the databases are chosen by dialect.
+type SQLStore struct {
+	db      *timedDB
+	dialect dialect
+	logger  *slog.Logger
+
+	// searchEnabled is false when SQLite lacks FTS5; see initSearch.
+	searchEnabled bool
+
+	// now returns the current time for note expiry; nil means time.Now.
+	// Tests replace it to step the clock.
+	now func() time.Time
+}
+
+// SQLiteStore is the name SQLStore had before it also served Postgres.
+//
+// Deprecated: use SQLStore.
+type SQLiteStore = SQLStore
+
+// Open opens the store for driver: a SQLite file at source, or the Postgres
+// database source names as a DSN. An empty driver means SQLite.
+func Open(driver, source string) (*SQLStore, error) {
+	switch driver {
+	case "", DriverSQLite:
+		return NewSQLiteStore(source)
+	case DriverPostgres:
+		return NewPostgresStore(source)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+}
+
+// clock returns the current time in UTC.
+func (s *SQLStore) clock() time.Time {
+	if s.now != nil {
+		return s.now().UTC()
+	}
+	return time.Now().UTC()
+}
@@ -0,0 +1,539 @@
+// ABOUTME: Export and import of the whole gateway state as a tarball of JSONL tables
+// ABOUTME: Used to move a gateway between machines; imports run in one transaction
+
+package store
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// stateManifestName is the first entry of a state export.
+const stateManifestName = "manifest.json"
+
+// stateTables lists the tables a state export covers, parents before the
+// tables whose foreign keys point at them. The FTS index is not exported;
+// importing ledger_events rebuilds it.
+var stateTables = []string{
+	"threads", "messages", "agent_state", "channel_bindings",
+	"principals", "roles", "principal_capabilities", "tool_overrides", "audit_log", "api_tokens",
+	"ledger_events", "bindings",
+	"admin_users", "admin_sessions", "admin_invites", "link_codes", "webauthn_credentials", "admin_oidc_identities", "login_attempts",
+	"log_entries", "todos", "project_members", "delegation_targets", "bbs_posts", "agent_mail", "agent_notes", "builtin_write_quota",
+	"message_usage", "usage_agent_hourly", "secrets", "deliveries",
+	"tool_snapshots", "tool_changes", "agent_sessions", "agent_inflight_requests",
+	"email_messages", "feature_flags", "attachments", "scheduled_messages",
+	"agent_groups", "agent_group_members", "tool_policies", "tool_policy_settings",
+	"questions", "idempotency_keys",
+}
+
+// authStateTables are left out of exports and imports unless asked for:
+// their rows let whoever holds them sign in to the admin UI.
+var authStateTables = map[string]bool{
+	"admin_sessions":       true,
+	"webauthn_credentials": true,
+}
+
+// ErrStateSchemaTooNew is returned when importing an export made by a
+// gateway with migrations this one does not know.
+var ErrStateSchemaTooNew = errors.New("state export is from a newer schema")
+
+// StateManifest describes a state export.
+type StateManifest struct {
+	SchemaVersion int          `json:"schema_version"`
+	CreatedAt     time.Time    `json:"created_at"`
+	Tables        []StateTable `json:"tables"`
+}
+
+// StateTable is one exported table: its columns, in the order they were
+// read, and how many rows its JSONL file holds.
+type StateTable struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Rows    int      `json:"rows"`
+}
+
+// ExportOptions controls ExportState.
+type ExportOptions struct {
+	// IncludeAuth also exports admin sessions and WebAuthn credentials.
+	IncludeAuth bool
+}
+
+// ConflictMode decides what ImportState does with a row whose key already
+// exists.
+type ConflictMode int
+
+const (
+	// ConflictSkipExisting keeps the existing row.
+	ConflictSkipExisting ConflictMode = iota
+	// ConflictOverwrite replaces the existing row's values with the imported ones.
+	ConflictOverwrite
+)
+
+// ImportOptions controls ImportState.
+type ImportOptions struct {
+	Conflict ConflictMode
+	// IncludeAuth also imports admin sessions and WebAuthn credentials
+	// when the export has them.
+	IncludeAuth bool
+}
+
+// ImportedTable counts what happened to one table's rows.
+type ImportedTable struct {
+	Name    string
+	Written int // inserted, or overwritten under ConflictOverwrite
+	Skipped int // already present under ConflictSkipExisting
+}
+
+// ImportResult is what ImportState did.
+type ImportResult struct {
+	Manifest StateManifest
+	Tables   []ImportedTable
+}
+
+// stateColumn is a column as PRAGMA table_info reports it. Postgres columns
+// are described the same way, bytea as BLOB.
+type stateColumn struct {
+	name     string
+	declType string
+}
+
+// isTime reports whether the driver would parse the column into a
+// time.Time. Those are read as text so timestamps keep their stored form.
+func (c stateColumn) isTime() bool {
+	switch strings.ToUpper(c.declType) {
+	case "DATE", "DATETIME", "TIMESTAMP":
+		return true
+	}
+	return false
+}
+
+func (c stateColumn) isBlob() bool {
+	return strings.EqualFold(c.declType, "BLOB")
+}
+
+// queryer is the part of a transaction the state helpers use.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// stateColumns returns the columns of table in the current schema. The
+// identity column Postgres tables have in place of SQLite's rowid is left
+// out, so exports from either database import into the other.
+func stateColumns(ctx context.Context, q queryer, d dialect, table string) ([]stateColumn, error) {
+	query := `SELECT name, type FROM pragma_table_info(?)`
+	if d == dialectPostgres {
+		query = `
+			SELECT column_name, CASE data_type WHEN 'bytea' THEN 'BLOB' ELSE upper(data_type) END
+			FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = ? AND column_name <> 'rowid'
+			ORDER BY ordinal_position`
+	}
+	rows, err := q.QueryContext(ctx, query, table)
+	if err != nil {
+		return nil, fmt.Errorf("reading columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var cols []stateColumn
+	for rows.Next() {
+		var c stateColumn
+		if err := rows.Scan(&c.name, &c.declType); err != nil {
+			return nil, fmt.Errorf("scanning columns of %s: %w", table, err)
+		}
+		cols = append(cols, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading columns of %s: %w", table, err)
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("table %s does not exist", table)
+	}
+	return cols, nil
+}
+
+// quoteIdent quotes a table or column name for SQL.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// ExportState writes every state table to w as a gzipped tarball: a
+// manifest.json, then tables/<name>.jsonl with one JSON object per row.
+// Timestamps are copied as stored and BLOBs are base64. The export reads
+// from a single transaction, so it is consistent while the gateway runs.
+func (s *SQLStore) ExportState(ctx context.Context, w io.Writer, opts ExportOptions) (*StateManifest, error) {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	manifest := &StateManifest{SchemaVersion: SchemaVersion(), CreatedAt: time.Now().UTC()}
+	columns := make(map[string][]stateColumn)
+	for _, table := range stateTables {
+		if authStateTables[table] && !opts.IncludeAuth {
+			continue
+		}
+		cols, err := stateColumns(ctx, tx, s.dialect, table)
+		if err != nil {
+			return nil, err
+		}
+		var count int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+quoteIdent(table)).Scan(&count); err != nil {
+			return nil, fmt.Errorf("counting %s: %w", table, err)
+		}
+		names := make([]string, len(cols))
+		for i, c := range cols {
+			names[i] = c.name
+		}
+		columns[table] = cols
+		manifest.Tables = append(manifest.Tables, StateTable{Name: table, Columns: names, Rows: count})
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding manifest: %w", err)
+	}
+	if err := writeTarFile(tw, stateManifestName, manifest.CreatedAt, data); err != nil {
+		return nil, err
+	}
+
+	for _, table := range manifest.Tables {
+		// tar needs each file's size up front, so a table is encoded in
+		// full before it is written.
+		var buf bytes.Buffer
+		if err := exportTable(ctx, tx, s.dialect, table.Name, columns[table.Name], &buf); err != nil {
+			return nil, err
+		}
+		if err := writeTarFile(tw, "tables/"+table.Name+".jsonl", manifest.CreatedAt, buf.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("closing tarball: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("closing gzip: %w", err)
+	}
+	return manifest, nil
+}
+
+// exportTable writes each row of table to w as a JSON object keyed by
+// column name, in insertion order on SQLite and key order on Postgres.
+func exportTable(ctx context.Context, tx *dbTx, d dialect, table string, cols []stateColumn, w io.Writer) error {
+	exprs := make([]string, len(cols))
+	for i, c := range cols {
+		exprs[i] = quoteIdent(c.name)
+		if c.isTime() {
+			exprs[i] = "CAST(" + exprs[i] + " AS TEXT)"
+		}
+	}
+	order := "rowid"
+	if d == dialectPostgres {
+		order = "1"
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT `+strings.Join(exprs, ", ")+` FROM `+quoteIdent(table)+` ORDER BY `+order)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+	values := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return fmt.Errorf("scanning %s: %w", table, err)
+		}
+		row := make(map[string]any, len(cols))
+		for i, c := range cols {
+			row[c.name] = values[i]
+			if b, ok := values[i].([]byte); ok && !c.isBlob() {
+				// Text read back as bytes; keep it readable.
+				row[c.name] = string(b)
+			}
+		}
+		if err := enc.Encode(row); err != nil {
+			return fmt.Errorf("encoding %s row: %w", table, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading %s: %w", table, err)
+	}
+	return nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, modTime time.Time, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing %s header: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// ImportState loads an export written by ExportState. It checks the
+// manifest's schema version first and then imports every table in one
+// transaction, so a failure leaves the store unchanged. Columns the export
+// lacks get their defaults; a column this schema lacks is an error.
+func (s *SQLStore) ImportState(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("opening gzip: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+	if hdr.Name != stateManifestName {
+		return nil, fmt.Errorf("first entry is %q, want %s", hdr.Name, stateManifestName)
+	}
+	var manifest StateManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("decoding manifest: %w", err)
+	}
+	if manifest.SchemaVersion > SchemaVersion() {
+		return nil, fmt.Errorf("%w: export has schema version %d, this gateway %d", ErrStateSchemaTooNew, manifest.SchemaVersion, SchemaVersion())
+	}
+	if manifest.SchemaVersion < 1 {
+		return nil, fmt.Errorf("manifest has invalid schema version %d", manifest.SchemaVersion)
+	}
+
+	known := make(map[string]bool, len(stateTables))
+	for _, table := range stateTables {
+		known[table] = true
+	}
+	tables := make(map[string]StateTable, len(manifest.Tables))
+	for _, table := range manifest.Tables {
+		if !known[table.Name] {
+			return nil, fmt.Errorf("export has unknown table %q", table.Name)
+		}
+		tables[table.Name] = table
+	}
+
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Foreign keys are checked at commit, so rows may arrive in any order.
+	deferKeys := `PRAGMA defer_foreign_keys = ON`
+	if s.dialect == dialectPostgres {
+		deferKeys = `SET CONSTRAINTS ALL DEFERRED`
+	}
+	if _, err := tx.ExecContext(ctx, deferKeys); err != nil {
+		return nil, fmt.Errorf("deferring foreign keys: %w", err)
+	}
+
+	result := &ImportResult{Manifest: manifest}
+	seen := make(map[string]bool, len(tables))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading export: %w", err)
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(hdr.Name, "tables/"), ".jsonl")
+		table, ok := tables[name]
+		if !ok || hdr.Name != "tables/"+name+".jsonl" {
+			return nil, fmt.Errorf("export has unexpected entry %q", hdr.Name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("export has %s twice", hdr.Name)
+		}
+		seen[name] = true
+		if authStateTables[name] && !opts.IncludeAuth {
+			continue
+		}
+
+		imported, err := importTable(ctx, tx, s.dialect, table, tr, opts.Conflict)
+		if err != nil {
+			return nil, err
+		}
+		result.Tables = append(result.Tables, imported)
+	}
+	for name := range tables {
+		if !seen[name] {
+			return nil, fmt.Errorf("export is missing tables/%s.jsonl", name)
+		}
+	}
+	if s.dialect == dialectPostgres && seen["tool_changes"] {
+		// Imported IDs bypass the identity sequence; move it past them.
+		if _, err := tx.ExecContext(ctx, `SELECT setval(pg_get_serial_sequence('tool_changes', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM tool_changes`); err != nil {
+			return nil, fmt.Errorf("resetting tool_changes id sequence: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing import: %w", err)
+	}
+
+	s.logger.Info("imported state",
+		"schema_version", manifest.SchemaVersion,
+		"exported_at", manifest.CreatedAt,
+		"tables", len(result.Tables))
+	return result, nil
+}
+
+// importTable inserts the JSONL rows of one table.
+func importTable(ctx context.Context, tx *dbTx, d dialect, table StateTable, r io.Reader, mode ConflictMode) (ImportedTable, error) {
+	imported := ImportedTable{Name: table.Name}
+
+	current, err := stateColumns(ctx, tx, d, table.Name)
+	if err != nil {
+		return imported, err
+	}
+	byName := make(map[string]stateColumn, len(current))
+	for _, c := range current {
+		byName[c.name] = c
+	}
+	cols := make([]stateColumn, len(table.Columns))
+	quoted := make([]string, len(table.Columns))
+	for i, name := range table.Columns {
+		c, ok := byName[name]
+		if !ok {
+			return imported, fmt.Errorf("table %s has no column %s", table.Name, name)
+		}
+		cols[i] = c
+		quoted[i] = quoteIdent(name)
+	}
+
+	query := `INSERT INTO ` + quoteIdent(table.Name) + ` (` + strings.Join(quoted, ", ") + `) VALUES (` +
+		strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ") + `) ON CONFLICT `
+	if mode == ConflictOverwrite {
+		if d == dialectPostgres {
+			// Postgres needs the conflict target SQLite infers.
+			key, err := primaryKeyColumns(ctx, tx, table.Name)
+			if err != nil {
+				return imported, err
+			}
+			query += `(` + strings.Join(key, ", ") + `) `
+		}
+		sets := make([]string, len(quoted))
+		for i, q := range quoted {
+			sets[i] = q + " = excluded." + q
+		}
+		query += `DO UPDATE SET ` + strings.Join(sets, ", ")
+	} else {
+		query += `DO NOTHING`
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return imported, fmt.Errorf("preparing %s insert: %w", table.Name, err)
+	}
+	defer stmt.Close()
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	args := make([]any, len(cols))
+	rows := 0
+	for {
+		var row map[string]any
+		if err := dec.Decode(&row); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return imported, fmt.Errorf("decoding %s row %d: %w", table.Name, rows+1, err)
+		}
+		rows++
+		for i, c := range cols {
+			v, err := stateValue(c, row[c.name])
+			if err != nil {
+				return imported, fmt.Errorf("%s row %d: %w", table.Name, rows, err)
+			}
+			args[i] = v
+		}
+		res, err := stmt.ExecContext(ctx, args...)
+		if err != nil {
+			return imported, fmt.Errorf("inserting %s row %d: %w", table.Name, rows, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			imported.Skipped++
+		} else {
+			imported.Written++
+		}
+	}
+	if rows != table.Rows {
+		return imported, fmt.Errorf("%s has %d rows, manifest says %d", table.Name, rows, table.Rows)
+	}
+	return imported, nil
+}
+
+// primaryKeyColumns returns the quoted primary key columns of a Postgres
+// table.
+func primaryKeyColumns(ctx context.Context, q queryer, table string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT a.attname
+		FROM pg_index i
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = CAST(? AS regclass) AND i.indisprimary
+		ORDER BY array_position(i.indkey, a.attnum)`, quoteIdent(table))
+	if err != nil {
+		return nil, fmt.Errorf("reading primary key of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var key []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning primary key of %s: %w", table, err)
+		}
+		key = append(key, quoteIdent(name))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading primary key of %s: %w", table, err)
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("table %s has no primary key", table)
+	}
+	return key, nil
+}
+
+// stateValue converts a decoded JSON value back to what the column stores.
+func stateValue(c stateColumn, v any) (any, error) {
+	switch v := v.(type) {
+	case nil, bool:
+		return v, nil
+	case string:
+		if !c.isBlob() {
+			return v, nil
+		}
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", c.name, err)
+		}
+		return b, nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		return v.Float64()
+	default:
+		return nil, fmt.Errorf("column %s has unsupported value %T", c.name, v)
+	}
+}
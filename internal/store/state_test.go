@@ -0,0 +1,304 @@
+// ABOUTME: Tests for exporting and importing gateway state
+// ABOUTME: Round-trips in-memory stores and checks conflict modes, auth tables and schema checks
+
+package store
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMemoryStore(t *testing.T) *SQLStore {
+	t.Helper()
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+// seedState fills s with a little of everything a migration must keep.
+func seedState(t *testing.T, s *SQLStore) {
+	t.Helper()
+	ctx := context.Background()
+	created := time.Date(2026, 3, 14, 15, 9, 26, 535897000, time.UTC)
+	seen := created.Add(time.Hour)
+
+	require.NoError(t, s.CreatePrincipal(ctx, &Principal{
+		ID: "agent-1", Type: PrincipalTypeAgent, PubkeyFP: strings.Repeat("ab", 32), DisplayName: "Agent One",
+		Status: PrincipalStatusApproved, CreatedAt: created, LastSeen: &seen, Metadata: map[string]any{"team": "red"},
+	}))
+	require.NoError(t, s.CreatePrincipal(ctx, &Principal{
+		ID: "agent-2", Type: PrincipalTypeAgent, PubkeyFP: strings.Repeat("cd", 32), DisplayName: "Agent Two",
+		Status: PrincipalStatusApproved, CreatedAt: created,
+	}))
+	require.NoError(t, s.AddRole(ctx, RoleSubjectPrincipal, "agent-1", RoleMember))
+	require.NoError(t, s.CreateBindingV2(ctx, &Binding{
+		ID: "binding-1", Frontend: "slack", ChannelID: "C1", AgentID: "agent-1", WorkingDir: "/srv/work",
+		CreatedAt: created, MaxResponseDuration: 90 * time.Second, FallbackAgentIDs: []string{"agent-2"},
+	}))
+	require.NoError(t, s.CreateThread(ctx, &Thread{
+		ID: "thread-1", FrontendName: "slack", ExternalID: "C1", AgentID: "agent-1", CreatedAt: created, UpdatedAt: seen,
+	}))
+	text := "hello"
+	threadID := "thread-1"
+	require.NoError(t, s.SaveEvent(ctx, &LedgerEvent{
+		ID: "event-1", ConversationKey: "agent-1", ThreadID: &threadID, Direction: EventDirectionInbound,
+		Author: "alice", Timestamp: created, Type: EventTypeMessage, Text: &text,
+	}))
+	require.NoError(t, s.SetNote(ctx, &AgentNote{ID: "note-1", AgentID: "agent-1", Key: "todo", Value: "ship it", CreatedAt: created, UpdatedAt: seen}))
+
+	require.NoError(t, s.CreateAdminUser(ctx, &AdminUser{ID: "admin-1", Username: "root", DisplayName: "Root", CreatedAt: created}))
+	require.NoError(t, s.CreateAdminSession(ctx, &AdminSession{ID: "session-1", UserID: "admin-1", CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}))
+	require.NoError(t, s.CreateWebAuthnCredential(ctx, &WebAuthnCredential{
+		ID: "cred-1", UserID: "admin-1", CredentialID: []byte{0, 1, 2, 0xff}, PublicKey: []byte("key"), Transports: `["usb"]`, SignCount: 7, CreatedAt: created,
+	}))
+}
+
+func exportState(t *testing.T, s *SQLStore, opts ExportOptions) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	_, err := s.ExportState(context.Background(), &buf, opts)
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := newMemoryStore(t)
+	seedState(t, src)
+
+	var buf bytes.Buffer
+	manifest, err := src.ExportState(ctx, &buf, ExportOptions{IncludeAuth: true})
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion(), manifest.SchemaVersion)
+
+	dst := newMemoryStore(t)
+	result, err := dst.ImportState(ctx, &buf, ImportOptions{IncludeAuth: true})
+	require.NoError(t, err)
+	assert.True(t, manifest.CreatedAt.Equal(result.Manifest.CreatedAt))
+
+	// Every table reads back exactly as exported, timestamps included.
+	assert.Equal(t, dumpTables(t, src), dumpTables(t, dst))
+
+	p, err := dst.GetPrincipal(ctx, "agent-1")
+	require.NoError(t, err)
+	want, _ := src.GetPrincipal(ctx, "agent-1")
+	assert.Equal(t, want, p)
+
+	thread, err := dst.GetThread(ctx, "thread-1")
+	require.NoError(t, err)
+	wantThread, _ := src.GetThread(ctx, "thread-1")
+	assert.Equal(t, wantThread, thread)
+
+	b, err := dst.GetBindingByID(ctx, "binding-1")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, b.MaxResponseDuration)
+	assert.Equal(t, []string{"agent-2"}, b.FallbackAgentIDs)
+
+	note, err := dst.GetNote(ctx, "agent-1", "todo")
+	require.NoError(t, err)
+	assert.Equal(t, "ship it", note.Value)
+
+	creds, err := dst.GetWebAuthnCredentialsByUser(ctx, "admin-1")
+	require.NoError(t, err)
+	require.Len(t, creds, 1)
+	assert.Equal(t, []byte{0, 1, 2, 0xff}, creds[0].CredentialID)
+
+	if src.searchEnabled {
+		results, err := dst.SearchMessages(ctx, "hello", SearchFilter{})
+		require.NoError(t, err)
+		assert.Len(t, results, 1, "imported events should be searchable")
+	}
+}
+
+// dumpTables reads every row of every state table of s as JSON.
+func dumpTables(t *testing.T, s *SQLStore) map[string][]string {
+	t.Helper()
+	out := make(map[string][]string)
+	for _, table := range stateTables {
+		rows, err := s.db.Query(`SELECT * FROM ` + quoteIdent(table) + ` ORDER BY rowid`)
+		require.NoError(t, err)
+		cols, err := rows.Columns()
+		require.NoError(t, err)
+		for rows.Next() {
+			vals := make([]any, len(cols))
+			ptrs := make([]any, len(cols))
+			for i := range vals {
+				ptrs[i] = &vals[i]
+			}
+			require.NoError(t, rows.Scan(ptrs...))
+			row, err := json.Marshal(vals)
+			require.NoError(t, err)
+			out[table] = append(out[table], string(row))
+		}
+		require.NoError(t, rows.Err())
+		_ = rows.Close()
+	}
+	return out
+}
+
+func TestStateExportSkipsAuthTablesByDefault(t *testing.T) {
+	ctx := context.Background()
+	src := newMemoryStore(t)
+	seedState(t, src)
+	data := exportState(t, src, ExportOptions{})
+
+	names := tarNames(t, data)
+	assert.NotContains(t, names, "tables/admin_sessions.jsonl")
+	assert.NotContains(t, names, "tables/webauthn_credentials.jsonl")
+	assert.Contains(t, names, "tables/admin_users.jsonl")
+
+	// An export that has them still leaves them out of an import unless asked.
+	withAuth := exportState(t, src, ExportOptions{IncludeAuth: true})
+	dst := newMemoryStore(t)
+	_, err := dst.ImportState(ctx, bytes.NewReader(withAuth), ImportOptions{})
+	require.NoError(t, err)
+	creds, err := dst.GetWebAuthnCredentialsByUser(ctx, "admin-1")
+	require.NoError(t, err)
+	assert.Empty(t, creds)
+	_, err = dst.GetAdminSession(ctx, "session-1")
+	assert.Error(t, err)
+}
+
+func TestStateImportConflictModes(t *testing.T) {
+	ctx := context.Background()
+	src := newMemoryStore(t)
+	seedState(t, src)
+	data := exportState(t, src, ExportOptions{})
+
+	newTarget := func() *SQLStore {
+		dst := newMemoryStore(t)
+		require.NoError(t, dst.SetNote(ctx, &AgentNote{ID: "note-1", AgentID: "agent-1", Key: "todo", Value: "local edit", CreatedAt: time.Now(), UpdatedAt: time.Now()}))
+		return dst
+	}
+
+	dst := newTarget()
+	result, err := dst.ImportState(ctx, bytes.NewReader(data), ImportOptions{Conflict: ConflictSkipExisting})
+	require.NoError(t, err)
+	note, err := dst.GetNote(ctx, "agent-1", "todo")
+	require.NoError(t, err)
+	assert.Equal(t, "local edit", note.Value)
+	assert.Equal(t, ImportedTable{Name: "agent_notes", Written: 0, Skipped: 1}, importedTable(result, "agent_notes"))
+	assert.Equal(t, ImportedTable{Name: "threads", Written: 1}, importedTable(result, "threads"))
+
+	dst = newTarget()
+	result, err = dst.ImportState(ctx, bytes.NewReader(data), ImportOptions{Conflict: ConflictOverwrite})
+	require.NoError(t, err)
+	note, err = dst.GetNote(ctx, "agent-1", "todo")
+	require.NoError(t, err)
+	assert.Equal(t, "ship it", note.Value)
+	assert.Equal(t, ImportedTable{Name: "agent_notes", Written: 1}, importedTable(result, "agent_notes"))
+}
+
+func importedTable(result *ImportResult, name string) ImportedTable {
+	for _, table := range result.Tables {
+		if table.Name == name {
+			return table
+		}
+	}
+	return ImportedTable{}
+}
+
+func TestStateImportRejectsNewerSchema(t *testing.T) {
+	src := newMemoryStore(t)
+	seedState(t, src)
+	data := rewriteManifest(t, exportState(t, src, ExportOptions{}), func(m *StateManifest) {
+		m.SchemaVersion = SchemaVersion() + 1
+	})
+
+	dst := newMemoryStore(t)
+	_, err := dst.ImportState(context.Background(), bytes.NewReader(data), ImportOptions{})
+	assert.ErrorIs(t, err, ErrStateSchemaTooNew)
+}
+
+func TestStateImportIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	src := newMemoryStore(t)
+	seedState(t, src)
+	// The manifest promises a row the last table does not have.
+	data := rewriteManifest(t, exportState(t, src, ExportOptions{}), func(m *StateManifest) {
+		m.Tables[len(m.Tables)-1].Rows++
+	})
+
+	dst := newMemoryStore(t)
+	_, err := dst.ImportState(ctx, bytes.NewReader(data), ImportOptions{})
+	require.Error(t, err)
+	_, err = dst.GetThread(ctx, "thread-1")
+	assert.ErrorIs(t, err, ErrNotFound, "a failed import must not leave earlier tables behind")
+}
+
+// TestStateTablesCoverSchema keeps stateTables in step with the schema: a
+// new table must be exported, or the export would silently lose it.
+func TestStateTablesCoverSchema(t *testing.T) {
+	s := newMemoryStore(t)
+	rows, err := s.db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name NOT LIKE 'ledger_events_fts%'`)
+	require.NoError(t, err)
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		tables = append(tables, name)
+	}
+	require.NoError(t, rows.Err())
+	assert.ElementsMatch(t, stateTables, tables)
+}
+
+func tarNames(t *testing.T, data []byte) []string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return names
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+	}
+}
+
+// rewriteManifest returns data with its manifest edited by fn.
+func rewriteManifest(t *testing.T, data []byte, fn func(*StateManifest)) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	var out bytes.Buffer
+	gzw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gzw)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		if hdr.Name == stateManifestName {
+			var m StateManifest
+			require.NoError(t, json.Unmarshal(body, &m))
+			fn(&m)
+			body, err = json.Marshal(m)
+			require.NoError(t, err)
+		}
+		require.NoError(t, writeTarFile(tw, hdr.Name, hdr.ModTime, body))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+	return out.Bytes()
+}
@@ -15,6 +15,9 @@ var ErrNotFound = errors.New("not found")
 // ErrDuplicateThread is returned when trying to create a thread that already exists.
 var ErrDuplicateThread = errors.New("thread already exists")
 
+// ErrQuotaExceeded is returned when a daily builtin write quota is used up.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
 // Thread represents a conversation thread linking a frontend conversation to an agent.
 type Thread struct {
 	ID           string
@@ -23,6 +26,10 @@ type Thread struct {
 	AgentID      string
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	MergedInto   string // set on tombstones: the thread this one was merged into
+	SplitFrom    string // set on split-off threads: the thread they were cut from
+	Archived     bool   // hidden from thread listings until unarchived or sent a message
+	Pinned       bool   // listed ahead of unpinned threads
 }
 
 // MessageType constants for message types.
@@ -30,6 +37,7 @@ const (
 	MessageTypeMessage    = "message"     // Regular text message
 	MessageTypeToolUse    = "tool_use"    // Tool invocation
 	MessageTypeToolResult = "tool_result" // Tool result
+	MessageTypeCitation   = "citation"    // Source for the response; Content is its JSON
 )
 
 // Message represents a single message within a thread for audit/history purposes.
@@ -71,24 +79,73 @@ type Todo struct {
 	Priority    string // low, medium, high
 	Notes       string
 	DueDate     *time.Time
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	// AssigneeAgentID is the agent the todo is assigned to; empty if
+	// unassigned. Only todos in a project can be assigned.
+	AssigneeAgentID string
+	// Project shares the todo with the project's members. Todos without a
+	// project are private to the agent that created them.
+	Project   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
+// TodoFilter selects todos for ListTodos. Empty fields match any todo.
+type TodoFilter struct {
+	AgentID         string // the agent that created the todo
+	AssigneeAgentID string
+	Project         string
+	Status          string
+	Priority        string
+	// VisibleTo, if set, keeps only the todos this agent created or that
+	// are in a project it is a member of.
+	VisibleTo string
+}
+
+// ProjectMember records that an agent may see and work on a project's todos.
+type ProjectMember struct {
+	Project string
+	AgentID string
+	AddedAt time.Time
+}
+
+// DelegationTarget allows AgentID to delegate tasks to TargetAgentID.
+type DelegationTarget struct {
+	AgentID       string
+	TargetAgentID string
+	AddedAt       time.Time
+}
+
+// BBS author kinds.
+const (
+	BBSAuthorAgent = "agent" // posted by an agent through the bbs_* tools
+	BBSAuthorHuman = "human" // posted by an admin user from the web UI
+)
+
 // BBSPost represents a bulletin board post or reply.
 type BBSPost struct {
-	ID        string
-	AgentID   string
-	ThreadID  string // empty for top-level threads
-	Subject   string // required for threads, empty for replies
-	Content   string
-	CreatedAt time.Time
+	ID         string
+	AgentID    string // posting agent, or the human's synthetic ID (see BBSAuthorHuman)
+	ThreadID   string // empty for top-level threads
+	Subject    string // required for threads, empty for replies
+	Content    string
+	AuthorKind string // BBSAuthorAgent or BBSAuthorHuman; empty means agent
+	AuthorName string // display name for human posts, empty for agents
+	Pinned     bool   // listed ahead of unpinned threads; only set on threads
+	CreatedAt  time.Time
 }
 
 // BBSThread is a post with its replies.
 type BBSThread struct {
 	Post    *BBSPost
 	Replies []*BBSPost
+	HasMore bool // GetBBSThreadPage left out later replies
+}
+
+// BBSThreadFilter selects and pages the threads ListBBSThreads returns.
+type BBSThreadFilter struct {
+	Since  *time.Time // only threads started at or after Since
+	Offset int
+	Limit  int // 0 means 50
 }
 
 // AgentMail represents a message between agents.
@@ -98,6 +155,8 @@ type AgentMail struct {
 	ToAgentID   string
 	Subject     string
 	Content     string
+	InReplyTo   string // ID of the mail this replies to; empty for a new conversation
+	ThreadID    string // ID of the first mail in the conversation; its own ID for that mail
 	ReadAt      *time.Time
 	CreatedAt   time.Time
 }
@@ -108,6 +167,7 @@ type AgentNote struct {
 	AgentID   string
 	Key       string
 	Value     string
+	ExpiresAt *time.Time // nil if the note never expires
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -119,7 +179,9 @@ type Store interface {
 	GetThread(ctx context.Context, id string) (*Thread, error)
 	GetThreadByFrontendID(ctx context.Context, frontendName, externalID string) (*Thread, error)
 	UpdateThread(ctx context.Context, thread *Thread) error
-	ListThreads(ctx context.Context, limit int) ([]*Thread, error)
+	ListThreads(ctx context.Context, limit int, includeArchived bool) ([]*Thread, error)
+	ArchiveThread(ctx context.Context, id string, archived bool) error
+	SetThreadPinned(ctx context.Context, id string, pinned bool) error
 
 	// Messages (for audit/history)
 	SaveMessage(ctx context.Context, msg *Message) error
@@ -141,6 +203,8 @@ type Store interface {
 	ListBindingsV2(ctx context.Context, filter BindingFilter) ([]Binding, error)
 	DeleteBindingByID(ctx context.Context, id string) error
 	DeleteBindingByChannel(ctx context.Context, frontend, channelID string) error
+	SetBindingWorkspace(ctx context.Context, id, workspace string) error
+	SetBindingContext(ctx context.Context, id, text string) error
 
 	// Ledger events
 	SaveEvent(ctx context.Context, event *LedgerEvent) error
@@ -150,6 +214,14 @@ type Store interface {
 	ListEventsByActorDesc(ctx context.Context, principalID string, limit int) ([]*LedgerEvent, error)
 	GetEvents(ctx context.Context, params GetEventsParams) (*GetEventsResult, error)
 	GetEventsByThreadID(ctx context.Context, threadID string, limit int) ([]*LedgerEvent, error)
+	GetThreadEventsPage(ctx context.Context, threadID string, limit int, before string) ([]*LedgerEvent, string, error)
+	ForEachThreadEvent(ctx context.Context, threadID string, fn func(*LedgerEvent) error) error
+	SearchMessages(ctx context.Context, query string, filter SearchFilter) ([]*SearchResult, error)
+
+	// WithTx runs fn in one transaction, committing the calls it makes on tx
+	// if it returns nil and rolling them back otherwise. Nested calls reuse
+	// the outer transaction.
+	WithTx(ctx context.Context, fn func(tx Store) error) error
 
 	// Close releases any resources held by the store
 	Close() error
@@ -164,28 +236,48 @@ type BuiltinStore interface {
 	// Todos
 	CreateTodo(ctx context.Context, todo *Todo) error
 	GetTodo(ctx context.Context, id string) (*Todo, error)
-	ListTodos(ctx context.Context, agentID string, status, priority string) ([]*Todo, error)
+	ListTodos(ctx context.Context, filter TodoFilter) ([]*Todo, error)
 	ListAllTodos(ctx context.Context, limit int) ([]*Todo, error)
 	UpdateTodo(ctx context.Context, todo *Todo) error
 	DeleteTodo(ctx context.Context, id string) error
 
+	// Project membership for shared todos
+	AddProjectMember(ctx context.Context, project, agentID string) error
+	RemoveProjectMember(ctx context.Context, project, agentID string) error
+	IsProjectMember(ctx context.Context, project, agentID string) (bool, error)
+	ListProjectMembers(ctx context.Context) ([]*ProjectMember, error)
+
+	// Delegation allowlist for delegate_task
+	AddDelegationTarget(ctx context.Context, agentID, targetAgentID string) error
+	RemoveDelegationTarget(ctx context.Context, agentID, targetAgentID string) error
+	CanDelegate(ctx context.Context, agentID, targetAgentID string) (bool, error)
+	ListDelegationTargets(ctx context.Context) ([]*DelegationTarget, error)
+
 	// BBS
 	CreateBBSPost(ctx context.Context, post *BBSPost) error
 	GetBBSPost(ctx context.Context, id string) (*BBSPost, error)
-	ListBBSThreads(ctx context.Context, limit int) ([]*BBSPost, error)
+	ListBBSThreads(ctx context.Context, filter BBSThreadFilter) ([]*BBSPost, error)
 	GetBBSThread(ctx context.Context, threadID string) (*BBSThread, error)
+	GetBBSThreadPage(ctx context.Context, threadID, afterReplyID string, limit int) (*BBSThread, error)
+	SearchBBSPosts(ctx context.Context, query string, limit int) ([]*BBSPost, error)
+	SetBBSThreadPinned(ctx context.Context, threadID string, pinned bool) error
 
 	// Mail
 	SendMail(ctx context.Context, mail *AgentMail) error
 	GetMail(ctx context.Context, id string) (*AgentMail, error)
-	ListInbox(ctx context.Context, agentID string, unreadOnly bool, limit int) ([]*AgentMail, error)
+	ListInbox(ctx context.Context, agentID string, unreadOnly bool, threadID string, limit int) ([]*AgentMail, error)
+	GetMailThread(ctx context.Context, threadID string) ([]*AgentMail, error)
 	MarkMailRead(ctx context.Context, id string) error
 
 	// Notes
 	SetNote(ctx context.Context, note *AgentNote) error
 	GetNote(ctx context.Context, agentID, key string) (*AgentNote, error)
-	ListNotes(ctx context.Context, agentID string) ([]*AgentNote, error)
+	ListNotes(ctx context.Context, agentID, prefix string) ([]*AgentNote, error)
 	DeleteNote(ctx context.Context, agentID, key string) error
+
+	// Daily write quotas
+	ConsumeBuiltinQuota(ctx context.Context, agentID, kind, day string, limit int) (int, error)
+	GetBuiltinQuotaUsage(ctx context.Context, agentID, day string) (map[string]int, error)
 }
 
 // TokenUsage represents token consumption from an LLM response.
@@ -214,6 +306,39 @@ type UsageStats struct {
 	RequestCount    int64
 }
 
+// AgentUsage is one agent's aggregated token usage within a UsageFilter window.
+type AgentUsage struct {
+	AgentID string
+	UsageStats
+	LastRequestAt time.Time
+}
+
+// DailyAgentUsage is one agent's aggregated token usage on one UTC day.
+type DailyAgentUsage struct {
+	Day     string // YYYY-MM-DD
+	AgentID string
+	UsageStats
+}
+
+// ThreadUsageSummary is one thread's aggregated token usage within a window.
+type ThreadUsageSummary struct {
+	ThreadID     string
+	FrontendName string // empty if the thread row no longer exists
+	ExternalID   string
+	UsageStats
+	LastRequestAt time.Time
+}
+
+// RequestUsage is the token usage of a single agent request, summed over its
+// usage rows. MessageID is the reply it was linked to, if any.
+type RequestUsage struct {
+	RequestID string
+	MessageID string
+	AgentID   string
+	UsageStats
+	CreatedAt time.Time
+}
+
 // UsageFilter contains optional filters for usage queries.
 type UsageFilter struct {
 	AgentID *string
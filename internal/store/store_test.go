@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -11,9 +12,13 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// setupTestStore creates a temporary SQLite store for testing.
-func setupTestStore(t *testing.T) *SQLiteStore {
+// setupTestStore creates a temporary store for testing: SQLite, or Postgres
+// when TEST_POSTGRES_DSN is set (see newPostgresTestStore).
+func setupTestStore(t *testing.T) *SQLStore {
 	t.Helper()
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		return newPostgresTestStore(t, dsn)
+	}
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
 
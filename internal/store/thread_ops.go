@@ -0,0 +1,295 @@
+// ABOUTME: Administrative thread merge and split operations
+// ABOUTME: Re-parents events, messages, and usage between threads inside a single transaction
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidThreadOperation indicates a merge or split request that cannot be applied
+// (e.g., merging a thread into itself or splitting at the last event).
+var ErrInvalidThreadOperation = errors.New("invalid thread operation")
+
+// ThreadMergeResult describes the outcome of MergeThreads.
+type ThreadMergeResult struct {
+	TargetID      string
+	SourceIDs     []string
+	EventsMoved   int64
+	MessagesMoved int64
+	UsageMoved    int64
+	MergeEventID  string // ledger event recording the merge on the target
+}
+
+// ThreadSplitResult describes the outcome of SplitThread.
+type ThreadSplitResult struct {
+	OriginalID    string
+	NewThreadID   string
+	CutEventID    string
+	EventsMoved   int64
+	MessagesMoved int64
+	UsageMoved    int64
+	SplitEventID  string // ledger event recording the split on the new thread
+}
+
+// threadRow is the subset of thread columns needed by merge and split.
+type threadRow struct {
+	id           string
+	frontendName string
+	externalID   string
+	agentID      string
+	mergedInto   sql.NullString
+}
+
+// getThreadTx loads a thread inside a transaction.
+func getThreadTx(ctx context.Context, tx *dbTx, id string) (*threadRow, error) {
+	var t threadRow
+	err := tx.QueryRowContext(ctx,
+		`SELECT id, frontend_name, external_id, agent_id, merged_into FROM threads WHERE id = ?`, id,
+	).Scan(&t.id, &t.frontendName, &t.externalID, &t.agentID, &t.mergedInto)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("thread %s: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying thread %s: %w", id, err)
+	}
+	if t.mergedInto.Valid {
+		return nil, fmt.Errorf("thread %s was merged into %s: %w", id, t.mergedInto.String, ErrInvalidThreadOperation)
+	}
+	return &t, nil
+}
+
+// execCount runs an update and returns the number of affected rows.
+func execCount(ctx context.Context, tx *dbTx, query string, args ...any) (int64, error) {
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// insertSystemEventTx records a system ledger event on a thread.
+func insertSystemEventTx(ctx context.Context, tx *dbTx, thread *threadRow, text string, ts time.Time) (string, error) {
+	id := uuid.New().String()
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO ledger_events (event_id, conversation_key, thread_id, direction, author, timestamp, type, text)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, thread.agentID, thread.id, string(EventDirectionInbound), "system", ts.Format(time.RFC3339), string(EventTypeSystem), text)
+	if err != nil {
+		return "", fmt.Errorf("recording system event: %w", err)
+	}
+	return id, nil
+}
+
+// MergeThreads moves all events, messages, and usage records from the source
+// threads onto the target thread. Events keep their timestamps, so the target
+// reads chronologically. Each source is left as a tombstone whose MergedInto
+// points at the target, and earlier tombstones pointing at a source are
+// re-pointed so lookups never need more than one hop.
+func (s *SQLStore) MergeThreads(ctx context.Context, targetID string, sourceIDs []string) (*ThreadMergeResult, error) {
+	sources := slices.Compact(slices.Sorted(slices.Values(sourceIDs)))
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no source threads: %w", ErrInvalidThreadOperation)
+	}
+	if slices.Contains(sources, targetID) {
+		return nil, fmt.Errorf("cannot merge thread %s into itself: %w", targetID, ErrInvalidThreadOperation)
+	}
+
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	target, err := getThreadTx(ctx, tx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range sources {
+		if _, err := getThreadTx(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(sources)), ",")
+	args := make([]any, 0, len(sources)+1)
+	args = append(args, targetID)
+	for _, id := range sources {
+		args = append(args, id)
+	}
+
+	result := &ThreadMergeResult{TargetID: targetID, SourceIDs: sources}
+	if result.EventsMoved, err = execCount(ctx, tx,
+		`UPDATE ledger_events SET thread_id = ?, conversation_key = ? WHERE thread_id IN (`+placeholders+`)`,
+		append([]any{targetID, target.agentID}, args[1:]...)...); err != nil {
+		return nil, fmt.Errorf("moving events: %w", err)
+	}
+	if result.MessagesMoved, err = execCount(ctx, tx,
+		`UPDATE messages SET thread_id = ? WHERE thread_id IN (`+placeholders+`)`, args...); err != nil {
+		return nil, fmt.Errorf("moving messages: %w", err)
+	}
+	if result.UsageMoved, err = execCount(ctx, tx,
+		`UPDATE message_usage SET thread_id = ? WHERE thread_id IN (`+placeholders+`)`, args...); err != nil {
+		return nil, fmt.Errorf("moving usage: %w", err)
+	}
+
+	now := time.Now().UTC()
+	nowStr := now.Format(time.RFC3339)
+	tombstoneArgs := append([]any{targetID, nowStr}, args[1:]...)
+	tombstoneArgs = append(tombstoneArgs, args[1:]...)
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE threads SET merged_into = ?, updated_at = ? WHERE id IN (`+placeholders+`) OR merged_into IN (`+placeholders+`)`,
+		tombstoneArgs...); err != nil {
+		return nil, fmt.Errorf("marking tombstones: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE threads SET updated_at = ? WHERE id = ?`, nowStr, targetID); err != nil {
+		return nil, fmt.Errorf("touching target thread: %w", err)
+	}
+
+	text := fmt.Sprintf("Merged threads %s into this thread", strings.Join(sources, ", "))
+	if result.MergeEventID, err = insertSystemEventTx(ctx, tx, target, text, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing merge: %w", err)
+	}
+
+	s.logger.Info("merged threads",
+		"target_id", targetID,
+		"source_ids", sources,
+		"events_moved", result.EventsMoved,
+		"usage_moved", result.UsageMoved,
+	)
+	return result, nil
+}
+
+// SplitThread moves every event after cutEventID (in timestamp, event_id order)
+// into a new thread linked back to the original through SplitFrom. Messages and
+// usage records tied to the moved events follow them.
+func (s *SQLStore) SplitThread(ctx context.Context, threadID, cutEventID string) (*ThreadSplitResult, error) {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	original, err := getThreadTx(ctx, tx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	var cutTS string
+	var cutThread sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT timestamp, thread_id FROM ledger_events WHERE event_id = ?`, cutEventID).Scan(&cutTS, &cutThread)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("cut event %s: %w", cutEventID, ErrEventNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying cut event: %w", err)
+	}
+	if cutThread.String != threadID {
+		return nil, fmt.Errorf("event %s does not belong to thread %s: %w", cutEventID, threadID, ErrInvalidThreadOperation)
+	}
+
+	movedIDs, err := eventsAfterTx(ctx, tx, threadID, cutTS, cutEventID)
+	if err != nil {
+		return nil, err
+	}
+	if len(movedIDs) == 0 {
+		return nil, fmt.Errorf("no events after %s to split off: %w", cutEventID, ErrInvalidThreadOperation)
+	}
+
+	now := time.Now().UTC()
+	nowStr := now.Format(time.RFC3339)
+	newThread := &threadRow{
+		id:           uuid.New().String(),
+		frontendName: original.frontendName,
+		externalID:   original.externalID + "#split-" + uuid.New().String()[:8],
+		agentID:      original.agentID,
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO threads (id, frontend_name, external_id, agent_id, created_at, updated_at, split_from)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, newThread.id, newThread.frontendName, newThread.externalID, newThread.agentID, nowStr, nowStr, threadID); err != nil {
+		return nil, fmt.Errorf("creating split thread: %w", err)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(movedIDs)), ",")
+	args := make([]any, 0, len(movedIDs)+1)
+	args = append(args, newThread.id)
+	for _, id := range movedIDs {
+		args = append(args, id)
+	}
+
+	result := &ThreadSplitResult{OriginalID: threadID, NewThreadID: newThread.id, CutEventID: cutEventID}
+	if result.EventsMoved, err = execCount(ctx, tx,
+		`UPDATE ledger_events SET thread_id = ? WHERE event_id IN (`+placeholders+`)`, args...); err != nil {
+		return nil, fmt.Errorf("moving events: %w", err)
+	}
+	if result.MessagesMoved, err = execCount(ctx, tx,
+		`UPDATE messages SET thread_id = ? WHERE id IN (`+placeholders+`)`, args...); err != nil {
+		return nil, fmt.Errorf("moving messages: %w", err)
+	}
+	// Usage follows its final message; unlinked usage follows by time.
+	if result.UsageMoved, err = execCount(ctx, tx,
+		`UPDATE message_usage SET thread_id = ? WHERE thread_id = ? AND (message_id IN (`+placeholders+`) OR (message_id IS NULL AND created_at > ?))`,
+		append(append([]any{newThread.id, threadID}, args[1:]...), cutTS)...); err != nil {
+		return nil, fmt.Errorf("moving usage: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE threads SET updated_at = ? WHERE id = ?`, nowStr, threadID); err != nil {
+		return nil, fmt.Errorf("touching original thread: %w", err)
+	}
+
+	text := fmt.Sprintf("Split from thread %s after event %s", threadID, cutEventID)
+	if result.SplitEventID, err = insertSystemEventTx(ctx, tx, newThread, text, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing split: %w", err)
+	}
+
+	s.logger.Info("split thread",
+		"thread_id", threadID,
+		"new_thread_id", newThread.id,
+		"cut_event_id", cutEventID,
+		"events_moved", result.EventsMoved,
+	)
+	return result, nil
+}
+
+// eventsAfterTx returns IDs of a thread's events ordered after the cut point.
+func eventsAfterTx(ctx context.Context, tx *dbTx, threadID, cutTS, cutEventID string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT event_id FROM ledger_events
+		WHERE thread_id = ? AND (timestamp > ? OR (timestamp = ? AND event_id > ?))
+		ORDER BY timestamp ASC, event_id ASC
+	`, threadID, cutTS, cutTS, cutEventID)
+	if err != nil {
+		return nil, fmt.Errorf("querying events after cut: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning event id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating events after cut: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,252 @@
+// ABOUTME: Tests for thread merge and split operations
+// ABOUTME: Covers event ordering, usage reattachment, tombstones, and validation errors
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// seedThread creates a thread with one event per timestamp offset (in minutes from base).
+func seedThread(t *testing.T, s *SQLStore, id, externalID string, base time.Time, offsets ...int) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := s.CreateThread(ctx, &Thread{
+		ID:           id,
+		FrontendName: "matrix",
+		ExternalID:   externalID,
+		AgentID:      "agent-1",
+		CreatedAt:    base,
+		UpdatedAt:    base,
+	}); err != nil {
+		t.Fatalf("CreateThread(%s): %v", id, err)
+	}
+
+	ids := make([]string, len(offsets))
+	for i, off := range offsets {
+		ids[i] = id + "-evt-" + time.Duration(off).String()
+		text := ids[i]
+		threadID := id
+		if err := s.SaveEvent(ctx, &LedgerEvent{
+			ID:              ids[i],
+			ConversationKey: "agent-1",
+			ThreadID:        &threadID,
+			Direction:       EventDirectionInbound,
+			Author:          "user",
+			Timestamp:       base.Add(time.Duration(off) * time.Minute),
+			Type:            EventTypeMessage,
+			Text:            &text,
+		}); err != nil {
+			t.Fatalf("SaveEvent: %v", err)
+		}
+	}
+	return ids
+}
+
+func eventIDs(t *testing.T, s *SQLStore, threadID string) []string {
+	t.Helper()
+	events, err := s.GetEventsByThreadID(context.Background(), threadID, 500)
+	if err != nil {
+		t.Fatalf("GetEventsByThreadID: %v", err)
+	}
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		if e.Type == EventTypeSystem {
+			continue
+		}
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestMergeThreads(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	target := seedThread(t, s, "target", "room-new", base, 0, 20)
+	source := seedThread(t, s, "source", "room-old", base, 10, 30)
+
+	if err := s.SaveUsage(ctx, &TokenUsage{ID: "u1", ThreadID: "source", RequestID: "r1", AgentID: "agent-1", InputTokens: 5, CreatedAt: base}); err != nil {
+		t.Fatalf("SaveUsage: %v", err)
+	}
+
+	result, err := s.MergeThreads(ctx, "target", []string{"source"})
+	if err != nil {
+		t.Fatalf("MergeThreads: %v", err)
+	}
+	if result.EventsMoved != 2 || result.UsageMoved != 1 {
+		t.Errorf("moved events=%d usage=%d, want 2 and 1", result.EventsMoved, result.UsageMoved)
+	}
+
+	want := []string{target[0], source[0], target[1], source[1]}
+	got := eventIDs(t, s, "target")
+	if len(got) != len(want) {
+		t.Fatalf("target events = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %s, want %s (chronological order)", i, got[i], want[i])
+		}
+	}
+
+	usage, err := s.GetThreadUsage(ctx, "target")
+	if err != nil {
+		t.Fatalf("GetThreadUsage: %v", err)
+	}
+	if len(usage) != 1 || usage[0].ID != "u1" {
+		t.Errorf("target usage = %+v, want u1", usage)
+	}
+
+	tomb, err := s.GetThread(ctx, "source")
+	if err != nil {
+		t.Fatalf("GetThread(source): %v", err)
+	}
+	if tomb.MergedInto != "target" {
+		t.Errorf("source.MergedInto = %q, want target", tomb.MergedInto)
+	}
+	byExternal, err := s.GetThreadByFrontendID(ctx, "matrix", "room-old")
+	if err != nil {
+		t.Fatalf("GetThreadByFrontendID: %v", err)
+	}
+	if byExternal.MergedInto != "target" {
+		t.Errorf("tombstone by external ID MergedInto = %q, want target", byExternal.MergedInto)
+	}
+
+	merge, err := s.GetEvent(ctx, result.MergeEventID)
+	if err != nil {
+		t.Fatalf("GetEvent(merge): %v", err)
+	}
+	if merge.Type != EventTypeSystem || merge.ThreadID == nil || *merge.ThreadID != "target" {
+		t.Errorf("merge event = %+v, want system event on target", merge)
+	}
+}
+
+func TestMergeThreads_RepointsEarlierTombstones(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	seedThread(t, s, "a", "room-a", base, 0)
+	seedThread(t, s, "b", "room-b", base, 1)
+	seedThread(t, s, "c", "room-c", base, 2)
+
+	if _, err := s.MergeThreads(ctx, "b", []string{"a"}); err != nil {
+		t.Fatalf("first merge: %v", err)
+	}
+	if _, err := s.MergeThreads(ctx, "c", []string{"b"}); err != nil {
+		t.Fatalf("second merge: %v", err)
+	}
+
+	a, err := s.GetThread(ctx, "a")
+	if err != nil {
+		t.Fatalf("GetThread(a): %v", err)
+	}
+	if a.MergedInto != "c" {
+		t.Errorf("a.MergedInto = %q, want c (single hop)", a.MergedInto)
+	}
+}
+
+func TestMergeThreads_Invalid(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	seedThread(t, s, "t1", "room-1", base, 0)
+	seedThread(t, s, "t2", "room-2", base, 1)
+
+	if _, err := s.MergeThreads(ctx, "t1", []string{"t1"}); !errors.Is(err, ErrInvalidThreadOperation) {
+		t.Errorf("self merge error = %v, want ErrInvalidThreadOperation", err)
+	}
+	if _, err := s.MergeThreads(ctx, "t1", nil); !errors.Is(err, ErrInvalidThreadOperation) {
+		t.Errorf("empty merge error = %v, want ErrInvalidThreadOperation", err)
+	}
+	if _, err := s.MergeThreads(ctx, "t1", []string{"missing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing source error = %v, want ErrNotFound", err)
+	}
+
+	if _, err := s.MergeThreads(ctx, "t1", []string{"t2"}); err != nil {
+		t.Fatalf("MergeThreads: %v", err)
+	}
+	if _, err := s.MergeThreads(ctx, "t2", []string{"t1"}); !errors.Is(err, ErrInvalidThreadOperation) {
+		t.Errorf("merge into tombstone error = %v, want ErrInvalidThreadOperation", err)
+	}
+}
+
+func TestSplitThread(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	ids := seedThread(t, s, "orig", "room", base, 0, 1, 2, 3)
+
+	if err := s.SaveUsage(ctx, &TokenUsage{ID: "early", ThreadID: "orig", RequestID: "r1", AgentID: "agent-1", CreatedAt: base}); err != nil {
+		t.Fatalf("SaveUsage: %v", err)
+	}
+	if err := s.LinkUsageToMessage(ctx, "r1", ids[0]); err != nil {
+		t.Fatalf("LinkUsageToMessage: %v", err)
+	}
+	if err := s.SaveUsage(ctx, &TokenUsage{ID: "late", ThreadID: "orig", RequestID: "r2", AgentID: "agent-1", CreatedAt: base.Add(3 * time.Minute)}); err != nil {
+		t.Fatalf("SaveUsage: %v", err)
+	}
+	if err := s.LinkUsageToMessage(ctx, "r2", ids[3]); err != nil {
+		t.Fatalf("LinkUsageToMessage: %v", err)
+	}
+
+	result, err := s.SplitThread(ctx, "orig", ids[1])
+	if err != nil {
+		t.Fatalf("SplitThread: %v", err)
+	}
+	if result.EventsMoved != 2 || result.UsageMoved != 1 {
+		t.Errorf("moved events=%d usage=%d, want 2 and 1", result.EventsMoved, result.UsageMoved)
+	}
+
+	if got := eventIDs(t, s, "orig"); len(got) != 2 || got[0] != ids[0] || got[1] != ids[1] {
+		t.Errorf("original events = %v, want %v", got, ids[:2])
+	}
+	if got := eventIDs(t, s, result.NewThreadID); len(got) != 2 || got[0] != ids[2] || got[1] != ids[3] {
+		t.Errorf("split events = %v, want %v", got, ids[2:])
+	}
+
+	usage, err := s.GetThreadUsage(ctx, result.NewThreadID)
+	if err != nil {
+		t.Fatalf("GetThreadUsage: %v", err)
+	}
+	if len(usage) != 1 || usage[0].ID != "late" {
+		t.Errorf("split usage = %+v, want only late", usage)
+	}
+
+	split, err := s.GetThread(ctx, result.NewThreadID)
+	if err != nil {
+		t.Fatalf("GetThread(split): %v", err)
+	}
+	if split.SplitFrom != "orig" || split.AgentID != "agent-1" {
+		t.Errorf("split thread = %+v, want SplitFrom=orig agent-1", split)
+	}
+}
+
+func TestSplitThread_Invalid(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	ids := seedThread(t, s, "t1", "room-1", base, 0, 1)
+	other := seedThread(t, s, "t2", "room-2", base, 0)
+
+	if _, err := s.SplitThread(ctx, "t1", ids[1]); !errors.Is(err, ErrInvalidThreadOperation) {
+		t.Errorf("split at last event error = %v, want ErrInvalidThreadOperation", err)
+	}
+	if _, err := s.SplitThread(ctx, "t1", other[0]); !errors.Is(err, ErrInvalidThreadOperation) {
+		t.Errorf("foreign event error = %v, want ErrInvalidThreadOperation", err)
+	}
+	if _, err := s.SplitThread(ctx, "t1", "missing"); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("missing event error = %v, want ErrEventNotFound", err)
+	}
+	if _, err := s.SplitThread(ctx, "missing", ids[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing thread error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,205 @@
+// ABOUTME: Persistence for pack tool catalog snapshots and their change history
+// ABOUTME: Stores the last-seen definition per tool and a versioned changelog of diffs
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ToolChangeType describes how a tool changed between pack registrations.
+type ToolChangeType string
+
+const (
+	ToolAdded    ToolChangeType = "added"
+	ToolRemoved  ToolChangeType = "removed"
+	ToolModified ToolChangeType = "modified"
+)
+
+// ToolFieldChange is one field-level difference in a modified tool definition.
+// Path uses dots for nesting (e.g., "input_schema.properties.query.type").
+type ToolFieldChange struct {
+	Path string `json:"path"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// ToolChange is one entry in the tool catalog changelog.
+type ToolChange struct {
+	ID             int64
+	CatalogVersion int64
+	PackID         string
+	PackVersion    string
+	ToolName       string
+	ChangeType     ToolChangeType
+	Diff           []ToolFieldChange // only set for modified tools
+	CreatedAt      time.Time
+}
+
+// ToolChangeFilter specifies criteria for listing tool changes.
+type ToolChangeFilter struct {
+	PackID string     // empty matches all packs
+	Since  *time.Time // inclusive
+	Until  *time.Time // exclusive
+	Limit  int        // default 100, max 500
+}
+
+// GetToolSnapshots returns the stored definitions for a pack's tools, keyed by
+// tool name. Definitions are opaque canonical strings owned by the caller.
+func (s *SQLStore) GetToolSnapshots(ctx context.Context, packID string) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT tool_name, definition FROM tool_snapshots WHERE pack_id = ?`, packID)
+	if err != nil {
+		return nil, fmt.Errorf("querying tool snapshots: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	snapshots := make(map[string]string)
+	for rows.Next() {
+		var name, def string
+		if err := rows.Scan(&name, &def); err != nil {
+			return nil, fmt.Errorf("scanning tool snapshot: %w", err)
+		}
+		snapshots[name] = def
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating tool snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// SaveToolCatalog replaces a pack's snapshots and appends its changes under a
+// new catalog version, atomically. When changes is empty nothing is written and
+// the current version is returned. The changes are updated with their assigned
+// ID, version, and timestamp.
+func (s *SQLStore) SaveToolCatalog(ctx context.Context, packID string, snapshots map[string]string, changes []*ToolChange) (int64, error) {
+	if len(changes) == 0 {
+		return s.GetToolCatalogVersion(ctx)
+	}
+
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var version int64
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(catalog_version), 0) + 1 FROM tool_changes`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("allocating catalog version: %w", err)
+	}
+
+	now := time.Now().UTC()
+	nowStr := now.Format(time.RFC3339)
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tool_snapshots WHERE pack_id = ?`, packID); err != nil {
+		return 0, fmt.Errorf("clearing tool snapshots: %w", err)
+	}
+	for name, def := range snapshots {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO tool_snapshots (pack_id, tool_name, definition, updated_at)
+			VALUES (?, ?, ?, ?)
+		`, packID, name, def, nowStr); err != nil {
+			return 0, fmt.Errorf("inserting tool snapshot %s: %w", name, err)
+		}
+	}
+
+	for _, c := range changes {
+		var diff sql.NullString
+		if len(c.Diff) > 0 {
+			data, err := json.Marshal(c.Diff)
+			if err != nil {
+				return 0, fmt.Errorf("marshaling diff for %s: %w", c.ToolName, err)
+			}
+			diff = sql.NullString{String: string(data), Valid: true}
+		}
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO tool_changes (catalog_version, pack_id, pack_version, tool_name, change_type, diff, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			RETURNING id
+		`, version, packID, nullString(c.PackVersion), c.ToolName, string(c.ChangeType), diff, nowStr).Scan(&c.ID)
+		if err != nil {
+			return 0, fmt.Errorf("inserting tool change %s: %w", c.ToolName, err)
+		}
+		c.PackID = packID
+		c.CatalogVersion = version
+		c.CreatedAt = now
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing tool catalog: %w", err)
+	}
+	return version, nil
+}
+
+// GetToolCatalogVersion returns the latest catalog version, or 0 if no tool
+// change has been recorded yet.
+func (s *SQLStore) GetToolCatalogVersion(ctx context.Context) (int64, error) {
+	var version int64
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(catalog_version), 0) FROM tool_changes`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("querying catalog version: %w", err)
+	}
+	return version, nil
+}
+
+// ListToolChanges returns tool changes matching the filter, newest first.
+func (s *SQLStore) ListToolChanges(ctx context.Context, filter ToolChangeFilter) ([]*ToolChange, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	query := `
+		SELECT id, catalog_version, pack_id, pack_version, tool_name, change_type, diff, created_at
+		FROM tool_changes WHERE 1=1`
+	var args []any
+	if filter.PackID != "" {
+		query += ` AND pack_id = ?`
+		args = append(args, filter.PackID)
+	}
+	if filter.Since != nil {
+		query += ` AND created_at >= ?`
+		args = append(args, filter.Since.UTC().Format(time.RFC3339))
+	}
+	if filter.Until != nil {
+		query += ` AND created_at < ?`
+		args = append(args, filter.Until.UTC().Format(time.RFC3339))
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying tool changes: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var changes []*ToolChange
+	for rows.Next() {
+		var c ToolChange
+		var packVersion, diff sql.NullString
+		var changeType, createdAt string
+		if err := rows.Scan(&c.ID, &c.CatalogVersion, &c.PackID, &packVersion, &c.ToolName,
+			&changeType, &diff, &createdAt); err != nil {
+			return nil, fmt.Errorf("scanning tool change: %w", err)
+		}
+		c.PackVersion = packVersion.String
+		c.ChangeType = ToolChangeType(changeType)
+		if diff.Valid {
+			if err := json.Unmarshal([]byte(diff.String), &c.Diff); err != nil {
+				return nil, fmt.Errorf("parsing diff for tool change %d: %w", c.ID, err)
+			}
+		}
+		c.CreatedAt = parseTimeWithWarning(createdAt, "tool_change", c.PackID+"/"+c.ToolName, "created_at")
+		changes = append(changes, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating tool changes: %w", err)
+	}
+	return changes, nil
+}
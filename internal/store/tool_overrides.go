@@ -0,0 +1,90 @@
+// ABOUTME: Per-agent tool overrides: an admin turns single tools on or off for an agent's principal
+// ABOUTME: Rows only exist for tools an admin has changed; capability grants decide the rest
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ToolOverride turns one tool on or off for the agents of a principal. A
+// disabled tool is refused with Reason even when the principal holds the
+// capabilities it requires.
+type ToolOverride struct {
+	PrincipalID string
+	ToolName    string
+	Enabled     bool
+	Reason      string
+	UpdatedBy   string
+	UpdatedAt   time.Time
+}
+
+// ListToolOverrides returns principalID's tool overrides, ordered by tool name.
+func (s *SQLStore) ListToolOverrides(ctx context.Context, principalID string) ([]*ToolOverride, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT principal_id, tool_name, enabled, reason, updated_by, updated_at
+		FROM tool_overrides
+		WHERE principal_id = ?
+		ORDER BY tool_name
+	`, principalID)
+	if err != nil {
+		return nil, fmt.Errorf("querying tool overrides: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	overrides := []*ToolOverride{}
+	for rows.Next() {
+		var (
+			o         ToolOverride
+			updatedBy sql.NullString
+			updatedAt string
+		)
+		if err := rows.Scan(&o.PrincipalID, &o.ToolName, &o.Enabled, &o.Reason, &updatedBy, &updatedAt); err != nil {
+			return nil, fmt.Errorf("scanning tool override: %w", err)
+		}
+		o.UpdatedBy = updatedBy.String
+		o.UpdatedAt = parseTimeWithWarning(updatedAt, "tool_override", o.ToolName, "updated_at")
+		overrides = append(overrides, &o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating tool overrides: %w", err)
+	}
+	return overrides, nil
+}
+
+// DisabledTools returns the tools disabled for principalID, each with the
+// reason it was disabled.
+func (s *SQLStore) DisabledTools(ctx context.Context, principalID string) (map[string]string, error) {
+	overrides, err := s.ListToolOverrides(ctx, principalID)
+	if err != nil {
+		return nil, err
+	}
+	disabled := make(map[string]string)
+	for _, o := range overrides {
+		if !o.Enabled {
+			disabled[o.ToolName] = o.Reason
+		}
+	}
+	return disabled, nil
+}
+
+// SetToolOverride inserts or replaces a tool override. UpdatedAt is set to now.
+func (s *SQLStore) SetToolOverride(ctx context.Context, o *ToolOverride) error {
+	o.UpdatedAt = time.Now().UTC()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO tool_overrides (principal_id, tool_name, enabled, reason, updated_by, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(principal_id, tool_name) DO UPDATE SET
+			enabled = excluded.enabled,
+			reason = excluded.reason,
+			updated_by = excluded.updated_by,
+			updated_at = excluded.updated_at
+	`, o.PrincipalID, o.ToolName, o.Enabled, o.Reason, nullString(o.UpdatedBy), o.UpdatedAt.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("saving tool override %s for %s: %w", o.ToolName, o.PrincipalID, err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// ABOUTME: Tests for per-agent tool override storage
+// ABOUTME: Covers upserting overrides, listing them per principal and the disabled tool set
+
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestToolOverrides(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	for _, o := range []*ToolOverride{
+		{PrincipalID: "agent-a", ToolName: "bbs_create_thread", Enabled: false, Reason: "too noisy", UpdatedBy: "admin"},
+		{PrincipalID: "agent-a", ToolName: "log_entry", Enabled: true},
+		{PrincipalID: "agent-b", ToolName: "bbs_create_thread", Enabled: false},
+	} {
+		if err := s.SetToolOverride(ctx, o); err != nil {
+			t.Fatalf("SetToolOverride(%s, %s): %v", o.PrincipalID, o.ToolName, err)
+		}
+	}
+
+	overrides, err := s.ListToolOverrides(ctx, "agent-a")
+	if err != nil {
+		t.Fatalf("ListToolOverrides: %v", err)
+	}
+	if len(overrides) != 2 || overrides[0].ToolName != "bbs_create_thread" || overrides[1].ToolName != "log_entry" {
+		t.Fatalf("overrides = %+v, want bbs_create_thread and log_entry", overrides)
+	}
+	if o := overrides[0]; o.Enabled || o.Reason != "too noisy" || o.UpdatedBy != "admin" || o.UpdatedAt.IsZero() {
+		t.Errorf("override = %+v", o)
+	}
+
+	disabled, err := s.DisabledTools(ctx, "agent-a")
+	if err != nil {
+		t.Fatalf("DisabledTools: %v", err)
+	}
+	if len(disabled) != 1 || disabled["bbs_create_thread"] != "too noisy" {
+		t.Errorf("disabled = %v, want only bbs_create_thread", disabled)
+	}
+
+	// Re-enabling replaces the override.
+	if err := s.SetToolOverride(ctx, &ToolOverride{PrincipalID: "agent-a", ToolName: "bbs_create_thread", Enabled: true}); err != nil {
+		t.Fatalf("SetToolOverride: %v", err)
+	}
+	if disabled, _ := s.DisabledTools(ctx, "agent-a"); len(disabled) != 0 {
+		t.Errorf("disabled after re-enabling = %v, want none", disabled)
+	}
+	if disabled, _ := s.DisabledTools(ctx, "agent-b"); len(disabled) != 1 {
+		t.Errorf("another principal's override was changed: %v", disabled)
+	}
+}
@@ -0,0 +1,293 @@
+// ABOUTME: Tool approval policies: ordered rules deciding whether a tool call is allowed, denied or asked about
+// ABOUTME: Rules are kept in evaluation order alongside a single default action for calls no rule matches
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Tool policy actions.
+const (
+	ToolPolicyAllow = "allow"
+	ToolPolicyDeny  = "deny"
+	ToolPolicyAsk   = "ask"
+)
+
+// DefaultToolPolicyAction applies to calls no rule matches until an admin
+// sets another: a person is asked, as before policies existed.
+const DefaultToolPolicyAction = ToolPolicyAsk
+
+// ErrToolPolicyOrder indicates a reorder did not list every policy exactly once.
+var ErrToolPolicyOrder = errors.New("order must list every tool policy exactly once")
+
+// ToolPolicy is one rule of the tool approval policy.
+type ToolPolicy struct {
+	ID       string
+	Position int // evaluation order, lowest first
+	// AgentID and Group limit the rule to one agent or to the members of an
+	// agent group; when both are empty the rule applies to every agent.
+	AgentID     string
+	Group       string
+	ToolPattern string // glob over the tool name, e.g. "fs_*"
+	Action      string // ToolPolicyAllow, ToolPolicyDeny or ToolPolicyAsk
+	// Matchers must all hold against the call's input for the rule to apply.
+	Matchers    []ToolPolicyMatcher
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CreatedBy   string
+}
+
+// ToolPolicyMatcher tests one input parameter of a tool call, e.g.
+// {Param: "path", Op: "startswith", Value: "/tmp"}.
+type ToolPolicyMatcher struct {
+	Param string `json:"param"`
+	Op    string `json:"op"`
+	Value string `json:"value"`
+}
+
+// CreateToolPolicy stores a new policy after the existing ones, setting its
+// Position and timestamps.
+func (s *SQLStore) CreateToolPolicy(ctx context.Context, p *ToolPolicy) error {
+	matchers, err := toolPolicyMatchersJSON(p.Matchers)
+	if err != nil {
+		return err
+	}
+	p.CreatedAt = time.Now().UTC()
+	p.UpdatedAt = p.CreatedAt
+
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(position), 0) + 1 FROM tool_policies`).Scan(&p.Position); err != nil {
+		return fmt.Errorf("finding next tool policy position: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO tool_policies (id, position, agent_id, group_name, tool_pattern, action, matchers, description, created_at, updated_at, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.ID, p.Position, nullString(p.AgentID), nullString(p.Group), p.ToolPattern, p.Action, matchers,
+		nullString(p.Description), p.CreatedAt.Format(time.RFC3339), p.UpdatedAt.Format(time.RFC3339), nullString(p.CreatedBy))
+	if err != nil {
+		return fmt.Errorf("inserting tool policy: %w", err)
+	}
+	return commitAuditedTx(ctx, tx)
+}
+
+// GetToolPolicy returns a policy by ID, or ErrNotFound.
+func (s *SQLStore) GetToolPolicy(ctx context.Context, id string) (*ToolPolicy, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, position, agent_id, group_name, tool_pattern, action, matchers, description, created_at, updated_at, created_by
+		FROM tool_policies
+		WHERE id = ?
+	`, id)
+	p, err := scanToolPolicy(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return p, err
+}
+
+// ListToolPolicies returns every policy in evaluation order.
+func (s *SQLStore) ListToolPolicies(ctx context.Context) ([]*ToolPolicy, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, position, agent_id, group_name, tool_pattern, action, matchers, description, created_at, updated_at, created_by
+		FROM tool_policies
+		ORDER BY position, created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("querying tool policies: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var policies []*ToolPolicy
+	for rows.Next() {
+		p, err := scanToolPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating tool policies: %w", err)
+	}
+	return policies, nil
+}
+
+// UpdateToolPolicy replaces a policy's rule, keeping its position and
+// creation details. Returns ErrNotFound if the policy does not exist.
+func (s *SQLStore) UpdateToolPolicy(ctx context.Context, p *ToolPolicy) error {
+	matchers, err := toolPolicyMatchersJSON(p.Matchers)
+	if err != nil {
+		return err
+	}
+	p.UpdatedAt = time.Now().UTC()
+
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE tool_policies
+		SET agent_id = ?, group_name = ?, tool_pattern = ?, action = ?, matchers = ?, description = ?, updated_at = ?
+		WHERE id = ?
+	`, nullString(p.AgentID), nullString(p.Group), p.ToolPattern, p.Action, matchers,
+		nullString(p.Description), p.UpdatedAt.Format(time.RFC3339), p.ID)
+	if err != nil {
+		return fmt.Errorf("updating tool policy: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("checking rows affected: %w", err)
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return commitAuditedTx(ctx, tx)
+}
+
+// DeleteToolPolicy removes a policy. Returns ErrNotFound if it does not exist.
+func (s *SQLStore) DeleteToolPolicy(ctx context.Context, id string) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM tool_policies WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("deleting tool policy: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("checking rows affected: %w", err)
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return commitAuditedTx(ctx, tx)
+}
+
+// ReorderToolPolicies sets the evaluation order to ids, which must name
+// every policy exactly once; otherwise it returns ErrToolPolicyOrder.
+func (s *SQLStore) ReorderToolPolicies(ctx context.Context, ids []string) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM tool_policies`)
+	if err != nil {
+		return fmt.Errorf("querying tool policies: %w", err)
+	}
+	var existing []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("scanning tool policy: %w", err)
+		}
+		existing = append(existing, id)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterating tool policies: %w", err)
+	}
+
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	slices.Sort(existing)
+	if !slices.Equal(sorted, existing) {
+		return ErrToolPolicyOrder
+	}
+
+	for i, id := range ids {
+		if _, err := tx.ExecContext(ctx, `UPDATE tool_policies SET position = ? WHERE id = ?`, i+1, id); err != nil {
+			return fmt.Errorf("updating tool policy position: %w", err)
+		}
+	}
+	return commitAuditedTx(ctx, tx)
+}
+
+// GetToolPolicyDefault returns the action for calls no policy matches,
+// DefaultToolPolicyAction if none has been set.
+func (s *SQLStore) GetToolPolicyDefault(ctx context.Context) (string, error) {
+	var action string
+	err := s.db.QueryRowContext(ctx, `SELECT default_action FROM tool_policy_settings WHERE id = 1`).Scan(&action)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultToolPolicyAction, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("querying tool policy default: %w", err)
+	}
+	return action, nil
+}
+
+// SetToolPolicyDefault sets the action for calls no policy matches.
+func (s *SQLStore) SetToolPolicyDefault(ctx context.Context, action, updatedBy string) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO tool_policy_settings (id, default_action, updated_at, updated_by)
+		VALUES (1, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			default_action = excluded.default_action,
+			updated_at = excluded.updated_at,
+			updated_by = excluded.updated_by
+	`, action, time.Now().UTC().Format(time.RFC3339), nullString(updatedBy))
+	if err != nil {
+		return fmt.Errorf("saving tool policy default: %w", err)
+	}
+	return commitAuditedTx(ctx, tx)
+}
+
+func scanToolPolicy(row interface{ Scan(...any) error }) (*ToolPolicy, error) {
+	var (
+		p                                                ToolPolicy
+		agentID, group, matchers, description, createdBy sql.NullString
+		createdAt, updatedAt                             string
+	)
+	err := row.Scan(&p.ID, &p.Position, &agentID, &group, &p.ToolPattern, &p.Action, &matchers,
+		&description, &createdAt, &updatedAt, &createdBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning tool policy: %w", err)
+	}
+	if matchers.Valid && matchers.String != "" {
+		if err := json.Unmarshal([]byte(matchers.String), &p.Matchers); err != nil {
+			return nil, fmt.Errorf("decoding matchers for tool policy %s: %w", p.ID, err)
+		}
+	}
+	p.AgentID = agentID.String
+	p.Group = group.String
+	p.Description = description.String
+	p.CreatedBy = createdBy.String
+	p.CreatedAt = parseTimeWithWarning(createdAt, "tool_policy", p.ID, "created_at")
+	p.UpdatedAt = parseTimeWithWarning(updatedAt, "tool_policy", p.ID, "updated_at")
+	return &p, nil
+}
+
+func toolPolicyMatchersJSON(matchers []ToolPolicyMatcher) (sql.NullString, error) {
+	if len(matchers) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(matchers)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("encoding tool policy matchers: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
@@ -0,0 +1,113 @@
+// ABOUTME: Tests for tool approval policy storage
+// ABOUTME: Covers CRUD in evaluation order, reordering, the default action, and audit entries
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func toolPolicyIDs(policies []*ToolPolicy) []string {
+	ids := make([]string, len(policies))
+	for i, p := range policies {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func TestToolPolicies(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	for _, p := range []*ToolPolicy{
+		{ID: "tp-1", ToolPattern: "fs_*", Action: ToolPolicyAllow, Matchers: []ToolPolicyMatcher{{Param: "path", Op: "startswith", Value: "/tmp"}}},
+		{ID: "tp-2", AgentID: "agent-a", ToolPattern: "*", Action: ToolPolicyDeny},
+		{ID: "tp-3", Group: "ops", ToolPattern: "shell", Action: ToolPolicyAsk, Description: "ops confirm shell"},
+	} {
+		if err := s.CreateToolPolicy(ctx, p); err != nil {
+			t.Fatalf("CreateToolPolicy(%s): %v", p.ID, err)
+		}
+	}
+
+	got, err := s.GetToolPolicy(ctx, "tp-1")
+	if err != nil {
+		t.Fatalf("GetToolPolicy: %v", err)
+	}
+	if got.Position != 1 || len(got.Matchers) != 1 || got.Matchers[0].Value != "/tmp" {
+		t.Errorf("GetToolPolicy = %+v, want position 1 with the path matcher", got)
+	}
+	if _, err := s.GetToolPolicy(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing GetToolPolicy err = %v, want ErrNotFound", err)
+	}
+
+	got.Action = ToolPolicyDeny
+	got.Matchers = nil
+	if err := s.UpdateToolPolicy(ctx, got); err != nil {
+		t.Fatalf("UpdateToolPolicy: %v", err)
+	}
+	if got, _ = s.GetToolPolicy(ctx, "tp-1"); got.Action != ToolPolicyDeny || got.Matchers != nil || got.Position != 1 {
+		t.Errorf("updated policy = %+v, want deny without matchers at position 1", got)
+	}
+	if err := s.UpdateToolPolicy(ctx, &ToolPolicy{ID: "missing", ToolPattern: "*", Action: ToolPolicyAsk}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing UpdateToolPolicy err = %v, want ErrNotFound", err)
+	}
+
+	if err := s.ReorderToolPolicies(ctx, []string{"tp-3", "tp-1", "tp-2"}); err != nil {
+		t.Fatalf("ReorderToolPolicies: %v", err)
+	}
+	for _, ids := range [][]string{{"tp-3", "tp-1"}, {"tp-3", "tp-1", "tp-1"}, {"tp-3", "tp-1", "tp-9"}} {
+		if err := s.ReorderToolPolicies(ctx, ids); !errors.Is(err, ErrToolPolicyOrder) {
+			t.Errorf("ReorderToolPolicies(%v) err = %v, want ErrToolPolicyOrder", ids, err)
+		}
+	}
+	list, err := s.ListToolPolicies(ctx)
+	if err != nil {
+		t.Fatalf("ListToolPolicies: %v", err)
+	}
+	if ids := toolPolicyIDs(list); len(ids) != 3 || ids[0] != "tp-3" || ids[1] != "tp-1" || ids[2] != "tp-2" {
+		t.Errorf("order = %v, want [tp-3 tp-1 tp-2]", ids)
+	}
+
+	if err := s.DeleteToolPolicy(ctx, "tp-1"); err != nil {
+		t.Fatalf("DeleteToolPolicy: %v", err)
+	}
+	if err := s.DeleteToolPolicy(ctx, "tp-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("repeat DeleteToolPolicy err = %v, want ErrNotFound", err)
+	}
+	p := &ToolPolicy{ID: "tp-4", ToolPattern: "*", Action: ToolPolicyAllow}
+	if err := s.CreateToolPolicy(ctx, p); err != nil {
+		t.Fatalf("CreateToolPolicy(tp-4): %v", err)
+	}
+	if p.Position != 4 {
+		t.Errorf("new policy position = %d, want 4 (after the last)", p.Position)
+	}
+}
+
+func TestToolPolicyDefault(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	if action, err := s.GetToolPolicyDefault(ctx); err != nil || action != ToolPolicyAsk {
+		t.Fatalf("unset default = %q, %v; want ask", action, err)
+	}
+	auditCtx := WithAuditEntry(ctx, &AuditEntry{
+		ActorPrincipalID: "admin-1", Action: AuditUpdateToolPolicy, TargetType: "tool_policy", TargetID: "default",
+	})
+	if err := s.SetToolPolicyDefault(auditCtx, ToolPolicyDeny, "admin-1"); err != nil {
+		t.Fatalf("SetToolPolicyDefault: %v", err)
+	}
+	if action, err := s.GetToolPolicyDefault(ctx); err != nil || action != ToolPolicyDeny {
+		t.Errorf("default = %q, %v; want deny", action, err)
+	}
+	if err := s.SetToolPolicyDefault(ctx, "maybe", ""); err == nil {
+		t.Error("SetToolPolicyDefault accepted an unknown action")
+	}
+
+	action := AuditUpdateToolPolicy
+	entries, err := s.ListAuditLog(ctx, AuditFilter{Action: &action})
+	if err != nil || len(entries) != 1 || entries[0].TargetID != "default" {
+		t.Errorf("audit entries = %+v, %v; want one for the default", entries, err)
+	}
+}
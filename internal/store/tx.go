@@ -0,0 +1,195 @@
+// ABOUTME: Store transactions: WithTx runs several store calls as one unit of work
+// ABOUTME: Store methods that open their own transaction nest inside it as savepoints
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+// txConn is what statements run through inside a transaction: a *sql.Tx,
+// or the connection a SQLite WithTx holds BEGIN IMMEDIATE on.
+type txConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// dbTx is the transaction a store method writes in: its own, or a savepoint
+// in the transaction of the WithTx call it runs under. Like *sql.Tx,
+// Rollback after Commit returns sql.ErrTxDone and does nothing.
+type dbTx struct {
+	txConn
+	commit   func() error
+	rollback func() error
+}
+
+// Commit commits the transaction or releases the savepoint.
+func (t *dbTx) Commit() error { return t.commit() }
+
+// Rollback rolls back the transaction or to the savepoint.
+func (t *dbTx) Rollback() error { return t.rollback() }
+
+// activeTx is the transaction a WithTx call holds, shared by the store it
+// hands to fn and everything nested in it.
+type activeTx struct {
+	conn       txConn
+	savepoints int
+}
+
+// beginTx starts the transaction a store method writes in. Under WithTx it
+// is a savepoint, so the method's writes still land or fail together
+// without ending the outer transaction.
+func (s *SQLStore) beginTx(ctx context.Context) (*dbTx, error) {
+	if s.db.tx != nil {
+		return s.db.tx.savepoint(ctx)
+	}
+	tx, err := s.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &dbTx{txConn: tx, commit: tx.Commit, rollback: tx.Rollback}, nil
+}
+
+// savepoint opens a savepoint in a's transaction.
+func (a *activeTx) savepoint(ctx context.Context) (*dbTx, error) {
+	a.savepoints++
+	name := fmt.Sprintf("sp_%d", a.savepoints)
+	if _, err := a.conn.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, fmt.Errorf("creating savepoint: %w", err)
+	}
+	done := false
+	return &dbTx{
+		txConn: a.conn,
+		commit: func() error {
+			if done {
+				return sql.ErrTxDone
+			}
+			if _, err := a.conn.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+				return err
+			}
+			done = true
+			return nil
+		},
+		rollback: func() error {
+			if done {
+				return sql.ErrTxDone
+			}
+			done = true
+			// The savepoint is undone even when ctx has ended.
+			ctx := context.WithoutCancel(ctx)
+			if _, err := a.conn.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); err != nil {
+				return err
+			}
+			_, err := a.conn.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+			return err
+		},
+	}, nil
+}
+
+// beginOuterTx starts the transaction WithTx runs fn in. On SQLite it
+// pins one connection and starts with BEGIN IMMEDIATE, taking the write
+// lock up front: a deferred transaction that reads first and writes later
+// can fail with SQLITE_BUSY part-way, after busy_timeout can no longer help.
+func (s *SQLStore) beginOuterTx(ctx context.Context) (*dbTx, txConn, error) {
+	if s.dialect != dialectSQLite {
+		tx, err := s.db.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("beginning transaction: %w", err)
+		}
+		return &dbTx{txConn: tx, commit: tx.Commit, rollback: tx.Rollback}, tx, nil
+	}
+
+	conn, err := s.db.DB.Conn(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting connection: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	done := false
+	return &dbTx{
+		txConn: conn,
+		commit: func() error {
+			if done {
+				return sql.ErrTxDone
+			}
+			if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+				return err
+			}
+			done = true
+			return conn.Close()
+		},
+		rollback: func() error {
+			if done {
+				return sql.ErrTxDone
+			}
+			done = true
+			if _, err := conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK"); err != nil {
+				// The connection may still be inside the transaction; drop
+				// it rather than hand it back to the pool.
+				_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+				_ = conn.Close()
+				return err
+			}
+			return conn.Close()
+		},
+	}, conn, nil
+}
+
+// WithTx runs fn in one transaction: the store calls fn makes on tx all
+// commit when it returns nil, and none do when it returns an error, panics,
+// or ctx ends first. tx must not be used after fn returns or from other
+// goroutines. WithTx on a store already in a transaction reuses it, with a
+// savepoint around fn.
+func (s *SQLStore) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	return s.WithSQLTx(ctx, func(tx *SQLStore) error { return fn(tx) })
+}
+
+// WithSQLTx is WithTx for callers that need more of the store than Store.
+func (s *SQLStore) WithSQLTx(ctx context.Context, fn func(tx *SQLStore) error) error {
+	if s.db.tx != nil {
+		sp, err := s.db.tx.savepoint(ctx)
+		if err != nil {
+			return err
+		}
+		return runTx(ctx, sp, func() error { return fn(s) })
+	}
+
+	tx, conn, err := s.beginOuterTx(ctx)
+	if err != nil {
+		return err
+	}
+	txStore := *s
+	txStore.db = &timedDB{DB: s.db.DB, observe: s.db.observe, tx: &activeTx{conn: conn}}
+	return runTx(ctx, tx, func() error { return fn(&txStore) })
+}
+
+// runTx runs fn and commits tx, rolling it back instead if fn fails or
+// panics or ctx has ended.
+func runTx(ctx context.Context, tx *dbTx, fn func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err := fn(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
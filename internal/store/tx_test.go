@@ -0,0 +1,190 @@
+// ABOUTME: Tests for store transactions: commit, rollback on error, panic and
+// ABOUTME: context cancellation, nested WithTx calls and store methods nesting as savepoints.
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func txTestThread(id string) *Thread {
+	now := time.Now()
+	return &Thread{ID: id, FrontendName: "test", ExternalID: id, AgentID: "agent-1", CreatedAt: now, UpdatedAt: now}
+}
+
+// threadExists reports whether s has thread id, failing on lookup errors.
+func threadExists(t *testing.T, s Store, id string) bool {
+	t.Helper()
+	_, err := s.GetThread(context.Background(), id)
+	if errors.Is(err, ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		t.Fatalf("GetThread(%s): %v", id, err)
+	}
+	return true
+}
+
+func TestWithTx_CommitsAndRollsBack(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	err := s.WithTx(ctx, func(tx Store) error {
+		if err := tx.CreateThread(ctx, txTestThread("committed")); err != nil {
+			return err
+		}
+		text := "hello"
+		threadID := "committed"
+		return tx.SaveEvent(ctx, &LedgerEvent{
+			ID: "event-1", ConversationKey: "agent-1", ThreadID: &threadID, Direction: EventDirectionInbound,
+			Author: "user", Timestamp: time.Now(), Type: EventTypeMessage, Text: &text,
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if !threadExists(t, s, "committed") {
+		t.Error("committed thread missing")
+	}
+	if _, err := s.GetEvent(ctx, "event-1"); err != nil {
+		t.Errorf("committed event: %v", err)
+	}
+
+	errBoom := errors.New("boom")
+	err = s.WithTx(ctx, func(tx Store) error {
+		if err := tx.CreateThread(ctx, txTestThread("rolled-back")); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("WithTx error = %v, want boom", err)
+	}
+	if threadExists(t, s, "rolled-back") {
+		t.Error("thread from a failed transaction was kept")
+	}
+}
+
+func TestWithTx_RollsBackOnPanic(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("WithTx swallowed the panic")
+			}
+		}()
+		_ = s.WithTx(ctx, func(tx Store) error {
+			if err := tx.CreateThread(ctx, txTestThread("panicked")); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	}()
+	if threadExists(t, s, "panicked") {
+		t.Error("thread from a panicking transaction was kept")
+	}
+	// The store is still writable: nothing was left holding the write lock.
+	if err := s.CreateThread(ctx, txTestThread("after")); err != nil {
+		t.Fatalf("CreateThread after panic: %v", err)
+	}
+}
+
+func TestWithTx_RollsBackOnContextCancel(t *testing.T) {
+	s := newTestStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := s.WithTx(ctx, func(tx Store) error {
+		if err := tx.CreateThread(ctx, txTestThread("canceled")); err != nil {
+			return err
+		}
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("WithTx error = %v, want context.Canceled", err)
+	}
+	if threadExists(t, s, "canceled") {
+		t.Error("thread from a canceled transaction was kept")
+	}
+	if err := s.CreateThread(context.Background(), txTestThread("after")); err != nil {
+		t.Fatalf("CreateThread after cancel: %v", err)
+	}
+}
+
+func TestWithTx_Nested(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	errInner := errors.New("inner failed")
+
+	// A failed nested call undoes only its own writes.
+	err := s.WithTx(ctx, func(tx Store) error {
+		if err := tx.CreateThread(ctx, txTestThread("outer")); err != nil {
+			return err
+		}
+		err := tx.WithTx(ctx, func(inner Store) error {
+			if err := inner.CreateThread(ctx, txTestThread("inner")); err != nil {
+				return err
+			}
+			return errInner
+		})
+		if !errors.Is(err, errInner) {
+			t.Errorf("nested WithTx error = %v, want inner failed", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if !threadExists(t, s, "outer") || threadExists(t, s, "inner") {
+		t.Error("want the outer thread kept and the failed inner one rolled back")
+	}
+
+	// A nested call that succeeded still rolls back with its outer one.
+	_ = s.WithTx(ctx, func(tx Store) error {
+		if err := tx.WithTx(ctx, func(inner Store) error {
+			return inner.CreateThread(ctx, txTestThread("inner-ok"))
+		}); err != nil {
+			t.Errorf("nested WithTx: %v", err)
+		}
+		return errInner
+	})
+	if threadExists(t, s, "inner-ok") {
+		t.Error("nested write survived its outer transaction's rollback")
+	}
+}
+
+func TestWithSQLTx_MethodTransactionsNest(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	errBoom := errors.New("boom")
+
+	// CreatePrincipal runs its own transaction; under WithSQLTx it becomes
+	// a savepoint and rolls back with the outer transaction.
+	err := s.WithSQLTx(ctx, func(tx *SQLStore) error {
+		p := &Principal{ID: "p-1", Type: PrincipalTypeClient, DisplayName: "Owner", Status: PrincipalStatusApproved, CreatedAt: time.Now()}
+		if err := tx.CreatePrincipal(ctx, p); err != nil {
+			return err
+		}
+		if err := tx.AddRole(ctx, RoleSubjectPrincipal, "p-1", RoleOwner); err != nil {
+			return err
+		}
+		if ok, err := tx.HasRole(ctx, RoleSubjectPrincipal, "p-1", RoleOwner); err != nil || !ok {
+			t.Errorf("HasRole inside the transaction = %v, %v; want true", ok, err)
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("WithSQLTx error = %v, want boom", err)
+	}
+	if _, err := s.GetPrincipal(ctx, "p-1"); !errors.Is(err, ErrPrincipalNotFound) {
+		t.Errorf("GetPrincipal after rollback = %v, want ErrPrincipalNotFound", err)
+	}
+	if ok, err := s.HasRole(ctx, RoleSubjectPrincipal, "p-1", RoleOwner); err != nil || ok {
+		t.Errorf("HasRole after rollback = %v, %v; want false", ok, err)
+	}
+}
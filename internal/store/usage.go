@@ -1,17 +1,19 @@
-// ABOUTME: SQLite implementation for token usage tracking
-// ABOUTME: Stores and retrieves LLM token consumption data for analytics
+// ABOUTME: SQLStore implementation for token usage tracking
+// ABOUTME: Stores and retrieves LLM token consumption data for analytics and drill-down
 
 package store
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"time"
 )
 
-// SaveUsage stores a token usage record.
-func (s *SQLiteStore) SaveUsage(ctx context.Context, usage *TokenUsage) error {
+// SaveUsage stores a token usage record and adds it to its agent's hourly rollup.
+func (s *SQLStore) SaveUsage(ctx context.Context, usage *TokenUsage) error {
 	query := `
 		INSERT INTO message_usage (
 			id, thread_id, message_id, request_id, agent_id,
@@ -21,7 +23,14 @@ func (s *SQLiteStore) SaveUsage(ctx context.Context, usage *TokenUsage) error {
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.ExecContext(ctx, query,
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	createdAt := usage.CreatedAt.UTC()
+	_, err = tx.ExecContext(ctx, query,
 		usage.ID,
 		usage.ThreadID,
 		nullString(usage.MessageID),
@@ -32,12 +41,44 @@ func (s *SQLiteStore) SaveUsage(ctx context.Context, usage *TokenUsage) error {
 		usage.CacheReadTokens,
 		usage.CacheWriteTokens,
 		usage.ThinkingTokens,
-		usage.CreatedAt.UTC().Format(time.RFC3339),
+		createdAt.Format(time.RFC3339),
 	)
 	if err != nil {
 		return fmt.Errorf("inserting usage: %w", err)
 	}
 
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO usage_agent_hourly (
+			agent_id, hour, input_tokens, output_tokens, cache_read_tokens, cache_write_tokens, thinking_tokens,
+			request_count, last_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?)
+		ON CONFLICT (agent_id, hour) DO UPDATE SET
+			input_tokens = usage_agent_hourly.input_tokens + excluded.input_tokens,
+			output_tokens = usage_agent_hourly.output_tokens + excluded.output_tokens,
+			cache_read_tokens = usage_agent_hourly.cache_read_tokens + excluded.cache_read_tokens,
+			cache_write_tokens = usage_agent_hourly.cache_write_tokens + excluded.cache_write_tokens,
+			thinking_tokens = usage_agent_hourly.thinking_tokens + excluded.thinking_tokens,
+			request_count = usage_agent_hourly.request_count + 1,
+			last_at = CASE WHEN excluded.last_at > usage_agent_hourly.last_at THEN excluded.last_at ELSE usage_agent_hourly.last_at END
+	`,
+		usage.AgentID,
+		createdAt.Truncate(time.Hour).Format(time.RFC3339),
+		usage.InputTokens,
+		usage.OutputTokens,
+		usage.CacheReadTokens,
+		usage.CacheWriteTokens,
+		usage.ThinkingTokens,
+		createdAt.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("updating usage rollup: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing usage: %w", err)
+	}
+
 	s.logger.Debug("saved token usage",
 		"id", usage.ID,
 		"thread_id", usage.ThreadID,
@@ -49,7 +90,7 @@ func (s *SQLiteStore) SaveUsage(ctx context.Context, usage *TokenUsage) error {
 }
 
 // LinkUsageToMessage updates a usage record with the final message ID.
-func (s *SQLiteStore) LinkUsageToMessage(ctx context.Context, requestID, messageID string) error {
+func (s *SQLStore) LinkUsageToMessage(ctx context.Context, requestID, messageID string) error {
 	query := `UPDATE message_usage SET message_id = ? WHERE request_id = ?`
 
 	result, err := s.db.ExecContext(ctx, query, messageID, requestID)
@@ -71,7 +112,7 @@ func (s *SQLiteStore) LinkUsageToMessage(ctx context.Context, requestID, message
 }
 
 // GetThreadUsage retrieves all usage records for a thread.
-func (s *SQLiteStore) GetThreadUsage(ctx context.Context, threadID string) ([]*TokenUsage, error) {
+func (s *SQLStore) GetThreadUsage(ctx context.Context, threadID string) ([]*TokenUsage, error) {
 	query := `
 		SELECT id, thread_id, message_id, request_id, agent_id,
 		       input_tokens, output_tokens, cache_read_tokens, cache_write_tokens, thinking_tokens,
@@ -103,21 +144,21 @@ func (s *SQLiteStore) GetThreadUsage(ctx context.Context, threadID string) ([]*T
 	return usages, nil
 }
 
-// GetUsageStats returns aggregated usage statistics with optional filters.
-func (s *SQLiteStore) GetUsageStats(ctx context.Context, filter UsageFilter) (*UsageStats, error) {
-	query := `
-		SELECT
-			COALESCE(SUM(input_tokens), 0) as total_input,
-			COALESCE(SUM(output_tokens), 0) as total_output,
-			COALESCE(SUM(cache_read_tokens), 0) as total_cache_read,
-			COALESCE(SUM(cache_write_tokens), 0) as total_cache_write,
-			COALESCE(SUM(thinking_tokens), 0) as total_thinking,
-			COUNT(*) as request_count
-		FROM message_usage
-		WHERE 1=1
-	`
-	args := []any{}
+// usageSums selects the summed token columns shared by the usage aggregates,
+// in the order scanned by scanUsageSums.
+const usageSums = `
+	COALESCE(SUM(input_tokens), 0),
+	COALESCE(SUM(output_tokens), 0),
+	COALESCE(SUM(cache_read_tokens), 0),
+	COALESCE(SUM(cache_write_tokens), 0),
+	COALESCE(SUM(thinking_tokens), 0),
+	COUNT(*)`
+
+// usageRank orders aggregates by total tokens (input + output + thinking).
+const usageRank = `SUM(input_tokens + output_tokens + thinking_tokens) DESC`
 
+// usageWhere appends the filter's conditions to a message_usage WHERE clause.
+func usageWhere(filter UsageFilter, query string, args []any) (string, []any) {
 	if filter.AgentID != nil {
 		query += " AND agent_id = ?"
 		args = append(args, *filter.AgentID)
@@ -130,17 +171,27 @@ func (s *SQLiteStore) GetUsageStats(ctx context.Context, filter UsageFilter) (*U
 		query += " AND created_at < ?"
 		args = append(args, filter.Until.UTC().Format(time.RFC3339))
 	}
+	return query, args
+}
 
-	var stats UsageStats
-	err := s.db.QueryRowContext(ctx, query, args...).Scan(
+// usageSumDests returns scan destinations for the usageSums columns.
+func usageSumDests(stats *UsageStats) []any {
+	return []any{
 		&stats.TotalInput,
 		&stats.TotalOutput,
 		&stats.TotalCacheRead,
 		&stats.TotalCacheWrite,
 		&stats.TotalThinking,
 		&stats.RequestCount,
-	)
-	if err != nil {
+	}
+}
+
+// GetUsageStats returns aggregated usage statistics with optional filters.
+func (s *SQLStore) GetUsageStats(ctx context.Context, filter UsageFilter) (*UsageStats, error) {
+	query, args := usageWhere(filter, `SELECT`+usageSums+` FROM message_usage WHERE 1=1`, nil)
+
+	var stats UsageStats
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(usageSumDests(&stats)...); err != nil {
 		return nil, fmt.Errorf("querying usage stats: %w", err)
 	}
 
@@ -150,6 +201,208 @@ func (s *SQLiteStore) GetUsageStats(ctx context.Context, filter UsageFilter) (*U
 	return &stats, nil
 }
 
+// ListAgentUsage returns per-agent usage within the filter window, highest
+// total tokens first. Windows on whole-hour boundaries (the admin date picker
+// always sends those) are answered from the hourly rollup so the overview
+// stays fast on large tables; other windows scan message_usage.
+func (s *SQLStore) ListAgentUsage(ctx context.Context, filter UsageFilter) ([]*AgentUsage, error) {
+	var query string
+	var args []any
+	if hourAligned(filter.Since) && hourAligned(filter.Until) {
+		// Column names match message_usage, so usageWhere and usageSums apply;
+		// created_at is the hour bucket and each row counts request_count requests.
+		query, args = usageWhere(filter, `
+			SELECT agent_id, MAX(last_at),
+				SUM(input_tokens), SUM(output_tokens), SUM(cache_read_tokens),
+				SUM(cache_write_tokens), SUM(thinking_tokens), SUM(request_count)
+			FROM (SELECT *, hour AS created_at FROM usage_agent_hourly) AS hourly
+			WHERE 1=1`, nil)
+	} else {
+		query, args = usageWhere(filter, `SELECT agent_id, MAX(created_at),`+usageSums+` FROM message_usage WHERE 1=1`, nil)
+	}
+	query += ` GROUP BY agent_id ORDER BY ` + usageRank
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying agent usage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []*AgentUsage
+	for rows.Next() {
+		var u AgentUsage
+		var last string
+		if err := rows.Scan(append([]any{&u.AgentID, &last}, usageSumDests(&u.UsageStats)...)...); err != nil {
+			return nil, fmt.Errorf("scanning agent usage: %w", err)
+		}
+		if u.LastRequestAt, err = time.Parse(time.RFC3339, last); err != nil {
+			return nil, fmt.Errorf("parsing created_at: %w", err)
+		}
+		u.TotalTokens = u.TotalInput + u.TotalOutput + u.TotalThinking
+		result = append(result, &u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating agent usage rows: %w", err)
+	}
+	return result, nil
+}
+
+// ListDailyAgentUsage returns usage within the filter window per UTC day and
+// agent, oldest day first. Like ListAgentUsage it reads the hourly rollup for
+// hour-aligned windows.
+func (s *SQLStore) ListDailyAgentUsage(ctx context.Context, filter UsageFilter) ([]*DailyAgentUsage, error) {
+	var query string
+	var args []any
+	if hourAligned(filter.Since) && hourAligned(filter.Until) {
+		query, args = usageWhere(filter, `
+			SELECT substr(created_at, 1, 10), agent_id,
+				SUM(input_tokens), SUM(output_tokens), SUM(cache_read_tokens),
+				SUM(cache_write_tokens), SUM(thinking_tokens), SUM(request_count)
+			FROM (SELECT *, hour AS created_at FROM usage_agent_hourly) AS hourly
+			WHERE 1=1`, nil)
+	} else {
+		query, args = usageWhere(filter, `SELECT substr(created_at, 1, 10), agent_id,`+usageSums+` FROM message_usage WHERE 1=1`, nil)
+	}
+	query += ` GROUP BY 1, 2 ORDER BY 1, 2`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying daily usage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []*DailyAgentUsage
+	for rows.Next() {
+		var u DailyAgentUsage
+		if err := rows.Scan(append([]any{&u.Day, &u.AgentID}, usageSumDests(&u.UsageStats)...)...); err != nil {
+			return nil, fmt.Errorf("scanning daily usage: %w", err)
+		}
+		u.TotalTokens = u.TotalInput + u.TotalOutput + u.TotalThinking
+		result = append(result, &u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating daily usage rows: %w", err)
+	}
+	return result, nil
+}
+
+// ListAgentThreadUsage returns per-thread usage for one agent within the
+// filter window (filter.AgentID is ignored), highest total tokens first,
+// capped at limit threads.
+func (s *SQLStore) ListAgentThreadUsage(ctx context.Context, agentID string, filter UsageFilter, limit int) ([]*ThreadUsageSummary, error) {
+	filter.AgentID = &agentID
+	inner, args := usageWhere(filter, `SELECT thread_id, MAX(created_at) AS last_at,`+usageSums+` FROM message_usage WHERE 1=1`, nil)
+	inner += ` GROUP BY thread_id ORDER BY ` + usageRank + ` LIMIT ?`
+	args = append(args, limit)
+
+	query := `
+		SELECT u.*, COALESCE(t.frontend_name, ''), COALESCE(t.external_id, '')
+		FROM (` + inner + `) u
+		LEFT JOIN threads t ON t.id = u.thread_id
+	`
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying thread usage summary: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []*ThreadUsageSummary
+	for rows.Next() {
+		var u ThreadUsageSummary
+		var last string
+		dests := append([]any{&u.ThreadID, &last}, usageSumDests(&u.UsageStats)...)
+		if err := rows.Scan(append(dests, &u.FrontendName, &u.ExternalID)...); err != nil {
+			return nil, fmt.Errorf("scanning thread usage summary: %w", err)
+		}
+		if u.LastRequestAt, err = time.Parse(time.RFC3339, last); err != nil {
+			return nil, fmt.Errorf("parsing created_at: %w", err)
+		}
+		u.TotalTokens = u.TotalInput + u.TotalOutput + u.TotalThinking
+		result = append(result, &u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating thread usage summary rows: %w", err)
+	}
+	// The join does not preserve the inner ordering.
+	slices.SortStableFunc(result, func(a, b *ThreadUsageSummary) int {
+		return cmp.Compare(b.TotalTokens, a.TotalTokens)
+	})
+	return result, nil
+}
+
+// ListThreadRequestUsage returns a thread's usage grouped by request within
+// the filter window, oldest first.
+func (s *SQLStore) ListThreadRequestUsage(ctx context.Context, threadID string, filter UsageFilter) ([]*RequestUsage, error) {
+	query, args := usageWhere(filter, `
+		SELECT request_id, MAX(COALESCE(message_id, '')), MIN(agent_id), MIN(created_at),`+usageSums+`
+		FROM message_usage WHERE thread_id = ?`, []any{threadID})
+	query += ` GROUP BY request_id ORDER BY MIN(created_at), request_id`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying request usage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []*RequestUsage
+	for rows.Next() {
+		var u RequestUsage
+		var created string
+		if err := rows.Scan(append([]any{&u.RequestID, &u.MessageID, &u.AgentID, &created}, usageSumDests(&u.UsageStats)...)...); err != nil {
+			return nil, fmt.Errorf("scanning request usage: %w", err)
+		}
+		if u.CreatedAt, err = time.Parse(time.RFC3339, created); err != nil {
+			return nil, fmt.Errorf("parsing created_at: %w", err)
+		}
+		u.TotalTokens = u.TotalInput + u.TotalOutput + u.TotalThinking
+		result = append(result, &u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating request usage rows: %w", err)
+	}
+	return result, nil
+}
+
+// hourAligned reports whether t is unset or falls on a whole UTC hour.
+func hourAligned(t *time.Time) bool {
+	return t == nil || t.Equal(t.Truncate(time.Hour))
+}
+
+// migrateUsageRollups fills usage_agent_hourly from message_usage for
+// databases that recorded usage before the rollup existed.
+func (s *SQLStore) migrateUsageRollups() error {
+	var rollups, usage int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM usage_agent_hourly`).Scan(&rollups); err != nil {
+		return fmt.Errorf("counting usage rollups: %w", err)
+	}
+	if rollups > 0 {
+		return nil
+	}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM message_usage`).Scan(&usage); err != nil {
+		return fmt.Errorf("counting usage: %w", err)
+	}
+	if usage == 0 {
+		return nil
+	}
+
+	s.logger.Info("backfilling hourly usage rollups", "usage_rows", usage)
+	_, err := s.db.Exec(`
+		INSERT INTO usage_agent_hourly (
+			agent_id, hour, input_tokens, output_tokens, cache_read_tokens, cache_write_tokens, thinking_tokens,
+			request_count, last_at
+		)
+		SELECT agent_id, strftime('%Y-%m-%dT%H:00:00Z', created_at),
+			SUM(input_tokens), SUM(output_tokens), SUM(cache_read_tokens),
+			SUM(cache_write_tokens), SUM(thinking_tokens), COUNT(*), MAX(created_at)
+		FROM message_usage
+		GROUP BY 1, 2
+	`)
+	if err != nil {
+		return fmt.Errorf("inserting usage rollups: %w", err)
+	}
+	return nil
+}
+
 // scanUsage scans a single usage row into a TokenUsage struct.
 func scanUsage(rows *sql.Rows) (*TokenUsage, error) {
 	var usage TokenUsage
@@ -185,5 +438,5 @@ func scanUsage(rows *sql.Rows) (*TokenUsage, error) {
 	return &usage, nil
 }
 
-// Ensure SQLiteStore implements UsageStore interface.
-var _ UsageStore = (*SQLiteStore)(nil)
+// Ensure SQLStore implements UsageStore interface.
+var _ UsageStore = (*SQLStore)(nil)
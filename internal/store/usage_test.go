@@ -1,10 +1,12 @@
 // ABOUTME: Tests for token usage tracking functionality
-// ABOUTME: Covers SaveUsage, LinkUsageToMessage, GetThreadUsage, GetUsageStats
+// ABOUTME: Covers SaveUsage, LinkUsageToMessage, GetThreadUsage, GetUsageStats, and the drill-down queries
 
 package store
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -274,3 +276,167 @@ func TestMockStore_UsageStore(t *testing.T) {
 	assert.Equal(t, int64(50), stats.TotalOutput)
 	assert.Equal(t, int64(1), stats.RequestCount)
 }
+
+func TestStore_UsageDrillDown(t *testing.T) {
+	store := setupTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, id := range []string{"thread-a", "thread-b", "thread-c"} {
+		require.NoError(t, store.CreateThread(ctx, &Thread{
+			ID: id, FrontendName: "matrix", ExternalID: "ext-" + id, AgentID: "agent-1",
+			CreatedAt: base, UpdatedAt: base,
+		}))
+	}
+	save := func(thread, request, agent string, input int32, at time.Time) {
+		require.NoError(t, store.SaveUsage(ctx, &TokenUsage{
+			ID: uuid.New().String(), ThreadID: thread, RequestID: request, AgentID: agent,
+			InputTokens: input, OutputTokens: 10, CreatedAt: at,
+		}))
+	}
+	save("thread-a", "req-1", "agent-1", 100, base)
+	save("thread-a", "req-1", "agent-1", 50, base.Add(time.Second)) // second row for the same request
+	save("thread-a", "req-2", "agent-1", 10, base.Add(time.Hour))
+	save("thread-b", "req-3", "agent-1", 900, base.Add(2*time.Hour))
+	save("thread-c", "req-4", "agent-2", 5000, base.Add(-48*time.Hour)) // outside the window below
+	require.NoError(t, store.LinkUsageToMessage(ctx, "req-1", "msg-1"))
+
+	agents, err := store.ListAgentUsage(ctx, UsageFilter{})
+	require.NoError(t, err)
+	require.Len(t, agents, 2)
+	assert.Equal(t, "agent-2", agents[0].AgentID)
+	assert.Equal(t, int64(1060), agents[1].TotalInput)
+	assert.Equal(t, int64(4), agents[1].RequestCount)
+	assert.Equal(t, base.Add(2*time.Hour), agents[1].LastRequestAt)
+
+	// Hour-aligned windows read the rollup; others scan the raw rows. Both agree.
+	unaligned := base.Add(-1000*time.Hour + time.Minute)
+	raw, err := store.ListAgentUsage(ctx, UsageFilter{Since: &unaligned})
+	require.NoError(t, err)
+	assert.Equal(t, agents, raw)
+
+	since := base.Add(-time.Hour)
+	agents, err = store.ListAgentUsage(ctx, UsageFilter{Since: &since})
+	require.NoError(t, err)
+	require.Len(t, agents, 1)
+	assert.Equal(t, "agent-1", agents[0].AgentID)
+
+	threads, err := store.ListAgentThreadUsage(ctx, "agent-1", UsageFilter{}, 10)
+	require.NoError(t, err)
+	require.Len(t, threads, 2)
+	assert.Equal(t, "thread-b", threads[0].ThreadID)
+	assert.Equal(t, "thread-a", threads[1].ThreadID)
+	assert.Equal(t, "ext-thread-a", threads[1].ExternalID)
+	assert.Equal(t, int64(160), threads[1].TotalInput)
+	assert.Equal(t, int64(190), threads[1].TotalTokens)
+
+	threads, err = store.ListAgentThreadUsage(ctx, "agent-1", UsageFilter{}, 1)
+	require.NoError(t, err)
+	require.Len(t, threads, 1)
+	assert.Equal(t, "thread-b", threads[0].ThreadID)
+
+	requests, err := store.ListThreadRequestUsage(ctx, "thread-a", UsageFilter{})
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Equal(t, "req-1", requests[0].RequestID)
+	assert.Equal(t, "msg-1", requests[0].MessageID)
+	assert.Equal(t, int64(150), requests[0].TotalInput)
+	assert.Equal(t, int64(2), requests[0].RequestCount)
+	assert.Equal(t, base, requests[0].CreatedAt)
+	assert.Empty(t, requests[1].MessageID)
+
+	until := base.Add(30 * time.Minute)
+	requests, err = store.ListThreadRequestUsage(ctx, "thread-a", UsageFilter{Until: &until})
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	days, err := store.ListDailyAgentUsage(ctx, UsageFilter{})
+	require.NoError(t, err)
+	require.Len(t, days, 2)
+	assert.Equal(t, "2026-02-27", days[0].Day)
+	assert.Equal(t, "agent-2", days[0].AgentID)
+	assert.Equal(t, "2026-03-01", days[1].Day)
+	assert.Equal(t, int64(1060), days[1].TotalInput)
+	assert.Equal(t, int64(4), days[1].RequestCount)
+	rawDays, err := store.ListDailyAgentUsage(ctx, UsageFilter{Since: &unaligned})
+	require.NoError(t, err)
+	assert.Equal(t, days, rawDays)
+}
+
+// TestStore_UsageDrillDown_LargeDataset seeds a synthetic usage table and
+// checks every drill-down level stays under a second. Seeding takes most of a
+// minute and timings mean little under -race, so it runs only via
+// `make usagescale`.
+func TestStore_UsageDrillDown_LargeDataset(t *testing.T) {
+	if os.Getenv("COVEN_USAGE_SCALE") == "" {
+		t.Skip("set COVEN_USAGE_SCALE=1 to seed a large usage dataset")
+	}
+	const (
+		rows    = 2_000_000
+		agents  = 25
+		threads = 20_000
+	)
+	path := filepath.Join(t.TempDir(), "usage.db")
+	store, err := NewSQLiteStore(path)
+	require.NoError(t, err)
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Seed in SQL with the usage indexes dropped; reopening the store below
+	// recreates them and backfills the rollup, as on an upgraded database.
+	for _, index := range []string{"thread", "agent", "created", "request", "agent_window", "thread_window"} {
+		_, err := store.db.ExecContext(ctx, "DROP INDEX idx_message_usage_"+index)
+		require.NoError(t, err)
+	}
+	_, err = store.db.ExecContext(ctx, `
+		WITH RECURSIVE seq(i) AS (SELECT 0 UNION ALL SELECT i + 1 FROM seq WHERE i + 1 < ?)
+		INSERT INTO threads (id, frontend_name, external_id, agent_id, created_at, updated_at)
+		SELECT printf('thread-%05d', i), 'bench', printf('ext-%05d', i), printf('agent-%02d', i % ?), ?, ?
+		FROM seq`, threads, agents, base.Format(time.RFC3339), base.Format(time.RFC3339))
+	require.NoError(t, err)
+	_, err = store.db.ExecContext(ctx, `
+		WITH RECURSIVE seq(i) AS (SELECT 0 UNION ALL SELECT i + 1 FROM seq WHERE i + 1 < ?)
+		INSERT INTO message_usage (id, thread_id, request_id, agent_id, input_tokens, output_tokens, created_at)
+		SELECT printf('u-%07d', i), printf('thread-%05d', i % ?), printf('req-%07d', i),
+		       printf('agent-%02d', (i % ?) % ?), i % 1000, i % 300,
+		       strftime('%Y-%m-%dT%H:%M:%SZ', ?, '+' || (i * 2) || ' seconds')
+		FROM seq`, rows, threads, threads, agents, base.Format("2006-01-02 15:04:05"))
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	store, err = NewSQLiteStore(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	since := base.Add(10 * 24 * time.Hour)
+	until := since.Add(24 * time.Hour)
+	windows := map[string]UsageFilter{"all": {}, "day": {Since: &since, Until: &until}}
+
+	for name, filter := range windows {
+		timed := func(level string, fn func() error) {
+			t.Helper()
+			start := time.Now()
+			require.NoError(t, fn())
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("%s (%s window) took %v, want under 1s", level, name, elapsed)
+			}
+		}
+		timed("agents", func() error {
+			result, err := store.ListAgentUsage(ctx, filter)
+			if err == nil && len(result) != agents {
+				t.Errorf("got %d agents, want %d", len(result), agents)
+			}
+			return err
+		})
+		timed("threads", func() error {
+			result, err := store.ListAgentThreadUsage(ctx, "agent-07", filter, 500)
+			if err == nil && len(result) == 0 {
+				t.Error("no threads for agent-07")
+			}
+			return err
+		})
+		timed("requests", func() error {
+			_, err := store.ListThreadRequestUsage(ctx, "thread-00007", filter)
+			return err
+		})
+	}
+}
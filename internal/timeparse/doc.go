@@ -0,0 +1,22 @@
+// Package timeparse is the single place API timestamps are parsed and
+// formatted.
+//
+// # Inputs
+//
+// Every timestamp accepted from clients, in query parameters or request
+// bodies, goes through Parse (or ParseParam/Optional/Query to get an *Error
+// naming the offending parameter). Accepted forms:
+//
+//   - RFC3339, with or without fractional seconds: 2026-01-02T15:04:05Z,
+//     2026-01-02T15:04:05.123+01:00
+//   - epoch seconds: 1767366245
+//   - epoch milliseconds: 1767366245123
+//
+// Integers of magnitude 1e11 or more are read as milliseconds.
+//
+// # Outputs
+//
+// Every timestamp emitted in JSON is RFC3339 in UTC with whole seconds,
+// produced by Format. The store keeps its own internal representation; it
+// never reaches clients directly.
+package timeparse
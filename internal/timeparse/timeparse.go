@@ -0,0 +1,111 @@
+// ABOUTME: Shared parsing and formatting for timestamps crossing the API boundary.
+// ABOUTME: Accepts RFC3339 and epoch seconds/milliseconds; always emits RFC3339 UTC.
+
+package timeparse
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// epochMillisCutoff separates epoch seconds from epoch milliseconds. Integers
+// at or above it are read as milliseconds: 1e11 seconds is the year 5138,
+// while 1e11 milliseconds is March 1973.
+const epochMillisCutoff = 100_000_000_000
+
+// Error reports a timestamp input that could not be parsed. Param names the
+// query parameter or body field so clients know which input to fix.
+type Error struct {
+	Param string
+	Value string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid %s timestamp %q: expected RFC3339 (e.g. 2006-01-02T15:04:05Z) or epoch seconds/milliseconds", e.Param, e.Value)
+}
+
+// ErrorCode is the machine-readable code in an Error's JSON form.
+const ErrorCode = "invalid_timestamp"
+
+// MarshalJSON renders the error as the body of a 400 response:
+// {"error": "...", "code": "invalid_timestamp", "param": "since", "value": "..."}.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+		Param string `json:"param"`
+		Value string `json:"value"`
+	}{e.Error(), ErrorCode, e.Param, e.Value})
+}
+
+// Parse reads a timestamp in any accepted format: RFC3339, RFC3339 with
+// fractional seconds, epoch seconds, or epoch milliseconds. The result is
+// in UTC.
+func Parse(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty timestamp")
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n >= epochMillisCutoff || n <= -epochMillisCutoff {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	// RFC3339 parsing accepts fractional seconds, so this covers RFC3339Nano.
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// ParseParam parses value as a timestamp, returning an *Error naming param
+// on failure.
+func ParseParam(param, value string) (time.Time, error) {
+	t, err := Parse(value)
+	if err != nil {
+		return time.Time{}, &Error{Param: param, Value: value}
+	}
+	return t, nil
+}
+
+// Optional parses an optional timestamp input. It returns nil for an empty
+// value and an *Error naming param if the value is not a timestamp.
+func Optional(param, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := ParseParam(param, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Query parses the named query parameter as an optional timestamp.
+func Query(q url.Values, param string) (*time.Time, error) {
+	return Optional(param, q.Get(param))
+}
+
+// Format renders t as RFC3339 in UTC, the one timestamp format the API
+// emits. The zero time formats as "" so optional fields can use omitempty.
+func Format(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// FormatPtr is Format for optional timestamps; nil and zero give nil.
+func FormatPtr(t *time.Time) *string {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	s := Format(*t)
+	return &s
+}
@@ -0,0 +1,118 @@
+// ABOUTME: Tests for timestamp parsing and formatting helpers.
+// ABOUTME: Table-driven over every accepted input format and failure mode.
+
+package timeparse
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	want := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		input string
+		want  time.Time
+	}{
+		{"rfc3339 utc", "2026-01-02T15:04:05Z", want},
+		{"rfc3339 offset", "2026-01-02T16:04:05+01:00", want},
+		{"rfc3339 nano", "2026-01-02T15:04:05.123456789Z", want.Add(123456789)},
+		{"epoch seconds", "1767366245", want},
+		{"epoch milliseconds", "1767366245000", want},
+		{"epoch milliseconds fraction", "1767366245250", want.Add(250 * time.Millisecond)},
+		{"surrounding space", " 1767366245 ", want},
+		{"epoch zero", "0", time.Unix(0, 0).UTC()},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Parse(tc.input)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tc.input, err)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tc.input, got, tc.want)
+			}
+			if got.Location() != time.UTC {
+				t.Errorf("Parse(%q) location = %v, want UTC", tc.input, got.Location())
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, input := range []string{"", "yesterday", "2026-01-02", "2026-01-02 15:04:05", "1.5e9", "0x10"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", input)
+		}
+	}
+}
+
+func TestParseParam_NamesParameter(t *testing.T) {
+	_, err := ParseParam("since", "last tuesday")
+	var perr *Error
+	if !errors.As(err, &perr) {
+		t.Fatalf("error = %v, want *Error", err)
+	}
+	if perr.Param != "since" || perr.Value != "last tuesday" {
+		t.Errorf("error = %+v, want param since with the bad value", perr)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	q := url.Values{"since": {"1767366245"}, "until": {"nope"}}
+
+	since, err := Query(q, "since")
+	if err != nil || since == nil || since.Unix() != 1767366245 {
+		t.Errorf("since = %v, %v", since, err)
+	}
+	if missing, err := Query(q, "before"); missing != nil || err != nil {
+		t.Errorf("missing param = %v, %v; want nil, nil", missing, err)
+	}
+	if _, err := Query(q, "until"); err == nil {
+		t.Error("invalid until accepted")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	local := time.FixedZone("PDT", -7*3600)
+	ts := time.Date(2026, 1, 2, 8, 4, 5, 999, local)
+
+	if got := Format(ts); got != "2026-01-02T15:04:05Z" {
+		t.Errorf("Format = %q, want RFC3339 UTC", got)
+	}
+	if got := Format(time.Time{}); got != "" {
+		t.Errorf("Format(zero) = %q, want empty", got)
+	}
+	if got := FormatPtr(nil); got != nil {
+		t.Errorf("FormatPtr(nil) = %v, want nil", *got)
+	}
+	if got := FormatPtr(&ts); got == nil || *got != "2026-01-02T15:04:05Z" {
+		t.Errorf("FormatPtr = %v", got)
+	}
+
+	// Output must round-trip through Parse.
+	back, err := Parse(Format(ts))
+	if err != nil || !back.Equal(ts.Truncate(time.Second)) {
+		t.Errorf("round trip = %v, %v", back, err)
+	}
+}
+
+func TestError_JSON(t *testing.T) {
+	_, err := ParseParam("until", "soon")
+	b, merr := json.Marshal(err)
+	if merr != nil {
+		t.Fatalf("Marshal: %v", merr)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(b, &body); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if body["code"] != ErrorCode || body["param"] != "until" || body["value"] != "soon" || body["error"] == "" {
+		t.Errorf("body = %v", body)
+	}
+}
@@ -0,0 +1,21 @@
+// Package tracing wires the gateway into OpenTelemetry.
+//
+// # Overview
+//
+// Setup installs a global tracer provider that batches spans to an OTLP/gRPC
+// collector. Packages start spans from otel.Tracer as usual; before Setup
+// runs (or when tracing is disabled) those spans are no-ops.
+//
+// One send is one trace:
+//
+//   - HTTPMiddleware opens the server span, continuing a caller's traceparent
+//   - conversation.Service adds spans for thread lookup, message recording
+//     and the agent round trip
+//   - agent.Manager passes the trace context to the agent in
+//     SendMessage.trace_context
+//   - agents echo it on ExecutePackTool, and packs.Router records each tool
+//     call beneath it
+//
+// The agent stream is long-lived, so gRPC metadata cannot carry per-message
+// context; Inject and Extract move it through plain string maps instead.
+package tracing
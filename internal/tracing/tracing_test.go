@@ -0,0 +1,162 @@
+// ABOUTME: Tests for trace context propagation and the HTTP tracing middleware.
+// ABOUTME: Records spans in memory and checks names, attributes, status and parentage.
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs an in-memory tracer provider for the test.
+func recordSpans(t *testing.T) (*tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+		_ = tp.Shutdown(context.Background())
+	})
+	return rec, tp
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestInjectExtractRoundTrip(t *testing.T) {
+	_, tp := recordSpans(t)
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent")
+	defer span.End()
+
+	carrier := Inject(ctx)
+	if carrier["traceparent"] == "" {
+		t.Fatalf("Inject() = %v, want traceparent", carrier)
+	}
+
+	got := trace.SpanContextFromContext(Extract(context.Background(), carrier))
+	if got.TraceID() != span.SpanContext().TraceID() {
+		t.Errorf("extracted trace ID = %s, want %s", got.TraceID(), span.SpanContext().TraceID())
+	}
+	if !got.IsRemote() {
+		t.Error("extracted span context should be remote")
+	}
+	if TraceID(ctx) != span.SpanContext().TraceID().String() {
+		t.Errorf("TraceID() = %q, want %s", TraceID(ctx), span.SpanContext().TraceID())
+	}
+}
+
+func TestInjectWithoutSpan(t *testing.T) {
+	if got := Inject(context.Background()); got != nil {
+		t.Errorf("Inject() = %v, want nil", got)
+	}
+	if got := TraceID(context.Background()); got != "" {
+		t.Errorf("TraceID() = %q, want empty", got)
+	}
+	ctx := context.Background()
+	if Extract(ctx, nil) != ctx {
+		t.Error("Extract with no carrier should return ctx unchanged")
+	}
+}
+
+func TestTraceIDUnsampled(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample()), sdktrace.WithSpanProcessor(rec))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "dropped")
+	defer span.End()
+
+	if got := TraceID(ctx); got != "" {
+		t.Errorf("TraceID() = %q, want empty for an unsampled span", got)
+	}
+}
+
+func TestEndSpanRecordsError(t *testing.T) {
+	rec, tp := recordSpans(t)
+	_, span := tp.Tracer("test").Start(context.Background(), "op")
+	EndSpan(span, context.DeadlineExceeded)
+
+	ended := rec.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("ended spans = %d, want 1", len(ended))
+	}
+	if ended[0].Status().Code != codes.Error {
+		t.Errorf("status = %v, want Error", ended[0].Status().Code)
+	}
+	if len(ended[0].Events()) != 1 {
+		t.Errorf("events = %d, want the recorded error", len(ended[0].Events()))
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	rec, tp := recordSpans(t)
+	prevProp := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagator)
+	t.Cleanup(func() { otel.SetTextMapPropagator(prevProp) })
+
+	parentCtx, parent := tp.Tracer("client").Start(context.Background(), "client")
+	parent.End()
+
+	var flushed bool
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/agents/{id}/send", HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if TraceID(r.Context()) != parent.SpanContext().TraceID().String() {
+			t.Errorf("handler trace ID = %q, want the caller's", TraceID(r.Context()))
+		}
+		w.WriteHeader(http.StatusBadGateway)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+			flushed = true
+		}
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/agents/a1/send", nil)
+	for k, v := range Inject(parentCtx) {
+		req.Header.Set(k, v)
+	}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if !flushed || !rr.Flushed {
+		t.Error("middleware should pass Flush through")
+	}
+	var server sdktrace.ReadOnlySpan
+	for _, s := range rec.Ended() {
+		if s.SpanKind() == trace.SpanKindServer {
+			server = s
+		}
+	}
+	if server == nil {
+		t.Fatal("no server span recorded")
+	}
+	if server.Name() != "POST /api/v1/agents/{id}/send" {
+		t.Errorf("span name = %q", server.Name())
+	}
+	if server.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("server span should continue the caller's trace")
+	}
+	if got := attr(server, "http.response.status_code").AsInt64(); got != http.StatusBadGateway {
+		t.Errorf("status code attribute = %d, want 502", got)
+	}
+	if got := attr(server, "url.path").AsString(); got != "/api/v1/agents/a1/send" {
+		t.Errorf("url.path = %q", got)
+	}
+	if server.Status().Code != codes.Error {
+		t.Errorf("span status = %v, want Error for a 5xx", server.Status().Code)
+	}
+}
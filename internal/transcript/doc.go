@@ -0,0 +1,31 @@
+// Package transcript renders a thread's ledger events as a document to
+// attach to a support ticket, served by GET /api/threads/{id}/export and the
+// web admin's thread export.
+//
+// # Formats
+//
+// FormatMarkdown writes the conversation as user and assistant turns with
+// their timestamps. Tool calls and results are folded into <details>
+// sections, errors are quoted, system notes (such as a merge) are set in
+// italics, and a token usage table closes the document.
+// Audit-only events (capability warnings, policy decisions, health changes)
+// are left out.
+//
+// FormatJSON writes a Document: every event of the thread, with tool names
+// and IDs taken out of the stored tool payloads, and the thread's usage.
+// SchemaVersion changes only when a field is removed or changes meaning.
+//
+// # Streaming
+//
+// Write reads events through an EventSource one at a time and writes each
+// as it goes, so a long thread is never held in memory whole. An error after
+// the first write leaves the document cut short; callers that have already
+// sent headers can only log it.
+//
+// # Redaction
+//
+// Options.Secrets lists values, typically every stored secret, that are
+// replaced with "[redacted]" wherever they appear in message text or tool
+// payloads, in both formats. Values shorter than four bytes are ignored so
+// ordinary words survive.
+package transcript
@@ -0,0 +1,340 @@
+// ABOUTME: Renders a thread's ledger events as a markdown or JSON transcript.
+// ABOUTME: Streams events from the store one at a time and masks stored secret values.
+
+package transcript
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+	"github.com/2389/coven-gateway/internal/usage"
+)
+
+// SchemaVersion is the version of the Document layout.
+const SchemaVersion = 1
+
+// redactedPlaceholder replaces secret values in exported text.
+const redactedPlaceholder = "[redacted]"
+
+// minSecretLen is the shortest secret value that is masked.
+const minSecretLen = 4
+
+// Format is a transcript format.
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+)
+
+// ParseFormat parses a ?format= value; empty means markdown.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", FormatMarkdown:
+		return FormatMarkdown, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("unknown format %q: want markdown or json", s)
+}
+
+// ContentType is the HTTP content type of a transcript in the format.
+func (f Format) ContentType() string {
+	if f == FormatJSON {
+		return "application/json"
+	}
+	return "text/markdown; charset=utf-8"
+}
+
+// Filename names the download of a thread's transcript in the format.
+func (f Format) Filename(threadID string) string {
+	if f == FormatJSON {
+		return "thread-" + threadID + ".json"
+	}
+	return "thread-" + threadID + ".md"
+}
+
+// EventSource reads a thread's ledger events in chronological order.
+type EventSource interface {
+	ForEachThreadEvent(ctx context.Context, threadID string, fn func(*store.LedgerEvent) error) error
+}
+
+// Options controls what Write produces.
+type Options struct {
+	Format     Format
+	Usage      *usage.ThreadUsage // nil leaves out the usage summary
+	Secrets    []string           // values to mask wherever they appear
+	ExportedAt time.Time          // zero means now
+}
+
+// Document is a JSON transcript. Write streams its fields in this order.
+type Document struct {
+	SchemaVersion int                `json:"schema_version"`
+	ExportedAt    string             `json:"exported_at"`
+	Thread        Thread             `json:"thread"`
+	Events        []Event            `json:"events"`
+	Usage         *usage.ThreadUsage `json:"usage,omitempty"`
+}
+
+// Thread describes the exported thread.
+type Thread struct {
+	ID         string `json:"id"`
+	AgentID    string `json:"agent_id"`
+	Frontend   string `json:"frontend"`
+	ExternalID string `json:"external_id"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+	SplitFrom  string `json:"split_from,omitempty"`
+}
+
+// Event is one ledger event of the thread.
+type Event struct {
+	ID        string `json:"id"`
+	Timestamp string `json:"timestamp"`
+	Direction string `json:"direction"` // inbound (to the agent) or outbound (from it)
+	Author    string `json:"author"`
+	Type      string `json:"type"` // ledger event type: message, tool_call, tool_result, error, ...
+	// Text is the message text, a tool call's input, or a tool result's output.
+	Text     string `json:"text,omitempty"`
+	ToolName string `json:"tool_name,omitempty"`
+	ToolID   string `json:"tool_id,omitempty"`
+}
+
+// Write renders thread's transcript to w, reading its events from src.
+func Write(ctx context.Context, w io.Writer, src EventSource, thread *store.Thread, opts Options) error {
+	exportedAt := opts.ExportedAt
+	if exportedAt.IsZero() {
+		exportedAt = time.Now()
+	}
+	bw := bufio.NewWriter(w)
+	t := &transcript{w: bw, redactor: newRedactor(opts.Secrets), tools: make(map[string]string)}
+
+	var err error
+	if opts.Format == FormatJSON {
+		err = t.writeJSON(ctx, src, thread, opts.Usage, exportedAt)
+	} else {
+		err = t.writeMarkdown(ctx, src, thread, opts.Usage, exportedAt)
+	}
+	if err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("writing transcript: %w", err)
+	}
+	return nil
+}
+
+// transcript holds the state of one Write. Write errors are kept in err and
+// later writes skipped, so callers check it once per event.
+type transcript struct {
+	w        io.Writer
+	err      error
+	redactor *strings.Replacer // nil when there is nothing to mask
+	tools    map[string]string // tool call ID -> tool name, to name results
+}
+
+func (t *transcript) write(s string) {
+	if t.err == nil {
+		_, t.err = io.WriteString(t.w, s)
+	}
+}
+
+func (t *transcript) printf(format string, args ...any) {
+	t.write(fmt.Sprintf(format, args...))
+}
+
+func (t *transcript) redact(s string) string {
+	if t.redactor == nil {
+		return s
+	}
+	return t.redactor.Replace(s)
+}
+
+// event converts a ledger event, unpacking tool payloads and masking secrets.
+func (t *transcript) event(e *store.LedgerEvent) Event {
+	msg := store.EventToMessage(e)
+	ev := Event{
+		ID:        e.ID,
+		Timestamp: timeparse.Format(e.Timestamp),
+		Direction: "inbound",
+		Author:    e.Author,
+		Type:      string(e.Type),
+		Text:      t.redact(msg.Content),
+		ToolName:  msg.ToolName,
+		ToolID:    msg.ToolID,
+	}
+	if e.Direction == store.EventDirectionOutbound {
+		ev.Direction = "outbound"
+	}
+	switch e.Type {
+	case store.EventTypeToolCall:
+		if ev.ToolID != "" {
+			t.tools[ev.ToolID] = ev.ToolName
+		}
+	case store.EventTypeToolResult:
+		ev.ToolName = t.tools[ev.ToolID]
+	}
+	return ev
+}
+
+func threadInfo(thread *store.Thread) Thread {
+	return Thread{
+		ID:         thread.ID,
+		AgentID:    thread.AgentID,
+		Frontend:   thread.FrontendName,
+		ExternalID: thread.ExternalID,
+		CreatedAt:  timeparse.Format(thread.CreatedAt),
+		UpdatedAt:  timeparse.Format(thread.UpdatedAt),
+		SplitFrom:  thread.SplitFrom,
+	}
+}
+
+// writeJSON streams a Document: the header fields, then each event as it is
+// read, then the usage.
+func (t *transcript) writeJSON(ctx context.Context, src EventSource, thread *store.Thread, u *usage.ThreadUsage, exportedAt time.Time) error {
+	head, err := json.Marshal(Document{
+		SchemaVersion: SchemaVersion,
+		ExportedAt:    timeparse.Format(exportedAt),
+		Thread:        threadInfo(thread),
+	})
+	if err != nil {
+		return fmt.Errorf("encoding transcript header: %w", err)
+	}
+	// Keep the header open after "thread" and stream the events array in.
+	prefix, _, _ := strings.Cut(string(head), `,"events":`)
+	t.write(prefix + `,"events":[`)
+
+	first := true
+	err = src.ForEachThreadEvent(ctx, thread.ID, func(e *store.LedgerEvent) error {
+		b, err := json.Marshal(t.event(e))
+		if err != nil {
+			return fmt.Errorf("encoding event %s: %w", e.ID, err)
+		}
+		if !first {
+			t.write(",")
+		}
+		first = false
+		t.write(string(b))
+		return t.err
+	})
+	if err != nil {
+		return err
+	}
+	t.write("]")
+
+	if u != nil {
+		b, err := json.Marshal(u)
+		if err != nil {
+			return fmt.Errorf("encoding usage: %w", err)
+		}
+		t.write(`,"usage":` + string(b))
+	}
+	t.write("}\n")
+	return t.err
+}
+
+// writeMarkdown streams the conversation as turns, then the usage table.
+func (t *transcript) writeMarkdown(ctx context.Context, src EventSource, thread *store.Thread, u *usage.ThreadUsage, exportedAt time.Time) error {
+	info := threadInfo(thread)
+	t.printf("# Thread %s\n\n", info.ID)
+	t.printf("- Agent: %s\n", info.AgentID)
+	t.printf("- Channel: %s %s\n", info.Frontend, info.ExternalID)
+	t.printf("- Started: %s\n", info.CreatedAt)
+	if info.SplitFrom != "" {
+		t.printf("- Split from: %s\n", info.SplitFrom)
+	}
+	t.printf("- Exported: %s\n", timeparse.Format(exportedAt))
+
+	err := src.ForEachThreadEvent(ctx, thread.ID, func(e *store.LedgerEvent) error {
+		t.markdownEvent(t.event(e))
+		return t.err
+	})
+	if err != nil {
+		return err
+	}
+	if u != nil {
+		t.markdownUsage(u.Totals)
+	}
+	return t.err
+}
+
+func (t *transcript) markdownEvent(ev Event) {
+	switch store.EventType(ev.Type) {
+	case store.EventTypeMessage:
+		role := "User"
+		if ev.Direction == "outbound" {
+			role = "Assistant"
+		}
+		t.printf("\n## %s: %s\n\n_%s_\n\n%s\n", role, ev.Author, ev.Timestamp, strings.TrimRight(ev.Text, "\n"))
+	case store.EventTypeToolCall:
+		t.details("Tool call: "+ev.ToolName, ev.Timestamp, ev.Text)
+	case store.EventTypeToolResult:
+		t.details("Tool result: "+ev.ToolName, ev.Timestamp, ev.Text)
+	case store.EventTypeSystem:
+		t.printf("\n_%s: %s_\n", ev.Timestamp, strings.TrimSpace(ev.Text))
+	case store.EventTypeError:
+		t.printf("\n> **Error** _%s_\n>\n> %s\n", ev.Timestamp, strings.ReplaceAll(strings.TrimRight(ev.Text, "\n"), "\n", "\n> "))
+	}
+}
+
+// details writes a collapsed section holding body as a code block.
+func (t *transcript) details(summary, timestamp, body string) {
+	fence := codeFence(body)
+	t.printf("\n<details>\n<summary>%s (%s)</summary>\n\n%s\n%s\n%s\n\n</details>\n",
+		html.EscapeString(strings.TrimSpace(summary)), timestamp, fence, strings.TrimRight(body, "\n"), fence)
+}
+
+func (t *transcript) markdownUsage(totals usage.Totals) {
+	t.write("\n## Token usage\n\n")
+	t.write("| Input | Output | Cache read | Cache write | Thinking | Total | Requests |\n")
+	t.write("|------:|-------:|-----------:|------------:|---------:|------:|---------:|\n")
+	t.printf("| %d | %d | %d | %d | %d | %d | %d |\n",
+		totals.InputTokens, totals.OutputTokens, totals.CacheReadTokens, totals.CacheWriteTokens,
+		totals.ThinkingTokens, totals.TotalTokens, totals.RequestCount)
+	if totals.EstimatedCostUSD != nil {
+		t.printf("\nEstimated cost: $%.4f\n", *totals.EstimatedCostUSD)
+	}
+}
+
+// codeFence returns a backtick fence longer than any run of backticks in s,
+// so s cannot close the block early.
+func codeFence(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// newRedactor masks each secret value of at least minSecretLen bytes, trying
+// longer values first so one secret containing another is masked whole.
+func newRedactor(secrets []string) *strings.Replacer {
+	values := slices.DeleteFunc(slices.Clone(secrets), func(s string) bool { return len(s) < minSecretLen })
+	if len(values) == 0 {
+		return nil
+	}
+	slices.SortFunc(values, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+	})
+	values = slices.Compact(values)
+	pairs := make([]string, 0, 2*len(values))
+	for _, v := range values {
+		pairs = append(pairs, v, redactedPlaceholder)
+	}
+	return strings.NewReplacer(pairs...)
+}
@@ -0,0 +1,149 @@
+// ABOUTME: Tests for transcript rendering in markdown and JSON.
+// ABOUTME: Covers turn layout, tool sections, usage, secret masking, and the JSON schema.
+
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/usage"
+)
+
+// eventList is an EventSource over fixed events.
+type eventList []*store.LedgerEvent
+
+func (l eventList) ForEachThreadEvent(_ context.Context, _ string, fn func(*store.LedgerEvent) error) error {
+	for _, e := range l {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func testThread() (*store.Thread, eventList) {
+	base := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	thread := &store.Thread{
+		ID: "t-1", AgentID: "agent-1", FrontendName: "slack", ExternalID: "C1",
+		CreatedAt: base, UpdatedAt: base.Add(time.Minute),
+	}
+	text := func(s string) *string { return &s }
+	events := eventList{
+		{ID: "e1", Direction: store.EventDirectionInbound, Author: "alice", Timestamp: base,
+			Type: store.EventTypeMessage, Text: text("deploy with token sk-live-12345")},
+		{ID: "e2", Direction: store.EventDirectionOutbound, Author: "agent-1", Timestamp: base.Add(time.Second),
+			Type: store.EventTypeToolCall, Text: text(`{"name":"shell","id":"call-1","input":"echo ` + "```" + `"}`)},
+		{ID: "e3", Direction: store.EventDirectionOutbound, Author: "agent-1", Timestamp: base.Add(2 * time.Second),
+			Type: store.EventTypeToolResult, Text: text(`{"id":"call-1","output":"done"}`)},
+		{ID: "e4", Direction: store.EventDirectionOutbound, Author: "agent-1", Timestamp: base.Add(3 * time.Second),
+			Type: store.EventTypeCapabilityWarning, Text: text("audit only")},
+		{ID: "e5", Direction: store.EventDirectionOutbound, Author: "agent-1", Timestamp: base.Add(4 * time.Second),
+			Type: store.EventTypeMessage, Text: text("Deployed.")},
+	}
+	return thread, events
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"": FormatMarkdown, "markdown": FormatMarkdown, "json": FormatJSON} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Error("ParseFormat(pdf) succeeded, want error")
+	}
+}
+
+func TestWrite_Markdown(t *testing.T) {
+	thread, events := testThread()
+	cost := 0.0123
+	var buf bytes.Buffer
+	err := Write(context.Background(), &buf, events, thread, Options{
+		Format:  FormatMarkdown,
+		Secrets: []string{"sk-live-12345", "sk-live", "abc"},
+		Usage:   &usage.ThreadUsage{Totals: usage.Totals{InputTokens: 10, OutputTokens: 5, TotalTokens: 15, RequestCount: 1, EstimatedCostUSD: &cost}},
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# Thread t-1",
+		"## User: alice\n\n_2026-03-04T10:00:00Z_\n\ndeploy with token [redacted]\n",
+		"<summary>Tool call: shell (2026-03-04T10:00:01Z)</summary>\n\n````\necho ```\n````",
+		"<summary>Tool result: shell (2026-03-04T10:00:02Z)</summary>",
+		"## Assistant: agent-1",
+		"| 10 | 5 | 0 | 0 | 0 | 15 | 1 |",
+		"Estimated cost: $0.0123",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown missing %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"sk-live", "audit only"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("markdown contains %q:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestWrite_JSON(t *testing.T) {
+	thread, events := testThread()
+	exportedAt := time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err := Write(context.Background(), &buf, events, thread, Options{
+		Format: FormatJSON, Secrets: []string{"sk-live-12345"}, ExportedAt: exportedAt,
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	var doc Document
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not a Document: %v\n%s", err, buf.String())
+	}
+	if doc.SchemaVersion != SchemaVersion || doc.ExportedAt != "2026-03-05T00:00:00Z" || doc.Thread.ID != "t-1" || doc.Thread.Frontend != "slack" {
+		t.Errorf("header = %+v", doc)
+	}
+	if len(doc.Events) != len(events) || doc.Usage != nil {
+		t.Fatalf("events = %d, usage = %v; want every event and no usage", len(doc.Events), doc.Usage)
+	}
+	if e := doc.Events[0]; e.Text != "deploy with token [redacted]" || e.Direction != "inbound" {
+		t.Errorf("message = %+v, want masked inbound text", e)
+	}
+	if e := doc.Events[2]; e.Type != "tool_result" || e.ToolName != "shell" || e.ToolID != "call-1" || e.Text != "done" {
+		t.Errorf("tool result = %+v, want named after its call", e)
+	}
+
+	buf.Reset()
+	if err := Write(context.Background(), &buf, eventList{}, thread, Options{Format: FormatJSON, Usage: &usage.ThreadUsage{}}); err != nil {
+		t.Fatalf("Write empty: %v", err)
+	}
+	doc = Document{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil || doc.Events == nil || doc.Usage == nil {
+		t.Errorf("empty thread = %s (%v), want an empty events array and usage", buf.String(), err)
+	}
+}
+
+func TestWrite_SourceError(t *testing.T) {
+	thread, _ := testThread()
+	boom := errors.New("boom")
+	err := Write(context.Background(), &bytes.Buffer{}, failingSource{boom}, thread, Options{})
+	if !errors.Is(err, boom) {
+		t.Errorf("err = %v, want the source error", err)
+	}
+}
+
+type failingSource struct{ err error }
+
+func (f failingSource) ForEachThreadEvent(context.Context, string, func(*store.LedgerEvent) error) error {
+	return f.err
+}
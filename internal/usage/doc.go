@@ -0,0 +1,18 @@
+// Package usage turns stored token usage into the reports served by the
+// usage API, with cost estimates from a configurable price table.
+//
+// # Pricing
+//
+// A Pricing maps agent IDs to a Price per million tokens of each kind;
+// the DefaultPriceKey entry covers agents without their own. Costs are
+// estimated per agent and summed, so a report mixing agents prices each at
+// its own rate. A report includes EstimatedCostUSD only when every agent in
+// it has a price; a partial sum would understate the cost.
+//
+// # Reports
+//
+// ThreadReport backs GET /api/threads/{id}/usage: thread totals and one
+// entry per agent request. Summarize backs GET /api/usage/summary, grouping
+// usage by agent (highest total tokens first) or by UTC day (oldest first).
+// The web admin serves the same shapes under /api/admin/usage.
+package usage
@@ -0,0 +1,208 @@
+// ABOUTME: Usage API reports: thread totals with per-request breakdown, and summaries grouped by agent or day
+// ABOUTME: Estimates cost from a per-agent price table with a "default" fallback
+
+package usage
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// DefaultPriceKey is the Pricing entry used for agents without their own.
+const DefaultPriceKey = "default"
+
+// Summary groupings accepted by Summarize.
+const (
+	GroupByAgent = "agent"
+	GroupByDay   = "day"
+)
+
+// ErrUnknownGroupBy is returned by Summarize for an unsupported grouping.
+var ErrUnknownGroupBy = errors.New(`group_by must be "agent" or "day"`)
+
+// Price is a price per million tokens, in US dollars.
+type Price struct {
+	Input      float64
+	Output     float64
+	CacheRead  float64
+	CacheWrite float64
+	Thinking   float64
+}
+
+// Pricing maps agent IDs, or DefaultPriceKey, to prices.
+type Pricing map[string]Price
+
+// Cost estimates what agentID's usage cost. ok is false if the agent has no
+// price.
+func (p Pricing) Cost(agentID string, s store.UsageStats) (cost float64, ok bool) {
+	price, ok := p[agentID]
+	if !ok {
+		if price, ok = p[DefaultPriceKey]; !ok {
+			return 0, false
+		}
+	}
+	cost = float64(s.TotalInput)*price.Input +
+		float64(s.TotalOutput)*price.Output +
+		float64(s.TotalCacheRead)*price.CacheRead +
+		float64(s.TotalCacheWrite)*price.CacheWrite +
+		float64(s.TotalThinking)*price.Thinking
+	return cost / 1e6, true
+}
+
+// Totals is the JSON form of aggregated usage.
+type Totals struct {
+	InputTokens      int64 `json:"input_tokens"`
+	OutputTokens     int64 `json:"output_tokens"`
+	CacheReadTokens  int64 `json:"cache_read_tokens"`
+	CacheWriteTokens int64 `json:"cache_write_tokens"`
+	ThinkingTokens   int64 `json:"thinking_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+	// RequestCount is the number of usage reports summed.
+	RequestCount int64 `json:"request_count"`
+	// EstimatedCostUSD is omitted unless every agent involved has a price.
+	EstimatedCostUSD *float64 `json:"estimated_cost_usd,omitempty"`
+}
+
+// tally sums usage across agents, pricing each at its own rate.
+type tally struct {
+	stats    store.UsageStats
+	cost     float64
+	unpriced bool
+}
+
+func (t *tally) add(p Pricing, agentID string, s store.UsageStats) {
+	t.stats.TotalInput += s.TotalInput
+	t.stats.TotalOutput += s.TotalOutput
+	t.stats.TotalCacheRead += s.TotalCacheRead
+	t.stats.TotalCacheWrite += s.TotalCacheWrite
+	t.stats.TotalThinking += s.TotalThinking
+	t.stats.TotalTokens += s.TotalTokens
+	t.stats.RequestCount += s.RequestCount
+	cost, ok := p.Cost(agentID, s)
+	t.cost += cost
+	t.unpriced = t.unpriced || !ok
+}
+
+// totals reports the sums, with the cost if p priced every agent added.
+func (t *tally) totals(p Pricing) Totals {
+	out := Totals{
+		InputTokens:      t.stats.TotalInput,
+		OutputTokens:     t.stats.TotalOutput,
+		CacheReadTokens:  t.stats.TotalCacheRead,
+		CacheWriteTokens: t.stats.TotalCacheWrite,
+		ThinkingTokens:   t.stats.TotalThinking,
+		TotalTokens:      t.stats.TotalTokens,
+		RequestCount:     t.stats.RequestCount,
+	}
+	if len(p) > 0 && !t.unpriced {
+		// Micro-dollar precision keeps float noise out of the JSON.
+		cost := math.Round(t.cost*1e6) / 1e6
+		out.EstimatedCostUSD = &cost
+	}
+	return out
+}
+
+// RequestTotals is one agent request's usage within a thread.
+type RequestTotals struct {
+	RequestID string `json:"request_id"`
+	MessageID string `json:"message_id,omitempty"` // the reply, once linked
+	AgentID   string `json:"agent_id"`
+	CreatedAt string `json:"created_at"`
+	Totals
+}
+
+// ThreadUsage is a thread's usage: its totals and each request's share,
+// oldest first.
+type ThreadUsage struct {
+	Totals   Totals          `json:"totals"`
+	Requests []RequestTotals `json:"requests"`
+}
+
+// ThreadReport builds a thread's usage from its per-request rows.
+func (p Pricing) ThreadReport(requests []*store.RequestUsage) ThreadUsage {
+	var all tally
+	report := ThreadUsage{Requests: make([]RequestTotals, len(requests))}
+	for i, r := range requests {
+		var one tally
+		one.add(p, r.AgentID, r.UsageStats)
+		all.add(p, r.AgentID, r.UsageStats)
+		report.Requests[i] = RequestTotals{
+			RequestID: r.RequestID,
+			MessageID: r.MessageID,
+			AgentID:   r.AgentID,
+			CreatedAt: timeparse.Format(r.CreatedAt),
+			Totals:    one.totals(p),
+		}
+	}
+	report.Totals = all.totals(p)
+	return report
+}
+
+// Group is one row of a Summary: an agent ID or a YYYY-MM-DD day.
+type Group struct {
+	Key           string `json:"key"`
+	LastRequestAt string `json:"last_request_at,omitempty"` // agent groups only
+	Totals
+}
+
+// Summary is usage in a window, overall and per group.
+type Summary struct {
+	GroupBy string  `json:"group_by"`
+	Totals  Totals  `json:"totals"`
+	Groups  []Group `json:"groups"`
+}
+
+// Source is the store access Summarize needs.
+type Source interface {
+	ListAgentUsage(ctx context.Context, filter store.UsageFilter) ([]*store.AgentUsage, error)
+	ListDailyAgentUsage(ctx context.Context, filter store.UsageFilter) ([]*store.DailyAgentUsage, error)
+}
+
+// Summarize reports usage within filter grouped by groupBy, which defaults
+// to GroupByAgent. Agent groups come highest total tokens first, day groups
+// oldest first.
+func (p Pricing) Summarize(ctx context.Context, src Source, groupBy string, filter store.UsageFilter) (*Summary, error) {
+	var all tally
+	summary := &Summary{GroupBy: groupBy, Groups: []Group{}}
+	switch groupBy {
+	case "", GroupByAgent:
+		summary.GroupBy = GroupByAgent
+		rows, err := src.ListAgentUsage(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			var one tally
+			one.add(p, r.AgentID, r.UsageStats)
+			all.add(p, r.AgentID, r.UsageStats)
+			summary.Groups = append(summary.Groups, Group{
+				Key:           r.AgentID,
+				LastRequestAt: timeparse.Format(r.LastRequestAt),
+				Totals:        one.totals(p),
+			})
+		}
+	case GroupByDay:
+		rows, err := src.ListDailyAgentUsage(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		// Rows come ordered by day, one per agent that day.
+		var day tally
+		for i, r := range rows {
+			day.add(p, r.AgentID, r.UsageStats)
+			all.add(p, r.AgentID, r.UsageStats)
+			if i == len(rows)-1 || rows[i+1].Day != r.Day {
+				summary.Groups = append(summary.Groups, Group{Key: r.Day, Totals: day.totals(p)})
+				day = tally{}
+			}
+		}
+	default:
+		return nil, ErrUnknownGroupBy
+	}
+	summary.Totals = all.totals(p)
+	return summary, nil
+}
@@ -0,0 +1,130 @@
+// ABOUTME: Tests for usage reports: per-agent pricing with a default, cost omission
+// ABOUTME: when an agent is unpriced, thread breakdowns, and agent/day summaries
+
+package usage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func stats(input, output int64) store.UsageStats {
+	return store.UsageStats{TotalInput: input, TotalOutput: output, TotalTokens: input + output, RequestCount: 1}
+}
+
+type fakeSource struct {
+	agents []*store.AgentUsage
+	days   []*store.DailyAgentUsage
+}
+
+func (f fakeSource) ListAgentUsage(context.Context, store.UsageFilter) ([]*store.AgentUsage, error) {
+	return f.agents, nil
+}
+
+func (f fakeSource) ListDailyAgentUsage(context.Context, store.UsageFilter) ([]*store.DailyAgentUsage, error) {
+	return f.days, nil
+}
+
+func costOf(t *testing.T, tot Totals) float64 {
+	t.Helper()
+	if tot.EstimatedCostUSD == nil {
+		t.Fatalf("totals %+v have no cost", tot)
+	}
+	return *tot.EstimatedCostUSD
+}
+
+func TestPricingCost(t *testing.T) {
+	p := Pricing{
+		DefaultPriceKey: {Input: 3, Output: 15},
+		"cheap":         {Input: 1, CacheRead: 0.5},
+	}
+	if cost, ok := p.Cost("any", stats(1_000_000, 100_000)); !ok || cost != 4.5 {
+		t.Errorf("default cost = %v, %v; want 4.5", cost, ok)
+	}
+	s := stats(2_000_000, 1_000_000)
+	s.TotalCacheRead = 2_000_000
+	if cost, ok := p.Cost("cheap", s); !ok || cost != 3 {
+		t.Errorf("cheap cost = %v, %v; want 3 (output unpriced)", cost, ok)
+	}
+	if _, ok := (Pricing{"cheap": {}}).Cost("other", s); ok {
+		t.Error("agent without a price or default was priced")
+	}
+}
+
+func TestThreadReport(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	requests := []*store.RequestUsage{
+		{RequestID: "r1", MessageID: "m1", AgentID: "a", UsageStats: stats(1_000_000, 0), CreatedAt: at},
+		{RequestID: "r2", AgentID: "b", UsageStats: stats(0, 1_000_000), CreatedAt: at.Add(time.Minute)},
+	}
+
+	report := Pricing{"a": {Input: 2}, "b": {Output: 10}}.ThreadReport(requests)
+	if report.Totals.InputTokens != 1_000_000 || report.Totals.OutputTokens != 1_000_000 || report.Totals.RequestCount != 2 {
+		t.Errorf("totals = %+v", report.Totals)
+	}
+	if got := costOf(t, report.Totals); got != 12 {
+		t.Errorf("total cost = %v, want 12", got)
+	}
+	if len(report.Requests) != 2 || report.Requests[0].MessageID != "m1" || costOf(t, report.Requests[1].Totals) != 10 {
+		t.Errorf("requests = %+v", report.Requests)
+	}
+
+	// One unpriced agent leaves the total without a cost but prices the rest.
+	report = Pricing{"a": {Input: 2}}.ThreadReport(requests)
+	if report.Totals.EstimatedCostUSD != nil || report.Requests[1].EstimatedCostUSD != nil {
+		t.Errorf("unpriced agent got a cost: %+v", report)
+	}
+	if costOf(t, report.Requests[0].Totals) != 2 {
+		t.Errorf("priced request = %+v", report.Requests[0])
+	}
+
+	if report := Pricing(nil).ThreadReport(nil); report.Totals.EstimatedCostUSD != nil || report.Requests == nil {
+		t.Errorf("empty report without pricing = %+v", report)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	ctx := context.Background()
+	src := fakeSource{
+		agents: []*store.AgentUsage{
+			{AgentID: "a", UsageStats: stats(3_000_000, 0), LastRequestAt: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)},
+			{AgentID: "b", UsageStats: stats(1_000_000, 0)},
+		},
+		days: []*store.DailyAgentUsage{
+			{Day: "2026-03-01", AgentID: "a", UsageStats: stats(1_000_000, 0)},
+			{Day: "2026-03-01", AgentID: "b", UsageStats: stats(1_000_000, 0)},
+			{Day: "2026-03-02", AgentID: "a", UsageStats: stats(2_000_000, 0)},
+		},
+	}
+	p := Pricing{DefaultPriceKey: {Input: 1}, "b": {Input: 5}}
+
+	byAgent, err := p.Summarize(ctx, src, "", store.UsageFilter{})
+	if err != nil {
+		t.Fatalf("Summarize(agent): %v", err)
+	}
+	if byAgent.GroupBy != GroupByAgent || len(byAgent.Groups) != 2 || byAgent.Groups[0].Key != "a" {
+		t.Fatalf("agent summary = %+v", byAgent)
+	}
+	if byAgent.Groups[0].LastRequestAt != "2026-03-02T09:00:00Z" || costOf(t, byAgent.Totals) != 8 {
+		t.Errorf("agent summary = %+v", byAgent)
+	}
+
+	byDay, err := p.Summarize(ctx, src, GroupByDay, store.UsageFilter{})
+	if err != nil {
+		t.Fatalf("Summarize(day): %v", err)
+	}
+	if len(byDay.Groups) != 2 || byDay.Groups[0].Key != "2026-03-01" || byDay.Groups[0].InputTokens != 2_000_000 {
+		t.Fatalf("day summary = %+v", byDay)
+	}
+	if costOf(t, byDay.Groups[0].Totals) != 6 || costOf(t, byDay.Groups[1].Totals) != 2 || costOf(t, byDay.Totals) != 8 {
+		t.Errorf("day costs = %+v", byDay)
+	}
+
+	if _, err := p.Summarize(ctx, src, "week", store.UsageFilter{}); !errors.Is(err, ErrUnknownGroupBy) {
+		t.Errorf("Summarize(week) error = %v, want ErrUnknownGroupBy", err)
+	}
+}
@@ -0,0 +1,61 @@
+// ABOUTME: Tests for the agent detail JSON endpoint.
+// ABOUTME: Checks today's builtin write usage against the configured quotas.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/builtins"
+)
+
+func TestHandleAgentDetailJSON_ShowsWriteQuota(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	limits, err := builtins.NewLimits(nil, map[string]int{builtins.QuotaBBS: 3, builtins.QuotaMail: -1})
+	if err != nil {
+		t.Fatalf("NewLimits: %v", err)
+	}
+	admin.config.BuiltinLimits = limits
+
+	ctx := context.Background()
+	today := builtins.QuotaDay(time.Now())
+	for range 2 {
+		if _, err := s.ConsumeBuiltinQuota(ctx, "agent-1", builtins.QuotaBBS, today, 3); err != nil {
+			t.Fatalf("ConsumeBuiltinQuota: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/agents/agent-1", nil)
+	req.SetPathValue("id", "agent-1")
+	rec := httptest.NewRecorder()
+	admin.handleAgentDetailJSON(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var detail agentDetailItem
+	if err := json.NewDecoder(rec.Body).Decode(&detail); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	got := make(map[string]quotaUsageItem)
+	for _, q := range detail.WriteQuota {
+		got[q.Kind] = q
+	}
+	if len(got) != len(builtins.QuotaKinds) {
+		t.Fatalf("quota kinds = %v, want %v", detail.WriteQuota, builtins.QuotaKinds)
+	}
+	if q := got[builtins.QuotaBBS]; q.Used != 2 || q.Limit != 3 {
+		t.Errorf("bbs = %+v, want 2 of 3", q)
+	}
+	if q := got[builtins.QuotaMail]; q.Used != 0 || q.Limit != -1 {
+		t.Errorf("mail = %+v, want unlimited and unused", q)
+	}
+	if q := got[builtins.QuotaLogs]; q.Limit != builtins.DefaultLimits().QuotaFor(builtins.QuotaLogs) {
+		t.Errorf("logs = %+v, want the default limit", q)
+	}
+}
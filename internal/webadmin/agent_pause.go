@@ -0,0 +1,106 @@
+// ABOUTME: Admin handlers for pausing and resuming agents
+// ABOUTME: Paused agents stay connected but the gateway stops routing new messages to them
+
+package webadmin
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// handleAgentPause stops routing new messages to an agent until it is resumed.
+func (a *Admin) handleAgentPause(w http.ResponseWriter, r *http.Request) {
+	a.setAgentPaused(w, r, true)
+}
+
+// handleAgentResume restores message routing to a paused agent.
+func (a *Admin) handleAgentResume(w http.ResponseWriter, r *http.Request) {
+	a.setAgentPaused(w, r, false)
+}
+
+// setAgentPaused applies a pause or resume to the manager and, when the agent
+// has a principal, persists it so the state survives reconnects.
+func (a *Admin) setAgentPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+	if a.manager == nil {
+		http.Error(w, "Agent manager not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	agentID := r.PathValue("id")
+	if agentID == "" {
+		http.Error(w, "Agent ID required", http.StatusBadRequest)
+		return
+	}
+
+	principalID, ok := a.agentPrincipalID(r, agentID)
+	if !ok {
+		http.Error(w, "Agent not found", http.StatusNotFound)
+		return
+	}
+
+	user := getUserFromContext(r)
+	info := agent.PauseInfo{PausedBy: user.Username, PausedAt: time.Now().UTC()}
+
+	if principalID != "" {
+		var pause *store.PrincipalPause
+		if paused {
+			pause = &store.PrincipalPause{PausedBy: info.PausedBy, PausedAt: info.PausedAt}
+		}
+		if err := a.store.SetPrincipalPause(r.Context(), principalID, pause); err != nil {
+			a.logger.Error("failed to persist agent pause", "agent_id", agentID, "paused", paused, "error", err)
+			http.Error(w, "Failed to update agent", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	action := store.AuditResumeAgent
+	if paused {
+		action = store.AuditPauseAgent
+		a.manager.Pause(agentID, info)
+	} else {
+		a.manager.Resume(agentID)
+	}
+
+	a.auditAdminAction(r, &store.AuditEntry{
+		ActorPrincipalID: user.ID,
+		Action:           action,
+		TargetType:       "agent",
+		TargetID:         agentID,
+		Detail: map[string]any{
+			"admin_user":   user.Username,
+			"principal_id": principalID,
+		},
+	})
+
+	result := map[string]any{"agent_id": agentID, "paused": paused}
+	if paused {
+		result["paused_by"] = info.PausedBy
+		result["paused_at"] = timeparse.Format(info.PausedAt)
+	}
+	a.writeJSON(w, result)
+}
+
+// agentPrincipalID resolves the principal backing an agent. A connected agent
+// reports its authenticated principal (empty when auth is disabled); otherwise
+// the ID must name an existing principal. Returns false if neither applies.
+func (a *Admin) agentPrincipalID(r *http.Request, agentID string) (string, bool) {
+	if conn, ok := a.manager.GetAgent(agentID); ok {
+		return conn.PrincipalID, true
+	}
+	if _, err := a.store.GetPrincipal(r.Context(), agentID); err != nil {
+		if !errors.Is(err, store.ErrPrincipalNotFound) {
+			a.logger.Warn("failed to look up agent principal", "agent_id", agentID, "error", err)
+		}
+		return "", false
+	}
+	return agentID, true
+}
@@ -0,0 +1,104 @@
+// ABOUTME: Tests for the agent pause and resume admin endpoints.
+// ABOUTME: Checks manager state, persistence on the principal, and audit entries.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func pauseRequest(action, agentID string) *http.Request {
+	req := csrfJSONRequest(http.MethodPost, "/api/admin/agents/"+agentID+"/"+action, "")
+	req.SetPathValue("id", agentID)
+	return req
+}
+
+func TestHandleAgentPause_PersistsAndAudits(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	admin.manager = agent.NewManager(slog.Default())
+	ctx := context.Background()
+
+	if err := s.CreatePrincipal(ctx, &store.Principal{
+		ID: "principal-1", Type: store.PrincipalTypeAgent, PubkeyFP: strings.Repeat("a", 64),
+		DisplayName: "Agent", Status: store.PrincipalStatusApproved, CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreatePrincipal: %v", err)
+	}
+	conn := agent.NewConnection(agent.ConnectionParams{ID: "agent-1", Name: "Agent", PrincipalID: "principal-1", Logger: slog.Default()})
+	if err := admin.manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	admin.handleAgentPause(rec, pauseRequest("pause", "agent-1"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("pause status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var result map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result["paused"] != true || result["paused_by"] != "testadmin" {
+		t.Errorf("pause response = %v, want paused by testadmin", result)
+	}
+
+	if err := admin.manager.CheckNotPaused("agent-1"); !errors.Is(err, agent.ErrAgentPaused) {
+		t.Errorf("manager not paused after pause: %v", err)
+	}
+	pause, err := s.GetPrincipalPause(ctx, "principal-1")
+	if err != nil || pause == nil || pause.PausedBy != "testadmin" {
+		t.Errorf("persisted pause = %+v, %v; want paused by testadmin", pause, err)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleAgentResume(rec, pauseRequest("resume", "agent-1"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("resume status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if err := admin.manager.CheckNotPaused("agent-1"); err != nil {
+		t.Errorf("manager still paused after resume: %v", err)
+	}
+	if pause, _ := s.GetPrincipalPause(ctx, "principal-1"); pause != nil {
+		t.Errorf("persisted pause = %+v after resume, want nil", pause)
+	}
+
+	for _, action := range []store.AuditAction{store.AuditPauseAgent, store.AuditResumeAgent} {
+		entries, err := s.ListAuditLog(ctx, store.AuditFilter{Action: &action})
+		if err != nil {
+			t.Fatalf("ListAuditLog: %v", err)
+		}
+		if len(entries) != 1 || entries[0].TargetID != "agent-1" {
+			t.Errorf("%s audit entries = %+v, want one for agent-1", action, entries)
+		}
+	}
+}
+
+func TestHandleAgentPause_Guards(t *testing.T) {
+	admin, _ := newThreadOpsAdmin(t)
+	admin.manager = agent.NewManager(slog.Default())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/agents/agent-1/pause", nil)
+	req.SetPathValue("id", "agent-1")
+	rec := httptest.NewRecorder()
+	admin.handleAgentPause(rec, requestWithUser(req))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("without CSRF status = %d, want 403", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleAgentPause(rec, pauseRequest("pause", "unknown"))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown agent status = %d, want 404", rec.Code)
+	}
+}
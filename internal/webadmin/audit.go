@@ -0,0 +1,214 @@
+// ABOUTME: Audit log recording for admin actions and the read-only audit log page and API
+// ABOUTME: Entries carry the admin user and source IP; most are written in the mutation's transaction
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// Default and maximum page sizes of GET /api/admin/audit.
+const (
+	auditPageSize    = 50
+	auditMaxPageSize = 500
+)
+
+// auditItem is one audit log entry as shown to the web admin.
+type auditItem struct {
+	ID         string         `json:"id"`
+	Actor      string         `json:"actor"`
+	AdminUser  string         `json:"adminUser,omitempty"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"targetType"`
+	TargetID   string         `json:"targetId"`
+	Detail     map[string]any `json:"detail,omitempty"`
+	SourceIP   string         `json:"sourceIp,omitempty"`
+	Timestamp  string         `json:"timestamp"`
+}
+
+// auditPage is the response of GET /api/admin/audit.
+type auditPage struct {
+	Entries    []auditItem `json:"entries"`
+	NextOffset *int        `json:"nextOffset,omitempty"`
+}
+
+type auditPageData struct {
+	Title     string
+	User      *store.AdminUser
+	CSRFToken string
+	PropsJSON template.JS
+}
+
+// newAuditEntry returns an entry for an action by the request's admin user.
+func newAuditEntry(r *http.Request, action store.AuditAction, targetType, targetID string, detail map[string]any) *store.AuditEntry {
+	user := getUserFromContext(r)
+	if detail == nil {
+		detail = map[string]any{}
+	}
+	detail["admin_user"] = user.Username
+	return &store.AuditEntry{
+		ActorPrincipalID: user.ID,
+		Action:           action,
+		TargetType:       targetType,
+		TargetID:         targetID,
+		Detail:           detail,
+	}
+}
+
+// auditedContext returns the request context carrying entry, so the store
+// writes it in the same transaction as the mutation it describes.
+func auditedContext(r *http.Request, entry *store.AuditEntry) context.Context {
+	entry.SourceIP = requestIP(r)
+	return store.WithAuditEntry(r.Context(), entry)
+}
+
+// auditAdminAction records an admin action; failures are logged, not surfaced.
+// Use auditedContext instead when the store mutation can write the entry.
+func (a *Admin) auditAdminAction(r *http.Request, entry *store.AuditEntry) {
+	entry.SourceIP = requestIP(r)
+	if err := a.store.AppendAuditLog(r.Context(), entry); err != nil {
+		a.logger.Warn("failed to write audit entry", "action", entry.Action, "error", err)
+	}
+}
+
+// requestIP returns the host part of the request's remote address.
+func requestIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// auditFilterFromQuery builds a store filter from the actor, action,
+// target_type, target_id, since, until, limit and offset query parameters.
+func auditFilterFromQuery(r *http.Request) (store.AuditFilter, error) {
+	q := r.URL.Query()
+	filter := store.AuditFilter{Limit: auditPageSize}
+	if v := q.Get("actor"); v != "" {
+		filter.ActorPrincipalID = &v
+	}
+	if v := q.Get("action"); v != "" {
+		action := store.AuditAction(v)
+		filter.Action = &action
+	}
+	if v := q.Get("target_type"); v != "" {
+		filter.TargetType = &v
+	}
+	if v := q.Get("target_id"); v != "" {
+		filter.TargetID = &v
+	}
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 {
+		filter.Limit = min(l, auditMaxPageSize)
+	}
+	if o, err := strconv.Atoi(q.Get("offset")); err == nil && o > 0 {
+		filter.Offset = o
+	}
+	var err error
+	if filter.Since, err = timeparse.Query(q, "since"); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = timeparse.Query(q, "until"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// listAuditPage returns one page of entries matching filter. It fetches one
+// extra entry to tell whether another page follows.
+func (a *Admin) listAuditPage(ctx context.Context, filter store.AuditFilter) (auditPage, error) {
+	limit := filter.Limit
+	filter.Limit++
+	entries, err := a.store.ListAuditLog(ctx, filter)
+	if err != nil {
+		return auditPage{}, err
+	}
+
+	page := auditPage{Entries: make([]auditItem, 0, min(len(entries), limit))}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		next := filter.Offset + limit
+		page.NextOffset = &next
+	}
+	for _, e := range entries {
+		adminUser, _ := e.Detail["admin_user"].(string)
+		page.Entries = append(page.Entries, auditItem{
+			ID:         e.ID,
+			Actor:      e.ActorPrincipalID,
+			AdminUser:  adminUser,
+			Action:     string(e.Action),
+			TargetType: e.TargetType,
+			TargetID:   e.TargetID,
+			Detail:     e.Detail,
+			SourceIP:   e.SourceIP,
+			Timestamp:  timeparse.Format(e.Timestamp),
+		})
+	}
+	return page, nil
+}
+
+// handleAuditJSON returns audit log entries, newest first.
+// Query params: actor, action, target_type, target_id, since and until
+// (RFC3339 or epoch), limit (max 500) and offset.
+func (a *Admin) handleAuditJSON(w http.ResponseWriter, r *http.Request) {
+	filter, err := auditFilterFromQuery(r)
+	if err != nil {
+		a.writeTimestampError(w, err)
+		return
+	}
+	page, err := a.listAuditPage(r.Context(), filter)
+	if err != nil {
+		a.logger.Error("failed to list audit log", "error", err)
+		http.Error(w, "Failed to load audit log", http.StatusInternalServerError)
+		return
+	}
+	a.writeJSON(w, page)
+}
+
+// handleAuditPage renders the read-only audit log page.
+func (a *Admin) handleAuditPage(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	csrfToken := a.ensureCSRFToken(w, r)
+
+	page, err := a.listAuditPage(r.Context(), store.AuditFilter{Limit: auditPageSize})
+	if err != nil {
+		a.logger.Error("failed to list audit log", "error", err)
+		page = auditPage{Entries: []auditItem{}}
+	}
+	actions := make([]string, len(store.ValidAuditActions))
+	for i, action := range store.ValidAuditActions {
+		actions[i] = string(action)
+	}
+
+	propsJSON, err := json.Marshal(map[string]any{
+		"entries":    page.Entries,
+		"nextOffset": page.NextOffset,
+		"actions":    actions,
+		"userName":   user.DisplayName,
+		"csrfToken":  csrfToken,
+	})
+	if err != nil {
+		a.logger.Error("failed to marshal audit props", "error", err)
+		propsJSON = []byte(`{"entries":[],"actions":[],"csrfToken":""}`)
+	}
+
+	tmpl := parseTemplate("templates/base.html", "templates/audit.html")
+	data := auditPageData{
+		Title:     "Audit Log",
+		User:      user,
+		CSRFToken: csrfToken,
+		PropsJSON: template.JS(propsJSON),
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		a.logger.Error("failed to render audit page", "error", err)
+	}
+}
@@ -0,0 +1,116 @@
+// ABOUTME: Tests for the audit log viewer and the entries admin handlers write.
+// ABOUTME: Covers filtering, offset pagination, source IP capture, and bad timestamps.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func decodeAuditPage(t *testing.T, rec *httptest.ResponseRecorder) auditPage {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var page auditPage
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return page
+}
+
+func TestHandleAuditJSON_FiltersAndPaginates(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	ctx := context.Background()
+	for i, action := range []store.AuditAction{
+		store.AuditCreateSecret, store.AuditUpdateSecret, store.AuditDeleteSecret, store.AuditCreateInvite,
+	} {
+		if err := s.AppendAuditLog(ctx, &store.AuditEntry{
+			ActorPrincipalID: "admin-1", Action: action, TargetType: "secret", TargetID: "s-1",
+			Timestamp: time.Date(2026, 1, 1, 12, i, 0, 0, time.UTC),
+		}); err != nil {
+			t.Fatalf("AppendAuditLog: %v", err)
+		}
+	}
+
+	get := func(query string) auditPage {
+		rec := httptest.NewRecorder()
+		admin.handleAuditJSON(rec, requestWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/audit?"+query, nil)))
+		return decodeAuditPage(t, rec)
+	}
+
+	page := get("limit=3")
+	if len(page.Entries) != 3 || page.Entries[0].Action != string(store.AuditCreateInvite) {
+		t.Fatalf("first page = %+v, want 3 entries newest first", page.Entries)
+	}
+	if page.NextOffset == nil || *page.NextOffset != 3 {
+		t.Fatalf("nextOffset = %v, want 3", page.NextOffset)
+	}
+	page = get("limit=3&offset=3")
+	if len(page.Entries) != 1 || page.Entries[0].Action != string(store.AuditCreateSecret) || page.NextOffset != nil {
+		t.Errorf("second page = %+v (next %v), want only create_secret and no next page", page.Entries, page.NextOffset)
+	}
+
+	page = get("action=update_secret")
+	if len(page.Entries) != 1 || page.Entries[0].Action != string(store.AuditUpdateSecret) {
+		t.Errorf("action filter = %+v, want one update_secret", page.Entries)
+	}
+	page = get("since=2026-01-01T12:02:00Z")
+	if len(page.Entries) != 2 {
+		t.Errorf("since filter returned %d entries, want 2", len(page.Entries))
+	}
+
+	rec := httptest.NewRecorder()
+	admin.handleAuditJSON(rec, requestWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/audit?since=yesterday", nil)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("bad since status = %d, want 400", rec.Code)
+	}
+}
+
+func TestHandlePrincipalApprove_RecordsSourceIP(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	ctx := context.Background()
+	if err := s.CreatePrincipal(ctx, &store.Principal{
+		ID: "p-1", Type: store.PrincipalTypeAgent, PubkeyFP: "fp-1", DisplayName: "Agent",
+		Status: store.PrincipalStatusPending, CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreatePrincipal: %v", err)
+	}
+
+	req := csrfJSONRequest(http.MethodPost, "/admin/principals/p-1/approve", "")
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.SetPathValue("id", "p-1")
+	rec := httptest.NewRecorder()
+	admin.handlePrincipalApprove(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("approve status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	action := store.AuditApprovePrincipal
+	entries, err := s.ListAuditLog(ctx, store.AuditFilter{Action: &action})
+	if err != nil {
+		t.Fatalf("ListAuditLog: %v", err)
+	}
+	if len(entries) != 1 || entries[0].SourceIP != "203.0.113.7" || entries[0].ActorPrincipalID != "test-user" {
+		t.Errorf("audit entries = %+v, want one from 203.0.113.7 by test-user", entries)
+	}
+
+	req = csrfJSONRequest(http.MethodPost, "/admin/principals/nobody/approve", "")
+	req.SetPathValue("id", "nobody")
+	rec = httptest.NewRecorder()
+	admin.handlePrincipalApprove(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown principal status = %d, want 404", rec.Code)
+	}
+	entries, err = s.ListAuditLog(ctx, store.AuditFilter{Action: &action})
+	if err != nil || len(entries) != 1 {
+		t.Errorf("audit entries after failed approve = %d, %v; want still 1", len(entries), err)
+	}
+}
@@ -0,0 +1,105 @@
+// ABOUTME: Settings panel and API for editing each binding's channel context
+// ABOUTME: The context is sent to the agent with every message through the binding
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// bindingItem is one binding as shown in the settings panel.
+type bindingItem struct {
+	ID        string `json:"id"`
+	Frontend  string `json:"frontend"`
+	ChannelID string `json:"channelId"`
+	AgentID   string `json:"agentId"`
+	Workspace string `json:"workspace,omitempty"`
+	Context   string `json:"context"`
+	// ContextHash is what the ledger records for messages sent with this
+	// context, so admins can match exchanges to the version in effect.
+	ContextHash string `json:"contextHash,omitempty"`
+}
+
+// bindingsPanel is the binding section of the settings page.
+type bindingsPanel struct {
+	Bindings        []bindingItem `json:"bindings"`
+	MaxContextBytes int           `json:"maxContextBytes"`
+}
+
+// updateBindingContextRequest is the body of PUT /api/admin/bindings/{id}/context.
+type updateBindingContextRequest struct {
+	Context string `json:"context"`
+}
+
+func newBindingItem(b *store.Binding) bindingItem {
+	return bindingItem{
+		ID:          b.ID,
+		Frontend:    b.Frontend,
+		ChannelID:   b.ChannelID,
+		AgentID:     b.AgentID,
+		Workspace:   b.Workspace,
+		Context:     b.Context,
+		ContextHash: store.BindingContextHash(b.Context),
+	}
+}
+
+// buildBindingsPanel lists every binding with its channel context.
+func (a *Admin) buildBindingsPanel(ctx context.Context) (*bindingsPanel, error) {
+	bindings, err := a.store.ListBindingsV2(ctx, store.BindingFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("listing bindings: %w", err)
+	}
+	panel := &bindingsPanel{Bindings: make([]bindingItem, 0, len(bindings)), MaxContextBytes: store.MaxBindingContextBytes}
+	for i := range bindings {
+		panel.Bindings = append(panel.Bindings, newBindingItem(&bindings[i]))
+	}
+	return panel, nil
+}
+
+// handleUpdateBindingContext replaces a binding's channel context; empty
+// clears it. The audit entry records hashes rather than the text.
+func (a *Admin) handleUpdateBindingContext(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	id := r.PathValue("id")
+
+	var req updateBindingContextRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Context) > store.MaxBindingContextBytes {
+		http.Error(w, fmt.Sprintf("Context must be at most %d bytes", store.MaxBindingContextBytes), http.StatusBadRequest)
+		return
+	}
+
+	before, err := a.store.GetBindingByID(r.Context(), id)
+	if err == nil {
+		err = a.store.SetBindingContext(r.Context(), id, req.Context)
+	}
+	if errors.Is(err, store.ErrBindingNotFound) {
+		http.Error(w, "Binding not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		a.logger.Error("failed to update binding context", "binding_id", id, "error", err)
+		http.Error(w, "Failed to update binding", http.StatusInternalServerError)
+		return
+	}
+
+	a.auditAdminAction(r, newAuditEntry(r, store.AuditUpdateBinding, "binding", id, map[string]any{
+		"context_hash":          store.BindingContextHash(req.Context),
+		"previous_context_hash": store.BindingContextHash(before.Context),
+	}))
+
+	before.Context = req.Context
+	a.writeJSON(w, newBindingItem(before))
+}
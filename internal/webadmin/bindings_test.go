@@ -0,0 +1,118 @@
+// ABOUTME: Tests for the settings-page bindings panel and the channel context
+// ABOUTME: endpoint: saving, clearing, size limits, unknown bindings and auditing.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func seedAdminBinding(t *testing.T, s *store.SQLStore, id, bindingContext string) {
+	t.Helper()
+	ctx := context.Background()
+	if err := s.CreatePrincipal(ctx, &store.Principal{
+		ID: "agent-1", Type: store.PrincipalTypeAgent, PubkeyFP: strings.Repeat("a", 64),
+		DisplayName: "Agent 1", Status: store.PrincipalStatusApproved, CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreatePrincipal: %v", err)
+	}
+	if err := s.CreateBindingV2(ctx, &store.Binding{
+		ID: id, Frontend: "slack", ChannelID: "C123", AgentID: "agent-1",
+		CreatedAt: time.Now().UTC(), Context: bindingContext,
+	}); err != nil {
+		t.Fatalf("CreateBindingV2: %v", err)
+	}
+}
+
+func putBindingContext(admin *Admin, id, body string) *httptest.ResponseRecorder {
+	req := csrfJSONRequest(http.MethodPut, "/api/admin/bindings/"+id+"/context", body)
+	req.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	admin.handleUpdateBindingContext(rec, req)
+	return rec
+}
+
+func TestHandleUpdateBindingContext(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	seedAdminBinding(t, s, "binding-1", "old context")
+	ctx := context.Background()
+
+	rec := putBindingContext(admin, "binding-1", `{"context":"Answer in French."}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var item bindingItem
+	if err := json.NewDecoder(rec.Body).Decode(&item); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if item.Context != "Answer in French." || item.ContextHash != store.BindingContextHash("Answer in French.") {
+		t.Errorf("item = %+v, want the new context and its hash", item)
+	}
+	if b, _ := s.GetBindingByID(ctx, "binding-1"); b == nil || b.Context != "Answer in French." {
+		t.Errorf("persisted binding = %+v, want new context", b)
+	}
+
+	action := store.AuditUpdateBinding
+	entries, err := s.ListAuditLog(ctx, store.AuditFilter{Action: &action})
+	if err != nil {
+		t.Fatalf("ListAuditLog: %v", err)
+	}
+	if len(entries) != 1 || entries[0].TargetID != "binding-1" ||
+		entries[0].Detail["previous_context_hash"] != store.BindingContextHash("old context") {
+		t.Errorf("audit entries = %+v, want one with the previous context hash", entries)
+	}
+
+	panel, err := admin.buildBindingsPanel(ctx)
+	if err != nil {
+		t.Fatalf("buildBindingsPanel: %v", err)
+	}
+	if len(panel.Bindings) != 1 || panel.Bindings[0].Context != "Answer in French." || panel.MaxContextBytes != store.MaxBindingContextBytes {
+		t.Errorf("panel = %+v", panel)
+	}
+
+	rec = putBindingContext(admin, "binding-1", `{"context":""}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("clear status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if b, _ := s.GetBindingByID(ctx, "binding-1"); b == nil || b.Context != "" {
+		t.Errorf("persisted binding = %+v, want cleared context", b)
+	}
+}
+
+func TestHandleUpdateBindingContext_Errors(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	seedAdminBinding(t, s, "binding-1", "")
+
+	tooLong := `{"context":"` + strings.Repeat("x", store.MaxBindingContextBytes+1) + `"}`
+	tests := []struct {
+		name, id, body string
+		want           int
+	}{
+		{"too long", "binding-1", tooLong, http.StatusBadRequest},
+		{"bad JSON", "binding-1", `{`, http.StatusBadRequest},
+		{"unknown binding", "missing", `{"context":"hi"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := putBindingContext(admin, tt.id, tt.body); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/bindings/binding-1/context", strings.NewReader(`{"context":"hi"}`))
+	req.SetPathValue("id", "binding-1")
+	rec := httptest.NewRecorder()
+	admin.handleUpdateBindingContext(rec, requestWithUser(req))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("without CSRF status = %d, want 403", rec.Code)
+	}
+}
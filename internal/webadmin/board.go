@@ -0,0 +1,201 @@
+// ABOUTME: Board (BBS) write handlers: admins start threads and reply from the web UI.
+// ABOUTME: Posts go through the same BuiltinStore methods as the bbs_* tools, attributed to a synthetic human author.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// humanAuthorPrefix starts the synthetic author ID of posts by admin users,
+// keeping them apart from agent IDs in bbs_posts.agent_id and mail.
+const humanAuthorPrefix = "human:"
+
+// boardPageSize is the number of threads on one page of the board.
+const boardPageSize = 50
+
+// boardPost is the JSON form of a board post for the Svelte island.
+type boardPost struct {
+	ID         string `json:"ID"`
+	AgentID    string `json:"AgentID"`
+	ThreadID   string `json:"ThreadID"`
+	Subject    string `json:"Subject"`
+	Content    string `json:"Content"`
+	AuthorKind string `json:"AuthorKind"`
+	AuthorName string `json:"AuthorName,omitempty"`
+	Pinned     bool   `json:"Pinned"`
+	CreatedAt  string `json:"CreatedAt"`
+}
+
+func toBoardPost(p *store.BBSPost) boardPost {
+	kind := p.AuthorKind
+	if kind == "" {
+		kind = store.BBSAuthorAgent
+	}
+	return boardPost{
+		ID:         p.ID,
+		AgentID:    p.AgentID,
+		ThreadID:   p.ThreadID,
+		Subject:    p.Subject,
+		Content:    p.Content,
+		AuthorKind: kind,
+		AuthorName: p.AuthorName,
+		Pinned:     p.Pinned,
+		CreatedAt:  timeparse.Format(p.CreatedAt),
+	}
+}
+
+func toBoardPosts(posts []*store.BBSPost) []boardPost {
+	items := make([]boardPost, 0, len(posts))
+	for _, p := range posts {
+		items = append(items, toBoardPost(p))
+	}
+	return items
+}
+
+// listBoardThreads returns up to limit threads from offset, pinned first,
+// and whether more follow.
+func (a *Admin) listBoardThreads(ctx context.Context, offset, limit int) ([]*store.BBSPost, bool, error) {
+	threads, err := a.store.ListBBSThreads(ctx, store.BBSThreadFilter{Offset: offset, Limit: limit + 1})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(threads) > limit {
+		return threads[:limit], true, nil
+	}
+	return threads, false, nil
+}
+
+// humanBoardPost returns a post attributed to the admin user.
+func humanBoardPost(user *store.AdminUser) *store.BBSPost {
+	return &store.BBSPost{
+		AgentID:    humanAuthorPrefix + user.Username,
+		AuthorKind: store.BBSAuthorHuman,
+		AuthorName: user.Username + " (human)",
+	}
+}
+
+// boardThreadRequest is the body of POST /api/admin/board.
+type boardThreadRequest struct {
+	Subject string `json:"subject"`
+	Content string `json:"content"`
+}
+
+// boardReplyRequest is the body of POST /api/admin/board/{id}/replies.
+type boardReplyRequest struct {
+	Content string `json:"content"`
+	// Notify mails the agent that started the thread about the reply.
+	Notify bool `json:"notify"`
+}
+
+// handleBoardCreateThread starts a board thread as the admin user.
+func (a *Admin) handleBoardCreateThread(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+
+	var req boardThreadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Subject = strings.TrimSpace(req.Subject)
+	if req.Subject == "" || strings.TrimSpace(req.Content) == "" {
+		http.Error(w, "subject and content are required", http.StatusBadRequest)
+		return
+	}
+
+	user := getUserFromContext(r)
+	post := humanBoardPost(user)
+	post.Subject = req.Subject
+	post.Content = req.Content
+	if err := a.store.CreateBBSPost(r.Context(), post); err != nil {
+		a.logger.Error("failed to create board thread", "error", err)
+		http.Error(w, "Failed to create thread", http.StatusInternalServerError)
+		return
+	}
+	a.logger.Info("board thread created", "thread_id", post.ID, "by", user.Username)
+
+	w.WriteHeader(http.StatusCreated)
+	a.writeJSON(w, toBoardPost(post))
+}
+
+// handleBoardReply replies to a board thread as the admin user and, when
+// asked, mails the agent that started the thread.
+func (a *Admin) handleBoardReply(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+
+	threadID := r.PathValue("id")
+	var req boardReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		http.Error(w, "content is required", http.StatusBadRequest)
+		return
+	}
+
+	thread, err := a.store.GetBBSThread(r.Context(), threadID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "thread not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		a.logger.Error("failed to get BBS thread", "error", err, "thread_id", threadID)
+		http.Error(w, "Failed to load thread", http.StatusInternalServerError)
+		return
+	}
+	if thread.Post.ThreadID != "" {
+		http.Error(w, "cannot reply to a reply, use the original thread", http.StatusBadRequest)
+		return
+	}
+
+	user := getUserFromContext(r)
+	post := humanBoardPost(user)
+	post.ThreadID = threadID
+	post.Content = req.Content
+	if err := a.store.CreateBBSPost(r.Context(), post); err != nil {
+		a.logger.Error("failed to create board reply", "error", err, "thread_id", threadID)
+		http.Error(w, "Failed to post reply", http.StatusInternalServerError)
+		return
+	}
+	a.logger.Info("board reply posted", "thread_id", threadID, "post_id", post.ID, "by", user.Username)
+
+	notified := false
+	if req.Notify && thread.Post.AuthorKind != store.BBSAuthorHuman {
+		notified = a.notifyBoardReply(r, thread.Post, post)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	a.writeJSON(w, map[string]any{"post": toBoardPost(post), "notified": notified})
+}
+
+// notifyBoardReply mails the thread's author about a human reply. Failures
+// are logged; the reply itself has already been saved.
+func (a *Admin) notifyBoardReply(r *http.Request, thread, reply *store.BBSPost) bool {
+	mail := &store.AgentMail{
+		FromAgentID: reply.AgentID,
+		ToAgentID:   thread.AgentID,
+		Subject:     "Re: " + thread.Subject,
+		Content: fmt.Sprintf("%s replied to your board thread %q:\n\n%s\n\nRead the thread with bbs_read_thread (thread_id %s).",
+			reply.AuthorName, thread.Subject, reply.Content, thread.ID),
+	}
+	if err := a.store.SendMail(r.Context(), mail); err != nil {
+		a.logger.Warn("failed to notify thread author", "thread_id", thread.ID, "agent_id", thread.AgentID, "error", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,194 @@
+// ABOUTME: Tests for board posting from the web UI: thread creation, replies, and author notification.
+// ABOUTME: Uses a real SQLite store so posts and mail land where the bbs_* and mail tools read them.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestHandleBoardCreateThread(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+
+	rec := httptest.NewRecorder()
+	admin.handleBoardCreateThread(rec, csrfJSONRequest(http.MethodPost, "/api/admin/board",
+		`{"subject":"  Deploy window ","content":"Friday **after** 5pm"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body.String())
+	}
+
+	var got boardPost
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Subject != "Deploy window" || got.AgentID != "human:testadmin" ||
+		got.AuthorKind != store.BBSAuthorHuman || got.AuthorName != "testadmin (human)" {
+		t.Errorf("post = %+v, want a trimmed subject attributed to the human admin", got)
+	}
+
+	stored, err := s.GetBBSPost(context.Background(), got.ID)
+	if err != nil {
+		t.Fatalf("GetBBSPost: %v", err)
+	}
+	if stored.AuthorKind != store.BBSAuthorHuman || stored.Content != "Friday **after** 5pm" {
+		t.Errorf("stored = %+v, want the human post", stored)
+	}
+}
+
+func TestHandleBoardCreateThread_Rejects(t *testing.T) {
+	admin, _ := newThreadOpsAdmin(t)
+
+	noCSRF := requestWithUser(httptest.NewRequest(http.MethodPost, "/api/admin/board",
+		strings.NewReader(`{"subject":"s","content":"c"}`)))
+	rec := httptest.NewRecorder()
+	admin.handleBoardCreateThread(rec, noCSRF)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("without CSRF: status = %d, want 403", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleBoardCreateThread(rec, csrfJSONRequest(http.MethodPost, "/api/admin/board",
+		`{"subject":"  ","content":"c"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("blank subject: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestHandleBoardReply_NotifiesAgentAuthor(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	ctx := context.Background()
+	thread := &store.BBSPost{AgentID: "agent-1", Subject: "Flaky build", Content: "CI keeps timing out"}
+	if err := s.CreateBBSPost(ctx, thread); err != nil {
+		t.Fatalf("CreateBBSPost: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := csrfJSONRequest(http.MethodPost, "/api/admin/board/"+thread.ID+"/replies",
+		`{"content":"Bumped the runner timeout","notify":true}`)
+	req.SetPathValue("id", thread.ID)
+	admin.handleBoardReply(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Post     boardPost `json:"post"`
+		Notified bool      `json:"notified"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.Notified || resp.Post.ThreadID != thread.ID || resp.Post.AuthorKind != store.BBSAuthorHuman {
+		t.Errorf("response = %+v, want a notified human reply in the thread", resp)
+	}
+
+	full, err := s.GetBBSThread(ctx, thread.ID)
+	if err != nil {
+		t.Fatalf("GetBBSThread: %v", err)
+	}
+	if len(full.Replies) != 1 || full.Replies[0].AuthorName != "testadmin (human)" {
+		t.Errorf("replies = %+v, want the admin reply", full.Replies)
+	}
+
+	inbox, err := s.ListInbox(ctx, "agent-1", true, "", 10)
+	if err != nil {
+		t.Fatalf("ListInbox: %v", err)
+	}
+	if len(inbox) != 1 {
+		t.Fatalf("inbox has %d messages, want 1", len(inbox))
+	}
+	if inbox[0].FromAgentID != "human:testadmin" || inbox[0].Subject != "Re: Flaky build" ||
+		!strings.Contains(inbox[0].Content, "Bumped the runner timeout") {
+		t.Errorf("mail = %+v, want the reply forwarded to the thread author", inbox[0])
+	}
+}
+
+func TestHandleBoardReply_Rejects(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	ctx := context.Background()
+	thread := &store.BBSPost{AgentID: "agent-1", Subject: "Topic", Content: "Body"}
+	if err := s.CreateBBSPost(ctx, thread); err != nil {
+		t.Fatalf("CreateBBSPost: %v", err)
+	}
+	reply := &store.BBSPost{AgentID: "agent-2", ThreadID: thread.ID, Content: "Reply"}
+	if err := s.CreateBBSPost(ctx, reply); err != nil {
+		t.Fatalf("CreateBBSPost reply: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		body string
+		want int
+	}{
+		{"unknown thread", "missing", `{"content":"hi"}`, http.StatusNotFound},
+		{"reply to a reply", reply.ID, `{"content":"hi"}`, http.StatusBadRequest},
+		{"empty content", thread.ID, `{"content":" "}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := csrfJSONRequest(http.MethodPost, "/api/admin/board/"+tt.id+"/replies", tt.body)
+			req.SetPathValue("id", tt.id)
+			admin.handleBoardReply(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	inbox, err := s.ListInbox(ctx, "agent-1", false, "", 10)
+	if err != nil {
+		t.Fatalf("ListInbox: %v", err)
+	}
+	if len(inbox) != 0 {
+		t.Errorf("inbox has %d messages, want none without notify", len(inbox))
+	}
+}
+
+func TestHandleBoardJSON_PagesPinnedFirst(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	ctx := context.Background()
+
+	var ids []string
+	for _, subject := range []string{"one", "two", "three"} {
+		p := &store.BBSPost{AgentID: "agent-1", Subject: subject, Content: subject}
+		if err := s.CreateBBSPost(ctx, p); err != nil {
+			t.Fatalf("CreateBBSPost: %v", err)
+		}
+		ids = append(ids, p.ID)
+	}
+	if err := s.SetBBSThreadPinned(ctx, ids[0], true); err != nil {
+		t.Fatalf("SetBBSThreadPinned: %v", err)
+	}
+
+	page := func(query string) (threads []boardPost, hasMore bool) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		admin.handleBoardJSON(rec, httptest.NewRequest(http.MethodGet, "/api/admin/board?"+query, nil))
+		var resp struct {
+			Threads []boardPost `json:"threads"`
+			HasMore bool        `json:"hasMore"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Threads, resp.HasMore
+	}
+
+	first, more := page("limit=2")
+	if len(first) != 2 || first[0].ID != ids[0] || !first[0].Pinned || !more {
+		t.Errorf("first page = %+v, more = %v; want the pinned thread first and more to come", first, more)
+	}
+	rest, more := page("limit=2&offset=2")
+	if len(rest) != 1 || more {
+		t.Errorf("second page = %+v, more = %v; want the last thread", rest, more)
+	}
+}
@@ -0,0 +1,345 @@
+// ABOUTME: Admin report of agents that called tools without a granted capability
+// ABOUTME: Lists the last week's capability warnings and grants missing capabilities with an audit entry
+// ABOUTME: Also lists and replaces a principal's grants, which may be patterns like "web.*" or "*"
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// capabilityWarningWindow is how far back the capabilities report looks.
+const capabilityWarningWindow = 7 * 24 * time.Hour
+
+// capabilityWarningItem is one agent, tool and capability on the report.
+type capabilityWarningItem struct {
+	AgentID     string `json:"agentId"`
+	PrincipalID string `json:"principalId"`
+	Tool        string `json:"tool"`
+	Capability  string `json:"capability"`
+	Count       int    `json:"count"`
+	LastSeen    string `json:"lastSeen"`
+}
+
+// grantCapabilityRequest is the body of POST /api/admin/capabilities/grant.
+type grantCapabilityRequest struct {
+	PrincipalID string `json:"principalId"`
+	Capability  string `json:"capability"`
+}
+
+// principalCapabilities is the body of PUT and the response of GET
+// /api/admin/principals/{id}/capabilities.
+type principalCapabilities struct {
+	PrincipalID  string   `json:"principalId"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// CapabilityNotifier applies a principal's changed capability grants to its
+// connected agents, so they take effect without a reconnect.
+type CapabilityNotifier interface {
+	CapabilitiesChanged(principalID string, capabilities []string)
+}
+
+// errWildcardCapability rejects a "*" grant to a principal without an admin role.
+var errWildcardCapability = errors.New(`capability "*" can only be granted to owner or admin principals`)
+
+type capabilitiesPageData struct {
+	Title     string
+	User      *store.AdminUser
+	CSRFToken string
+	PropsJSON template.JS
+}
+
+// listCapabilityWarningItems returns the warnings recorded within the report window.
+func (a *Admin) listCapabilityWarningItems(r *http.Request) ([]capabilityWarningItem, error) {
+	warnings, err := a.store.ListCapabilityWarnings(r.Context(), time.Now().Add(-capabilityWarningWindow))
+	if err != nil {
+		return nil, err
+	}
+	items := make([]capabilityWarningItem, len(warnings))
+	for i, w := range warnings {
+		items[i] = capabilityWarningItem{
+			AgentID:     w.AgentID,
+			PrincipalID: w.PrincipalID,
+			Tool:        w.Tool,
+			Capability:  w.Capability,
+			Count:       w.Count,
+			LastSeen:    timeparse.Format(w.LastSeen),
+		}
+	}
+	return items, nil
+}
+
+// SetCapabilityEnforcement changes the enforcement mode the capabilities
+// page shows, after packs.capability_enforcement is reloaded.
+func (a *Admin) SetCapabilityEnforcement(mode string) {
+	a.enforcement.Store(&mode)
+}
+
+// SetCapabilityNotifier wires the gateway hook told about capability changes.
+func (a *Admin) SetCapabilityNotifier(n CapabilityNotifier) {
+	a.capabilities = n
+}
+
+// notifyCapabilitiesChanged passes a principal's current grants to the
+// capability notifier, if one is set.
+func (a *Admin) notifyCapabilitiesChanged(ctx context.Context, principalID string) {
+	if a.capabilities == nil {
+		return
+	}
+	caps, err := a.store.ListCapabilities(ctx, principalID)
+	if err != nil {
+		a.logger.Warn("failed to list capabilities for connected agents", "principal_id", principalID, "error", err)
+		return
+	}
+	a.capabilities.CapabilitiesChanged(principalID, caps)
+}
+
+func (a *Admin) capabilityEnforcement() string {
+	if mode := a.enforcement.Load(); mode != nil {
+		return *mode
+	}
+	return a.config.CapabilityEnforcement
+}
+
+// handleCapabilitiesPage renders the capability warnings report.
+func (a *Admin) handleCapabilitiesPage(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	csrfToken := a.ensureCSRFToken(w, r)
+
+	items, err := a.listCapabilityWarningItems(r)
+	if err != nil {
+		a.logger.Error("failed to list capability warnings", "error", err)
+		items = []capabilityWarningItem{}
+	}
+
+	propsJSON, err := json.Marshal(map[string]any{
+		"warnings":    items,
+		"enforcement": a.capabilityEnforcement(),
+		"userName":    user.DisplayName,
+		"csrfToken":   csrfToken,
+	})
+	if err != nil {
+		a.logger.Error("failed to marshal capabilities props", "error", err)
+		propsJSON = []byte(`{"warnings":[],"csrfToken":""}`)
+	}
+
+	tmpl := parseTemplate("templates/base.html", "templates/capabilities.html")
+	data := capabilitiesPageData{
+		Title:     "Capabilities",
+		User:      user,
+		CSRFToken: csrfToken,
+		PropsJSON: template.JS(propsJSON),
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		a.logger.Error("failed to render capabilities page", "error", err)
+	}
+}
+
+// handleCapabilityWarningsJSON returns the capability warnings of the last 7 days.
+func (a *Admin) handleCapabilityWarningsJSON(w http.ResponseWriter, r *http.Request) {
+	items, err := a.listCapabilityWarningItems(r)
+	if err != nil {
+		a.logger.Error("failed to list capability warnings", "error", err)
+		http.Error(w, "Failed to load capability warnings", http.StatusInternalServerError)
+		return
+	}
+	a.writeJSON(w, items)
+}
+
+// handleGrantCapability grants a capability to a principal so its calls stop
+// triggering warnings, and stop being denied once enforcement is on.
+func (a *Admin) handleGrantCapability(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+
+	var req grantCapabilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.PrincipalID = strings.TrimSpace(req.PrincipalID)
+	req.Capability = strings.TrimSpace(req.Capability)
+	if req.PrincipalID == "" || req.Capability == "" {
+		http.Error(w, "principalId and capability are required", http.StatusBadRequest)
+		return
+	}
+	if _, err := a.store.GetPrincipal(r.Context(), req.PrincipalID); err != nil {
+		if errors.Is(err, store.ErrPrincipalNotFound) {
+			http.Error(w, "Principal not found", http.StatusNotFound)
+			return
+		}
+		a.logger.Error("failed to look up principal", "principal_id", req.PrincipalID, "error", err)
+		http.Error(w, "Failed to grant capability", http.StatusInternalServerError)
+		return
+	}
+	if err := a.validateCapabilityGrant(r.Context(), req.PrincipalID, req.Capability); err != nil {
+		a.writeCapabilityGrantError(w, req.PrincipalID, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	if err := a.store.GrantCapability(r.Context(), req.PrincipalID, req.Capability, user.ID); err != nil {
+		a.logger.Error("failed to grant capability", "principal_id", req.PrincipalID, "capability", req.Capability, "error", err)
+		http.Error(w, "Failed to grant capability", http.StatusInternalServerError)
+		return
+	}
+
+	a.auditCapabilityChange(r, store.AuditGrantCapability, req.PrincipalID, req.Capability)
+	a.notifyCapabilitiesChanged(r.Context(), req.PrincipalID)
+
+	a.writeJSON(w, map[string]any{"principalId": req.PrincipalID, "capability": req.Capability})
+}
+
+// validateCapabilityGrant checks that capability is a valid capability or
+// pattern, and that "*" only goes to principals holding the owner or admin role.
+func (a *Admin) validateCapabilityGrant(ctx context.Context, principalID, capability string) error {
+	if err := packs.ValidateCapability(capability); err != nil {
+		return err
+	}
+	if capability != packs.WildcardCapability {
+		return nil
+	}
+	roles, err := a.store.ListRoles(ctx, store.RoleSubjectPrincipal, principalID)
+	if err != nil {
+		return fmt.Errorf("listing roles: %w", err)
+	}
+	if slices.Contains(roles, store.RoleOwner) || slices.Contains(roles, store.RoleAdmin) {
+		return nil
+	}
+	return errWildcardCapability
+}
+
+// writeCapabilityGrantError writes a 400 for an invalid or disallowed grant
+// and a 500 for anything else.
+func (a *Admin) writeCapabilityGrantError(w http.ResponseWriter, principalID string, err error) {
+	if errors.Is(err, packs.ErrInvalidCapability) || errors.Is(err, errWildcardCapability) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.logger.Error("failed to validate capability grant", "principal_id", principalID, "error", err)
+	http.Error(w, "Failed to update capabilities", http.StatusInternalServerError)
+}
+
+// handlePrincipalCapabilitiesJSON returns the capabilities granted to a principal.
+func (a *Admin) handlePrincipalCapabilitiesJSON(w http.ResponseWriter, r *http.Request) {
+	principalID := r.PathValue("id")
+	if _, err := a.store.GetPrincipal(r.Context(), principalID); err != nil {
+		if errors.Is(err, store.ErrPrincipalNotFound) {
+			http.Error(w, "Principal not found", http.StatusNotFound)
+			return
+		}
+		a.logger.Error("failed to look up principal", "principal_id", principalID, "error", err)
+		http.Error(w, "Failed to load capabilities", http.StatusInternalServerError)
+		return
+	}
+	caps, err := a.store.ListCapabilities(r.Context(), principalID)
+	if err != nil {
+		a.logger.Error("failed to list capabilities", "principal_id", principalID, "error", err)
+		http.Error(w, "Failed to load capabilities", http.StatusInternalServerError)
+		return
+	}
+	a.writeJSON(w, principalCapabilities{PrincipalID: principalID, Capabilities: caps})
+}
+
+// handleSetPrincipalCapabilities replaces a principal's capability grants,
+// granting and revoking the difference with an audit entry for each change.
+// Grants may be patterns such as "web.*", and "*" for owner or admin principals.
+// The principal's connected agents are given the new set without reconnecting.
+func (a *Admin) handleSetPrincipalCapabilities(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+
+	principalID := r.PathValue("id")
+	var req principalCapabilities
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if _, err := a.store.GetPrincipal(r.Context(), principalID); err != nil {
+		if errors.Is(err, store.ErrPrincipalNotFound) {
+			http.Error(w, "Principal not found", http.StatusNotFound)
+			return
+		}
+		a.logger.Error("failed to look up principal", "principal_id", principalID, "error", err)
+		http.Error(w, "Failed to update capabilities", http.StatusInternalServerError)
+		return
+	}
+
+	want := make([]string, 0, len(req.Capabilities))
+	for _, c := range req.Capabilities {
+		c = strings.TrimSpace(c)
+		if c == "" || slices.Contains(want, c) {
+			continue
+		}
+		if err := a.validateCapabilityGrant(r.Context(), principalID, c); err != nil {
+			a.writeCapabilityGrantError(w, principalID, err)
+			return
+		}
+		want = append(want, c)
+	}
+
+	held, err := a.store.ListCapabilities(r.Context(), principalID)
+	if err != nil {
+		a.logger.Error("failed to list capabilities", "principal_id", principalID, "error", err)
+		http.Error(w, "Failed to update capabilities", http.StatusInternalServerError)
+		return
+	}
+
+	user := getUserFromContext(r)
+	changed := false
+	for _, c := range want {
+		if slices.Contains(held, c) {
+			continue
+		}
+		if err := a.store.GrantCapability(r.Context(), principalID, c, user.ID); err != nil {
+			a.logger.Error("failed to grant capability", "principal_id", principalID, "capability", c, "error", err)
+			http.Error(w, "Failed to update capabilities", http.StatusInternalServerError)
+			return
+		}
+		a.auditCapabilityChange(r, store.AuditGrantCapability, principalID, c)
+		changed = true
+	}
+	for _, c := range held {
+		if slices.Contains(want, c) {
+			continue
+		}
+		if err := a.store.RevokeCapability(r.Context(), principalID, c); err != nil {
+			a.logger.Error("failed to revoke capability", "principal_id", principalID, "capability", c, "error", err)
+			http.Error(w, "Failed to update capabilities", http.StatusInternalServerError)
+			return
+		}
+		a.auditCapabilityChange(r, store.AuditRevokeCapability, principalID, c)
+		changed = true
+	}
+	if changed {
+		a.notifyCapabilitiesChanged(r.Context(), principalID)
+	}
+
+	slices.Sort(want)
+	a.writeJSON(w, principalCapabilities{PrincipalID: principalID, Capabilities: want})
+}
+
+// auditCapabilityChange records one capability granted to or revoked from a principal.
+func (a *Admin) auditCapabilityChange(r *http.Request, action store.AuditAction, principalID, capability string) {
+	a.auditAdminAction(r, newAuditEntry(r, action, "principal", principalID, map[string]any{
+		"capability": capability,
+	}))
+}
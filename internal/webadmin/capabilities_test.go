@@ -0,0 +1,197 @@
+// ABOUTME: Tests for the capability warnings report and capability grants.
+// ABOUTME: Covers the 7-day window, granting with an audit entry, request validation, and pattern grants.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func saveCapabilityWarning(t *testing.T, s *store.SQLStore, id, agentID string, at time.Time) {
+	t.Helper()
+	text := `{"tool":"log_entry","capability":"base","principal_id":"p-` + agentID + `"}`
+	if err := s.SaveEvent(context.Background(), &store.LedgerEvent{
+		ID: id, ConversationKey: agentID, Direction: store.EventDirectionOutbound, Author: agentID,
+		Timestamp: at, Type: store.EventTypeCapabilityWarning, Text: &text,
+	}); err != nil {
+		t.Fatalf("SaveEvent: %v", err)
+	}
+}
+
+func TestHandleCapabilityWarningsJSON(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	now := time.Now().UTC()
+	saveCapabilityWarning(t, s, "w-1", "agent-recent", now.Add(-time.Hour))
+	saveCapabilityWarning(t, s, "w-2", "agent-recent", now.Add(-2*time.Hour))
+	saveCapabilityWarning(t, s, "w-3", "agent-stale", now.Add(-8*24*time.Hour))
+
+	rec := httptest.NewRecorder()
+	admin.handleCapabilityWarningsJSON(rec, requestWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/capabilities/warnings", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var items []capabilityWarningItem
+	if err := json.NewDecoder(rec.Body).Decode(&items); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(items) != 1 || items[0].AgentID != "agent-recent" || items[0].Count != 2 || items[0].PrincipalID != "p-agent-recent" {
+		t.Errorf("items = %+v, want only agent-recent with 2 warnings", items)
+	}
+}
+
+func TestHandleGrantCapability(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	ctx := context.Background()
+	if err := s.CreatePrincipal(ctx, &store.Principal{
+		ID: "p-1", Type: store.PrincipalTypeAgent, PubkeyFP: "fp-1", DisplayName: "Agent",
+		Status: store.PrincipalStatusApproved, CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreatePrincipal: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	admin.handleGrantCapability(rec, csrfJSONRequest(http.MethodPost, "/api/admin/capabilities/grant",
+		`{"principalId":"p-1","capability":" base "}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("grant status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	caps, err := s.ListCapabilities(ctx, "p-1")
+	if err != nil || len(caps) != 1 || caps[0] != "base" {
+		t.Errorf("capabilities = %v, %v; want [base]", caps, err)
+	}
+
+	action := store.AuditGrantCapability
+	entries, err := s.ListAuditLog(ctx, store.AuditFilter{Action: &action})
+	if err != nil {
+		t.Fatalf("ListAuditLog: %v", err)
+	}
+	if len(entries) != 1 || entries[0].TargetID != "p-1" {
+		t.Errorf("audit entries = %+v, want one for p-1", entries)
+	}
+
+	for body, want := range map[string]int{
+		`{"principalId":"nobody","capability":"base"}`: http.StatusNotFound,
+		`{"principalId":"p-1"}`:                        http.StatusBadRequest,
+		`{"principalId":"p-1","capability":"*"}`:       http.StatusBadRequest,
+		`{"principalId":"p-1","capability":"web.*.x"}`: http.StatusBadRequest,
+		`not json`: http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		admin.handleGrantCapability(rec, csrfJSONRequest(http.MethodPost, "/api/admin/capabilities/grant", body))
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", body, rec.Code, want)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleGrantCapability(rec, requestWithUser(httptest.NewRequest(http.MethodPost, "/api/admin/capabilities/grant", nil)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("without CSRF status = %d, want 403", rec.Code)
+	}
+}
+
+// recordingNotifier records the capability sets passed to CapabilitiesChanged.
+type recordingNotifier struct {
+	changes map[string][][]string
+}
+
+func (n *recordingNotifier) CapabilitiesChanged(principalID string, capabilities []string) {
+	n.changes[principalID] = append(n.changes[principalID], capabilities)
+}
+
+func TestHandleSetPrincipalCapabilities(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	notifier := &recordingNotifier{changes: map[string][][]string{}}
+	admin.SetCapabilityNotifier(notifier)
+	ctx := context.Background()
+	for _, id := range []string{"p-agent", "p-admin"} {
+		if err := s.CreatePrincipal(ctx, &store.Principal{
+			ID: id, Type: store.PrincipalTypeAgent, PubkeyFP: "fp-" + id, DisplayName: id,
+			Status: store.PrincipalStatusApproved, CreatedAt: time.Now(),
+		}); err != nil {
+			t.Fatalf("CreatePrincipal: %v", err)
+		}
+	}
+	if err := s.AddRole(ctx, store.RoleSubjectPrincipal, "p-admin", store.RoleAdmin); err != nil {
+		t.Fatalf("AddRole: %v", err)
+	}
+	if err := s.GrantCapability(ctx, "p-agent", "base", "seed"); err != nil {
+		t.Fatalf("GrantCapability: %v", err)
+	}
+
+	set := func(id, body string) *httptest.ResponseRecorder {
+		req := csrfJSONRequest(http.MethodPut, "/api/admin/principals/"+id+"/capabilities", body)
+		req.SetPathValue("id", id)
+		rec := httptest.NewRecorder()
+		admin.handleSetPrincipalCapabilities(rec, req)
+		return rec
+	}
+
+	rec := set("p-agent", `{"capabilities":["web.*"," notes ","web.*"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("set status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var got principalCapabilities
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got.Capabilities) != 2 || got.Capabilities[0] != "notes" || got.Capabilities[1] != "web.*" {
+		t.Errorf("response capabilities = %v, want [notes web.*]", got.Capabilities)
+	}
+	caps, err := s.ListCapabilities(ctx, "p-agent")
+	if err != nil || len(caps) != 2 || caps[0] != "notes" || caps[1] != "web.*" {
+		t.Errorf("stored capabilities = %v, %v; want [notes web.*]", caps, err)
+	}
+
+	if pushed := notifier.changes["p-agent"]; len(pushed) != 1 || len(pushed[0]) != 2 || pushed[0][0] != "notes" {
+		t.Errorf("notified %v, want one change to [notes web.*]", pushed)
+	}
+	if rec := set("p-agent", `{"capabilities":["notes","web.*"]}`); rec.Code != http.StatusOK {
+		t.Fatalf("unchanged set status = %d", rec.Code)
+	}
+	if pushed := notifier.changes["p-agent"]; len(pushed) != 1 {
+		t.Errorf("unchanged grants notified again: %v", pushed)
+	}
+
+	revoke := store.AuditRevokeCapability
+	entries, err := s.ListAuditLog(ctx, store.AuditFilter{Action: &revoke})
+	if err != nil || len(entries) != 1 || entries[0].Detail["capability"] != "base" {
+		t.Errorf("revoke audit entries = %+v, %v; want one for base", entries, err)
+	}
+
+	for body, want := range map[string]int{
+		`{"capabilities":["*"]}`:           http.StatusBadRequest,
+		`{"capabilities":["web.*.fetch"]}`: http.StatusBadRequest,
+		`{"capabilities":["web."]}`:        http.StatusBadRequest,
+		`not json`:                         http.StatusBadRequest,
+	} {
+		if rec := set("p-agent", body); rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", body, rec.Code, want)
+		}
+	}
+	if rec := set("nobody", `{"capabilities":["base"]}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown principal status = %d, want 404", rec.Code)
+	}
+
+	if rec := set("p-admin", `{"capabilities":["*"]}`); rec.Code != http.StatusOK {
+		t.Fatalf("admin wildcard status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	req := requestWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/principals/p-admin/capabilities", nil))
+	req.SetPathValue("id", "p-admin")
+	rec = httptest.NewRecorder()
+	admin.handlePrincipalCapabilitiesJSON(rec, req)
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.Code != http.StatusOK || len(got.Capabilities) != 1 || got.Capabilities[0] != "*" {
+		t.Errorf("GET = %d %+v, want [*]", rec.Code, got)
+	}
+}
@@ -10,13 +10,16 @@ import (
 	"time"
 
 	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/builtins"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
 )
 
 // chatMessage represents a message in the chat stream.
 type chatMessage struct {
-	Type      string    `json:"type"` // "user", "text", "thinking", "tool_use", "tool_result", "usage", "tool_state", "tool_approval", "user_question", "canceled", "error", "done"
+	Type      string    `json:"type"` // "user", "text", "thinking", "tool_use", "tool_result", "usage", "tool_state", "tool_approval", "user_question", "plan", "plan_step_update", "citation", "agent_pending", "canceled", "truncated", "system", "error", "done"
 	Content   string    `json:"content,omitempty"`
+	Continued bool      `json:"continued,omitempty"` // appends Content to the previous text or thinking event
 	ToolName  string    `json:"tool_name,omitempty"`
 	ToolID    string    `json:"tool_id,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
@@ -28,17 +31,30 @@ type chatMessage struct {
 	CacheWriteTokens int32 `json:"cache_write_tokens,omitempty"`
 	ThinkingTokens   int32 `json:"thinking_tokens,omitempty"`
 
-	// ToolState fields (for type="tool_state")
+	// ToolState fields (for type="tool_state"; also the step status for type="plan_step_update")
 	State  string `json:"state,omitempty"`
 	Detail string `json:"detail,omitempty"`
 
-	// Canceled fields (for type="canceled")
+	// Plan fields (for type="plan" and type="plan_step_update"). Content holds
+	// the plain-text rendering; plans loaded from history have no Steps.
+	Steps     []chatPlanStep `json:"steps,omitempty"`
+	StepIndex *int           `json:"step_index,omitempty"`
+
+	// Citation is the cited source (for type="citation"); Content holds its
+	// plain-text rendering.
+	Citation *agent.CitationEvent `json:"citation,omitempty"`
+
+	// Canceled fields (for type="canceled" and type="truncated")
 	Reason string `json:"reason,omitempty"`
 
 	// ToolApproval fields (for type="tool_approval")
 	InputJSON string `json:"input_json,omitempty"`
 	RequestID string `json:"request_id,omitempty"`
 
+	// PendingAgent is the agent now awaiting approval (for type="agent_pending");
+	// Content holds a one-line summary.
+	PendingAgent *pendingAgentItem `json:"pending_agent,omitempty"`
+
 	// UserQuestion fields (for type="user_question")
 	QuestionID     string           `json:"question_id,omitempty"`
 	Question       string           `json:"question,omitempty"`
@@ -46,6 +62,27 @@ type chatMessage struct {
 	MultiSelect    bool             `json:"multi_select,omitempty"`
 	Header         string           `json:"header,omitempty"`
 	TimeoutSeconds int32            `json:"timeout_seconds,omitempty"`
+	// AllowFreeText, Pattern and MaxLength constrain a typed response.
+	AllowFreeText *bool  `json:"allow_free_text,omitempty"`
+	Pattern       string `json:"pattern,omitempty"`
+	MaxLength     int32  `json:"max_length,omitempty"`
+	// QuestionContext is what the agent was doing when it asked.
+	QuestionContext *builtins.QuestionContext `json:"context,omitempty"`
+}
+
+// chatPlanStep is one step of a plan message.
+type chatPlanStep struct {
+	Title  string `json:"title"`
+	Status string `json:"status"`
+}
+
+// MarshalJSON renders Timestamp as RFC3339 UTC like every other API timestamp.
+func (m chatMessage) MarshalJSON() ([]byte, error) {
+	type message chatMessage
+	return json.Marshal(struct {
+		message
+		Timestamp string `json:"timestamp"`
+	}{message(m), timeparse.Format(m.Timestamp)})
 }
 
 // questionOption represents a choice in a user question.
@@ -303,6 +340,39 @@ var chatConverters = map[agent.ResponseEvent]chatMsgConverter{
 		m.Type = "canceled"
 		m.Reason = r.Text
 	},
+	agent.EventTruncated: func(r *agent.Response, m *chatMessage) {
+		m.Type = "truncated"
+		m.Reason = r.Error
+		if r.Truncated != nil {
+			m.Content = r.Truncated.Summary()
+		}
+	},
+	agent.EventPlan: func(r *agent.Response, m *chatMessage) {
+		m.Type = "plan"
+		if r.Plan != nil {
+			m.Content = r.Plan.Text()
+			m.Steps = make([]chatPlanStep, len(r.Plan.Steps))
+			for i, step := range r.Plan.Steps {
+				m.Steps[i] = chatPlanStep{Title: step.Title, Status: step.Status}
+			}
+		}
+	},
+	agent.EventPlanStepUpdate: func(r *agent.Response, m *chatMessage) {
+		m.Type = "plan_step_update"
+		if r.PlanStep != nil {
+			m.Content = r.PlanStep.Text()
+			m.StepIndex = &r.PlanStep.Index
+			m.State = r.PlanStep.Status
+			m.Detail = r.PlanStep.Detail
+		}
+	},
+	agent.EventCitation: func(r *agent.Response, m *chatMessage) {
+		m.Type = "citation"
+		if r.Citation != nil {
+			m.Content = r.Citation.Text()
+			m.Citation = r.Citation
+		}
+	},
 	agent.EventToolApprovalRequest: func(r *agent.Response, m *chatMessage) {
 		m.Type = "tool_approval"
 		if r.ToolApprovalRequest != nil {
@@ -342,6 +412,20 @@ func (h *chatHub) sendToAgent(agentID string, msg *chatMessage) int {
 	return sent
 }
 
+// broadcast sends a message to every session, whichever agent it is for.
+func (h *chatHub) broadcast(msg *chatMessage) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sent := 0
+	for _, session := range h.sessions {
+		if session.send(msg) {
+			sent++
+		}
+	}
+	return sent
+}
+
 // parseToolCallData parses tool call JSON data from event text.
 func parseToolCallData(text *string, msg *chatMessage) {
 	if text == nil {
@@ -409,6 +493,19 @@ func ledgerEventToChatMessage(event *store.LedgerEvent) *chatMessage {
 	case store.EventTypeError:
 		msg.Type = "error"
 		msg.Content = textFromEvent(event.Text)
+	case store.EventTypeSystem:
+		msg.Type = "system"
+		msg.Content = textFromEvent(event.Text)
+	case store.EventTypePlan:
+		msg.Type = "plan"
+		msg.Content = textFromEvent(event.Text)
+	case store.EventTypeCitation:
+		msg.Type = "citation"
+		var c agent.CitationEvent
+		if err := json.Unmarshal([]byte(textFromEvent(event.Text)), &c); err == nil {
+			msg.Citation = &c
+			msg.Content = c.Text()
+		}
 	default:
 		msg.Type = "text"
 		msg.Content = textFromEvent(event.Text)
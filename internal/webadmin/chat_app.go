@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"sort"
 
+	"github.com/2389/coven-gateway/internal/agent"
 	"github.com/2389/coven-gateway/internal/store"
 )
 
@@ -52,21 +53,36 @@ func (a *Admin) handleChatApp(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleAgentsJSON returns the connected agents as JSON for the Svelte sidebar.
+// Repeated ?tag=key=value parameters narrow it to agents carrying those tags.
 func (a *Admin) handleAgentsJSON(w http.ResponseWriter, r *http.Request) {
 	type agentJSON struct {
-		ID        string `json:"id"`
-		Name      string `json:"name"`
-		Connected bool   `json:"connected"`
+		ID        string            `json:"id"`
+		Name      string            `json:"name"`
+		Connected bool              `json:"connected"`
+		Tags      map[string]string `json:"tags,omitempty"`
+	}
+
+	filter, err := agent.ParseTagFilter(r.URL.Query()["tag"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	var agents []agentJSON
 	if a.manager != nil {
 		for _, info := range a.manager.ListAgents() {
-			agents = append(agents, agentJSON{
+			if !info.Metadata.MatchesTags(filter) {
+				continue
+			}
+			item := agentJSON{
 				ID:        info.ID,
 				Name:      info.Name,
 				Connected: true,
-			})
+			}
+			if info.Metadata != nil {
+				item.Tags = info.Metadata.Tags
+			}
+			agents = append(agents, item)
 		}
 	}
 	if agents == nil {
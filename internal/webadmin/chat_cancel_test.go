@@ -0,0 +1,72 @@
+// ABOUTME: Tests for stopping a chat response from the web UI.
+// ABOUTME: Checks CSRF, that requests are matched to their chat, and the canceled event.
+
+package webadmin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/conversation"
+)
+
+// waitingSender answers only once its send context is canceled, with a
+// canceled event giving the cause.
+type waitingSender struct{}
+
+func (waitingSender) SendMessage(ctx context.Context, _ *agent.SendRequest) (<-chan *agent.Response, error) {
+	ch := make(chan *agent.Response, 1)
+	go func() {
+		defer close(ch)
+		<-ctx.Done()
+		ch <- &agent.Response{Event: agent.EventCanceled, Error: context.Cause(ctx).Error(), Done: true}
+	}()
+	return ch, nil
+}
+
+func TestHandleChatCancel(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	admin.conversation = conversation.New(s, waitingSender{}, admin.logger, nil)
+
+	resp, err := admin.conversation.SendMessage(context.Background(), &conversation.SendRequest{
+		ThreadID: "agent-1", FrontendName: "webadmin", ExternalID: "agent-1",
+		AgentID: "agent-1", Sender: "testadmin", Content: "keep going",
+	})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	cancelRequest := func(agentID, token string) *httptest.ResponseRecorder {
+		form := url.Values{"request_id": {resp.MessageID}, "csrf_token": {token}}
+		req := httptest.NewRequest(http.MethodPost, "/chat/"+agentID+"/cancel", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: "tok"})
+		req.SetPathValue("id", agentID)
+		rec := httptest.NewRecorder()
+		admin.handleChatCancel(rec, requestWithUser(req))
+		return rec
+	}
+
+	if rec := cancelRequest("agent-1", "wrong"); rec.Code != http.StatusForbidden {
+		t.Errorf("bad CSRF token: status %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := cancelRequest("agent-2", "tok"); rec.Code != http.StatusNotFound {
+		t.Errorf("another agent's chat: status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := cancelRequest("agent-1", "tok"); rec.Code != http.StatusAccepted {
+		t.Fatalf("cancel: status %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+
+	var last *agent.Response
+	for r := range resp.Stream {
+		last = r
+	}
+	if last == nil || last.Event != agent.EventCanceled || !strings.Contains(last.Error, agent.UserRequestedReason) {
+		t.Errorf("last response = %+v, want canceled with reason %s", last, agent.UserRequestedReason)
+	}
+}
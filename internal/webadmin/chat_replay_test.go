@@ -0,0 +1,84 @@
+// ABOUTME: Tests for chat stream resumption with Last-Event-ID.
+// ABOUTME: Checks that missed ledger events replay once, with SSE ids, and long text is split.
+
+package webadmin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestReplayChatEvents_ResumesAfterLastEventID(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	seedAdminThread(t, s, "thread-1", "e-1", "e-2", "e-3", "e-4")
+
+	rec := httptest.NewRecorder()
+	sc := &chatStreamContext{w: rec, flusher: rec, seenEvents: make(map[string]struct{}), logger: admin.logger}
+	req := httptest.NewRequest(http.MethodGet, "/chat/agent-1/stream", nil)
+	admin.replayChatEvents(req, sc, "agent-1", "e-2")
+
+	body := rec.Body.String()
+	for _, id := range []string{"e-1", "e-2"} {
+		if strings.Contains(body, "id: "+id+"\n") {
+			t.Errorf("replayed %s, which the client already had:\n%s", id, body)
+		}
+	}
+	if i, j := strings.Index(body, "id: e-3\n"), strings.Index(body, "id: e-4\n"); i < 0 || j < i {
+		t.Fatalf("body = %q, want e-3 then e-4", body)
+	}
+
+	// A live broadcast of an event already replayed is not sent twice.
+	e4, err := s.GetEvent(req.Context(), "e-4")
+	if err != nil {
+		t.Fatalf("GetEvent: %v", err)
+	}
+	before := rec.Body.Len()
+	sc.sendLedgerEvent(e4)
+	if rec.Body.Len() != before {
+		t.Error("replayed event was sent again when broadcast")
+	}
+
+	// An id from another agent's conversation replays nothing.
+	other := httptest.NewRecorder()
+	sc = &chatStreamContext{w: other, flusher: other, seenEvents: make(map[string]struct{}), logger: admin.logger}
+	admin.replayChatEvents(req, sc, "agent-2", "e-2")
+	if other.Body.Len() != 0 {
+		t.Errorf("replay for a foreign event id wrote %q", other.Body.String())
+	}
+}
+
+func TestWriteChatMessage_SplitsLongText(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sc := &chatStreamContext{w: rec, flusher: rec, chunkBytes: 8, logger: slog.Default()}
+	text := "漢字😀かな\n交じり"
+	sc.writeChatMessage("e-9", &chatMessage{Type: "text", Content: text, Timestamp: time.Now()})
+
+	events := strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n")
+	if len(events) < 3 {
+		t.Fatalf("got %d events, want the text split:\n%s", len(events), rec.Body.String())
+	}
+	var joined strings.Builder
+	for i, ev := range events {
+		if hasID := strings.HasPrefix(ev, "id: e-9\n"); hasID != (i == len(events)-1) {
+			t.Errorf("event %d has id = %v, want the id on the last event only", i, hasID)
+		}
+		_, data, _ := strings.Cut(ev, "data: ")
+		var msg chatMessage
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			t.Fatalf("event %d data %q: %v", i, data, err)
+		}
+		if !utf8.ValidString(msg.Content) || msg.Continued != (i > 0) {
+			t.Errorf("event %d = %+v, want valid UTF-8 continued only after the first", i, msg)
+		}
+		joined.WriteString(msg.Content)
+	}
+	if joined.String() != text {
+		t.Errorf("joined = %q, want %q", joined.String(), text)
+	}
+}
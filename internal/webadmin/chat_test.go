@@ -0,0 +1,66 @@
+// ABOUTME: Tests for converting agent responses and ledger events to chat messages.
+// ABOUTME: Focuses on truncation markers and plan cards, which the chat renders specially.
+
+package webadmin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestConvertAgentResponse_Truncated(t *testing.T) {
+	ev := &agent.TruncatedEvent{Reason: agent.TruncatedReasonMaxDuration, Elapsed: 31 * time.Minute, Limit: 30 * time.Minute}
+	msg := convertAgentResponse(&agent.Response{Event: agent.EventTruncated, Error: ev.Reason, Done: true, Truncated: ev})
+	if msg.Type != "truncated" || msg.Reason != agent.TruncatedReasonMaxDuration {
+		t.Fatalf("message = %+v, want a truncated marker", msg)
+	}
+	if msg.Content != ev.Summary() {
+		t.Errorf("content = %q, want %q", msg.Content, ev.Summary())
+	}
+}
+
+func TestLedgerEventToChatMessage_System(t *testing.T) {
+	text := "Response truncated after 31m0s: exceeded max response duration of 30m0s"
+	msg := ledgerEventToChatMessage(&store.LedgerEvent{
+		Type: store.EventTypeSystem, Direction: store.EventDirectionOutbound, Author: "system", Text: &text,
+	})
+	if msg.Type != "system" || msg.Content != text {
+		t.Errorf("message = %+v, want a system note", msg)
+	}
+}
+
+func TestConvertAgentResponse_Plan(t *testing.T) {
+	msg := convertAgentResponse(&agent.Response{Event: agent.EventPlan, Plan: &agent.PlanEvent{
+		Steps: []agent.PlanStep{{Title: "check logs", Status: "pending"}},
+	}})
+	if msg.Type != "plan" || len(msg.Steps) != 1 || msg.Steps[0].Title != "check logs" {
+		t.Fatalf("message = %+v, want a one-step plan", msg)
+	}
+	if msg.Content != "Plan:\n1. check logs" {
+		t.Errorf("content = %q, want the numbered-list fallback", msg.Content)
+	}
+
+	msg = convertAgentResponse(&agent.Response{Event: agent.EventPlanStepUpdate, PlanStep: &agent.PlanStepEvent{Index: 0, Status: "completed"}})
+	if msg.Type != "plan_step_update" || msg.StepIndex == nil || *msg.StepIndex != 0 || msg.State != "completed" {
+		t.Errorf("message = %+v, want step 0 completed", msg)
+	}
+}
+
+func TestCitationChatMessages(t *testing.T) {
+	c := &agent.CitationEvent{Index: 1, Title: "Go spec", URI: "https://go.dev/ref/spec"}
+	msg := convertAgentResponse(&agent.Response{Event: agent.EventCitation, Citation: c})
+	if msg.Type != "citation" || msg.Citation != c || msg.Content != "[1] Go spec <https://go.dev/ref/spec>" {
+		t.Errorf("live message = %+v, want a citation", msg)
+	}
+
+	text := c.JSON()
+	msg = ledgerEventToChatMessage(&store.LedgerEvent{
+		Type: store.EventTypeCitation, Direction: store.EventDirectionOutbound, Text: &text,
+	})
+	if msg.Type != "citation" || msg.Citation == nil || *msg.Citation != *c {
+		t.Errorf("history message = %+v, want the stored citation", msg)
+	}
+}
@@ -0,0 +1,89 @@
+// ABOUTME: Admin API for the delegation allowlist: which agents each agent may ask through delegate_task
+// ABOUTME: An agent with the "delegate" capability can only delegate to targets listed here
+
+package webadmin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// delegationItem is one allowed delegation.
+type delegationItem struct {
+	AgentID       string `json:"agentId"`
+	TargetAgentID string `json:"targetAgentId"`
+	AddedAt       string `json:"addedAt"`
+}
+
+// handleDelegationsJSON handles GET /api/admin/delegations.
+func (a *Admin) handleDelegationsJSON(w http.ResponseWriter, r *http.Request) {
+	targets, err := a.store.ListDelegationTargets(r.Context())
+	if err != nil {
+		a.logger.Error("failed to list delegation targets", "error", err)
+		http.Error(w, `{"error":"failed to load delegations"}`, http.StatusInternalServerError)
+		return
+	}
+	items := make([]delegationItem, 0, len(targets))
+	for _, t := range targets {
+		items = append(items, delegationItem{AgentID: t.AgentID, TargetAgentID: t.TargetAgentID, AddedAt: timeparse.Format(t.AddedAt)})
+	}
+	a.writeJSON(w, map[string]any{"delegations": items})
+}
+
+// handleAddDelegationTarget handles PUT /api/admin/agents/{id}/delegates/{target}.
+func (a *Admin) handleAddDelegationTarget(w http.ResponseWriter, r *http.Request) {
+	agentID, targetID, ok := a.delegationParams(w, r)
+	if !ok {
+		return
+	}
+	if err := a.store.AddDelegationTarget(r.Context(), agentID, targetID); err != nil {
+		a.logger.Error("failed to add delegation target", "agent_id", agentID, "target_agent_id", targetID, "error", err)
+		http.Error(w, "Failed to add delegation target", http.StatusInternalServerError)
+		return
+	}
+	a.auditAdminAction(r, newAuditEntry(r, store.AuditAddDelegationTarget, "agent", agentID, map[string]any{"target_agent_id": targetID}))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRemoveDelegationTarget handles DELETE /api/admin/agents/{id}/delegates/{target}.
+// Delegations already running are not stopped.
+func (a *Admin) handleRemoveDelegationTarget(w http.ResponseWriter, r *http.Request) {
+	agentID, targetID, ok := a.delegationParams(w, r)
+	if !ok {
+		return
+	}
+	err := a.store.RemoveDelegationTarget(r.Context(), agentID, targetID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Not a delegation target of the agent", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		a.logger.Error("failed to remove delegation target", "agent_id", agentID, "target_agent_id", targetID, "error", err)
+		http.Error(w, "Failed to remove delegation target", http.StatusInternalServerError)
+		return
+	}
+	a.auditAdminAction(r, newAuditEntry(r, store.AuditRemoveDelegationTarget, "agent", agentID, map[string]any{"target_agent_id": targetID}))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// delegationParams checks the CSRF token and reads the agent and target from
+// the path, writing an error response if they are unusable.
+func (a *Admin) delegationParams(w http.ResponseWriter, r *http.Request) (agentID, targetID string, ok bool) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		return "", "", false
+	}
+	agentID, targetID = r.PathValue("id"), r.PathValue("target")
+	if agentID == "" || targetID == "" {
+		http.Error(w, "Agent and target IDs are required", http.StatusBadRequest)
+		return "", "", false
+	}
+	if agentID == targetID {
+		http.Error(w, "An agent cannot delegate to itself", http.StatusBadRequest)
+		return "", "", false
+	}
+	return agentID, targetID, true
+}
@@ -0,0 +1,79 @@
+// ABOUTME: Tests for the delegation allowlist admin API: adding, listing and
+// ABOUTME: removing targets, refusing self-delegation, and auditing changes.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestHandleDelegationTargets(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	ctx := context.Background()
+
+	call := func(method, agentID, target string) int {
+		req := csrfJSONRequest(method, "/api/admin/agents/"+agentID+"/delegates/"+target, "")
+		req.SetPathValue("id", agentID)
+		req.SetPathValue("target", target)
+		rec := httptest.NewRecorder()
+		if method == http.MethodPut {
+			admin.handleAddDelegationTarget(rec, req)
+		} else {
+			admin.handleRemoveDelegationTarget(rec, req)
+		}
+		return rec.Code
+	}
+
+	for _, d := range [][2]string{{"agent-1", "agent-2"}, {"agent-1", "agent-3"}} {
+		if code := call(http.MethodPut, d[0], d[1]); code != http.StatusNoContent {
+			t.Fatalf("adding %v: status = %d, want 204", d, code)
+		}
+	}
+	if code := call(http.MethodPut, "agent-1", "agent-1"); code != http.StatusBadRequest {
+		t.Errorf("self-delegation: status = %d, want 400", code)
+	}
+
+	rec := httptest.NewRecorder()
+	admin.handleDelegationsJSON(rec, httptest.NewRequest(http.MethodGet, "/api/admin/delegations", nil))
+	var resp struct {
+		Delegations []delegationItem `json:"delegations"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Delegations) != 2 || resp.Delegations[0].TargetAgentID != "agent-2" || resp.Delegations[1].TargetAgentID != "agent-3" {
+		t.Errorf("delegations = %+v, want agent-1 to agent-2 and agent-3", resp.Delegations)
+	}
+
+	if code := call(http.MethodDelete, "agent-1", "agent-2"); code != http.StatusNoContent {
+		t.Errorf("removing target: status = %d, want 204", code)
+	}
+	if code := call(http.MethodDelete, "agent-1", "agent-2"); code != http.StatusNotFound {
+		t.Errorf("removing missing target: status = %d, want 404", code)
+	}
+	if ok, _ := s.CanDelegate(ctx, "agent-1", "agent-2"); ok {
+		t.Error("agent-1 can still delegate to agent-2")
+	}
+
+	for action, want := range map[store.AuditAction]int{store.AuditAddDelegationTarget: 2, store.AuditRemoveDelegationTarget: 1} {
+		entries, err := s.ListAuditLog(ctx, store.AuditFilter{Action: &action})
+		if err != nil || len(entries) != want {
+			t.Errorf("%s audit entries = %d, err = %v, want %d", action, len(entries), err, want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/agents/agent-1/delegates/agent-2", nil)
+	req.SetPathValue("id", "agent-1")
+	req.SetPathValue("target", "agent-2")
+	rec = httptest.NewRecorder()
+	admin.handleAddDelegationTarget(rec, requestWithUser(req))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("without CSRF status = %d, want 403", rec.Code)
+	}
+}
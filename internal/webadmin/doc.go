@@ -25,6 +25,7 @@
 //   - Password: Initial bootstrap only
 //   - WebAuthn/Passkeys: Primary auth (requires HTTPS)
 //   - JWT Sessions: Browser session tokens
+//   - OIDC SSO: Optional, when webadmin.oidc.issuer is configured
 //
 // Bootstrap flow:
 //
@@ -33,6 +34,12 @@
 //  3. Register a passkey
 //  4. Login with passkey thereafter
 //
+// With OIDC configured, the login page also offers "Sign in with SSO". The
+// auth-code flow uses PKCE and a nonce; ID tokens are checked against the
+// provider's JWKS. A user's first SSO login provisions a passwordless admin
+// user whose role comes from webadmin.oidc.role_claim (member by default).
+// Email domain and group requirements are enforced on every login.
+//
 // # Chat Interface
 //
 // The chat UI provides real-time messaging:
@@ -54,8 +61,62 @@
 //
 //   - Agents: List, approve, revoke agents
 //   - Tools: View available tools from all packs
-//   - Bindings: Manage channel-to-agent bindings
+//   - Bindings: Manage channel-to-agent bindings and edit each channel's context
 //   - Credentials: Manage WebAuthn credentials
+//   - API Tokens: Mint, list, and revoke tokens (values are shown once at creation)
+//   - Sessions: See who is signed in, revoke a session, or sign out everywhere else
+//   - Tool Packs: See each external pack's tools and whether it is keeping up its heartbeats
+//
+// Sessions record the user agent and IP they signed in from; requireAuth
+// updates last_seen_at at most once a minute. Sessions are named by a hash of
+// their ID in the API, since the ID itself is the session cookie.
+//
+// # Usage Drill-down
+//
+// The usage page ranks agents by tokens for a date window; an agent opens its
+// threads, and a thread links to its detail page with per-request usage shown
+// next to each reply. Every level shares the window picker and exports CSV
+// (format=csv on the /api/admin/usage/... endpoints).
+//
+// # Todos
+//
+// The todos page groups todos by project and highlights those past their due
+// date. A project exists while it has members; add and remove them with
+// PUT and DELETE /api/admin/projects/{project}/members/{agent}. Todos without
+// a project stay private to the agent that created them.
+//
+// # Delegation
+//
+// An agent with the "delegate" capability can hand a task to another agent
+// with delegate_task, but only to targets an admin allowed. GET
+// /api/admin/delegations lists the allowlist; PUT and DELETE
+// /api/admin/agents/{id}/delegates/{target} change it.
+//
+// # Tool overrides
+//
+// The agent detail page lists every registered tool with a toggle, backed by
+// GET and PUT /api/admin/agents/{id}/tools. Overrides are stored on the
+// agent's principal, so an agent connected without auth has none. Turning a
+// tool off takes it out of the agent's tool list at once and fails its calls
+// with tool_disabled and the reason given.
+//
+// # Agents awaiting approval
+//
+// With auth.agent_auto_registration set to pending, an agent with an unknown
+// key registers a pending principal and waits on its open stream. Every open
+// chat gets an agent_pending event for it, and webadmin.pending_agent_webhook_url,
+// if set, is POSTed the same details. GET /api/admin/agents?status=pending lists
+// the waiting agents with their key fingerprint, hostname and repository.
+// Approving the principal sends Welcome on the stream the agent holds open.
+//
+// # Board
+//
+// The board page shows the agents' BBS threads. Admins can start threads and
+// reply through POST /api/admin/board and /api/admin/board/{id}/replies;
+// those posts are stored with author_kind "human" under a "human:<username>"
+// author ID, so bbs_read_thread shows agents who wrote them. A reply can also
+// mail the agent that started the thread. Threads pinned with bbs_pin_thread
+// lead the list, which shows 50 threads per ?page=N.
 //
 // # Help Documentation
 //
@@ -0,0 +1,209 @@
+// ABOUTME: Admin settings page and API for runtime feature flags
+// ABOUTME: Lists known flags with their effective state and audits every change
+
+package webadmin
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/2389/coven-gateway/internal/flags"
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// flagItem is one flag as shown in the settings panel.
+type flagItem struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Default     bool     `json:"default"`
+	Enabled     bool     `json:"enabled"`
+	Overridden  bool     `json:"overridden"`
+	Percentage  *int     `json:"percentage"`
+	Principals  []string `json:"principals"`
+	UpdatedAt   string   `json:"updatedAt,omitempty"`
+	UpdatedBy   string   `json:"updatedBy,omitempty"`
+}
+
+// updateFlagRequest is the body of PUT /api/admin/flags.
+type updateFlagRequest struct {
+	Name       string   `json:"name"`
+	Enabled    bool     `json:"enabled"`
+	Percentage *int     `json:"percentage"`
+	Principals []string `json:"principals"`
+}
+
+type settingsPageData struct {
+	Title     string
+	User      *store.AdminUser
+	CSRFToken string
+	PropsJSON template.JS
+}
+
+// listFlagItems returns every known flag with its current override applied.
+func (a *Admin) listFlagItems(r *http.Request) []flagItem {
+	if a.flags == nil {
+		return []flagItem{}
+	}
+	states := a.flags.List(r.Context())
+	items := make([]flagItem, 0, len(states))
+	for _, st := range states {
+		items = append(items, newFlagItem(st))
+	}
+	return items
+}
+
+func newFlagItem(st flags.State) flagItem {
+	item := flagItem{
+		Name:        st.Name,
+		Description: st.Description,
+		Default:     st.Default,
+		Enabled:     st.Default,
+		Principals:  []string{},
+	}
+	if o := st.Override; o != nil {
+		item.Overridden = true
+		item.Enabled = o.Enabled
+		item.Percentage = o.Percentage
+		if o.Principals != nil {
+			item.Principals = o.Principals
+		}
+		item.UpdatedAt = timeparse.Format(o.UpdatedAt)
+		item.UpdatedBy = o.UpdatedBy
+	}
+	return item
+}
+
+// handleSettingsPage renders the settings page with the feature flag,
+// binding context, API token and session panels.
+func (a *Admin) handleSettingsPage(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	csrfToken := a.ensureCSRFToken(w, r)
+
+	tokens, err := a.buildTokensPanel(r.Context(), user)
+	if err != nil {
+		a.logger.Error("failed to build tokens panel", "error", err)
+		tokens = &tokensPanel{Tokens: []tokenItem{}, Principals: []tokenPrincipal{}}
+	}
+	sessions, err := a.buildSessionsPanel(r.Context(), user, getSessionFromContext(r))
+	if err != nil {
+		a.logger.Error("failed to build sessions panel", "error", err)
+		sessions = &sessionsPanel{Sessions: []sessionItem{}}
+	}
+	bindings, err := a.buildBindingsPanel(r.Context())
+	if err != nil {
+		a.logger.Error("failed to build bindings panel", "error", err)
+		bindings = &bindingsPanel{Bindings: []bindingItem{}, MaxContextBytes: store.MaxBindingContextBytes}
+	}
+
+	propsJSON, err := json.Marshal(map[string]any{
+		"flags":     a.listFlagItems(r),
+		"bindings":  bindings,
+		"tokens":    tokens,
+		"sessions":  sessions,
+		"packs":     a.buildPacksPanel(),
+		"userName":  user.DisplayName,
+		"csrfToken": csrfToken,
+	})
+	if err != nil {
+		a.logger.Error("failed to marshal settings props", "error", err)
+		propsJSON = []byte(`{"flags":[],"csrfToken":""}`)
+	}
+
+	tmpl := parseTemplate("templates/base.html", "templates/settings.html")
+	data := settingsPageData{
+		Title:     "Settings",
+		User:      user,
+		CSRFToken: csrfToken,
+		PropsJSON: template.JS(propsJSON),
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		a.logger.Error("failed to render settings page", "error", err)
+	}
+}
+
+// handleFlagsJSON returns all known feature flags.
+func (a *Admin) handleFlagsJSON(w http.ResponseWriter, r *http.Request) {
+	a.writeJSON(w, a.listFlagItems(r))
+}
+
+// handleUpdateFlag stores an override for one feature flag.
+func (a *Admin) handleUpdateFlag(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+	if a.flags == nil {
+		http.Error(w, "Feature flags not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req updateFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Percentage != nil && (*req.Percentage < 0 || *req.Percentage > 100) {
+		http.Error(w, "percentage must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+	principals := make([]string, 0, len(req.Principals))
+	for _, p := range req.Principals {
+		if p = strings.TrimSpace(p); p != "" {
+			principals = append(principals, p)
+		}
+	}
+
+	var before *store.FeatureFlag
+	for _, st := range a.flags.List(r.Context()) {
+		if st.Name == req.Name {
+			before = st.Override
+		}
+	}
+
+	user := getUserFromContext(r)
+	flag := &store.FeatureFlag{
+		Name:       req.Name,
+		Enabled:    req.Enabled,
+		Percentage: req.Percentage,
+		Principals: principals,
+		UpdatedBy:  user.Username,
+	}
+	if err := a.flags.Set(r.Context(), flag); err != nil {
+		if errors.Is(err, flags.ErrUnknownFlag) {
+			http.Error(w, "Unknown flag", http.StatusNotFound)
+			return
+		}
+		a.logger.Error("failed to update feature flag", "flag", req.Name, "error", err)
+		http.Error(w, "Failed to update flag", http.StatusInternalServerError)
+		return
+	}
+
+	detail := map[string]any{
+		"admin_user": user.Username,
+		"enabled":    flag.Enabled,
+		"percentage": flag.Percentage,
+		"principals": flag.Principals,
+	}
+	if before != nil {
+		detail["previous"] = map[string]any{
+			"enabled":    before.Enabled,
+			"percentage": before.Percentage,
+			"principals": before.Principals,
+		}
+	}
+	a.auditAdminAction(r, &store.AuditEntry{
+		ActorPrincipalID: user.ID,
+		Action:           store.AuditUpdateFlag,
+		TargetType:       "feature_flag",
+		TargetID:         flag.Name,
+		Detail:           detail,
+	})
+
+	def, _ := flags.Lookup(flag.Name)
+	a.writeJSON(w, newFlagItem(flags.State{Definition: def, Override: flag}))
+}
@@ -0,0 +1,98 @@
+// ABOUTME: Tests for the feature flag admin API.
+// ABOUTME: Covers listing defaults, updating overrides, validation, and auditing.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/flags"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func listFlags(t *testing.T, admin *Admin) map[string]flagItem {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	admin.handleFlagsJSON(rec, requestWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/flags", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var items []flagItem
+	if err := json.NewDecoder(rec.Body).Decode(&items); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	byName := make(map[string]flagItem, len(items))
+	for _, f := range items {
+		byName[f.Name] = f
+	}
+	return byName
+}
+
+func TestHandleUpdateFlag_UpdatesAndAudits(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	admin.flags = flags.New(s, slog.Default())
+
+	before := listFlags(t, admin)
+	if len(before) != len(flags.Known) {
+		t.Fatalf("listed %d flags, want %d", len(before), len(flags.Known))
+	}
+	if f := before[flags.SSEResume]; f.Overridden || f.Enabled != f.Default {
+		t.Errorf("sse_resume before update = %+v, want default", f)
+	}
+
+	rec := httptest.NewRecorder()
+	admin.handleUpdateFlag(rec, csrfJSONRequest(http.MethodPut, "/api/admin/flags",
+		`{"name":"sse_resume","enabled":true,"percentage":25,"principals":[" p1 ",""]}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	f := listFlags(t, admin)[flags.SSEResume]
+	if !f.Overridden || !f.Enabled || f.Percentage == nil || *f.Percentage != 25 || f.UpdatedBy != "testadmin" {
+		t.Errorf("sse_resume after update = %+v", f)
+	}
+	if len(f.Principals) != 1 || f.Principals[0] != "p1" {
+		t.Errorf("principals = %v, want [p1]", f.Principals)
+	}
+	if !admin.flags.EnabledFor(context.Background(), flags.SSEResume, "p1") {
+		t.Error("allowlisted principal not enabled after update")
+	}
+
+	action := store.AuditUpdateFlag
+	entries, err := s.ListAuditLog(context.Background(), store.AuditFilter{Action: &action})
+	if err != nil {
+		t.Fatalf("ListAuditLog: %v", err)
+	}
+	if len(entries) != 1 || entries[0].TargetID != flags.SSEResume {
+		t.Errorf("audit entries = %+v, want one for sse_resume", entries)
+	}
+}
+
+func TestHandleUpdateFlag_Validation(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	admin.flags = flags.New(s, slog.Default())
+
+	req := requestWithUser(httptest.NewRequest(http.MethodPut, "/api/admin/flags", nil))
+	rec := httptest.NewRecorder()
+	admin.handleUpdateFlag(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("without CSRF status = %d, want 403", rec.Code)
+	}
+
+	for body, want := range map[string]int{
+		`{"name":"no_such_flag","enabled":true}`:                http.StatusNotFound,
+		`{"name":"sse_resume","enabled":true,"percentage":101}`: http.StatusBadRequest,
+		`not json`: http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		admin.handleUpdateFlag(rec, csrfJSONRequest(http.MethodPut, "/api/admin/flags", body))
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", body, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// ABOUTME: Device linking helpers: the approval page's code listing and a QR code of a code's approval URL.
+// ABOUTME: GET /admin/link/{id}/qr renders the PNG server-side so an admin can open the approval on a phone.
+
+package webadmin
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+const (
+	// maxLinkFieldLen bounds the device_name and platform a device may send.
+	maxLinkFieldLen = 100
+
+	// linkQRSize is the width and height of link code QR images, in pixels.
+	linkQRSize = 256
+)
+
+// errLinkCodeExpired is the response to approving a code past its expiry.
+const errLinkCodeExpired = "Link code expired; ask the device to request a new one"
+
+// linkCodeItem is a pending link code as the approval page shows it.
+type linkCodeItem struct {
+	ID          string `json:"ID"`
+	Code        string `json:"Code"`
+	Fingerprint string `json:"Fingerprint"`
+	DeviceName  string `json:"DeviceName"`
+	Platform    string `json:"Platform"`
+	Status      string `json:"Status"`
+	CreatedAt   string `json:"CreatedAt"`
+	ExpiresAt   string `json:"ExpiresAt"`
+}
+
+func linkCodeItems(codes []*store.LinkCode) []linkCodeItem {
+	items := make([]linkCodeItem, 0, len(codes))
+	for _, c := range codes {
+		items = append(items, linkCodeItem{
+			ID:          c.ID,
+			Code:        c.Code,
+			Fingerprint: c.Fingerprint,
+			DeviceName:  c.DeviceName,
+			Platform:    c.Platform,
+			Status:      string(c.Status),
+			CreatedAt:   timeparse.Format(c.CreatedAt),
+			ExpiresAt:   timeparse.Format(c.ExpiresAt),
+		})
+	}
+	return items
+}
+
+// handleLinkQR renders a pending code's approval URL as a QR PNG.
+func (a *Admin) handleLinkQR(w http.ResponseWriter, r *http.Request) {
+	linkCode, err := a.store.GetLinkCode(r.Context(), r.PathValue("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Code not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		a.logger.Error("failed to get link code", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	if linkCode.Status != store.LinkCodeStatusPending {
+		http.Error(w, "Code already processed", http.StatusBadRequest)
+		return
+	}
+	if !time.Now().Before(linkCode.ExpiresAt) {
+		http.Error(w, errLinkCodeExpired, http.StatusGone)
+		return
+	}
+
+	png, err := qrcode.Encode(a.linkApprovalURL(r, linkCode.Code), qrcode.Medium, linkQRSize)
+	if err != nil {
+		a.logger.Error("failed to render link QR code", "error", err)
+		http.Error(w, "Failed to render QR code", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(png)
+}
+
+// linkApprovalURL is the approval page with code highlighted, on BaseURL or,
+// if that is unset, the host the request came in on.
+func (a *Admin) linkApprovalURL(r *http.Request, code string) string {
+	base := strings.TrimSuffix(a.config.BaseURL, "/")
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + "/admin/link?code=" + url.QueryEscape(code)
+}
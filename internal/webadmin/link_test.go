@@ -0,0 +1,157 @@
+// ABOUTME: Tests for device linking: device name and platform capture, the QR endpoint,
+// ABOUTME: and approvals of codes that expired before or during the approval.
+
+package webadmin
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// newLinkAdmin returns an admin able to approve link codes, with the admin
+// user requestWithUser acts as.
+func newLinkAdmin(t *testing.T) (*Admin, *store.SQLStore) {
+	t.Helper()
+	admin, s, _ := newTokensAdmin(t)
+	if err := s.CreateAdminUser(context.Background(), &store.AdminUser{ID: "test-user", Username: "test-user", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("CreateAdminUser: %v", err)
+	}
+	return admin, s
+}
+
+func seedLinkCode(t *testing.T, s *store.SQLStore, id string, expiresAt time.Time) {
+	t.Helper()
+	if err := s.CreateLinkCode(context.Background(), &store.LinkCode{
+		ID: id, Code: "CODE" + id, Fingerprint: strings.Repeat("a", 63) + id[:1], DeviceName: "Pixel", Platform: "android",
+		Status: store.LinkCodeStatusPending, CreatedAt: time.Now(), ExpiresAt: expiresAt,
+	}); err != nil {
+		t.Fatalf("CreateLinkCode: %v", err)
+	}
+}
+
+func TestHandleLinkRequest_DeviceFields(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	fp := strings.Repeat("f", 64)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		admin.handleLinkRequest(rec, httptest.NewRequest(http.MethodPost, "/api/link/request", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(`{"fingerprint":"` + fp + `","device_name":" Work laptop ","platform":"macos"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	codes, err := s.ListPendingLinkCodes(context.Background())
+	if err != nil || len(codes) != 1 {
+		t.Fatalf("ListPendingLinkCodes = %v, %v", codes, err)
+	}
+	if codes[0].DeviceName != "Work laptop" || codes[0].Platform != "macos" {
+		t.Errorf("stored device = %q on %q, want trimmed name and platform", codes[0].DeviceName, codes[0].Platform)
+	}
+
+	if rec := post(`{"fingerprint":"` + fp + `","device_name":"x","platform":"` + strings.Repeat("p", maxLinkFieldLen+1) + `"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("overlong platform status = %d, want 400", rec.Code)
+	}
+	if rec := post(`{"fingerprint":"` + fp + `","device_name":"   "}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("blank device_name status = %d, want 400", rec.Code)
+	}
+}
+
+func TestHandleLinkApprove_Expiry(t *testing.T) {
+	admin, s := newLinkAdmin(t)
+	seedLinkCode(t, s, "1-live", time.Now().Add(5*time.Minute))
+	seedLinkCode(t, s, "2-expired", time.Now().Add(-time.Minute))
+
+	approve := func(id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := csrfJSONRequest(http.MethodPost, "/admin/link/"+id+"/approve", "")
+		req.SetPathValue("id", id)
+		admin.handleLinkApprove(rec, req)
+		return rec
+	}
+
+	rec := approve("2-expired")
+	if rec.Code != http.StatusGone || !strings.Contains(rec.Body.String(), "expired") {
+		t.Errorf("expired approval = %d %q, want 410 saying the code expired", rec.Code, rec.Body.String())
+	}
+	if lc, _ := s.GetLinkCode(context.Background(), "2-expired"); lc.Status != store.LinkCodeStatusPending {
+		t.Errorf("expired code status = %s, want it left pending", lc.Status)
+	}
+
+	if rec := approve("1-live"); rec.Code != http.StatusOK {
+		t.Fatalf("live approval = %d %s", rec.Code, rec.Body.String())
+	}
+	lc, err := s.GetLinkCode(context.Background(), "1-live")
+	if err != nil || lc.Status != store.LinkCodeStatusApproved || lc.Token == nil {
+		t.Errorf("approved code = %+v, %v; want approved with a token", lc, err)
+	}
+	if rec := approve("1-live"); rec.Code != http.StatusBadRequest {
+		t.Errorf("second approval = %d, want 400", rec.Code)
+	}
+}
+
+// expiringLinkStore lets a code expire between the handler's check and the
+// store update, the window an approval can race the code's expiry in.
+type expiringLinkStore struct {
+	*store.SQLStore
+}
+
+func (e expiringLinkStore) ApproveLinkCode(context.Context, string, string, string, string) error {
+	return store.ErrLinkCodeExpired
+}
+
+func TestHandleLinkApprove_ExpiresDuringApproval(t *testing.T) {
+	admin, s := newLinkAdmin(t)
+	seedLinkCode(t, s, "1-racing", time.Now().Add(time.Minute))
+	admin.store = expiringLinkStore{s}
+
+	rec := httptest.NewRecorder()
+	req := csrfJSONRequest(http.MethodPost, "/admin/link/1-racing/approve", "")
+	req.SetPathValue("id", "1-racing")
+	admin.handleLinkApprove(rec, req)
+	if rec.Code != http.StatusGone {
+		t.Errorf("status = %d %q, want 410", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleLinkQR(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	admin.config.BaseURL = "https://coven.example/"
+	seedLinkCode(t, s, "1-live", time.Now().Add(5*time.Minute))
+	seedLinkCode(t, s, "2-expired", time.Now().Add(-time.Minute))
+
+	qr := func(id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := requestWithUser(httptest.NewRequest(http.MethodGet, "/admin/link/"+id+"/qr", nil))
+		req.SetPathValue("id", id)
+		admin.handleLinkQR(rec, req)
+		return rec
+	}
+
+	rec := qr("1-live")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("QR = %d %s, want a PNG", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !bytes.HasPrefix(rec.Body.Bytes(), []byte("\x89PNG\r\n\x1a\n")) {
+		t.Error("QR body is not a PNG")
+	}
+	if got := admin.linkApprovalURL(httptest.NewRequest(http.MethodGet, "/", nil), "AB 12"); got != "https://coven.example/admin/link?code=AB+12" {
+		t.Errorf("approval URL = %q", got)
+	}
+
+	if rec := qr("2-expired"); rec.Code != http.StatusGone {
+		t.Errorf("expired QR = %d, want 410", rec.Code)
+	}
+	if rec := qr("3-missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing QR = %d, want 404", rec.Code)
+	}
+}
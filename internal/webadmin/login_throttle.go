@@ -0,0 +1,183 @@
+// ABOUTME: Lockout of web admin password logins after repeated failures from one username and IP
+// ABOUTME: Also serves the owner-only API that lists lockouts and clears them
+
+package webadmin
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+const (
+	// maxLoginFailures failed logins within loginFailureWindow lock the
+	// username out from that IP.
+	maxLoginFailures   = 5
+	loginFailureWindow = 10 * time.Minute
+
+	// The first lockout lasts loginLockoutBase; each one after it doubles,
+	// up to loginLockoutMax.
+	loginLockoutBase = time.Minute
+	loginLockoutMax  = time.Hour
+
+	// maxLoginUsernameLength bounds the username a failure is recorded
+	// under, since any string may be typed into the form.
+	maxLoginUsernameLength = 256
+)
+
+// errInvalidLogin is shown for a wrong username or password, and for a
+// locked-out one, so the response never reveals a lockout.
+const errInvalidLogin = "Invalid username or password"
+
+// loginLockoutItem is one locked-out username and IP as the admin API
+// lists it.
+type loginLockoutItem struct {
+	Username    string `json:"username"`
+	IP          string `json:"ip"`
+	Lockouts    int    `json:"lockouts"`
+	LockedUntil string `json:"lockedUntil"`
+}
+
+// loginLockoutDuration is how long the lockout after lockouts earlier ones
+// lasts.
+func loginLockoutDuration(lockouts int) time.Duration {
+	d := loginLockoutBase
+	for range lockouts {
+		d *= 2
+		if d >= loginLockoutMax {
+			return loginLockoutMax
+		}
+	}
+	return d
+}
+
+// loginKey is the username and IP a login attempt is counted under. A long
+// username is cut on a rune boundary so the key stays valid UTF-8.
+func loginKey(r *http.Request, username string) (string, string) {
+	if n := maxLoginUsernameLength; len(username) > n {
+		for n > 0 && !utf8.RuneStart(username[n]) {
+			n--
+		}
+		username = username[:n]
+	}
+	return username, requestIP(r)
+}
+
+// loginLocked reports whether username is locked out from the request's IP.
+// A store error is logged and lets the attempt through.
+func (a *Admin) loginLocked(r *http.Request, username string) bool {
+	username, ip := loginKey(r, username)
+	attempt, err := a.store.GetLoginAttempt(r.Context(), username, ip)
+	if errors.Is(err, store.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		a.logger.Error("failed to get login attempts", "error", err)
+		return false
+	}
+	if !attempt.Locked(time.Now()) {
+		return false
+	}
+	a.logger.Warn("admin login refused while locked out",
+		"security_event", "login_locked_out", "username", username, "ip", ip,
+		"locked_until", timeparse.Format(*attempt.LockedUntil))
+	return true
+}
+
+// recordLoginFailure counts a failed login and locks the username out from
+// the request's IP once it has failed maxLoginFailures times in
+// loginFailureWindow.
+func (a *Admin) recordLoginFailure(r *http.Request, username string) {
+	username, ip := loginKey(r, username)
+	now := time.Now()
+	attempt, err := a.store.RecordLoginFailure(r.Context(), username, ip, now, loginFailureWindow)
+	if err != nil {
+		a.logger.Error("failed to record login failure", "error", err)
+		return
+	}
+	if attempt.Failures < maxLoginFailures {
+		return
+	}
+	until := now.Add(loginLockoutDuration(attempt.Lockouts))
+	if err := a.store.LockLogin(r.Context(), username, ip, until); err != nil {
+		a.logger.Error("failed to lock out login", "error", err)
+		return
+	}
+	a.logger.Warn("admin login locked out after repeated failures",
+		"security_event", "login_lockout", "username", username, "ip", ip,
+		"failures", attempt.Failures, "lockouts", attempt.Lockouts+1, "locked_until", timeparse.Format(until))
+}
+
+// clearLoginFailures forgets the failures of a username that just signed in
+// from the request's IP.
+func (a *Admin) clearLoginFailures(r *http.Request, username string) {
+	username, ip := loginKey(r, username)
+	if _, err := a.store.ClearLoginAttempts(r.Context(), username, ip); err != nil {
+		a.logger.Error("failed to clear login failures", "error", err)
+	}
+}
+
+// handleLoginLockoutsJSON lists the usernames and IPs locked out now.
+func (a *Admin) handleLoginLockoutsJSON(w http.ResponseWriter, r *http.Request) {
+	if !a.tokenAccessFor(r.Context(), getUserFromContext(r)).all {
+		http.Error(w, "Only owners can manage login lockouts", http.StatusForbidden)
+		return
+	}
+	lockouts, err := a.store.ListLoginLockouts(r.Context(), time.Now())
+	if err != nil {
+		a.logger.Error("failed to list login lockouts", "error", err)
+		http.Error(w, "Failed to list login lockouts", http.StatusInternalServerError)
+		return
+	}
+	items := make([]loginLockoutItem, 0, len(lockouts))
+	for _, l := range lockouts {
+		items = append(items, loginLockoutItem{
+			Username:    l.Username,
+			IP:          l.IP,
+			Lockouts:    l.Lockouts,
+			LockedUntil: timeparse.Format(*l.LockedUntil),
+		})
+	}
+	a.writeJSON(w, map[string]any{"lockouts": items})
+}
+
+// handleClearLoginLockout forgets the failures and lockouts of the username
+// query parameter, from the ip parameter or, if that is empty, every IP.
+func (a *Admin) handleClearLoginLockout(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	user := getUserFromContext(r)
+	if !a.tokenAccessFor(r.Context(), user).all {
+		http.Error(w, "Only owners can manage login lockouts", http.StatusForbidden)
+		return
+	}
+	username := r.URL.Query().Get("username")
+	ip := r.URL.Query().Get("ip")
+	if username == "" {
+		http.Error(w, "username is required", http.StatusBadRequest)
+		return
+	}
+
+	n, err := a.store.ClearLoginAttempts(r.Context(), username, ip)
+	if err != nil {
+		a.logger.Error("failed to clear login lockout", "username", username, "error", err)
+		http.Error(w, "Failed to clear login lockout", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "No login failures recorded", http.StatusNotFound)
+		return
+	}
+	a.auditAdminAction(r, newAuditEntry(r, store.AuditClearLoginLockout, "admin_login", username, map[string]any{
+		"ip":      ip,
+		"cleared": n,
+	}))
+	a.logger.Info("admin login lockout cleared", "username", username, "ip", ip, "by", user.Username)
+	a.writeJSON(w, map[string]int64{"cleared": n})
+}
@@ -0,0 +1,186 @@
+// ABOUTME: Tests for password login lockout: counting failures, refusing before the user lookup,
+// ABOUTME: clearing on success, and the owner-only API that lists and clears lockouts.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// lookupCountingStore counts user lookups, which come just before the
+// bcrypt comparison: a refused attempt that never looks the user up never
+// compares a hash either.
+type lookupCountingStore struct {
+	*store.SQLStore
+	lookups atomic.Int32
+}
+
+func (l *lookupCountingStore) GetAdminUserByUsername(ctx context.Context, username string) (*store.AdminUser, error) {
+	l.lookups.Add(1)
+	return l.SQLStore.GetAdminUserByUsername(ctx, username)
+}
+
+// newLoginAdmin returns an admin with user "alice", password "right".
+func newLoginAdmin(t *testing.T) (*Admin, *lookupCountingStore) {
+	t.Helper()
+	admin, s := newThreadOpsAdmin(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("right"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+	if err := s.CreateAdminUser(context.Background(), &store.AdminUser{
+		ID: "user-alice", Username: "alice", PasswordHash: string(hash), CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreateAdminUser: %v", err)
+	}
+	counting := &lookupCountingStore{SQLStore: s}
+	admin.store = counting
+	return admin, counting
+}
+
+// postLogin submits the login form from ip.
+func postLogin(admin *Admin, ip, username, password string) *httptest.ResponseRecorder {
+	form := url.Values{"username": {username}, "password": {password}, "csrf_token": {"tok"}}
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: "tok"})
+	req.RemoteAddr = ip + ":4321"
+	rec := httptest.NewRecorder()
+	admin.handleLogin(rec, req)
+	return rec
+}
+
+func TestHandleLogin_LockoutAfterFailures(t *testing.T) {
+	admin, s := newLoginAdmin(t)
+
+	for i := range maxLoginFailures {
+		rec := postLogin(admin, "10.0.0.1", "alice", "wrong")
+		if rec.Code == http.StatusSeeOther || !strings.Contains(rec.Body.String(), errInvalidLogin) {
+			t.Fatalf("failure %d = %d, want the login page saying %q", i+1, rec.Code, errInvalidLogin)
+		}
+	}
+	attempt, err := s.GetLoginAttempt(context.Background(), "alice", "10.0.0.1")
+	if err != nil || !attempt.Locked(time.Now()) || attempt.Lockouts != 1 {
+		t.Fatalf("attempt = %+v, %v; want one lockout in force", attempt, err)
+	}
+
+	// The right password is refused with the same message, before the user
+	// is even looked up.
+	before := s.lookups.Load()
+	rec := postLogin(admin, "10.0.0.1", "alice", "right")
+	if rec.Code == http.StatusSeeOther || !strings.Contains(rec.Body.String(), errInvalidLogin) {
+		t.Errorf("locked-out login = %d, want the generic error", rec.Code)
+	}
+	if n := s.lookups.Load() - before; n != 0 {
+		t.Errorf("locked-out login looked the user up %d times, want 0", n)
+	}
+
+	// Another IP is not locked out.
+	if rec := postLogin(admin, "10.0.0.2", "alice", "right"); rec.Code != http.StatusSeeOther {
+		t.Errorf("login from another IP = %d, want a redirect", rec.Code)
+	}
+}
+
+func TestHandleLogin_SuccessClearsFailures(t *testing.T) {
+	admin, s := newLoginAdmin(t)
+
+	for range maxLoginFailures - 1 {
+		postLogin(admin, "10.0.0.1", "alice", "wrong")
+	}
+	if rec := postLogin(admin, "10.0.0.1", "alice", "right"); rec.Code != http.StatusSeeOther {
+		t.Fatalf("login = %d, want a redirect", rec.Code)
+	}
+	if _, err := s.GetLoginAttempt(context.Background(), "alice", "10.0.0.1"); err == nil {
+		t.Error("failures still recorded after a successful login")
+	}
+	// The counter starts over: one more failure does not lock out.
+	postLogin(admin, "10.0.0.1", "alice", "wrong")
+	if rec := postLogin(admin, "10.0.0.1", "alice", "right"); rec.Code != http.StatusSeeOther {
+		t.Errorf("login after one failure = %d, want a redirect", rec.Code)
+	}
+}
+
+func TestLoginLockoutDuration(t *testing.T) {
+	for lockouts, want := range map[int]time.Duration{
+		0:  loginLockoutBase,
+		1:  2 * loginLockoutBase,
+		3:  8 * loginLockoutBase,
+		20: loginLockoutMax,
+	} {
+		if got := loginLockoutDuration(lockouts); got != want {
+			t.Errorf("loginLockoutDuration(%d) = %v, want %v", lockouts, got, want)
+		}
+	}
+}
+
+func TestLoginKey_CutsLongUsernameOnRuneBoundary(t *testing.T) {
+	username := strings.Repeat("€", 100) // 300 bytes; 256 falls inside a rune
+	got, _ := loginKey(httptest.NewRequest(http.MethodPost, "/login", nil), username)
+	if !utf8.ValidString(got) {
+		t.Fatalf("loginKey username is not valid UTF-8: %q", got)
+	}
+	if len(got) > maxLoginUsernameLength || !strings.HasPrefix(username, got) || len(got) < maxLoginUsernameLength-2 {
+		t.Errorf("loginKey username is %d bytes, want the longest whole-rune prefix within %d", len(got), maxLoginUsernameLength)
+	}
+}
+
+func TestHandleClearLoginLockout(t *testing.T) {
+	admin, s, _ := newTokensAdmin(t)
+	owner := seedTokenAdmin(t, s, "olive", "p-olive", store.RoleOwner)
+	bob := seedTokenAdmin(t, s, "bob", "p-bob", store.RoleAdmin)
+	ctx := context.Background()
+	if _, err := s.RecordLoginFailure(ctx, "alice", "10.0.0.1", time.Now(), loginFailureWindow); err != nil {
+		t.Fatalf("RecordLoginFailure: %v", err)
+	}
+	if err := s.LockLogin(ctx, "alice", "10.0.0.1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("LockLogin: %v", err)
+	}
+
+	list := httptest.NewRecorder()
+	admin.handleLoginLockoutsJSON(list, tokenRequestAs(owner, http.MethodGet, "/api/admin/login-lockouts", ""))
+	var resp struct {
+		Lockouts []loginLockoutItem `json:"lockouts"`
+	}
+	if err := json.NewDecoder(list.Body).Decode(&resp); err != nil || len(resp.Lockouts) != 1 || resp.Lockouts[0].Username != "alice" {
+		t.Fatalf("lockouts = %+v, %v; want alice's", resp, err)
+	}
+
+	clearAs := func(user *store.AdminUser, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		admin.handleClearLoginLockout(rec, tokenRequestAs(user, http.MethodDelete, "/api/admin/login-lockouts?"+query, ""))
+		return rec
+	}
+	if rec := clearAs(bob, "username=alice"); rec.Code != http.StatusForbidden {
+		t.Errorf("non-owner clear = %d, want 403", rec.Code)
+	}
+	if rec := clearAs(owner, "ip=10.0.0.1"); rec.Code != http.StatusBadRequest {
+		t.Errorf("clear without username = %d, want 400", rec.Code)
+	}
+	if rec := clearAs(owner, "username=alice&ip=10.0.0.1"); rec.Code != http.StatusOK {
+		t.Fatalf("clear = %d %s", rec.Code, rec.Body.String())
+	}
+	if _, err := s.GetLoginAttempt(ctx, "alice", "10.0.0.1"); err == nil {
+		t.Error("lockout still recorded after clearing")
+	}
+	if rec := clearAs(owner, "username=alice"); rec.Code != http.StatusNotFound {
+		t.Errorf("clearing again = %d, want 404", rec.Code)
+	}
+
+	entries, err := s.ListAuditLog(ctx, store.AuditFilter{})
+	if err != nil || len(entries) != 1 || entries[0].Action != store.AuditClearLoginLockout || entries[0].TargetID != "alice" {
+		t.Errorf("audit entries = %+v, %v; want one clear_login_lockout for alice", entries, err)
+	}
+}
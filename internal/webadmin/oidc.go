@@ -0,0 +1,703 @@
+// ABOUTME: Optional OpenID Connect login for the admin UI (auth-code flow with PKCE)
+// ABOUTME: Verifies ID tokens against the provider's JWKS and provisions admin users on first login
+
+package webadmin
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+const (
+	// oidcStateCookieName binds an in-flight SSO login to the browser that started it.
+	oidcStateCookieName = "coven_oidc_state"
+
+	// oidcSessionCookieName marks sessions created through SSO, so logout
+	// knows whether to visit the provider's end-session endpoint.
+	oidcSessionCookieName = "coven_admin_sso"
+
+	// oidcLoginTimeout is how long a user has to finish logging in at the provider.
+	oidcLoginTimeout = 10 * time.Minute
+
+	// oidcMaxPendingLogins caps unfinished logins held in memory.
+	oidcMaxPendingLogins = 1000
+
+	// oidcClockSkew is the leeway allowed on ID token timestamps.
+	oidcClockSkew = time.Minute
+
+	// oidcJWKSRefreshInterval limits how often an unknown key ID triggers a JWKS refetch.
+	oidcJWKSRefreshInterval = time.Minute
+)
+
+// oidcSigningMethods are the ID token algorithms accepted. Symmetric and
+// "none" algorithms are never accepted.
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// OIDCConfig configures SSO login through an OpenID Connect provider.
+// SSO is enabled when Issuer is set.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string   // defaults to BaseURL + /auth/oidc/callback
+	Scopes       []string // defaults to openid, email, profile
+
+	AllowedDomains []string // verified email domains allowed to log in; empty allows any
+	RequiredGroups []string // at least one must appear in GroupsClaim; empty disables the check
+	GroupsClaim    string   // defaults to "groups"
+	RoleClaim      string   // claim holding owner/admin/member for new users; default member
+
+	ProviderLogout bool // redirect SSO users to the provider's end-session endpoint on logout
+}
+
+// errOIDCDenied is returned when a valid ID token fails the configured
+// domain or group requirements. Its message is shown on the login page.
+var errOIDCDenied = errors.New("your account is not permitted to sign in here")
+
+// oidcDiscovery is the subset of the provider metadata document we use.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// oidcPendingLogin is the per-login secret state kept between redirect and callback.
+type oidcPendingLogin struct {
+	verifier  string
+	nonce     string
+	expiresAt time.Time
+}
+
+// oidcProvider talks to one OpenID Connect provider. Discovery and the JWKS
+// are fetched lazily so a provider outage does not block gateway startup.
+type oidcProvider struct {
+	cfg    OIDCConfig
+	client *http.Client
+	logger *slog.Logger
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+	pending     map[string]oidcPendingLogin // keyed by state
+}
+
+// newOIDCProvider returns a provider for cfg, filling in defaults.
+func newOIDCProvider(cfg OIDCConfig, baseURL string, logger *slog.Logger) *oidcProvider {
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	if cfg.RedirectURL == "" {
+		cfg.RedirectURL = strings.TrimSuffix(baseURL, "/") + "/auth/oidc/callback"
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	return &oidcProvider{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		logger:  logger,
+		pending: make(map[string]oidcPendingLogin),
+	}
+}
+
+// discover returns the provider metadata, fetching it on first use.
+func (p *oidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var d oidcDiscovery
+	if err := p.getJSON(ctx, p.cfg.Issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("fetching provider metadata: %w", err)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("provider metadata issuer %q does not match configured issuer %q", d.Issuer, p.cfg.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("provider metadata is missing authorization, token, or jwks endpoint")
+	}
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, rawURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// oauth2Config builds the auth-code flow configuration from discovery.
+func (p *oidcProvider) oauth2Config(d *oidcDiscovery) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		Endpoint:     oauth2.Endpoint{AuthURL: d.AuthorizationEndpoint, TokenURL: d.TokenEndpoint},
+		RedirectURL:  p.cfg.RedirectURL,
+		Scopes:       p.cfg.Scopes,
+	}
+}
+
+// beginLogin records a pending login and returns its state.
+func (p *oidcProvider) beginLogin(verifier, nonce string) (string, error) {
+	state, err := generateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for s, pl := range p.pending {
+		if now.After(pl.expiresAt) {
+			delete(p.pending, s)
+		}
+	}
+	if len(p.pending) >= oidcMaxPendingLogins {
+		return "", errors.New("too many logins in progress")
+	}
+	p.pending[state] = oidcPendingLogin{verifier: verifier, nonce: nonce, expiresAt: now.Add(oidcLoginTimeout)}
+	return state, nil
+}
+
+// takeLogin removes and returns the pending login for state, if unexpired.
+func (p *oidcProvider) takeLogin(state string) (oidcPendingLogin, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pl, ok := p.pending[state]
+	delete(p.pending, state)
+	if !ok || time.Now().After(pl.expiresAt) {
+		return oidcPendingLogin{}, false
+	}
+	return pl, true
+}
+
+// jwk is one key of a JSON Web Key Set.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes an RSA or EC signing key.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	dec := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "RSA":
+		n, err := dec(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decoding n: %w", err)
+		}
+		e, err := dec(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decoding e: %w", err)
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := dec(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decoding x: %w", err)
+		}
+		y, err := dec(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decoding y: %w", err)
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// signingKey returns the provider key for kid, refetching the JWKS when the
+// key is unknown (providers rotate keys) at most once per refresh interval.
+func (p *oidcProvider) signingKey(ctx context.Context, jwksURI, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < oidcJWKSRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	p.keysFetched = time.Now()
+	if err := p.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("fetching jwks: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			p.logger.Warn("skipping unusable jwks key", "kid", k.Kid, "error", err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	p.keys = keys
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds kid in the cached keys. A token without a kid matches only
+// when the provider publishes exactly one key. Callers hold p.mu.
+func (p *oidcProvider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok && kid != ""
+}
+
+// oidcIdentity is the verified content of an ID token.
+type oidcIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Username      string // preferred_username
+	claims        jwt.MapClaims
+}
+
+// verifyIDToken checks the ID token's signature, issuer, audience, authorized
+// party, expiry, issue time, and nonce, and returns its identity.
+func (p *oidcProvider) verifyIDToken(ctx context.Context, d *oidcDiscovery, rawIDToken, nonce string) (*oidcIdentity, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(oidcClockSkew),
+	)
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(rawIDToken, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.signingKey(ctx, d.JWKSURI, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if _, ok := claims["iat"]; !ok {
+		return nil, errors.New("invalid id token: missing iat")
+	}
+	aud, err := claims.GetAudience()
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+	azp, _ := claims["azp"].(string)
+	if (len(aud) > 1 || azp != "") && azp != p.cfg.ClientID {
+		return nil, fmt.Errorf("invalid id token: authorized party %q is not this client", azp)
+	}
+	gotNonce, _ := claims["nonce"].(string)
+	if gotNonce == "" || subtle.ConstantTimeCompare([]byte(gotNonce), []byte(nonce)) != 1 {
+		return nil, errors.New("invalid id token: nonce mismatch")
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, errors.New("invalid id token: missing sub")
+	}
+
+	id := &oidcIdentity{Subject: sub, claims: claims}
+	id.Email, _ = claims["email"].(string)
+	id.EmailVerified, _ = claims["email_verified"].(bool)
+	id.Name, _ = claims["name"].(string)
+	id.Username, _ = claims["preferred_username"].(string)
+	return id, nil
+}
+
+// claimStrings returns a string or list-of-strings claim as a slice.
+func (id *oidcIdentity) claimStrings(name string) []string {
+	switch v := id.claims[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// authorize applies the configured domain and group requirements.
+func (p *oidcProvider) authorize(id *oidcIdentity) error {
+	if len(p.cfg.AllowedDomains) > 0 {
+		at := strings.LastIndex(id.Email, "@")
+		if at < 0 || !id.EmailVerified {
+			return errOIDCDenied
+		}
+		domain := id.Email[at+1:]
+		allowed := false
+		for _, d := range p.cfg.AllowedDomains {
+			if strings.EqualFold(domain, strings.TrimPrefix(d, "@")) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return errOIDCDenied
+		}
+	}
+	if len(p.cfg.RequiredGroups) > 0 {
+		groups := id.claimStrings(p.cfg.GroupsClaim)
+		for _, want := range p.cfg.RequiredGroups {
+			for _, g := range groups {
+				if g == want {
+					return nil
+				}
+			}
+		}
+		return errOIDCDenied
+	}
+	return nil
+}
+
+// role maps RoleClaim to the most privileged role it names, defaulting to member.
+func (p *oidcProvider) role(id *oidcIdentity) store.RoleName {
+	if p.cfg.RoleClaim == "" {
+		return store.RoleMember
+	}
+	rank := map[store.RoleName]int{store.RoleMember: 0, store.RoleAdmin: 1, store.RoleOwner: 2}
+	best := store.RoleMember
+	for _, v := range id.claimStrings(p.cfg.RoleClaim) {
+		r := store.RoleName(strings.ToLower(v))
+		if n, ok := rank[r]; ok && n > rank[best] {
+			best = r
+		}
+	}
+	return best
+}
+
+// oidcUsernameBase derives a valid admin username from the identity's
+// preferred_username or email local part.
+func oidcUsernameBase(id *oidcIdentity) string {
+	src := id.Username
+	if src == "" {
+		src, _, _ = strings.Cut(id.Email, "@")
+	}
+	var b strings.Builder
+	for _, r := range src {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	name := b.String()
+	if name == "" || !(name[0] >= 'a' && name[0] <= 'z' || name[0] >= 'A' && name[0] <= 'Z') {
+		name = "sso_" + name
+	}
+	for len(name) < 3 {
+		name += "_"
+	}
+	if len(name) > 28 {
+		name = name[:28] // leave room for a numeric suffix
+	}
+	return name
+}
+
+// oidcAdminUser returns the admin user linked to the identity, creating one
+// on first login with the role from RoleClaim.
+func (a *Admin) oidcAdminUser(ctx context.Context, id *oidcIdentity) (*store.AdminUser, error) {
+	issuer := a.oidc.cfg.Issuer
+	user, err := a.store.GetAdminUserByOIDCIdentity(ctx, issuer, id.Subject)
+	if err == nil || !errors.Is(err, store.ErrAdminUserNotFound) {
+		return user, err
+	}
+
+	displayName := id.Name
+	if displayName == "" {
+		displayName = id.Email
+	}
+	base := oidcUsernameBase(id)
+	if displayName == "" {
+		displayName = base
+	}
+	identity := &store.AdminOIDCIdentity{Issuer: issuer, Subject: id.Subject, Email: id.Email, CreatedAt: time.Now()}
+	for attempt := 1; attempt <= 20; attempt++ {
+		username := base
+		if attempt > 1 {
+			username = fmt.Sprintf("%s_%d", base, attempt)
+		}
+		user = &store.AdminUser{ID: uuid.New().String(), Username: username, DisplayName: displayName, CreatedAt: time.Now()}
+		err = a.store.CreateOIDCAdminUser(ctx, user, identity)
+		if errors.Is(err, store.ErrUsernameExists) {
+			continue
+		}
+		if errors.Is(err, store.ErrOIDCIdentityExists) {
+			// A concurrent first login won the race
+			return a.store.GetAdminUserByOIDCIdentity(ctx, issuer, id.Subject)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		role := a.oidc.role(id)
+		a.linkOIDCPrincipal(ctx, user, role)
+		a.logger.Info("provisioned admin user from sso", "username", username, "role", role)
+		return user, nil
+	}
+	return nil, fmt.Errorf("no free username for %q", base)
+}
+
+// linkOIDCPrincipal gives a newly provisioned SSO user a principal holding
+// the mapped role, the same way setup links the owner's principal. Failures
+// are logged; the user can still log in, just without API access.
+func (a *Admin) linkOIDCPrincipal(ctx context.Context, user *store.AdminUser, role store.RoleName) {
+	if a.principalStore == nil {
+		a.logger.Warn("no principal store, sso user created without a role", "user_id", user.ID)
+		return
+	}
+
+	fp, err := generateSecureToken(32)
+	if err != nil {
+		a.logger.Error("failed to generate principal fingerprint", "error", err)
+		return
+	}
+	principal := &store.Principal{
+		ID:          uuid.New().String(),
+		Type:        store.PrincipalTypeClient,
+		PubkeyFP:    fp,
+		DisplayName: user.DisplayName + " (SSO)",
+		Status:      store.PrincipalStatusApproved,
+		CreatedAt:   time.Now(),
+	}
+	if err := a.principalStore.CreatePrincipal(ctx, principal); err != nil {
+		a.logger.Error("failed to create principal for sso user", "user_id", user.ID, "error", err)
+		return
+	}
+	if err := a.principalStore.AddRole(ctx, store.RoleSubjectPrincipal, principal.ID, role); err != nil {
+		a.logger.Error("failed to add role to sso principal", "principal_id", principal.ID, "role", role, "error", err)
+	}
+	if err := a.store.SetAdminUserPrincipal(ctx, user.ID, principal.ID); err != nil {
+		a.logger.Error("failed to link principal to sso user", "principal_id", principal.ID, "error", err)
+		return
+	}
+	user.PrincipalID = principal.ID
+}
+
+// handleOIDCLogin starts SSO login by redirecting to the provider.
+func (a *Admin) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	d, err := a.oidc.discover(r.Context())
+	if err != nil {
+		a.logger.Error("sso provider discovery failed", "error", err)
+		a.showLoginError(w, r, "SSO provider is unavailable, please try again later")
+		return
+	}
+
+	verifier := oauth2.GenerateVerifier()
+	nonce, err := generateSecureToken(16)
+	if err != nil {
+		a.logger.Error("failed to generate sso nonce", "error", err)
+		a.showLoginError(w, r, "An error occurred")
+		return
+	}
+	state, err := a.oidc.beginLogin(verifier, nonce)
+	if err != nil {
+		a.logger.Warn("failed to start sso login", "error", err)
+		a.showLoginError(w, r, "Too many logins in progress, please try again shortly")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    state,
+		Path:     "/auth/oidc",
+		MaxAge:   int(oidcLoginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode, // sent on the provider's top-level redirect back
+	})
+
+	authURL := a.oidc.oauth2Config(d).AuthCodeURL(state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// handleOIDCCallback completes SSO login: it checks state, exchanges the code
+// with the PKCE verifier, verifies the ID token, and creates a session.
+func (a *Admin) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookieName, Value: "", Path: "/auth/oidc", MaxAge: -1, HttpOnly: true})
+
+	if e := q.Get("error"); e != "" {
+		a.logger.Warn("sso provider returned an error", "error", e, "description", q.Get("error_description"))
+		a.showLoginError(w, r, "SSO login was canceled or denied")
+		return
+	}
+
+	state := q.Get("state")
+	cookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		a.showLoginError(w, r, "SSO login expired or was started in another browser, please try again")
+		return
+	}
+	pending, ok := a.oidc.takeLogin(state)
+	if !ok {
+		a.showLoginError(w, r, "SSO login expired, please try again")
+		return
+	}
+
+	d, err := a.oidc.discover(r.Context())
+	if err != nil {
+		a.logger.Error("sso provider discovery failed", "error", err)
+		a.showLoginError(w, r, "SSO provider is unavailable, please try again later")
+		return
+	}
+	ctx := context.WithValue(r.Context(), oauth2.HTTPClient, a.oidc.client)
+	token, err := a.oidc.oauth2Config(d).Exchange(ctx, q.Get("code"), oauth2.VerifierOption(pending.verifier))
+	if err != nil {
+		a.logger.Warn("sso code exchange failed", "error", err)
+		a.showLoginError(w, r, "SSO login failed")
+		return
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		a.logger.Warn("sso token response has no id_token")
+		a.showLoginError(w, r, "SSO login failed")
+		return
+	}
+
+	id, err := a.oidc.verifyIDToken(r.Context(), d, rawIDToken, pending.nonce)
+	if err != nil {
+		a.logger.Warn("sso id token rejected", "error", err)
+		a.showLoginError(w, r, "SSO login failed")
+		return
+	}
+	if err := a.oidc.authorize(id); err != nil {
+		a.logger.Warn("sso login denied", "subject", id.Subject, "email", id.Email)
+		a.showLoginError(w, r, "SSO login denied: "+err.Error())
+		return
+	}
+
+	user, err := a.oidcAdminUser(r.Context(), id)
+	if err != nil {
+		a.logger.Error("failed to resolve sso user", "subject", id.Subject, "error", err)
+		a.showLoginError(w, r, "An error occurred")
+		return
+	}
+	if err := a.createSession(w, r, user.ID); err != nil {
+		a.logger.Error("failed to create session", "error", err)
+		a.showLoginError(w, r, "An error occurred")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcSessionCookieName,
+		Value:    "1",
+		Path:     "/",
+		MaxAge:   int(SessionDuration.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	a.logger.Info("admin sso login successful", "username", user.Username)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// oidcLogoutURL returns the provider's end-session URL for an SSO session
+// when provider logout is enabled, or "" to log out locally only.
+func (a *Admin) oidcLogoutURL(r *http.Request) string {
+	if a.oidc == nil || !a.oidc.cfg.ProviderLogout {
+		return ""
+	}
+	if c, err := r.Cookie(oidcSessionCookieName); err != nil || c.Value == "" {
+		return ""
+	}
+	d, err := a.oidc.discover(r.Context())
+	if err != nil || d.EndSessionEndpoint == "" {
+		return ""
+	}
+	u, err := url.Parse(d.EndSessionEndpoint)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	q.Set("client_id", a.oidc.cfg.ClientID)
+	if a.config.BaseURL != "" {
+		q.Set("post_logout_redirect_uri", strings.TrimSuffix(a.config.BaseURL, "/")+"/login")
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,448 @@
+// ABOUTME: Tests for OIDC single sign-on against a fake provider on httptest.
+// ABOUTME: Covers the PKCE code flow, ID token validation, provisioning, access rules, and logout.
+
+package webadmin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+const testOIDCClientID = "coven-admin"
+
+type fakeOIDCCode struct {
+	challenge string
+	nonce     string
+}
+
+// fakeOIDCProvider is a minimal OpenID provider: discovery, JWKS, and a token
+// endpoint that enforces PKCE and signs ID tokens with an RSA key.
+type fakeOIDCProvider struct {
+	srv *httptest.Server
+	key *rsa.PrivateKey
+	kid string
+
+	mu     sync.Mutex
+	codes  map[string]fakeOIDCCode
+	claims jwt.MapClaims // merged into every issued ID token
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	fp := &fakeOIDCProvider{key: key, kid: "test-key", codes: map[string]fakeOIDCCode{}, claims: jwt.MapClaims{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 fp.srv.URL,
+			"authorization_endpoint": fp.srv.URL + "/authorize",
+			"token_endpoint":         fp.srv.URL + "/token",
+			"jwks_uri":               fp.srv.URL + "/jwks",
+			"end_session_endpoint":   fp.srv.URL + "/logout",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		enc := base64.RawURLEncoding
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": fp.kid, "use": "sig", "alg": "RS256",
+			"n": enc.EncodeToString(key.N.Bytes()),
+			"e": enc.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", fp.handleToken)
+	fp.srv = httptest.NewServer(mux)
+	t.Cleanup(fp.srv.Close)
+	return fp
+}
+
+func (fp *fakeOIDCProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+		return
+	}
+	fp.mu.Lock()
+	c, ok := fp.codes[r.PostForm.Get("code")]
+	delete(fp.codes, r.PostForm.Get("code"))
+	fp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != c.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	idToken := fp.sign(fp.idClaims(c.nonce), jwt.SigningMethodRS256, fp.key)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access", "token_type": "Bearer", "expires_in": 3600, "id_token": idToken,
+	})
+}
+
+// idClaims returns valid ID token claims for nonce, with overrides applied.
+func (fp *fakeOIDCProvider) idClaims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": fp.srv.URL, "aud": testOIDCClientID, "sub": "user-123", "nonce": nonce,
+		"iat": now.Unix(), "exp": now.Add(5 * time.Minute).Unix(),
+	}
+	fp.mu.Lock()
+	for k, v := range fp.claims {
+		claims[k] = v
+	}
+	fp.mu.Unlock()
+	return claims
+}
+
+func (fp *fakeOIDCProvider) sign(claims jwt.MapClaims, method jwt.SigningMethod, key any) string {
+	tok := jwt.NewWithClaims(method, claims)
+	tok.Header["kid"] = fp.kid
+	s, err := tok.SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (fp *fakeOIDCProvider) setClaims(claims jwt.MapClaims) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	fp.claims = claims
+}
+
+func newOIDCTestAdmin(t *testing.T, fp *fakeOIDCProvider, mutate func(*OIDCConfig)) (*Admin, *store.SQLStore) {
+	t.Helper()
+	s, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	cfg := OIDCConfig{Issuer: fp.srv.URL, ClientID: testOIDCClientID, ClientSecret: "secret", RoleClaim: "roles"}
+	if mutate != nil {
+		mutate(&cfg)
+	}
+	a := &Admin{store: s, principalStore: s, logger: slog.Default(), config: Config{BaseURL: "https://admin.example.com", OIDC: cfg}}
+	a.oidc = newOIDCProvider(cfg, a.config.BaseURL, a.logger)
+	return a, s
+}
+
+// oidcLogin runs the browser side of the flow: start login, "authenticate" at
+// the fake provider, and return the callback response.
+func oidcLogin(t *testing.T, a *Admin, fp *fakeOIDCProvider) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	a.handleOIDCLogin(rec, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	loc, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("parsing redirect: %v", err)
+	}
+	q := loc.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" || q.Get("nonce") == "" {
+		t.Fatalf("authorize URL missing PKCE or nonce: %s", loc)
+	}
+	if q.Get("redirect_uri") != "https://admin.example.com/auth/oidc/callback" {
+		t.Errorf("redirect_uri = %q", q.Get("redirect_uri"))
+	}
+
+	fp.mu.Lock()
+	fp.codes["code-1"] = fakeOIDCCode{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	fp.mu.Unlock()
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?code=code-1&state="+url.QueryEscape(q.Get("state")), nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	cb := httptest.NewRecorder()
+	a.handleOIDCCallback(cb, req)
+	return cb
+}
+
+func responseCookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == name && c.MaxAge >= 0 {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestOIDCLogin_ProvisionsUserWithMappedRole(t *testing.T) {
+	fp := newFakeOIDCProvider(t)
+	a, s := newOIDCTestAdmin(t, fp, nil)
+	fp.setClaims(jwt.MapClaims{
+		"email": "ada@example.com", "email_verified": true, "preferred_username": "ada.l",
+		"name": "Ada Lovelace", "roles": []string{"member", "admin"},
+	})
+
+	rec := oidcLogin(t, a, fp)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/" {
+		t.Fatalf("callback: status = %d, location = %q, body = %s", rec.Code, rec.Header().Get("Location"), rec.Body.String())
+	}
+	session := responseCookie(rec, SessionCookieName)
+	if session == nil || responseCookie(rec, oidcSessionCookieName) == nil {
+		t.Fatal("expected session and sso cookies")
+	}
+
+	ctx := context.Background()
+	if _, err := s.GetAdminSession(ctx, session.Value); err != nil {
+		t.Fatalf("session not stored: %v", err)
+	}
+	user, err := s.GetAdminUserByOIDCIdentity(ctx, fp.srv.URL, "user-123")
+	if err != nil {
+		t.Fatalf("GetAdminUserByOIDCIdentity: %v", err)
+	}
+	if user.Username != "ada_l" || user.DisplayName != "Ada Lovelace" || user.PasswordHash != "" {
+		t.Errorf("user = %+v, want passwordless ada_l", user)
+	}
+	roles, err := s.ListRoles(ctx, store.RoleSubjectPrincipal, user.PrincipalID)
+	if err != nil || len(roles) != 1 || roles[0] != store.RoleAdmin {
+		t.Errorf("roles = %v (err %v), want [admin]", roles, err)
+	}
+
+	// A second login reuses the linked user
+	if rec := oidcLogin(t, a, fp); rec.Code != http.StatusSeeOther {
+		t.Fatalf("second login: status = %d", rec.Code)
+	}
+	if n, _ := s.CountAdminUsers(ctx); n != 1 {
+		t.Errorf("admin users = %d, want 1", n)
+	}
+}
+
+func TestOIDCLogin_DefaultsToMemberAndAvoidsUsernameClash(t *testing.T) {
+	fp := newFakeOIDCProvider(t)
+	a, s := newOIDCTestAdmin(t, fp, nil)
+	ctx := context.Background()
+	if err := s.CreateAdminUser(ctx, &store.AdminUser{ID: "existing", Username: "grace", PasswordHash: "x", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("CreateAdminUser: %v", err)
+	}
+	fp.setClaims(jwt.MapClaims{"email": "grace@example.com"})
+
+	if rec := oidcLogin(t, a, fp); rec.Code != http.StatusSeeOther {
+		t.Fatalf("callback: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	user, err := s.GetAdminUserByOIDCIdentity(ctx, fp.srv.URL, "user-123")
+	if err != nil {
+		t.Fatalf("GetAdminUserByOIDCIdentity: %v", err)
+	}
+	if user.Username != "grace_2" {
+		t.Errorf("username = %q, want grace_2", user.Username)
+	}
+	roles, _ := s.ListRoles(ctx, store.RoleSubjectPrincipal, user.PrincipalID)
+	if len(roles) != 1 || roles[0] != store.RoleMember {
+		t.Errorf("roles = %v, want [member]", roles)
+	}
+}
+
+func TestOIDCLogin_AccessRequirements(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*OIDCConfig)
+		claims jwt.MapClaims
+		allow  bool
+	}{
+		{"allowed domain", func(c *OIDCConfig) { c.AllowedDomains = []string{"example.com"} },
+			jwt.MapClaims{"email": "a@Example.com", "email_verified": true}, true},
+		{"other domain", func(c *OIDCConfig) { c.AllowedDomains = []string{"example.com"} },
+			jwt.MapClaims{"email": "a@evil.com", "email_verified": true}, false},
+		{"unverified email", func(c *OIDCConfig) { c.AllowedDomains = []string{"example.com"} },
+			jwt.MapClaims{"email": "a@example.com"}, false},
+		{"required group present", func(c *OIDCConfig) { c.RequiredGroups = []string{"ops", "admins"} },
+			jwt.MapClaims{"email": "a@example.com", "groups": []string{"eng", "admins"}}, true},
+		{"required group missing", func(c *OIDCConfig) { c.RequiredGroups = []string{"admins"} },
+			jwt.MapClaims{"email": "a@example.com", "groups": []string{"eng"}}, false},
+		{"custom groups claim", func(c *OIDCConfig) { c.RequiredGroups = []string{"admins"}; c.GroupsClaim = "teams" },
+			jwt.MapClaims{"email": "a@example.com", "teams": "admins"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp := newFakeOIDCProvider(t)
+			a, s := newOIDCTestAdmin(t, fp, tt.mutate)
+			fp.setClaims(tt.claims)
+
+			rec := oidcLogin(t, a, fp)
+			if got := responseCookie(rec, SessionCookieName) != nil; got != tt.allow {
+				t.Fatalf("session created = %v, want %v (status %d)", got, tt.allow, rec.Code)
+			}
+			if !tt.allow {
+				if !strings.Contains(rec.Body.String(), "not permitted") {
+					t.Errorf("denial page missing reason: %s", rec.Body.String())
+				}
+				if n, _ := s.CountAdminUsers(context.Background()); n != 0 {
+					t.Errorf("denied login provisioned %d users", n)
+				}
+			}
+		})
+	}
+}
+
+func TestOIDCCallback_RejectsBadState(t *testing.T) {
+	fp := newFakeOIDCProvider(t)
+	a, _ := newOIDCTestAdmin(t, fp, nil)
+
+	rec := httptest.NewRecorder()
+	a.handleOIDCLogin(rec, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+	loc, _ := url.Parse(rec.Header().Get("Location"))
+	state := loc.Query().Get("state")
+
+	// No state cookie: the callback did not come from the browser that started login
+	cb := httptest.NewRecorder()
+	a.handleOIDCCallback(cb, httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?code=x&state="+state, nil))
+	if responseCookie(cb, SessionCookieName) != nil || !strings.Contains(cb.Body.String(), "another browser") {
+		t.Errorf("missing cookie: status %d, body %s", cb.Code, cb.Body.String())
+	}
+
+	// Unknown state, even with a matching cookie
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?code=x&state=forged", nil)
+	req.AddCookie(&http.Cookie{Name: oidcStateCookieName, Value: "forged"})
+	cb = httptest.NewRecorder()
+	a.handleOIDCCallback(cb, req)
+	if responseCookie(cb, SessionCookieName) != nil || !strings.Contains(cb.Body.String(), "expired") {
+		t.Errorf("forged state: status %d, body %s", cb.Code, cb.Body.String())
+	}
+}
+
+func TestOIDCVerifyIDToken(t *testing.T) {
+	fp := newFakeOIDCProvider(t)
+	a, _ := newOIDCTestAdmin(t, fp, nil)
+	ctx := context.Background()
+	d, err := a.oidc.discover(ctx)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	with := func(overrides jwt.MapClaims) jwt.MapClaims {
+		c := fp.idClaims("nonce-1")
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+	rs256 := func(c jwt.MapClaims) string { return fp.sign(c, jwt.SigningMethodRS256, fp.key) }
+	past := time.Now().Add(-time.Hour).Unix()
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"valid", rs256(with(nil)), true},
+		{"audience list with matching azp", rs256(with(jwt.MapClaims{"aud": []string{testOIDCClientID, "other"}, "azp": testOIDCClientID})), true},
+		{"wrong audience", rs256(with(jwt.MapClaims{"aud": "someone-else"})), false},
+		{"audience list without azp", rs256(with(jwt.MapClaims{"aud": []string{testOIDCClientID, "other"}})), false},
+		{"foreign azp", rs256(with(jwt.MapClaims{"azp": "someone-else"})), false},
+		{"wrong issuer", rs256(with(jwt.MapClaims{"iss": "https://evil.example.com"})), false},
+		{"expired", rs256(with(jwt.MapClaims{"exp": past})), false},
+		{"missing exp", rs256(with(jwt.MapClaims{"exp": nil})), false},
+		{"missing iat", rs256(with(jwt.MapClaims{"iat": nil})), false},
+		{"wrong nonce", rs256(with(jwt.MapClaims{"nonce": "replayed"})), false},
+		{"missing nonce", rs256(with(jwt.MapClaims{"nonce": nil})), false},
+		{"missing sub", rs256(with(jwt.MapClaims{"sub": nil})), false},
+		{"signed by another key", fp.sign(with(nil), jwt.SigningMethodRS256, otherKey), false},
+		{"symmetric HS256 with client secret", fp.sign(with(nil), jwt.SigningMethodHS256, []byte("secret")), false},
+		{"alg none", fp.sign(with(nil), jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType), false},
+		{"tampered payload", tamperJWT(rs256(with(nil))), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.oidc.verifyIDToken(ctx, d, tt.token, "nonce-1")
+			if (err == nil) != tt.ok {
+				t.Errorf("verifyIDToken error = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+// tamperJWT swaps the subject in the payload, keeping the original signature.
+func tamperJWT(token string) string {
+	parts := strings.Split(token, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	payload = []byte(strings.Replace(string(payload), `"user-123"`, `"user-999"`, 1))
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	return strings.Join(parts, ".")
+}
+
+func TestOIDCLogout_ProviderEndSession(t *testing.T) {
+	fp := newFakeOIDCProvider(t)
+	a, s := newOIDCTestAdmin(t, fp, func(c *OIDCConfig) { c.ProviderLogout = true })
+	fp.setClaims(jwt.MapClaims{"email": "ada@example.com"})
+
+	login := oidcLogin(t, a, fp)
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(responseCookie(login, SessionCookieName))
+	req.AddCookie(responseCookie(login, oidcSessionCookieName))
+	rec := httptest.NewRecorder()
+	a.handleLogout(rec, req)
+
+	loc, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(loc.String(), fp.srv.URL+"/logout") {
+		t.Fatalf("logout redirect = %q, want provider end-session endpoint", rec.Header().Get("Location"))
+	}
+	if loc.Query().Get("client_id") != testOIDCClientID || loc.Query().Get("post_logout_redirect_uri") != "https://admin.example.com/login" {
+		t.Errorf("end-session params = %v", loc.Query())
+	}
+	if _, err := s.GetAdminSession(context.Background(), responseCookie(login, SessionCookieName).Value); err == nil {
+		t.Error("local session should be deleted")
+	}
+
+	// Password sessions log out locally even with provider logout enabled
+	rec = httptest.NewRecorder()
+	a.handleLogout(rec, httptest.NewRequest(http.MethodPost, "/logout", nil))
+	if rec.Header().Get("Location") != "/login" {
+		t.Errorf("password logout redirect = %q, want /login", rec.Header().Get("Location"))
+	}
+}
+
+func TestLoginPage_ShowsSSOWhenConfigured(t *testing.T) {
+	fp := newFakeOIDCProvider(t)
+	a, _ := newOIDCTestAdmin(t, fp, nil)
+
+	rec := httptest.NewRecorder()
+	a.renderLoginPage(rec, "", "csrf")
+	if !strings.Contains(rec.Body.String(), `"sso":true`) || !strings.Contains(rec.Body.String(), "/auth/oidc/login") {
+		t.Error("login page should offer SSO when configured")
+	}
+
+	a.oidc = nil
+	rec = httptest.NewRecorder()
+	a.renderLoginPage(rec, "", "csrf")
+	if strings.Contains(rec.Body.String(), "/auth/oidc/login") {
+		t.Error("login page should not offer SSO when unconfigured")
+	}
+}
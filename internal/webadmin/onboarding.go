@@ -0,0 +1,257 @@
+// ABOUTME: First-run onboarding checklist computed from live gateway state
+// ABOUTME: Serves checklist JSON for the dashboard and a test round-trip to a connected agent
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+// onboardingTestMessage is the content sent by the "send test message" action.
+const onboardingTestMessage = "Hello from the coven-gateway onboarding checklist. Please reply briefly to confirm you can hear me."
+
+// onboardingTestTimeout bounds how long the test round-trip waits for a reply.
+const onboardingTestTimeout = 60 * time.Second
+
+// Onboarding step IDs, in the order operators should complete them.
+const (
+	stepAdminAccount   = "admin_account"
+	stepAgentApproved  = "agent_approved"
+	stepAgentConnected = "agent_connected"
+	stepBindingCreated = "binding_created"
+	stepMessageSent    = "message_exchanged"
+)
+
+type onboardingStep struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Done        bool   `json:"done"`
+	ActionLabel string `json:"actionLabel,omitempty"`
+	ActionURL   string `json:"actionUrl,omitempty"`
+}
+
+type onboardingState struct {
+	Steps           []onboardingStep `json:"steps"`
+	Complete        bool             `json:"complete"`
+	AgentServerURL  string           `json:"agentServerUrl"`
+	InstallCommand  string           `json:"installCommand"`
+	ConnectedAgents []agentItem      `json:"connectedAgents"`
+}
+
+// computeOnboarding evaluates each checklist step against the store and agent
+// manager. A failed query leaves that step unchecked rather than failing.
+func (a *Admin) computeOnboarding(ctx context.Context) onboardingState {
+	agents := a.listAgentItems()
+	if agents == nil {
+		agents = []agentItem{}
+	}
+
+	steps := []onboardingStep{
+		{
+			ID:          stepAdminAccount,
+			Title:       "Create an admin account",
+			Description: "Bootstrap the first admin user for this gateway.",
+			Done:        a.onboardingCheck(ctx, stepAdminAccount, a.hasAdminUser),
+		},
+		{
+			ID:          stepAgentApproved,
+			Title:       "Approve an agent",
+			Description: "Agents register as pending principals until an admin approves them.",
+			Done:        a.onboardingCheck(ctx, stepAgentApproved, a.hasApprovedAgent),
+			ActionLabel: "Review principals",
+			ActionURL:   "/admin/principals",
+		},
+		{
+			ID:          stepAgentConnected,
+			Title:       "Connect an agent",
+			Description: "Run coven-agent pointed at this gateway's gRPC address.",
+			Done:        len(agents) > 0,
+			ActionLabel: "View agents",
+			ActionURL:   "/admin/agents",
+		},
+		{
+			ID:          stepBindingCreated,
+			Title:       "Bind a channel",
+			Description: "Bind a frontend channel (Slack, Matrix, ...) to an agent with /bind or coven-admin.",
+			Done: a.onboardingCheck(ctx, stepBindingCreated, func(ctx context.Context) (bool, error) {
+				n, err := a.store.CountBindings(ctx)
+				return n > 0, err
+			}),
+		},
+		{
+			ID:          stepMessageSent,
+			Title:       "Exchange a message",
+			Description: "Send a message and receive a reply from an agent.",
+			Done:        a.onboardingCheck(ctx, stepMessageSent, a.store.HasAgentReply),
+		},
+	}
+
+	complete := true
+	for _, s := range steps {
+		complete = complete && s.Done
+	}
+
+	serverURL := a.config.AgentServerURL
+	if serverURL == "" {
+		serverURL = "http://127.0.0.1:50051"
+	}
+
+	return onboardingState{
+		Steps:           steps,
+		Complete:        complete,
+		AgentServerURL:  serverURL,
+		InstallCommand:  fmt.Sprintf("coven-agent --server %s --name %q", serverURL, "my-agent"),
+		ConnectedAgents: agents,
+	}
+}
+
+// onboardingCheck runs a step check, logging and returning false on error.
+func (a *Admin) onboardingCheck(ctx context.Context, step string, check func(context.Context) (bool, error)) bool {
+	if a.store == nil {
+		return false
+	}
+	done, err := check(ctx)
+	if err != nil {
+		a.logger.Warn("onboarding check failed", "step", step, "error", err)
+		return false
+	}
+	return done
+}
+
+func (a *Admin) hasAdminUser(ctx context.Context) (bool, error) {
+	n, err := a.store.CountAdminUsers(ctx)
+	return n > 0, err
+}
+
+// hasApprovedAgent reports whether any agent principal has been approved.
+// Online and offline agents have already passed approval.
+func (a *Admin) hasApprovedAgent(ctx context.Context) (bool, error) {
+	agentType := store.PrincipalTypeAgent
+	for _, status := range []store.PrincipalStatus{
+		store.PrincipalStatusApproved, store.PrincipalStatusOnline, store.PrincipalStatusOffline,
+	} {
+		n, err := a.store.CountPrincipals(ctx, store.PrincipalFilter{Type: &agentType, Status: &status})
+		if err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// handleOnboardingJSON returns the onboarding checklist state.
+func (a *Admin) handleOnboardingJSON(w http.ResponseWriter, r *http.Request) {
+	a.writeJSON(w, a.computeOnboarding(r.Context()))
+}
+
+// onboardingTestRequest is the optional body of POST /api/admin/onboarding/test-message.
+type onboardingTestRequest struct {
+	AgentID string `json:"agent_id"`
+}
+
+// handleOnboardingTestMessage sends a canned message to a connected agent and
+// waits for its reply, proving the full send/respond path works.
+func (a *Admin) handleOnboardingTestMessage(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+	user := a.checkChatSendPrereqs(w, r)
+	if user == nil {
+		return
+	}
+
+	var req onboardingTestRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.AgentID == "" {
+		agents := a.listAgentItems()
+		if len(agents) == 0 {
+			http.Error(w, "No agent connected", http.StatusConflict)
+			return
+		}
+		req.AgentID = agents[0].ID
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), onboardingTestTimeout)
+	defer cancel()
+
+	// Same thread convention as the chat page: one conversation per agent.
+	resp, err := a.conversation.SendMessage(ctx, &conversation.SendRequest{
+		ThreadID:     req.AgentID,
+		FrontendName: "webadmin",
+		ExternalID:   req.AgentID,
+		AgentID:      req.AgentID,
+		Sender:       user.Username,
+		Content:      onboardingTestMessage,
+	})
+	if err != nil {
+		if errors.Is(err, agent.ErrAgentNotFound) {
+			http.Error(w, "Agent not connected", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, agent.ErrAgentPaused) {
+			http.Error(w, "Agent is paused", http.StatusConflict)
+			return
+		}
+		a.logger.Error("onboarding test message failed", "agent_id", req.AgentID, "error", err)
+		http.Error(w, "Failed to send message", http.StatusInternalServerError)
+		return
+	}
+
+	reply, replyErr := collectReply(ctx, resp.Stream)
+	result := map[string]any{
+		"agent_id":  req.AgentID,
+		"thread_id": resp.ThreadID,
+		"ok":        replyErr == "",
+		"reply":     reply,
+	}
+	if replyErr != "" {
+		result["error"] = replyErr
+	}
+	a.writeJSON(w, result)
+}
+
+// collectReply drains an agent response stream and returns the full text, or
+// an error message if the agent failed or the context expired first.
+func collectReply(ctx context.Context, stream <-chan *agent.Response) (string, string) {
+	var text strings.Builder
+	for {
+		select {
+		case <-ctx.Done():
+			return text.String(), "timed out waiting for the agent to reply"
+		case resp, ok := <-stream:
+			if !ok {
+				return text.String(), ""
+			}
+			switch resp.Event {
+			case agent.EventText:
+				text.WriteString(resp.Text)
+			case agent.EventDone:
+				if resp.Text != "" {
+					return resp.Text, ""
+				}
+				return text.String(), ""
+			case agent.EventError:
+				return text.String(), resp.Error
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// ABOUTME: Tests for the onboarding checklist state and test-message endpoint.
+// ABOUTME: Drives each checklist step from real store state.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func onboardingSteps(t *testing.T, admin *Admin) map[string]bool {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/onboarding", nil)
+	rec := httptest.NewRecorder()
+	admin.handleOnboardingJSON(rec, requestWithUser(req))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var state onboardingState
+	if err := json.NewDecoder(rec.Body).Decode(&state); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if state.Complete {
+		t.Error("checklist reported complete without a connected agent")
+	}
+	done := make(map[string]bool, len(state.Steps))
+	for _, s := range state.Steps {
+		done[s.ID] = s.Done
+	}
+	return done
+}
+
+func TestHandleOnboardingJSON_ReflectsStoreState(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	admin.config.AgentServerURL = "http://gw.example:50051"
+	ctx := context.Background()
+
+	for id, done := range onboardingSteps(t, admin) {
+		if done {
+			t.Errorf("fresh store: step %s done, want pending", id)
+		}
+	}
+
+	if err := s.CreateAdminUser(ctx, &store.AdminUser{ID: "admin-1", Username: "admin", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("CreateAdminUser: %v", err)
+	}
+	if err := s.CreatePrincipal(ctx, &store.Principal{
+		ID: "agent-1", Type: store.PrincipalTypeAgent, PubkeyFP: strings.Repeat("a", 64),
+		DisplayName: "Agent", Status: store.PrincipalStatusApproved, CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreatePrincipal: %v", err)
+	}
+	if err := s.CreateBindingV2(ctx, &store.Binding{
+		ID: "b-1", Frontend: "slack", ChannelID: "C001", AgentID: "agent-1", CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreateBindingV2: %v", err)
+	}
+	reply := "hi"
+	if err := s.SaveEvent(ctx, &store.LedgerEvent{
+		ID: "e-1", ConversationKey: "agent-1", Direction: store.EventDirectionOutbound,
+		Author: "agent:agent-1", Timestamp: time.Now(), Type: store.EventTypeMessage, Text: &reply,
+	}); err != nil {
+		t.Fatalf("SaveEvent: %v", err)
+	}
+
+	done := onboardingSteps(t, admin)
+	for _, id := range []string{stepAdminAccount, stepAgentApproved, stepBindingCreated, stepMessageSent} {
+		if !done[id] {
+			t.Errorf("step %s pending, want done", id)
+		}
+	}
+	if done[stepAgentConnected] {
+		t.Error("agent_connected done with no agent manager")
+	}
+
+	state := admin.computeOnboarding(ctx)
+	if !strings.Contains(state.InstallCommand, "http://gw.example:50051") {
+		t.Errorf("install command = %q, want gateway address", state.InstallCommand)
+	}
+}
+
+func TestHandleOnboardingTestMessage_Guards(t *testing.T) {
+	admin, _ := newThreadOpsAdmin(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/onboarding/test-message", nil)
+	rec := httptest.NewRecorder()
+	admin.handleOnboardingTestMessage(rec, requestWithUser(req))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("without CSRF status = %d, want 403", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleOnboardingTestMessage(rec, csrfJSONRequest(http.MethodPost, "/api/admin/onboarding/test-message", ""))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("without conversation service status = %d, want 503", rec.Code)
+	}
+}
+
+func TestCollectReply(t *testing.T) {
+	stream := make(chan *agent.Response, 3)
+	stream <- &agent.Response{Event: agent.EventText, Text: "hel"}
+	stream <- &agent.Response{Event: agent.EventText, Text: "lo"}
+	stream <- &agent.Response{Event: agent.EventDone, Done: true}
+
+	reply, errMsg := collectReply(context.Background(), stream)
+	if reply != "hello" || errMsg != "" {
+		t.Errorf("collectReply = (%q, %q), want (hello, \"\")", reply, errMsg)
+	}
+
+	stream = make(chan *agent.Response, 1)
+	stream <- &agent.Response{Event: agent.EventError, Error: "boom", Done: true}
+	if _, errMsg := collectReply(context.Background(), stream); errMsg != "boom" {
+		t.Errorf("error reply = %q, want boom", errMsg)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, errMsg := collectReply(ctx, make(chan *agent.Response)); errMsg == "" {
+		t.Error("expected timeout error for canceled context")
+	}
+}
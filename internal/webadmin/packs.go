@@ -0,0 +1,49 @@
+// ABOUTME: Health of the external tool packs connected over gRPC, for the settings page
+// ABOUTME: Serves GET /api/admin/packs, which shows whether each pack is keeping up its heartbeats
+
+package webadmin
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// packHealthItem is one connected external pack as the packs panel shows it.
+type packHealthItem struct {
+	ID            string   `json:"id"`
+	Version       string   `json:"version"`
+	Tools         []string `json:"tools"`
+	Healthy       bool     `json:"healthy"`
+	LastHeartbeat string   `json:"lastHeartbeat,omitempty"` // empty if the pack never heartbeated
+}
+
+// packsPanel is the tool packs section of the settings page.
+type packsPanel struct {
+	Packs []packHealthItem `json:"packs"`
+}
+
+// buildPacksPanel lists the connected external packs by ID.
+func (a *Admin) buildPacksPanel() *packsPanel {
+	panel := &packsPanel{Packs: []packHealthItem{}}
+	if a.registry == nil {
+		return panel
+	}
+	for _, pi := range a.registry.ListPacks() {
+		tools := append([]string{}, pi.ToolNames...)
+		sort.Strings(tools)
+		item := packHealthItem{ID: pi.ID, Version: pi.Version, Tools: tools, Healthy: pi.Healthy}
+		if !pi.LastHeartbeat.IsZero() {
+			item.LastHeartbeat = timeparse.Format(pi.LastHeartbeat)
+		}
+		panel.Packs = append(panel.Packs, item)
+	}
+	sort.Slice(panel.Packs, func(i, j int) bool { return panel.Packs[i].ID < panel.Packs[j].ID })
+	return panel
+}
+
+// handlePacksJSON returns the connected external packs and their health.
+func (a *Admin) handlePacksJSON(w http.ResponseWriter, _ *http.Request) {
+	a.writeJSON(w, a.buildPacksPanel())
+}
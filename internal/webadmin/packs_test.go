@@ -0,0 +1,71 @@
+// ABOUTME: Tests for the external tool pack health API used by the settings page.
+// ABOUTME: Covers packs that never heartbeat, packs keeping up, and packs gone quiet.
+
+package webadmin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/packs"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestHandlePacksJSON(t *testing.T) {
+	registry := packs.NewRegistry(slog.Default())
+	registry.SetHeartbeatMissWindow(50 * time.Millisecond)
+	for _, id := range []string{"quiet", "alive", "streaming"} {
+		if err := registry.RegisterPack(id, &pb.PackManifest{PackId: id, Version: "1.0.0", Tools: []*pb.ToolDefinition{
+			{Name: id + ":b"}, {Name: id + ":a"},
+		}}); err != nil {
+			t.Fatalf("RegisterPack(%s): %v", id, err)
+		}
+	}
+	if err := registry.Heartbeat("quiet"); err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := registry.Heartbeat("alive"); err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	newTestAdmin(registry).handlePacksJSON(rec, requestWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/packs", nil)))
+	var panel packsPanel
+	if err := json.NewDecoder(rec.Body).Decode(&panel); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(panel.Packs) != 3 {
+		t.Fatalf("packs = %+v, want 3", panel.Packs)
+	}
+
+	for i, want := range []struct {
+		id            string
+		healthy       bool
+		lastHeartbeat bool
+	}{
+		{"alive", true, true},
+		{"quiet", false, true},
+		{"streaming", true, false},
+	} {
+		got := panel.Packs[i]
+		if got.ID != want.id || got.Healthy != want.healthy || (got.LastHeartbeat != "") != want.lastHeartbeat {
+			t.Errorf("pack %d = %+v, want %s healthy=%v with heartbeat=%v", i, got, want.id, want.healthy, want.lastHeartbeat)
+		}
+		if len(got.Tools) != 2 || got.Tools[0] != want.id+":a" {
+			t.Errorf("pack %s tools = %v, want both, sorted", got.ID, got.Tools)
+		}
+	}
+}
+
+func TestHandlePacksJSON_NoRegistry(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestAdmin(nil).handlePacksJSON(rec, requestWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/packs", nil)))
+	if body := rec.Body.String(); body != "{\"packs\":[]}\n" {
+		t.Errorf("body = %q, want an empty pack list", body)
+	}
+}
@@ -0,0 +1,147 @@
+// ABOUTME: Tells admins about agents waiting for approval: a chat banner, an optional webhook,
+// ABOUTME: and GET /api/admin/agents?status=pending with what each agent reported about itself
+
+package webadmin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+const pendingAgentWebhookTimeout = 5 * time.Second
+
+// pendingAgentItem is an agent waiting for its principal to be approved, with
+// the registration details an admin needs to decide.
+type pendingAgentItem struct {
+	AgentID      string `json:"agent_id"`
+	Name         string `json:"name"`
+	PrincipalID  string `json:"principal_id"`
+	Fingerprint  string `json:"fingerprint"`
+	Hostname     string `json:"hostname,omitempty"`
+	OS           string `json:"os,omitempty"`
+	WorkingDir   string `json:"working_dir,omitempty"`
+	Repo         string `json:"repo,omitempty"`
+	Branch       string `json:"branch,omitempty"`
+	ConnectedAt  string `json:"connected_at"`
+	NewPrincipal bool   `json:"new_principal"`
+}
+
+// pendingAgentWebhook is the body POSTed to webadmin.pending_agent_webhook_url.
+type pendingAgentWebhook struct {
+	Event      string           `json:"event"` // always "agent_pending"
+	Agent      pendingAgentItem `json:"agent"`
+	ApproveURL string           `json:"approve_url,omitempty"`
+}
+
+func newPendingAgentItem(p agent.PendingAgent) pendingAgentItem {
+	item := pendingAgentItem{
+		AgentID:      p.AgentID,
+		Name:         p.Name,
+		PrincipalID:  p.PrincipalID,
+		Fingerprint:  p.Fingerprint,
+		ConnectedAt:  timeparse.Format(p.ConnectedAt),
+		NewPrincipal: p.NewPrincipal,
+	}
+	if m := p.Metadata; m != nil {
+		item.Hostname = m.Hostname
+		item.OS = m.OS
+		item.WorkingDir = m.WorkingDir
+		if m.Git != nil {
+			item.Repo = m.Git.Remote
+			item.Branch = m.Git.Branch
+		}
+	}
+	return item
+}
+
+// handlePendingAgentsJSON handles GET /api/admin/agents. Only status=pending is
+// supported; connected agents are listed by GET /api/agents.
+func (a *Admin) handlePendingAgentsJSON(w http.ResponseWriter, r *http.Request) {
+	if status := r.URL.Query().Get("status"); status != "pending" {
+		http.Error(w, `status must be "pending"; connected agents are listed at /api/agents`, http.StatusBadRequest)
+		return
+	}
+	items := []pendingAgentItem{}
+	if a.manager != nil {
+		for _, p := range a.manager.ListPending() {
+			items = append(items, newPendingAgentItem(p))
+		}
+	}
+	a.writeJSON(w, map[string]any{"agents": items})
+}
+
+// AgentPending tells admins that an agent auto-registered a pending principal
+// and is waiting for approval: open chat streams get an agent_pending event
+// and the configured webhook, if any, is posted to. Agents reconnecting with
+// a principal that was already pending are not announced again; they are
+// still listed by GET /api/admin/agents?status=pending.
+func (a *Admin) AgentPending(p agent.PendingAgent) {
+	if !p.NewPrincipal {
+		return
+	}
+	item := newPendingAgentItem(p)
+
+	if a.chatHub != nil {
+		summary := fmt.Sprintf("Agent %s is waiting for approval", item.Name)
+		if item.Hostname != "" {
+			summary += " (" + item.Hostname + ")"
+		}
+		sent := a.chatHub.broadcast(&chatMessage{
+			Type:         "agent_pending",
+			Content:      summary,
+			Timestamp:    time.Now(),
+			PendingAgent: &item,
+		})
+		a.logger.Debug("pending agent announced to chat clients", "agent_id", item.AgentID, "clients", sent)
+	}
+
+	if url := a.config.PendingAgentWebhookURL; url != "" {
+		body := pendingAgentWebhook{Event: "agent_pending", Agent: item, ApproveURL: a.principalsURL()}
+		go func() {
+			if err := postPendingAgent(url, body); err != nil {
+				a.logger.Warn("pending agent webhook failed", "url", url, "agent_id", item.AgentID, "error", err)
+			}
+		}()
+	}
+}
+
+// principalsURL returns the absolute link to the principals page, where
+// pending principals are approved, or "" when the base URL is unknown.
+func (a *Admin) principalsURL() string {
+	if a.config.BaseURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(a.config.BaseURL, "/") + "/admin/principals"
+}
+
+// postPendingAgent delivers one webhook. Delivery is best effort and not retried.
+func postPendingAgent(url string, body pendingAgentWebhook) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encoding webhook: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pendingAgentWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// ABOUTME: Tests for admin notifications about agents awaiting approval: the pending
+// ABOUTME: agents list, the chat banner event, and the optional webhook.
+
+package webadmin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/agent"
+)
+
+func TestHandlePendingAgentsJSON(t *testing.T) {
+	admin, _ := newThreadOpsAdmin(t)
+	admin.manager = agent.NewManager(slog.Default())
+	if _, err := admin.manager.AddPending(agent.PendingAgent{
+		AgentID: "agent-1", Name: "builder", PrincipalID: "p-1", Fingerprint: "ab12", ConnectedAt: time.Now(),
+		Metadata: &agent.Metadata{Hostname: "build-box", Git: &agent.GitInfo{Remote: "git@example.com:org/repo.git", Branch: "main"}},
+	}); err != nil {
+		t.Fatalf("AddPending: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	admin.handlePendingAgentsJSON(rec, httptest.NewRequest(http.MethodGet, "/api/admin/agents?status=pending", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Agents []pendingAgentItem `json:"agents"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Agents) != 1 {
+		t.Fatalf("agents = %+v, want one", resp.Agents)
+	}
+	if got := resp.Agents[0]; got.AgentID != "agent-1" || got.Fingerprint != "ab12" || got.Hostname != "build-box" || got.Repo != "git@example.com:org/repo.git" {
+		t.Errorf("agent = %+v, want agent-1 with fingerprint, hostname and repo", got)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handlePendingAgentsJSON(rec, httptest.NewRequest(http.MethodGet, "/api/admin/agents", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("without status = %d, want 400", rec.Code)
+	}
+}
+
+func TestAgentPending(t *testing.T) {
+	hooks := make(chan pendingAgentWebhook, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body pendingAgentWebhook
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode webhook: %v", err)
+		}
+		hooks <- body
+	}))
+	defer srv.Close()
+
+	admin, _ := newThreadOpsAdmin(t)
+	admin.config = Config{BaseURL: "https://coven.example.com/", PendingAgentWebhookURL: srv.URL}
+	admin.chatHub = newChatHub()
+	defer admin.chatHub.Close()
+	session := admin.chatHub.getOrCreateSession("other-agent", "user-1")
+
+	// A reconnect of an agent whose principal was already pending stays quiet.
+	admin.AgentPending(agent.PendingAgent{AgentID: "agent-1", Name: "builder", PrincipalID: "p-1"})
+	admin.AgentPending(agent.PendingAgent{
+		AgentID: "agent-2", Name: "builder", PrincipalID: "p-2", Fingerprint: "ab12", NewPrincipal: true,
+		Metadata: &agent.Metadata{Hostname: "build-box"},
+	})
+
+	select {
+	case msg := <-session.messages:
+		if msg.Type != "agent_pending" || msg.PendingAgent == nil || msg.PendingAgent.AgentID != "agent-2" {
+			t.Errorf("chat message = %+v, want agent_pending for agent-2", msg)
+		}
+		if msg.Content != "Agent builder is waiting for approval (build-box)" {
+			t.Errorf("content = %q", msg.Content)
+		}
+	default:
+		t.Fatal("no agent_pending message on the chat session")
+	}
+	if len(session.messages) != 0 {
+		t.Errorf("%d extra chat messages, want none for the already pending principal", len(session.messages))
+	}
+
+	select {
+	case body := <-hooks:
+		if body.Event != "agent_pending" || body.Agent.Fingerprint != "ab12" || body.ApproveURL != "https://coven.example.com/admin/principals" {
+			t.Errorf("webhook = %+v", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook not posted")
+	}
+	select {
+	case body := <-hooks:
+		t.Errorf("unexpected second webhook %+v", body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
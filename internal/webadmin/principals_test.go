@@ -0,0 +1,63 @@
+// ABOUTME: Tests for principal approval state surfaced to the admin UI.
+// ABOUTME: Covers waiting-agent annotations and pushing decisions to connected agents.
+
+package webadmin
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestPrincipalViews_MarksWaitingAgents(t *testing.T) {
+	manager := agent.NewManager(slog.Default())
+	if _, err := manager.AddPending(agent.PendingAgent{AgentID: "agent-1", PrincipalID: "p-waiting"}); err != nil {
+		t.Fatalf("AddPending: %v", err)
+	}
+
+	admin := &Admin{manager: manager, logger: slog.Default()}
+	views := admin.principalViews([]store.Principal{
+		{ID: "p-waiting", Status: store.PrincipalStatusPending},
+		{ID: "p-idle", Status: store.PrincipalStatusPending},
+	})
+
+	if len(views) != 2 {
+		t.Fatalf("expected 2 views, got %d", len(views))
+	}
+	if views[0].WaitingAgents != 1 {
+		t.Errorf("p-waiting WaitingAgents = %d, want 1", views[0].WaitingAgents)
+	}
+	if views[1].WaitingAgents != 0 {
+		t.Errorf("p-idle WaitingAgents = %d, want 0", views[1].WaitingAgents)
+	}
+}
+
+func TestPrincipalViews_NilManager(t *testing.T) {
+	admin := &Admin{logger: slog.Default()}
+	views := admin.principalViews(nil)
+	if views == nil || len(views) != 0 {
+		t.Errorf("expected empty non-nil views, got %#v", views)
+	}
+}
+
+func TestNotifyPendingAgents_DeliversDecision(t *testing.T) {
+	manager := agent.NewManager(slog.Default())
+	decisions, err := manager.AddPending(agent.PendingAgent{AgentID: "agent-1", PrincipalID: "p1"})
+	if err != nil {
+		t.Fatalf("AddPending: %v", err)
+	}
+
+	admin := &Admin{manager: manager, logger: slog.Default()}
+	admin.notifyPendingAgents("p1", agent.ApprovalRevoked)
+
+	select {
+	case d := <-decisions:
+		if d != agent.ApprovalRevoked {
+			t.Errorf("decision = %v, want revoked", d)
+		}
+	default:
+		t.Fatal("expected a decision to be delivered")
+	}
+}
@@ -0,0 +1,166 @@
+// ABOUTME: Admin handlers for listing and answering agents' ask_user questions
+// ABOUTME: Answers record the context version shown; chat streams replay unanswered questions
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/builtins"
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+// QuestionRouter lists pending ask_user questions and delivers answers to them.
+type QuestionRouter interface {
+	Pending() []builtins.PendingQuestion
+	ListPending(ctx context.Context, agentID string) ([]builtins.PendingQuestion, error)
+	Lookup(questionID string) (builtins.PendingQuestion, bool)
+	DeliverAnswer(agentID, questionID string, answer *pb.AnswerQuestionRequest) error
+	AnswerError(questionID string) error
+}
+
+// SetQuestionRouter wires the router that owns pending questions. It is set
+// after construction because the router streams questions through the Admin.
+func (a *Admin) SetQuestionRouter(r QuestionRouter) {
+	a.questions = r
+}
+
+// handleQuestionsJSON lists pending questions with their context snapshots.
+// Optional ?agent_id= filters by agent.
+func (a *Admin) handleQuestionsJSON(w http.ResponseWriter, r *http.Request) {
+	if a.questions == nil {
+		http.Error(w, "Questions not available", http.StatusServiceUnavailable)
+		return
+	}
+	agentID := r.URL.Query().Get("agent_id")
+	items := []builtins.PendingQuestion{}
+	for _, pq := range a.questions.Pending() {
+		if agentID == "" || pq.Request.GetAgentId() == agentID {
+			items = append(items, pq)
+		}
+	}
+	a.writeJSON(w, map[string]any{"questions": items})
+}
+
+// answerQuestionRequest is the body of POST /api/admin/questions/{id}/answer.
+type answerQuestionRequest struct {
+	Selected       []string `json:"selected"`
+	CustomText     string   `json:"custom_text,omitempty"`
+	ContextVersion string   `json:"context_version,omitempty"`
+}
+
+// handleAnswerQuestion delivers an answer to a pending question and audits it,
+// including the context version the answerer saw next to the current one.
+func (a *Admin) handleAnswerQuestion(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+	if a.questions == nil {
+		http.Error(w, "Questions not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req answerQuestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Selected) == 0 && req.CustomText == "" {
+		http.Error(w, "At least one selection or custom_text is required", http.StatusBadRequest)
+		return
+	}
+
+	questionID := r.PathValue("id")
+	pq, ok := a.questions.Lookup(questionID)
+	if !ok {
+		a.writeAnswerError(w, a.questions.AnswerError(questionID))
+		return
+	}
+	agentID := pq.Request.GetAgentId()
+
+	answer := &pb.AnswerQuestionRequest{AgentId: agentID, QuestionId: questionID, Selected: req.Selected}
+	if req.CustomText != "" {
+		answer.CustomText = &req.CustomText
+	}
+	if req.ContextVersion != "" {
+		answer.ContextVersion = &req.ContextVersion
+	}
+	if err := a.questions.DeliverAnswer(agentID, questionID, answer); err != nil {
+		a.writeAnswerError(w, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	a.auditAdminAction(r, &store.AuditEntry{
+		ActorPrincipalID: user.ID,
+		Action:           store.AuditAnswerQuestion,
+		TargetType:       "question",
+		TargetID:         questionID,
+		Detail: map[string]any{
+			"admin_user":           user.Username,
+			"agent_id":             agentID,
+			"context_version":      pq.ContextVersion(),
+			"seen_context_version": req.ContextVersion,
+		},
+	})
+
+	a.writeJSON(w, map[string]any{
+		"success":         true,
+		"context_version": req.ContextVersion,
+	})
+}
+
+// writeAnswerError reports why an answer was refused: 404 for an unknown
+// question, 409 for one already answered or expired, and 422 with the
+// violations as JSON for one that breaks the question's constraints.
+func (a *Admin) writeAnswerError(w http.ResponseWriter, err error) {
+	var invalid *builtins.InvalidAnswerError
+	switch {
+	case errors.As(err, &invalid):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		if err := json.NewEncoder(w).Encode(invalid); err != nil {
+			a.logger.Error("failed to encode JSON response", "error", err)
+		}
+	case errors.Is(err, builtins.ErrQuestionAnswered):
+		http.Error(w, "Question already answered", http.StatusConflict)
+	case errors.Is(err, builtins.ErrQuestionExpired):
+		http.Error(w, "Question expired before it was answered", http.StatusConflict)
+	case errors.Is(err, builtins.ErrQuestionNotFound):
+		http.Error(w, "Question not found", http.StatusNotFound)
+	default:
+		a.logger.Error("failed to deliver question answer", "error", err)
+		http.Error(w, "Failed to deliver answer", http.StatusInternalServerError)
+	}
+}
+
+// replayPendingQuestions sends a newly connected chat client the agent's
+// unanswered questions, each with the time left to answer it. Questions
+// asked while nobody was connected reach the user this way.
+func (a *Admin) replayPendingQuestions(r *http.Request, ctx *chatStreamContext, agentID string) {
+	if a.questions == nil {
+		return
+	}
+	pending, err := a.questions.ListPending(r.Context(), agentID)
+	if err != nil {
+		a.logger.Warn("failed to list pending questions for replay", "agent_id", agentID, "error", err)
+		return
+	}
+	now := time.Now()
+	for _, pq := range pending {
+		remaining := int32(pq.ExpiresAt.Sub(now) / time.Second)
+		if remaining <= 0 {
+			continue
+		}
+		msg := userQuestionMessage(pq.Request)
+		msg.Timestamp = pq.AskedAt
+		msg.TimeoutSeconds = remaining
+		ctx.sendSessionMessage(msg)
+	}
+}
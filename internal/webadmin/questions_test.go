@@ -0,0 +1,267 @@
+// ABOUTME: Tests for the pending questions listing and answer endpoints.
+// ABOUTME: Uses a real question router, with a store where replay and refusals are tested.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/builtins"
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+type discardStreamer struct{}
+
+func (discardStreamer) SendUserQuestion(string, *pb.UserQuestionRequest) error { return nil }
+
+func askTestQuestion(t *testing.T, router *builtins.InMemoryQuestionRouter) <-chan *pb.AnswerQuestionRequest {
+	t.Helper()
+	snap := &builtins.QuestionContext{ThreadID: "thread-1", ToolCalls: []builtins.ToolCallSummary{{Name: "bash"}}}
+	body, err := snap.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize: %v", err)
+	}
+	answers, err := router.SendQuestion(context.Background(), "agent-1", &pb.UserQuestionRequest{
+		AgentId:     "agent-1",
+		QuestionId:  "q-1",
+		Question:    "Force-push to main?",
+		Options:     []*pb.QuestionOption{{Label: "Yes"}, {Label: "No"}},
+		ContextJson: &body,
+	})
+	if err != nil {
+		t.Fatalf("SendQuestion: %v", err)
+	}
+	return answers
+}
+
+func TestHandleQuestionsJSON_IncludesContext(t *testing.T) {
+	admin, _ := newThreadOpsAdmin(t)
+	router := builtins.NewInMemoryQuestionRouter(discardStreamer{})
+	admin.SetQuestionRouter(router)
+	askTestQuestion(t, router)
+
+	rec := httptest.NewRecorder()
+	admin.handleQuestionsJSON(rec, requestWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/questions?agent_id=agent-1", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Questions []struct {
+			QuestionID string                    `json:"question_id"`
+			AskedAt    string                    `json:"asked_at"`
+			Context    *builtins.QuestionContext `json:"context"`
+		} `json:"questions"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Questions) != 1 || resp.Questions[0].QuestionID != "q-1" {
+		t.Fatalf("questions = %+v, want q-1", resp.Questions)
+	}
+	q := resp.Questions[0]
+	if q.Context == nil || q.Context.ThreadID != "thread-1" || q.Context.Version == "" {
+		t.Errorf("context = %+v, want thread-1 with a version", q.Context)
+	}
+	if _, err := time.Parse(time.RFC3339, q.AskedAt); err != nil {
+		t.Errorf("asked_at = %q, want RFC3339", q.AskedAt)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleQuestionsJSON(rec, requestWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/questions?agent_id=other", nil)))
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || len(resp.Questions) != 0 {
+		t.Errorf("filtered questions = %+v (err %v), want none", resp.Questions, err)
+	}
+}
+
+func TestHandleAnswerQuestion_RecordsSeenContextVersion(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	router := builtins.NewInMemoryQuestionRouter(discardStreamer{})
+	admin.SetQuestionRouter(router)
+	answers := askTestQuestion(t, router)
+	current := router.Pending()[0].ContextVersion()
+
+	req := csrfJSONRequest(http.MethodPost, "/api/admin/questions/q-1/answer", `{"selected":["No"],"context_version":"stale-v"}`)
+	req.SetPathValue("id", "q-1")
+	rec := httptest.NewRecorder()
+	admin.handleAnswerQuestion(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	select {
+	case answer := <-answers:
+		if answer.GetContextVersion() != "stale-v" || answer.GetSelected()[0] != "No" {
+			t.Errorf("answer = %+v, want No with seen version stale-v", answer)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("answer not delivered")
+	}
+
+	action := store.AuditAnswerQuestion
+	entries, err := s.ListAuditLog(context.Background(), store.AuditFilter{Action: &action})
+	if err != nil {
+		t.Fatalf("ListAuditLog: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %d, want 1", len(entries))
+	}
+	if entries[0].Detail["seen_context_version"] != "stale-v" || entries[0].Detail["context_version"] != current {
+		t.Errorf("audit detail = %+v, want seen stale-v and current %s", entries[0].Detail, current)
+	}
+
+	req = csrfJSONRequest(http.MethodPost, "/api/admin/questions/q-1/answer", `{"selected":["No"]}`)
+	req.SetPathValue("id", "q-1")
+	rec = httptest.NewRecorder()
+	admin.handleAnswerQuestion(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("second answer status = %d, want 404", rec.Code)
+	}
+}
+
+func TestHandleAnswerQuestion_Guards(t *testing.T) {
+	admin, _ := newThreadOpsAdmin(t)
+
+	rec := httptest.NewRecorder()
+	admin.handleAnswerQuestion(rec, requestWithUser(httptest.NewRequest(http.MethodPost, "/api/admin/questions/q-1/answer", nil)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("without CSRF status = %d, want 403", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleAnswerQuestion(rec, csrfJSONRequest(http.MethodPost, "/api/admin/questions/q-1/answer", `{"selected":["Yes"]}`))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("without router status = %d, want 503", rec.Code)
+	}
+
+	admin.SetQuestionRouter(builtins.NewInMemoryQuestionRouter(discardStreamer{}))
+	rec = httptest.NewRecorder()
+	admin.handleAnswerQuestion(rec, csrfJSONRequest(http.MethodPost, "/api/admin/questions/q-1/answer", `{}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("empty answer status = %d, want 400", rec.Code)
+	}
+}
+
+func TestReplayPendingQuestions(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	router := builtins.NewInMemoryQuestionRouter(admin) // no chat hub: every send fails
+	router.SetStore(s)
+	admin.SetQuestionRouter(router)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, agentID := range []string{"agent-1", "agent-2"} {
+		if _, err := router.SendQuestion(ctx, agentID, &pb.UserQuestionRequest{
+			AgentId: agentID, QuestionId: "q-" + agentID, Question: "Deploy now?",
+			Options: []*pb.QuestionOption{{Label: "Yes"}}, TimeoutSeconds: 120,
+		}); err != nil {
+			t.Fatalf("SendQuestion: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	sc := &chatStreamContext{w: rec, flusher: rec, seenQuestions: make(map[string]struct{}), logger: admin.logger}
+	req := httptest.NewRequest(http.MethodGet, "/chat/agent-1/stream", nil)
+	admin.replayPendingQuestions(req, sc, "agent-1")
+
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "event: user_question\n") || !strings.Contains(body, `"question_id":"q-agent-1"`) {
+		t.Fatalf("body = %q, want agent-1's question", body)
+	}
+	if strings.Contains(body, "q-agent-2") {
+		t.Errorf("replayed another agent's question: %q", body)
+	}
+	if !strings.Contains(body, `"timeout_seconds":119`) && !strings.Contains(body, `"timeout_seconds":120`) {
+		t.Errorf("body = %q, want the remaining timeout", body)
+	}
+
+	// The same question arriving live is not shown twice.
+	before := rec.Body.Len()
+	sc.sendSessionMessage(&chatMessage{Type: "user_question", QuestionID: "q-agent-1"})
+	if rec.Body.Len() != before {
+		t.Error("replayed question was sent again")
+	}
+}
+
+func TestHandleAnswerQuestion_ExplainsRefusal(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	router := builtins.NewInMemoryQuestionRouter(discardStreamer{})
+	router.SetStore(s)
+	admin.SetQuestionRouter(router)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, id := range []string{"q-answered", "q-expired"} {
+		if _, err := router.SendQuestion(ctx, "agent-1", &pb.UserQuestionRequest{
+			AgentId: "agent-1", QuestionId: id, Question: "Deploy now?",
+			Options: []*pb.QuestionOption{{Label: "Yes"}}, TimeoutSeconds: 60,
+		}); err != nil {
+			t.Fatalf("SendQuestion: %v", err)
+		}
+	}
+	if err := router.DeliverAnswer("agent-1", "q-answered", &pb.AnswerQuestionRequest{Selected: []string{"Yes"}}); err != nil {
+		t.Fatalf("DeliverAnswer: %v", err)
+	}
+	cancel() // the agent stops waiting for q-expired
+
+	answer := func(id string) *httptest.ResponseRecorder {
+		req := csrfJSONRequest(http.MethodPost, "/api/admin/questions/"+id+"/answer", `{"selected":["Yes"]}`)
+		req.SetPathValue("id", id)
+		rec := httptest.NewRecorder()
+		admin.handleAnswerQuestion(rec, req)
+		return rec
+	}
+
+	if rec := answer("q-answered"); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "already answered") {
+		t.Errorf("answered: %d %q, want 409 already answered", rec.Code, rec.Body.String())
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if q, err := s.GetQuestion(context.Background(), "q-expired"); err == nil && q.Status == store.QuestionExpired {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("q-expired was not marked expired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rec := answer("q-expired"); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "expired") {
+		t.Errorf("expired: %d %q, want 409 expired", rec.Code, rec.Body.String())
+	}
+	if rec := answer("q-missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing: status %d, want 404", rec.Code)
+	}
+}
+
+func TestHandleAnswerQuestion_InvalidAnswer(t *testing.T) {
+	admin, _ := newThreadOpsAdmin(t)
+	router := builtins.NewInMemoryQuestionRouter(discardStreamer{})
+	admin.SetQuestionRouter(router)
+	askTestQuestion(t, router)
+
+	req := csrfJSONRequest(http.MethodPost, "/api/admin/questions/q-1/answer", `{"selected":["Maybe"]}`)
+	req.SetPathValue("id", "q-1")
+	rec := httptest.NewRecorder()
+	admin.handleAnswerQuestion(rec, req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Violations []builtins.AnswerViolation `json:"violations"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Violations) != 1 || resp.Violations[0].Field != "selected" {
+		t.Errorf("violations = %+v, want one for selected", resp.Violations)
+	}
+	if _, ok := router.Lookup("q-1"); !ok {
+		t.Error("question no longer pending after an invalid answer")
+	}
+}
@@ -0,0 +1,104 @@
+// ABOUTME: Admin API for per-tool and per-agent reliability signals
+// ABOUTME: Serves success rates and failure streaks and annotates tool/agent list items
+
+package webadmin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/reliability"
+)
+
+// reliabilityItem is one tool's or agent's reliability as shown in the admin UI.
+type reliabilityItem struct {
+	Kind                string  `json:"kind"`
+	Name                string  `json:"name"`
+	Samples             int     `json:"samples"`
+	SuccessRate         float64 `json:"successRate"`
+	FailureStreak       int     `json:"failureStreak"`
+	SecondsSinceSuccess float64 `json:"secondsSinceSuccess"` // -1 if never succeeded
+	BelowThreshold      bool    `json:"belowThreshold"`
+}
+
+// reliabilityResponse is the body of GET /api/admin/reliability.
+type reliabilityResponse struct {
+	Window     int               `json:"window"`
+	WindowSize int               `json:"windowSize"`
+	MinSamples int               `json:"minSamples"`
+	Threshold  float64           `json:"threshold"`
+	Stats      []reliabilityItem `json:"stats"`
+}
+
+func newReliabilityItem(s reliability.Stat, now time.Time) reliabilityItem {
+	since := -1.0
+	if d := s.SinceLastSuccess(now); d >= 0 {
+		since = d.Seconds()
+	}
+	return reliabilityItem{
+		Kind:                string(s.Kind),
+		Name:                s.Name,
+		Samples:             s.Samples,
+		SuccessRate:         s.SuccessRate,
+		FailureStreak:       s.FailureStreak,
+		SecondsSinceSuccess: since,
+		BelowThreshold:      s.BelowThreshold,
+	}
+}
+
+// reliabilityFor returns the full-window reliability of one tool or agent, or
+// nil if tracking is disabled or nothing has been recorded for it yet.
+func (a *Admin) reliabilityFor(kind reliability.Kind, name string) *reliabilityItem {
+	if a.reliability == nil {
+		return nil
+	}
+	s, ok := a.reliability.Stat(kind, name)
+	if !ok {
+		return nil
+	}
+	item := newReliabilityItem(s, time.Now())
+	return &item
+}
+
+// handleReliabilityJSON returns reliability stats for tracked tools and agents.
+// Query params: kind (tool|agent, default both) and window (number of most
+// recent outcomes to evaluate, default and maximum the configured window size).
+func (a *Admin) handleReliabilityJSON(w http.ResponseWriter, r *http.Request) {
+	if a.reliability == nil {
+		http.Error(w, "Reliability tracking not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	kind := reliability.Kind(r.URL.Query().Get("kind"))
+	if kind != "" && kind != reliability.KindTool && kind != reliability.KindAgent {
+		http.Error(w, "kind must be tool or agent", http.StatusBadRequest)
+		return
+	}
+
+	cfg := a.reliability.Config()
+	window := cfg.WindowSize
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			http.Error(w, "window must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		window = min(n, cfg.WindowSize)
+	}
+
+	now := time.Now()
+	stats := a.reliability.Stats(kind, window)
+	items := make([]reliabilityItem, 0, len(stats))
+	for _, s := range stats {
+		items = append(items, newReliabilityItem(s, now))
+	}
+
+	a.writeJSON(w, reliabilityResponse{
+		Window:     window,
+		WindowSize: cfg.WindowSize,
+		MinSamples: cfg.MinSamples,
+		Threshold:  cfg.Threshold,
+		Stats:      items,
+	})
+}
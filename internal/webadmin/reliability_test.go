@@ -0,0 +1,76 @@
+// ABOUTME: Tests for the reliability admin API.
+// ABOUTME: Covers window/kind query handling and threshold flags in the JSON response.
+
+package webadmin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/reliability"
+)
+
+func getReliability(t *testing.T, admin *Admin, query string) (int, reliabilityResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/reliability"+query, nil)
+	rec := httptest.NewRecorder()
+	admin.handleReliabilityJSON(rec, requestWithUser(req))
+
+	var resp reliabilityResponse
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func TestHandleReliabilityJSON(t *testing.T) {
+	admin, _ := newThreadOpsAdmin(t)
+	if code, _ := getReliability(t, admin, ""); code != http.StatusServiceUnavailable {
+		t.Errorf("without tracker status = %d, want 503", code)
+	}
+
+	admin.reliability = reliability.New(reliability.Config{WindowSize: 10, MinSamples: 3, Threshold: 0.9})
+	for _, ok := range []bool{true, true, true, true, false, false} {
+		admin.reliability.RecordTool("search", ok)
+	}
+	admin.reliability.RecordAgent("agent-1", true)
+
+	code, resp := getReliability(t, admin, "?kind=tool")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if len(resp.Stats) != 1 || resp.Stats[0].Name != "search" {
+		t.Fatalf("stats = %+v, want only search", resp.Stats)
+	}
+	s := resp.Stats[0]
+	if s.Samples != 6 || s.FailureStreak != 2 || !s.BelowThreshold {
+		t.Errorf("search = %+v, want 6 samples, streak 2, below threshold", s)
+	}
+	if resp.Window != 10 || resp.Threshold != 0.9 {
+		t.Errorf("window/threshold = %d/%v, want 10/0.9", resp.Window, resp.Threshold)
+	}
+
+	_, resp = getReliability(t, admin, "?kind=tool&window=2")
+	if s := resp.Stats[0]; s.Samples != 2 || s.SuccessRate != 0 {
+		t.Errorf("window=2: %+v, want 2 samples at rate 0", s)
+	}
+
+	_, resp = getReliability(t, admin, "?window=500")
+	if resp.Window != 10 || len(resp.Stats) != 2 {
+		t.Errorf("window=500: window %d, %d stats; want clamped to 10 with both entities", resp.Window, len(resp.Stats))
+	}
+
+	for _, q := range []string{"?window=0", "?window=abc", "?kind=pack"} {
+		if code, _ := getReliability(t, admin, q); code != http.StatusBadRequest {
+			t.Errorf("%s status = %d, want 400", q, code)
+		}
+	}
+
+	if item := admin.reliabilityFor(reliability.KindAgent, "agent-1"); item == nil || item.BelowThreshold {
+		t.Errorf("agent-1 reliability = %+v, want tracked and healthy", item)
+	}
+}
@@ -0,0 +1,77 @@
+// ABOUTME: Message search for the threads page: GET /api/admin/search over conversation text
+// ABOUTME: Wraps Store.SearchMessages; results link to their thread with the match highlighted
+
+package webadmin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// searchResultItem is one message search match as shown to the web admin.
+type searchResultItem struct {
+	EventID   string `json:"eventId"`
+	ThreadID  string `json:"threadId,omitempty"`
+	AgentID   string `json:"agentId"`
+	Author    string `json:"author"`
+	Type      string `json:"type"`
+	Timestamp string `json:"timestamp"`
+	Snippet   string `json:"snippet"` // HTML-escaped, matches wrapped in <mark>
+}
+
+// handleSearchJSON handles GET /api/admin/search?q=&agent_id=&thread_id=&since=&until=&limit=.
+func (a *Admin) handleSearchJSON(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := strings.TrimSpace(q.Get("q"))
+	if query == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	filter := store.SearchFilter{AgentID: q.Get("agent_id"), ThreadID: q.Get("thread_id")}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+	var err error
+	if filter.Since, err = timeparse.Query(q, "since"); err != nil {
+		a.writeTimestampError(w, err)
+		return
+	}
+	if filter.Until, err = timeparse.Query(q, "until"); err != nil {
+		a.writeTimestampError(w, err)
+		return
+	}
+
+	results, err := a.store.SearchMessages(r.Context(), query, filter)
+	if errors.Is(err, store.ErrSearchUnavailable) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		a.logger.Error("failed to search messages", "error", err)
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+	items := make([]searchResultItem, len(results))
+	for i, res := range results {
+		items[i] = searchResultItem{
+			EventID:   res.EventID,
+			ThreadID:  res.ThreadID,
+			AgentID:   res.AgentID,
+			Author:    res.Author,
+			Type:      string(res.Type),
+			Timestamp: timeparse.Format(res.Timestamp),
+			Snippet:   res.Snippet,
+		}
+	}
+	a.writeJSON(w, map[string]any{"query": query, "results": items})
+}
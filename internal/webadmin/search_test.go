@@ -0,0 +1,51 @@
+// ABOUTME: Tests for the admin message search endpoint.
+// ABOUTME: Covers matches linking back to threads and query validation.
+
+package webadmin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleSearchJSON(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	seedAdminThread(t, s, "t1", "deploy-staging", "lunch")
+	seedAdminThread(t, s, "t2", "deploy-prod")
+
+	rec := httptest.NewRecorder()
+	admin.handleSearchJSON(rec, requestWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/search?q=deploy&thread_id=t1", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Query   string             `json:"query"`
+		Results []searchResultItem `json:"results"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].EventID != "deploy-staging" || resp.Results[0].ThreadID != "t1" {
+		t.Fatalf("results = %+v, want deploy-staging in t1", resp.Results)
+	}
+	if resp.Results[0].Snippet != "<mark>deploy</mark>-staging" {
+		t.Errorf("snippet = %q", resp.Results[0].Snippet)
+	}
+}
+
+func TestHandleSearchJSON_BadRequests(t *testing.T) {
+	admin, _ := newThreadOpsAdmin(t)
+	for _, path := range []string{
+		"/api/admin/search",
+		"/api/admin/search?q=x&since=yesterday",
+		"/api/admin/search?q=x&limit=0",
+	} {
+		rec := httptest.NewRecorder()
+		admin.handleSearchJSON(rec, requestWithUser(httptest.NewRequest(http.MethodGet, path, nil)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// ABOUTME: Admin API for listing and revoking web admin sessions from the settings page
+// ABOUTME: Sessions are named by a hash of their ID, since the ID itself is the session cookie
+
+package webadmin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// sessionTouchInterval is how stale a session's last_seen_at may get before
+// an authenticated request updates it, so busy pages don't write on every
+// request.
+const sessionTouchInterval = time.Minute
+
+// maxUserAgentLength bounds the user agent stored with a session.
+const maxUserAgentLength = 512
+
+// sessionItem is one admin session as shown in the settings panel.
+type sessionItem struct {
+	ID          string `json:"id"` // sessionHandle, never the session ID
+	UserID      string `json:"userId"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	CreatedAt   string `json:"createdAt"`
+	ExpiresAt   string `json:"expiresAt"`
+	LastSeenAt  string `json:"lastSeenAt"`
+	UserAgent   string `json:"userAgent,omitempty"`
+	IP          string `json:"ip,omitempty"`
+	Current     bool   `json:"current"` // the session making this request
+}
+
+// sessionsPanel is the session section of the settings page.
+type sessionsPanel struct {
+	Sessions  []sessionItem `json:"sessions"`
+	ManageAll bool          `json:"manageAll"` // owner: sees and revokes every user's sessions
+}
+
+// revokeOtherSessionsRequest is the optional body of
+// POST /api/admin/sessions/revoke-others.
+type revokeOtherSessionsRequest struct {
+	UserID string `json:"userId"` // empty means the current user
+}
+
+// sessionHandle is the public name of a session. The session ID is the
+// cookie value, so it is never sent to the browser.
+func sessionHandle(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:16])
+}
+
+// truncateUserAgent bounds a client-supplied user agent for storage.
+func truncateUserAgent(ua string) string {
+	if len(ua) > maxUserAgentLength {
+		return ua[:maxUserAgentLength]
+	}
+	return ua
+}
+
+// getSessionFromContext retrieves the authenticated session from the request context.
+func getSessionFromContext(r *http.Request) *store.AdminSession {
+	session, _ := r.Context().Value(sessionContextKey).(*store.AdminSession)
+	return session
+}
+
+// touchSession records that session was used, at most once per
+// sessionTouchInterval. Failures are logged, not surfaced.
+func (a *Admin) touchSession(ctx context.Context, session *store.AdminSession) {
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) < sessionTouchInterval {
+		return
+	}
+	if err := a.store.TouchAdminSession(ctx, session.ID, now); err != nil {
+		a.logger.Warn("failed to update admin session last seen", "user_id", session.UserID, "error", err)
+		return
+	}
+	session.LastSeenAt = now
+}
+
+// visibleSessions lists the sessions user may manage: every session for
+// owners, as with API tokens, and otherwise their own.
+func (a *Admin) visibleSessions(ctx context.Context, user *store.AdminUser) ([]*store.AdminSession, bool, error) {
+	manageAll := a.tokenAccessFor(ctx, user).all
+	listFor := user.ID
+	if manageAll {
+		listFor = ""
+	}
+	sessions, err := a.store.ListAdminSessions(ctx, listFor)
+	if err != nil {
+		return nil, false, fmt.Errorf("listing sessions: %w", err)
+	}
+	return sessions, manageAll, nil
+}
+
+// buildSessionsPanel lists the sessions visible to user, marking current.
+func (a *Admin) buildSessionsPanel(ctx context.Context, user *store.AdminUser, current *store.AdminSession) (*sessionsPanel, error) {
+	sessions, manageAll, err := a.visibleSessions(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	users := map[string]*store.AdminUser{user.ID: user}
+	if manageAll {
+		all, err := a.store.ListAdminUsers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing admin users: %w", err)
+		}
+		for _, u := range all {
+			users[u.ID] = u
+		}
+	}
+
+	panel := &sessionsPanel{Sessions: []sessionItem{}, ManageAll: manageAll}
+	for _, s := range sessions {
+		item := sessionItem{
+			ID:         sessionHandle(s.ID),
+			UserID:     s.UserID,
+			CreatedAt:  timeparse.Format(s.CreatedAt),
+			ExpiresAt:  timeparse.Format(s.ExpiresAt),
+			LastSeenAt: timeparse.Format(s.LastSeenAt),
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			Current:    current != nil && s.ID == current.ID,
+		}
+		if u := users[s.UserID]; u != nil {
+			item.Username = u.Username
+			item.DisplayName = u.DisplayName
+		}
+		panel.Sessions = append(panel.Sessions, item)
+	}
+	return panel, nil
+}
+
+// handleSessionsJSON returns the sessions panel for the current admin.
+func (a *Admin) handleSessionsJSON(w http.ResponseWriter, r *http.Request) {
+	panel, err := a.buildSessionsPanel(r.Context(), getUserFromContext(r), getSessionFromContext(r))
+	if err != nil {
+		a.logger.Error("failed to build sessions panel", "error", err)
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+	a.writeJSON(w, panel)
+}
+
+// handleRevokeSession signs out one session the admin may manage, named by
+// its handle.
+func (a *Admin) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	user := getUserFromContext(r)
+	handle := r.PathValue("id")
+
+	sessions, _, err := a.visibleSessions(r.Context(), user)
+	if err != nil {
+		a.logger.Error("failed to list sessions", "error", err)
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+	var target *store.AdminSession
+	for _, s := range sessions {
+		if sessionHandle(s.ID) == handle {
+			target = s
+			break
+		}
+	}
+	if target == nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	if err := a.store.DeleteAdminSession(r.Context(), target.ID); err != nil {
+		a.logger.Error("failed to revoke session", "user_id", target.UserID, "error", err)
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+	a.auditAdminAction(r, newAuditEntry(r, store.AuditRevokeSession, "admin_user", target.UserID, map[string]any{
+		"session": handle,
+		"ip":      target.IP,
+	}))
+	a.logger.Info("admin session revoked", "user_id", target.UserID, "session", handle, "by", user.Username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRevokeOtherSessions signs a user out everywhere: it deletes all of
+// their sessions except the one making the request. Owners may name another
+// user; everyone else signs out their own other sessions.
+func (a *Admin) handleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	user := getUserFromContext(r)
+	current := getSessionFromContext(r)
+
+	var req revokeOtherSessionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	targetID := req.UserID
+	if targetID == "" {
+		targetID = user.ID
+	}
+	if targetID != user.ID {
+		if !a.tokenAccessFor(r.Context(), user).all {
+			http.Error(w, "Only owners can sign out other users", http.StatusForbidden)
+			return
+		}
+		if _, err := a.store.GetAdminUser(r.Context(), targetID); err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	keep := ""
+	if current != nil && current.UserID == targetID {
+		keep = current.ID
+	}
+	n, err := a.store.DeleteAdminSessionsForUser(r.Context(), targetID, keep)
+	if err != nil {
+		a.logger.Error("failed to revoke sessions", "user_id", targetID, "error", err)
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+	a.auditAdminAction(r, newAuditEntry(r, store.AuditRevokeSession, "admin_user", targetID, map[string]any{
+		"sessions":  n,
+		"all_other": true,
+	}))
+	a.logger.Info("admin sessions revoked", "user_id", targetID, "count", n, "by", user.Username)
+	a.writeJSON(w, map[string]int64{"revoked": n})
+}
@@ -0,0 +1,227 @@
+// ABOUTME: Tests for the settings page session endpoints and session bookkeeping in requireAuth.
+// ABOUTME: Covers last-seen throttling, client details, hashed session names, revocation and sign-out-everywhere.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func seedAdminSession(t *testing.T, s *store.SQLStore, id, userID string, lastSeen time.Time) *store.AdminSession {
+	t.Helper()
+	session := &store.AdminSession{
+		ID: id, UserID: userID, CreatedAt: lastSeen, ExpiresAt: time.Now().Add(time.Hour),
+		LastSeenAt: lastSeen, UserAgent: "test-agent/" + id, IP: "192.0.2.1",
+	}
+	if err := s.CreateAdminSession(context.Background(), session); err != nil {
+		t.Fatalf("CreateAdminSession: %v", err)
+	}
+	return session
+}
+
+func sessionRequestAs(user *store.AdminUser, session *store.AdminSession, method, path, body string) *http.Request {
+	req := tokenRequestAs(user, method, path, body)
+	return req.WithContext(context.WithValue(req.Context(), sessionContextKey, session))
+}
+
+func sessionIDs(t *testing.T, s *store.SQLStore, userID string) map[string]bool {
+	t.Helper()
+	sessions, err := s.ListAdminSessions(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("ListAdminSessions: %v", err)
+	}
+	ids := map[string]bool{}
+	for _, session := range sessions {
+		ids[session.ID] = true
+	}
+	return ids
+}
+
+func TestRequireAuth_TouchesLastSeenAtMostOncePerMinute(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	user := seedTokenAdmin(t, s, "alice", "", "")
+	seedAdminSession(t, s, "sess-1", user.ID, time.Now().Add(-5*time.Minute))
+
+	var seen *store.AdminSession
+	handler := admin.requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		seen = getSessionFromContext(r)
+	})
+	request := func() {
+		req := httptest.NewRequest(http.MethodGet, "/admin/settings", nil)
+		req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: "sess-1"})
+		handler(httptest.NewRecorder(), req)
+	}
+	lastSeen := func() time.Time {
+		session, err := s.GetAdminSession(context.Background(), "sess-1")
+		if err != nil {
+			t.Fatalf("GetAdminSession: %v", err)
+		}
+		return session.LastSeenAt
+	}
+
+	request()
+	if seen == nil || seen.ID != "sess-1" {
+		t.Fatalf("session in context = %+v, want sess-1", seen)
+	}
+	if since := time.Since(lastSeen()); since > 5*time.Second {
+		t.Errorf("stale session not touched: last seen %s ago", since)
+	}
+
+	recent := time.Now().Add(-30 * time.Second).Truncate(time.Second)
+	if err := s.TouchAdminSession(context.Background(), "sess-1", recent); err != nil {
+		t.Fatalf("TouchAdminSession: %v", err)
+	}
+	request()
+	if got := lastSeen(); !got.Equal(recent) {
+		t.Errorf("last seen = %s, want it left at %s within a minute", got, recent)
+	}
+}
+
+func TestCreateSession_RecordsClientDetails(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	user := seedTokenAdmin(t, s, "alice", "", "")
+
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (test)")
+	req.RemoteAddr = "203.0.113.7:51234"
+	if err := admin.createSession(httptest.NewRecorder(), req, user.ID); err != nil {
+		t.Fatalf("createSession: %v", err)
+	}
+
+	sessions, err := s.ListAdminSessions(context.Background(), user.ID)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("sessions = %v, err = %v", sessions, err)
+	}
+	if sessions[0].UserAgent != "Mozilla/5.0 (test)" || sessions[0].IP != "203.0.113.7" {
+		t.Errorf("session client = %q from %q", sessions[0].UserAgent, sessions[0].IP)
+	}
+}
+
+func TestHandleSessionsJSON_OwnSessionsByHandle(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	alice := seedTokenAdmin(t, s, "alice", "", "")
+	bob := seedTokenAdmin(t, s, "bob", "", "")
+	current := seedAdminSession(t, s, "alice-1", alice.ID, time.Now())
+	seedAdminSession(t, s, "alice-2", alice.ID, time.Now().Add(-time.Hour))
+	seedAdminSession(t, s, "bob-1", bob.ID, time.Now())
+
+	rec := httptest.NewRecorder()
+	admin.handleSessionsJSON(rec, sessionRequestAs(alice, current, http.MethodGet, "/api/admin/sessions", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var panel sessionsPanel
+	if err := json.NewDecoder(rec.Body).Decode(&panel); err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+	if panel.ManageAll || len(panel.Sessions) != 2 {
+		t.Fatalf("panel = %+v, want alice's two sessions", panel)
+	}
+	first := panel.Sessions[0]
+	if first.ID != sessionHandle("alice-1") || !first.Current || first.Username != "alice" || first.UserAgent != "test-agent/alice-1" {
+		t.Errorf("first session = %+v, want the current alice-1 by handle", first)
+	}
+	if panel.Sessions[1].Current {
+		t.Error("alice-2 marked current")
+	}
+}
+
+func TestHandleSessionsJSON_OwnerSeesEveryone(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	owner := seedTokenAdmin(t, s, "olive", "p-olive", store.RoleOwner)
+	bob := seedTokenAdmin(t, s, "bob", "", "")
+	current := seedAdminSession(t, s, "olive-1", owner.ID, time.Now())
+	seedAdminSession(t, s, "bob-1", bob.ID, time.Now())
+
+	panel, err := admin.buildSessionsPanel(context.Background(), owner, current)
+	if err != nil {
+		t.Fatalf("buildSessionsPanel: %v", err)
+	}
+	if !panel.ManageAll || len(panel.Sessions) != 2 {
+		t.Fatalf("panel = %+v, want both users' sessions", panel)
+	}
+}
+
+func TestHandleRevokeSession(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	alice := seedTokenAdmin(t, s, "alice", "", "")
+	bob := seedTokenAdmin(t, s, "bob", "", "")
+	current := seedAdminSession(t, s, "alice-1", alice.ID, time.Now())
+	seedAdminSession(t, s, "alice-2", alice.ID, time.Now())
+	seedAdminSession(t, s, "bob-1", bob.ID, time.Now())
+
+	revoke := func(handle string) int {
+		req := sessionRequestAs(alice, current, http.MethodDelete, "/api/admin/sessions/"+handle, "")
+		req.SetPathValue("id", handle)
+		rec := httptest.NewRecorder()
+		admin.handleRevokeSession(rec, req)
+		return rec.Code
+	}
+
+	if code := revoke(sessionHandle("bob-1")); code != http.StatusNotFound {
+		t.Errorf("revoking another user's session: status = %d, want 404", code)
+	}
+	if code := revoke("alice-2"); code != http.StatusNotFound {
+		t.Errorf("revoking by raw session ID: status = %d, want 404", code)
+	}
+	if code := revoke(sessionHandle("alice-2")); code != http.StatusNoContent {
+		t.Fatalf("revoking own session: status = %d, want 204", code)
+	}
+
+	if ids := sessionIDs(t, s, ""); ids["alice-2"] || !ids["alice-1"] || !ids["bob-1"] {
+		t.Errorf("sessions left = %v, want alice-1 and bob-1", ids)
+	}
+	action := store.AuditRevokeSession
+	entries, err := s.ListAuditLog(context.Background(), store.AuditFilter{Action: &action})
+	if err != nil || len(entries) != 1 || entries[0].TargetID != alice.ID {
+		t.Errorf("audit entries = %+v, err = %v, want one revoke_session for alice", entries, err)
+	}
+}
+
+func TestHandleRevokeOtherSessions(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	alice := seedTokenAdmin(t, s, "alice", "", "")
+	owner := seedTokenAdmin(t, s, "olive", "p-olive", store.RoleOwner)
+	current := seedAdminSession(t, s, "alice-1", alice.ID, time.Now())
+	seedAdminSession(t, s, "alice-2", alice.ID, time.Now())
+	seedAdminSession(t, s, "alice-3", alice.ID, time.Now())
+	ownerSession := seedAdminSession(t, s, "olive-1", owner.ID, time.Now())
+
+	rec := httptest.NewRecorder()
+	admin.handleRevokeOtherSessions(rec, sessionRequestAs(alice, current, http.MethodPost, "/api/admin/sessions/revoke-others", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp map[string]int64
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp["revoked"] != 2 {
+		t.Errorf("response = %v, err = %v, want 2 revoked", resp, err)
+	}
+	if ids := sessionIDs(t, s, alice.ID); len(ids) != 1 || !ids["alice-1"] {
+		t.Errorf("alice's sessions = %v, want only the current one", ids)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleRevokeOtherSessions(rec, sessionRequestAs(alice, current, http.MethodPost, "/api/admin/sessions/revoke-others", `{"userId":"`+owner.ID+`"}`))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("non-owner signing out another user: status = %d, want 403", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleRevokeOtherSessions(rec, sessionRequestAs(owner, ownerSession, http.MethodPost, "/api/admin/sessions/revoke-others", `{"userId":"`+alice.ID+`"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("owner signing out alice: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if ids := sessionIDs(t, s, alice.ID); len(ids) != 0 {
+		t.Errorf("alice's sessions = %v, want none", ids)
+	}
+	if ids := sessionIDs(t, s, owner.ID); !ids["olive-1"] {
+		t.Error("owner's own session was revoked")
+	}
+}
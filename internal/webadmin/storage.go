@@ -0,0 +1,33 @@
+// ABOUTME: Admin API exposing disk space and database growth status
+// ABOUTME: Polled by the storage banner shown on every admin page
+
+package webadmin
+
+import (
+	"net/http"
+
+	"github.com/2389/coven-gateway/internal/diskmon"
+)
+
+// storageResponse is the body of GET /api/admin/storage. Enabled is false
+// when the gateway runs without a storage monitor (in-memory database or
+// monitoring disabled); the status fields are then zero.
+type storageResponse struct {
+	Enabled bool `json:"enabled"`
+	diskmon.Status
+	GrowthPerDayText string `json:"growth_per_day_text,omitempty"`
+}
+
+// handleStorageJSON returns the latest storage check.
+func (a *Admin) handleStorageJSON(w http.ResponseWriter, r *http.Request) {
+	if a.storage == nil {
+		a.writeJSON(w, storageResponse{Status: diskmon.Status{Level: diskmon.LevelOK}})
+		return
+	}
+	st := a.storage.Status()
+	resp := storageResponse{Enabled: true, Status: st}
+	if st.GrowthPerDay > 0 {
+		resp.GrowthPerDayText = diskmon.FormatBytes(st.GrowthPerDay) + "/day"
+	}
+	a.writeJSON(w, resp)
+}
@@ -0,0 +1,55 @@
+// ABOUTME: Tests for the storage status admin API.
+// ABOUTME: Uses a storage monitor with a fake disk probe to drive the banner states.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/diskmon"
+)
+
+func getStorage(t *testing.T, admin *Admin) storageResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/storage", nil)
+	rec := httptest.NewRecorder()
+	admin.handleStorageJSON(rec, requestWithUser(req))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp storageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return resp
+}
+
+func TestHandleStorageJSON(t *testing.T) {
+	admin, _ := newThreadOpsAdmin(t)
+	if resp := getStorage(t, admin); resp.Enabled || resp.Level != diskmon.LevelOK {
+		t.Errorf("without monitor = %+v, want disabled and ok", resp)
+	}
+
+	free := uint64(50 << 20)
+	probe := diskmon.ProbeFunc(func(string) (diskmon.DiskUsage, error) {
+		return diskmon.DiskUsage{Total: 1 << 30, Free: free}, nil
+	})
+	admin.storage = diskmon.New(filepath.Join(t.TempDir(), "gateway.db"), diskmon.Config{Probe: probe, MinFree: 100 << 20}, nil)
+	admin.storage.Check(context.Background())
+
+	resp := getStorage(t, admin)
+	if !resp.Enabled || resp.Level != diskmon.LevelCritical || !resp.Degraded || len(resp.Warnings) == 0 {
+		t.Errorf("below floor = %+v, want enabled critical with warnings", resp)
+	}
+
+	free = 900 << 20
+	admin.storage.Check(context.Background())
+	if resp := getStorage(t, admin); resp.Level != diskmon.LevelOK || resp.Degraded {
+		t.Errorf("after recovery = %+v, want ok", resp)
+	}
+}
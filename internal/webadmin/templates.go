@@ -12,6 +12,8 @@ import (
 
 	"github.com/2389/coven-gateway/internal/assets"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+	"github.com/2389/coven-gateway/internal/usage"
 )
 
 // templateFuncs provides functions available in all Go templates.
@@ -38,6 +40,7 @@ type loginData struct {
 	Title     string
 	Error     string
 	CSRFToken string
+	SSO       bool // show "Sign in with SSO"
 }
 
 type inviteData struct {
@@ -59,6 +62,18 @@ type agentItem struct {
 	ID        string `json:"id"`
 	Name      string `json:"name"`
 	Connected bool   `json:"connected"`
+	Paused    bool   `json:"paused,omitempty"`
+	PausedBy  string `json:"paused_by,omitempty"`
+	PausedAt  string `json:"paused_at,omitempty"`
+
+	// Heartbeat health, named as in GET /api/agents so the page can refresh
+	// from either.
+	Health        string  `json:"health,omitempty"`
+	HealthReason  string  `json:"health_reason,omitempty"`
+	LastHeartbeat string  `json:"last_heartbeat,omitempty"`
+	LatencyMsP95  float64 `json:"latency_ms_p95,omitempty"`
+
+	Reliability *reliabilityItem `json:"reliability,omitempty"`
 }
 
 type principalsPageData struct {
@@ -83,16 +98,20 @@ type threadDetailData struct {
 }
 
 type toolItem struct {
-	Name                 string   `json:"name"`
-	Description          string   `json:"description"`
-	TimeoutSeconds       int32    `json:"timeoutSeconds"`
-	RequiredCapabilities []string `json:"requiredCapabilities"`
+	Name                 string           `json:"name"`
+	Description          string           `json:"description"`
+	TimeoutSeconds       int32            `json:"timeoutSeconds"`
+	RequiredCapabilities []string         `json:"requiredCapabilities"`
+	RecentChange         *toolChangeItem  `json:"recentChange,omitempty"`
+	Reliability          *reliabilityItem `json:"reliability,omitempty"`
+	Origin               string           `json:"origin,omitempty"` // upstream URL for imported MCP tools
 }
 
 type packItem struct {
-	ID      string     `json:"id"`
-	Version string     `json:"version"`
-	Tools   []toolItem `json:"tools"`
+	ID       string     `json:"id"`
+	Version  string     `json:"version"`
+	Tools    []toolItem `json:"tools"`
+	Degraded string     `json:"degraded,omitempty"` // why an upstream or external pack is unhealthy
 }
 
 type toolsPageData struct {
@@ -118,6 +137,18 @@ type agentDetailItem struct {
 	Workspaces   []string
 	InstanceID   string
 	Backend      string
+	Paused       bool
+	PausedBy     string
+	PausedAt     string
+	WriteQuota   []quotaUsageItem
+}
+
+// quotaUsageItem is today's use of one builtin daily write quota; a
+// negative Limit means unlimited.
+type quotaUsageItem struct {
+	Kind  string
+	Used  int
+	Limit int
 }
 
 type agentDetailData struct {
@@ -158,6 +189,7 @@ func (a *Admin) renderLoginPage(w http.ResponseWriter, errorMsg, csrfToken strin
 		Title:     "Login",
 		Error:     errorMsg,
 		CSRFToken: csrfToken,
+		SSO:       a.oidc != nil,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -253,14 +285,10 @@ func (a *Admin) renderDashboard(w http.ResponseWriter, user *store.AdminUser, cs
 func (a *Admin) renderPrincipalsPage(w http.ResponseWriter, user *store.AdminUser, csrfToken string, principals []store.Principal) {
 	tmpl := parseTemplate("templates/base.html", "templates/principals.html")
 
-	if principals == nil {
-		principals = []store.Principal{}
-	}
-
 	// Build complete props JSON for the Svelte island.
 	// Use template.HTML to prevent Go's html/template from escaping inside <script>.
 	propsMap := map[string]any{
-		"principals": principals,
+		"principals": a.principalViews(principals),
 		"userName":   user.DisplayName,
 		"csrfToken":  csrfToken,
 	}
@@ -316,7 +344,7 @@ func (a *Admin) renderThreadsPageWithData(w http.ResponseWriter, user *store.Adm
 }
 
 // renderThreadDetail renders a single thread with its messages.
-func (a *Admin) renderThreadDetail(w http.ResponseWriter, user *store.AdminUser, thread *store.Thread, messages []*store.Message, csrfToken string) {
+func (a *Admin) renderThreadDetail(w http.ResponseWriter, user *store.AdminUser, thread *store.Thread, messages []*store.Message, requestUsage []requestUsageItem, csrfToken string) {
 	tmpl := parseTemplate("templates/base.html", "templates/thread_detail.html")
 
 	if messages == nil {
@@ -341,7 +369,7 @@ func (a *Admin) renderThreadDetail(w http.ResponseWriter, user *store.AdminUser,
 			Type:      m.Type,
 			ToolName:  m.ToolName,
 			ToolID:    m.ToolID,
-			CreatedAt: m.CreatedAt.Format(time.RFC3339),
+			CreatedAt: timeparse.Format(m.CreatedAt),
 		})
 	}
 
@@ -349,15 +377,17 @@ func (a *Admin) renderThreadDetail(w http.ResponseWriter, user *store.AdminUser,
 		"ID":           thread.ID,
 		"FrontendName": thread.FrontendName,
 		"AgentID":      thread.AgentID,
-		"CreatedAt":    thread.CreatedAt.Format(time.RFC3339),
-		"UpdatedAt":    thread.UpdatedAt.Format(time.RFC3339),
+		"SplitFrom":    thread.SplitFrom,
+		"CreatedAt":    timeparse.Format(thread.CreatedAt),
+		"UpdatedAt":    timeparse.Format(thread.UpdatedAt),
 	}
 
 	props := map[string]any{
-		"thread":    threadProps,
-		"messages":  msgItems,
-		"userName":  user.DisplayName,
-		"csrfToken": csrfToken,
+		"thread":       threadProps,
+		"messages":     msgItems,
+		"requestUsage": requestUsage,
+		"userName":     user.DisplayName,
+		"csrfToken":    csrfToken,
 	}
 	propsJSON, err := json.Marshal(props)
 	if err != nil {
@@ -452,6 +482,9 @@ func (a *Admin) renderAgentDetail(w http.ResponseWriter, user *store.AdminUser,
 	if agent.Workspaces == nil {
 		agent.Workspaces = []string{}
 	}
+	if agent.WriteQuota == nil {
+		agent.WriteQuota = []quotaUsageItem{}
+	}
 	if threads == nil {
 		threads = []*store.Thread{}
 	}
@@ -469,8 +502,8 @@ func (a *Admin) renderAgentDetail(w http.ResponseWriter, user *store.AdminUser,
 			ID:           t.ID,
 			FrontendName: t.FrontendName,
 			AgentID:      t.AgentID,
-			CreatedAt:    t.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:    t.UpdatedAt.Format(time.RFC3339),
+			CreatedAt:    timeparse.Format(t.CreatedAt),
+			UpdatedAt:    timeparse.Format(t.UpdatedAt),
 		})
 	}
 
@@ -566,34 +599,8 @@ func (a *Admin) renderSetupComplete(w http.ResponseWriter, displayName, apiToken
 func (a *Admin) renderLinkPage(w http.ResponseWriter, user *store.AdminUser, codes []*store.LinkCode, csrfToken string) {
 	tmpl := parseTemplate("templates/base.html", "templates/link.html")
 
-	if codes == nil {
-		codes = []*store.LinkCode{}
-	}
-
-	type codeJSON struct {
-		ID          string `json:"ID"`
-		Code        string `json:"Code"`
-		Fingerprint string `json:"Fingerprint"`
-		DeviceName  string `json:"DeviceName"`
-		Status      string `json:"Status"`
-		CreatedAt   string `json:"CreatedAt"`
-		ExpiresAt   string `json:"ExpiresAt"`
-	}
-	items := make([]codeJSON, 0, len(codes))
-	for _, c := range codes {
-		items = append(items, codeJSON{
-			ID:          c.ID,
-			Code:        c.Code,
-			Fingerprint: c.Fingerprint,
-			DeviceName:  c.DeviceName,
-			Status:      string(c.Status),
-			CreatedAt:   c.CreatedAt.Format(time.RFC3339),
-			ExpiresAt:   c.ExpiresAt.Format(time.RFC3339),
-		})
-	}
-
 	props := map[string]any{
-		"codes":     items,
+		"codes":     linkCodeItems(codes),
 		"userName":  user.DisplayName,
 		"csrfToken": csrfToken,
 	}
@@ -653,7 +660,7 @@ func (a *Admin) renderLogsPage(w http.ResponseWriter, user *store.AdminUser, ent
 			AgentID:   e.AgentID,
 			Message:   e.Message,
 			Tags:      tags,
-			CreatedAt: e.CreatedAt.Format(time.RFC3339),
+			CreatedAt: timeparse.Format(e.CreatedAt),
 		})
 	}
 
@@ -693,46 +700,12 @@ type todosPageData struct {
 }
 
 // renderTodosPage renders the todos page.
-func (a *Admin) renderTodosPage(w http.ResponseWriter, user *store.AdminUser, todos []*store.Todo, csrfToken string) {
+func (a *Admin) renderTodosPage(w http.ResponseWriter, user *store.AdminUser, todos []*store.Todo, members []*store.ProjectMember, csrfToken string) {
 	tmpl := parseTemplate("templates/base.html", "templates/todos.html")
 
-	if todos == nil {
-		todos = []*store.Todo{}
-	}
-
-	type todoJSON struct {
-		ID          string  `json:"ID"`
-		AgentID     string  `json:"AgentID"`
-		Description string  `json:"Description"`
-		Status      string  `json:"Status"`
-		Priority    string  `json:"Priority"`
-		Notes       string  `json:"Notes"`
-		DueDate     *string `json:"DueDate"`
-		CreatedAt   string  `json:"CreatedAt"`
-		UpdatedAt   string  `json:"UpdatedAt"`
-	}
-	items := make([]todoJSON, 0, len(todos))
-	for _, t := range todos {
-		var dueDate *string
-		if t.DueDate != nil {
-			s := t.DueDate.Format(time.RFC3339)
-			dueDate = &s
-		}
-		items = append(items, todoJSON{
-			ID:          t.ID,
-			AgentID:     t.AgentID,
-			Description: t.Description,
-			Status:      t.Status,
-			Priority:    t.Priority,
-			Notes:       t.Notes,
-			DueDate:     dueDate,
-			CreatedAt:   t.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:   t.UpdatedAt.Format(time.RFC3339),
-		})
-	}
-
 	props := map[string]any{
-		"todos":     items,
+		"todos":     todoItems(todos, time.Now()),
+		"projects":  projectItems(members),
 		"userName":  user.DisplayName,
 		"csrfToken": csrfToken,
 	}
@@ -767,35 +740,13 @@ type boardPageData struct {
 }
 
 // renderBoardPage renders the BBS board page.
-func (a *Admin) renderBoardPage(w http.ResponseWriter, user *store.AdminUser, threads []*store.BBSPost, csrfToken string) {
+func (a *Admin) renderBoardPage(w http.ResponseWriter, user *store.AdminUser, threads []*store.BBSPost, page int, hasMore bool, csrfToken string) {
 	tmpl := parseTemplate("templates/base.html", "templates/board.html")
 
-	if threads == nil {
-		threads = []*store.BBSPost{}
-	}
-
-	type postJSON struct {
-		ID        string `json:"ID"`
-		AgentID   string `json:"AgentID"`
-		ThreadID  string `json:"ThreadID"`
-		Subject   string `json:"Subject"`
-		Content   string `json:"Content"`
-		CreatedAt string `json:"CreatedAt"`
-	}
-	items := make([]postJSON, 0, len(threads))
-	for _, t := range threads {
-		items = append(items, postJSON{
-			ID:        t.ID,
-			AgentID:   t.AgentID,
-			ThreadID:  t.ThreadID,
-			Subject:   t.Subject,
-			Content:   t.Content,
-			CreatedAt: t.CreatedAt.Format(time.RFC3339),
-		})
-	}
-
 	props := map[string]any{
-		"threads":   items,
+		"threads":   toBoardPosts(threads),
+		"page":      page,
+		"hasMore":   hasMore,
 		"userName":  user.DisplayName,
 		"csrfToken": csrfToken,
 	}
@@ -830,33 +781,16 @@ type usagePageData struct {
 }
 
 // renderUsagePage renders the token usage analytics page with pre-fetched props for the Svelte island.
-func (a *Admin) renderUsagePage(w http.ResponseWriter, user *store.AdminUser, csrfToken string, usage *store.UsageStats) {
+func (a *Admin) renderUsagePage(w http.ResponseWriter, user *store.AdminUser, csrfToken string, summary *usage.Summary) {
 	tmpl := parseTemplate("templates/base.html", "templates/usage.html")
 
-	usageMap := map[string]int64{
-		"totalInput":      0,
-		"totalOutput":     0,
-		"totalCacheRead":  0,
-		"totalCacheWrite": 0,
-		"totalThinking":   0,
-		"totalTokens":     0,
-		"requestCount":    0,
-	}
-	if usage != nil {
-		usageMap["totalInput"] = usage.TotalInput
-		usageMap["totalOutput"] = usage.TotalOutput
-		usageMap["totalCacheRead"] = usage.TotalCacheRead
-		usageMap["totalCacheWrite"] = usage.TotalCacheWrite
-		usageMap["totalThinking"] = usage.TotalThinking
-		usageMap["totalTokens"] = usage.TotalTokens
-		usageMap["requestCount"] = usage.RequestCount
-	}
-
 	props := map[string]any{
-		"stats":     usageMap,
 		"userName":  user.DisplayName,
 		"csrfToken": csrfToken,
 	}
+	if summary != nil {
+		props["summary"] = summary
+	}
 	propsJSON, err := json.Marshal(props)
 	if err != nil {
 		a.logger.Error("failed to marshal usage props", "error", err)
@@ -0,0 +1,74 @@
+// ABOUTME: Admin download of a thread's transcript, behind the thread detail page's Export button.
+// ABOUTME: Renders through the transcript package with stored secrets masked.
+
+package webadmin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/transcript"
+)
+
+// handleThreadExport handles GET /admin/threads/{id}/export?format=markdown|json.
+func (a *Admin) handleThreadExport(w http.ResponseWriter, r *http.Request) {
+	threadID := r.PathValue("id")
+	format, err := transcript.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	thread, err := a.store.GetThread(r.Context(), threadID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Thread not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		a.logger.Error("failed to get thread", "error", err, "thread_id", threadID)
+		http.Error(w, "Failed to load thread", http.StatusInternalServerError)
+		return
+	}
+	if thread.MergedInto != "" {
+		target := "/admin/threads/" + thread.MergedInto + "/export"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+		return
+	}
+
+	requests, err := a.store.ListThreadRequestUsage(r.Context(), threadID, store.UsageFilter{})
+	if err != nil {
+		a.logger.Error("failed to list request usage", "error", err, "thread_id", threadID)
+		http.Error(w, "Failed to load usage", http.StatusInternalServerError)
+		return
+	}
+	var secrets []string
+	if sqlStore := a.getSQLStore(); sqlStore != nil {
+		all, err := sqlStore.ListAllSecrets(r.Context())
+		if err != nil {
+			// Exporting without them could leak a secret into a ticket.
+			a.logger.Error("failed to load secrets for export redaction", "error", err)
+			http.Error(w, "Failed to load secrets", http.StatusInternalServerError)
+			return
+		}
+		for _, s := range all {
+			secrets = append(secrets, s.Value)
+		}
+	}
+
+	report := a.config.UsagePricing.ThreadReport(requests)
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", format.Filename(threadID)))
+	err = transcript.Write(r.Context(), w, a.store, thread, transcript.Options{
+		Format:  format,
+		Usage:   &report,
+		Secrets: secrets,
+	})
+	if err != nil {
+		a.logger.Error("failed to export thread", "thread_id", threadID, "error", err)
+	}
+}
@@ -0,0 +1,61 @@
+// ABOUTME: Tests for the admin thread transcript download.
+// ABOUTME: Covers both formats, secret masking, and tombstone redirects.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/transcript"
+)
+
+func TestHandleThreadExport(t *testing.T) {
+	a, s := newThreadOpsAdmin(t)
+	seedAdminThread(t, s, "t-export", "e-token-abcd1234", "e-plain")
+	seedAdminThread(t, s, "t-merged")
+	if _, err := s.MergeThreads(context.Background(), "t-export", []string{"t-merged"}); err != nil {
+		t.Fatalf("MergeThreads: %v", err)
+	}
+	if err := s.CreateSecret(context.Background(), &store.Secret{ID: "s1", Key: "TOKEN", Value: "abcd1234"}); err != nil {
+		t.Fatalf("CreateSecret: %v", err)
+	}
+
+	export := func(id, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/threads/"+id+"/export"+query, nil)
+		req.SetPathValue("id", id)
+		rec := httptest.NewRecorder()
+		a.handleThreadExport(rec, req)
+		return rec
+	}
+
+	rec := export("t-export", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "e-token-[redacted]") || strings.Contains(rec.Body.String(), "abcd1234") {
+		t.Errorf("markdown = %d %s, want the secret masked", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.Contains(got, "thread-t-export.md") {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	rec = export("t-export", "?format=json")
+	var doc transcript.Document
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil || len(doc.Events) != 3 || doc.Usage == nil {
+		t.Errorf("json = %+v (%v), want both messages, the merge note, and usage", doc, err)
+	}
+
+	if rec := export("t-merged", "?format=json"); rec.Code != http.StatusPermanentRedirect ||
+		rec.Header().Get("Location") != "/admin/threads/t-export/export?format=json" {
+		t.Errorf("merged = %d to %q, want a redirect to the target's export", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := export("t-missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("missing = %d, want 404", rec.Code)
+	}
+	if rec := export("t-export", "?format=pdf"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad format = %d, want 400", rec.Code)
+	}
+}
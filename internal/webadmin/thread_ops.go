@@ -0,0 +1,214 @@
+// ABOUTME: Admin handlers for merging, splitting, archiving, and pinning conversation threads
+// ABOUTME: Wraps the store operations with CSRF checks, error mapping, and audit logging
+
+package webadmin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// mergeThreadsRequest is the body of POST /api/admin/threads/merge.
+type mergeThreadsRequest struct {
+	Target  string   `json:"target"`
+	Sources []string `json:"sources"`
+}
+
+// splitThreadRequest is the body of POST /api/admin/threads/{id}/split.
+type splitThreadRequest struct {
+	EventID string `json:"event_id"`
+}
+
+// updateThreadRequest is the body of PATCH /api/admin/threads/{id}. Fields
+// left out are not changed.
+type updateThreadRequest struct {
+	Archived *bool `json:"archived"`
+	Pinned   *bool `json:"pinned"`
+}
+
+// handleMergeThreads moves the source threads' history onto the target thread.
+func (a *Admin) handleMergeThreads(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+
+	var req mergeThreadsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Target == "" || len(req.Sources) == 0 {
+		http.Error(w, "target and sources required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := a.store.MergeThreads(r.Context(), req.Target, req.Sources)
+	if err != nil {
+		a.writeThreadOpError(w, "merge", err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	a.auditAdminAction(r, &store.AuditEntry{
+		ActorPrincipalID: user.ID,
+		Action:           store.AuditMergeThreads,
+		TargetType:       "thread",
+		TargetID:         result.TargetID,
+		Detail: map[string]any{
+			"admin_user":     user.Username,
+			"source_ids":     result.SourceIDs,
+			"events_moved":   result.EventsMoved,
+			"messages_moved": result.MessagesMoved,
+			"usage_moved":    result.UsageMoved,
+		},
+	})
+	a.logger.Info("threads merged", "target_id", result.TargetID, "source_ids", result.SourceIDs, "by", user.Username)
+
+	a.writeJSON(w, map[string]any{
+		"target_id":      result.TargetID,
+		"source_ids":     result.SourceIDs,
+		"events_moved":   result.EventsMoved,
+		"messages_moved": result.MessagesMoved,
+		"usage_moved":    result.UsageMoved,
+		"merge_event_id": result.MergeEventID,
+	})
+}
+
+// handleSplitThread moves every event after the given one into a new thread.
+func (a *Admin) handleSplitThread(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+
+	threadID := r.PathValue("id")
+	if threadID == "" {
+		http.Error(w, "Thread ID required", http.StatusBadRequest)
+		return
+	}
+
+	var req splitThreadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.EventID == "" {
+		http.Error(w, "event_id required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := a.store.SplitThread(r.Context(), threadID, req.EventID)
+	if err != nil {
+		a.writeThreadOpError(w, "split", err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	a.auditAdminAction(r, &store.AuditEntry{
+		ActorPrincipalID: user.ID,
+		Action:           store.AuditSplitThread,
+		TargetType:       "thread",
+		TargetID:         result.OriginalID,
+		Detail: map[string]any{
+			"admin_user":     user.Username,
+			"new_thread_id":  result.NewThreadID,
+			"cut_event_id":   result.CutEventID,
+			"events_moved":   result.EventsMoved,
+			"messages_moved": result.MessagesMoved,
+			"usage_moved":    result.UsageMoved,
+		},
+	})
+	a.logger.Info("thread split", "thread_id", result.OriginalID, "new_thread_id", result.NewThreadID, "by", user.Username)
+
+	a.writeJSON(w, map[string]any{
+		"original_id":    result.OriginalID,
+		"new_thread_id":  result.NewThreadID,
+		"cut_event_id":   result.CutEventID,
+		"events_moved":   result.EventsMoved,
+		"messages_moved": result.MessagesMoved,
+		"usage_moved":    result.UsageMoved,
+		"split_event_id": result.SplitEventID,
+	})
+}
+
+// handleUpdateThread archives, unarchives, pins, or unpins a thread and
+// returns it.
+func (a *Admin) handleUpdateThread(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+
+	threadID := r.PathValue("id")
+	var req updateThreadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Archived == nil && req.Pinned == nil {
+		http.Error(w, "archived or pinned required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	var err error
+	if req.Archived != nil {
+		err = a.store.ArchiveThread(ctx, threadID, *req.Archived)
+	}
+	if err == nil && req.Pinned != nil {
+		err = a.store.SetThreadPinned(ctx, threadID, *req.Pinned)
+	}
+	var thread *store.Thread
+	if err == nil {
+		thread, err = a.store.GetThread(ctx, threadID)
+	}
+	if err != nil {
+		a.writeThreadOpError(w, "update", err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	a.logger.Info("thread updated", "thread_id", threadID, "archived", thread.Archived, "pinned", thread.Pinned, "by", user.Username)
+	a.writeJSON(w, thread)
+}
+
+// writeThreadOpError maps store errors from merge/split onto HTTP statuses.
+func (a *Admin) writeThreadOpError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrEventNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, store.ErrInvalidThreadOperation):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		a.logger.Error("thread "+op+" failed", "error", err)
+		http.Error(w, "Failed to "+op+" threads", http.StatusInternalServerError)
+	}
+}
+
+// writeTimestampError writes a 400 naming the timestamp parameter that failed
+// to parse. See timeparse.Error for the body.
+func (a *Admin) writeTimestampError(w http.ResponseWriter, err error) {
+	var terr *timeparse.Error
+	if !errors.As(err, &terr) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	if err := json.NewEncoder(w).Encode(terr); err != nil {
+		a.logger.Error("failed to encode JSON response", "error", err)
+	}
+}
+
+// writeJSON encodes v as the JSON response body.
+func (a *Admin) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		a.logger.Error("failed to encode JSON response", "error", err)
+	}
+}
@@ -0,0 +1,247 @@
+// ABOUTME: Tests for the admin thread merge, split, archive, and pin endpoints.
+// ABOUTME: Covers CSRF, error mapping, audit entries, and tombstone redirects.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func newThreadOpsAdmin(t *testing.T) (*Admin, *store.SQLStore) {
+	t.Helper()
+	s, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return &Admin{store: s, logger: slog.Default()}, s
+}
+
+func seedAdminThread(t *testing.T, s *store.SQLStore, id string, eventIDs ...string) {
+	t.Helper()
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := s.CreateThread(ctx, &store.Thread{
+		ID: id, FrontendName: "matrix", ExternalID: "room-" + id, AgentID: "agent-1",
+		CreatedAt: base, UpdatedAt: base,
+	}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	for i, eventID := range eventIDs {
+		threadID := id
+		text := eventID
+		if err := s.SaveEvent(ctx, &store.LedgerEvent{
+			ID: eventID, ConversationKey: "agent-1", ThreadID: &threadID,
+			Direction: store.EventDirectionInbound, Author: "user",
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			Type:      store.EventTypeMessage, Text: &text,
+		}); err != nil {
+			t.Fatalf("SaveEvent: %v", err)
+		}
+	}
+}
+
+// csrfJSONRequest builds an authenticated JSON POST carrying a valid CSRF token.
+func csrfJSONRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CSRF-Token", "tok")
+	req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: "tok"})
+	return requestWithUser(req)
+}
+
+func TestHandleMergeThreads_MergesAndAudits(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	seedAdminThread(t, s, "target", "t-1")
+	seedAdminThread(t, s, "source", "s-1")
+
+	rec := httptest.NewRecorder()
+	admin.handleMergeThreads(rec, csrfJSONRequest(http.MethodPost, "/api/admin/threads/merge",
+		`{"target":"target","sources":["source"]}`))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		TargetID    string `json:"target_id"`
+		EventsMoved int64  `json:"events_moved"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.TargetID != "target" || resp.EventsMoved != 1 {
+		t.Errorf("response = %+v, want target with 1 event moved", resp)
+	}
+
+	action := store.AuditMergeThreads
+	entries, err := s.ListAuditLog(context.Background(), store.AuditFilter{Action: &action})
+	if err != nil {
+		t.Fatalf("ListAuditLog: %v", err)
+	}
+	if len(entries) != 1 || entries[0].TargetID != "target" || entries[0].ActorPrincipalID != "test-user" {
+		t.Errorf("audit entries = %+v, want one merge entry by test-user", entries)
+	}
+}
+
+func TestHandleMergeThreads_RequiresCSRF(t *testing.T) {
+	admin, _ := newThreadOpsAdmin(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/threads/merge", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+	admin.handleMergeThreads(rec, requestWithUser(req))
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+}
+
+func TestHandleMergeThreads_ErrorStatuses(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	seedAdminThread(t, s, "t1", "e-1")
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"missing fields", `{"target":"t1"}`, http.StatusBadRequest},
+		{"self merge", `{"target":"t1","sources":["t1"]}`, http.StatusBadRequest},
+		{"unknown source", `{"target":"t1","sources":["nope"]}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			admin.handleMergeThreads(rec, csrfJSONRequest(http.MethodPost, "/api/admin/threads/merge", tt.body))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleSplitThread(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	seedAdminThread(t, s, "orig", "e-1", "e-2", "e-3")
+
+	req := csrfJSONRequest(http.MethodPost, "/api/admin/threads/orig/split", `{"event_id":"e-1"}`)
+	req.SetPathValue("id", "orig")
+	rec := httptest.NewRecorder()
+	admin.handleSplitThread(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		NewThreadID string `json:"new_thread_id"`
+		EventsMoved int64  `json:"events_moved"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.NewThreadID == "" || resp.EventsMoved != 2 {
+		t.Errorf("response = %+v, want new thread with 2 events", resp)
+	}
+
+	action := store.AuditSplitThread
+	entries, err := s.ListAuditLog(context.Background(), store.AuditFilter{Action: &action})
+	if err != nil {
+		t.Fatalf("ListAuditLog: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Detail["new_thread_id"] != resp.NewThreadID {
+		t.Errorf("audit entries = %+v, want one split entry naming the new thread", entries)
+	}
+
+	req = csrfJSONRequest(http.MethodPost, "/api/admin/threads/orig/split", `{"event_id":"missing"}`)
+	req.SetPathValue("id", "orig")
+	rec = httptest.NewRecorder()
+	admin.handleSplitThread(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown event status = %d, want 404", rec.Code)
+	}
+}
+
+func TestHandleThreadDetailJSON_RedirectsMergedThread(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	seedAdminThread(t, s, "target", "t-1")
+	seedAdminThread(t, s, "source", "s-1")
+	if _, err := s.MergeThreads(context.Background(), "target", []string{"source"}); err != nil {
+		t.Fatalf("MergeThreads: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/threads/source", nil)
+	req.SetPathValue("id", "source")
+	rec := httptest.NewRecorder()
+	admin.handleThreadDetailJSON(rec, requestWithUser(req))
+
+	if rec.Code != http.StatusPermanentRedirect {
+		t.Fatalf("status = %d, want 308", rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/api/admin/threads/target" {
+		t.Errorf("Location = %q, want /api/admin/threads/target", loc)
+	}
+}
+
+func TestHandleUpdateThread(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	seedAdminThread(t, s, "t1")
+	seedAdminThread(t, s, "t2")
+
+	req := csrfJSONRequest(http.MethodPatch, "/api/admin/threads/t1", `{"archived":true,"pinned":true}`)
+	req.SetPathValue("id", "t1")
+	rec := httptest.NewRecorder()
+	admin.handleUpdateThread(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var thread store.Thread
+	if err := json.NewDecoder(rec.Body).Decode(&thread); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !thread.Archived || !thread.Pinned {
+		t.Errorf("thread = %+v, want archived and pinned", thread)
+	}
+
+	for _, tt := range []struct {
+		query string
+		want  int
+	}{{"", 1}, {"?include_archived=true", 2}} {
+		rec := httptest.NewRecorder()
+		admin.handleThreadsJSON(rec, requestWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/threads"+tt.query, nil)))
+		var threads []store.Thread
+		if err := json.NewDecoder(rec.Body).Decode(&threads); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(threads) != tt.want {
+			t.Errorf("GET /api/admin/threads%s listed %d threads, want %d", tt.query, len(threads), tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		name, id, body string
+		want           int
+	}{
+		{"no fields", "t1", `{}`, http.StatusBadRequest},
+		{"unknown thread", "nope", `{"pinned":true}`, http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := csrfJSONRequest(http.MethodPatch, "/api/admin/threads/"+tt.id, tt.body)
+			req.SetPathValue("id", tt.id)
+			rec := httptest.NewRecorder()
+			admin.handleUpdateThread(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
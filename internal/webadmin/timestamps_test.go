@@ -0,0 +1,138 @@
+// ABOUTME: Tests timestamp handling across the admin JSON API.
+// ABOUTME: Inputs accept RFC3339 and epoch forms with named errors; outputs are RFC3339 UTC.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// nonUTC is a stored timestamp with an offset, which must be emitted as UTC.
+var nonUTC = time.Date(2026, 3, 1, 9, 30, 0, 0, time.FixedZone("EST", -5*3600))
+
+const nonUTCWant = "2026-03-01T14:30:00Z"
+
+func TestTimestampInputs(t *testing.T) {
+	admin, _ := newThreadOpsAdmin(t)
+	epoch := fmt.Sprint(nonUTC.Unix())
+	millis := fmt.Sprint(nonUTC.UnixMilli())
+
+	tests := []struct {
+		name      string
+		path      string
+		handler   http.HandlerFunc
+		wantParam string // empty means the request must succeed
+	}{
+		{"usage rfc3339", "/api/admin/usage?since=2026-03-01T00:00:00Z", admin.handleUsageJSON, ""},
+		{"usage epoch seconds", "/api/admin/usage?since=" + epoch, admin.handleUsageJSON, ""},
+		{"usage epoch millis", "/api/admin/usage?until=" + millis, admin.handleUsageJSON, ""},
+		{"usage bad since", "/api/admin/usage?since=yesterday", admin.handleUsageJSON, "since"},
+		{"usage bad until", "/api/admin/usage?until=2026-03-01", admin.handleUsageJSON, "until"},
+		{"changelog rfc3339nano", "/api/admin/tools/changelog?since=2026-03-01T00:00:00.5Z", admin.handleToolChangelogJSON, ""},
+		{"changelog epoch", "/api/admin/tools/changelog?until=" + epoch, admin.handleToolChangelogJSON, ""},
+		{"changelog bad until", "/api/admin/tools/changelog?until=later", admin.handleToolChangelogJSON, "until"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tc.handler(rec, requestWithUser(httptest.NewRequest(http.MethodGet, tc.path, nil)))
+
+			if tc.wantParam == "" {
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+				}
+				return
+			}
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", rec.Code)
+			}
+			var body map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if body["code"] != timeparse.ErrorCode || body["param"] != tc.wantParam {
+				t.Errorf("error body = %v, want code %s naming %s", body, timeparse.ErrorCode, tc.wantParam)
+			}
+		})
+	}
+}
+
+func TestTimestampOutputs_RFC3339UTC(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	ctx := context.Background()
+
+	if err := s.CreateThread(ctx, &store.Thread{
+		ID: "t-1", FrontendName: "web", ExternalID: "e-1", AgentID: "agent-1", CreatedAt: nonUTC, UpdatedAt: nonUTC,
+	}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	if err := s.CreatePrincipal(ctx, &store.Principal{
+		ID: "p-1", Type: store.PrincipalTypeAgent, PubkeyFP: strings.Repeat("d", 64),
+		DisplayName: "p", Status: store.PrincipalStatusApproved, CreatedAt: nonUTC,
+	}); err != nil {
+		t.Fatalf("CreatePrincipal: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		handler http.HandlerFunc
+		field   func(body []byte) (string, error)
+	}{
+		{"threads", "/api/admin/threads", admin.handleThreadsJSON, func(b []byte) (string, error) {
+			var threads []map[string]any
+			err := json.Unmarshal(b, &threads)
+			if err != nil || len(threads) == 0 {
+				return "", fmt.Errorf("threads: %v (%d)", err, len(threads))
+			}
+			return fmt.Sprint(threads[0]["CreatedAt"]), nil
+		}},
+		{"principals", "/api/admin/principals", admin.handlePrincipalsJSON, func(b []byte) (string, error) {
+			var principals []map[string]any
+			err := json.Unmarshal(b, &principals)
+			if err != nil || len(principals) == 0 {
+				return "", fmt.Errorf("principals: %v (%d)", err, len(principals))
+			}
+			if _, ok := principals[0]["WaitingAgents"]; !ok {
+				return "", fmt.Errorf("WaitingAgents missing from %v", principals[0])
+			}
+			return fmt.Sprint(principals[0]["CreatedAt"]), nil
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tc.handler(rec, requestWithUser(httptest.NewRequest(http.MethodGet, tc.path, nil)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+			got, err := tc.field(rec.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != nonUTCWant {
+				t.Errorf("CreatedAt = %q, want %q", got, nonUTCWant)
+			}
+		})
+	}
+
+	b, err := json.Marshal(&chatMessage{Type: "text", Timestamp: nonUTC})
+	if err != nil {
+		t.Fatalf("marshal chatMessage: %v", err)
+	}
+	if !strings.Contains(string(b), `"timestamp":"`+nonUTCWant+`"`) || !strings.Contains(string(b), `"type":"text"`) {
+		t.Errorf("chatMessage JSON = %s", b)
+	}
+}
@@ -0,0 +1,147 @@
+// ABOUTME: Todo board data for the todos page and the admin API for project membership
+// ABOUTME: Members of a project can see, update and be assigned its todos through the todo_* tools
+
+package webadmin
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// projectNamePattern limits project names to characters that are safe in a
+// todo_list scope and a URL path.
+var projectNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// todoItem is one todo as shown on the todos page.
+type todoItem struct {
+	ID              string  `json:"ID"`
+	AgentID         string  `json:"AgentID"`
+	Description     string  `json:"Description"`
+	Status          string  `json:"Status"`
+	Priority        string  `json:"Priority"`
+	Notes           string  `json:"Notes"`
+	DueDate         *string `json:"DueDate"`
+	AssigneeAgentID string  `json:"AssigneeAgentID"`
+	Project         string  `json:"Project"`
+	Overdue         bool    `json:"Overdue"` // past its due date and not completed
+	CreatedAt       string  `json:"CreatedAt"`
+	UpdatedAt       string  `json:"UpdatedAt"`
+}
+
+// todoItems converts todos for the todos page, marking those overdue at now.
+func todoItems(todos []*store.Todo, now time.Time) []todoItem {
+	items := make([]todoItem, 0, len(todos))
+	for _, t := range todos {
+		items = append(items, todoItem{
+			ID:              t.ID,
+			AgentID:         t.AgentID,
+			Description:     t.Description,
+			Status:          t.Status,
+			Priority:        t.Priority,
+			Notes:           t.Notes,
+			DueDate:         timeparse.FormatPtr(t.DueDate),
+			AssigneeAgentID: t.AssigneeAgentID,
+			Project:         t.Project,
+			Overdue:         t.DueDate != nil && t.DueDate.Before(now) && t.Status != "completed",
+			CreatedAt:       timeparse.Format(t.CreatedAt),
+			UpdatedAt:       timeparse.Format(t.UpdatedAt),
+		})
+	}
+	return items
+}
+
+// projectItem is a project and its members.
+type projectItem struct {
+	Name    string          `json:"name"`
+	Members []projectMember `json:"members"`
+}
+
+type projectMember struct {
+	AgentID string `json:"agentId"`
+	AddedAt string `json:"addedAt"`
+}
+
+// projectItems groups memberships, which ListProjectMembers returns ordered
+// by project, into projects.
+func projectItems(members []*store.ProjectMember) []projectItem {
+	items := []projectItem{}
+	for _, m := range members {
+		if len(items) == 0 || items[len(items)-1].Name != m.Project {
+			items = append(items, projectItem{Name: m.Project})
+		}
+		last := &items[len(items)-1]
+		last.Members = append(last.Members, projectMember{AgentID: m.AgentID, AddedAt: timeparse.Format(m.AddedAt)})
+	}
+	return items
+}
+
+// handleProjectsJSON handles GET /api/admin/projects.
+func (a *Admin) handleProjectsJSON(w http.ResponseWriter, r *http.Request) {
+	members, err := a.store.ListProjectMembers(r.Context())
+	if err != nil {
+		a.logger.Error("failed to list project members", "error", err)
+		http.Error(w, `{"error":"failed to load projects"}`, http.StatusInternalServerError)
+		return
+	}
+	a.writeJSON(w, map[string]any{"projects": projectItems(members)})
+}
+
+// handleAddProjectMember handles PUT /api/admin/projects/{project}/members/{agent}.
+// A project exists while it has members, so adding the first one creates it.
+func (a *Admin) handleAddProjectMember(w http.ResponseWriter, r *http.Request) {
+	project, agentID, ok := a.projectMemberParams(w, r)
+	if !ok {
+		return
+	}
+	if err := a.store.AddProjectMember(r.Context(), project, agentID); err != nil {
+		a.logger.Error("failed to add project member", "project", project, "agent_id", agentID, "error", err)
+		http.Error(w, "Failed to add project member", http.StatusInternalServerError)
+		return
+	}
+	a.auditAdminAction(r, newAuditEntry(r, store.AuditAddProjectMember, "agent", agentID, map[string]any{"project": project}))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRemoveProjectMember handles DELETE /api/admin/projects/{project}/members/{agent}.
+func (a *Admin) handleRemoveProjectMember(w http.ResponseWriter, r *http.Request) {
+	project, agentID, ok := a.projectMemberParams(w, r)
+	if !ok {
+		return
+	}
+	err := a.store.RemoveProjectMember(r.Context(), project, agentID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Not a member of the project", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		a.logger.Error("failed to remove project member", "project", project, "agent_id", agentID, "error", err)
+		http.Error(w, "Failed to remove project member", http.StatusInternalServerError)
+		return
+	}
+	a.auditAdminAction(r, newAuditEntry(r, store.AuditRemoveProjectMember, "agent", agentID, map[string]any{"project": project}))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// projectMemberParams checks the CSRF token and reads the project and agent
+// from the path, writing an error response if either is unusable.
+func (a *Admin) projectMemberParams(w http.ResponseWriter, r *http.Request) (project, agentID string, ok bool) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		return "", "", false
+	}
+	project, agentID = r.PathValue("project"), r.PathValue("agent")
+	if !projectNamePattern.MatchString(project) {
+		http.Error(w, "Project names are 1-64 letters, digits, '.', '_' or '-'", http.StatusBadRequest)
+		return "", "", false
+	}
+	if agentID == "" {
+		http.Error(w, "Agent ID is required", http.StatusBadRequest)
+		return "", "", false
+	}
+	return project, agentID, true
+}
@@ -0,0 +1,92 @@
+// ABOUTME: Tests for the todos page data and the project membership admin API.
+// ABOUTME: Covers overdue marking, grouping members by project, validation and auditing.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestTodoItems_MarksOverdue(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	items := todoItems([]*store.Todo{
+		{ID: "late", Status: "pending", DueDate: &past, Project: "apollo"},
+		{ID: "done", Status: "completed", DueDate: &past},
+		{ID: "later", Status: "pending", DueDate: &future},
+		{ID: "undated", Status: "pending"},
+	}, now)
+
+	overdue := map[string]bool{}
+	for _, item := range items {
+		overdue[item.ID] = item.Overdue
+	}
+	if !overdue["late"] || overdue["done"] || overdue["later"] || overdue["undated"] {
+		t.Errorf("overdue = %v, want only late", overdue)
+	}
+	if items[0].Project != "apollo" {
+		t.Errorf("Project = %q, want apollo", items[0].Project)
+	}
+}
+
+func TestHandleProjectMembers(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+
+	call := func(method, project, agent string) int {
+		req := csrfJSONRequest(method, "/api/admin/projects/"+project+"/members/"+agent, "")
+		req.SetPathValue("project", project)
+		req.SetPathValue("agent", agent)
+		rec := httptest.NewRecorder()
+		if method == http.MethodPut {
+			admin.handleAddProjectMember(rec, req)
+		} else {
+			admin.handleRemoveProjectMember(rec, req)
+		}
+		return rec.Code
+	}
+
+	for _, m := range [][2]string{{"apollo", "agent-2"}, {"apollo", "agent-1"}, {"zeus", "agent-1"}} {
+		if code := call(http.MethodPut, m[0], m[1]); code != http.StatusNoContent {
+			t.Fatalf("adding %v: status = %d, want 204", m, code)
+		}
+	}
+	if code := call(http.MethodPut, ".hidden", "agent-1"); code != http.StatusBadRequest {
+		t.Errorf("invalid project name: status = %d, want 400", code)
+	}
+
+	rec := httptest.NewRecorder()
+	admin.handleProjectsJSON(rec, httptest.NewRequest(http.MethodGet, "/api/admin/projects", nil))
+	var resp struct {
+		Projects []projectItem `json:"projects"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Projects) != 2 || resp.Projects[0].Name != "apollo" || len(resp.Projects[0].Members) != 2 ||
+		resp.Projects[0].Members[0].AgentID != "agent-1" || resp.Projects[1].Name != "zeus" {
+		t.Errorf("projects = %+v, want apollo (agent-1, agent-2) and zeus", resp.Projects)
+	}
+
+	if code := call(http.MethodDelete, "apollo", "agent-2"); code != http.StatusNoContent {
+		t.Errorf("removing member: status = %d, want 204", code)
+	}
+	if code := call(http.MethodDelete, "apollo", "agent-2"); code != http.StatusNotFound {
+		t.Errorf("removing non-member: status = %d, want 404", code)
+	}
+	if ok, _ := s.IsProjectMember(context.Background(), "apollo", "agent-2"); ok {
+		t.Error("agent-2 is still a member of apollo")
+	}
+
+	action := store.AuditAddProjectMember
+	entries, err := s.ListAuditLog(context.Background(), store.AuditFilter{Action: &action})
+	if err != nil || len(entries) != 3 {
+		t.Errorf("add_project_member audit entries = %d, err = %v, want 3", len(entries), err)
+	}
+}
@@ -0,0 +1,408 @@
+// ABOUTME: Admin API for self-serve API token management from the settings page
+// ABOUTME: Lists, mints, and revokes tokens; a minted value is returned once and never stored
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// Default and maximum lifetimes for tokens minted from the admin UI.
+const (
+	defaultTokenTTL = 30 * 24 * time.Hour
+	maxTokenTTL     = 365 * 24 * time.Hour
+)
+
+// tokenItem is one token as shown in the settings panel. It never carries
+// the token value.
+type tokenItem struct {
+	ID            string   `json:"id"`
+	PrincipalID   string   `json:"principalId"`
+	PrincipalName string   `json:"principalName"`
+	Scopes        []string `json:"scopes"`
+	AgentIDs      []string `json:"agentIds,omitempty"`     // agents the token is limited to
+	Capabilities  []string `json:"capabilities,omitempty"` // capability subset the token is limited to
+	CreatedBy     string   `json:"createdBy,omitempty"`
+	CreatedAt     string   `json:"createdAt"`
+	ExpiresAt     string   `json:"expiresAt"`
+	LastUsedAt    string   `json:"lastUsedAt,omitempty"`
+	RevokedAt     string   `json:"revokedAt,omitempty"`
+	Status        string   `json:"status"` // "active", "expired", or "revoked"
+}
+
+// tokenPrincipal is a principal the admin may mint tokens for.
+type tokenPrincipal struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	Type        string `json:"type"`
+}
+
+// tokensPanel is the token section of the settings page.
+type tokensPanel struct {
+	Enabled    bool             `json:"enabled"` // false when the gateway has no JWT secret
+	Tokens     []tokenItem      `json:"tokens"`
+	Principals []tokenPrincipal `json:"principals"`
+	ManageAll  bool             `json:"manageAll"` // owner: may manage every principal's tokens
+	Scopes     []string         `json:"scopes"`
+}
+
+// createTokenRequest is the body of POST /api/admin/tokens.
+type createTokenRequest struct {
+	PrincipalID string   `json:"principalId"` // empty means the admin's own principal
+	TTLSeconds  int64    `json:"ttlSeconds"`
+	Scopes      []string `json:"scopes"`
+
+	// AgentIDs and Capabilities optionally limit the token; empty means
+	// every agent and every capability the principal holds.
+	AgentIDs     []string `json:"agentIds"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// createTokenResponse carries the only copy of a new token's value.
+type createTokenResponse struct {
+	Token  string    `json:"token"`
+	Record tokenItem `json:"record"`
+}
+
+// tokenAccess is what an admin user may manage.
+type tokenAccess struct {
+	ownPrincipal string // empty until the admin's client principal exists
+	all          bool
+}
+
+func (t tokenAccess) canManage(principalID string) bool {
+	return t.all || (principalID != "" && principalID == t.ownPrincipal)
+}
+
+// tokenAccessFor works out which principals user may manage tokens for:
+// their own client principal, or every principal if it holds the owner role.
+func (a *Admin) tokenAccessFor(ctx context.Context, user *store.AdminUser) tokenAccess {
+	access := tokenAccess{ownPrincipal: user.PrincipalID}
+	if user.PrincipalID == "" {
+		return access
+	}
+	roles, err := a.store.ListRoles(ctx, store.RoleSubjectPrincipal, user.PrincipalID)
+	if err != nil {
+		a.logger.Warn("failed to list roles for admin principal", "principal_id", user.PrincipalID, "error", err)
+		return access
+	}
+	access.all = slices.Contains(roles, store.RoleOwner)
+	return access
+}
+
+// tokensEnabled reports whether tokens can be minted at all.
+func (a *Admin) tokensEnabled() bool {
+	return a.tokenMinter != nil
+}
+
+// mintToken issues a token, recording it when a minter is configured.
+func (a *Admin) mintToken(ctx context.Context, principalID, createdBy string, ttl time.Duration, scopes []string) (string, error) {
+	if a.tokenMinter != nil {
+		token, _, err := a.tokenMinter.Mint(ctx, auth.MintRequest{
+			PrincipalID: principalID, CreatedBy: createdBy, TTL: ttl, Scopes: scopes,
+		})
+		return token, err
+	}
+	return a.tokenGenerator.Generate(principalID, ttl)
+}
+
+// buildTokensPanel lists the tokens and principals visible to user.
+func (a *Admin) buildTokensPanel(ctx context.Context, user *store.AdminUser) (*tokensPanel, error) {
+	panel := &tokensPanel{
+		Enabled:    a.tokensEnabled(),
+		Tokens:     []tokenItem{},
+		Principals: []tokenPrincipal{},
+		Scopes:     auth.AllScopes,
+	}
+	if !panel.Enabled {
+		return panel, nil
+	}
+	access := a.tokenAccessFor(ctx, user)
+	panel.ManageAll = access.all
+
+	names := map[string]string{}
+	if access.all {
+		principals, err := a.store.ListPrincipals(ctx, store.PrincipalFilter{Limit: 1000})
+		if err != nil {
+			return nil, fmt.Errorf("listing principals: %w", err)
+		}
+		for _, p := range principals {
+			names[p.ID] = p.DisplayName
+			if p.Status == store.PrincipalStatusRevoked || p.Status == store.PrincipalStatusPending {
+				continue
+			}
+			panel.Principals = append(panel.Principals, tokenPrincipal{ID: p.ID, DisplayName: p.DisplayName, Type: string(p.Type)})
+		}
+	} else if access.ownPrincipal != "" {
+		if p, err := a.store.GetPrincipal(ctx, access.ownPrincipal); err == nil {
+			names[p.ID] = p.DisplayName
+			panel.Principals = append(panel.Principals, tokenPrincipal{ID: p.ID, DisplayName: p.DisplayName, Type: string(p.Type)})
+		}
+	}
+
+	if !access.all && access.ownPrincipal == "" {
+		return panel, nil
+	}
+	listFor := access.ownPrincipal
+	if access.all {
+		listFor = ""
+	}
+	tokens, err := a.store.ListAPITokens(ctx, listFor)
+	if err != nil {
+		return nil, fmt.Errorf("listing tokens: %w", err)
+	}
+	now := time.Now()
+	for _, t := range tokens {
+		panel.Tokens = append(panel.Tokens, newTokenItem(t, names[t.PrincipalID], now))
+	}
+	return panel, nil
+}
+
+func newTokenItem(t *store.APIToken, principalName string, now time.Time) tokenItem {
+	item := tokenItem{
+		ID:            t.ID,
+		PrincipalID:   t.PrincipalID,
+		PrincipalName: principalName,
+		Scopes:        t.Scopes,
+		AgentIDs:      t.AgentIDs,
+		Capabilities:  t.Capabilities,
+		CreatedBy:     t.CreatedBy,
+		CreatedAt:     timeparse.Format(t.CreatedAt),
+		ExpiresAt:     timeparse.Format(t.ExpiresAt),
+		Status:        "active",
+	}
+	if t.LastUsedAt != nil {
+		item.LastUsedAt = timeparse.Format(*t.LastUsedAt)
+	}
+	switch {
+	case t.RevokedAt != nil:
+		item.RevokedAt = timeparse.Format(*t.RevokedAt)
+		item.Status = "revoked"
+	case !now.Before(t.ExpiresAt):
+		item.Status = "expired"
+	}
+	return item
+}
+
+// handleTokensJSON returns the token panel for the current admin.
+func (a *Admin) handleTokensJSON(w http.ResponseWriter, r *http.Request) {
+	panel, err := a.buildTokensPanel(r.Context(), getUserFromContext(r))
+	if err != nil {
+		a.logger.Error("failed to build tokens panel", "error", err)
+		http.Error(w, "Failed to list tokens", http.StatusInternalServerError)
+		return
+	}
+	a.writeJSON(w, panel)
+}
+
+// ensureOwnPrincipal returns the admin's client principal, creating and
+// linking one on first use.
+func (a *Admin) ensureOwnPrincipal(ctx context.Context, user *store.AdminUser) (string, error) {
+	if user.PrincipalID != "" {
+		return user.PrincipalID, nil
+	}
+	if a.principalStore == nil {
+		return "", errors.New("principal store not configured")
+	}
+	fp, err := generateSecureToken(32)
+	if err != nil {
+		return "", fmt.Errorf("generating principal fingerprint: %w", err)
+	}
+	p := &store.Principal{
+		ID:          uuid.New().String(),
+		Type:        store.PrincipalTypeClient,
+		PubkeyFP:    fp,
+		DisplayName: user.DisplayName + " (API)",
+		Status:      store.PrincipalStatusApproved,
+		CreatedAt:   time.Now(),
+	}
+	if err := a.principalStore.CreatePrincipal(ctx, p); err != nil {
+		return "", fmt.Errorf("creating principal: %w", err)
+	}
+	if err := a.principalStore.AddRole(ctx, store.RoleSubjectPrincipal, p.ID, store.RoleAdmin); err != nil {
+		return "", fmt.Errorf("granting admin role: %w", err)
+	}
+	if err := a.store.SetAdminUserPrincipal(ctx, user.ID, p.ID); err != nil {
+		return "", fmt.Errorf("linking principal: %w", err)
+	}
+	user.PrincipalID = p.ID
+	a.logger.Info("created API principal for admin user", "username", user.Username, "principal_id", p.ID)
+	return p.ID, nil
+}
+
+// handleCreateToken mints a token. The response is the only place the
+// token value ever appears.
+func (a *Admin) handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	if !a.tokensEnabled() {
+		http.Error(w, "Token minting is not configured (no jwt_secret)", http.StatusServiceUnavailable)
+		return
+	}
+	user := getUserFromContext(r)
+
+	var req createTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := auth.ValidateScopes(req.Scopes); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ttl := defaultTokenTTL
+	if req.TTLSeconds < 0 {
+		http.Error(w, "ttlSeconds must be positive", http.StatusBadRequest)
+		return
+	}
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+		if ttl > maxTokenTTL {
+			http.Error(w, fmt.Sprintf("ttlSeconds exceeds maximum of %d", int64(maxTokenTTL.Seconds())), http.StatusBadRequest)
+			return
+		}
+	}
+
+	principal, ok := a.resolveTokenPrincipal(w, r, user, req.PrincipalID)
+	if !ok {
+		return
+	}
+
+	token, rec, err := a.tokenMinter.Mint(r.Context(), auth.MintRequest{
+		PrincipalID: principal.ID,
+		CreatedBy:   user.Username,
+		TTL:         ttl,
+		Scopes:      req.Scopes,
+
+		AgentIDs:     req.AgentIDs,
+		Capabilities: req.Capabilities,
+	})
+	if err != nil {
+		a.logger.Error("failed to mint token", "principal_id", principal.ID, "error", err)
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	detail := map[string]any{
+		"admin_user":  user.Username,
+		"token_id":    rec.ID,
+		"scopes":      rec.Scopes,
+		"ttl_seconds": int64(ttl.Seconds()),
+		"expires_at":  timeparse.Format(rec.ExpiresAt),
+	}
+	if rec.AgentIDs != nil {
+		detail["agent_ids"] = rec.AgentIDs
+	}
+	if rec.Capabilities != nil {
+		detail["capabilities"] = rec.Capabilities
+	}
+	a.auditAdminAction(r, &store.AuditEntry{
+		ActorPrincipalID: user.ID,
+		Action:           store.AuditCreateToken,
+		TargetType:       "principal",
+		TargetID:         principal.ID,
+		Detail:           detail,
+	})
+	a.logger.Info("api token created", "token_id", rec.ID, "principal_id", principal.ID, "by", user.Username)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	a.writeJSON(w, createTokenResponse{Token: token, Record: newTokenItem(rec, principal.DisplayName, time.Now())})
+}
+
+// resolveTokenPrincipal picks the principal a new token is for, enforcing
+// that only owners mint for principals other than their own. It writes the
+// error response and returns false on failure.
+func (a *Admin) resolveTokenPrincipal(w http.ResponseWriter, r *http.Request, user *store.AdminUser, principalID string) (*store.Principal, bool) {
+	ctx := r.Context()
+	if principalID == "" || principalID == user.PrincipalID {
+		own, err := a.ensureOwnPrincipal(ctx, user)
+		if err != nil {
+			a.logger.Error("failed to prepare admin principal", "username", user.Username, "error", err)
+			http.Error(w, "Failed to create token", http.StatusInternalServerError)
+			return nil, false
+		}
+		principalID = own
+	} else if !a.tokenAccessFor(ctx, user).canManage(principalID) {
+		http.Error(w, "Only owners can create tokens for other principals", http.StatusForbidden)
+		return nil, false
+	}
+
+	principal, err := a.store.GetPrincipal(ctx, principalID)
+	if err != nil {
+		http.Error(w, "Principal not found", http.StatusNotFound)
+		return nil, false
+	}
+	if principal.Status == store.PrincipalStatusRevoked || principal.Status == store.PrincipalStatusPending {
+		http.Error(w, fmt.Sprintf("Principal is %s", principal.Status), http.StatusConflict)
+		return nil, false
+	}
+	return principal, true
+}
+
+// handleRevokeToken revokes a token the admin is allowed to manage.
+func (a *Admin) handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	user := getUserFromContext(r)
+	id := r.PathValue("id")
+
+	tok, err := a.store.GetAPIToken(r.Context(), id)
+	if errors.Is(err, store.ErrAPITokenNotFound) {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		a.logger.Error("failed to load token", "token_id", id, "error", err)
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+	if !a.tokenAccessFor(r.Context(), user).canManage(tok.PrincipalID) {
+		// Indistinguishable from a missing token so IDs can't be probed.
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	alreadyRevoked := tok.RevokedAt != nil
+	if !alreadyRevoked {
+		ctx := auditedContext(r, newAuditEntry(r, store.AuditRevokeToken, "token", id, map[string]any{
+			"principal_id": tok.PrincipalID,
+			"scopes":       tok.Scopes,
+		}))
+		if err := a.store.RevokeAPIToken(ctx, id, user.Username); err != nil {
+			a.logger.Error("failed to revoke token", "token_id", id, "error", err)
+			http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+			return
+		}
+		a.logger.Info("api token revoked", "token_id", id, "principal_id", tok.PrincipalID, "by", user.Username)
+	}
+
+	tok, err = a.store.GetAPIToken(r.Context(), id)
+	if err != nil {
+		a.logger.Error("failed to reload token", "token_id", id, "error", err)
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+	name := ""
+	if p, err := a.store.GetPrincipal(r.Context(), tok.PrincipalID); err == nil {
+		name = p.DisplayName
+	}
+	a.writeJSON(w, newTokenItem(tok, name, time.Now()))
+}
@@ -0,0 +1,217 @@
+// ABOUTME: Tests for the settings page API token endpoints.
+// ABOUTME: Covers one-time display, owner vs own-principal access, revocation, and audit entries.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+var tokensTestSecret = []byte("webadmin-tokens-test-secret-32b!")
+
+// newTokensAdmin returns an admin wired for token minting plus a verifier
+// that checks tokens against their records.
+func newTokensAdmin(t *testing.T) (*Admin, *store.SQLStore, *auth.TrackedVerifier) {
+	t.Helper()
+	admin, s := newThreadOpsAdmin(t)
+	jwtVerifier, err := auth.NewJWTVerifier(tokensTestSecret)
+	if err != nil {
+		t.Fatalf("NewJWTVerifier: %v", err)
+	}
+	admin.principalStore = s
+	admin.tokenGenerator = jwtVerifier
+	admin.tokenMinter = auth.NewTokenMinter(jwtVerifier, s)
+	return admin, s, auth.NewTrackedVerifier(jwtVerifier, s, nil)
+}
+
+// seedTokenAdmin creates an admin user, optionally linked to a principal
+// holding role.
+func seedTokenAdmin(t *testing.T, s *store.SQLStore, username, principalID string, role store.RoleName) *store.AdminUser {
+	t.Helper()
+	ctx := context.Background()
+	user := &store.AdminUser{ID: "user-" + username, Username: username, DisplayName: username, CreatedAt: time.Now()}
+	if err := s.CreateAdminUser(ctx, user); err != nil {
+		t.Fatalf("CreateAdminUser: %v", err)
+	}
+	if principalID == "" {
+		return user
+	}
+	seedTokenPrincipal(t, s, principalID)
+	if err := s.AddRole(ctx, store.RoleSubjectPrincipal, principalID, role); err != nil {
+		t.Fatalf("AddRole: %v", err)
+	}
+	if err := s.SetAdminUserPrincipal(ctx, user.ID, principalID); err != nil {
+		t.Fatalf("SetAdminUserPrincipal: %v", err)
+	}
+	user.PrincipalID = principalID
+	return user
+}
+
+func seedTokenPrincipal(t *testing.T, s *store.SQLStore, id string) {
+	t.Helper()
+	if err := s.CreatePrincipal(context.Background(), &store.Principal{
+		ID: id, Type: store.PrincipalTypeClient, PubkeyFP: "fp-" + id, DisplayName: id,
+		Status: store.PrincipalStatusApproved, CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreatePrincipal: %v", err)
+	}
+}
+
+func tokenRequestAs(user *store.AdminUser, method, path, body string) *http.Request {
+	req := csrfJSONRequest(method, path, body)
+	return req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+}
+
+func createTokenAs(t *testing.T, admin *Admin, user *store.AdminUser, body string) (*httptest.ResponseRecorder, createTokenResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	admin.handleCreateToken(rec, tokenRequestAs(user, http.MethodPost, "/api/admin/tokens", body))
+	var resp createTokenResponse
+	if rec.Code == http.StatusCreated {
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+	}
+	return rec, resp
+}
+
+func TestHandleCreateToken_OwnPrincipalShownOnce(t *testing.T) {
+	admin, s, verifier := newTokensAdmin(t)
+	user := seedTokenAdmin(t, s, "alice", "", "")
+
+	rec, resp := createTokenAs(t, admin, user, `{"ttlSeconds":3600,"scopes":["client"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Error("token response is cacheable")
+	}
+	if user.PrincipalID == "" || resp.Record.PrincipalID != user.PrincipalID {
+		t.Fatalf("record principal = %q, want the admin's new principal %q", resp.Record.PrincipalID, user.PrincipalID)
+	}
+	claims, err := verifier.VerifyClaims(resp.Token)
+	if err != nil {
+		t.Fatalf("minted token does not verify: %v", err)
+	}
+	if claims.TokenID != resp.Record.ID || len(claims.Scopes) != 1 || claims.Scopes[0] != auth.ScopeClient {
+		t.Errorf("claims = %+v, want jti %s with [client]", claims, resp.Record.ID)
+	}
+
+	// The link survives a reload, so the next session sees its tokens.
+	stored, err := s.GetAdminUser(context.Background(), user.ID)
+	if err != nil || stored.PrincipalID != user.PrincipalID {
+		t.Fatalf("stored principal = %v (%v), want %s", stored, err, user.PrincipalID)
+	}
+
+	list := httptest.NewRecorder()
+	admin.handleTokensJSON(list, tokenRequestAs(stored, http.MethodGet, "/api/admin/tokens", ""))
+	if strings.Contains(list.Body.String(), resp.Token) {
+		t.Fatal("token value retrievable from the list endpoint")
+	}
+	var panel tokensPanel
+	if err := json.NewDecoder(list.Body).Decode(&panel); err != nil {
+		t.Fatalf("decoding list: %v", err)
+	}
+	if len(panel.Tokens) != 1 || panel.Tokens[0].ID != resp.Record.ID || panel.Tokens[0].LastUsedAt == "" {
+		t.Errorf("tokens = %+v, want the new token with its last use", panel.Tokens)
+	}
+	if panel.ManageAll {
+		t.Error("admin without the owner role can manage all tokens")
+	}
+
+	action := store.AuditCreateToken
+	entries, err := s.ListAuditLog(context.Background(), store.AuditFilter{Action: &action})
+	if err != nil || len(entries) != 1 || entries[0].Detail["token_id"] != resp.Record.ID {
+		t.Errorf("audit entries = %+v (%v), want one create_token for the token", entries, err)
+	}
+}
+
+func TestHandleCreateToken_Validation(t *testing.T) {
+	admin, s, _ := newTokensAdmin(t)
+	user := seedTokenAdmin(t, s, "alice", "p-alice", store.RoleAdmin)
+	seedTokenPrincipal(t, s, "p-other")
+
+	for name, tc := range map[string]struct {
+		body string
+		want int
+	}{
+		"missing client scope": {`{"scopes":["admin"]}`, http.StatusBadRequest},
+		"unknown scope":        {`{"scopes":["client","root"]}`, http.StatusBadRequest},
+		"ttl too long":         {`{"scopes":["client"],"ttlSeconds":40000000}`, http.StatusBadRequest},
+		"other principal":      {`{"scopes":["client"],"principalId":"p-other"}`, http.StatusForbidden},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if rec, _ := createTokenAs(t, admin, user, tc.body); rec.Code != tc.want {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/tokens", strings.NewReader(`{"scopes":["client"]}`))
+	admin.handleCreateToken(rec, req.WithContext(context.WithValue(req.Context(), userContextKey, user)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status without CSRF = %d, want 403", rec.Code)
+	}
+}
+
+func TestHandleRevokeToken_OwnerManagesAll(t *testing.T) {
+	admin, s, verifier := newTokensAdmin(t)
+	owner := seedTokenAdmin(t, s, "olivia", "p-owner", store.RoleOwner)
+	alice := seedTokenAdmin(t, s, "alice", "p-alice", store.RoleAdmin)
+
+	// The owner can mint for another principal.
+	rec, resp := createTokenAs(t, admin, owner, `{"principalId":"p-alice","scopes":["client","admin"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("owner create status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	_, ownerTok := createTokenAs(t, admin, owner, `{"scopes":["client"]}`)
+
+	// Alice sees her token but cannot touch the owner's.
+	deny := httptest.NewRecorder()
+	req := tokenRequestAs(alice, http.MethodPost, "/api/admin/tokens/"+ownerTok.Record.ID+"/revoke", "")
+	req.SetPathValue("id", ownerTok.Record.ID)
+	admin.handleRevokeToken(deny, req)
+	if deny.Code != http.StatusNotFound {
+		t.Errorf("revoking another principal's token = %d, want 404", deny.Code)
+	}
+
+	ok := httptest.NewRecorder()
+	req = tokenRequestAs(owner, http.MethodPost, "/api/admin/tokens/"+resp.Record.ID+"/revoke", "")
+	req.SetPathValue("id", resp.Record.ID)
+	admin.handleRevokeToken(ok, req)
+	if ok.Code != http.StatusOK {
+		t.Fatalf("owner revoke status = %d, body = %s", ok.Code, ok.Body.String())
+	}
+	var item tokenItem
+	if err := json.NewDecoder(ok.Body).Decode(&item); err != nil || item.Status != "revoked" {
+		t.Errorf("revoked item = %+v (%v), want status revoked", item, err)
+	}
+	if _, err := verifier.Verify(resp.Token); !errors.Is(err, auth.ErrRevokedToken) {
+		t.Errorf("Verify after revoke = %v, want ErrRevokedToken", err)
+	}
+
+	action := store.AuditRevokeToken
+	entries, err := s.ListAuditLog(context.Background(), store.AuditFilter{Action: &action})
+	if err != nil || len(entries) != 1 || entries[0].TargetID != resp.Record.ID {
+		t.Errorf("audit entries = %+v (%v), want one revoke_token", entries, err)
+	}
+
+	panel, err := admin.buildTokensPanel(context.Background(), owner)
+	if err != nil {
+		t.Fatalf("buildTokensPanel: %v", err)
+	}
+	if !panel.ManageAll || len(panel.Tokens) != 2 {
+		t.Errorf("owner panel = %+v, want every token", panel)
+	}
+}
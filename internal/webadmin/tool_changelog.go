@@ -0,0 +1,114 @@
+// ABOUTME: Admin handler for the pack tool catalog changelog
+// ABOUTME: Lists added/removed/modified tools and flags recent changes on the tools page
+
+package webadmin
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// toolChangeRecentWindow is how long a tool shows the "changed recently" indicator.
+const toolChangeRecentWindow = 7 * 24 * time.Hour
+
+type toolChangeItem struct {
+	ID             int64                   `json:"id"`
+	CatalogVersion int64                   `json:"catalogVersion"`
+	PackID         string                  `json:"packId"`
+	PackVersion    string                  `json:"packVersion"`
+	ToolName       string                  `json:"toolName"`
+	ChangeType     string                  `json:"changeType"`
+	Diff           []store.ToolFieldChange `json:"diff"`
+	CreatedAt      string                  `json:"createdAt"`
+}
+
+func toToolChangeItem(c *store.ToolChange) toolChangeItem {
+	diff := c.Diff
+	if diff == nil {
+		diff = []store.ToolFieldChange{}
+	}
+	return toolChangeItem{
+		ID:             c.ID,
+		CatalogVersion: c.CatalogVersion,
+		PackID:         c.PackID,
+		PackVersion:    c.PackVersion,
+		ToolName:       c.ToolName,
+		ChangeType:     string(c.ChangeType),
+		Diff:           diff,
+		CreatedAt:      timeparse.Format(c.CreatedAt),
+	}
+}
+
+// handleToolChangelogJSON returns tool catalog changes, newest first.
+// Query params: pack, since and until (RFC3339 or epoch), limit (max 500).
+func (a *Admin) handleToolChangelogJSON(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := store.ToolChangeFilter{PackID: q.Get("pack"), Limit: 100}
+	if limitStr := q.Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 500 {
+			filter.Limit = l
+		}
+	}
+	var err error
+	if filter.Since, err = timeparse.Query(q, "since"); err != nil {
+		a.writeTimestampError(w, err)
+		return
+	}
+	if filter.Until, err = timeparse.Query(q, "until"); err != nil {
+		a.writeTimestampError(w, err)
+		return
+	}
+
+	changes, err := a.store.ListToolChanges(r.Context(), filter)
+	if err != nil {
+		a.logger.Error("failed to list tool changes", "error", err)
+		http.Error(w, "Failed to load tool changelog", http.StatusInternalServerError)
+		return
+	}
+	version, err := a.store.GetToolCatalogVersion(r.Context())
+	if err != nil {
+		a.logger.Error("failed to read tool catalog version", "error", err)
+		http.Error(w, "Failed to load tool changelog", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]toolChangeItem, 0, len(changes))
+	for _, c := range changes {
+		items = append(items, toToolChangeItem(c))
+	}
+	a.writeJSON(w, map[string]any{
+		"catalogVersion": version,
+		"changes":        items,
+	})
+}
+
+// recentToolChanges returns the latest change within the recent window for
+// each tool, keyed by pack ID and tool name. Errors are logged and yield no
+// indicators rather than failing the tools page.
+func (a *Admin) recentToolChanges(ctx context.Context) map[string]*toolChangeItem {
+	recent := make(map[string]*toolChangeItem)
+	if a.store == nil {
+		return recent
+	}
+
+	since := time.Now().Add(-toolChangeRecentWindow)
+	changes, err := a.store.ListToolChanges(ctx, store.ToolChangeFilter{Since: &since, Limit: 500})
+	if err != nil {
+		a.logger.Warn("failed to load recent tool changes", "error", err)
+		return recent
+	}
+	for _, c := range changes {
+		key := c.PackID + "/" + c.ToolName
+		if _, seen := recent[key]; seen {
+			continue // changes are newest first
+		}
+		item := toToolChangeItem(c)
+		recent[key] = &item
+	}
+	return recent
+}
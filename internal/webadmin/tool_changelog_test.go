@@ -0,0 +1,81 @@
+// ABOUTME: Tests for the admin tool changelog endpoint and recent-change indicators.
+// ABOUTME: Uses a real SQLite store seeded with catalog changes.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+func TestHandleToolChangelogJSON_FiltersByPack(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	ctx := context.Background()
+	if _, err := s.SaveToolCatalog(ctx, "pack-a", map[string]string{"a": "{}"},
+		[]*store.ToolChange{{ToolName: "a", ChangeType: store.ToolAdded}}); err != nil {
+		t.Fatalf("SaveToolCatalog: %v", err)
+	}
+	if _, err := s.SaveToolCatalog(ctx, "pack-b", map[string]string{"b": "{}"},
+		[]*store.ToolChange{{ToolName: "b", ChangeType: store.ToolAdded}}); err != nil {
+		t.Fatalf("SaveToolCatalog: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/tools/changelog?pack=pack-b", nil)
+	rec := httptest.NewRecorder()
+	admin.handleToolChangelogJSON(rec, requestWithUser(req))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		CatalogVersion int64            `json:"catalogVersion"`
+		Changes        []toolChangeItem `json:"changes"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.CatalogVersion != 2 {
+		t.Errorf("catalogVersion = %d, want 2", resp.CatalogVersion)
+	}
+	if len(resp.Changes) != 1 || resp.Changes[0].ToolName != "b" || resp.Changes[0].CatalogVersion != 2 {
+		t.Errorf("changes = %+v, want only pack-b's tool", resp.Changes)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/tools/changelog?since=yesterday", nil)
+	rec = httptest.NewRecorder()
+	admin.handleToolChangelogJSON(rec, requestWithUser(req))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid since status = %d, want 400", rec.Code)
+	}
+}
+
+func TestListPackItems_FlagsRecentlyChangedTools(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	registry := packs.NewRegistry(slog.Default())
+	admin.registry = registry
+
+	manifest := &pb.PackManifest{PackId: "pack-a", Version: "1.0.0", Tools: []*pb.ToolDefinition{{Name: "tool-a"}}}
+	if _, _, err := packs.NewCatalogTracker(s, slog.Default()).Record(context.Background(), manifest); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := registry.RegisterPack("pack-a", manifest); err != nil {
+		t.Fatalf("RegisterPack: %v", err)
+	}
+
+	items := admin.listPackItems(context.Background())
+	if len(items) != 1 || len(items[0].Tools) != 1 {
+		t.Fatalf("items = %+v, want one pack with one tool", items)
+	}
+	change := items[0].Tools[0].RecentChange
+	if change == nil || change.ChangeType != string(store.ToolAdded) {
+		t.Errorf("recentChange = %+v, want added", change)
+	}
+}
@@ -0,0 +1,186 @@
+// ABOUTME: Admin API for per-agent tool overrides: turning single tools on or off for an agent
+// ABOUTME: Overrides are stored on the agent's principal and pushed to its connected agents
+
+package webadmin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// ToolOverrideNotifier sends a principal's connected agents their tool list
+// after its tool overrides change, so they take effect without a reconnect.
+type ToolOverrideNotifier interface {
+	ToolOverridesChanged(principalID string)
+}
+
+// agentToolItem is one tool on an agent's toggle list.
+type agentToolItem struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Pack        string `json:"pack"`
+	Enabled     bool   `json:"enabled"`
+	Reason      string `json:"reason"`
+	UpdatedBy   string `json:"updatedBy,omitempty"`
+	UpdatedAt   string `json:"updatedAt,omitempty"`
+}
+
+// agentTools is the response of GET and PUT /api/admin/agents/{id}/tools.
+type agentTools struct {
+	AgentID     string          `json:"agentId"`
+	PrincipalID string          `json:"principalId"`
+	Tools       []agentToolItem `json:"tools"`
+}
+
+// setAgentToolsRequest is the body of PUT /api/admin/agents/{id}/tools. Tools
+// it leaves out keep their current override.
+type setAgentToolsRequest struct {
+	Tools []struct {
+		Name    string `json:"name"`
+		Enabled bool   `json:"enabled"`
+		Reason  string `json:"reason"`
+	} `json:"tools"`
+}
+
+// SetToolOverrideNotifier wires the gateway hook told about tool override changes.
+func (a *Admin) SetToolOverrideNotifier(n ToolOverrideNotifier) {
+	a.toolOverrides = n
+}
+
+// handleAgentToolsJSON handles GET /api/admin/agents/{id}/tools.
+func (a *Admin) handleAgentToolsJSON(w http.ResponseWriter, r *http.Request) {
+	agentID, principalID, ok := a.toolOverridePrincipal(w, r)
+	if !ok {
+		return
+	}
+	a.writeAgentTools(w, r, agentID, principalID)
+}
+
+// handleSetAgentTools handles PUT /api/admin/agents/{id}/tools, upserting an
+// override for each tool in the body.
+func (a *Admin) handleSetAgentTools(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	agentID, principalID, ok := a.toolOverridePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var req setAgentToolsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for _, t := range req.Tools {
+		if strings.TrimSpace(t.Name) == "" {
+			http.Error(w, "Tool name is required", http.StatusBadRequest)
+			return
+		}
+	}
+
+	user := getUserFromContext(r)
+	for _, t := range req.Tools {
+		o := &store.ToolOverride{
+			PrincipalID: principalID,
+			ToolName:    strings.TrimSpace(t.Name),
+			Enabled:     t.Enabled,
+			Reason:      strings.TrimSpace(t.Reason),
+			UpdatedBy:   user.Username,
+		}
+		if err := a.store.SetToolOverride(r.Context(), o); err != nil {
+			a.logger.Error("failed to set tool override", "agent_id", agentID, "tool", o.ToolName, "error", err)
+			http.Error(w, "Failed to update tools", http.StatusInternalServerError)
+			return
+		}
+		a.auditAdminAction(r, newAuditEntry(r, store.AuditSetToolOverride, "agent", agentID, map[string]any{
+			"principal_id": principalID,
+			"tool":         o.ToolName,
+			"enabled":      o.Enabled,
+			"reason":       o.Reason,
+		}))
+	}
+
+	if a.toolOverrides != nil && len(req.Tools) > 0 {
+		a.toolOverrides.ToolOverridesChanged(principalID)
+	}
+	a.writeAgentTools(w, r, agentID, principalID)
+}
+
+// toolOverridePrincipal reads the agent from the path and resolves the
+// principal its overrides are stored on, writing an error response if there
+// is none.
+func (a *Admin) toolOverridePrincipal(w http.ResponseWriter, r *http.Request) (agentID, principalID string, ok bool) {
+	if a.manager == nil {
+		http.Error(w, "Agent manager not available", http.StatusServiceUnavailable)
+		return "", "", false
+	}
+	agentID = r.PathValue("id")
+	if agentID == "" {
+		http.Error(w, "Agent ID required", http.StatusBadRequest)
+		return "", "", false
+	}
+	principalID, found := a.agentPrincipalID(r, agentID)
+	if !found {
+		http.Error(w, "Agent not found", http.StatusNotFound)
+		return "", "", false
+	}
+	if principalID == "" {
+		http.Error(w, "Agent has no principal; tool overrides need auth enabled", http.StatusConflict)
+		return "", "", false
+	}
+	return agentID, principalID, true
+}
+
+// writeAgentTools writes every registered tool with its override for
+// principalID, plus overrides of tools that are not registered right now.
+func (a *Admin) writeAgentTools(w http.ResponseWriter, r *http.Request, agentID, principalID string) {
+	overrides, err := a.store.ListToolOverrides(r.Context(), principalID)
+	if err != nil {
+		a.logger.Error("failed to list tool overrides", "agent_id", agentID, "error", err)
+		http.Error(w, "Failed to load tools", http.StatusInternalServerError)
+		return
+	}
+
+	byName := make(map[string]*agentToolItem)
+	add := func(name, description, pack string) {
+		if _, ok := byName[name]; !ok {
+			byName[name] = &agentToolItem{Name: name, Description: description, Pack: pack, Enabled: true}
+		}
+	}
+	if a.registry != nil {
+		for _, bp := range a.registry.ListBuiltinPacks() {
+			for _, t := range bp.Tools {
+				if t.Definition != nil {
+					add(t.Definition.GetName(), t.Definition.GetDescription(), bp.ID)
+				}
+			}
+		}
+		for _, t := range a.registry.GetAllTools() {
+			if t.Definition != nil {
+				add(t.Definition.GetName(), t.Definition.GetDescription(), t.PackID)
+			}
+		}
+	}
+	for _, o := range overrides {
+		add(o.ToolName, "", "")
+		item := byName[o.ToolName]
+		item.Enabled = o.Enabled
+		item.Reason = o.Reason
+		item.UpdatedBy = o.UpdatedBy
+		item.UpdatedAt = timeparse.Format(o.UpdatedAt)
+	}
+
+	tools := make([]agentToolItem, 0, len(byName))
+	for _, item := range byName {
+		tools = append(tools, *item)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	a.writeJSON(w, agentTools{AgentID: agentID, PrincipalID: principalID, Tools: tools})
+}
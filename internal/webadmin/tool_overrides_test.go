@@ -0,0 +1,116 @@
+// ABOUTME: Tests for the per-agent tool override admin API: listing, toggling,
+// ABOUTME: notifying connected agents, auditing, and agents without a principal.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/agent"
+	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
+)
+
+type recordingToolOverrideNotifier struct {
+	principals []string
+}
+
+func (n *recordingToolOverrideNotifier) ToolOverridesChanged(principalID string) {
+	n.principals = append(n.principals, principalID)
+}
+
+func agentToolsRequest(method, agentID, body string) *http.Request {
+	req := csrfJSONRequest(method, "/api/admin/agents/"+agentID+"/tools", body)
+	req.SetPathValue("id", agentID)
+	return req
+}
+
+func TestHandleAgentTools(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	ctx := context.Background()
+	admin.manager = agent.NewManager(slog.Default())
+	admin.registry = packs.NewRegistry(slog.Default())
+	if err := admin.registry.RegisterPack("bbs-pack", &pb.PackManifest{PackId: "bbs-pack", Version: "1.0.0", Tools: []*pb.ToolDefinition{
+		{Name: "bbs_create_thread", Description: "Start a thread"}, {Name: "bbs_list_threads"},
+	}}); err != nil {
+		t.Fatalf("RegisterPack: %v", err)
+	}
+	conn := agent.NewConnection(agent.ConnectionParams{ID: "agent-1", Name: "Agent", PrincipalID: "principal-1", Logger: slog.Default()})
+	if err := admin.manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	notifier := &recordingToolOverrideNotifier{}
+	admin.SetToolOverrideNotifier(notifier)
+
+	rec := httptest.NewRecorder()
+	admin.handleSetAgentTools(rec, agentToolsRequest(http.MethodPut, "agent-1",
+		`{"tools":[{"name":"bbs_create_thread","enabled":false,"reason":"too noisy"}]}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp agentTools
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.PrincipalID != "principal-1" || len(resp.Tools) != 2 {
+		t.Fatalf("response = %+v, want two tools for principal-1", resp)
+	}
+	if got := resp.Tools[0]; got.Name != "bbs_create_thread" || got.Enabled || got.Reason != "too noisy" || got.UpdatedBy != "testadmin" || got.Pack != "bbs-pack" {
+		t.Errorf("bbs_create_thread = %+v, want disabled by testadmin for being too noisy", got)
+	}
+	if got := resp.Tools[1]; got.Name != "bbs_list_threads" || !got.Enabled {
+		t.Errorf("bbs_list_threads = %+v, want enabled", got)
+	}
+	if len(notifier.principals) != 1 || notifier.principals[0] != "principal-1" {
+		t.Errorf("notified principals = %v, want [principal-1]", notifier.principals)
+	}
+
+	disabled, err := s.DisabledTools(ctx, "principal-1")
+	if err != nil || disabled["bbs_create_thread"] != "too noisy" {
+		t.Errorf("DisabledTools = %v, %v; want bbs_create_thread disabled", disabled, err)
+	}
+	action := store.AuditSetToolOverride
+	if entries, err := s.ListAuditLog(ctx, store.AuditFilter{Action: &action}); err != nil || len(entries) != 1 || entries[0].TargetID != "agent-1" {
+		t.Errorf("audit entries = %+v, %v; want one for agent-1", entries, err)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleAgentToolsJSON(rec, agentToolsRequest(http.MethodGet, "agent-1", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d", rec.Code)
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Tools[0].Enabled {
+		t.Errorf("GET tools = %+v, %v; want bbs_create_thread disabled", resp.Tools, err)
+	}
+}
+
+func TestHandleAgentTools_Guards(t *testing.T) {
+	admin, _ := newThreadOpsAdmin(t)
+	admin.manager = agent.NewManager(slog.Default())
+	conn := agent.NewConnection(agent.ConnectionParams{ID: "anon", Name: "Anon", Logger: slog.Default()})
+	if err := admin.manager.Register(conn); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/agents/anon/tools", nil)
+	req.SetPathValue("id", "anon")
+	rec := httptest.NewRecorder()
+	admin.handleSetAgentTools(rec, requestWithUser(req))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("without CSRF status = %d, want 403", rec.Code)
+	}
+
+	for agentID, want := range map[string]int{"unknown": http.StatusNotFound, "anon": http.StatusConflict} {
+		rec = httptest.NewRecorder()
+		admin.handleAgentToolsJSON(rec, agentToolsRequest(http.MethodGet, agentID, ""))
+		if rec.Code != want {
+			t.Errorf("%s status = %d, want %d", agentID, rec.Code, want)
+		}
+	}
+}
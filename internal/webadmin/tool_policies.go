@@ -0,0 +1,327 @@
+// ABOUTME: Admin page and API for tool approval policies: ordered allow/deny/ask rules and a default
+// ABOUTME: Every change is validated with the packs evaluator's rules and audited
+
+package webadmin
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+)
+
+// toolPolicyOperators are the parameter matcher operators offered by the page.
+var toolPolicyOperators = []string{
+	packs.MatchEquals,
+	packs.MatchNotEquals,
+	packs.MatchStartsWith,
+	packs.MatchEndsWith,
+	packs.MatchContains,
+	packs.MatchGlob,
+}
+
+// toolPolicyItem is one policy rule as shown on the tool policies page.
+type toolPolicyItem struct {
+	ID          string                    `json:"id"`
+	Position    int                       `json:"position"`
+	AgentID     string                    `json:"agentId,omitempty"`
+	Group       string                    `json:"group,omitempty"`
+	ToolPattern string                    `json:"toolPattern"`
+	Action      string                    `json:"action"`
+	Matchers    []store.ToolPolicyMatcher `json:"matchers"`
+	Description string                    `json:"description,omitempty"`
+	CreatedAt   string                    `json:"createdAt"`
+	UpdatedAt   string                    `json:"updatedAt"`
+	CreatedBy   string                    `json:"createdBy,omitempty"`
+}
+
+// toolPoliciesResponse is the body of GET /api/admin/tool-policies.
+type toolPoliciesResponse struct {
+	Policies      []toolPolicyItem `json:"policies"`
+	DefaultAction string           `json:"defaultAction"`
+}
+
+// toolPolicyRequest is the body of POST /api/admin/tool-policies and
+// PUT /api/admin/tool-policies/{id}.
+type toolPolicyRequest struct {
+	AgentID     string                    `json:"agentId"`
+	Group       string                    `json:"group"`
+	ToolPattern string                    `json:"toolPattern"`
+	Action      string                    `json:"action"`
+	Matchers    []store.ToolPolicyMatcher `json:"matchers"`
+	Description string                    `json:"description"`
+}
+
+type toolPoliciesPageData struct {
+	Title     string
+	User      *store.AdminUser
+	CSRFToken string
+	PropsJSON template.JS
+}
+
+func newToolPolicyItem(p *store.ToolPolicy) toolPolicyItem {
+	matchers := p.Matchers
+	if matchers == nil {
+		matchers = []store.ToolPolicyMatcher{}
+	}
+	return toolPolicyItem{
+		ID:          p.ID,
+		Position:    p.Position,
+		AgentID:     p.AgentID,
+		Group:       p.Group,
+		ToolPattern: p.ToolPattern,
+		Action:      p.Action,
+		Matchers:    matchers,
+		Description: p.Description,
+		CreatedAt:   timeparse.Format(p.CreatedAt),
+		UpdatedAt:   timeparse.Format(p.UpdatedAt),
+		CreatedBy:   p.CreatedBy,
+	}
+}
+
+// loadToolPolicies returns every policy in evaluation order with the default.
+func (a *Admin) loadToolPolicies(r *http.Request) (*toolPoliciesResponse, error) {
+	policies, err := a.store.ListToolPolicies(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	defaultAction, err := a.store.GetToolPolicyDefault(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp := &toolPoliciesResponse{Policies: make([]toolPolicyItem, 0, len(policies)), DefaultAction: defaultAction}
+	for _, p := range policies {
+		resp.Policies = append(resp.Policies, newToolPolicyItem(p))
+	}
+	return resp, nil
+}
+
+// handleToolPoliciesPage renders the tool policies page.
+func (a *Admin) handleToolPoliciesPage(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	csrfToken := a.ensureCSRFToken(w, r)
+
+	policies, err := a.loadToolPolicies(r)
+	if err != nil {
+		a.logger.Error("failed to load tool policies", "error", err)
+		policies = &toolPoliciesResponse{Policies: []toolPolicyItem{}, DefaultAction: store.DefaultToolPolicyAction}
+	}
+
+	propsJSON, err := json.Marshal(map[string]any{
+		"policies":      policies.Policies,
+		"defaultAction": policies.DefaultAction,
+		"operators":     toolPolicyOperators,
+		"userName":      user.DisplayName,
+		"csrfToken":     csrfToken,
+	})
+	if err != nil {
+		a.logger.Error("failed to marshal tool policies props", "error", err)
+		propsJSON = []byte(`{"policies":[],"csrfToken":""}`)
+	}
+
+	tmpl := parseTemplate("templates/base.html", "templates/tool_policies.html")
+	data := toolPoliciesPageData{
+		Title:     "Tool Policies",
+		User:      user,
+		CSRFToken: csrfToken,
+		PropsJSON: template.JS(propsJSON),
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		a.logger.Error("failed to render tool policies page", "error", err)
+	}
+}
+
+// handleToolPoliciesJSON returns every policy in evaluation order with the
+// default action.
+func (a *Admin) handleToolPoliciesJSON(w http.ResponseWriter, r *http.Request) {
+	policies, err := a.loadToolPolicies(r)
+	if err != nil {
+		a.logger.Error("failed to load tool policies", "error", err)
+		http.Error(w, "Failed to load tool policies", http.StatusInternalServerError)
+		return
+	}
+	a.writeJSON(w, policies)
+}
+
+// decodeToolPolicy reads and validates a policy rule from the request body,
+// writing a 400 and returning nil when it is not acceptable.
+func decodeToolPolicy(w http.ResponseWriter, r *http.Request) *store.ToolPolicy {
+	var req toolPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil
+	}
+	p := &store.ToolPolicy{
+		AgentID:     strings.TrimSpace(req.AgentID),
+		Group:       strings.TrimSpace(req.Group),
+		ToolPattern: strings.TrimSpace(req.ToolPattern),
+		Action:      req.Action,
+		Description: strings.TrimSpace(req.Description),
+	}
+	for _, m := range req.Matchers {
+		m.Param = strings.TrimSpace(m.Param)
+		p.Matchers = append(p.Matchers, m)
+	}
+	if err := packs.ValidateToolPolicy(p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	return p
+}
+
+// toolPolicyDetail describes a rule for its audit entry.
+func toolPolicyDetail(p *store.ToolPolicy) map[string]any {
+	return map[string]any{
+		"agent_id":     p.AgentID,
+		"group":        p.Group,
+		"tool_pattern": p.ToolPattern,
+		"action":       p.Action,
+		"matchers":     p.Matchers,
+	}
+}
+
+// handleCreateToolPolicy appends a policy rule after the existing ones.
+func (a *Admin) handleCreateToolPolicy(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+	p := decodeToolPolicy(w, r)
+	if p == nil {
+		return
+	}
+	p.ID = uuid.New().String()
+	p.CreatedBy = getUserFromContext(r).Username
+
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditCreateToolPolicy, "tool_policy", p.ID, toolPolicyDetail(p)))
+	if err := a.store.CreateToolPolicy(ctx, p); err != nil {
+		a.logger.Error("failed to create tool policy", "error", err)
+		http.Error(w, "Failed to create tool policy", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	a.writeJSON(w, newToolPolicyItem(p))
+}
+
+// handleUpdateToolPolicy replaces a policy rule, keeping its place in the order.
+func (a *Admin) handleUpdateToolPolicy(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+	p := decodeToolPolicy(w, r)
+	if p == nil {
+		return
+	}
+	p.ID = r.PathValue("id")
+
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditUpdateToolPolicy, "tool_policy", p.ID, toolPolicyDetail(p)))
+	if err := a.store.UpdateToolPolicy(ctx, p); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Tool policy not found", http.StatusNotFound)
+			return
+		}
+		a.logger.Error("failed to update tool policy", "id", p.ID, "error", err)
+		http.Error(w, "Failed to update tool policy", http.StatusInternalServerError)
+		return
+	}
+	updated, err := a.store.GetToolPolicy(r.Context(), p.ID)
+	if err != nil {
+		a.logger.Error("failed to reload tool policy", "id", p.ID, "error", err)
+		http.Error(w, "Failed to update tool policy", http.StatusInternalServerError)
+		return
+	}
+	a.writeJSON(w, newToolPolicyItem(updated))
+}
+
+// handleDeleteToolPolicy removes a policy rule.
+func (a *Admin) handleDeleteToolPolicy(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+	id := r.PathValue("id")
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditDeleteToolPolicy, "tool_policy", id, nil))
+	if err := a.store.DeleteToolPolicy(ctx, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Tool policy not found", http.StatusNotFound)
+			return
+		}
+		a.logger.Error("failed to delete tool policy", "id", id, "error", err)
+		http.Error(w, "Failed to delete tool policy", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleReorderToolPolicies sets the evaluation order from a body of
+// {"ids": [...]} listing every policy once.
+func (a *Admin) handleReorderToolPolicies(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditUpdateToolPolicy, "tool_policy", "order", map[string]any{
+		"order": req.IDs,
+	}))
+	if err := a.store.ReorderToolPolicies(ctx, req.IDs); err != nil {
+		if errors.Is(err, store.ErrToolPolicyOrder) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a.logger.Error("failed to reorder tool policies", "error", err)
+		http.Error(w, "Failed to reorder tool policies", http.StatusInternalServerError)
+		return
+	}
+	a.handleToolPoliciesJSON(w, r)
+}
+
+// handleSetToolPolicyDefault sets the action for calls no rule matches from
+// a body of {"action": "allow"|"deny"|"ask"}.
+func (a *Admin) handleSetToolPolicyDefault(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+	var req struct {
+		Action string `json:"action"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !packs.ValidToolPolicyAction(req.Action) {
+		http.Error(w, "action must be allow, deny or ask", http.StatusBadRequest)
+		return
+	}
+
+	user := getUserFromContext(r)
+	detail := map[string]any{"action": req.Action}
+	if previous, err := a.store.GetToolPolicyDefault(r.Context()); err == nil {
+		detail["previous"] = previous
+	}
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditUpdateToolPolicy, "tool_policy", "default", detail))
+	if err := a.store.SetToolPolicyDefault(ctx, req.Action, user.Username); err != nil {
+		a.logger.Error("failed to set tool policy default", "error", err)
+		http.Error(w, "Failed to set default action", http.StatusInternalServerError)
+		return
+	}
+	a.writeJSON(w, map[string]string{"defaultAction": req.Action})
+}
@@ -0,0 +1,117 @@
+// ABOUTME: Tests for the tool policies admin API.
+// ABOUTME: Covers create/update/delete, reordering, the default action, validation, CSRF, and audit entries.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/2389/coven-gateway/internal/store"
+)
+
+func TestToolPoliciesAPI(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	ctx := context.Background()
+
+	create := func(body string) toolPolicyItem {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		admin.handleCreateToolPolicy(rec, csrfJSONRequest(http.MethodPost, "/api/admin/tool-policies", body))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		var item toolPolicyItem
+		if err := json.NewDecoder(rec.Body).Decode(&item); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return item
+	}
+	tmp := create(`{"toolPattern":"fs_*","action":"allow","matchers":[{"param":" path ","op":"startswith","value":"/tmp/"}]}`)
+	deny := create(`{"group":"ops","toolPattern":"shell","action":"deny","description":"no shell for ops"}`)
+	if tmp.Position != 1 || deny.Position != 2 || tmp.Matchers[0].Param != "path" {
+		t.Errorf("created %+v and %+v, want positions 1 and 2 with a trimmed param", tmp, deny)
+	}
+
+	for body, want := range map[string]string{
+		`{"toolPattern":"*","action":"maybe"}`:                                       "action",
+		`{"agentId":"a","group":"ops","toolPattern":"*","action":"ask"}`:             "not both",
+		`{"toolPattern":"*","action":"ask","matchers":[{"param":"p","op":"regex"}]}`: "unknown operator",
+		`{"toolPattern":"","action":"ask"}`:                                          "required",
+	} {
+		rec := httptest.NewRecorder()
+		admin.handleCreateToolPolicy(rec, csrfJSONRequest(http.MethodPost, "/api/admin/tool-policies", body))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("create %s = %d %q, want 400 mentioning %q", body, rec.Code, rec.Body.String(), want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	req := csrfJSONRequest(http.MethodPut, "/api/admin/tool-policies/"+deny.ID, `{"group":"ops","toolPattern":"shell*","action":"ask"}`)
+	req.SetPathValue("id", deny.ID)
+	admin.handleUpdateToolPolicy(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"toolPattern":"shell*"`) {
+		t.Errorf("update = %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleReorderToolPolicies(rec, csrfJSONRequest(http.MethodPut, "/api/admin/tool-policies/order", `{"ids":["`+tmp.ID+`"]}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("partial reorder status = %d, want 400", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	admin.handleReorderToolPolicies(rec, csrfJSONRequest(http.MethodPut, "/api/admin/tool-policies/order", `{"ids":["`+deny.ID+`","`+tmp.ID+`"]}`))
+	var list toolPoliciesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("reorder = %d, %v", rec.Code, err)
+	}
+	if len(list.Policies) != 2 || list.Policies[0].ID != deny.ID || list.DefaultAction != store.ToolPolicyAsk {
+		t.Errorf("after reorder = %+v, want the ops rule first and the default ask", list)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleSetToolPolicyDefault(rec, csrfJSONRequest(http.MethodPut, "/api/admin/tool-policies/default", `{"action":"sometimes"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid default status = %d, want 400", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	admin.handleSetToolPolicyDefault(rec, csrfJSONRequest(http.MethodPut, "/api/admin/tool-policies/default", `{"action":"deny"}`))
+	if action, _ := s.GetToolPolicyDefault(ctx); rec.Code != http.StatusOK || action != store.ToolPolicyDeny {
+		t.Errorf("set default = %d, stored %q; want deny", rec.Code, action)
+	}
+
+	rec = httptest.NewRecorder()
+	req = csrfJSONRequest(http.MethodDelete, "/api/admin/tool-policies/"+tmp.ID, "")
+	req.SetPathValue("id", tmp.ID)
+	admin.handleDeleteToolPolicy(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d, want 204", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	admin.handleDeleteToolPolicy(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("repeat delete status = %d, want 404", rec.Code)
+	}
+
+	for action, want := range map[store.AuditAction]int{
+		store.AuditCreateToolPolicy: 2,
+		store.AuditUpdateToolPolicy: 3, // the rule, the order, the default
+		store.AuditDeleteToolPolicy: 1,
+	} {
+		entries, err := s.ListAuditLog(ctx, store.AuditFilter{Action: &action})
+		if err != nil || len(entries) != want {
+			t.Errorf("%s audit entries = %d, %v; want %d", action, len(entries), err, want)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	noCSRF := requestWithUser(httptest.NewRequest(http.MethodPost, "/api/admin/tool-policies", strings.NewReader(`{"toolPattern":"*","action":"allow"}`)))
+	admin.handleCreateToolPolicy(rec, noCSRF)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("without CSRF status = %d, want 403", rec.Code)
+	}
+}
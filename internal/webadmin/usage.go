@@ -0,0 +1,286 @@
+// ABOUTME: Token usage drill-down handlers: agents, then an agent's threads, then a thread's requests
+// ABOUTME: Every level takes the same since/until window and can be exported as CSV with format=csv
+
+package webadmin
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+	"github.com/2389/coven-gateway/internal/usage"
+)
+
+// usageThreadLimit caps the threads listed for one agent.
+const usageThreadLimit = 500
+
+// usageTotals is the JSON form of store.UsageStats used across the usage pages.
+type usageTotals struct {
+	TotalInput      int64 `json:"totalInput"`
+	TotalOutput     int64 `json:"totalOutput"`
+	TotalCacheRead  int64 `json:"totalCacheRead"`
+	TotalCacheWrite int64 `json:"totalCacheWrite"`
+	TotalThinking   int64 `json:"totalThinking"`
+	TotalTokens     int64 `json:"totalTokens"`
+	RequestCount    int64 `json:"requestCount"`
+}
+
+func toUsageTotals(s store.UsageStats) usageTotals {
+	return usageTotals{
+		TotalInput:      s.TotalInput,
+		TotalOutput:     s.TotalOutput,
+		TotalCacheRead:  s.TotalCacheRead,
+		TotalCacheWrite: s.TotalCacheWrite,
+		TotalThinking:   s.TotalThinking,
+		TotalTokens:     s.TotalTokens,
+		RequestCount:    s.RequestCount,
+	}
+}
+
+// usageCSVHeader is the trailing CSV columns shared by every drill-down level.
+var usageCSVHeader = []string{"total_tokens", "input_tokens", "output_tokens", "cache_read_tokens", "cache_write_tokens", "thinking_tokens", "requests"}
+
+func (u usageTotals) csvFields() []string {
+	return []string{
+		strconv.FormatInt(u.TotalTokens, 10),
+		strconv.FormatInt(u.TotalInput, 10),
+		strconv.FormatInt(u.TotalOutput, 10),
+		strconv.FormatInt(u.TotalCacheRead, 10),
+		strconv.FormatInt(u.TotalCacheWrite, 10),
+		strconv.FormatInt(u.TotalThinking, 10),
+		strconv.FormatInt(u.RequestCount, 10),
+	}
+}
+
+// agentUsageItem is one row of the usage overview.
+type agentUsageItem struct {
+	AgentID       string `json:"agentId"`
+	LastRequestAt string `json:"lastRequestAt"`
+	usageTotals
+}
+
+// threadUsageItem is one row of an agent's per-thread breakdown.
+type threadUsageItem struct {
+	ThreadID      string `json:"threadId"`
+	FrontendName  string `json:"frontendName"`
+	ExternalID    string `json:"externalId"`
+	LastRequestAt string `json:"lastRequestAt"`
+	usageTotals
+}
+
+// requestUsageItem is one agent request within a thread. MessageID is the
+// reply the request produced, which the thread page annotates inline.
+type requestUsageItem struct {
+	RequestID string `json:"requestId"`
+	MessageID string `json:"messageId,omitempty"`
+	AgentID   string `json:"agentId"`
+	CreatedAt string `json:"createdAt"`
+	usageTotals
+}
+
+// usageWindow parses the since/until query parameters shared by the usage
+// endpoints.
+func usageWindow(r *http.Request) (store.UsageFilter, error) {
+	var filter store.UsageFilter
+	var err error
+	if filter.Since, err = timeparse.Query(r.URL.Query(), "since"); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = timeparse.Query(r.URL.Query(), "until"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// listAgentUsageItems returns the usage overview rows, highest tokens first.
+func (a *Admin) listAgentUsageItems(ctx context.Context, filter store.UsageFilter) ([]agentUsageItem, error) {
+	usage, err := a.store.ListAgentUsage(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]agentUsageItem, len(usage))
+	for i, u := range usage {
+		items[i] = agentUsageItem{AgentID: u.AgentID, LastRequestAt: timeparse.Format(u.LastRequestAt), usageTotals: toUsageTotals(u.UsageStats)}
+	}
+	return items, nil
+}
+
+// listRequestUsageItems returns a thread's per-request usage, oldest first.
+func (a *Admin) listRequestUsageItems(ctx context.Context, threadID string, filter store.UsageFilter) ([]requestUsageItem, error) {
+	usage, err := a.store.ListThreadRequestUsage(ctx, threadID, filter)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]requestUsageItem, len(usage))
+	for i, u := range usage {
+		items[i] = requestUsageItem{
+			RequestID:   u.RequestID,
+			MessageID:   u.MessageID,
+			AgentID:     u.AgentID,
+			CreatedAt:   timeparse.Format(u.CreatedAt),
+			usageTotals: toUsageTotals(u.UsageStats),
+		}
+	}
+	return items, nil
+}
+
+// handleUsageSummaryJSON handles GET /api/admin/usage/summary: the same
+// report as the gateway's /api/usage/summary, for the usage page.
+func (a *Admin) handleUsageSummaryJSON(w http.ResponseWriter, r *http.Request) {
+	filter, err := usageWindow(r)
+	if err != nil {
+		a.writeTimestampError(w, err)
+		return
+	}
+	summary, err := a.config.UsagePricing.Summarize(r.Context(), a.store, r.URL.Query().Get("group_by"), filter)
+	if errors.Is(err, usage.ErrUnknownGroupBy) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		a.logger.Error("failed to summarize usage", "error", err)
+		http.Error(w, "Failed to load usage", http.StatusInternalServerError)
+		return
+	}
+	a.writeUsageJSON(w, summary)
+}
+
+// handleUsageAgentsJSON handles GET /api/admin/usage/agents: every agent's
+// usage in the window, highest tokens first.
+func (a *Admin) handleUsageAgentsJSON(w http.ResponseWriter, r *http.Request) {
+	filter, err := usageWindow(r)
+	if err != nil {
+		a.writeTimestampError(w, err)
+		return
+	}
+	items, err := a.listAgentUsageItems(r.Context(), filter)
+	if err != nil {
+		a.logger.Error("failed to list agent usage", "error", err)
+		http.Error(w, "Failed to load usage", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		rows := make([][]string, len(items))
+		for i, it := range items {
+			rows[i] = append([]string{it.AgentID, it.LastRequestAt}, it.csvFields()...)
+		}
+		a.writeUsageCSV(w, "usage-agents.csv", []string{"agent_id", "last_request_at"}, rows)
+		return
+	}
+	a.writeUsageJSON(w, map[string]any{"agents": items})
+}
+
+// handleUsageAgentThreadsJSON handles GET /api/admin/usage/agents/{id}/threads:
+// one agent's usage broken down by thread, highest tokens first.
+func (a *Admin) handleUsageAgentThreadsJSON(w http.ResponseWriter, r *http.Request) {
+	agentID := r.PathValue("id")
+	filter, err := usageWindow(r)
+	if err != nil {
+		a.writeTimestampError(w, err)
+		return
+	}
+	usage, err := a.store.ListAgentThreadUsage(r.Context(), agentID, filter, usageThreadLimit)
+	if err != nil {
+		a.logger.Error("failed to list thread usage", "error", err, "agent_id", agentID)
+		http.Error(w, "Failed to load usage", http.StatusInternalServerError)
+		return
+	}
+	items := make([]threadUsageItem, len(usage))
+	for i, u := range usage {
+		items[i] = threadUsageItem{
+			ThreadID:      u.ThreadID,
+			FrontendName:  u.FrontendName,
+			ExternalID:    u.ExternalID,
+			LastRequestAt: timeparse.Format(u.LastRequestAt),
+			usageTotals:   toUsageTotals(u.UsageStats),
+		}
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		rows := make([][]string, len(items))
+		for i, it := range items {
+			rows[i] = append([]string{it.ThreadID, it.FrontendName, it.ExternalID, it.LastRequestAt}, it.csvFields()...)
+		}
+		a.writeUsageCSV(w, "usage-"+agentID+"-threads.csv", []string{"thread_id", "frontend", "external_id", "last_request_at"}, rows)
+		return
+	}
+	a.writeUsageJSON(w, map[string]any{"agentId": agentID, "threads": items})
+}
+
+// handleUsageThreadRequestsJSON handles GET /api/admin/usage/threads/{id}/requests:
+// one thread's usage per request, oldest first. Merged threads redirect to
+// their target, keeping the window.
+func (a *Admin) handleUsageThreadRequestsJSON(w http.ResponseWriter, r *http.Request) {
+	threadID := r.PathValue("id")
+	filter, err := usageWindow(r)
+	if err != nil {
+		a.writeTimestampError(w, err)
+		return
+	}
+	thread, err := a.store.GetThread(r.Context(), threadID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Thread not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		a.logger.Error("failed to get thread", "error", err, "thread_id", threadID)
+		http.Error(w, "Failed to load thread", http.StatusInternalServerError)
+		return
+	}
+	if thread.MergedInto != "" {
+		target := "/api/admin/usage/threads/" + thread.MergedInto + "/requests"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+		return
+	}
+
+	items, err := a.listRequestUsageItems(r.Context(), threadID, filter)
+	if err != nil {
+		a.logger.Error("failed to list request usage", "error", err, "thread_id", threadID)
+		http.Error(w, "Failed to load usage", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		rows := make([][]string, len(items))
+		for i, it := range items {
+			rows[i] = append([]string{it.RequestID, it.MessageID, it.AgentID, it.CreatedAt}, it.csvFields()...)
+		}
+		a.writeUsageCSV(w, "usage-thread-"+threadID+".csv", []string{"request_id", "message_id", "agent_id", "created_at"}, rows)
+		return
+	}
+	a.writeUsageJSON(w, map[string]any{"threadId": threadID, "requests": items})
+}
+
+func (a *Admin) writeUsageJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		a.logger.Debug("failed to encode usage JSON", "error", err)
+	}
+}
+
+// writeUsageCSV writes rows as a CSV attachment. header lists the leading
+// columns; the usage columns are appended.
+func (a *Admin) writeUsageCSV(w http.ResponseWriter, filename string, header []string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(append(header, usageCSVHeader...))
+	for _, row := range rows {
+		_ = cw.Write(row)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		a.logger.Debug("failed to write usage CSV", "error", err)
+	}
+}
@@ -0,0 +1,184 @@
+// ABOUTME: Tests for the token usage drill-down endpoints.
+// ABOUTME: Covers ranking, windows, CSV export, merged-thread redirects, and the priced summary.
+
+package webadmin
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/usage"
+)
+
+func seedAdminUsage(t *testing.T, s *store.SQLStore) {
+	t.Helper()
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	rows := []struct {
+		id, thread, request, agent string
+		input                      int32
+		at                         time.Time
+	}{
+		{"u1", "thread-a", "req-1", "agent-1", 100, base},
+		{"u2", "thread-a", "req-2", "agent-1", 200, base.Add(time.Hour)},
+		{"u3", "thread-b", "req-3", "agent-1", 50, base.Add(2 * time.Hour)},
+		{"u4", "thread-b", "req-4", "agent-2", 1000, base.Add(-72 * time.Hour)},
+	}
+	for _, r := range rows {
+		if err := s.SaveUsage(ctx, &store.TokenUsage{
+			ID: r.id, ThreadID: r.thread, RequestID: r.request, AgentID: r.agent,
+			InputTokens: r.input, OutputTokens: 1, CreatedAt: r.at,
+		}); err != nil {
+			t.Fatalf("SaveUsage: %v", err)
+		}
+	}
+	if err := s.LinkUsageToMessage(ctx, "req-1", "a-1"); err != nil {
+		t.Fatalf("LinkUsageToMessage: %v", err)
+	}
+}
+
+func TestHandleUsageAgents_RanksWithinWindow(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	seedAdminThread(t, s, "thread-a", "a-1")
+	seedAdminThread(t, s, "thread-b")
+	seedAdminUsage(t, s)
+
+	var resp struct {
+		Agents []agentUsageItem `json:"agents"`
+	}
+	rec := httptest.NewRecorder()
+	admin.handleUsageAgentsJSON(rec, httptest.NewRequest(http.MethodGet, "/api/admin/usage/agents", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Agents) != 2 || resp.Agents[0].AgentID != "agent-2" || resp.Agents[1].TotalInput != 350 {
+		t.Fatalf("agents = %+v, want agent-2 first and agent-1 with 350 input", resp.Agents)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleUsageAgentsJSON(rec, httptest.NewRequest(http.MethodGet, "/api/admin/usage/agents?since=2026-01-01T00:00:00Z", nil))
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Agents) != 1 || resp.Agents[0].AgentID != "agent-1" {
+		t.Errorf("windowed agents = %+v, want only agent-1", resp.Agents)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleUsageAgentsJSON(rec, httptest.NewRequest(http.MethodGet, "/api/admin/usage/agents?since=yesterday", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("bad since: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestHandleUsageSummary_PricesAgents(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	seedAdminThread(t, s, "thread-a", "a-1")
+	seedAdminThread(t, s, "thread-b")
+	seedAdminUsage(t, s)
+	admin.config.UsagePricing = usage.Pricing{usage.DefaultPriceKey: {Input: 1000}}
+
+	var summary usage.Summary
+	rec := httptest.NewRecorder()
+	admin.handleUsageSummaryJSON(rec, httptest.NewRequest(http.MethodGet, "/api/admin/usage/summary?since=2026-01-01T00:00:00Z", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(summary.Groups) != 1 || summary.Groups[0].Key != "agent-1" || summary.Totals.InputTokens != 350 {
+		t.Fatalf("summary = %+v, want agent-1 alone with 350 input", summary)
+	}
+	if cost := summary.Totals.EstimatedCostUSD; cost == nil || *cost != 0.35 {
+		t.Errorf("cost = %v, want 0.35", cost)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.handleUsageSummaryJSON(rec, httptest.NewRequest(http.MethodGet, "/api/admin/usage/summary?group_by=hour", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("bad group_by: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestHandleUsageAgentThreads_CSV(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	seedAdminThread(t, s, "thread-a", "a-1")
+	seedAdminThread(t, s, "thread-b")
+	seedAdminUsage(t, s)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/usage/agents/agent-1/threads?format=csv", nil)
+	req.SetPathValue("id", "agent-1")
+	rec := httptest.NewRecorder()
+	admin.handleUsageAgentThreadsJSON(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parsing CSV: %v", err)
+	}
+	if len(records) != 3 || records[0][0] != "thread_id" || records[0][4] != "total_tokens" {
+		t.Fatalf("records = %v, want a header and two threads", records)
+	}
+	if records[1][0] != "thread-a" || records[1][2] != "room-thread-a" || records[1][4] != "302" {
+		t.Errorf("top thread row = %v, want thread-a with 302 tokens", records[1])
+	}
+}
+
+func TestHandleUsageThreadRequests_FollowsMerge(t *testing.T) {
+	admin, s := newThreadOpsAdmin(t)
+	seedAdminThread(t, s, "thread-a", "a-1")
+	seedAdminThread(t, s, "thread-b")
+	seedAdminUsage(t, s)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/usage/threads/thread-a/requests", nil)
+	req.SetPathValue("id", "thread-a")
+	rec := httptest.NewRecorder()
+	admin.handleUsageThreadRequestsJSON(rec, req)
+
+	var resp struct {
+		Requests []requestUsageItem `json:"requests"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Requests) != 2 || resp.Requests[0].RequestID != "req-1" || resp.Requests[0].MessageID != "a-1" {
+		t.Fatalf("requests = %+v, want req-1 linked to a-1 first", resp.Requests)
+	}
+
+	if _, err := s.MergeThreads(context.Background(), "thread-b", []string{"thread-a"}); err != nil {
+		t.Fatalf("MergeThreads: %v", err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/usage/threads/thread-a/requests?format=csv", nil)
+	req.SetPathValue("id", "thread-a")
+	rec = httptest.NewRecorder()
+	admin.handleUsageThreadRequestsJSON(rec, req)
+	if rec.Code != http.StatusPermanentRedirect {
+		t.Fatalf("merged thread: status = %d, want 308", rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/api/admin/usage/threads/thread-b/requests?format=csv" {
+		t.Errorf("Location = %q", loc)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/usage/threads/missing/requests", nil)
+	req.SetPathValue("id", "missing")
+	rec = httptest.NewRecorder()
+	admin.handleUsageThreadRequestsJSON(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing thread: status = %d, want 404", rec.Code)
+	}
+}
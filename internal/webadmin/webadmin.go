@@ -6,6 +6,7 @@ package webadmin
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -16,6 +17,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-webauthn/webauthn/webauthn"
@@ -23,9 +25,18 @@ import (
 
 	"github.com/2389/coven-gateway/internal/agent"
 	"github.com/2389/coven-gateway/internal/assets"
+	"github.com/2389/coven-gateway/internal/auth"
+	"github.com/2389/coven-gateway/internal/builtins"
 	"github.com/2389/coven-gateway/internal/conversation"
+	"github.com/2389/coven-gateway/internal/diskmon"
+	"github.com/2389/coven-gateway/internal/flags"
+	"github.com/2389/coven-gateway/internal/mcpbridge"
 	"github.com/2389/coven-gateway/internal/packs"
+	"github.com/2389/coven-gateway/internal/reliability"
+	"github.com/2389/coven-gateway/internal/ssechunk"
 	"github.com/2389/coven-gateway/internal/store"
+	"github.com/2389/coven-gateway/internal/timeparse"
+	"github.com/2389/coven-gateway/internal/usage"
 	pb "github.com/2389/coven-gateway/proto/coven"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -56,12 +67,34 @@ const (
 // contextKey is a custom type for context keys to avoid collisions.
 type contextKey string
 
-const userContextKey contextKey = "admin_user"
+const (
+	userContextKey    contextKey = "admin_user"
+	sessionContextKey contextKey = "admin_session"
+)
 
 // Config holds admin UI configuration.
 type Config struct {
 	// BaseURL is the external URL for generating invite links
 	BaseURL string
+	// AgentServerURL is the gRPC URL agents connect to, shown in setup snippets
+	AgentServerURL string
+	// OIDC enables single sign-on through an OpenID Connect provider when Issuer is set
+	OIDC OIDCConfig
+	// BuiltinLimits supplies the daily write quotas shown on agent pages;
+	// the zero value shows the defaults
+	BuiltinLimits builtins.Limits
+	// CapabilityEnforcement is the packs.capability_enforcement mode shown on
+	// the capabilities report
+	CapabilityEnforcement string
+	// UsagePricing is the usage.pricing table behind the usage page's cost
+	// estimates; nil shows no costs
+	UsagePricing usage.Pricing
+	// SSEChunkBytes is server.sse_chunk_bytes, the largest text one chat
+	// stream event carries; zero uses ssechunk.DefaultMaxBytes
+	SSEChunkBytes int
+	// PendingAgentWebhookURL, when set, is POSTed each agent that auto-registers
+	// a principal awaiting approval
+	PendingAgentWebhookURL string
 }
 
 // TokenGenerator creates JWT tokens for principals.
@@ -69,6 +102,11 @@ type TokenGenerator interface {
 	Generate(principalID string, expiresIn time.Duration) (string, error)
 }
 
+// TokenMinter issues tokens that are recorded so they can be listed and revoked.
+type TokenMinter interface {
+	Mint(ctx context.Context, req auth.MintRequest) (string, *store.APIToken, error)
+}
+
 // PrincipalStore provides methods for principal and role management.
 type PrincipalStore interface {
 	CreatePrincipal(ctx context.Context, p *store.Principal) error
@@ -82,13 +120,20 @@ type FullStore interface {
 
 	// Threads
 	CreateThread(ctx context.Context, thread *store.Thread) error
-	ListThreads(ctx context.Context, limit int) ([]*store.Thread, error)
+	ListThreads(ctx context.Context, limit int, includeArchived bool) ([]*store.Thread, error)
+	ArchiveThread(ctx context.Context, id string, archived bool) error
+	SetThreadPinned(ctx context.Context, id string, pinned bool) error
 	GetThread(ctx context.Context, id string) (*store.Thread, error)
 	GetThreadMessages(ctx context.Context, threadID string, limit int) ([]*store.Message, error)
+	MergeThreads(ctx context.Context, targetID string, sourceIDs []string) (*store.ThreadMergeResult, error)
+	SplitThread(ctx context.Context, threadID, cutEventID string) (*store.ThreadSplitResult, error)
 
 	// Ledger events (unified message storage)
 	GetEvents(ctx context.Context, params store.GetEventsParams) (*store.GetEventsResult, error)
+	GetEvent(ctx context.Context, id string) (*store.LedgerEvent, error)
 	GetEventsByThreadID(ctx context.Context, threadID string, limit int) ([]*store.LedgerEvent, error)
+	ForEachThreadEvent(ctx context.Context, threadID string, fn func(*store.LedgerEvent) error) error
+	SearchMessages(ctx context.Context, query string, filter store.SearchFilter) ([]*store.SearchResult, error)
 
 	// Messages
 	SaveMessage(ctx context.Context, msg *store.Message) error
@@ -98,6 +143,7 @@ type FullStore interface {
 	CountPrincipals(ctx context.Context, filter store.PrincipalFilter) (int, error)
 	GetPrincipal(ctx context.Context, id string) (*store.Principal, error)
 	UpdatePrincipalStatus(ctx context.Context, id string, status store.PrincipalStatus) error
+	SetPrincipalPause(ctx context.Context, id string, pause *store.PrincipalPause) error
 	DeletePrincipal(ctx context.Context, id string) error
 
 	// Link codes
@@ -106,17 +152,77 @@ type FullStore interface {
 	GetLinkCode(ctx context.Context, id string) (*store.LinkCode, error)
 	ListPendingLinkCodes(ctx context.Context) ([]*store.LinkCode, error)
 	ApproveLinkCode(ctx context.Context, id string, approvedBy string, principalID string, token string) error
-	DeleteExpiredLinkCodes(ctx context.Context) error
 
 	// Builtin tool pack data (for admin UI)
 	SearchLogEntries(ctx context.Context, agentID string, query string, since *time.Time, limit int) ([]*store.LogEntry, error)
 	ListAllTodos(ctx context.Context, limit int) ([]*store.Todo, error)
-	ListBBSThreads(ctx context.Context, limit int) ([]*store.BBSPost, error)
+	ListProjectMembers(ctx context.Context) ([]*store.ProjectMember, error)
+	AddProjectMember(ctx context.Context, project, agentID string) error
+	RemoveProjectMember(ctx context.Context, project, agentID string) error
+	ListDelegationTargets(ctx context.Context) ([]*store.DelegationTarget, error)
+	AddDelegationTarget(ctx context.Context, agentID, targetAgentID string) error
+	RemoveDelegationTarget(ctx context.Context, agentID, targetAgentID string) error
+	ListBBSThreads(ctx context.Context, filter store.BBSThreadFilter) ([]*store.BBSPost, error)
 	GetBBSThread(ctx context.Context, threadID string) (*store.BBSThread, error)
+	CreateBBSPost(ctx context.Context, post *store.BBSPost) error
+	SendMail(ctx context.Context, mail *store.AgentMail) error
+	GetBuiltinQuotaUsage(ctx context.Context, agentID, day string) (map[string]int, error)
 
 	// Token usage tracking
 	GetUsageStats(ctx context.Context, filter store.UsageFilter) (*store.UsageStats, error)
 	GetThreadUsage(ctx context.Context, threadID string) ([]*store.TokenUsage, error)
+	ListAgentUsage(ctx context.Context, filter store.UsageFilter) ([]*store.AgentUsage, error)
+	ListAgentThreadUsage(ctx context.Context, agentID string, filter store.UsageFilter, limit int) ([]*store.ThreadUsageSummary, error)
+	ListThreadRequestUsage(ctx context.Context, threadID string, filter store.UsageFilter) ([]*store.RequestUsage, error)
+	ListDailyAgentUsage(ctx context.Context, filter store.UsageFilter) ([]*store.DailyAgentUsage, error)
+
+	// Bindings
+	ListBindingsV2(ctx context.Context, filter store.BindingFilter) ([]store.Binding, error)
+	GetBindingByID(ctx context.Context, id string) (*store.Binding, error)
+	SetBindingContext(ctx context.Context, id, text string) error
+
+	// Onboarding checks
+	CountBindings(ctx context.Context) (int, error)
+	HasAgentReply(ctx context.Context) (bool, error)
+
+	// Tool catalog changelog
+	ListToolChanges(ctx context.Context, filter store.ToolChangeFilter) ([]*store.ToolChange, error)
+	GetToolCatalogVersion(ctx context.Context) (int64, error)
+
+	// Audit log
+	AppendAuditLog(ctx context.Context, e *store.AuditEntry) error
+	ListAuditLog(ctx context.Context, f store.AuditFilter) ([]store.AuditEntry, error)
+
+	// Capability grants
+	GrantCapability(ctx context.Context, principalID, capability, grantedBy string) error
+	RevokeCapability(ctx context.Context, principalID, capability string) error
+	ListCapabilities(ctx context.Context, principalID string) ([]string, error)
+	ListCapabilityWarnings(ctx context.Context, since time.Time) ([]store.CapabilityWarning, error)
+
+	// Tool overrides
+	ListToolOverrides(ctx context.Context, principalID string) ([]*store.ToolOverride, error)
+	SetToolOverride(ctx context.Context, o *store.ToolOverride) error
+
+	// API tokens
+	ListAPITokens(ctx context.Context, principalID string) ([]*store.APIToken, error)
+	GetAPIToken(ctx context.Context, id string) (*store.APIToken, error)
+	RevokeAPIToken(ctx context.Context, id, revokedBy string) error
+	SetAdminUserPrincipal(ctx context.Context, userID, principalID string) error
+	ListRoles(ctx context.Context, subjectType store.RoleSubjectType, subjectID string) ([]store.RoleName, error)
+
+	// Tool approval policies
+	CreateToolPolicy(ctx context.Context, p *store.ToolPolicy) error
+	GetToolPolicy(ctx context.Context, id string) (*store.ToolPolicy, error)
+	ListToolPolicies(ctx context.Context) ([]*store.ToolPolicy, error)
+	UpdateToolPolicy(ctx context.Context, p *store.ToolPolicy) error
+	DeleteToolPolicy(ctx context.Context, id string) error
+	ReorderToolPolicies(ctx context.Context, ids []string) error
+	GetToolPolicyDefault(ctx context.Context) (string, error)
+	SetToolPolicyDefault(ctx context.Context, action, updatedBy string) error
+
+	// SSO identities
+	GetAdminUserByOIDCIdentity(ctx context.Context, issuer, subject string) (*store.AdminUser, error)
+	CreateOIDCAdminUser(ctx context.Context, user *store.AdminUser, identity *store.AdminOIDCIdentity) error
 }
 
 // Admin handles admin UI routes and authentication.
@@ -131,17 +237,29 @@ type Admin struct {
 	logger           *slog.Logger
 	webauthn         *webauthn.WebAuthn
 	webauthnSessions *webAuthnSessionStore
+	oidc             *oidcProvider // nil when SSO is not configured
 	chatHub          *chatHub
 	tokenGenerator   TokenGenerator
+	tokenMinter      TokenMinter
+	flags            *flags.Service
+	reliability      *reliability.Tracker
+	storage          *diskmon.Monitor
+	questions        QuestionRouter       // set by SetQuestionRouter
+	capabilities     CapabilityNotifier   // set by SetCapabilityNotifier
+	toolOverrides    ToolOverrideNotifier // set by SetToolOverrideNotifier
+
+	// enforcement, once set by SetCapabilityEnforcement, overrides
+	// Config.CapabilityEnforcement.
+	enforcement atomic.Pointer[string]
 }
 
-// getSQLiteStore returns the underlying SQLiteStore if available.
-// Returns nil and logs an error if the store is not a SQLiteStore.
+// getSQLStore returns the underlying SQLStore if available.
+// Returns nil and logs an error if the store is not a SQLStore.
 // This helper deduplicates the type assertion pattern used across secrets handlers.
-func (a *Admin) getSQLiteStore() *store.SQLiteStore {
-	sqlStore, ok := a.store.(*store.SQLiteStore)
+func (a *Admin) getSQLStore() *store.SQLStore {
+	sqlStore, ok := a.store.(*store.SQLStore)
 	if !ok {
-		a.logger.Error("store is not SQLiteStore")
+		a.logger.Error("store is not SQLStore")
 		return nil
 	}
 	return sqlStore
@@ -157,6 +275,10 @@ type NewConfig struct {
 	Registry       *packs.Registry
 	Config         Config
 	TokenGenerator TokenGenerator
+	TokenMinter    TokenMinter // records tokens for listing and revocation; nil falls back to TokenGenerator
+	Flags          *flags.Service
+	Reliability    *reliability.Tracker
+	Storage        *diskmon.Monitor // optional: disk space and database growth alarms
 }
 
 // New creates a new Admin handler.
@@ -183,6 +305,10 @@ func NewWithConfig(cfg NewConfig) *Admin {
 		logger:         slog.Default().With("component", "admin"),
 		chatHub:        newChatHub(),
 		tokenGenerator: cfg.TokenGenerator,
+		tokenMinter:    cfg.TokenMinter,
+		flags:          cfg.Flags,
+		reliability:    cfg.Reliability,
+		storage:        cfg.Storage,
 	}
 
 	// Initialize WebAuthn (errors are logged but don't prevent startup)
@@ -190,6 +316,10 @@ func NewWithConfig(cfg NewConfig) *Admin {
 		a.logger.Warn("failed to initialize WebAuthn, passkey login disabled", "error", err)
 	}
 
+	if cfg.Config.OIDC.Issuer != "" {
+		a.oidc = newOIDCProvider(cfg.Config.OIDC, cfg.Config.BaseURL, a.logger)
+	}
+
 	return a
 }
 
@@ -210,7 +340,19 @@ func (a *Admin) SendUserQuestion(agentID string, req *pb.UserQuestionRequest) er
 		return errors.New("chat hub not initialized")
 	}
 
-	// Convert proto options to chat message options
+	msg := userQuestionMessage(req)
+	sent := a.chatHub.sendToAgent(agentID, msg)
+	if sent == 0 {
+		// No active sessions for this agent
+		return fmt.Errorf("no connected clients for agent %s", agentID)
+	}
+
+	a.logger.Debug("user question sent to clients", "agent_id", agentID, "question_id", req.GetQuestionId(), "clients", sent)
+	return nil
+}
+
+// userQuestionMessage converts a question request to its chat message.
+func userQuestionMessage(req *pb.UserQuestionRequest) *chatMessage {
 	options := make([]questionOption, len(req.GetOptions()))
 	for i, opt := range req.GetOptions() {
 		options[i] = questionOption{
@@ -222,26 +364,23 @@ func (a *Admin) SendUserQuestion(agentID string, req *pb.UserQuestionRequest) er
 	}
 
 	msg := &chatMessage{
-		Type:           "user_question",
-		Timestamp:      time.Now(),
-		QuestionID:     req.GetQuestionId(),
-		Question:       req.GetQuestion(),
-		Options:        options,
-		MultiSelect:    req.GetMultiSelect(),
-		TimeoutSeconds: req.GetTimeoutSeconds(),
-	}
+		Type:            "user_question",
+		Timestamp:       time.Now(),
+		QuestionID:      req.GetQuestionId(),
+		Question:        req.GetQuestion(),
+		Options:         options,
+		MultiSelect:     req.GetMultiSelect(),
+		TimeoutSeconds:  req.GetTimeoutSeconds(),
+		Pattern:         req.GetPattern(),
+		MaxLength:       req.GetMaxLength(),
+		QuestionContext: builtins.PendingQuestion{Request: req}.Context(),
+	}
+	allowFreeText := builtins.AllowsFreeText(req)
+	msg.AllowFreeText = &allowFreeText
 	if req.Header != nil {
 		msg.Header = *req.Header
 	}
-
-	sent := a.chatHub.sendToAgent(agentID, msg)
-	if sent == 0 {
-		// No active sessions for this agent
-		return fmt.Errorf("no connected clients for agent %s", agentID)
-	}
-
-	a.logger.Debug("user question sent to clients", "agent_id", agentID, "question_id", req.GetQuestionId(), "clients", sent)
-	return nil
+	return msg
 }
 
 // registerRootRoutes registers the root (/) routes - Chat interface.
@@ -257,15 +396,18 @@ func (a *Admin) registerRootRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /setup", a.handleSetupSubmit)
 	mux.HandleFunc("GET /invite/{token}", a.handleInvitePage)
 	mux.HandleFunc("POST /invite/{token}", a.handleInviteSignup)
+	if a.oidc != nil {
+		mux.HandleFunc("GET /auth/oidc/login", a.handleOIDCLogin)
+		mux.HandleFunc("GET /auth/oidc/callback", a.handleOIDCCallback)
+	}
 
-	// Device linking API (unauthenticated for devices)
-	mux.HandleFunc("POST /api/link/request", a.handleLinkRequest)
-	mux.HandleFunc("GET /api/link/status/{code}", a.handleLinkStatus)
+	a.RegisterDeviceRoutes(mux)
 
 	// Chat API and SSE
 	mux.HandleFunc("GET /api/agents", a.requireAuth(a.handleAgentsJSON))
 	mux.HandleFunc("GET /chat/{id}/send", a.requireAuth(a.handleChatSend))
 	mux.HandleFunc("POST /chat/{id}/send", a.requireAuth(a.handleChatSend))
+	mux.HandleFunc("POST /chat/{id}/cancel", a.requireAuth(a.handleChatCancel))
 	mux.HandleFunc("GET /chat/{id}/stream", a.requireAuth(a.handleChatStream))
 
 	// WebAuthn/Passkey routes
@@ -281,22 +423,40 @@ func (a *Admin) registerAdminRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /admin/{$}", a.requireAuth(a.handleDashboard))
 	mux.HandleFunc("GET /admin/dashboard", a.requireAuth(a.handleDashboard))
 	mux.HandleFunc("GET /api/admin/dashboard", a.requireAuth(a.handleDashboardJSON))
+	mux.HandleFunc("GET /api/admin/onboarding", a.requireAuth(a.handleOnboardingJSON))
+	mux.HandleFunc("POST /api/admin/onboarding/test-message", a.requireAuth(a.handleOnboardingTestMessage))
 
 	// Device linking UI (authenticated)
 	mux.HandleFunc("GET /admin/link", a.requireAuth(a.handleLinkPage))
 	mux.HandleFunc("GET /api/admin/link", a.requireAuth(a.handleLinkJSON))
 	mux.HandleFunc("POST /admin/link/{id}/approve", a.requireAuth(a.handleLinkApprove))
+	mux.HandleFunc("GET /admin/link/{id}/qr", a.requireAuth(a.handleLinkQR))
 
 	// Agent management
 	mux.HandleFunc("GET /admin/agents", a.requireAuth(a.handleAgentsPage))
 	mux.HandleFunc("GET /admin/agents/{id}", a.requireAuth(a.handleAgentDetail))
+	mux.HandleFunc("GET /api/admin/agents", a.requireAuth(a.handlePendingAgentsJSON))
 	mux.HandleFunc("GET /api/admin/agents/{id}", a.requireAuth(a.handleAgentDetailJSON))
 	mux.HandleFunc("POST /admin/agents/{id}/approve", a.requireAuth(a.handleAgentApprove))
 	mux.HandleFunc("POST /admin/agents/{id}/revoke", a.requireAuth(a.handleAgentRevoke))
+	mux.HandleFunc("POST /api/admin/agents/{id}/pause", a.requireAuth(a.handleAgentPause))
+	mux.HandleFunc("POST /api/admin/agents/{id}/resume", a.requireAuth(a.handleAgentResume))
+	mux.HandleFunc("GET /api/admin/agents/{id}/tools", a.requireAuth(a.handleAgentToolsJSON))
+	mux.HandleFunc("PUT /api/admin/agents/{id}/tools", a.requireAuth(a.handleSetAgentTools))
+
+	// Pending ask_user questions
+	mux.HandleFunc("GET /api/admin/questions", a.requireAuth(a.handleQuestionsJSON))
+	mux.HandleFunc("POST /api/admin/questions/{id}/answer", a.requireAuth(a.handleAnswerQuestion))
 
 	// Tools management
 	mux.HandleFunc("GET /admin/tools", a.requireAuth(a.handleToolsPage))
 	mux.HandleFunc("GET /api/admin/tools", a.requireAuth(a.handleToolsJSON))
+	mux.HandleFunc("GET /api/admin/tools/changelog", a.requireAuth(a.handleToolChangelogJSON))
+	mux.HandleFunc("GET /api/admin/packs", a.requireAuth(a.handlePacksJSON))
+
+	// Reliability (per-tool and per-agent success rates)
+	mux.HandleFunc("GET /api/admin/reliability", a.requireAuth(a.handleReliabilityJSON))
+	mux.HandleFunc("GET /api/admin/storage", a.requireAuth(a.handleStorageJSON))
 
 	// Activity logs (builtin pack data)
 	mux.HandleFunc("GET /admin/logs", a.requireAuth(a.handleLogsPage))
@@ -305,11 +465,21 @@ func (a *Admin) registerAdminRoutes(mux *http.ServeMux) {
 	// Todos (builtin pack data)
 	mux.HandleFunc("GET /admin/todos", a.requireAuth(a.handleTodosPage))
 	mux.HandleFunc("GET /api/admin/todos", a.requireAuth(a.handleTodosJSON))
+	mux.HandleFunc("GET /api/admin/projects", a.requireAuth(a.handleProjectsJSON))
+	mux.HandleFunc("PUT /api/admin/projects/{project}/members/{agent}", a.requireAuth(a.handleAddProjectMember))
+	mux.HandleFunc("DELETE /api/admin/projects/{project}/members/{agent}", a.requireAuth(a.handleRemoveProjectMember))
+
+	// Delegation allowlist (delegate_task)
+	mux.HandleFunc("GET /api/admin/delegations", a.requireAuth(a.handleDelegationsJSON))
+	mux.HandleFunc("PUT /api/admin/agents/{id}/delegates/{target}", a.requireAuth(a.handleAddDelegationTarget))
+	mux.HandleFunc("DELETE /api/admin/agents/{id}/delegates/{target}", a.requireAuth(a.handleRemoveDelegationTarget))
 
 	// BBS Board (builtin pack data)
 	mux.HandleFunc("GET /admin/board", a.requireAuth(a.handleBoardPage))
 	mux.HandleFunc("GET /api/admin/board", a.requireAuth(a.handleBoardJSON))
 	mux.HandleFunc("GET /api/admin/board/{id}", a.requireAuth(a.handleBoardThreadJSON))
+	mux.HandleFunc("POST /api/admin/board", a.requireAuth(a.handleBoardCreateThread))
+	mux.HandleFunc("POST /api/admin/board/{id}/replies", a.requireAuth(a.handleBoardReply))
 
 	// Principals management
 	mux.HandleFunc("GET /admin/principals", a.requireAuth(a.handlePrincipalsPage))
@@ -317,12 +487,51 @@ func (a *Admin) registerAdminRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /admin/principals/{id}/approve", a.requireAuth(a.handlePrincipalApprove))
 	mux.HandleFunc("POST /admin/principals/{id}/revoke", a.requireAuth(a.handlePrincipalRevoke))
 	mux.HandleFunc("DELETE /admin/principals/{id}", a.requireAuth(a.handlePrincipalDelete))
+	mux.HandleFunc("GET /api/admin/principals/{id}/capabilities", a.requireAuth(a.handlePrincipalCapabilitiesJSON))
+	mux.HandleFunc("PUT /api/admin/principals/{id}/capabilities", a.requireAuth(a.handleSetPrincipalCapabilities))
+
+	// Settings (feature flags, binding context, API tokens, sessions)
+	mux.HandleFunc("GET /admin/settings", a.requireAuth(a.handleSettingsPage))
+	mux.HandleFunc("GET /api/admin/tokens", a.requireAuth(a.handleTokensJSON))
+	mux.HandleFunc("POST /api/admin/tokens", a.requireAuth(a.handleCreateToken))
+	mux.HandleFunc("POST /api/admin/tokens/{id}/revoke", a.requireAuth(a.handleRevokeToken))
+	mux.HandleFunc("GET /api/admin/flags", a.requireAuth(a.handleFlagsJSON))
+	mux.HandleFunc("GET /api/admin/sessions", a.requireAuth(a.handleSessionsJSON))
+	mux.HandleFunc("DELETE /api/admin/sessions/{id}", a.requireAuth(a.handleRevokeSession))
+	mux.HandleFunc("POST /api/admin/sessions/revoke-others", a.requireAuth(a.handleRevokeOtherSessions))
+	mux.HandleFunc("GET /api/admin/login-lockouts", a.requireAuth(a.handleLoginLockoutsJSON))
+	mux.HandleFunc("DELETE /api/admin/login-lockouts", a.requireAuth(a.handleClearLoginLockout))
+	mux.HandleFunc("PUT /api/admin/flags", a.requireAuth(a.handleUpdateFlag))
+	mux.HandleFunc("PUT /api/admin/bindings/{id}/context", a.requireAuth(a.handleUpdateBindingContext))
+
+	// Tool approval policies
+	mux.HandleFunc("GET /admin/tool-policies", a.requireAuth(a.handleToolPoliciesPage))
+	mux.HandleFunc("GET /api/admin/tool-policies", a.requireAuth(a.handleToolPoliciesJSON))
+	mux.HandleFunc("POST /api/admin/tool-policies", a.requireAuth(a.handleCreateToolPolicy))
+	mux.HandleFunc("PUT /api/admin/tool-policies/order", a.requireAuth(a.handleReorderToolPolicies))
+	mux.HandleFunc("PUT /api/admin/tool-policies/default", a.requireAuth(a.handleSetToolPolicyDefault))
+	mux.HandleFunc("PUT /api/admin/tool-policies/{id}", a.requireAuth(a.handleUpdateToolPolicy))
+	mux.HandleFunc("DELETE /api/admin/tool-policies/{id}", a.requireAuth(a.handleDeleteToolPolicy))
+
+	// Audit log
+	mux.HandleFunc("GET /admin/audit", a.requireAuth(a.handleAuditPage))
+	mux.HandleFunc("GET /api/admin/audit", a.requireAuth(a.handleAuditJSON))
+
+	// Capability warnings report
+	mux.HandleFunc("GET /admin/capabilities", a.requireAuth(a.handleCapabilitiesPage))
+	mux.HandleFunc("GET /api/admin/capabilities/warnings", a.requireAuth(a.handleCapabilityWarningsJSON))
+	mux.HandleFunc("POST /api/admin/capabilities/grant", a.requireAuth(a.handleGrantCapability))
 
 	// Threads browsing (admin view)
 	mux.HandleFunc("GET /admin/threads", a.requireAuth(a.handleThreadsPage))
 	mux.HandleFunc("GET /api/admin/threads", a.requireAuth(a.handleThreadsJSON))
+	mux.HandleFunc("GET /api/admin/search", a.requireAuth(a.handleSearchJSON))
 	mux.HandleFunc("GET /admin/threads/{id}", a.requireAuth(a.handleThreadDetail))
 	mux.HandleFunc("GET /api/admin/threads/{id}", a.requireAuth(a.handleThreadDetailJSON))
+	mux.HandleFunc("GET /admin/threads/{id}/export", a.requireAuth(a.handleThreadExport))
+	mux.HandleFunc("POST /api/admin/threads/merge", a.requireAuth(a.handleMergeThreads))
+	mux.HandleFunc("POST /api/admin/threads/{id}/split", a.requireAuth(a.handleSplitThread))
+	mux.HandleFunc("PATCH /api/admin/threads/{id}", a.requireAuth(a.handleUpdateThread))
 
 	// Legacy chat page - redirect to root chat with agent param
 	mux.HandleFunc("GET /admin/chat/{id}", a.requireAuth(func(w http.ResponseWriter, r *http.Request) {
@@ -333,6 +542,10 @@ func (a *Admin) registerAdminRoutes(mux *http.ServeMux) {
 	// Token usage page
 	mux.HandleFunc("GET /admin/usage", a.requireAuth(a.handleUsagePage))
 	mux.HandleFunc("GET /api/admin/usage", a.requireAuth(a.handleUsageJSON))
+	mux.HandleFunc("GET /api/admin/usage/summary", a.requireAuth(a.handleUsageSummaryJSON))
+	mux.HandleFunc("GET /api/admin/usage/agents", a.requireAuth(a.handleUsageAgentsJSON))
+	mux.HandleFunc("GET /api/admin/usage/agents/{id}/threads", a.requireAuth(a.handleUsageAgentThreadsJSON))
+	mux.HandleFunc("GET /api/admin/usage/threads/{id}/requests", a.requireAuth(a.handleUsageThreadRequestsJSON))
 
 	// Secrets management
 	mux.HandleFunc("GET /admin/secrets", a.requireAuth(a.handleSecretsPage))
@@ -346,6 +559,14 @@ func (a *Admin) registerAdminRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/admin/invites", a.requireAuth(a.handleCreateInviteJSON))
 }
 
+// RegisterDeviceRoutes registers the unauthenticated device linking API.
+// RegisterRoutes includes these; a gateway serving the UI on a separate
+// listener also registers them on its API listener, where devices reach it.
+func (a *Admin) RegisterDeviceRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/link/request", a.handleLinkRequest)
+	mux.HandleFunc("GET /api/link/status/{code}", a.handleLinkStatus)
+}
+
 // RegisterRoutes registers all admin routes on the given mux.
 func (a *Admin) RegisterRoutes(mux *http.ServeMux) {
 	mux.Handle("GET /static/", http.StripPrefix("/static/", assets.FileServer()))
@@ -391,36 +612,45 @@ func (a *Admin) handleHealthStream(w http.ResponseWriter, r *http.Request) {
 // requireAuth wraps a handler to require authentication.
 func (a *Admin) requireAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		user, err := a.getUserFromSession(r)
+		session, user, err := a.sessionFromRequest(r)
 		if err != nil {
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
+		a.touchSession(r.Context(), session)
 
-		// Add user to context
+		// Add user and session to context
 		ctx := context.WithValue(r.Context(), userContextKey, user)
+		ctx = context.WithValue(ctx, sessionContextKey, session)
 		next(w, r.WithContext(ctx))
 	}
 }
 
 // getUserFromSession retrieves the authenticated user from the session cookie.
 func (a *Admin) getUserFromSession(r *http.Request) (*store.AdminUser, error) {
+	_, user, err := a.sessionFromRequest(r)
+	return user, err
+}
+
+// sessionFromRequest retrieves the session named by the session cookie and
+// its user.
+func (a *Admin) sessionFromRequest(r *http.Request) (*store.AdminSession, *store.AdminUser, error) {
 	cookie, err := r.Cookie(SessionCookieName)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	session, err := a.store.GetAdminSession(r.Context(), cookie.Value)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	user, err := a.store.GetAdminUser(r.Context(), session.UserID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return user, nil
+	return session, user, nil
 }
 
 // getUserFromContext retrieves the authenticated user from the request context.
@@ -487,6 +717,8 @@ func (a *Admin) createSession(w http.ResponseWriter, r *http.Request, userID str
 		UserID:    userID,
 		CreatedAt: time.Now(),
 		ExpiresAt: time.Now().Add(SessionDuration),
+		UserAgent: truncateUserAgent(r.UserAgent()),
+		IP:        requestIP(r),
 	}
 
 	if err := a.store.CreateAdminSession(r.Context(), session); err != nil {
@@ -562,12 +794,20 @@ func (a *Admin) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A locked-out attempt is refused before the user lookup and bcrypt, so
+	// it neither costs a hash nor times differently for a right password.
+	if a.loginLocked(r, username) {
+		a.showLoginError(w, r, errInvalidLogin)
+		return
+	}
+
 	user, userErr := a.store.GetAdminUserByUsername(r.Context(), username)
 	bcryptErr := timingSafeCompare(user, userErr, password)
 
 	if userErr != nil {
 		if errors.Is(userErr, store.ErrAdminUserNotFound) {
-			a.showLoginError(w, r, "Invalid username or password")
+			a.recordLoginFailure(r, username)
+			a.showLoginError(w, r, errInvalidLogin)
 		} else {
 			a.logger.Error("failed to get user", "error", userErr)
 			a.showLoginError(w, r, "An error occurred")
@@ -576,14 +816,17 @@ func (a *Admin) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if user.PasswordHash == "" {
+		a.recordLoginFailure(r, username)
 		a.showLoginError(w, r, "Password login not enabled for this account")
 		return
 	}
 
 	if bcryptErr != nil {
-		a.showLoginError(w, r, "Invalid username or password")
+		a.recordLoginFailure(r, username)
+		a.showLoginError(w, r, errInvalidLogin)
 		return
 	}
+	a.clearLoginFailures(r, username)
 
 	if err := a.createSession(w, r, user.ID); err != nil {
 		a.logger.Error("failed to create session", "error", err)
@@ -628,6 +871,14 @@ func (a *Admin) handleLogout(w http.ResponseWriter, r *http.Request) {
 		HttpOnly: true,
 	})
 
+	// SSO sessions may also end the session at the provider
+	if logoutURL := a.oidcLogoutURL(r); logoutURL != "" {
+		http.SetCookie(w, &http.Cookie{Name: oidcSessionCookieName, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+		http.Redirect(w, r, logoutURL, http.StatusSeeOther)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcSessionCookieName, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
 
@@ -649,9 +900,10 @@ func (a *Admin) handleSetupPage(w http.ResponseWriter, r *http.Request) {
 	a.renderSetupPage(w, "", csrfToken)
 }
 
-// createOwnerPrincipal creates an owner principal for API access during setup.
+// createOwnerPrincipal creates an owner principal for API access during setup
+// and links it to the admin user, so the settings page can manage its tokens.
 // Returns the generated API token, or empty string on any error.
-func (a *Admin) createOwnerPrincipal(ctx context.Context, displayName string) string {
+func (a *Admin) createOwnerPrincipal(ctx context.Context, user *store.AdminUser) string {
 	if a.principalStore == nil || a.tokenGenerator == nil {
 		return ""
 	}
@@ -667,7 +919,7 @@ func (a *Admin) createOwnerPrincipal(ctx context.Context, displayName string) st
 		ID:          principalID,
 		Type:        store.PrincipalTypeClient,
 		PubkeyFP:    fpBytes,
-		DisplayName: displayName + " (API)",
+		DisplayName: user.DisplayName + " (API)",
 		Status:      store.PrincipalStatusApproved,
 		CreatedAt:   time.Now(),
 	}
@@ -682,8 +934,12 @@ func (a *Admin) createOwnerPrincipal(ctx context.Context, displayName string) st
 		// Continue - principal was created even if role assignment failed
 	}
 
+	if err := a.store.SetAdminUserPrincipal(ctx, user.ID, principalID); err != nil {
+		a.logger.Error("failed to link owner principal to admin user", "principal_id", principalID, "error", err)
+	}
+
 	// Generate 30-day token
-	token, err := a.tokenGenerator.Generate(principalID, 30*24*time.Hour)
+	token, err := a.mintToken(ctx, principalID, user.Username, defaultTokenTTL, auth.AllScopes)
 	if err != nil {
 		a.logger.Error("failed to generate API token", "principal_id", principalID, "error", err)
 		return ""
@@ -777,7 +1033,7 @@ func (a *Admin) handleSetupSubmit(w http.ResponseWriter, r *http.Request) {
 
 	var apiToken string
 	if data.createPrincipal {
-		apiToken = a.createOwnerPrincipal(r.Context(), data.displayName)
+		apiToken = a.createOwnerPrincipal(r.Context(), user)
 	}
 
 	if err := a.createSession(w, r, userID); err != nil {
@@ -962,10 +1218,10 @@ func (a *Admin) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	csrfToken := a.ensureCSRFToken(w, r)
 
 	agents := a.listAgentItems()
-	packs := a.listPackItems()
+	packs := a.listPackItems(r.Context())
 
 	threadCount := 0
-	if threads, err := a.store.ListThreads(r.Context(), 1000); err == nil {
+	if threads, err := a.store.ListThreads(r.Context(), 1000, false); err == nil {
 		threadCount = len(threads)
 	}
 
@@ -980,11 +1236,27 @@ func (a *Admin) listAgentItems() []agentItem {
 	var agents []agentItem
 	if a.manager != nil {
 		for _, info := range a.manager.ListAgents() {
-			agents = append(agents, agentItem{
-				ID:        info.ID,
-				Name:      info.Name,
-				Connected: true,
-			})
+			item := agentItem{
+				ID:          info.ID,
+				Name:        info.Name,
+				Connected:   true,
+				Reliability: a.reliabilityFor(reliability.KindAgent, info.ID),
+
+				Health:       string(info.Health.State),
+				HealthReason: info.Health.Reason,
+			}
+			if !info.Health.LastHeartbeat.IsZero() {
+				item.LastHeartbeat = timeparse.Format(info.Health.LastHeartbeat)
+			}
+			if info.Health.Samples > 0 {
+				item.LatencyMsP95 = float64(info.Health.LatencyP95) / float64(time.Millisecond)
+			}
+			if info.Paused != nil {
+				item.Paused = true
+				item.PausedBy = info.Paused.PausedBy
+				item.PausedAt = timeparse.Format(info.Paused.PausedAt)
+			}
+			agents = append(agents, item)
 		}
 	}
 	if agents == nil {
@@ -1017,7 +1289,8 @@ func (a *Admin) handleAgentApprove(w http.ResponseWriter, r *http.Request) {
 
 	// Find the principal by looking for an agent with matching ID
 	// The agent ID might be the principal ID or we need to search
-	if err := a.store.UpdatePrincipalStatus(r.Context(), agentID, store.PrincipalStatusApproved); err != nil {
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditApprovePrincipal, "principal", agentID, nil))
+	if err := a.store.UpdatePrincipalStatus(ctx, agentID, store.PrincipalStatusApproved); err != nil {
 		if errors.Is(err, store.ErrPrincipalNotFound) {
 			http.Error(w, "Agent not found", http.StatusNotFound)
 			return
@@ -1028,6 +1301,7 @@ func (a *Admin) handleAgentApprove(w http.ResponseWriter, r *http.Request) {
 	}
 
 	a.logger.Info("agent approved", "agent_id", agentID)
+	a.notifyPendingAgents(agentID, agent.ApprovalApproved)
 	http.Redirect(w, r, "/admin/agents", http.StatusSeeOther)
 }
 
@@ -1044,7 +1318,8 @@ func (a *Admin) handleAgentRevoke(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := a.store.UpdatePrincipalStatus(r.Context(), agentID, store.PrincipalStatusRevoked); err != nil {
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditRevokePrincipal, "principal", agentID, nil))
+	if err := a.store.UpdatePrincipalStatus(ctx, agentID, store.PrincipalStatusRevoked); err != nil {
 		if errors.Is(err, store.ErrPrincipalNotFound) {
 			http.Error(w, "Agent not found", http.StatusNotFound)
 			return
@@ -1055,6 +1330,7 @@ func (a *Admin) handleAgentRevoke(w http.ResponseWriter, r *http.Request) {
 	}
 
 	a.logger.Info("agent revoked", "agent_id", agentID)
+	a.notifyPendingAgents(agentID, agent.ApprovalRevoked)
 	http.Redirect(w, r, "/admin/agents", http.StatusSeeOther)
 }
 
@@ -1087,11 +1363,17 @@ func (a *Admin) handleAgentDetail(w http.ResponseWriter, r *http.Request) {
 				break
 			}
 		}
+		if pause, ok := a.manager.PauseState(agentID); ok {
+			agentInfo.Paused = true
+			agentInfo.PausedBy = pause.PausedBy
+			agentInfo.PausedAt = timeparse.Format(pause.PausedAt)
+		}
 	}
+	agentInfo.WriteQuota = a.agentWriteQuota(r.Context(), agentID)
 
 	// Get threads associated with this agent
 	// For now, we'll get all threads and filter - could be optimized with store method
-	allThreads, err := a.store.ListThreads(r.Context(), 100)
+	allThreads, err := a.store.ListThreads(r.Context(), 100, false)
 	var agentThreads []*store.Thread
 	if err == nil {
 		for _, thread := range allThreads {
@@ -1106,6 +1388,26 @@ func (a *Admin) handleAgentDetail(w http.ResponseWriter, r *http.Request) {
 	a.renderAgentDetail(w, user, agentInfo, agentThreads, csrfToken)
 }
 
+// agentWriteQuota returns the agent's builtin write counts for today (UTC)
+// against the configured daily quotas. A store error is logged and yields
+// no rows rather than failing the page.
+func (a *Admin) agentWriteQuota(ctx context.Context, agentID string) []quotaUsageItem {
+	usage, err := a.store.GetBuiltinQuotaUsage(ctx, agentID, builtins.QuotaDay(time.Now()))
+	if err != nil {
+		a.logger.Warn("failed to load builtin quota usage", "agent_id", agentID, "error", err)
+		return nil
+	}
+	items := make([]quotaUsageItem, 0, len(builtins.QuotaKinds))
+	for _, kind := range builtins.QuotaKinds {
+		items = append(items, quotaUsageItem{
+			Kind:  kind,
+			Used:  usage[kind],
+			Limit: a.config.BuiltinLimits.QuotaFor(kind),
+		})
+	}
+	return items
+}
+
 // handleAgentDetailJSON returns agent detail as JSON for the Svelte island.
 func (a *Admin) handleAgentDetailJSON(w http.ResponseWriter, r *http.Request) {
 	agentID := r.PathValue("id")
@@ -1132,7 +1434,13 @@ func (a *Admin) handleAgentDetailJSON(w http.ResponseWriter, r *http.Request) {
 				break
 			}
 		}
+		if pause, ok := a.manager.PauseState(agentID); ok {
+			agentInfo.Paused = true
+			agentInfo.PausedBy = pause.PausedBy
+			agentInfo.PausedAt = timeparse.Format(pause.PausedAt)
+		}
 	}
+	agentInfo.WriteQuota = a.agentWriteQuota(r.Context(), agentID)
 
 	if agentInfo.Capabilities == nil {
 		agentInfo.Capabilities = []string{}
@@ -1140,6 +1448,9 @@ func (a *Admin) handleAgentDetailJSON(w http.ResponseWriter, r *http.Request) {
 	if agentInfo.Workspaces == nil {
 		agentInfo.Workspaces = []string{}
 	}
+	if agentInfo.WriteQuota == nil {
+		agentInfo.WriteQuota = []quotaUsageItem{}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(agentInfo); err != nil {
@@ -1148,7 +1459,8 @@ func (a *Admin) handleAgentDetailJSON(w http.ResponseWriter, r *http.Request) {
 }
 
 // createInviteToken generates and stores a new invite token, returning the invite URL.
-func (a *Admin) createInviteToken(ctx context.Context, user *store.AdminUser) (string, error) {
+// The audit entry names the invite by a digest so the token itself is never logged.
+func (a *Admin) createInviteToken(r *http.Request, user *store.AdminUser) (string, error) {
 	token, err := generateSecureToken(32)
 	if err != nil {
 		return "", fmt.Errorf("generate token: %w", err)
@@ -1161,6 +1473,10 @@ func (a *Admin) createInviteToken(ctx context.Context, user *store.AdminUser) (s
 		ExpiresAt: time.Now().Add(InviteDuration),
 	}
 
+	digest := sha256.Sum256([]byte(token))
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditCreateInvite, "invite", hex.EncodeToString(digest[:8]), map[string]any{
+		"expires_at": timeparse.Format(invite.ExpiresAt),
+	}))
 	if err := a.store.CreateAdminInvite(ctx, invite); err != nil {
 		return "", fmt.Errorf("store invite: %w", err)
 	}
@@ -1177,7 +1493,7 @@ func (a *Admin) handleCreateInviteJSON(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	inviteURL, err := a.createInviteToken(r.Context(), getUserFromContext(r))
+	inviteURL, err := a.createInviteToken(r, getUserFromContext(r))
 	if err != nil {
 		a.logger.Error("failed to create invite", "error", err)
 		http.Error(w, "Failed to create invite", http.StatusInternalServerError)
@@ -1206,10 +1522,11 @@ func sortedToolItems(tools []toolItem) []toolItem {
 	return tools
 }
 
-func (a *Admin) listPackItems() []packItem {
+func (a *Admin) listPackItems(ctx context.Context) []packItem {
 	if a.registry == nil {
 		return []packItem{}
 	}
+	recent := a.recentToolChanges(ctx)
 
 	var items []packItem
 
@@ -1225,9 +1542,15 @@ func (a *Admin) listPackItems() []packItem {
 				Description:          t.Definition.GetDescription(),
 				TimeoutSeconds:       t.Definition.GetTimeoutSeconds(),
 				RequiredCapabilities: t.Definition.GetRequiredCapabilities(),
+				Reliability:          a.reliabilityFor(reliability.KindTool, t.Definition.GetName()),
+				Origin:               bp.Origin,
 			})
 		}
-		items = append(items, packItem{ID: bp.ID, Version: "builtin", Tools: sortedToolItems(tools)})
+		version := "builtin"
+		if strings.HasPrefix(bp.ID, mcpbridge.PackPrefix) {
+			version = "mcp"
+		}
+		items = append(items, packItem{ID: bp.ID, Version: version, Tools: sortedToolItems(tools), Degraded: bp.Degraded})
 	}
 
 	// External packs: group tools by pack ID
@@ -1241,10 +1564,16 @@ func (a *Admin) listPackItems() []packItem {
 			Description:          t.Definition.GetDescription(),
 			TimeoutSeconds:       t.Definition.GetTimeoutSeconds(),
 			RequiredCapabilities: t.Definition.GetRequiredCapabilities(),
+			RecentChange:         recent[t.PackID+"/"+t.Definition.GetName()],
+			Reliability:          a.reliabilityFor(reliability.KindTool, t.Definition.GetName()),
 		})
 	}
 	for _, pi := range a.registry.ListPacks() {
-		items = append(items, packItem{ID: pi.ID, Version: pi.Version, Tools: sortedToolItems(toolsByPack[pi.ID])})
+		item := packItem{ID: pi.ID, Version: pi.Version, Tools: sortedToolItems(toolsByPack[pi.ID])}
+		if !pi.Healthy {
+			item.Degraded = "missed heartbeats"
+		}
+		items = append(items, item)
 	}
 
 	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
@@ -1256,13 +1585,13 @@ func (a *Admin) handleToolsPage(w http.ResponseWriter, r *http.Request) {
 	user := getUserFromContext(r)
 	csrfToken := a.ensureCSRFToken(w, r)
 
-	packs := a.listPackItems()
+	packs := a.listPackItems(r.Context())
 	a.renderToolsPage(w, user, csrfToken, packs)
 }
 
 // handleToolsJSON returns tool packs as JSON for the Svelte island.
 func (a *Admin) handleToolsJSON(w http.ResponseWriter, r *http.Request) {
-	packs := a.listPackItems()
+	packs := a.listPackItems(r.Context())
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(packs); err != nil {
@@ -1326,7 +1655,7 @@ func (a *Admin) handleLogsJSON(w http.ResponseWriter, r *http.Request) {
 			AgentID:   e.AgentID,
 			Message:   e.Message,
 			Tags:      tags,
-			CreatedAt: e.CreatedAt.Format(time.RFC3339),
+			CreatedAt: timeparse.Format(e.CreatedAt),
 		})
 	}
 
@@ -1349,8 +1678,12 @@ func (a *Admin) handleTodosPage(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		a.logger.Error("failed to pre-fetch todos", "error", err)
 	}
+	members, err := a.store.ListProjectMembers(r.Context())
+	if err != nil {
+		a.logger.Error("failed to pre-fetch project members", "error", err)
+	}
 
-	a.renderTodosPage(w, user, todos, csrfToken)
+	a.renderTodosPage(w, user, todos, members, csrfToken)
 }
 
 // handleTodosJSON returns todos as JSON for the Svelte island.
@@ -1369,40 +1702,7 @@ func (a *Admin) handleTodosJSON(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"failed to load todos"}`, http.StatusInternalServerError)
 		return
 	}
-	if todos == nil {
-		todos = []*store.Todo{}
-	}
-
-	type todoJSON struct {
-		ID          string  `json:"ID"`
-		AgentID     string  `json:"AgentID"`
-		Description string  `json:"Description"`
-		Status      string  `json:"Status"`
-		Priority    string  `json:"Priority"`
-		Notes       string  `json:"Notes"`
-		DueDate     *string `json:"DueDate"`
-		CreatedAt   string  `json:"CreatedAt"`
-		UpdatedAt   string  `json:"UpdatedAt"`
-	}
-	items := make([]todoJSON, 0, len(todos))
-	for _, t := range todos {
-		var dueDate *string
-		if t.DueDate != nil {
-			s := t.DueDate.Format(time.RFC3339)
-			dueDate = &s
-		}
-		items = append(items, todoJSON{
-			ID:          t.ID,
-			AgentID:     t.AgentID,
-			Description: t.Description,
-			Status:      t.Status,
-			Priority:    t.Priority,
-			Notes:       t.Notes,
-			DueDate:     dueDate,
-			CreatedAt:   t.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:   t.UpdatedAt.Format(time.RFC3339),
-		})
-	}
+	items := todoItems(todos, time.Now())
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string]any{"todos": items}); err != nil {
@@ -1414,61 +1714,47 @@ func (a *Admin) handleTodosJSON(w http.ResponseWriter, r *http.Request) {
 // BBS Board Handlers (builtin pack data)
 // =============================================================================
 
-// handleBoardPage renders the BBS board page.
+// handleBoardPage renders the BBS board page, one page of boardPageSize
+// threads per ?page=N.
 func (a *Admin) handleBoardPage(w http.ResponseWriter, r *http.Request) {
 	user := getUserFromContext(r)
 	csrfToken := a.ensureCSRFToken(w, r)
 
-	threads, err := a.store.ListBBSThreads(r.Context(), 50)
+	page := 1
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 1 {
+		page = p
+	}
+	threads, hasMore, err := a.listBoardThreads(r.Context(), (page-1)*boardPageSize, boardPageSize)
 	if err != nil {
 		a.logger.Error("failed to pre-fetch BBS threads", "error", err)
 	}
 
-	a.renderBoardPage(w, user, threads, csrfToken)
+	a.renderBoardPage(w, user, threads, page, hasMore, csrfToken)
 }
 
-// handleBoardJSON returns BBS threads as JSON for the Svelte island.
+// handleBoardJSON returns BBS threads as JSON for the Svelte island, paged
+// with ?offset and ?limit.
 func (a *Admin) handleBoardJSON(w http.ResponseWriter, r *http.Request) {
 	limitStr := r.URL.Query().Get("limit")
-	limit := 50
+	limit := boardPageSize
 	if limitStr != "" {
 		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 200 {
 			limit = l
 		}
 	}
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o > 0 {
+		offset = o
+	}
 
-	threads, err := a.store.ListBBSThreads(r.Context(), limit)
+	threads, hasMore, err := a.listBoardThreads(r.Context(), offset, limit)
 	if err != nil {
 		a.logger.Error("failed to list BBS threads", "error", err)
 		http.Error(w, `{"error":"failed to load threads"}`, http.StatusInternalServerError)
 		return
 	}
-	if threads == nil {
-		threads = []*store.BBSPost{}
-	}
-
-	type postJSON struct {
-		ID        string `json:"ID"`
-		AgentID   string `json:"AgentID"`
-		ThreadID  string `json:"ThreadID"`
-		Subject   string `json:"Subject"`
-		Content   string `json:"Content"`
-		CreatedAt string `json:"CreatedAt"`
-	}
-	items := make([]postJSON, 0, len(threads))
-	for _, t := range threads {
-		items = append(items, postJSON{
-			ID:        t.ID,
-			AgentID:   t.AgentID,
-			ThreadID:  t.ThreadID,
-			Subject:   t.Subject,
-			Content:   t.Content,
-			CreatedAt: t.CreatedAt.Format(time.RFC3339),
-		})
-	}
-
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]any{"threads": items}); err != nil {
+	if err := json.NewEncoder(w).Encode(map[string]any{"threads": toBoardPosts(threads), "hasMore": hasMore}); err != nil {
 		a.logger.Error("failed to encode board JSON", "error", err)
 	}
 }
@@ -1492,36 +1778,9 @@ func (a *Admin) handleBoardThreadJSON(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	type postJSON struct {
-		ID        string `json:"ID"`
-		AgentID   string `json:"AgentID"`
-		Subject   string `json:"Subject"`
-		Content   string `json:"Content"`
-		CreatedAt string `json:"CreatedAt"`
-	}
-	var replies []postJSON
-	for _, r := range thread.Replies {
-		replies = append(replies, postJSON{
-			ID:        r.ID,
-			AgentID:   r.AgentID,
-			Subject:   r.Subject,
-			Content:   r.Content,
-			CreatedAt: r.CreatedAt.Format(time.RFC3339),
-		})
-	}
-	if replies == nil {
-		replies = []postJSON{}
-	}
-
 	result := map[string]any{
-		"post": postJSON{
-			ID:        thread.Post.ID,
-			AgentID:   thread.Post.AgentID,
-			Subject:   thread.Post.Subject,
-			Content:   thread.Post.Content,
-			CreatedAt: thread.Post.CreatedAt.Format(time.RFC3339),
-		},
-		"replies": replies,
+		"post":    toBoardPost(thread.Post),
+		"replies": toBoardPosts(thread.Replies),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1575,11 +1834,44 @@ func (a *Admin) handlePrincipalsJSON(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(principals); err != nil {
+	if err := json.NewEncoder(w).Encode(a.principalViews(principals)); err != nil {
 		a.logger.Error("failed to encode principals JSON", "error", err)
 	}
 }
 
+// principalView is a principal plus live connection state for the admin UI.
+type principalView struct {
+	store.Principal
+	WaitingAgents int // agents connected right now and waiting for approval
+}
+
+// MarshalJSON adds WaitingAgents to the principal's own JSON object; without
+// it the embedded Principal's MarshalJSON would be promoted and drop the field.
+func (v principalView) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(v.Principal)
+	if err != nil {
+		return nil, err
+	}
+	return append(b[:len(b)-1], fmt.Sprintf(`,"WaitingAgents":%d}`, v.WaitingAgents)...), nil
+}
+
+// principalViews annotates principals with how many of their agents are
+// currently connected and waiting for approval.
+func (a *Admin) principalViews(principals []store.Principal) []principalView {
+	waiting := make(map[string]int)
+	if a.manager != nil {
+		for _, p := range a.manager.ListPending() {
+			waiting[p.PrincipalID]++
+		}
+	}
+
+	views := make([]principalView, len(principals))
+	for i, p := range principals {
+		views[i] = principalView{Principal: p, WaitingAgents: waiting[p.ID]}
+	}
+	return views
+}
+
 // handlePrincipalApprove approves a pending principal.
 func (a *Admin) handlePrincipalApprove(w http.ResponseWriter, r *http.Request) {
 	if !a.validateCSRF(r) {
@@ -1593,7 +1885,8 @@ func (a *Admin) handlePrincipalApprove(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := a.store.UpdatePrincipalStatus(r.Context(), principalID, store.PrincipalStatusApproved); err != nil {
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditApprovePrincipal, "principal", principalID, nil))
+	if err := a.store.UpdatePrincipalStatus(ctx, principalID, store.PrincipalStatusApproved); err != nil {
 		if errors.Is(err, store.ErrPrincipalNotFound) {
 			http.Error(w, "Principal not found", http.StatusNotFound)
 			return
@@ -1604,6 +1897,7 @@ func (a *Admin) handlePrincipalApprove(w http.ResponseWriter, r *http.Request) {
 	}
 
 	a.logger.Info("principal approved", "principal_id", principalID)
+	a.notifyPendingAgents(principalID, agent.ApprovalApproved)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	_, _ = w.Write([]byte(`<span class="px-2 py-1 text-xs rounded-full bg-green-100 text-green-800">approved</span>`))
 }
@@ -1621,7 +1915,8 @@ func (a *Admin) handlePrincipalRevoke(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := a.store.UpdatePrincipalStatus(r.Context(), principalID, store.PrincipalStatusRevoked); err != nil {
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditRevokePrincipal, "principal", principalID, nil))
+	if err := a.store.UpdatePrincipalStatus(ctx, principalID, store.PrincipalStatusRevoked); err != nil {
 		if errors.Is(err, store.ErrPrincipalNotFound) {
 			http.Error(w, "Principal not found", http.StatusNotFound)
 			return
@@ -1632,10 +1927,21 @@ func (a *Admin) handlePrincipalRevoke(w http.ResponseWriter, r *http.Request) {
 	}
 
 	a.logger.Info("principal revoked", "principal_id", principalID)
+	a.notifyPendingAgents(principalID, agent.ApprovalRevoked)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	_, _ = w.Write([]byte(`<span class="px-2 py-1 text-xs rounded-full bg-red-100 text-red-800">revoked</span>`))
 }
 
+// notifyPendingAgents pushes an approval decision to any agents of the
+// principal that are connected and waiting, so they proceed or disconnect
+// without reconnecting.
+func (a *Admin) notifyPendingAgents(principalID string, decision agent.ApprovalDecision) {
+	if a.manager == nil {
+		return
+	}
+	a.manager.ResolvePending(principalID, decision)
+}
+
 // handlePrincipalDelete deletes a principal.
 func (a *Admin) handlePrincipalDelete(w http.ResponseWriter, r *http.Request) {
 	if !a.validateCSRF(r) {
@@ -1649,7 +1955,8 @@ func (a *Admin) handlePrincipalDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := a.store.DeletePrincipal(r.Context(), principalID); err != nil {
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditDeletePrincipal, "principal", principalID, nil))
+	if err := a.store.DeletePrincipal(ctx, principalID); err != nil {
 		if errors.Is(err, store.ErrPrincipalNotFound) {
 			http.Error(w, "Principal not found", http.StatusNotFound)
 			return
@@ -1660,6 +1967,7 @@ func (a *Admin) handlePrincipalDelete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	a.logger.Info("principal deleted", "principal_id", principalID)
+	a.notifyPendingAgents(principalID, agent.ApprovalRevoked)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -1673,7 +1981,7 @@ func (a *Admin) handleThreadsPage(w http.ResponseWriter, r *http.Request) {
 	csrfToken := a.ensureCSRFToken(w, r)
 
 	// Load threads from store
-	threads, err := a.store.ListThreads(r.Context(), 100)
+	threads, err := a.store.ListThreads(r.Context(), 100, false)
 	if err != nil {
 		a.logger.Error("failed to list threads", "error", err)
 		threads = nil // Show empty state on error
@@ -1682,9 +1990,11 @@ func (a *Admin) handleThreadsPage(w http.ResponseWriter, r *http.Request) {
 	a.renderThreadsPageWithData(w, user, threads, csrfToken)
 }
 
-// handleThreadsJSON returns threads as JSON for the Svelte island.
+// handleThreadsJSON returns threads as JSON for the Svelte island. Archived
+// threads are included only with ?include_archived=true.
 func (a *Admin) handleThreadsJSON(w http.ResponseWriter, r *http.Request) {
-	threads, err := a.store.ListThreads(r.Context(), 100)
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	threads, err := a.store.ListThreads(r.Context(), 100, includeArchived)
 	if err != nil {
 		a.logger.Error("failed to list threads", "error", err)
 		http.Error(w, "Failed to load threads", http.StatusInternalServerError)
@@ -1718,17 +2028,54 @@ func (a *Admin) handleThreadDetail(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to load thread", http.StatusInternalServerError)
 		return
 	}
+	if thread.MergedInto != "" {
+		http.Redirect(w, r, "/admin/threads/"+thread.MergedInto, http.StatusSeeOther)
+		return
+	}
 
-	messages, err := a.store.GetThreadMessages(r.Context(), threadID, 100)
+	messages, err := a.threadEventMessages(r, threadID)
 	if err != nil {
-		a.logger.Error("failed to get thread messages", "error", err, "thread_id", threadID)
 		http.Error(w, "Failed to load messages", http.StatusInternalServerError)
 		return
 	}
+	requestUsage, ok := a.threadRequestUsage(w, r, threadID)
+	if !ok {
+		return
+	}
 
 	user := getUserFromContext(r)
 	csrfToken := a.ensureCSRFToken(w, r)
-	a.renderThreadDetail(w, user, thread, messages, csrfToken)
+	a.renderThreadDetail(w, user, thread, messages, requestUsage, csrfToken)
+}
+
+// threadRequestUsage loads a thread's per-request usage for the since/until
+// window in the query (the usage drill-down links here with its window).
+// It writes the error response and returns false on failure.
+func (a *Admin) threadRequestUsage(w http.ResponseWriter, r *http.Request, threadID string) ([]requestUsageItem, bool) {
+	filter, err := usageWindow(r)
+	if err != nil {
+		a.writeTimestampError(w, err)
+		return nil, false
+	}
+	items, err := a.listRequestUsageItems(r.Context(), threadID, filter)
+	if err != nil {
+		a.logger.Error("failed to list request usage", "error", err, "thread_id", threadID)
+		http.Error(w, "Failed to load usage", http.StatusInternalServerError)
+		return nil, false
+	}
+	return items, true
+}
+
+// threadEventMessages loads a thread's recent history from ledger_events, the
+// source of truth for messages. Message IDs are event IDs, so they can be used
+// as split points.
+func (a *Admin) threadEventMessages(r *http.Request, threadID string) ([]*store.Message, error) {
+	events, err := a.store.GetEventsByThreadID(r.Context(), threadID, 100)
+	if err != nil {
+		a.logger.Error("failed to get thread events", "error", err, "thread_id", threadID)
+		return nil, err
+	}
+	return store.EventsToMessages(events), nil
 }
 
 // handleThreadDetailJSON returns thread detail as JSON for the Svelte island.
@@ -1749,20 +2096,28 @@ func (a *Admin) handleThreadDetailJSON(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to load thread", http.StatusInternalServerError)
 		return
 	}
+	if thread.MergedInto != "" {
+		http.Redirect(w, r, "/api/admin/threads/"+thread.MergedInto, http.StatusPermanentRedirect)
+		return
+	}
 
-	messages, err := a.store.GetThreadMessages(r.Context(), threadID, 100)
+	messages, err := a.threadEventMessages(r, threadID)
 	if err != nil {
-		a.logger.Error("failed to get thread messages", "error", err, "thread_id", threadID)
 		http.Error(w, "Failed to load messages", http.StatusInternalServerError)
 		return
 	}
 	if messages == nil {
 		messages = []*store.Message{}
 	}
+	requestUsage, ok := a.threadRequestUsage(w, r, threadID)
+	if !ok {
+		return
+	}
 
 	result := map[string]any{
-		"thread":   thread,
-		"messages": messages,
+		"thread":       thread,
+		"messages":     messages,
+		"requestUsage": requestUsage,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(result); err != nil {
@@ -1844,8 +2199,15 @@ func (a *Admin) handleChatSend(w http.ResponseWriter, r *http.Request) {
 	convResp, err := a.conversation.SendMessage(context.WithoutCancel(r.Context()), convReq)
 	if err != nil {
 		a.logger.Error("failed to send message to agent", "error", err, "agent_id", agentID)
+		var pausedErr *agent.PausedError
 		if errors.Is(err, agent.ErrAgentNotFound) {
 			http.Error(w, "Agent not connected", http.StatusNotFound)
+		} else if errors.As(err, &pausedErr) {
+			http.Error(w, "Agent paused by "+pausedErr.PausedBy, http.StatusConflict)
+		} else if errors.Is(err, agent.ErrAgentBusy) {
+			http.Error(w, "Agent is busy; try again shortly", http.StatusTooManyRequests)
+		} else if errors.Is(err, diskmon.ErrStorageFull) {
+			http.Error(w, "Gateway storage is full; new messages are paused", http.StatusInsufficientStorage)
 		} else {
 			http.Error(w, "Failed to send message", http.StatusInternalServerError)
 		}
@@ -1867,10 +2229,12 @@ func (a *Admin) handleChatSend(w http.ResponseWriter, r *http.Request) {
 
 	a.logger.Debug("message sent to agent", "agent_id", agentID, "user", user.Username)
 
-	// Return success - responses will stream via /stream endpoint
+	// Return success - responses will stream via /stream endpoint. The
+	// request ID lets the UI stop the response via /cancel.
 	response := map[string]string{
-		"status":   "sent",
-		"agent_id": agentID,
+		"status":     "sent",
+		"agent_id":   agentID,
+		"request_id": convResp.MessageID,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1879,6 +2243,36 @@ func (a *Admin) handleChatSend(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleChatCancel stops a response this chat started: the agent is told to
+// cancel it and the stream ends with a canceled event, reason user_requested.
+func (a *Admin) handleChatCancel(w http.ResponseWriter, r *http.Request) {
+	if !a.validateCSRF(r) {
+		http.Error(w, "Invalid request", http.StatusForbidden)
+		return
+	}
+	if a.conversation == nil {
+		http.Error(w, "Conversation service not available", http.StatusServiceUnavailable)
+		return
+	}
+	agentID := r.PathValue("id")
+	requestID := r.FormValue("request_id")
+	if requestID == "" {
+		http.Error(w, "request_id required", http.StatusBadRequest)
+		return
+	}
+
+	// Admins may cancel any request, but only through the chat it belongs to.
+	if req, ok := a.conversation.InFlight(requestID); !ok || req.AgentID != agentID {
+		http.Error(w, "Request not found or already finished", http.StatusNotFound)
+		return
+	}
+	if err := a.conversation.CancelRequest(requestID, agent.UserRequestedReason); err != nil {
+		http.Error(w, "Request not found or already finished", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
 // handlePipeResponse processes a single response and returns true to continue, false to stop.
 func handlePipeResponse(ctx context.Context, session *chatSession, resp *agent.Response) bool {
 	msg := convertAgentResponse(resp)
@@ -1931,21 +2325,49 @@ func (a *Admin) pipeAgentResponses(ctx context.Context, agentID, userID string,
 
 // chatStreamContext holds state for an SSE chat stream.
 type chatStreamContext struct {
-	w          http.ResponseWriter
-	flusher    http.Flusher
-	session    *chatSession
-	seenEvents map[string]struct{}
-	logger     *slog.Logger
+	w             http.ResponseWriter
+	flusher       http.Flusher
+	session       *chatSession
+	seenEvents    map[string]struct{}
+	seenQuestions map[string]struct{} // questions already replayed on connect
+	chunkBytes    int                 // largest text or thinking content per event
+	logger        *slog.Logger
 }
 
 // sendSessionMessage handles a message from the chat session.
 func (ctx *chatStreamContext) sendSessionMessage(msg *chatMessage) {
-	data, err := json.Marshal(msg)
-	if err != nil {
-		ctx.logger.Error("failed to marshal chat message", "error", err)
-		return
+	if msg.Type == "user_question" {
+		if _, seen := ctx.seenQuestions[msg.QuestionID]; seen {
+			return
+		}
+		ctx.seenQuestions[msg.QuestionID] = struct{}{}
+	}
+	ctx.writeChatMessage("", msg)
+}
+
+// writeChatMessage writes msg as an SSE event. Text and thinking longer than
+// the chunk size go out as several events, the later ones marked continued.
+// id, when set, goes on the last of them only, so a client that drops
+// part-way through is replayed the whole message.
+func (ctx *chatStreamContext) writeChatMessage(id string, msg *chatMessage) {
+	chunks := []string{msg.Content}
+	if msg.Type == "text" || msg.Type == "thinking" {
+		chunks = ssechunk.Split(msg.Content, ctx.chunkBytes)
+	}
+	for i, chunk := range chunks {
+		part := *msg
+		part.Content = chunk
+		part.Continued = i > 0
+		data, err := json.Marshal(&part)
+		if err != nil {
+			ctx.logger.Error("failed to marshal chat message", "error", err)
+			return
+		}
+		if id != "" && i == len(chunks)-1 {
+			_, _ = fmt.Fprintf(ctx.w, "id: %s\n", id)
+		}
+		_, _ = fmt.Fprintf(ctx.w, "event: %s\ndata: %s\n\n", msg.Type, data)
 	}
-	_, _ = fmt.Fprintf(ctx.w, "event: %s\ndata: %s\n\n", msg.Type, data)
 	ctx.flusher.Flush()
 }
 
@@ -1959,33 +2381,76 @@ func (ctx *chatStreamContext) sendBroadcastEvent(event *store.LedgerEvent) {
 		return
 	}
 
+	ctx.sendLedgerEvent(event)
+}
+
+// sendLagged tells the client that skipped broadcast events were dropped
+// because it was not keeping up.
+func (ctx *chatStreamContext) sendLagged(skipped int) {
+	_, _ = fmt.Fprintf(ctx.w, "event: lagged\ndata: {\"skipped\": %d}\n\n", skipped)
+	ctx.flusher.Flush()
+}
+
+// sendLedgerEvent writes a persisted event once per stream. Its ID goes out
+// as the SSE id so a reconnecting client can send it back as Last-Event-ID.
+func (ctx *chatStreamContext) sendLedgerEvent(event *store.LedgerEvent) {
 	// Skip already-seen events
 	if _, seen := ctx.seenEvents[event.ID]; seen {
 		return
 	}
 	ctx.seenEvents[event.ID] = struct{}{}
 
-	msg := ledgerEventToChatMessage(event)
-	data, err := json.Marshal(msg)
-	if err != nil {
-		ctx.logger.Error("failed to marshal broadcast event", "error", err)
+	ctx.writeChatMessage(event.ID, ledgerEventToChatMessage(event))
+}
+
+// maxChatReplayEvents bounds how much history a reconnecting stream replays.
+const maxChatReplayEvents = 1000
+
+// replayChatEvents sends the events a reconnecting client missed, starting
+// after the one named by its Last-Event-ID. The ledger orders events by the
+// second, so events from that same second may be sent again.
+func (a *Admin) replayChatEvents(r *http.Request, ctx *chatStreamContext, agentID, lastEventID string) {
+	last, err := a.store.GetEvent(r.Context(), lastEventID)
+	if err != nil || last.ConversationKey != agentID {
+		a.logger.Debug("cannot replay chat stream from unknown event", "agent_id", agentID, "last_event_id", lastEventID, "error", err)
 		return
 	}
+	ctx.seenEvents[last.ID] = struct{}{}
 
-	_, _ = fmt.Fprintf(ctx.w, "event: %s\ndata: %s\n\n", msg.Type, data)
-	ctx.flusher.Flush()
+	since := last.Timestamp
+	cursor := ""
+	for sent := 0; sent < maxChatReplayEvents; {
+		page, err := a.store.GetEvents(r.Context(), store.GetEventsParams{
+			ConversationKey: agentID,
+			Since:           &since,
+			Limit:           min(500, maxChatReplayEvents-sent),
+			Cursor:          cursor,
+		})
+		if err != nil {
+			a.logger.Warn("failed to replay chat stream", "agent_id", agentID, "error", err)
+			return
+		}
+		for i := range page.Events {
+			ctx.sendLedgerEvent(&page.Events[i])
+		}
+		sent += len(page.Events)
+		if !page.HasMore {
+			return
+		}
+		cursor = page.NextCursor
+	}
 }
 
 // setupChatStreamBroadcaster subscribes to the broadcaster and configures the session.
-func (a *Admin) setupChatStreamBroadcaster(r *http.Request, session *chatSession, agentID string) <-chan *store.LedgerEvent {
+func (a *Admin) setupChatStreamBroadcaster(r *http.Request, session *chatSession, agentID string) *conversation.Subscription {
 	if a.broadcaster == nil {
 		return nil
 	}
-	broadcastCh, subID := a.broadcaster.Subscribe(r.Context(), agentID)
+	sub := a.broadcaster.Subscribe(r.Context(), agentID)
 	session.mu.Lock()
-	session.broadcastSubID = subID
+	session.broadcastSubID = sub.ID
 	session.mu.Unlock()
-	return broadcastCh
+	return sub
 }
 
 // handleChatStream handles SSE streaming of chat responses.
@@ -2017,7 +2482,12 @@ func (a *Admin) handleChatStream(w http.ResponseWriter, r *http.Request) {
 	}
 
 	session := a.chatHub.getOrCreateSession(agentID, user.ID)
-	broadcastCh := a.setupChatStreamBroadcaster(r, session, agentID)
+	sub := a.setupChatStreamBroadcaster(r, session, agentID)
+	var broadcastCh <-chan conversation.BroadcastEvent
+	if sub != nil {
+		defer sub.Close()
+		broadcastCh = sub.Events()
+	}
 
 	_, _ = fmt.Fprintf(w, "event: connected\ndata: {\"agent_id\": %q}\n\n", agentID)
 	flusher.Flush()
@@ -2026,18 +2496,29 @@ func (a *Admin) handleChatStream(w http.ResponseWriter, r *http.Request) {
 	defer heartbeat.Stop()
 
 	ctx := &chatStreamContext{
-		w:          w,
-		flusher:    flusher,
-		session:    session,
-		seenEvents: make(map[string]struct{}),
-		logger:     a.logger,
+		w:             w,
+		flusher:       flusher,
+		session:       session,
+		seenEvents:    make(map[string]struct{}),
+		seenQuestions: make(map[string]struct{}),
+		chunkBytes:    ssechunk.MaxBytes(a.config.SSEChunkBytes),
+		logger:        a.logger,
+	}
+
+	// A reconnecting EventSource sends the last id it saw; fill the gap
+	// before going live. Events broadcast meanwhile are deduplicated.
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		a.replayChatEvents(r, ctx, agentID, lastEventID)
 	}
+	a.replayPendingQuestions(r, ctx, agentID)
 
-	a.runChatStreamLoop(r, ctx, heartbeat, broadcastCh)
+	a.runChatStreamLoop(r, ctx, heartbeat, sub, broadcastCh)
 }
 
-// runChatStreamLoop runs the main event loop for chat streaming.
-func (a *Admin) runChatStreamLoop(r *http.Request, ctx *chatStreamContext, heartbeat *time.Ticker, broadcastCh <-chan *store.LedgerEvent) {
+// runChatStreamLoop runs the main event loop for chat streaming. A
+// subscription the broadcaster drops for falling behind ends the stream, so
+// the client reconnects and replays what it missed.
+func (a *Admin) runChatStreamLoop(r *http.Request, ctx *chatStreamContext, heartbeat *time.Ticker, sub *conversation.Subscription, broadcastCh <-chan conversation.BroadcastEvent) {
 	for {
 		select {
 		case <-r.Context().Done():
@@ -2052,12 +2533,19 @@ func (a *Admin) runChatStreamLoop(r *http.Request, ctx *chatStreamContext, heart
 				return
 			}
 			ctx.sendSessionMessage(msg)
-		case event, ok := <-broadcastCh:
+		case delivery, ok := <-broadcastCh:
 			if !ok {
+				if sub.Err() != nil {
+					return
+				}
 				broadcastCh = nil
 				continue
 			}
-			ctx.sendBroadcastEvent(event)
+			if delivery.Lagged > 0 {
+				ctx.sendLagged(delivery.Lagged)
+				continue
+			}
+			ctx.sendBroadcastEvent(delivery.Event)
 		}
 	}
 }
@@ -2071,9 +2559,6 @@ func (a *Admin) handleLinkPage(w http.ResponseWriter, r *http.Request) {
 	user := getUserFromContext(r)
 	csrfToken := a.ensureCSRFToken(w, r)
 
-	// Clean up expired codes
-	_ = a.store.DeleteExpiredLinkCodes(r.Context())
-
 	codes, err := a.store.ListPendingLinkCodes(r.Context())
 	if err != nil {
 		a.logger.Error("failed to list link codes", "error", err)
@@ -2086,8 +2571,6 @@ func (a *Admin) handleLinkPage(w http.ResponseWriter, r *http.Request) {
 
 // handleLinkJSON returns pending link codes as JSON for the Svelte island.
 func (a *Admin) handleLinkJSON(w http.ResponseWriter, r *http.Request) {
-	_ = a.store.DeleteExpiredLinkCodes(r.Context())
-
 	codes, err := a.store.ListPendingLinkCodes(r.Context())
 	if err != nil {
 		a.logger.Error("failed to list link codes", "error", err)
@@ -2098,30 +2581,8 @@ func (a *Admin) handleLinkJSON(w http.ResponseWriter, r *http.Request) {
 		codes = []*store.LinkCode{}
 	}
 
-	type codeJSON struct {
-		ID          string `json:"ID"`
-		Code        string `json:"Code"`
-		Fingerprint string `json:"Fingerprint"`
-		DeviceName  string `json:"DeviceName"`
-		Status      string `json:"Status"`
-		CreatedAt   string `json:"CreatedAt"`
-		ExpiresAt   string `json:"ExpiresAt"`
-	}
-	items := make([]codeJSON, 0, len(codes))
-	for _, c := range codes {
-		items = append(items, codeJSON{
-			ID:          c.ID,
-			Code:        c.Code,
-			Fingerprint: c.Fingerprint,
-			DeviceName:  c.DeviceName,
-			Status:      string(c.Status),
-			CreatedAt:   c.CreatedAt.Format(time.RFC3339),
-			ExpiresAt:   c.ExpiresAt.Format(time.RFC3339),
-		})
-	}
-
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]any{"codes": items}); err != nil {
+	if err := json.NewEncoder(w).Encode(map[string]any{"codes": linkCodeItems(codes)}); err != nil {
 		a.logger.Error("failed to encode link JSON", "error", err)
 	}
 }
@@ -2155,7 +2616,8 @@ func (a *Admin) getOrCreatePrincipalForLink(ctx context.Context, linkCode *store
 	return principalID, nil
 }
 
-// validatePendingLinkCode fetches a link code and validates it's pending.
+// validatePendingLinkCode fetches a link code and validates it's pending and
+// unexpired.
 func (a *Admin) validatePendingLinkCode(w http.ResponseWriter, ctx context.Context, id string) (*store.LinkCode, bool) {
 	linkCode, err := a.store.GetLinkCode(ctx, id)
 	if err != nil {
@@ -2167,6 +2629,10 @@ func (a *Admin) validatePendingLinkCode(w http.ResponseWriter, ctx context.Conte
 		}
 		return nil, false
 	}
+	if linkCode.Status == store.LinkCodeStatusExpired || (linkCode.Status == store.LinkCodeStatusPending && !time.Now().Before(linkCode.ExpiresAt)) {
+		http.Error(w, errLinkCodeExpired, http.StatusGone)
+		return nil, false
+	}
 	if linkCode.Status != store.LinkCodeStatusPending {
 		http.Error(w, "Code already processed", http.StatusBadRequest)
 		return nil, false
@@ -2175,12 +2641,12 @@ func (a *Admin) validatePendingLinkCode(w http.ResponseWriter, ctx context.Conte
 }
 
 // generateApprovalToken creates a principal and generates an auth token.
-func (a *Admin) generateApprovalToken(ctx context.Context, linkCode *store.LinkCode) (string, string, error) {
+func (a *Admin) generateApprovalToken(ctx context.Context, linkCode *store.LinkCode, approvedBy string) (string, string, error) {
 	principalID, err := a.getOrCreatePrincipalForLink(ctx, linkCode)
 	if err != nil {
 		return "", "", fmt.Errorf("create principal: %w", err)
 	}
-	token, err := a.tokenGenerator.Generate(principalID, 30*24*time.Hour)
+	token, err := a.mintToken(ctx, principalID, approvedBy, defaultTokenTTL, []string{auth.ScopeClient})
 	if err != nil {
 		return "", "", fmt.Errorf("generate token: %w", err)
 	}
@@ -2213,7 +2679,7 @@ func (a *Admin) handleLinkApprove(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	principalID, token, err := a.generateApprovalToken(r.Context(), linkCode)
+	principalID, token, err := a.generateApprovalToken(r.Context(), linkCode, user.Username)
 	if err != nil {
 		a.logger.Error("failed to generate approval", "error", err)
 		http.Error(w, "Failed to approve", http.StatusInternalServerError)
@@ -2221,11 +2687,24 @@ func (a *Admin) handleLinkApprove(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := a.store.ApproveLinkCode(r.Context(), id, user.ID, principalID, token); err != nil {
+		if errors.Is(err, store.ErrLinkCodeExpired) {
+			// The code expired while the token was minted. The token was
+			// never handed out: the device only receives it from an
+			// approved code.
+			a.logger.Warn("link code expired during approval", "code", linkCode.Code, "approved_by", user.Username)
+			http.Error(w, errLinkCodeExpired, http.StatusGone)
+			return
+		}
 		a.logger.Error("failed to approve link code", "error", err)
 		http.Error(w, "Failed to approve", http.StatusInternalServerError)
 		return
 	}
 
+	a.auditAdminAction(r, newAuditEntry(r, store.AuditCreatePrincipal, "principal", principalID, map[string]any{
+		"device_name": linkCode.DeviceName,
+		"platform":    linkCode.Platform,
+		"link_code":   linkCode.Code,
+	}))
 	a.logger.Info("link code approved", "code", linkCode.Code, "device", linkCode.DeviceName, "approved_by", user.Username)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	_, _ = w.Write([]byte(`<span class="px-2 py-1 text-xs rounded-full bg-success/20 text-success font-medium">Approved</span>`))
@@ -2237,16 +2716,23 @@ func (a *Admin) handleLinkRequest(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Fingerprint string `json:"fingerprint"`
 		DeviceName  string `json:"device_name"`
+		Platform    string `json:"platform"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	req.DeviceName = strings.TrimSpace(req.DeviceName)
+	req.Platform = strings.TrimSpace(req.Platform)
 
 	if req.Fingerprint == "" || req.DeviceName == "" {
 		http.Error(w, "fingerprint and device_name required", http.StatusBadRequest)
 		return
 	}
+	if len(req.DeviceName) > maxLinkFieldLen || len(req.Platform) > maxLinkFieldLen {
+		http.Error(w, fmt.Sprintf("device_name and platform must be at most %d bytes", maxLinkFieldLen), http.StatusBadRequest)
+		return
+	}
 
 	// Validate fingerprint format (SHA256 hex = 64 chars)
 	if len(req.Fingerprint) != 64 {
@@ -2268,6 +2754,7 @@ func (a *Admin) handleLinkRequest(w http.ResponseWriter, r *http.Request) {
 		Code:        code,
 		Fingerprint: req.Fingerprint,
 		DeviceName:  req.DeviceName,
+		Platform:    req.Platform,
 		Status:      store.LinkCodeStatusPending,
 		CreatedAt:   now,
 		ExpiresAt:   now.Add(LinkCodeDuration),
@@ -2284,12 +2771,12 @@ func (a *Admin) handleLinkRequest(w http.ResponseWriter, r *http.Request) {
 	if len(fpPreview) > 16 {
 		fpPreview = fpPreview[:16] + "..."
 	}
-	a.logger.Info("link code created", "code", code, "device", req.DeviceName, "fingerprint", fpPreview)
+	a.logger.Info("link code created", "code", code, "device", req.DeviceName, "platform", req.Platform, "fingerprint", fpPreview)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string]any{
 		"code":       linkCode.Code,
-		"expires_at": linkCode.ExpiresAt.Format(time.RFC3339),
+		"expires_at": timeparse.Format(linkCode.ExpiresAt),
 	}); err != nil {
 		a.logger.Debug("failed to encode response", "error", err)
 	}
@@ -2358,23 +2845,27 @@ func (a *Admin) handleLinkStatus(w http.ResponseWriter, r *http.Request) {
 // Token Usage Handlers
 // =============================================================================
 
-// handleUsagePage renders the token usage analytics page.
+// handleUsagePage renders the token usage analytics page with the all-time
+// per-agent summary.
 func (a *Admin) handleUsagePage(w http.ResponseWriter, r *http.Request) {
 	user := getUserFromContext(r)
 	csrfToken := a.ensureCSRFToken(w, r)
 
-	usage, _ := a.store.GetUsageStats(r.Context(), store.UsageFilter{})
+	summary, err := a.config.UsagePricing.Summarize(r.Context(), a.store, usage.GroupByAgent, store.UsageFilter{})
+	if err != nil {
+		a.logger.Error("failed to summarize usage", "error", err)
+	}
 
-	a.renderUsagePage(w, user, csrfToken, usage)
+	a.renderUsagePage(w, user, csrfToken, summary)
 }
 
 // handleDashboardJSON returns all dashboard data as JSON for the Svelte island refresh.
 func (a *Admin) handleDashboardJSON(w http.ResponseWriter, r *http.Request) {
 	agents := a.listAgentItems()
-	packs := a.listPackItems()
+	packs := a.listPackItems(r.Context())
 
 	threadCount := 0
-	if threads, err := a.store.ListThreads(r.Context(), 1000); err == nil {
+	if threads, err := a.store.ListThreads(r.Context(), 1000, false); err == nil {
 		threadCount = len(threads)
 	}
 
@@ -2416,17 +2907,10 @@ func (a *Admin) handleDashboardJSON(w http.ResponseWriter, r *http.Request) {
 
 // handleUsageJSON returns token usage stats as JSON with optional filters.
 func (a *Admin) handleUsageJSON(w http.ResponseWriter, r *http.Request) {
-	filter := store.UsageFilter{}
-
-	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
-		if since, err := time.Parse(time.RFC3339, sinceStr); err == nil {
-			filter.Since = &since
-		}
-	}
-	if untilStr := r.URL.Query().Get("until"); untilStr != "" {
-		if until, err := time.Parse(time.RFC3339, untilStr); err == nil {
-			filter.Until = &until
-		}
+	filter, err := usageWindow(r)
+	if err != nil {
+		a.writeTimestampError(w, err)
+		return
 	}
 
 	stats, err := a.store.GetUsageStats(r.Context(), filter)
@@ -2436,20 +2920,7 @@ func (a *Admin) handleUsageJSON(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := map[string]int64{
-		"totalInput":      stats.TotalInput,
-		"totalOutput":     stats.TotalOutput,
-		"totalCacheRead":  stats.TotalCacheRead,
-		"totalCacheWrite": stats.TotalCacheWrite,
-		"totalThinking":   stats.TotalThinking,
-		"totalTokens":     stats.TotalTokens,
-		"requestCount":    stats.RequestCount,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		a.logger.Debug("failed to encode usage JSON", "error", err)
-	}
+	a.writeUsageJSON(w, toUsageTotals(*stats))
 }
 
 // =============================================================================
@@ -2480,7 +2951,7 @@ func (a *Admin) handleSecretsPage(w http.ResponseWriter, r *http.Request) {
 
 // listSecretItems fetches all secrets and converts to display items.
 func (a *Admin) listSecretItems(ctx context.Context) []secretItem {
-	sqlStore := a.getSQLiteStore()
+	sqlStore := a.getSQLStore()
 	if sqlStore == nil {
 		return []secretItem{}
 	}
@@ -2546,7 +3017,7 @@ func (a *Admin) handleSecretsGetValue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sqlStore := a.getSQLiteStore()
+	sqlStore := a.getSQLStore()
 	if sqlStore == nil {
 		http.Error(w, "Server configuration error", http.StatusInternalServerError)
 		return
@@ -2628,7 +3099,7 @@ func (a *Admin) handleSecretsCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sqlStore := a.getSQLiteStore()
+	sqlStore := a.getSQLStore()
 	if sqlStore == nil {
 		http.Error(w, "Server configuration error", http.StatusInternalServerError)
 		return
@@ -2640,8 +3111,13 @@ func (a *Admin) handleSecretsCreate(w http.ResponseWriter, r *http.Request) {
 		userID = &user.ID
 	}
 	secret := buildSecret(data, userID)
+	secret.ID = uuid.New().String()
 
-	if err := sqlStore.CreateSecret(r.Context(), secret); err != nil {
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditCreateSecret, "secret", secret.ID, map[string]any{
+		"key":      secret.Key,
+		"agent_id": data.agentID,
+	}))
+	if err := sqlStore.CreateSecret(ctx, secret); err != nil {
 		a.logger.Error("failed to create secret", "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -2681,7 +3157,7 @@ func (a *Admin) handleSecretsUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sqlStore := a.getSQLiteStore()
+	sqlStore := a.getSQLStore()
 	if sqlStore == nil {
 		http.Error(w, "Server configuration error", http.StatusInternalServerError)
 		return
@@ -2701,7 +3177,10 @@ func (a *Admin) handleSecretsUpdate(w http.ResponseWriter, r *http.Request) {
 
 	// Update value
 	secret.Value = value
-	if err := sqlStore.UpdateSecret(r.Context(), secret); err != nil {
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditUpdateSecret, "secret", secretID, map[string]any{
+		"key": secret.Key,
+	}))
+	if err := sqlStore.UpdateSecret(ctx, secret); err != nil {
 		a.logger.Error("failed to update secret", "error", err)
 		http.Error(w, "Failed to update secret", http.StatusInternalServerError)
 		return
@@ -2724,13 +3203,14 @@ func (a *Admin) handleSecretsDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sqlStore := a.getSQLiteStore()
+	sqlStore := a.getSQLStore()
 	if sqlStore == nil {
 		http.Error(w, "Server configuration error", http.StatusInternalServerError)
 		return
 	}
 
-	if err := sqlStore.DeleteSecret(r.Context(), secretID); err != nil {
+	ctx := auditedContext(r, newAuditEntry(r, store.AuditDeleteSecret, "secret", secretID, nil))
+	if err := sqlStore.DeleteSecret(ctx, secretID); err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "Secret not found", http.StatusNotFound)
 			return
@@ -13,6 +13,7 @@ import (
 
 	"github.com/2389/coven-gateway/internal/packs"
 	"github.com/2389/coven-gateway/internal/store"
+	pb "github.com/2389/coven-gateway/proto/coven"
 )
 
 // newTestAdmin creates a minimal Admin instance for handler testing.
@@ -109,3 +110,28 @@ func TestIsValidEnvKey_MaxLength(t *testing.T) {
 		t.Error("expected 257-char key to be invalid")
 	}
 }
+
+func TestListPackItems_ShowsUpstreamOriginAndHealth(t *testing.T) {
+	registry := packs.NewRegistry(slog.Default())
+	admin := newTestAdmin(registry)
+	pack := &packs.BuiltinPack{
+		ID:     "mcp:github",
+		Origin: "https://mcp.example.com/github",
+		Tools:  []*packs.BuiltinTool{{Definition: &pb.ToolDefinition{Name: "mcp:github:search"}}},
+	}
+	if err := registry.RegisterBuiltinPack(pack); err != nil {
+		t.Fatalf("RegisterBuiltinPack: %v", err)
+	}
+	registry.SetBuiltinPackDegraded("mcp:github", "HTTP 502")
+
+	items := admin.listPackItems(context.Background())
+	if len(items) != 1 || len(items[0].Tools) != 1 {
+		t.Fatalf("items = %+v, want one pack with one tool", items)
+	}
+	if items[0].Version != "mcp" || items[0].Degraded != "HTTP 502" {
+		t.Errorf("pack = %+v, want a degraded mcp pack", items[0])
+	}
+	if got := items[0].Tools[0].Origin; got != "https://mcp.example.com/github" {
+		t.Errorf("tool origin = %q, want the upstream URL", got)
+	}
+}
@@ -93,6 +93,65 @@ func (ToolState) EnumDescriptor() ([]byte, []int) {
 	return file_coven_proto_rawDescGZIP(), []int{0}
 }
 
+// Plan step progress
+type PlanStepStatus int32
+
+const (
+	PlanStepStatus_PLAN_STEP_STATUS_UNSPECIFIED PlanStepStatus = 0
+	PlanStepStatus_PLAN_STEP_STATUS_PENDING     PlanStepStatus = 1
+	PlanStepStatus_PLAN_STEP_STATUS_IN_PROGRESS PlanStepStatus = 2
+	PlanStepStatus_PLAN_STEP_STATUS_COMPLETED   PlanStepStatus = 3
+	PlanStepStatus_PLAN_STEP_STATUS_SKIPPED     PlanStepStatus = 4
+	PlanStepStatus_PLAN_STEP_STATUS_FAILED      PlanStepStatus = 5
+)
+
+// Enum value maps for PlanStepStatus.
+var (
+	PlanStepStatus_name = map[int32]string{
+		0: "PLAN_STEP_STATUS_UNSPECIFIED",
+		1: "PLAN_STEP_STATUS_PENDING",
+		2: "PLAN_STEP_STATUS_IN_PROGRESS",
+		3: "PLAN_STEP_STATUS_COMPLETED",
+		4: "PLAN_STEP_STATUS_SKIPPED",
+		5: "PLAN_STEP_STATUS_FAILED",
+	}
+	PlanStepStatus_value = map[string]int32{
+		"PLAN_STEP_STATUS_UNSPECIFIED": 0,
+		"PLAN_STEP_STATUS_PENDING":     1,
+		"PLAN_STEP_STATUS_IN_PROGRESS": 2,
+		"PLAN_STEP_STATUS_COMPLETED":   3,
+		"PLAN_STEP_STATUS_SKIPPED":     4,
+		"PLAN_STEP_STATUS_FAILED":      5,
+	}
+)
+
+func (x PlanStepStatus) Enum() *PlanStepStatus {
+	p := new(PlanStepStatus)
+	*p = x
+	return p
+}
+
+func (x PlanStepStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PlanStepStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_coven_proto_enumTypes[1].Descriptor()
+}
+
+func (PlanStepStatus) Type() protoreflect.EnumType {
+	return &file_coven_proto_enumTypes[1]
+}
+
+func (x PlanStepStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PlanStepStatus.Descriptor instead.
+func (PlanStepStatus) EnumDescriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{1}
+}
+
 // Priority for context injection
 type InjectionPriority int32
 
@@ -130,11 +189,11 @@ func (x InjectionPriority) String() string {
 }
 
 func (InjectionPriority) Descriptor() protoreflect.EnumDescriptor {
-	return file_coven_proto_enumTypes[1].Descriptor()
+	return file_coven_proto_enumTypes[2].Descriptor()
 }
 
 func (InjectionPriority) Type() protoreflect.EnumType {
-	return &file_coven_proto_enumTypes[1]
+	return &file_coven_proto_enumTypes[2]
 }
 
 func (x InjectionPriority) Number() protoreflect.EnumNumber {
@@ -143,7 +202,60 @@ func (x InjectionPriority) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use InjectionPriority.Descriptor instead.
 func (InjectionPriority) EnumDescriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{1}
+	return file_coven_proto_rawDescGZIP(), []int{2}
+}
+
+// Approval state of the agent's principal
+type RegistrationState int32
+
+const (
+	RegistrationState_REGISTRATION_STATE_UNSPECIFIED RegistrationState = 0
+	RegistrationState_REGISTRATION_STATE_PENDING     RegistrationState = 1 // Waiting for admin approval; stream is restricted
+	RegistrationState_REGISTRATION_STATE_APPROVED    RegistrationState = 2 // Approved; Welcome follows on the same stream
+	RegistrationState_REGISTRATION_STATE_REVOKED     RegistrationState = 3 // Revoked; the stream will be closed
+)
+
+// Enum value maps for RegistrationState.
+var (
+	RegistrationState_name = map[int32]string{
+		0: "REGISTRATION_STATE_UNSPECIFIED",
+		1: "REGISTRATION_STATE_PENDING",
+		2: "REGISTRATION_STATE_APPROVED",
+		3: "REGISTRATION_STATE_REVOKED",
+	}
+	RegistrationState_value = map[string]int32{
+		"REGISTRATION_STATE_UNSPECIFIED": 0,
+		"REGISTRATION_STATE_PENDING":     1,
+		"REGISTRATION_STATE_APPROVED":    2,
+		"REGISTRATION_STATE_REVOKED":     3,
+	}
+)
+
+func (x RegistrationState) Enum() *RegistrationState {
+	p := new(RegistrationState)
+	*p = x
+	return p
+}
+
+func (x RegistrationState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RegistrationState) Descriptor() protoreflect.EnumDescriptor {
+	return file_coven_proto_enumTypes[3].Descriptor()
+}
+
+func (RegistrationState) Type() protoreflect.EnumType {
+	return &file_coven_proto_enumTypes[3]
+}
+
+func (x RegistrationState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RegistrationState.Descriptor instead.
+func (RegistrationState) EnumDescriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{3}
 }
 
 // Messages from agent to server
@@ -369,8 +481,10 @@ type AgentMetadata struct {
 	Git              *GitInfo               `protobuf:"bytes,2,opt,name=git,proto3" json:"git,omitempty"`
 	Hostname         string                 `protobuf:"bytes,3,opt,name=hostname,proto3" json:"hostname,omitempty"`
 	Os               string                 `protobuf:"bytes,4,opt,name=os,proto3" json:"os,omitempty"`
-	Workspaces       []string               `protobuf:"bytes,5,rep,name=workspaces,proto3" json:"workspaces,omitempty"` // Workspace tags for filtering
-	Backend          string                 `protobuf:"bytes,6,opt,name=backend,proto3" json:"backend,omitempty"`       // Backend type: "mux", "cli", "acp", "direct"
+	Workspaces       []string               `protobuf:"bytes,5,rep,name=workspaces,proto3" json:"workspaces,omitempty"`                                                               // Workspace tags for filtering
+	Backend          string                 `protobuf:"bytes,6,opt,name=backend,proto3" json:"backend,omitempty"`                                                                     // Backend type: "mux", "cli", "acp", "direct"
+	MaxConcurrent    int32                  `protobuf:"varint,7,opt,name=max_concurrent,json=maxConcurrent,proto3" json:"max_concurrent,omitempty"`                                   // Most requests the agent wants at once; 0 = no limit
+	Tags             map[string]string      `protobuf:"bytes,8,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Structured labels (env=prod, team=infra) for filtering and routing
 	unknownFields    protoimpl.UnknownFields
 	sizeCache        protoimpl.SizeCache
 }
@@ -447,6 +561,20 @@ func (x *AgentMetadata) GetBackend() string {
 	return ""
 }
 
+func (x *AgentMetadata) GetMaxConcurrent() int32 {
+	if x != nil {
+		return x.MaxConcurrent
+	}
+	return 0
+}
+
+func (x *AgentMetadata) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
 // Agent registration
 type RegisterAgent struct {
 	state            protoimpl.MessageState `protogen:"open.v1"`
@@ -454,7 +582,8 @@ type RegisterAgent struct {
 	Name             string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                                                 // Human-readable name
 	Capabilities     []string               `protobuf:"bytes,3,rep,name=capabilities,proto3" json:"capabilities,omitempty"`                                 // What this agent can do
 	Metadata         *AgentMetadata         `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`                                         // Environment context
-	ProtocolFeatures []string               `protobuf:"bytes,5,rep,name=protocol_features,json=protocolFeatures,proto3" json:"protocol_features,omitempty"` // Supported features: "token_usage", "tool_states", "injection", "cancellation"
+	ProtocolFeatures []string               `protobuf:"bytes,5,rep,name=protocol_features,json=protocolFeatures,proto3" json:"protocol_features,omitempty"` // Supported features: "token_usage", "tool_states", "injection", "cancellation", "resume"
+	SessionToken     string                 `protobuf:"bytes,6,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`             // Token from a previous Welcome, to resume that session
 	unknownFields    protoimpl.UnknownFields
 	sizeCache        protoimpl.SizeCache
 }
@@ -524,6 +653,13 @@ func (x *RegisterAgent) GetProtocolFeatures() []string {
 	return nil
 }
 
+func (x *RegisterAgent) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
 // Response to a message request
 type MessageResponse struct {
 	state     protoimpl.MessageState `protogen:"open.v1"`
@@ -543,6 +679,9 @@ type MessageResponse struct {
 	//	*MessageResponse_Usage
 	//	*MessageResponse_ToolState
 	//	*MessageResponse_Cancelled
+	//	*MessageResponse_Plan
+	//	*MessageResponse_PlanStepUpdate
+	//	*MessageResponse_Citation
 	Event         isMessageResponse_Event `protobuf_oneof:"event"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -709,6 +848,33 @@ func (x *MessageResponse) GetCancelled() *Cancelled {
 	return nil
 }
 
+func (x *MessageResponse) GetPlan() *Plan {
+	if x != nil {
+		if x, ok := x.Event.(*MessageResponse_Plan); ok {
+			return x.Plan
+		}
+	}
+	return nil
+}
+
+func (x *MessageResponse) GetPlanStepUpdate() *PlanStepUpdate {
+	if x != nil {
+		if x, ok := x.Event.(*MessageResponse_PlanStepUpdate); ok {
+			return x.PlanStepUpdate
+		}
+	}
+	return nil
+}
+
+func (x *MessageResponse) GetCitation() *Citation {
+	if x != nil {
+		if x, ok := x.Event.(*MessageResponse_Citation); ok {
+			return x.Citation
+		}
+	}
+	return nil
+}
+
 type isMessageResponse_Event interface {
 	isMessageResponse_Event()
 }
@@ -765,6 +931,18 @@ type MessageResponse_Cancelled struct {
 	Cancelled *Cancelled `protobuf:"bytes,14,opt,name=cancelled,proto3,oneof"` // Request was cancelled
 }
 
+type MessageResponse_Plan struct {
+	Plan *Plan `protobuf:"bytes,15,opt,name=plan,proto3,oneof"` // Steps the agent intends to take
+}
+
+type MessageResponse_PlanStepUpdate struct {
+	PlanStepUpdate *PlanStepUpdate `protobuf:"bytes,16,opt,name=plan_step_update,json=planStepUpdate,proto3,oneof"` // Progress on a step of the latest plan
+}
+
+type MessageResponse_Citation struct {
+	Citation *Citation `protobuf:"bytes,17,opt,name=citation,proto3,oneof"` // Source backing part of the response text
+}
+
 func (*MessageResponse_Thinking) isMessageResponse_Event() {}
 
 func (*MessageResponse_Text) isMessageResponse_Event() {}
@@ -791,6 +969,12 @@ func (*MessageResponse_ToolState) isMessageResponse_Event() {}
 
 func (*MessageResponse_Cancelled) isMessageResponse_Event() {}
 
+func (*MessageResponse_Plan) isMessageResponse_Event() {}
+
+func (*MessageResponse_PlanStepUpdate) isMessageResponse_Event() {}
+
+func (*MessageResponse_Citation) isMessageResponse_Event() {}
+
 // Backend session initialized (session_id assigned/confirmed)
 type SessionInit struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1019,28 +1203,28 @@ func (x *ToolStateUpdate) GetDetail() string {
 	return ""
 }
 
-// Cancellation acknowledgment (agent → server)
-type Cancelled struct {
+type PlanStep struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"` // Echo back the reason
+	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`                              // Short description ("check logs")
+	Status        PlanStepStatus         `protobuf:"varint,2,opt,name=status,proto3,enum=coven.PlanStepStatus" json:"status,omitempty"` // Usually PENDING when the plan is first sent
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Cancelled) Reset() {
-	*x = Cancelled{}
+func (x *PlanStep) Reset() {
+	*x = PlanStep{}
 	mi := &file_coven_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Cancelled) String() string {
+func (x *PlanStep) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Cancelled) ProtoMessage() {}
+func (*PlanStep) ProtoMessage() {}
 
-func (x *Cancelled) ProtoReflect() protoreflect.Message {
+func (x *PlanStep) ProtoReflect() protoreflect.Message {
 	mi := &file_coven_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1052,43 +1236,48 @@ func (x *Cancelled) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Cancelled.ProtoReflect.Descriptor instead.
-func (*Cancelled) Descriptor() ([]byte, []int) {
+// Deprecated: Use PlanStep.ProtoReflect.Descriptor instead.
+func (*PlanStep) Descriptor() ([]byte, []int) {
 	return file_coven_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *Cancelled) GetReason() string {
+func (x *PlanStep) GetTitle() string {
 	if x != nil {
-		return x.Reason
+		return x.Title
 	}
 	return ""
 }
 
-// Context injection request (server → agent)
-type InjectContext struct {
+func (x *PlanStep) GetStatus() PlanStepStatus {
+	if x != nil {
+		return x.Status
+	}
+	return PlanStepStatus_PLAN_STEP_STATUS_UNSPECIFIED
+}
+
+// Plan the agent announces before executing. Sending a new plan in the same
+// request replaces the previous one.
+type Plan struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	InjectionId   string                 `protobuf:"bytes,1,opt,name=injection_id,json=injectionId,proto3" json:"injection_id,omitempty"`      // Unique ID for acknowledgment
-	Content       string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`                                 // Content to inject
-	Priority      InjectionPriority      `protobuf:"varint,3,opt,name=priority,proto3,enum=coven.InjectionPriority" json:"priority,omitempty"` // When to process
-	Source        *string                `protobuf:"bytes,4,opt,name=source,proto3,oneof" json:"source,omitempty"`                             // Origin (e.g., "pack:elevenlabs", "system")
+	Steps         []*PlanStep            `protobuf:"bytes,1,rep,name=steps,proto3" json:"steps,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *InjectContext) Reset() {
-	*x = InjectContext{}
+func (x *Plan) Reset() {
+	*x = Plan{}
 	mi := &file_coven_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *InjectContext) String() string {
+func (x *Plan) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*InjectContext) ProtoMessage() {}
+func (*Plan) ProtoMessage() {}
 
-func (x *InjectContext) ProtoReflect() protoreflect.Message {
+func (x *Plan) ProtoReflect() protoreflect.Message {
 	mi := &file_coven_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1100,63 +1289,42 @@ func (x *InjectContext) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use InjectContext.ProtoReflect.Descriptor instead.
-func (*InjectContext) Descriptor() ([]byte, []int) {
+// Deprecated: Use Plan.ProtoReflect.Descriptor instead.
+func (*Plan) Descriptor() ([]byte, []int) {
 	return file_coven_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *InjectContext) GetInjectionId() string {
-	if x != nil {
-		return x.InjectionId
-	}
-	return ""
-}
-
-func (x *InjectContext) GetContent() string {
+func (x *Plan) GetSteps() []*PlanStep {
 	if x != nil {
-		return x.Content
-	}
-	return ""
-}
-
-func (x *InjectContext) GetPriority() InjectionPriority {
-	if x != nil {
-		return x.Priority
+		return x.Steps
 	}
-	return InjectionPriority_INJECTION_PRIORITY_UNSPECIFIED
-}
-
-func (x *InjectContext) GetSource() string {
-	if x != nil && x.Source != nil {
-		return *x.Source
-	}
-	return ""
+	return nil
 }
 
-// Context injection acknowledgment (agent → server)
-type InjectionAck struct {
+// Status change for one step of the request's latest plan
+type PlanStepUpdate struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	InjectionId   string                 `protobuf:"bytes,1,opt,name=injection_id,json=injectionId,proto3" json:"injection_id,omitempty"` // Matches InjectContext.injection_id
-	Accepted      bool                   `protobuf:"varint,2,opt,name=accepted,proto3" json:"accepted,omitempty"`                         // Whether agent accepted the injection
-	Reason        *string                `protobuf:"bytes,3,opt,name=reason,proto3,oneof" json:"reason,omitempty"`                        // Why rejected (if not accepted)
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"` // Zero-based index into Plan.steps
+	Status        PlanStepStatus         `protobuf:"varint,2,opt,name=status,proto3,enum=coven.PlanStepStatus" json:"status,omitempty"`
+	Detail        *string                `protobuf:"bytes,3,opt,name=detail,proto3,oneof" json:"detail,omitempty"` // Optional note (failure reason, etc.)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *InjectionAck) Reset() {
-	*x = InjectionAck{}
+func (x *PlanStepUpdate) Reset() {
+	*x = PlanStepUpdate{}
 	mi := &file_coven_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *InjectionAck) String() string {
+func (x *PlanStepUpdate) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*InjectionAck) ProtoMessage() {}
+func (*PlanStepUpdate) ProtoMessage() {}
 
-func (x *InjectionAck) ProtoReflect() protoreflect.Message {
+func (x *PlanStepUpdate) ProtoReflect() protoreflect.Message {
 	mi := &file_coven_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1168,55 +1336,59 @@ func (x *InjectionAck) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use InjectionAck.ProtoReflect.Descriptor instead.
-func (*InjectionAck) Descriptor() ([]byte, []int) {
+// Deprecated: Use PlanStepUpdate.ProtoReflect.Descriptor instead.
+func (*PlanStepUpdate) Descriptor() ([]byte, []int) {
 	return file_coven_proto_rawDescGZIP(), []int{11}
 }
 
-func (x *InjectionAck) GetInjectionId() string {
+func (x *PlanStepUpdate) GetIndex() int32 {
 	if x != nil {
-		return x.InjectionId
+		return x.Index
 	}
-	return ""
+	return 0
 }
 
-func (x *InjectionAck) GetAccepted() bool {
+func (x *PlanStepUpdate) GetStatus() PlanStepStatus {
 	if x != nil {
-		return x.Accepted
+		return x.Status
 	}
-	return false
+	return PlanStepStatus_PLAN_STEP_STATUS_UNSPECIFIED
 }
 
-func (x *InjectionAck) GetReason() string {
-	if x != nil && x.Reason != nil {
-		return *x.Reason
+func (x *PlanStepUpdate) GetDetail() string {
+	if x != nil && x.Detail != nil {
+		return *x.Detail
 	}
 	return ""
 }
 
-// Request cancellation (server → agent)
-type CancelRequest struct {
+// Source the agent drew on. The response text refers to it with a "[index]"
+// marker. Citations may arrive before, between, or after the text chunks
+// they annotate; a later citation with the same index replaces the earlier one.
+type Citation struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Request to cancel
-	Reason        *string                `protobuf:"bytes,2,opt,name=reason,proto3,oneof" json:"reason,omitempty"`                  // Why cancelled (e.g., "user_requested")
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"` // Footnote number, starting at 1
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Uri           string                 `protobuf:"bytes,3,opt,name=uri,proto3" json:"uri,omitempty"`
+	Snippet       *string                `protobuf:"bytes,4,opt,name=snippet,proto3,oneof" json:"snippet,omitempty"` // Quoted passage supporting the claim
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CancelRequest) Reset() {
-	*x = CancelRequest{}
+func (x *Citation) Reset() {
+	*x = Citation{}
 	mi := &file_coven_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CancelRequest) String() string {
+func (x *Citation) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CancelRequest) ProtoMessage() {}
+func (*Citation) ProtoMessage() {}
 
-func (x *CancelRequest) ProtoReflect() protoreflect.Message {
+func (x *Citation) ProtoReflect() protoreflect.Message {
 	mi := &file_coven_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1228,49 +1400,61 @@ func (x *CancelRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CancelRequest.ProtoReflect.Descriptor instead.
-func (*CancelRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use Citation.ProtoReflect.Descriptor instead.
+func (*Citation) Descriptor() ([]byte, []int) {
 	return file_coven_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *CancelRequest) GetRequestId() string {
+func (x *Citation) GetIndex() int32 {
 	if x != nil {
-		return x.RequestId
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Citation) GetTitle() string {
+	if x != nil {
+		return x.Title
 	}
 	return ""
 }
 
-func (x *CancelRequest) GetReason() string {
-	if x != nil && x.Reason != nil {
-		return *x.Reason
+func (x *Citation) GetUri() string {
+	if x != nil {
+		return x.Uri
 	}
 	return ""
 }
 
-// Request for tool approval before execution (agent → server)
-type ToolApprovalRequest struct {
+func (x *Citation) GetSnippet() string {
+	if x != nil && x.Snippet != nil {
+		return *x.Snippet
+	}
+	return ""
+}
+
+// Cancellation acknowledgment (agent → server)
+type Cancelled struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`                                // Correlates with ToolUse.id
-	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                            // Tool name
-	InputJson     string                 `protobuf:"bytes,3,opt,name=input_json,json=inputJson,proto3" json:"input_json,omitempty"` // Tool input for display
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"` // Echo back the reason
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ToolApprovalRequest) Reset() {
-	*x = ToolApprovalRequest{}
+func (x *Cancelled) Reset() {
+	*x = Cancelled{}
 	mi := &file_coven_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ToolApprovalRequest) String() string {
+func (x *Cancelled) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ToolApprovalRequest) ProtoMessage() {}
+func (*Cancelled) ProtoMessage() {}
 
-func (x *ToolApprovalRequest) ProtoReflect() protoreflect.Message {
+func (x *Cancelled) ProtoReflect() protoreflect.Message {
 	mi := &file_coven_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1282,55 +1466,43 @@ func (x *ToolApprovalRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ToolApprovalRequest.ProtoReflect.Descriptor instead.
-func (*ToolApprovalRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use Cancelled.ProtoReflect.Descriptor instead.
+func (*Cancelled) Descriptor() ([]byte, []int) {
 	return file_coven_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *ToolApprovalRequest) GetId() string {
-	if x != nil {
-		return x.Id
-	}
-	return ""
-}
-
-func (x *ToolApprovalRequest) GetName() string {
-	if x != nil {
-		return x.Name
-	}
-	return ""
-}
-
-func (x *ToolApprovalRequest) GetInputJson() string {
+func (x *Cancelled) GetReason() string {
 	if x != nil {
-		return x.InputJson
+		return x.Reason
 	}
 	return ""
 }
 
-type ToolUse struct {
+// Context injection request (server → agent)
+type InjectContext struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	InputJson     string                 `protobuf:"bytes,3,opt,name=input_json,json=inputJson,proto3" json:"input_json,omitempty"`
+	InjectionId   string                 `protobuf:"bytes,1,opt,name=injection_id,json=injectionId,proto3" json:"injection_id,omitempty"`      // Unique ID for acknowledgment
+	Content       string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`                                 // Content to inject
+	Priority      InjectionPriority      `protobuf:"varint,3,opt,name=priority,proto3,enum=coven.InjectionPriority" json:"priority,omitempty"` // When to process
+	Source        *string                `protobuf:"bytes,4,opt,name=source,proto3,oneof" json:"source,omitempty"`                             // Origin (e.g., "pack:elevenlabs", "system")
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ToolUse) Reset() {
-	*x = ToolUse{}
+func (x *InjectContext) Reset() {
+	*x = InjectContext{}
 	mi := &file_coven_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ToolUse) String() string {
+func (x *InjectContext) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ToolUse) ProtoMessage() {}
+func (*InjectContext) ProtoMessage() {}
 
-func (x *ToolUse) ProtoReflect() protoreflect.Message {
+func (x *InjectContext) ProtoReflect() protoreflect.Message {
 	mi := &file_coven_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1342,55 +1514,63 @@ func (x *ToolUse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ToolUse.ProtoReflect.Descriptor instead.
-func (*ToolUse) Descriptor() ([]byte, []int) {
+// Deprecated: Use InjectContext.ProtoReflect.Descriptor instead.
+func (*InjectContext) Descriptor() ([]byte, []int) {
 	return file_coven_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *ToolUse) GetId() string {
+func (x *InjectContext) GetInjectionId() string {
 	if x != nil {
-		return x.Id
+		return x.InjectionId
 	}
 	return ""
 }
 
-func (x *ToolUse) GetName() string {
+func (x *InjectContext) GetContent() string {
 	if x != nil {
-		return x.Name
+		return x.Content
 	}
 	return ""
 }
 
-func (x *ToolUse) GetInputJson() string {
+func (x *InjectContext) GetPriority() InjectionPriority {
 	if x != nil {
-		return x.InputJson
+		return x.Priority
+	}
+	return InjectionPriority_INJECTION_PRIORITY_UNSPECIFIED
+}
+
+func (x *InjectContext) GetSource() string {
+	if x != nil && x.Source != nil {
+		return *x.Source
 	}
 	return ""
 }
 
-type ToolResult struct {
+// Context injection acknowledgment (agent → server)
+type InjectionAck struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Output        string                 `protobuf:"bytes,2,opt,name=output,proto3" json:"output,omitempty"`
-	IsError       bool                   `protobuf:"varint,3,opt,name=is_error,json=isError,proto3" json:"is_error,omitempty"`
+	InjectionId   string                 `protobuf:"bytes,1,opt,name=injection_id,json=injectionId,proto3" json:"injection_id,omitempty"` // Matches InjectContext.injection_id
+	Accepted      bool                   `protobuf:"varint,2,opt,name=accepted,proto3" json:"accepted,omitempty"`                         // Whether agent accepted the injection
+	Reason        *string                `protobuf:"bytes,3,opt,name=reason,proto3,oneof" json:"reason,omitempty"`                        // Why rejected (if not accepted)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ToolResult) Reset() {
-	*x = ToolResult{}
+func (x *InjectionAck) Reset() {
+	*x = InjectionAck{}
 	mi := &file_coven_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ToolResult) String() string {
+func (x *InjectionAck) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ToolResult) ProtoMessage() {}
+func (*InjectionAck) ProtoMessage() {}
 
-func (x *ToolResult) ProtoReflect() protoreflect.Message {
+func (x *InjectionAck) ProtoReflect() protoreflect.Message {
 	mi := &file_coven_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1402,53 +1582,55 @@ func (x *ToolResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ToolResult.ProtoReflect.Descriptor instead.
-func (*ToolResult) Descriptor() ([]byte, []int) {
+// Deprecated: Use InjectionAck.ProtoReflect.Descriptor instead.
+func (*InjectionAck) Descriptor() ([]byte, []int) {
 	return file_coven_proto_rawDescGZIP(), []int{15}
 }
 
-func (x *ToolResult) GetId() string {
+func (x *InjectionAck) GetInjectionId() string {
 	if x != nil {
-		return x.Id
+		return x.InjectionId
 	}
 	return ""
 }
 
-func (x *ToolResult) GetOutput() string {
+func (x *InjectionAck) GetAccepted() bool {
 	if x != nil {
-		return x.Output
+		return x.Accepted
 	}
-	return ""
+	return false
 }
 
-func (x *ToolResult) GetIsError() bool {
-	if x != nil {
-		return x.IsError
+func (x *InjectionAck) GetReason() string {
+	if x != nil && x.Reason != nil {
+		return *x.Reason
 	}
-	return false
+	return ""
 }
 
-type Done struct {
+// Request cancellation (server → agent)
+type CancelRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	FullResponse  string                 `protobuf:"bytes,1,opt,name=full_response,json=fullResponse,proto3" json:"full_response,omitempty"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Request to cancel
+	Reason        *string                `protobuf:"bytes,2,opt,name=reason,proto3,oneof" json:"reason,omitempty"`                  // Why cancelled (e.g., "user_requested")
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Done) Reset() {
-	*x = Done{}
+func (x *CancelRequest) Reset() {
+	*x = CancelRequest{}
 	mi := &file_coven_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Done) String() string {
+func (x *CancelRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Done) ProtoMessage() {}
+func (*CancelRequest) ProtoMessage() {}
 
-func (x *Done) ProtoReflect() protoreflect.Message {
+func (x *CancelRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_coven_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1460,41 +1642,49 @@ func (x *Done) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Done.ProtoReflect.Descriptor instead.
-func (*Done) Descriptor() ([]byte, []int) {
+// Deprecated: Use CancelRequest.ProtoReflect.Descriptor instead.
+func (*CancelRequest) Descriptor() ([]byte, []int) {
 	return file_coven_proto_rawDescGZIP(), []int{16}
 }
 
-func (x *Done) GetFullResponse() string {
+func (x *CancelRequest) GetRequestId() string {
 	if x != nil {
-		return x.FullResponse
+		return x.RequestId
 	}
 	return ""
 }
 
-type FileData struct {
+func (x *CancelRequest) GetReason() string {
+	if x != nil && x.Reason != nil {
+		return *x.Reason
+	}
+	return ""
+}
+
+// Request for tool approval before execution (agent → server)
+type ToolApprovalRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	MimeType      string                 `protobuf:"bytes,2,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`
-	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`                                // Correlates with ToolUse.id
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                            // Tool name
+	InputJson     string                 `protobuf:"bytes,3,opt,name=input_json,json=inputJson,proto3" json:"input_json,omitempty"` // Tool input for display
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FileData) Reset() {
-	*x = FileData{}
+func (x *ToolApprovalRequest) Reset() {
+	*x = ToolApprovalRequest{}
 	mi := &file_coven_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FileData) String() string {
+func (x *ToolApprovalRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FileData) ProtoMessage() {}
+func (*ToolApprovalRequest) ProtoMessage() {}
 
-func (x *FileData) ProtoReflect() protoreflect.Message {
+func (x *ToolApprovalRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_coven_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1506,53 +1696,55 @@ func (x *FileData) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FileData.ProtoReflect.Descriptor instead.
-func (*FileData) Descriptor() ([]byte, []int) {
+// Deprecated: Use ToolApprovalRequest.ProtoReflect.Descriptor instead.
+func (*ToolApprovalRequest) Descriptor() ([]byte, []int) {
 	return file_coven_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *FileData) GetFilename() string {
+func (x *ToolApprovalRequest) GetId() string {
 	if x != nil {
-		return x.Filename
+		return x.Id
 	}
 	return ""
 }
 
-func (x *FileData) GetMimeType() string {
+func (x *ToolApprovalRequest) GetName() string {
 	if x != nil {
-		return x.MimeType
+		return x.Name
 	}
 	return ""
 }
 
-func (x *FileData) GetData() []byte {
+func (x *ToolApprovalRequest) GetInputJson() string {
 	if x != nil {
-		return x.Data
+		return x.InputJson
 	}
-	return nil
+	return ""
 }
 
-type Heartbeat struct {
+type ToolUse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	TimestampMs   int64                  `protobuf:"varint,1,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	InputJson     string                 `protobuf:"bytes,3,opt,name=input_json,json=inputJson,proto3" json:"input_json,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Heartbeat) Reset() {
-	*x = Heartbeat{}
+func (x *ToolUse) Reset() {
+	*x = ToolUse{}
 	mi := &file_coven_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Heartbeat) String() string {
+func (x *ToolUse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Heartbeat) ProtoMessage() {}
+func (*ToolUse) ProtoMessage() {}
 
-func (x *Heartbeat) ProtoReflect() protoreflect.Message {
+func (x *ToolUse) ProtoReflect() protoreflect.Message {
 	mi := &file_coven_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1564,42 +1756,58 @@ func (x *Heartbeat) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Heartbeat.ProtoReflect.Descriptor instead.
-func (*Heartbeat) Descriptor() ([]byte, []int) {
+// Deprecated: Use ToolUse.ProtoReflect.Descriptor instead.
+func (*ToolUse) Descriptor() ([]byte, []int) {
 	return file_coven_proto_rawDescGZIP(), []int{18}
 }
 
-func (x *Heartbeat) GetTimestampMs() int64 {
+func (x *ToolUse) GetId() string {
 	if x != nil {
-		return x.TimestampMs
+		return x.Id
 	}
-	return 0
+	return ""
 }
 
-// Agent requests pack tool execution (agent → server)
-type ExecutePackTool struct {
+func (x *ToolUse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ToolUse) GetInputJson() string {
+	if x != nil {
+		return x.InputJson
+	}
+	return ""
+}
+
+type ToolResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Unique ID for correlation
-	ToolName      string                 `protobuf:"bytes,2,opt,name=tool_name,json=toolName,proto3" json:"tool_name,omitempty"`    // Name of the pack tool to execute
-	InputJson     string                 `protobuf:"bytes,3,opt,name=input_json,json=inputJson,proto3" json:"input_json,omitempty"` // Tool input as JSON
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Output        string                 `protobuf:"bytes,2,opt,name=output,proto3" json:"output,omitempty"`
+	IsError       bool                   `protobuf:"varint,3,opt,name=is_error,json=isError,proto3" json:"is_error,omitempty"`
+	Partial       bool                   `protobuf:"varint,4,opt,name=partial,proto3" json:"partial,omitempty"`   // More output for this tool call follows
+	Sequence      uint64                 `protobuf:"varint,5,opt,name=sequence,proto3" json:"sequence,omitempty"` // Position of a partial result, starting at 1
+	Cached        bool                   `protobuf:"varint,6,opt,name=cached,proto3" json:"cached,omitempty"`     // Output served from the gateway's tool result cache
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ExecutePackTool) Reset() {
-	*x = ExecutePackTool{}
+func (x *ToolResult) Reset() {
+	*x = ToolResult{}
 	mi := &file_coven_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ExecutePackTool) String() string {
+func (x *ToolResult) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ExecutePackTool) ProtoMessage() {}
+func (*ToolResult) ProtoMessage() {}
 
-func (x *ExecutePackTool) ProtoReflect() protoreflect.Message {
+func (x *ToolResult) ProtoReflect() protoreflect.Message {
 	mi := &file_coven_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1611,59 +1819,74 @@ func (x *ExecutePackTool) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ExecutePackTool.ProtoReflect.Descriptor instead.
-func (*ExecutePackTool) Descriptor() ([]byte, []int) {
+// Deprecated: Use ToolResult.ProtoReflect.Descriptor instead.
+func (*ToolResult) Descriptor() ([]byte, []int) {
 	return file_coven_proto_rawDescGZIP(), []int{19}
 }
 
-func (x *ExecutePackTool) GetRequestId() string {
+func (x *ToolResult) GetId() string {
 	if x != nil {
-		return x.RequestId
+		return x.Id
 	}
 	return ""
 }
 
-func (x *ExecutePackTool) GetToolName() string {
+func (x *ToolResult) GetOutput() string {
 	if x != nil {
-		return x.ToolName
+		return x.Output
 	}
 	return ""
 }
 
-func (x *ExecutePackTool) GetInputJson() string {
+func (x *ToolResult) GetIsError() bool {
 	if x != nil {
-		return x.InputJson
+		return x.IsError
 	}
-	return ""
+	return false
 }
 
-// Server returns pack tool execution result (server → agent)
-type PackToolResult struct {
-	state     protoimpl.MessageState `protogen:"open.v1"`
-	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Correlates with ExecutePackTool.request_id
-	// Types that are valid to be assigned to Result:
-	//
-	//	*PackToolResult_OutputJson
-	//	*PackToolResult_Error
-	Result        isPackToolResult_Result `protobuf_oneof:"result"`
+func (x *ToolResult) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+func (x *ToolResult) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *ToolResult) GetCached() bool {
+	if x != nil {
+		return x.Cached
+	}
+	return false
+}
+
+type Done struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FullResponse  string                 `protobuf:"bytes,1,opt,name=full_response,json=fullResponse,proto3" json:"full_response,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PackToolResult) Reset() {
-	*x = PackToolResult{}
+func (x *Done) Reset() {
+	*x = Done{}
 	mi := &file_coven_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PackToolResult) String() string {
+func (x *Done) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PackToolResult) ProtoMessage() {}
+func (*Done) ProtoMessage() {}
 
-func (x *PackToolResult) ProtoReflect() protoreflect.Message {
+func (x *Done) ProtoReflect() protoreflect.Message {
 	mi := &file_coven_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1675,12 +1898,254 @@ func (x *PackToolResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PackToolResult.ProtoReflect.Descriptor instead.
-func (*PackToolResult) Descriptor() ([]byte, []int) {
+// Deprecated: Use Done.ProtoReflect.Descriptor instead.
+func (*Done) Descriptor() ([]byte, []int) {
 	return file_coven_proto_rawDescGZIP(), []int{20}
 }
 
-func (x *PackToolResult) GetRequestId() string {
+func (x *Done) GetFullResponse() string {
+	if x != nil {
+		return x.FullResponse
+	}
+	return ""
+}
+
+type FileData struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	MimeType      string                 `protobuf:"bytes,2,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileData) Reset() {
+	*x = FileData{}
+	mi := &file_coven_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileData) ProtoMessage() {}
+
+func (x *FileData) ProtoReflect() protoreflect.Message {
+	mi := &file_coven_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileData.ProtoReflect.Descriptor instead.
+func (*FileData) Descriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *FileData) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *FileData) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+func (x *FileData) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type Heartbeat struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	TimestampMs    int64                  `protobuf:"varint,1,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"`
+	AckTimestampMs int64                  `protobuf:"varint,2,opt,name=ack_timestamp_ms,json=ackTimestampMs,proto3" json:"ack_timestamp_ms,omitempty"` // timestamp_ms of the last HeartbeatAck received (0 if none)
+	AckReceivedMs  int64                  `protobuf:"varint,3,opt,name=ack_received_ms,json=ackReceivedMs,proto3" json:"ack_received_ms,omitempty"`    // Agent clock when that ack arrived
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Heartbeat) Reset() {
+	*x = Heartbeat{}
+	mi := &file_coven_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Heartbeat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Heartbeat) ProtoMessage() {}
+
+func (x *Heartbeat) ProtoReflect() protoreflect.Message {
+	mi := &file_coven_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Heartbeat.ProtoReflect.Descriptor instead.
+func (*Heartbeat) Descriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *Heartbeat) GetTimestampMs() int64 {
+	if x != nil {
+		return x.TimestampMs
+	}
+	return 0
+}
+
+func (x *Heartbeat) GetAckTimestampMs() int64 {
+	if x != nil {
+		return x.AckTimestampMs
+	}
+	return 0
+}
+
+func (x *Heartbeat) GetAckReceivedMs() int64 {
+	if x != nil {
+		return x.AckReceivedMs
+	}
+	return 0
+}
+
+// Agent requests pack tool execution (agent → server)
+type ExecutePackTool struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Unique ID for correlation
+	ToolName  string                 `protobuf:"bytes,2,opt,name=tool_name,json=toolName,proto3" json:"tool_name,omitempty"`    // Name of the pack tool to execute
+	InputJson string                 `protobuf:"bytes,3,opt,name=input_json,json=inputJson,proto3" json:"input_json,omitempty"` // Tool input as JSON
+	// W3C trace context of the request this call serves, as received in
+	// SendMessage.trace_context, so the gateway's tool span joins that trace.
+	TraceContext  map[string]string `protobuf:"bytes,4,rep,name=trace_context,json=traceContext,proto3" json:"trace_context,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecutePackTool) Reset() {
+	*x = ExecutePackTool{}
+	mi := &file_coven_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecutePackTool) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecutePackTool) ProtoMessage() {}
+
+func (x *ExecutePackTool) ProtoReflect() protoreflect.Message {
+	mi := &file_coven_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecutePackTool.ProtoReflect.Descriptor instead.
+func (*ExecutePackTool) Descriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ExecutePackTool) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ExecutePackTool) GetToolName() string {
+	if x != nil {
+		return x.ToolName
+	}
+	return ""
+}
+
+func (x *ExecutePackTool) GetInputJson() string {
+	if x != nil {
+		return x.InputJson
+	}
+	return ""
+}
+
+func (x *ExecutePackTool) GetTraceContext() map[string]string {
+	if x != nil {
+		return x.TraceContext
+	}
+	return nil
+}
+
+// Server returns pack tool execution result (server → agent)
+type PackToolResult struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Correlates with ExecutePackTool.request_id
+	// Types that are valid to be assigned to Result:
+	//
+	//	*PackToolResult_OutputJson
+	//	*PackToolResult_Error
+	Result        isPackToolResult_Result `protobuf_oneof:"result"`
+	Cached        bool                    `protobuf:"varint,4,opt,name=cached,proto3" json:"cached,omitempty"` // Output served from the result cache; echo it on ToolResult.cached
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PackToolResult) Reset() {
+	*x = PackToolResult{}
+	mi := &file_coven_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PackToolResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PackToolResult) ProtoMessage() {}
+
+func (x *PackToolResult) ProtoReflect() protoreflect.Message {
+	mi := &file_coven_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PackToolResult.ProtoReflect.Descriptor instead.
+func (*PackToolResult) Descriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *PackToolResult) GetRequestId() string {
 	if x != nil {
 		return x.RequestId
 	}
@@ -1712,6 +2177,13 @@ func (x *PackToolResult) GetError() string {
 	return ""
 }
 
+func (x *PackToolResult) GetCached() bool {
+	if x != nil {
+		return x.Cached
+	}
+	return false
+}
+
 type isPackToolResult_Result interface {
 	isPackToolResult_Result()
 }
@@ -1741,6 +2213,12 @@ type ServerMessage struct {
 	//	*ServerMessage_InjectContext
 	//	*ServerMessage_CancelRequest
 	//	*ServerMessage_PackToolResult
+	//	*ServerMessage_RegistrationStatus
+	//	*ServerMessage_ToolsChanged
+	//	*ServerMessage_ResumeRequest
+	//	*ServerMessage_Goodbye
+	//	*ServerMessage_HeartbeatAck
+	//	*ServerMessage_CapabilityUpdate
 	Payload       isServerMessage_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -1748,7 +2226,7 @@ type ServerMessage struct {
 
 func (x *ServerMessage) Reset() {
 	*x = ServerMessage{}
-	mi := &file_coven_proto_msgTypes[21]
+	mi := &file_coven_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1760,7 +2238,7 @@ func (x *ServerMessage) String() string {
 func (*ServerMessage) ProtoMessage() {}
 
 func (x *ServerMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[21]
+	mi := &file_coven_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1773,7 +2251,7 @@ func (x *ServerMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ServerMessage.ProtoReflect.Descriptor instead.
 func (*ServerMessage) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{21}
+	return file_coven_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *ServerMessage) GetPayload() isServerMessage_Payload {
@@ -1855,19 +2333,73 @@ func (x *ServerMessage) GetPackToolResult() *PackToolResult {
 	return nil
 }
 
-type isServerMessage_Payload interface {
-	isServerMessage_Payload()
+func (x *ServerMessage) GetRegistrationStatus() *RegistrationStatus {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_RegistrationStatus); ok {
+			return x.RegistrationStatus
+		}
+	}
+	return nil
 }
 
-type ServerMessage_Welcome struct {
-	Welcome *Welcome `protobuf:"bytes,1,opt,name=welcome,proto3,oneof"`
+func (x *ServerMessage) GetToolsChanged() *ToolsChanged {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_ToolsChanged); ok {
+			return x.ToolsChanged
+		}
+	}
+	return nil
 }
 
-type ServerMessage_SendMessage struct {
-	SendMessage *SendMessage `protobuf:"bytes,2,opt,name=send_message,json=sendMessage,proto3,oneof"`
+func (x *ServerMessage) GetResumeRequest() *ResumeRequest {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_ResumeRequest); ok {
+			return x.ResumeRequest
+		}
+	}
+	return nil
 }
 
-type ServerMessage_Shutdown struct {
+func (x *ServerMessage) GetGoodbye() *Goodbye {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_Goodbye); ok {
+			return x.Goodbye
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetHeartbeatAck() *HeartbeatAck {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_HeartbeatAck); ok {
+			return x.HeartbeatAck
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetCapabilityUpdate() *CapabilityUpdate {
+	if x != nil {
+		if x, ok := x.Payload.(*ServerMessage_CapabilityUpdate); ok {
+			return x.CapabilityUpdate
+		}
+	}
+	return nil
+}
+
+type isServerMessage_Payload interface {
+	isServerMessage_Payload()
+}
+
+type ServerMessage_Welcome struct {
+	Welcome *Welcome `protobuf:"bytes,1,opt,name=welcome,proto3,oneof"`
+}
+
+type ServerMessage_SendMessage struct {
+	SendMessage *SendMessage `protobuf:"bytes,2,opt,name=send_message,json=sendMessage,proto3,oneof"`
+}
+
+type ServerMessage_Shutdown struct {
 	Shutdown *Shutdown `protobuf:"bytes,3,opt,name=shutdown,proto3,oneof"`
 }
 
@@ -1891,6 +2423,30 @@ type ServerMessage_PackToolResult struct {
 	PackToolResult *PackToolResult `protobuf:"bytes,8,opt,name=pack_tool_result,json=packToolResult,proto3,oneof"` // Result of pack tool execution
 }
 
+type ServerMessage_RegistrationStatus struct {
+	RegistrationStatus *RegistrationStatus `protobuf:"bytes,9,opt,name=registration_status,json=registrationStatus,proto3,oneof"` // Approval state for a pending principal
+}
+
+type ServerMessage_ToolsChanged struct {
+	ToolsChanged *ToolsChanged `protobuf:"bytes,10,opt,name=tools_changed,json=toolsChanged,proto3,oneof"` // Pack tool catalog changed
+}
+
+type ServerMessage_ResumeRequest struct {
+	ResumeRequest *ResumeRequest `protobuf:"bytes,11,opt,name=resume_request,json=resumeRequest,proto3,oneof"` // Re-sent request still in flight when the session dropped
+}
+
+type ServerMessage_Goodbye struct {
+	Goodbye *Goodbye `protobuf:"bytes,12,opt,name=goodbye,proto3,oneof"` // Connection superseded by a newer one for the same agent ID
+}
+
+type ServerMessage_HeartbeatAck struct {
+	HeartbeatAck *HeartbeatAck `protobuf:"bytes,13,opt,name=heartbeat_ack,json=heartbeatAck,proto3,oneof"` // Answers a heartbeat, for latency and clock skew
+}
+
+type ServerMessage_CapabilityUpdate struct {
+	CapabilityUpdate *CapabilityUpdate `protobuf:"bytes,14,opt,name=capability_update,json=capabilityUpdate,proto3,oneof"` // Principal capability grants changed
+}
+
 func (*ServerMessage_Welcome) isServerMessage_Payload() {}
 
 func (*ServerMessage_SendMessage) isServerMessage_Payload() {}
@@ -1907,6 +2463,18 @@ func (*ServerMessage_CancelRequest) isServerMessage_Payload() {}
 
 func (*ServerMessage_PackToolResult) isServerMessage_Payload() {}
 
+func (*ServerMessage_RegistrationStatus) isServerMessage_Payload() {}
+
+func (*ServerMessage_ToolsChanged) isServerMessage_Payload() {}
+
+func (*ServerMessage_ResumeRequest) isServerMessage_Payload() {}
+
+func (*ServerMessage_Goodbye) isServerMessage_Payload() {}
+
+func (*ServerMessage_HeartbeatAck) isServerMessage_Payload() {}
+
+func (*ServerMessage_CapabilityUpdate) isServerMessage_Payload() {}
+
 // Server rejects registration (e.g., agent_id already taken)
 type RegistrationError struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1918,7 +2486,7 @@ type RegistrationError struct {
 
 func (x *RegistrationError) Reset() {
 	*x = RegistrationError{}
-	mi := &file_coven_proto_msgTypes[22]
+	mi := &file_coven_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1930,7 +2498,7 @@ func (x *RegistrationError) String() string {
 func (*RegistrationError) ProtoMessage() {}
 
 func (x *RegistrationError) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[22]
+	mi := &file_coven_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1943,7 +2511,7 @@ func (x *RegistrationError) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegistrationError.ProtoReflect.Descriptor instead.
 func (*RegistrationError) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{22}
+	return file_coven_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *RegistrationError) GetReason() string {
@@ -1960,6 +2528,76 @@ func (x *RegistrationError) GetSuggestedId() string {
 	return ""
 }
 
+// Registration status push (server → agent). Sent while the principal is
+// pending and again when an admin approves or revokes it.
+type RegistrationStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	State         RegistrationState      `protobuf:"varint,1,opt,name=state,proto3,enum=coven.RegistrationState" json:"state,omitempty"`
+	PrincipalId   string                 `protobuf:"bytes,2,opt,name=principal_id,json=principalId,proto3" json:"principal_id,omitempty"` // Principal awaiting approval
+	Hint          string                 `protobuf:"bytes,3,opt,name=hint,proto3" json:"hint,omitempty"`                                  // Human-readable explanation
+	AdminUrl      string                 `protobuf:"bytes,4,opt,name=admin_url,json=adminUrl,proto3" json:"admin_url,omitempty"`          // Where an admin can approve the principal
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegistrationStatus) Reset() {
+	*x = RegistrationStatus{}
+	mi := &file_coven_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegistrationStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegistrationStatus) ProtoMessage() {}
+
+func (x *RegistrationStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_coven_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegistrationStatus.ProtoReflect.Descriptor instead.
+func (*RegistrationStatus) Descriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *RegistrationStatus) GetState() RegistrationState {
+	if x != nil {
+		return x.State
+	}
+	return RegistrationState_REGISTRATION_STATE_UNSPECIFIED
+}
+
+func (x *RegistrationStatus) GetPrincipalId() string {
+	if x != nil {
+		return x.PrincipalId
+	}
+	return ""
+}
+
+func (x *RegistrationStatus) GetHint() string {
+	if x != nil {
+		return x.Hint
+	}
+	return ""
+}
+
+func (x *RegistrationStatus) GetAdminUrl() string {
+	if x != nil {
+		return x.AdminUrl
+	}
+	return ""
+}
+
 // Response to tool approval request (server → agent)
 type ToolApprovalResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1972,7 +2610,7 @@ type ToolApprovalResponse struct {
 
 func (x *ToolApprovalResponse) Reset() {
 	*x = ToolApprovalResponse{}
-	mi := &file_coven_proto_msgTypes[23]
+	mi := &file_coven_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1984,7 +2622,7 @@ func (x *ToolApprovalResponse) String() string {
 func (*ToolApprovalResponse) ProtoMessage() {}
 
 func (x *ToolApprovalResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[23]
+	mi := &file_coven_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1997,7 +2635,7 @@ func (x *ToolApprovalResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ToolApprovalResponse.ProtoReflect.Descriptor instead.
 func (*ToolApprovalResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{23}
+	return file_coven_proto_rawDescGZIP(), []int{28}
 }
 
 func (x *ToolApprovalResponse) GetId() string {
@@ -2023,22 +2661,26 @@ func (x *ToolApprovalResponse) GetApproveAll() bool {
 
 // Server acknowledges registration
 type Welcome struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	ServerId       string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
-	AgentId        string                 `protobuf:"bytes,2,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`                                                            // Confirmed agent ID (instance name)
-	InstanceId     string                 `protobuf:"bytes,3,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`                                                   // Short code for binding commands
-	PrincipalId    string                 `protobuf:"bytes,4,opt,name=principal_id,json=principalId,proto3" json:"principal_id,omitempty"`                                                // Principal UUID for reference
-	AvailableTools []*ToolDefinition      `protobuf:"bytes,5,rep,name=available_tools,json=availableTools,proto3" json:"available_tools,omitempty"`                                       // Pack tools available to this agent
-	McpToken       string                 `protobuf:"bytes,6,opt,name=mcp_token,json=mcpToken,proto3" json:"mcp_token,omitempty"`                                                         // Token for MCP endpoint authentication (capability-scoped)
-	McpEndpoint    string                 `protobuf:"bytes,7,opt,name=mcp_endpoint,json=mcpEndpoint,proto3" json:"mcp_endpoint,omitempty"`                                                // Base MCP endpoint URL (e.g., "http://gateway:8080/mcp")
-	Secrets        map[string]string      `protobuf:"bytes,8,rep,name=secrets,proto3" json:"secrets,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Resolved env vars for this agent (global + overrides)
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ServerId        string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	AgentId         string                 `protobuf:"bytes,2,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`                                                            // Confirmed agent ID (instance name)
+	InstanceId      string                 `protobuf:"bytes,3,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`                                                   // Short code for binding commands
+	PrincipalId     string                 `protobuf:"bytes,4,opt,name=principal_id,json=principalId,proto3" json:"principal_id,omitempty"`                                                // Principal UUID for reference
+	AvailableTools  []*ToolDefinition      `protobuf:"bytes,5,rep,name=available_tools,json=availableTools,proto3" json:"available_tools,omitempty"`                                       // Pack tools available to this agent
+	McpToken        string                 `protobuf:"bytes,6,opt,name=mcp_token,json=mcpToken,proto3" json:"mcp_token,omitempty"`                                                         // Token for MCP endpoint authentication (capability-scoped)
+	McpEndpoint     string                 `protobuf:"bytes,7,opt,name=mcp_endpoint,json=mcpEndpoint,proto3" json:"mcp_endpoint,omitempty"`                                                // Base MCP endpoint URL (e.g., "http://gateway:8080/mcp")
+	Secrets         map[string]string      `protobuf:"bytes,8,rep,name=secrets,proto3" json:"secrets,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Resolved env vars for this agent (global + overrides)
+	CatalogVersion  int64                  `protobuf:"varint,9,opt,name=catalog_version,json=catalogVersion,proto3" json:"catalog_version,omitempty"`                                      // Tool catalog version the available_tools reflect
+	SessionToken    string                 `protobuf:"bytes,10,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`                                            // Present on the next registration to resume this session
+	Resumed         bool                   `protobuf:"varint,11,opt,name=resumed,proto3" json:"resumed,omitempty"`                                                                         // True when the presented session token was honored
+	AckedRequestIds []string               `protobuf:"bytes,12,rep,name=acked_request_ids,json=ackedRequestIds,proto3" json:"acked_request_ids,omitempty"`                                 // Recent requests the gateway saw finish (resumed sessions only)
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *Welcome) Reset() {
 	*x = Welcome{}
-	mi := &file_coven_proto_msgTypes[24]
+	mi := &file_coven_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2050,7 +2692,7 @@ func (x *Welcome) String() string {
 func (*Welcome) ProtoMessage() {}
 
 func (x *Welcome) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[24]
+	mi := &file_coven_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2063,7 +2705,7 @@ func (x *Welcome) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Welcome.ProtoReflect.Descriptor instead.
 func (*Welcome) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{24}
+	return file_coven_proto_rawDescGZIP(), []int{29}
 }
 
 func (x *Welcome) GetServerId() string {
@@ -2122,21 +2764,57 @@ func (x *Welcome) GetSecrets() map[string]string {
 	return nil
 }
 
+func (x *Welcome) GetCatalogVersion() int64 {
+	if x != nil {
+		return x.CatalogVersion
+	}
+	return 0
+}
+
+func (x *Welcome) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
+func (x *Welcome) GetResumed() bool {
+	if x != nil {
+		return x.Resumed
+	}
+	return false
+}
+
+func (x *Welcome) GetAckedRequestIds() []string {
+	if x != nil {
+		return x.AckedRequestIds
+	}
+	return nil
+}
+
 // Server tells agent to process a message
 type SendMessage struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Unique request ID for correlation
-	ThreadId      string                 `protobuf:"bytes,2,opt,name=thread_id,json=threadId,proto3" json:"thread_id,omitempty"`    // Conversation thread
-	Sender        string                 `protobuf:"bytes,3,opt,name=sender,proto3" json:"sender,omitempty"`                        // Who sent the message
-	Content       string                 `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`                      // Message content
-	Attachments   []*FileAttachment      `protobuf:"bytes,5,rep,name=attachments,proto3" json:"attachments,omitempty"`
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	RequestId   string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Unique request ID for correlation
+	ThreadId    string                 `protobuf:"bytes,2,opt,name=thread_id,json=threadId,proto3" json:"thread_id,omitempty"`    // Conversation thread
+	Sender      string                 `protobuf:"bytes,3,opt,name=sender,proto3" json:"sender,omitempty"`                        // Who sent the message
+	Content     string                 `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`                      // Message content
+	Attachments []*FileAttachment      `protobuf:"bytes,5,rep,name=attachments,proto3" json:"attachments,omitempty"`
+	Workspace   string                 `protobuf:"bytes,6,opt,name=workspace,proto3" json:"workspace,omitempty"` // Agent workspace to handle the message in; empty for its default
+	// Guidance configured on the channel's binding (e.g. "answer tersely").
+	// Empty when the binding has none; agents decide how to apply it.
+	ChannelContext string `protobuf:"bytes,7,opt,name=channel_context,json=channelContext,proto3" json:"channel_context,omitempty"`
+	// W3C trace context (traceparent, tracestate) of the gateway span for
+	// this request; empty when tracing is off. Agents may continue the trace.
+	// x-request-id, when set, is the ID the gateway logs the request under.
+	TraceContext  map[string]string `protobuf:"bytes,8,rep,name=trace_context,json=traceContext,proto3" json:"trace_context,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SendMessage) Reset() {
 	*x = SendMessage{}
-	mi := &file_coven_proto_msgTypes[25]
+	mi := &file_coven_proto_msgTypes[30]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2148,7 +2826,7 @@ func (x *SendMessage) String() string {
 func (*SendMessage) ProtoMessage() {}
 
 func (x *SendMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[25]
+	mi := &file_coven_proto_msgTypes[30]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2161,7 +2839,7 @@ func (x *SendMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SendMessage.ProtoReflect.Descriptor instead.
 func (*SendMessage) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{25}
+	return file_coven_proto_rawDescGZIP(), []int{30}
 }
 
 func (x *SendMessage) GetRequestId() string {
@@ -2199,18 +2877,129 @@ func (x *SendMessage) GetAttachments() []*FileAttachment {
 	return nil
 }
 
+func (x *SendMessage) GetWorkspace() string {
+	if x != nil {
+		return x.Workspace
+	}
+	return ""
+}
+
+func (x *SendMessage) GetChannelContext() string {
+	if x != nil {
+		return x.ChannelContext
+	}
+	return ""
+}
+
+func (x *SendMessage) GetTraceContext() map[string]string {
+	if x != nil {
+		return x.TraceContext
+	}
+	return nil
+}
+
+// Server re-sends a request that was in flight when the agent's previous
+// connection (or the gateway) went away. Agents that still hold the request
+// keep streaming responses under the same request_id; others start it over.
+type ResumeRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	RequestId      string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	ThreadId       string                 `protobuf:"bytes,2,opt,name=thread_id,json=threadId,proto3" json:"thread_id,omitempty"`
+	Sender         string                 `protobuf:"bytes,3,opt,name=sender,proto3" json:"sender,omitempty"`
+	Content        string                 `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	Workspace      string                 `protobuf:"bytes,5,opt,name=workspace,proto3" json:"workspace,omitempty"`
+	ChannelContext string                 `protobuf:"bytes,6,opt,name=channel_context,json=channelContext,proto3" json:"channel_context,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ResumeRequest) Reset() {
+	*x = ResumeRequest{}
+	mi := &file_coven_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeRequest) ProtoMessage() {}
+
+func (x *ResumeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_coven_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeRequest.ProtoReflect.Descriptor instead.
+func (*ResumeRequest) Descriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ResumeRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ResumeRequest) GetThreadId() string {
+	if x != nil {
+		return x.ThreadId
+	}
+	return ""
+}
+
+func (x *ResumeRequest) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *ResumeRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *ResumeRequest) GetWorkspace() string {
+	if x != nil {
+		return x.Workspace
+	}
+	return ""
+}
+
+func (x *ResumeRequest) GetChannelContext() string {
+	if x != nil {
+		return x.ChannelContext
+	}
+	return ""
+}
+
 type FileAttachment struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	MimeType      string                 `protobuf:"bytes,2,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`
-	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Filename string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	MimeType string                 `protobuf:"bytes,2,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`
+	Data     []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	// Gateway-assigned ID of the stored copy, downloadable from
+	// GET /api/attachments/{id}; empty when the gateway did not store it.
+	AttachmentId  string `protobuf:"bytes,4,opt,name=attachment_id,json=attachmentId,proto3" json:"attachment_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *FileAttachment) Reset() {
 	*x = FileAttachment{}
-	mi := &file_coven_proto_msgTypes[26]
+	mi := &file_coven_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2222,7 +3011,7 @@ func (x *FileAttachment) String() string {
 func (*FileAttachment) ProtoMessage() {}
 
 func (x *FileAttachment) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[26]
+	mi := &file_coven_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2235,7 +3024,7 @@ func (x *FileAttachment) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FileAttachment.ProtoReflect.Descriptor instead.
 func (*FileAttachment) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{26}
+	return file_coven_proto_rawDescGZIP(), []int{32}
 }
 
 func (x *FileAttachment) GetFilename() string {
@@ -2259,6 +3048,125 @@ func (x *FileAttachment) GetData() []byte {
 	return nil
 }
 
+func (x *FileAttachment) GetAttachmentId() string {
+	if x != nil {
+		return x.AttachmentId
+	}
+	return ""
+}
+
+// Pack tools available to the agent changed (server → agent). Sent when a pack
+// connects or disconnects. catalog_version only increases when a tool definition
+// is added, removed, or modified, so agents can keep cached schemas while it is
+// unchanged.
+type ToolsChanged struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	CatalogVersion int64                  `protobuf:"varint,1,opt,name=catalog_version,json=catalogVersion,proto3" json:"catalog_version,omitempty"`
+	AvailableTools []*ToolDefinition      `protobuf:"bytes,2,rep,name=available_tools,json=availableTools,proto3" json:"available_tools,omitempty"` // Full replacement list
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ToolsChanged) Reset() {
+	*x = ToolsChanged{}
+	mi := &file_coven_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolsChanged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolsChanged) ProtoMessage() {}
+
+func (x *ToolsChanged) ProtoReflect() protoreflect.Message {
+	mi := &file_coven_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolsChanged.ProtoReflect.Descriptor instead.
+func (*ToolsChanged) Descriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ToolsChanged) GetCatalogVersion() int64 {
+	if x != nil {
+		return x.CatalogVersion
+	}
+	return 0
+}
+
+func (x *ToolsChanged) GetAvailableTools() []*ToolDefinition {
+	if x != nil {
+		return x.AvailableTools
+	}
+	return nil
+}
+
+// The agent's capabilities changed (server → agent). Sent when an admin changes
+// the grants of the agent's principal, to agents that advertise the
+// "capability_update" protocol feature. available_tools is the pack tool list
+// for the new set; both fields are full replacements.
+type CapabilityUpdate struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Capabilities   []string               `protobuf:"bytes,1,rep,name=capabilities,proto3" json:"capabilities,omitempty"`                           // Full replacement set
+	AvailableTools []*ToolDefinition      `protobuf:"bytes,2,rep,name=available_tools,json=availableTools,proto3" json:"available_tools,omitempty"` // Pack tools for the new set
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CapabilityUpdate) Reset() {
+	*x = CapabilityUpdate{}
+	mi := &file_coven_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapabilityUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapabilityUpdate) ProtoMessage() {}
+
+func (x *CapabilityUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_coven_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapabilityUpdate.ProtoReflect.Descriptor instead.
+func (*CapabilityUpdate) Descriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *CapabilityUpdate) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *CapabilityUpdate) GetAvailableTools() []*ToolDefinition {
+	if x != nil {
+		return x.AvailableTools
+	}
+	return nil
+}
+
 // Server tells agent to shut down
 type Shutdown struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -2269,19 +3177,112 @@ type Shutdown struct {
 
 func (x *Shutdown) Reset() {
 	*x = Shutdown{}
-	mi := &file_coven_proto_msgTypes[27]
+	mi := &file_coven_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Shutdown) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Shutdown) ProtoMessage() {}
+
+func (x *Shutdown) ProtoReflect() protoreflect.Message {
+	mi := &file_coven_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Shutdown.ProtoReflect.Descriptor instead.
+func (*Shutdown) Descriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *Shutdown) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// Server tells a connection it has been replaced by a newer one for the same
+// agent ID; the stream is closed after this message.
+type Goodbye struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Goodbye) Reset() {
+	*x = Goodbye{}
+	mi := &file_coven_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Goodbye) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Goodbye) ProtoMessage() {}
+
+func (x *Goodbye) ProtoReflect() protoreflect.Message {
+	mi := &file_coven_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Goodbye.ProtoReflect.Descriptor instead.
+func (*Goodbye) Descriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *Goodbye) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// Server answers a heartbeat so the agent can report the round trip in its
+// next one (sent to agents advertising the "heartbeat_ack" feature).
+type HeartbeatAck struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	TimestampMs       int64                  `protobuf:"varint,1,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"`                     // Echo of the heartbeat's timestamp_ms
+	ServerTimestampMs int64                  `protobuf:"varint,2,opt,name=server_timestamp_ms,json=serverTimestampMs,proto3" json:"server_timestamp_ms,omitempty"` // Gateway clock when the ack was sent
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *HeartbeatAck) Reset() {
+	*x = HeartbeatAck{}
+	mi := &file_coven_proto_msgTypes[37]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Shutdown) String() string {
+func (x *HeartbeatAck) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Shutdown) ProtoMessage() {}
+func (*HeartbeatAck) ProtoMessage() {}
 
-func (x *Shutdown) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[27]
+func (x *HeartbeatAck) ProtoReflect() protoreflect.Message {
+	mi := &file_coven_proto_msgTypes[37]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2292,34 +3293,47 @@ func (x *Shutdown) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Shutdown.ProtoReflect.Descriptor instead.
-func (*Shutdown) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{27}
+// Deprecated: Use HeartbeatAck.ProtoReflect.Descriptor instead.
+func (*HeartbeatAck) Descriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{37}
 }
 
-func (x *Shutdown) GetReason() string {
+func (x *HeartbeatAck) GetTimestampMs() int64 {
 	if x != nil {
-		return x.Reason
+		return x.TimestampMs
 	}
-	return ""
+	return 0
+}
+
+func (x *HeartbeatAck) GetServerTimestampMs() int64 {
+	if x != nil {
+		return x.ServerTimestampMs
+	}
+	return 0
 }
 
 // Binding represents a channel-to-agent mapping for message routing
 type Binding struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Frontend      string                 `protobuf:"bytes,2,opt,name=frontend,proto3" json:"frontend,omitempty"`
-	ChannelId     string                 `protobuf:"bytes,3,opt,name=channel_id,json=channelId,proto3" json:"channel_id,omitempty"`
-	AgentId       string                 `protobuf:"bytes,4,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
-	CreatedAt     string                 `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // ISO-8601
-	CreatedBy     *string                `protobuf:"bytes,6,opt,name=created_by,json=createdBy,proto3,oneof" json:"created_by,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Id                 string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Frontend           string                 `protobuf:"bytes,2,opt,name=frontend,proto3" json:"frontend,omitempty"`
+	ChannelId          string                 `protobuf:"bytes,3,opt,name=channel_id,json=channelId,proto3" json:"channel_id,omitempty"`
+	AgentId            string                 `protobuf:"bytes,4,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	CreatedAt          string                 `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // ISO-8601
+	CreatedBy          *string                `protobuf:"bytes,6,opt,name=created_by,json=createdBy,proto3,oneof" json:"created_by,omitempty"`
+	MaxResponseSeconds *int32                 `protobuf:"varint,7,opt,name=max_response_seconds,json=maxResponseSeconds,proto3,oneof" json:"max_response_seconds,omitempty"` // Response limit override (unset = gateway default)
+	AgentName          string                 `protobuf:"bytes,8,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`                                     // Principal display name; "(deleted agent)" if the principal is gone (ListBindings only)
+	AgentStatus        string                 `protobuf:"bytes,9,opt,name=agent_status,json=agentStatus,proto3" json:"agent_status,omitempty"`                               // "online", "grace" (reconnect grace period), or "offline" (ListBindings only)
+	FallbackAgentIds   []string               `protobuf:"bytes,10,rep,name=fallback_agent_ids,json=fallbackAgentIds,proto3" json:"fallback_agent_ids,omitempty"`             // Tried in order once the agent is offline past its grace period
+	EffectiveAgentId   string                 `protobuf:"bytes,11,opt,name=effective_agent_id,json=effectiveAgentId,proto3" json:"effective_agent_id,omitempty"`             // Agent messages currently route to; empty if none is reachable (ListBindings only)
+	Context            string                 `protobuf:"bytes,12,opt,name=context,proto3" json:"context,omitempty"`                                                         // Channel context sent to the agent with each message
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *Binding) Reset() {
 	*x = Binding{}
-	mi := &file_coven_proto_msgTypes[28]
+	mi := &file_coven_proto_msgTypes[38]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2331,7 +3345,7 @@ func (x *Binding) String() string {
 func (*Binding) ProtoMessage() {}
 
 func (x *Binding) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[28]
+	mi := &file_coven_proto_msgTypes[38]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2344,7 +3358,7 @@ func (x *Binding) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Binding.ProtoReflect.Descriptor instead.
 func (*Binding) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{28}
+	return file_coven_proto_rawDescGZIP(), []int{38}
 }
 
 func (x *Binding) GetId() string {
@@ -2389,6 +3403,48 @@ func (x *Binding) GetCreatedBy() string {
 	return ""
 }
 
+func (x *Binding) GetMaxResponseSeconds() int32 {
+	if x != nil && x.MaxResponseSeconds != nil {
+		return *x.MaxResponseSeconds
+	}
+	return 0
+}
+
+func (x *Binding) GetAgentName() string {
+	if x != nil {
+		return x.AgentName
+	}
+	return ""
+}
+
+func (x *Binding) GetAgentStatus() string {
+	if x != nil {
+		return x.AgentStatus
+	}
+	return ""
+}
+
+func (x *Binding) GetFallbackAgentIds() []string {
+	if x != nil {
+		return x.FallbackAgentIds
+	}
+	return nil
+}
+
+func (x *Binding) GetEffectiveAgentId() string {
+	if x != nil {
+		return x.EffectiveAgentId
+	}
+	return ""
+}
+
+func (x *Binding) GetContext() string {
+	if x != nil {
+		return x.Context
+	}
+	return ""
+}
+
 type ListBindingsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Frontend      *string                `protobuf:"bytes,1,opt,name=frontend,proto3,oneof" json:"frontend,omitempty"`
@@ -2399,7 +3455,7 @@ type ListBindingsRequest struct {
 
 func (x *ListBindingsRequest) Reset() {
 	*x = ListBindingsRequest{}
-	mi := &file_coven_proto_msgTypes[29]
+	mi := &file_coven_proto_msgTypes[39]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2411,7 +3467,7 @@ func (x *ListBindingsRequest) String() string {
 func (*ListBindingsRequest) ProtoMessage() {}
 
 func (x *ListBindingsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[29]
+	mi := &file_coven_proto_msgTypes[39]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2424,7 +3480,7 @@ func (x *ListBindingsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListBindingsRequest.ProtoReflect.Descriptor instead.
 func (*ListBindingsRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{29}
+	return file_coven_proto_rawDescGZIP(), []int{39}
 }
 
 func (x *ListBindingsRequest) GetFrontend() string {
@@ -2450,7 +3506,7 @@ type ListBindingsResponse struct {
 
 func (x *ListBindingsResponse) Reset() {
 	*x = ListBindingsResponse{}
-	mi := &file_coven_proto_msgTypes[30]
+	mi := &file_coven_proto_msgTypes[40]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2462,7 +3518,7 @@ func (x *ListBindingsResponse) String() string {
 func (*ListBindingsResponse) ProtoMessage() {}
 
 func (x *ListBindingsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[30]
+	mi := &file_coven_proto_msgTypes[40]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2475,7 +3531,7 @@ func (x *ListBindingsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListBindingsResponse.ProtoReflect.Descriptor instead.
 func (*ListBindingsResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{30}
+	return file_coven_proto_rawDescGZIP(), []int{40}
 }
 
 func (x *ListBindingsResponse) GetBindings() []*Binding {
@@ -2486,17 +3542,20 @@ func (x *ListBindingsResponse) GetBindings() []*Binding {
 }
 
 type CreateBindingRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Frontend      string                 `protobuf:"bytes,1,opt,name=frontend,proto3" json:"frontend,omitempty"`
-	ChannelId     string                 `protobuf:"bytes,2,opt,name=channel_id,json=channelId,proto3" json:"channel_id,omitempty"`
-	AgentId       string                 `protobuf:"bytes,3,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Frontend           string                 `protobuf:"bytes,1,opt,name=frontend,proto3" json:"frontend,omitempty"`
+	ChannelId          string                 `protobuf:"bytes,2,opt,name=channel_id,json=channelId,proto3" json:"channel_id,omitempty"`
+	AgentId            string                 `protobuf:"bytes,3,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	MaxResponseSeconds *int32                 `protobuf:"varint,4,opt,name=max_response_seconds,json=maxResponseSeconds,proto3,oneof" json:"max_response_seconds,omitempty"` // Response limit override (0 = gateway default)
+	FallbackAgentIds   []string               `protobuf:"bytes,5,rep,name=fallback_agent_ids,json=fallbackAgentIds,proto3" json:"fallback_agent_ids,omitempty"`              // Agents to route to, in order, while agent_id is offline
+	Context            string                 `protobuf:"bytes,6,opt,name=context,proto3" json:"context,omitempty"`                                                          // Channel context sent to the agent with each message
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *CreateBindingRequest) Reset() {
 	*x = CreateBindingRequest{}
-	mi := &file_coven_proto_msgTypes[31]
+	mi := &file_coven_proto_msgTypes[41]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2508,7 +3567,7 @@ func (x *CreateBindingRequest) String() string {
 func (*CreateBindingRequest) ProtoMessage() {}
 
 func (x *CreateBindingRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[31]
+	mi := &file_coven_proto_msgTypes[41]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2521,7 +3580,7 @@ func (x *CreateBindingRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateBindingRequest.ProtoReflect.Descriptor instead.
 func (*CreateBindingRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{31}
+	return file_coven_proto_rawDescGZIP(), []int{41}
 }
 
 func (x *CreateBindingRequest) GetFrontend() string {
@@ -2545,17 +3604,40 @@ func (x *CreateBindingRequest) GetAgentId() string {
 	return ""
 }
 
+func (x *CreateBindingRequest) GetMaxResponseSeconds() int32 {
+	if x != nil && x.MaxResponseSeconds != nil {
+		return *x.MaxResponseSeconds
+	}
+	return 0
+}
+
+func (x *CreateBindingRequest) GetFallbackAgentIds() []string {
+	if x != nil {
+		return x.FallbackAgentIds
+	}
+	return nil
+}
+
+func (x *CreateBindingRequest) GetContext() string {
+	if x != nil {
+		return x.Context
+	}
+	return ""
+}
+
 type UpdateBindingRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	AgentId       string                 `protobuf:"bytes,2,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Id                 string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	AgentId            string                 `protobuf:"bytes,2,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	MaxResponseSeconds *int32                 `protobuf:"varint,3,opt,name=max_response_seconds,json=maxResponseSeconds,proto3,oneof" json:"max_response_seconds,omitempty"` // If set, replaces the override (0 clears it)
+	Context            *string                `protobuf:"bytes,4,opt,name=context,proto3,oneof" json:"context,omitempty"`                                                    // If set, replaces the channel context ("" clears it)
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *UpdateBindingRequest) Reset() {
 	*x = UpdateBindingRequest{}
-	mi := &file_coven_proto_msgTypes[32]
+	mi := &file_coven_proto_msgTypes[42]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2567,7 +3649,7 @@ func (x *UpdateBindingRequest) String() string {
 func (*UpdateBindingRequest) ProtoMessage() {}
 
 func (x *UpdateBindingRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[32]
+	mi := &file_coven_proto_msgTypes[42]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2580,7 +3662,7 @@ func (x *UpdateBindingRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateBindingRequest.ProtoReflect.Descriptor instead.
 func (*UpdateBindingRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{32}
+	return file_coven_proto_rawDescGZIP(), []int{42}
 }
 
 func (x *UpdateBindingRequest) GetId() string {
@@ -2597,6 +3679,20 @@ func (x *UpdateBindingRequest) GetAgentId() string {
 	return ""
 }
 
+func (x *UpdateBindingRequest) GetMaxResponseSeconds() int32 {
+	if x != nil && x.MaxResponseSeconds != nil {
+		return *x.MaxResponseSeconds
+	}
+	return 0
+}
+
+func (x *UpdateBindingRequest) GetContext() string {
+	if x != nil && x.Context != nil {
+		return *x.Context
+	}
+	return ""
+}
+
 type DeleteBindingRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -2606,7 +3702,7 @@ type DeleteBindingRequest struct {
 
 func (x *DeleteBindingRequest) Reset() {
 	*x = DeleteBindingRequest{}
-	mi := &file_coven_proto_msgTypes[33]
+	mi := &file_coven_proto_msgTypes[43]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2618,7 +3714,7 @@ func (x *DeleteBindingRequest) String() string {
 func (*DeleteBindingRequest) ProtoMessage() {}
 
 func (x *DeleteBindingRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[33]
+	mi := &file_coven_proto_msgTypes[43]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2631,7 +3727,7 @@ func (x *DeleteBindingRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteBindingRequest.ProtoReflect.Descriptor instead.
 func (*DeleteBindingRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{33}
+	return file_coven_proto_rawDescGZIP(), []int{43}
 }
 
 func (x *DeleteBindingRequest) GetId() string {
@@ -2649,7 +3745,7 @@ type DeleteBindingResponse struct {
 
 func (x *DeleteBindingResponse) Reset() {
 	*x = DeleteBindingResponse{}
-	mi := &file_coven_proto_msgTypes[34]
+	mi := &file_coven_proto_msgTypes[44]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2661,7 +3757,7 @@ func (x *DeleteBindingResponse) String() string {
 func (*DeleteBindingResponse) ProtoMessage() {}
 
 func (x *DeleteBindingResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[34]
+	mi := &file_coven_proto_msgTypes[44]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2674,21 +3770,23 @@ func (x *DeleteBindingResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteBindingResponse.ProtoReflect.Descriptor instead.
 func (*DeleteBindingResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{34}
+	return file_coven_proto_rawDescGZIP(), []int{44}
 }
 
 // Token management messages
 type CreateTokenRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	PrincipalId   string                 `protobuf:"bytes,1,opt,name=principal_id,json=principalId,proto3" json:"principal_id,omitempty"` // Principal to create token for
-	TtlSeconds    int64                  `protobuf:"varint,2,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`   // Token lifetime in seconds (default: 30 days)
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	PrincipalId        string                 `protobuf:"bytes,1,opt,name=principal_id,json=principalId,proto3" json:"principal_id,omitempty"`                      // Principal to create token for
+	TtlSeconds         int64                  `protobuf:"varint,2,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`                        // Token lifetime in seconds (default: 30 days)
+	AgentIds           []string               `protobuf:"bytes,3,rep,name=agent_ids,json=agentIds,proto3" json:"agent_ids,omitempty"`                               // Agents the token may reach (empty: any)
+	CapabilitiesSubset []string               `protobuf:"bytes,4,rep,name=capabilities_subset,json=capabilitiesSubset,proto3" json:"capabilities_subset,omitempty"` // Capabilities the token may use (empty: all)
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *CreateTokenRequest) Reset() {
 	*x = CreateTokenRequest{}
-	mi := &file_coven_proto_msgTypes[35]
+	mi := &file_coven_proto_msgTypes[45]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2700,7 +3798,7 @@ func (x *CreateTokenRequest) String() string {
 func (*CreateTokenRequest) ProtoMessage() {}
 
 func (x *CreateTokenRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[35]
+	mi := &file_coven_proto_msgTypes[45]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2713,7 +3811,7 @@ func (x *CreateTokenRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateTokenRequest.ProtoReflect.Descriptor instead.
 func (*CreateTokenRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{35}
+	return file_coven_proto_rawDescGZIP(), []int{45}
 }
 
 func (x *CreateTokenRequest) GetPrincipalId() string {
@@ -2730,6 +3828,20 @@ func (x *CreateTokenRequest) GetTtlSeconds() int64 {
 	return 0
 }
 
+func (x *CreateTokenRequest) GetAgentIds() []string {
+	if x != nil {
+		return x.AgentIds
+	}
+	return nil
+}
+
+func (x *CreateTokenRequest) GetCapabilitiesSubset() []string {
+	if x != nil {
+		return x.CapabilitiesSubset
+	}
+	return nil
+}
+
 type CreateTokenResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`                          // The generated JWT token
@@ -2740,7 +3852,7 @@ type CreateTokenResponse struct {
 
 func (x *CreateTokenResponse) Reset() {
 	*x = CreateTokenResponse{}
-	mi := &file_coven_proto_msgTypes[36]
+	mi := &file_coven_proto_msgTypes[46]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2752,7 +3864,7 @@ func (x *CreateTokenResponse) String() string {
 func (*CreateTokenResponse) ProtoMessage() {}
 
 func (x *CreateTokenResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[36]
+	mi := &file_coven_proto_msgTypes[46]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2765,7 +3877,7 @@ func (x *CreateTokenResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateTokenResponse.ProtoReflect.Descriptor instead.
 func (*CreateTokenResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{36}
+	return file_coven_proto_rawDescGZIP(), []int{46}
 }
 
 func (x *CreateTokenResponse) GetToken() string {
@@ -2792,13 +3904,14 @@ type Principal struct {
 	PubkeyFp      *string                `protobuf:"bytes,5,opt,name=pubkey_fp,json=pubkeyFp,proto3,oneof" json:"pubkey_fp,omitempty"` // SSH public key fingerprint (for agents)
 	CreatedAt     string                 `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`    // ISO-8601
 	Roles         []string               `protobuf:"bytes,7,rep,name=roles,proto3" json:"roles,omitempty"`
+	Tags          map[string]string      `protobuf:"bytes,8,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Tags the agent last registered with
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Principal) Reset() {
 	*x = Principal{}
-	mi := &file_coven_proto_msgTypes[37]
+	mi := &file_coven_proto_msgTypes[47]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2810,7 +3923,7 @@ func (x *Principal) String() string {
 func (*Principal) ProtoMessage() {}
 
 func (x *Principal) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[37]
+	mi := &file_coven_proto_msgTypes[47]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2823,7 +3936,7 @@ func (x *Principal) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Principal.ProtoReflect.Descriptor instead.
 func (*Principal) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{37}
+	return file_coven_proto_rawDescGZIP(), []int{47}
 }
 
 func (x *Principal) GetId() string {
@@ -2875,6 +3988,13 @@ func (x *Principal) GetRoles() []string {
 	return nil
 }
 
+func (x *Principal) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
 type ListPrincipalsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Type          *string                `protobuf:"bytes,1,opt,name=type,proto3,oneof" json:"type,omitempty"`     // Filter by type
@@ -2885,7 +4005,7 @@ type ListPrincipalsRequest struct {
 
 func (x *ListPrincipalsRequest) Reset() {
 	*x = ListPrincipalsRequest{}
-	mi := &file_coven_proto_msgTypes[38]
+	mi := &file_coven_proto_msgTypes[48]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2897,7 +4017,7 @@ func (x *ListPrincipalsRequest) String() string {
 func (*ListPrincipalsRequest) ProtoMessage() {}
 
 func (x *ListPrincipalsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[38]
+	mi := &file_coven_proto_msgTypes[48]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2910,7 +4030,7 @@ func (x *ListPrincipalsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListPrincipalsRequest.ProtoReflect.Descriptor instead.
 func (*ListPrincipalsRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{38}
+	return file_coven_proto_rawDescGZIP(), []int{48}
 }
 
 func (x *ListPrincipalsRequest) GetType() string {
@@ -2936,7 +4056,7 @@ type ListPrincipalsResponse struct {
 
 func (x *ListPrincipalsResponse) Reset() {
 	*x = ListPrincipalsResponse{}
-	mi := &file_coven_proto_msgTypes[39]
+	mi := &file_coven_proto_msgTypes[49]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2948,7 +4068,7 @@ func (x *ListPrincipalsResponse) String() string {
 func (*ListPrincipalsResponse) ProtoMessage() {}
 
 func (x *ListPrincipalsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[39]
+	mi := &file_coven_proto_msgTypes[49]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2961,7 +4081,7 @@ func (x *ListPrincipalsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListPrincipalsResponse.ProtoReflect.Descriptor instead.
 func (*ListPrincipalsResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{39}
+	return file_coven_proto_rawDescGZIP(), []int{49}
 }
 
 func (x *ListPrincipalsResponse) GetPrincipals() []*Principal {
@@ -2984,7 +4104,7 @@ type CreatePrincipalRequest struct {
 
 func (x *CreatePrincipalRequest) Reset() {
 	*x = CreatePrincipalRequest{}
-	mi := &file_coven_proto_msgTypes[40]
+	mi := &file_coven_proto_msgTypes[50]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2996,7 +4116,7 @@ func (x *CreatePrincipalRequest) String() string {
 func (*CreatePrincipalRequest) ProtoMessage() {}
 
 func (x *CreatePrincipalRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[40]
+	mi := &file_coven_proto_msgTypes[50]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3009,7 +4129,7 @@ func (x *CreatePrincipalRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreatePrincipalRequest.ProtoReflect.Descriptor instead.
 func (*CreatePrincipalRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{40}
+	return file_coven_proto_rawDescGZIP(), []int{50}
 }
 
 func (x *CreatePrincipalRequest) GetType() string {
@@ -3056,7 +4176,7 @@ type DeletePrincipalRequest struct {
 
 func (x *DeletePrincipalRequest) Reset() {
 	*x = DeletePrincipalRequest{}
-	mi := &file_coven_proto_msgTypes[41]
+	mi := &file_coven_proto_msgTypes[51]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3068,7 +4188,7 @@ func (x *DeletePrincipalRequest) String() string {
 func (*DeletePrincipalRequest) ProtoMessage() {}
 
 func (x *DeletePrincipalRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[41]
+	mi := &file_coven_proto_msgTypes[51]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3081,7 +4201,7 @@ func (x *DeletePrincipalRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeletePrincipalRequest.ProtoReflect.Descriptor instead.
 func (*DeletePrincipalRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{41}
+	return file_coven_proto_rawDescGZIP(), []int{51}
 }
 
 func (x *DeletePrincipalRequest) GetId() string {
@@ -3099,7 +4219,7 @@ type DeletePrincipalResponse struct {
 
 func (x *DeletePrincipalResponse) Reset() {
 	*x = DeletePrincipalResponse{}
-	mi := &file_coven_proto_msgTypes[42]
+	mi := &file_coven_proto_msgTypes[52]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3111,7 +4231,7 @@ func (x *DeletePrincipalResponse) String() string {
 func (*DeletePrincipalResponse) ProtoMessage() {}
 
 func (x *DeletePrincipalResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[42]
+	mi := &file_coven_proto_msgTypes[52]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3124,23 +4244,24 @@ func (x *DeletePrincipalResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeletePrincipalResponse.ProtoReflect.Descriptor instead.
 func (*DeletePrincipalResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{42}
+	return file_coven_proto_rawDescGZIP(), []int{52}
 }
 
 // Request to answer a user question
 type AnswerQuestionRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`                // Which agent asked the question
-	QuestionId    string                 `protobuf:"bytes,2,opt,name=question_id,json=questionId,proto3" json:"question_id,omitempty"`       // Correlates with UserQuestionRequest.question_id
-	Selected      []string               `protobuf:"bytes,3,rep,name=selected,proto3" json:"selected,omitempty"`                             // Selected option label(s)
-	CustomText    *string                `protobuf:"bytes,4,opt,name=custom_text,json=customText,proto3,oneof" json:"custom_text,omitempty"` // If user typed a custom "Other" response
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	AgentId        string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`                            // Which agent asked the question
+	QuestionId     string                 `protobuf:"bytes,2,opt,name=question_id,json=questionId,proto3" json:"question_id,omitempty"`                   // Correlates with UserQuestionRequest.question_id
+	Selected       []string               `protobuf:"bytes,3,rep,name=selected,proto3" json:"selected,omitempty"`                                         // Selected option label(s)
+	CustomText     *string                `protobuf:"bytes,4,opt,name=custom_text,json=customText,proto3,oneof" json:"custom_text,omitempty"`             // If user typed a custom "Other" response
+	ContextVersion *string                `protobuf:"bytes,5,opt,name=context_version,json=contextVersion,proto3,oneof" json:"context_version,omitempty"` // Version of the context snapshot the answerer saw
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *AnswerQuestionRequest) Reset() {
 	*x = AnswerQuestionRequest{}
-	mi := &file_coven_proto_msgTypes[43]
+	mi := &file_coven_proto_msgTypes[53]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3152,7 +4273,7 @@ func (x *AnswerQuestionRequest) String() string {
 func (*AnswerQuestionRequest) ProtoMessage() {}
 
 func (x *AnswerQuestionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[43]
+	mi := &file_coven_proto_msgTypes[53]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3165,7 +4286,7 @@ func (x *AnswerQuestionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AnswerQuestionRequest.ProtoReflect.Descriptor instead.
 func (*AnswerQuestionRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{43}
+	return file_coven_proto_rawDescGZIP(), []int{53}
 }
 
 func (x *AnswerQuestionRequest) GetAgentId() string {
@@ -3196,6 +4317,13 @@ func (x *AnswerQuestionRequest) GetCustomText() string {
 	return ""
 }
 
+func (x *AnswerQuestionRequest) GetContextVersion() string {
+	if x != nil && x.ContextVersion != nil {
+		return *x.ContextVersion
+	}
+	return ""
+}
+
 // Response to answering a question
 type AnswerQuestionResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -3207,7 +4335,7 @@ type AnswerQuestionResponse struct {
 
 func (x *AnswerQuestionResponse) Reset() {
 	*x = AnswerQuestionResponse{}
-	mi := &file_coven_proto_msgTypes[44]
+	mi := &file_coven_proto_msgTypes[54]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3219,7 +4347,7 @@ func (x *AnswerQuestionResponse) String() string {
 func (*AnswerQuestionResponse) ProtoMessage() {}
 
 func (x *AnswerQuestionResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[44]
+	mi := &file_coven_proto_msgTypes[54]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3232,7 +4360,7 @@ func (x *AnswerQuestionResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AnswerQuestionResponse.ProtoReflect.Descriptor instead.
 func (*AnswerQuestionResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{44}
+	return file_coven_proto_rawDescGZIP(), []int{54}
 }
 
 func (x *AnswerQuestionResponse) GetSuccess() bool {
@@ -3262,7 +4390,7 @@ type ApproveToolRequest struct {
 
 func (x *ApproveToolRequest) Reset() {
 	*x = ApproveToolRequest{}
-	mi := &file_coven_proto_msgTypes[45]
+	mi := &file_coven_proto_msgTypes[55]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3274,7 +4402,7 @@ func (x *ApproveToolRequest) String() string {
 func (*ApproveToolRequest) ProtoMessage() {}
 
 func (x *ApproveToolRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[45]
+	mi := &file_coven_proto_msgTypes[55]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3287,7 +4415,7 @@ func (x *ApproveToolRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ApproveToolRequest.ProtoReflect.Descriptor instead.
 func (*ApproveToolRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{45}
+	return file_coven_proto_rawDescGZIP(), []int{55}
 }
 
 func (x *ApproveToolRequest) GetAgentId() string {
@@ -3329,7 +4457,7 @@ type ApproveToolResponse struct {
 
 func (x *ApproveToolResponse) Reset() {
 	*x = ApproveToolResponse{}
-	mi := &file_coven_proto_msgTypes[46]
+	mi := &file_coven_proto_msgTypes[56]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3341,7 +4469,7 @@ func (x *ApproveToolResponse) String() string {
 func (*ApproveToolResponse) ProtoMessage() {}
 
 func (x *ApproveToolResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[46]
+	mi := &file_coven_proto_msgTypes[56]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3354,7 +4482,7 @@ func (x *ApproveToolResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ApproveToolResponse.ProtoReflect.Descriptor instead.
 func (*ApproveToolResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{46}
+	return file_coven_proto_rawDescGZIP(), []int{56}
 }
 
 func (x *ApproveToolResponse) GetSuccess() bool {
@@ -3382,7 +4510,7 @@ type StreamEventsRequest struct {
 
 func (x *StreamEventsRequest) Reset() {
 	*x = StreamEventsRequest{}
-	mi := &file_coven_proto_msgTypes[47]
+	mi := &file_coven_proto_msgTypes[57]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3394,7 +4522,7 @@ func (x *StreamEventsRequest) String() string {
 func (*StreamEventsRequest) ProtoMessage() {}
 
 func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[47]
+	mi := &file_coven_proto_msgTypes[57]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3407,7 +4535,7 @@ func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
 func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{47}
+	return file_coven_proto_rawDescGZIP(), []int{57}
 }
 
 func (x *StreamEventsRequest) GetConversationKey() string {
@@ -3449,7 +4577,7 @@ type ClientStreamEvent struct {
 
 func (x *ClientStreamEvent) Reset() {
 	*x = ClientStreamEvent{}
-	mi := &file_coven_proto_msgTypes[48]
+	mi := &file_coven_proto_msgTypes[58]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3461,7 +4589,7 @@ func (x *ClientStreamEvent) String() string {
 func (*ClientStreamEvent) ProtoMessage() {}
 
 func (x *ClientStreamEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[48]
+	mi := &file_coven_proto_msgTypes[58]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3474,7 +4602,7 @@ func (x *ClientStreamEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClientStreamEvent.ProtoReflect.Descriptor instead.
 func (*ClientStreamEvent) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{48}
+	return file_coven_proto_rawDescGZIP(), []int{58}
 }
 
 func (x *ClientStreamEvent) GetConversationKey() string {
@@ -3676,20 +4804,24 @@ func (*ClientStreamEvent_UserQuestion) isClientStreamEvent_Payload() {}
 // User question request sent to clients (from ask_user builtin tool)
 type UserQuestionRequest struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
-	AgentId        string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`                       // Which agent is asking
-	QuestionId     string                 `protobuf:"bytes,2,opt,name=question_id,json=questionId,proto3" json:"question_id,omitempty"`              // Unique ID for correlation
-	Question       string                 `protobuf:"bytes,3,opt,name=question,proto3" json:"question,omitempty"`                                    // The question text
-	Options        []*QuestionOption      `protobuf:"bytes,4,rep,name=options,proto3" json:"options,omitempty"`                                      // Multiple choice options
-	MultiSelect    bool                   `protobuf:"varint,5,opt,name=multi_select,json=multiSelect,proto3" json:"multi_select,omitempty"`          // Can select multiple answers
-	Header         *string                `protobuf:"bytes,6,opt,name=header,proto3,oneof" json:"header,omitempty"`                                  // Short label/category for the question
-	TimeoutSeconds int32                  `protobuf:"varint,7,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"` // How long client has to respond
+	AgentId        string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`                            // Which agent is asking
+	QuestionId     string                 `protobuf:"bytes,2,opt,name=question_id,json=questionId,proto3" json:"question_id,omitempty"`                   // Unique ID for correlation
+	Question       string                 `protobuf:"bytes,3,opt,name=question,proto3" json:"question,omitempty"`                                         // The question text
+	Options        []*QuestionOption      `protobuf:"bytes,4,rep,name=options,proto3" json:"options,omitempty"`                                           // Multiple choice options
+	MultiSelect    bool                   `protobuf:"varint,5,opt,name=multi_select,json=multiSelect,proto3" json:"multi_select,omitempty"`               // Can select multiple answers
+	Header         *string                `protobuf:"bytes,6,opt,name=header,proto3,oneof" json:"header,omitempty"`                                       // Short label/category for the question
+	TimeoutSeconds int32                  `protobuf:"varint,7,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`      // How long client has to respond
+	ContextJson    *string                `protobuf:"bytes,8,opt,name=context_json,json=contextJson,proto3,oneof" json:"context_json,omitempty"`          // JSON context snapshot (thread, recent exchanges, tool chain)
+	AllowFreeText  *bool                  `protobuf:"varint,9,opt,name=allow_free_text,json=allowFreeText,proto3,oneof" json:"allow_free_text,omitempty"` // Accept custom text besides the options (unset: true)
+	Pattern        *string                `protobuf:"bytes,10,opt,name=pattern,proto3,oneof" json:"pattern,omitempty"`                                    // Regular expression custom text must match
+	MaxLength      *int32                 `protobuf:"varint,11,opt,name=max_length,json=maxLength,proto3,oneof" json:"max_length,omitempty"`              // Maximum custom text length in characters
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
 
 func (x *UserQuestionRequest) Reset() {
 	*x = UserQuestionRequest{}
-	mi := &file_coven_proto_msgTypes[49]
+	mi := &file_coven_proto_msgTypes[59]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3701,7 +4833,7 @@ func (x *UserQuestionRequest) String() string {
 func (*UserQuestionRequest) ProtoMessage() {}
 
 func (x *UserQuestionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[49]
+	mi := &file_coven_proto_msgTypes[59]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3714,7 +4846,7 @@ func (x *UserQuestionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UserQuestionRequest.ProtoReflect.Descriptor instead.
 func (*UserQuestionRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{49}
+	return file_coven_proto_rawDescGZIP(), []int{59}
 }
 
 func (x *UserQuestionRequest) GetAgentId() string {
@@ -3766,6 +4898,34 @@ func (x *UserQuestionRequest) GetTimeoutSeconds() int32 {
 	return 0
 }
 
+func (x *UserQuestionRequest) GetContextJson() string {
+	if x != nil && x.ContextJson != nil {
+		return *x.ContextJson
+	}
+	return ""
+}
+
+func (x *UserQuestionRequest) GetAllowFreeText() bool {
+	if x != nil && x.AllowFreeText != nil {
+		return *x.AllowFreeText
+	}
+	return false
+}
+
+func (x *UserQuestionRequest) GetPattern() string {
+	if x != nil && x.Pattern != nil {
+		return *x.Pattern
+	}
+	return ""
+}
+
+func (x *UserQuestionRequest) GetMaxLength() int32 {
+	if x != nil && x.MaxLength != nil {
+		return *x.MaxLength
+	}
+	return 0
+}
+
 // A single option in a user question
 type QuestionOption struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -3777,7 +4937,7 @@ type QuestionOption struct {
 
 func (x *QuestionOption) Reset() {
 	*x = QuestionOption{}
-	mi := &file_coven_proto_msgTypes[50]
+	mi := &file_coven_proto_msgTypes[60]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3789,7 +4949,7 @@ func (x *QuestionOption) String() string {
 func (*QuestionOption) ProtoMessage() {}
 
 func (x *QuestionOption) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[50]
+	mi := &file_coven_proto_msgTypes[60]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3802,7 +4962,7 @@ func (x *QuestionOption) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use QuestionOption.ProtoReflect.Descriptor instead.
 func (*QuestionOption) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{50}
+	return file_coven_proto_rawDescGZIP(), []int{60}
 }
 
 func (x *QuestionOption) GetLabel() string {
@@ -3833,7 +4993,7 @@ type ClientToolApprovalRequest struct {
 
 func (x *ClientToolApprovalRequest) Reset() {
 	*x = ClientToolApprovalRequest{}
-	mi := &file_coven_proto_msgTypes[51]
+	mi := &file_coven_proto_msgTypes[61]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3845,7 +5005,7 @@ func (x *ClientToolApprovalRequest) String() string {
 func (*ClientToolApprovalRequest) ProtoMessage() {}
 
 func (x *ClientToolApprovalRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[51]
+	mi := &file_coven_proto_msgTypes[61]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3858,7 +5018,7 @@ func (x *ClientToolApprovalRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClientToolApprovalRequest.ProtoReflect.Descriptor instead.
 func (*ClientToolApprovalRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{51}
+	return file_coven_proto_rawDescGZIP(), []int{61}
 }
 
 func (x *ClientToolApprovalRequest) GetAgentId() string {
@@ -3906,7 +5066,7 @@ type TextChunk struct {
 
 func (x *TextChunk) Reset() {
 	*x = TextChunk{}
-	mi := &file_coven_proto_msgTypes[52]
+	mi := &file_coven_proto_msgTypes[62]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3918,7 +5078,7 @@ func (x *TextChunk) String() string {
 func (*TextChunk) ProtoMessage() {}
 
 func (x *TextChunk) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[52]
+	mi := &file_coven_proto_msgTypes[62]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3931,7 +5091,7 @@ func (x *TextChunk) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TextChunk.ProtoReflect.Descriptor instead.
 func (*TextChunk) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{52}
+	return file_coven_proto_rawDescGZIP(), []int{62}
 }
 
 func (x *TextChunk) GetContent() string {
@@ -3951,7 +5111,7 @@ type ThinkingChunk struct {
 
 func (x *ThinkingChunk) Reset() {
 	*x = ThinkingChunk{}
-	mi := &file_coven_proto_msgTypes[53]
+	mi := &file_coven_proto_msgTypes[63]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3963,7 +5123,7 @@ func (x *ThinkingChunk) String() string {
 func (*ThinkingChunk) ProtoMessage() {}
 
 func (x *ThinkingChunk) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[53]
+	mi := &file_coven_proto_msgTypes[63]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3976,7 +5136,7 @@ func (x *ThinkingChunk) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ThinkingChunk.ProtoReflect.Descriptor instead.
 func (*ThinkingChunk) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{53}
+	return file_coven_proto_rawDescGZIP(), []int{63}
 }
 
 func (x *ThinkingChunk) GetContent() string {
@@ -3996,7 +5156,7 @@ type StreamDone struct {
 
 func (x *StreamDone) Reset() {
 	*x = StreamDone{}
-	mi := &file_coven_proto_msgTypes[54]
+	mi := &file_coven_proto_msgTypes[64]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4008,7 +5168,7 @@ func (x *StreamDone) String() string {
 func (*StreamDone) ProtoMessage() {}
 
 func (x *StreamDone) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[54]
+	mi := &file_coven_proto_msgTypes[64]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4021,7 +5181,7 @@ func (x *StreamDone) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StreamDone.ProtoReflect.Descriptor instead.
 func (*StreamDone) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{54}
+	return file_coven_proto_rawDescGZIP(), []int{64}
 }
 
 func (x *StreamDone) GetFullResponse() string {
@@ -4042,7 +5202,7 @@ type StreamError struct {
 
 func (x *StreamError) Reset() {
 	*x = StreamError{}
-	mi := &file_coven_proto_msgTypes[55]
+	mi := &file_coven_proto_msgTypes[65]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4054,7 +5214,7 @@ func (x *StreamError) String() string {
 func (*StreamError) ProtoMessage() {}
 
 func (x *StreamError) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[55]
+	mi := &file_coven_proto_msgTypes[65]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4067,7 +5227,7 @@ func (x *StreamError) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StreamError.ProtoReflect.Descriptor instead.
 func (*StreamError) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{55}
+	return file_coven_proto_rawDescGZIP(), []int{65}
 }
 
 func (x *StreamError) GetMessage() string {
@@ -4099,7 +5259,7 @@ type AgentInfo struct {
 
 func (x *AgentInfo) Reset() {
 	*x = AgentInfo{}
-	mi := &file_coven_proto_msgTypes[56]
+	mi := &file_coven_proto_msgTypes[66]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4111,7 +5271,7 @@ func (x *AgentInfo) String() string {
 func (*AgentInfo) ProtoMessage() {}
 
 func (x *AgentInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[56]
+	mi := &file_coven_proto_msgTypes[66]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4124,7 +5284,7 @@ func (x *AgentInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AgentInfo.ProtoReflect.Descriptor instead.
 func (*AgentInfo) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{56}
+	return file_coven_proto_rawDescGZIP(), []int{66}
 }
 
 func (x *AgentInfo) GetId() string {
@@ -4178,7 +5338,7 @@ type ListAgentsRequest struct {
 
 func (x *ListAgentsRequest) Reset() {
 	*x = ListAgentsRequest{}
-	mi := &file_coven_proto_msgTypes[57]
+	mi := &file_coven_proto_msgTypes[67]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4190,7 +5350,7 @@ func (x *ListAgentsRequest) String() string {
 func (*ListAgentsRequest) ProtoMessage() {}
 
 func (x *ListAgentsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[57]
+	mi := &file_coven_proto_msgTypes[67]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4203,7 +5363,7 @@ func (x *ListAgentsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListAgentsRequest.ProtoReflect.Descriptor instead.
 func (*ListAgentsRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{57}
+	return file_coven_proto_rawDescGZIP(), []int{67}
 }
 
 func (x *ListAgentsRequest) GetWorkspace() string {
@@ -4222,7 +5382,7 @@ type ListAgentsResponse struct {
 
 func (x *ListAgentsResponse) Reset() {
 	*x = ListAgentsResponse{}
-	mi := &file_coven_proto_msgTypes[58]
+	mi := &file_coven_proto_msgTypes[68]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4234,7 +5394,7 @@ func (x *ListAgentsResponse) String() string {
 func (*ListAgentsResponse) ProtoMessage() {}
 
 func (x *ListAgentsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[58]
+	mi := &file_coven_proto_msgTypes[68]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4247,7 +5407,7 @@ func (x *ListAgentsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListAgentsResponse.ProtoReflect.Descriptor instead.
 func (*ListAgentsResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{58}
+	return file_coven_proto_rawDescGZIP(), []int{68}
 }
 
 func (x *ListAgentsResponse) GetAgents() []*AgentInfo {
@@ -4268,7 +5428,7 @@ type RegisterAgentRequest struct {
 
 func (x *RegisterAgentRequest) Reset() {
 	*x = RegisterAgentRequest{}
-	mi := &file_coven_proto_msgTypes[59]
+	mi := &file_coven_proto_msgTypes[69]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4280,7 +5440,7 @@ func (x *RegisterAgentRequest) String() string {
 func (*RegisterAgentRequest) ProtoMessage() {}
 
 func (x *RegisterAgentRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[59]
+	mi := &file_coven_proto_msgTypes[69]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4293,7 +5453,7 @@ func (x *RegisterAgentRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegisterAgentRequest.ProtoReflect.Descriptor instead.
 func (*RegisterAgentRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{59}
+	return file_coven_proto_rawDescGZIP(), []int{69}
 }
 
 func (x *RegisterAgentRequest) GetDisplayName() string {
@@ -4321,7 +5481,7 @@ type RegisterAgentResponse struct {
 
 func (x *RegisterAgentResponse) Reset() {
 	*x = RegisterAgentResponse{}
-	mi := &file_coven_proto_msgTypes[60]
+	mi := &file_coven_proto_msgTypes[70]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4333,7 +5493,7 @@ func (x *RegisterAgentResponse) String() string {
 func (*RegisterAgentResponse) ProtoMessage() {}
 
 func (x *RegisterAgentResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[60]
+	mi := &file_coven_proto_msgTypes[70]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4346,7 +5506,7 @@ func (x *RegisterAgentResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegisterAgentResponse.ProtoReflect.Descriptor instead.
 func (*RegisterAgentResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{60}
+	return file_coven_proto_rawDescGZIP(), []int{70}
 }
 
 func (x *RegisterAgentResponse) GetPrincipalId() string {
@@ -4374,7 +5534,7 @@ type RegisterClientRequest struct {
 
 func (x *RegisterClientRequest) Reset() {
 	*x = RegisterClientRequest{}
-	mi := &file_coven_proto_msgTypes[61]
+	mi := &file_coven_proto_msgTypes[71]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4386,7 +5546,7 @@ func (x *RegisterClientRequest) String() string {
 func (*RegisterClientRequest) ProtoMessage() {}
 
 func (x *RegisterClientRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[61]
+	mi := &file_coven_proto_msgTypes[71]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4399,7 +5559,7 @@ func (x *RegisterClientRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegisterClientRequest.ProtoReflect.Descriptor instead.
 func (*RegisterClientRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{61}
+	return file_coven_proto_rawDescGZIP(), []int{71}
 }
 
 func (x *RegisterClientRequest) GetDisplayName() string {
@@ -4427,7 +5587,7 @@ type RegisterClientResponse struct {
 
 func (x *RegisterClientResponse) Reset() {
 	*x = RegisterClientResponse{}
-	mi := &file_coven_proto_msgTypes[62]
+	mi := &file_coven_proto_msgTypes[72]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4439,7 +5599,7 @@ func (x *RegisterClientResponse) String() string {
 func (*RegisterClientResponse) ProtoMessage() {}
 
 func (x *RegisterClientResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[62]
+	mi := &file_coven_proto_msgTypes[72]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4452,7 +5612,7 @@ func (x *RegisterClientResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegisterClientResponse.ProtoReflect.Descriptor instead.
 func (*RegisterClientResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{62}
+	return file_coven_proto_rawDescGZIP(), []int{72}
 }
 
 func (x *RegisterClientResponse) GetPrincipalId() string {
@@ -4483,7 +5643,7 @@ type ClientSendMessageRequest struct {
 
 func (x *ClientSendMessageRequest) Reset() {
 	*x = ClientSendMessageRequest{}
-	mi := &file_coven_proto_msgTypes[63]
+	mi := &file_coven_proto_msgTypes[73]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4495,7 +5655,7 @@ func (x *ClientSendMessageRequest) String() string {
 func (*ClientSendMessageRequest) ProtoMessage() {}
 
 func (x *ClientSendMessageRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[63]
+	mi := &file_coven_proto_msgTypes[73]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4508,7 +5668,7 @@ func (x *ClientSendMessageRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClientSendMessageRequest.ProtoReflect.Descriptor instead.
 func (*ClientSendMessageRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{63}
+	return file_coven_proto_rawDescGZIP(), []int{73}
 }
 
 func (x *ClientSendMessageRequest) GetConversationKey() string {
@@ -4541,16 +5701,20 @@ func (x *ClientSendMessageRequest) GetIdempotencyKey() string {
 
 // ClientSendMessageResponse is the response for direct client message sending.
 type ClientSendMessageResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`                        // "accepted" or "duplicate"
-	MessageId     string                 `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"` // assigned message ID (empty for duplicates)
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Status    string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`                        // "accepted" or "duplicate"
+	MessageId string                 `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"` // assigned message ID (empty for duplicates)
+	// The agent that will answer, for attribution (empty for duplicates or when unknown)
+	AgentId       string `protobuf:"bytes,3,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	AgentName     string `protobuf:"bytes,4,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	InstanceId    string `protobuf:"bytes,5,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ClientSendMessageResponse) Reset() {
 	*x = ClientSendMessageResponse{}
-	mi := &file_coven_proto_msgTypes[64]
+	mi := &file_coven_proto_msgTypes[74]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4562,7 +5726,7 @@ func (x *ClientSendMessageResponse) String() string {
 func (*ClientSendMessageResponse) ProtoMessage() {}
 
 func (x *ClientSendMessageResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[64]
+	mi := &file_coven_proto_msgTypes[74]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4575,7 +5739,7 @@ func (x *ClientSendMessageResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClientSendMessageResponse.ProtoReflect.Descriptor instead.
 func (*ClientSendMessageResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{64}
+	return file_coven_proto_rawDescGZIP(), []int{74}
 }
 
 func (x *ClientSendMessageResponse) GetStatus() string {
@@ -4592,6 +5756,27 @@ func (x *ClientSendMessageResponse) GetMessageId() string {
 	return ""
 }
 
+func (x *ClientSendMessageResponse) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *ClientSendMessageResponse) GetAgentName() string {
+	if x != nil {
+		return x.AgentName
+	}
+	return ""
+}
+
+func (x *ClientSendMessageResponse) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
 // MeResponse contains the authenticated principal's identity information
 type MeResponse struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
@@ -4608,7 +5793,7 @@ type MeResponse struct {
 
 func (x *MeResponse) Reset() {
 	*x = MeResponse{}
-	mi := &file_coven_proto_msgTypes[65]
+	mi := &file_coven_proto_msgTypes[75]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4620,7 +5805,7 @@ func (x *MeResponse) String() string {
 func (*MeResponse) ProtoMessage() {}
 
 func (x *MeResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[65]
+	mi := &file_coven_proto_msgTypes[75]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4633,7 +5818,7 @@ func (x *MeResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MeResponse.ProtoReflect.Descriptor instead.
 func (*MeResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{65}
+	return file_coven_proto_rawDescGZIP(), []int{75}
 }
 
 func (x *MeResponse) GetPrincipalId() string {
@@ -4693,7 +5878,7 @@ type Event struct {
 	Direction        string                 `protobuf:"bytes,3,opt,name=direction,proto3" json:"direction,omitempty"` // "inbound_to_agent" or "outbound_from_agent"
 	Author           string                 `protobuf:"bytes,4,opt,name=author,proto3" json:"author,omitempty"`
 	Timestamp        string                 `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // ISO-8601
-	Type             string                 `protobuf:"bytes,6,opt,name=type,proto3" json:"type,omitempty"`           // "message", "tool_call", "tool_result", "system", "error"
+	Type             string                 `protobuf:"bytes,6,opt,name=type,proto3" json:"type,omitempty"`           // "message", "tool_call", "tool_result", "system", "error", "plan", "citation"
 	Text             *string                `protobuf:"bytes,7,opt,name=text,proto3,oneof" json:"text,omitempty"`
 	RawTransport     *string                `protobuf:"bytes,8,opt,name=raw_transport,json=rawTransport,proto3,oneof" json:"raw_transport,omitempty"`
 	RawPayloadRef    *string                `protobuf:"bytes,9,opt,name=raw_payload_ref,json=rawPayloadRef,proto3,oneof" json:"raw_payload_ref,omitempty"`
@@ -4705,7 +5890,7 @@ type Event struct {
 
 func (x *Event) Reset() {
 	*x = Event{}
-	mi := &file_coven_proto_msgTypes[66]
+	mi := &file_coven_proto_msgTypes[76]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4717,7 +5902,7 @@ func (x *Event) String() string {
 func (*Event) ProtoMessage() {}
 
 func (x *Event) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[66]
+	mi := &file_coven_proto_msgTypes[76]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4730,7 +5915,7 @@ func (x *Event) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Event.ProtoReflect.Descriptor instead.
 func (*Event) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{66}
+	return file_coven_proto_rawDescGZIP(), []int{76}
 }
 
 func (x *Event) GetId() string {
@@ -4823,7 +6008,7 @@ type GetEventsRequest struct {
 
 func (x *GetEventsRequest) Reset() {
 	*x = GetEventsRequest{}
-	mi := &file_coven_proto_msgTypes[67]
+	mi := &file_coven_proto_msgTypes[77]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4835,7 +6020,7 @@ func (x *GetEventsRequest) String() string {
 func (*GetEventsRequest) ProtoMessage() {}
 
 func (x *GetEventsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[67]
+	mi := &file_coven_proto_msgTypes[77]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4848,7 +6033,7 @@ func (x *GetEventsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetEventsRequest.ProtoReflect.Descriptor instead.
 func (*GetEventsRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{67}
+	return file_coven_proto_rawDescGZIP(), []int{77}
 }
 
 func (x *GetEventsRequest) GetConversationKey() string {
@@ -4897,7 +6082,7 @@ type GetEventsResponse struct {
 
 func (x *GetEventsResponse) Reset() {
 	*x = GetEventsResponse{}
-	mi := &file_coven_proto_msgTypes[68]
+	mi := &file_coven_proto_msgTypes[78]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4909,7 +6094,7 @@ func (x *GetEventsResponse) String() string {
 func (*GetEventsResponse) ProtoMessage() {}
 
 func (x *GetEventsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[68]
+	mi := &file_coven_proto_msgTypes[78]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4922,7 +6107,7 @@ func (x *GetEventsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetEventsResponse.ProtoReflect.Descriptor instead.
 func (*GetEventsResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{68}
+	return file_coven_proto_rawDescGZIP(), []int{78}
 }
 
 func (x *GetEventsResponse) GetEvents() []*Event {
@@ -4953,14 +6138,16 @@ type ToolDefinition struct {
 	Description          string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
 	InputSchemaJson      string                 `protobuf:"bytes,3,opt,name=input_schema_json,json=inputSchemaJson,proto3" json:"input_schema_json,omitempty"` // MCP-compatible JSON Schema
 	RequiredCapabilities []string               `protobuf:"bytes,4,rep,name=required_capabilities,json=requiredCapabilities,proto3" json:"required_capabilities,omitempty"`
-	TimeoutSeconds       int32                  `protobuf:"varint,5,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"` // optional, default 30
+	TimeoutSeconds       int32                  `protobuf:"varint,5,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`      // optional, default 30
+	Cacheable            bool                   `protobuf:"varint,6,opt,name=cacheable,proto3" json:"cacheable,omitempty"`                                      // Result depends only on the input; the gateway may cache it
+	CacheTtlSeconds      int32                  `protobuf:"varint,7,opt,name=cache_ttl_seconds,json=cacheTtlSeconds,proto3" json:"cache_ttl_seconds,omitempty"` // How long a cached result stays fresh (required with cacheable)
 	unknownFields        protoimpl.UnknownFields
 	sizeCache            protoimpl.SizeCache
 }
 
 func (x *ToolDefinition) Reset() {
 	*x = ToolDefinition{}
-	mi := &file_coven_proto_msgTypes[69]
+	mi := &file_coven_proto_msgTypes[79]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4972,7 +6159,7 @@ func (x *ToolDefinition) String() string {
 func (*ToolDefinition) ProtoMessage() {}
 
 func (x *ToolDefinition) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[69]
+	mi := &file_coven_proto_msgTypes[79]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4985,7 +6172,7 @@ func (x *ToolDefinition) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ToolDefinition.ProtoReflect.Descriptor instead.
 func (*ToolDefinition) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{69}
+	return file_coven_proto_rawDescGZIP(), []int{79}
 }
 
 func (x *ToolDefinition) GetName() string {
@@ -5023,6 +6210,20 @@ func (x *ToolDefinition) GetTimeoutSeconds() int32 {
 	return 0
 }
 
+func (x *ToolDefinition) GetCacheable() bool {
+	if x != nil {
+		return x.Cacheable
+	}
+	return false
+}
+
+func (x *ToolDefinition) GetCacheTtlSeconds() int32 {
+	if x != nil {
+		return x.CacheTtlSeconds
+	}
+	return 0
+}
+
 type PackManifest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	PackId        string                 `protobuf:"bytes,1,opt,name=pack_id,json=packId,proto3" json:"pack_id,omitempty"`
@@ -5034,7 +6235,7 @@ type PackManifest struct {
 
 func (x *PackManifest) Reset() {
 	*x = PackManifest{}
-	mi := &file_coven_proto_msgTypes[70]
+	mi := &file_coven_proto_msgTypes[80]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -5046,7 +6247,7 @@ func (x *PackManifest) String() string {
 func (*PackManifest) ProtoMessage() {}
 
 func (x *PackManifest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[70]
+	mi := &file_coven_proto_msgTypes[80]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5059,7 +6260,7 @@ func (x *PackManifest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PackManifest.ProtoReflect.Descriptor instead.
 func (*PackManifest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{70}
+	return file_coven_proto_rawDescGZIP(), []int{80}
 }
 
 func (x *PackManifest) GetPackId() string {
@@ -5095,7 +6296,7 @@ type ExecuteToolRequest struct {
 
 func (x *ExecuteToolRequest) Reset() {
 	*x = ExecuteToolRequest{}
-	mi := &file_coven_proto_msgTypes[71]
+	mi := &file_coven_proto_msgTypes[81]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -5107,7 +6308,7 @@ func (x *ExecuteToolRequest) String() string {
 func (*ExecuteToolRequest) ProtoMessage() {}
 
 func (x *ExecuteToolRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[71]
+	mi := &file_coven_proto_msgTypes[81]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5120,7 +6321,7 @@ func (x *ExecuteToolRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExecuteToolRequest.ProtoReflect.Descriptor instead.
 func (*ExecuteToolRequest) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{71}
+	return file_coven_proto_rawDescGZIP(), []int{81}
 }
 
 func (x *ExecuteToolRequest) GetToolName() string {
@@ -5151,14 +6352,16 @@ type ExecuteToolResponse struct {
 	//
 	//	*ExecuteToolResponse_OutputJson
 	//	*ExecuteToolResponse_Error
+	//	*ExecuteToolResponse_Chunk
 	Result        isExecuteToolResponse_Result `protobuf_oneof:"result"`
+	Cached        bool                         `protobuf:"varint,5,opt,name=cached,proto3" json:"cached,omitempty"` // Set by the gateway on output served from its result cache
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ExecuteToolResponse) Reset() {
 	*x = ExecuteToolResponse{}
-	mi := &file_coven_proto_msgTypes[72]
+	mi := &file_coven_proto_msgTypes[82]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -5170,7 +6373,7 @@ func (x *ExecuteToolResponse) String() string {
 func (*ExecuteToolResponse) ProtoMessage() {}
 
 func (x *ExecuteToolResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[72]
+	mi := &file_coven_proto_msgTypes[82]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5183,7 +6386,7 @@ func (x *ExecuteToolResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExecuteToolResponse.ProtoReflect.Descriptor instead.
 func (*ExecuteToolResponse) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{72}
+	return file_coven_proto_rawDescGZIP(), []int{82}
 }
 
 func (x *ExecuteToolResponse) GetRequestId() string {
@@ -5218,6 +6421,22 @@ func (x *ExecuteToolResponse) GetError() string {
 	return ""
 }
 
+func (x *ExecuteToolResponse) GetChunk() *ToolResultChunk {
+	if x != nil {
+		if x, ok := x.Result.(*ExecuteToolResponse_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+func (x *ExecuteToolResponse) GetCached() bool {
+	if x != nil {
+		return x.Cached
+	}
+	return false
+}
+
 type isExecuteToolResponse_Result interface {
 	isExecuteToolResponse_Result()
 }
@@ -5230,10 +6449,69 @@ type ExecuteToolResponse_Error struct {
 	Error string `protobuf:"bytes,3,opt,name=error,proto3,oneof"`
 }
 
+type ExecuteToolResponse_Chunk struct {
+	Chunk *ToolResultChunk `protobuf:"bytes,4,opt,name=chunk,proto3,oneof"` // Partial output; more chunks or a final result follow
+}
+
 func (*ExecuteToolResponse_OutputJson) isExecuteToolResponse_Result() {}
 
 func (*ExecuteToolResponse_Error) isExecuteToolResponse_Result() {}
 
+func (*ExecuteToolResponse_Chunk) isExecuteToolResponse_Result() {}
+
+// Partial output of a streaming tool call (pack → server)
+type ToolResultChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sequence      uint64                 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"` // 1 for a call's first chunk, then consecutive
+	Output        string                 `protobuf:"bytes,2,opt,name=output,proto3" json:"output,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolResultChunk) Reset() {
+	*x = ToolResultChunk{}
+	mi := &file_coven_proto_msgTypes[83]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolResultChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolResultChunk) ProtoMessage() {}
+
+func (x *ToolResultChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_coven_proto_msgTypes[83]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolResultChunk.ProtoReflect.Descriptor instead.
+func (*ToolResultChunk) Descriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{83}
+}
+
+func (x *ToolResultChunk) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *ToolResultChunk) GetOutput() string {
+	if x != nil {
+		return x.Output
+	}
+	return ""
+}
+
 // Pack registration acknowledgment
 type PackWelcome struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -5245,7 +6523,7 @@ type PackWelcome struct {
 
 func (x *PackWelcome) Reset() {
 	*x = PackWelcome{}
-	mi := &file_coven_proto_msgTypes[73]
+	mi := &file_coven_proto_msgTypes[84]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -5257,7 +6535,7 @@ func (x *PackWelcome) String() string {
 func (*PackWelcome) ProtoMessage() {}
 
 func (x *PackWelcome) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[73]
+	mi := &file_coven_proto_msgTypes[84]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5270,7 +6548,7 @@ func (x *PackWelcome) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PackWelcome.ProtoReflect.Descriptor instead.
 func (*PackWelcome) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{73}
+	return file_coven_proto_rawDescGZIP(), []int{84}
 }
 
 func (x *PackWelcome) GetPackId() string {
@@ -5287,6 +6565,51 @@ func (x *PackWelcome) GetRejectedTools() []string {
 	return nil
 }
 
+// Pack liveness signal (pack → server)
+type PackHeartbeat struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PackId        string                 `protobuf:"bytes,1,opt,name=pack_id,json=packId,proto3" json:"pack_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PackHeartbeat) Reset() {
+	*x = PackHeartbeat{}
+	mi := &file_coven_proto_msgTypes[85]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PackHeartbeat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PackHeartbeat) ProtoMessage() {}
+
+func (x *PackHeartbeat) ProtoReflect() protoreflect.Message {
+	mi := &file_coven_proto_msgTypes[85]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PackHeartbeat.ProtoReflect.Descriptor instead.
+func (*PackHeartbeat) Descriptor() ([]byte, []int) {
+	return file_coven_proto_rawDescGZIP(), []int{85}
+}
+
+func (x *PackHeartbeat) GetPackId() string {
+	if x != nil {
+		return x.PackId
+	}
+	return ""
+}
+
 // Available tools list for agents
 type AvailableTools struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -5297,7 +6620,7 @@ type AvailableTools struct {
 
 func (x *AvailableTools) Reset() {
 	*x = AvailableTools{}
-	mi := &file_coven_proto_msgTypes[74]
+	mi := &file_coven_proto_msgTypes[86]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -5309,7 +6632,7 @@ func (x *AvailableTools) String() string {
 func (*AvailableTools) ProtoMessage() {}
 
 func (x *AvailableTools) ProtoReflect() protoreflect.Message {
-	mi := &file_coven_proto_msgTypes[74]
+	mi := &file_coven_proto_msgTypes[86]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5322,7 +6645,7 @@ func (x *AvailableTools) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AvailableTools.ProtoReflect.Descriptor instead.
 func (*AvailableTools) Descriptor() ([]byte, []int) {
-	return file_coven_proto_rawDescGZIP(), []int{74}
+	return file_coven_proto_rawDescGZIP(), []int{86}
 }
 
 func (x *AvailableTools) GetTools() []*ToolDefinition {
@@ -5350,7 +6673,7 @@ const file_coven_proto_rawDesc = "" +
 	"\x05dirty\x18\x03 \x01(\bR\x05dirty\x12\x16\n" +
 	"\x06remote\x18\x04 \x01(\tR\x06remote\x12\x14\n" +
 	"\x05ahead\x18\x05 \x01(\x05R\x05ahead\x12\x16\n" +
-	"\x06behind\x18\x06 \x01(\x05R\x06behind\"\xc4\x01\n" +
+	"\x06behind\x18\x06 \x01(\x05R\x06behind\"\xd8\x02\n" +
 	"\rAgentMetadata\x12+\n" +
 	"\x11working_directory\x18\x01 \x01(\tR\x10workingDirectory\x12 \n" +
 	"\x03git\x18\x02 \x01(\v2\x0e.coven.GitInfoR\x03git\x12\x1a\n" +
@@ -5359,13 +6682,19 @@ const file_coven_proto_rawDesc = "" +
 	"\n" +
 	"workspaces\x18\x05 \x03(\tR\n" +
 	"workspaces\x12\x18\n" +
-	"\abackend\x18\x06 \x01(\tR\abackend\"\xc1\x01\n" +
+	"\abackend\x18\x06 \x01(\tR\abackend\x12%\n" +
+	"\x0emax_concurrent\x18\a \x01(\x05R\rmaxConcurrent\x122\n" +
+	"\x04tags\x18\b \x03(\v2\x1e.coven.AgentMetadata.TagsEntryR\x04tags\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xe6\x01\n" +
 	"\rRegisterAgent\x12\x19\n" +
 	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\"\n" +
 	"\fcapabilities\x18\x03 \x03(\tR\fcapabilities\x120\n" +
 	"\bmetadata\x18\x04 \x01(\v2\x14.coven.AgentMetadataR\bmetadata\x12+\n" +
-	"\x11protocol_features\x18\x05 \x03(\tR\x10protocolFeatures\"\x98\x05\n" +
+	"\x11protocol_features\x18\x05 \x03(\tR\x10protocolFeatures\x12#\n" +
+	"\rsession_token\x18\x06 \x01(\tR\fsessionToken\"\xad\x06\n" +
 	"\x0fMessageResponse\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x1c\n" +
@@ -5384,7 +6713,10 @@ const file_coven_proto_rawDesc = "" +
 	"\x05usage\x18\f \x01(\v2\x11.coven.TokenUsageH\x00R\x05usage\x127\n" +
 	"\n" +
 	"tool_state\x18\r \x01(\v2\x16.coven.ToolStateUpdateH\x00R\ttoolState\x120\n" +
-	"\tcancelled\x18\x0e \x01(\v2\x10.coven.CancelledH\x00R\tcancelledB\a\n" +
+	"\tcancelled\x18\x0e \x01(\v2\x10.coven.CancelledH\x00R\tcancelled\x12!\n" +
+	"\x04plan\x18\x0f \x01(\v2\v.coven.PlanH\x00R\x04plan\x12A\n" +
+	"\x10plan_step_update\x18\x10 \x01(\v2\x15.coven.PlanStepUpdateH\x00R\x0eplanStepUpdate\x12-\n" +
+	"\bcitation\x18\x11 \x01(\v2\x0f.coven.CitationH\x00R\bcitationB\a\n" +
 	"\x05event\",\n" +
 	"\vSessionInit\x12\x1d\n" +
 	"\n" +
@@ -5402,7 +6734,24 @@ const file_coven_proto_rawDesc = "" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12&\n" +
 	"\x05state\x18\x02 \x01(\x0e2\x10.coven.ToolStateR\x05state\x12\x1b\n" +
 	"\x06detail\x18\x03 \x01(\tH\x00R\x06detail\x88\x01\x01B\t\n" +
-	"\a_detail\"#\n" +
+	"\a_detail\"O\n" +
+	"\bPlanStep\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12-\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x15.coven.PlanStepStatusR\x06status\"-\n" +
+	"\x04Plan\x12%\n" +
+	"\x05steps\x18\x01 \x03(\v2\x0f.coven.PlanStepR\x05steps\"}\n" +
+	"\x0ePlanStepUpdate\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12-\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x15.coven.PlanStepStatusR\x06status\x12\x1b\n" +
+	"\x06detail\x18\x03 \x01(\tH\x00R\x06detail\x88\x01\x01B\t\n" +
+	"\a_detail\"s\n" +
+	"\bCitation\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x10\n" +
+	"\x03uri\x18\x03 \x01(\tR\x03uri\x12\x1d\n" +
+	"\asnippet\x18\x04 \x01(\tH\x00R\asnippet\x88\x01\x01B\n" +
+	"\n" +
+	"\b_snippet\"#\n" +
 	"\tCancelled\x12\x16\n" +
 	"\x06reason\x18\x01 \x01(\tR\x06reason\"\xaa\x01\n" +
 	"\rInjectContext\x12!\n" +
@@ -5430,33 +6779,43 @@ const file_coven_proto_rawDesc = "" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1d\n" +
 	"\n" +
-	"input_json\x18\x03 \x01(\tR\tinputJson\"O\n" +
+	"input_json\x18\x03 \x01(\tR\tinputJson\"\x9d\x01\n" +
 	"\n" +
 	"ToolResult\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
 	"\x06output\x18\x02 \x01(\tR\x06output\x12\x19\n" +
-	"\bis_error\x18\x03 \x01(\bR\aisError\"+\n" +
+	"\bis_error\x18\x03 \x01(\bR\aisError\x12\x18\n" +
+	"\apartial\x18\x04 \x01(\bR\apartial\x12\x1a\n" +
+	"\bsequence\x18\x05 \x01(\x04R\bsequence\x12\x16\n" +
+	"\x06cached\x18\x06 \x01(\bR\x06cached\"+\n" +
 	"\x04Done\x12#\n" +
 	"\rfull_response\x18\x01 \x01(\tR\ffullResponse\"W\n" +
 	"\bFileData\x12\x1a\n" +
 	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x1b\n" +
 	"\tmime_type\x18\x02 \x01(\tR\bmimeType\x12\x12\n" +
-	"\x04data\x18\x03 \x01(\fR\x04data\".\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\"\x80\x01\n" +
 	"\tHeartbeat\x12!\n" +
-	"\ftimestamp_ms\x18\x01 \x01(\x03R\vtimestampMs\"l\n" +
+	"\ftimestamp_ms\x18\x01 \x01(\x03R\vtimestampMs\x12(\n" +
+	"\x10ack_timestamp_ms\x18\x02 \x01(\x03R\x0eackTimestampMs\x12&\n" +
+	"\x0fack_received_ms\x18\x03 \x01(\x03R\rackReceivedMs\"\xfc\x01\n" +
 	"\x0fExecutePackTool\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x1b\n" +
 	"\ttool_name\x18\x02 \x01(\tR\btoolName\x12\x1d\n" +
 	"\n" +
-	"input_json\x18\x03 \x01(\tR\tinputJson\"t\n" +
+	"input_json\x18\x03 \x01(\tR\tinputJson\x12M\n" +
+	"\rtrace_context\x18\x04 \x03(\v2(.coven.ExecutePackTool.TraceContextEntryR\ftraceContext\x1a?\n" +
+	"\x11TraceContextEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x8c\x01\n" +
 	"\x0ePackToolResult\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12!\n" +
 	"\voutput_json\x18\x02 \x01(\tH\x00R\n" +
 	"outputJson\x12\x16\n" +
-	"\x05error\x18\x03 \x01(\tH\x00R\x05errorB\b\n" +
-	"\x06result\"\xfe\x03\n" +
+	"\x05error\x18\x03 \x01(\tH\x00R\x05error\x12\x16\n" +
+	"\x06cached\x18\x04 \x01(\bR\x06cachedB\b\n" +
+	"\x06result\"\xf7\x06\n" +
 	"\rServerMessage\x12*\n" +
 	"\awelcome\x18\x01 \x01(\v2\x0e.coven.WelcomeH\x00R\awelcome\x127\n" +
 	"\fsend_message\x18\x02 \x01(\v2\x12.coven.SendMessageH\x00R\vsendMessage\x12-\n" +
@@ -5465,16 +6824,28 @@ const file_coven_proto_rawDesc = "" +
 	"\x12registration_error\x18\x05 \x01(\v2\x18.coven.RegistrationErrorH\x00R\x11registrationError\x12=\n" +
 	"\x0einject_context\x18\x06 \x01(\v2\x14.coven.InjectContextH\x00R\rinjectContext\x12=\n" +
 	"\x0ecancel_request\x18\a \x01(\v2\x14.coven.CancelRequestH\x00R\rcancelRequest\x12A\n" +
-	"\x10pack_tool_result\x18\b \x01(\v2\x15.coven.PackToolResultH\x00R\x0epackToolResultB\t\n" +
+	"\x10pack_tool_result\x18\b \x01(\v2\x15.coven.PackToolResultH\x00R\x0epackToolResult\x12L\n" +
+	"\x13registration_status\x18\t \x01(\v2\x19.coven.RegistrationStatusH\x00R\x12registrationStatus\x12:\n" +
+	"\rtools_changed\x18\n" +
+	" \x01(\v2\x13.coven.ToolsChangedH\x00R\ftoolsChanged\x12=\n" +
+	"\x0eresume_request\x18\v \x01(\v2\x14.coven.ResumeRequestH\x00R\rresumeRequest\x12*\n" +
+	"\agoodbye\x18\f \x01(\v2\x0e.coven.GoodbyeH\x00R\agoodbye\x12:\n" +
+	"\rheartbeat_ack\x18\r \x01(\v2\x13.coven.HeartbeatAckH\x00R\fheartbeatAck\x12F\n" +
+	"\x11capability_update\x18\x0e \x01(\v2\x17.coven.CapabilityUpdateH\x00R\x10capabilityUpdateB\t\n" +
 	"\apayload\"N\n" +
 	"\x11RegistrationError\x12\x16\n" +
 	"\x06reason\x18\x01 \x01(\tR\x06reason\x12!\n" +
-	"\fsuggested_id\x18\x02 \x01(\tR\vsuggestedId\"c\n" +
+	"\fsuggested_id\x18\x02 \x01(\tR\vsuggestedId\"\x98\x01\n" +
+	"\x12RegistrationStatus\x12.\n" +
+	"\x05state\x18\x01 \x01(\x0e2\x18.coven.RegistrationStateR\x05state\x12!\n" +
+	"\fprincipal_id\x18\x02 \x01(\tR\vprincipalId\x12\x12\n" +
+	"\x04hint\x18\x03 \x01(\tR\x04hint\x12\x1b\n" +
+	"\tadmin_url\x18\x04 \x01(\tR\badminUrl\"c\n" +
 	"\x14ToolApprovalResponse\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
 	"\bapproved\x18\x02 \x01(\bR\bapproved\x12\x1f\n" +
 	"\vapprove_all\x18\x03 \x01(\bR\n" +
-	"approveAll\"\xf8\x02\n" +
+	"approveAll\"\x8c\x04\n" +
 	"\aWelcome\x12\x1b\n" +
 	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x19\n" +
 	"\bagent_id\x18\x02 \x01(\tR\aagentId\x12\x1f\n" +
@@ -5484,23 +6855,54 @@ const file_coven_proto_rawDesc = "" +
 	"\x0favailable_tools\x18\x05 \x03(\v2\x15.coven.ToolDefinitionR\x0eavailableTools\x12\x1b\n" +
 	"\tmcp_token\x18\x06 \x01(\tR\bmcpToken\x12!\n" +
 	"\fmcp_endpoint\x18\a \x01(\tR\vmcpEndpoint\x125\n" +
-	"\asecrets\x18\b \x03(\v2\x1b.coven.Welcome.SecretsEntryR\asecrets\x1a:\n" +
+	"\asecrets\x18\b \x03(\v2\x1b.coven.Welcome.SecretsEntryR\asecrets\x12'\n" +
+	"\x0fcatalog_version\x18\t \x01(\x03R\x0ecatalogVersion\x12#\n" +
+	"\rsession_token\x18\n" +
+	" \x01(\tR\fsessionToken\x12\x18\n" +
+	"\aresumed\x18\v \x01(\bR\aresumed\x12*\n" +
+	"\x11acked_request_ids\x18\f \x03(\tR\x0fackedRequestIds\x1a:\n" +
 	"\fSecretsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xb4\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x87\x03\n" +
 	"\vSendMessage\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x1b\n" +
 	"\tthread_id\x18\x02 \x01(\tR\bthreadId\x12\x16\n" +
 	"\x06sender\x18\x03 \x01(\tR\x06sender\x12\x18\n" +
 	"\acontent\x18\x04 \x01(\tR\acontent\x127\n" +
-	"\vattachments\x18\x05 \x03(\v2\x15.coven.FileAttachmentR\vattachments\"]\n" +
+	"\vattachments\x18\x05 \x03(\v2\x15.coven.FileAttachmentR\vattachments\x12\x1c\n" +
+	"\tworkspace\x18\x06 \x01(\tR\tworkspace\x12'\n" +
+	"\x0fchannel_context\x18\a \x01(\tR\x0echannelContext\x12I\n" +
+	"\rtrace_context\x18\b \x03(\v2$.coven.SendMessage.TraceContextEntryR\ftraceContext\x1a?\n" +
+	"\x11TraceContextEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xc4\x01\n" +
+	"\rResumeRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x1b\n" +
+	"\tthread_id\x18\x02 \x01(\tR\bthreadId\x12\x16\n" +
+	"\x06sender\x18\x03 \x01(\tR\x06sender\x12\x18\n" +
+	"\acontent\x18\x04 \x01(\tR\acontent\x12\x1c\n" +
+	"\tworkspace\x18\x05 \x01(\tR\tworkspace\x12'\n" +
+	"\x0fchannel_context\x18\x06 \x01(\tR\x0echannelContext\"\x82\x01\n" +
 	"\x0eFileAttachment\x12\x1a\n" +
 	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x1b\n" +
 	"\tmime_type\x18\x02 \x01(\tR\bmimeType\x12\x12\n" +
-	"\x04data\x18\x03 \x01(\fR\x04data\"\"\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12#\n" +
+	"\rattachment_id\x18\x04 \x01(\tR\fattachmentId\"w\n" +
+	"\fToolsChanged\x12'\n" +
+	"\x0fcatalog_version\x18\x01 \x01(\x03R\x0ecatalogVersion\x12>\n" +
+	"\x0favailable_tools\x18\x02 \x03(\v2\x15.coven.ToolDefinitionR\x0eavailableTools\"v\n" +
+	"\x10CapabilityUpdate\x12\"\n" +
+	"\fcapabilities\x18\x01 \x03(\tR\fcapabilities\x12>\n" +
+	"\x0favailable_tools\x18\x02 \x03(\v2\x15.coven.ToolDefinitionR\x0eavailableTools\"\"\n" +
 	"\bShutdown\x12\x16\n" +
-	"\x06reason\x18\x01 \x01(\tR\x06reason\"\xc1\x01\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\"!\n" +
+	"\aGoodbye\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\"a\n" +
+	"\fHeartbeatAck\x12!\n" +
+	"\ftimestamp_ms\x18\x01 \x01(\x03R\vtimestampMs\x12.\n" +
+	"\x13server_timestamp_ms\x18\x02 \x01(\x03R\x11serverTimestampMs\"\xc9\x03\n" +
 	"\aBinding\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
 	"\bfrontend\x18\x02 \x01(\tR\bfrontend\x12\x1d\n" +
@@ -5510,34 +6912,54 @@ const file_coven_proto_rawDesc = "" +
 	"\n" +
 	"created_at\x18\x05 \x01(\tR\tcreatedAt\x12\"\n" +
 	"\n" +
-	"created_by\x18\x06 \x01(\tH\x00R\tcreatedBy\x88\x01\x01B\r\n" +
-	"\v_created_by\"p\n" +
+	"created_by\x18\x06 \x01(\tH\x00R\tcreatedBy\x88\x01\x01\x125\n" +
+	"\x14max_response_seconds\x18\a \x01(\x05H\x01R\x12maxResponseSeconds\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"agent_name\x18\b \x01(\tR\tagentName\x12!\n" +
+	"\fagent_status\x18\t \x01(\tR\vagentStatus\x12,\n" +
+	"\x12fallback_agent_ids\x18\n" +
+	" \x03(\tR\x10fallbackAgentIds\x12,\n" +
+	"\x12effective_agent_id\x18\v \x01(\tR\x10effectiveAgentId\x12\x18\n" +
+	"\acontext\x18\f \x01(\tR\acontextB\r\n" +
+	"\v_created_byB\x17\n" +
+	"\x15_max_response_seconds\"p\n" +
 	"\x13ListBindingsRequest\x12\x1f\n" +
 	"\bfrontend\x18\x01 \x01(\tH\x00R\bfrontend\x88\x01\x01\x12\x1e\n" +
 	"\bagent_id\x18\x02 \x01(\tH\x01R\aagentId\x88\x01\x01B\v\n" +
 	"\t_frontendB\v\n" +
 	"\t_agent_id\"B\n" +
 	"\x14ListBindingsResponse\x12*\n" +
-	"\bbindings\x18\x01 \x03(\v2\x0e.coven.BindingR\bbindings\"l\n" +
+	"\bbindings\x18\x01 \x03(\v2\x0e.coven.BindingR\bbindings\"\x84\x02\n" +
 	"\x14CreateBindingRequest\x12\x1a\n" +
 	"\bfrontend\x18\x01 \x01(\tR\bfrontend\x12\x1d\n" +
 	"\n" +
 	"channel_id\x18\x02 \x01(\tR\tchannelId\x12\x19\n" +
-	"\bagent_id\x18\x03 \x01(\tR\aagentId\"A\n" +
+	"\bagent_id\x18\x03 \x01(\tR\aagentId\x125\n" +
+	"\x14max_response_seconds\x18\x04 \x01(\x05H\x00R\x12maxResponseSeconds\x88\x01\x01\x12,\n" +
+	"\x12fallback_agent_ids\x18\x05 \x03(\tR\x10fallbackAgentIds\x12\x18\n" +
+	"\acontext\x18\x06 \x01(\tR\acontextB\x17\n" +
+	"\x15_max_response_seconds\"\xbc\x01\n" +
 	"\x14UpdateBindingRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x19\n" +
-	"\bagent_id\x18\x02 \x01(\tR\aagentId\"&\n" +
+	"\bagent_id\x18\x02 \x01(\tR\aagentId\x125\n" +
+	"\x14max_response_seconds\x18\x03 \x01(\x05H\x00R\x12maxResponseSeconds\x88\x01\x01\x12\x1d\n" +
+	"\acontext\x18\x04 \x01(\tH\x01R\acontext\x88\x01\x01B\x17\n" +
+	"\x15_max_response_secondsB\n" +
+	"\n" +
+	"\b_context\"&\n" +
 	"\x14DeleteBindingRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\"\x17\n" +
-	"\x15DeleteBindingResponse\"X\n" +
+	"\x15DeleteBindingResponse\"\xa6\x01\n" +
 	"\x12CreateTokenRequest\x12!\n" +
 	"\fprincipal_id\x18\x01 \x01(\tR\vprincipalId\x12\x1f\n" +
 	"\vttl_seconds\x18\x02 \x01(\x03R\n" +
-	"ttlSeconds\"J\n" +
+	"ttlSeconds\x12\x1b\n" +
+	"\tagent_ids\x18\x03 \x03(\tR\bagentIds\x12/\n" +
+	"\x13capabilities_subset\x18\x04 \x03(\tR\x12capabilitiesSubset\"J\n" +
 	"\x13CreateTokenResponse\x12\x14\n" +
 	"\x05token\x18\x01 \x01(\tR\x05token\x12\x1d\n" +
 	"\n" +
-	"expires_at\x18\x02 \x01(\tR\texpiresAt\"\xcf\x01\n" +
+	"expires_at\x18\x02 \x01(\tR\texpiresAt\"\xb8\x02\n" +
 	"\tPrincipal\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\x12!\n" +
@@ -5546,7 +6968,11 @@ const file_coven_proto_rawDesc = "" +
 	"\tpubkey_fp\x18\x05 \x01(\tH\x00R\bpubkeyFp\x88\x01\x01\x12\x1d\n" +
 	"\n" +
 	"created_at\x18\x06 \x01(\tR\tcreatedAt\x12\x14\n" +
-	"\x05roles\x18\a \x03(\tR\x05rolesB\f\n" +
+	"\x05roles\x18\a \x03(\tR\x05roles\x12.\n" +
+	"\x04tags\x18\b \x03(\v2\x1a.coven.Principal.TagsEntryR\x04tags\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\f\n" +
 	"\n" +
 	"_pubkey_fp\"a\n" +
 	"\x15ListPrincipalsRequest\x12\x17\n" +
@@ -5569,15 +6995,17 @@ const file_coven_proto_rawDesc = "" +
 	"_pubkey_fp\"(\n" +
 	"\x16DeletePrincipalRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\"\x19\n" +
-	"\x17DeletePrincipalResponse\"\xa5\x01\n" +
+	"\x17DeletePrincipalResponse\"\xe7\x01\n" +
 	"\x15AnswerQuestionRequest\x12\x19\n" +
 	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12\x1f\n" +
 	"\vquestion_id\x18\x02 \x01(\tR\n" +
 	"questionId\x12\x1a\n" +
 	"\bselected\x18\x03 \x03(\tR\bselected\x12$\n" +
 	"\vcustom_text\x18\x04 \x01(\tH\x00R\n" +
-	"customText\x88\x01\x01B\x0e\n" +
-	"\f_custom_text\"W\n" +
+	"customText\x88\x01\x01\x12,\n" +
+	"\x0fcontext_version\x18\x05 \x01(\tH\x01R\x0econtextVersion\x88\x01\x01B\x0e\n" +
+	"\f_custom_textB\x12\n" +
+	"\x10_context_version\"W\n" +
 	"\x16AnswerQuestionResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x19\n" +
 	"\x05error\x18\x02 \x01(\tH\x00R\x05error\x88\x01\x01B\b\n" +
@@ -5613,7 +7041,7 @@ const file_coven_proto_rawDesc = "" +
 	"\x05event\x18\v \x01(\v2\f.coven.EventH\x00R\x05event\x12G\n" +
 	"\rtool_approval\x18\f \x01(\v2 .coven.ClientToolApprovalRequestH\x00R\ftoolApproval\x12A\n" +
 	"\ruser_question\x18\r \x01(\v2\x1a.coven.UserQuestionRequestH\x00R\fuserQuestionB\t\n" +
-	"\apayload\"\x92\x02\n" +
+	"\apayload\"\xea\x03\n" +
 	"\x13UserQuestionRequest\x12\x19\n" +
 	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12\x1f\n" +
 	"\vquestion_id\x18\x02 \x01(\tR\n" +
@@ -5622,8 +7050,19 @@ const file_coven_proto_rawDesc = "" +
 	"\aoptions\x18\x04 \x03(\v2\x15.coven.QuestionOptionR\aoptions\x12!\n" +
 	"\fmulti_select\x18\x05 \x01(\bR\vmultiSelect\x12\x1b\n" +
 	"\x06header\x18\x06 \x01(\tH\x00R\x06header\x88\x01\x01\x12'\n" +
-	"\x0ftimeout_seconds\x18\a \x01(\x05R\x0etimeoutSecondsB\t\n" +
-	"\a_header\"]\n" +
+	"\x0ftimeout_seconds\x18\a \x01(\x05R\x0etimeoutSeconds\x12&\n" +
+	"\fcontext_json\x18\b \x01(\tH\x01R\vcontextJson\x88\x01\x01\x12+\n" +
+	"\x0fallow_free_text\x18\t \x01(\bH\x02R\rallowFreeText\x88\x01\x01\x12\x1d\n" +
+	"\apattern\x18\n" +
+	" \x01(\tH\x03R\apattern\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"max_length\x18\v \x01(\x05H\x04R\tmaxLength\x88\x01\x01B\t\n" +
+	"\a_headerB\x0f\n" +
+	"\r_context_jsonB\x12\n" +
+	"\x10_allow_free_textB\n" +
+	"\n" +
+	"\b_patternB\r\n" +
+	"\v_max_length\"]\n" +
 	"\x0eQuestionOption\x12\x14\n" +
 	"\x05label\x18\x01 \x01(\tR\x05label\x12%\n" +
 	"\vdescription\x18\x02 \x01(\tH\x00R\vdescription\x88\x01\x01B\x0e\n" +
@@ -5678,11 +7117,16 @@ const file_coven_proto_rawDesc = "" +
 	"\x10conversation_key\x18\x01 \x01(\tR\x0fconversationKey\x12\x18\n" +
 	"\acontent\x18\x02 \x01(\tR\acontent\x127\n" +
 	"\vattachments\x18\x03 \x03(\v2\x15.coven.FileAttachmentR\vattachments\x12'\n" +
-	"\x0fidempotency_key\x18\x04 \x01(\tR\x0eidempotencyKey\"R\n" +
+	"\x0fidempotency_key\x18\x04 \x01(\tR\x0eidempotencyKey\"\xad\x01\n" +
 	"\x19ClientSendMessageResponse\x12\x16\n" +
 	"\x06status\x18\x01 \x01(\tR\x06status\x12\x1d\n" +
 	"\n" +
-	"message_id\x18\x02 \x01(\tR\tmessageId\"\xa4\x02\n" +
+	"message_id\x18\x02 \x01(\tR\tmessageId\x12\x19\n" +
+	"\bagent_id\x18\x03 \x01(\tR\aagentId\x12\x1d\n" +
+	"\n" +
+	"agent_name\x18\x04 \x01(\tR\tagentName\x12\x1f\n" +
+	"\vinstance_id\x18\x05 \x01(\tR\n" +
+	"instanceId\"\xa4\x02\n" +
 	"\n" +
 	"MeResponse\x12!\n" +
 	"\fprincipal_id\x18\x01 \x01(\tR\vprincipalId\x12%\n" +
@@ -5728,13 +7172,15 @@ const file_coven_proto_rawDesc = "" +
 	"\vnext_cursor\x18\x02 \x01(\tH\x00R\n" +
 	"nextCursor\x88\x01\x01\x12\x19\n" +
 	"\bhas_more\x18\x03 \x01(\bR\ahasMoreB\x0e\n" +
-	"\f_next_cursor\"\xd0\x01\n" +
+	"\f_next_cursor\"\x9a\x02\n" +
 	"\x0eToolDefinition\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
 	"\vdescription\x18\x02 \x01(\tR\vdescription\x12*\n" +
 	"\x11input_schema_json\x18\x03 \x01(\tR\x0finputSchemaJson\x123\n" +
 	"\x15required_capabilities\x18\x04 \x03(\tR\x14requiredCapabilities\x12'\n" +
-	"\x0ftimeout_seconds\x18\x05 \x01(\x05R\x0etimeoutSeconds\"n\n" +
+	"\x0ftimeout_seconds\x18\x05 \x01(\x05R\x0etimeoutSeconds\x12\x1c\n" +
+	"\tcacheable\x18\x06 \x01(\bR\tcacheable\x12*\n" +
+	"\x11cache_ttl_seconds\x18\a \x01(\x05R\x0fcacheTtlSeconds\"n\n" +
 	"\fPackManifest\x12\x17\n" +
 	"\apack_id\x18\x01 \x01(\tR\x06packId\x12\x18\n" +
 	"\aversion\x18\x02 \x01(\tR\aversion\x12+\n" +
@@ -5744,17 +7190,24 @@ const file_coven_proto_rawDesc = "" +
 	"\n" +
 	"input_json\x18\x02 \x01(\tR\tinputJson\x12\x1d\n" +
 	"\n" +
-	"request_id\x18\x03 \x01(\tR\trequestId\"y\n" +
+	"request_id\x18\x03 \x01(\tR\trequestId\"\xc1\x01\n" +
 	"\x13ExecuteToolResponse\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12!\n" +
 	"\voutput_json\x18\x02 \x01(\tH\x00R\n" +
 	"outputJson\x12\x16\n" +
-	"\x05error\x18\x03 \x01(\tH\x00R\x05errorB\b\n" +
-	"\x06result\"M\n" +
+	"\x05error\x18\x03 \x01(\tH\x00R\x05error\x12.\n" +
+	"\x05chunk\x18\x04 \x01(\v2\x16.coven.ToolResultChunkH\x00R\x05chunk\x12\x16\n" +
+	"\x06cached\x18\x05 \x01(\bR\x06cachedB\b\n" +
+	"\x06result\"E\n" +
+	"\x0fToolResultChunk\x12\x1a\n" +
+	"\bsequence\x18\x01 \x01(\x04R\bsequence\x12\x16\n" +
+	"\x06output\x18\x02 \x01(\tR\x06output\"M\n" +
 	"\vPackWelcome\x12\x17\n" +
 	"\apack_id\x18\x01 \x01(\tR\x06packId\x12%\n" +
-	"\x0erejected_tools\x18\x02 \x03(\tR\rrejectedTools\"=\n" +
+	"\x0erejected_tools\x18\x02 \x03(\tR\rrejectedTools\"(\n" +
+	"\rPackHeartbeat\x12\x17\n" +
+	"\apack_id\x18\x01 \x01(\tR\x06packId\"=\n" +
 	"\x0eAvailableTools\x12+\n" +
 	"\x05tools\x18\x01 \x03(\v2\x15.coven.ToolDefinitionR\x05tools*\xf3\x01\n" +
 	"\tToolState\x12\x1a\n" +
@@ -5766,12 +7219,24 @@ const file_coven_proto_rawDesc = "" +
 	"\x11TOOL_STATE_FAILED\x10\x05\x12\x15\n" +
 	"\x11TOOL_STATE_DENIED\x10\x06\x12\x16\n" +
 	"\x12TOOL_STATE_TIMEOUT\x10\a\x12\x18\n" +
-	"\x14TOOL_STATE_CANCELLED\x10\b*\x99\x01\n" +
+	"\x14TOOL_STATE_CANCELLED\x10\b*\xcd\x01\n" +
+	"\x0ePlanStepStatus\x12 \n" +
+	"\x1cPLAN_STEP_STATUS_UNSPECIFIED\x10\x00\x12\x1c\n" +
+	"\x18PLAN_STEP_STATUS_PENDING\x10\x01\x12 \n" +
+	"\x1cPLAN_STEP_STATUS_IN_PROGRESS\x10\x02\x12\x1e\n" +
+	"\x1aPLAN_STEP_STATUS_COMPLETED\x10\x03\x12\x1c\n" +
+	"\x18PLAN_STEP_STATUS_SKIPPED\x10\x04\x12\x1b\n" +
+	"\x17PLAN_STEP_STATUS_FAILED\x10\x05*\x99\x01\n" +
 	"\x11InjectionPriority\x12\"\n" +
 	"\x1eINJECTION_PRIORITY_UNSPECIFIED\x10\x00\x12 \n" +
 	"\x1cINJECTION_PRIORITY_IMMEDIATE\x10\x01\x12\x1d\n" +
 	"\x19INJECTION_PRIORITY_NORMAL\x10\x02\x12\x1f\n" +
-	"\x1bINJECTION_PRIORITY_DEFERRED\x10\x032L\n" +
+	"\x1bINJECTION_PRIORITY_DEFERRED\x10\x03*\x98\x01\n" +
+	"\x11RegistrationState\x12\"\n" +
+	"\x1eREGISTRATION_STATE_UNSPECIFIED\x10\x00\x12\x1e\n" +
+	"\x1aREGISTRATION_STATE_PENDING\x10\x01\x12\x1f\n" +
+	"\x1bREGISTRATION_STATE_APPROVED\x10\x02\x12\x1e\n" +
+	"\x1aREGISTRATION_STATE_REVOKED\x10\x032L\n" +
 	"\fCovenControl\x12<\n" +
 	"\vAgentStream\x12\x13.coven.AgentMessage\x1a\x14.coven.ServerMessage(\x010\x012\xca\x04\n" +
 	"\fAdminService\x12G\n" +
@@ -5793,11 +7258,12 @@ const file_coven_proto_rawDesc = "" +
 	"\rRegisterAgent\x12\x1b.coven.RegisterAgentRequest\x1a\x1c.coven.RegisterAgentResponse\x12M\n" +
 	"\x0eRegisterClient\x12\x1c.coven.RegisterClientRequest\x1a\x1d.coven.RegisterClientResponse\x12D\n" +
 	"\vApproveTool\x12\x19.coven.ApproveToolRequest\x1a\x1a.coven.ApproveToolResponse\x12M\n" +
-	"\x0eAnswerQuestion\x12\x1c.coven.AnswerQuestionRequest\x1a\x1d.coven.AnswerQuestionResponse2\x8d\x01\n" +
+	"\x0eAnswerQuestion\x12\x1c.coven.AnswerQuestionRequest\x1a\x1d.coven.AnswerQuestionResponse2\xc8\x01\n" +
 	"\vPackService\x12<\n" +
 	"\bRegister\x12\x13.coven.PackManifest\x1a\x19.coven.ExecuteToolRequest0\x01\x12@\n" +
 	"\n" +
-	"ToolResult\x12\x1a.coven.ExecuteToolResponse\x1a\x16.google.protobuf.Emptyb\x06proto3"
+	"ToolResult\x12\x1a.coven.ExecuteToolResponse\x1a\x16.google.protobuf.Empty\x129\n" +
+	"\tHeartbeat\x12\x14.coven.PackHeartbeat\x1a\x16.google.protobuf.Emptyb\x06proto3"
 
 var (
 	file_coven_proto_rawDescOnce sync.Once
@@ -5811,185 +7277,225 @@ func file_coven_proto_rawDescGZIP() []byte {
 	return file_coven_proto_rawDescData
 }
 
-var file_coven_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_coven_proto_msgTypes = make([]protoimpl.MessageInfo, 76)
+var file_coven_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_coven_proto_msgTypes = make([]protoimpl.MessageInfo, 92)
 var file_coven_proto_goTypes = []any{
 	(ToolState)(0),                    // 0: coven.ToolState
-	(InjectionPriority)(0),            // 1: coven.InjectionPriority
-	(*AgentMessage)(nil),              // 2: coven.AgentMessage
-	(*GitInfo)(nil),                   // 3: coven.GitInfo
-	(*AgentMetadata)(nil),             // 4: coven.AgentMetadata
-	(*RegisterAgent)(nil),             // 5: coven.RegisterAgent
-	(*MessageResponse)(nil),           // 6: coven.MessageResponse
-	(*SessionInit)(nil),               // 7: coven.SessionInit
-	(*SessionOrphaned)(nil),           // 8: coven.SessionOrphaned
-	(*TokenUsage)(nil),                // 9: coven.TokenUsage
-	(*ToolStateUpdate)(nil),           // 10: coven.ToolStateUpdate
-	(*Cancelled)(nil),                 // 11: coven.Cancelled
-	(*InjectContext)(nil),             // 12: coven.InjectContext
-	(*InjectionAck)(nil),              // 13: coven.InjectionAck
-	(*CancelRequest)(nil),             // 14: coven.CancelRequest
-	(*ToolApprovalRequest)(nil),       // 15: coven.ToolApprovalRequest
-	(*ToolUse)(nil),                   // 16: coven.ToolUse
-	(*ToolResult)(nil),                // 17: coven.ToolResult
-	(*Done)(nil),                      // 18: coven.Done
-	(*FileData)(nil),                  // 19: coven.FileData
-	(*Heartbeat)(nil),                 // 20: coven.Heartbeat
-	(*ExecutePackTool)(nil),           // 21: coven.ExecutePackTool
-	(*PackToolResult)(nil),            // 22: coven.PackToolResult
-	(*ServerMessage)(nil),             // 23: coven.ServerMessage
-	(*RegistrationError)(nil),         // 24: coven.RegistrationError
-	(*ToolApprovalResponse)(nil),      // 25: coven.ToolApprovalResponse
-	(*Welcome)(nil),                   // 26: coven.Welcome
-	(*SendMessage)(nil),               // 27: coven.SendMessage
-	(*FileAttachment)(nil),            // 28: coven.FileAttachment
-	(*Shutdown)(nil),                  // 29: coven.Shutdown
-	(*Binding)(nil),                   // 30: coven.Binding
-	(*ListBindingsRequest)(nil),       // 31: coven.ListBindingsRequest
-	(*ListBindingsResponse)(nil),      // 32: coven.ListBindingsResponse
-	(*CreateBindingRequest)(nil),      // 33: coven.CreateBindingRequest
-	(*UpdateBindingRequest)(nil),      // 34: coven.UpdateBindingRequest
-	(*DeleteBindingRequest)(nil),      // 35: coven.DeleteBindingRequest
-	(*DeleteBindingResponse)(nil),     // 36: coven.DeleteBindingResponse
-	(*CreateTokenRequest)(nil),        // 37: coven.CreateTokenRequest
-	(*CreateTokenResponse)(nil),       // 38: coven.CreateTokenResponse
-	(*Principal)(nil),                 // 39: coven.Principal
-	(*ListPrincipalsRequest)(nil),     // 40: coven.ListPrincipalsRequest
-	(*ListPrincipalsResponse)(nil),    // 41: coven.ListPrincipalsResponse
-	(*CreatePrincipalRequest)(nil),    // 42: coven.CreatePrincipalRequest
-	(*DeletePrincipalRequest)(nil),    // 43: coven.DeletePrincipalRequest
-	(*DeletePrincipalResponse)(nil),   // 44: coven.DeletePrincipalResponse
-	(*AnswerQuestionRequest)(nil),     // 45: coven.AnswerQuestionRequest
-	(*AnswerQuestionResponse)(nil),    // 46: coven.AnswerQuestionResponse
-	(*ApproveToolRequest)(nil),        // 47: coven.ApproveToolRequest
-	(*ApproveToolResponse)(nil),       // 48: coven.ApproveToolResponse
-	(*StreamEventsRequest)(nil),       // 49: coven.StreamEventsRequest
-	(*ClientStreamEvent)(nil),         // 50: coven.ClientStreamEvent
-	(*UserQuestionRequest)(nil),       // 51: coven.UserQuestionRequest
-	(*QuestionOption)(nil),            // 52: coven.QuestionOption
-	(*ClientToolApprovalRequest)(nil), // 53: coven.ClientToolApprovalRequest
-	(*TextChunk)(nil),                 // 54: coven.TextChunk
-	(*ThinkingChunk)(nil),             // 55: coven.ThinkingChunk
-	(*StreamDone)(nil),                // 56: coven.StreamDone
-	(*StreamError)(nil),               // 57: coven.StreamError
-	(*AgentInfo)(nil),                 // 58: coven.AgentInfo
-	(*ListAgentsRequest)(nil),         // 59: coven.ListAgentsRequest
-	(*ListAgentsResponse)(nil),        // 60: coven.ListAgentsResponse
-	(*RegisterAgentRequest)(nil),      // 61: coven.RegisterAgentRequest
-	(*RegisterAgentResponse)(nil),     // 62: coven.RegisterAgentResponse
-	(*RegisterClientRequest)(nil),     // 63: coven.RegisterClientRequest
-	(*RegisterClientResponse)(nil),    // 64: coven.RegisterClientResponse
-	(*ClientSendMessageRequest)(nil),  // 65: coven.ClientSendMessageRequest
-	(*ClientSendMessageResponse)(nil), // 66: coven.ClientSendMessageResponse
-	(*MeResponse)(nil),                // 67: coven.MeResponse
-	(*Event)(nil),                     // 68: coven.Event
-	(*GetEventsRequest)(nil),          // 69: coven.GetEventsRequest
-	(*GetEventsResponse)(nil),         // 70: coven.GetEventsResponse
-	(*ToolDefinition)(nil),            // 71: coven.ToolDefinition
-	(*PackManifest)(nil),              // 72: coven.PackManifest
-	(*ExecuteToolRequest)(nil),        // 73: coven.ExecuteToolRequest
-	(*ExecuteToolResponse)(nil),       // 74: coven.ExecuteToolResponse
-	(*PackWelcome)(nil),               // 75: coven.PackWelcome
-	(*AvailableTools)(nil),            // 76: coven.AvailableTools
-	nil,                               // 77: coven.Welcome.SecretsEntry
-	(*emptypb.Empty)(nil),             // 78: google.protobuf.Empty
+	(PlanStepStatus)(0),               // 1: coven.PlanStepStatus
+	(InjectionPriority)(0),            // 2: coven.InjectionPriority
+	(RegistrationState)(0),            // 3: coven.RegistrationState
+	(*AgentMessage)(nil),              // 4: coven.AgentMessage
+	(*GitInfo)(nil),                   // 5: coven.GitInfo
+	(*AgentMetadata)(nil),             // 6: coven.AgentMetadata
+	(*RegisterAgent)(nil),             // 7: coven.RegisterAgent
+	(*MessageResponse)(nil),           // 8: coven.MessageResponse
+	(*SessionInit)(nil),               // 9: coven.SessionInit
+	(*SessionOrphaned)(nil),           // 10: coven.SessionOrphaned
+	(*TokenUsage)(nil),                // 11: coven.TokenUsage
+	(*ToolStateUpdate)(nil),           // 12: coven.ToolStateUpdate
+	(*PlanStep)(nil),                  // 13: coven.PlanStep
+	(*Plan)(nil),                      // 14: coven.Plan
+	(*PlanStepUpdate)(nil),            // 15: coven.PlanStepUpdate
+	(*Citation)(nil),                  // 16: coven.Citation
+	(*Cancelled)(nil),                 // 17: coven.Cancelled
+	(*InjectContext)(nil),             // 18: coven.InjectContext
+	(*InjectionAck)(nil),              // 19: coven.InjectionAck
+	(*CancelRequest)(nil),             // 20: coven.CancelRequest
+	(*ToolApprovalRequest)(nil),       // 21: coven.ToolApprovalRequest
+	(*ToolUse)(nil),                   // 22: coven.ToolUse
+	(*ToolResult)(nil),                // 23: coven.ToolResult
+	(*Done)(nil),                      // 24: coven.Done
+	(*FileData)(nil),                  // 25: coven.FileData
+	(*Heartbeat)(nil),                 // 26: coven.Heartbeat
+	(*ExecutePackTool)(nil),           // 27: coven.ExecutePackTool
+	(*PackToolResult)(nil),            // 28: coven.PackToolResult
+	(*ServerMessage)(nil),             // 29: coven.ServerMessage
+	(*RegistrationError)(nil),         // 30: coven.RegistrationError
+	(*RegistrationStatus)(nil),        // 31: coven.RegistrationStatus
+	(*ToolApprovalResponse)(nil),      // 32: coven.ToolApprovalResponse
+	(*Welcome)(nil),                   // 33: coven.Welcome
+	(*SendMessage)(nil),               // 34: coven.SendMessage
+	(*ResumeRequest)(nil),             // 35: coven.ResumeRequest
+	(*FileAttachment)(nil),            // 36: coven.FileAttachment
+	(*ToolsChanged)(nil),              // 37: coven.ToolsChanged
+	(*CapabilityUpdate)(nil),          // 38: coven.CapabilityUpdate
+	(*Shutdown)(nil),                  // 39: coven.Shutdown
+	(*Goodbye)(nil),                   // 40: coven.Goodbye
+	(*HeartbeatAck)(nil),              // 41: coven.HeartbeatAck
+	(*Binding)(nil),                   // 42: coven.Binding
+	(*ListBindingsRequest)(nil),       // 43: coven.ListBindingsRequest
+	(*ListBindingsResponse)(nil),      // 44: coven.ListBindingsResponse
+	(*CreateBindingRequest)(nil),      // 45: coven.CreateBindingRequest
+	(*UpdateBindingRequest)(nil),      // 46: coven.UpdateBindingRequest
+	(*DeleteBindingRequest)(nil),      // 47: coven.DeleteBindingRequest
+	(*DeleteBindingResponse)(nil),     // 48: coven.DeleteBindingResponse
+	(*CreateTokenRequest)(nil),        // 49: coven.CreateTokenRequest
+	(*CreateTokenResponse)(nil),       // 50: coven.CreateTokenResponse
+	(*Principal)(nil),                 // 51: coven.Principal
+	(*ListPrincipalsRequest)(nil),     // 52: coven.ListPrincipalsRequest
+	(*ListPrincipalsResponse)(nil),    // 53: coven.ListPrincipalsResponse
+	(*CreatePrincipalRequest)(nil),    // 54: coven.CreatePrincipalRequest
+	(*DeletePrincipalRequest)(nil),    // 55: coven.DeletePrincipalRequest
+	(*DeletePrincipalResponse)(nil),   // 56: coven.DeletePrincipalResponse
+	(*AnswerQuestionRequest)(nil),     // 57: coven.AnswerQuestionRequest
+	(*AnswerQuestionResponse)(nil),    // 58: coven.AnswerQuestionResponse
+	(*ApproveToolRequest)(nil),        // 59: coven.ApproveToolRequest
+	(*ApproveToolResponse)(nil),       // 60: coven.ApproveToolResponse
+	(*StreamEventsRequest)(nil),       // 61: coven.StreamEventsRequest
+	(*ClientStreamEvent)(nil),         // 62: coven.ClientStreamEvent
+	(*UserQuestionRequest)(nil),       // 63: coven.UserQuestionRequest
+	(*QuestionOption)(nil),            // 64: coven.QuestionOption
+	(*ClientToolApprovalRequest)(nil), // 65: coven.ClientToolApprovalRequest
+	(*TextChunk)(nil),                 // 66: coven.TextChunk
+	(*ThinkingChunk)(nil),             // 67: coven.ThinkingChunk
+	(*StreamDone)(nil),                // 68: coven.StreamDone
+	(*StreamError)(nil),               // 69: coven.StreamError
+	(*AgentInfo)(nil),                 // 70: coven.AgentInfo
+	(*ListAgentsRequest)(nil),         // 71: coven.ListAgentsRequest
+	(*ListAgentsResponse)(nil),        // 72: coven.ListAgentsResponse
+	(*RegisterAgentRequest)(nil),      // 73: coven.RegisterAgentRequest
+	(*RegisterAgentResponse)(nil),     // 74: coven.RegisterAgentResponse
+	(*RegisterClientRequest)(nil),     // 75: coven.RegisterClientRequest
+	(*RegisterClientResponse)(nil),    // 76: coven.RegisterClientResponse
+	(*ClientSendMessageRequest)(nil),  // 77: coven.ClientSendMessageRequest
+	(*ClientSendMessageResponse)(nil), // 78: coven.ClientSendMessageResponse
+	(*MeResponse)(nil),                // 79: coven.MeResponse
+	(*Event)(nil),                     // 80: coven.Event
+	(*GetEventsRequest)(nil),          // 81: coven.GetEventsRequest
+	(*GetEventsResponse)(nil),         // 82: coven.GetEventsResponse
+	(*ToolDefinition)(nil),            // 83: coven.ToolDefinition
+	(*PackManifest)(nil),              // 84: coven.PackManifest
+	(*ExecuteToolRequest)(nil),        // 85: coven.ExecuteToolRequest
+	(*ExecuteToolResponse)(nil),       // 86: coven.ExecuteToolResponse
+	(*ToolResultChunk)(nil),           // 87: coven.ToolResultChunk
+	(*PackWelcome)(nil),               // 88: coven.PackWelcome
+	(*PackHeartbeat)(nil),             // 89: coven.PackHeartbeat
+	(*AvailableTools)(nil),            // 90: coven.AvailableTools
+	nil,                               // 91: coven.AgentMetadata.TagsEntry
+	nil,                               // 92: coven.ExecutePackTool.TraceContextEntry
+	nil,                               // 93: coven.Welcome.SecretsEntry
+	nil,                               // 94: coven.SendMessage.TraceContextEntry
+	nil,                               // 95: coven.Principal.TagsEntry
+	(*emptypb.Empty)(nil),             // 96: google.protobuf.Empty
 }
 var file_coven_proto_depIdxs = []int32{
-	5,  // 0: coven.AgentMessage.register:type_name -> coven.RegisterAgent
-	6,  // 1: coven.AgentMessage.response:type_name -> coven.MessageResponse
-	20, // 2: coven.AgentMessage.heartbeat:type_name -> coven.Heartbeat
-	13, // 3: coven.AgentMessage.injection_ack:type_name -> coven.InjectionAck
-	21, // 4: coven.AgentMessage.execute_pack_tool:type_name -> coven.ExecutePackTool
-	3,  // 5: coven.AgentMetadata.git:type_name -> coven.GitInfo
-	4,  // 6: coven.RegisterAgent.metadata:type_name -> coven.AgentMetadata
-	16, // 7: coven.MessageResponse.tool_use:type_name -> coven.ToolUse
-	17, // 8: coven.MessageResponse.tool_result:type_name -> coven.ToolResult
-	18, // 9: coven.MessageResponse.done:type_name -> coven.Done
-	19, // 10: coven.MessageResponse.file:type_name -> coven.FileData
-	15, // 11: coven.MessageResponse.tool_approval_request:type_name -> coven.ToolApprovalRequest
-	7,  // 12: coven.MessageResponse.session_init:type_name -> coven.SessionInit
-	8,  // 13: coven.MessageResponse.session_orphaned:type_name -> coven.SessionOrphaned
-	9,  // 14: coven.MessageResponse.usage:type_name -> coven.TokenUsage
-	10, // 15: coven.MessageResponse.tool_state:type_name -> coven.ToolStateUpdate
-	11, // 16: coven.MessageResponse.cancelled:type_name -> coven.Cancelled
-	0,  // 17: coven.ToolStateUpdate.state:type_name -> coven.ToolState
-	1,  // 18: coven.InjectContext.priority:type_name -> coven.InjectionPriority
-	26, // 19: coven.ServerMessage.welcome:type_name -> coven.Welcome
-	27, // 20: coven.ServerMessage.send_message:type_name -> coven.SendMessage
-	29, // 21: coven.ServerMessage.shutdown:type_name -> coven.Shutdown
-	25, // 22: coven.ServerMessage.tool_approval:type_name -> coven.ToolApprovalResponse
-	24, // 23: coven.ServerMessage.registration_error:type_name -> coven.RegistrationError
-	12, // 24: coven.ServerMessage.inject_context:type_name -> coven.InjectContext
-	14, // 25: coven.ServerMessage.cancel_request:type_name -> coven.CancelRequest
-	22, // 26: coven.ServerMessage.pack_tool_result:type_name -> coven.PackToolResult
-	71, // 27: coven.Welcome.available_tools:type_name -> coven.ToolDefinition
-	77, // 28: coven.Welcome.secrets:type_name -> coven.Welcome.SecretsEntry
-	28, // 29: coven.SendMessage.attachments:type_name -> coven.FileAttachment
-	30, // 30: coven.ListBindingsResponse.bindings:type_name -> coven.Binding
-	39, // 31: coven.ListPrincipalsResponse.principals:type_name -> coven.Principal
-	54, // 32: coven.ClientStreamEvent.text:type_name -> coven.TextChunk
-	55, // 33: coven.ClientStreamEvent.thinking:type_name -> coven.ThinkingChunk
-	16, // 34: coven.ClientStreamEvent.tool_use:type_name -> coven.ToolUse
-	17, // 35: coven.ClientStreamEvent.tool_result:type_name -> coven.ToolResult
-	10, // 36: coven.ClientStreamEvent.tool_state:type_name -> coven.ToolStateUpdate
-	9,  // 37: coven.ClientStreamEvent.usage:type_name -> coven.TokenUsage
-	56, // 38: coven.ClientStreamEvent.done:type_name -> coven.StreamDone
-	57, // 39: coven.ClientStreamEvent.error:type_name -> coven.StreamError
-	68, // 40: coven.ClientStreamEvent.event:type_name -> coven.Event
-	53, // 41: coven.ClientStreamEvent.tool_approval:type_name -> coven.ClientToolApprovalRequest
-	51, // 42: coven.ClientStreamEvent.user_question:type_name -> coven.UserQuestionRequest
-	52, // 43: coven.UserQuestionRequest.options:type_name -> coven.QuestionOption
-	4,  // 44: coven.AgentInfo.metadata:type_name -> coven.AgentMetadata
-	58, // 45: coven.ListAgentsResponse.agents:type_name -> coven.AgentInfo
-	28, // 46: coven.ClientSendMessageRequest.attachments:type_name -> coven.FileAttachment
-	68, // 47: coven.GetEventsResponse.events:type_name -> coven.Event
-	71, // 48: coven.PackManifest.tools:type_name -> coven.ToolDefinition
-	71, // 49: coven.AvailableTools.tools:type_name -> coven.ToolDefinition
-	2,  // 50: coven.CovenControl.AgentStream:input_type -> coven.AgentMessage
-	31, // 51: coven.AdminService.ListBindings:input_type -> coven.ListBindingsRequest
-	33, // 52: coven.AdminService.CreateBinding:input_type -> coven.CreateBindingRequest
-	34, // 53: coven.AdminService.UpdateBinding:input_type -> coven.UpdateBindingRequest
-	35, // 54: coven.AdminService.DeleteBinding:input_type -> coven.DeleteBindingRequest
-	37, // 55: coven.AdminService.CreateToken:input_type -> coven.CreateTokenRequest
-	40, // 56: coven.AdminService.ListPrincipals:input_type -> coven.ListPrincipalsRequest
-	42, // 57: coven.AdminService.CreatePrincipal:input_type -> coven.CreatePrincipalRequest
-	43, // 58: coven.AdminService.DeletePrincipal:input_type -> coven.DeletePrincipalRequest
-	69, // 59: coven.ClientService.GetEvents:input_type -> coven.GetEventsRequest
-	78, // 60: coven.ClientService.GetMe:input_type -> google.protobuf.Empty
-	65, // 61: coven.ClientService.SendMessage:input_type -> coven.ClientSendMessageRequest
-	49, // 62: coven.ClientService.StreamEvents:input_type -> coven.StreamEventsRequest
-	59, // 63: coven.ClientService.ListAgents:input_type -> coven.ListAgentsRequest
-	61, // 64: coven.ClientService.RegisterAgent:input_type -> coven.RegisterAgentRequest
-	63, // 65: coven.ClientService.RegisterClient:input_type -> coven.RegisterClientRequest
-	47, // 66: coven.ClientService.ApproveTool:input_type -> coven.ApproveToolRequest
-	45, // 67: coven.ClientService.AnswerQuestion:input_type -> coven.AnswerQuestionRequest
-	72, // 68: coven.PackService.Register:input_type -> coven.PackManifest
-	74, // 69: coven.PackService.ToolResult:input_type -> coven.ExecuteToolResponse
-	23, // 70: coven.CovenControl.AgentStream:output_type -> coven.ServerMessage
-	32, // 71: coven.AdminService.ListBindings:output_type -> coven.ListBindingsResponse
-	30, // 72: coven.AdminService.CreateBinding:output_type -> coven.Binding
-	30, // 73: coven.AdminService.UpdateBinding:output_type -> coven.Binding
-	36, // 74: coven.AdminService.DeleteBinding:output_type -> coven.DeleteBindingResponse
-	38, // 75: coven.AdminService.CreateToken:output_type -> coven.CreateTokenResponse
-	41, // 76: coven.AdminService.ListPrincipals:output_type -> coven.ListPrincipalsResponse
-	39, // 77: coven.AdminService.CreatePrincipal:output_type -> coven.Principal
-	44, // 78: coven.AdminService.DeletePrincipal:output_type -> coven.DeletePrincipalResponse
-	70, // 79: coven.ClientService.GetEvents:output_type -> coven.GetEventsResponse
-	67, // 80: coven.ClientService.GetMe:output_type -> coven.MeResponse
-	66, // 81: coven.ClientService.SendMessage:output_type -> coven.ClientSendMessageResponse
-	50, // 82: coven.ClientService.StreamEvents:output_type -> coven.ClientStreamEvent
-	60, // 83: coven.ClientService.ListAgents:output_type -> coven.ListAgentsResponse
-	62, // 84: coven.ClientService.RegisterAgent:output_type -> coven.RegisterAgentResponse
-	64, // 85: coven.ClientService.RegisterClient:output_type -> coven.RegisterClientResponse
-	48, // 86: coven.ClientService.ApproveTool:output_type -> coven.ApproveToolResponse
-	46, // 87: coven.ClientService.AnswerQuestion:output_type -> coven.AnswerQuestionResponse
-	73, // 88: coven.PackService.Register:output_type -> coven.ExecuteToolRequest
-	78, // 89: coven.PackService.ToolResult:output_type -> google.protobuf.Empty
-	70, // [70:90] is the sub-list for method output_type
-	50, // [50:70] is the sub-list for method input_type
-	50, // [50:50] is the sub-list for extension type_name
-	50, // [50:50] is the sub-list for extension extendee
-	0,  // [0:50] is the sub-list for field type_name
+	7,  // 0: coven.AgentMessage.register:type_name -> coven.RegisterAgent
+	8,  // 1: coven.AgentMessage.response:type_name -> coven.MessageResponse
+	26, // 2: coven.AgentMessage.heartbeat:type_name -> coven.Heartbeat
+	19, // 3: coven.AgentMessage.injection_ack:type_name -> coven.InjectionAck
+	27, // 4: coven.AgentMessage.execute_pack_tool:type_name -> coven.ExecutePackTool
+	5,  // 5: coven.AgentMetadata.git:type_name -> coven.GitInfo
+	91, // 6: coven.AgentMetadata.tags:type_name -> coven.AgentMetadata.TagsEntry
+	6,  // 7: coven.RegisterAgent.metadata:type_name -> coven.AgentMetadata
+	22, // 8: coven.MessageResponse.tool_use:type_name -> coven.ToolUse
+	23, // 9: coven.MessageResponse.tool_result:type_name -> coven.ToolResult
+	24, // 10: coven.MessageResponse.done:type_name -> coven.Done
+	25, // 11: coven.MessageResponse.file:type_name -> coven.FileData
+	21, // 12: coven.MessageResponse.tool_approval_request:type_name -> coven.ToolApprovalRequest
+	9,  // 13: coven.MessageResponse.session_init:type_name -> coven.SessionInit
+	10, // 14: coven.MessageResponse.session_orphaned:type_name -> coven.SessionOrphaned
+	11, // 15: coven.MessageResponse.usage:type_name -> coven.TokenUsage
+	12, // 16: coven.MessageResponse.tool_state:type_name -> coven.ToolStateUpdate
+	17, // 17: coven.MessageResponse.cancelled:type_name -> coven.Cancelled
+	14, // 18: coven.MessageResponse.plan:type_name -> coven.Plan
+	15, // 19: coven.MessageResponse.plan_step_update:type_name -> coven.PlanStepUpdate
+	16, // 20: coven.MessageResponse.citation:type_name -> coven.Citation
+	0,  // 21: coven.ToolStateUpdate.state:type_name -> coven.ToolState
+	1,  // 22: coven.PlanStep.status:type_name -> coven.PlanStepStatus
+	13, // 23: coven.Plan.steps:type_name -> coven.PlanStep
+	1,  // 24: coven.PlanStepUpdate.status:type_name -> coven.PlanStepStatus
+	2,  // 25: coven.InjectContext.priority:type_name -> coven.InjectionPriority
+	92, // 26: coven.ExecutePackTool.trace_context:type_name -> coven.ExecutePackTool.TraceContextEntry
+	33, // 27: coven.ServerMessage.welcome:type_name -> coven.Welcome
+	34, // 28: coven.ServerMessage.send_message:type_name -> coven.SendMessage
+	39, // 29: coven.ServerMessage.shutdown:type_name -> coven.Shutdown
+	32, // 30: coven.ServerMessage.tool_approval:type_name -> coven.ToolApprovalResponse
+	30, // 31: coven.ServerMessage.registration_error:type_name -> coven.RegistrationError
+	18, // 32: coven.ServerMessage.inject_context:type_name -> coven.InjectContext
+	20, // 33: coven.ServerMessage.cancel_request:type_name -> coven.CancelRequest
+	28, // 34: coven.ServerMessage.pack_tool_result:type_name -> coven.PackToolResult
+	31, // 35: coven.ServerMessage.registration_status:type_name -> coven.RegistrationStatus
+	37, // 36: coven.ServerMessage.tools_changed:type_name -> coven.ToolsChanged
+	35, // 37: coven.ServerMessage.resume_request:type_name -> coven.ResumeRequest
+	40, // 38: coven.ServerMessage.goodbye:type_name -> coven.Goodbye
+	41, // 39: coven.ServerMessage.heartbeat_ack:type_name -> coven.HeartbeatAck
+	38, // 40: coven.ServerMessage.capability_update:type_name -> coven.CapabilityUpdate
+	3,  // 41: coven.RegistrationStatus.state:type_name -> coven.RegistrationState
+	83, // 42: coven.Welcome.available_tools:type_name -> coven.ToolDefinition
+	93, // 43: coven.Welcome.secrets:type_name -> coven.Welcome.SecretsEntry
+	36, // 44: coven.SendMessage.attachments:type_name -> coven.FileAttachment
+	94, // 45: coven.SendMessage.trace_context:type_name -> coven.SendMessage.TraceContextEntry
+	83, // 46: coven.ToolsChanged.available_tools:type_name -> coven.ToolDefinition
+	83, // 47: coven.CapabilityUpdate.available_tools:type_name -> coven.ToolDefinition
+	42, // 48: coven.ListBindingsResponse.bindings:type_name -> coven.Binding
+	95, // 49: coven.Principal.tags:type_name -> coven.Principal.TagsEntry
+	51, // 50: coven.ListPrincipalsResponse.principals:type_name -> coven.Principal
+	66, // 51: coven.ClientStreamEvent.text:type_name -> coven.TextChunk
+	67, // 52: coven.ClientStreamEvent.thinking:type_name -> coven.ThinkingChunk
+	22, // 53: coven.ClientStreamEvent.tool_use:type_name -> coven.ToolUse
+	23, // 54: coven.ClientStreamEvent.tool_result:type_name -> coven.ToolResult
+	12, // 55: coven.ClientStreamEvent.tool_state:type_name -> coven.ToolStateUpdate
+	11, // 56: coven.ClientStreamEvent.usage:type_name -> coven.TokenUsage
+	68, // 57: coven.ClientStreamEvent.done:type_name -> coven.StreamDone
+	69, // 58: coven.ClientStreamEvent.error:type_name -> coven.StreamError
+	80, // 59: coven.ClientStreamEvent.event:type_name -> coven.Event
+	65, // 60: coven.ClientStreamEvent.tool_approval:type_name -> coven.ClientToolApprovalRequest
+	63, // 61: coven.ClientStreamEvent.user_question:type_name -> coven.UserQuestionRequest
+	64, // 62: coven.UserQuestionRequest.options:type_name -> coven.QuestionOption
+	6,  // 63: coven.AgentInfo.metadata:type_name -> coven.AgentMetadata
+	70, // 64: coven.ListAgentsResponse.agents:type_name -> coven.AgentInfo
+	36, // 65: coven.ClientSendMessageRequest.attachments:type_name -> coven.FileAttachment
+	80, // 66: coven.GetEventsResponse.events:type_name -> coven.Event
+	83, // 67: coven.PackManifest.tools:type_name -> coven.ToolDefinition
+	87, // 68: coven.ExecuteToolResponse.chunk:type_name -> coven.ToolResultChunk
+	83, // 69: coven.AvailableTools.tools:type_name -> coven.ToolDefinition
+	4,  // 70: coven.CovenControl.AgentStream:input_type -> coven.AgentMessage
+	43, // 71: coven.AdminService.ListBindings:input_type -> coven.ListBindingsRequest
+	45, // 72: coven.AdminService.CreateBinding:input_type -> coven.CreateBindingRequest
+	46, // 73: coven.AdminService.UpdateBinding:input_type -> coven.UpdateBindingRequest
+	47, // 74: coven.AdminService.DeleteBinding:input_type -> coven.DeleteBindingRequest
+	49, // 75: coven.AdminService.CreateToken:input_type -> coven.CreateTokenRequest
+	52, // 76: coven.AdminService.ListPrincipals:input_type -> coven.ListPrincipalsRequest
+	54, // 77: coven.AdminService.CreatePrincipal:input_type -> coven.CreatePrincipalRequest
+	55, // 78: coven.AdminService.DeletePrincipal:input_type -> coven.DeletePrincipalRequest
+	81, // 79: coven.ClientService.GetEvents:input_type -> coven.GetEventsRequest
+	96, // 80: coven.ClientService.GetMe:input_type -> google.protobuf.Empty
+	77, // 81: coven.ClientService.SendMessage:input_type -> coven.ClientSendMessageRequest
+	61, // 82: coven.ClientService.StreamEvents:input_type -> coven.StreamEventsRequest
+	71, // 83: coven.ClientService.ListAgents:input_type -> coven.ListAgentsRequest
+	73, // 84: coven.ClientService.RegisterAgent:input_type -> coven.RegisterAgentRequest
+	75, // 85: coven.ClientService.RegisterClient:input_type -> coven.RegisterClientRequest
+	59, // 86: coven.ClientService.ApproveTool:input_type -> coven.ApproveToolRequest
+	57, // 87: coven.ClientService.AnswerQuestion:input_type -> coven.AnswerQuestionRequest
+	84, // 88: coven.PackService.Register:input_type -> coven.PackManifest
+	86, // 89: coven.PackService.ToolResult:input_type -> coven.ExecuteToolResponse
+	89, // 90: coven.PackService.Heartbeat:input_type -> coven.PackHeartbeat
+	29, // 91: coven.CovenControl.AgentStream:output_type -> coven.ServerMessage
+	44, // 92: coven.AdminService.ListBindings:output_type -> coven.ListBindingsResponse
+	42, // 93: coven.AdminService.CreateBinding:output_type -> coven.Binding
+	42, // 94: coven.AdminService.UpdateBinding:output_type -> coven.Binding
+	48, // 95: coven.AdminService.DeleteBinding:output_type -> coven.DeleteBindingResponse
+	50, // 96: coven.AdminService.CreateToken:output_type -> coven.CreateTokenResponse
+	53, // 97: coven.AdminService.ListPrincipals:output_type -> coven.ListPrincipalsResponse
+	51, // 98: coven.AdminService.CreatePrincipal:output_type -> coven.Principal
+	56, // 99: coven.AdminService.DeletePrincipal:output_type -> coven.DeletePrincipalResponse
+	82, // 100: coven.ClientService.GetEvents:output_type -> coven.GetEventsResponse
+	79, // 101: coven.ClientService.GetMe:output_type -> coven.MeResponse
+	78, // 102: coven.ClientService.SendMessage:output_type -> coven.ClientSendMessageResponse
+	62, // 103: coven.ClientService.StreamEvents:output_type -> coven.ClientStreamEvent
+	72, // 104: coven.ClientService.ListAgents:output_type -> coven.ListAgentsResponse
+	74, // 105: coven.ClientService.RegisterAgent:output_type -> coven.RegisterAgentResponse
+	76, // 106: coven.ClientService.RegisterClient:output_type -> coven.RegisterClientResponse
+	60, // 107: coven.ClientService.ApproveTool:output_type -> coven.ApproveToolResponse
+	58, // 108: coven.ClientService.AnswerQuestion:output_type -> coven.AnswerQuestionResponse
+	85, // 109: coven.PackService.Register:output_type -> coven.ExecuteToolRequest
+	96, // 110: coven.PackService.ToolResult:output_type -> google.protobuf.Empty
+	96, // 111: coven.PackService.Heartbeat:output_type -> google.protobuf.Empty
+	91, // [91:112] is the sub-list for method output_type
+	70, // [70:91] is the sub-list for method input_type
+	70, // [70:70] is the sub-list for extension type_name
+	70, // [70:70] is the sub-list for extension extendee
+	0,  // [0:70] is the sub-list for field type_name
 }
 
 func init() { file_coven_proto_init() }
@@ -6018,16 +7524,21 @@ func file_coven_proto_init() {
 		(*MessageResponse_Usage)(nil),
 		(*MessageResponse_ToolState)(nil),
 		(*MessageResponse_Cancelled)(nil),
+		(*MessageResponse_Plan)(nil),
+		(*MessageResponse_PlanStepUpdate)(nil),
+		(*MessageResponse_Citation)(nil),
 	}
 	file_coven_proto_msgTypes[8].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[10].OneofWrappers = []any{}
 	file_coven_proto_msgTypes[11].OneofWrappers = []any{}
 	file_coven_proto_msgTypes[12].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[20].OneofWrappers = []any{
+	file_coven_proto_msgTypes[14].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[15].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[16].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[24].OneofWrappers = []any{
 		(*PackToolResult_OutputJson)(nil),
 		(*PackToolResult_Error)(nil),
 	}
-	file_coven_proto_msgTypes[21].OneofWrappers = []any{
+	file_coven_proto_msgTypes[25].OneofWrappers = []any{
 		(*ServerMessage_Welcome)(nil),
 		(*ServerMessage_SendMessage)(nil),
 		(*ServerMessage_Shutdown)(nil),
@@ -6036,17 +7547,25 @@ func file_coven_proto_init() {
 		(*ServerMessage_InjectContext)(nil),
 		(*ServerMessage_CancelRequest)(nil),
 		(*ServerMessage_PackToolResult)(nil),
+		(*ServerMessage_RegistrationStatus)(nil),
+		(*ServerMessage_ToolsChanged)(nil),
+		(*ServerMessage_ResumeRequest)(nil),
+		(*ServerMessage_Goodbye)(nil),
+		(*ServerMessage_HeartbeatAck)(nil),
+		(*ServerMessage_CapabilityUpdate)(nil),
 	}
-	file_coven_proto_msgTypes[28].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[29].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[37].OneofWrappers = []any{}
 	file_coven_proto_msgTypes[38].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[40].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[43].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[44].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[46].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[39].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[41].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[42].OneofWrappers = []any{}
 	file_coven_proto_msgTypes[47].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[48].OneofWrappers = []any{
+	file_coven_proto_msgTypes[48].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[50].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[53].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[54].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[56].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[57].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[58].OneofWrappers = []any{
 		(*ClientStreamEvent_Text)(nil),
 		(*ClientStreamEvent_Thinking)(nil),
 		(*ClientStreamEvent_ToolUse)(nil),
@@ -6059,26 +7578,27 @@ func file_coven_proto_init() {
 		(*ClientStreamEvent_ToolApproval)(nil),
 		(*ClientStreamEvent_UserQuestion)(nil),
 	}
-	file_coven_proto_msgTypes[49].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[50].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[54].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[56].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[57].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[65].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[59].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[60].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[64].OneofWrappers = []any{}
 	file_coven_proto_msgTypes[66].OneofWrappers = []any{}
 	file_coven_proto_msgTypes[67].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[68].OneofWrappers = []any{}
-	file_coven_proto_msgTypes[72].OneofWrappers = []any{
+	file_coven_proto_msgTypes[75].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[76].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[77].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[78].OneofWrappers = []any{}
+	file_coven_proto_msgTypes[82].OneofWrappers = []any{
 		(*ExecuteToolResponse_OutputJson)(nil),
 		(*ExecuteToolResponse_Error)(nil),
+		(*ExecuteToolResponse_Chunk)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_coven_proto_rawDesc), len(file_coven_proto_rawDesc)),
-			NumEnums:      2,
-			NumMessages:   76,
+			NumEnums:      4,
+			NumMessages:   92,
 			NumExtensions: 0,
 			NumServices:   4,
 		},
@@ -140,7 +140,8 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 //
 // AdminService provides administrative operations for managing the gateway.
-// All methods require admin or owner role (enforced by RequireAdmin interceptor).
+// Members may call ListBindings and ListPrincipals; every other method needs
+// the admin or owner role, and agents may call none (RequireAdmin interceptor).
 type AdminServiceClient interface {
 	ListBindings(ctx context.Context, in *ListBindingsRequest, opts ...grpc.CallOption) (*ListBindingsResponse, error)
 	CreateBinding(ctx context.Context, in *CreateBindingRequest, opts ...grpc.CallOption) (*Binding, error)
@@ -247,7 +248,8 @@ func (c *adminServiceClient) DeletePrincipal(ctx context.Context, in *DeletePrin
 // for forward compatibility.
 //
 // AdminService provides administrative operations for managing the gateway.
-// All methods require admin or owner role (enforced by RequireAdmin interceptor).
+// Members may call ListBindings and ListPrincipals; every other method needs
+// the admin or owner role, and agents may call none (RequireAdmin interceptor).
 type AdminServiceServer interface {
 	ListBindings(context.Context, *ListBindingsRequest) (*ListBindingsResponse, error)
 	CreateBinding(context.Context, *CreateBindingRequest) (*Binding, error)
@@ -933,6 +935,7 @@ var ClientService_ServiceDesc = grpc.ServiceDesc{
 const (
 	PackService_Register_FullMethodName   = "/coven.PackService/Register"
 	PackService_ToolResult_FullMethodName = "/coven.PackService/ToolResult"
+	PackService_Heartbeat_FullMethodName  = "/coven.PackService/Heartbeat"
 )
 
 // PackServiceClient is the client API for PackService service.
@@ -945,6 +948,8 @@ type PackServiceClient interface {
 	Register(ctx context.Context, in *PackManifest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecuteToolRequest], error)
 	// Pack sends tool execution results back
 	ToolResult(ctx context.Context, in *ExecuteToolResponse, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Pack signals it is alive; a pack that stops heartbeating is marked unhealthy
+	Heartbeat(ctx context.Context, in *PackHeartbeat, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type packServiceClient struct {
@@ -984,6 +989,16 @@ func (c *packServiceClient) ToolResult(ctx context.Context, in *ExecuteToolRespo
 	return out, nil
 }
 
+func (c *packServiceClient) Heartbeat(ctx context.Context, in *PackHeartbeat, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, PackService_Heartbeat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PackServiceServer is the server API for PackService service.
 // All implementations must embed UnimplementedPackServiceServer
 // for forward compatibility.
@@ -994,6 +1009,8 @@ type PackServiceServer interface {
 	Register(*PackManifest, grpc.ServerStreamingServer[ExecuteToolRequest]) error
 	// Pack sends tool execution results back
 	ToolResult(context.Context, *ExecuteToolResponse) (*emptypb.Empty, error)
+	// Pack signals it is alive; a pack that stops heartbeating is marked unhealthy
+	Heartbeat(context.Context, *PackHeartbeat) (*emptypb.Empty, error)
 	mustEmbedUnimplementedPackServiceServer()
 }
 
@@ -1010,6 +1027,9 @@ func (UnimplementedPackServiceServer) Register(*PackManifest, grpc.ServerStreami
 func (UnimplementedPackServiceServer) ToolResult(context.Context, *ExecuteToolResponse) (*emptypb.Empty, error) {
 	return nil, status.Error(codes.Unimplemented, "method ToolResult not implemented")
 }
+func (UnimplementedPackServiceServer) Heartbeat(context.Context, *PackHeartbeat) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method Heartbeat not implemented")
+}
 func (UnimplementedPackServiceServer) mustEmbedUnimplementedPackServiceServer() {}
 func (UnimplementedPackServiceServer) testEmbeddedByValue()                     {}
 
@@ -1060,6 +1080,24 @@ func _PackService_ToolResult_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PackService_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PackHeartbeat)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PackServiceServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PackService_Heartbeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PackServiceServer).Heartbeat(ctx, req.(*PackHeartbeat))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // PackService_ServiceDesc is the grpc.ServiceDesc for PackService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -1071,6 +1109,10 @@ var PackService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ToolResult",
 			Handler:    _PackService_ToolResult_Handler,
 		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _PackService_Heartbeat_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -12,6 +12,8 @@ const registry: Record<string, () => Promise<{ default: any }>> = {
   'agent-detail-page': () => import('../lib/components/AgentDetailPage.svelte'),
   'agents-page': () => import('../lib/components/AgentsPage.svelte'),
   'board-page': () => import('../lib/components/BoardPage.svelte'),
+  'capabilities-page': () => import('../lib/components/CapabilitiesPage.svelte'),
+  'audit-page': () => import('../lib/components/AuditPage.svelte'),
   'chat-app': () => import('../lib/components/ChatApp.svelte'),
   'connection-badge': () => import('../lib/components/ConnectionBadge.svelte'),
   'dashboard-page': () => import('../lib/components/DashboardPage.svelte'),
@@ -21,11 +23,14 @@ const registry: Record<string, () => Promise<{ default: any }>> = {
   'logs-page': () => import('../lib/components/LogsPage.svelte'),
   'principals-page': () => import('../lib/components/PrincipalsPage.svelte'),
   'secrets-page': () => import('../lib/components/SecretsPage.svelte'),
+  'settings-page': () => import('../lib/components/SettingsPage.svelte'),
   'setup-complete': () => import('../lib/components/SetupComplete.svelte'),
   'setup-form': () => import('../lib/components/SetupForm.svelte'),
+  'storage-banner': () => import('../lib/components/StorageBanner.svelte'),
   'thread-detail-page': () => import('../lib/components/ThreadDetailPage.svelte'),
   'threads-page': () => import('../lib/components/ThreadsPage.svelte'),
   'todos-page': () => import('../lib/components/TodosPage.svelte'),
+  'tool-policies-page': () => import('../lib/components/ToolPoliciesPage.svelte'),
   'tools-page': () => import('../lib/components/ToolsPage.svelte'),
   'usage-page': () => import('../lib/components/UsagePage.svelte'),
 };
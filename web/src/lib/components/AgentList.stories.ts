@@ -2,23 +2,32 @@ import type { Meta, StoryObj } from '@storybook/svelte';
 import AgentList from './AgentList.svelte';
 
 const defaultAgents = [
-  { id: 'agent-1', name: 'Claude Agent', connected: true },
-  { id: 'agent-2', name: 'Code Assistant', connected: true },
+  { id: 'agent-1', name: 'Claude Agent', connected: true, tags: { env: 'prod', team: 'infra' } },
+  { id: 'agent-2', name: 'Code Assistant', connected: true, tags: { env: 'dev' } },
   { id: 'agent-3', name: 'Research Bot', connected: false },
 ];
 
+type MockAgent = { id: string; name: string; connected: boolean; tags?: Record<string, string> };
+
 /**
- * Mock fetch('/api/agents') for the lifetime of a story.
+ * Mock fetch('/api/agents') for the lifetime of a story, applying ?tag= filters.
  * Returns a cleanup function that restores the original fetch.
  */
-function mockAgentsFetch(agents: typeof defaultAgents) {
+function mockAgentsFetch(agents: MockAgent[]) {
   return () => {
     const originalFetch = globalThis.fetch;
     globalThis.fetch = (async (input: RequestInfo | URL, init?: RequestInit) => {
       const url =
         typeof input === 'string' ? input : input instanceof URL ? input.toString() : input.url;
-      if (url === '/api/agents') {
-        return new Response(JSON.stringify(agents), {
+      if (url.startsWith('/api/agents')) {
+        const want = new URLSearchParams(url.split('?')[1] ?? '').getAll('tag');
+        const matched = agents.filter((a) =>
+          want.every((t) => {
+            const [k, v] = t.split('=');
+            return a.tags?.[k] === v;
+          }),
+        );
+        return new Response(JSON.stringify(matched), {
           status: 200,
           headers: { 'Content-Type': 'application/json' },
         });
@@ -0,0 +1,75 @@
+import { render, screen, fireEvent, waitFor } from '@testing-library/svelte';
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import BoardPage from './BoardPage.svelte';
+
+const agentThread = {
+  ID: 't1',
+  AgentID: 'agent-1',
+  ThreadID: '',
+  Subject: 'Flaky build',
+  Content: 'CI keeps **timing out**',
+  AuthorKind: 'agent' as const,
+  CreatedAt: '2026-01-01T12:00:00Z',
+};
+
+function jsonResponse(body: unknown, ok = true) {
+  return { ok, json: () => Promise.resolve(body), text: () => Promise.resolve(String(body)) };
+}
+
+describe('BoardPage', () => {
+  afterEach(() => {
+    vi.unstubAllGlobals();
+  });
+
+  it('labels human posts with their author name', () => {
+    render(BoardPage, {
+      props: {
+        csrfToken: 'tok',
+        threads: [
+          { ...agentThread, ID: 't2', AgentID: 'human:alice', AuthorKind: 'human', AuthorName: 'alice (human)' },
+        ],
+      },
+    });
+    expect(screen.getByText('alice (human)')).toBeTruthy();
+    expect(screen.queryByText('human:alice')).toBeNull();
+  });
+
+  it('posts a new thread with the CSRF token', async () => {
+    const created = { ...agentThread, ID: 't3', AgentID: 'human:alice', Subject: 'Hello', Content: 'World', AuthorKind: 'human', AuthorName: 'alice (human)' };
+    const fetchMock = vi.fn().mockResolvedValue(jsonResponse(created));
+    vi.stubGlobal('fetch', fetchMock);
+
+    render(BoardPage, { props: { csrfToken: 'tok', threads: [] } });
+    await fireEvent.click(screen.getByText('New thread'));
+    await fireEvent.input(screen.getByLabelText('Subject'), { target: { value: 'Hello' } });
+    await fireEvent.input(screen.getByLabelText('Content'), { target: { value: 'World' } });
+    await fireEvent.click(screen.getByText('Post thread'));
+
+    await waitFor(() => expect(screen.getByText('Hello')).toBeTruthy());
+    const [url, init] = fetchMock.mock.calls[0];
+    expect(url).toBe('/api/admin/board');
+    expect(init.headers['X-CSRF-Token']).toBe('tok');
+    expect(JSON.parse(init.body)).toEqual({ subject: 'Hello', content: 'World' });
+  });
+
+  it('renders markdown and replies with author notification', async () => {
+    const reply = { ...agentThread, ID: 'r1', ThreadID: 't1', AgentID: 'human:alice', Content: 'On it', AuthorKind: 'human', AuthorName: 'alice (human)' };
+    const fetchMock = vi
+      .fn()
+      .mockResolvedValueOnce(jsonResponse({ post: agentThread, replies: [] }))
+      .mockResolvedValueOnce(jsonResponse({ post: reply, notified: true }));
+    vi.stubGlobal('fetch', fetchMock);
+
+    render(BoardPage, { props: { csrfToken: 'tok', threads: [agentThread] } });
+    await fireEvent.click(screen.getByText('Flaky build'));
+    await waitFor(() => expect(screen.getByText('timing out').tagName).toBe('STRONG'));
+
+    await fireEvent.input(screen.getByLabelText('Reply'), { target: { value: 'On it' } });
+    await fireEvent.click(screen.getByRole('button', { name: 'Reply' }));
+
+    await waitFor(() => expect(screen.getAllByTestId('board-reply')).toHaveLength(1));
+    const [url, init] = fetchMock.mock.calls[1];
+    expect(url).toBe('/api/admin/board/t1/replies');
+    expect(JSON.parse(init.body)).toEqual({ content: 'On it', notify: true });
+  });
+});
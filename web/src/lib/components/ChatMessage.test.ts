@@ -113,4 +113,75 @@ describe('ChatMessage', () => {
     expect(el.textContent).toContain('Canceled');
     expect(el.textContent).not.toContain(':');
   });
+
+  it('renders truncated marker with the summary', () => {
+    render(ChatMessage, {
+      props: {
+        message: msg({
+          type: 'truncated',
+          reason: 'max_response_duration',
+          content: 'Response truncated after 31m0s: exceeded max response duration of 30m0s',
+        }),
+      },
+    });
+    const el = screen.getByTestId('chat-message');
+    expect(el.getAttribute('data-message-type')).toBe('truncated');
+    expect(el.className).toContain('justify-center');
+    expect(el.textContent).toContain('exceeded max response duration');
+  });
+
+  it('renders plan card with step statuses', () => {
+    render(ChatMessage, {
+      props: {
+        message: msg({
+          type: 'plan',
+          content: 'Plan:\n1. check logs\n2. restart service',
+          steps: [
+            { title: 'check logs', status: 'completed' },
+            { title: 'restart service', status: 'pending' },
+          ],
+        }),
+      },
+    });
+    expect(screen.getByTestId('chat-message').getAttribute('data-message-type')).toBe('plan');
+    const steps = screen.getAllByTestId('plan-step');
+    expect(steps).toHaveLength(2);
+    expect(steps[0].getAttribute('data-status')).toBe('completed');
+    expect(screen.getByTestId('plan-toggle').textContent).toContain('1/2');
+  });
+
+  it('renders plan text when loaded from history', () => {
+    render(ChatMessage, {
+      props: { message: msg({ type: 'plan', content: 'Plan:\n1. check logs' }) },
+    });
+    expect(screen.getByTestId('plan-card').textContent).toContain('1. check logs');
+  });
+
+  it('links citation markers and lists sources', () => {
+    render(ChatMessage, {
+      props: {
+        message: msg({
+          type: 'text',
+          content: 'Slices share arrays [1] and grow [2]; see [3].',
+          sources: [
+            { index: 1, title: 'Go spec', uri: 'https://go.dev/ref/spec' },
+            { index: 2, title: '', uri: 'https://go.dev/blog/slices-intro', snippet: 'A slice is a descriptor' },
+          ],
+        }),
+      },
+    });
+    const el = screen.getByTestId('chat-message');
+    const links = el.querySelectorAll('.chat-message-content a');
+    expect(links).toHaveLength(2);
+    expect(links[0].getAttribute('href')).toBe('https://go.dev/ref/spec');
+    expect(links[0].textContent).toBe('[1]');
+    // Markers without a matching source stay plain text
+    expect(el.textContent).toContain('[3]');
+
+    const sources = screen.getAllByTestId('chat-source');
+    expect(sources).toHaveLength(2);
+    expect(sources[0].textContent).toContain('Go spec');
+    expect(sources[1].textContent).toContain('https://go.dev/blog/slices-intro');
+    expect(sources[1].textContent).toContain('A slice is a descriptor');
+  });
 });
@@ -68,6 +68,13 @@ export const WithError: Story = {
   },
 };
 
+export const WithSSO: Story = {
+  args: {
+    csrfToken: 'abc123def456',
+    sso: true,
+  },
+};
+
 export const PasskeyUnsupported: Story = {
   args: {
     csrfToken: 'abc123def456',
@@ -43,6 +43,15 @@ describe('LoginForm', () => {
     expect(btn).toBeTruthy();
   });
 
+  it('shows SSO button only when sso is enabled', () => {
+    const { unmount } = render(LoginForm, { props: { csrfToken: 'tok123' } });
+    expect(screen.queryByTestId('sso-button')).toBeNull();
+    unmount();
+
+    render(LoginForm, { props: { csrfToken: 'tok123', sso: true } });
+    expect(screen.getByTestId('sso-button').textContent).toContain('Sign in with SSO');
+  });
+
   it('username and password inputs have required attribute', () => {
     render(LoginForm, { props: { csrfToken: 'tok123' } });
     const username = screen.getByLabelText('Username') as HTMLInputElement;
@@ -0,0 +1,44 @@
+import { render, screen, waitFor } from '@testing-library/svelte';
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import PendingQuestionsBadge from './PendingQuestionsBadge.svelte';
+
+function mockQuestions(body: unknown, ok = true) {
+  vi.stubGlobal(
+    'fetch',
+    vi.fn().mockResolvedValue({ ok, json: () => Promise.resolve(body) }),
+  );
+}
+
+describe('PendingQuestionsBadge', () => {
+  afterEach(() => {
+    vi.unstubAllGlobals();
+  });
+
+  it('renders nothing when no questions are pending', async () => {
+    mockQuestions({ questions: [] });
+    render(PendingQuestionsBadge);
+    await waitFor(() => expect(fetch).toHaveBeenCalledWith('/api/admin/questions'));
+    expect(screen.queryByTestId('pending-questions-badge')).toBeNull();
+  });
+
+  it('shows the pending question count', async () => {
+    mockQuestions({ questions: [{ question_id: 'q-1' }, { question_id: 'q-2' }] });
+    render(PendingQuestionsBadge);
+    await waitFor(() => expect(screen.getByTestId('pending-questions-badge')).toBeTruthy());
+    expect(screen.getByText('2 questions pending')).toBeTruthy();
+    expect(screen.getByTestId('pending-questions-badge').getAttribute('href')).toBe('/');
+  });
+
+  it('uses the singular for one question', async () => {
+    mockQuestions({ questions: [{ question_id: 'q-1' }] });
+    render(PendingQuestionsBadge);
+    await waitFor(() => expect(screen.getByText('1 question pending')).toBeTruthy());
+  });
+
+  it('stays hidden when the request fails', async () => {
+    mockQuestions({}, false);
+    render(PendingQuestionsBadge);
+    await waitFor(() => expect(fetch).toHaveBeenCalled());
+    expect(screen.queryByTestId('pending-questions-badge')).toBeNull();
+  });
+});
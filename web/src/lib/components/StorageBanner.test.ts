@@ -0,0 +1,59 @@
+import { render, screen, waitFor } from '@testing-library/svelte';
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import StorageBanner from './StorageBanner.svelte';
+
+function mockStatus(body: unknown, ok = true) {
+  vi.stubGlobal(
+    'fetch',
+    vi.fn().mockResolvedValue({ ok, json: () => Promise.resolve(body) }),
+  );
+}
+
+describe('StorageBanner', () => {
+  afterEach(() => {
+    vi.unstubAllGlobals();
+  });
+
+  it('renders nothing when storage is ok', async () => {
+    mockStatus({ enabled: true, level: 'ok', degraded: false });
+    render(StorageBanner);
+    await waitFor(() => expect(fetch).toHaveBeenCalledWith('/api/admin/storage'));
+    expect(screen.queryByTestId('storage-banner')).toBeNull();
+  });
+
+  it('renders nothing when monitoring is disabled', async () => {
+    mockStatus({ enabled: false, level: 'ok', degraded: false });
+    render(StorageBanner);
+    await waitFor(() => expect(fetch).toHaveBeenCalled());
+    expect(screen.queryByTestId('storage-banner')).toBeNull();
+  });
+
+  it('shows warnings and growth rate', async () => {
+    mockStatus({
+      enabled: true,
+      level: 'warning',
+      degraded: false,
+      warnings: ['free space is 8.0% (4.0 GiB), below the 10% warning threshold'],
+      growth_per_day_text: '120.0 MiB/day',
+    });
+    render(StorageBanner);
+    await waitFor(() => expect(screen.getByTestId('storage-banner')).toBeTruthy());
+    expect(screen.getByText('Storage running low')).toBeTruthy();
+    expect(screen.getByText(/below the 10% warning threshold/)).toBeTruthy();
+    expect(screen.getByText('Database growth: 120.0 MiB/day')).toBeTruthy();
+  });
+
+  it('shows a danger banner when sends are refused', async () => {
+    mockStatus({ enabled: true, level: 'critical', degraded: true, warnings: ['below floor'] });
+    render(StorageBanner);
+    await waitFor(() => expect(screen.getByText('Storage full: new messages are paused')).toBeTruthy());
+    expect(screen.queryByTestId('alert-dismiss')).toBeNull();
+  });
+
+  it('stays hidden when the request fails', async () => {
+    mockStatus({}, false);
+    render(StorageBanner);
+    await waitFor(() => expect(fetch).toHaveBeenCalled());
+    expect(screen.queryByTestId('storage-banner')).toBeNull();
+  });
+});
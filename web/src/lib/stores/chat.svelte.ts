@@ -14,7 +14,8 @@
  */
 
 import { createSSEStream, type SSEStatus } from './sse.svelte';
-import type { ChatMessage, ChatMessageType, QuestionOption } from '../types/chat';
+import { addCitation } from '../types/chat';
+import type { ChatMessage, ChatMessageType, Citation, PlanStep, PlanStepStatus, QuestionContext, QuestionOption } from '../types/chat';
 
 export interface ChatStreamOptions {
   /** Max reconnection attempts. 0 = infinite. Default: 5. */
@@ -42,7 +43,8 @@ export interface ChatStream {
 const CHAT_EVENT_TYPES: ChatMessageType[] = [
   'user', 'text', 'thinking', 'tool_use', 'tool_result',
   'error', 'done', 'usage', 'tool_state', 'canceled',
-  'tool_approval', 'user_question',
+  'truncated', 'system', 'tool_approval', 'user_question',
+  'plan', 'plan_step_update', 'citation',
 ];
 
 let idCounter = 0;
@@ -63,6 +65,8 @@ export function createChatStream(agentId: string, options: ChatStreamOptions = {
 
   let messages = $state<ChatMessage[]>([]);
   let isStreaming = $state(false);
+  // Citations that arrived before any text of the current reply
+  let pendingSources: Citation[] = [];
 
   function handleEvent(type: ChatMessageType, event: MessageEvent) {
     let data: Record<string, unknown>;
@@ -83,6 +87,45 @@ export function createChatStream(agentId: string, options: ChatStreamOptions = {
       return;
     }
 
+    if (type === 'plan_step_update') {
+      // Tick off a step on the latest plan card rather than adding a message
+      const plan = messages.findLast((m) => m.type === 'plan' && m.steps);
+      const index = data.step_index as number | undefined;
+      if (plan?.steps && index !== undefined && index >= 0 && index < plan.steps.length) {
+        plan.steps[index].status = data.state as PlanStepStatus;
+      }
+      return;
+    }
+
+    if (type === 'citation') {
+      // Attach to the reply's latest text; citations may arrive before,
+      // between, or after text chunks.
+      const citation = data.citation as Citation | undefined;
+      if (!citation) return;
+      const last = messages.findLast((m) => m.type === 'text' || m.type === 'user');
+      if (last?.type === 'text') {
+        last.sources = addCitation(last.sources ?? [], citation);
+      } else {
+        pendingSources = addCitation(pendingSources, citation);
+      }
+      return;
+    }
+
+    if (type === 'user_question' && messages.some((m) => m.questionId === data.question_id)) {
+      // Pending questions are replayed on every (re)connect
+      return;
+    }
+
+    // Long text arrives split over several events; the later ones extend
+    // the previous chunk instead of starting a new block
+    if ((type === 'text' || type === 'thinking') && data.continued) {
+      const prev = messages.at(-1);
+      if (prev?.type === type) {
+        prev.content += (data.content as string) ?? '';
+        return;
+      }
+    }
+
     const msg: ChatMessage = {
       id: (data.id as string) ?? nextId(),
       type,
@@ -98,8 +141,13 @@ export function createChatStream(agentId: string, options: ChatStreamOptions = {
       question: data.question as string | undefined,
       options: Array.isArray(data.options) ? data.options as QuestionOption[] : undefined,
       multiSelect: data.multi_select as boolean | undefined,
+      allowFreeText: data.allow_free_text as boolean | undefined,
+      pattern: data.pattern as string | undefined,
+      maxLength: data.max_length as number | undefined,
       header: data.header as string | undefined,
       timeoutSeconds: data.timeout_seconds as number | undefined,
+      questionContext: data.context as QuestionContext | undefined,
+      steps: Array.isArray(data.steps) ? data.steps as PlanStep[] : undefined,
     };
 
     // For tool_use, the "content" field from backend is the input JSON
@@ -108,12 +156,32 @@ export function createChatStream(agentId: string, options: ChatStreamOptions = {
       msg.content = '';
     }
 
+    if (type === 'user') {
+      pendingSources = [];
+    }
+    if (type === 'text') {
+      // The reply's sources move to its newest chunk so they render once,
+      // under the end of the answer
+      const prev = messages.findLast((m) => m.type === 'text' || m.type === 'user');
+      let sources = prev?.type === 'text' ? (prev.sources ?? []) : [];
+      if (prev?.type === 'text') prev.sources = undefined;
+      for (const c of pendingSources) sources = addCitation(sources, c);
+      pendingSources = [];
+      if (sources.length > 0) msg.sources = sources;
+    }
+
     if (type === 'error') {
       onError?.(msg.content);
     }
 
+    // The gateway cut the reply short or it was stopped; no done event will follow
+    if (type === 'truncated' || type === 'canceled') {
+      isStreaming = false;
+      onDone?.();
+    }
+
     // Mark streaming when agent starts responding
-    if (type === 'text' || type === 'thinking' || type === 'tool_use') {
+    if (type === 'text' || type === 'thinking' || type === 'tool_use' || type === 'plan') {
       isStreaming = true;
     }
 
@@ -137,6 +205,39 @@ export function createChatStream(agentId: string, options: ChatStreamOptions = {
     }
   };
 
+  // The gateway dropped broadcast events this client was too slow to take
+  onevents['lagged'] = (event: MessageEvent) => {
+    let skipped = 0;
+    try {
+      skipped = JSON.parse(event.data).skipped ?? 0;
+    } catch {
+      // Still worth telling the user something was missed
+    }
+    messages.push({
+      id: nextId(),
+      type: 'system',
+      content: `Missed ${skipped || 'some'} updates while the connection was slow. Reload to see the full conversation.`,
+      timestamp: new Date(),
+    });
+  };
+
+  // A new agent is waiting for an admin to approve its principal; every
+  // open chat hears about it, whichever agent it is with
+  onevents['agent_pending'] = (event: MessageEvent) => {
+    let content = 'A new agent is waiting for approval';
+    try {
+      content = JSON.parse(event.data).content || content;
+    } catch {
+      // Still worth showing the generic notice
+    }
+    messages.push({
+      id: nextId(),
+      type: 'system',
+      content: `${content}. Approve it on the Principals page.`,
+      timestamp: new Date(),
+    });
+  };
+
   const url = `/chat/${encodeURIComponent(agentId)}/stream`;
 
   const stream = createSSEStream(url, {
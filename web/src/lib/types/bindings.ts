@@ -0,0 +1,21 @@
+/**
+ * Binding types matching the backend bindingItem and bindingsPanel structs
+ * in internal/webadmin/bindings.go.
+ */
+
+export interface BindingItem {
+  id: string;
+  frontend: string;
+  channelId: string;
+  agentId: string;
+  workspace?: string;
+  /** Channel context sent to the agent with each message; empty for none. */
+  context: string;
+  /** SHA-256 of the context, as recorded on ledger events. */
+  contextHash?: string;
+}
+
+export interface BindingsPanelData {
+  bindings: BindingItem[];
+  maxContextBytes: number;
+}
@@ -14,8 +14,13 @@ export type ChatMessageType =
   | 'usage'
   | 'tool_state'
   | 'canceled'
+  | 'truncated'
+  | 'system'
   | 'tool_approval'
-  | 'user_question';
+  | 'user_question'
+  | 'plan'
+  | 'plan_step_update'
+  | 'citation';
 
 export interface ChatMessage {
   id: string;
@@ -39,16 +44,46 @@ export interface ChatMessage {
   state?: string;
   detail?: string;
 
-  // Canceled
+  // Canceled / truncated
   reason?: string;
 
+  // Plan. Plans loaded from history carry only the text in content.
+  steps?: PlanStep[];
+
+  // Sources cited by an agent text message, sorted by index
+  sources?: Citation[];
+
   // User question
   questionId?: string;
   question?: string;
   options?: QuestionOption[];
   multiSelect?: boolean;
+  allowFreeText?: boolean;
+  pattern?: string;
+  maxLength?: number;
   header?: string;
   timeoutSeconds?: number;
+  questionContext?: QuestionContext;
+}
+
+export type PlanStepStatus = 'pending' | 'in_progress' | 'completed' | 'skipped' | 'failed';
+
+export interface PlanStep {
+  title: string;
+  status: PlanStepStatus;
+}
+
+/** A source the agent cited; [index] markers in the text refer to it */
+export interface Citation {
+  index: number;
+  title: string;
+  uri: string;
+  snippet?: string;
+}
+
+/** Record c on sources, replacing any earlier citation with the same index */
+export function addCitation(sources: Citation[], c: Citation): Citation[] {
+  return [...sources.filter((s) => s.index !== c.index), c].sort((a, b) => a.index - b.index);
 }
 
 export interface QuestionOption {
@@ -56,6 +91,15 @@ export interface QuestionOption {
   description?: string;
 }
 
+/** Snapshot of what the agent was doing when it asked a question */
+export interface QuestionContext {
+  version: string;
+  thread_id?: string;
+  exchanges?: { author: string; direction: 'inbound' | 'outbound'; text: string; timestamp?: string }[];
+  tool_calls?: { name: string; params?: Record<string, string> }[];
+  truncated?: boolean;
+}
+
 /** Sender derived from message type */
 export type MessageSender = 'user' | 'agent' | 'system';
 
@@ -67,6 +111,8 @@ export function getMessageSender(type: ChatMessageType): MessageSender {
     case 'done':
     case 'usage':
     case 'canceled':
+    case 'truncated':
+    case 'system':
       return 'system';
     default:
       return 'agent';
@@ -79,12 +125,20 @@ export function isDisplayableMessage(type: ChatMessageType): boolean {
     case 'done':
     case 'usage':
     case 'canceled':
+    case 'plan_step_update':
+    case 'citation':
       return false;
     default:
       return true;
   }
 }
 
+/** A live plan with steps still pending or in progress; the thread pins it */
+export function isActivePlan(message: ChatMessage): boolean {
+  return message.type === 'plan' &&
+    (message.steps ?? []).some((s) => s.status === 'pending' || s.status === 'in_progress');
+}
+
 /** Group messages by date for date separator rendering */
 export function groupMessagesByDate(messages: ChatMessage[]): Map<string, ChatMessage[]> {
   const groups = new Map<string, ChatMessage[]>();
@@ -0,0 +1,18 @@
+/**
+ * Tool pack health types matching the backend packHealthItem and packsPanel
+ * structs in internal/webadmin/packs.go.
+ */
+
+export interface PackHealth {
+  id: string;
+  version: string;
+  tools: string[];
+  /** False once a pack that heartbeats has gone quiet past the miss window. */
+  healthy: boolean;
+  /** Empty for packs that have never heartbeated. */
+  lastHeartbeat?: string;
+}
+
+export interface PacksPanelData {
+  packs: PackHealth[];
+}
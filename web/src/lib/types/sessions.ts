@@ -0,0 +1,24 @@
+/**
+ * Admin session types matching the backend sessionItem and sessionsPanel
+ * structs in internal/webadmin/sessions.go. The id is a hash of the session
+ * ID, never the session cookie itself.
+ */
+
+export interface AdminSession {
+  id: string;
+  userId: string;
+  username: string;
+  displayName: string;
+  createdAt: string;
+  expiresAt: string;
+  lastSeenAt: string;
+  userAgent?: string;
+  ip?: string;
+  /** The session viewing the page. */
+  current: boolean;
+}
+
+export interface SessionsPanelData {
+  sessions: AdminSession[];
+  manageAll: boolean;
+}
@@ -0,0 +1,37 @@
+/**
+ * API token types matching the backend tokenItem and tokensPanel structs
+ * in internal/webadmin/tokens.go. Token values are never part of these.
+ */
+
+export type TokenStatus = 'active' | 'expired' | 'revoked';
+
+export interface ApiToken {
+  id: string;
+  principalId: string;
+  principalName: string;
+  scopes: string[];
+  /** Agents the token is limited to; absent means any agent. */
+  agentIds?: string[];
+  /** Capability subset the token is limited to; absent means all. */
+  capabilities?: string[];
+  createdBy?: string;
+  createdAt: string;
+  expiresAt: string;
+  lastUsedAt?: string;
+  revokedAt?: string;
+  status: TokenStatus;
+}
+
+export interface TokenPrincipal {
+  id: string;
+  displayName: string;
+  type: string;
+}
+
+export interface TokensPanelData {
+  enabled: boolean;
+  tokens: ApiToken[];
+  principals: TokenPrincipal[];
+  manageAll: boolean;
+  scopes: string[];
+}
@@ -0,0 +1,100 @@
+/**
+ * Token usage drill-down types matching the backend usageTotals,
+ * agentUsageItem, threadUsageItem, and requestUsageItem structs
+ * in internal/webadmin/usage.go.
+ */
+
+export interface UsageTotals {
+  totalInput: number;
+  totalOutput: number;
+  totalCacheRead: number;
+  totalCacheWrite: number;
+  totalThinking: number;
+  totalTokens: number;
+  requestCount: number;
+}
+
+export interface AgentUsage extends UsageTotals {
+  agentId: string;
+  lastRequestAt: string;
+}
+
+export interface ThreadUsage extends UsageTotals {
+  threadId: string;
+  frontendName: string;
+  externalId: string;
+  lastRequestAt: string;
+}
+
+export interface RequestUsage extends UsageTotals {
+  requestId: string;
+  messageId?: string;
+  agentId: string;
+  createdAt: string;
+}
+
+/**
+ * Usage summary from GET /api/admin/usage/summary, matching usage.Summary in
+ * internal/usage/usage.go (the same shape as the gateway's /api/usage/summary).
+ */
+export interface SummaryTotals {
+  input_tokens: number;
+  output_tokens: number;
+  cache_read_tokens: number;
+  cache_write_tokens: number;
+  thinking_tokens: number;
+  total_tokens: number;
+  request_count: number;
+  /** Absent unless usage.pricing prices every agent involved. */
+  estimated_cost_usd?: number;
+}
+
+export interface SummaryGroup extends SummaryTotals {
+  key: string;
+  last_request_at?: string;
+}
+
+export interface UsageSummary {
+  group_by: 'agent' | 'day';
+  totals: SummaryTotals;
+  groups: SummaryGroup[];
+}
+
+/** Converts summary totals to the drill-down's UsageTotals shape. */
+export function fromSummaryTotals(t: SummaryTotals): UsageTotals {
+  return {
+    totalInput: t.input_tokens,
+    totalOutput: t.output_tokens,
+    totalCacheRead: t.cache_read_tokens,
+    totalCacheWrite: t.cache_write_tokens,
+    totalThinking: t.thinking_tokens,
+    totalTokens: t.total_tokens,
+    requestCount: t.request_count,
+  };
+}
+
+/** A since/until window as RFC3339 strings; empty means unbounded. */
+export interface UsageRange {
+  since: string;
+  until: string;
+}
+
+/** Appends the window (and any extra params) to a usage endpoint URL. */
+export function usageURL(path: string, range: UsageRange, extra: Record<string, string> = {}): string {
+  const params = new URLSearchParams(extra);
+  if (range.since) params.set('since', range.since);
+  if (range.until) params.set('until', range.until);
+  const query = params.toString();
+  return query ? `${path}?${query}` : path;
+}
+
+export function formatCost(usd: number | undefined): string {
+  if (usd === undefined) return '\u2014';
+  return '$' + (usd < 1 ? usd.toFixed(4) : usd.toFixed(2));
+}
+
+export function formatTokens(n: number): string {
+  if (n >= 1_000_000) return (n / 1_000_000).toFixed(1) + 'M';
+  if (n >= 1_000) return (n / 1_000).toFixed(1) + 'K';
+  return n.toString();
+}